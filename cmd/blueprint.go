@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var blueprintCmd = &cobra.Command{
+	Use:   "blueprint",
+	Short: "Manage cached blueprints and push blueprints to OCI registries",
+	Long: `Manage the blueprints and Helm charts cached by Shipyard and push blueprints to OCI registries,
+the commit of every source is recorded when it is fetched and the cached copy is used until it is updated`,
+}
+
+func newBlueprintListCmd(lock *clients.SourceLock) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the cached blueprints and Helm charts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sources, err := lock.Sources()
+			if err != nil {
+				return newCommandError(ErrorCodeState, "Unable to read cached blueprints: %s", err)
+			}
+
+			if len(sources) == 0 {
+				cmd.Println("No cached blueprints found")
+				return nil
+			}
+
+			cmd.Printf("%-60s %-12s %s\n", "SOURCE", "COMMIT", "FETCHED")
+			for _, s := range sources {
+				cmd.Printf("%-60s %-12s %s\n", s.Source, shortCommit(s.Commit), s.FetchedAt.Local().Format("2006-01-02 15:04:05"))
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
+
+func newBlueprintUpdateCmd(bp clients.Getter, lock *clients.SourceLock) *cobra.Command {
+	return &cobra.Command{
+		Use:   "update [name]",
+		Short: "Fetch the latest version of the cached blueprints and Helm charts",
+		Long: `Fetch the latest version of the cached blueprints and Helm charts and record the new
+commit in the lock file, when a name is given only the sources containing the name are updated`,
+		Example: `
+  # Update all the cached blueprints
+  shipyard blueprint update
+
+  # Update the blueprints from a repository
+  shipyard blueprint update github.com/shipyard-run/blueprints
+	`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+			}
+
+			sources, err := lock.Sources()
+			if err != nil {
+				return newCommandError(ErrorCodeState, "Unable to read cached blueprints: %s", err)
+			}
+
+			matched := []clients.LockedSource{}
+			for _, s := range sources {
+				if strings.Contains(s.Source, name) {
+					matched = append(matched, s)
+				}
+			}
+
+			if len(matched) == 0 {
+				return newCommandError(ErrorCodeUsage, "No cached blueprints match '%s', use 'shipyard blueprint list' to show the cached blueprints", name)
+			}
+
+			bp.SetForce(true)
+
+			failed := 0
+			for _, s := range matched {
+				err := bp.Get(s.Source, s.Path)
+				if err != nil {
+					cmd.Printf("Unable to update %s: %s\n", s.Source, err)
+					failed++
+
+					continue
+				}
+
+				updated, err := lock.Find(s.Source)
+				if err != nil || updated == nil {
+					cmd.Printf("Updated %s\n", s.Source)
+					continue
+				}
+
+				if updated.Commit == s.Commit {
+					cmd.Printf("%s is up to date\n", s.Source)
+					continue
+				}
+
+				cmd.Printf("Updated %s %s -> %s\n", s.Source, shortCommit(s.Commit), shortCommit(updated.Commit))
+			}
+
+			if failed > 0 {
+				return newCommandError(ErrorCodeUnknown, "Unable to update %d of %d blueprints", failed, len(matched))
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
+
+func newBlueprintPushCmd(reg clients.BlueprintRegistry) *cobra.Command {
+	return &cobra.Command{
+		Use:   "push [folder] [reference]",
+		Short: "Push a blueprint to an OCI registry",
+		Long: `Package the blueprint in a folder as an OCI artifact and push it to a registry, the credentials
+for the registry are read from the Docker config, login with 'docker login' before pushing.
+
+Blueprints pushed to a registry can be run with 'shipyard run oci://[reference]'`,
+		Example: `
+  # Push the blueprint in the current folder
+  shipyard blueprint push ./ ghcr.io/org/consul:v1
+
+  # Run the pushed blueprint
+  shipyard run oci://ghcr.io/org/consul:v1
+	`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, ref := args[0], strings.TrimPrefix(args[1], "oci://")
+
+			if !utils.IsLocalFolder(dir) {
+				return newCommandError(ErrorCodeUsage, "Folder %s does not exist", dir)
+			}
+
+			digest, err := reg.Push(context.Background(), dir, ref)
+			if err != nil {
+				return newCommandError(ErrorCodeUnknown, "Unable to push blueprint: %s", err)
+			}
+
+			cmd.Printf("Pushed %s to %s, digest: %s\n", dir, ref, digest)
+			cmd.Printf("Run the blueprint with 'shipyard run oci://%s'\n", ref)
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
+
+// shortCommit returns the abbreviated form of a commit
+func shortCommit(c string) string {
+	if c == "" {
+		return "-"
+	}
+
+	if len(c) > 12 {
+		return c[:12]
+	}
+
+	return c
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBlueprintUpdate(t *testing.T, err error) (*cobra.Command, *mocks.Getter, *clients.SourceLock, *bytes.Buffer) {
+	lock := clients.NewSourceLock(filepath.Join(t.TempDir(), "sources.lock.json"))
+	lock.Add(clients.LockedSource{Source: "github.com/org/blueprints//consul", Path: "/tmp/consul", Commit: "abc"})
+	lock.Add(clients.LockedSource{Source: "github.com/org/blueprints//vault", Path: "/tmp/vault", Commit: "def"})
+
+	bp := &mocks.Getter{}
+	bp.On("Get", mock.Anything, mock.Anything).Return(err)
+	bp.On("SetForce", mock.Anything)
+
+	out := bytes.NewBufferString("")
+	c := newBlueprintUpdateCmd(bp, lock)
+	c.SetOut(out)
+
+	return c, bp, lock, out
+}
+
+func TestBlueprintUpdateUpdatesAllSources(t *testing.T) {
+	c, bp, _, _ := setupBlueprintUpdate(t, nil)
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	bp.AssertCalled(t, "SetForce", true)
+	bp.AssertCalled(t, "Get", "github.com/org/blueprints//consul", "/tmp/consul")
+	bp.AssertCalled(t, "Get", "github.com/org/blueprints//vault", "/tmp/vault")
+}
+
+func TestBlueprintUpdateWithNameUpdatesMatchingSources(t *testing.T) {
+	c, bp, _, out := setupBlueprintUpdate(t, nil)
+	c.SetArgs([]string{"consul"})
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	bp.AssertNumberOfCalls(t, "Get", 1)
+	bp.AssertCalled(t, "Get", "github.com/org/blueprints//consul", "/tmp/consul")
+	assert.Contains(t, out.String(), "is up to date")
+}
+
+func TestBlueprintUpdateWithUnknownNameReturnsError(t *testing.T) {
+	c, bp, _, _ := setupBlueprintUpdate(t, nil)
+	c.SetArgs([]string{"nomad"})
+
+	err := c.Execute()
+	require.Error(t, err)
+
+	assert.Equal(t, ErrorCodeUsage, err.(*CommandError).Code)
+	bp.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+func TestBlueprintUpdateReturnsErrorWhenGetFails(t *testing.T) {
+	c, _, _, out := setupBlueprintUpdate(t, fmt.Errorf("boom"))
+
+	err := c.Execute()
+	require.Error(t, err)
+
+	assert.Contains(t, out.String(), "Unable to update github.com/org/blueprints//consul")
+}
+
+func TestBlueprintListShowsSources(t *testing.T) {
+	_, _, lock, _ := setupBlueprintUpdate(t, nil)
+
+	out := bytes.NewBufferString("")
+	c := newBlueprintListCmd(lock)
+	c.SetOut(out)
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	assert.Contains(t, out.String(), "github.com/org/blueprints//consul")
+	assert.Contains(t, out.String(), "def")
+}
+
+func setupBlueprintPush(t *testing.T, err error) (*cobra.Command, *mocks.BlueprintRegistry, *bytes.Buffer) {
+	reg := &mocks.BlueprintRegistry{}
+	reg.On("Push", mock.Anything, mock.Anything, mock.Anything).Return("sha256:abc", err)
+
+	out := bytes.NewBufferString("")
+	c := newBlueprintPushCmd(reg)
+	c.SetOut(out)
+
+	return c, reg, out
+}
+
+func TestBlueprintPushPushesFolder(t *testing.T) {
+	c, reg, out := setupBlueprintPush(t, nil)
+	dir := t.TempDir()
+	c.SetArgs([]string{dir, "oci://ghcr.io/org/consul:v1"})
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	reg.AssertCalled(t, "Push", mock.Anything, dir, "ghcr.io/org/consul:v1")
+	assert.Contains(t, out.String(), "sha256:abc")
+}
+
+func TestBlueprintPushWithMissingFolderReturnsError(t *testing.T) {
+	c, reg, _ := setupBlueprintPush(t, nil)
+	c.SetArgs([]string{"/nope/missing", "ghcr.io/org/consul:v1"})
+
+	err := c.Execute()
+	require.Error(t, err)
+
+	assert.Equal(t, ErrorCodeUsage, err.(*CommandError).Code)
+	reg.AssertNotCalled(t, "Push", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBlueprintPushReturnsErrorWhenPushFails(t *testing.T) {
+	c, _, _ := setupBlueprintPush(t, fmt.Errorf("denied"))
+	c.SetArgs([]string{t.TempDir(), "ghcr.io/org/consul:v1"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
@@ -0,0 +1,472 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the image cache",
+	Long:  `Manage the image cache used by Shipyard to cache images pulled by clusters`,
+}
+
+// cacheLogLine matches the access log written by the image cache
+// [cache status] [time] "uri" status bytes "host"
+var cacheLogLine = regexp.MustCompile(`(HIT|MISS|EXPIRED|STALE|UPDATING|REVALIDATED|BYPASS) \[[^\]]*\] "([^"]*)" (\d{3}) (\d+)(?: "([^"]*)")?`)
+
+// cacheImagePath extracts the image name from a registry API path
+var cacheImagePath = regexp.MustCompile(`^/v2/(.+)/(manifests|blobs)/`)
+
+type cacheRegistryStats struct {
+	Registry string
+	Hits     int
+	Misses   int
+	Bytes    int64
+}
+
+type cacheImageStats struct {
+	Image    string
+	Requests int
+	Bytes    int64
+}
+
+type cacheStats struct {
+	Registries []*cacheRegistryStats
+	Images     []*cacheImageStats
+}
+
+func newCacheStatsCmd(ct clients.ContainerTasks) *cobra.Command {
+	var top int
+
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show hit and miss statistics for the image cache",
+		Long:  `Show hit and miss statistics for the image cache, statistics are read from the logs of the image cache`,
+		Example: `
+  shipyard cache stats
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := ct.FindContainerIDs("docker-cache", config.TypeImageCache)
+			if err != nil {
+				return fmt.Errorf("Unable to find image cache: %s", err)
+			}
+
+			if len(ids) == 0 {
+				return fmt.Errorf("Image cache is not running")
+			}
+
+			rc, err := ct.ContainerLogs(ids[0], true, true)
+			if err != nil {
+				return fmt.Errorf("Unable to read logs for image cache: %s", err)
+			}
+			defer rc.Close()
+
+			// the logs are multiplexed, stdout and stderr are combined
+			buf := bytes.NewBuffer(nil)
+			_, err = stdcopy.StdCopy(buf, buf, rc)
+			if err != nil {
+				return fmt.Errorf("Unable to read logs for image cache: %s", err)
+			}
+
+			s := parseCacheStats(buf)
+
+			totalHits := 0
+			totalMisses := 0
+
+			cmd.Println()
+			cmd.Printf("%-30s %-8s %-8s %-9s %s\n", "REGISTRY", "HITS", "MISSES", "HIT RATE", "BYTES SERVED")
+			for _, r := range s.Registries {
+				cmd.Printf("%-30s %-8d %-8d %-9s %s\n", r.Registry, r.Hits, r.Misses, hitRate(r.Hits, r.Misses), formatBytes(r.Bytes))
+
+				totalHits += r.Hits
+				totalMisses += r.Misses
+			}
+
+			cmd.Println()
+			cmd.Printf("%-50s %-9s %s\n", "IMAGE", "REQUESTS", "BYTES SERVED")
+			for i, im := range s.Images {
+				if i >= top {
+					break
+				}
+
+				cmd.Printf("%-50s %-9d %s\n", im.Image, im.Requests, formatBytes(im.Bytes))
+			}
+
+			cmd.Println()
+			cmd.Printf("Hits: %d Misses: %d Hit Rate: %s\n", totalHits, totalMisses, hitRate(totalHits, totalMisses))
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	statsCmd.Flags().IntVarP(&top, "top", "", 10, "Number of cached images to show")
+
+	return statsCmd
+}
+
+func newCacheWarmCmd(ct clients.ContainerTasks, l hclog.Logger) *cobra.Command {
+	var variables []string
+	var variablesFile string
+	var platform string
+
+	warmCmd := &cobra.Command{
+		Use:   "warm [images file | blueprint]",
+		Short: "Pull images through the image cache ahead of time",
+		Long: `Pull images through the image cache ahead of time.
+	Images can be specified in a file containing one image per line, or
+	read from the resources in a local blueprint. The platform for multi-arch
+	images can be set after the image name e.g. "consul:1.10.0 linux/amd64"`,
+		Example: `
+  # Warm the cache with images listed in a file
+  shipyard cache warm ./images.txt
+
+  # Warm the cache with the images used by a blueprint
+  shipyard cache warm ./my-blueprint
+
+  # Warm the cache with the amd64 images used by a blueprint
+  shipyard cache warm --platform linux/amd64 ./my-blueprint
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vars := map[string]string{}
+			for _, v := range variables {
+				parts := strings.Split(v, "=")
+				if len(parts) == 2 {
+					vars[parts[0]] = parts[1]
+				}
+			}
+
+			images, err := readWarmImages(args[0], vars, variablesFile)
+			if err != nil {
+				return err
+			}
+
+			if len(images) == 0 {
+				return fmt.Errorf("No images found in %s", args[0])
+			}
+
+			// set the default platform for images which do not specify one
+			for i := range images {
+				if images[i].Platform == "" {
+					images[i].Platform = platform
+				}
+
+				err := images[i].Validate()
+				if err != nil {
+					return err
+				}
+			}
+
+			proxy, err := cacheProxyAddress(ct)
+			if err != nil {
+				return err
+			}
+
+			hc, err := clients.NewCacheProxyClient(proxy, filepath.Join(utils.CertsDir(""), "root.cert"))
+			if err != nil {
+				return err
+			}
+
+			cw := clients.NewCacheWarmer(hc, l)
+
+			var total int64
+			failed := 0
+
+			for _, i := range images {
+				if i.Platform != "" {
+					cmd.Printf("Warming %s (%s) ", i.Name, i.Platform)
+				} else {
+					cmd.Printf("Warming %s ", i.Name)
+				}
+
+				size, err := cw.Warm(i)
+				if err != nil {
+					cmd.Printf("failed: %s\n", err)
+					failed++
+					continue
+				}
+
+				cmd.Printf("%s\n", formatBytes(size))
+				total += size
+			}
+
+			cmd.Println()
+			cmd.Printf("Warmed %d of %d images, %s downloaded\n", len(images)-failed, len(images), formatBytes(total))
+
+			if failed > 0 {
+				return fmt.Errorf("Unable to warm %d images", failed)
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	warmCmd.Flags().StringSliceVarP(&variables, "var", "", nil, "Allows setting variables from the command line when reading images from a blueprint, e.g --var key=value. Can be specified multiple times")
+	warmCmd.Flags().StringVarP(&variablesFile, "vars-file", "", "", "Load variables from a location other than *.vars files in the blueprint folder. E.g --vars-file=./file.vars")
+	warmCmd.Flags().StringVarP(&platform, "platform", "", "", "Platform to warm for multi-arch images which do not specify a platform e.g. linux/amd64, defaults to the platform of the current machine")
+
+	return warmCmd
+}
+
+// readWarmImages returns the images to warm, path can either be a file containing
+// a list of images or a blueprint folder or HCL file
+func readWarmImages(path string, vars map[string]string, variablesFile string) ([]config.Image, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %s", path, err)
+	}
+
+	if fi.IsDir() || utils.IsConfigFile(path) {
+		return blueprintImages(path, vars, variablesFile)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %s", path, err)
+	}
+	defer f.Close()
+
+	images := []config.Image{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// ignore blank lines and comments
+		l := strings.TrimSpace(scanner.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		// lines are in the format image [platform]
+		parts := strings.Fields(l)
+		i := config.Image{Name: parts[0]}
+		if len(parts) > 1 {
+			i.Platform = parts[1]
+		}
+
+		images = appendUniqueImage(images, i)
+	}
+
+	return images, scanner.Err()
+}
+
+// blueprintImages parses the blueprint at the given path and returns the
+// images used by the resources, images built locally are not returned
+func blueprintImages(path string, vars map[string]string, variablesFile string) ([]config.Image, error) {
+	c, err := parseBlueprint(path, vars, variablesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	images := []config.Image{}
+	add := func(i *config.Image) {
+		if i == nil || i.Name == "" || strings.HasPrefix(i.Name, "shipyard.run/localcache") {
+			return
+		}
+
+		images = appendUniqueImage(images, config.Image{Name: i.Name, Platform: i.Platform})
+	}
+
+	for _, r := range c.Resources {
+		switch v := r.(type) {
+		case *config.Container:
+			add(v.Image)
+		case *config.Sidecar:
+			add(&v.Image)
+		case *config.ExecRemote:
+			add(v.Image)
+		case *config.Docs:
+			add(v.Image)
+		case *config.K8sCluster:
+			for i := range v.Images {
+				add(&v.Images[i])
+			}
+		case *config.NomadCluster:
+			for i := range v.Images {
+				add(&v.Images[i])
+			}
+		}
+	}
+
+	return images, nil
+}
+
+// parseBlueprint parses the blueprint folder or HCL file at the given path
+func parseBlueprint(path string, vars map[string]string, variablesFile string) (*config.Config, error) {
+	c := config.New()
+
+	var err error
+	if utils.IsConfigFile(path) {
+		err = config.ParseSingleFile(path, c, vars, variablesFile)
+	} else {
+		err = config.ParseFolder(path, c, false, "", false, []string{}, vars, variablesFile)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse blueprint: %s", err)
+	}
+
+	return c, nil
+}
+
+// cacheProxyAddress returns the address of the image cache proxy
+// which is exposed on the Docker host
+func cacheProxyAddress(ct clients.ContainerTasks) (string, error) {
+	ids, err := ct.FindContainerIDs("docker-cache", config.TypeImageCache)
+	if err != nil {
+		return "", fmt.Errorf("Unable to find image cache: %s", err)
+	}
+
+	if len(ids) == 0 {
+		return "", fmt.Errorf("Image cache is not running, start a blueprint with 'shipyard run' to create the cache")
+	}
+
+	info, err := ct.ContainerInfo(ids[0])
+	if err != nil {
+		return "", fmt.Errorf("Unable to read image cache details: %s", err)
+	}
+
+	ci, ok := info.(types.ContainerJSON)
+	if !ok || ci.NetworkSettings == nil {
+		return "", fmt.Errorf("Unable to read image cache details")
+	}
+
+	bindings := ci.NetworkSettings.Ports[nat.Port("3128/tcp")]
+	if len(bindings) == 0 {
+		return "", fmt.Errorf("Image cache does not expose a proxy port")
+	}
+
+	return fmt.Sprintf("http://%s:%s", utils.GetDockerIP(), bindings[0].HostPort), nil
+}
+
+// parseCacheStats reads the access log for the image cache and returns
+// the statistics grouped by registry and image
+func parseCacheStats(r io.Reader) *cacheStats {
+	registries := map[string]*cacheRegistryStats{}
+	images := map[string]*cacheImageStats{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := cacheLogLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		registry := m[5]
+		if registry == "" {
+			registry = "unknown"
+		}
+
+		size, _ := strconv.ParseInt(m[4], 10, 64)
+
+		rs, ok := registries[registry]
+		if !ok {
+			rs = &cacheRegistryStats{Registry: registry}
+			registries[registry] = rs
+		}
+
+		switch m[1] {
+		case "HIT", "STALE", "UPDATING", "REVALIDATED":
+			rs.Hits++
+		default:
+			rs.Misses++
+		}
+
+		rs.Bytes += size
+
+		if im := cacheImagePath.FindStringSubmatch(m[2]); im != nil {
+			name := im[1]
+			if registry != "unknown" {
+				name = fmt.Sprintf("%s/%s", registry, im[1])
+			}
+
+			is, ok := images[name]
+			if !ok {
+				is = &cacheImageStats{Image: name}
+				images[name] = is
+			}
+
+			is.Requests++
+			is.Bytes += size
+		}
+	}
+
+	s := &cacheStats{}
+	for _, r := range registries {
+		s.Registries = append(s.Registries, r)
+	}
+
+	for _, i := range images {
+		s.Images = append(s.Images, i)
+	}
+
+	sort.Slice(s.Registries, func(i, j int) bool {
+		return s.Registries[i].Registry < s.Registries[j].Registry
+	})
+
+	// order images by the data served, the most valuable images are first
+	sort.Slice(s.Images, func(i, j int) bool {
+		if s.Images[i].Bytes == s.Images[j].Bytes {
+			return s.Images[i].Image < s.Images[j].Image
+		}
+
+		return s.Images[i].Bytes > s.Images[j].Bytes
+	})
+
+	return s
+}
+
+func hitRate(hits, misses int) string {
+	if hits+misses == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.1f%%", float64(hits)/float64(hits+misses)*100)
+}
+
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// appendUniqueImage appends the image when the list does not contain
+// an image with the same name and platform
+func appendUniqueImage(list []config.Image, i config.Image) []config.Image {
+	for _, l := range list {
+		if l.Name == i.Name && l.Platform == i.Platform {
+			return list
+		}
+	}
+
+	return append(list, i)
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupCacheStats(t *testing.T, logs string) (*cobra.Command, *mocks.MockContainerTasks, *bytes.Buffer) {
+	// multiplex the logs in the same way as the Docker engine
+	mux := bytes.NewBuffer(nil)
+	stdcopy.NewStdWriter(mux, stdcopy.Stdout).Write([]byte(logs))
+
+	mt := &mocks.MockContainerTasks{}
+	mt.On("FindContainerIDs", "docker-cache", mock.Anything).Return([]string{"abc"}, nil)
+	mt.On("ContainerLogs", "abc", true, true).Return(ioutil.NopCloser(mux), nil)
+
+	out := bytes.NewBuffer(nil)
+
+	cmd := newCacheStatsCmd(mt)
+	cmd.SetOut(out)
+
+	return cmd, mt, out
+}
+
+func TestCacheStatsParsesHitsAndMisses(t *testing.T) {
+	s := parseCacheStats(strings.NewReader(cacheLogs))
+
+	assert.Len(t, s.Registries, 2)
+
+	assert.Equal(t, "quay.io", s.Registries[0].Registry)
+	assert.Equal(t, 1, s.Registries[0].Hits)
+	assert.Equal(t, 1, s.Registries[0].Misses)
+	assert.Equal(t, int64(3072), s.Registries[0].Bytes)
+
+	assert.Equal(t, "registry-1.docker.io", s.Registries[1].Registry)
+	assert.Equal(t, 2, s.Registries[1].Hits)
+	assert.Equal(t, 0, s.Registries[1].Misses)
+}
+
+func TestCacheStatsOrdersImagesByBytes(t *testing.T) {
+	s := parseCacheStats(strings.NewReader(cacheLogs))
+
+	assert.Len(t, s.Images, 2)
+	assert.Equal(t, "registry-1.docker.io/library/consul", s.Images[0].Image)
+	assert.Equal(t, 2, s.Images[0].Requests)
+	assert.Equal(t, "quay.io/jetstack/cert-manager", s.Images[1].Image)
+}
+
+func TestCacheStatsPrintsStatistics(t *testing.T) {
+	cmd, _, out := setupCacheStats(t, cacheLogs)
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "quay.io")
+	assert.Contains(t, out.String(), "Hits: 3 Misses: 1 Hit Rate: 75.0%")
+}
+
+func TestCacheStatsReturnsErrorWhenCacheNotRunning(t *testing.T) {
+	cmd, mt, _ := setupCacheStats(t, cacheLogs)
+	removeOn(&mt.Mock, "FindContainerIDs")
+	mt.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{}, nil)
+
+	err := cmd.Execute()
+	assert.Error(t, err)
+}
+
+func TestCacheStatsReturnsErrorWhenLogsError(t *testing.T) {
+	cmd, mt, _ := setupCacheStats(t, cacheLogs)
+	removeOn(&mt.Mock, "ContainerLogs")
+	mt.On("ContainerLogs", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	err := cmd.Execute()
+	assert.Error(t, err)
+}
+
+func TestCacheWarmReadsImagesFromFile(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "images.txt")
+	ioutil.WriteFile(f, []byte(warmImages), os.ModePerm)
+
+	images, err := readWarmImages(f, nil, "")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []config.Image{
+		{Name: "consul:1.10.0"},
+		{Name: "quay.io/jetstack/cert-manager-controller:v1.6.0"},
+		{Name: "consul:1.10.0", Platform: "linux/amd64"},
+	}, images)
+}
+
+func TestCacheWarmReadsImagesFromBlueprint(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(warmBlueprint), os.ModePerm)
+
+	images, err := readWarmImages(dir, map[string]string{"consul_version": "1.11.0"}, "")
+	assert.NoError(t, err)
+
+	assert.Contains(t, images, config.Image{Name: "consul:1.11.0"})
+	assert.Contains(t, images, config.Image{Name: "envoyproxy/envoy:v1.18.4", Platform: "linux/amd64"})
+	assert.Len(t, images, 2)
+}
+
+func TestCacheWarmReturnsErrorWhenFileNotFound(t *testing.T) {
+	_, err := readWarmImages("/not/exist", nil, "")
+	assert.Error(t, err)
+}
+
+func TestCacheProxyAddressReturnsHostPort(t *testing.T) {
+	mt := &mocks.MockContainerTasks{}
+	mt.On("FindContainerIDs", "docker-cache", mock.Anything).Return([]string{"abc"}, nil)
+	mt.On("ContainerInfo", "abc").Return(types.ContainerJSON{
+		NetworkSettings: &types.NetworkSettings{
+			NetworkSettingsBase: types.NetworkSettingsBase{
+				Ports: nat.PortMap{"3128/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "31234"}}},
+			},
+		},
+	}, nil)
+
+	addr, err := cacheProxyAddress(mt)
+	assert.NoError(t, err)
+
+	assert.True(t, strings.HasSuffix(addr, ":31234"))
+}
+
+func TestCacheProxyAddressReturnsErrorWhenCacheNotRunning(t *testing.T) {
+	mt := &mocks.MockContainerTasks{}
+	mt.On("FindContainerIDs", "docker-cache", mock.Anything).Return([]string{}, nil)
+
+	_, err := cacheProxyAddress(mt)
+	assert.Error(t, err)
+}
+
+var cacheLogs = `
+MISS [01/Jan/2022:10:00:00 +0000] "/v2/jetstack/cert-manager/manifests/v1.6.0" 200 1024 "quay.io"
+HIT [01/Jan/2022:10:00:01 +0000] "/v2/jetstack/cert-manager/manifests/v1.6.0" 200 2048 "quay.io"
+HIT [01/Jan/2022:10:00:02 +0000] "/v2/library/consul/blobs/sha256:abc" 200 20480 "registry-1.docker.io"
+HIT [01/Jan/2022:10:00:03 +0000] "/v2/library/consul/manifests/1.10.0" 200 512 "registry-1.docker.io"
+- [01/Jan/2022:10:00:04 +0000] "/v2/" 401 0 "registry-1.docker.io"
+CONNECTPROXY: 10.5.0.2 - - [01/Jan/2022:10:00:04 +0000] "CONNECT quay.io:443 HTTP/1.1" 200 0
+`
+
+var warmImages = `
+# images used by the consul blueprint
+consul:1.10.0
+
+quay.io/jetstack/cert-manager-controller:v1.6.0
+consul:1.10.0
+consul:1.10.0 linux/amd64
+`
+
+var warmBlueprint = `
+variable "consul_version" {
+  default = "1.10.0"
+}
+
+container "consul" {
+  image {
+    name = "consul:${var.consul_version}"
+  }
+}
+
+sidecar "envoy" {
+  target = "container.consul"
+
+  image {
+    name     = "envoyproxy/envoy:v1.18.4"
+    platform = "linux/amd64"
+  }
+}
+
+container "local" {
+  image {
+    name = "shipyard.run/localcache/app:latest"
+  }
+}
+`
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var certCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Manage the trust of the Shipyard root CA",
+	Long:  `Manage the trust of the Shipyard root CA which signs the certificates used by ingresses`,
+}
+
+func newCertTrustCmd(cc clients.Connector) *cobra.Command {
+	return &cobra.Command{
+		Use:   "trust",
+		Short: "Trust the Shipyard root CA",
+		Long: `Installs the Shipyard root CA in $HOME/.shipyard/certs into the trust stores of the
+operating system so that https ingresses open in browsers without certificate warnings.
+
+On Linux the CA is added to the system trust store using sudo, Firefox and Chrome use their
+own trust store and certutil (libnss3-tools) must be installed for the CA to be added to them.
+On macOS the CA is added to the login keychain, on Windows to the trusted roots of the user.`,
+		Example: `
+  # Trust the Shipyard root CA
+  shipyard cert trust
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the CA is created when the connector is first started
+			cb, err := cc.GetLocalCertBundle(utils.CertsDir(""))
+			if err != nil || cb == nil {
+				cb, err = cc.GenerateLocalCertBundle(utils.CertsDir(""))
+				if err != nil {
+					return fmt.Errorf("Unable to generate the Shipyard root CA: %s", err)
+				}
+			}
+
+			err = cc.TrustCA(utils.CertsDir(""))
+			if err != nil {
+				return fmt.Errorf("Unable to trust the Shipyard root CA: %s", err)
+			}
+
+			cmd.Printf("Trusted the Shipyard root CA %s\n", cb.RootCertPath)
+			cmd.Println()
+			cmd.Println("Restart any open browsers for the change to take effect")
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
+
+func newCertUntrustCmd(cc clients.Connector) *cobra.Command {
+	return &cobra.Command{
+		Use:   "untrust",
+		Short: "Remove the Shipyard root CA from the trust stores",
+		Long:  `Removes the Shipyard root CA in $HOME/.shipyard/certs from the trust stores it was installed in by 'shipyard cert trust'`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cb, err := cc.GetLocalCertBundle(utils.CertsDir(""))
+			if err != nil || cb == nil {
+				cmd.Println("The Shipyard root CA has not been created")
+				return nil
+			}
+
+			err = cc.UntrustCA(utils.CertsDir(""))
+			if err != nil {
+				return fmt.Errorf("Unable to remove the Shipyard root CA from the trust stores: %s", err)
+			}
+
+			cmd.Println("Removed the Shipyard root CA from the trust stores")
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
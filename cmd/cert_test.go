@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupCertTrust(t *testing.T) (*clients.ConnectorMock, *bytes.Buffer) {
+	cc := &clients.ConnectorMock{}
+	cc.On("GetLocalCertBundle", mock.Anything).Return(&clients.CertBundle{RootCertPath: "/certs/root.cert"}, nil)
+	cc.On("GenerateLocalCertBundle", mock.Anything).Return(&clients.CertBundle{RootCertPath: "/certs/root.cert"}, nil)
+	cc.On("TrustCA", mock.Anything).Return(nil)
+	cc.On("UntrustCA", mock.Anything).Return(nil)
+
+	return cc, bytes.NewBuffer(nil)
+}
+
+func TestCertTrustTrustsCA(t *testing.T) {
+	cc, out := setupCertTrust(t)
+
+	c := newCertTrustCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "TrustCA", utils.CertsDir(""))
+	cc.AssertNotCalled(t, "GenerateLocalCertBundle", mock.Anything)
+	assert.Contains(t, out.String(), "Trusted the Shipyard root CA /certs/root.cert")
+}
+
+func TestCertTrustGeneratesCAWhenMissing(t *testing.T) {
+	cc, out := setupCertTrust(t)
+	removeOn(&cc.Mock, "GetLocalCertBundle")
+	cc.On("GetLocalCertBundle", mock.Anything).Return(nil, fmt.Errorf("Unable to find root certificate"))
+
+	c := newCertTrustCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "GenerateLocalCertBundle", utils.CertsDir(""))
+	cc.AssertCalled(t, "TrustCA", utils.CertsDir(""))
+}
+
+func TestCertTrustReturnsErrorOnFailure(t *testing.T) {
+	cc, out := setupCertTrust(t)
+	removeOn(&cc.Mock, "TrustCA")
+	cc.On("TrustCA", mock.Anything).Return(fmt.Errorf("boom"))
+
+	c := newCertTrustCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
+
+func TestCertUntrustRemovesCA(t *testing.T) {
+	cc, out := setupCertTrust(t)
+
+	c := newCertUntrustCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "UntrustCA", utils.CertsDir(""))
+	assert.Contains(t, out.String(), "Removed the Shipyard root CA")
+}
+
+func TestCertUntrustWithNoCADoesNothing(t *testing.T) {
+	cc, out := setupCertTrust(t)
+	removeOn(&cc.Mock, "GetLocalCertBundle")
+	cc.On("GetLocalCertBundle", mock.Anything).Return(nil, fmt.Errorf("Unable to find root certificate"))
+
+	c := newCertUntrustCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertNotCalled(t, "UntrustCA", mock.Anything)
+}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// updateCheck is a versioned dependency of the running blueprint
+type updateCheck struct {
+	Resource string
+	Current  string
+	Latest   string
+	Error    error
+
+	// repository identifies the upstream source, for blueprints and modules
+	// this is the git repository which is used to show the diff
+	repository string
+	// lookup returns the versions available upstream
+	lookup func() ([]string, error)
+}
+
+func newCheckUpdatesCmd(u clients.Updates, h clients.History, b clients.System) *cobra.Command {
+	var diff bool
+
+	checkUpdatesCmd := &cobra.Command{
+		Use:   "check-updates",
+		Short: "Check for newer versions of the blueprints, modules, Helm charts, and images in use",
+		Long: `Check for newer versions of the blueprints, modules, Helm charts, and images in use.
+
+Versions are read from the state of the running blueprint, only dependencies which
+are pinned to a semantic version are checked.`,
+		Example: `
+  # Check for updates
+  shipyard check-updates
+
+  # Check for updates and open the changes for remote blueprints and modules in the browser
+  shipyard check-updates --diff
+`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := config.New()
+			err := c.FromJSON(utils.StatePath())
+			if err != nil {
+				return fmt.Errorf("No resources are running, start a stack with 'shipyard run [blueprint]'")
+			}
+
+			checks := updateChecks(c, u, h)
+			if len(checks) == 0 {
+				cmd.Println("No versioned blueprints, modules, Helm charts, or images found")
+				return nil
+			}
+
+			// cache lookups as the same image or repository can be used by many resources
+			cache := map[string][]string{}
+			updates := 0
+
+			cmd.Printf("%-40s %-20s %s\n", "RESOURCE", "CURRENT", "LATEST")
+
+			for _, uc := range checks {
+				versions, ok := cache[uc.repository]
+				if !ok {
+					versions, uc.Error = uc.lookup()
+					if uc.Error == nil {
+						cache[uc.repository] = versions
+					}
+				}
+
+				latest := "up to date"
+				switch {
+				case uc.Error != nil:
+					latest = fmt.Sprintf("unable to check: %s", uc.Error)
+				default:
+					uc.Latest = clients.LatestVersion(uc.Current, versions)
+					if uc.Latest != "" {
+						latest = uc.Latest
+						updates++
+					}
+				}
+
+				cmd.Printf("%-40s %-20s %s\n", uc.Resource, uc.Current, latest)
+
+				if diff && uc.Latest != "" {
+					if link := compareURL(uc.repository, uc.Current, uc.Latest); link != "" {
+						err := b.OpenBrowser(link)
+						if err != nil {
+							cmd.Printf("Unable to open browser for %s: %s\n", link, err)
+						}
+					}
+				}
+			}
+
+			cmd.Println("")
+			cmd.Printf("%d update(s) available\n", updates)
+
+			return nil
+		},
+	}
+
+	checkUpdatesCmd.Flags().BoolVarP(&diff, "diff", "", false, "Open the changes between the current and latest versions of remote blueprints and modules in the browser")
+
+	return checkUpdatesCmd
+}
+
+// updateChecks returns the versioned dependencies for the blueprint, module, Helm chart, and image resources
+func updateChecks(c *config.Config, u clients.Updates, h clients.History) []*updateCheck {
+	checks := []*updateCheck{}
+
+	// the source for the blueprint is not stored in the state, use the last successful run
+	if h != nil {
+		if entries, err := h.Read(); err == nil {
+			for i := len(entries) - 1; i >= 0; i-- {
+				e := entries[i]
+				if e.Command == "apply" && e.Result == clients.HistoryResultSuccess {
+					if uc := gitUpdateCheck("blueprint", e.Blueprint, u); uc != nil {
+						checks = append(checks, uc)
+					}
+
+					break
+				}
+			}
+		}
+	}
+
+	for _, r := range c.Resources {
+		name := fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)
+
+		switch v := r.(type) {
+		case *config.Module:
+			if uc := gitUpdateCheck(name, v.RemoteSource, u); uc != nil {
+				checks = append(checks, uc)
+			}
+
+		case *config.Helm:
+			if v.Repository == nil || v.Version == "" {
+				continue
+			}
+
+			repo := v.Repository.URL
+			chart := strings.TrimPrefix(v.Chart, v.Repository.Name+"/")
+
+			checks = append(checks, &updateCheck{
+				Resource:   name,
+				Current:    v.Version,
+				repository: fmt.Sprintf("%s#%s", repo, chart),
+				lookup:     func() ([]string, error) { return u.ChartVersions(repo, chart) },
+			})
+
+		case *config.Container:
+			images := []config.Image{}
+			if v.Image != nil {
+				images = append(images, *v.Image)
+			}
+
+			for _, ic := range v.InitContainers {
+				images = append(images, ic.Image)
+			}
+
+			checks = append(checks, imageUpdateChecks(name, images, u)...)
+
+		case *config.Sidecar:
+			checks = append(checks, imageUpdateChecks(name, []config.Image{v.Image}, u)...)
+
+		case *config.K8sCluster:
+			checks = append(checks, imageUpdateChecks(name, v.Images, u)...)
+
+		case *config.NomadCluster:
+			checks = append(checks, imageUpdateChecks(name, v.Images, u)...)
+		}
+	}
+
+	return checks
+}
+
+func gitUpdateCheck(name, source string, u clients.Updates) *updateCheck {
+	ref := clients.BlueprintRef(source)
+	repo := gitRepository(source)
+
+	if ref == "" || repo == "" {
+		return nil
+	}
+
+	return &updateCheck{
+		Resource:   name,
+		Current:    ref,
+		repository: repo,
+		lookup:     func() ([]string, error) { return u.GitTags(repo) },
+	}
+}
+
+func imageUpdateChecks(name string, images []config.Image, u clients.Updates) []*updateCheck {
+	checks := []*updateCheck{}
+
+	for _, i := range images {
+		named, err := reference.ParseNormalizedNamed(i.Name)
+		if err != nil {
+			continue
+		}
+
+		tagged, ok := named.(reference.Tagged)
+		if !ok {
+			continue
+		}
+
+		repo := named.Name()
+		checks = append(checks, &updateCheck{
+			Resource:   fmt.Sprintf("%s (%s)", name, reference.FamiliarName(named)),
+			Current:    tagged.Tag(),
+			repository: repo,
+			lookup:     func() ([]string, error) { return u.ImageTags(repo) },
+		})
+	}
+
+	return checks
+}
+
+// gitRepository returns the git repository for a Go Getter source, when the source is not
+// a remote git repository an empty string is returned
+// e.g. github.com/shipyard-run/blueprints//consul?ref=v0.1.0 returns https://github.com/shipyard-run/blueprints
+func gitRepository(source string) string {
+	s := strings.TrimPrefix(source, "git::")
+
+	if i := strings.Index(s, "?"); i > -1 {
+		s = s[:i]
+	}
+
+	scheme := ""
+	if i := strings.Index(s, "://"); i > -1 {
+		scheme = s[:i+3]
+		s = s[i+3:]
+	}
+
+	// remove the sub folder
+	if i := strings.Index(s, "//"); i > -1 {
+		s = s[:i]
+	}
+
+	if scheme == "" {
+		if !strings.HasPrefix(s, "github.com/") && !strings.HasPrefix(s, "gitlab.com/") && !strings.HasPrefix(s, "bitbucket.org/") {
+			return ""
+		}
+
+		scheme = "https://"
+	}
+
+	return scheme + s
+}
+
+// compareURL returns the URL showing the changes between two refs, only GitHub repositories
+// are supported, for all other repositories an empty string is returned
+func compareURL(repository, from, to string) string {
+	if !strings.HasPrefix(repository, "https://github.com/") {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/compare/%s...%s", strings.TrimSuffix(repository, ".git"), from, to)
+}
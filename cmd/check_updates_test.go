@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupCheckUpdates(t *testing.T, state string) (*cobra.Command, *mocks.MockUpdates, *mocks.System, *bytes.Buffer) {
+	mu := &mocks.MockUpdates{}
+	mu.On("GitTags", "https://github.com/shipyard-run/blueprints").Return([]string{"v0.1.0", "v0.2.0"}, nil)
+	mu.On("GitTags", "https://github.com/shipyard-run/modules").Return([]string{"v1.0.0"}, nil)
+	mu.On("ChartVersions", "https://helm.releases.hashicorp.com", "consul").Return([]string{"0.40.0", "0.41.0"}, nil)
+	mu.On("ImageTags", "docker.io/library/consul").Return([]string{"1.10.1", "1.11.0", "latest"}, nil)
+	mu.On("ImageTags", "ghcr.io/org/app").Return(nil, fmt.Errorf("boom"))
+
+	mh := &clients.HistoryMock{}
+	mh.On("Read").Return([]clients.HistoryEntry{
+		{Time: time.Now(), Command: "apply", Blueprint: "github.com/shipyard-run/blueprints//consul?ref=v0.1.0", Result: clients.HistoryResultSuccess},
+		{Time: time.Now(), Command: "apply", Blueprint: "./local", Result: clients.HistoryResultFailed},
+	}, nil)
+
+	ms := &mocks.System{}
+	ms.On("OpenBrowser", mock.Anything).Return(nil)
+
+	t.Cleanup(setupState(state))
+
+	out := bytes.NewBufferString("")
+	c := newCheckUpdatesCmd(mu, mh, ms)
+	c.SetOut(out)
+
+	return c, mu, ms, out
+}
+
+func TestCheckUpdatesWithNoStateReturnsError(t *testing.T) {
+	c, _, _, _ := setupCheckUpdates(t, "")
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
+
+func TestCheckUpdatesReportsNewerVersions(t *testing.T) {
+	c, _, ms, out := setupCheckUpdates(t, checkUpdatesState)
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	assert.Regexp(t, `blueprint\s+v0.1.0\s+v0.2.0`, out.String())
+	assert.Regexp(t, `module.consul\s+v1.0.0\s+up to date`, out.String())
+	assert.Regexp(t, `helm.consul\s+0.40.0\s+0.41.0`, out.String())
+	assert.Regexp(t, `container.consul \(consul\)\s+1.10.1\s+1.11.0`, out.String())
+	assert.Regexp(t, `container.app \(ghcr.io/org/app\)\s+v1\s+unable to check: boom`, out.String())
+	assert.Contains(t, out.String(), "4 update(s) available")
+
+	ms.AssertNotCalled(t, "OpenBrowser", mock.Anything)
+}
+
+func TestCheckUpdatesLooksUpEachSourceOnce(t *testing.T) {
+	c, mu, _, _ := setupCheckUpdates(t, checkUpdatesState)
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	mu.AssertNumberOfCalls(t, "ImageTags", 2)
+}
+
+func TestCheckUpdatesWithDiffOpensCompare(t *testing.T) {
+	c, _, ms, _ := setupCheckUpdates(t, checkUpdatesState)
+	c.Flags().Set("diff", "true")
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	ms.AssertCalled(t, "OpenBrowser", "https://github.com/shipyard-run/blueprints/compare/v0.1.0...v0.2.0")
+	ms.AssertNumberOfCalls(t, "OpenBrowser", 1)
+}
+
+func TestGitRepositoryReturnsRepository(t *testing.T) {
+	tt := map[string]string{
+		"github.com/shipyard-run/blueprints//consul?ref=v0.1.0":     "https://github.com/shipyard-run/blueprints",
+		"git::https://example.com/org/repo.git//modules?ref=v1.0.0": "https://example.com/org/repo.git",
+		"/home/nic/blueprints/consul":                               "",
+		"./consul":                                                  "",
+	}
+
+	for k, v := range tt {
+		assert.Equal(t, v, gitRepository(k), k)
+	}
+}
+
+var checkUpdatesState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "module",
+      "source": "/home/nic/.shipyard/blueprints/github.com/shipyard-run/modules/consul",
+      "remote_source": "github.com/shipyard-run/modules//consul?ref=v1.0.0"
+	},
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "helm",
+      "cluster": "k8s_cluster.k3s",
+      "chart": "hashicorp/consul",
+      "version": "0.40.0",
+      "repository": {
+        "name": "hashicorp",
+        "url": "https://helm.releases.hashicorp.com"
+      }
+	},
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "container",
+      "image": {
+        "name": "consul:1.10.1"
+      }
+	},
+	{
+      "name": "consul_2",
+      "status": "applied",
+      "type": "container",
+      "image": {
+        "name": "consul:1.10.1"
+      }
+	},
+	{
+      "name": "app",
+      "status": "applied",
+      "type": "container",
+      "image": {
+        "name": "ghcr.io/org/app:v1"
+      }
+	},
+	{
+      "name": "local",
+      "status": "applied",
+      "type": "container",
+      "image": {
+        "name": "app"
+      }
+	}
+  ]
+}
+`
@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -74,3 +80,142 @@ PowerShell:
 		}
 	},
 }
+
+// completionFunc completes the arguments or flags of a command
+type completionFunc func(cmd *cobra.Command, args []string, complete string) ([]string, cobra.ShellCompDirective)
+
+// completionSource returns the values which can be completed
+type completionSource func() ([]string, error)
+
+// completeArgs completes each argument from the source at the same position,
+// a nil source or arguments after the last source are not completed
+func completeArgs(sources ...completionSource) completionFunc {
+	return func(cmd *cobra.Command, args []string, complete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(sources) || sources[len(args)] == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		return completeFrom(sources[len(args)], nil)
+	}
+}
+
+// completeEachArg completes any number of arguments from the source, values
+// which have already been given are not completed again
+func completeEachArg(source completionSource) completionFunc {
+	return func(cmd *cobra.Command, args []string, complete string) ([]string, cobra.ShellCompDirective) {
+		return completeFrom(source, args)
+	}
+}
+
+func completeFrom(source completionSource, exclude []string) ([]string, cobra.ShellCompDirective) {
+	values, err := source()
+	if err != nil {
+		return []string{err.Error()}, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	completions := []string{}
+	for _, v := range values {
+		if !contains(exclude, v) {
+			completions = append(completions, v)
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// stateResources returns the resources in the state file with the given types,
+// all resources are returned when no types are given. Disabled resources are
+// not returned.
+func stateResources(types ...config.ResourceType) completionSource {
+	return func() ([]string, error) {
+		sc := config.New()
+		err := sc.FromJSON(utils.StatePath())
+		if err != nil {
+			return nil, fmt.Errorf("unable to load state file, check you have running resources: %s", err)
+		}
+
+		resources := []string{}
+		for _, r := range sc.Resources {
+			if r.Info().Disabled || r.Info().Status == config.Disabled {
+				continue
+			}
+
+			if len(types) > 0 && !containsType(types, r.Info().Type) {
+				continue
+			}
+
+			resources = append(resources, fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name))
+		}
+
+		return resources, nil
+	}
+}
+
+// stateResourceTypes returns the types of the resources in the state file
+func stateResourceTypes() ([]string, error) {
+	sc := config.New()
+	err := sc.FromJSON(utils.StatePath())
+	if err != nil {
+		return nil, fmt.Errorf("unable to load state file, check you have running resources: %s", err)
+	}
+
+	types := []string{}
+	for _, r := range sc.Resources {
+		if !contains(types, string(r.Info().Type)) {
+			types = append(types, string(r.Info().Type))
+		}
+	}
+
+	sort.Strings(types)
+
+	return types, nil
+}
+
+// completeBlueprints completes the blueprints in the blueprint cache, local
+// folders and files are completed when the argument is a path or no cached
+// blueprints match
+func completeBlueprints(lock *clients.SourceLock) completionFunc {
+	return func(cmd *cobra.Command, args []string, complete string) ([]string, cobra.ShellCompDirective) {
+		if strings.HasPrefix(complete, ".") || strings.HasPrefix(complete, "/") || strings.HasPrefix(complete, "~") {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		sources, err := lock.Sources()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		blueprints := []string{}
+		for _, s := range sources {
+			if strings.HasPrefix(s.Source, complete) {
+				blueprints = append(blueprints, s.Source)
+			}
+		}
+
+		if len(blueprints) == 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		return blueprints, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func containsType(types []config.ResourceType, t config.ResourceType) bool {
+	for _, i := range types {
+		if i == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(s []string, v string) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteArgsCompletesFromSourceAtPosition(t *testing.T) {
+	cleanup := setupState(baseState)
+	defer cleanup()
+
+	f := completeArgs(nil, stateResources(config.TypeK8sCluster, config.TypeNomadCluster))
+
+	r, _ := f(nil, []string{}, "")
+	assert.Empty(t, r)
+
+	r, d := f(nil, []string{"nginx:latest"}, "")
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, d)
+	assert.ElementsMatch(t, []string{"k8s_cluster.k3s", "nomad_cluster.dev"}, r)
+
+	r, _ = f(nil, []string{"nginx:latest", "k8s_cluster.k3s"}, "")
+	assert.Empty(t, r)
+}
+
+func TestCompleteEachArgDoesNotCompleteGivenArgs(t *testing.T) {
+	cleanup := setupState(baseState)
+	defer cleanup()
+
+	r, _ := completeEachArg(stateResources())(nil, []string{"network.dc1", "sidecar.envoy"}, "")
+
+	assert.ElementsMatch(t, []string{"k8s_cluster.k3s", "nomad_cluster.dev", "container.consul"}, r)
+}
+
+func TestStateResourcesReturnsErrorWithoutState(t *testing.T) {
+	cleanup := setupState("")
+	defer cleanup()
+
+	r, d := completeEachArg(stateResources())(nil, []string{}, "")
+
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, d)
+	assert.Len(t, r, 1)
+	assert.Contains(t, r[0], "unable to load state file")
+}
+
+func TestStateResourceTypesReturnsSortedTypes(t *testing.T) {
+	cleanup := setupState(baseState)
+	defer cleanup()
+
+	types, err := stateResourceTypes()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"container", "k8s_cluster", "network", "nomad_cluster", "sidecar"}, types)
+}
+
+func TestCompleteBlueprintsCompletesCachedBlueprints(t *testing.T) {
+	lock := clients.NewSourceLock(filepath.Join(t.TempDir(), "sources.lock.json"))
+	lock.Add(clients.LockedSource{Source: "github.com/shipyard-run/blueprints//vault-k8s"})
+	lock.Add(clients.LockedSource{Source: "github.com/shipyard-run/blueprints//consul-nomad"})
+
+	f := completeBlueprints(lock)
+
+	r, d := f(nil, []string{}, "github.com/shipyard-run/blueprints//v")
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, d)
+	assert.Equal(t, []string{"github.com/shipyard-run/blueprints//vault-k8s"}, r)
+
+	// local paths are completed by the shell
+	r, d = f(nil, []string{}, "./")
+	assert.Equal(t, cobra.ShellCompDirectiveDefault, d)
+	assert.Empty(t, r)
+
+	r, d = f(nil, []string{}, "my-stack")
+	assert.Equal(t, cobra.ShellCompDirectiveDefault, d)
+	assert.Empty(t, r)
+}
@@ -1,58 +1,89 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
-func newDestroyCmd(cc clients.Connector) *cobra.Command {
-	return &cobra.Command{
+func newDestroyCmd(cc clients.Connector, h clients.History, dc clients.Docker) *cobra.Command {
+	notify := &notifyFlags{}
+
+	destroyCmd := &cobra.Command{
 		Use:   "destroy [file]",
 		Short: "Destroy the current stack or file",
 		Long: `Destroy the current stack or file. 
 	If the optional parameter "file" is passed then only the resources contained
 	in the file will be destroyed`,
-		Example: `yard destroy`,
-		Run: func(cmd *cobra.Command, args []string) {
+		Example: `
+  # Destroy all the resources
+  shipyard destroy
+
+  # Destroy all the resources and post the destroy_complete event to a webhook
+  shipyard destroy --webhook https://ci.example.com/events
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			dst := ""
 			if len(args) > 0 {
 				dst = args[0]
 			}
 
+			n, err := notify.notifications()
+			if err != nil {
+				return err
+			}
+
+			engine.SetNotifications(n)
+
 			// When destroying a stack all the config
 			// which is created with apply is copied
 			// to the state folder
-			var err error
+			startTime := time.Now()
+
+			// sample the usage before the containers are removed so that the
+			// totals for the lifetime of the environment are recorded
+			usage := sampleResourceUsage(dc, hclog.Default())
+
 			if dst == "" {
 				err = engine.Destroy(dst, true)
 			} else {
 				err = engine.Destroy(dst, false)
 			}
 
+			herr := recordHistory(h, "destroy", dst, nil, "", "", startTime, usage, nil, err)
+			if herr != nil {
+				hclog.Default().Error("Unable to record history", "error", herr)
+			}
+
 			if err != nil {
-				hclog.Default().Error("Unable to destroy stack", "error", err)
-				return
+				// resources which could not be destroyed remain in the state
+				if _, serr := os.Stat(utils.StatePath()); serr == nil {
+					return newCommandError(ErrorCodePartialDestroy, "Unable to destroy stack, some resources were not destroyed: %s", err)
+				}
+
+				return fmt.Errorf("Unable to destroy stack: %s", err)
 			}
 
 			if dst == "" {
 				// clean up the data folder
 				os.RemoveAll(utils.GetDataFolder(""))
 
-				// remove the certs
-				os.RemoveAll(utils.CertsDir(""))
+				// shutdown ingress when we destroy all resources
+				shipyard.StopConnector(cc, hclog.Default())
 			}
 
-			// shutdown ingress when we destroy all resources
-			if cc.IsRunning() && dst == "" {
-				err = cc.Stop()
-				if err != nil {
-					hclog.Default().Error("Unable to stop ingress", "error", err)
-				}
-			}
+			return nil
 		},
+		SilenceUsage: true,
 	}
+
+	notify.add(destroyCmd)
+
+	return destroyCmd
 }
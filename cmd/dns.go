@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"github.com/docker/docker/client"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/server"
+	"github.com/spf13/cobra"
+)
+
+// defaultDNSAddr is the address the DNS server listens on, a high port is
+// used so that the server does not need to run as root
+const defaultDNSAddr = "127.0.0.1:5353"
+
+// dnsResolverPaths are the files which configure the resolver of the host
+// to send queries for shipyard.run to the Shipyard DNS server
+var dnsResolverPaths = map[string]string{
+	"linux":  "/etc/systemd/resolved.conf.d/shipyard.conf",
+	"darwin": "/etc/resolver/shipyard.run",
+}
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "Resolve the FQDNs of resources from the host",
+	Long: `Run a local DNS server which resolves the *.shipyard.run FQDNs of resources to the
+IP addresses of their containers, and configure the resolver of the host to use it`,
+}
+
+func newDNSServeCmd(dc clients.Docker, l hclog.Logger) *cobra.Command {
+	var addr string
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Start a DNS server which resolves the FQDNs of resources",
+		Long: `Starts a DNS server which resolves the *.shipyard.run FQDNs of resources to the IP
+addresses of their containers, queries for other domains are refused. Use 'shipyard dns setup'
+to send the queries for shipyard.run from the host to the server.`,
+		Example: `
+  # Start the DNS server
+  shipyard dns serve
+
+  # Resolve a container
+  dig @127.0.0.1 -p 5353 consul.container.shipyard.run
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d := server.NewDNS(addr, containerLookup(dc), l.Named("dns"))
+
+			err := d.Start()
+			if err != nil {
+				return newCommandError(ErrorCodeUnknown, "Unable to start DNS server: %s", err)
+			}
+
+			cmd.Printf("DNS server listening on %s\n", d.Addr())
+
+			sig := make(chan os.Signal, 1)
+			signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+			<-sig
+
+			return d.Stop()
+		},
+		SilenceUsage: true,
+	}
+
+	serveCmd.Flags().StringVarP(&addr, "addr", "", defaultDNSAddr, "Address the DNS server listens on")
+
+	return serveCmd
+}
+
+func newDNSSetupCmd() *cobra.Command {
+	var addr string
+	var remove bool
+	var print bool
+
+	setupCmd := &cobra.Command{
+		Use:   "setup",
+		Short: "Configure the host to resolve shipyard.run using the Shipyard DNS server",
+		Long: `Configures the resolver of the host to send queries for shipyard.run to the DNS
+server started by 'shipyard dns serve', queries for other domains are unchanged.
+
+On Linux a drop-in file is written for systemd-resolved, on macOS a resolver file is written
+to /etc/resolver. Writing the files requires root, use --print to show the configuration
+without writing it.`,
+		Example: `
+  # Configure the resolver
+  sudo shipyard dns setup
+
+  # Remove the configuration
+  sudo shipyard dns setup --remove
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, ok := dnsResolverPaths[runtime.GOOS]
+			if !ok {
+				return newCommandError(ErrorCodeUsage, "Configuring the resolver is not supported on %s, configure the resolver to send queries for %s to %s", runtime.GOOS, server.DNSDomain, addr)
+			}
+
+			conf, err := dnsResolverConfig(runtime.GOOS, addr)
+			if err != nil {
+				return newCommandError(ErrorCodeUsage, "Invalid address %s: %s", addr, err)
+			}
+
+			if print {
+				cmd.Printf("# %s\n%s", path, conf)
+				return nil
+			}
+
+			if remove {
+				err := os.Remove(path)
+				if err != nil && !os.IsNotExist(err) {
+					return newCommandError(ErrorCodeUnknown, "Unable to remove %s: %s", path, err)
+				}
+
+				cmd.Printf("Removed the resolver configuration %s\n", path)
+				printDNSRestart(cmd)
+
+				return nil
+			}
+
+			err = os.MkdirAll(filepath.Dir(path), 0755)
+			if err == nil {
+				err = ioutil.WriteFile(path, []byte(conf), 0644)
+			}
+
+			if err != nil {
+				if os.IsPermission(err) {
+					return newCommandError(ErrorCodeUnknown, "Unable to write %s, run the command with sudo", path)
+				}
+
+				return newCommandError(ErrorCodeUnknown, "Unable to write %s: %s", path, err)
+			}
+
+			cmd.Printf("Wrote the resolver configuration %s\n", path)
+			printDNSRestart(cmd)
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	setupCmd.Flags().StringVarP(&addr, "addr", "", defaultDNSAddr, "Address of the Shipyard DNS server")
+	setupCmd.Flags().BoolVarP(&remove, "remove", "", false, "Remove the resolver configuration")
+	setupCmd.Flags().BoolVarP(&print, "print", "", false, "Print the resolver configuration without writing it")
+
+	return setupCmd
+}
+
+// dnsResolverConfig returns the resolver configuration for the operating system
+func dnsResolverConfig(goos, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+
+	if goos == "darwin" {
+		return fmt.Sprintf("nameserver %s\nport %s\n", host, port), nil
+	}
+
+	return fmt.Sprintf("[Resolve]\nDNS=%s\nDomains=~%s\n", addr, server.DNSDomain), nil
+}
+
+func printDNSRestart(cmd *cobra.Command) {
+	if runtime.GOOS == "linux" {
+		cmd.Println("Restart systemd-resolved for the change to take effect: sudo systemctl restart systemd-resolved")
+	}
+}
+
+// containerLookup returns a DNSLookup which resolves the FQDN to the IP addresses of
+// the container with the same name on all the networks the container is attached to
+func containerLookup(dc clients.Docker) server.DNSLookup {
+	return func(fqdn string) ([]net.IP, error) {
+		info, err := dc.ContainerInspect(context.Background(), fqdn)
+		if err != nil {
+			if client.IsErrNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		ips := []net.IP{}
+		if info.NetworkSettings == nil {
+			return ips, nil
+		}
+
+		for _, n := range info.NetworkSettings.Networks {
+			if ip := net.ParseIP(n.IPAddress); ip != nil {
+				ips = append(ips, ip)
+			}
+
+			if ip := net.ParseIP(n.GlobalIPv6Address); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+
+		return ips, nil
+	}
+}
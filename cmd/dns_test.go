@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupDNSSetup(t *testing.T) (string, *bytes.Buffer) {
+	if _, ok := dnsResolverPaths[runtime.GOOS]; !ok {
+		t.Skip("configuring the resolver is not supported on " + runtime.GOOS)
+	}
+
+	path := filepath.Join(t.TempDir(), "resolver", "shipyard.conf")
+
+	old := dnsResolverPaths[runtime.GOOS]
+	dnsResolverPaths[runtime.GOOS] = path
+	t.Cleanup(func() {
+		dnsResolverPaths[runtime.GOOS] = old
+	})
+
+	return path, bytes.NewBuffer(nil)
+}
+
+func TestDNSSetupWritesResolverConfig(t *testing.T) {
+	path, out := setupDNSSetup(t)
+
+	c := newDNSSetupCmd()
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	conf, _ := dnsResolverConfig(runtime.GOOS, defaultDNSAddr)
+	assert.Equal(t, conf, string(d))
+	assert.Contains(t, out.String(), "Wrote the resolver configuration")
+}
+
+func TestDNSSetupPrintDoesNotWriteConfig(t *testing.T) {
+	path, out := setupDNSSetup(t)
+
+	c := newDNSSetupCmd()
+	c.SetOut(out)
+	c.SetArgs([]string{"--print"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	assert.NoFileExists(t, path)
+	assert.Contains(t, out.String(), path)
+}
+
+func TestDNSSetupRemoveRemovesConfig(t *testing.T) {
+	path, out := setupDNSSetup(t)
+
+	c := newDNSSetupCmd()
+	c.SetOut(out)
+	c.SetArgs([]string{})
+	assert.NoError(t, c.Execute())
+
+	c = newDNSSetupCmd()
+	c.SetOut(out)
+	c.SetArgs([]string{"--remove"})
+	assert.NoError(t, c.Execute())
+
+	assert.NoFileExists(t, path)
+}
+
+func TestDNSSetupWithInvalidAddrReturnsError(t *testing.T) {
+	_, out := setupDNSSetup(t)
+
+	c := newDNSSetupCmd()
+	c.SetOut(out)
+	c.SetArgs([]string{"--addr", "localhost"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeUsage, err.(*CommandError).Code)
+}
+
+func TestDNSResolverConfig(t *testing.T) {
+	conf, err := dnsResolverConfig("linux", "127.0.0.1:5353")
+	assert.NoError(t, err)
+	assert.Equal(t, "[Resolve]\nDNS=127.0.0.1:5353\nDomains=~shipyard.run\n", conf)
+
+	conf, err = dnsResolverConfig("darwin", "127.0.0.1:5353")
+	assert.NoError(t, err)
+	assert.Equal(t, "nameserver 127.0.0.1\nport 5353\n", conf)
+}
+
+func TestContainerLookupReturnsContainerIPs(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("ContainerInspect", mock.Anything, "consul.container.shipyard.run").Return(
+		types.ContainerJSON{
+			NetworkSettings: &types.NetworkSettings{
+				Networks: map[string]*network.EndpointSettings{
+					"cloud": &network.EndpointSettings{IPAddress: "10.5.0.2", GlobalIPv6Address: "fd00::2"},
+				},
+			},
+		}, nil)
+	md.On("ContainerInspect", mock.Anything, "vault.container.shipyard.run").Return(types.ContainerJSON{}, errdefs.NotFound(fmt.Errorf("not found")))
+
+	l := containerLookup(md)
+
+	ips, err := l("consul.container.shipyard.run")
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("10.5.0.2"), net.ParseIP("fd00::2")}, ips)
+
+	ips, err = l("vault.container.shipyard.run")
+	assert.NoError(t, err)
+	assert.Empty(t, ips)
+}
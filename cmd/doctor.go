@@ -0,0 +1,510 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	gversion "github.com/hashicorp/go-version"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/server"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+type doctorStatus string
+
+const (
+	doctorOK      doctorStatus = "ok"
+	doctorWarning doctorStatus = "warning"
+	doctorError   doctorStatus = "error"
+)
+
+// minimum versions of the container engines which are tested with Shipyard
+const (
+	doctorMinDockerVersion = "20.10.0"
+	doctorMinPodmanVersion = "3.0.0"
+)
+
+// minimum resources recommended to run clusters
+const (
+	doctorMinMemory = 4 * 1024 * 1024 * 1024
+	doctorMinCPUs   = 2
+	doctorMinDisk   = 10 * 1024 * 1024 * 1024
+)
+
+// inotify limits required by k3s, the default limits are too low to run
+// a cluster with more than a few pods
+const (
+	doctorMinInotifyInstances = 512
+	doctorMinInotifyWatches   = 524288
+)
+
+// doctorDNSName is resolved to check that the wildcard DNS for shipyard.run
+// is not blocked by DNS rebind protection
+const doctorDNSName = "doctor.container.shipyard.run"
+
+// doctorResult is the result of a single check, Fix describes how
+// to fix warnings and errors
+type doctorResult struct {
+	Check   string       `json:"check"`
+	Status  doctorStatus `json:"status"`
+	Message string       `json:"message"`
+	Fix     string       `json:"fix,omitempty"`
+}
+
+// doctor checks that the machine is able to run blueprints, the functions
+// used to access the system can be replaced in tests
+type doctor struct {
+	dc clients.Docker
+	cc clients.Connector
+
+	lookupHost func(host string) ([]string, error)
+	freeDisk   func(path string) (uint64, error)
+	inotifyDir string
+}
+
+func newDoctorCmd(dc clients.Docker, cc clients.Connector) *cobra.Command {
+	var jsonFlag bool
+
+	doctorCmd := &cobra.Command{
+		Use:   "doctor [blueprint]",
+		Short: "Check the system is able to run blueprints and print fixes for problems",
+		Long: `Check the system is able to run blueprints and print fixes for any problems found.
+
+Doctor checks the container engine is reachable and supported, the memory, CPU,
+and disk available, cgroup v2 and inotify limits for Kubernetes clusters, DNS
+resolution of *.shipyard.run, and the certificates for the local connector.
+
+When a blueprint is given the host ports used by the blueprint are checked to
+ensure they are not used by another process.`,
+		Example: `
+  # Check the system
+  shipyard doctor
+
+  # Check the system and the ports used by a blueprint
+  shipyard doctor ./my-stack
+	`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d := &doctor{
+				dc:         dc,
+				cc:         cc,
+				lookupHost: net.LookupHost,
+				freeDisk:   freeDiskSpace,
+				inotifyDir: "/proc/sys/fs/inotify",
+			}
+
+			results := d.run()
+
+			if len(args) == 1 {
+				r, err := d.checkPorts(args[0])
+				if err != nil {
+					return newCommandError(ErrorCodeConfig, "Unable to read blueprint: %s", err)
+				}
+
+				results = append(results, r...)
+			}
+
+			if jsonFlag {
+				err := json.NewEncoder(cmd.OutOrStdout()).Encode(results)
+				if err != nil {
+					return err
+				}
+			} else {
+				printDoctorResults(cmd.OutOrStdout(), results)
+			}
+
+			return doctorFailed(results)
+		},
+		SilenceUsage: true,
+	}
+
+	doctorCmd.Flags().BoolVarP(&jsonFlag, "json", "", false, "Output the results as JSON")
+
+	return doctorCmd
+}
+
+// run runs the system checks, the checks which need the container
+// engine are skipped when the engine can not be reached
+func (d *doctor) run() []doctorResult {
+	results := []doctorResult{}
+
+	engine := d.checkEngine()
+	results = append(results, engine)
+
+	if engine.Status != doctorError {
+		results = append(results, d.checkEngineResources()...)
+	}
+
+	results = append(results, d.checkDisk())
+	results = append(results, d.checkInotify()...)
+	results = append(results, d.checkDNS())
+	results = append(results, d.checkCertificates())
+
+	return results
+}
+
+// checkEngine checks that Docker or Podman can be reached and is a supported version
+func (d *doctor) checkEngine() doctorResult {
+	r := doctorResult{Check: "Container engine"}
+
+	if d.dc == nil {
+		r.Status = doctorError
+		r.Message = "Unable to create a client for the container engine"
+		r.Fix = "Install Docker or Podman, when the engine is not running locally set DOCKER_HOST to the address of the engine"
+
+		return r
+	}
+
+	ver, err := d.dc.ServerVersion(context.Background())
+	if err != nil {
+		r.Status = doctorError
+		r.Message = fmt.Sprintf("Unable to connect to the container engine: %s", err)
+		r.Fix = "Start Docker or Podman, when the engine is not running locally set DOCKER_HOST to the address of the engine"
+
+		return r
+	}
+
+	name, min := "Docker", doctorMinDockerVersion
+	for _, c := range ver.Components {
+		if c.Name == clients.EngineTypePodman {
+			name, min = "Podman", doctorMinPodmanVersion
+		}
+	}
+
+	r.Status = doctorOK
+	r.Message = fmt.Sprintf("%s %s", name, ver.Version)
+
+	current, err := gversion.NewVersion(ver.Version)
+	if err != nil {
+		return r
+	}
+
+	if current.LessThan(gversion.Must(gversion.NewVersion(min))) {
+		r.Status = doctorWarning
+		r.Message = fmt.Sprintf("%s %s is older than the minimum supported version %s", name, ver.Version, min)
+		r.Fix = fmt.Sprintf("Upgrade %s to version %s or later", name, min)
+	}
+
+	return r
+}
+
+// checkEngineResources checks the memory and CPUs available to the engine and that
+// the engine can run privileged containers for clusters
+func (d *doctor) checkEngineResources() []doctorResult {
+	info, err := d.dc.Info(context.Background())
+	if err != nil {
+		return []doctorResult{{
+			Check:   "Engine resources",
+			Status:  doctorWarning,
+			Message: fmt.Sprintf("Unable to read the engine info: %s", err),
+		}}
+	}
+
+	results := []doctorResult{}
+
+	mem := doctorResult{Check: "Memory", Status: doctorOK, Message: fmt.Sprintf("%s available to containers", formatBytes(info.MemTotal))}
+	if info.MemTotal < doctorMinMemory {
+		mem.Status = doctorWarning
+		mem.Fix = fmt.Sprintf("Clusters need at least %s of memory, increase the memory available to the container engine", formatBytes(doctorMinMemory))
+	}
+
+	results = append(results, mem)
+
+	cpu := doctorResult{Check: "CPUs", Status: doctorOK, Message: fmt.Sprintf("%d CPUs available to containers", info.NCPU)}
+	if info.NCPU < doctorMinCPUs {
+		cpu.Status = doctorWarning
+		cpu.Fix = fmt.Sprintf("Clusters need at least %d CPUs, increase the CPUs available to the container engine", doctorMinCPUs)
+	}
+
+	results = append(results, cpu)
+
+	results = append(results, checkCgroups(info))
+
+	return results
+}
+
+// checkCgroups checks that privileged containers for clusters can be run
+func checkCgroups(info types.Info) doctorResult {
+	r := doctorResult{Check: "cgroups", Status: doctorOK, Message: fmt.Sprintf("cgroup v%s", info.CgroupVersion)}
+	if info.CgroupVersion == "" {
+		r.Message = "cgroup version unknown"
+	}
+
+	rootless := false
+	for _, so := range info.SecurityOptions {
+		if strings.Contains(so, "name=rootless") {
+			rootless = true
+		}
+	}
+
+	if rootless && info.CgroupVersion != "2" {
+		r.Status = doctorError
+		r.Message = "The rootless engine is using cgroup v1, Kubernetes and Nomad clusters can not be created"
+		r.Fix = "Enable cgroup v2 by adding systemd.unified_cgroup_hierarchy=1 to the kernel command line, or run the engine as root"
+	}
+
+	return r
+}
+
+// checkDisk checks the free disk space for the Shipyard home folder
+func (d *doctor) checkDisk() doctorResult {
+	r := doctorResult{Check: "Disk space"}
+
+	free, err := d.freeDisk(utils.ShipyardHome())
+	if err != nil {
+		// the home folder is created by the first run
+		free, err = d.freeDisk(filepath.Dir(utils.ShipyardHome()))
+	}
+
+	if err != nil {
+		r.Status = doctorWarning
+		r.Message = fmt.Sprintf("Unable to determine the free disk space: %s", err)
+
+		return r
+	}
+
+	r.Status = doctorOK
+	r.Message = fmt.Sprintf("%s free", formatBytes(int64(free)))
+
+	if free < doctorMinDisk {
+		r.Status = doctorWarning
+		r.Fix = fmt.Sprintf("Images and clusters need at least %s of disk space, free up space or remove unused images with 'shipyard purge'", formatBytes(doctorMinDisk))
+	}
+
+	return r
+}
+
+// checkInotify checks the inotify limits are high enough for k3s, the limits are
+// only checked on Linux where the containers share the kernel of the host
+func (d *doctor) checkInotify() []doctorResult {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+
+	limits := []struct {
+		name string
+		min  int
+	}{
+		{"max_user_instances", doctorMinInotifyInstances},
+		{"max_user_watches", doctorMinInotifyWatches},
+	}
+
+	results := []doctorResult{}
+	for _, l := range limits {
+		r := doctorResult{Check: "inotify " + l.name}
+
+		v, err := readIntFile(filepath.Join(d.inotifyDir, l.name))
+		if err != nil {
+			r.Status = doctorWarning
+			r.Message = fmt.Sprintf("Unable to read the limit: %s", err)
+			results = append(results, r)
+
+			continue
+		}
+
+		r.Status = doctorOK
+		r.Message = strconv.Itoa(v)
+
+		if v < l.min {
+			r.Status = doctorWarning
+			r.Message = fmt.Sprintf("%d is lower than the %d needed by Kubernetes clusters", v, l.min)
+			r.Fix = fmt.Sprintf("Run 'sudo sysctl -w fs.inotify.%s=%d' and add 'fs.inotify.%s=%d' to /etc/sysctl.conf", l.name, l.min, l.name, l.min)
+		}
+
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// checkDNS checks that *.shipyard.run resolves to the local machine
+func (d *doctor) checkDNS() doctorResult {
+	r := doctorResult{Check: "DNS"}
+
+	addrs, err := d.lookupHost(doctorDNSName)
+	if err != nil || len(addrs) == 0 {
+		r.Status = doctorError
+		r.Message = "Unable to resolve *.shipyard.run"
+		r.Fix = "Routers with DNS rebind protection block names which resolve to 127.0.0.1, add shipyard.run to the allowed domains or use a public DNS server such as 1.1.1.1"
+
+		return r
+	}
+
+	for _, a := range addrs {
+		if ip := net.ParseIP(a); ip != nil && ip.IsLoopback() {
+			r.Status = doctorOK
+			r.Message = fmt.Sprintf("*.shipyard.run resolves to %s", a)
+
+			return r
+		}
+	}
+
+	r.Status = doctorError
+	r.Message = fmt.Sprintf("*.shipyard.run resolves to %s, not the local machine", strings.Join(addrs, ", "))
+	r.Fix = "Check the DNS server and the hosts file are not overriding shipyard.run"
+
+	return r
+}
+
+// checkCertificates checks the certificates for the local connector have not expired
+func (d *doctor) checkCertificates() doctorResult {
+	r := doctorResult{Check: "Connector certificates"}
+
+	cb, err := d.cc.GetLocalCertBundle(utils.CertsDir(""))
+	if err != nil || cb == nil {
+		r.Status = doctorOK
+		r.Message = "Not created, the certificates are generated by 'shipyard run'"
+
+		return r
+	}
+
+	for _, c := range []struct {
+		name string
+		path string
+		fix  string
+	}{
+		{"root CA", cb.RootCertPath, "Run 'shipyard connector rotate-certs --ca' and recreate any clusters"},
+		{"leaf certificate", cb.LeafCertPath, "Run 'shipyard connector rotate-certs'"},
+	} {
+		exp, err := clients.CertExpiry(c.path)
+		if err != nil {
+			r.Status = doctorError
+			r.Message = fmt.Sprintf("Unable to read the %s: %s", c.name, err)
+			r.Fix = c.fix
+
+			return r
+		}
+
+		if time.Now().After(exp) {
+			r.Status = doctorError
+			r.Message = fmt.Sprintf("The %s expired at %s", c.name, exp.Local().Format(time.RFC1123))
+			r.Fix = c.fix
+
+			return r
+		}
+
+		if time.Until(exp) < server.RotateBefore {
+			r.Status = doctorWarning
+			r.Message = fmt.Sprintf("The %s expires at %s", c.name, exp.Local().Format(time.RFC1123))
+			r.Fix = c.fix
+
+			return r
+		}
+	}
+
+	r.Status = doctorOK
+	r.Message = "Valid"
+
+	return r
+}
+
+// checkPorts checks that the host ports used by the blueprint are not used by other
+// processes, ports for resources which have already been created are not checked
+func (d *doctor) checkPorts(path string) ([]doctorResult, error) {
+	c, err := parseBlueprint(path, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	sc := config.New()
+	sc.FromJSON(utils.StatePath())
+
+	results := []doctorResult{}
+	for _, r := range c.Resources {
+		if r.Info().Status == config.Disabled {
+			continue
+		}
+
+		if s, err := sc.FindResource(fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)); err == nil && s.Info().Status == config.Applied {
+			continue
+		}
+
+		for _, p := range config.HostPorts(r) {
+			// free ports are assigned to ports set to 0 when the blueprint is applied
+			if p.Host == "0" {
+				continue
+			}
+
+			res := doctorResult{Check: fmt.Sprintf("Port %s for %s.%s", p.Host, r.Info().Type, r.Info().Name), Status: doctorOK, Message: "Available"}
+
+			if err := utils.CheckPortAvailable(p.Protocol, p.BindAddress(), p.Host); err != nil {
+				res.Status = doctorError
+				res.Message = fmt.Sprintf("Port %s is already in use", p.Host)
+
+				pn, _ := strconv.Atoi(p.Host)
+				if owner := utils.PortOwner(p.Protocol, pn); owner != "" {
+					res.Message = fmt.Sprintf("Port %s is already in use by %s", p.Host, owner)
+				}
+
+				res.Fix = fmt.Sprintf("Stop the process using port %s or change the host port in the blueprint, set the host port to 0 to use a free port", p.Host)
+			}
+
+			results = append(results, res)
+		}
+	}
+
+	return results, nil
+}
+
+func printDoctorResults(w io.Writer, results []doctorResult) {
+	fmt.Fprintln(w)
+
+	for _, r := range results {
+		status := fmt.Sprintf(Green, "  OK   ")
+		switch r.Status {
+		case doctorWarning:
+			status = fmt.Sprintf(Yellow, "WARNING")
+		case doctorError:
+			status = fmt.Sprintf(Red, " ERROR ")
+		}
+
+		fmt.Fprintf(w, " [ %s ] %s: %s\n", status, r.Check, r.Message)
+		if r.Fix != "" {
+			fmt.Fprintf(w, "             %s\n", r.Fix)
+		}
+	}
+
+	fmt.Fprintln(w)
+}
+
+// doctorFailed returns an error when any of the checks failed
+func doctorFailed(results []doctorResult) error {
+	failed := 0
+	for _, r := range results {
+		if r.Status == doctorError {
+			failed++
+		}
+	}
+
+	if failed == 0 {
+		return nil
+	}
+
+	// the engine is needed by every blueprint
+	if results[0].Status == doctorError {
+		return newCommandError(ErrorCodeDockerUnavailable, "%d checks failed", failed)
+	}
+
+	return fmt.Errorf("%d checks failed", failed)
+}
+
+func readIntFile(path string) (int, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(d)))
+}
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/shipyard-run/connector/crypto"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupDoctor(t *testing.T) (*doctor, *mocks.MockDocker) {
+	setupWatchState(t)
+
+	md := &mocks.MockDocker{}
+	md.On("ServerVersion", mock.Anything).Return(types.Version{
+		Version:    "20.10.12",
+		Components: []types.ComponentVersion{{Name: clients.EngineTypeDocker}},
+	}, nil)
+	md.On("Info", mock.Anything).Return(types.Info{MemTotal: 8 * 1024 * 1024 * 1024, NCPU: 4, CgroupVersion: "2"}, nil)
+
+	cc := &clients.ConnectorMock{}
+	cc.On("GetLocalCertBundle", mock.Anything).Return(nil, fmt.Errorf("not found"))
+
+	inotify := t.TempDir()
+	writeDoctorFile(t, filepath.Join(inotify, "max_user_instances"), 1024)
+	writeDoctorFile(t, filepath.Join(inotify, "max_user_watches"), 1048576)
+
+	d := &doctor{
+		dc: md,
+		cc: cc,
+		lookupHost: func(host string) ([]string, error) {
+			return []string{"127.0.0.1"}, nil
+		},
+		freeDisk: func(path string) (uint64, error) {
+			return 100 * 1024 * 1024 * 1024, nil
+		},
+		inotifyDir: inotify,
+	}
+
+	return d, md
+}
+
+func writeDoctorFile(t *testing.T, path string, v int) {
+	err := ioutil.WriteFile(path, []byte(strconv.Itoa(v)+"\n"), os.ModePerm)
+	assert.NoError(t, err)
+}
+
+func findDoctorResult(t *testing.T, results []doctorResult, check string) doctorResult {
+	for _, r := range results {
+		if r.Check == check {
+			return r
+		}
+	}
+
+	t.Fatalf("Check %s not found", check)
+	return doctorResult{}
+}
+
+func TestDoctorReturnsNoErrorsForHealthySystem(t *testing.T) {
+	d, _ := setupDoctor(t)
+
+	results := d.run()
+	for _, r := range results {
+		assert.Equal(t, doctorOK, r.Status, r.Check)
+	}
+
+	assert.NoError(t, doctorFailed(results))
+	assert.Equal(t, "Docker 20.10.12", findDoctorResult(t, results, "Container engine").Message)
+}
+
+func TestDoctorReturnsErrorWhenEngineUnavailable(t *testing.T) {
+	d, md := setupDoctor(t)
+	removeOn(&md.Mock, "ServerVersion")
+	md.On("ServerVersion", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	results := d.run()
+
+	r := findDoctorResult(t, results, "Container engine")
+	assert.Equal(t, doctorError, r.Status)
+	assert.NotEmpty(t, r.Fix)
+
+	md.AssertNotCalled(t, "Info", mock.Anything)
+
+	err := doctorFailed(results)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeDockerUnavailable, ErrorCodeFor(err))
+}
+
+func TestDoctorWarnsForOldEngine(t *testing.T) {
+	d, md := setupDoctor(t)
+	removeOn(&md.Mock, "ServerVersion")
+	md.On("ServerVersion", mock.Anything).Return(types.Version{
+		Version:    "2.2.1",
+		Components: []types.ComponentVersion{{Name: clients.EngineTypePodman}},
+	}, nil)
+
+	r := d.checkEngine()
+	assert.Equal(t, doctorWarning, r.Status)
+	assert.Contains(t, r.Message, "Podman 2.2.1")
+}
+
+func TestDoctorWarnsForLowMemory(t *testing.T) {
+	d, md := setupDoctor(t)
+	removeOn(&md.Mock, "Info")
+	md.On("Info", mock.Anything).Return(types.Info{MemTotal: 2 * 1024 * 1024 * 1024, NCPU: 4, CgroupVersion: "2"}, nil)
+
+	r := findDoctorResult(t, d.checkEngineResources(), "Memory")
+	assert.Equal(t, doctorWarning, r.Status)
+}
+
+func TestDoctorReturnsErrorForRootlessCgroupV1(t *testing.T) {
+	r := checkCgroups(types.Info{CgroupVersion: "1", SecurityOptions: []string{"name=rootless"}})
+	assert.Equal(t, doctorError, r.Status)
+
+	r = checkCgroups(types.Info{CgroupVersion: "1"})
+	assert.Equal(t, doctorOK, r.Status)
+}
+
+func TestDoctorWarnsForLowDiskSpace(t *testing.T) {
+	d, _ := setupDoctor(t)
+	d.freeDisk = func(path string) (uint64, error) {
+		return 1024 * 1024 * 1024, nil
+	}
+
+	r := d.checkDisk()
+	assert.Equal(t, doctorWarning, r.Status)
+}
+
+func TestDoctorWarnsForLowInotifyLimits(t *testing.T) {
+	d, _ := setupDoctor(t)
+	writeDoctorFile(t, filepath.Join(d.inotifyDir, "max_user_instances"), 128)
+
+	results := d.checkInotify()
+	if len(results) == 0 {
+		t.Skip("inotify limits are only checked on Linux")
+	}
+
+	r := findDoctorResult(t, results, "inotify max_user_instances")
+	assert.Equal(t, doctorWarning, r.Status)
+	assert.Contains(t, r.Fix, "fs.inotify.max_user_instances=512")
+}
+
+func TestDoctorReturnsErrorWhenDNSFails(t *testing.T) {
+	d, _ := setupDoctor(t)
+	d.lookupHost = func(host string) ([]string, error) {
+		return nil, fmt.Errorf("no such host")
+	}
+
+	r := d.checkDNS()
+	assert.Equal(t, doctorError, r.Status)
+	assert.NotEmpty(t, r.Fix)
+}
+
+func TestDoctorReturnsErrorWhenDNSNotLocal(t *testing.T) {
+	d, _ := setupDoctor(t)
+	d.lookupHost = func(host string) ([]string, error) {
+		return []string{"10.0.0.1"}, nil
+	}
+
+	r := d.checkDNS()
+	assert.Equal(t, doctorError, r.Status)
+}
+
+func TestDoctorReturnsErrorWhenCertificateExpired(t *testing.T) {
+	d, _ := setupDoctor(t)
+
+	// a certificate file which can not be read is reported
+	dir := t.TempDir()
+	cc := &clients.ConnectorMock{}
+	cc.On("GetLocalCertBundle", mock.Anything).Return(&clients.CertBundle{
+		RootCertPath: filepath.Join(dir, "root.cert"),
+		LeafCertPath: filepath.Join(dir, "leaf.cert"),
+	}, nil)
+	d.cc = cc
+
+	r := d.checkCertificates()
+	assert.Equal(t, doctorError, r.Status)
+	assert.Contains(t, r.Fix, "rotate-certs")
+}
+
+func TestDoctorChecksCertificates(t *testing.T) {
+	d, _ := setupDoctor(t)
+
+	k, err := crypto.GenerateKeyPair()
+	assert.NoError(t, err)
+
+	ca, err := crypto.GenerateCA(k.Private)
+	assert.NoError(t, err)
+
+	cert := filepath.Join(t.TempDir(), "root.cert")
+	assert.NoError(t, ca.WriteFile(cert))
+
+	cc := &clients.ConnectorMock{}
+	cc.On("GetLocalCertBundle", mock.Anything).Return(&clients.CertBundle{RootCertPath: cert, LeafCertPath: cert}, nil)
+	d.cc = cc
+
+	r := d.checkCertificates()
+	assert.Equal(t, doctorOK, r.Status)
+}
+
+func TestDoctorReturnsErrorWhenPortInUse(t *testing.T) {
+	d, _ := setupDoctor(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	port := l.Addr().(*net.TCPAddr).Port
+
+	dir := config.CreateTestFiles(t, fmt.Sprintf(doctorBlueprint, port))
+
+	results, err := d.checkPorts(dir)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	assert.Equal(t, doctorError, results[0].Status)
+	assert.Contains(t, results[0].Check, "container.web")
+}
+
+func TestDoctorReturnsOKWhenPortAvailable(t *testing.T) {
+	d, _ := setupDoctor(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	dir := config.CreateTestFiles(t, fmt.Sprintf(doctorBlueprint, port))
+
+	results, err := d.checkPorts(dir)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, doctorOK, results[0].Status)
+}
+
+const doctorBlueprint = `
+container "web" {
+  image {
+    name = "nginx:latest"
+  }
+
+  port {
+    local  = 80
+    remote = 80
+    host   = %d
+    bind   = "127.0.0.1"
+  }
+}
+`
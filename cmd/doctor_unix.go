@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import "golang.org/x/sys/unix"
+
+// freeDiskSpace returns the bytes available to the user on the disk containing path
+func freeDiskSpace(path string) (uint64, error) {
+	st := unix.Statfs_t{}
+	err := unix.Statfs(path, &st)
+	if err != nil {
+		return 0, err
+	}
+
+	return st.Bavail * uint64(st.Bsize), nil
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import "golang.org/x/sys/windows"
+
+// freeDiskSpace returns the bytes available to the user on the disk containing path
+func freeDiskSpace(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var free, total, totalFree uint64
+	err = windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree)
+	if err != nil {
+		return 0, err
+	}
+
+	return free, nil
+}
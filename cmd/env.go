@@ -12,40 +12,69 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// envVar is an environment variable printed by the env command
+type envVar struct {
+	Key   string
+	Value string
+}
+
+// wellKnownPorts maps the default ports for tools to the environment
+// variable used by the tool to find the server
+var wellKnownPorts = map[string]string{
+	"8200": "VAULT_ADDR",
+	"8500": "CONSUL_HTTP_ADDR",
+	"4646": "NOMAD_ADDR",
+}
+
 func newEnvCmd(e shipyard.Engine) *cobra.Command {
 	var unset bool
 
 	envCmd := &cobra.Command{
-		Use:   "env",
+		Use:   "env [resource]",
 		Short: "Prints environment variables defined by the blueprint",
-		Long:  "Prints environment variables defined by the blueprint",
+		Long: `Prints environment variables defined by the blueprint and the configuration
+to connect tools to the resources in the environment.
+
+KUBECONFIG is set for Kubernetes clusters, NOMAD_ADDR for Nomad clusters, and
+VAULT_ADDR, CONSUL_HTTP_ADDR, or NOMAD_ADDR for containers and ingresses which
+expose the default port for the tool on the host. Networks set SHIPYARD_NETWORK
+and SHIPYARD_NETWORK_SUBNET.
+
+When a resource is given only the variables for that resource are printed.`,
 		Example: `
   # Display environment variables
   shipyard env
-  
+
   VAR1=value
   VAR2=value
-  
+
   # Set environment variables on Linux based systems
   eval $(shipyard env)
-    
+
   # Set environment variables on Windows based systems
   Invoke-Expression "shipyard env" | ForEach-Object { Invoke-Expression $_ }
 
+  # Configure kubectl for a single cluster
+  eval $(shipyard env k8s_cluster.k3s)
+
   # Unset environment variables on Linux based systems
   eval $(shipyard env --unset)
 
   # Unset environment variables on Windows based systems
   Invoke-Expression "shipyard env --unset" | ForEach-Object { Remove-Item $_ }
 `,
-		Args: cobra.ArbitraryArgs,
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: getTaintResources,
 		RunE: func(cmd *cobra.Command, args []string) error {
-
 			c := config.New()
 			err := c.FromJSON(utils.StatePath())
 			if err != nil {
-				fmt.Println("Unable to load state", err)
-				os.Exit(1)
+				return newCommandError(ErrorCodeState, "Unable to load state, is an environment running? %s", err)
+			}
+
+			vars, err := environmentVariables(c, args)
+			if err != nil {
+				return err
 			}
 
 			prefix := "export "
@@ -59,32 +88,18 @@ func newEnvCmd(e shipyard.Engine) *cobra.Command {
 				}
 			}
 
-			if c.Blueprint != nil && len(c.Blueprint.Environment) > 0 {
-				for _, env := range c.Blueprint.Environment {
-					env.Value = strings.ReplaceAll(env.Value, `\`, `\\`)
-					if unset {
-						fmt.Printf("%s%s\n", prefix, env.Key)
-					} else {
-						fmt.Printf("%s%s=\"%s\"\n", prefix, env.Key, env.Value)
-					}
+			out := cmd.OutOrStdout()
+			for _, v := range vars {
+				if unset {
+					fmt.Fprintf(out, "%s%s\n", prefix, v.Key)
+					continue
 				}
-			}
 
-			// add output variables
-			for _, r := range c.Resources {
-				if r.Info().Type == config.TypeOutput {
-					if r.Info().Disabled {
-						continue
-					}
-
-					val := strings.ReplaceAll(r.(*config.Output).Value, `\`, `\\`)
-					if unset {
-						fmt.Printf("%s%s\n", prefix, r.Info().Name)
-					} else {
-						fmt.Printf("%s%s=\"%s\"\n", prefix, r.Info().Name, val)
-					}
-				}
+				val := strings.ReplaceAll(v.Value, `\`, `\\`)
+				val = strings.ReplaceAll(val, `"`, `\"`)
+				fmt.Fprintf(out, "%s%s=\"%s\"\n", prefix, v.Key, val)
 			}
+
 			return nil
 		},
 		SilenceUsage: true,
@@ -93,3 +108,126 @@ func newEnvCmd(e shipyard.Engine) *cobra.Command {
 	envCmd.Flags().BoolVarP(&unset, "unset", "", false, "When set to true Shipyard will print unset commands for environment variables defined by the blueprint")
 	return envCmd
 }
+
+// environmentVariables returns the variables for the given resources, when no
+// resources are given the blueprint environment and all resources are returned
+func environmentVariables(c *config.Config, resources []string) ([]envVar, error) {
+	vars := []envVar{}
+
+	rs := []config.Resource{}
+	if len(resources) == 0 {
+		if c.Blueprint != nil {
+			for _, env := range c.Blueprint.Environment {
+				vars = append(vars, envVar{env.Key, env.Value})
+			}
+		}
+
+		rs = c.Resources
+	}
+
+	for _, name := range resources {
+		r, err := c.FindResource(name)
+		if err != nil {
+			return nil, newCommandError(ErrorCodeUsage, "Unable to find resource %s, resources are specified as type.name e.g. k8s_cluster.k3s", name)
+		}
+
+		rs = append(rs, r)
+	}
+
+	kubeConfigs := []string{}
+	for _, r := range rs {
+		if r.Info().Disabled || r.Info().Status == config.Disabled {
+			continue
+		}
+
+		// only resources which have been created can be connected to
+		if r.Info().Type != config.TypeOutput && r.Info().Status != config.Applied && r.Info().Status != config.Paused {
+			continue
+		}
+
+		rv, err := resourceEnvironment(r)
+		if err != nil {
+			return nil, newCommandError(ErrorCodeState, "Unable to read the config for %s.%s: %s", r.Info().Type, r.Info().Name, err)
+		}
+
+		for _, v := range rv {
+			// multiple kubeconfig files can be set using the path list separator
+			if v.Key == "KUBECONFIG" {
+				kubeConfigs = append(kubeConfigs, v.Value)
+				continue
+			}
+
+			if !hasEnvVar(vars, v.Key) {
+				vars = append(vars, v)
+			}
+		}
+	}
+
+	if len(kubeConfigs) > 0 {
+		vars = append(vars, envVar{"KUBECONFIG", strings.Join(kubeConfigs, string(os.PathListSeparator))})
+	}
+
+	return vars, nil
+}
+
+// resourceEnvironment returns the variables used by tools to connect to the resource
+func resourceEnvironment(r config.Resource) ([]envVar, error) {
+	name := fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)
+
+	switch v := r.(type) {
+	case *config.Output:
+		return []envVar{{v.Name, v.Value}}, nil
+	case *config.K8sCluster:
+		_, kubeConfig, _, err := utils.CreateKubeConfigPath(v.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		return []envVar{{"KUBECONFIG", kubeConfig}}, nil
+	case *config.NomadCluster:
+		cc, _, err := utils.GetClusterConfig(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return []envVar{{"NOMAD_ADDR", cc.APIAddress(utils.LocalContext)}}, nil
+	case *config.Network:
+		return []envVar{{"SHIPYARD_NETWORK", v.Name}, {"SHIPYARD_NETWORK_SUBNET", v.Subnet}}, nil
+	}
+
+	vars := []envVar{}
+	for _, p := range config.HostPorts(r) {
+		key, ok := wellKnownPorts[p.Remote]
+		if !ok {
+			key, ok = wellKnownPorts[p.Local]
+		}
+
+		if !ok || p.Protocol == "udp" {
+			continue
+		}
+
+		host := "localhost"
+		if b := p.BindAddress(); b != "" && b != "0.0.0.0" {
+			host = b
+		}
+
+		protocol := "http"
+		if p.TLS != nil {
+			protocol = "https"
+		}
+
+		vars = append(vars, envVar{key, fmt.Sprintf("%s://%s:%s", protocol, host, p.Host)})
+	}
+
+	return vars, nil
+}
+
+func hasEnvVar(vars []envVar, key string) bool {
+	for _, v := range vars {
+		if v.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"testing"
 
@@ -43,9 +44,9 @@ func TestSetsEnvironmentVariables(t *testing.T) {
 	err := en.Execute()
 	assert.NoError(t, err)
 
-	assert.Contains(t, `export foo="bar"`, out.String())
-	assert.Contains(t, `export abc="12\"3"`, out.String())
-	assert.Contains(t, `export apples="pears"`, out.String())
+	assert.Contains(t, out.String(), `export foo="bar"`)
+	assert.Contains(t, out.String(), `export abc="12\"3"`)
+	assert.Contains(t, out.String(), `export apples="pears"`)
 }
 
 func TestUnsetsEnvironmentVariables(t *testing.T) {
@@ -57,18 +58,105 @@ func TestUnsetsEnvironmentVariables(t *testing.T) {
 	err := en.Execute()
 	assert.NoError(t, err)
 
-	assert.Contains(t, `unset foo`, out.String())
-	assert.Contains(t, `unset abc`, out.String())
-	assert.Contains(t, `unset apples`, out.String())
+	assert.Contains(t, out.String(), `unset foo`)
+	assert.Contains(t, out.String(), `unset abc`)
+	assert.Contains(t, out.String(), `unset apples`)
 }
 
+func TestSetsEnvironmentVariablesForResources(t *testing.T) {
+	en := setupEnvState(t, envResourceState)
+	out := bytes.NewBufferString("")
+	en.SetOutput(out)
+
+	err := en.Execute()
+	assert.NoError(t, err)
+
+	_, kc1, _, _ := utils.CreateKubeConfigPath("k3s")
+	_, kc2, _, _ := utils.CreateKubeConfigPath("dev")
+
+	assert.Contains(t, out.String(), fmt.Sprintf(`export KUBECONFIG="%s%s%s"`, kc1, string(os.PathListSeparator), kc2))
+	assert.Contains(t, out.String(), `export VAULT_ADDR="http://localhost:18200"`)
+	assert.Contains(t, out.String(), `export CONSUL_HTTP_ADDR="https://127.0.0.1:18500"`)
+	assert.Contains(t, out.String(), `export SHIPYARD_NETWORK="cloud"`)
+	assert.Contains(t, out.String(), `export SHIPYARD_NETWORK_SUBNET="10.5.0.0/16"`)
+
+	// resources which have not been created are ignored
+	assert.NotContains(t, out.String(), "18600")
+}
+
+func TestSetsEnvironmentVariablesForResource(t *testing.T) {
+	en := setupEnvState(t, envResourceState)
+	out := bytes.NewBufferString("")
+	en.SetOutput(out)
+	en.SetArgs([]string{"k8s_cluster.dev"})
+
+	err := en.Execute()
+	assert.NoError(t, err)
+
+	_, kc, _, _ := utils.CreateKubeConfigPath("dev")
+
+	assert.Equal(t, fmt.Sprintf("export KUBECONFIG=\"%s\"\n", kc), out.String())
+}
+
+func TestEnvReturnsErrorWhenResourceNotFound(t *testing.T) {
+	en := setupEnvState(t, envResourceState)
+	en.SetOutput(bytes.NewBufferString(""))
+	en.SetArgs([]string{"k8s_cluster.missing"})
+
+	err := en.Execute()
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeUsage, ErrorCodeFor(err))
+}
+
+var envResourceState = `
+{
+  "resources": [
+	{
+      "name": "k3s",
+      "status": "applied",
+      "type": "k8s_cluster"
+	},
+	{
+      "name": "dev",
+      "status": "applied",
+      "type": "k8s_cluster"
+	},
+	{
+      "name": "cloud",
+      "status": "applied",
+      "type": "network",
+      "subnet": "10.5.0.0/16"
+	},
+	{
+      "name": "vault",
+      "status": "applied",
+      "type": "container",
+      "ports": [{"local": "8200", "remote": "8200", "host": "18200"}]
+	},
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "container_ingress",
+      "target": "container.consul",
+      "ports": [{"local": "8500", "remote": "8500", "host": "18500", "bind": "127.0.0.1", "tls": {}}]
+	},
+	{
+      "name": "nomad",
+      "status": "pending_creation",
+      "type": "container",
+      "ports": [{"local": "4646", "remote": "4646", "host": "18600"}]
+	}
+  ]
+}
+`
+
 var envState = `
 {
   "blueprint": {
-    "environment": {
-      "apples":"pears",
-      "abc":"12\"3"
-    }
+    "environment": [
+      {"key": "apples", "value": "pears"},
+      {"key": "abc", "value": "12\"3"}
+    ]
   },
   "resources": [
 	{
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrorCode is the class of an error returned by a command, error codes and
+// the exit codes for them are stable between releases so that scripts can
+// branch on the class of the failure
+type ErrorCode string
+
+const (
+	// ErrorCodeUnknown is returned for errors which have not been classified
+	ErrorCodeUnknown ErrorCode = "unknown"
+	// ErrorCodeConfig is returned when the blueprint or its variables are not valid
+	ErrorCodeConfig ErrorCode = "config_error"
+	// ErrorCodeDockerUnavailable is returned when Docker is not running or can not be reached
+	ErrorCodeDockerUnavailable ErrorCode = "docker_unavailable"
+	// ErrorCodeProvisioning is returned when resources could not be created
+	ErrorCodeProvisioning ErrorCode = "provisioning_failed"
+	// ErrorCodeTimeout is returned when a resource did not become ready in time
+	ErrorCodeTimeout ErrorCode = "timeout"
+	// ErrorCodePartialDestroy is returned when some resources could not be destroyed
+	ErrorCodePartialDestroy ErrorCode = "partial_destroy"
+	// ErrorCodeState is returned when the state can not be read or written
+	ErrorCodeState ErrorCode = "state_error"
+	// ErrorCodeUsage is returned for invalid arguments and flags
+	ErrorCodeUsage ErrorCode = "usage_error"
+	// ErrorCodeTestFailed is returned when the tests for a blueprint fail
+	ErrorCodeTestFailed ErrorCode = "test_failed"
+)
+
+// exitCodes is the catalogue of the process exit codes for each error code,
+// codes must never be changed or reused
+var exitCodes = map[ErrorCode]int{
+	ErrorCodeUnknown:           1,
+	ErrorCodeConfig:            2,
+	ErrorCodeDockerUnavailable: 3,
+	ErrorCodeProvisioning:      4,
+	ErrorCodeTimeout:           5,
+	ErrorCodePartialDestroy:    6,
+	ErrorCodeState:             7,
+	ErrorCodeUsage:             8,
+	ErrorCodeTestFailed:        9,
+}
+
+// CommandError is an error returned by a command with the class of the error
+type CommandError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// newCommandError returns an error with the given code and message
+func newCommandError(code ErrorCode, format string, a ...interface{}) error {
+	return &CommandError{Code: code, Err: fmt.Errorf(format, a...)}
+}
+
+// ErrorCodeFor returns the error code for an error, errors which have not been
+// classified return ErrorCodeUnknown
+func ErrorCodeFor(err error) ErrorCode {
+	var ce *CommandError
+	if errors.As(err, &ce) {
+		return ce.Code
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorCodeTimeout
+	}
+
+	return ErrorCodeUnknown
+}
+
+// ExitCode returns the process exit code for an error, nil returns 0
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	return exitCodes[ErrorCodeFor(err)]
+}
+
+// applyErrorCode returns the code for an error returned when applying a
+// blueprint, errors from the resources do not have a type so timeouts are
+// detected from the message
+func applyErrorCode(err error) ErrorCode {
+	if strings.Contains(strings.ToLower(err.Error()), "timeout") {
+		return ErrorCodeTimeout
+	}
+
+	return ErrorCodeProvisioning
+}
+
+// jsonError is written to stderr when a command with JSON output fails
+type jsonError struct {
+	Error     string    `json:"error"`
+	ErrorCode ErrorCode `json:"error_code"`
+	ExitCode  int       `json:"exit_code"`
+}
+
+// writeJSONError writes the error with the error and exit codes as JSON
+func writeJSONError(w io.Writer, err error) error {
+	d, jerr := json.Marshal(jsonError{
+		Error:     err.Error(),
+		ErrorCode: ErrorCodeFor(err),
+		ExitCode:  ExitCode(err),
+	})
+
+	if jerr != nil {
+		return jerr
+	}
+
+	_, jerr = fmt.Fprintln(w, string(d))
+
+	return jerr
+}
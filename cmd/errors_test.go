@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCodeReturnsZeroForNil(t *testing.T) {
+	require.Equal(t, 0, ExitCode(nil))
+}
+
+func TestExitCodeReturnsOneForUnclassifiedErrors(t *testing.T) {
+	require.Equal(t, 1, ExitCode(fmt.Errorf("boom")))
+}
+
+func TestExitCodeReturnsCodeForWrappedCommandErrors(t *testing.T) {
+	err := fmt.Errorf("outer: %w", newCommandError(ErrorCodePartialDestroy, "inner"))
+
+	require.Equal(t, ErrorCodePartialDestroy, ErrorCodeFor(err))
+	require.Equal(t, 6, ExitCode(err))
+}
+
+func TestExitCodeReturnsTimeoutForDeadlineExceeded(t *testing.T) {
+	err := fmt.Errorf("waiting: %w", context.DeadlineExceeded)
+
+	require.Equal(t, 5, ExitCode(err))
+}
+
+func TestExitCodesAreUnique(t *testing.T) {
+	seen := map[int]ErrorCode{}
+
+	for code, exit := range exitCodes {
+		other, ok := seen[exit]
+		require.False(t, ok, "exit code %d is used by %s and %s", exit, code, other)
+
+		seen[exit] = code
+	}
+}
+
+func TestApplyErrorCodeDetectsTimeouts(t *testing.T) {
+	require.Equal(t, ErrorCodeTimeout, applyErrorCode(fmt.Errorf("Timeout waiting for pods app=web to start")))
+	require.Equal(t, ErrorCodeProvisioning, applyErrorCode(fmt.Errorf("Unable to pull image")))
+}
+
+func TestWriteJSONErrorWritesCodes(t *testing.T) {
+	out := bytes.NewBufferString("")
+
+	err := writeJSONError(out, newCommandError(ErrorCodeConfig, "Blueprint is not valid"))
+	require.NoError(t, err)
+
+	je := jsonError{}
+	err = json.Unmarshal(out.Bytes(), &je)
+	require.NoError(t, err)
+
+	require.Equal(t, "Blueprint is not valid", je.Error)
+	require.Equal(t, ErrorCodeConfig, je.ErrorCode)
+	require.Equal(t, 2, je.ExitCode)
+}
+
+func TestJSONOutputDetectsOutputAndJSONFlags(t *testing.T) {
+	var output string
+	var asJSON bool
+
+	c := &cobra.Command{Use: "test"}
+	c.Flags().StringVarP(&output, "output", "o", "text", "")
+	c.Flags().BoolVarP(&asJSON, "json", "", false, "")
+
+	require.False(t, jsonOutput(c))
+
+	c.Flags().Set("output", "json")
+	require.True(t, jsonOutput(c))
+
+	c.Flags().Set("output", "text")
+	c.Flags().Set("json", "true")
+	require.True(t, jsonOutput(c))
+}
+
+func TestInvalidFlagsReturnUsageError(t *testing.T) {
+	rootCmd.SetArgs([]string{"validate", "--unknown"})
+	rootCmd.SetOut(bytes.NewBufferString(""))
+	rootCmd.SetErr(bytes.NewBufferString(""))
+
+	_, err := rootCmd.ExecuteC()
+	require.Error(t, err)
+	require.Equal(t, ErrorCodeUsage, ErrorCodeFor(err))
+}
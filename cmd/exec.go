@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -15,9 +16,20 @@ import (
 
 func newExecCmd(dt clients.ContainerTasks) *cobra.Command {
 	return &cobra.Command{
-		Use:   "exec <resource> <pod> <container> -- <command>",
+		Use:   "exec <resource> [pod] [container] -- [command]",
 		Short: "Execute a command in a Resource",
-		Long:  `Execute a command in a Resource or start a Tools resource and execute`,
+		Long: `Execute a command in a Resource or start a Tools resource and execute
+
+Containers and sidecars are attached to directly, for Kubernetes and Nomad clusters
+the command is executed in the server node unless a pod is specified. Pods are attached
+to using kubectl and the kubeconfig for the cluster.
+
+Sessions are recorded in asciinema format to $HOME/.shipyard/logs/sessions
+when recording is enabled in the user config $HOME/.shipyard/config.hcl
+
+  exec {
+    record = true
+  }`,
 		Example: `
 		# Execute a command in the first container of a Kubernetes pod
 		shipyard exec k8s_cluster.k3s mypod -- ls -las
@@ -30,8 +42,12 @@ func newExecCmd(dt clients.ContainerTasks) *cobra.Command {
 		
 		# Create a default shell in a container
 		shipyard exec container.consul
+
+		# Create a default shell in the server node of a Kubernetes cluster
+		shipyard exec k8s_cluster.k3s
 		`,
 		Args:               cobra.MinimumNArgs(1),
+		ValidArgsFunction:  getExecResources,
 		DisableFlagParsing: true,
 		SilenceUsage:       true,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -50,31 +66,31 @@ func newExecCmd(dt clients.ContainerTasks) *cobra.Command {
 				return xerrors.Errorf("Unable to find resource %s: %w", parameters[0], err)
 			}
 
-			switch r.Info().Type {
-			case config.TypeContainer:
-				return createContainerShell(r, dt, command)
-			case config.TypeK8sCluster:
-				pod := ""
-				container := ""
-
-				if len(parameters) != 2 {
-					return fmt.Errorf("Please specify a Kubernetes pod or service for this cluster")
-				}
-
-				// no pod specified use default
-				if len(parameters) == 2 {
-					pod = parameters[1]
-				}
-
-				if len(parameters) == 3 {
-					pod = parameters[1]
-					container = parameters[2]
-				}
-
-				return createK8sShell(r, dt, pod, container, command)
-			case config.TypeNomadCluster:
-			default:
-				return fmt.Errorf("Unknown resource type")
+			uc, err := config.LoadUserConfig(utils.UserConfigPath())
+			if err != nil {
+				return xerrors.Errorf("Unable to load user config: %w", err)
+			}
+
+			in, stdout, _ := term.StdStreams()
+			if !uc.RecordExec() {
+				return createShell(r, dt, parameters, command, in, stdout)
+			}
+
+			// record the session for audit
+			rec, err := newSessionRecorder(stdout, parameters[0], command)
+			if err != nil {
+				return xerrors.Errorf("Unable to record session: %w", err)
+			}
+
+			err = createShell(r, dt, parameters, command, in, rec)
+
+			recErr := rec.Close(err)
+			if err != nil {
+				return err
+			}
+
+			if recErr != nil {
+				return xerrors.Errorf("Unable to record session: %w", recErr)
 			}
 
 			return nil
@@ -82,6 +98,48 @@ func newExecCmd(dt clients.ContainerTasks) *cobra.Command {
 	}
 }
 
+// createShell executes the command in the given resource
+func createShell(r config.Resource, dt clients.ContainerTasks, parameters, command []string, in io.ReadCloser, out io.Writer) error {
+	switch r.Info().Type {
+	case config.TypeContainer, config.TypeSidecar:
+		if len(parameters) > 1 {
+			return fmt.Errorf("Too many parameters, only the resource name can be specified for a %s", r.Info().Type)
+		}
+
+		return createContainerShell(r.Info().Name, r.Info().Type, dt, command, in, out)
+	case config.TypeK8sCluster:
+		// no pod specified use the server node
+		if len(parameters) == 1 {
+			return createContainerShell(fmt.Sprintf("server.%s", r.Info().Name), r.Info().Type, dt, command, in, out)
+		}
+
+		if len(parameters) > 3 {
+			return fmt.Errorf("Too many parameters, specify a Kubernetes pod and optionally a container")
+		}
+
+		pod := parameters[1]
+		container := ""
+
+		if len(parameters) == 3 {
+			container = parameters[2]
+		}
+
+		return createK8sShell(r, dt, pod, container, command, in, out)
+	case config.TypeNomadCluster:
+		if len(parameters) > 1 {
+			return fmt.Errorf("Too many parameters, commands can only be executed in the server node of a Nomad cluster")
+		}
+
+		return createContainerShell(fmt.Sprintf("server.%s", r.Info().Name), r.Info().Type, dt, command, in, out)
+	default:
+		return fmt.Errorf("Unable to execute commands in resources of type %s", r.Info().Type)
+	}
+}
+
+// getExecResources returns the resources in the state file which commands can
+// be executed in for shell completion, only the first argument is completed
+var getExecResources = completeArgs(stateResources(config.TypeContainer, config.TypeSidecar, config.TypeK8sCluster, config.TypeNomadCluster))
+
 // parse parameters splits the args from the command to be executed
 func parseParameters(args []string) ([]string, []string) {
 	commandIndex := -1
@@ -99,19 +157,20 @@ func parseParameters(args []string) ([]string, []string) {
 	return args[0:commandIndex], args[commandIndex+1:]
 }
 
-func createContainerShell(r config.Resource, dt clients.ContainerTasks, command []string) error {
+// createContainerShell executes the command in the container with the given name, the
+// name is the container name without the type and domain e.g. server.k3s
+func createContainerShell(name string, typ config.ResourceType, dt clients.ContainerTasks, command []string, in io.ReadCloser, out io.Writer) error {
 	if len(command) == 0 {
 		command = []string{"sh"}
 	}
 
 	// find the container id
-	ids, err := dt.FindContainerIDs(r.Info().Name, config.TypeContainer)
+	ids, err := dt.FindContainerIDs(name, typ)
 	if err != nil || len(ids) == 0 {
-		return fmt.Errorf("Unable to find container %s", r.Info().Name)
+		return fmt.Errorf("Unable to find container %s", utils.FQDN(name, string(typ)))
 	}
 
-	in, stdout, _ := term.StdStreams()
-	err = dt.CreateShell(ids[0], command, in, stdout, stdout)
+	err = dt.CreateShell(ids[0], command, in, out, out)
 	if err != nil {
 		return fmt.Errorf("Could not execute command for container %s. Error: %s", ids[0], err)
 	}
@@ -119,7 +178,7 @@ func createContainerShell(r config.Resource, dt clients.ContainerTasks, command
 	return nil
 }
 
-func createK8sShell(r config.Resource, dt clients.ContainerTasks, pod, container string, command []string) error {
+func createK8sShell(r config.Resource, dt clients.ContainerTasks, pod, container string, command []string, in io.ReadCloser, out io.Writer) error {
 	clusterName := r.Info().Name
 
 	exec := []string{"kubectl", "exec", "-ti", pod}
@@ -178,8 +237,7 @@ func createK8sShell(r config.Resource, dt clients.ContainerTasks, pod, container
 	}
 	defer dt.RemoveContainer(tools, true)
 
-	in, stdout, _ := term.StdStreams()
-	err = dt.CreateShell(tools, append(exec, command...), in, stdout, stdout)
+	err = dt.CreateShell(tools, append(exec, command...), in, out, out)
 	if err != nil {
 		return fmt.Errorf("Could not execute command for cluster %s. Error: %s", clusterName, err)
 	}
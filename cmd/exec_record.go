@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/docker/docker/pkg/term"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// sessionHeader is the header of an asciinema v2 recording
+// https://github.com/asciinema/asciinema/blob/develop/doc/asciicast-v2.md
+type sessionHeader struct {
+	Version   int               `json:"version"`
+	Width     uint16            `json:"width"`
+	Height    uint16            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// sessionAuditEntry is written to the session index when a recorded
+// session starts and finishes
+type sessionAuditEntry struct {
+	ID       string    `json:"id"`
+	Event    string    `json:"event"`
+	Resource string    `json:"resource"`
+	Command  []string  `json:"command,omitempty"`
+	User     string    `json:"user"`
+	Hostname string    `json:"hostname"`
+	Time     time.Time `json:"time"`
+	File     string    `json:"file"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// sessionRecorder is a Writer which writes the output of an exec session to
+// the terminal and records it in asciinema format
+type sessionRecorder struct {
+	mutex   sync.Mutex
+	out     io.Writer
+	file    *os.File
+	start   time.Time
+	pending []byte
+
+	audit sessionAuditEntry
+}
+
+// sessionsDir returns the folder where recorded sessions are stored
+func sessionsDir() string {
+	return filepath.Join(utils.LogsDir(), "sessions")
+}
+
+// sessionIndexPath returns the location of the audit index for recorded sessions
+func sessionIndexPath() string {
+	return filepath.Join(sessionsDir(), "index.jsonl")
+}
+
+// newSessionRecorder creates a new recording for an exec session in the given
+// resource and adds a started entry to the audit index
+func newSessionRecorder(out io.Writer, resource string, command []string) (*sessionRecorder, error) {
+	err := os.MkdirAll(sessionsDir(), os.ModePerm)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create sessions folder: %w", err)
+	}
+
+	start := time.Now()
+	id := fmt.Sprintf("%s-%s", start.Format("20060102T150405.000"), resource)
+	path := filepath.Join(sessionsDir(), id+".cast")
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create session recording: %w", err)
+	}
+
+	width, height := terminalSize(out)
+	h := sessionHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: start.Unix(),
+		Command:   strings.Join(command, " "),
+		Title:     fmt.Sprintf("shipyard exec %s", resource),
+		Env: map[string]string{
+			"SHELL": os.Getenv("SHELL"),
+			"TERM":  os.Getenv("TERM"),
+		},
+	}
+
+	err = json.NewEncoder(f).Encode(h)
+	if err != nil {
+		f.Close()
+		return nil, xerrors.Errorf("Unable to write session header: %w", err)
+	}
+
+	s := &sessionRecorder{
+		out:   out,
+		file:  f,
+		start: start,
+		audit: sessionAuditEntry{
+			ID:       id,
+			Event:    "started",
+			Resource: resource,
+			Command:  command,
+			User:     currentUser(),
+			Hostname: currentHostname(),
+			Time:     start,
+			File:     path,
+		},
+	}
+
+	err = writeSessionAudit(s.audit)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Write the data to the terminal and record it as an output event
+func (s *sessionRecorder) Write(p []byte) (int, error) {
+	n, err := s.out.Write(p)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// events must contain valid UTF-8, hold back any partial character
+	// at the end of the data until the rest of it has been written
+	data := append(s.pending, p[:n]...)
+	i := len(data)
+	for j := len(data) - 1; j >= 0 && j >= len(data)-utf8.UTFMax; j-- {
+		if utf8.RuneStart(data[j]) {
+			if !utf8.FullRune(data[j:]) {
+				i = j
+			}
+
+			break
+		}
+	}
+
+	s.pending = append([]byte{}, data[i:]...)
+
+	if i > 0 {
+		s.writeEvent(string(data[:i]))
+	}
+
+	return n, err
+}
+
+// Fd returns the file descriptor of the terminal so that the session
+// is still treated as a tty
+func (s *sessionRecorder) Fd() uintptr {
+	if f, ok := s.out.(*os.File); ok {
+		return f.Fd()
+	}
+
+	return ^uintptr(0)
+}
+
+// Close the recording and add a finished entry to the audit index,
+// err is the error returned by the session
+func (s *sessionRecorder) Close(err error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.pending) > 0 {
+		s.writeEvent(string(s.pending))
+		s.pending = nil
+	}
+
+	s.file.Close()
+
+	s.audit.Event = "finished"
+	s.audit.Time = time.Now()
+	if err != nil {
+		s.audit.Error = err.Error()
+	}
+
+	return writeSessionAudit(s.audit)
+}
+
+func (s *sessionRecorder) writeEvent(data string) {
+	elapsed := time.Since(s.start).Seconds()
+
+	// errors are ignored as a failed recording should not break the session
+	d, _ := json.Marshal([]interface{}{elapsed, "o", data})
+	s.file.Write(append(d, '\n'))
+}
+
+// writeSessionAudit appends an entry to the session index
+func writeSessionAudit(e sessionAuditEntry) error {
+	f, err := os.OpenFile(sessionIndexPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return xerrors.Errorf("Unable to open session index: %w", err)
+	}
+	defer f.Close()
+
+	err = json.NewEncoder(f).Encode(e)
+	if err != nil {
+		return xerrors.Errorf("Unable to write session index: %w", err)
+	}
+
+	return nil
+}
+
+// terminalSize returns the width and height of the terminal, when the
+// output is not a terminal the default size of 80x24 is returned
+func terminalSize(out io.Writer) (uint16, uint16) {
+	fd, isTerminal := term.GetFdInfo(out)
+	if isTerminal {
+		if ws, err := term.GetWinsize(fd); err == nil && ws.Width > 0 {
+			return ws.Width, ws.Height
+		}
+	}
+
+	return 80, 24
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
+
+func currentHostname() string {
+	h, _ := os.Hostname()
+	return h
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupRecordedExec(t *testing.T) (*cobra.Command, *mocks.MockContainerTasks, func()) {
+	c, mt, cleanup := setupExec(baseState)
+
+	err := ioutil.WriteFile(utils.UserConfigPath(), []byte(userConfigRecordExec), os.ModePerm)
+	assert.NoError(t, err)
+
+	removeOn(&mt.Mock, "CreateShell")
+	mt.On("CreateShell", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(3).(io.Writer).Write([]byte("hello world"))
+	}).Return(nil)
+
+	return c, mt, cleanup
+}
+
+func readLines(t *testing.T, file string) []string {
+	f, err := os.Open(file)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	lines := []string{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+
+	return lines
+}
+
+func readSessionIndex(t *testing.T) []sessionAuditEntry {
+	entries := []sessionAuditEntry{}
+	for _, l := range readLines(t, sessionIndexPath()) {
+		e := sessionAuditEntry{}
+		assert.NoError(t, json.Unmarshal([]byte(l), &e))
+
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+func TestExecDoesNotRecordWhenDisabled(t *testing.T) {
+	c, _, cleanup := setupExec(baseState)
+	defer cleanup()
+
+	c.SetArgs([]string{"container.consul"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	assert.NoFileExists(t, sessionIndexPath())
+}
+
+func TestExecRecordsSessionWhenEnabled(t *testing.T) {
+	c, _, cleanup := setupRecordedExec(t)
+	defer cleanup()
+
+	c.SetArgs([]string{"container.consul", "--", "ls", "-las"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	entries := readSessionIndex(t)
+	assert.Len(t, entries, 2)
+
+	assert.Equal(t, "started", entries[0].Event)
+	assert.Equal(t, "finished", entries[1].Event)
+	assert.Equal(t, entries[0].ID, entries[1].ID)
+	assert.Equal(t, "container.consul", entries[0].Resource)
+	assert.Equal(t, []string{"ls", "-las"}, entries[0].Command)
+	assert.Equal(t, filepath.Join(sessionsDir(), entries[0].ID+".cast"), entries[0].File)
+
+	lines := readLines(t, entries[0].File)
+	assert.Len(t, lines, 2)
+
+	h := sessionHeader{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &h))
+	assert.Equal(t, 2, h.Version)
+	assert.Equal(t, uint16(80), h.Width)
+	assert.Equal(t, uint16(24), h.Height)
+	assert.Equal(t, "ls -las", h.Command)
+
+	ev := []interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &ev))
+	assert.Equal(t, "o", ev[1])
+	assert.Equal(t, "hello world", ev[2])
+}
+
+func TestExecRecordsErrorInSessionIndex(t *testing.T) {
+	c, mt, cleanup := setupRecordedExec(t)
+	defer cleanup()
+
+	removeOn(&mt.Mock, "CreateShell")
+	mt.On("CreateShell", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	c.SetArgs([]string{"container.consul"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+
+	entries := readSessionIndex(t)
+	assert.Len(t, entries, 2)
+	assert.Contains(t, entries[1].Error, "boom")
+}
+
+func TestSessionRecorderHoldsBackPartialCharacters(t *testing.T) {
+	cleanup := setupState("")
+	defer cleanup()
+
+	out := bytes.NewBuffer(nil)
+	rec, err := newSessionRecorder(out, "container.consul", nil)
+	assert.NoError(t, err)
+
+	// € is encoded as 3 bytes, split it across two writes
+	rec.Write([]byte("a\xe2\x82"))
+	rec.Write([]byte("\xac"))
+	rec.Close(nil)
+
+	assert.Equal(t, "a€", out.String())
+
+	lines := readLines(t, rec.audit.File)
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[1], `"a"`)
+	assert.Contains(t, lines[2], `"€"`)
+}
+
+func TestSessionRecorderFdIsInvalidWhenNotAFile(t *testing.T) {
+	rec := &sessionRecorder{out: bytes.NewBuffer(nil)}
+
+	assert.Equal(t, ^uintptr(0), rec.Fd())
+}
+
+const userConfigRecordExec = `
+exec {
+	record = true
+}
+`
@@ -106,16 +106,70 @@ func TestExecCreatesShellInContainerWithCustomCommand(t *testing.T) {
 	assert.Equal(t, []string{"ls", "-las"}, call.Arguments[1].([]string))
 }
 
-func TestExecK8sWithNoPodReturnsError(t *testing.T) {
-	c, _, cleanup := setupExec(baseState)
+func TestExecCreatesShellInSidecar(t *testing.T) {
+	c, mt, cleanup := setupExec(baseState)
+	defer cleanup()
+
+	c.SetArgs([]string{"sidecar.envoy"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	mt.AssertCalled(t, "FindContainerIDs", "envoy", config.TypeSidecar)
+}
+
+func TestExecK8sWithNoPodCreatesShellInServer(t *testing.T) {
+	c, mt, cleanup := setupExec(baseState)
 	defer cleanup()
 
+	c.SetArgs([]string{"k8s_cluster.k3s", "--", "kubectl", "get", "pods"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	mt.AssertCalled(t, "FindContainerIDs", "server.k3s", config.TypeK8sCluster)
+	mt.AssertNotCalled(t, "CreateContainer", mock.Anything)
+
+	call := getCalls(&mt.Mock, "CreateShell")[0]
+	assert.Equal(t, "abc", call.Arguments[0])
+	assert.Equal(t, []string{"kubectl", "get", "pods"}, call.Arguments[1].([]string))
+}
+
+func TestExecK8sWithNoRunningServerReturnsError(t *testing.T) {
+	c, mt, cleanup := setupExec(baseState)
+	defer cleanup()
+
+	removeOn(&mt.Mock, "FindContainerIDs")
+	mt.On("FindContainerIDs", "server.k3s", config.TypeK8sCluster).Return([]string{}, nil)
+
 	c.SetArgs([]string{"k8s_cluster.k3s"})
 
 	err := c.Execute()
 	assert.Error(t, err)
 }
 
+func TestExecNomadCreatesShellInServer(t *testing.T) {
+	c, mt, cleanup := setupExec(baseState)
+	defer cleanup()
+
+	c.SetArgs([]string{"nomad_cluster.dev"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	mt.AssertCalled(t, "FindContainerIDs", "server.dev", config.TypeNomadCluster)
+}
+
+func TestExecNomadWithJobReturnsError(t *testing.T) {
+	c, _, cleanup := setupExec(baseState)
+	defer cleanup()
+
+	c.SetArgs([]string{"nomad_cluster.dev", "myjob"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
+
 func TestExecK8sPullsImage(t *testing.T) {
 	c, mt, cleanup := setupExec(baseState)
 	defer cleanup()
@@ -221,6 +275,49 @@ func TestExecCreatesShellInClusterWithCustomCommand(t *testing.T) {
 	assert.Equal(t, []string{"kubectl", "exec", "-ti", "mypod", "ls", "-las"}, call.Arguments[1].([]string))
 }
 
+func TestExecCreatesShellInClusterPodContainer(t *testing.T) {
+	c, mt, cleanup := setupExec(baseState)
+	defer cleanup()
+
+	c.SetArgs([]string{"k8s_cluster.k3s", "mypod", "web", "--", "ls"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	call := getCalls(&mt.Mock, "CreateShell")[0]
+
+	assert.Equal(t, []string{"kubectl", "exec", "-ti", "mypod", "-c", "web", "ls"}, call.Arguments[1].([]string))
+}
+
+func TestExecK8sWithTooManyParametersReturnsError(t *testing.T) {
+	c, _, cleanup := setupExec(baseState)
+	defer cleanup()
+
+	c.SetArgs([]string{"k8s_cluster.k3s", "mypod", "web", "other"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
+
+func TestExecCompletesResourcesFromState(t *testing.T) {
+	c, _, cleanup := setupExec(baseState)
+	defer cleanup()
+
+	r, d := getExecResources(c, []string{}, "")
+
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, d)
+	assert.ElementsMatch(t, []string{"k8s_cluster.k3s", "nomad_cluster.dev", "container.consul", "sidecar.envoy"}, r)
+}
+
+func TestExecCompletesOnlyResource(t *testing.T) {
+	c, _, cleanup := setupExec(baseState)
+	defer cleanup()
+
+	r, _ := getExecResources(c, []string{"k8s_cluster.k3s"}, "")
+
+	assert.Empty(t, r)
+}
+
 func TestExecCreatesShellErrorReturnsError(t *testing.T) {
 	c, mt, cleanup := setupExec(baseState)
 	defer cleanup()
@@ -253,12 +350,26 @@ var baseState = `
 	  }]
 	},
 	{
+      "name": "dev",
+      "status": "running",
+	  "type": "nomad_cluster",
+	  "networks": [{
+		"name": "network.dc1"
+	  }]
+	},
+	{
       "name": "consul",
       "status": "running",
 	  "type": "container",
 	  "networks": [{
 		"name": "network.dc1"
 	  }]
+	},
+	{
+      "name": "envoy",
+      "status": "running",
+	  "type": "sidecar",
+	  "target": "container.consul"
 	}
   ]
 }
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// fleetTimeout is the time to wait for the connector on a joined machine to return its environment
+var fleetTimeout = 5 * time.Second
+
+// getRemoteEnvironment requests the environment from a joined machine, replaced in tests
+var getRemoteEnvironment = clients.GetRemoteEnvironment
+
+var fleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: "Manage the environments running on the joined machines",
+	Long: `Manage the environments running on this machine and on the machines which have been
+joined with 'shipyard connector join'`,
+}
+
+func newFleetListCmd(h clients.History) *cobra.Command {
+	var owner string
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the environments running on this machine and the joined machines",
+		Long: `Lists the environments running on this machine and on the machines which have been joined
+with 'shipyard connector join'. The environment on a joined machine is reported by its connector,
+machines where the connector can not be reached are shown as unreachable. Machines with no
+running environment are not shown, unreachable machines are always shown.
+
+The environment is requested over mTLS from the connector on each joined machine, only
+connectors which have been joined can read it.`,
+		Example: `
+  # List all environments
+  shipyard fleet list
+
+  # List the environments created by a user
+  shipyard fleet list --owner nic
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			local, err := clients.LocalEnvironment(utils.StatePath(), h)
+			if err != nil {
+				return fmt.Errorf("Unable to read the local environment: %s", err)
+			}
+
+			envs := []*clients.Environment{local}
+
+			rcs, err := clients.ListRemoteConnectors()
+			if err != nil {
+				return fmt.Errorf("Unable to list remote connectors: %s", err)
+			}
+
+			for _, rc := range rcs {
+				envs = append(envs, getRemoteEnvironment(utils.CertsDir(""), rc, fleetTimeout))
+			}
+
+			cmd.Printf("%-20s %-12s %-8s %-12s %s\n", "HOST", "OWNER", "AGE", "HEALTH", "BLUEPRINT")
+
+			for _, e := range envs {
+				if e.Health != clients.EnvironmentUnreachable && e.Resources == 0 {
+					continue
+				}
+
+				// the owner of an unreachable machine is not known, it is always shown
+				// so that forgotten machines are not hidden
+				if owner != "" && e.Owner != owner && e.Health != clients.EnvironmentUnreachable {
+					continue
+				}
+
+				age := "-"
+				if e.Created != nil {
+					age = formatAge(time.Since(*e.Created))
+				}
+
+				cmd.Printf("%-20s %-12s %-8s %-12s %s\n", e.Host, valueOrDash(e.Owner), age, e.Health, valueOrDash(e.Blueprint))
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	listCmd.Flags().StringVarP(&owner, "owner", "", "", "Only show environments created by the given user, unreachable machines are always shown")
+
+	return listCmd
+}
+
+// formatAge returns the age of an environment in minutes, hours, or days
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
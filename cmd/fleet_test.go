@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupFleetList(t *testing.T, remotes map[string]*clients.Environment) (*clients.HistoryMock, *bytes.Buffer) {
+	home := os.Getenv(utils.HomeEnvName())
+	os.Setenv(utils.HomeEnvName(), t.TempDir())
+	t.Cleanup(func() {
+		os.Setenv(utils.HomeEnvName(), home)
+	})
+
+	for name := range remotes {
+		err := clients.SaveRemoteConnector(&clients.RemoteConnector{Name: name, EnvironmentAddress: name + ".local:30004"})
+		assert.NoError(t, err)
+	}
+
+	// a nil environment is a machine which can not be reached
+	getRemoteEnvironment = func(dir string, rc *clients.RemoteConnector, timeout time.Duration) *clients.Environment {
+		assert.Equal(t, utils.CertsDir(""), dir)
+
+		if e := remotes[rc.Name]; e != nil {
+			e.Host = rc.Name
+			return e
+		}
+
+		return &clients.Environment{Host: rc.Name, Health: clients.EnvironmentUnreachable}
+	}
+	t.Cleanup(func() {
+		getRemoteEnvironment = clients.GetRemoteEnvironment
+	})
+
+	hm := &clients.HistoryMock{}
+	hm.On("Read").Return([]clients.HistoryEntry{}, nil)
+
+	return hm, bytes.NewBuffer(nil)
+}
+
+func TestFleetListShowsRemoteEnvironments(t *testing.T) {
+	created := time.Now().Add(-72 * time.Hour)
+	hm, out := setupFleetList(t, map[string]*clients.Environment{
+		"lab":   {Owner: "nic", Blueprint: "github.com/org/consul", Created: &created, Resources: 3, Health: clients.EnvironmentHealthy},
+		"build": {Owner: "erik", Resources: 0, Health: clients.EnvironmentHealthy},
+	})
+
+	c := newFleetListCmd(hm)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	assert.Regexp(t, `lab\s+nic\s+3d\s+healthy\s+github.com/org/consul`, out.String())
+	assert.NotContains(t, out.String(), "build")
+}
+
+func TestFleetListShowsUnreachableMachines(t *testing.T) {
+	hm, out := setupFleetList(t, map[string]*clients.Environment{"lab": nil})
+
+	c := newFleetListCmd(hm)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	assert.Regexp(t, `lab\s+-\s+-\s+unreachable`, out.String())
+}
+
+func TestFleetListFiltersByOwner(t *testing.T) {
+	hm, out := setupFleetList(t, map[string]*clients.Environment{
+		"lab":     {Owner: "nic", Resources: 3, Health: clients.EnvironmentHealthy},
+		"build":   {Owner: "erik", Resources: 1, Health: clients.EnvironmentDegraded},
+		"staging": nil,
+	})
+
+	c := newFleetListCmd(hm)
+	c.SetOut(out)
+	c.SetArgs([]string{"--owner", "erik"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	assert.Regexp(t, `build\s+erik\s+-\s+degraded`, out.String())
+	assert.NotContains(t, out.String(), "lab")
+
+	// the owner of an unreachable machine is not known so it is not hidden
+	assert.Regexp(t, `staging\s+-\s+-\s+unreachable`, out.String())
+}
+
+func TestFormatAge(t *testing.T) {
+	assert.Equal(t, "45m", formatAge(45*time.Minute))
+	assert.Equal(t, "30h", formatAge(30*time.Hour))
+	assert.Equal(t, "12d", formatAge(12*24*time.Hour))
+}
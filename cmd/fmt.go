@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/hcl2/hclwrite"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+)
+
+func newFmtCmd() *cobra.Command {
+	var check bool
+	var diff bool
+	var recursive bool
+
+	fmtCmd := &cobra.Command{
+		Use:   "fmt [path]",
+		Short: "Rewrite blueprint files in the canonical format",
+		Long: `Rewrite the HCL and variables files in a blueprint in the canonical format.
+
+The names of the files which are changed are printed. With --check files are not
+changed and fmt exits with an error when any file is not formatted, use --check
+in CI to ensure blueprints are formatted.`,
+		Example: `
+  # Format the blueprint in the current folder
+  shipyard fmt
+
+  # Check the blueprint and all sub folders are formatted, showing the changes required
+  shipyard fmt --check --diff --recursive ./my-blueprint
+	`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "."
+			if len(args) == 1 {
+				path = args[0]
+			}
+
+			files, err := formatFiles(path, recursive)
+			if err != nil {
+				return err
+			}
+
+			unformatted := 0
+			for _, f := range files {
+				changed, err := formatFile(cmd.OutOrStdout(), f, !check, diff)
+				if err != nil {
+					return err
+				}
+
+				if changed {
+					unformatted++
+				}
+			}
+
+			if check && unformatted > 0 {
+				return newCommandError(ErrorCodeConfig, "%d file(s) are not formatted, run 'shipyard fmt' to format the files", unformatted)
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	fmtCmd.Flags().BoolVarP(&check, "check", "", false, "Check the files are formatted without changing them, returns an error when a file is not formatted")
+	fmtCmd.Flags().BoolVarP(&diff, "diff", "", false, "Print the changes made to the files as a unified diff")
+	fmtCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Format the files in sub folders")
+
+	return fmtCmd
+}
+
+// formatFiles returns the HCL and variables files to format, hidden folders are skipped
+func formatFiles(path string, recursive bool) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find %s", path)
+	}
+
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	files := []string{}
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if p == path {
+				return nil
+			}
+
+			if !recursive || strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if ext := filepath.Ext(p); ext == ".hcl" || ext == ".vars" {
+			files = append(files, p)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// formatFile formats a file in the canonical HCL style, when write is true the
+// formatted file is written. Returns true when the file was not formatted.
+func formatFile(w io.Writer, file string, write bool, diff bool) (bool, error) {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, fmt.Errorf("Unable to read file %s: %s", file, err)
+	}
+
+	// files which are not valid HCL can not be formatted
+	_, diag := hclwrite.ParseConfig(src, file, hcl.Pos{Line: 1, Column: 1})
+	if diag.HasErrors() {
+		return false, newCommandError(ErrorCodeConfig, "Unable to format file %s: %s", file, diag.Error())
+	}
+
+	out := hclwrite.Format(src)
+	if bytes.Equal(src, out) {
+		return false, nil
+	}
+
+	fmt.Fprintln(w, file)
+
+	if diff {
+		d, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(src)),
+			B:        difflib.SplitLines(string(out)),
+			FromFile: file,
+			ToFile:   file,
+			Context:  3,
+		})
+
+		if err != nil {
+			return false, err
+		}
+
+		fmt.Fprintln(w, d)
+	}
+
+	if write {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+
+		err = ioutil.WriteFile(file, out, fi.Mode())
+		if err != nil {
+			return false, fmt.Errorf("Unable to write file %s: %s", file, err)
+		}
+	}
+
+	return true, nil
+}
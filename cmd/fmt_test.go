@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func setupFmt(t *testing.T, blueprint string) (*cobra.Command, *bytes.Buffer, string) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(blueprint), os.ModePerm)
+	require.NoError(t, err)
+
+	out := bytes.NewBufferString("")
+
+	fc := newFmtCmd()
+	fc.SetOut(out)
+	fc.SetErr(bytes.NewBufferString(""))
+
+	return fc, out, dir
+}
+
+func readFmtFile(t *testing.T, file string) string {
+	d, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+
+	return string(d)
+}
+
+func TestFmtRewritesUnformattedFiles(t *testing.T) {
+	fc, out, dir := setupFmt(t, fmtUnformatted)
+	fc.SetArgs([]string{dir})
+
+	err := fc.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, out.String(), filepath.Join(dir, "main.hcl"))
+	require.Equal(t, fmtFormatted, readFmtFile(t, filepath.Join(dir, "main.hcl")))
+}
+
+func TestFmtDoesNotPrintFormattedFiles(t *testing.T) {
+	fc, out, dir := setupFmt(t, fmtFormatted)
+	fc.SetArgs([]string{dir})
+
+	err := fc.Execute()
+	require.NoError(t, err)
+
+	require.Empty(t, out.String())
+}
+
+func TestFmtCheckReturnsErrorAndDoesNotWrite(t *testing.T) {
+	fc, out, dir := setupFmt(t, fmtUnformatted)
+	fc.SetArgs([]string{"--check", dir})
+
+	err := fc.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1 file(s) are not formatted")
+
+	require.Contains(t, out.String(), filepath.Join(dir, "main.hcl"))
+	require.Equal(t, fmtUnformatted, readFmtFile(t, filepath.Join(dir, "main.hcl")))
+}
+
+func TestFmtCheckWithFormattedFilesReturnsNoError(t *testing.T) {
+	fc, _, dir := setupFmt(t, fmtFormatted)
+	fc.SetArgs([]string{"--check", dir})
+
+	err := fc.Execute()
+	require.NoError(t, err)
+}
+
+func TestFmtDiffPrintsChanges(t *testing.T) {
+	fc, out, dir := setupFmt(t, fmtUnformatted)
+	fc.SetArgs([]string{"--check", "--diff", dir})
+
+	fc.Execute()
+
+	require.Contains(t, out.String(), "-  subnet=\"10.6.0.0/16\"")
+	require.Contains(t, out.String(), "+  subnet = \"10.6.0.0/16\"")
+}
+
+func TestFmtSkipsSubFoldersUnlessRecursive(t *testing.T) {
+	fc, _, dir := setupFmt(t, fmtFormatted)
+
+	sub := filepath.Join(dir, "modules")
+	os.MkdirAll(sub, os.ModePerm)
+	err := ioutil.WriteFile(filepath.Join(sub, "module.hcl"), []byte(fmtUnformatted), os.ModePerm)
+	require.NoError(t, err)
+
+	fc.SetArgs([]string{"--check", dir})
+	err = fc.Execute()
+	require.NoError(t, err)
+
+	fc.SetArgs([]string{"--check", "--recursive", dir})
+	err = fc.Execute()
+	require.Error(t, err)
+}
+
+func TestFmtWithInvalidHCLReturnsError(t *testing.T) {
+	fc, _, dir := setupFmt(t, `network "local" {`)
+	fc.SetArgs([]string{dir})
+
+	err := fc.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Unable to format file")
+}
+
+const fmtUnformatted = `network "local" {
+  subnet="10.6.0.0/16"
+}
+
+container "consul" {
+    image {
+    name = "consul:1.8.1"
+  }
+  network {
+    name = "network.local"
+    ip_address = "10.6.0.200"
+  }
+}
+`
+
+const fmtFormatted = `network "local" {
+  subnet = "10.6.0.0/16"
+}
+
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+  network {
+    name       = "network.local"
+    ip_address = "10.6.0.200"
+  }
+}
+`
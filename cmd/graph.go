@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// graphNode is a resource in the dependency graph
+type graphNode struct {
+	address  string
+	disabled bool
+}
+
+// graphEdge is a dependency, from must be created before to
+type graphEdge struct {
+	from string
+	to   string
+}
+
+func newGraphCmd() *cobra.Command {
+	var variables []string
+	var variablesFile string
+	var format string
+
+	graphCmd := &cobra.Command{
+		Use:   "graph [blueprint]",
+		Short: "Print the dependency graph of a blueprint",
+		Long: `Print the dependency graph of the resources in a blueprint.
+
+The graph contains the dependencies set with depends_on and the dependencies
+found when the references in the blueprint are resolved, an edge from A to B
+means A is created before B. Disabled resources are drawn with a dashed outline.
+The graph can be rendered with Graphviz or Mermaid.`,
+		Example: `
+  # Render the graph for the blueprint in the current folder with Graphviz
+  shipyard graph | dot -Tsvg > graph.svg
+
+  # Print the graph as a Mermaid flowchart
+  shipyard graph --format mermaid ./my-blueprint
+	`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "dot" && format != "mermaid" {
+				return fmt.Errorf("Invalid format '%s', valid formats are dot or mermaid", format)
+			}
+
+			path := "."
+			if len(args) == 1 {
+				path = args[0]
+			}
+
+			vars := map[string]string{}
+			for _, v := range variables {
+				parts := strings.Split(v, "=")
+				if len(parts) == 2 {
+					vars[parts[0]] = parts[1]
+				}
+			}
+
+			nodes, edges, err := blueprintGraph(path, vars, variablesFile)
+			if err != nil {
+				return err
+			}
+
+			if format == "mermaid" {
+				writeMermaidGraph(cmd.OutOrStdout(), nodes, edges)
+				return nil
+			}
+
+			writeDotGraph(cmd.OutOrStdout(), nodes, edges)
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	graphCmd.Flags().StringVarP(&format, "format", "", "dot", "Output format for the graph [dot, mermaid]")
+	graphCmd.Flags().StringSliceVarP(&variables, "var", "", nil, "Allows setting variables from the command line, variables are specified as a key and value, e.g --var key=value. Can be specified multiple times")
+	graphCmd.Flags().StringVarP(&variablesFile, "vars-file", "", "", "Load variables from a location other than *.vars files in the blueprint folder. E.g --vars-file=./file.vars")
+
+	return graphCmd
+}
+
+// blueprintGraph parses the blueprint and returns the resources and the dependencies
+// between them ordered by address, the graph is checked for cycles
+func blueprintGraph(path string, vars map[string]string, variablesFile string) ([]graphNode, []graphEdge, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil, fmt.Errorf("Unable to find blueprint %s", path)
+	}
+
+	c := config.New()
+
+	// resources which pull images depend on the image cache which is created by the engine
+	c.AddResource(config.NewImageCache(utils.CacheResourceName))
+
+	var err error
+	if utils.IsConfigFile(path) {
+		err = config.ParseSingleFile(path, c, vars, variablesFile)
+	} else {
+		err = config.ParseFolder(path, c, false, "", false, []string{}, vars, variablesFile)
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to parse blueprint: %s", err)
+	}
+
+	err = config.ParseReferences(c)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to parse blueprint: %s", err)
+	}
+
+	d, err := c.DoYaLikeDAGs()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to create dependency graph: %s", err)
+	}
+
+	err = d.Validate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to validate dependency graph: %s", err)
+	}
+
+	nodes := []graphNode{}
+	for _, r := range c.Resources {
+		nodes = append(nodes, graphNode{address: resourceAddress(r), disabled: r.Info().Disabled})
+	}
+
+	edges := []graphEdge{}
+	for _, e := range d.Edges() {
+		// resources without dependencies are connected to the blueprint
+		from, ok := e.Source().(config.Resource)
+		if !ok {
+			continue
+		}
+
+		edges = append(edges, graphEdge{from: resourceAddress(from), to: resourceAddress(e.Target().(config.Resource))})
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].address < nodes[j].address })
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+
+		return edges[i].to < edges[j].to
+	})
+
+	return nodes, edges, nil
+}
+
+// resourceAddress returns the address used to reference a resource, resources
+// in modules are prefixed with the module name
+func resourceAddress(r config.Resource) string {
+	i := r.Info()
+	if i.Module != "" {
+		return fmt.Sprintf("module.%s.%s.%s", i.Module, i.Type, i.Name)
+	}
+
+	return fmt.Sprintf("%s.%s", i.Type, i.Name)
+}
+
+func writeDotGraph(w io.Writer, nodes []graphNode, edges []graphEdge) {
+	fmt.Fprintln(w, "digraph shipyard {")
+	fmt.Fprintln(w, "  rankdir = \"LR\";")
+	fmt.Fprintln(w, "  node [shape = box];")
+	fmt.Fprintln(w)
+
+	for _, n := range nodes {
+		if n.disabled {
+			fmt.Fprintf(w, "  %q [style = dashed];\n", n.address)
+			continue
+		}
+
+		fmt.Fprintf(w, "  %q;\n", n.address)
+	}
+
+	if len(edges) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	for _, e := range edges {
+		fmt.Fprintf(w, "  %q -> %q;\n", e.from, e.to)
+	}
+
+	fmt.Fprintln(w, "}")
+}
+
+func writeMermaidGraph(w io.Writer, nodes []graphNode, edges []graphEdge) {
+	fmt.Fprintln(w, "graph LR")
+
+	// mermaid ids can not contain the characters used in addresses
+	ids := map[string]string{}
+	disabled := false
+
+	for i, n := range nodes {
+		ids[n.address] = fmt.Sprintf("n%d", i)
+
+		class := ""
+		if n.disabled {
+			class = ":::disabled"
+			disabled = true
+		}
+
+		fmt.Fprintf(w, "  %s[\"%s\"]%s\n", ids[n.address], n.address, class)
+	}
+
+	for _, e := range edges {
+		fmt.Fprintf(w, "  %s --> %s\n", ids[e.from], ids[e.to])
+	}
+
+	if disabled {
+		fmt.Fprintln(w, "  classDef disabled stroke-dasharray: 5 5")
+	}
+}
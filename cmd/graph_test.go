@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func setupGraph(t *testing.T, blueprint string) (*cobra.Command, *bytes.Buffer, string) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(blueprint), os.ModePerm)
+	require.NoError(t, err)
+
+	out := bytes.NewBufferString("")
+
+	gc := newGraphCmd()
+	gc.SetOut(out)
+	gc.SetErr(bytes.NewBufferString(""))
+
+	return gc, out, dir
+}
+
+func TestGraphReturnsResourcesAndDependencies(t *testing.T) {
+	_, _, dir := setupGraph(t, graphBlueprint)
+
+	nodes, edges, err := blueprintGraph(dir, nil, "")
+	require.NoError(t, err)
+
+	require.Equal(t, []graphNode{
+		{address: "container.consul"},
+		{address: "container.web", disabled: true},
+		{address: "image_cache.docker-cache"},
+		{address: "network.local"},
+	}, nodes)
+
+	require.Contains(t, edges, graphEdge{from: "network.local", to: "container.consul"})
+	require.Contains(t, edges, graphEdge{from: "container.consul", to: "container.web"})
+}
+
+func TestGraphWritesDot(t *testing.T) {
+	gc, out, dir := setupGraph(t, graphBlueprint)
+	gc.SetArgs([]string{dir})
+
+	err := gc.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, out.String(), "digraph shipyard {")
+	require.Contains(t, out.String(), `"container.web" [style = dashed];`)
+	require.Contains(t, out.String(), `"network.local" -> "container.consul";`)
+}
+
+func TestGraphWritesMermaid(t *testing.T) {
+	gc, out, dir := setupGraph(t, graphBlueprint)
+	gc.SetArgs([]string{"--format", "mermaid", dir})
+
+	err := gc.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, out.String(), "graph LR\n")
+	require.Contains(t, out.String(), `n0["container.consul"]`)
+	require.Contains(t, out.String(), `n1["container.web"]:::disabled`)
+	require.Contains(t, out.String(), "n3 --> n0\n")
+	require.Contains(t, out.String(), "classDef disabled")
+}
+
+func TestGraphWithInvalidFormatReturnsError(t *testing.T) {
+	gc, _, dir := setupGraph(t, graphBlueprint)
+	gc.SetArgs([]string{"--format", "png", dir})
+
+	err := gc.Execute()
+	require.Error(t, err)
+}
+
+func TestGraphWithMissingBlueprintReturnsError(t *testing.T) {
+	_, _, err := blueprintGraph("/not/exist", nil, "")
+	require.Error(t, err)
+}
+
+func TestGraphWithMissingDependencyReturnsError(t *testing.T) {
+	gc, _, dir := setupGraph(t, graphBlueprintMissingDependency)
+	gc.SetArgs([]string{dir})
+
+	err := gc.Execute()
+	require.Error(t, err)
+}
+
+const graphBlueprint = `
+network "local" {
+  subnet = "10.0.0.0/16"
+}
+
+container "consul" {
+  network {
+    name = "network.local"
+  }
+
+  image {
+    name = "consul:1.10.0"
+  }
+}
+
+container "web" {
+  depends_on = ["container.consul"]
+  disabled   = true
+
+  image {
+    name = "nginx:latest"
+  }
+}
+`
+
+const graphBlueprintMissingDependency = `
+container "web" {
+  depends_on = ["container.consul"]
+
+  image {
+    name = "nginx:latest"
+  }
+}
+`
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// defaultHelpWidth is used to wrap the help when the width of the terminal
+// can not be determined
+const defaultHelpWidth = 80
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// helpText is the translated help for a command, empty fields fall back to the
+// English text defined on the command
+type helpText struct {
+	Short   string `json:"short"`
+	Long    string `json:"long"`
+	Example string `json:"example"`
+}
+
+// helpCatalogue contains the translated help for a language
+type helpCatalogue struct {
+	// Headings are the section headings used in the help templates
+	Headings map[string]string `json:"headings"`
+	// Commands is the help for a command keyed by the command path
+	// i.e. "shipyard cache warm"
+	Commands map[string]helpText `json:"commands"`
+}
+
+// englishHeadings are the default section headings for the help
+var englishHeadings = map[string]string{
+	"usage":              "Usage:",
+	"aliases":            "Aliases:",
+	"examples":           "Examples:",
+	"available_commands": "Available Commands:",
+	"flags":              "Flags:",
+	"global_flags":       "Global Flags:",
+	"additional_help":    "Additional help topics:",
+	"more_info":          `Use "%s [command] --help" for more information about a command.`,
+}
+
+const helpTemplate = `{{with (or .Long .Short)}}{{. | wrap | trimTrailingWhitespaces}}
+
+{{end}}{{if or .Runnable .HasSubCommands}}{{.UsageString}}{{end}}`
+
+const usageTemplate = `{{heading "usage"}}{{if .Runnable}}
+  {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
+  {{.CommandPath}} [command]{{end}}{{if gt (len .Aliases) 0}}
+
+{{heading "aliases"}}
+  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+{{heading "examples"}}
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}
+
+{{heading "available_commands"}}{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+
+{{heading "flags"}}
+{{.LocalFlags.FlagUsagesWrapped helpWidth | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
+
+{{heading "global_flags"}}
+{{.InheritedFlags.FlagUsagesWrapped helpWidth | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
+
+{{heading "additional_help"}}{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
+  {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
+
+{{moreInfo .CommandPath}}{{end}}
+`
+
+// setupHelp configures the help for the command and all sub commands to be
+// wrapped to the width of the terminal and translated to the users language
+func setupHelp(root *cobra.Command, lang string) error {
+	cat, err := loadHelpCatalogue(lang)
+	if err != nil {
+		return err
+	}
+
+	headings := map[string]string{}
+	for k, v := range englishHeadings {
+		headings[k] = v
+		if t, ok := cat.Headings[k]; ok && t != "" {
+			headings[k] = t
+		}
+	}
+
+	cobra.AddTemplateFunc("wrap", func(s string) string { return wrapText(s, helpWidth()) })
+	cobra.AddTemplateFunc("helpWidth", helpWidth)
+	cobra.AddTemplateFunc("heading", func(k string) string { return headings[k] })
+	cobra.AddTemplateFunc("moreInfo", func(path string) string { return fmt.Sprintf(headings["more_info"], path) })
+
+	root.SetHelpTemplate(helpTemplate)
+	root.SetUsageTemplate(usageTemplate)
+
+	translateCommands(root, cat)
+
+	return nil
+}
+
+// translateCommands replaces the help for the command and all sub commands
+// with the text from the catalogue
+func translateCommands(c *cobra.Command, cat *helpCatalogue) {
+	if t, ok := cat.Commands[c.CommandPath()]; ok {
+		if t.Short != "" {
+			c.Short = t.Short
+		}
+
+		if t.Long != "" {
+			c.Long = t.Long
+		}
+
+		if t.Example != "" {
+			c.Example = t.Example
+		}
+	}
+
+	for _, sc := range c.Commands() {
+		translateCommands(sc, cat)
+	}
+}
+
+// loadHelpCatalogue loads the translations for the given language, when no
+// translations exist for the language an empty catalogue is returned and the
+// English help is used
+func loadHelpCatalogue(lang string) (*helpCatalogue, error) {
+	cat := &helpCatalogue{Headings: map[string]string{}, Commands: map[string]helpText{}}
+
+	if lang == "" || lang == "en" {
+		return cat, nil
+	}
+
+	d, err := localeFiles.ReadFile(fmt.Sprintf("locales/%s.json", lang))
+	if err != nil {
+		return cat, nil
+	}
+
+	err = json.Unmarshal(d, cat)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse help translations for language %s: %s", lang, err)
+	}
+
+	return cat, nil
+}
+
+// helpLanguage returns the language for the help from SHIPYARD_LANG, or the
+// standard locale environment variables i.e. LANG=de_DE.UTF-8 returns de
+func helpLanguage() string {
+	for _, e := range []string{"SHIPYARD_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(e)
+		if v == "" {
+			continue
+		}
+
+		v = strings.SplitN(v, ".", 2)[0]
+		v = strings.SplitN(v, "_", 2)[0]
+		v = strings.ToLower(v)
+
+		if v == "c" || v == "posix" {
+			return "en"
+		}
+
+		return v
+	}
+
+	return "en"
+}
+
+// helpWidth returns the width of the terminal
+func helpWidth() int {
+	w, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || w <= 0 {
+		return defaultHelpWidth
+	}
+
+	return w
+}
+
+// wrapText wraps the paragraphs in the text to the given width, lines which
+// are indented such as lists and code examples are not wrapped
+func wrapText(s string, width int) string {
+	out := []string{}
+	para := []string{}
+
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+
+		out = append(out, wrapParagraph(strings.Join(para, " "), width)...)
+		para = []string{}
+	}
+
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) == "" || strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t") {
+			flush()
+			out = append(out, l)
+			continue
+		}
+
+		para = append(para, strings.TrimSpace(l))
+	}
+
+	flush()
+
+	return strings.Join(out, "\n")
+}
+
+func wrapParagraph(s string, width int) []string {
+	lines := []string{}
+	line := ""
+
+	for _, w := range strings.Fields(s) {
+		if line != "" && len(line)+len(w)+1 > width {
+			lines = append(lines, line)
+			line = ""
+		}
+
+		if line == "" {
+			line = w
+			continue
+		}
+
+		line += " " + w
+	}
+
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
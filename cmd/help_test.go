@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/shlex"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+// exampleCommands returns the shipyard commands from the example for a
+// command, comments and output in the example are ignored
+func exampleCommands(example string) []string {
+	cmds := []string{}
+
+	for _, l := range strings.Split(example, "\n") {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "shipyard ") || l == "shipyard" {
+			cmds = append(cmds, l)
+		}
+	}
+
+	return cmds
+}
+
+func allCommands(c *cobra.Command) []*cobra.Command {
+	cmds := []*cobra.Command{c}
+	for _, sc := range c.Commands() {
+		cmds = append(cmds, allCommands(sc)...)
+	}
+
+	return cmds
+}
+
+func TestHelpExamplesAreValidCommands(t *testing.T) {
+	for _, c := range allCommands(rootCmd) {
+		for _, e := range exampleCommands(c.Example) {
+			args, err := shlex.Split(e)
+			require.NoError(t, err, "example '%s' for '%s' can not be parsed", e, c.CommandPath())
+
+			// only validate the shipyard command when piped to another command
+			for i, a := range args {
+				if a == "|" || a == ">" || a == "&&" || a == ";" {
+					args = args[:i]
+					break
+				}
+			}
+
+			ec, rest, err := rootCmd.Find(args[1:])
+			require.NoError(t, err, "example '%s' for '%s' is not a valid command", e, c.CommandPath())
+
+			// commands which parse their own flags receive all the arguments
+			pos := rest
+			if !ec.DisableFlagParsing {
+				err = ec.ParseFlags(rest)
+				require.NoError(t, err, "example '%s' for '%s' has invalid flags", e, c.CommandPath())
+
+				pos = ec.Flags().Args()
+			}
+
+			err = ec.ValidateArgs(pos)
+			require.NoError(t, err, "example '%s' for '%s' has invalid arguments", e, c.CommandPath())
+		}
+	}
+}
+
+func TestHelpTranslationsReferenceExistingCommands(t *testing.T) {
+	paths := map[string]bool{}
+	for _, c := range allCommands(rootCmd) {
+		paths[c.CommandPath()] = true
+	}
+
+	files, err := localeFiles.ReadDir("locales")
+	require.NoError(t, err)
+
+	for _, f := range files {
+		d, err := localeFiles.ReadFile("locales/" + f.Name())
+		require.NoError(t, err)
+
+		cat := &helpCatalogue{}
+		err = json.Unmarshal(d, cat)
+		require.NoError(t, err, "unable to parse %s", f.Name())
+
+		for k := range cat.Headings {
+			require.Contains(t, englishHeadings, k, "%s contains unknown heading %s", f.Name(), k)
+		}
+
+		for p := range cat.Commands {
+			require.True(t, paths[p], "%s contains help for unknown command %s", f.Name(), p)
+		}
+	}
+}
+
+func TestHelpLanguageUsesShipyardLangFirst(t *testing.T) {
+	t.Setenv("LANG", "fr_FR.UTF-8")
+	t.Setenv("SHIPYARD_LANG", "de")
+
+	require.Equal(t, "de", helpLanguage())
+}
+
+func TestHelpLanguageParsesLocale(t *testing.T) {
+	t.Setenv("SHIPYARD_LANG", "")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "de_DE.UTF-8")
+
+	require.Equal(t, "de", helpLanguage())
+
+	t.Setenv("LANG", "C")
+	require.Equal(t, "en", helpLanguage())
+}
+
+func TestSetupHelpTranslatesCommands(t *testing.T) {
+	root := &cobra.Command{Use: "shipyard", Short: "Modern cloud native development environments"}
+	run := &cobra.Command{Use: "run", Short: "Run the supplied stack configuration", Run: func(cmd *cobra.Command, args []string) {}}
+	root.AddCommand(run)
+
+	err := setupHelp(root, "de")
+	require.NoError(t, err)
+
+	require.Equal(t, "Die angegebene Blueprint-Konfiguration ausführen", run.Short)
+
+	out := bytes.NewBufferString("")
+	root.SetOut(out)
+	root.SetArgs([]string{"--help"})
+	root.Execute()
+
+	require.Contains(t, out.String(), "Verfügbare Befehle:")
+
+	// reset the template functions for the other tests
+	setupHelp(root, "en")
+}
+
+func TestSetupHelpWithUnknownLanguageUsesEnglish(t *testing.T) {
+	root := &cobra.Command{Use: "shipyard", Short: "Modern cloud native development environments"}
+
+	err := setupHelp(root, "xx")
+	require.NoError(t, err)
+
+	require.Equal(t, "Modern cloud native development environments", root.Short)
+}
+
+func TestWrapTextWrapsParagraphs(t *testing.T) {
+	w := wrapText("one two three four five six", 10)
+
+	require.Equal(t, "one two\nthree four\nfive six", w)
+}
+
+func TestWrapTextDoesNotWrapIndentedLines(t *testing.T) {
+	w := wrapText("one two three\n\n  shipyard run --profile minimal ./my-stack", 10)
+
+	require.Equal(t, "one two\nthree\n\n  shipyard run --profile minimal ./my-stack", w)
+}
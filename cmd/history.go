@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func newHistoryCmd(h clients.History) *cobra.Command {
+	var blueprint string
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the history of applied and destroyed blueprints",
+		Long:  "Show the history of applied and destroyed blueprints",
+		Example: `
+  # Show all history
+  shipyard history
+
+  # Show the history for a blueprint
+  shipyard history --blueprint github.com/shipyard-run/blueprints//consul-nomad
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := h.Read()
+			if err != nil {
+				return fmt.Errorf("Unable to read history: %s", err)
+			}
+
+			cmd.Printf("%-20s %-8s %-8s %-10s %-12s %-12s %s\n", "TIME", "COMMAND", "RESULT", "DURATION", "REF", "VARIABLES", "BLUEPRINT")
+
+			for _, e := range entries {
+				if blueprint != "" && !strings.Contains(e.Blueprint, blueprint) {
+					continue
+				}
+
+				cmd.Printf(
+					"%-20s %-8s %-8s %-10s %-12s %-12s %s\n",
+					e.Time.Local().Format("2006-01-02 15:04:05"),
+					e.Command,
+					e.Result,
+					e.Duration.Round(time.Second).String(),
+					valueOrDash(e.Ref),
+					valueOrDash(e.VariablesHash),
+					e.Blueprint,
+				)
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	historyCmd.Flags().StringVarP(&blueprint, "blueprint", "", "", "Only show history for blueprints matching the given source")
+	return historyCmd
+}
+
+// recordHistory logs the result of an apply or destroy command to the history,
+// usage is the resource usage of the environment and can be nil, pulls are the
+// images pulled by the command
+func recordHistory(h clients.History, command, source string, vars map[string]string, variablesFile, overlay string, start time.Time, usage *clients.ResourceUsage, pulls []clients.ImagePullRecord, cmdErr error) error {
+	if h == nil {
+		return nil
+	}
+
+	// store absolute paths so that the command can be re-run from any folder
+	if source != "" && utils.IsLocalFolder(source) {
+		source, _ = filepath.Abs(source)
+	}
+
+	if variablesFile != "" {
+		variablesFile, _ = filepath.Abs(variablesFile)
+	}
+
+	e := clients.HistoryEntry{
+		Time:          start,
+		Command:       command,
+		Blueprint:     source,
+		Ref:           clients.BlueprintRef(source),
+		VariablesHash: clients.HashVariables(vars, variablesFile),
+		Variables:     vars,
+		VariablesFile: variablesFile,
+		Overlay:       overlay,
+		Duration:      time.Since(start),
+		Result:        clients.HistoryResultSuccess,
+		Usage:         usage,
+		ImagePulls:    pulls,
+	}
+
+	if cmdErr != nil {
+		e.Result = clients.HistoryResultFailed
+		e.Error = cmdErr.Error()
+	}
+
+	return h.Log(e)
+}
+
+func valueOrDash(v string) string {
+	if v == "" {
+		return "-"
+	}
+
+	return v
+}
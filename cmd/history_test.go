@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupHistory(t *testing.T) (*clients.HistoryMock, *bytes.Buffer) {
+	mh := &clients.HistoryMock{}
+	mh.On("Read").Return([]clients.HistoryEntry{
+		{Time: time.Now(), Command: "apply", Blueprint: "github.com/shipyard-run/blueprints//consul?ref=v0.1.0", Ref: "v0.1.0", Result: clients.HistoryResultSuccess},
+		{Time: time.Now(), Command: "apply", Blueprint: "./local", Result: clients.HistoryResultFailed},
+	}, nil)
+
+	return mh, bytes.NewBufferString("")
+}
+
+func TestHistoryPrintsEntries(t *testing.T) {
+	mh, out := setupHistory(t)
+
+	hc := newHistoryCmd(mh)
+	hc.SetOut(out)
+
+	err := hc.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "v0.1.0")
+	assert.Contains(t, out.String(), "./local")
+}
+
+func TestHistoryFiltersByBlueprint(t *testing.T) {
+	mh, out := setupHistory(t)
+
+	hc := newHistoryCmd(mh)
+	hc.SetOut(out)
+	hc.Flags().Set("blueprint", "consul")
+
+	err := hc.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "v0.1.0")
+	assert.NotContains(t, out.String(), "./local")
+}
+
+func TestRecordHistoryLogsFailure(t *testing.T) {
+	mh := &clients.HistoryMock{}
+	mh.On("Log", mock.Anything).Return(nil)
+
+	err := recordHistory(mh, "apply", "./", map[string]string{"a": "b"}, "", "", time.Now(), nil, nil, fmt.Errorf("boom"))
+	assert.NoError(t, err)
+
+	e := mh.Calls[0].Arguments[0].(clients.HistoryEntry)
+	assert.Equal(t, clients.HistoryResultFailed, e.Result)
+	assert.Equal(t, "boom", e.Error)
+	assert.NotEmpty(t, e.VariablesHash)
+}
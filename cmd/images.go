@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var imagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "Export and import the images used by blueprints",
+	Long:  `Export and import the images used by blueprints, image bundles allow blueprints to be run on machines without network access`,
+}
+
+func newImagesExportCmd(ct clients.ContainerTasks) *cobra.Command {
+	var variables []string
+	var variablesFile string
+	var overlay string
+
+	exportCmd := &cobra.Command{
+		Use:   "export [blueprint] [bundle]",
+		Short: "Export the images used by a blueprint to a bundle",
+		Long: `Export all the images used by a blueprint, including the cluster node images, to a tar archive.
+The bundle can be imported on another machine with 'shipyard images import' or 'docker load'.
+Images which are not in the local cache are pulled before they are exported.`,
+		Example: `
+  # Export the images for the blueprint in the current folder
+  shipyard images export ./ bundle.tar
+
+  # Export the images for a blueprint with an overlay
+  shipyard images export --overlay staging-sim ./my-stack bundle.tar
+	`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vars := map[string]string{}
+			for _, v := range variables {
+				parts := strings.Split(v, "=")
+				if len(parts) == 2 {
+					vars[parts[0]] = parts[1]
+				}
+			}
+
+			config.SetOverlay(overlay)
+			defer config.SetOverlay("")
+
+			images, err := bundleImages(args[0], vars, variablesFile)
+			if err != nil {
+				return err
+			}
+
+			names := []string{}
+			for _, i := range images {
+				cmd.Printf("Pulling %s\n", i.Name)
+
+				err := ct.PullImage(i, false)
+				if err != nil {
+					return fmt.Errorf("Unable to pull image %s: %s", i.Name, err)
+				}
+
+				names = append(names, i.Name)
+			}
+
+			f, err := os.Create(args[1])
+			if err != nil {
+				return fmt.Errorf("Unable to create bundle %s: %s", args[1], err)
+			}
+			defer f.Close()
+
+			err = ct.ExportImages(names, f)
+			if err != nil {
+				return fmt.Errorf("Unable to export images: %s", err)
+			}
+
+			cmd.Println()
+			cmd.Printf("Exported %d images to %s\n", len(names), args[1])
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	exportCmd.Flags().StringSliceVarP(&variables, "var", "", nil, "Allows setting variables from the command line when reading images from a blueprint, e.g --var key=value. Can be specified multiple times")
+	exportCmd.Flags().StringVarP(&variablesFile, "vars-file", "", "", "Load variables from a location other than *.vars files in the blueprint folder. E.g --vars-file=./file.vars")
+	exportCmd.Flags().StringVarP(&overlay, "overlay", "", "", "Merge the overlay overrides/[name].hcl from the blueprint folder on top of the blueprint. E.g --overlay=staging-sim")
+
+	return exportCmd
+}
+
+func newImagesImportCmd(ct clients.ContainerTasks) *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import [bundle]",
+		Short: "Import the images from a bundle",
+		Long:  `Import the images from a bundle created with 'shipyard images export' into the local cache`,
+		Example: `
+  # Import the images and run the blueprint without pulling images
+  shipyard images import bundle.tar
+  shipyard run --offline ./my-stack
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("Unable to open bundle %s: %s", args[0], err)
+			}
+			defer f.Close()
+
+			images, err := ct.ImportImages(f)
+			if err != nil {
+				return fmt.Errorf("Unable to import images: %s", err)
+			}
+
+			for _, i := range images {
+				cmd.Printf("Imported %s\n", i)
+			}
+
+			cmd.Println()
+			cmd.Printf("Imported %d images from %s\n", len(images), args[0])
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	return importCmd
+}
+
+// bundleImages returns the images which are required to run the blueprint
+// without network access, this includes the default images used by the providers
+func bundleImages(path string, vars map[string]string, variablesFile string) ([]config.Image, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %s", path, err)
+	}
+
+	c, err := parseBlueprint(path, vars, variablesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// the image cache is always created by the engine
+	resources := append([]config.Resource{config.NewImageCache("docker-cache")}, c.Resources...)
+
+	images := []config.Image{}
+	for _, r := range resources {
+		for _, i := range providers.Images(r) {
+			// an archive can only contain a single platform for an image
+			exists := false
+			for _, e := range images {
+				if e.Name == i.Name {
+					exists = true
+				}
+			}
+
+			if !exists {
+				images = append(images, i)
+			}
+		}
+	}
+
+	return images, nil
+}
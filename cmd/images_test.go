@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupImagesExport(t *testing.T) (*mocks.MockContainerTasks, *bytes.Buffer) {
+	mt := &mocks.MockContainerTasks{}
+	mt.On("PullImage", mock.Anything, false).Return(nil)
+	mt.On("ExportImages", mock.Anything, mock.Anything).Return(nil)
+	mt.On("ImportImages", mock.Anything).Return([]string{"consul:1.10.0"}, nil)
+
+	return mt, bytes.NewBuffer(nil)
+}
+
+func TestBundleImagesReturnsProviderImages(t *testing.T) {
+	dir := config.CreateTestFiles(t, imagesBlueprint)
+
+	images, err := bundleImages(dir, nil, "")
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, i := range images {
+		names = append(names, i.Name)
+	}
+
+	assert.Len(t, names, 3)
+	assert.Contains(t, names, "consul:1.10.0")
+	assert.Contains(t, names, "shipyardrun/k3s:v1.22.4")
+}
+
+func TestImagesExportPullsAndExportsImages(t *testing.T) {
+	mt, out := setupImagesExport(t)
+	dir := config.CreateTestFiles(t, imagesBlueprint)
+	bundle := filepath.Join(t.TempDir(), "bundle.tar")
+
+	c := newImagesExportCmd(mt)
+	c.SetOut(out)
+	c.SetArgs([]string{dir, bundle})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	mt.AssertNumberOfCalls(t, "PullImage", 3)
+
+	images := getCalls(&mt.Mock, "ExportImages")[0].Arguments[0].([]string)
+	assert.Contains(t, images, "consul:1.10.0")
+	assert.FileExists(t, bundle)
+}
+
+func TestImagesExportReturnsErrorWhenPullFails(t *testing.T) {
+	mt, out := setupImagesExport(t)
+	removeOn(&mt.Mock, "PullImage")
+	mt.On("PullImage", mock.Anything, false).Return(fmt.Errorf("boom"))
+
+	dir := config.CreateTestFiles(t, imagesBlueprint)
+
+	c := newImagesExportCmd(mt)
+	c.SetOut(out)
+	c.SetArgs([]string{dir, filepath.Join(t.TempDir(), "bundle.tar")})
+
+	err := c.Execute()
+	assert.Error(t, err)
+
+	mt.AssertNotCalled(t, "ExportImages", mock.Anything, mock.Anything)
+}
+
+func TestImagesImportLoadsBundle(t *testing.T) {
+	mt, out := setupImagesExport(t)
+
+	bundle := filepath.Join(t.TempDir(), "bundle.tar")
+	err := ioutil.WriteFile(bundle, []byte("archive"), 0644)
+	assert.NoError(t, err)
+
+	c := newImagesImportCmd(mt)
+	c.SetOut(out)
+	c.SetArgs([]string{bundle})
+
+	err = c.Execute()
+	assert.NoError(t, err)
+
+	mt.AssertCalled(t, "ImportImages", mock.Anything)
+	assert.Contains(t, out.String(), "Imported consul:1.10.0")
+}
+
+var imagesBlueprint = `
+k8s_cluster "k3s" {
+  driver = "k3s"
+
+  network {
+    name = "network.cloud"
+  }
+}
+
+network "cloud" {
+  subnet = "10.5.0.0/16"
+}
+
+container "consul" {
+  image {
+    name = "consul:1.10.0"
+  }
+
+  network {
+    name = "network.cloud"
+  }
+}
+`
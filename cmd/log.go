@@ -1,20 +1,31 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
-	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io"
-	"math/rand"
 	"os"
 	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/docker/docker/api/types"
+	timetypes "github.com/docker/docker/api/types/time"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/fatih/color"
 	"github.com/hashicorp/go-hclog"
 	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/config"
@@ -22,9 +33,114 @@ import (
 	"github.com/shipyard-run/shipyard/pkg/utils"
 )
 
-func newLogCmd(engine shipyard.Engine, dc clients.Docker, stdout, stderr io.Writer) *cobra.Command {
+// logFlags are the flags for the log command
+type logFlags struct {
+	tail       string
+	since      string
+	timestamps bool
+	noFollow   bool
+	filter     string
+	pods       string
+	jobs       []string
+	output     string
+	dir        string
+}
+
+// apiTimestamps returns true when the timestamps added by the Docker and Kubernetes APIs
+// are needed, the timestamp of each line is a separate field in the JSON output
+func (f *logFlags) apiTimestamps() bool {
+	return f.timestamps || f.output == "json"
+}
+
+// nomadLogLineBytes is the average length of a log line used to calculate the offset
+// for --tail when reading Nomad allocation logs, the Nomad API can only offset by bytes
+const nomadLogLineBytes = 256
+
+// containerLogsOptions returns the Docker log options for the flags
+func (f *logFlags) containerLogsOptions() types.ContainerLogsOptions {
+	return types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     !f.noFollow,
+		Tail:       f.tail,
+		Since:      f.since,
+		Timestamps: f.apiTimestamps(),
+	}
+}
+
+// podLogOptions returns the Kubernetes log options for the flags
+func (f *logFlags) podLogOptions(container string) *v1.PodLogOptions {
+	o := &v1.PodLogOptions{
+		Container:  container,
+		Follow:     !f.noFollow,
+		Timestamps: f.apiTimestamps(),
+	}
+
+	if f.tail != "all" {
+		// the value has been checked when validating the flags
+		tail, _ := strconv.ParseInt(f.tail, 10, 64)
+		o.TailLines = &tail
+	}
+
+	if f.since != "" {
+		ts, _ := timetypes.GetTimestamp(f.since, time.Now())
+		sec, nsec, _ := timetypes.ParseTimestamps(ts, 0)
+
+		since := metav1.NewTime(time.Unix(sec, nsec))
+		o.SinceTime = &since
+	}
+
+	return o
+}
+
+// allocationLogOffset returns the number of bytes from the end of the
+// Nomad allocation logs which approximates the number of lines for --tail
+func (f *logFlags) allocationLogOffset() int64 {
+	if f.tail == "all" {
+		return 0
+	}
+
+	tail, _ := strconv.ParseInt(f.tail, 10, 64)
+	if tail == 0 {
+		// an offset of 0 returns the complete log, only read new lines
+		return 1
+	}
+
+	return tail * nomadLogLineBytes
+}
+
+// validate the flags, the Docker engine only reports invalid values when the logs are read
+func (f *logFlags) validate() error {
+	if f.tail != "all" {
+		if _, err := strconv.Atoi(f.tail); err != nil {
+			return fmt.Errorf("invalid value for --tail '%s', specify a number of lines or all", f.tail)
+		}
+	}
+
+	if f.output != "text" && f.output != "json" {
+		return fmt.Errorf("invalid value for --output '%s', specify text or json", f.output)
+	}
+
+	if f.filter != "" {
+		if _, err := regexp.Compile(f.filter); err != nil {
+			return fmt.Errorf("invalid value for --filter '%s': %s", f.filter, err)
+		}
+	}
+
+	if f.since != "" {
+		if _, err := timetypes.GetTimestamp(f.since, time.Now()); err != nil {
+			return fmt.Errorf("invalid value for --since '%s', specify a timestamp or a relative duration e.g. 10m", f.since)
+		}
+	}
+
+	return nil
+}
+
+func newLogCmd(engine shipyard.Engine, dc clients.Docker, kc clients.Kubernetes, nc clients.Nomad, pc clients.Plugins, stdout, stderr io.Writer) *cobra.Command {
+	flags := &logFlags{}
+
 	logCmd := &cobra.Command{
-		Use:     "log <command> ",
+		Use:     "log [resource]...",
 		Short:   "Tails logs for running shipyard resources",
 		Long:    "Tails logs for running shipyard resources",
 		Aliases: []string{"logs"},
@@ -34,12 +150,40 @@ func newLogCmd(engine shipyard.Engine, dc clients.Docker, stdout, stderr io.Writ
 
 	# Tail logs for a specific resource
 	shipyard log container.nginx
+
+	# Tail logs for all containers and the Kubernetes cluster dev
+	shipyard log 'container.*' k8s_cluster.dev
+
+	# Only show lines containing error or warn
+	shipyard log --filter '(?i)error|warn'
+
+	# Write the last 10 minutes of logs with timestamps and exit
+	shipyard log --since 10m --timestamps --no-follow
+
+	# Tail logs for the pods with the label app=vault in the Kubernetes cluster dev
+	shipyard log k8s_cluster.dev --pods app=vault
+
+	# Tail logs for the allocations of the job example in the Nomad cluster dev
+	shipyard log nomad_cluster.dev --jobs example
+
+	# Write the logs for all resources as JSON to a file for each resource in $HOME/.shipyard/logs/ci
+	shipyard log --output json --dir ci --no-follow
 	`,
 		Args:              cobra.ArbitraryArgs,
 		ValidArgsFunction: getResources,
-		RunE:              newLogCmdFunc(dc, stdout, stderr),
+		RunE:              newLogCmdFunc(dc, kc, nc, pc, stdout, stderr, flags),
 	}
 
+	logCmd.Flags().StringVarP(&flags.tail, "tail", "", "40", "Number of lines to show from the end of the logs, use all to show all lines")
+	logCmd.Flags().StringVarP(&flags.since, "since", "", "", "Show logs since a timestamp e.g. 2021-06-01T13:23:37Z, or a relative duration e.g. 10m")
+	logCmd.Flags().BoolVarP(&flags.timestamps, "timestamps", "", false, "Show the timestamp for each line")
+	logCmd.Flags().StringVarP(&flags.filter, "filter", "", "", "Only show lines matching the regular expression")
+	logCmd.Flags().BoolVarP(&flags.noFollow, "no-follow", "", false, "Write the current logs and exit rather than following the output")
+	logCmd.Flags().StringVarP(&flags.pods, "pods", "", "", "Show the logs for the pods matching the label selector rather than the nodes of Kubernetes clusters e.g. app=vault")
+	logCmd.Flags().StringSliceVarP(&flags.jobs, "jobs", "", nil, "Show the logs for the allocations of the jobs rather than the nodes of Nomad clusters")
+	logCmd.Flags().StringVarP(&flags.output, "output", "o", "text", "Output format for the logs [text, json], json writes an object for each line")
+	logCmd.Flags().StringVarP(&flags.dir, "dir", "", "", "Write the logs for each resource to a separate file in the folder, relative folders are created in $HOME/.shipyard/logs")
+
 	return logCmd
 }
 
@@ -53,57 +197,135 @@ var termColors = []color.Attribute{
 	color.FgWhite,
 }
 
-func getResources(cmd *cobra.Command, args []string, complete string) ([]string, cobra.ShellCompDirective) {
-	loggable, err := getLoggable()
-	if err != nil {
-		return []string{err.Error()}, cobra.ShellCompDirectiveNoFileComp
-	}
+// getResources returns the containers which can be logged for shell completion
+var getResources = completeEachArg(getLoggable)
 
-	return loggable, cobra.ShellCompDirectiveNoFileComp
+// logStream is an open log stream and the name which prefixes each line
+type logStream struct {
+	name   string
+	rc     io.ReadCloser
+	stdout io.Writer
+	stderr io.Writer
+	// raw streams are written as is, Docker log streams of containers
+	// without a TTY are multiplexed and need to be split
+	raw bool
+	// each line starts with the timestamp added by the API
+	timestamps bool
 }
 
-func newLogCmdFunc(dc clients.Docker, stdout, stderr io.Writer) func(cmd *cobra.Command, args []string) error {
+func newLogCmdFunc(dc clients.Docker, kc clients.Kubernetes, nc clients.Nomad, pc clients.Plugins, stdout, stderr io.Writer, flags *logFlags) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
+		err := flags.validate()
+		if err != nil {
+			return err
+		}
+
 		log := hclog.Default()
 		sigs := make(chan os.Signal, 1)
 		signal.Notify(sigs, os.Interrupt)
 		waitGroup := sync.WaitGroup{}
 
-		var loggable []string
+		targets, err := selectLogTargets(args)
+		if err != nil {
+			return err
+		}
+
+		format := logFormat{json: flags.output == "json", plain: flags.dir != ""}
+		if flags.filter != "" {
+			// the expression has been checked when validating the flags
+			format.filter = regexp.MustCompile(flags.filter)
+		}
+
+		ctx := context.Background()
+
+		streams := []logStream{}
+		workloads := map[string]bool{}
+
+		for _, t := range targets {
+			switch {
+			case flags.pods != "" && strings.HasPrefix(t.resource, string(config.TypeK8sCluster)+"."):
+				// the logs for the pods replace the logs for the nodes of the cluster
+				if !workloads[t.resource] {
+					workloads[t.resource] = true
+					streams = append(streams, podLogStreams(ctx, kc, t.resource, flags, stdout, log)...)
+				}
+			case len(flags.jobs) > 0 && strings.HasPrefix(t.resource, string(config.TypeNomadCluster)+"."):
+				if !workloads[t.resource] {
+					workloads[t.resource] = true
+					streams = append(streams, allocationLogStreams(ctx, nc, t.resource, flags, stdout, stderr, log)...)
+				}
+			case t.plugin != nil:
+				rc, err := pc.Logs(t.plugin, !flags.noFollow, flags.tail)
+				if err != nil {
+					log.Error("Unable to get logs for resource", "resource", t.resource, "error", err)
+					continue
+				}
 
-		if len(args) == 1 {
-			loggable = []string{args[0]}
-		} else {
-			var err error
-			loggable, err = getLoggable()
+				// plugins write plain text logs
+				streams = append(streams, logStream{name: t.container, rc: rc, stdout: stdout, stderr: stderr, raw: true})
+			default:
+				rc, err := dc.ContainerLogs(
+					ctx,
+					t.container,
+					flags.containerLogsOptions(),
+				)
+
+				if err != nil {
+					log.Error("Unable to get logs for container", "error", err)
+					continue
+				}
+
+				// the log stream is only multiplexed when the container does not have a TTY
+				tty := false
+				if info, err := dc.ContainerInspect(ctx, t.container); err == nil && info.Config != nil {
+					tty = info.Config.Tty
+				}
+
+				streams = append(streams, logStream{name: t.container, rc: rc, stdout: stdout, stderr: stderr, raw: tty, timestamps: flags.apiTimestamps()})
+			}
+		}
+
+		// write the logs for each stream to a file rather than stdout and stderr
+		if flags.dir != "" {
+			dir := flags.dir
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(utils.LogsDir(), dir)
+			}
+
+			files, err := newLogFiles(dir, flags.output)
 			if err != nil {
 				return err
 			}
-		}
+			defer files.Close()
 
-		ctx := context.Background()
+			for i := range streams {
+				w, err := files.Writer(streams[i].name)
+				if err != nil {
+					return err
+				}
 
-		for _, r := range loggable {
-			rc, err := dc.ContainerLogs(
-				ctx,
-				r,
-				types.ContainerLogsOptions{
-					ShowStdout: true,
-					ShowStderr: true,
-					Follow:     true,
-					Tail:       "40",
-				},
-			)
-
-			if err == nil {
-				waitGroup.Add(1)
-				go func(rc io.ReadCloser, name string, c color.Attribute, log hclog.Logger) {
-					writeLogOutput(rc, stdout, stderr, name, c, log)
-					waitGroup.Done()
-				}(rc, r, getRandomColor(), log)
-			} else {
-				log.Error("Unable to get logs for container", "error", err)
+				streams[i].stdout = w
+				streams[i].stderr = w
 			}
+
+			fmt.Fprintf(stdout, "Writing logs to %s\n", dir)
+		}
+
+		for _, s := range streams {
+			f := format
+			f.timestamps = s.timestamps
+
+			waitGroup.Add(1)
+			go func(s logStream, c color.Attribute, f logFormat, log hclog.Logger) {
+				writeLogOutput(s.rc, s.stdout, s.stderr, s.name, c, f, s.raw, log)
+				waitGroup.Done()
+			}(s, getResourceColor(s.name), f, log)
+		}
+
+		// when not following, return once the current logs have been written
+		if flags.noFollow {
+			waitGroup.Wait()
+			return nil
 		}
 
 		// send an interrupt when the waitGroup is done
@@ -120,9 +342,155 @@ func newLogCmdFunc(dc clients.Docker, stdout, stderr io.Writer) func(cmd *cobra.
 	}
 }
 
+// podLogStreams opens the log streams for the containers of the pods in the Kubernetes
+// cluster which match the label selector
+func podLogStreams(ctx context.Context, kc clients.Kubernetes, resource string, flags *logFlags, stdout io.Writer, log hclog.Logger) []logStream {
+	_, conf, _, err := utils.CreateKubeConfigPath(strings.TrimPrefix(resource, string(config.TypeK8sCluster)+"."))
+	if err != nil {
+		log.Error("Unable to find the Kubernetes config", "cluster", resource, "error", err)
+		return nil
+	}
+
+	kc, err = kc.SetConfig(conf)
+	if err != nil {
+		log.Error("Unable to create Kubernetes client", "cluster", resource, "error", err)
+		return nil
+	}
+
+	pods, err := kc.GetPods(flags.pods)
+	if err != nil {
+		log.Error("Unable to list pods", "cluster", resource, "selector", flags.pods, "error", err)
+		return nil
+	}
+
+	if len(pods.Items) == 0 {
+		log.Warn("No pods match the selector", "cluster", resource, "selector", flags.pods)
+	}
+
+	streams := []logStream{}
+	for _, p := range pods.Items {
+		for _, c := range p.Spec.Containers {
+			rc, err := kc.StreamPodLogs(ctx, p.Name, p.Namespace, flags.podLogOptions(c.Name))
+			if err != nil {
+				log.Error("Unable to get logs for pod", "pod", p.Name, "container", c.Name, "error", err)
+				continue
+			}
+
+			// the Kubernetes API combines stdout and stderr
+			streams = append(streams, logStream{
+				name:       fmt.Sprintf("%s/%s", p.Name, c.Name),
+				rc:         rc,
+				stdout:     stdout,
+				stderr:     stdout,
+				raw:        true,
+				timestamps: flags.apiTimestamps(),
+			})
+		}
+	}
+
+	return streams
+}
+
+// allocationLogStreams opens the stdout and stderr log streams for the tasks in the
+// running allocations of the jobs in the Nomad cluster
+func allocationLogStreams(ctx context.Context, nc clients.Nomad, resource string, flags *logFlags, stdout, stderr io.Writer, log hclog.Logger) []logStream {
+	if flags.since != "" || flags.timestamps {
+		log.Warn("--since and --timestamps are not supported for Nomad allocation logs", "cluster", resource)
+	}
+
+	conf, _, err := utils.GetClusterConfig(resource)
+	if err != nil {
+		log.Error("Unable to read the cluster config", "cluster", resource, "error", err)
+		return nil
+	}
+
+	err = nc.SetConfig(conf, string(utils.LocalContext))
+	if err != nil {
+		log.Error("Unable to create Nomad client", "cluster", resource, "error", err)
+		return nil
+	}
+
+	streams := []logStream{}
+	for _, j := range flags.jobs {
+		allocs, err := nc.JobAllocations(j)
+		if err != nil {
+			log.Error("Unable to get allocations for job", "cluster", resource, "job", j, "error", err)
+			continue
+		}
+
+		if len(allocs) == 0 {
+			log.Warn("No running allocations for job", "cluster", resource, "job", j)
+		}
+
+		// sort the allocations so the streams are always opened in the same order
+		ids := []string{}
+		for id := range allocs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			for _, task := range allocs[id] {
+				name := fmt.Sprintf("%s/%s/%s", j, shortAllocID(id), task)
+
+				for _, lt := range []string{"stdout", "stderr"} {
+					rc, err := nc.AllocationLogs(ctx, id, task, lt, !flags.noFollow, flags.allocationLogOffset())
+					if err != nil {
+						log.Error("Unable to get logs for allocation", "allocation", id, "task", task, "error", err)
+						continue
+					}
+
+					out := stdout
+					if lt == "stderr" {
+						out = stderr
+					}
+
+					streams = append(streams, logStream{name: name, rc: rc, stdout: out, stderr: out, raw: true})
+				}
+			}
+		}
+	}
+
+	return streams
+}
+
+// shortAllocID returns the short form of the allocation id used by the Nomad CLI
+func shortAllocID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+
+	return id
+}
+
+// logTarget is a container which logs can be read from and
+// the resource which created it e.g. k8s_cluster.dev
+type logTarget struct {
+	resource  string
+	container string
+	// plugin is set for resources provided by plugins, the plugin
+	// returns the logs rather than Docker
+	plugin *config.PluginResource
+}
+
 // if this methods returns and error, it will get returned as shell-completion data
 // otherwise fmt.println() gets lost
 func getLoggable() ([]string, error) {
+	targets, err := getLogTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	loggable := []string{}
+	for _, t := range targets {
+		loggable = append(loggable, t.container)
+	}
+
+	return loggable, nil
+}
+
+// getLogTargets returns the containers for the resources in the state file which can be logged
+func getLogTargets() ([]logTarget, error) {
 	// get the list of resources that can be logged
 	c := config.New()
 	err := c.FromJSON(utils.StatePath())
@@ -130,82 +498,279 @@ func getLoggable() ([]string, error) {
 		return nil, fmt.Errorf("unable to load state file, check you have running resources: %s", err)
 	}
 
-	// if an argument is provided, only tail logs for that resource
-	// first validate that the resource exists
 	resources := c.Resources
 
-	loggable := []string{}
+	targets := []logTarget{}
 	for _, r := range resources {
-		switch r.Info().Type {
-		case config.TypeContainer:
-			if !r.Info().Disabled {
-				loggable = append(loggable, utils.FQDN(r.Info().Name, string(r.Info().Type)))
-			}
-		case config.TypeK8sCluster:
-			if !r.Info().Disabled {
-				loggable = append(loggable, fmt.Sprintf("%s.%s", "server", utils.FQDN(r.Info().Name, string(r.Info().Type))))
-			}
-		case config.TypeNomadCluster:
-			if !r.Info().Disabled {
-				loggable = append(loggable, fmt.Sprintf("%s.%s", "server", utils.FQDN(r.Info().Name, string(r.Info().Type))))
-
-				// add the client nodes
-				nomad := r.(*config.NomadCluster)
-				for n := 0; n < nomad.ClientNodes; n++ {
-					loggable = append(loggable, fmt.Sprintf("%d.%s.%s", n+1, "client", utils.FQDN(r.Info().Name, string(r.Info().Type))))
-				}
-			}
-		case config.TypeSidecar:
-			if !r.Info().Disabled {
-				loggable = append(loggable, utils.FQDN(r.Info().Name, string(r.Info().Type)))
-			}
-		case config.TypeK8sIngress:
-			if !r.Info().Disabled {
-				loggable = append(loggable, utils.FQDN(r.Info().Name, string(r.Info().Type)))
+		if p, ok := r.(*config.PluginResource); ok && !p.Disabled {
+			id := fmt.Sprintf("%s.%s", p.Type, p.Name)
+			targets = append(targets, logTarget{resource: id, container: id, plugin: p})
+			continue
+		}
+
+		for _, c := range config.LogContainers(r) {
+			targets = append(targets, logTarget{
+				resource:  fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name),
+				container: c,
+			})
+		}
+	}
+
+	return targets, nil
+}
+
+// selectLogTargets returns the containers matching the given names, a name can be a resource
+// e.g. container.consul, a container e.g. consul.container.shipyard.run, or a glob pattern
+// matching either e.g. 'container.*'. Names which are not patterns and do not match a resource
+// in the state are returned as container names.
+func selectLogTargets(names []string) ([]logTarget, error) {
+	// when the state can not be read the names are used as container names
+	targets, err := getLogTargets()
+	if len(names) == 0 {
+		return targets, err
+	}
+
+	selected := []logTarget{}
+	added := map[string]bool{}
+
+	for _, n := range names {
+		matched := false
+
+		for _, t := range targets {
+			rm, err := path.Match(n, t.resource)
+			if err != nil {
+				return nil, fmt.Errorf("invalid resource pattern '%s': %s", n, err)
 			}
-		case config.TypeNomadIngress:
-			if !r.Info().Disabled {
-				loggable = append(loggable, utils.FQDN(r.Info().Name, string(r.Info().Type)))
+
+			cm, _ := path.Match(n, t.container)
+			if !rm && !cm && n != strings.TrimSuffix(t.container, ".shipyard.run") {
+				continue
 			}
-		case config.TypeContainerIngress:
-			if !r.Info().Disabled {
-				loggable = append(loggable, utils.FQDN(r.Info().Name, string(r.Info().Type)))
+
+			matched = true
+			if !added[t.container] {
+				added[t.container] = true
+				selected = append(selected, t)
 			}
-		case config.TypeImageCache:
-			loggable = append(loggable, utils.FQDN(r.Info().Name, string(r.Info().Type)))
+		}
+
+		if matched {
+			continue
+		}
+
+		if strings.ContainsAny(n, "*?[") {
+			return nil, fmt.Errorf("no resources match the pattern '%s'", n)
+		}
+
+		if !added[n] {
+			added[n] = true
+			selected = append(selected, logTarget{container: n})
 		}
 	}
-	return loggable, nil
+
+	return selected, nil
+}
+
+// getResourceColor returns the color for the resource, the color is generated
+// from the name so that a resource always has the same color
+func getResourceColor(name string) color.Attribute {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+
+	return termColors[h.Sum32()%uint32(len(termColors))]
+}
+
+// logFormat defines how the lines of a log stream are written
+type logFormat struct {
+	// only lines matching the filter are written
+	filter *regexp.Regexp
+	// write a JSON object for each line
+	json bool
+	// the lines start with the timestamp added by the API
+	timestamps bool
+	// write the lines without the name of the stream or colors,
+	// used when each stream is written to a separate file
+	plain bool
+}
+
+// logEntry is the JSON object written for each line with --output json
+type logEntry struct {
+	Resource  string `json:"resource"`
+	Stream    string `json:"stream"`
+	Timestamp string `json:"timestamp"`
+	Message   string `json:"message"`
 }
 
-func getRandomColor() color.Attribute {
-	return termColors[rand.Intn(len(termColors)-1)]
+// writeLogOutput writes the Docker log stream, the stream of containers without a TTY
+// multiplexes stdout and stderr with a header for each frame, containers with a TTY
+// write the raw output. When a filter is set only the lines matching the filter are written
+func writeLogOutput(rc io.ReadCloser, stdout, stderr io.Writer, name string, c color.Attribute, format logFormat, tty bool, log hclog.Logger) {
+	name = strings.TrimSuffix(name, ".shipyard.run")
+
+	var cw *color.Color
+	if !format.plain {
+		cw = color.New(c)
+	}
+
+	out := &logLineWriter{w: stdout, name: name, stream: "stdout", color: cw, format: format}
+	errOut := &logLineWriter{w: stderr, name: name, stream: "stderr", color: cw, format: format}
+
+	var err error
+	if tty {
+		_, err = io.Copy(out, rc)
+	} else {
+		_, err = stdcopy.StdCopy(out, errOut, rc)
+	}
+
+	out.Flush()
+	errOut.Flush()
+
+	if err != nil {
+		log.Error("Unable to read from log stream", "name", name, "error", err)
+	}
+}
+
+// logLineWriter writes complete lines prefixed with the name of the container,
+// partial lines are buffered until the rest of the line has been read so that
+// the output of different containers is never interleaved within a line
+type logLineWriter struct {
+	w      io.Writer
+	name   string
+	stream string
+	color  *color.Color
+	format logFormat
+	buf    []byte
 }
 
-func writeLogOutput(rc io.ReadCloser, stdout, stderr io.Writer, name string, c color.Attribute, log hclog.Logger) {
-	hdr := make([]byte, 8)
-	colorWriter := color.New(c)
+func (l *logLineWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
 
 	for {
-		_, err := rc.Read(hdr)
-		if err != nil {
-			log.Error("Unable to read from log stream", "name", name, "error", err)
-			return
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
 		}
 
-		var w io.Writer
-		switch hdr[0] {
-		case 1:
-			w = stdout
-		default:
-			w = stderr
+		l.writeLine(string(l.buf[:i]))
+		l.buf = l.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any partial line remaining at the end of the stream
+func (l *logLineWriter) Flush() {
+	if len(l.buf) == 0 {
+		return
+	}
+
+	l.writeLine(string(l.buf))
+	l.buf = nil
+}
+
+func (l *logLineWriter) writeLine(line string) {
+	// TTY output uses carriage returns
+	line = strings.TrimRight(line, "\r")
+
+	if l.format.json {
+		l.writeJSON(line)
+		return
+	}
+
+	if l.format.filter != nil && !l.format.filter.MatchString(line) {
+		return
+	}
+
+	if l.color == nil {
+		fmt.Fprintln(l.w, line)
+		return
+	}
+
+	l.color.Fprintf(l.w, "[%s]   %s\n", l.name, line)
+}
+
+func (l *logLineWriter) writeJSON(line string) {
+	ts := time.Now().UTC()
+
+	// the APIs prefix the line with the timestamp e.g. 2021-06-01T13:23:37.123456789Z
+	if l.format.timestamps {
+		if i := strings.IndexByte(line, ' '); i > 0 {
+			if t, err := time.Parse(time.RFC3339Nano, line[:i]); err == nil {
+				ts = t
+				line = line[i+1:]
+			}
 		}
+	}
+
+	if l.format.filter != nil && !l.format.filter.MatchString(line) {
+		return
+	}
+
+	// encode to a buffer so that each object is a single write
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(logEntry{Resource: l.name, Stream: l.stream, Timestamp: ts.Format(time.RFC3339Nano), Message: line})
 
-		count := binary.BigEndian.Uint32(hdr[4:])
-		dat := make([]byte, count)
-		_, err = rc.Read(dat)
+	l.w.Write(buf.Bytes())
+}
 
-		name = strings.TrimSuffix(name, ".shipyard.run")
-		colorWriter.Fprintf(w, "[%s]   %s", name, string(dat))
+// logFiles creates a file in the folder for each log stream, streams with the same
+// name such as the stdout and stderr logs of a Nomad task are written to the same file
+type logFiles struct {
+	dir   string
+	ext   string
+	files map[string]*logFile
+}
+
+func newLogFiles(dir, output string) (*logFiles, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create folder for logs %s: %s", dir, err)
+	}
+
+	ext := ".log"
+	if output == "json" {
+		ext = ".json"
 	}
+
+	return &logFiles{dir: dir, ext: ext, files: map[string]*logFile{}}, nil
+}
+
+// Writer returns the writer for the file of the stream with the given name
+func (l *logFiles) Writer(name string) (io.Writer, error) {
+	name = strings.TrimSuffix(name, ".shipyard.run")
+	if f, ok := l.files[name]; ok {
+		return f, nil
+	}
+
+	file := filepath.Join(l.dir, strings.ReplaceAll(name, "/", "_")+l.ext)
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create log file %s: %s", file, err)
+	}
+
+	l.files[name] = &logFile{f: f}
+
+	return l.files[name], nil
+}
+
+// Close all the files
+func (l *logFiles) Close() {
+	for _, f := range l.files {
+		f.f.Close()
+	}
+}
+
+// logFile is a file which can be written to by multiple log streams
+type logFile struct {
+	mutex sync.Mutex
+	f     *os.File
+}
+
+func (l *logFile) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.f.Write(p)
 }
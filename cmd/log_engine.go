@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+func newLogEngineCmd(current *utils.LogFile) *cobra.Command {
+	var list bool
+	var tail string
+
+	engineCmd := &cobra.Command{
+		Use:   "engine [file]",
+		Short: "Show the log files written by previous runs of Shipyard",
+		Long: `Show the log files written by previous runs of Shipyard.
+	The debug logs for every run are written to a file in $HOME/.shipyard/logs/engine,
+	the newest files are kept and files are rotated when they reach 10MB`,
+		Example: `
+  # Show the logs for the previous run
+  shipyard logs engine
+
+  # List the log files
+  shipyard logs engine --list
+
+  # Show the last 100 lines of a log file
+  shipyard logs engine --tail 100 engine-20220301-101500-4242.log
+	`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if tail != "all" {
+				if _, err := strconv.Atoi(tail); err != nil {
+					return newCommandError(ErrorCodeUsage, "Invalid value for --tail '%s', specify a number of lines or all", tail)
+				}
+			}
+
+			files, err := engineLogFiles(current)
+			if err != nil {
+				return fmt.Errorf("Unable to read engine logs: %s", err)
+			}
+
+			if list {
+				for i := len(files) - 1; i >= 0; i-- {
+					size := int64(0)
+					if fi, err := os.Stat(files[i]); err == nil {
+						size = fi.Size()
+					}
+
+					cmd.Printf("%-45s %s\n", filepath.Base(files[i]), units.HumanSize(float64(size)))
+				}
+
+				return nil
+			}
+
+			if len(files) == 0 {
+				return fmt.Errorf("No engine logs found in %s", utils.EngineLogsDir())
+			}
+
+			// default to the newest file
+			file := files[len(files)-1]
+			if len(args) == 1 {
+				file = filepath.Join(utils.EngineLogsDir(), filepath.Base(args[0]))
+			}
+
+			d, err := ioutil.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("Unable to read engine log %s: %s", filepath.Base(file), err)
+			}
+
+			cmd.Print(tailLines(string(d), tail))
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	engineCmd.Flags().BoolVarP(&list, "list", "", false, "List the log files from newest to oldest")
+	engineCmd.Flags().StringVarP(&tail, "tail", "", "all", "Number of lines to show from the end of the log, use all to show all lines")
+
+	return engineCmd
+}
+
+// engineLogFiles returns the engine log files from oldest to newest, the
+// file for the current process is not included
+func engineLogFiles(current *utils.LogFile) ([]string, error) {
+	files, err := utils.LogFiles(utils.EngineLogsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil || current.Path() == "" {
+		return files, nil
+	}
+
+	previous := []string{}
+	for _, f := range files {
+		if f != current.Path() {
+			previous = append(previous, f)
+		}
+	}
+
+	return previous, nil
+}
+
+// tailLines returns the last n lines of s, all lines are returned when n is all
+func tailLines(s, n string) string {
+	if n == "all" {
+		return s
+	}
+
+	count, _ := strconv.Atoi(n)
+	lines := strings.SplitAfter(s, "\n")
+
+	// the output ends with a new line which leaves an empty element
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if count < len(lines) {
+		lines = lines[len(lines)-count:]
+	}
+
+	return strings.Join(lines, "")
+}
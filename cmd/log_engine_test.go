@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+func setupLogEngine(t *testing.T) (*utils.LogFile, *bytes.Buffer) {
+	utils.SetShipyardHome(t.TempDir())
+	t.Cleanup(func() { utils.SetShipyardHome("") })
+
+	dir := utils.EngineLogsDir()
+	os.MkdirAll(dir, os.ModePerm)
+
+	for i, f := range []string{"engine-20220301-100000-1.log", "engine-20220302-100000-2.log"} {
+		p := filepath.Join(dir, f)
+		ioutil.WriteFile(p, []byte(f+" line 1\n"+f+" line 2\n"), 0644)
+
+		mt := time.Now().Add(time.Duration(i-10) * time.Hour)
+		os.Chtimes(p, mt, mt)
+	}
+
+	// the log file for the current process
+	current := utils.NewLogFile(dir, 1024, 10)
+	current.Write([]byte("current\n"))
+	t.Cleanup(func() { current.Close() })
+
+	return current, bytes.NewBuffer([]byte{})
+}
+
+func TestLogEngineShowsPreviousRun(t *testing.T) {
+	current, out := setupLogEngine(t)
+
+	c := newLogEngineCmd(current)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	assert.Equal(t, "engine-20220302-100000-2.log line 1\nengine-20220302-100000-2.log line 2\n", out.String())
+}
+
+func TestLogEngineShowsTailOfFile(t *testing.T) {
+	current, out := setupLogEngine(t)
+
+	c := newLogEngineCmd(current)
+	c.SetOut(out)
+	c.SetArgs([]string{"--tail", "1", "engine-20220301-100000-1.log"})
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	assert.Equal(t, "engine-20220301-100000-1.log line 2\n", out.String())
+}
+
+func TestLogEngineListsFiles(t *testing.T) {
+	current, out := setupLogEngine(t)
+
+	c := newLogEngineCmd(current)
+	c.SetOut(out)
+	c.SetArgs([]string{"--list"})
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	assert.Regexp(t, "(?s)engine-20220302-100000-2.log.*engine-20220301-100000-1.log", out.String())
+	assert.NotContains(t, out.String(), filepath.Base(current.Path()))
+}
+
+func TestLogEngineReturnsErrorWhenNoLogs(t *testing.T) {
+	utils.SetShipyardHome(t.TempDir())
+	t.Cleanup(func() { utils.SetShipyardHome("") })
+
+	c := newLogEngineCmd(nil)
+	c.SetOut(bytes.NewBuffer([]byte{}))
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
+
+func TestLogFormatFromArgs(t *testing.T) {
+	assert.Equal(t, "json", logFormatFromArgs([]string{"run", "--log-format=json", "./blueprint"}))
+	assert.Equal(t, "json", logFormatFromArgs([]string{"--log-format", "json", "run"}))
+	assert.Equal(t, "text", logFormatFromArgs([]string{"run", "--", "--log-format=json"}))
+	assert.Equal(t, "text", logFormatFromArgs([]string{"run"}))
+}
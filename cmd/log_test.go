@@ -3,21 +3,35 @@ package cmd
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/fatih/color"
+	"github.com/hashicorp/go-hclog"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 )
 
 const (
 	logStdOut = 1
-	logStdErr = 0
+	logStdErr = 2
 )
 
 type testWriter struct {
@@ -40,6 +54,12 @@ func (tw *testWriter) Write(p []byte) (n int, err error) {
 }
 
 func setupLog(t *testing.T, logStream int) (*cobra.Command, *mocks.MockDocker, *bytes.Buffer, *bytes.Buffer) {
+	lc, md, _, _, stdout, stderr := setupLogWithClients(t, logStream)
+
+	return lc, md, stdout, stderr
+}
+
+func setupLogWithClients(t *testing.T, logStream int) (*cobra.Command, *mocks.MockDocker, *clients.MockKubernetes, *mocks.MockNomad, *bytes.Buffer, *bytes.Buffer) {
 	// setup the statefile
 	t.Cleanup(setupState(logState))
 
@@ -51,6 +71,7 @@ func setupLog(t *testing.T, logStream int) (*cobra.Command, *mocks.MockDocker, *
 
 	md := &mocks.MockDocker{}
 	md.On("ServerVersion", mock.Anything).Return(types.Version{}, nil)
+	md.On("ContainerInspect", mock.Anything, mock.Anything).Return(types.ContainerJSON{Config: &container.Config{}}, nil)
 
 	md.On("ContainerLogs", mock.Anything, mock.Anything, mock.Anything).Once().Return(
 		io.NopCloser(bytes.NewBuffer(log)),
@@ -82,15 +103,51 @@ func setupLog(t *testing.T, logStream int) (*cobra.Command, *mocks.MockDocker, *
 		nil,
 	)
 
-	lc := newLogCmd(nil, md, stdout, stderr)
+	mk := &clients.MockKubernetes{}
+	mk.On("SetConfig", mock.Anything).Return(nil)
+	mk.On("GetPods", mock.Anything).Return(&v1.PodList{Items: []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "vault-0", Namespace: "default"},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "vault"}, {Name: "agent"}}},
+		},
+	}}, nil)
+	for _, c := range []string{"vault", "agent"} {
+		mk.On("StreamPodLogs", mock.Anything, "vault-0", "default", podLogContainer(c)).Return(
+			io.NopCloser(bytes.NewBufferString(fmt.Sprintf("%s log\n", c))),
+			nil,
+		)
+	}
+
+	mn := &mocks.MockNomad{}
+	mn.On("SetConfig", mock.Anything, mock.Anything).Return(nil)
+	mn.On("JobAllocations", mock.Anything).Return(map[string][]string{"da975cd1-8b04-6bce-9d5c-03e47353768c": []string{"web"}}, nil)
+
+	for _, lt := range []string{"stdout", "stderr"} {
+		mn.On("AllocationLogs", mock.Anything, "da975cd1-8b04-6bce-9d5c-03e47353768c", "web", lt, mock.Anything, mock.Anything).Return(
+			io.NopCloser(bytes.NewBufferString(fmt.Sprintf("web %s\n", lt))),
+			nil,
+		)
+	}
 
-	return lc, md, stdout.Buffer, stderr.Buffer
+	lc := newLogCmd(nil, md, mk, mn, &mocks.MockPlugins{}, stdout, stderr)
+
+	return lc, md, mk, mn, stdout.Buffer, stderr.Buffer
+}
+
+// podLogContainer matches the pod log options for the given container
+func podLogContainer(name string) interface{} {
+	return mock.MatchedBy(func(o *v1.PodLogOptions) bool { return o.Container == name })
 }
 
 // createLogOutput creates a byte array that is formatted as a docker log
 func createLogOutput(logStream int) []byte {
+	return createLogFrames(logStream, logLines...)
+}
+
+// createLogFrames creates a multiplexed docker log stream with a frame for each of the given values
+func createLogFrames(logStream int, frames ...string) []byte {
 	out := []byte{}
-	for _, line := range logLines {
+	for _, line := range frames {
 		hdr := make([]byte, 8)
 
 		// stdout
@@ -155,6 +212,212 @@ func TestLogWithSpecificResourceCallsDockerLog(t *testing.T) {
 //	md.AssertNumberOfCalls(t, "ContainerLogs", 0)
 //}
 
+func TestLogWithFlagsCallsDockerLogWithOptions(t *testing.T) {
+	lc, md, _, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"consul.container.shipyard.run", "--tail", "all", "--since", "10m", "--timestamps", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	logOptions := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     false,
+		Tail:       "all",
+		Since:      "10m",
+		Timestamps: true,
+	}
+
+	md.AssertCalled(t, "ContainerLogs", mock.Anything, "consul.container.shipyard.run", logOptions)
+}
+
+func TestLogWithNoFollowWritesLogsAndReturns(t *testing.T) {
+	lc, _, stdout, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, stdout.String(), "[consul.container]   [16:10:20] [main/INFO]: Applying mixin: R1_17.MixinPersistentStateManager...")
+}
+
+func TestLogStreamsPluginResourceLogs(t *testing.T) {
+	t.Cleanup(setupState(pluginLogState))
+
+	stdout := newTestWriter()
+	stderr := newTestWriter()
+
+	mp := &mocks.MockPlugins{}
+	mp.On("Logs", mock.Anything, false, "40").Return(io.NopCloser(bytes.NewBufferString("vm booted\n")), nil)
+
+	lc := newLogCmd(nil, &mocks.MockDocker{}, &clients.MockKubernetes{}, &mocks.MockNomad{}, mp, stdout, stderr)
+	lc.SetArgs([]string{"--no-follow"})
+
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, stdout.Buffer.String(), "[firecracker_vm.dev]")
+	require.Contains(t, stdout.Buffer.String(), "vm booted")
+}
+
+var pluginLogState = `
+{
+ "resources": [
+    {
+      "name": "dev",
+      "type": "firecracker_vm",
+      "status": "applied",
+      "plugin": "firecracker",
+      "attributes": {
+        "memory": 1024
+      }
+    }
+ ]
+}
+`
+
+func TestLogWithInvalidTailReturnsError(t *testing.T) {
+	lc, md, _, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"--tail", "lots"})
+	err := lc.Execute()
+	require.Error(t, err)
+
+	md.AssertNotCalled(t, "ContainerLogs", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLogWithInvalidSinceReturnsError(t *testing.T) {
+	lc, md, _, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"--since", "yesterday"})
+	err := lc.Execute()
+	require.Error(t, err)
+
+	md.AssertNotCalled(t, "ContainerLogs", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLogWithPatternCallsDockerLogForMatchingResources(t *testing.T) {
+	lc, md, _, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"container.*", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	// disabled resources are not in the state
+	md.AssertNumberOfCalls(t, "ContainerLogs", 1)
+	md.AssertCalled(t, "ContainerLogs", mock.Anything, "consul.container.shipyard.run", mock.Anything)
+}
+
+func TestLogWithMultipleResourcesCallsDockerLogForEach(t *testing.T) {
+	lc, md, _, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"container.consul", "nomad_cluster.dev", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	md.AssertNumberOfCalls(t, "ContainerLogs", 4)
+	md.AssertCalled(t, "ContainerLogs", mock.Anything, "consul.container.shipyard.run", mock.Anything)
+	md.AssertCalled(t, "ContainerLogs", mock.Anything, "server.dev.nomad-cluster.shipyard.run", mock.Anything)
+	md.AssertCalled(t, "ContainerLogs", mock.Anything, "1.client.dev.nomad-cluster.shipyard.run", mock.Anything)
+	md.AssertCalled(t, "ContainerLogs", mock.Anything, "2.client.dev.nomad-cluster.shipyard.run", mock.Anything)
+}
+
+func TestLogWithPatternMatchingNoResourcesReturnsError(t *testing.T) {
+	lc, md, _, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"registry.*"})
+	err := lc.Execute()
+	require.Error(t, err)
+
+	md.AssertNotCalled(t, "ContainerLogs", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLogWithFilterOnlyWritesMatchingLines(t *testing.T) {
+	lc, _, stdout, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"container.consul", "--filter", "Mixin(NbtTag|ScreenHandler)", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, stdout.String(), "[consul.container]   [16:10:20] [main/INFO]: Applying mixin: R1_17.MixinNbtTag...")
+	require.Contains(t, stdout.String(), "[consul.container]   [16:10:20] [main/INFO]: Applying mixin: R1_17.MixinScreenHandler...")
+	require.NotContains(t, stdout.String(), "MixinBlockEntity")
+}
+
+func TestLogWithInvalidFilterReturnsError(t *testing.T) {
+	lc, md, _, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"--filter", "Mixin("})
+	err := lc.Execute()
+	require.Error(t, err)
+
+	md.AssertNotCalled(t, "ContainerLogs", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLogWritesTTYLogWithoutDemultiplexing(t *testing.T) {
+	stdout := newTestWriter()
+	stderr := newTestWriter()
+
+	rc := io.NopCloser(bytes.NewBufferString("first line\r\nsecond line\r\npartial"))
+	writeLogOutput(rc, stdout, stderr, "consul.container.shipyard.run", color.FgRed, logFormat{}, true, hclog.NewNullLogger())
+
+	require.Equal(t, "[consul.container]   first line\n[consul.container]   second line\n[consul.container]   partial\n", stdout.Buffer.String())
+	require.Empty(t, stderr.Buffer.String())
+}
+
+func TestLogWritesLogWithShortReads(t *testing.T) {
+	stdout := newTestWriter()
+	stderr := newTestWriter()
+
+	// return a single byte for each read
+	rc := io.NopCloser(iotest.OneByteReader(bytes.NewBuffer(createLogOutput(logStdOut))))
+	writeLogOutput(rc, stdout, stderr, "consul.container.shipyard.run", color.FgRed, logFormat{}, false, hclog.NewNullLogger())
+
+	for _, l := range logLines {
+		require.Contains(t, stdout.Buffer.String(), "[consul.container]   "+l)
+	}
+}
+
+func TestLogJoinsLinesSplitAcrossFrames(t *testing.T) {
+	stdout := newTestWriter()
+	stderr := newTestWriter()
+
+	log := createLogFrames(logStdOut, "first ", "line\nsecond line\n")
+	log = append(log, createLogFrames(logStdErr, "error line\n")...)
+
+	rc := io.NopCloser(bytes.NewBuffer(log))
+	writeLogOutput(rc, stdout, stderr, "consul.container.shipyard.run", color.FgRed, logFormat{}, false, hclog.NewNullLogger())
+
+	require.Equal(t, "[consul.container]   first line\n[consul.container]   second line\n", stdout.Buffer.String())
+	require.Equal(t, "[consul.container]   error line\n", stderr.Buffer.String())
+}
+
+func TestLogInspectsContainersForTTY(t *testing.T) {
+	lc, md, stdout, _ := setupLog(t, logStdOut)
+	removeOn(&md.Mock, "ContainerInspect")
+	removeOn(&md.Mock, "ContainerLogs")
+
+	md.On("ContainerInspect", mock.Anything, mock.Anything).Return(types.ContainerJSON{Config: &container.Config{Tty: true}}, nil)
+	md.On("ContainerLogs", mock.Anything, mock.Anything, mock.Anything).Return(io.NopCloser(bytes.NewBufferString("tty output\n")), nil)
+
+	lc.SetArgs([]string{"container.consul", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	md.AssertCalled(t, "ContainerInspect", mock.Anything, "consul.container.shipyard.run")
+	require.Equal(t, "[consul.container]   tty output\n", stdout.String())
+}
+
+func TestLogColorIsStableForResource(t *testing.T) {
+	c := getResourceColor("consul.container.shipyard.run")
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, c, getResourceColor("consul.container.shipyard.run"))
+	}
+
+	require.Contains(t, termColors, c)
+}
+
 func TestLogWritesDockerLogToStdOut(t *testing.T) {
 	lc, _, stdout, _ := setupLog(t, logStdOut)
 
@@ -179,6 +442,139 @@ func TestLogWritesDockerLogToStdErr(t *testing.T) {
 	require.Contains(t, stderr.String(), "[consul.container]   [16:10:20] [main/INFO]: Applying mixin: R1_17.MixinBlockEntity...")
 }
 
+func TestLogWithPodsStreamsPodLogs(t *testing.T) {
+	lc, md, mk, _, stdout, _ := setupLogWithClients(t, logStdOut)
+
+	lc.SetArgs([]string{"k8s_cluster.dev", "--pods", "app=vault", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	mk.AssertCalled(t, "GetPods", "app=vault")
+	md.AssertNotCalled(t, "ContainerLogs", mock.Anything, mock.Anything, mock.Anything)
+
+	require.Contains(t, stdout.String(), "[vault-0/vault]   vault log")
+	require.Contains(t, stdout.String(), "[vault-0/agent]   agent log")
+}
+
+func TestLogWithPodsSetsPodLogOptions(t *testing.T) {
+	lc, _, mk, _, _, _ := setupLogWithClients(t, logStdOut)
+
+	lc.SetArgs([]string{"k8s_cluster.dev", "--pods", "app=vault", "--tail", "10", "--since", "10m", "--timestamps", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	opts := getCalls(&mk.Mock, "StreamPodLogs")[0].Arguments.Get(3).(*v1.PodLogOptions)
+	require.False(t, opts.Follow)
+	require.True(t, opts.Timestamps)
+	require.Equal(t, int64(10), *opts.TailLines)
+	require.WithinDuration(t, time.Now().Add(-10*time.Minute), opts.SinceTime.Time, time.Minute)
+}
+
+func TestLogWithPodsStillLogsOtherResources(t *testing.T) {
+	lc, md, mk, _, _, _ := setupLogWithClients(t, logStdOut)
+
+	lc.SetArgs([]string{"k8s_cluster.dev", "container.consul", "--pods", "app=vault", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	mk.AssertNumberOfCalls(t, "StreamPodLogs", 2)
+	md.AssertNumberOfCalls(t, "ContainerLogs", 1)
+	md.AssertCalled(t, "ContainerLogs", mock.Anything, "consul.container.shipyard.run", mock.Anything)
+}
+
+func TestLogWithJobsStreamsAllocationLogs(t *testing.T) {
+	lc, md, _, mn, stdout, stderr := setupLogWithClients(t, logStdOut)
+
+	lc.SetArgs([]string{"nomad_cluster.dev", "--jobs", "example", "--tail", "10", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	mn.AssertCalled(t, "JobAllocations", "example")
+	mn.AssertCalled(t, "AllocationLogs", mock.Anything, "da975cd1-8b04-6bce-9d5c-03e47353768c", "web", "stdout", false, int64(10*nomadLogLineBytes))
+	md.AssertNotCalled(t, "ContainerLogs", mock.Anything, mock.Anything, mock.Anything)
+
+	require.Contains(t, stdout.String(), "[example/da975cd1/web]   web stdout")
+	require.Contains(t, stderr.String(), "[example/da975cd1/web]   web stderr")
+}
+
+func TestLogWithJSONOutputWritesObjectForEachLine(t *testing.T) {
+	lc, md, stdout, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"container.consul", "--output", "json", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	// timestamps are requested so they can be parsed from the lines
+	opts := getCalls(&md.Mock, "ContainerLogs")[0].Arguments.Get(2).(types.ContainerLogsOptions)
+	require.True(t, opts.Timestamps)
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, len(logLines))
+
+	e := logEntry{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &e))
+	require.Equal(t, "consul.container", e.Resource)
+	require.Equal(t, "stdout", e.Stream)
+	require.Equal(t, strings.TrimSuffix(logLines[0], "\n"), e.Message)
+	require.NotEmpty(t, e.Timestamp)
+}
+
+func TestLogWithInvalidOutputReturnsError(t *testing.T) {
+	lc, md, _, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"--output", "yaml"})
+	err := lc.Execute()
+	require.Error(t, err)
+
+	md.AssertNotCalled(t, "ContainerLogs", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLogJSONParsesTimestamps(t *testing.T) {
+	stdout := newTestWriter()
+	stderr := newTestWriter()
+
+	log := createLogFrames(logStdErr, "2021-06-01T13:23:37.123456789Z something <failed>\n")
+
+	rc := io.NopCloser(bytes.NewBuffer(log))
+	writeLogOutput(rc, stdout, stderr, "consul.container.shipyard.run", color.FgRed, logFormat{json: true, timestamps: true}, false, hclog.NewNullLogger())
+
+	require.Empty(t, stdout.Buffer.String())
+	require.Equal(
+		t,
+		`{"resource":"consul.container","stream":"stderr","timestamp":"2021-06-01T13:23:37.123456789Z","message":"something <failed>"}`+"\n",
+		stderr.Buffer.String(),
+	)
+}
+
+func TestLogWithDirWritesFileForEachResource(t *testing.T) {
+	lc, _, stdout, _ := setupLog(t, logStdOut)
+	dir := t.TempDir()
+
+	lc.SetArgs([]string{"container.consul", "nomad_cluster.dev", "--dir", dir, "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, stdout.String(), "Writing logs to "+dir)
+	require.NotContains(t, stdout.String(), logLines[0])
+
+	d, err := ioutil.ReadFile(filepath.Join(dir, "consul.container.log"))
+	require.NoError(t, err)
+	require.Equal(t, strings.Join(logLines, ""), string(d))
+
+	require.FileExists(t, filepath.Join(dir, "server.dev.nomad-cluster.log"))
+	require.FileExists(t, filepath.Join(dir, "1.client.dev.nomad-cluster.log"))
+}
+
+func TestLogWithRelativeDirWritesToLogsDir(t *testing.T) {
+	lc, _, _, _ := setupLog(t, logStdOut)
+
+	lc.SetArgs([]string{"container.consul", "--dir", "ci", "--output", "json", "--no-follow"})
+	err := lc.Execute()
+	require.NoError(t, err)
+
+	require.FileExists(t, filepath.Join(utils.LogsDir(), "ci", "consul.container.json"))
+}
+
 var logState = `
 {
  "resources": [
@@ -294,10 +690,10 @@ var logState = `
 }`
 
 var logLines = []string{
-	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinNbtTag...",
-	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinBlockEntity...",
-	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinChestBlockEntity...",
-	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinScreenHandler...",
-	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinChunkGenerator...",
-	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinPersistentStateManager...",
+	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinNbtTag...\n",
+	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinBlockEntity...\n",
+	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinChestBlockEntity...\n",
+	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinScreenHandler...\n",
+	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinChunkGenerator...\n",
+	"[16:10:20] [main/INFO]: Applying mixin: R1_17.MixinPersistentStateManager...\n",
 }
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/server"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+)
+
+// startMetrics serves the Prometheus metrics for the engine on addr until the
+// command exits, the metrics record the events published by the engine
+func startMetrics(addr string, e shipyard.Engine, l hclog.Logger) error {
+	m, _ := shipyard.NewMetrics(e.Events(), e.GetClients().ImagePulls)
+
+	s := server.NewMetricsServer(addr, m, l.Named("metrics"))
+
+	err := s.Start()
+	if err != nil {
+		return newCommandError(ErrorCodeUsage, "Unable to serve metrics on %s: %s", addr, err)
+	}
+
+	l.Debug("Serving metrics", "addr", s.Addr())
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// notifyFlags are the flags which send the lifecycle events for a command
+// to webhooks in addition to the notifications defined in the blueprint
+type notifyFlags struct {
+	webhooks []string
+	slack    []string
+}
+
+func (f *notifyFlags) add(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&f.webhooks, "webhook", "", nil, "Post the events for the command as JSON to the URL, e.g. --webhook https://ci.example.com/events. Can be specified multiple times")
+	cmd.Flags().StringSliceVarP(&f.slack, "slack-webhook", "", nil, "Post a message for each event to the Slack incoming webhook URL. Can be specified multiple times")
+}
+
+// notifications returns the notifications for the flags
+func (f *notifyFlags) notifications() ([]config.Notification, error) {
+	n := []config.Notification{}
+
+	for i, u := range f.webhooks {
+		n = append(n, config.Notification{Name: fmt.Sprintf("webhook-%d", i+1), Type: config.NotificationWebhook, URL: u})
+	}
+
+	for i, u := range f.slack {
+		n = append(n, config.Notification{Name: fmt.Sprintf("slack-%d", i+1), Type: config.NotificationSlack, URL: u})
+	}
+
+	for _, nt := range n {
+		err := nt.Validate()
+		if err != nil {
+			return nil, newCommandError(ErrorCodeUsage, "Unable to send notifications to '%s', %s", nt.URL, err)
+		}
+	}
+
+	return n, nil
+}
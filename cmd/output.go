@@ -34,13 +34,122 @@ var outputCmd = &cobra.Command{
 					continue
 				}
 
+				// sensitive values are only shown when the output is requested by name
 				out[r.Info().Name] = r.(*config.Output).Value
+				if r.(*config.Output).Sensitive {
+					out[r.Info().Name] = utils.RedactedValue
+				}
 
 				if len(args) > 0 && strings.ToLower(args[0]) == strings.ToLower(r.Info().Name) {
 					cmd.Println(r.(*config.Output).Value)
 					return
 				}
 			}
+
+			// tunnels output the public url assigned by the provider
+			if r.Info().Type == config.TypeTunnel {
+				if r.Info().Disabled || r.(*config.Tunnel).PublicURL == "" {
+					continue
+				}
+
+				name := fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)
+				out[name] = r.(*config.Tunnel).PublicURL
+
+				if len(args) > 0 && strings.ToLower(args[0]) == strings.ToLower(name) {
+					cmd.Println(r.(*config.Tunnel).PublicURL)
+					return
+				}
+			}
+
+			// exec_local resources output the stdout of the command
+			if r.Info().Type == config.TypeExecLocal {
+				if r.Info().Disabled || r.(*config.ExecLocal).Output == "" {
+					continue
+				}
+
+				name := fmt.Sprintf("%s.%s.output", r.Info().Type, r.Info().Name)
+				out[name] = r.(*config.ExecLocal).Output
+
+				if len(args) > 0 && strings.ToLower(args[0]) == strings.ToLower(name) {
+					cmd.Println(r.(*config.ExecLocal).Output)
+					return
+				}
+			}
+
+			// terraform resources output the outputs of the module
+			if r.Info().Type == config.TypeTerraform {
+				tf := r.(*config.Terraform)
+				if tf.Disabled {
+					continue
+				}
+
+				for k, v := range tf.Outputs {
+					name := fmt.Sprintf("%s.%s.output.%s", r.Info().Type, r.Info().Name, k)
+
+					// sensitive values are only shown when the output is requested by name
+					out[name] = v
+					if tf.IsSensitive(k) {
+						out[name] = utils.RedactedValue
+					}
+
+					if len(args) > 0 && strings.ToLower(args[0]) == strings.ToLower(name) {
+						cmd.Println(v)
+						return
+					}
+				}
+			}
+
+			// certificates output the location of the certificate and key
+			if r.Info().Type == config.TypeCertificateCA || r.Info().Type == config.TypeCertificateLeaf {
+				if r.Info().Disabled {
+					continue
+				}
+
+				values := map[string]string{}
+				switch c := r.(type) {
+				case *config.CertificateCA:
+					values["cert_path"], values["key_path"] = c.CertPath, c.KeyPath
+				case *config.CertificateLeaf:
+					values["cert_path"], values["key_path"] = c.CertPath, c.KeyPath
+				}
+
+				for k, v := range values {
+					name := fmt.Sprintf("%s.%s.%s", r.Info().Type, r.Info().Name, k)
+					out[name] = v
+
+					if len(args) > 0 && strings.ToLower(args[0]) == strings.ToLower(name) {
+						cmd.Println(v)
+						return
+					}
+				}
+			}
+
+			// nomad clusters output the address and token for the consul server
+			if r.Info().Type == config.TypeNomadCluster {
+				nc := r.(*config.NomadCluster)
+				if nc.Disabled {
+					continue
+				}
+
+				values := map[string]string{
+					"consul_http_addr": nc.ConsulHTTPAddr,
+					"consul_acl_token": nc.ConsulACLToken,
+				}
+
+				for k, v := range values {
+					if v == "" {
+						continue
+					}
+
+					name := fmt.Sprintf("%s.%s.%s", r.Info().Type, r.Info().Name, k)
+					out[name] = v
+
+					if len(args) > 0 && strings.ToLower(args[0]) == strings.ToLower(name) {
+						cmd.Println(v)
+						return
+					}
+				}
+			}
 		}
 
 		s, _ := prettyjson.Marshal(out)
@@ -3,52 +3,100 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/filters"
 	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
-var pauseCmd = &cobra.Command{
-	Use:   "pause",
-	Short: "Pauses all resources for the currently active blueprint",
-	Long:  `Pause all resources for the currently active blueprint freeing up memory and CPU`,
-	Example: `
-  shipyard pause 
+// pauseTimeout is the time containers are given to stop before they are killed
+const pauseTimeout = 20 * time.Second
+
+func newPauseCmd(dc clients.Docker) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pause",
+		Short: "Pauses all resources for the currently active blueprint",
+		Long: `Pause all resources for the currently active blueprint freeing up memory and CPU.
+
+The containers for the resources are stopped, the state, networks, and volumes are
+not changed. Paused resources are started again with 'shipyard resume'.`,
+		Example: `
+  # Stop the containers overnight and start them again in the morning
+  shipyard pause
+  shipyard resume
 	`,
-	Args: cobra.ArbitraryArgs,
-	Run: func(cmd *cobra.Command, args []string) {
-		var err error
-		fmt.Println("Pausing resources")
-
-		// create a docker client
-		c, err := clients.NewDocker()
-		if err != nil {
-			fmt.Println("Unable to connect to Docker daemon", err)
-			os.Exit(1)
-		}
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Println("Pausing resources")
+
+			paused, err := pauseResources(dc)
+			for _, r := range paused {
+				cmd.Println("  Paused", r)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			cmd.Println()
+			cmd.Println("Resume the resources with 'shipyard resume'")
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
+
+// pauseResources stops the containers for the created resources and sets the status
+// of the resources to paused, returns the names of the paused resources
+func pauseResources(dc clients.Docker) ([]string, error) {
+	c := config.New()
+	err := c.FromJSON(utils.StatePath())
+	if err != nil {
+		return nil, newCommandError(ErrorCodeState, "Unable to load state, is an environment running? %s", err)
+	}
 
-		filters := filters.NewArgs()
-		filters.Add("name", "shipyard")
-		filters.Add("status", "running")
+	ctx := context.Background()
+	paused := []string{}
 
-		cl, err := c.ContainerList(
-			context.Background(),
-			types.ContainerListOptions{
-				Filters: filters,
-			},
-		)
+	var stopErr error
+	for _, r := range c.Resources {
+		containers := config.LogContainers(r)
+		if r.Info().Status != config.Applied || len(containers) == 0 {
+			continue
+		}
+
+		for _, name := range containers {
+			// containers which are missing or already stopped are started by resume
+			info, err := dc.ContainerInspect(ctx, name)
+			if err != nil || info.ContainerJSONBase == nil || info.State == nil || !info.State.Running {
+				continue
+			}
 
-		sd := 20 * time.Second
-		for _, con := range cl {
-			err := c.ContainerStop(context.Background(), con.ID, &sd)
+			sd := pauseTimeout
+			err = dc.ContainerStop(ctx, name, &sd)
 			if err != nil {
-				fmt.Println("Unable to stop container", con.Names[0], err)
-				os.Exit(1)
+				stopErr = newCommandError(ErrorCodeDockerUnavailable, "Unable to stop container %s: %s", name, err)
+				break
 			}
 		}
-	},
+
+		if stopErr != nil {
+			break
+		}
+
+		r.Info().Status = config.Paused
+		paused = append(paused, fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name))
+	}
+
+	// save the resources which were paused even when a container could not be stopped
+	// so that resume starts them
+	err = c.ToJSON(utils.StatePath())
+	if err != nil {
+		return paused, newCommandError(ErrorCodeState, "Unable to save state: %s", err)
+	}
+
+	return paused, stopErr
 }
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupPause(t *testing.T, status config.Status) *mocks.MockDocker {
+	c := config.NewContainer("web")
+	c.Status = status
+
+	n := config.NewNetwork("local")
+	n.Status = config.Applied
+
+	p := config.NewContainer("pending")
+	p.Status = config.PendingCreation
+
+	setupWatchState(t, c, n, p)
+
+	md := &mocks.MockDocker{}
+	md.On("ContainerInspect", mock.Anything, "web.container.shipyard.run").Return(containerJSON("abc", true, "", nil), nil)
+	md.On("ContainerStop", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	md.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	return md
+}
+
+func stateStatus(t *testing.T, resource string) config.Status {
+	sc := config.New()
+	err := sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err := sc.FindResource(resource)
+	assert.NoError(t, err)
+
+	return r.Info().Status
+}
+
+func TestPauseStopsContainersAndSetsStatus(t *testing.T) {
+	md := setupPause(t, config.Applied)
+
+	c := newPauseCmd(md)
+	c.SetOut(bytes.NewBuffer(nil))
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ContainerStop", mock.Anything, "web.container.shipyard.run", mock.Anything)
+	md.AssertNumberOfCalls(t, "ContainerStop", 1)
+
+	assert.Equal(t, config.Paused, stateStatus(t, "container.web"))
+	assert.Equal(t, config.Applied, stateStatus(t, "network.local"))
+	assert.Equal(t, config.PendingCreation, stateStatus(t, "container.pending"))
+}
+
+func TestPauseDoesNotStopContainersWhichAreNotRunning(t *testing.T) {
+	md := setupPause(t, config.Applied)
+	removeOn(&md.Mock, "ContainerInspect")
+	md.On("ContainerInspect", mock.Anything, mock.Anything).Return(containerJSON("abc", false, "", nil), nil)
+
+	paused, err := pauseResources(md)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"container.web"}, paused)
+
+	md.AssertNotCalled(t, "ContainerStop", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPauseReturnsErrorWhenStopFails(t *testing.T) {
+	md := setupPause(t, config.Applied)
+	removeOn(&md.Mock, "ContainerStop")
+	md.On("ContainerStop", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	_, err := pauseResources(md)
+	assert.Error(t, err)
+
+	assert.Equal(t, config.Applied, stateStatus(t, "container.web"))
+}
+
+func TestPauseReturnsErrorWhenNoState(t *testing.T) {
+	setupWatchState(t)
+	md := &mocks.MockDocker{}
+
+	_, err := pauseResources(md)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeState, ErrorCodeFor(err))
+}
+
+func setupResume(t *testing.T, status config.Status) *mocks.MockDocker {
+	md := setupPause(t, status)
+
+	// the container is running once it has been started
+	removeOn(&md.Mock, "ContainerInspect")
+	md.On("ContainerInspect", mock.Anything, "web.container.shipyard.run").Return(containerJSON("abc", false, "", nil), nil).Once()
+	md.On("ContainerInspect", mock.Anything, "web.container.shipyard.run").Return(containerJSON("abc", true, "", nil), nil)
+
+	return md
+}
+
+func TestResumeStartsPausedContainersAndSetsStatus(t *testing.T) {
+	md := setupResume(t, config.Paused)
+
+	c := newResumeCmd(md)
+	c.SetOut(bytes.NewBuffer(nil))
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ContainerStart", mock.Anything, "web.container.shipyard.run", mock.Anything)
+	md.AssertNumberOfCalls(t, "ContainerStart", 1)
+
+	assert.Equal(t, config.Applied, stateStatus(t, "container.web"))
+	assert.Equal(t, config.PendingCreation, stateStatus(t, "container.pending"))
+}
+
+func TestResumeStartsContainersStoppedForAppliedResources(t *testing.T) {
+	md := setupResume(t, config.Applied)
+
+	resumed, containers, err := resumeResources(md)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"container.web"}, resumed)
+	assert.Equal(t, []string{"web.container.shipyard.run"}, containers)
+}
+
+func TestResumeDoesNotStartRunningContainers(t *testing.T) {
+	md := setupPause(t, config.Applied)
+
+	resumed, _, err := resumeResources(md)
+	assert.NoError(t, err)
+	assert.Empty(t, resumed)
+
+	md.AssertNotCalled(t, "ContainerStart", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResumeReturnsErrorWhenStartFails(t *testing.T) {
+	md := setupResume(t, config.Paused)
+	removeOn(&md.Mock, "ContainerStart")
+	md.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	_, _, err := resumeResources(md)
+	assert.Error(t, err)
+
+	assert.Equal(t, config.Paused, stateStatus(t, "container.web"))
+}
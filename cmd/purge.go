@@ -5,52 +5,175 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/go-units"
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
+// scopes which can be purged
+const (
+	purgeScopeImages     = "images"
+	purgeScopeBlueprints = "blueprints"
+	purgeScopeHelm       = "helm"
+	purgeScopeCerts      = "certs"
+	purgeScopeState      = "state"
+	purgeScopeReleases   = "releases"
+)
+
+// purgeItem is an image, volume, or folder which is removed by purge, size
+// is -1 when the size of the item can not be determined
+type purgeItem struct {
+	scope  string
+	name   string
+	size   int64
+	remove func() error
+}
+
+type purgeOptions struct {
+	images     bool
+	blueprints bool
+	helm       bool
+	certs      bool
+	state      bool
+	all        bool
+	dryRun     bool
+}
+
+// scopes returns the scopes selected by the options, when no scope
+// is selected everything is purged
+func (o purgeOptions) scopes() map[string]bool {
+	if o.all || !(o.images || o.blueprints || o.helm || o.certs || o.state) {
+		return map[string]bool{
+			purgeScopeImages:     true,
+			purgeScopeBlueprints: true,
+			purgeScopeHelm:       true,
+			purgeScopeCerts:      true,
+			purgeScopeState:      true,
+			purgeScopeReleases:   true,
+		}
+	}
+
+	return map[string]bool{
+		purgeScopeImages:     o.images,
+		purgeScopeBlueprints: o.blueprints,
+		purgeScopeHelm:       o.helm,
+		purgeScopeCerts:      o.certs,
+		purgeScopeState:      o.state,
+	}
+}
+
 func newPurgeCmd(dt clients.Docker, il clients.ImageLog, l hclog.Logger) *cobra.Command {
+	opts := &purgeOptions{}
+
 	purgeCmd := &cobra.Command{
 		Use:   "purge",
 		Short: "Purges Docker images, Helm charts, and Blueprints downloaded by Shipyard",
-		Long:  "Purges Docker images, Helm charts, and Blueprints downloaded by Shipyard",
+		Long: `Purges Docker images, Helm charts, and Blueprints downloaded by Shipyard
+
+The data to remove is selected with scopes, when no scope is given everything is
+removed. A report of the items and their size is shown before they are removed,
+use --dry-run to show the report without removing anything.
+
+  --images      images pulled and built by Shipyard and the image cache volume
+  --blueprints  blueprints downloaded from remote sources
+  --helm        Helm charts downloaded from remote sources
+  --certs       certificates generated for the connector and ingress
+  --state       state, data folders, and cluster config, only when no resources are running
+  --all         all of the above and downloaded Shipyard releases`,
 		Example: `
-  shipyard purge
+  # Show what would be removed
+  shipyard purge --dry-run
+
+  # Remove the cached blueprints and Helm charts but keep images for offline use
+  shipyard purge --blueprints --helm
+
+  # Remove everything
+  shipyard purge --all
 	`,
-		Args:         cobra.ArbitraryArgs,
-		RunE:         newPurgeCmdFunc(dt, il, l),
+		Args:         cobra.NoArgs,
+		RunE:         newPurgeCmdFunc(dt, il, opts, l),
 		SilenceUsage: true,
 	}
 
+	purgeCmd.Flags().BoolVarP(&opts.images, "images", "", false, "Remove images pulled and built by Shipyard")
+	purgeCmd.Flags().BoolVarP(&opts.blueprints, "blueprints", "", false, "Remove downloaded blueprints")
+	purgeCmd.Flags().BoolVarP(&opts.helm, "helm", "", false, "Remove downloaded Helm charts")
+	purgeCmd.Flags().BoolVarP(&opts.certs, "certs", "", false, "Remove generated certificates")
+	purgeCmd.Flags().BoolVarP(&opts.state, "state", "", false, "Remove the state, data folders, and cluster config")
+	purgeCmd.Flags().BoolVarP(&opts.all, "all", "", false, "Remove everything, this is the default when no scope is given")
+	purgeCmd.Flags().BoolVarP(&opts.dryRun, "dry-run", "", false, "Show the items which would be removed without removing them")
+
 	return purgeCmd
 }
 
-func newPurgeCmdFunc(dt clients.Docker, il clients.ImageLog, l hclog.Logger) func(cmd *cobra.Command, args []string) error {
+func newPurgeCmdFunc(dt clients.Docker, il clients.ImageLog, opts *purgeOptions, l hclog.Logger) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		images, _ := il.Read(clients.ImageTypeDocker)
+		scopes := opts.scopes()
 
-		bHasError := false
+		// removing the state while resources are running orphans the resources
+		if scopes[purgeScopeState] && hasRunningResources() {
+			if opts.state && !opts.all {
+				return newCommandError(ErrorCodeUsage, "Unable to purge state while resources are running, run 'shipyard destroy' first")
+			}
 
-		for _, i := range images {
-			l.Info("Removing image", "image", i)
+			cmd.Println("Resources are running, the state will not be purged")
+			scopes[purgeScopeState] = false
+		}
+
+		items, bHasError := purgeItems(dt, il, scopes, l)
+
+		printPurgeReport(cmd, items, opts.dryRun)
 
-			_, err := dt.ImageRemove(context.Background(), i, types.ImageRemoveOptions{Force: true, PruneChildren: true})
+		if opts.dryRun {
+			return nil
+		}
+
+		for _, i := range items {
+			l.Info("Removing", "scope", i.scope, "item", i.name)
+
+			err := i.remove()
 			if err != nil {
-				l.Error("Unable to delete", "image", i, "error", err)
+				l.Error("Unable to remove", "scope", i.scope, "item", i.name, "error", err)
+				bHasError = true
 			}
 		}
-		il.Clear()
 
-		// Remove any images which have been built
+		if scopes[purgeScopeImages] {
+			il.Clear()
+		}
+
+		if bHasError {
+			return fmt.Errorf("An error occured when purging data")
+		}
+
+		return nil
+	}
+}
+
+// purgeItems returns the items for the selected scopes, an error is
+// flagged when the images built by Shipyard can not be listed
+func purgeItems(dt clients.Docker, il clients.ImageLog, scopes map[string]bool, l hclog.Logger) ([]purgeItem, bool) {
+	items := []purgeItem{}
+	bHasError := false
+
+	if scopes[purgeScopeImages] {
+		images, _ := il.Read(clients.ImageTypeDocker)
+
+		for _, i := range images {
+			items = append(items, imagePurgeItem(dt, i, i, imageSize(dt, i)))
+		}
+
+		// images which have been built
 		filter := filters.NewArgs()
 		filter.Add("reference", "shipyard.run/localcache/*")
 
-		// check if the image already exists, if so do not rebuild unless force
 		sum, err := dt.ImageList(context.Background(), types.ImageListOptions{Filters: filter})
 		if err != nil {
 			l.Error("Unable to check image cache", "error", err)
@@ -58,67 +181,137 @@ func newPurgeCmdFunc(dt clients.Docker, il clients.ImageLog, l hclog.Logger) fun
 		}
 
 		for _, i := range sum {
-			l.Info("Removing image", "image", i.ID)
-
-			_, err := dt.ImageRemove(context.Background(), i.ID, types.ImageRemoveOptions{Force: true, PruneChildren: true})
-			if err != nil {
-				l.Error("Unable to delete", "image", i.ID, "error", err)
-				bHasError = true
+			name := i.ID
+			if len(i.RepoTags) > 0 {
+				name = i.RepoTags[0]
 			}
-		}
 
-		l.Info("Removing Docker image cache")
-		err = dt.VolumeRemove(context.Background(), utils.FQDNVolumeName("images"), true)
-		if err != nil {
-			l.Error("Unable to remove cached image volume", "error", err)
-			bHasError = true
+			items = append(items, imagePurgeItem(dt, name, i.ID, i.Size))
 		}
 
-		hcp := utils.GetBlueprintLocalFolder("")
-		l.Info("Removing cached blueprints", "path", hcp)
-		err = os.RemoveAll(hcp)
-		if err != nil {
-			l.Error("Unable to remove cached blueprints", "error", err)
-			bHasError = true
-		}
+		vol := utils.FQDNVolumeName("images")
+		items = append(items, purgeItem{
+			scope: purgeScopeImages,
+			name:  vol,
+			size:  -1,
+			remove: func() error {
+				return dt.VolumeRemove(context.Background(), vol, true)
+			},
+		})
+	}
 
-		bcp := utils.GetHelmLocalFolder("")
-		l.Info("Removing cached Helm charts", "path", bcp)
-		err = os.RemoveAll(bcp)
-		if err != nil {
-			l.Error("Unable to remove cached Helm charts", "error", err)
-			bHasError = true
-		}
+	folders := []struct{ scope, dir string }{
+		{purgeScopeBlueprints, utils.GetBlueprintLocalFolder("")},
+		{purgeScopeHelm, utils.GetHelmLocalFolder("")},
+		{purgeScopeCerts, filepath.Join(utils.ShipyardHome(), "certs")},
+		{purgeScopeState, utils.GetDataFolder("")},
+		{purgeScopeState, path.Join(utils.ShipyardHome(), "config")},
+		{purgeScopeState, utils.StateDir()},
+		{purgeScopeReleases, utils.GetReleasesFolder()},
+	}
 
-		// delete the releases
-		rcp := utils.GetReleasesFolder()
-		l.Info("Removing cached releases", "path", rcp)
-		err = os.RemoveAll(rcp)
-		if err != nil {
-			l.Error("Unable to remove cached Releases", "error", err)
-			bHasError = true
+	for _, f := range folders {
+		// folders which do not exist are not shown in the report
+		if _, err := os.Stat(f.dir); !scopes[f.scope] || os.IsNotExist(err) {
+			continue
 		}
 
-		dcp := utils.GetDataFolder("")
-		l.Info("Removing data folders", "path", dcp)
-		err = os.RemoveAll(dcp)
-		if err != nil {
-			l.Error("Unable to remove data folder", "error", err)
-			bHasError = true
-		}
+		items = append(items, folderPurgeItem(f.scope, f.dir))
+	}
+
+	return items, bHasError
+}
+
+func imagePurgeItem(dt clients.Docker, name, id string, size int64) purgeItem {
+	return purgeItem{
+		scope: purgeScopeImages,
+		name:  name,
+		size:  size,
+		remove: func() error {
+			_, err := dt.ImageRemove(context.Background(), id, types.ImageRemoveOptions{Force: true, PruneChildren: true})
+			return err
+		},
+	}
+}
+
+func folderPurgeItem(scope, dir string) purgeItem {
+	return purgeItem{
+		scope: scope,
+		name:  dir,
+		size:  folderSize(dir),
+		remove: func() error {
+			return os.RemoveAll(dir)
+		},
+	}
+}
 
-		cp := path.Join(utils.ShipyardHome(), "config")
-		l.Info("Removing config", "path", cp)
-		err = os.RemoveAll(cp)
+// imageSize returns the size of a local image or -1 when the
+// image does not exist
+func imageSize(dt clients.Docker, image string) int64 {
+	ii, _, err := dt.ImageInspectWithRaw(context.Background(), image)
+	if err != nil {
+		return -1
+	}
+
+	return ii.Size
+}
+
+// folderSize returns the total size of the files in the folder
+func folderSize(dir string) int64 {
+	var size int64
+
+	filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
-			l.Error("Unable to remove config folder", "error", err)
-			bHasError = true
+			return nil
 		}
 
-		if bHasError {
-			return fmt.Errorf("An error occured when purging data")
+		if !info.IsDir() {
+			size += info.Size()
 		}
 
 		return nil
+	})
+
+	return size
+}
+
+func printPurgeReport(cmd *cobra.Command, items []purgeItem, dryRun bool) {
+	if len(items) == 0 {
+		cmd.Println("Nothing to purge")
+		return
 	}
+
+	if dryRun {
+		cmd.Println("The following items would be removed:")
+	} else {
+		cmd.Println("Removing the following items:")
+	}
+
+	cmd.Println()
+	cmd.Printf("%-12s %-70s %s\n", "SCOPE", "ITEM", "SIZE")
+
+	var total int64
+	for _, i := range items {
+		size := "unknown"
+		if i.size >= 0 {
+			size = units.HumanSize(float64(i.size))
+			total += i.size
+		}
+
+		cmd.Printf("%-12s %-70s %s\n", i.scope, i.name, size)
+	}
+
+	cmd.Println()
+	cmd.Printf("Total: %s\n", units.HumanSize(float64(total)))
+}
+
+// hasRunningResources returns true when the state contains resources
+func hasRunningResources() bool {
+	sc := config.New()
+	err := sc.FromJSON(utils.StatePath())
+	if err != nil {
+		return false
+	}
+
+	return len(sc.Resources) > 0
 }
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/docker/docker/api/types"
@@ -42,12 +44,15 @@ func setupPurgeCommand(t *testing.T) (*cobra.Command, *mocks.MockDocker, *mocks.
 	mockDocker.On("ImageRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
 	mockDocker.On("VolumeRemove", mock.Anything, mock.Anything, true).Return(nil)
 	mockDocker.On("ImageList", mock.Anything, mock.Anything).Return([]types.ImageSummary{}, nil)
+	mockDocker.On("ImageInspectWithRaw", mock.Anything, mock.Anything).Return(types.ImageInspect{Size: 1000}, nil)
 
 	mockImageLog := &mocks.ImageLog{}
 	mockImageLog.On("Read", mock.Anything).Return([]string{"one", "two"}, nil)
 	mockImageLog.On("Clear").Return(nil)
 
 	pc := newPurgeCmd(mockDocker, mockImageLog, hclog.NewNullLogger())
+	pc.SetOut(bytes.NewBufferString(""))
+	pc.SetArgs([]string{})
 
 	return pc, mockDocker, mockImageLog, func() {
 		os.RemoveAll(dir)
@@ -98,3 +103,74 @@ func TestPurgeRemovesHelmCharts(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NoDirExists(t, utils.GetHelmLocalFolder(""))
 }
+
+func TestPurgeWithDryRunDoesNotRemoveAnything(t *testing.T) {
+	pc, md, mi, cleanup := setupPurgeCommand(t)
+	defer cleanup()
+
+	out := bytes.NewBufferString("")
+	pc.SetOut(out)
+	pc.SetArgs([]string{"--dry-run"})
+
+	err := pc.Execute()
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "ImageRemove", mock.Anything, mock.Anything, mock.Anything)
+	md.AssertNotCalled(t, "VolumeRemove", mock.Anything, mock.Anything, mock.Anything)
+	mi.AssertNotCalled(t, "Clear")
+	assert.DirExists(t, utils.GetBlueprintLocalFolder(""))
+	assert.DirExists(t, utils.GetHelmLocalFolder(""))
+
+	assert.Contains(t, out.String(), "would be removed")
+	assert.Contains(t, out.String(), utils.GetHelmLocalFolder(""))
+	assert.Contains(t, out.String(), "Total: 2kB")
+}
+
+func TestPurgeWithScopeOnlyRemovesScope(t *testing.T) {
+	pc, md, mi, cleanup := setupPurgeCommand(t)
+	defer cleanup()
+
+	pc.SetArgs([]string{"--helm"})
+
+	err := pc.Execute()
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "ImageRemove", mock.Anything, mock.Anything, mock.Anything)
+	mi.AssertNotCalled(t, "Clear")
+	assert.DirExists(t, utils.GetBlueprintLocalFolder(""))
+	assert.NoDirExists(t, utils.GetHelmLocalFolder(""))
+}
+
+func TestPurgeWithStateFailsWhenResourcesAreRunning(t *testing.T) {
+	pc, _, _, cleanup := setupPurgeCommand(t)
+	defer cleanup()
+
+	writeRunningState(t)
+	pc.SetArgs([]string{"--state"})
+
+	err := pc.Execute()
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeUsage, ErrorCodeFor(err))
+	assert.FileExists(t, utils.StatePath())
+}
+
+func TestPurgeAllKeepsStateWhenResourcesAreRunning(t *testing.T) {
+	pc, _, _, cleanup := setupPurgeCommand(t)
+	defer cleanup()
+
+	writeRunningState(t)
+	pc.SetArgs([]string{"--all"})
+
+	err := pc.Execute()
+	assert.NoError(t, err)
+	assert.FileExists(t, utils.StatePath())
+	assert.NoDirExists(t, utils.GetBlueprintLocalFolder(""))
+}
+
+func writeRunningState(t *testing.T) {
+	err := os.MkdirAll(filepath.Dir(utils.StatePath()), os.ModePerm)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(utils.StatePath(), []byte(`{"resources": [{"name": "test", "type": "network", "status": "applied", "subnet": "10.0.0.0/24"}]}`), os.ModePerm)
+	assert.NoError(t, err)
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
@@ -13,19 +15,39 @@ import (
 	"golang.org/x/xerrors"
 )
 
+// pushTarget is an image which is pushed to a cluster
+type pushTarget struct {
+	image   string
+	cluster config.Resource
+}
+
 func newPushCmd(ct clients.ContainerTasks, kc clients.Kubernetes, ht clients.HTTP, nc clients.Nomad, l hclog.Logger) *cobra.Command {
 	var force bool
+	var watch bool
+	var interval time.Duration
 
 	pushCmd := &cobra.Command{
-		Use:                   "push [image] [cluster]",
-		Short:                 "Push a local Docker image to a cluster",
-		Long:                  `Push a local Docker image to a cluster`,
-		Example:               `yard push nicholasjackson/fake-service:v0.1.3 k8s_cluster.k3s`,
+		Use:   "push [image] [cluster]",
+		Short: "Push a local Docker image to a cluster",
+		Long: `Push a local Docker image to a cluster.
+When --watch is specified the image is pushed again every time the local tag changes, when no
+image is specified all the copy_images for the running clusters are watched.`,
+		Example: `
+  # Push an image to a cluster
+  yard push nicholasjackson/fake-service:v0.1.3 k8s_cluster.k3s
+
+  # Push the image to the cluster every time it is rebuilt
+  yard push --watch myapp:dev k8s_cluster.k3s
+
+  # Keep the copy_images for all clusters in sync
+  yard push --watch
+	`,
 		DisableFlagsInUseLine: true,
 		Args:                  cobra.MaximumNArgs(3),
+		ValidArgsFunction:     completeArgs(nil, stateResources(config.TypeK8sCluster, config.TypeNomadCluster)),
 		SilenceUsage:          true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 2 {
+			if len(args) < 2 && !(watch && len(args) == 0) {
 				return xerrors.Errorf("Push requires two arguments [image] [cluster]")
 			}
 
@@ -33,16 +55,6 @@ func newPushCmd(ct clients.ContainerTasks, kc clients.Kubernetes, ht clients.HTT
 				ct.SetForcePull(true)
 			}
 
-			image := args[0]
-			cluster := args[1]
-
-			fmt.Printf("Pushing image %s to cluster %s\n\n", image, cluster)
-
-			// check the resource is of the allowed type
-			if !strings.HasPrefix(cluster, "nomad_cluster") && !strings.HasPrefix(cluster, "k8s_cluster") {
-				return xerrors.Errorf("Invalid resource type, only resources type nomad_cluster and k8s_cluster are supported")
-			}
-
 			// find the cluster in the state
 			sc := config.New()
 			err := sc.FromJSON(utils.StatePath())
@@ -50,28 +62,136 @@ func newPushCmd(ct clients.ContainerTasks, kc clients.Kubernetes, ht clients.HTT
 				return xerrors.Errorf("No resources are running, start a stack with 'shipyard run [blueprint]'")
 			}
 
-			p, err := sc.FindResource(cluster)
-			if err != nil {
-				return xerrors.Errorf("Cluster %s is not running", cluster)
+			push := func(t pushTarget) error {
+				fmt.Printf("Pushing image %s to cluster %s\n\n", t.image, t.cluster.Info().Name)
+
+				switch t.cluster.Info().Type {
+				case config.TypeK8sCluster:
+					return pushK8sCluster(t.image, t.cluster.(*config.K8sCluster), ct, kc, ht, l)
+				case config.TypeNomadCluster:
+					return pushNomadCluster(t.image, t.cluster.(*config.NomadCluster), ct, nc, l, true)
+				}
+
+				return nil
 			}
 
-			switch p.Info().Type {
-			case config.TypeK8sCluster:
-				return pushK8sCluster(image, p.(*config.K8sCluster), ct, kc, ht, l, true)
-			case config.TypeNomadCluster:
-				return pushNomadCluster(image, p.(*config.NomadCluster), ct, nc, l, true)
+			var targets []pushTarget
+			if len(args) == 0 {
+				targets = copyImageTargets(sc)
+				if len(targets) == 0 {
+					return xerrors.Errorf("No clusters with copy_images are running")
+				}
+			} else {
+				image := args[0]
+				cluster := args[1]
+
+				// check the resource is of the allowed type
+				if !strings.HasPrefix(cluster, "nomad_cluster") && !strings.HasPrefix(cluster, "k8s_cluster") {
+					return xerrors.Errorf("Invalid resource type, only resources type nomad_cluster and k8s_cluster are supported")
+				}
+
+				p, err := sc.FindResource(cluster)
+				if err != nil {
+					return xerrors.Errorf("Cluster %s is not running", cluster)
+				}
+
+				targets = []pushTarget{{image: image, cluster: p}}
+
+				if !watch {
+					return push(targets[0])
+				}
 			}
 
-			return nil
+			ctx, cancel := interruptContext()
+			defer cancel()
+
+			fmt.Printf("Watching %d images for changes, press Ctrl-C to stop\n\n", len(targets))
+
+			return watchImages(ctx, ct, targets, interval, push, l)
 		},
 	}
 
 	pushCmd.Flags().BoolVarP(&force, "force-update", "", false, "When set to true Shipyard will ignore cached images or files and will download all resources")
+	pushCmd.Flags().BoolVarP(&watch, "watch", "", false, "Push the image again every time the local tag changes until interrupted")
+	pushCmd.Flags().DurationVarP(&interval, "interval", "", 2*time.Second, "Interval used to check for changes to the local images when watching")
 
 	return pushCmd
 }
 
-func pushK8sCluster(image string, c *config.K8sCluster, ct clients.ContainerTasks, kc clients.Kubernetes, ht clients.HTTP, log hclog.Logger, force bool) error {
+// copyImageTargets returns the copy_images for all the clusters in the state
+func copyImageTargets(c *config.Config) []pushTarget {
+	targets := []pushTarget{}
+
+	for _, r := range c.Resources {
+		var images []string
+
+		switch v := r.(type) {
+		case *config.K8sCluster:
+			images = v.CopyImages
+		case *config.NomadCluster:
+			images = v.CopyImages
+		}
+
+		for _, i := range images {
+			targets = append(targets, pushTarget{image: i, cluster: r})
+		}
+	}
+
+	return targets
+}
+
+// watchImages checks the id of the local images at the given interval and pushes
+// the image to the cluster when the id changes, the images are not pushed when the
+// watch starts
+func watchImages(ctx context.Context, ct clients.ContainerTasks, targets []pushTarget, interval time.Duration, push func(pushTarget) error, l hclog.Logger) error {
+	ids := map[string]string{}
+	for _, t := range targets {
+		id, err := ct.FindImageID(t.image)
+		if err != nil {
+			l.Debug("Unable to find image", "image", t.image, "error", err)
+		}
+
+		ids[t.image] = id
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+
+		changed := map[string]string{}
+		for image, last := range ids {
+			id, err := ct.FindImageID(image)
+			if err != nil {
+				l.Debug("Unable to find image", "image", image, "error", err)
+				continue
+			}
+
+			if id != last {
+				changed[image] = id
+			}
+		}
+
+		for _, t := range targets {
+			if _, ok := changed[t.image]; !ok {
+				continue
+			}
+
+			err := push(t)
+			if err != nil {
+				l.Error("Unable to push image", "image", t.image, "cluster", t.cluster.Info().Name, "error", err)
+			}
+		}
+
+		for image, id := range changed {
+			ids[image] = id
+		}
+	}
+}
+
+func pushK8sCluster(image string, c *config.K8sCluster, ct clients.ContainerTasks, kc clients.Kubernetes, ht clients.HTTP, log hclog.Logger) error {
 	cl := providers.NewK8sCluster(c, ct, kc, ht, nil, log)
 
 	// get the id of the cluster
@@ -81,8 +201,8 @@ func pushK8sCluster(image string, c *config.K8sCluster, ct clients.ContainerTask
 	}
 
 	for _, id := range ids {
-		log.Info("Pushing to container", "id", id, "image", image)
-		err = cl.ImportLocalDockerImages(utils.ImageVolumeName, id, []config.Image{config.Image{Name: strings.Trim(image, " ")}}, force)
+		log.Info("Pushing to container", "id", id, "image", image, "namespace", c.Namespace())
+		err = cl.PushLocalDockerImages(id, []config.Image{config.Image{Name: strings.Trim(image, " ")}})
 		if err != nil {
 			return xerrors.Errorf("Error pushing image: %w ", err)
 		}
@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -18,6 +23,7 @@ func setupPush(state string) (*cobra.Command, *mocks.MockContainerTasks, func())
 	mt.On("PullImage", mock.Anything, false).Return(nil)
 	mt.On("CopyLocalDockerImagesToVolume", mock.Anything, mock.Anything, mock.Anything).Return([]string{"/images/file.tar"}, nil)
 	mt.On("ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mt.On("ImportImagesToContainerd", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mt.On("SetForcePull", mock.Anything).Return(nil)
 
 	mk := &clients.MockKubernetes{}
@@ -27,6 +33,14 @@ func setupPush(state string) (*cobra.Command, *mocks.MockContainerTasks, func())
 	return newPushCmd(mt, mk, mh, mn, hclog.NewNullLogger()), mt, setupState(state)
 }
 
+func writeTempState(t *testing.T, state string) string {
+	path := filepath.Join(t.TempDir(), "state.json")
+	err := ioutil.WriteFile(path, []byte(state), 0644)
+	assert.NoError(t, err)
+
+	return path
+}
+
 func TestPushInvalidArgsReturnsError(t *testing.T) {
 	c, _, cleanup := setupPush(clusterState)
 	defer cleanup()
@@ -76,7 +90,7 @@ func TestPushK8sClusterIDNotFoundReturnsError(t *testing.T) {
 	err := c.Execute()
 	assert.NoError(t, err)
 
-	mt.AssertNotCalled(t, "CopyLocalDockerImagesToVolume", mock.Anything, mock.Anything, mock.Anything)
+	mt.AssertNotCalled(t, "ImportImagesToContainerd", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestPushWithForceSetsFlag(t *testing.T) {
@@ -88,8 +102,21 @@ func TestPushWithForceSetsFlag(t *testing.T) {
 	err := c.Execute()
 	assert.NoError(t, err)
 
+	mt.AssertCalled(t, "SetForcePull", true)
+	mt.AssertCalled(t, "ImportImagesToContainerd", "abc", []string{"consul:v1.6.1"}, "k8s.io", mock.Anything)
+}
+
+func TestPushNomadClusterWithForceSetsFlag(t *testing.T) {
+	c, mt, cleanup := setupPush(clusterState)
+	defer cleanup()
+
+	c.SetArgs([]string{"consul:v1.6.1", "nomad_cluster.nomad"})
+	c.Flags().Set("force-update", "true")
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	mt.AssertCalled(t, "SetForcePull", true)
 	mt.AssertCalled(t, "CopyLocalDockerImagesToVolume", mock.Anything, mock.Anything, true)
-	mt.AssertCalled(t, "SetForcePull", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestPushK8sClusterPushesImage(t *testing.T) {
@@ -100,7 +127,19 @@ func TestPushK8sClusterPushesImage(t *testing.T) {
 	err := c.Execute()
 	assert.NoError(t, err)
 
-	mt.AssertCalled(t, "CopyLocalDockerImagesToVolume", mock.Anything, mock.Anything, true)
+	mt.AssertCalled(t, "ImportImagesToContainerd", "abc", []string{"consul:v1.6.1"}, "k8s.io", mock.Anything)
+	mt.AssertNotCalled(t, "CopyLocalDockerImagesToVolume", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPushK8sClusterUsesContainerdNamespace(t *testing.T) {
+	c, mt, cleanup := setupPush(clusterNamespaceState)
+	defer cleanup()
+
+	c.SetArgs([]string{"consul:v1.6.1", "k8s_cluster.k3s"})
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	mt.AssertCalled(t, "ImportImagesToContainerd", "abc", []string{"consul:v1.6.1"}, "shipyard", mock.Anything)
 }
 
 func TestPushNomadClusterIDErrorReturnsError(t *testing.T) {
@@ -140,6 +179,81 @@ func TestPushNomadClusterPushesImage(t *testing.T) {
 	mt.AssertCalled(t, "CopyLocalDockerImagesToVolume", mock.Anything, mock.Anything, mock.Anything)
 }
 
+func TestPushWatchWithNoCopyImagesReturnsError(t *testing.T) {
+	c, _, cleanup := setupPush(clusterState)
+	defer cleanup()
+
+	c.SetArgs([]string{})
+	c.Flags().Set("watch", "true")
+	err := c.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "No clusters with copy_images")
+}
+
+func TestCopyImageTargetsReturnsImagesForClusters(t *testing.T) {
+	c := config.New()
+	err := c.FromJSON(writeTempState(t, clusterCopyImagesState))
+	assert.NoError(t, err)
+
+	targets := copyImageTargets(c)
+	assert.Len(t, targets, 3)
+	assert.Equal(t, "myapp:dev", targets[0].image)
+	assert.Equal(t, "k3s", targets[0].cluster.Info().Name)
+	assert.Equal(t, "nomad", targets[2].cluster.Info().Name)
+}
+
+func TestWatchImagesPushesChangedImages(t *testing.T) {
+	mt := &mocks.MockContainerTasks{}
+	mt.On("FindImageID", "myapp:dev").Return("sha256:1", nil).Twice()
+	mt.On("FindImageID", "myapp:dev").Return("sha256:2", nil)
+	mt.On("FindImageID", "api:dev").Return("sha256:3", nil)
+
+	targets := []pushTarget{
+		{image: "myapp:dev", cluster: config.NewK8sCluster("k3s")},
+		{image: "api:dev", cluster: config.NewK8sCluster("k3s")},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pushed := []string{}
+	push := func(pt pushTarget) error {
+		pushed = append(pushed, pt.image)
+		cancel()
+
+		return nil
+	}
+
+	err := watchImages(ctx, mt, targets, time.Millisecond, push, hclog.NewNullLogger())
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"myapp:dev"}, pushed)
+}
+
+func TestWatchImagesContinuesWhenImageNotFound(t *testing.T) {
+	mt := &mocks.MockContainerTasks{}
+	mt.On("FindImageID", "myapp:dev").Return("", fmt.Errorf("not found")).Twice()
+	mt.On("FindImageID", "myapp:dev").Return("sha256:1", nil)
+
+	targets := []pushTarget{{image: "myapp:dev", cluster: config.NewNomadCluster("nomad")}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pushed := 0
+	push := func(pt pushTarget) error {
+		pushed++
+		cancel()
+
+		return fmt.Errorf("boom")
+	}
+
+	err := watchImages(ctx, mt, targets, time.Millisecond, push, hclog.NewNullLogger())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, pushed)
+}
+
 var clusterState = `
 {
   "blueprint": null,
@@ -169,3 +283,37 @@ var clusterState = `
   ]
 }
 `
+
+var clusterNamespaceState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "k3s",
+      "status": "running",
+	  "type": "k8s_cluster",
+	  "containerd_namespace": "shipyard"
+	}
+  ]
+}
+`
+
+var clusterCopyImagesState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "k3s",
+      "status": "applied",
+	  "type": "k8s_cluster",
+	  "copy_images": ["myapp:dev", "api:dev"]
+	},
+	{
+      "name": "nomad",
+      "status": "applied",
+	  "type": "nomad_cluster",
+	  "copy_images": ["myapp:dev"]
+	}
+  ]
+}
+`
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/spf13/cobra"
+)
+
+// blueprintUsage is the resource usage of all the environments created from a blueprint
+type blueprintUsage struct {
+	Blueprint  string  `json:"blueprint"`
+	Runs       int     `json:"runs"`
+	CPUSeconds float64 `json:"cpu_seconds"`
+	PeakMemory uint64  `json:"peak_memory"`
+	Disk       uint64  `json:"disk"`
+	Network    uint64  `json:"network"`
+}
+
+// imagePullUsage is the time spent pulling an image for all runs
+type imagePullUsage struct {
+	Image    string        `json:"image"`
+	Pulls    int           `json:"pulls"`
+	Failures int           `json:"failures"`
+	Retries  int           `json:"retries"`
+	Total    time.Duration `json:"total"`
+	Average  time.Duration `json:"average"`
+	Longest  time.Duration `json:"longest"`
+	Bytes    int64         `json:"bytes"`
+}
+
+func newReportCmd(h clients.History) *cobra.Command {
+	var blueprint string
+	var jsonFlag bool
+	var imagePulls bool
+
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Show the resources used by the environments for each blueprint",
+		Long: `Show the resources used by the environments for each blueprint.
+
+The usage of the Shipyard containers is sampled from the Docker engine after
+an apply and before a destroy and is stored in the history. CPU seconds and
+network traffic are the totals for all runs, peak memory and disk are the
+largest values for any run.
+
+The time spent pulling images for each apply is also recorded in the history,
+use --image-pulls to show the pull timings for each image.`,
+		Example: `
+  # Show the usage for all blueprints
+  shipyard report
+
+  # Show the usage for a blueprint
+  shipyard report --blueprint github.com/shipyard-run/blueprints//consul-nomad
+
+  # Show the time spent pulling each image
+  shipyard report --image-pulls
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := h.Read()
+			if err != nil {
+				return fmt.Errorf("Unable to read history: %s", err)
+			}
+
+			if imagePulls {
+				return printImagePullReport(cmd, imagePullReport(entries, blueprint), jsonFlag)
+			}
+
+			report := []*blueprintUsage{}
+			for _, u := range usageReport(entries) {
+				if blueprint != "" && !strings.Contains(u.Blueprint, blueprint) {
+					continue
+				}
+
+				report = append(report, u)
+			}
+
+			if jsonFlag {
+				d, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("Unable to marshal report: %s", err)
+				}
+
+				cmd.Println(string(d))
+				return nil
+			}
+
+			cmd.Printf("%-6s %-12s %-12s %-12s %-12s %s\n", "RUNS", "CPU SECONDS", "PEAK MEMORY", "DISK", "NETWORK", "BLUEPRINT")
+
+			for _, u := range report {
+				cmd.Printf(
+					"%-6d %-12.1f %-12s %-12s %-12s %s\n",
+					u.Runs,
+					u.CPUSeconds,
+					formatBytes(int64(u.PeakMemory)),
+					formatBytes(int64(u.Disk)),
+					formatBytes(int64(u.Network)),
+					u.Blueprint,
+				)
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	reportCmd.Flags().StringVarP(&blueprint, "blueprint", "", "", "Only show usage for blueprints matching the given source")
+	reportCmd.Flags().BoolVarP(&jsonFlag, "json", "", false, "Output the report as JSON")
+	reportCmd.Flags().BoolVarP(&imagePulls, "image-pulls", "", false, "Show the time spent pulling each image")
+	return reportCmd
+}
+
+func printImagePullReport(cmd *cobra.Command, report []*imagePullUsage, jsonFlag bool) error {
+	if jsonFlag {
+		d, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Unable to marshal report: %s", err)
+		}
+
+		cmd.Println(string(d))
+		return nil
+	}
+
+	cmd.Printf("%-6s %-9s %-8s %-10s %-10s %-10s %-12s %s\n", "PULLS", "FAILURES", "RETRIES", "TOTAL", "AVERAGE", "LONGEST", "SIZE", "IMAGE")
+
+	for _, u := range report {
+		cmd.Printf(
+			"%-6d %-9d %-8d %-10s %-10s %-10s %-12s %s\n",
+			u.Pulls,
+			u.Failures,
+			u.Retries,
+			u.Total.Round(time.Millisecond*100),
+			u.Average.Round(time.Millisecond*100),
+			u.Longest.Round(time.Millisecond*100),
+			formatBytes(u.Bytes),
+			u.Image,
+		)
+	}
+
+	return nil
+}
+
+// imagePullReport totals the image pulls recorded in the history for each image
+// ordered by the total time spent pulling the image, when blueprint is set only
+// the pulls for matching blueprints are included
+func imagePullReport(entries []clients.HistoryEntry, blueprint string) []*imagePullUsage {
+	report := []*imagePullUsage{}
+	byImage := map[string]*imagePullUsage{}
+
+	for _, e := range entries {
+		if blueprint != "" && !strings.Contains(e.Blueprint, blueprint) {
+			continue
+		}
+
+		for _, p := range e.ImagePulls {
+			u, ok := byImage[p.Image]
+			if !ok {
+				u = &imagePullUsage{Image: p.Image}
+				byImage[p.Image] = u
+				report = append(report, u)
+			}
+
+			u.Pulls++
+			u.Total += p.Duration
+
+			if p.Attempts > 1 {
+				u.Retries += p.Attempts - 1
+			}
+
+			if p.Error != "" {
+				u.Failures++
+			}
+
+			if p.Duration > u.Longest {
+				u.Longest = p.Duration
+			}
+
+			if p.Bytes > u.Bytes {
+				u.Bytes = p.Bytes
+			}
+		}
+	}
+
+	for _, u := range report {
+		u.Average = u.Total / time.Duration(u.Pulls)
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].Total > report[j].Total
+	})
+
+	return report
+}
+
+// usageReport totals the usage recorded in the history for each blueprint ordered
+// by CPU seconds. Usage is cumulative for the lifetime of an environment, the samples
+// from the applies and the destroy of an environment are merged and the environment
+// is attributed to the last blueprint which was applied
+func usageReport(entries []clients.HistoryEntry) []*blueprintUsage {
+	report := []*blueprintUsage{}
+	byBlueprint := map[string]*blueprintUsage{}
+
+	var current *clients.ResourceUsage
+	blueprint := ""
+
+	endEnvironment := func() {
+		defer func() { current = nil }()
+
+		if current == nil || blueprint == "" {
+			return
+		}
+
+		u, ok := byBlueprint[blueprint]
+		if !ok {
+			u = &blueprintUsage{Blueprint: blueprint}
+			byBlueprint[blueprint] = u
+			report = append(report, u)
+		}
+
+		u.Runs++
+		u.CPUSeconds += current.CPUSeconds
+		u.Network += current.NetworkRx + current.NetworkTx
+
+		if current.PeakMemory > u.PeakMemory {
+			u.PeakMemory = current.PeakMemory
+		}
+
+		if current.Disk > u.Disk {
+			u.Disk = current.Disk
+		}
+	}
+
+	for _, e := range entries {
+		if e.Command == "apply" && e.Blueprint != "" {
+			blueprint = e.Blueprint
+		}
+
+		if e.Usage != nil {
+			if current == nil {
+				current = &clients.ResourceUsage{}
+			}
+
+			current.Merge(e.Usage)
+		}
+
+		// destroying all resources ends the environment
+		if e.Command == "destroy" && e.Blueprint == "" && e.Result == clients.HistoryResultSuccess {
+			endEnvironment()
+		}
+	}
+
+	// include the running environment
+	endEnvironment()
+
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].CPUSeconds > report[j].CPUSeconds
+	})
+
+	return report
+}
+
+// sampleResourceUsage returns the resource usage of the running containers, the
+// usage is informational so errors are logged and nil is returned
+func sampleResourceUsage(dc clients.Docker, l hclog.Logger) *clients.ResourceUsage {
+	if dc == nil {
+		return nil
+	}
+
+	u, err := clients.SampleResourceUsage(dc)
+	if err != nil {
+		l.Debug("Unable to sample resource usage", "error", err)
+		return nil
+	}
+
+	return u
+}
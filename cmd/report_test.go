@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	assert "github.com/stretchr/testify/require"
+)
+
+var reportEntries = []clients.HistoryEntry{
+	{Time: time.Now(), Command: "apply", Blueprint: "./consul", Result: clients.HistoryResultSuccess, Usage: &clients.ResourceUsage{CPUSeconds: 10, PeakMemory: 1024, NetworkRx: 100}},
+	{Time: time.Now(), Command: "destroy", Result: clients.HistoryResultSuccess, Usage: &clients.ResourceUsage{CPUSeconds: 30, PeakMemory: 2048, NetworkRx: 300, NetworkTx: 100}},
+	{Time: time.Now(), Command: "apply", Blueprint: "./consul", Result: clients.HistoryResultSuccess, Usage: &clients.ResourceUsage{CPUSeconds: 20, PeakMemory: 512, Disk: 4096}},
+	{Time: time.Now(), Command: "destroy", Result: clients.HistoryResultSuccess},
+	{Time: time.Now(), Command: "apply", Blueprint: "./nomad", Result: clients.HistoryResultSuccess, Usage: &clients.ResourceUsage{CPUSeconds: 100, PeakMemory: 4096}},
+}
+
+func setupReport(t *testing.T) (*clients.HistoryMock, *bytes.Buffer) {
+	mh := &clients.HistoryMock{}
+	mh.On("Read").Return(reportEntries, nil)
+
+	return mh, bytes.NewBufferString("")
+}
+
+func TestUsageReportMergesSamplesForEnvironment(t *testing.T) {
+	r := usageReport(reportEntries)
+	assert.Len(t, r, 2)
+
+	assert.Equal(t, "./consul", r[1].Blueprint)
+	assert.Equal(t, 2, r[1].Runs)
+	assert.Equal(t, float64(50), r[1].CPUSeconds)
+	assert.Equal(t, uint64(2048), r[1].PeakMemory)
+	assert.Equal(t, uint64(4096), r[1].Disk)
+	assert.Equal(t, uint64(400), r[1].Network)
+}
+
+func TestUsageReportIncludesRunningEnvironment(t *testing.T) {
+	r := usageReport(reportEntries)
+	assert.Len(t, r, 2)
+
+	assert.Equal(t, "./nomad", r[0].Blueprint)
+	assert.Equal(t, 1, r[0].Runs)
+	assert.Equal(t, float64(100), r[0].CPUSeconds)
+}
+
+func TestReportPrintsUsage(t *testing.T) {
+	mh, out := setupReport(t)
+
+	rc := newReportCmd(mh)
+	rc.SetOut(out)
+
+	err := rc.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "./consul")
+	assert.Contains(t, out.String(), "./nomad")
+	assert.Contains(t, out.String(), "4.0 KiB")
+}
+
+func TestReportFiltersByBlueprint(t *testing.T) {
+	mh, out := setupReport(t)
+
+	rc := newReportCmd(mh)
+	rc.SetOut(out)
+	rc.Flags().Set("blueprint", "nomad")
+
+	err := rc.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "./nomad")
+	assert.NotContains(t, out.String(), "./consul")
+}
+
+func TestReportOutputsJSON(t *testing.T) {
+	mh, out := setupReport(t)
+
+	rc := newReportCmd(mh)
+	rc.SetOut(out)
+	rc.Flags().Set("json", "true")
+
+	err := rc.Execute()
+	assert.NoError(t, err)
+
+	r := []blueprintUsage{}
+	err = json.Unmarshal(out.Bytes(), &r)
+	assert.NoError(t, err)
+	assert.Len(t, r, 2)
+}
+
+var pullEntries = []clients.HistoryEntry{
+	{Time: time.Now(), Command: "apply", Blueprint: "./consul", Result: clients.HistoryResultSuccess, ImagePulls: []clients.ImagePullRecord{
+		{Image: "docker.io/library/consul:1.10.1", Duration: 10 * time.Second, Attempts: 1, Bytes: 1024},
+		{Image: "docker.io/library/envoy:1.18.3", Duration: 2 * time.Second, Attempts: 3, Error: "boom"},
+	}},
+	{Time: time.Now(), Command: "apply", Blueprint: "./nomad", Result: clients.HistoryResultSuccess, ImagePulls: []clients.ImagePullRecord{
+		{Image: "docker.io/library/consul:1.10.1", Duration: 20 * time.Second, Attempts: 2, Bytes: 1024},
+	}},
+}
+
+func TestImagePullReportTotalsPullsForImage(t *testing.T) {
+	r := imagePullReport(pullEntries, "")
+	assert.Len(t, r, 2)
+
+	assert.Equal(t, "docker.io/library/consul:1.10.1", r[0].Image)
+	assert.Equal(t, 2, r[0].Pulls)
+	assert.Equal(t, 1, r[0].Retries)
+	assert.Equal(t, 30*time.Second, r[0].Total)
+	assert.Equal(t, 15*time.Second, r[0].Average)
+	assert.Equal(t, 20*time.Second, r[0].Longest)
+
+	assert.Equal(t, 1, r[1].Failures)
+	assert.Equal(t, 2, r[1].Retries)
+}
+
+func TestImagePullReportFiltersByBlueprint(t *testing.T) {
+	r := imagePullReport(pullEntries, "nomad")
+	assert.Len(t, r, 1)
+
+	assert.Equal(t, 1, r[0].Pulls)
+	assert.Equal(t, 20*time.Second, r[0].Total)
+}
+
+func TestReportPrintsImagePulls(t *testing.T) {
+	mh := &clients.HistoryMock{}
+	mh.On("Read").Return(pullEntries, nil)
+	out := bytes.NewBufferString("")
+
+	rc := newReportCmd(mh)
+	rc.SetOut(out)
+	rc.Flags().Set("image-pulls", "true")
+
+	err := rc.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "docker.io/library/envoy:1.18.3")
+	assert.Contains(t, out.String(), "30s")
+}
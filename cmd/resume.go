@@ -3,12 +3,10 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"time"
 
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/filters"
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/config"
@@ -16,115 +14,167 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var resumeCmd = &cobra.Command{
-	Use:   "resume",
-	Short: "Resume a paused session and restart all resources",
-	Long:  `Resume a paused session and restart all resources`,
-	Example: `
+// resumeTimeout is the time to wait for the resumed containers to start
+const resumeTimeout = 60 * time.Second
+
+func newResumeCmd(dc clients.Docker) *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume",
+		Short: "Resume a paused session and restart all resources",
+		Long: `Resume a paused session and restart all resources, the containers stopped by
+'shipyard pause' are started and the health checks for Helm charts and Kubernetes
+config are run again`,
+		Example: `
   shipyard resume
 	`,
-	Args: cobra.ArbitraryArgs,
-	Run: func(cmd *cobra.Command, args []string) {
-		var err error
-		fmt.Println("Resuming session")
-
-		l := createLogger()
-
-		// create a docker client
-		c, err := clients.NewDocker()
-		if err != nil {
-			l.Error("Unable to connect to Docker daemon", "error", err)
-			os.Exit(1)
-		}
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Println("Resuming session")
 
-		cl, err := getContainers(c, "exited")
-		if err != nil {
-			l.Error("Unable to get container status", "error", err)
-			os.Exit(1)
-		}
+			resumed, containers, err := resumeResources(dc)
+			for _, r := range resumed {
+				cmd.Println("  Resumed", r)
+			}
 
-		// start the containers
-		for _, con := range cl {
-			err := c.ContainerStart(context.Background(), con.ID, types.ContainerStartOptions{})
 			if err != nil {
-				l.Error("Unable to start container", "name", con.Names[0], "error", err)
-				os.Exit(1)
+				return err
 			}
-		}
 
-		l.Info("Checking health of containers")
-		// wait for containers to get healthy
-		_, err = checkStatus(c)
-		if err != nil {
-			l.Error("Uable to check health of containers", "error", err)
-			os.Exit(1)
-		}
+			cmd.Println("Checking health of containers")
 
-		// get the health checks from the config and test
-		con := config.New()
-		err = con.FromJSON(utils.StatePath())
-		if err != nil {
-			l.Error("Unable to load state", "error", err)
-			os.Exit(1)
-		}
+			// wait for containers to get healthy
+			err = waitForContainers(dc, containers, resumeTimeout)
+			if err != nil {
+				return newCommandError(ErrorCodeTimeout, "Unable to check health of containers: %s", err)
+			}
 
-		for _, res := range con.Resources {
-			switch res.Info().Type {
-			case config.TypeHelm:
-				co := res.(*config.Helm)
-				hc := co.HealthCheck
-
-				if hc != nil && len(hc.Pods) != 0 {
-					l.Debug("Health check pods in Helm chart", "chart", co.Info().Name)
-					err := healthCheckHelm(co)
-					if err != nil {
-						l.Error("Unable to check health of helm chart", "error", err)
-						os.Exit(1)
-					}
+			// get the health checks from the config and test
+			con := config.New()
+			err = con.FromJSON(utils.StatePath())
+			if err != nil {
+				return newCommandError(ErrorCodeState, "Unable to load state: %s", err)
+			}
+
+			for _, res := range con.Resources {
+				if res.Info().Status != config.Applied {
+					continue
 				}
-			case config.TypeK8sConfig:
-				co := res.(*config.K8sConfig)
-				hc := co.HealthCheck
-
-				if hc != nil && len(hc.Pods) != 0 {
-					l.Debug("Health check pods in Kubernetes config", "chart", co.Info().Name)
-					err := healthCheckK8sConfig(co)
-					if err != nil {
-						l.Error("Unable to check health of k8s_config chart", "error", err)
-						os.Exit(1)
+
+				switch res.Info().Type {
+				case config.TypeHelm:
+					co := res.(*config.Helm)
+					hc := co.HealthCheck
+
+					if hc != nil && len(hc.Pods) != 0 {
+						err := healthCheckHelm(co)
+						if err != nil {
+							return newCommandError(ErrorCodeTimeout, "Unable to check health of helm chart %s: %s", co.Name, err)
+						}
+					}
+				case config.TypeK8sConfig:
+					co := res.(*config.K8sConfig)
+					hc := co.HealthCheck
+
+					if hc != nil && len(hc.Pods) != 0 {
+						err := healthCheckK8sConfig(co)
+						if err != nil {
+							return newCommandError(ErrorCodeTimeout, "Unable to check health of k8s_config %s: %s", co.Name, err)
+						}
 					}
 				}
 			}
 
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
+
+// resumeResources starts the containers for the paused resources and the containers
+// stopped by the memory monitor, the status of the paused resources is set to applied.
+// Returns the names of the resumed resources and the names of the started containers.
+func resumeResources(dc clients.Docker) ([]string, []string, error) {
+	c := config.New()
+	err := c.FromJSON(utils.StatePath())
+	if err != nil {
+		return nil, nil, newCommandError(ErrorCodeState, "Unable to load state, is an environment running? %s", err)
+	}
+
+	ctx := context.Background()
+	resumed := []string{}
+	containers := []string{}
+
+	var startErr error
+	for _, r := range c.Resources {
+		if r.Info().Status != config.Paused && r.Info().Status != config.Applied {
+			continue
 		}
 
-	},
-}
+		started := false
+		for _, name := range config.LogContainers(r) {
+			info, err := dc.ContainerInspect(ctx, name)
+			if err != nil {
+				// missing containers for created resources are recreated by run
+				if r.Info().Status == config.Applied {
+					continue
+				}
+			} else if info.ContainerJSONBase != nil && info.State != nil && info.State.Running {
+				continue
+			}
 
-func checkStatus(c clients.Docker) (bool, error) {
-	st := time.Now()
+			err = dc.ContainerStart(ctx, name, types.ContainerStartOptions{})
+			if err != nil {
+				startErr = newCommandError(ErrorCodeDockerUnavailable, "Unable to start container %s: %s", name, err)
+				break
+			}
 
-	for {
-		if time.Now().Sub(st) > (60 * time.Second) {
-			return false, fmt.Errorf("Health check timeout waiting for containers to start failed")
+			containers = append(containers, name)
+			started = true
+		}
+
+		if startErr != nil {
+			break
 		}
 
-		// get the container status and check if running
-		cl, err := getContainers(c, "")
-		if err != nil {
-			return false, err
+		if r.Info().Status == config.Paused || started {
+			resumed = append(resumed, fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name))
 		}
 
+		r.Info().Status = config.Applied
+	}
+
+	err = c.ToJSON(utils.StatePath())
+	if err != nil {
+		return resumed, containers, newCommandError(ErrorCodeState, "Unable to save state: %s", err)
+	}
+
+	return resumed, containers, startErr
+}
+
+// waitForContainers blocks until all the containers are running or the timeout expires
+func waitForContainers(dc clients.Docker, containers []string, timeout time.Duration) error {
+	st := time.Now()
+
+	for {
 		allRunning := true
-		for _, con := range cl {
-			if con.State != "running" {
+		for _, name := range containers {
+			info, err := dc.ContainerInspect(context.Background(), name)
+			if err != nil {
+				return err
+			}
+
+			if info.ContainerJSONBase == nil || info.State == nil || !info.State.Running {
 				allRunning = false
 				break
 			}
 		}
 
 		if allRunning {
-			return true, nil
+			return nil
+		}
+
+		if time.Since(st) > timeout {
+			return fmt.Errorf("Health check timeout waiting for containers to start")
 		}
 
 		// wait 1s then try again
@@ -132,28 +182,6 @@ func checkStatus(c clients.Docker) (bool, error) {
 	}
 }
 
-func getContainers(c clients.Docker, status string) ([]types.Container, error) {
-	filters := filters.NewArgs()
-	filters.Add("name", "shipyard")
-
-	if status != "" {
-		filters.Add("status", status)
-	}
-
-	cl, err := c.ContainerList(
-		context.Background(),
-		types.ContainerListOptions{
-			Filters: filters,
-		},
-	)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return cl, nil
-}
-
 // TODO: HealthChecks should really be moved to a central universal functional call
 // copy pasta for now
 func healthCheckHelm(h *config.Helm) error {
@@ -163,7 +191,11 @@ func healthCheckHelm(h *config.Helm) error {
 		return nil
 	}
 
-	_, conf, _ := utils.CreateKubeConfigPath(cl.Info().Name)
+	_, conf, _, err := utils.CreateKubeConfigPath(cl.Info().Name)
+	if err != nil {
+		return err
+	}
+
 	kc, err = kc.SetConfig(conf)
 	if err != nil {
 		return nil
@@ -184,7 +216,11 @@ func healthCheckK8sConfig(h *config.K8sConfig) error {
 		return nil
 	}
 
-	_, conf, _ := utils.CreateKubeConfigPath(cl.Info().Name)
+	_, conf, _, err := utils.CreateKubeConfigPath(cl.Info().Name)
+	if err != nil {
+		return err
+	}
+
 	kc, err = kc.SetConfig(conf)
 	if err != nil {
 		return nil
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	gvm "github.com/shipyard-run/version-manager"
+	"github.com/spf13/cobra"
+)
+
+func newRollbackCmd(e shipyard.Engine, h clients.History, bp clients.Getter, hc clients.HTTP, bc clients.System, vm gvm.Versions, cc clients.Connector, l hclog.Logger) *cobra.Command {
+	var noOpen bool
+	var y bool
+	var noTTY bool
+
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Re-apply the previous successful blueprint and variables",
+		Long: `Re-apply the previous successful blueprint and variables.
+	The history of applied blueprints is used to find the last successful apply
+	which used a different blueprint version or variables to the current environment`,
+		Example: `
+  # Roll back to the previous blueprint
+  shipyard rollback
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := h.Read()
+			if err != nil {
+				return fmt.Errorf("Unable to read history: %s", err)
+			}
+
+			entry := findRollbackEntry(entries)
+			if entry == nil {
+				return fmt.Errorf("Unable to find a previous successful apply to roll back to")
+			}
+
+			cmd.Printf("Rolling back to %s applied at %s\n", entry.Blueprint, entry.Time.Local().Format("2006-01-02 15:04:05"))
+
+			if utils.IsLocalFolder(entry.Blueprint) {
+				cmd.Println("Blueprint is a local folder, changes to the files since the previous apply will not be rolled back")
+			}
+
+			cmd.Println("")
+
+			// convert the variables back to the format used by the run command
+			variables := []string{}
+			for k, v := range entry.Variables {
+				variables = append(variables, fmt.Sprintf("%s=%s", k, v))
+			}
+
+			// the history contains the variables from any profile
+			opts := &runOptions{
+				noOpen:        noOpen,
+				autoApprove:   y,
+				variables:     variables,
+				variablesFile: entry.VariablesFile,
+				overlay:       entry.Overlay,
+				noTTY:         noTTY,
+				timings:       timingsTable,
+			}
+
+			rc := newRunCmdFunc(e, bp, hc, bc, vm, cc, opts, l)
+
+			return rc(cmd, []string{entry.Blueprint})
+		},
+		SilenceUsage: true,
+	}
+
+	rollbackCmd.Flags().BoolVarP(&y, "y", "y", false, "When set, Shipyard will not prompt for confirmation")
+	rollbackCmd.Flags().BoolVarP(&noTTY, "no-tty", "", false, "When set the progress of the apply is written as log messages instead of a live view")
+	rollbackCmd.Flags().BoolVarP(&noOpen, "no-browser", "", false, "When set to true Shipyard will not open the browser windows defined in the blueprint")
+
+	return rollbackCmd
+}
+
+// findRollbackEntry returns the most recent successful apply which used a different
+// blueprint or variables to the last apply, returns nil when there is no entry
+func findRollbackEntry(entries []clients.HistoryEntry) *clients.HistoryEntry {
+	var current *clients.HistoryEntry
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Command != "apply" {
+			continue
+		}
+
+		// the first apply found is the current environment
+		if current == nil {
+			current = &e
+			continue
+		}
+
+		if e.Result != clients.HistoryResultSuccess {
+			continue
+		}
+
+		if e.Blueprint != current.Blueprint || e.VariablesHash != current.VariablesHash || e.Overlay != current.Overlay {
+			return &e
+		}
+	}
+
+	return nil
+}
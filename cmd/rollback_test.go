@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+var rollbackHistory = []clients.HistoryEntry{
+	{Time: time.Now(), Command: "apply", Blueprint: "github.com/shipyard-run/blueprints//consul?ref=v0.1.0", VariablesHash: "abc", Variables: map[string]string{"version": "1.9.0"}, Result: clients.HistoryResultSuccess},
+	{Time: time.Now(), Command: "apply", Blueprint: "github.com/shipyard-run/blueprints//consul?ref=v0.2.0", VariablesHash: "abc", Result: clients.HistoryResultFailed},
+	{Time: time.Now(), Command: "apply", Blueprint: "github.com/shipyard-run/blueprints//consul?ref=v0.2.0", VariablesHash: "abc", Result: clients.HistoryResultSuccess},
+	{Time: time.Now(), Command: "destroy", Result: clients.HistoryResultSuccess},
+	{Time: time.Now(), Command: "apply", Blueprint: "github.com/shipyard-run/blueprints//consul?ref=v0.2.0", VariablesHash: "abc", Result: clients.HistoryResultFailed},
+}
+
+func setupRollback(t *testing.T, entries []clients.HistoryEntry) (*cobra.Command, *runMocks) {
+	_, rm := setupRun(t, "")
+
+	rm.history.On("Read").Return(entries, nil)
+
+	cmd := newRollbackCmd(rm.engine, rm.history, rm.getter, rm.http, rm.system, rm.vm, rm.connector, hclog.NewNullLogger())
+	cmd.SetOut(bytes.NewBuffer([]byte("")))
+
+	return cmd, rm
+}
+
+func TestFindRollbackEntryReturnsPreviousSuccessfulVersion(t *testing.T) {
+	e := findRollbackEntry(rollbackHistory)
+
+	assert.NotNil(t, e)
+	assert.Equal(t, "github.com/shipyard-run/blueprints//consul?ref=v0.1.0", e.Blueprint)
+}
+
+func TestFindRollbackEntryReturnsNilWhenNoPreviousVersion(t *testing.T) {
+	e := findRollbackEntry(rollbackHistory[1:])
+
+	assert.Nil(t, e)
+}
+
+func TestFindRollbackEntryDetectsChangedVariables(t *testing.T) {
+	entries := []clients.HistoryEntry{
+		{Command: "apply", Blueprint: "./", VariablesHash: "abc", Result: clients.HistoryResultSuccess},
+		{Command: "apply", Blueprint: "./", VariablesHash: "def", Result: clients.HistoryResultSuccess},
+	}
+
+	e := findRollbackEntry(entries)
+
+	assert.NotNil(t, e)
+	assert.Equal(t, "abc", e.VariablesHash)
+}
+
+func TestFindRollbackEntryDetectsChangedOverlay(t *testing.T) {
+	entries := []clients.HistoryEntry{
+		{Command: "apply", Blueprint: "./", VariablesHash: "abc", Overlay: "staging", Result: clients.HistoryResultSuccess},
+		{Command: "apply", Blueprint: "./", VariablesHash: "abc", Result: clients.HistoryResultSuccess},
+	}
+
+	e := findRollbackEntry(entries)
+
+	assert.NotNil(t, e)
+	assert.Equal(t, "staging", e.Overlay)
+}
+
+func TestRollbackAppliesPreviousBlueprint(t *testing.T) {
+	rf, rm := setupRollback(t, rollbackHistory)
+	rf.SetArgs([]string{"--no-browser"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	rm.getter.AssertCalled(t, "Get", "github.com/shipyard-run/blueprints//consul?ref=v0.1.0", mock.Anything)
+	rm.engine.AssertCalled(t, "ApplyWithVariables", mock.Anything, map[string]string{"version": "1.9.0"}, "")
+}
+
+func TestRollbackReturnsErrorWhenNoPreviousApply(t *testing.T) {
+	rf, rm := setupRollback(t, rollbackHistory[1:])
+
+	err := rf.Execute()
+	assert.Error(t, err)
+
+	rm.engine.AssertNotCalled(t, "ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything)
+}
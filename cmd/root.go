@@ -3,10 +3,14 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/docker/docker/pkg/term"
 	"github.com/hashicorp/go-hclog"
 	gvm "github.com/shipyard-run/version-manager"
 
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/shipyard"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 
@@ -25,6 +29,25 @@ var engine shipyard.Engine
 var logger hclog.Logger
 var engineClients *shipyard.Clients
 
+// log formats which can be set with the --log-format flag
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// engineLogMaxSize is the size in bytes at which the engine log file is rotated
+const engineLogMaxSize = 10 * 1024 * 1024
+
+// engineLogRetain is the number of engine log files which are kept
+const engineLogRetain = 20
+
+var logOutputFormat = logFormatText
+var engineLog *utils.LogFile
+
+// metricsAddr is the address the Prometheus metrics are served on, metrics
+// are only served when the address is set with --metrics-addr
+var metricsAddr = ""
+
 var version string // set by build process
 var date string    // set by build process
 var commit string  // set by build process
@@ -35,43 +58,155 @@ func init() {
 
 	// setup dependencies
 	logger = createLogger()
-	engine, vm = createEngine(logger)
+
+	// the proxy must be set before the engine creates any clients
+	configureProxy(logger)
+
+	var err error
+	engine, vm, err = createEngine(logger)
+	if err != nil {
+		// the commands can not be created without the engine, exit with the
+		// error rather than a stack trace
+		ce := newCommandError(ErrorCodeDockerUnavailable, "Unable to create the Shipyard engine: %s", err)
+		fmt.Fprintln(os.Stderr, ce)
+		os.Exit(ExitCode(ce))
+	}
+
 	engineClients = engine.GetClients()
 
 	//cobra.OnInitialize(configure)
 
 	//rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default is $HOME/.shipyard/config)")
 
+	// the format is read from the arguments when the logger is created, the
+	// flag is defined so that it is accepted and shown in the help
+	rootCmd.PersistentFlags().StringVar(&logOutputFormat, "log-format", logOutputFormat, "Format for the log output [text, json]")
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", metricsAddr, "Address to serve Prometheus metrics for the engine on e.g. 127.0.0.1:9091, metrics are not served when empty")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if logOutputFormat != logFormatText && logOutputFormat != logFormatJSON {
+			return newCommandError(ErrorCodeUsage, "Invalid value for --log-format '%s', specify text or json", logOutputFormat)
+		}
+
+		if metricsAddr != "" {
+			return startMetrics(metricsAddr, engine, logger)
+		}
+
+		return nil
+	}
+
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(newDoctorCmd(engineClients.Docker, engineClients.Connector))
+	rootCmd.AddCommand(newCheckUpdatesCmd(engineClients.Updates, engineClients.History, engineClients.Browser))
 	rootCmd.AddCommand(outputCmd)
 	rootCmd.AddCommand(newEnvCmd(engine))
 	rootCmd.AddCommand(newRunCmd(engine, engineClients.Getter, engineClients.HTTP, engineClients.Browser, vm, engineClients.Connector, logger))
-	rootCmd.AddCommand(newTestCmd(engine, engineClients.Getter, engineClients.HTTP, engineClients.Browser, logger))
-	rootCmd.AddCommand(pauseCmd)
-	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(newRollbackCmd(engine, engineClients.History, engineClients.Getter, engineClients.HTTP, engineClients.Browser, vm, engineClients.Connector, logger))
+	rootCmd.AddCommand(newServeCmd(engine, engineClients.Getter, engineClients.HTTP, engineClients.Browser, vm, engineClients.Connector, logger))
+	rootCmd.AddCommand(newTestCmd(engine, engineClients.Getter, engineClients.HTTP, engineClients.Browser, vm, logger))
+	rootCmd.AddCommand(newPauseCmd(engineClients.Docker))
+	rootCmd.AddCommand(newResumeCmd(engineClients.Docker))
 	rootCmd.AddCommand(newGetCmd(engineClients.Getter))
-	rootCmd.AddCommand(newDestroyCmd(engineClients.Connector))
-	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(newGraphCmd())
+	rootCmd.AddCommand(newValidateCmd())
+	rootCmd.AddCommand(newFmtCmd())
+	rootCmd.AddCommand(newDestroyCmd(engineClients.Connector, engineClients.History, engineClients.Docker))
+	rootCmd.AddCommand(newStatusCmd(engineClients.Docker))
+	rootCmd.AddCommand(newHistoryCmd(engineClients.History))
+	rootCmd.AddCommand(newReportCmd(engineClients.History))
 	rootCmd.AddCommand(newPurgeCmd(engineClients.Docker, engineClients.ImageLog, logger))
-	rootCmd.AddCommand(taintCmd)
+	rootCmd.AddCommand(newTaintCmd())
 	rootCmd.AddCommand(newExecCmd(engineClients.ContainerTasks))
 	rootCmd.AddCommand(newVersionCmd(vm))
+	rootCmd.AddCommand(newUpgradeCmd(vm))
 	rootCmd.AddCommand(uninstallCmd)
 	rootCmd.AddCommand(newPushCmd(engineClients.ContainerTasks, engineClients.Kubernetes, engineClients.HTTP, engineClients.Nomad, logger))
-	rootCmd.AddCommand(newLogCmd(engine, engineClients.Docker, os.Stdout, os.Stderr), completionCmd)
+	logCmd := newLogCmd(engine, engineClients.Docker, engineClients.Kubernetes, engineClients.Nomad, engineClients.Plugins, os.Stdout, os.Stderr)
+	logCmd.AddCommand(newLogEngineCmd(engineLog))
+	rootCmd.AddCommand(logCmd, completionCmd)
+
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(newCacheStatsCmd(engineClients.ContainerTasks))
+	cacheCmd.AddCommand(newCacheWarmCmd(engineClients.ContainerTasks, logger))
+
+	rootCmd.AddCommand(certCmd)
+	certCmd.AddCommand(newCertTrustCmd(engineClients.Connector))
+	certCmd.AddCommand(newCertUntrustCmd(engineClients.Connector))
+
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(newSnapshotCreateCmd(engineClients.ContainerTasks))
+	snapshotCmd.AddCommand(newSnapshotRestoreCmd(engine, engineClients.ContainerTasks))
+	snapshotCmd.AddCommand(newSnapshotListCmd())
+	snapshotCmd.AddCommand(newSnapshotDeleteCmd())
+
+	rootCmd.AddCommand(blueprintCmd)
+	blueprintLock := clients.NewSourceLock(utils.SourceLockPath())
+	blueprintCmd.AddCommand(newBlueprintListCmd(blueprintLock))
+	blueprintCmd.AddCommand(newBlueprintUpdateCmd(engineClients.Getter, blueprintLock))
+	blueprintCmd.AddCommand(newBlueprintPushCmd(engineClients.Registry))
+
+	rootCmd.AddCommand(fleetCmd)
+	fleetCmd.AddCommand(newFleetListCmd(engineClients.History))
+
+	rootCmd.AddCommand(stateCmd)
+	stateCmd.AddCommand(newStateMigrateCmd())
+
+	rootCmd.AddCommand(dnsCmd)
+	dnsCmd.AddCommand(newDNSServeCmd(engineClients.Docker, logger))
+	dnsCmd.AddCommand(newDNSSetupCmd())
+
+	rootCmd.AddCommand(imagesCmd)
+	imagesCmd.AddCommand(newImagesExportCmd(engineClients.ContainerTasks))
+	imagesCmd.AddCommand(newImagesImportCmd(engineClients.ContainerTasks))
 
 	// add the server commands
 	rootCmd.AddCommand(connectorCmd)
 	connectorCmd.AddCommand(newConnectorRunCommand())
 	connectorCmd.AddCommand(connectorStopCmd)
 	connectorCmd.AddCommand(newConnectorCertCmd())
+	connectorCmd.AddCommand(newConnectorRotateCertsCmd(engineClients.Connector))
+	connectorCmd.AddCommand(newConnectorImportCACmd(engineClients.Connector))
+	connectorCmd.AddCommand(newConnectorJoinTokenCmd(engineClients.Connector))
+	connectorCmd.AddCommand(newConnectorJoinCmd(engineClients.Connector))
+	connectorCmd.AddCommand(newConnectorRemotesCmd())
+	connectorCmd.AddCommand(newConnectorStatusCmd(engineClients.Connector))
+	connectorCmd.AddCommand(newConnectorInstallServiceCmd(engineClients.Connector))
+	connectorCmd.AddCommand(newConnectorUninstallServiceCmd(engineClients.Connector))
+
+	// invalid flags return a usage error
+	rootCmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return &CommandError{Code: ErrorCodeUsage, Err: err}
+	})
+
+	// wrap and translate the help for all commands
+	err = setupHelp(rootCmd, helpLanguage())
+	if err != nil {
+		logger.Error("Unable to load help translations", "error", err)
+	}
 }
 
-func createEngine(l hclog.Logger) (shipyard.Engine, gvm.Versions) {
+// configureProxy sets the proxy for all outbound connections from the user config,
+// errors are logged so that commands which do not make connections can still be used
+func configureProxy(l hclog.Logger) {
+	var p *config.Proxy
+
+	uc, err := config.LoadUserConfig(utils.UserConfigPath())
+	if err != nil {
+		l.Error("Unable to load user config, the proxy from the environment is used", "error", err)
+	} else {
+		p = uc.Proxy
+	}
+
+	err = clients.SetProxy(p)
+	if err != nil {
+		l.Error("Unable to configure proxy", "error", err)
+	}
+}
+
+func createEngine(l hclog.Logger) (shipyard.Engine, gvm.Versions, error) {
 	engine, err := shipyard.New(l)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
 
 	o := gvm.Options{
@@ -106,21 +241,65 @@ func createEngine(l hclog.Logger) (shipyard.Engine, gvm.Versions) {
 		return "shipyard"
 	}
 
-	vm := gvm.New(o)
+	// releases are always verified before they are run or installed
+	vm := &verifiedVersions{gvm.New(o)}
 
-	return engine, vm
+	return engine, vm, nil
 }
 
 func createLogger() hclog.Logger {
+	logOutputFormat = logFormatFromArgs(os.Args[1:])
+
+	// sensitive variables, outputs, and generated passwords are redacted from the logs,
+	// hclog can only detect a terminal when writing directly to a file so color
+	// is set explicitly
+	color := hclog.ColorOff
+	if _, isTerminal := term.GetFdInfo(os.Stderr); isTerminal {
+		color = hclog.ForceColor
+	}
+
+	opts := &hclog.LoggerOptions{Output: utils.NewRedactingWriter(os.Stderr), Color: color, Level: logLevel(), JSONFormat: logOutputFormat == logFormatJSON}
+
+	// an intercept logger allows the run dashboard to show the
+	// messages from the providers
+	l := hclog.NewInterceptLogger(opts)
+
+	// the debug logs for every run are written to a file so that
+	// failed runs can be debugged after the output has gone
+	engineLog = utils.NewLogFile(utils.EngineLogsDir(), engineLogMaxSize, engineLogRetain)
+	l.RegisterSink(hclog.NewSinkAdapter(&hclog.LoggerOptions{Output: utils.NewRedactingWriter(engineLog), Level: hclog.Debug, JSONFormat: opts.JSONFormat}))
+
+	return l
+}
+
+// logLevel returns the level for the logger set with the LOG_LEVEL
+// environment variable, defaults to info
+func logLevel() hclog.Level {
+	if l := hclog.LevelFromString(os.Getenv("LOG_LEVEL")); l != hclog.NoLevel {
+		return l
+	}
 
-	opts := &hclog.LoggerOptions{Color: hclog.AutoColor}
+	return hclog.Info
+}
+
+// logFormatFromArgs returns the value of the --log-format flag, the logger is
+// created before the flags are parsed so the arguments are read directly
+func logFormatFromArgs(args []string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
 
-	// set the log level
-	if lev := os.Getenv("LOG_LEVEL"); lev != "" {
-		opts.Level = hclog.LevelFromString(lev)
+		if strings.HasPrefix(a, "--log-format=") {
+			return strings.TrimPrefix(a, "--log-format=")
+		}
+
+		if a == "--log-format" && i+1 < len(args) {
+			return args[i+1]
+		}
 	}
 
-	return hclog.New(opts)
+	return logFormatText
 }
 
 // Execute the root command
@@ -129,15 +308,34 @@ func Execute(v, c, d string) error {
 	commit = c
 	date = d
 
-	err := rootCmd.Execute()
+	ec, err := rootCmd.ExecuteC()
 
 	if err != nil {
+		// commands with JSON output also write the error as JSON so that
+		// scripts can read the error code
+		if ec != nil && jsonOutput(ec) {
+			writeJSONError(os.Stderr, err)
+		}
+
 		fmt.Println(discordHelp)
 	}
 
 	return err
 }
 
+// jsonOutput returns true when the command has been run with JSON output
+func jsonOutput(c *cobra.Command) bool {
+	if f := c.Flags().Lookup("output"); f != nil && f.Value.String() == "json" {
+		return true
+	}
+
+	if f := c.Flags().Lookup("json"); f != nil && f.Value.String() == "true" {
+		return true
+	}
+
+	return false
+}
+
 var discordHelp = `
 ### For help and support join our community on Discord: https://discord.gg/ZuEFPJU69D ###
 `
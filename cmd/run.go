@@ -25,12 +25,11 @@ import (
 )
 
 func newRunCmd(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc clients.System, vm gvm.Versions, cc clients.Connector, l hclog.Logger) *cobra.Command {
-	var noOpen bool
-	var force bool
-	var y bool
-	var runVersion string
-	var variables []string
-	var variablesFile string
+	opts := &runOptions{}
+	var merge bool
+	notify := &notifyFlags{}
+
+	runFunc := newRunCmdFunc(e, bp, hc, bc, vm, cc, opts, l)
 
 	runCmd := &cobra.Command{
 		Use:   "run [file] [directory] ...",
@@ -38,42 +37,131 @@ func newRunCmd(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc clients
 		Long:  `Run the supplied stack configuration`,
 		Example: `
   # Recursively create a stack from a directory
-  shipyard run ./-stack
+  shipyard run ./my-stack
 
   # Create a stack from a specific file
   shipyard run my-stack/network.hcl
 
   # Create a stack from a blueprint in GitHub
   shipyard run github.com/shipyard-run/blueprints//vault-k8s
+
+  # Create a stack using the variables from a profile defined in the blueprint
+  shipyard run --profile minimal ./my-stack
+
+  # Create a stack with the overlay overrides/staging-sim.hcl merged on top of the blueprint
+  shipyard run --overlay staging-sim ./my-stack
+
+  # Create a stack overriding the values of the helm resource vault
+  shipyard run --helm-set helm.vault.values.server.dev.enabled=true ./my-stack
+
+  # Create a stack on a machine without network access using images imported with 'shipyard images import'
+  shipyard run --offline ./my-stack
+
+  # Record the images, blueprints and charts fetched by the stack, then create the stack in CI using the recorded fixtures
+  shipyard run --record-fixtures ./fixtures ./my-stack
+  shipyard run --replay-fixtures ./fixtures ./my-stack
+
+  # Destroy and re-create the container api when running the stack, other resources are not changed
+  shipyard run --recreate container.api ./my-stack
+
+  # Create a stack and recreate the changed resources every time a file in the blueprint changes
+  shipyard run --watch ./my-stack
+
+  # Add the resources from a team blueprint to the running stack, the team blueprint can use the networks of the stack
+  shipyard run ./platform
+  shipyard run --merge ./team-a
+
+  # Create a stack and write the time taken to create each resource as JSON
+  shipyard run --timings json ./my-stack
+
+  # Create a stack and post a message to Slack as each resource is created
+  shipyard run --slack-webhook https://hooks.slack.com/services/T000/B000/XXX ./my-stack
 	`,
-		Args:         cobra.ArbitraryArgs,
-		RunE:         newRunCmdFunc(e, bp, hc, bc, vm, cc, &noOpen, &force, &runVersion, &y, &variables, &variablesFile, l),
+		Args:              cobra.ArbitraryArgs,
+		ValidArgsFunction: completeBlueprints(clients.NewSourceLock(utils.SourceLockPath())),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateTimingsFormat(opts.timings); err != nil {
+				return err
+			}
+
+			n, err := notify.notifications()
+			if err != nil {
+				return err
+			}
+
+			e.SetNotifications(n)
+			e.SetMerge(merge)
+
+			return runFunc(cmd, args)
+		},
 		SilenceUsage: true,
 	}
 
-	runCmd.Flags().StringVarP(&runVersion, "version", "v", "", "When set, run creates the specified resources using a particular Shipyard version")
-	runCmd.Flags().BoolVarP(&y, "y", "y", false, "When set, Shipyard will not prompt for confirmation")
-	runCmd.Flags().BoolVarP(&noOpen, "no-browser", "", false, "When set to true Shipyard will not open the browser windows defined in the blueprint")
-	runCmd.Flags().BoolVarP(&force, "force-update", "", false, "When set to true Shipyard ignores cached images or files and will download all resources")
-	runCmd.Flags().StringSliceVarP(&variables, "var", "", nil, "Allows setting variables from the command line, variables are specified as a key and value, e.g --var key=value. Can be specified multiple times")
-	runCmd.Flags().StringVarP(&variablesFile, "vars-file", "", "", "Load variables from a location other than *.vars files in the blueprint folder. E.g --vars-file=./file.vars")
+	runCmd.Flags().StringVarP(&opts.version, "version", "v", "", "When set, run creates the specified resources using a particular Shipyard version")
+	runCmd.Flags().BoolVarP(&opts.autoApprove, "y", "y", false, "When set, Shipyard will not prompt for confirmation")
+	runCmd.Flags().BoolVarP(&opts.noOpen, "no-browser", "", false, "When set to true Shipyard will not open the browser windows defined in the blueprint")
+	runCmd.Flags().BoolVarP(&opts.force, "force-update", "", false, "When set to true Shipyard ignores cached images or files and will download all resources")
+	runCmd.Flags().StringSliceVarP(&opts.variables, "var", "", nil, "Allows setting variables from the command line, variables are specified as a key and value, e.g --var key=value. Can be specified multiple times")
+	runCmd.Flags().StringVarP(&opts.variablesFile, "vars-file", "", "", "Load variables from a location other than *.vars files in the blueprint folder. E.g --vars-file=./file.vars")
+	runCmd.Flags().StringVarP(&opts.profile, "profile", "", "", "Run the blueprint with the variables from a profile defined in the blueprint, variables set with --var take precedence. E.g --profile=minimal")
+	runCmd.Flags().StringVarP(&opts.overlay, "overlay", "", "", "Merge the overlay overrides/[name].hcl from the blueprint folder on top of the blueprint. E.g --overlay=staging-sim")
+	runCmd.Flags().StringSliceVarP(&opts.helmSet, "helm-set", "", nil, "Override the values of a helm resource without editing the blueprint, values are merged on top of the values in the blueprint. E.g --helm-set helm.vault.values.server.dev.enabled=true. Can be specified multiple times")
+	runCmd.Flags().BoolVarP(&opts.offline, "offline", "", false, "When set, Shipyard does not pull images from remote registries, images must be imported with 'shipyard images import' or exist in the local cache")
+	runCmd.Flags().StringVarP(&opts.recordFixtures, "record-fixtures", "", "", "Record the digests of the images, and the blueprints, files and Helm charts fetched when creating the stack to the given directory. E.g --record-fixtures=./fixtures")
+	runCmd.Flags().StringSliceVarP(&opts.recreate, "recreate", "", nil, "Destroy and re-create the resource in the state when running the stack, e.g --recreate container.api. Can be specified multiple times")
+	runCmd.Flags().StringVarP(&opts.timings, "timings", "", timingsTable, "Format of the report showing the time taken to create each resource after the stack has been created, one of table, json, none")
+	runCmd.Flags().BoolVarP(&opts.noTTY, "no-tty", "", false, "When set the progress of the apply is written as log messages instead of a live view, the live view is only shown when the output is a terminal")
+	runCmd.Flags().BoolVarP(&opts.watch, "watch", "", false, "Watch the files in a local blueprint after creating the stack and recreate the resources which change every time a file changes, stop watching with Ctrl-C")
+	runCmd.Flags().BoolVarP(&merge, "merge", "", false, "Apply the blueprint into the running stack alongside the resources from other blueprints, resources with the same name as a resource from another blueprint are reported as a conflict")
+	notify.add(runCmd)
+	runCmd.Flags().StringVarP(&opts.replayFixtures, "replay-fixtures", "", "", "Create the stack using the images, blueprints, files and Helm charts recorded with --record-fixtures, fetches which have not been recorded fail. E.g --replay-fixtures=./fixtures")
 
 	return runCmd
 }
 
-func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc clients.System, vm gvm.Versions, cc clients.Connector, noOpen *bool, force *bool, runVersion *string, autoApprove *bool, variables *[]string, variablesFile *string, l hclog.Logger) func(cmd *cobra.Command, args []string) error {
+// runOptions are the options for the run command, the fields are set from the
+// flags of the run command or by commands which re-use the run command
+type runOptions struct {
+	noOpen         bool
+	force          bool
+	autoApprove    bool
+	version        string
+	variables      []string
+	variablesFile  string
+	profile        string
+	overlay        string
+	offline        bool
+	helmSet        []string
+	recordFixtures string
+	replayFixtures string
+	watch          bool
+	recreate       []string
+	noTTY          bool
+	timings        string
+}
+
+func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc clients.System, vm gvm.Versions, cc clients.Connector, opts *runOptions, l hclog.Logger) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		// create the shipyard and sub folders in the users home directory
 		utils.CreateFolders()
 
-		if *force == true {
+		if opts.force == true {
 			bp.SetForce(true)
 			e.GetClients().ContainerTasks.SetForcePull(true)
 		}
 
+		if opts.offline {
+			e.GetClients().ContainerTasks.SetOffline(true)
+		}
+
+		err := setupFixtures(e.GetClients().Fixtures, bp, opts.recordFixtures, opts.replayFixtures)
+		if err != nil {
+			return err
+		}
+
 		// parse the vars into a map
 		vars := map[string]string{}
-		for _, v := range *variables {
+		for _, v := range opts.variables {
 			parts := strings.Split(v, "=")
 			if len(parts) == 2 {
 				vars[parts[0]] = parts[1]
@@ -86,47 +174,25 @@ func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc cli
 			cmd.Println("")
 			cmd.Println("###### SYSTEM DIAGNOSTICS ######")
 			cmd.Println(s)
-			return err
+			return &CommandError{Code: ErrorCodeDockerUnavailable, Err: err}
 		}
 
 		// check the variables file exists
-		if variablesFile != nil && *variablesFile != "" {
-			if _, err := os.Stat(*variablesFile); err != nil {
-				return fmt.Errorf("Variables file %s, does not exist", *variablesFile)
+		if opts.variablesFile != "" {
+			if _, err := os.Stat(opts.variablesFile); err != nil {
+				return newCommandError(ErrorCodeConfig, "Variables file %s, does not exist", opts.variablesFile)
 			}
-		} else {
-			vf := ""
-			variablesFile = &vf
 		}
 
 		// are we running with a different shipyard version, if so check it is installed
-		if *runVersion != "" {
-			return runWithOtherVersion(*runVersion, *autoApprove, args, *force, *noOpen, cmd, vm, bc, *variables, *variablesFile)
-		}
-
-		// create the certificates for the connector
-		if cb, err := cc.GetLocalCertBundle(utils.CertsDir("")); err != nil || cb == nil {
-			// generate certs
-			l.Debug("Generating TLS Certificates for Ingress", "path", utils.CertsDir(""))
-			_, err := cc.GenerateLocalCertBundle(utils.CertsDir(""))
-			if err != nil {
-				return fmt.Errorf("Unable to generate connector certificates: %s", err)
-			}
+		if opts.version != "" {
+			return runWithOtherVersion(opts.version, opts.autoApprove, args, opts.force, opts.noOpen, cmd, vm, bc, opts.variables, opts.variablesFile, opts.profile, opts.overlay, opts.helmSet)
 		}
 
-		// start the connector
-		if !cc.IsRunning() {
-			cb, err := cc.GetLocalCertBundle(utils.CertsDir(""))
-			if err != nil {
-				return fmt.Errorf("Unable to get certificates to secure ingress: %s", err)
-			}
-
-			l.Debug("Starting Ingress")
-
-			err = cc.Start(cb)
-			if err != nil {
-				return fmt.Errorf("Unable to start ingress: %s", err)
-			}
+		// create the certificates and start the connector
+		err = shipyard.StartConnector(cc, l)
+		if err != nil {
+			return err
 		}
 
 		dst := ""
@@ -140,25 +206,96 @@ func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc cli
 			dst = "./"
 		}
 
+		// keep the original source so it can be recorded in the history
+		source := dst
+
+		if opts.watch && !utils.IsLocalFolder(dst) && !utils.IsConfigFile(dst) {
+			return newCommandError(ErrorCodeUsage, "Unable to watch blueprint %s, only local blueprints can be watched", dst)
+		}
+
 		if dst != "" {
 			cmd.Println("Running configuration from: ", dst)
 			cmd.Println("")
 
-			if !utils.IsLocalFolder(dst) && !utils.IsHCLFile(dst) {
-				// fetch the remote server from github
-				err := bp.Get(dst, utils.GetBlueprintLocalFolder(dst))
-				if err != nil {
-					return fmt.Errorf("Unable to retrieve blueprint: %s", err)
+			if !utils.IsLocalFolder(dst) && !utils.IsConfigFile(dst) {
+				if opts.offline {
+					// use the previously downloaded copy of the blueprint
+					if !utils.IsLocalFolder(utils.GetBlueprintLocalFolder(dst)) {
+						return fmt.Errorf("Unable to retrieve blueprint %s when running offline, the blueprint has not been downloaded", dst)
+					}
+				} else {
+					// fetch the remote server from github
+					err := bp.Get(dst, utils.GetBlueprintLocalFolder(dst))
+					if err != nil {
+						return fmt.Errorf("Unable to retrieve blueprint: %s", err)
+					}
 				}
 
 				dst = utils.GetBlueprintLocalFolder(dst)
 			}
 		}
 
+		// merge the environment overlay on top of the blueprint
+		if opts.overlay != "" {
+			if utils.IsConfigFile(dst) {
+				return newCommandError(ErrorCodeUsage, "Unable to use overlay '%s', overlays can only be used with a blueprint folder", opts.overlay)
+			}
+
+			cmd.Println("Using overlay: ", opts.overlay)
+			cmd.Println("")
+		}
+
+		config.SetOverlay(opts.overlay)
+
+		// fallback variants of resources are selected using the capabilities of the engine
+		config.SetHostCapabilities(hostCapabilities(e.GetClients().Docker, l))
+
+		// values set on the command line are merged on top of the helm values in the blueprint
+		overrides, err := config.SetHelmOverrides(opts.helmSet)
+		if err != nil {
+			return &CommandError{Code: ErrorCodeUsage, Err: err}
+		}
+
+		if len(overrides) > 0 {
+			cmd.Println("Using Helm value overrides:")
+			for _, o := range overrides {
+				cmd.Println("  ", o.String())
+			}
+			cmd.Println("")
+		}
+
 		// Parse the config to check it is valid
-		err = e.ParseConfigWithVariables(dst, vars, *variablesFile)
+		err = e.ParseConfigWithVariables(dst, vars, opts.variablesFile)
 		if err != nil {
-			return fmt.Errorf("Unable to read config: %s", err)
+			return newCommandError(ErrorCodeConfig, "Unable to read config: %s", err)
+		}
+
+		// add the variables from the profile and parse the config again
+		// so that the config reflects the profile
+		if opts.profile != "" {
+			if e.Blueprint() == nil {
+				return newCommandError(ErrorCodeConfig, "Unable to use profile '%s', the blueprint does not define any profiles", opts.profile)
+			}
+
+			p, err := e.Blueprint().Profile(opts.profile)
+			if err != nil {
+				return newCommandError(ErrorCodeConfig, "Unable to use profile: %s", err)
+			}
+
+			cmd.Println("Using profile: ", p.Name)
+			cmd.Println("")
+
+			// variables set on the command line override the profile
+			for k, v := range p.Variables {
+				if _, ok := vars[k]; !ok {
+					vars[k] = v
+				}
+			}
+
+			err = e.ParseConfigWithVariables(dst, vars, opts.variablesFile)
+			if err != nil {
+				return newCommandError(ErrorCodeConfig, "Unable to read config: %s", err)
+			}
 		}
 
 		// have we already got a blueprint in the state
@@ -173,28 +310,81 @@ func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc cli
 
 			if !valid || err != nil {
 				// we neeed to go in to the check loop
-				return runWithOtherVersion(e.Blueprint().ShipyardVersion, *autoApprove, args, *force, *noOpen, cmd, vm, bc, *variables, *variablesFile)
+				// the profile has been resolved, pass the variables from the profile to the other version
+				profileVars := []string{}
+				for k, v := range vars {
+					profileVars = append(profileVars, fmt.Sprintf("%s=%s", k, v))
+				}
+
+				return runWithOtherVersion(e.Blueprint().ShipyardVersion, opts.autoApprove, args, opts.force, opts.noOpen, cmd, vm, bc, profileVars, opts.variablesFile, "", opts.overlay, opts.helmSet)
+			}
+		}
+
+		// tainted resources are destroyed and re-created by the apply
+		if len(opts.recreate) > 0 {
+			err := taintResources(opts.recreate)
+			if err != nil {
+				return err
 			}
+
+			cmd.Println("Re-creating: ", strings.Join(opts.recreate, ", "))
+			cmd.Println("")
 		}
 
+		// show a live view of the apply when the output is a terminal,
+		// otherwise the progress is written to the log
+		dashboard := useDashboard(cmd.OutOrStdout(), opts.noTTY)
+
 		// update status every 30s to let people know we are still running
 		statusUpdate := time.NewTicker(15 * time.Second)
 		startTime := time.Now()
 
-		go func() {
-			for range statusUpdate.C {
-				elapsedTime := time.Now().Sub(startTime).Seconds()
-				logger.Info(fmt.Sprintf("Please wait, still creating resources [Elapsed Time: %f]", elapsedTime))
-			}
-		}()
+		if !dashboard {
+			go func() {
+				for range statusUpdate.C {
+					elapsedTime := time.Now().Sub(startTime).Seconds()
+					logger.Info(fmt.Sprintf("Please wait, still creating resources [Elapsed Time: %f]", elapsedTime))
+				}
+			}()
+		}
+
+		// expose the progress of the apply so that external tools can display it
+		progress, unsubscribe := shipyard.NewProgress(e.Events())
+		defer unsubscribe()
+
+		ps := shipyard.NewProgressServer(progress, l)
+		if err := ps.Start(utils.ProgressSocketPath()); err != nil {
+			l.Debug("Unable to start progress server", "error", err)
+		}
+		defer ps.Stop()
+
+		stopDashboard := func() {}
+		if dashboard {
+			stopDashboard = newDashboard(cmd.OutOrStdout(), progress, e.GetClients().ImagePulls).Start(e.Events(), l)
+		}
+
+		res, err := e.ApplyWithVariables(dst, vars, opts.variablesFile)
+
+		stopDashboard()
+
+		usage := sampleResourceUsage(e.GetClients().Docker, l)
+
+		herr := recordHistory(e.GetClients().History, "apply", source, vars, opts.variablesFile, opts.overlay, startTime, usage, e.GetClients().ImagePulls.Records(), err)
+		if herr != nil {
+			l.Error("Unable to record history", "error", herr)
+		}
 
-		res, err := e.ApplyWithVariables(dst, vars, *variablesFile)
 		if err != nil {
-			return fmt.Errorf("Unable to apply blueprint: %s", err)
+			return newCommandError(applyErrorCode(err), "Unable to apply blueprint: %s", err)
+		}
+
+		terr := newTimingReport(res).write(cmd.OutOrStdout(), opts.timings)
+		if terr != nil {
+			l.Error("Unable to write timing report", "error", terr)
 		}
 
 		// do not open the browser windows
-		if *noOpen == false {
+		if opts.noOpen == false {
 
 			browserList := []string{}
 			checkDuration := 30 * time.Second
@@ -243,7 +433,12 @@ func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc cli
 					c := r.(*config.NomadCluster)
 					if c.OpenInBrowser {
 						// get the API port
-						config, _ := utils.GetClusterConfig("nomad_cluster." + c.Name)
+						config, _, err := utils.GetClusterConfig("nomad_cluster." + c.Name)
+						if err != nil {
+							l.Error("Unable to read the cluster config", "cluster", c.Name, "error", err)
+							continue
+						}
+
 						browserList = append(browserList, buildBrowserPath("server."+r.Info().Name, fmt.Sprintf("%d", config.APIPort), r.Info().Type, "/"))
 					}
 				case config.TypeK8sIngress:
@@ -326,8 +521,54 @@ func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc cli
 			}
 		}
 
+		if opts.watch {
+			return watchAndApply(cmd, e, dst, vars, opts.variablesFile, l)
+		}
+
+		return nil
+	}
+}
+
+// hostCapabilities returns the capabilities of the engine which are used to select
+// the fallback variants of resources, nil is returned when the engine can not be probed
+func hostCapabilities(dc clients.Docker, l hclog.Logger) *config.HostCapabilities {
+	if dc == nil {
+		return nil
+	}
+
+	ec, err := clients.ProbeCapabilities(dc)
+	if err != nil {
+		l.Debug("Unable to determine host capabilities, fallbacks will not be used", "error", err)
+		return nil
+	}
+
+	return ec.Host()
+}
+
+// setupFixtures records the external fetches to, or replays them from, the fixtures
+// directory, remote modules are fetched with the Getter so they are also recorded
+func setupFixtures(f *clients.Fixtures, bp clients.Getter, record, replay string) error {
+	if record != "" && replay != "" {
+		return newCommandError(ErrorCodeUsage, "Only one of --record-fixtures or --replay-fixtures can be specified")
+	}
+
+	var err error
+	switch {
+	case record != "":
+		err = f.Record(record)
+	case replay != "":
+		err = f.Replay(replay)
+	default:
 		return nil
 	}
+
+	if err != nil {
+		return fmt.Errorf("Unable to use fixtures: %s", err)
+	}
+
+	config.SetModuleGetter(bp.Get)
+
+	return nil
 }
 
 func buildBrowserPath(n, p string, t config.ResourceType, path string) string {
@@ -366,7 +607,10 @@ func runWithOtherVersion(
 	vm gvm.Versions,
 	sys clients.System,
 	variables []string,
-	variablesFile string) error {
+	variablesFile string,
+	profile string,
+	overlay string,
+	helmSet []string) error {
 
 	var exePath string
 
@@ -438,6 +682,18 @@ func runWithOtherVersion(
 		}
 	}
 
+	if profile != "" {
+		commandString = append(commandString, "--profile="+profile)
+	}
+
+	if overlay != "" {
+		commandString = append(commandString, "--overlay="+overlay)
+	}
+
+	for _, h := range helmSet {
+		commandString = append(commandString, "--helm-set="+h)
+	}
+
 	commandString = append(commandString, args[0])
 
 	execCmd := exec.Command(exePath, commandString...)
@@ -0,0 +1,363 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/term"
+	"github.com/docker/go-units"
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// dashboardInterval is the time between redraws of the dashboard
+const dashboardInterval = 250 * time.Millisecond
+
+// useDashboard returns true when the progress of an apply should be shown as
+// a live dashboard, the dashboard is only shown when the output is a terminal,
+// the log level does not show the detail from the providers, and the log
+// output is not JSON
+func useDashboard(out io.Writer, noTTY bool) bool {
+	if noTTY || logLevel() <= hclog.Debug || logOutputFormat == logFormatJSON {
+		return false
+	}
+
+	_, isTerminal := term.GetFdInfo(out)
+	return isTerminal
+}
+
+// dashboard renders a live view of an apply to a terminal, one line is shown for
+// each resource with its status, elapsed time, the last step logged by its
+// provider, and the progress of the image pull for the resource.
+// While the dashboard is shown the log output is disabled, warnings and errors
+// are collected and written when the dashboard is stopped.
+type dashboard struct {
+	out      io.Writer
+	progress *shipyard.Progress
+	pulls    *clients.ImagePulls
+	width    int
+	now      func() time.Time
+
+	sync     sync.Mutex
+	rows     []*dashboardRow
+	warnings []string
+	lines    int // number of lines written by the last draw
+}
+
+type dashboardRow struct {
+	resource string // e.g. container.consul
+	name     string // name logged by the provider as the ref
+	image    string
+	status   config.Status
+	started  time.Time
+	finished time.Time
+	step     string
+	err      string
+	written  bool // finished rows are only written once
+}
+
+func newDashboard(out io.Writer, progress *shipyard.Progress, pulls *clients.ImagePulls) *dashboard {
+	w, _ := terminalSize(out)
+
+	return &dashboard{
+		out:      out,
+		progress: progress,
+		pulls:    pulls,
+		width:    int(w),
+		now:      time.Now,
+	}
+}
+
+// Start draws the dashboard until the returned function is called, the logger
+// is silenced and the messages logged by the providers are used for the steps
+func (d *dashboard) Start(b *shipyard.EventBus, l hclog.Logger) func() {
+	unsubscribe := b.Subscribe(d.handle, shipyard.ResourceCreateStarted, shipyard.ResourceCreateSucceeded, shipyard.ResourceCreateFailed)
+
+	il, intercept := l.(hclog.InterceptLogger)
+	if intercept {
+		il.RegisterSink(d)
+	}
+
+	l.SetLevel(hclog.Off)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		t := time.NewTicker(dashboardInterval)
+		defer t.Stop()
+
+		for {
+			d.draw()
+
+			select {
+			case <-t.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+
+		unsubscribe()
+		if intercept {
+			il.DeregisterSink(d)
+		}
+
+		l.SetLevel(logLevel())
+
+		d.draw()
+
+		d.sync.Lock()
+		defer d.sync.Unlock()
+
+		if len(d.warnings) > 0 {
+			fmt.Fprintln(d.out)
+			for _, w := range d.warnings {
+				fmt.Fprintln(d.out, w)
+			}
+		}
+	}
+}
+
+func (d *dashboard) handle(e shipyard.Event) {
+	if e.Resource == nil {
+		return
+	}
+
+	d.sync.Lock()
+	defer d.sync.Unlock()
+
+	i := e.Resource.Info()
+	resource := fmt.Sprintf("%s.%s", i.Type, i.Name)
+	if i.Module != "" {
+		resource = fmt.Sprintf("module.%s.%s", i.Module, resource)
+	}
+
+	switch e.Type {
+	case shipyard.ResourceCreateStarted:
+		d.rows = append(d.rows, &dashboardRow{
+			resource: resource,
+			name:     i.Name,
+			image:    resourceImage(e.Resource),
+			status:   config.PendingCreation,
+			started:  e.Time,
+		})
+	case shipyard.ResourceCreateSucceeded, shipyard.ResourceCreateFailed:
+		for _, r := range d.rows {
+			if r.resource != resource || !r.finished.IsZero() {
+				continue
+			}
+
+			r.finished = e.Time
+			r.status = config.Applied
+			r.step = ""
+
+			if e.Error != nil {
+				r.status = config.Failed
+				r.err = e.Error.Error()
+			}
+		}
+	}
+}
+
+// Accept implements the hclog.SinkAdapter interface, messages logged by a provider
+// with the name of a resource which is being created become the step for the resource
+func (d *dashboard) Accept(name string, level hclog.Level, msg string, args ...interface{}) {
+	if level < hclog.Info {
+		return
+	}
+
+	d.sync.Lock()
+	defer d.sync.Unlock()
+
+	if level >= hclog.Warn {
+		d.warnings = append(d.warnings, formatLogMessage(level, msg, args...))
+	}
+
+	ref := ""
+	for i := 0; i+1 < len(args); i += 2 {
+		if k, ok := args[i].(string); ok && k == "ref" {
+			ref = fmt.Sprintf("%v", args[i+1])
+		}
+	}
+
+	if ref == "" {
+		return
+	}
+
+	for _, r := range d.rows {
+		if r.name == ref && r.finished.IsZero() {
+			r.step = msg
+		}
+	}
+}
+
+// draw replaces the previous output of the dashboard with the current state,
+// resources which have finished are written above the lines which are redrawn
+// so that the number of redrawn lines stays within the height of the terminal
+func (d *dashboard) draw() {
+	finished, live := d.render()
+
+	d.sync.Lock()
+	defer d.sync.Unlock()
+
+	// move the cursor to the start of the previous output and clear it
+	if d.lines > 0 {
+		fmt.Fprintf(d.out, "\033[%dA\033[J", d.lines)
+	}
+
+	for _, l := range append(finished, live...) {
+		fmt.Fprintln(d.out, l)
+	}
+
+	d.lines = len(live)
+}
+
+// render returns the lines for the resources which have finished since the
+// last render and the lines for the apply and the resources in progress
+func (d *dashboard) render() ([]string, []string) {
+	d.sync.Lock()
+	defer d.sync.Unlock()
+
+	now := d.now()
+	ps := d.progress.State()
+	pulls := d.pulls.Active()
+	matched := map[string]bool{}
+
+	finished := []string{}
+	live := []string{}
+
+	header := fmt.Sprintf("Creating resources [%d/%d] %s", ps.Completed, ps.Total, formatElapsed(time.Duration(ps.ElapsedSeconds*float64(time.Second))))
+	if ps.ETASeconds > 0 {
+		header += fmt.Sprintf(", about %s remaining", formatElapsed(time.Duration(ps.ETASeconds*float64(time.Second))))
+	}
+
+	live = append(live, header)
+
+	for _, r := range d.rows {
+		if r.written {
+			continue
+		}
+
+		switch r.status {
+		case config.Applied:
+			finished = append(finished, fmt.Sprintf(Green, "[ CREATED ]  ")+d.row(r.resource, r.finished.Sub(r.started), ""))
+			r.written = true
+		case config.Failed:
+			finished = append(finished, fmt.Sprintf(Red, "[ FAILED ]   ")+d.row(r.resource, r.finished.Sub(r.started), r.err))
+			r.written = true
+		default:
+			detail := r.step
+			for _, p := range pulls {
+				if r.image != "" && sameImage(p.Image, r.image) {
+					detail = formatPull(p)
+					matched[p.Image] = true
+				}
+			}
+
+			live = append(live, fmt.Sprintf(Yellow, "[ CREATING ] ")+d.row(r.resource, now.Sub(r.started), detail))
+		}
+	}
+
+	// pulls which can not be matched to a resource such as the images
+	// for clusters are shown on their own line
+	for _, p := range pulls {
+		if !matched[p.Image] {
+			live = append(live, fmt.Sprintf(White, "[ PULLING ]  ")+d.row(p.Image, 0, formatPull(p)))
+		}
+	}
+
+	return finished, live
+}
+
+// row formats the resource, elapsed time, and detail so that the line
+// including the status fits the terminal
+func (d *dashboard) row(resource string, elapsed time.Duration, detail string) string {
+	e := ""
+	if elapsed > 0 {
+		e = formatElapsed(elapsed)
+	}
+
+	s := strings.TrimRight(fmt.Sprintf("%-40s %7s  %s", resource, e, detail), " ")
+
+	max := d.width - 14
+	if max > 3 && len(s) > max {
+		return s[:max-3] + "..."
+	}
+
+	return s
+}
+
+// resourceImage returns the image used by the resource, an empty string
+// is returned for resources which do not have a configurable image
+func resourceImage(r config.Resource) string {
+	switch v := r.(type) {
+	case *config.Container:
+		if v.Image != nil {
+			return v.Image.Name
+		}
+	case *config.Sidecar:
+		return v.Image.Name
+	case *config.ExecRemote:
+		if v.Image != nil {
+			return v.Image.Name
+		}
+	case *config.Docs:
+		if v.Image != nil {
+			return v.Image.Name
+		}
+	}
+
+	return ""
+}
+
+// sameImage compares the canonical name used for a pull with the name of
+// an image in the config e.g. docker.io/library/consul:latest and consul
+func sameImage(canonical, image string) bool {
+	normalize := func(s string) string {
+		s = strings.TrimPrefix(s, "docker.io/")
+		s = strings.TrimPrefix(s, "library/")
+
+		if !strings.Contains(s, "@") && !strings.Contains(s[strings.LastIndex(s, "/")+1:], ":") {
+			s += ":latest"
+		}
+
+		return s
+	}
+
+	return normalize(canonical) == normalize(image)
+}
+
+func formatPull(p clients.ImagePullProgress) string {
+	return fmt.Sprintf("Pulling image %s/%s", units.HumanSize(float64(p.Current)), units.HumanSize(float64(p.Total)))
+}
+
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+
+	return d.Round(time.Second).String()
+}
+
+func formatLogMessage(level hclog.Level, msg string, args ...interface{}) string {
+	parts := []string{fmt.Sprintf("[%s] %s:", strings.ToUpper(level.String()), msg)}
+	for i := 0; i+1 < len(args); i += 2 {
+		parts = append(parts, fmt.Sprintf("%v=%v", args[i], args[i+1]))
+	}
+
+	return utils.Redact(strings.Join(parts, " "))
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+)
+
+func setupDashboard(t *testing.T) (*dashboard, *shipyard.EventBus, *clients.ImagePulls, *bytes.Buffer) {
+	b := shipyard.NewEventBus()
+	p, unsubscribe := shipyard.NewProgress(b)
+	t.Cleanup(unsubscribe)
+
+	ip := clients.NewImagePulls()
+	out := bytes.NewBuffer([]byte{})
+
+	d := newDashboard(out, p, ip)
+	d.width = 200
+
+	return d, b, ip, out
+}
+
+func setupDashboardEvents(t *testing.T) (*dashboard, *shipyard.EventBus, *clients.ImagePulls) {
+	d, b, ip, _ := setupDashboard(t)
+	t.Cleanup(b.Subscribe(d.handle))
+
+	return d, b, ip
+}
+
+func testDashboardContainer(name, image string) *config.Container {
+	c := config.NewContainer(name)
+	c.Image = &config.Image{Name: image}
+
+	return c
+}
+
+func TestUseDashboardReturnsFalseWhenNotTerminal(t *testing.T) {
+	assert.False(t, useDashboard(bytes.NewBuffer([]byte{}), false))
+}
+
+func TestDashboardRendersResourceInProgress(t *testing.T) {
+	d, b, _ := setupDashboardEvents(t)
+
+	b.Publish(shipyard.Event{Type: shipyard.ApplyStarted, Total: 2})
+	b.Publish(shipyard.Event{Type: shipyard.ResourceCreateStarted, Resource: testDashboardContainer("consul", "consul:1.10.0")})
+
+	d.Accept("", hclog.Info, "Creating Container", "ref", "consul")
+
+	finished, live := d.render()
+	assert.Empty(t, finished)
+	assert.Len(t, live, 2)
+	assert.Contains(t, live[0], "Creating resources [0/2]")
+	assert.Contains(t, live[1], "[ CREATING ]")
+	assert.Contains(t, live[1], "container.consul")
+	assert.Contains(t, live[1], "Creating Container")
+}
+
+func TestDashboardRendersPullProgressForResource(t *testing.T) {
+	d, b, ip := setupDashboardEvents(t)
+
+	b.Publish(shipyard.Event{Type: shipyard.ResourceCreateStarted, Resource: testDashboardContainer("consul", "consul:1.10.0")})
+
+	ip.Progress(clients.ImagePullProgress{Image: "docker.io/library/consul:1.10.0", Current: 50000000, Total: 100000000})
+	ip.Progress(clients.ImagePullProgress{Image: "docker.io/rancher/k3s:v1.22.4", Current: 10000000, Total: 200000000})
+
+	_, live := d.render()
+	assert.Len(t, live, 3)
+	assert.Contains(t, live[1], "container.consul")
+	assert.Contains(t, live[1], "Pulling image 50MB/100MB")
+
+	// pulls which are not for a resource are shown on their own line
+	assert.Contains(t, live[2], "[ PULLING ]")
+	assert.Contains(t, live[2], "docker.io/rancher/k3s:v1.22.4")
+}
+
+func TestDashboardWritesFinishedResourcesOnce(t *testing.T) {
+	d, b, _ := setupDashboardEvents(t)
+
+	c := testDashboardContainer("consul", "consul:1.10.0")
+	v := testDashboardContainer("vault", "vault:1.9.0")
+
+	b.Publish(shipyard.Event{Type: shipyard.ResourceCreateStarted, Resource: c})
+	b.Publish(shipyard.Event{Type: shipyard.ResourceCreateStarted, Resource: v})
+	b.Publish(shipyard.Event{Type: shipyard.ResourceCreateSucceeded, Resource: c})
+	b.Publish(shipyard.Event{Type: shipyard.ResourceCreateFailed, Resource: v, Error: fmt.Errorf("unable to pull image")})
+
+	finished, live := d.render()
+	assert.Len(t, finished, 2)
+	assert.Contains(t, finished[0], "[ CREATED ]")
+	assert.Contains(t, finished[1], "[ FAILED ]")
+	assert.Contains(t, finished[1], "unable to pull image")
+	assert.Len(t, live, 1)
+
+	finished, _ = d.render()
+	assert.Empty(t, finished)
+}
+
+func TestDashboardTruncatesLinesToTerminalWidth(t *testing.T) {
+	d, b, _ := setupDashboardEvents(t)
+	d.width = 80
+
+	b.Publish(shipyard.Event{Type: shipyard.ResourceCreateStarted, Resource: testDashboardContainer("consul", "consul:1.10.0")})
+	d.Accept("", hclog.Info, strings.Repeat("a", 100), "ref", "consul")
+
+	_, live := d.render()
+	assert.True(t, strings.HasSuffix(live[1], "..."))
+	assert.Len(t, strings.TrimPrefix(live[1], fmt.Sprintf(Yellow, "[ CREATING ] ")), 66)
+}
+
+func TestDashboardStartSilencesLoggerAndWritesWarnings(t *testing.T) {
+	d, b, _, out := setupDashboard(t)
+
+	logOut := bytes.NewBuffer([]byte{})
+	l := hclog.NewInterceptLogger(&hclog.LoggerOptions{Output: logOut, Level: hclog.Info})
+
+	stop := d.Start(b, l)
+
+	c := testDashboardContainer("consul", "consul:1.10.0")
+	b.Publish(shipyard.Event{Type: shipyard.ResourceCreateStarted, Resource: c})
+	l.Info("Creating Container", "ref", "consul")
+	l.Warn("Unable to connect to health check", "ref", "consul")
+	b.Publish(shipyard.Event{Type: shipyard.ResourceCreateSucceeded, Resource: c, Time: time.Now()})
+
+	stop()
+
+	assert.Empty(t, logOut.String())
+	assert.Contains(t, out.String(), "[ CREATED ]")
+	assert.Contains(t, out.String(), "[WARN] Unable to connect to health check: ref=consul")
+
+	// the log level is restored when the dashboard stops
+	l.Info("Apply complete")
+	assert.Contains(t, logOut.String(), "Apply complete")
+}
+
+func TestSameImageComparesCanonicalNames(t *testing.T) {
+	assert.True(t, sameImage("docker.io/library/consul:latest", "consul"))
+	assert.True(t, sameImage("docker.io/library/consul:1.10.0", "consul:1.10.0"))
+	assert.True(t, sameImage("docker.io/shipyardrun/ingress:latest", "shipyardrun/ingress"))
+	assert.True(t, sameImage("localhost:5000/app:latest", "localhost:5000/app"))
+	assert.False(t, sameImage("docker.io/library/consul:1.10.0", "consul:1.9.0"))
+}
@@ -29,6 +29,7 @@ type runMocks struct {
 	system    *clientmocks.System
 	vm        *gvm.MockVersions
 	connector *clients.ConnectorMock
+	history   *clients.HistoryMock
 }
 
 func setupRun(t *testing.T, timeout string) (*cobra.Command, *runMocks) {
@@ -47,6 +48,7 @@ func setupRun(t *testing.T, timeout string) (*cobra.Command, *runMocks) {
 
 	mockTasks := &clientmocks.MockContainerTasks{}
 	mockTasks.On("SetForcePull", mock.Anything)
+	mockTasks.On("SetOffline", mock.Anything)
 
 	mockConnector := &clients.ConnectorMock{}
 	mockConnector.On("GetLocalCertBundle", mock.Anything).Return(
@@ -67,12 +69,16 @@ func setupRun(t *testing.T, timeout string) (*cobra.Command, *runMocks) {
 		nil,
 	)
 
+	mockHistory := &clients.HistoryMock{}
+	mockHistory.On("Log", mock.Anything).Return(nil)
+
 	clients := &shipyard.Clients{
 		HTTP:           mockHTTP,
 		Getter:         mockGetter,
 		Browser:        mockSystem,
 		ContainerTasks: mockTasks,
 		Connector:      mockConnector,
+		History:        mockHistory,
 	}
 
 	mockEngine := &mocks.Engine{}
@@ -80,6 +86,9 @@ func setupRun(t *testing.T, timeout string) (*cobra.Command, *runMocks) {
 	mockEngine.On("ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
 	mockEngine.On("GetClients", mock.Anything).Return(clients)
 	mockEngine.On("ResourceCountForType", mock.Anything).Return(0)
+	mockEngine.On("Events").Return(shipyard.NewEventBus())
+	mockEngine.On("SetNotifications", mock.Anything).Return()
+	mockEngine.On("SetMerge", mock.Anything).Return()
 
 	bp := config.Blueprint{BrowserWindows: []string{"http://localhost", "http://localhost2"}}
 
@@ -100,6 +109,7 @@ func setupRun(t *testing.T, timeout string) (*cobra.Command, *runMocks) {
 		system:    mockSystem,
 		vm:        vm,
 		connector: mockConnector,
+		history:   mockHistory,
 	}
 
 	cmd := newRunCmd(mockEngine, mockGetter, mockHTTP, mockSystem, vm, mockConnector, hclog.Default())
@@ -118,6 +128,45 @@ func TestRunSetsForceOnGetter(t *testing.T) {
 	rm.getter.AssertCalled(t, "SetForce", true)
 }
 
+func TestRunFuncWithOptionsSetsForceOnGetter(t *testing.T) {
+	_, rm := setupRun(t, "")
+
+	opts := &runOptions{force: true, noOpen: true, timings: timingsNone}
+	rf := newRunCmdFunc(rm.engine, rm.getter, rm.http, rm.system, rm.vm, rm.connector, opts, hclog.Default())
+
+	cmd := &cobra.Command{}
+	cmd.SetOut(bytes.NewBuffer([]byte("")))
+
+	err := rf(cmd, []string{"/tmp"})
+	assert.NoError(t, err)
+
+	rm.getter.AssertCalled(t, "SetForce", true)
+	rm.system.AssertNotCalled(t, "OpenBrowser", mock.Anything)
+}
+
+func TestRunWithMergeSetsMergeOnEngine(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--merge", "/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	rm.engine.AssertCalled(t, "SetMerge", true)
+}
+
+func TestRunRecordsHistory(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	e := getCalls(&rm.history.Mock, "Log")[0].Arguments[0].(clients.HistoryEntry)
+	assert.Equal(t, "apply", e.Command)
+	assert.Equal(t, "/tmp", e.Blueprint)
+	assert.Equal(t, clients.HistoryResultSuccess, e.Result)
+}
+
 func TestRunPreflightsSystem(t *testing.T) {
 	rf, rm := setupRun(t, "")
 	rf.SetArgs([]string{"/tmp"})
@@ -128,6 +177,29 @@ func TestRunPreflightsSystem(t *testing.T) {
 	rm.system.AssertCalled(t, "Preflight")
 }
 
+func TestRunWithPreflightErrorReturnsDockerUnavailable(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	removeOn(&rm.system.Mock, "Preflight")
+	rm.system.On("Preflight").Return(fmt.Errorf("Docker is not running"))
+	rf.SetArgs([]string{"/tmp"})
+
+	err := rf.Execute()
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeDockerUnavailable, ErrorCodeFor(err))
+	assert.Equal(t, 3, ExitCode(err))
+}
+
+func TestRunWithApplyErrorReturnsProvisioningFailed(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	removeOn(&rm.engine.Mock, "ApplyWithVariables")
+	rm.engine.On("ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+	rf.SetArgs([]string{"/tmp"})
+
+	err := rf.Execute()
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeProvisioning, ErrorCodeFor(err))
+}
+
 func TestRunOtherVersionChecksInstalledVersions(t *testing.T) {
 	version := "v0.0.99"
 	rf, rm := setupRun(t, "")
@@ -235,6 +307,29 @@ func TestRunSetsDestinationFromArgsWhenPresent(t *testing.T) {
 	rm.engine.AssertCalled(t, "ApplyWithVariables", "/tmp", mock.Anything, mock.Anything)
 }
 
+func TestRunSetsNotificationsFromFlags(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--webhook=https://ci.example.com/events", "--slack-webhook=https://hooks.slack.com/services/abc", "/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	rm.engine.AssertCalled(t, "SetNotifications", []config.Notification{
+		{Name: "webhook-1", Type: config.NotificationWebhook, URL: "https://ci.example.com/events"},
+		{Name: "slack-1", Type: config.NotificationSlack, URL: "https://hooks.slack.com/services/abc"},
+	})
+}
+
+func TestRunWithInvalidWebhookReturnsError(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--webhook=ci.example.com", "/tmp"})
+
+	err := rf.Execute()
+	assert.Error(t, err)
+
+	rm.engine.AssertNotCalled(t, "ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestRunSetsVariablesFileReturnsErrorWhenMissing(t *testing.T) {
 	rf, _ := setupRun(t, "")
 	rf.SetArgs([]string{"--vars-file=./vars.file", "/tmp"})
@@ -260,6 +355,139 @@ func TestRunSetsVariablesFileWhenPresent(t *testing.T) {
 	rm.engine.AssertCalled(t, "ApplyWithVariables", "/tmp", mock.Anything, tmpFile.Name())
 }
 
+func setupRunProfile(rm *runMocks) {
+	removeOn(&rm.engine.Mock, "Blueprint")
+	rm.engine.On("Blueprint").Return(&config.Blueprint{
+		Profiles: []config.Profile{
+			{Name: "minimal", Variables: map[string]string{"nodes": "1", "monitoring": "false"}},
+		},
+	})
+}
+
+func TestRunWithProfileAppliesProfileVariables(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	setupRunProfile(rm)
+	rf.SetArgs([]string{"--profile=minimal", "/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	rm.engine.AssertCalled(t, "ApplyWithVariables", "/tmp", map[string]string{"nodes": "1", "monitoring": "false"}, "")
+}
+
+func TestRunWithProfileVariablesOverriddenByVar(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	setupRunProfile(rm)
+	rf.SetArgs([]string{"--profile=minimal", "--var=nodes=3", "/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	rm.engine.AssertCalled(t, "ApplyWithVariables", "/tmp", map[string]string{"nodes": "3", "monitoring": "false"}, "")
+}
+
+func TestRunWithUnknownProfileReturnsError(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	setupRunProfile(rm)
+	rf.SetArgs([]string{"--profile=full", "/tmp"})
+
+	err := rf.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "available profiles: minimal")
+
+	rm.engine.AssertNotCalled(t, "ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunWithOverlayRecordsOverlayInHistory(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--overlay=staging-sim", "/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	e := getCalls(&rm.history.Mock, "Log")[0].Arguments[0].(clients.HistoryEntry)
+	assert.Equal(t, "staging-sim", e.Overlay)
+}
+
+func TestRunWithOverlayAndFileReturnsError(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "*.hcl")
+	assert.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--overlay=staging-sim", tmpFile.Name()})
+
+	err = rf.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "overlays can only be used with a blueprint folder")
+
+	rm.engine.AssertNotCalled(t, "ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunWithOfflineSetsOfflineOnContainerTasks(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--offline", "/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	rm.engine.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks).AssertCalled(t, "SetOffline", true)
+}
+
+func TestRunWithOfflineDoesNotFetchRemoteBlueprint(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--offline", "github.com/shipyard-run/blueprints//not-downloaded"})
+
+	err := rf.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "has not been downloaded")
+
+	rm.getter.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+func TestRunWithRecordFixturesRecordsToFolder(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	t.Cleanup(func() { config.SetModuleGetter(nil) })
+
+	f := clients.NewFixtures()
+	rm.engine.GetClients().Fixtures = f
+
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	rf.SetArgs([]string{"--record-fixtures", dir, "/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	assert.True(t, f.Recording())
+	assert.DirExists(t, dir)
+}
+
+func TestRunWithReplayFixturesReturnsErrorWhenNotRecorded(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	t.Cleanup(func() { config.SetModuleGetter(nil) })
+
+	rm.engine.GetClients().Fixtures = clients.NewFixtures()
+
+	rf.SetArgs([]string{"--replay-fixtures", t.TempDir(), "/tmp"})
+
+	err := rf.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to use fixtures")
+
+	rm.engine.AssertNotCalled(t, "ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunWithRecordAndReplayFixturesReturnsError(t *testing.T) {
+	rf, rm := setupRun(t, "")
+
+	rf.SetArgs([]string{"--record-fixtures", t.TempDir(), "--replay-fixtures", t.TempDir(), "/tmp"})
+
+	err := rf.Execute()
+	assert.Error(t, err)
+
+	rm.engine.AssertNotCalled(t, "ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestRunSetsDestinationToDownloadedBlueprintFromArgsWhenRemote(t *testing.T) {
 	rf, rm := setupRun(t, "")
 	rf.SetArgs([]string{"github.com/shipyard-run/blueprints//vault-k8s"})
@@ -370,7 +598,7 @@ func TestRunOpensBrowserWindowForResources(t *testing.T) {
 	// should be opened
 	n1 := config.NewNomadCluster("test")
 	n1.OpenInBrowser = true
-	nomadConfig, _ := utils.GetClusterConfig("nomad_cluster.test")
+	nomadConfig, _, _ := utils.GetClusterConfig("nomad_cluster.test")
 
 	rm.engine.On("ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything).Return(
 		[]config.Resource{d, i, c, d2, i2, c2, n1},
@@ -403,3 +631,72 @@ func TestRunDoesNotOpensBrowserWindowWhenCheckError(t *testing.T) {
 
 	rm.system.AssertNumberOfCalls(t, "OpenBrowser", 0)
 }
+
+func TestRunWithHelmSetPrintsOverrides(t *testing.T) {
+	rf, _ := setupRun(t, "")
+	t.Cleanup(func() { config.SetHelmOverrides(nil) })
+
+	out := bytes.NewBuffer([]byte(""))
+	rf.SetOut(out)
+	rf.SetArgs([]string{"--helm-set", "helm.vault.values.server.dev.enabled=true", "/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "helm.vault.values.server.dev.enabled=true")
+}
+
+func TestRunWithInvalidHelmSetReturnsError(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--helm-set", "vault.server.dev.enabled=true", "/tmp"})
+
+	err := rf.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid helm value")
+
+	rm.engine.AssertNotCalled(t, "ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunWithRecreateTaintsResources(t *testing.T) {
+	c := config.NewContainer("api")
+	c.Status = config.Applied
+	setupWatchState(t, c)
+
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--recreate", "container.api", "/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, _ := sc.FindResource("container.api")
+	assert.Equal(t, config.PendingModification, r.Info().Status)
+
+	rm.engine.AssertCalled(t, "ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunWithRecreateAndUnknownResourceReturnsError(t *testing.T) {
+	setupWatchState(t, config.NewContainer("api"))
+
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--recreate", "container.web", "/tmp"})
+
+	err := rf.Execute()
+	assert.Error(t, err)
+
+	rm.engine.AssertNotCalled(t, "ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunWithWatchAndRemoteBlueprintReturnsError(t *testing.T) {
+	rf, rm := setupRun(t, "")
+	rf.SetArgs([]string{"--watch", "github.com/shipyard-run/blueprints//vault-k8s"})
+
+	err := rf.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only local blueprints can be watched")
+
+	rm.engine.AssertNotCalled(t, "ApplyWithVariables", mock.Anything, mock.Anything, mock.Anything)
+}
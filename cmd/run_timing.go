@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// output formats of the timing report
+const (
+	timingsTable = "table"
+	timingsJSON  = "json"
+	timingsNone  = "none"
+)
+
+// resourceTiming is the time taken to create a resource split into the
+// time spent fetching images, starting, and health checking the resource
+type resourceTiming struct {
+	Resource    string                   `json:"resource"`
+	Started     time.Time                `json:"started"`
+	Finished    time.Time                `json:"finished"`
+	Total       time.Duration            `json:"total"`
+	Image       time.Duration            `json:"image"`
+	Start       time.Duration            `json:"start"`
+	HealthCheck time.Duration            `json:"health_check"`
+	Phases      map[string]time.Duration `json:"phases,omitempty"`
+}
+
+// timingReport is the timing of the resources created by an apply
+type timingReport struct {
+	// Total is the wall clock time from the start of the first resource
+	// to the end of the last, resources are created in parallel so this is
+	// less than the sum of the resource totals
+	Total     time.Duration     `json:"total"`
+	Resources []*resourceTiming `json:"resources"`
+}
+
+func validateTimingsFormat(f string) error {
+	switch f {
+	case timingsTable, timingsJSON, timingsNone:
+		return nil
+	}
+
+	return newCommandError(ErrorCodeUsage, "Invalid value for --timings '%s', must be one of table, json, none", f)
+}
+
+// newTimingReport returns the timing of the resources which were created,
+// resources which were not created have no timing and are not reported
+func newTimingReport(res []config.Resource) *timingReport {
+	tr := &timingReport{Resources: []*resourceTiming{}}
+
+	var first, last time.Time
+	for _, r := range res {
+		t := r.Info().Timing
+		if t == nil || t.Operation != "create" || t.Finished.IsZero() {
+			continue
+		}
+
+		rt := &resourceTiming{
+			Resource:    fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name),
+			Started:     t.Started,
+			Finished:    t.Finished,
+			Total:       t.Duration(),
+			Image:       t.Phase(config.PhasePull) + t.Phase(config.PhaseBuild),
+			HealthCheck: t.Phase(config.PhaseHealthCheck) + t.Phase(config.PhaseWaitFor),
+			Phases:      t.Phases,
+		}
+
+		// the remaining time is spent starting the resource
+		rt.Start = rt.Total - rt.Image - rt.HealthCheck
+		if rt.Start < 0 {
+			rt.Start = 0
+		}
+
+		if first.IsZero() || t.Started.Before(first) {
+			first = t.Started
+		}
+
+		if t.Finished.After(last) {
+			last = t.Finished
+		}
+
+		tr.Resources = append(tr.Resources, rt)
+	}
+
+	// slowest resources first
+	sort.SliceStable(tr.Resources, func(i, j int) bool {
+		return tr.Resources[i].Total > tr.Resources[j].Total
+	})
+
+	tr.Total = last.Sub(first)
+
+	return tr
+}
+
+// write the report in the given format
+func (tr *timingReport) write(w io.Writer, format string) error {
+	switch format {
+	case timingsNone:
+		return nil
+	case timingsJSON:
+		d, err := json.MarshalIndent(tr, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, string(d))
+		return nil
+	}
+
+	if len(tr.Resources) == 0 {
+		return nil
+	}
+
+	var image, start, health time.Duration
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "%-40s %-10s %-10s %-10s %s\n", "RESOURCE", "TOTAL", "IMAGE", "START", "HEALTH CHECK")
+	for _, rt := range tr.Resources {
+		fmt.Fprintf(w, "%-40s %-10s %-10s %-10s %s\n", rt.Resource, roundTiming(rt.Total), roundTiming(rt.Image), roundTiming(rt.Start), roundTiming(rt.HealthCheck))
+
+		image += rt.Image
+		start += rt.Start
+		health += rt.HealthCheck
+	}
+
+	fmt.Fprintf(w, "%-40s %-10s %-10s %-10s %s\n", "total", roundTiming(tr.Total), roundTiming(image), roundTiming(start), roundTiming(health))
+
+	return nil
+}
+
+func roundTiming(d time.Duration) string {
+	return d.Round(time.Millisecond * 100).String()
+}
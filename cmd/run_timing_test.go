@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupTimingReport() []config.Resource {
+	start := time.Now()
+
+	c := config.NewContainer("api")
+	c.Timing = &config.Timing{
+		Operation: "create",
+		Started:   start,
+		Finished:  start.Add(10 * time.Second),
+		Phases: map[string]time.Duration{
+			config.PhasePull:        4 * time.Second,
+			config.PhaseHealthCheck: 3 * time.Second,
+			config.PhaseWaitFor:     time.Second,
+		},
+	}
+
+	n := config.NewNetwork("cloud")
+	n.Timing = &config.Timing{Operation: "create", Started: start, Finished: start.Add(time.Second)}
+
+	// resources which were not created are not reported
+	e := config.NewExecLocal("setup")
+
+	return []config.Resource{n, c, e}
+}
+
+func TestTimingReportSplitsPhases(t *testing.T) {
+	tr := newTimingReport(setupTimingReport())
+
+	assert.Len(t, tr.Resources, 2)
+	assert.Equal(t, 10*time.Second, tr.Total)
+
+	rt := tr.Resources[0]
+	assert.Equal(t, "container.api", rt.Resource)
+	assert.Equal(t, 10*time.Second, rt.Total)
+	assert.Equal(t, 4*time.Second, rt.Image)
+	assert.Equal(t, 4*time.Second, rt.HealthCheck)
+	assert.Equal(t, 2*time.Second, rt.Start)
+}
+
+func TestTimingReportWritesTable(t *testing.T) {
+	out := bytes.NewBufferString("")
+
+	err := newTimingReport(setupTimingReport()).write(out, timingsTable)
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "RESOURCE")
+	assert.Regexp(t, `container.api\s+10s\s+4s\s+2s\s+4s`, out.String())
+	assert.Regexp(t, `total\s+10s\s+4s\s+3s\s+4s`, out.String())
+}
+
+func TestTimingReportWritesJSON(t *testing.T) {
+	out := bytes.NewBufferString("")
+
+	err := newTimingReport(setupTimingReport()).write(out, timingsJSON)
+	assert.NoError(t, err)
+
+	tr := &timingReport{}
+	err = json.Unmarshal(out.Bytes(), tr)
+	assert.NoError(t, err)
+
+	assert.Len(t, tr.Resources, 2)
+	assert.Equal(t, 4*time.Second, tr.Resources[0].Phases[config.PhasePull])
+}
+
+func TestTimingReportWritesNothingForNone(t *testing.T) {
+	out := bytes.NewBufferString("")
+
+	err := newTimingReport(setupTimingReport()).write(out, timingsNone)
+	assert.NoError(t, err)
+
+	assert.Empty(t, out.String())
+}
+
+func TestValidateTimingsFormatReturnsErrorForInvalidFormat(t *testing.T) {
+	assert.NoError(t, validateTimingsFormat(timingsJSON))
+	assert.Error(t, validateTimingsFormat("xml"))
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hashicorp/go-hclog"
+	"github.com/spf13/cobra"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// watchDebounce is the time to wait after a file changes before applying
+// the blueprint, editors often write a file several times when saving
+const watchDebounce = 500 * time.Millisecond
+
+// watchAndApply watches the blueprint and applies the changed resources
+// every time a file in the blueprint changes until the process is interrupted
+func watchAndApply(cmd *cobra.Command, e shipyard.Engine, path string, vars map[string]string, variablesFile string, l hclog.Logger) error {
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	cmd.Println("")
+	cmd.Println("Watching", path, "for changes, press Ctrl-C to stop")
+
+	return watchBlueprint(ctx, path, watchDebounce, func(files []string) {
+		cmd.Println("")
+		cmd.Println("Files changed:", strings.Join(files, ", "))
+
+		changed, err := taintChangedResources(path, vars, variablesFile)
+		if err != nil {
+			cmd.Println("Unable to read config:", err)
+			return
+		}
+
+		for _, r := range changed {
+			cmd.Println("  Recreating", r)
+		}
+
+		_, err = e.ApplyWithVariables(path, vars, variablesFile)
+		if err != nil {
+			cmd.Println("Unable to apply blueprint:", err)
+			return
+		}
+
+		cmd.Println("Blueprint applied, watching for changes")
+	}, l)
+}
+
+// taintChangedResources compares the blueprint with the state and marks the
+// resources which have changed to be recreated by the next apply, returns the
+// names of the changed resources
+func taintChangedResources(path string, vars map[string]string, variablesFile string) ([]string, error) {
+	sc := config.New()
+	err := sc.FromJSON(utils.StatePath())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load state: %s", err)
+	}
+
+	cc := config.New()
+
+	// the cache is needed to parse networks
+	if cache, err := sc.FindResource("docker-cache"); err == nil {
+		cc.AddResource(cache)
+	}
+
+	if utils.IsConfigFile(path) {
+		err = config.ParseSingleFile(path, cc, vars, variablesFile)
+	} else {
+		err = config.ParseFolder(path, cc, false, "", false, []string{}, vars, variablesFile)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	config.ParseReferences(cc)
+
+	diff, err := sc.Diff(cc)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for _, d := range diff {
+		for _, r := range sc.Resources {
+			if r.Info().Name != d.Info().Name || r.Info().Type != d.Info().Type {
+				continue
+			}
+
+			// resources which have not been created are already created by the apply
+			if r.Info().Status == config.Applied || r.Info().Status == config.PendingUpdate {
+				r.Info().Status = config.PendingModification
+				changed = append(changed, fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name))
+			}
+		}
+	}
+
+	if len(changed) == 0 {
+		return changed, nil
+	}
+
+	err = sc.ToJSON(utils.StatePath())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to save state: %s", err)
+	}
+
+	return changed, nil
+}
+
+// watchBlueprint calls apply with the changed files every time the files in the
+// blueprint change, changes are grouped until no files have changed for the
+// debounce interval. Hidden files and folders are ignored. Returns when the
+// context is cancelled.
+func watchBlueprint(ctx context.Context, path string, debounce time.Duration, apply func(files []string), l hclog.Logger) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("Unable to watch blueprint: %s", err)
+	}
+	defer w.Close()
+
+	// when the blueprint is a single file watch the folder and filter the events
+	file := ""
+	if utils.IsConfigFile(path) {
+		file = filepath.Clean(path)
+		path = filepath.Dir(path)
+	}
+
+	err = watchFolders(w, path)
+	if err != nil {
+		return fmt.Errorf("Unable to watch blueprint: %s", err)
+	}
+
+	changed := map[string]bool{}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+
+			if ignoreWatchedFile(ev.Name) || (file != "" && filepath.Clean(ev.Name) != file) {
+				continue
+			}
+
+			// new folders are not watched automatically
+			if ev.Op&fsnotify.Create == fsnotify.Create {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					err := watchFolders(w, ev.Name)
+					if err != nil {
+						l.Error("Unable to watch folder", "folder", ev.Name, "error", err)
+					}
+				}
+			}
+
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+
+			l.Debug("Blueprint file changed", "file", ev.Name, "op", ev.Op.String())
+
+			changed[ev.Name] = true
+			timer.Reset(debounce)
+
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+
+			l.Error("Error watching blueprint", "error", err)
+
+		case <-timer.C:
+			files := []string{}
+			for f := range changed {
+				files = append(files, f)
+			}
+
+			sort.Strings(files)
+			changed = map[string]bool{}
+
+			apply(files)
+		}
+	}
+}
+
+// watchFolders adds the folder and all sub folders to the watcher
+func watchFolders(w *fsnotify.Watcher, path string) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		if p != path && ignoreWatchedFile(p) {
+			return filepath.SkipDir
+		}
+
+		return w.Add(p)
+	})
+}
+
+// ignoreWatchedFile returns true for hidden files and the temporary
+// files created by editors
+func ignoreWatchedFile(path string) bool {
+	n := filepath.Base(path)
+
+	return strings.HasPrefix(n, ".") ||
+		strings.HasSuffix(n, "~") ||
+		strings.HasSuffix(n, ".swp") ||
+		strings.HasSuffix(n, ".swx") ||
+		n == "4913"
+}
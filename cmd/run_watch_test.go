@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func startWatchBlueprint(t *testing.T, path string) chan []string {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	applied := make(chan []string, 10)
+	started := make(chan struct{})
+
+	go func() {
+		close(started)
+		watchBlueprint(ctx, path, 50*time.Millisecond, func(files []string) {
+			applied <- files
+		}, hclog.NewNullLogger())
+	}()
+
+	<-started
+
+	// give the watcher time to add the folders
+	time.Sleep(100 * time.Millisecond)
+
+	return applied
+}
+
+func TestWatchBlueprintAppliesChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	applied := startWatchBlueprint(t, dir)
+
+	err := ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(watchNetwork), os.ModePerm)
+	assert.NoError(t, err)
+
+	select {
+	case files := <-applied:
+		assert.Equal(t, []string{filepath.Join(dir, "main.hcl")}, files)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for apply")
+	}
+}
+
+func TestWatchBlueprintIgnoresHiddenFiles(t *testing.T) {
+	dir := t.TempDir()
+	applied := startWatchBlueprint(t, dir)
+
+	err := ioutil.WriteFile(filepath.Join(dir, ".main.hcl.swp"), []byte(watchNetwork), os.ModePerm)
+	assert.NoError(t, err)
+
+	select {
+	case <-applied:
+		t.Fatal("Hidden files should not be applied")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWatchBlueprintWithFileOnlyAppliesTheFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.hcl")
+
+	err := ioutil.WriteFile(file, []byte(watchNetwork), os.ModePerm)
+	assert.NoError(t, err)
+
+	applied := startWatchBlueprint(t, file)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "other.hcl"), []byte(watchNetwork), os.ModePerm)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(file, []byte(watchNetworkChanged), os.ModePerm)
+	assert.NoError(t, err)
+
+	select {
+	case files := <-applied:
+		assert.Equal(t, []string{file}, files)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for apply")
+	}
+}
+
+func TestTaintChangedResourcesMarksChangedResourcesForRecreation(t *testing.T) {
+	n := config.NewNetwork("local")
+	n.Subnet = "10.6.0.0/16"
+	n.Status = config.Applied
+
+	setupWatchState(t, n)
+
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(watchNetworkChanged), os.ModePerm)
+	assert.NoError(t, err)
+
+	changed, err := taintChangedResources(dir, nil, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"network.local"}, changed)
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err := sc.FindResource("network.local")
+	assert.NoError(t, err)
+	assert.Equal(t, config.PendingModification, r.Info().Status)
+}
+
+func TestTaintChangedResourcesDoesNotChangeUnchangedResources(t *testing.T) {
+	n := config.NewNetwork("local")
+	n.Subnet = "10.6.0.0/16"
+	n.Status = config.Applied
+
+	setupWatchState(t, n)
+
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(watchNetwork), os.ModePerm)
+	assert.NoError(t, err)
+
+	changed, err := taintChangedResources(dir, nil, "")
+	assert.NoError(t, err)
+	assert.Empty(t, changed)
+}
+
+const watchNetwork = `
+network "local" {
+  subnet = "10.6.0.0/16"
+}
+`
+
+const watchNetworkChanged = `
+network "local" {
+  subnet = "10.7.0.0/16"
+}
+`
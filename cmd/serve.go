@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/server"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	gvm "github.com/shipyard-run/version-manager"
+	"github.com/spf13/cobra"
+)
+
+// defaultAPIAddr is the address the API listens on, the API is only
+// reachable from the local machine unless the address is changed
+const defaultAPIAddr = "127.0.0.1:30090"
+
+func newServeCmd(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc clients.System, vm gvm.Versions, cc clients.Connector, l hclog.Logger) *cobra.Command {
+	var addr string
+	var token string
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Starts an API to manage the environment",
+		Long: `Starts a HTTP API which creates and destroys the environment, streams the status
+and logs of resources, and returns the outputs.
+
+Requests must set the token in the Authorization header as a bearer token. When a
+token is not specified with --token or SHIPYARD_API_TOKEN, a token is generated and
+written to $HOME/.shipyard/api_token.`,
+		Example: `
+  # Start the API
+  shipyard serve
+
+  # Create an environment
+  curl -H "Authorization: Bearer $(cat ~/.shipyard/api_token)" \
+    -d '{"blueprint": "github.com/shipyard-run/blueprints//consul-docker"}' \
+    http://127.0.0.1:30090/v1/environment
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if token == "" {
+				token = os.Getenv("SHIPYARD_API_TOKEN")
+			}
+
+			if token == "" {
+				var err error
+				token, err = generateAPIToken()
+				if err != nil {
+					return err
+				}
+
+				cmd.Printf("Generated API token, the token is in %s\n", utils.APITokenPath())
+			}
+
+			env := &engineEnvironment{e: e, bp: bp, hc: hc, bc: bc, vm: vm, cc: cc, l: l}
+			api := server.NewEngineAPI(addr, token, env, e.GetClients().Docker, l)
+
+			sig := make(chan os.Signal, 1)
+			signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+
+			go func() {
+				<-sig
+
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				api.Stop(ctx)
+			}()
+
+			cmd.Printf("API listening on http://%s\n", addr)
+
+			err := api.Start()
+			if err != nil {
+				return newCommandError(ErrorCodeUsage, "Unable to start API on %s: %s", addr, err)
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	serveCmd.Flags().StringVarP(&addr, "addr", "", defaultAPIAddr, "Address the API listens on")
+	serveCmd.Flags().StringVarP(&token, "token", "", "", "Token used to authenticate requests, defaults to SHIPYARD_API_TOKEN or a generated token")
+
+	return serveCmd
+}
+
+// generateAPIToken creates a random token and writes it to the api token file
+// so that local tools can read it
+func generateAPIToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("Unable to generate API token: %s", err)
+	}
+
+	token := hex.EncodeToString(b)
+
+	os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+	err = ioutil.WriteFile(utils.APITokenPath(), []byte(token), 0600)
+	if err != nil {
+		return "", fmt.Errorf("Unable to write API token: %s", err)
+	}
+
+	return token, nil
+}
+
+// engineEnvironment creates and destroys the environment for the API using the
+// same code as the run and destroy commands
+type engineEnvironment struct {
+	e  shipyard.Engine
+	bp clients.Getter
+	hc clients.HTTP
+	bc clients.System
+	vm gvm.Versions
+	cc clients.Connector
+	l  hclog.Logger
+}
+
+func (ee *engineEnvironment) Apply(req server.EnvironmentRequest, log io.Writer) error {
+	variables := []string{}
+	for k, v := range req.Variables {
+		variables = append(variables, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	opts := &runOptions{
+		noOpen:        true,
+		autoApprove:   true,
+		variables:     variables,
+		variablesFile: req.VariablesFile,
+		noTTY:         true,
+		timings:       timingsNone,
+	}
+
+	rc := newRunCmdFunc(ee.e, ee.bp, ee.hc, ee.bc, ee.vm, ee.cc, opts, ee.l)
+
+	cmd := &cobra.Command{}
+	cmd.SetOut(log)
+	cmd.SetErr(log)
+
+	return rc(cmd, []string{req.Blueprint})
+}
+
+func (ee *engineEnvironment) Destroy(log io.Writer) error {
+	dc := newDestroyCmd(ee.cc, ee.e.GetClients().History, ee.e.GetClients().Docker)
+	dc.SetArgs([]string{})
+	dc.SetOut(log)
+	dc.SetErr(log)
+
+	// return the error to the caller rather than printing it
+	dc.SilenceErrors = true
+
+	return dc.Execute()
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestGenerateAPITokenWritesTokenFile(t *testing.T) {
+	setupWatchState(t)
+
+	token, err := generateAPIToken()
+	assert.NoError(t, err)
+	assert.Len(t, token, 64)
+
+	d, err := ioutil.ReadFile(utils.APITokenPath())
+	assert.NoError(t, err)
+	assert.Equal(t, token, string(d))
+
+	fi, err := os.Stat(utils.APITokenPath())
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+}
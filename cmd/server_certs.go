@@ -3,8 +3,11 @@ package cmd
 import (
 	"fmt"
 	"path"
+	"time"
 
 	"github.com/shipyard-run/connector/crypto"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -93,3 +96,82 @@ func newConnectorCertCmd() *cobra.Command {
 
 	return connectorCertCmd
 }
+
+func newConnectorRotateCertsCmd(cc clients.Connector) *cobra.Command {
+	var rotateCA bool
+
+	rotateCmd := &cobra.Command{
+		Use:   "rotate-certs",
+		Short: "Rotate the certificates used by the local connector",
+		Long: `Generates a new leaf certificate for the local connector signed by the existing root CA.
+A running connector loads the new certificate without restarting, connectors also rotate
+the certificate automatically before it expires.`,
+		Example: `
+  # Rotate the leaf certificate
+  shipyard connector rotate-certs
+
+  # Replace the root CA and the leaf certificate
+  shipyard connector rotate-certs --ca
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the trust stores contain the CA which is being replaced
+			trusted := rotateCA && cc.CATrusted(utils.CertsDir(""))
+
+			cb, err := cc.RotateLocalCerts(utils.CertsDir(""), rotateCA)
+			if err != nil {
+				return fmt.Errorf("Unable to rotate connector certificates: %s", err)
+			}
+
+			exp, err := clients.CertExpiry(cb.LeafCertPath)
+			if err != nil {
+				return fmt.Errorf("Unable to read the rotated certificate: %s", err)
+			}
+
+			cmd.Printf("Rotated the connector certificate, the new certificate expires at %s\n", exp.Local().Format(time.RFC1123))
+
+			if rotateCA {
+				cmd.Println()
+				cmd.Println("The root CA has been replaced, restart the connector and recreate any clusters so that they trust the new CA")
+			}
+
+			if trusted {
+				cmd.Println("The previous root CA is still trusted by the system, run 'shipyard cert trust' to trust the new CA")
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	rotateCmd.Flags().BoolVarP(&rotateCA, "ca", "", false, "Replace the root CA as well as the leaf certificate")
+
+	return rotateCmd
+}
+
+func newConnectorImportCACmd(cc clients.Connector) *cobra.Command {
+	return &cobra.Command{
+		Use:   "import-ca [name] [file]",
+		Short: "Trust an external CA",
+		Long: `Adds a PEM encoded CA certificate to the CAs trusted by the local connector so that
+connectors on other machines which have certificates signed by the CA can authenticate.
+A running connector trusts the CA for incoming connections without restarting, the connector
+must be restarted to trust the CA for outgoing connections.`,
+		Example: `
+  # Trust the CA of the connector on another machine
+  shipyard connector import-ca build-server ./root.cert
+	`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := cc.ImportCA(utils.CertsDir(""), args[0], args[1])
+			if err != nil {
+				return fmt.Errorf("Unable to import CA: %s", err)
+			}
+
+			cmd.Printf("Imported CA %s\n", args[0])
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/connector/crypto"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupConnectorCerts(t *testing.T) (*clients.ConnectorMock, *bytes.Buffer) {
+	k, err := crypto.GenerateKeyPair()
+	assert.NoError(t, err)
+
+	c, err := crypto.GenerateCA(k.Private)
+	assert.NoError(t, err)
+
+	leaf := filepath.Join(t.TempDir(), "leaf.cert")
+	assert.NoError(t, c.WriteFile(leaf))
+
+	cc := &clients.ConnectorMock{}
+	cc.On("RotateLocalCerts", mock.Anything, mock.Anything).Return(&clients.CertBundle{LeafCertPath: leaf}, nil)
+	cc.On("ImportCA", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	cc.On("CATrusted", mock.Anything).Return(false)
+
+	return cc, bytes.NewBuffer(nil)
+}
+
+func TestConnectorRotateCertsRotatesLeaf(t *testing.T) {
+	cc, out := setupConnectorCerts(t)
+
+	c := newConnectorRotateCertsCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "RotateLocalCerts", utils.CertsDir(""), false)
+	assert.Contains(t, out.String(), "Rotated the connector certificate")
+	assert.NotContains(t, out.String(), "root CA has been replaced")
+}
+
+func TestConnectorRotateCertsWithCARotatesCA(t *testing.T) {
+	cc, out := setupConnectorCerts(t)
+
+	c := newConnectorRotateCertsCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{"--ca"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "RotateLocalCerts", utils.CertsDir(""), true)
+	assert.Contains(t, out.String(), "root CA has been replaced")
+}
+
+func TestConnectorRotateCertsWithTrustedCAPromptsToTrustNewCA(t *testing.T) {
+	cc, out := setupConnectorCerts(t)
+	removeOn(&cc.Mock, "CATrusted")
+	cc.On("CATrusted", mock.Anything).Return(true)
+
+	c := newConnectorRotateCertsCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{"--ca"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "run 'shipyard cert trust'")
+}
+
+func TestConnectorImportCAImportsCA(t *testing.T) {
+	cc, out := setupConnectorCerts(t)
+
+	c := newConnectorImportCACmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{"build-server", "./root.cert"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "ImportCA", utils.CertsDir(""), "build-server", "./root.cert")
+}
+
+func TestConnectorImportCAReturnsErrorOnFailure(t *testing.T) {
+	cc, out := setupConnectorCerts(t)
+	removeOn(&cc.Mock, "ImportCA")
+	cc.On("ImportCA", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	c := newConnectorImportCACmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{"build-server", "./root.cert"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// defaultConnectorAPIPort is the port of the API of a connector started by Shipyard
+const defaultConnectorAPIPort = "30003"
+
+func newConnectorJoinTokenCmd(cc clients.Connector) *cobra.Command {
+	var address string
+
+	joinTokenCmd := &cobra.Command{
+		Use:   "join-token",
+		Short: "Create a token which allows a connector on another machine to join",
+		Long: `Creates a single use token which allows the connector on another machine to join the
+local connector. The address is the host name or IP address other machines use to reach this
+machine, it is added to the certificate of the connector. The token expires after 15 minutes.`,
+		Example: `
+  # Allow a laptop to join the connector on a lab server
+  shipyard connector join-token --address lab.local
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if address == "" {
+				return newCommandError(ErrorCodeUsage, "The --address flag must be specified")
+			}
+
+			jt, err := cc.CreateJoinToken(utils.CertsDir(""), address)
+			if err != nil {
+				return fmt.Errorf("Unable to create join token: %s", err)
+			}
+
+			cmd.Printf("Created a join token which expires at %s, run the following command on the other machine:\n", jt.Expires.Local().Format(time.RFC1123))
+			cmd.Println()
+			cmd.Printf("  shipyard connector join %s --token %s\n", net.JoinHostPort(address, defaultConnectorAPIPort), jt)
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	joinTokenCmd.Flags().StringVarP(&address, "address", "", "", "Host name or IP address of this machine which is reachable from the other machine")
+
+	return joinTokenCmd
+}
+
+func newConnectorJoinCmd(cc clients.Connector) *cobra.Command {
+	var token string
+
+	joinCmd := &cobra.Command{
+		Use:   "join [address]",
+		Short: "Join the connector on another machine",
+		Long: `Joins the connector on another machine so that ingresses can tunnel services between
+the machines. The connectors exchange and trust each others CA, the token is created on the other
+machine with 'shipyard connector join-token'. The token is not sent to the other machine, the
+CA of the other machine is only trusted when it matches the fingerprint in the token. The address is the address of the API of the other
+connector, the port defaults to 30003.
+
+Once joined, ingresses with the remote driver reference the other machine by the name shown
+by 'shipyard connector remotes'.`,
+		Example: `
+  # Join the connector on a lab server
+  shipyard connector join lab.local:30003 --token 3f8a...
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if token == "" {
+				return newCommandError(ErrorCodeUsage, "The --token flag must be specified")
+			}
+
+			address := args[0]
+			if _, _, err := net.SplitHostPort(address); err != nil {
+				address = net.JoinHostPort(address, defaultConnectorAPIPort)
+			}
+
+			rc, err := cc.Join(utils.CertsDir(""), address, token)
+			if err != nil {
+				return fmt.Errorf("Unable to join connector at %s: %s", address, err)
+			}
+
+			cmd.Printf("Joined %s, the connector is reachable at %s\n", rc.Name, rc.ConnectorAddress)
+
+			if cc.IsRunning() {
+				cmd.Println()
+				cmd.Println("Restart the connector so that it trusts the CA of the other machine for outgoing connections")
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	joinCmd.Flags().StringVarP(&token, "token", "", "", "Join token created on the other machine with 'shipyard connector join-token'")
+
+	return joinCmd
+}
+
+func newConnectorRemotesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remotes",
+		Short: "List the connectors on other machines which have been joined",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rcs, err := clients.ListRemoteConnectors()
+			if err != nil {
+				return fmt.Errorf("Unable to list remote connectors: %s", err)
+			}
+
+			if len(rcs) == 0 {
+				cmd.Println("No machines have been joined, use 'shipyard connector join' to join a machine")
+				return nil
+			}
+
+			cmd.Printf("%-20s %-30s %s\n", "NAME", "CONNECTOR", "JOINED")
+			for _, rc := range rcs {
+				cmd.Printf("%-20s %-30s %s\n", rc.Name, rc.ConnectorAddress, rc.Joined.Local().Format(time.RFC1123))
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
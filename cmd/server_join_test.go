@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupConnectorJoin(t *testing.T) (*clients.ConnectorMock, *bytes.Buffer) {
+	cc := &clients.ConnectorMock{}
+	cc.On("CreateJoinToken", mock.Anything, mock.Anything).Return(&clients.JoinToken{Token: "abc123", CAFingerprint: "f00d", Expires: time.Now()}, nil)
+	cc.On("Join", mock.Anything, mock.Anything, mock.Anything).Return(&clients.RemoteConnector{Name: "lab", ConnectorAddress: "lab.local:30001"}, nil)
+	cc.On("IsRunning").Return(true)
+
+	return cc, bytes.NewBuffer(nil)
+}
+
+func TestConnectorJoinTokenPrintsJoinCommand(t *testing.T) {
+	cc, out := setupConnectorJoin(t)
+
+	c := newConnectorJoinTokenCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{"--address", "lab.local"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "CreateJoinToken", utils.CertsDir(""), "lab.local")
+	assert.Contains(t, out.String(), "shipyard connector join lab.local:30003 --token f00d.abc123")
+}
+
+func TestConnectorJoinTokenRequiresAddress(t *testing.T) {
+	cc, out := setupConnectorJoin(t)
+
+	c := newConnectorJoinTokenCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.Error(t, err)
+
+	cc.AssertNotCalled(t, "CreateJoinToken", mock.Anything, mock.Anything)
+}
+
+func TestConnectorJoinAddsDefaultPort(t *testing.T) {
+	cc, out := setupConnectorJoin(t)
+
+	c := newConnectorJoinCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{"lab.local", "--token", "abc123"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "Join", utils.CertsDir(""), "lab.local:30003", "abc123")
+	assert.Contains(t, out.String(), "Joined lab")
+	assert.Contains(t, out.String(), "Restart the connector")
+}
+
+func TestConnectorJoinRequiresToken(t *testing.T) {
+	cc, out := setupConnectorJoin(t)
+
+	c := newConnectorJoinCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{"lab.local:30003"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+
+	cc.AssertNotCalled(t, "Join", mock.Anything, mock.Anything, mock.Anything)
+}
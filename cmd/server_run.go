@@ -1,32 +1,38 @@
 package cmd
 
 import (
-	"crypto/tls"
-	"crypto/x509"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net"
 	"os"
-	"os/signal"
+	"path/filepath"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/connector/http"
 	"github.com/shipyard-run/connector/protos/shipyard"
 	"github.com/shipyard-run/connector/remote"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/server"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// certCheckInterval is the interval the connector checks if the certificates
+// have been rotated or are about to expire
+var certCheckInterval = time.Minute
+
 func newConnectorRunCommand() *cobra.Command {
 	var grpcBindAddr string
 	var httpBindAddr string
 	var apiBindAddr string
+	var environmentBindAddr string
 	var pathCertRoot string
+	var pathTrustedCerts string
 	var pathCertServer string
 	var pathKeyServer string
 	var logLevel string
@@ -57,42 +63,60 @@ func newConnectorRunCommand() *cobra.Command {
 				lo.Output = f // set the logger to use file output
 			}
 
-			l := hclog.New(&lo)
+			l := hclog.NewInterceptLogger(&lo)
+
+			// connection attempts to remote connectors are only reported in the log
+			// of the gRPC server, the metrics read them from the log
+			metrics := server.NewMetrics()
+			l.RegisterSink(metrics)
 
-			grpcServer := grpc.NewServer()
+			var certs *server.Certificates
+
+			grpcServer := grpc.NewServer(grpc.StatsHandler(metrics))
 			s := remote.New(l.Named("grpc_server"), nil, nil, nil)
 
 			// do we need to set up the server to use TLS?
 			if pathCertServer != "" && pathKeyServer != "" && pathCertRoot != "" {
-				certificate, err := tls.LoadX509KeyPair(pathCertServer, pathKeyServer)
+				var err error
+				certs, err = server.NewCertificates(pathCertRoot, pathTrustedCerts, pathCertServer, pathKeyServer, l.Named("certificates"))
 				if err != nil {
-					return fmt.Errorf("could not load server key pair: %s", err)
+					return err
 				}
 
-				// Create a certificate pool from the certificate authority
-				certPool := x509.NewCertPool()
-				ca, err := ioutil.ReadFile(pathCertRoot)
-				if err != nil {
-					return fmt.Errorf("could not read ca certificate: %s", err)
+				// rotate the leaf certificate before it expires when the root key is available
+				certDir := filepath.Dir(pathCertServer)
+				if _, err := os.Stat(filepath.Join(certDir, "root.key")); err == nil {
+					cc := clients.NewConnector(clients.ConnectorOptions{GrpcBind: grpcBindAddr, HTTPBind: httpBindAddr})
+
+					certs.SetRotateFunc(func() error {
+						_, err := cc.RotateLocalCerts(certDir, false)
+						return err
+					})
 				}
 
-				// Append the client certificates from the CA
-				if ok := certPool.AppendCertsFromPEM(ca); !ok {
-					return errors.New("failed to append client certs")
+				if exp, err := clients.CertExpiry(pathCertRoot); err == nil && time.Until(exp) < server.RotateBefore {
+					l.Warn("Root certificate expires soon, run 'shipyard connector rotate-certs --ca' to replace it", "expiry", exp)
 				}
 
-				creds := credentials.NewTLS(&tls.Config{
-					ClientAuth:   tls.RequireAndVerifyClientCert,
-					Certificates: []tls.Certificate{certificate},
-					ClientCAs:    certPool,
-				})
+				err = certs.Check()
+				if err != nil {
+					l.Error("Unable to check certificates", "error", err)
+				}
 
-				grpcServer = grpc.NewServer(grpc.Creds(creds))
-				s = remote.New(l.Named("grpc_server"), certPool, &certificate, nil)
+				certs.Start(certCheckInterval)
+				defer certs.Stop()
+
+				grpcServer = grpc.NewServer(grpc.Creds(credentials.NewTLS(certs.ServerTLSConfig())), grpc.StatsHandler(metrics))
+				s = remote.New(l.Named("grpc_server"), certs.CertPool(), certs.Certificate(), nil)
 			}
 
 			shipyard.RegisterRemoteConnectionServer(grpcServer, s)
 
+			// the standard gRPC health service allows tools such as grpc_health_probe
+			// to check the connector
+			hs := health.NewServer()
+			healthpb.RegisterHealthServer(grpcServer, hs)
+
 			// create a listener for the server
 			l.Info("Starting gRPC server", "bind_addr", grpcBindAddr)
 			lis, err := net.Listen("tcp", grpcBindAddr)
@@ -119,16 +143,35 @@ func newConnectorRunCommand() *cobra.Command {
 			// we should look at merging the connector server and the API server
 			l.Info("Starting API server", "bind_addr", apiBindAddr)
 			api := server.New(apiBindAddr, l.Named("api_server"))
+
+			// SOCKS proxies create tunnels to remote connectors using the gRPC server
+			api.SetServiceExposer(clients.NewConnector(clients.ConnectorOptions{GrpcBind: grpcBindAddr}))
+			api.SetStatusSources(metrics, s, certs)
+
+			// connectors on other machines join using a token created with 'shipyard connector join-token'
+			if certs != nil {
+				api.SetJoiner(clients.NewConnector(clients.ConnectorOptions{GrpcBind: grpcBindAddr, HTTPBind: httpBindAddr}), filepath.Dir(pathCertServer))
+			}
+
+			// 'shipyard fleet list' on joined machines reads the environment running on this machine,
+			// it is only served over mTLS to connectors with a certificate signed by a trusted CA
+			if certs != nil && environmentBindAddr != "" {
+				l.Info("Starting environment server", "bind_addr", environmentBindAddr)
+				api.SetEnvironmentSource(environmentBindAddr, certs.ServerTLSConfig(), utils.StatePath(), clients.NewHistoryFileLog(utils.HistoryPath()))
+			}
 			api.Start()
 
-			c := make(chan os.Signal, 1)
-			signal.Notify(c, os.Interrupt)
-			signal.Notify(c, os.Kill)
+			// stop the lowest priority resources before the OOM killer stops the clusters
+			stopMonitor := startMemoryMonitor(l.Named("memory_monitor"))
+			defer stopMonitor()
 
-			// Block until a signal is received.
-			sig := <-c
-			log.Println("Got signal:", sig)
+			// Block until a signal is received or the service manager stops the connector
+			err = waitForShutdown()
+			if err != nil {
+				l.Error("Unable to run connector service", "error", err)
+			}
 
+			hs.Shutdown()
 			s.Shutdown()
 
 			return nil
@@ -138,7 +181,9 @@ func newConnectorRunCommand() *cobra.Command {
 	connectorRunCmd.Flags().StringVarP(&grpcBindAddr, "grpc-bind", "", ":9090", "Bind address for the gRPC API")
 	connectorRunCmd.Flags().StringVarP(&httpBindAddr, "http-bind", "", ":9091", "Bind address for the HTTP API")
 	connectorRunCmd.Flags().StringVarP(&apiBindAddr, "api-bind", "", ":9092", "Bind address for the API Server")
+	connectorRunCmd.Flags().StringVarP(&environmentBindAddr, "environment-bind", "", "", "Bind address for the mTLS endpoint which reports the environment to joined machines, disabled when empty")
 	connectorRunCmd.Flags().StringVarP(&pathCertRoot, "root-cert-path", "", "", "Path for the PEM encoded TLS root certificate")
+	connectorRunCmd.Flags().StringVarP(&pathTrustedCerts, "trusted-certs-path", "", "", "Path for a folder containing additional PEM encoded CA certificates trusted by the connector")
 	connectorRunCmd.Flags().StringVarP(&pathCertServer, "server-cert-path", "", "", "Path for the servers PEM encoded TLS certificate")
 	connectorRunCmd.Flags().StringVarP(&pathKeyServer, "server-key-path", "", "", "Path for the servers PEM encoded Private Key")
 	connectorRunCmd.Flags().StringVarP(&logLevel, "log-level", "", "info", "Log output level [debug, trace, info]")
@@ -146,3 +191,30 @@ func newConnectorRunCommand() *cobra.Command {
 
 	return connectorRunCmd
 }
+
+// startMemoryMonitor starts the memory monitor when a memory_pressure policy is set in
+// the user config, the returned function stops the monitor
+func startMemoryMonitor(l hclog.Logger) func() {
+	uc, err := config.LoadUserConfig(utils.UserConfigPath())
+	if err != nil {
+		l.Error("Unable to load user config, memory pressure is not monitored", "error", err)
+		return func() {}
+	}
+
+	if uc.MemoryPressure == nil {
+		return func() {}
+	}
+
+	dc, err := clients.NewDocker()
+	if err != nil {
+		l.Error("Unable to connect to Docker, memory pressure is not monitored", "error", err)
+		return func() {}
+	}
+
+	l.Info("Monitoring memory pressure", "threshold", uc.MemoryPressure.Threshold, "interval", uc.MemoryPressure.IntervalDuration())
+
+	mm := server.NewMemoryMonitor(uc.MemoryPressure, dc, l)
+	mm.Start(uc.MemoryPressure.IntervalDuration())
+
+	return mm.Stop
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"log"
+	"os"
+	"os/signal"
+)
+
+// waitForShutdown blocks until the process receives a signal
+func waitForShutdown() error {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Kill)
+
+	sig := <-c
+	log.Println("Got signal:", sig)
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"golang.org/x/sys/windows/svc"
+)
+
+// waitForShutdown blocks until the process receives a signal, when the connector
+// is started by the service control manager it blocks until the service is stopped
+func waitForShutdown() error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+
+	if isService {
+		return svc.Run(clients.ConnectorServiceName, &connectorService{})
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Kill)
+
+	sig := <-c
+	log.Println("Got signal:", sig)
+
+	return nil
+}
+
+// connectorService handles requests from the service control manager
+type connectorService struct{}
+
+func (s *connectorService) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for c := range r {
+		switch c.Cmd {
+		case svc.Interrogate:
+			changes <- c.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			log.Println("Got service request:", c.Cmd)
+			changes <- svc.Status{State: svc.StopPending}
+			return false, 0
+		}
+	}
+
+	return false, 0
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func newConnectorInstallServiceCmd(cc clients.Connector) *cobra.Command {
+	return &cobra.Command{
+		Use:   "install-service",
+		Short: "Install the connector as a service",
+		Long: `Registers the connector with the operating systems service manager so that ingresses
+survive logout and reboot. The connector is installed as a launchd agent on macOS,
+a systemd user unit on Linux, and a Windows service on Windows.`,
+		Example: `
+  # Install the connector as a service
+  shipyard connector install-service
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cc.ServiceInstalled() {
+				return fmt.Errorf("The connector service is already installed, run 'shipyard connector uninstall-service' to remove it")
+			}
+
+			// stop any connector started by run so the service can bind the ports
+			if cc.IsRunning() {
+				err := cc.Stop()
+				if err != nil {
+					return fmt.Errorf("Unable to stop the running connector: %s", err)
+				}
+			}
+
+			// the service uses the same certificates as the connector started by run
+			cb, err := cc.GetLocalCertBundle(utils.CertsDir(""))
+			if err != nil || cb == nil {
+				cb, err = cc.GenerateLocalCertBundle(utils.CertsDir(""))
+				if err != nil {
+					return fmt.Errorf("Unable to generate connector certificates: %s", err)
+				}
+			}
+
+			err = cc.InstallService(cb)
+			if err != nil {
+				return fmt.Errorf("Unable to install the connector service: %s", err)
+			}
+
+			cmd.Println("Installed the connector service")
+
+			if runtime.GOOS == "linux" {
+				cmd.Println()
+				cmd.Println("To start the connector at boot without logging in run 'loginctl enable-linger $USER'")
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
+
+func newConnectorUninstallServiceCmd(cc clients.Connector) *cobra.Command {
+	return &cobra.Command{
+		Use:   "uninstall-service",
+		Short: "Uninstall the connector service",
+		Long:  `Stops the connector service and removes it from the operating systems service manager`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cc.ServiceInstalled() {
+				cmd.Println("The connector service is not installed")
+				return nil
+			}
+
+			err := cc.UninstallService()
+			if err != nil {
+				return fmt.Errorf("Unable to uninstall the connector service: %s", err)
+			}
+
+			cmd.Println("Uninstalled the connector service")
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
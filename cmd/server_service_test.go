@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupConnectorService(installed, running bool) (*clients.ConnectorMock, *bytes.Buffer) {
+	cc := &clients.ConnectorMock{}
+	cc.On("ServiceInstalled").Return(installed)
+	cc.On("IsRunning").Return(running)
+	cc.On("Stop").Return(nil)
+	cc.On("GetLocalCertBundle", mock.Anything).Return(&clients.CertBundle{}, nil)
+	cc.On("GenerateLocalCertBundle", mock.Anything).Return(&clients.CertBundle{}, nil)
+	cc.On("InstallService", mock.Anything).Return(nil)
+	cc.On("UninstallService").Return(nil)
+
+	return cc, bytes.NewBuffer(nil)
+}
+
+func TestConnectorInstallServiceInstallsService(t *testing.T) {
+	cc, out := setupConnectorService(false, false)
+
+	c := newConnectorInstallServiceCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "InstallService", mock.Anything)
+	cc.AssertNotCalled(t, "Stop")
+	cc.AssertNotCalled(t, "GenerateLocalCertBundle", mock.Anything)
+	assert.Contains(t, out.String(), "Installed the connector service")
+}
+
+func TestConnectorInstallServiceStopsRunningConnector(t *testing.T) {
+	cc, out := setupConnectorService(false, true)
+
+	c := newConnectorInstallServiceCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "Stop")
+	cc.AssertCalled(t, "InstallService", mock.Anything)
+}
+
+func TestConnectorInstallServiceGeneratesCertificates(t *testing.T) {
+	cc, out := setupConnectorService(false, false)
+	removeOn(&cc.Mock, "GetLocalCertBundle")
+	cc.On("GetLocalCertBundle", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	c := newConnectorInstallServiceCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "GenerateLocalCertBundle", mock.Anything)
+	cc.AssertCalled(t, "InstallService", mock.Anything)
+}
+
+func TestConnectorInstallServiceReturnsErrorWhenInstalled(t *testing.T) {
+	cc, out := setupConnectorService(true, true)
+
+	c := newConnectorInstallServiceCmd(cc)
+	c.SetOut(out)
+	c.SetErr(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already installed")
+
+	cc.AssertNotCalled(t, "InstallService", mock.Anything)
+}
+
+func TestConnectorInstallServiceReturnsErrorWhenInstallFails(t *testing.T) {
+	cc, out := setupConnectorService(false, false)
+	removeOn(&cc.Mock, "InstallService")
+	cc.On("InstallService", mock.Anything).Return(fmt.Errorf("boom"))
+
+	c := newConnectorInstallServiceCmd(cc)
+	c.SetOut(out)
+	c.SetErr(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
+
+func TestConnectorUninstallServiceUninstallsService(t *testing.T) {
+	cc, out := setupConnectorService(true, true)
+
+	c := newConnectorUninstallServiceCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "UninstallService")
+}
+
+func TestConnectorUninstallServiceDoesNothingWhenNotInstalled(t *testing.T) {
+	cc, out := setupConnectorService(false, false)
+
+	c := newConnectorUninstallServiceCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	cc.AssertNotCalled(t, "UninstallService")
+	assert.Contains(t, out.String(), "not installed")
+}
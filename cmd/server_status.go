@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/spf13/cobra"
+)
+
+func newConnectorStatusCmd(cc clients.Connector) *cobra.Command {
+	var jsonFlag bool
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of the local connector",
+		Long: `Shows the health, tunnels, and metrics of the local connector.
+
+The connector also exposes the status as JSON at http://localhost:9092/status, Prometheus
+metrics at http://localhost:9092/metrics, and the standard gRPC health service on the
+gRPC port.`,
+		Example: `
+  # Show the status of the connector
+  shipyard connector status
+
+  # Output the status as JSON
+  shipyard connector status --json
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := cc.Status()
+			if err != nil {
+				return fmt.Errorf("Unable to get the connector status, is the connector running? %s", err)
+			}
+
+			if jsonFlag {
+				d, err := json.MarshalIndent(st, "", "  ")
+				if err != nil {
+					return fmt.Errorf("Unable to marshal status: %s", err)
+				}
+
+				cmd.Println(string(d))
+				return nil
+			}
+
+			printConnectorStatus(cmd, st)
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	statusCmd.Flags().BoolVarP(&jsonFlag, "json", "", false, "Output the status as JSON")
+
+	return statusCmd
+}
+
+func printConnectorStatus(cmd *cobra.Command, st *clients.ConnectorStatus) {
+	health := "healthy"
+	if !st.Healthy {
+		health = fmt.Sprintf("unhealthy (%s)", st.Error)
+	}
+
+	cmd.Printf("%-20s %s\n", "Health:", health)
+	cmd.Printf("%-20s %s\n", "Uptime:", st.Uptime.Round(time.Second))
+	cmd.Printf("%-20s %d\n", "Connections:", st.Connections)
+	cmd.Printf("%-20s %s received, %s sent\n", "Transferred:", formatBytes(int64(st.BytesReceived)), formatBytes(int64(st.BytesSent)))
+	cmd.Printf("%-20s %d (%d failed attempts)\n", "Reconnects:", st.Reconnects, st.ConnectFailures)
+	cmd.Printf("%-20s auth %d, router %d, stream %d, socks %d\n", "Proxies:", st.AuthProxies, st.Routers, st.StreamProxies, st.SocksProxies)
+
+	if st.CertificateExpiry != nil {
+		cmd.Printf("%-20s %s\n", "Certificate expiry:", st.CertificateExpiry.Local().Format(time.RFC1123))
+	}
+
+	cmd.Println()
+	cmd.Printf("Tunnels: %d active, %d errored\n", st.ActiveTunnels, st.ErroredTunnels)
+
+	if len(st.Tunnels) == 0 {
+		return
+	}
+
+	cmd.Println()
+	cmd.Printf("%-10s %-8s %-7s %-20s %-24s %s\n", "STATUS", "TYPE", "PORT", "NAME", "REMOTE", "DESTINATION")
+
+	for _, t := range st.Tunnels {
+		cmd.Printf("%-10s %-8s %-7d %-20s %-24s %s\n", t.Status, t.Type, t.SourcePort, t.Name, t.RemoteAddr, t.DestinationAddr)
+	}
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupConnectorStatus(t *testing.T, st *clients.ConnectorStatus, err error) (*clients.ConnectorMock, *bytes.Buffer) {
+	cc := &clients.ConnectorMock{}
+	cc.On("Status").Return(st, err)
+
+	return cc, bytes.NewBuffer(nil)
+}
+
+func TestConnectorStatusPrintsSummary(t *testing.T) {
+	cc, out := setupConnectorStatus(t, &clients.ConnectorStatus{
+		Healthy:       true,
+		Reconnects:    3,
+		BytesSent:     2048,
+		ActiveTunnels: 1,
+		Tunnels: []clients.ConnectorTunnel{
+			{Name: "consul", Type: "remote", Status: "complete", SourcePort: 8500, DestinationAddr: "consul.container.shipyard.run:8500"},
+		},
+	}, nil)
+
+	c := newConnectorStatusCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "healthy")
+	assert.Contains(t, out.String(), "2.0 KiB sent")
+	assert.Contains(t, out.String(), "3 (0 failed attempts)")
+	assert.Contains(t, out.String(), "consul.container.shipyard.run:8500")
+}
+
+func TestConnectorStatusOutputsJSON(t *testing.T) {
+	cc, out := setupConnectorStatus(t, &clients.ConnectorStatus{Healthy: true, ActiveTunnels: 2}, nil)
+
+	c := newConnectorStatusCmd(cc)
+	c.SetOut(out)
+	c.SetArgs([]string{"--json"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	st := &clients.ConnectorStatus{}
+	err = json.Unmarshal(out.Bytes(), st)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, st.ActiveTunnels)
+}
+
+func TestConnectorStatusReturnsErrorWhenNotRunning(t *testing.T) {
+	cc, out := setupConnectorStatus(t, nil, fmt.Errorf("connection refused"))
+
+	c := newConnectorStatusCmd(cc)
+	c.SetOut(out)
+	c.SetErr(out)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is the connector running")
+}
@@ -0,0 +1,435 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore the running environment",
+	Long: `Save the running environment to a snapshot and restore it later, snapshots contain the
+filesystems of the containers, the contents of the Docker volumes, and the state`,
+}
+
+// snapshotNameFormat is the format for snapshot names, names are used as image tags
+var snapshotNameFormat = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// snapshotManifest describes the contents of a snapshot
+type snapshotManifest struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	// Images is the image committed for each container, keyed by resource e.g. container.api
+	Images map[string]string `json:"images"`
+	// Volumes is the names of the Docker volumes in the snapshot
+	Volumes []string `json:"volumes"`
+}
+
+func newSnapshotCreateCmd(ct clients.ContainerTasks) *cobra.Command {
+	return &cobra.Command{
+		Use:   "create [name]",
+		Short: "Create a snapshot of the running environment",
+		Long: `Create a snapshot of the running environment, the filesystem of every container and sidecar
+is committed to an image and the contents of the Docker volumes used by the containers
+are copied to the snapshot along with the state.
+
+Files mounted from the local machine are not part of the snapshot.`,
+		Example: `
+  # Seed the environment and save it as demo
+  shipyard run ./my-stack
+  shipyard snapshot create demo
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := createSnapshot(ct, args[0])
+			if err != nil {
+				return err
+			}
+
+			for r := range m.Images {
+				cmd.Println("Committed", r)
+			}
+
+			for _, v := range m.Volumes {
+				cmd.Println("Copied volume", v)
+			}
+
+			cmd.Println()
+			cmd.Printf("Created snapshot %s, restore it with 'shipyard snapshot restore %s'\n", m.Name, m.Name)
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
+
+func newSnapshotRestoreCmd(e shipyard.Engine, ct clients.ContainerTasks) *cobra.Command {
+	var force bool
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore [name]",
+		Short: "Restore the environment from a snapshot",
+		Long: `Restore the environment from a snapshot, containers are created from the committed images
+and the Docker volumes are replaced with the contents of the snapshot.
+
+The environment must be destroyed before restoring a snapshot, use --force to
+destroy the running environment.`,
+		Example: `
+  # Restore the snapshot demo
+  shipyard snapshot restore demo
+
+  # Destroy the running environment and restore the snapshot demo
+  shipyard snapshot restore --force demo
+	`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: getSnapshotNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := readSnapshotManifest(args[0])
+			if err != nil {
+				return err
+			}
+
+			if _, err := os.Stat(utils.StatePath()); err == nil {
+				if !force {
+					return newCommandError(ErrorCodeUsage, "An environment is already running, destroy it or use --force to replace it")
+				}
+
+				cmd.Println("Destroying the running environment")
+
+				err = e.Destroy("", true)
+				if err != nil {
+					return newCommandError(ErrorCodePartialDestroy, "Unable to destroy the running environment: %s", err)
+				}
+			}
+
+			err = restoreSnapshot(ct, m)
+			if err != nil {
+				return err
+			}
+
+			cmd.Printf("Restoring snapshot %s\n", m.Name)
+
+			_, err = e.Apply("")
+			if err != nil {
+				return newCommandError(applyErrorCode(err), "Unable to restore snapshot: %s", err)
+			}
+
+			cmd.Println()
+			cmd.Printf("Restored snapshot %s\n", m.Name)
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	restoreCmd.Flags().BoolVarP(&force, "force", "f", false, "Destroy the running environment before restoring the snapshot")
+
+	return restoreCmd
+}
+
+func newSnapshotListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the snapshots",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snapshots, err := listSnapshots()
+			if err != nil {
+				return err
+			}
+
+			if len(snapshots) == 0 {
+				cmd.Println("No snapshots found")
+				return nil
+			}
+
+			cmd.Printf("%-20s %-20s %-10s %s\n", "NAME", "CREATED", "IMAGES", "VOLUMES")
+			for _, s := range snapshots {
+				cmd.Printf("%-20s %-20s %-10d %d\n", s.Name, s.Created.Local().Format("2006-01-02 15:04:05"), len(s.Images), len(s.Volumes))
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
+
+func newSnapshotDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete [name]",
+		Short: "Delete a snapshot",
+		Long: `Delete a snapshot, the images committed for the snapshot are left in the local cache
+and are removed by 'shipyard purge'`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: getSnapshotNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := readSnapshotManifest(args[0])
+			if err != nil {
+				return err
+			}
+
+			err = os.RemoveAll(snapshotDir(m.Name))
+			if err != nil {
+				return fmt.Errorf("Unable to delete snapshot %s: %s", m.Name, err)
+			}
+
+			cmd.Println("Deleted snapshot", m.Name)
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
+
+// createSnapshot commits the containers and copies the volumes and state for the
+// running environment to the snapshot
+func createSnapshot(ct clients.ContainerTasks, name string) (*snapshotManifest, error) {
+	if !snapshotNameFormat.MatchString(name) {
+		return nil, newCommandError(ErrorCodeUsage, "Invalid snapshot name %s, names can only contain lowercase letters, numbers, '_', '.', and '-'", name)
+	}
+
+	dir := snapshotDir(name)
+	if _, err := os.Stat(dir); err == nil {
+		return nil, newCommandError(ErrorCodeUsage, "Snapshot %s already exists", name)
+	}
+
+	sc := config.New()
+	err := sc.FromJSON(utils.StatePath())
+	if err != nil {
+		return nil, newCommandError(ErrorCodeState, "Unable to load state, is an environment running? %s", err)
+	}
+
+	m := &snapshotManifest{Name: name, Created: time.Now().UTC(), Images: map[string]string{}, Volumes: []string{}}
+
+	volumes := map[string]bool{}
+	for _, r := range sc.Resources {
+		if r.Info().Status == config.Disabled {
+			continue
+		}
+
+		var vols []config.Volume
+		switch v := r.(type) {
+		case *config.Container:
+			vols = v.Volumes
+		case *config.Sidecar:
+			vols = v.Volumes
+		default:
+			continue
+		}
+
+		ids, err := ct.FindContainerIDs(r.Info().Name, r.Info().Type)
+		if err != nil || len(ids) == 0 {
+			return nil, newCommandError(ErrorCodeState, "Unable to find the container for %s.%s", r.Info().Type, r.Info().Name)
+		}
+
+		image := snapshotImage(name, r)
+		err = ct.CommitContainer(ids[0], image)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to commit %s.%s: %s", r.Info().Type, r.Info().Name, err)
+		}
+
+		m.Images[fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)] = image
+
+		for _, v := range vols {
+			if v.Type == "volume" {
+				volumes[v.Source] = true
+			}
+		}
+	}
+
+	for v := range volumes {
+		m.Volumes = append(m.Volumes, v)
+	}
+
+	sort.Strings(m.Volumes)
+
+	err = writeSnapshot(ct, dir, m)
+	if err != nil {
+		// do not leave a partial snapshot
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// writeSnapshot copies the volumes and the state to the snapshot folder
+// and writes the manifest
+func writeSnapshot(ct clients.ContainerTasks, dir string, m *snapshotManifest) error {
+	// the state and the volumes can contain credentials, only the user can read the snapshot
+	err := os.MkdirAll(filepath.Join(dir, "volumes"), 0700)
+	if err != nil {
+		return fmt.Errorf("Unable to create snapshot folder: %s", err)
+	}
+
+	for _, v := range m.Volumes {
+		f, err := os.OpenFile(snapshotVolumePath(m.Name, v), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("Unable to create archive for volume %s: %s", v, err)
+		}
+
+		err = ct.ExportVolume(v, f)
+		f.Close()
+
+		if err != nil {
+			return fmt.Errorf("Unable to copy volume %s: %s", v, err)
+		}
+	}
+
+	state, err := ioutil.ReadFile(utils.StatePath())
+	if err != nil {
+		return newCommandError(ErrorCodeState, "Unable to read state: %s", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "state.json"), state, 0600)
+	if err != nil {
+		return fmt.Errorf("Unable to write state to snapshot: %s", err)
+	}
+
+	d, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "snapshot.json"), d, 0600)
+	if err != nil {
+		return fmt.Errorf("Unable to write snapshot manifest: %s", err)
+	}
+
+	return nil
+}
+
+// restoreSnapshot copies the volumes in the snapshot to Docker and writes the state
+// so that the next apply creates the containers from the committed images
+func restoreSnapshot(ct clients.ContainerTasks, m *snapshotManifest) error {
+	sc := config.New()
+	err := sc.FromJSON(filepath.Join(snapshotDir(m.Name), "state.json"))
+	if err != nil {
+		return newCommandError(ErrorCodeState, "Unable to load the state for snapshot %s: %s", m.Name, err)
+	}
+
+	for _, v := range m.Volumes {
+		f, err := os.Open(snapshotVolumePath(m.Name, v))
+		if err != nil {
+			return fmt.Errorf("Unable to open archive for volume %s: %s", v, err)
+		}
+
+		err = ct.ImportVolume(v, f)
+		f.Close()
+
+		if err != nil {
+			return fmt.Errorf("Unable to restore volume %s: %s", v, err)
+		}
+	}
+
+	for _, r := range sc.Resources {
+		if r.Info().Status == config.Disabled {
+			continue
+		}
+
+		image, ok := m.Images[fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)]
+		if ok {
+			switch v := r.(type) {
+			case *config.Container:
+				v.Image = &config.Image{Name: image}
+				v.Build = nil
+			case *config.Sidecar:
+				v.Image = config.Image{Name: image}
+			}
+		}
+
+		r.Info().Status = config.PendingCreation
+	}
+
+	err = sc.ToJSON(utils.StatePath())
+	if err != nil {
+		return newCommandError(ErrorCodeState, "Unable to save state: %s", err)
+	}
+
+	return nil
+}
+
+// readSnapshotManifest returns the manifest for the snapshot with the given name
+func readSnapshotManifest(name string) (*snapshotManifest, error) {
+	d, err := ioutil.ReadFile(filepath.Join(snapshotDir(name), "snapshot.json"))
+	if err != nil {
+		return nil, newCommandError(ErrorCodeUsage, "Snapshot %s does not exist", name)
+	}
+
+	m := &snapshotManifest{}
+	err = json.Unmarshal(d, m)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read snapshot %s: %s", name, err)
+	}
+
+	return m, nil
+}
+
+// listSnapshots returns the manifests for all snapshots sorted by the time they were created
+func listSnapshots() ([]*snapshotManifest, error) {
+	files, err := ioutil.ReadDir(utils.SnapshotsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("Unable to read snapshots: %s", err)
+	}
+
+	snapshots := []*snapshotManifest{}
+	for _, f := range files {
+		if !f.IsDir() {
+			continue
+		}
+
+		m, err := readSnapshotManifest(f.Name())
+		if err != nil {
+			continue
+		}
+
+		snapshots = append(snapshots, m)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+
+	return snapshots, nil
+}
+
+func getSnapshotNames(cmd *cobra.Command, args []string, complete string) ([]string, cobra.ShellCompDirective) {
+	snapshots, _ := listSnapshots()
+
+	names := []string{}
+	for _, s := range snapshots {
+		names = append(names, s.Name)
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func snapshotDir(name string) string {
+	return filepath.Join(utils.SnapshotsDir(), name)
+}
+
+func snapshotVolumePath(name, volume string) string {
+	return filepath.Join(snapshotDir(name), "volumes", volume+".tar")
+}
+
+// snapshotImage returns the name of the image a container is committed to, images
+// in the localcache are never pulled
+func snapshotImage(name string, r config.Resource) string {
+	return fmt.Sprintf("shipyard.run/localcache/snapshot-%s-%s:%s", r.Info().Type, r.Info().Name, name)
+}
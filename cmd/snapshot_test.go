@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+
+	shipyardmocks "github.com/shipyard-run/shipyard/pkg/shipyard/mocks"
+)
+
+func setupSnapshot(t *testing.T) *mocks.MockContainerTasks {
+	c := config.NewContainer("db")
+	c.Image = &config.Image{Name: "postgres:13"}
+	c.Volumes = []config.Volume{
+		{Source: "pgdata", Destination: "/var/lib/postgresql/data", Type: "volume"},
+		{Source: "./files", Destination: "/files"},
+	}
+	c.Status = config.Applied
+
+	s := config.NewSidecar("envoy")
+	s.Target = "container.db"
+	s.Image = config.Image{Name: "envoyproxy/envoy:v1.18.3"}
+	s.Status = config.Applied
+
+	d := config.NewContainer("disabled")
+	d.Status = config.Disabled
+
+	setupWatchState(t, c, s, d)
+
+	mt := &mocks.MockContainerTasks{}
+	mt.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"abc"}, nil)
+	mt.On("CommitContainer", mock.Anything, mock.Anything).Return(nil)
+	mt.On("ExportVolume", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(io.Writer).Write([]byte("archive"))
+	}).Return(nil)
+	mt.On("ImportVolume", mock.Anything, mock.Anything).Return(nil)
+
+	return mt
+}
+
+func TestSnapshotCreateCommitsContainersAndCopiesVolumes(t *testing.T) {
+	mt := setupSnapshot(t)
+
+	m, err := createSnapshot(mt, "demo")
+	assert.NoError(t, err)
+
+	mt.AssertCalled(t, "CommitContainer", "abc", "shipyard.run/localcache/snapshot-container-db:demo")
+	mt.AssertCalled(t, "CommitContainer", "abc", "shipyard.run/localcache/snapshot-sidecar-envoy:demo")
+	mt.AssertNumberOfCalls(t, "CommitContainer", 2)
+
+	assert.Equal(t, []string{"pgdata"}, m.Volumes)
+
+	d, err := ioutil.ReadFile(snapshotVolumePath("demo", "pgdata"))
+	assert.NoError(t, err)
+	assert.Equal(t, "archive", string(d))
+
+	assert.FileExists(t, filepath.Join(snapshotDir("demo"), "state.json"))
+	assert.FileExists(t, filepath.Join(snapshotDir("demo"), "snapshot.json"))
+}
+
+func TestSnapshotCreateOnlyAllowsUserToReadSnapshot(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not supported on Windows")
+	}
+
+	mt := setupSnapshot(t)
+
+	_, err := createSnapshot(mt, "demo")
+	assert.NoError(t, err)
+
+	fi, err := os.Stat(snapshotDir("demo"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), fi.Mode().Perm())
+
+	for _, f := range []string{"state.json", "snapshot.json", filepath.Join("volumes", "pgdata.tar")} {
+		fi, err := os.Stat(filepath.Join(snapshotDir("demo"), f))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), f)
+	}
+}
+
+func TestSnapshotCreateReturnsErrorForInvalidName(t *testing.T) {
+	mt := setupSnapshot(t)
+
+	_, err := createSnapshot(mt, "My Snapshot")
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeUsage, ErrorCodeFor(err))
+}
+
+func TestSnapshotCreateReturnsErrorWhenSnapshotExists(t *testing.T) {
+	mt := setupSnapshot(t)
+
+	_, err := createSnapshot(mt, "demo")
+	assert.NoError(t, err)
+
+	_, err = createSnapshot(mt, "demo")
+	assert.Error(t, err)
+}
+
+func TestSnapshotCreateRemovesSnapshotWhenVolumeExportFails(t *testing.T) {
+	mt := setupSnapshot(t)
+	removeOn(&mt.Mock, "ExportVolume")
+	mt.On("ExportVolume", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	_, err := createSnapshot(mt, "demo")
+	assert.Error(t, err)
+
+	assert.NoDirExists(t, snapshotDir("demo"))
+}
+
+func TestSnapshotCreateReturnsErrorWhenNoState(t *testing.T) {
+	setupWatchState(t)
+	mt := &mocks.MockContainerTasks{}
+
+	_, err := createSnapshot(mt, "demo")
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeState, ErrorCodeFor(err))
+}
+
+func TestSnapshotRestoreImportsVolumesAndAppliesState(t *testing.T) {
+	mt := setupSnapshot(t)
+	_, err := createSnapshot(mt, "demo")
+	assert.NoError(t, err)
+
+	// the environment has been destroyed
+	err = os.Remove(utils.StatePath())
+	assert.NoError(t, err)
+
+	me := &shipyardmocks.Engine{}
+	me.On("Apply", "").Return(nil, nil)
+
+	c := newSnapshotRestoreCmd(me, mt)
+	c.SetOut(bytes.NewBuffer(nil))
+	c.SetArgs([]string{"demo"})
+
+	err = c.Execute()
+	assert.NoError(t, err)
+
+	mt.AssertCalled(t, "ImportVolume", "pgdata", mock.Anything)
+	me.AssertCalled(t, "Apply", "")
+	me.AssertNotCalled(t, "Destroy", mock.Anything, mock.Anything)
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err := sc.FindResource("container.db")
+	assert.NoError(t, err)
+	assert.Equal(t, config.PendingCreation, r.Info().Status)
+	assert.Equal(t, "shipyard.run/localcache/snapshot-container-db:demo", r.(*config.Container).Image.Name)
+
+	r, err = sc.FindResource("sidecar.envoy")
+	assert.NoError(t, err)
+	assert.Equal(t, "shipyard.run/localcache/snapshot-sidecar-envoy:demo", r.(*config.Sidecar).Image.Name)
+
+	r, err = sc.FindResource("container.disabled")
+	assert.NoError(t, err)
+	assert.Equal(t, config.Disabled, r.Info().Status)
+}
+
+func TestSnapshotRestoreReturnsErrorWhenEnvironmentRunning(t *testing.T) {
+	mt := setupSnapshot(t)
+	_, err := createSnapshot(mt, "demo")
+	assert.NoError(t, err)
+
+	me := &shipyardmocks.Engine{}
+
+	c := newSnapshotRestoreCmd(me, mt)
+	c.SetOut(bytes.NewBuffer(nil))
+	c.SetErr(bytes.NewBuffer(nil))
+	c.SetArgs([]string{"demo"})
+
+	err = c.Execute()
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeUsage, ErrorCodeFor(err))
+
+	mt.AssertNotCalled(t, "ImportVolume", mock.Anything, mock.Anything)
+}
+
+func TestSnapshotRestoreWithForceDestroysEnvironment(t *testing.T) {
+	mt := setupSnapshot(t)
+	_, err := createSnapshot(mt, "demo")
+	assert.NoError(t, err)
+
+	me := &shipyardmocks.Engine{}
+	me.On("Destroy", "", true).Return(nil)
+	me.On("Apply", "").Return(nil, nil)
+
+	c := newSnapshotRestoreCmd(me, mt)
+	c.SetOut(bytes.NewBuffer(nil))
+	c.SetArgs([]string{"--force", "demo"})
+
+	err = c.Execute()
+	assert.NoError(t, err)
+
+	me.AssertCalled(t, "Destroy", "", true)
+	me.AssertCalled(t, "Apply", "")
+}
+
+func TestSnapshotRestoreReturnsErrorWhenSnapshotDoesNotExist(t *testing.T) {
+	mt := setupSnapshot(t)
+	me := &shipyardmocks.Engine{}
+
+	c := newSnapshotRestoreCmd(me, mt)
+	c.SetOut(bytes.NewBuffer(nil))
+	c.SetErr(bytes.NewBuffer(nil))
+	c.SetArgs([]string{"missing"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
+
+func TestSnapshotListAndDelete(t *testing.T) {
+	mt := setupSnapshot(t)
+	_, err := createSnapshot(mt, "demo")
+	assert.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	c := newSnapshotListCmd()
+	c.SetOut(out)
+	c.SetArgs([]string{})
+
+	err = c.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "demo")
+
+	c = newSnapshotDeleteCmd()
+	c.SetOut(bytes.NewBuffer(nil))
+	c.SetArgs([]string{"demo"})
+
+	err = c.Execute()
+	assert.NoError(t, err)
+	assert.NoDirExists(t, snapshotDir("demo"))
+}
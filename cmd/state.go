@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Manage the Shipyard state file",
+}
+
+func newStateMigrateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade the state file to the version used by this version of Shipyard",
+		Long: `Upgrade the state file to the version used by this version of Shipyard, a copy of the
+original state is saved next to the state file before it is replaced.
+
+State written by older versions of Shipyard is also migrated when it is loaded and
+saved in the new format on the next run, migrate upgrades the file immediately.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			applied, err := config.MigrateStateFile(utils.StatePath())
+			if err == config.StateNotFoundError {
+				cmd.Println("No state file found, nothing to migrate")
+				return nil
+			}
+
+			if err != nil {
+				return newCommandError(ErrorCodeState, "Unable to migrate state: %s", err)
+			}
+
+			if len(applied) == 0 {
+				cmd.Printf("State is up to date, version %d\n", config.StateVersion)
+				return nil
+			}
+
+			for _, m := range applied {
+				cmd.Printf("Migrated state to version %d: %s\n", m.Version, m.Description)
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func setupStateMigrate(t *testing.T, state string) (*cobra.Command, *bytes.Buffer) {
+	utils.SetShipyardHome(t.TempDir())
+	t.Cleanup(func() { utils.SetShipyardHome("") })
+
+	if state != "" {
+		os.MkdirAll(utils.StateDir(), os.ModePerm)
+		ioutil.WriteFile(utils.StatePath(), []byte(state), 0644)
+	}
+
+	out := bytes.NewBufferString("")
+	c := newStateMigrateCmd()
+	c.SetOut(out)
+
+	return c, out
+}
+
+func TestStateMigrateUpgradesState(t *testing.T) {
+	c, out := setupStateMigrate(t, `{"resources": []}`)
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, out.String(), "Migrated state to version 1")
+	require.FileExists(t, utils.StatePath()+".v0.bak")
+
+	d, _ := ioutil.ReadFile(utils.StatePath())
+	require.Contains(t, string(d), `"version":1`)
+}
+
+func TestStateMigrateWithCurrentStateDoesNothing(t *testing.T) {
+	c, out := setupStateMigrate(t, `{"version": 1, "resources": []}`)
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, out.String(), "up to date")
+}
+
+func TestStateMigrateWithNewerStateReturnsError(t *testing.T) {
+	c, _ := setupStateMigrate(t, `{"version": 99, "resources": []}`)
+
+	err := c.Execute()
+	require.Error(t, err)
+
+	require.Equal(t, ErrorCodeState, err.(*CommandError).Code)
+}
+
+func TestStateMigrateWithNoStateDoesNothing(t *testing.T) {
+	c, out := setupStateMigrate(t, "")
+
+	err := c.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, out.String(), "No state file found")
+}
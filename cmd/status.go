@@ -1,13 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/docker/go-connections/nat"
 	"github.com/hokaccha/go-prettyjson"
+	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
 )
 
 /*
@@ -27,110 +35,372 @@ const (
 	White   = "\033[1;37m%s\033[0m"
 )
 
-var jsonFlag bool
-var resourceType string
-
-var statusCmd = &cobra.Command{
-	Use:   "status",
-	Short: "Show the status of the current stack",
-	Long:  `Show the status of the current stack`,
-	Args:  cobra.NoArgs,
-	Run: func(cmd *cobra.Command, args []string) {
-		// load the stack
-		c := config.New()
-		err := c.FromJSON(utils.StatePath())
-		if err != nil {
-			fmt.Println("Unable to load state", err)
-			os.Exit(1)
-		}
+// health of the containers for a resource
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+	HealthStarting  = "starting"
+	HealthRunning   = "running"
+	HealthMissing   = "missing"
+)
 
-		if jsonFlag {
-			s, err := prettyjson.Marshal(c)
-			if err != nil {
-				fmt.Println("Unable to load state", err)
-				os.Exit(1)
+// StatusReport is written by the status command with --output json or yaml
+type StatusReport struct {
+	Resources []ResourceStatus `json:"resources"`
+	Summary   StatusSummary    `json:"summary"`
+}
+
+// ResourceStatus is the status of a resource and the containers it created
+type ResourceStatus struct {
+	Resource   string            `json:"resource"`
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Module     string            `json:"module,omitempty"`
+	Status     string            `json:"status"`
+	Health     string            `json:"health,omitempty"` // healthy, unhealthy, or starting, not set for resources without containers
+	Containers []ContainerStatus `json:"containers,omitempty"`
+}
+
+// ContainerStatus is the status of a container read from the Docker engine
+type ContainerStatus struct {
+	FQDN   string       `json:"fqdn"`
+	ID     string       `json:"id,omitempty"`
+	Health string       `json:"health"` // healthy, unhealthy, or starting for containers with a health check, otherwise running or the state of the container
+	Ports  []PortStatus `json:"ports,omitempty"`
+}
+
+// PortStatus is a container port published on the host
+type PortStatus struct {
+	Local    string `json:"local"`
+	Host     string `json:"host"`
+	HostIP   string `json:"host_ip,omitempty"`
+	Protocol string `json:"protocol"`
+}
+
+// StatusSummary contains the number of resources in each status
+type StatusSummary struct {
+	Pending   int `json:"pending"`
+	Created   int `json:"created"`
+	Failed    int `json:"failed"`
+	Disabled  int `json:"disabled"`
+	Paused    int `json:"paused"`
+	Healthy   int `json:"healthy"`
+	Unhealthy int `json:"unhealthy"`
+}
+
+func newStatusCmd(dc clients.Docker) *cobra.Command {
+	var jsonFlag bool
+	var output string
+	var resourceType string
+	var watchFlag bool
+	var watchInterval time.Duration
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of the current stack",
+		Long: `Show the status of the current stack.
+
+For each resource the status, containers, published ports, and the health of the
+containers are shown, with --output json or yaml the status is written in a structured
+format for scripting. --json writes the raw state.
+
+When --watch is specified an event is written every time the status of a
+resource changes until the command is interrupted, with --json each event
+is written as a single line of JSON.`,
+		Example: `
+  # Show the status of the containers
+  shipyard status --type container
+
+  # List the unhealthy resources
+  shipyard status -o json | jq -r '.resources[] | select(.health == "unhealthy") | .resource'
+
+  # Wait for a resource to fail
+  shipyard status --watch --json | jq -r 'select(.status == "failed") | .resource'
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "text" && output != "json" && output != "yaml" {
+				return fmt.Errorf("Invalid output format '%s', valid formats are text, json, or yaml", output)
 			}
 
-			fmt.Println(string(s))
-		} else {
-			fmt.Println()
-			fmt.Printf("%-13s %-30s %s\n", "STATUS", "RESOURCE", "FQDN")
+			out := cmd.OutOrStdout()
+
+			if watchFlag {
+				ctx, cancel := interruptContext()
+				defer cancel()
+
+				err := watchStatus(ctx, watchInterval, resourceType, jsonFlag || output == "json", out)
+				if err != nil {
+					return fmt.Errorf("Unable to watch status: %s", err)
+				}
 
-			createdCount := 0
-			failedCount := 0
-			pendingCount := 0
+				return nil
+			}
 
-			// sort the resources
-			resources := map[config.ResourceType][]config.Resource{}
+			// load the stack
+			c := config.New()
+			err := c.FromJSON(utils.StatePath())
+			if err != nil {
+				return fmt.Errorf("Unable to load state: %s", err)
+			}
 
-			for _, r := range c.Resources {
-				if resources[r.Info().Type] == nil {
-					resources[r.Info().Type] = []config.Resource{}
+			if jsonFlag {
+				s, err := prettyjson.Marshal(c)
+				if err != nil {
+					return fmt.Errorf("Unable to load state: %s", err)
 				}
 
-				resources[r.Info().Type] = append(resources[r.Info().Type], r)
+				fmt.Fprintln(out, string(s))
+				return nil
 			}
 
-			for _, ress := range resources {
-				for _, r := range ress {
-					if resourceType != "" && string(r.Info().Type) != resourceType {
-						continue
-					}
-
-					status := fmt.Sprintf(White, "[ PENDING ]  ")
-					switch r.Info().Status {
-					case config.Applied:
-						status = fmt.Sprintf(Green, "[ CREATED ]  ")
-						createdCount++
-					case config.Failed:
-						status = fmt.Sprintf(Red, "[ FAILED ]   ")
-						failedCount++
-					case config.Disabled:
-						status = fmt.Sprintf(Teal, "[ DISABLED ] ")
-						failedCount++
-					default:
-						pendingCount++
-					}
-
-					res := fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)
-					fqdn := utils.FQDN(r.Info().Name, string(r.Info().Type))
-
-					switch r.Info().Type {
-					case config.TypeNomadCluster:
-						fmt.Printf("%-13s %-30s %s\n", status, res, fmt.Sprintf("%s.%s", "server", utils.FQDN(r.Info().Name, string(r.Info().Type))))
-
-						// add the client nodes
-						nomad := r.(*config.NomadCluster)
-						for n := 0; n < nomad.ClientNodes; n++ {
-							fmt.Printf("%-13s %-30s %s\n", "", "", fmt.Sprintf("%d.%s.%s", n+1, "client", utils.FQDN(r.Info().Name, string(r.Info().Type))))
-						}
-					case config.TypeK8sCluster:
-						fmt.Printf("%-13s %-30s %s\n", status, res, fmt.Sprintf("%s.%s", "server", utils.FQDN(r.Info().Name, string(r.Info().Type))))
-					case config.TypeContainer:
-						fallthrough
-					case config.TypeSidecar:
-						fallthrough
-					case config.TypeK8sIngress:
-						fallthrough
-					case config.TypeNomadIngress:
-						fallthrough
-					case config.TypeContainerIngress:
-						fallthrough
-					case config.TypeImageCache:
-						fmt.Printf("%-13s %-30s %s\n", status, res, fqdn)
-					default:
-						fmt.Printf("%-13s %-30s %s\n", status, res, "")
-					}
+			report := statusReport(context.Background(), c, dc, resourceType)
+
+			switch output {
+			case "json":
+				d, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("Unable to marshal status: %s", err)
+				}
+
+				fmt.Fprintln(out, string(d))
+			case "yaml":
+				d, err := yaml.Marshal(report)
+				if err != nil {
+					return fmt.Errorf("Unable to marshal status: %s", err)
 				}
+
+				fmt.Fprint(out, string(d))
+			default:
+				printStatus(out, report)
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	statusCmd.Flags().BoolVarP(&jsonFlag, "json", "", false, "Output the state as JSON")
+	statusCmd.Flags().StringVarP(&output, "output", "o", "text", "Output format for the status [text, json, yaml]")
+	statusCmd.Flags().StringVarP(&resourceType, "type", "", "", "Resource type used to filter status list")
+	statusCmd.RegisterFlagCompletionFunc("type", completeEachArg(stateResourceTypes))
+	statusCmd.Flags().BoolVarP(&watchFlag, "watch", "", false, "Write an event every time the status of a resource changes until interrupted")
+	statusCmd.Flags().DurationVarP(&watchInterval, "interval", "", 1*time.Second, "Interval used to check for status changes when watching")
+
+	return statusCmd
+}
+
+// statusReport returns the status of the resources in the state ordered by type and
+// name, the containers for created resources are inspected to determine their health
+func statusReport(ctx context.Context, c *config.Config, dc clients.Docker, filter string) StatusReport {
+	report := StatusReport{Resources: []ResourceStatus{}}
+
+	resources := []config.Resource{}
+	for _, r := range c.Resources {
+		if filter != "" && string(r.Info().Type) != filter {
+			continue
+		}
+
+		resources = append(resources, r)
+	}
+
+	sort.SliceStable(resources, func(i, j int) bool {
+		a, b := resources[i].Info(), resources[j].Info()
+		return fmt.Sprintf("%s.%s.%s", a.Module, a.Type, a.Name) < fmt.Sprintf("%s.%s.%s", b.Module, b.Type, b.Name)
+	})
+
+	for _, r := range resources {
+		i := r.Info()
+
+		rs := ResourceStatus{
+			Resource: fmt.Sprintf("%s.%s", i.Type, i.Name),
+			Type:     string(i.Type),
+			Name:     i.Name,
+			Module:   i.Module,
+			Status:   string(i.Status),
+		}
+
+		switch i.Status {
+		case config.Applied:
+			report.Summary.Created++
+		case config.Failed:
+			report.Summary.Failed++
+		case config.Disabled:
+			report.Summary.Disabled++
+		case config.Paused:
+			report.Summary.Paused++
+		default:
+			report.Summary.Pending++
+		}
+
+		// only created resources have running containers
+		if i.Status == config.Applied {
+			for _, name := range config.LogContainers(r) {
+				rs.Containers = append(rs.Containers, containerStatus(ctx, dc, name))
+			}
+
+			rs.Health = resourceHealth(rs.Containers)
+		}
+
+		switch rs.Health {
+		case HealthHealthy:
+			report.Summary.Healthy++
+		case HealthUnhealthy:
+			report.Summary.Unhealthy++
+		}
+
+		report.Resources = append(report.Resources, rs)
+	}
+
+	return report
+}
+
+// containerStatus inspects the container with the given name, containers which can
+// not be found have the health missing
+func containerStatus(ctx context.Context, dc clients.Docker, name string) ContainerStatus {
+	cs := ContainerStatus{FQDN: name, Health: HealthMissing}
+
+	if dc == nil {
+		return cs
+	}
+
+	info, err := dc.ContainerInspect(ctx, name)
+	if err != nil || info.ContainerJSONBase == nil {
+		return cs
+	}
+
+	cs.ID = info.ID
+
+	if s := info.State; s != nil {
+		switch {
+		case s.Health != nil && s.Health.Status != "none":
+			cs.Health = s.Health.Status
+		case s.Running:
+			cs.Health = HealthRunning
+		default:
+			cs.Health = s.Status
+		}
+	}
+
+	if info.NetworkSettings != nil {
+		cs.Ports = publishedPorts(info.NetworkSettings.Ports)
+	}
+
+	return cs
+}
+
+// publishedPorts returns the container ports which are bound to a host port
+func publishedPorts(pm nat.PortMap) []PortStatus {
+	ports := []PortStatus{}
+
+	for p, bindings := range pm {
+		for _, b := range bindings {
+			if b.HostPort == "" {
+				continue
 			}
 
-			fmt.Println()
-			fmt.Printf("Pending: %d Created: %d Failed: %d\n", pendingCount, createdCount, failedCount)
+			ports = append(ports, PortStatus{Local: p.Port(), Host: b.HostPort, HostIP: b.HostIP, Protocol: p.Proto()})
+		}
+	}
+
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Host != ports[j].Host {
+			return ports[i].Host < ports[j].Host
 		}
-	},
+
+		return ports[i].HostIP < ports[j].HostIP
+	})
+
+	return ports
 }
 
-func init() {
-	statusCmd.Flags().BoolVarP(&jsonFlag, "json", "", false, "Output the status as JSON")
-	statusCmd.Flags().StringVarP(&resourceType, "type", "", "", "Resource type used to filter status list")
+// resourceHealth summarises the health of the containers for a resource, a resource is
+// unhealthy when any container is not running or has failed its health check
+func resourceHealth(containers []ContainerStatus) string {
+	if len(containers) == 0 {
+		return ""
+	}
+
+	health := HealthHealthy
+
+	for _, c := range containers {
+		switch c.Health {
+		case HealthHealthy, HealthRunning:
+		case HealthStarting:
+			health = HealthStarting
+		default:
+			return HealthUnhealthy
+		}
+	}
+
+	return health
+}
+
+func printStatus(w io.Writer, report StatusReport) {
+	line := "%-13s %-30s %-10s %-45s %-13s %s\n"
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, line, "STATUS", "RESOURCE", "HEALTH", "FQDN", "ID", "PORTS")
+
+	for _, r := range report.Resources {
+		status := fmt.Sprintf(White, "[ PENDING ]  ")
+		switch config.Status(r.Status) {
+		case config.Applied:
+			status = fmt.Sprintf(Green, "[ CREATED ]  ")
+		case config.Failed:
+			status = fmt.Sprintf(Red, "[ FAILED ]   ")
+		case config.Disabled:
+			status = fmt.Sprintf(Teal, "[ DISABLED ] ")
+		case config.Paused:
+			status = fmt.Sprintf(Yellow, "[ PAUSED ]   ")
+		}
+
+		if len(r.Containers) == 0 {
+			fmt.Fprintf(w, line, status, r.Resource, "", "", "", "")
+			continue
+		}
+
+		// the first container is written on the same line as the resource
+		for n, c := range r.Containers {
+			if n > 0 {
+				status = ""
+				r.Resource = ""
+			}
+
+			fmt.Fprintf(w, line, status, r.Resource, c.Health, c.FQDN, shortID(c.ID), formatPorts(c.Ports))
+		}
+	}
+
+	s := report.Summary
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Pending: %d Created: %d Failed: %d Disabled: %d Paused: %d\n", s.Pending, s.Created, s.Failed, s.Disabled, s.Paused)
+	fmt.Fprintf(w, "Healthy: %d Unhealthy: %d\n", s.Healthy, s.Unhealthy)
+}
+
+// formatPorts returns the published ports in the Docker format, e.g. 18500->8500/tcp,
+// ports bound to both the IPv4 and IPv6 addresses are only written once
+func formatPorts(ports []PortStatus) string {
+	p := []string{}
+	seen := map[string]bool{}
+
+	for _, port := range ports {
+		f := fmt.Sprintf("%s->%s/%s", port.Host, port.Local, port.Protocol)
+		if seen[f] {
+			continue
+		}
+
+		seen[f] = true
+		p = append(p, f)
+	}
+
+	return strings.Join(p, ",")
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+
+	return id
 }
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func containerJSON(id string, running bool, health string, ports nat.PortMap) types.ContainerJSON {
+	state := &types.ContainerState{Running: running, Status: "exited"}
+	if running {
+		state.Status = "running"
+	}
+
+	if health != "" {
+		state.Health = &types.Health{Status: health}
+	}
+
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: id, State: state},
+		NetworkSettings:   &types.NetworkSettings{NetworkSettingsBase: types.NetworkSettingsBase{Ports: ports}},
+	}
+}
+
+func setupStatus(t *testing.T, state string) (*cobra.Command, *mocks.MockDocker, *bytes.Buffer) {
+	t.Cleanup(setupState(state))
+
+	md := &mocks.MockDocker{}
+	md.On("ContainerInspect", mock.Anything, "consul.container.shipyard.run").Return(
+		containerJSON("6d3c4a1b8e2f9a7c5b3d1e0f", true, "healthy", nat.PortMap{
+			"8500/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "18500"}, {HostIP: "::", HostPort: "18500"}},
+			"8600/udp": nil,
+		}),
+		nil,
+	)
+
+	md.On("ContainerInspect", mock.Anything, "vault.container.shipyard.run").Return(
+		containerJSON("9a8b7c6d5e4f", true, "", nil),
+		nil,
+	)
+
+	out := bytes.NewBufferString("")
+
+	sc := newStatusCmd(md)
+	sc.SetOut(out)
+	sc.SetErr(bytes.NewBufferString(""))
+
+	return sc, md, out
+}
+
+func statusFromJSON(t *testing.T, out *bytes.Buffer) StatusReport {
+	sr := StatusReport{}
+	err := json.Unmarshal(out.Bytes(), &sr)
+	require.NoError(t, err)
+
+	return sr
+}
+
+func TestStatusWritesJSON(t *testing.T) {
+	sc, _, out := setupStatus(t, statusState)
+	sc.SetArgs([]string{"-o", "json"})
+
+	err := sc.Execute()
+	require.NoError(t, err)
+
+	sr := statusFromJSON(t, out)
+	require.Len(t, sr.Resources, 4)
+
+	// resources are ordered by type and name
+	require.Equal(t, "container.consul", sr.Resources[0].Resource)
+	require.Equal(t, "container.consul_disabled", sr.Resources[1].Resource)
+	require.Equal(t, "container.vault", sr.Resources[2].Resource)
+	require.Equal(t, "network.onprem", sr.Resources[3].Resource)
+
+	consul := sr.Resources[0]
+	require.Equal(t, "applied", consul.Status)
+	require.Equal(t, HealthHealthy, consul.Health)
+	require.Len(t, consul.Containers, 1)
+	require.Equal(t, "consul.container.shipyard.run", consul.Containers[0].FQDN)
+	require.Equal(t, "6d3c4a1b8e2f9a7c5b3d1e0f", consul.Containers[0].ID)
+	require.Equal(t, []PortStatus{
+		{Local: "8500", Host: "18500", HostIP: "0.0.0.0", Protocol: "tcp"},
+		{Local: "8500", Host: "18500", HostIP: "::", Protocol: "tcp"},
+	}, consul.Containers[0].Ports)
+
+	// containers without a health check are healthy when running
+	require.Equal(t, HealthRunning, sr.Resources[2].Containers[0].Health)
+	require.Equal(t, HealthHealthy, sr.Resources[2].Health)
+
+	// disabled and pending resources have no containers
+	require.Empty(t, sr.Resources[1].Containers)
+	require.Empty(t, sr.Resources[1].Health)
+
+	require.Equal(t, StatusSummary{Pending: 1, Created: 2, Disabled: 1, Healthy: 2}, sr.Summary)
+}
+
+func TestStatusWritesYAML(t *testing.T) {
+	sc, _, out := setupStatus(t, statusState)
+	sc.SetArgs([]string{"-o", "yaml"})
+
+	err := sc.Execute()
+	require.NoError(t, err)
+
+	sr := StatusReport{}
+	err = yaml.Unmarshal(out.Bytes(), &sr)
+	require.NoError(t, err)
+
+	require.Len(t, sr.Resources, 4)
+	require.Equal(t, HealthHealthy, sr.Resources[0].Health)
+}
+
+func TestStatusFiltersByType(t *testing.T) {
+	sc, _, out := setupStatus(t, statusState)
+	sc.SetArgs([]string{"-o", "json", "--type", "network"})
+
+	err := sc.Execute()
+	require.NoError(t, err)
+
+	sr := statusFromJSON(t, out)
+	require.Len(t, sr.Resources, 1)
+	require.Equal(t, "network.onprem", sr.Resources[0].Resource)
+}
+
+func TestStatusMissingContainerIsUnhealthy(t *testing.T) {
+	sc, md, out := setupStatus(t, statusState)
+	removeOn(&md.Mock, "ContainerInspect")
+	md.On("ContainerInspect", mock.Anything, mock.Anything).Return(types.ContainerJSON{}, fmt.Errorf("No such container"))
+	sc.SetArgs([]string{"-o", "json"})
+
+	err := sc.Execute()
+	require.NoError(t, err)
+
+	sr := statusFromJSON(t, out)
+	require.Equal(t, HealthMissing, sr.Resources[0].Containers[0].Health)
+	require.Equal(t, HealthUnhealthy, sr.Resources[0].Health)
+	require.Equal(t, 2, sr.Summary.Unhealthy)
+}
+
+func TestStatusFailedHealthCheckIsUnhealthy(t *testing.T) {
+	sc, md, out := setupStatus(t, statusState)
+	removeOn(&md.Mock, "ContainerInspect")
+	md.On("ContainerInspect", mock.Anything, mock.Anything).Return(containerJSON("abc", true, "unhealthy", nil), nil)
+	sc.SetArgs([]string{"-o", "json"})
+
+	err := sc.Execute()
+	require.NoError(t, err)
+
+	sr := statusFromJSON(t, out)
+	require.Equal(t, HealthUnhealthy, sr.Resources[0].Health)
+}
+
+func TestStatusWritesTable(t *testing.T) {
+	sc, _, out := setupStatus(t, statusState)
+	sc.SetArgs([]string{})
+
+	err := sc.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, out.String(), "container.consul")
+	require.Contains(t, out.String(), "6d3c4a1b8e2f ")
+	require.Contains(t, out.String(), "18500->8500/tcp\n")
+	require.Contains(t, out.String(), "Pending: 1 Created: 2 Failed: 0 Disabled: 1")
+	require.Contains(t, out.String(), "Healthy: 2 Unhealthy: 0")
+}
+
+func TestStatusWithInvalidOutputReturnsError(t *testing.T) {
+	sc, _, _ := setupStatus(t, statusState)
+	sc.SetArgs([]string{"-o", "xml"})
+
+	err := sc.Execute()
+	require.Error(t, err)
+}
+
+var statusState = `
+{
+  "resources": [
+    {
+      "name": "onprem",
+      "type": "network",
+      "status": "pending_creation",
+      "subnet": "10.6.0.0/16"
+    },
+    {
+      "name": "vault",
+      "type": "container",
+      "status": "applied",
+      "image": {
+        "name": "vault:1.6.1"
+      }
+    },
+    {
+      "name": "consul_disabled",
+      "type": "container",
+      "status": "disabled",
+      "disabled": true,
+      "image": {
+        "name": "consul:1.8.1"
+      }
+    },
+    {
+      "name": "consul",
+      "type": "container",
+      "status": "applied",
+      "image": {
+        "name": "consul:1.8.1"
+      }
+    }
+  ]
+}
+`
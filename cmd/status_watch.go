@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// StatusEvent is written when the status of a resource changes
+type StatusEvent struct {
+	Time           time.Time `json:"time"`
+	Resource       string    `json:"resource"`
+	Module         string    `json:"module,omitempty"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status,omitempty"`
+}
+
+type watchedResource struct {
+	resource string
+	module   string
+	status   string
+}
+
+// watchStatus polls the state file at the given interval and writes an event
+// for every resource which changes status until the context is cancelled.
+// When the watch starts an event is written for all existing resources.
+func watchStatus(ctx context.Context, interval time.Duration, filter string, asJSON bool, w io.Writer) error {
+	known := map[string]watchedResource{}
+
+	for {
+		current, err := readStatus(filter)
+		if err == nil {
+			for _, e := range statusChanges(known, current, time.Now()) {
+				err := writeStatusEvent(w, e, asJSON)
+				if err != nil {
+					return err
+				}
+			}
+
+			known = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// interruptContext returns a context which is cancelled when the
+// process is interrupted, used by commands which watch for changes
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
+
+		signal.Stop(sigs)
+	}()
+
+	return ctx, cancel
+}
+
+// readStatus returns the status of the resources in the state file keyed by
+// resource id, no resources are returned when the state does not exist
+func readStatus(filter string) (map[string]watchedResource, error) {
+	status := map[string]watchedResource{}
+
+	if _, err := os.Stat(utils.StatePath()); os.IsNotExist(err) {
+		return status, nil
+	}
+
+	c := config.New()
+	err := c.FromJSON(utils.StatePath())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range c.Resources {
+		i := r.Info()
+		if filter != "" && string(i.Type) != filter {
+			continue
+		}
+
+		status[config.ResourceID(i.Module, i.Type, i.Name)] = watchedResource{
+			resource: fmt.Sprintf("%s.%s", i.Type, i.Name),
+			module:   i.Module,
+			status:   string(i.Status),
+		}
+	}
+
+	return status, nil
+}
+
+// statusChanges compares the previous and current status of the resources
+// and returns an event for each new, changed, or removed resource
+func statusChanges(previous, current map[string]watchedResource, now time.Time) []StatusEvent {
+	events := []StatusEvent{}
+
+	for _, id := range sortedKeys(current) {
+		c := current[id]
+		p, ok := previous[id]
+		if ok && p.status == c.status {
+			continue
+		}
+
+		events = append(events, StatusEvent{Time: now, Resource: c.resource, Module: c.module, Status: c.status, PreviousStatus: p.status})
+	}
+
+	for _, id := range sortedKeys(previous) {
+		p := previous[id]
+		if _, ok := current[id]; ok || p.status == string(config.Destroyed) {
+			continue
+		}
+
+		events = append(events, StatusEvent{Time: now, Resource: p.resource, Module: p.module, Status: string(config.Destroyed), PreviousStatus: p.status})
+	}
+
+	return events
+}
+
+// sortedKeys returns the ids of the resources ordered by resource name
+// so that the events are always written in the same order
+func sortedKeys(r map[string]watchedResource) []string {
+	keys := []string{}
+	for k := range r {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return r[keys[i]].module+r[keys[i]].resource < r[keys[j]].module+r[keys[j]].resource
+	})
+
+	return keys
+}
+
+func writeStatusEvent(w io.Writer, e StatusEvent, asJSON bool) error {
+	if asJSON {
+		// json.Encoder writes each event on a single line
+		return json.NewEncoder(w).Encode(e)
+	}
+
+	_, err := fmt.Fprintf(w, "%s %-30s %s -> %s\n", e.Time.Format(time.RFC3339), e.Resource, statusOrNone(e.PreviousStatus), e.Status)
+	return err
+}
+
+func statusOrNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+
+	return s
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupWatchState(t *testing.T, resources ...config.Resource) {
+	dir := t.TempDir()
+
+	home := os.Getenv(utils.HomeEnvName())
+	os.Setenv(utils.HomeEnvName(), dir)
+
+	t.Cleanup(func() {
+		os.Setenv(utils.HomeEnvName(), home)
+	})
+
+	if len(resources) == 0 {
+		return
+	}
+
+	c := config.New()
+	for _, r := range resources {
+		err := c.AddResource(r)
+		assert.NoError(t, err)
+	}
+
+	err := c.ToJSON(utils.StatePath())
+	assert.NoError(t, err)
+}
+
+func TestWatchStatusWritesInitialStatusAsJSON(t *testing.T) {
+	c := config.NewContainer("web")
+	c.Status = config.Applied
+	n := config.NewNetwork("cloud")
+	n.Status = config.PendingCreation
+
+	setupWatchState(t, c, n)
+
+	// a cancelled context checks the state once
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	out := bytes.NewBufferString("")
+	err := watchStatus(ctx, time.Millisecond, "", true, out)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	e := StatusEvent{}
+	err = json.Unmarshal([]byte(lines[0]), &e)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "container.web", e.Resource)
+	assert.Equal(t, "applied", e.Status)
+	assert.Empty(t, e.PreviousStatus)
+}
+
+func TestWatchStatusFiltersByType(t *testing.T) {
+	setupWatchState(t, config.NewContainer("web"), config.NewNetwork("cloud"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	out := bytes.NewBufferString("")
+	err := watchStatus(ctx, time.Millisecond, "network", true, out)
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "network.cloud")
+	assert.NotContains(t, out.String(), "container.web")
+}
+
+func TestWatchStatusWithNoStateWritesNothing(t *testing.T) {
+	setupWatchState(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	out := bytes.NewBufferString("")
+	err := watchStatus(ctx, time.Millisecond, "", true, out)
+	assert.NoError(t, err)
+
+	assert.Empty(t, out.String())
+}
+
+func TestStatusChangesReturnsChangedResources(t *testing.T) {
+	prev := map[string]watchedResource{
+		"a": {resource: "container.web", status: "pending_creation"},
+		"b": {resource: "network.cloud", status: "applied"},
+	}
+
+	curr := map[string]watchedResource{
+		"a": {resource: "container.web", status: "applied"},
+		"b": {resource: "network.cloud", status: "applied"},
+	}
+
+	e := statusChanges(prev, curr, time.Now())
+	assert.Len(t, e, 1)
+	assert.Equal(t, "container.web", e[0].Resource)
+	assert.Equal(t, "applied", e[0].Status)
+	assert.Equal(t, "pending_creation", e[0].PreviousStatus)
+}
+
+func TestStatusChangesReturnsDestroyedForRemovedResources(t *testing.T) {
+	prev := map[string]watchedResource{
+		"a": {resource: "container.web", status: "applied"},
+		"b": {resource: "network.cloud", status: "destroyed"},
+	}
+
+	e := statusChanges(prev, map[string]watchedResource{}, time.Now())
+	assert.Len(t, e, 1)
+	assert.Equal(t, "container.web", e[0].Resource)
+	assert.Equal(t, "destroyed", e[0].Status)
+	assert.Equal(t, "applied", e[0].PreviousStatus)
+}
+
+func TestWriteStatusEventWritesText(t *testing.T) {
+	out := bytes.NewBufferString("")
+	e := StatusEvent{Time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Resource: "container.web", Status: "applied"}
+
+	err := writeStatusEvent(out, e, false)
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "container.web")
+	assert.Contains(t, out.String(), "none -> applied")
+}
@@ -1,47 +1,76 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
-var taintCmd = &cobra.Command{
-	Use:   "taint [type].[name]",
-	Short: "Taint a resource e.g. 'shipyard taint container.test'",
-	Long: `Taint a resource and mark is to be re-created on the next Apply
-	Example use to remove a container named test
-	shipyard taint container.test
+func newTaintCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "taint [type].[name] ...",
+		Short: "Taint a resource e.g. 'shipyard taint container.test'",
+		Long: `Taint one or more resources in the state and mark them to be destroyed and
+re-created on the next apply, other resources are not changed.
+
+Use taint to pick up a new image or configuration for a single resource
+without destroying the environment. Resources can also be re-created when
+running a blueprint with 'shipyard run --recreate'.`,
+		Example: `
+  # Re-create the container named test on the next run
+  shipyard taint container.test
+  shipyard run ./my-stack
+
+  # Re-create several resources
+  shipyard taint container.api container.web
 	`,
-	Args: cobra.ArbitraryArgs,
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) != 1 {
-			fmt.Println("The resource to taint must be specified as an argument")
-			os.Exit(1)
-		}
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: getTaintResources,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := taintResources(args)
+			if err != nil {
+				return err
+			}
 
-		c := config.New()
-		err := c.FromJSON(utils.StatePath())
-		if err != nil {
-			fmt.Println("Unable to load state", err)
-			os.Exit(1)
-		}
+			for _, a := range args {
+				cmd.Println("Tainted", a, "the resource will be re-created on the next run")
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+}
 
-		r, err := c.FindResource(args[0])
+// taintResources marks the resources in the state to be destroyed
+// and re-created by the next apply
+func taintResources(names []string) error {
+	c := config.New()
+	err := c.FromJSON(utils.StatePath())
+	if err != nil {
+		return newCommandError(ErrorCodeState, "Unable to load state: %s", err)
+	}
+
+	for _, n := range names {
+		r, err := c.FindResource(n)
 		if err != nil || r == nil {
-			fmt.Println("Unable to locate resource in the state", args[0])
-			os.Exit(1)
+			return newCommandError(ErrorCodeUsage, "Unable to locate resource %s in the state", n)
+		}
+
+		if r.Info().Status == config.Disabled {
+			return newCommandError(ErrorCodeUsage, "Unable to taint resource %s, the resource is disabled", n)
 		}
 
 		r.Info().Status = config.PendingModification
+	}
 
-		err = c.ToJSON(utils.StatePath())
-		if err != nil {
-			fmt.Println("Unable to save state", err)
-			os.Exit(1)
-		}
-	},
+	err = c.ToJSON(utils.StatePath())
+	if err != nil {
+		return newCommandError(ErrorCodeState, "Unable to save state: %s", err)
+	}
+
+	return nil
 }
+
+// getTaintResources returns the resources in the state file for shell completion
+var getTaintResources = completeEachArg(stateResources())
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupTaint(t *testing.T) *cobra.Command {
+	api := config.NewContainer("api")
+	api.Status = config.Applied
+
+	web := config.NewContainer("web")
+	web.Status = config.Applied
+
+	off := config.NewContainer("off")
+	off.Status = config.Disabled
+
+	setupWatchState(t, api, web, off)
+
+	tc := newTaintCmd()
+	tc.SetOut(bytes.NewBufferString(""))
+	tc.SetErr(bytes.NewBufferString(""))
+
+	return tc
+}
+
+func taintedStatus(t *testing.T, name string) config.Status {
+	sc := config.New()
+	err := sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err := sc.FindResource(name)
+	assert.NoError(t, err)
+
+	return r.Info().Status
+}
+
+func TestTaintMarksResourcesForRecreation(t *testing.T) {
+	tc := setupTaint(t)
+	tc.SetArgs([]string{"container.api"})
+
+	err := tc.Execute()
+	assert.NoError(t, err)
+
+	assert.Equal(t, config.PendingModification, taintedStatus(t, "container.api"))
+	assert.Equal(t, config.Applied, taintedStatus(t, "container.web"))
+}
+
+func TestTaintMarksMultipleResources(t *testing.T) {
+	tc := setupTaint(t)
+	tc.SetArgs([]string{"container.api", "container.web"})
+
+	err := tc.Execute()
+	assert.NoError(t, err)
+
+	assert.Equal(t, config.PendingModification, taintedStatus(t, "container.api"))
+	assert.Equal(t, config.PendingModification, taintedStatus(t, "container.web"))
+}
+
+func TestTaintWithUnknownResourceReturnsErrorAndDoesNotChangeState(t *testing.T) {
+	tc := setupTaint(t)
+	tc.SetArgs([]string{"container.api", "container.missing"})
+
+	err := tc.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "container.missing")
+
+	assert.Equal(t, config.Applied, taintedStatus(t, "container.api"))
+}
+
+func TestTaintWithDisabledResourceReturnsError(t *testing.T) {
+	tc := setupTaint(t)
+	tc.SetArgs([]string{"container.off"})
+
+	err := tc.Execute()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "disabled")
+}
+
+func TestTaintWithoutStateReturnsError(t *testing.T) {
+	setupWatchState(t)
+
+	tc := newTaintCmd()
+	tc.SetOut(bytes.NewBufferString(""))
+	tc.SetErr(bytes.NewBufferString(""))
+	tc.SetArgs([]string{"container.api"})
+
+	err := tc.Execute()
+	assert.Error(t, err)
+}
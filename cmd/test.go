@@ -25,8 +25,10 @@ import (
 	"github.com/docker/docker/api/types/filters"
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/shipyard"
 	"github.com/shipyard-run/shipyard/pkg/utils"
+	gvm "github.com/shipyard-run/version-manager"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/util/jsonpath"
 )
@@ -43,8 +45,9 @@ var output = bytes.NewBufferString("")
 var commandOutput = bytes.NewBufferString("")
 var commandExitCode = 0
 
-func newTestCmd(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc clients.System, l hclog.Logger) *cobra.Command {
+func newTestCmd(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc clients.System, vm gvm.Versions, l hclog.Logger) *cobra.Command {
 	var testFolder string
+	var junit string
 	var force bool
 	var dontDestroy bool
 	var purge bool
@@ -53,12 +56,26 @@ func newTestCmd(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc client
 	var tags string
 
 	var testCmd = &cobra.Command{
-		Use:                   "test [blueprint]",
-		Short:                 "Run functional tests for the blueprint",
-		Long:                  `Run functional tests for the blueprint, this command will start the shipyard blueprint `,
+		Use:   "test [blueprint]",
+		Short: "Run functional tests for the blueprint",
+		Long: `Run functional tests for the blueprint, this command will start the shipyard blueprint.
+
+When the blueprint contains test resources, the blueprint is applied, the http, script,
+and pod_ready assertions in the test resources are run, and the blueprint is destroyed.
+Otherwise the cucumber features in the test or functional_tests folder are run.
+
+The command returns a non-zero exit code when any of the tests fail.`,
+		Example: `
+  # Run the tests for the blueprint in the current folder
+  shipyard test
+
+  # Write a JUnit report for CI
+  shipyard test ./my-blueprint --junit ./reports/shipyard.xml
+`,
 		DisableFlagsInUseLine: true,
 		Args:                  cobra.ArbitraryArgs,
-		RunE:                  newTestCmdFunc(e, bp, hc, bc, testFolder, &force, &purge, &variables, &variablesFile, &tags, &dontDestroy, l),
+		RunE:                  newTestCmdFunc(e, bp, hc, bc, vm, &testFolder, &force, &purge, &variables, &variablesFile, &tags, &dontDestroy, &junit, l),
+		SilenceUsage:          true,
 	}
 
 	testCmd.Flags().StringVarP(&testFolder, "test-folder", "", "", "Specify the folder containing the functional tests.")
@@ -68,6 +85,7 @@ func newTestCmd(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc client
 	testCmd.Flags().StringVarP(&variablesFile, "vars-file", "", "", "Load variables from a location other than *.vars files in the blueprint folder. E.g --vars-file=./file.vars")
 	testCmd.Flags().StringVarP(&tags, "tags", "", "", "Test tags to run e.g. @wip, @wip,@new, when not set all tests are run")
 	testCmd.Flags().BoolVarP(&dontDestroy, "dont-destroy", "", false, "When set to true, Shipyard does not destroy the blueprint after executing the tests")
+	testCmd.Flags().StringVarP(&junit, "junit", "", "", "Write the test results to the given file in JUnit XML format")
 
 	return testCmd
 }
@@ -77,17 +95,61 @@ func newTestCmdFunc(
 	bp clients.Getter,
 	hc clients.HTTP,
 	bc clients.System,
-	testFolder string,
+	vm gvm.Versions,
+	testFolder *string,
 	force *bool,
 	purge *bool,
 	variables *[]string,
 	variablesFile *string,
 	tags *string,
 	dontDestroy *bool,
+	junit *string,
 	l hclog.Logger) func(cmd *cobra.Command, args []string) error {
 
 	return func(cmd *cobra.Command, args []string) error {
-		tr := CucumberRunner{cmd, args, e, bp, hc, bc, testFolder, "", "", force, purge, l, *variables, nil, *variablesFile, *tags, dontDestroy}
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		// parse the vars into a map
+		vars := map[string]string{}
+		for _, v := range *variables {
+			parts := strings.Split(v, "=")
+			if len(parts) == 2 {
+				vars[parts[0]] = parts[1]
+			}
+		}
+
+		// blueprints which define test resources are tested without the cucumber features
+		c, err := parseBlueprint(path, vars, *variablesFile)
+		if err == nil && len(c.FindResourcesByType(string(config.TypeTest))) > 0 {
+			return runBlueprintTests(cmd, e, bp, hc, bc, vm, path, variables, variablesFile, force, *dontDestroy, *junit, l)
+		}
+
+		folder := *testFolder
+		if folder == "" {
+			folder = "test"
+
+			// functional_tests is used by blueprints which have test in the blueprint folder
+			if fi, err := os.Stat(filepath.Join(path, "functional_tests")); err == nil && fi.IsDir() {
+				folder = "functional_tests"
+			}
+		}
+
+		if _, serr := os.Stat(filepath.Join(path, folder)); serr != nil {
+			if err != nil {
+				return newCommandError(ErrorCodeConfig, "%s", err)
+			}
+
+			return newCommandError(ErrorCodeUsage, "The blueprint does not contain any test resources or a %s folder", folder)
+		}
+
+		if *junit != "" {
+			opts.Format = fmt.Sprintf("%s,junit:%s", opts.Format, *junit)
+		}
+
+		tr := CucumberRunner{cmd, args, e, bp, hc, bc, folder, "", "", force, purge, l, *variables, nil, *variablesFile, *tags, dontDestroy}
 		tr.start()
 
 		return nil
@@ -158,11 +220,17 @@ func (cr *CucumberRunner) initializeSuite(ctx *godog.ScenarioContext) {
 		commandExitCode = 0
 		cr.variables = cr.baseVariables
 
-		cr.e, _ = createEngine(cr.l)
+		e, _, err := createEngine(cr.l)
+		if err != nil {
+			cr.l.Error("Unable to create engine", "error", err)
+		} else {
+			cr.e = e
+		}
 
 		// do we need to pure the cache
 		if *cr.purge {
-			pc := newPurgeCmdFunc(cr.e.GetClients().Docker, cr.e.GetClients().ImageLog, cr.e.GetClients().Logger)
+			opts := &purgeOptions{images: true, blueprints: true, helm: true}
+			pc := newPurgeCmdFunc(cr.e.GetClients().Docker, cr.e.GetClients().ImageLog, opts, cr.e.GetClients().Logger)
 			pc(cr.cmd, cr.args)
 		}
 	})
@@ -188,7 +256,7 @@ func (cr *CucumberRunner) initializeSuite(ctx *godog.ScenarioContext) {
 			return
 		}
 
-		dest := newDestroyCmd(cr.e.GetClients().Connector)
+		dest := newDestroyCmd(cr.e.GetClients().Connector, cr.e.GetClients().History, cr.e.GetClients().Docker)
 		dest.SetArgs([]string{})
 		dest.Execute()
 	})
@@ -254,13 +322,24 @@ func (cr *CucumberRunner) iRunApplyAtPathWithVersion(fp, version string) error {
 	}
 
 	logger := hclog.New(opts)
-	engine, vm := createEngine(logger)
+	engine, vm, err := createEngine(logger)
+	if err != nil {
+		return err
+	}
 
 	cr.e = engine
 	cr.l = logger
 
-	noOpen := true
-	approve := true
+	runOpts := &runOptions{
+		noOpen:        true,
+		force:         *cr.force,
+		autoApprove:   true,
+		version:       version,
+		variables:     cr.variables,
+		variablesFile: cr.variablesFile,
+		noTTY:         true,
+		timings:       timingsNone,
+	}
 
 	// re-use the run command
 	rc := newRunCmdFunc(
@@ -270,12 +349,7 @@ func (cr *CucumberRunner) iRunApplyAtPathWithVersion(fp, version string) error {
 		engine.GetClients().Browser,
 		vm,
 		engine.GetClients().Connector,
-		&noOpen,
-		cr.force,
-		&version,
-		&approve,
-		&cr.variables,
-		&cr.variablesFile,
+		runOpts,
 		cr.l,
 	)
 
@@ -287,7 +361,7 @@ func (cr *CucumberRunner) iRunApplyAtPathWithVersion(fp, version string) error {
 		logger.Debug("Running test with", "variables", cr.variables)
 	}
 
-	err := rc(cr.cmd, args)
+	err = rc(cr.cmd, args)
 	if err != nil {
 		fmt.Println(output.String())
 	}
@@ -518,7 +592,7 @@ func (cr *CucumberRunner) theShipyardVariableKHasAValueV(key, value string) erro
 	return nil
 }
 
-//ctx.Step(`^the info "([^"]*)" for the running "([^"]*)" running called "([^"]*)" should equal "([^"]*)"$`, cr.theContainerInfoShouldContainer)
+// ctx.Step(`^the info "([^"]*)" for the running "([^"]*)" running called "([^"]*)" should equal "([^"]*)"$`, cr.theContainerInfoShouldContainer)
 func (cr *CucumberRunner) theResourceInfoShouldContain(path, resource, name, value string) error {
 	s, err := cr.getJSONPath(path, resource, name)
 	if err != nil {
@@ -556,7 +630,12 @@ func (cr *CucumberRunner) theResourceInfoShouldExist(path, resource, name string
 
 func (cr *CucumberRunner) whenIRunTheScript(arg1 *godog.DocString) error {
 	// copy the script into a temp file and try to execute it
-	tmpFile, err := ioutil.TempFile(utils.ShipyardTemp(), "*.sh")
+	tmp, err := utils.ShipyardTemp()
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(tmp, "*.sh")
 	if err != nil {
 		return err
 	}
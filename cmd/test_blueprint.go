@@ -0,0 +1,438 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	gvm "github.com/shipyard-run/version-manager"
+	"github.com/spf13/cobra"
+)
+
+// runBlueprintTests applies the blueprint, runs the assertions in the test resources,
+// and destroys the blueprint, an error is returned when any of the assertions fail
+func runBlueprintTests(
+	cmd *cobra.Command,
+	e shipyard.Engine,
+	bp clients.Getter,
+	hc clients.HTTP,
+	bc clients.System,
+	vm gvm.Versions,
+	path string,
+	variables *[]string,
+	variablesFile *string,
+	force *bool,
+	dontDestroy bool,
+	junit string,
+	l hclog.Logger) error {
+
+	opts := &runOptions{
+		noOpen:        true,
+		force:         *force,
+		autoApprove:   true,
+		variables:     *variables,
+		variablesFile: *variablesFile,
+		noTTY:         true,
+		timings:       timingsNone,
+	}
+
+	// re-use the run command to create the resources
+	rc := newRunCmdFunc(e, bp, hc, bc, vm, e.GetClients().Connector, opts, l)
+
+	destroy := func() {
+		if dontDestroy {
+			cmd.Println("Not automatically destroying resources, run the command 'shipyard destroy' manually")
+			return
+		}
+
+		dest := newDestroyCmd(e.GetClients().Connector, e.GetClients().History, e.GetClients().Docker)
+		dest.SetArgs([]string{})
+		dest.SetOut(cmd.OutOrStdout())
+		dest.SetErr(cmd.ErrOrStderr())
+		dest.Execute()
+	}
+
+	err := rc(cmd, []string{path})
+	if err != nil {
+		destroy()
+		return err
+	}
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	if err != nil {
+		destroy()
+		return newCommandError(ErrorCodeState, "Unable to load state: %s", err)
+	}
+
+	// scripts are run in the blueprint folder
+	dir, _ := filepath.Abs(path)
+	if utils.IsConfigFile(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	bt := newBlueprintTester(hc, e.GetClients().Kubernetes, dir)
+
+	// scripts can use the same variables as 'shipyard env' to connect to the resources
+	vars, _ := environmentVariables(sc, nil)
+	for _, v := range vars {
+		bt.env = append(bt.env, fmt.Sprintf("%s=%s", v.Key, v.Value))
+	}
+
+	cmd.Println()
+	cmd.Println("Running tests")
+	cmd.Println()
+
+	results := []testResult{}
+	failed := 0
+	for _, r := range sc.FindResourcesByType(string(config.TypeTest)) {
+		if r.Info().Disabled || r.Info().Status == config.Disabled {
+			continue
+		}
+
+		res := bt.run(r.(*config.Test))
+		if res.Failed() {
+			failed++
+		}
+
+		results = append(results, res)
+	}
+
+	printTestResults(cmd.OutOrStdout(), results)
+
+	if junit != "" {
+		err = writeJUnitReport(junit, results)
+		if err != nil {
+			destroy()
+			return fmt.Errorf("Unable to write JUnit report: %s", err)
+		}
+	}
+
+	destroy()
+
+	if failed > 0 {
+		return newCommandError(ErrorCodeTestFailed, "%d of %d tests failed", failed, len(results))
+	}
+
+	return nil
+}
+
+// testAssertion is the result of a single assertion in a test resource
+type testAssertion struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// testResult is the result of the assertions for a test resource
+type testResult struct {
+	Name       string
+	Assertions []testAssertion
+}
+
+// Failed returns true when any of the assertions failed
+func (r testResult) Failed() bool {
+	for _, a := range r.Assertions {
+		if a.Err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// blueprintTester runs the assertions defined by the test resources in a blueprint
+type blueprintTester struct {
+	hc clients.HTTP
+	kc clients.Kubernetes
+
+	// dir is the folder scripts are run in
+	dir string
+	// env is added to the environment of scripts
+	env []string
+	// retryInterval is the time between attempts of a http assertion
+	retryInterval time.Duration
+}
+
+func newBlueprintTester(hc clients.HTTP, kc clients.Kubernetes, dir string) *blueprintTester {
+	return &blueprintTester{hc: hc, kc: kc, dir: dir, retryInterval: 2 * time.Second}
+}
+
+// run executes all the assertions for the test, assertions do not stop on the
+// first failure so that the report contains the result of every assertion
+func (bt *blueprintTester) run(t *config.Test) testResult {
+	res := testResult{Name: fmt.Sprintf("%s.%s", t.Type, t.Name)}
+	timeout := t.TimeoutDuration()
+
+	for _, h := range t.HTTP {
+		res.Assertions = append(res.Assertions, bt.assert(httpAssertionName(h), func() error {
+			return bt.checkHTTP(h, timeout)
+		}))
+	}
+
+	for _, s := range t.Script {
+		res.Assertions = append(res.Assertions, bt.assert(scriptAssertionName(s), func() error {
+			return bt.checkScript(s, timeout)
+		}))
+	}
+
+	for _, p := range t.PodReady {
+		res.Assertions = append(res.Assertions, bt.assert(fmt.Sprintf("pod_ready %s %s", p.Cluster, strings.Join(p.Pods, ", ")), func() error {
+			return bt.checkPods(p, timeout)
+		}))
+	}
+
+	return res
+}
+
+func (bt *blueprintTester) assert(name string, f func() error) testAssertion {
+	st := time.Now()
+	err := f()
+
+	return testAssertion{Name: name, Duration: time.Since(st), Err: err}
+}
+
+// checkHTTP retries the request until a success code is returned and the body
+// contains the expected value or the timeout elapses
+func (bt *blueprintTester) checkHTTP(h config.TestHTTP, timeout time.Duration) error {
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	codes := h.SuccessCodes
+	if len(codes) == 0 {
+		codes = []int{http.StatusOK}
+	}
+
+	var err error
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err = bt.httpAttempt(method, h, codes)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+
+		time.Sleep(bt.retryInterval)
+	}
+}
+
+func (bt *blueprintTester) httpAttempt(method string, h config.TestHTTP, codes []int) error {
+	req, err := http.NewRequest(method, h.Address, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := bt.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	ok := false
+	for _, c := range codes {
+		if resp.StatusCode == c {
+			ok = true
+		}
+	}
+
+	if !ok {
+		return fmt.Errorf("expected status code %v, got %d", codes, resp.StatusCode)
+	}
+
+	if h.BodyContains != "" && !strings.Contains(string(body), h.BodyContains) {
+		return fmt.Errorf("expected response body to contain '%s', got '%s'", h.BodyContains, string(body))
+	}
+
+	return nil
+}
+
+// checkScript runs the command or script once and checks the exit code and output
+func (bt *blueprintTester) checkScript(s config.TestScript, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var c *exec.Cmd
+	if s.Script != "" {
+		c = exec.CommandContext(ctx, "sh", "-c", s.Script)
+	} else {
+		c = exec.CommandContext(ctx, s.Command[0], s.Command[1:]...)
+	}
+
+	out := bytes.NewBufferString("")
+	c.Stdout = out
+	c.Stderr = out
+	c.Dir = bt.dir
+	c.Env = append(os.Environ(), bt.env...)
+
+	exitCode := 0
+	err := c.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("script did not complete within %s\nOutput:\n%s", timeout, out.String())
+	}
+
+	if ee, ok := err.(*exec.ExitError); ok {
+		exitCode = ee.ExitCode()
+	} else if err != nil {
+		return fmt.Errorf("unable to run script: %s", err)
+	}
+
+	if exitCode != s.ExitCode {
+		return fmt.Errorf("expected exit code %d, got %d\nOutput:\n%s", s.ExitCode, exitCode, out.String())
+	}
+
+	if s.OutputContains != "" && !strings.Contains(out.String(), s.OutputContains) {
+		return fmt.Errorf("expected output to contain '%s'\nOutput:\n%s", s.OutputContains, out.String())
+	}
+
+	return nil
+}
+
+// checkPods waits for the pods matching the selectors to be running
+func (bt *blueprintTester) checkPods(p config.TestPodReady, timeout time.Duration) error {
+	_, kubeConfig, _, err := utils.CreateKubeConfigPath(strings.TrimPrefix(p.Cluster, fmt.Sprintf("%s.", config.TypeK8sCluster)))
+	if err != nil {
+		return err
+	}
+
+	kc, err := bt.kc.SetConfig(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("unable to create Kubernetes client for %s: %s", p.Cluster, err)
+	}
+
+	return kc.HealthCheckPods(p.Pods, timeout)
+}
+
+func httpAssertionName(h config.TestHTTP) string {
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	return fmt.Sprintf("http %s %s", method, h.Address)
+}
+
+func scriptAssertionName(s config.TestScript) string {
+	if s.Script != "" {
+		return fmt.Sprintf("script %s", strings.Split(strings.TrimSpace(s.Script), "\n")[0])
+	}
+
+	return fmt.Sprintf("script %s", strings.Join(s.Command, " "))
+}
+
+func printTestResults(w io.Writer, results []testResult) {
+	passed, failed := 0, 0
+
+	for _, r := range results {
+		fmt.Fprintln(w, r.Name)
+
+		for _, a := range r.Assertions {
+			if a.Err != nil {
+				failed++
+				fmt.Fprintf(w, "  %s %s (%s)\n", fmt.Sprintf(Red, "[ FAIL ]"), a.Name, a.Duration.Round(time.Millisecond))
+				fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(a.Err.Error(), "\n", "\n    "))
+				continue
+			}
+
+			passed++
+			fmt.Fprintf(w, "  %s %s (%s)\n", fmt.Sprintf(Green, "[ PASS ]"), a.Name, a.Duration.Round(time.Millisecond))
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Passed: %d Failed: %d\n", passed, failed)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+// writeJUnitReport writes the results to the file in JUnit XML format, each test
+// resource is a test suite and each assertion a test case
+func writeJUnitReport(path string, results []testResult) error {
+	report := junitTestSuites{Name: "shipyard"}
+
+	var total time.Duration
+	for _, r := range results {
+		suite := junitTestSuite{Name: r.Name}
+
+		var suiteTime time.Duration
+		for _, a := range r.Assertions {
+			tc := junitTestCase{ClassName: r.Name, Name: a.Name, Time: junitTime(a.Duration)}
+			if a.Err != nil {
+				tc.Failure = &junitFailure{Message: strings.Split(a.Err.Error(), "\n")[0], Content: a.Err.Error()}
+				suite.Failures++
+			}
+
+			suite.Tests++
+			suiteTime += a.Duration
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+
+		suite.Time = junitTime(suiteTime)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+		total += suiteTime
+	}
+
+	report.Time = junitTime(total)
+
+	d, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append([]byte(xml.Header), d...), 0644)
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupBlueprintTester(t *testing.T) (*blueprintTester, *clients.MockKubernetes) {
+	kc := &clients.MockKubernetes{}
+
+	bt := newBlueprintTester(clients.NewHTTP(time.Millisecond, hclog.NewNullLogger()), kc, t.TempDir())
+	bt.retryInterval = time.Millisecond
+
+	return bt, kc
+}
+
+func TestBlueprintTesterPassesHTTPAssertions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "status: ok")
+	}))
+	defer ts.Close()
+
+	bt, _ := setupBlueprintTester(t)
+
+	tst := config.NewTest("api")
+	tst.Timeout = "100ms"
+	tst.HTTP = []config.TestHTTP{{Address: ts.URL, BodyContains: "ok"}}
+
+	res := bt.run(tst)
+	assert.False(t, res.Failed())
+	assert.Len(t, res.Assertions, 1)
+	assert.Equal(t, "http GET "+ts.URL, res.Assertions[0].Name)
+}
+
+func TestBlueprintTesterFailsHTTPAssertionsWithWrongStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	bt, _ := setupBlueprintTester(t)
+
+	tst := config.NewTest("api")
+	tst.Timeout = "10ms"
+	tst.HTTP = []config.TestHTTP{{Address: ts.URL, SuccessCodes: []int{200, 201}}}
+
+	res := bt.run(tst)
+	assert.True(t, res.Failed())
+	assert.Contains(t, res.Assertions[0].Err.Error(), "expected status code [200 201], got 500")
+}
+
+func TestBlueprintTesterChecksScriptExitCodeAndOutput(t *testing.T) {
+	bt, _ := setupBlueprintTester(t)
+	bt.env = []string{"TEST_VALUE=hello"}
+
+	tst := config.NewTest("script")
+	tst.Script = []config.TestScript{
+		{Script: "echo $TEST_VALUE", OutputContains: "hello"},
+		{Script: "exit 3", ExitCode: 3},
+		{Command: []string{"sh", "-c", "exit 1"}},
+	}
+
+	res := bt.run(tst)
+	assert.Len(t, res.Assertions, 3)
+	assert.NoError(t, res.Assertions[0].Err)
+	assert.NoError(t, res.Assertions[1].Err)
+	assert.Error(t, res.Assertions[2].Err)
+	assert.Contains(t, res.Assertions[2].Err.Error(), "expected exit code 0, got 1")
+}
+
+func TestBlueprintTesterChecksPods(t *testing.T) {
+	setupWatchState(t)
+	bt, kc := setupBlueprintTester(t)
+	kc.On("SetConfig", mock.Anything).Return(nil)
+	kc.On("HealthCheckPods", []string{"app=api"}, config.DefaultTestTimeout).Return(fmt.Errorf("pods not ready"))
+
+	tst := config.NewTest("pods")
+	tst.PodReady = []config.TestPodReady{{Cluster: "k8s_cluster.k3s", Pods: []string{"app=api"}}}
+
+	res := bt.run(tst)
+	assert.True(t, res.Failed())
+	assert.Contains(t, kc.Calls[0].Arguments.String(0), filepath.Join("config", "k3s", "kubeconfig.yaml"))
+}
+
+func TestWriteJUnitReportWritesSuites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "junit.xml")
+
+	results := []testResult{
+		{
+			Name: "test.api",
+			Assertions: []testAssertion{
+				{Name: "http GET http://localhost", Duration: time.Second},
+				{Name: "script exit 1", Duration: 2 * time.Second, Err: fmt.Errorf("expected exit code 0, got 1\nOutput:")},
+			},
+		},
+	}
+
+	err := writeJUnitReport(path, results)
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	report := junitTestSuites{}
+	err = xml.Unmarshal(d, &report)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, "3.000", report.Time)
+	assert.Len(t, report.Suites, 1)
+	assert.Equal(t, "test.api", report.Suites[0].Name)
+	assert.Nil(t, report.Suites[0].TestCases[0].Failure)
+	assert.Equal(t, "expected exit code 0, got 1", report.Suites[0].TestCases[1].Failure.Message)
+}
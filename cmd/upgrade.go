@@ -1,16 +1,221 @@
 package cmd
 
 import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	gvm "github.com/shipyard-run/version-manager"
 	"github.com/spf13/cobra"
 )
 
-var upgradeCmd = &cobra.Command{
-	Use:   "upgrade",
-	Short: "Upgrade Shipyard",
-	Long:  `Upgrade the Shipyard binary, but leaves the stacks alone`,
-	DisableFlagsInUseLine: true,
-	Args: cobra.NoArgs,
-	Run: func(cmd *cobra.Command, args []string) {
-		// Do Stuff Here
-	},
-}
\ No newline at end of file
+// release channels, stable only contains releases without a pre-release
+// suffix, beta also contains pre-releases e.g. v0.5.0-beta.1
+const (
+	channelStable = "stable"
+	channelBeta   = "beta"
+)
+
+// currentExecutable returns the path of the running Shipyard binary, it is
+// a variable so that it can be replaced in tests
+var currentExecutable = os.Executable
+
+func newUpgradeCmd(vm gvm.Versions) *cobra.Command {
+	var channel string
+	var upgradeVersion string
+
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade Shipyard",
+		Long: `Upgrade the Shipyard binary, but leaves the stacks alone.
+
+The release is downloaded to the releases folder, its checksum is verified, and
+the running binary is replaced with the new version.`,
+		Example: `
+  # Upgrade to the latest stable release
+  shipyard upgrade
+
+  # Upgrade to the latest release including pre-releases
+  shipyard upgrade --channel beta
+
+  # Install a specific version
+  shipyard upgrade --version v0.5.0
+`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tag, url, err := findRelease(vm, channel, upgradeVersion)
+			if err != nil {
+				return err
+			}
+
+			if upgradeVersion == "" && !isNewerVersion(tag, version) {
+				cmd.Printf("Shipyard %s is the latest %s release\n", version, channel)
+				return nil
+			}
+
+			cmd.Println("Downloading", url)
+
+			// the archive is verified against the checksums file before it is uncompressed
+			path, err := vm.DownloadRelease(tag, url)
+			if err != nil {
+				return newCommandError(ErrorCodeUnknown, "Unable to download Shipyard %s: %s", tag, err)
+			}
+
+			exe, err := currentExecutable()
+			if err != nil {
+				return newCommandError(ErrorCodeUnknown, "Unable to find the Shipyard binary: %s", err)
+			}
+
+			exe, err = filepath.EvalSymlinks(exe)
+			if err != nil {
+				return newCommandError(ErrorCodeUnknown, "Unable to find the Shipyard binary: %s", err)
+			}
+
+			err = replaceExecutable(path, exe)
+			if err != nil {
+				return newCommandError(ErrorCodeUnknown, "Unable to replace %s: %s", exe, err)
+			}
+
+			cmd.Printf("Shipyard upgraded from %s to %s\n", version, tag)
+
+			return nil
+		},
+	}
+
+	upgradeCmd.Flags().StringVarP(&channel, "channel", "", channelStable, "Release channel to upgrade from, stable or beta")
+	upgradeCmd.Flags().StringVarP(&upgradeVersion, "version", "v", "", "Install the given version rather than the latest release")
+
+	return upgradeCmd
+}
+
+// findRelease returns the tag and download URL for the latest release in the channel,
+// when a version is given the release for the version is returned
+func findRelease(vm gvm.Versions, channel, ver string) (string, string, error) {
+	if channel != channelStable && channel != channelBeta {
+		return "", "", newCommandError(ErrorCodeUsage, "Unknown release channel '%s', the channel must be stable or beta", channel)
+	}
+
+	releases, err := vm.ListReleases("")
+	if err != nil {
+		return "", "", newCommandError(ErrorCodeUnknown, "Unable to list Shipyard releases: %s", err)
+	}
+
+	if ver != "" {
+		for tag, url := range releases {
+			if strings.TrimPrefix(tag, "v") == strings.TrimPrefix(ver, "v") {
+				return tag, url, nil
+			}
+		}
+
+		return "", "", newCommandError(ErrorCodeUsage, "Shipyard %s does not exist or has no release for this platform, use 'shipyard version list' to show the available versions", ver)
+	}
+
+	var latest *semver.Version
+	for tag := range releases {
+		v, err := semver.NewVersion(tag)
+		if err != nil {
+			continue
+		}
+
+		if channel == channelStable && v.Prerelease() != "" {
+			continue
+		}
+
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+		}
+	}
+
+	if latest == nil {
+		return "", "", newCommandError(ErrorCodeUnknown, "No %s releases of Shipyard are available for this platform", channel)
+	}
+
+	return latest.Original(), releases[latest.Original()], nil
+}
+
+// isNewerVersion returns true when the release is newer than the current version,
+// development builds which are not a semantic version can always be upgraded
+func isNewerVersion(release, current string) bool {
+	r, err := semver.NewVersion(release)
+	if err != nil {
+		return false
+	}
+
+	c, err := semver.NewVersion(current)
+	if err != nil {
+		return true
+	}
+
+	return r.GreaterThan(c)
+}
+
+// replaceExecutable replaces the binary at dst with src, the new binary is copied
+// next to dst and renamed over it so that dst is never left partially written.
+// The running binary is moved aside first as Windows does not allow a running
+// executable to be replaced.
+func replaceExecutable(src, dst string) error {
+	info, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + ".new"
+	err = copyExecutable(src, tmp, info.Mode())
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	old := dst + ".old"
+	os.Remove(old)
+
+	err = os.Rename(dst, old)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	err = os.Rename(tmp, dst)
+	if err != nil {
+		// restore the previous binary
+		os.Rename(old, dst)
+		os.Remove(tmp)
+		return err
+	}
+
+	// removing the old binary fails on Windows while it is running, it is
+	// removed by the next upgrade
+	os.Remove(old)
+
+	return nil
+}
+
+func copyExecutable(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	err = out.Close()
+	if err != nil {
+		return err
+	}
+
+	// the mode given to OpenFile is masked by the umask
+	return os.Chmod(dst, mode)
+}
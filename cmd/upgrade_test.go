@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gvm "github.com/shipyard-run/version-manager"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+const releaseURL = "https://github.com/shipyard-run/shipyard/releases/download"
+
+func setupUpgrade(t *testing.T, current string) (*cobra.Command, *gvm.MockVersions, string, *bytes.Buffer) {
+	dir := t.TempDir()
+
+	// the downloaded release
+	release := filepath.Join(dir, "release")
+	err := ioutil.WriteFile(release, []byte("new"), 0755)
+	assert.NoError(t, err)
+
+	// the running binary
+	exe := filepath.Join(dir, "shipyard")
+	err = ioutil.WriteFile(exe, []byte("old"), 0755)
+	assert.NoError(t, err)
+
+	currentVersion := version
+	version = current
+	currentExecutable = func() (string, error) { return exe, nil }
+
+	t.Cleanup(func() {
+		version = currentVersion
+		currentExecutable = os.Executable
+	})
+
+	vm := &gvm.MockVersions{}
+	vm.On("ListReleases", "").Return(map[string]string{
+		"v0.4.0":        releaseURL + "/v0.4.0/shipyard_0.4.0_linux_x86_64.tar.gz",
+		"v0.5.0":        releaseURL + "/v0.5.0/shipyard_0.5.0_linux_x86_64.tar.gz",
+		"v0.6.0-beta.1": releaseURL + "/v0.6.0-beta.1/shipyard_0.6.0-beta.1_linux_x86_64.tar.gz",
+	}, nil)
+	vm.On("DownloadRelease", mock.Anything, mock.Anything).Return(release, nil)
+
+	out := bytes.NewBufferString("")
+	c := newUpgradeCmd(&verifiedVersions{vm})
+	c.SetOut(out)
+	c.SetErr(out)
+
+	return c, vm, exe, out
+}
+
+func TestUpgradeInstallsLatestStableRelease(t *testing.T) {
+	c, vm, exe, out := setupUpgrade(t, "0.4.0")
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	vm.AssertCalled(t, "DownloadRelease", "v0.5.0", releaseURL+"/v0.5.0/shipyard_0.5.0_linux_x86_64.tar.gz?checksum=file:"+releaseURL+"/v0.5.0/shipyard_0.5.0_checksums.txt")
+
+	d, err := ioutil.ReadFile(exe)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(d))
+	assert.NoFileExists(t, exe+".old")
+	assert.Contains(t, out.String(), "Shipyard upgraded from 0.4.0 to v0.5.0")
+}
+
+func TestUpgradeWithBetaChannelInstallsPreRelease(t *testing.T) {
+	c, vm, _, _ := setupUpgrade(t, "0.4.0")
+	c.SetArgs([]string{"--channel", "beta"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	vm.AssertCalled(t, "DownloadRelease", "v0.6.0-beta.1", mock.Anything)
+}
+
+func TestUpgradeWithVersionInstallsVersion(t *testing.T) {
+	c, vm, _, _ := setupUpgrade(t, "0.5.0")
+	c.SetArgs([]string{"--version", "0.4.0"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	vm.AssertCalled(t, "DownloadRelease", "v0.4.0", mock.Anything)
+}
+
+func TestUpgradeWithUnknownVersionReturnsError(t *testing.T) {
+	c, _, _, _ := setupUpgrade(t, "0.5.0")
+	c.SetArgs([]string{"--version", "0.9.0"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeUsage, ErrorCodeFor(err))
+}
+
+func TestUpgradeWithUnknownChannelReturnsError(t *testing.T) {
+	c, _, _, _ := setupUpgrade(t, "0.5.0")
+	c.SetArgs([]string{"--channel", "nightly"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+	assert.Equal(t, ErrorCodeUsage, ErrorCodeFor(err))
+}
+
+func TestUpgradeWhenUpToDateDoesNotDownload(t *testing.T) {
+	c, vm, exe, out := setupUpgrade(t, "0.5.0")
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	vm.AssertNotCalled(t, "DownloadRelease", mock.Anything, mock.Anything)
+
+	d, err := ioutil.ReadFile(exe)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", string(d))
+	assert.Contains(t, out.String(), "latest stable release")
+}
+
+func TestUpgradeWhenDownloadFailsKeepsBinary(t *testing.T) {
+	c, vm, exe, _ := setupUpgrade(t, "0.4.0")
+	c.SetArgs([]string{})
+	removeOn(&vm.Mock, "DownloadRelease")
+	vm.On("DownloadRelease", mock.Anything, mock.Anything).Return("", os.ErrNotExist)
+
+	err := c.Execute()
+	assert.Error(t, err)
+
+	d, err := ioutil.ReadFile(exe)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", string(d))
+}
+
+func TestVersionCheckReportsAvailableUpdate(t *testing.T) {
+	_, vm, _, _ := setupUpgrade(t, "0.4.0")
+
+	out := bytes.NewBufferString("")
+	c := newVersionCmd(vm)
+	c.SetOut(out)
+	c.SetArgs([]string{"--check"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Shipyard v0.5.0 is available")
+}
+
+func TestVersionCheckWhenUpToDate(t *testing.T) {
+	_, vm, _, _ := setupUpgrade(t, "0.5.0")
+
+	out := bytes.NewBufferString("")
+	c := newVersionCmd(vm)
+	c.SetOut(out)
+	c.SetArgs([]string{"--check"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "up to date")
+}
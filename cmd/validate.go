@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func newValidateCmd() *cobra.Command {
+	var variables []string
+	var variablesFile string
+	var output string
+
+	validateCmd := &cobra.Command{
+		Use:   "validate [blueprint]",
+		Short: "Check a blueprint for errors without creating any resources",
+		Long: `Check a blueprint for errors without creating any resources.
+
+Validate reports unknown attributes and blocks, missing required attributes,
+invalid resource names, duplicate resources, and references to resources which
+do not exist. Docker is not required to validate a blueprint.`,
+		Example: `
+  # Validate the blueprint in the current folder
+  shipyard validate
+
+  # Validate a blueprint and output the problems as JSON
+  shipyard validate -o json ./my-blueprint
+	`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "text" && output != "json" {
+				return newCommandError(ErrorCodeUsage, "Invalid output '%s', valid outputs are text or json", output)
+			}
+
+			path := "."
+			if len(args) == 1 {
+				path = args[0]
+			}
+
+			vars := map[string]string{}
+			for _, v := range variables {
+				parts := strings.Split(v, "=")
+				if len(parts) == 2 {
+					vars[parts[0]] = parts[1]
+				}
+			}
+
+			errs := config.Validate(path, vars, variablesFile)
+
+			if output == "json" {
+				if errs == nil {
+					errs = []config.ValidationError{}
+				}
+
+				d, err := json.MarshalIndent(errs, "", "  ")
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintln(cmd.OutOrStdout(), string(d))
+			} else {
+				for _, e := range errs {
+					fmt.Fprintln(cmd.OutOrStdout(), e.Error())
+				}
+			}
+
+			if len(errs) > 0 {
+				return newCommandError(ErrorCodeConfig, "Blueprint %s is not valid, found %d problem(s)", path, len(errs))
+			}
+
+			if output == "text" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Blueprint %s is valid\n", path)
+			}
+
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	validateCmd.Flags().StringVarP(&output, "output", "o", "text", "Output format for the problems found [text, json]")
+	validateCmd.Flags().StringSliceVarP(&variables, "var", "", nil, "Allows setting variables from the command line, variables are specified as a key and value, e.g --var key=value. Can be specified multiple times")
+	validateCmd.Flags().StringVarP(&variablesFile, "vars-file", "", "", "Load variables from a location other than *.vars files in the blueprint folder. E.g --vars-file=./file.vars")
+
+	return validateCmd
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func setupValidate(t *testing.T, blueprint string) (*cobra.Command, *bytes.Buffer, string) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(blueprint), os.ModePerm)
+	require.NoError(t, err)
+
+	out := bytes.NewBufferString("")
+
+	vc := newValidateCmd()
+	vc.SetOut(out)
+	vc.SetErr(bytes.NewBufferString(""))
+
+	return vc, out, dir
+}
+
+func TestValidateWithValidBlueprint(t *testing.T) {
+	vc, out, dir := setupValidate(t, graphBlueprint)
+	vc.SetArgs([]string{dir})
+
+	err := vc.Execute()
+	require.NoError(t, err)
+
+	require.Contains(t, out.String(), "is valid")
+}
+
+func TestValidateWithInvalidBlueprintReturnsError(t *testing.T) {
+	vc, out, dir := setupValidate(t, validateInvalidBlueprint)
+	vc.SetArgs([]string{dir})
+
+	err := vc.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "found 2 problem(s)")
+
+	require.Contains(t, out.String(), `An argument named "ports" is not expected here.`)
+	require.Contains(t, out.String(), "resource 'container.web' is already defined at")
+}
+
+func TestValidateWritesJSON(t *testing.T) {
+	vc, out, dir := setupValidate(t, validateInvalidBlueprint)
+	vc.SetArgs([]string{"-o", "json", dir})
+
+	err := vc.Execute()
+	require.Error(t, err)
+
+	errs := []config.ValidationError{}
+	err = json.Unmarshal(out.Bytes(), &errs)
+	require.NoError(t, err)
+
+	require.Len(t, errs, 2)
+	require.Equal(t, "container.web", errs[0].Resource)
+	require.Equal(t, 8, errs[0].Line)
+}
+
+func TestValidateWithInvalidOutputReturnsError(t *testing.T) {
+	vc, _, dir := setupValidate(t, graphBlueprint)
+	vc.SetArgs([]string{"-o", "xml", dir})
+
+	err := vc.Execute()
+	require.Error(t, err)
+}
+
+var validateInvalidBlueprint = `
+container "web" {
+  image {
+    name = "nginx:1.19"
+  }
+
+  # ports must be defined using port blocks
+  ports = ["80:80"]
+}
+
+container "web" {
+  image {
+    name = "nginx:1.20"
+  }
+}
+`
@@ -8,6 +8,9 @@ import (
 )
 
 func newVersionCmd(vm gvm.Versions) *cobra.Command {
+	var check bool
+	var channel string
+
 	var versionCmd = &cobra.Command{
 		Use:           "version",
 		Short:         "Shipyard version manager commands",
@@ -18,10 +21,29 @@ func newVersionCmd(vm gvm.Versions) *cobra.Command {
 			cmd.Println("Current Version:", version)
 			cmd.Println("")
 
-			return fmt.Errorf("")
+			if !check {
+				return fmt.Errorf("")
+			}
+
+			tag, _, err := findRelease(vm, channel, "")
+			if err != nil {
+				return err
+			}
+
+			if !isNewerVersion(tag, version) {
+				cmd.Printf("Shipyard is up to date with the latest %s release\n", channel)
+				return nil
+			}
+
+			cmd.Printf("Shipyard %s is available, run 'shipyard upgrade --channel %s' to upgrade\n", tag, channel)
+
+			return nil
 		},
 	}
 
+	versionCmd.Flags().BoolVarP(&check, "check", "", false, "Check if a newer version of Shipyard is available")
+	versionCmd.Flags().StringVarP(&channel, "channel", "", channelStable, "Release channel to check, stable or beta")
+
 	versionCmd.AddCommand(newVersionListCmd(vm))
 	versionCmd.AddCommand(newVersionInstallCmd(vm))
 	return versionCmd
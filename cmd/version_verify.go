@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	gvm "github.com/shipyard-run/version-manager"
+)
+
+// verifiedVersions wraps the version manager so that every release which is
+// downloaded is verified against the checksums file published with the release,
+// a release which does not match the checksum is not uncompressed
+type verifiedVersions struct {
+	gvm.Versions
+}
+
+// DownloadRelease downloads the release at url after verifying the checksum
+func (v *verifiedVersions) DownloadRelease(tag, url string) (string, error) {
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+
+	return v.Versions.DownloadRelease(tag, fmt.Sprintf("%s%schecksum=file:%s", url, sep, checksumsURL(tag, url)))
+}
+
+// checksumsURL returns the location of the checksums file which is published
+// alongside the release assets e.g. shipyard_0.5.0_checksums.txt
+func checksumsURL(tag, url string) string {
+	if i := strings.Index(url, "?"); i >= 0 {
+		url = url[:i]
+	}
+
+	base := url[:strings.LastIndex(url, "/")+1]
+	return fmt.Sprintf("%sshipyard_%s_checksums.txt", base, strings.TrimPrefix(tag, "v"))
+}
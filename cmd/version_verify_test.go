@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	gvm "github.com/shipyard-run/version-manager"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func releaseArchive(t *testing.T) []byte {
+	buf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+
+	data := []byte("shipyard")
+	err := tw.WriteHeader(&tar.Header{Name: "shipyard", Mode: 0755, Size: int64(len(data))})
+	assert.NoError(t, err)
+
+	_, err = tw.Write(data)
+	assert.NoError(t, err)
+
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gw.Close())
+
+	return buf.Bytes()
+}
+
+func setupVerifiedVersions(t *testing.T, checksum string) (gvm.Versions, string, string) {
+	archive := releaseArchive(t)
+	if checksum == "" {
+		s := sha256.Sum256(archive)
+		checksum = hex.EncodeToString(s[:])
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v0.5.0/shipyard_0.5.0_linux_x86_64.tar.gz", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write(archive)
+	})
+	mux.HandleFunc("/v0.5.0/shipyard_0.5.0_checksums.txt", func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(rw, "%s  shipyard_0.5.0_linux_x86_64.tar.gz\n", checksum)
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	dir := t.TempDir()
+	vm := gvm.New(gvm.Options{
+		ReleasesPath: dir,
+		ExeNameFunc: func(version, goos, goarch string) string {
+			return "shipyard"
+		},
+	})
+
+	return &verifiedVersions{vm}, ts.URL + "/v0.5.0/shipyard_0.5.0_linux_x86_64.tar.gz", dir
+}
+
+func TestVerifiedVersionsAddsChecksumsFileToURL(t *testing.T) {
+	vm := &gvm.MockVersions{}
+	vm.On("DownloadRelease", mock.Anything, mock.Anything).Return("", nil)
+
+	_, err := (&verifiedVersions{vm}).DownloadRelease("v0.5.0", releaseURL+"/v0.5.0/shipyard_0.5.0_linux_x86_64.tar.gz")
+	assert.NoError(t, err)
+
+	vm.AssertCalled(t, "DownloadRelease", "v0.5.0", releaseURL+"/v0.5.0/shipyard_0.5.0_linux_x86_64.tar.gz?checksum=file:"+releaseURL+"/v0.5.0/shipyard_0.5.0_checksums.txt")
+}
+
+func TestVerifiedVersionsDownloadsRelease(t *testing.T) {
+	vm, url, dir := setupVerifiedVersions(t, "")
+
+	path, err := vm.DownloadRelease("v0.5.0", url)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "v0.5.0", "shipyard"), path)
+
+	d, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "shipyard", string(d))
+}
+
+func TestVerifiedVersionsWithChecksumMismatchReturnsError(t *testing.T) {
+	s := sha256.Sum256([]byte("tampered"))
+	vm, url, dir := setupVerifiedVersions(t, hex.EncodeToString(s[:]))
+
+	_, err := vm.DownloadRelease("v0.5.0", url)
+	assert.Error(t, err)
+
+	assert.NoFileExists(t, filepath.Join(dir, "v0.5.0", "shipyard"))
+}
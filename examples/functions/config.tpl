@@ -0,0 +1,5 @@
+datacenter = "${datacenter}"
+%{ for p in ports ~}
+port ${p}
+%{ endfor ~}
+home = "${home()}"
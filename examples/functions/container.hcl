@@ -37,5 +37,8 @@ container "consul" {
     shipyard_ip       = shipyard_ip()
     cluster_api       = cluster_api("nomad_cluster.dc1")
     var_len           = len(var.test_var)
+    string_len        = len("consul")
+    map_len           = len({ a = 1, b = 2, c = 3 })
+    template          = templatefile("./config.tpl", { datacenter = "dc1", ports = [8500, 8600] })
   }
 }
@@ -13,6 +13,6 @@ var date = "0000-00-00"
 func main() {
 	err := cmd.Execute(version, commit, date)
 	if err != nil {
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -0,0 +1,192 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/hashicorp/go-getter"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/oras"
+)
+
+// Media types of the OCI artifacts created by BlueprintRegistry.Push
+const (
+	BlueprintConfigMediaType = "application/vnd.shipyard.blueprint.config.v1+json"
+	BlueprintLayerMediaType  = "application/vnd.shipyard.blueprint.layer.v1.tar+gzip"
+)
+
+// BlueprintRegistry pushes blueprints to and pulls blueprints from
+// OCI registries such as ghcr.io
+type BlueprintRegistry interface {
+	// Push packages the blueprint in the folder as an OCI artifact and pushes it
+	// to the reference e.g. ghcr.io/org/blueprint:v1, returns the digest of the
+	// pushed manifest
+	Push(ctx context.Context, dir, ref string) (string, error)
+
+	// Pull fetches the blueprint at the reference and extracts it to the folder
+	Pull(ctx context.Context, ref, dst string) error
+}
+
+// BlueprintRegistryImpl is the concrete implementation of BlueprintRegistry,
+// credentials for the registry are read from the Docker config e.g.
+// the credentials saved by docker login
+type BlueprintRegistryImpl struct {
+	target func(ref string) remotes.Resolver
+	tgz    *TarGz
+}
+
+// NewBlueprintRegistry creates a new BlueprintRegistry
+func NewBlueprintRegistry() *BlueprintRegistryImpl {
+	return &BlueprintRegistryImpl{target: registryTarget, tgz: &TarGz{}}
+}
+
+// Push the blueprint in dir to the registry
+func (r *BlueprintRegistryImpl) Push(ctx context.Context, dir, ref string) (string, error) {
+	ref = ociReference(ref)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.hcl"))
+	if len(files) == 0 {
+		return "", fmt.Errorf("folder %s does not contain a blueprint", dir)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := r.tgz.Compress(buf, &TarGzOptions{OmitRoot: true}, dir)
+	if err != nil {
+		return "", fmt.Errorf("unable to package blueprint %s: %s", dir, err)
+	}
+
+	store := content.NewMemory()
+
+	layer, err := store.Add("", BlueprintLayerMediaType, buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	config, err := store.Add("", BlueprintConfigMediaType, []byte("{}"))
+	if err != nil {
+		return "", err
+	}
+
+	manifestData, manifest, err := content.GenerateManifest(&config, nil, layer)
+	if err != nil {
+		return "", err
+	}
+
+	err = store.StoreManifest(ref, manifest, manifestData)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = oras.Copy(ctx, store, ref, r.target(ref), "", oras.WithNameValidation(nil))
+	if err != nil {
+		return "", fmt.Errorf("unable to push blueprint to %s: %s", ref, err)
+	}
+
+	return manifest.Digest.String(), nil
+}
+
+// Pull the blueprint at ref and extract it to dst
+func (r *BlueprintRegistryImpl) Pull(ctx context.Context, ref, dst string) error {
+	ref = ociReference(ref)
+
+	store := content.NewMemory()
+	layers := []ocispec.Descriptor{}
+
+	_, err := oras.Copy(ctx, r.target(ref), ref, store, "",
+		oras.WithPullEmptyNameAllowed(),
+		oras.WithAllowedMediaTypes([]string{ocispec.MediaTypeImageManifest, BlueprintConfigMediaType, BlueprintLayerMediaType}),
+		oras.WithLayerDescriptors(func(l []ocispec.Descriptor) {
+			layers = l
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to pull blueprint %s: %s", ref, err)
+	}
+
+	for _, l := range layers {
+		if l.MediaType != BlueprintLayerMediaType {
+			continue
+		}
+
+		_, data, ok := store.Get(l)
+		if !ok {
+			return fmt.Errorf("unable to pull blueprint %s: layer %s was not downloaded", ref, l.Digest)
+		}
+
+		err = os.MkdirAll(dst, os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		return r.tgz.Uncompress(bytes.NewReader(data), dst)
+	}
+
+	return fmt.Errorf("%s is not a Shipyard blueprint, push blueprints with 'shipyard blueprint push'", ref)
+}
+
+// ociReference returns the reference with the oci:// scheme removed and the
+// tag set to latest when the reference does not have a tag or digest
+func ociReference(ref string) string {
+	ref = strings.TrimPrefix(ref, "oci://")
+
+	name := ref[strings.LastIndex(ref, "/")+1:]
+	if !strings.Contains(name, ":") && !strings.Contains(name, "@") {
+		ref = ref + ":latest"
+	}
+
+	return ref
+}
+
+// registryTarget returns the resolver for the registry in the reference,
+// registries on the local machine are accessed over plain HTTP
+func registryTarget(ref string) remotes.Resolver {
+	host := ref
+	if i := strings.Index(ref, "/"); i >= 0 {
+		host = ref[:i]
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	plainHTTP := host == "localhost" || net.ParseIP(host).IsLoopback()
+
+	reg, _ := content.NewRegistry(content.RegistryOptions{PlainHTTP: plainHTTP})
+	return reg
+}
+
+// ociGetter is a go-getter Getter which fetches blueprints pushed to an
+// OCI registry e.g. oci://ghcr.io/org/blueprint:v1
+type ociGetter struct {
+	registry BlueprintRegistry
+	ctx      context.Context
+}
+
+func (g *ociGetter) ClientMode(u *url.URL) (getter.ClientMode, error) {
+	return getter.ClientModeDir, nil
+}
+
+func (g *ociGetter) Get(dst string, u *url.URL) error {
+	ctx := g.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return g.registry.Pull(ctx, u.Host+u.Path, dst)
+}
+
+func (g *ociGetter) GetFile(dst string, u *url.URL) error {
+	return fmt.Errorf("blueprints in OCI registries are folders, single files can not be fetched")
+}
+
+func (g *ociGetter) SetClient(c *getter.Client) {
+	g.ctx = c.Ctx
+}
@@ -0,0 +1,80 @@
+package clients
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/pkg/content"
+)
+
+func setupBlueprintRegistry(t *testing.T) (*BlueprintRegistryImpl, string) {
+	store := content.NewMemory()
+
+	r := NewBlueprintRegistry()
+	r.target = func(ref string) remotes.Resolver { return store }
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "files"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(`container "consul" {}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "files", "config.json"), []byte(`{}`), 0644)
+
+	return r, dir
+}
+
+func TestBlueprintRegistryPushesAndPullsBlueprint(t *testing.T) {
+	r, dir := setupBlueprintRegistry(t)
+
+	digest, err := r.Push(context.Background(), dir, "ghcr.io/org/consul:v1")
+	require.NoError(t, err)
+	assert.Contains(t, digest, "sha256:")
+
+	dst := filepath.Join(t.TempDir(), "consul")
+	err = r.Pull(context.Background(), "ghcr.io/org/consul:v1", dst)
+	require.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(dst, "main.hcl"))
+	assert.FileExists(t, filepath.Join(dst, "files", "config.json"))
+}
+
+func TestBlueprintRegistryPushReturnsErrorWhenNoBlueprint(t *testing.T) {
+	r, _ := setupBlueprintRegistry(t)
+
+	_, err := r.Push(context.Background(), t.TempDir(), "ghcr.io/org/consul:v1")
+	assert.Error(t, err)
+}
+
+func TestBlueprintRegistryPullReturnsErrorWhenNotFound(t *testing.T) {
+	r, _ := setupBlueprintRegistry(t)
+
+	err := r.Pull(context.Background(), "ghcr.io/org/consul:v1", t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestOCIReferenceAddsLatestTag(t *testing.T) {
+	assert.Equal(t, "ghcr.io/org/consul:latest", ociReference("oci://ghcr.io/org/consul"))
+	assert.Equal(t, "ghcr.io/org/consul:v1", ociReference("ghcr.io/org/consul:v1"))
+	assert.Equal(t, "localhost:5000/consul:latest", ociReference("localhost:5000/consul"))
+	assert.Equal(t, "ghcr.io/org/consul@sha256:abc", ociReference("ghcr.io/org/consul@sha256:abc"))
+}
+
+func TestOCIGetterPullsBlueprint(t *testing.T) {
+	r := &mocks.BlueprintRegistry{}
+	r.On("Pull", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	g := &ociGetter{registry: r}
+	u, _ := url.Parse("oci://ghcr.io/org/consul:v1")
+
+	err := g.Get("/tmp/consul", u)
+	require.NoError(t, err)
+
+	r.AssertCalled(t, "Pull", mock.Anything, "ghcr.io/org/consul:v1", "/tmp/consul")
+}
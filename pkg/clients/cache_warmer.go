@@ -0,0 +1,333 @@
+package clients
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+const (
+	mediaTypeManifestList  = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeManifest      = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeOCIIndex      = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest   = "application/vnd.oci.image.manifest.v1+json"
+	defaultRegistryAddress = "registry-1.docker.io"
+)
+
+// CacheWarmer pulls images through the image cache so that the manifests and
+// layers are cached before they are requested by a cluster
+type CacheWarmer struct {
+	client *http.Client
+	log    hclog.Logger
+}
+
+type registryManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Layers []struct {
+		Digest string `json:"digest"`
+	} `json:"layers"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+			Variant      string `json:"variant"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// NewCacheWarmer creates a CacheWarmer which uses the given http.Client to
+// pull images, the client should be configured to use the image cache as a proxy
+func NewCacheWarmer(c *http.Client, l hclog.Logger) *CacheWarmer {
+	return &CacheWarmer{c, l}
+}
+
+// NewCacheProxyClient returns a http.Client which sends requests through the image
+// cache at the given address. The image cache generates certificates for the
+// registries it caches using the Shipyard root CA, rootCert is the location of this CA.
+func NewCacheProxyClient(proxy, rootCert string) (*http.Client, error) {
+	pu, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid proxy address %s: %s", proxy, err)
+	}
+
+	ca, err := ioutil.ReadFile(rootCert)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read root CA for image cache: %s", err)
+	}
+
+	// registries which are not cached are tunneled by the proxy so the
+	// system roots are still required
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("Unable to parse root CA for image cache")
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyURL(pu),
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}, nil
+}
+
+// Warm pulls the manifest, config, and layers for the given image and returns
+// the number of bytes downloaded. For multi-arch images only the layers for the
+// image platform are pulled, when the platform is not set the platform of the
+// current machine is used.
+func (c *CacheWarmer) Warm(image config.Image) (int64, error) {
+	named, err := reference.ParseNormalizedNamed(image.Name)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid image name %s: %s", image.Name, err)
+	}
+
+	pos, arch, variant := "linux", runtime.GOARCH, ""
+	if image.Platform != "" {
+		pos, arch, variant, err = image.PlatformParts()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	named = reference.TagNameOnly(named)
+
+	registry := reference.Domain(named)
+	if registry == "docker.io" {
+		registry = defaultRegistryAddress
+	}
+
+	ref := ""
+	switch r := named.(type) {
+	case reference.Digested:
+		ref = r.Digest().String()
+	case reference.Tagged:
+		ref = r.Tag()
+	}
+
+	rs := &registrySession{client: c.client, base: fmt.Sprintf("https://%s/v2/%s", registry, reference.Path(named))}
+
+	m, size, err := rs.manifest(ref)
+	if err != nil {
+		return 0, err
+	}
+
+	// multi-arch images return a list of manifests, pull the manifest
+	// for the platform
+	if len(m.Manifests) > 0 {
+		digest := ""
+		for _, pm := range m.Manifests {
+			if pm.Platform.OS == pos && pm.Platform.Architecture == arch && (variant == "" || pm.Platform.Variant == variant) {
+				digest = pm.Digest
+				break
+			}
+		}
+
+		// only fall back to the first manifest when no platform has been requested
+		if digest == "" && image.Platform != "" {
+			return size, fmt.Errorf("Image %s does not contain a manifest for platform %s", image.Name, image.Platform)
+		}
+
+		if digest == "" {
+			digest = m.Manifests[0].Digest
+		}
+
+		c.log.Debug("Image is a manifest list, pulling platform manifest", "image", image.Name, "platform", fmt.Sprintf("%s/%s", pos, arch), "digest", digest)
+
+		var s int64
+		m, s, err = rs.manifest(digest)
+		if err != nil {
+			return 0, err
+		}
+
+		size += s
+	}
+
+	blobs := []string{}
+	if m.Config.Digest != "" {
+		blobs = append(blobs, m.Config.Digest)
+	}
+
+	for _, l := range m.Layers {
+		blobs = append(blobs, l.Digest)
+	}
+
+	for _, b := range blobs {
+		c.log.Debug("Pulling blob", "image", image.Name, "digest", b)
+
+		s, err := rs.blob(b)
+		if err != nil {
+			return size, err
+		}
+
+		size += s
+	}
+
+	return size, nil
+}
+
+// registrySession makes requests to a single repository, handling the
+// token authentication used by most public registries
+type registrySession struct {
+	client *http.Client
+	base   string
+	token  string
+}
+
+func (r *registrySession) manifest(ref string) (*registryManifest, int64, error) {
+	resp, err := r.get(
+		fmt.Sprintf("%s/manifests/%s", r.base, ref),
+		mediaTypeManifestList, mediaTypeOCIIndex, mediaTypeManifest, mediaTypeOCIManifest,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	d, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Unable to read manifest: %s", err)
+	}
+
+	m := &registryManifest{}
+	err = json.Unmarshal(d, m)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Unable to parse manifest: %s", err)
+	}
+
+	return m, int64(len(d)), nil
+}
+
+func (r *registrySession) blob(digest string) (int64, error) {
+	resp, err := r.get(fmt.Sprintf("%s/blobs/%s", r.base, digest))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// the data is not needed, reading the body is enough to populate the cache
+	return io.Copy(ioutil.Discard, resp.Body)
+}
+
+func (r *registrySession) get(uri string, accept ...string) (*http.Response, error) {
+	resp, err := r.do(uri, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && r.token == "" {
+		resp.Body.Close()
+
+		err = r.authenticate(resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err = r.do(uri, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Unable to fetch %s, registry returned status %d", uri, resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+func (r *registrySession) do(uri string, accept []string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to fetch %s: %s", uri, err)
+	}
+
+	return resp, nil
+}
+
+// authenticate fetches an anonymous token using the challenge returned by the registry
+// e.g. Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"
+func (r *registrySession) authenticate(challenge string) error {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return fmt.Errorf("Registry requires unsupported authentication: %s", challenge)
+	}
+
+	params := map[string]string{}
+	for _, p := range strings.Split(challenge[7:], ",") {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) == 2 {
+			params[kv[0]] = strings.Trim(kv[1], `"`)
+		}
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("Registry returned an invalid authentication realm: %s", challenge)
+	}
+
+	q := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if v, ok := params[k]; ok {
+			q.Set(k, v)
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	resp, err := r.client.Get(realm.String())
+	if err != nil {
+		return fmt.Errorf("Unable to fetch registry token: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unable to fetch registry token, status %d", resp.StatusCode)
+	}
+
+	t := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&t)
+	if err != nil {
+		return fmt.Errorf("Unable to parse registry token: %s", err)
+	}
+
+	r.token = t.Token
+	if r.token == "" {
+		r.token = t.AccessToken
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package clients
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupCacheWarmer(t *testing.T, auth bool) (*CacheWarmer, *httptest.Server, *[]string) {
+	requests := []string{}
+
+	var ts *httptest.Server
+	ts = httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+
+		if r.URL.Path == "/token" {
+			assert.Equal(t, "repository:test/app:pull", r.URL.Query().Get("scope"))
+			fmt.Fprint(rw, `{"token": "abc"}`)
+			return
+		}
+
+		if auth && r.Header.Get("Authorization") != "Bearer abc" {
+			rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:test/app:pull"`, ts.URL))
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/test/app/manifests/v1":
+			fmt.Fprintf(rw, cacheWarmerManifestList, runtime.GOARCH)
+		case "/v2/test/app/manifests/sha256:platform", "/v2/test/app/manifests/sha256:other":
+			fmt.Fprint(rw, cacheWarmerManifest)
+		case "/v2/test/app/blobs/sha256:config", "/v2/test/app/blobs/sha256:layer1":
+			fmt.Fprint(rw, "1234567890")
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return NewCacheWarmer(ts.Client(), hclog.NewNullLogger()), ts, &requests
+}
+
+func TestCacheWarmerPullsManifestAndBlobs(t *testing.T) {
+	cw, ts, requests := setupCacheWarmer(t, false)
+
+	size, err := cw.Warm(config.Image{Name: fmt.Sprintf("%s/test/app:v1", strings.TrimPrefix(ts.URL, "https://"))})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"/v2/test/app/manifests/v1",
+		"/v2/test/app/manifests/sha256:platform",
+		"/v2/test/app/blobs/sha256:config",
+		"/v2/test/app/blobs/sha256:layer1",
+	}, *requests)
+
+	assert.Greater(t, size, int64(20))
+}
+
+func TestCacheWarmerPullsManifestForPlatform(t *testing.T) {
+	cw, ts, requests := setupCacheWarmer(t, false)
+
+	_, err := cw.Warm(config.Image{Name: fmt.Sprintf("%s/test/app:v1", strings.TrimPrefix(ts.URL, "https://")), Platform: "linux/s390x"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "/v2/test/app/manifests/sha256:other", (*requests)[1])
+}
+
+func TestCacheWarmerReturnsErrorWhenPlatformNotFound(t *testing.T) {
+	cw, ts, _ := setupCacheWarmer(t, false)
+
+	_, err := cw.Warm(config.Image{Name: fmt.Sprintf("%s/test/app:v1", strings.TrimPrefix(ts.URL, "https://")), Platform: "windows/amd64"})
+	assert.Error(t, err)
+}
+
+func TestCacheWarmerAuthenticatesWithToken(t *testing.T) {
+	cw, ts, requests := setupCacheWarmer(t, true)
+
+	_, err := cw.Warm(config.Image{Name: fmt.Sprintf("%s/test/app:v1", strings.TrimPrefix(ts.URL, "https://"))})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "/v2/test/app/manifests/v1", (*requests)[0])
+	assert.Equal(t, "/token", (*requests)[1])
+	assert.Equal(t, "/v2/test/app/manifests/v1", (*requests)[2])
+}
+
+func TestCacheWarmerReturnsErrorWhenImageNotFound(t *testing.T) {
+	cw, ts, _ := setupCacheWarmer(t, false)
+
+	_, err := cw.Warm(config.Image{Name: fmt.Sprintf("%s/test/missing:v1", strings.TrimPrefix(ts.URL, "https://"))})
+	assert.Error(t, err)
+}
+
+func TestCacheWarmerReturnsErrorForInvalidImage(t *testing.T) {
+	cw, _, _ := setupCacheWarmer(t, false)
+
+	_, err := cw.Warm(config.Image{Name: "UPPER/case"})
+	assert.Error(t, err)
+}
+
+var cacheWarmerManifestList = `
+{
+  "mediaType": "application/vnd.docker.distribution.manifest.list.v2+json",
+  "manifests": [
+    {
+      "digest": "sha256:other",
+      "platform": {"architecture": "s390x", "os": "linux"}
+    },
+    {
+      "digest": "sha256:platform",
+      "platform": {"architecture": "%s", "os": "linux"}
+    }
+  ]
+}
+`
+
+var cacheWarmerManifest = `
+{
+  "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+  "config": {"digest": "sha256:config"},
+  "layers": [
+    {"digest": "sha256:layer1"}
+  ]
+}
+`
@@ -0,0 +1,100 @@
+package clients
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// EngineCapabilities describes the features supported by the container engine,
+// rootless engines and engines which remap the root user with user namespaces
+// are unable to perform some operations which require root
+type EngineCapabilities struct {
+	Rootless       bool // engine is running as a non root user
+	UserNamespaces bool // engine remaps users in containers with user namespaces
+	CgroupV2       bool // engine is using cgroup v2
+
+	Runtimes []string // container runtimes registered with the engine e.g. runc, nvidia
+
+	OS     string // operating system of the containers run by the engine e.g. linux, windows
+	Arch   string // architecture of the engine using the Go names e.g. amd64, arm64
+	Memory int64  // total memory available to the engine in bytes
+}
+
+// WindowsContainers returns true when the engine runs Windows containers
+func (e *EngineCapabilities) WindowsContainers() bool {
+	return e.OS == config.PlatformWindows
+}
+
+// HasRuntime returns true when the given container runtime is registered with the engine
+func (e *EngineCapabilities) HasRuntime(name string) bool {
+	for _, r := range e.Runtimes {
+		if r == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PrivilegedPorts returns true when containers can bind host ports below 1024
+func (e *EngineCapabilities) PrivilegedPorts() bool {
+	return !e.Rootless
+}
+
+// PrivilegedContainers returns true when the engine can run privileged containers
+// such as k3s and Nomad clusters, rootless engines require cgroup v2
+func (e *EngineCapabilities) PrivilegedContainers() bool {
+	return !e.Rootless || e.CgroupV2
+}
+
+// Host returns the capabilities used to select the fallback variants of resources,
+// GPUs can be passed to containers when the nvidia runtime is registered
+func (e *EngineCapabilities) Host() *config.HostCapabilities {
+	return &config.HostCapabilities{GPU: e.HasRuntime("nvidia"), Arch: e.Arch, Memory: e.Memory}
+}
+
+// ProbeCapabilities queries the engine to determine its capabilities
+func ProbeCapabilities(c Docker) (*EngineCapabilities, error) {
+	info, err := c.Info(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	ec := &EngineCapabilities{CgroupV2: info.CgroupVersion == "2", OS: info.OSType, Arch: engineArch(info.Architecture), Memory: info.MemTotal}
+
+	// security options are formatted as name=[option],[key]=[value]
+	for _, so := range info.SecurityOptions {
+		switch {
+		case strings.Contains(so, "name=rootless"):
+			ec.Rootless = true
+		case strings.Contains(so, "name=userns"):
+			ec.UserNamespaces = true
+		}
+	}
+
+	for r := range info.Runtimes {
+		ec.Runtimes = append(ec.Runtimes, r)
+	}
+
+	sort.Strings(ec.Runtimes)
+
+	return ec, nil
+}
+
+// engineArch converts the architecture reported by the engine, which uses the
+// kernel names, to the names used by Go and image platforms
+func engineArch(a string) string {
+	switch a {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	case "armv7l":
+		return "arm"
+	}
+
+	return a
+}
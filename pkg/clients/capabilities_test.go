@@ -0,0 +1,87 @@
+package clients
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestProbeCapabilitiesDetectsRootless(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("Info", mock.Anything).Return(types.Info{SecurityOptions: []string{"name=seccomp,profile=default", "name=rootless"}, CgroupVersion: "2"}, nil)
+
+	ec, err := ProbeCapabilities(md)
+	assert.NoError(t, err)
+
+	assert.True(t, ec.Rootless)
+	assert.True(t, ec.CgroupV2)
+	assert.False(t, ec.UserNamespaces)
+	assert.False(t, ec.PrivilegedPorts())
+	assert.True(t, ec.PrivilegedContainers())
+}
+
+func TestProbeCapabilitiesDetectsUserNamespaces(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("Info", mock.Anything).Return(types.Info{SecurityOptions: []string{"name=userns"}, CgroupVersion: "1"}, nil)
+
+	ec, err := ProbeCapabilities(md)
+	assert.NoError(t, err)
+
+	assert.False(t, ec.Rootless)
+	assert.True(t, ec.UserNamespaces)
+	assert.True(t, ec.PrivilegedPorts())
+}
+
+func TestProbeCapabilitiesDetectsRuntimes(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("Info", mock.Anything).Return(types.Info{Runtimes: map[string]types.Runtime{"runc": {}, "nvidia": {}}}, nil)
+
+	ec, err := ProbeCapabilities(md)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"nvidia", "runc"}, ec.Runtimes)
+	assert.True(t, ec.HasRuntime("nvidia"))
+	assert.False(t, ec.HasRuntime("kata"))
+}
+
+func TestProbeCapabilitiesReturnsErrorWhenInfoFails(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("Info", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	_, err := ProbeCapabilities(md)
+	assert.Error(t, err)
+}
+
+func TestRootlessEngineCannotRunPrivilegedWithCgroupV1(t *testing.T) {
+	ec := &EngineCapabilities{Rootless: true}
+
+	assert.False(t, ec.PrivilegedContainers())
+}
+
+func TestProbeCapabilitiesReturnsHostCapabilities(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("Info", mock.Anything).Return(types.Info{Architecture: "aarch64", MemTotal: 8589934592, Runtimes: map[string]types.Runtime{"runc": {}, "nvidia": {}}}, nil)
+
+	ec, err := ProbeCapabilities(md)
+	assert.NoError(t, err)
+
+	h := ec.Host()
+	assert.True(t, h.GPU)
+	assert.Equal(t, "arm64", h.Arch)
+	assert.Equal(t, int64(8589934592), h.Memory)
+}
+
+func TestProbeCapabilitiesDetectsWindowsContainers(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("Info", mock.Anything).Return(types.Info{OSType: "windows"}, nil)
+
+	ec, err := ProbeCapabilities(md)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "windows", ec.OS)
+	assert.True(t, ec.WindowsContainers())
+}
@@ -2,17 +2,35 @@ package clients
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/creack/pty"
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/gohup"
 )
 
 var ErrorCommandTimeout = fmt.Errorf("Command timed out before completing")
 
+// ErrorCommandExited is returned when a command exits with a non zero
+// exit code and CheckExitCode is set
+type ErrorCommandExited struct {
+	ExitCode int
+}
+
+func (e ErrorCommandExited) Error() string {
+	return fmt.Sprintf("Command exited with code %d", e.ExitCode)
+}
+
+// ptyDrainTimeout is the maximum time to wait for the output from a
+// pseudo terminal to be written once the command has exited
+var ptyDrainTimeout = 5 * time.Second
+
 type CommandConfig struct {
 	Command          string
 	Args             []string
@@ -21,6 +39,9 @@ type CommandConfig struct {
 	RunInBackground  bool
 	LogFilePath      string
 	Timeout          time.Duration
+	PTY              bool      // allocate a pseudo terminal for commands which require a terminal, ignored when running in the background
+	CheckExitCode    bool      // return an error when the command exits with a non zero exit code, ignored when running in the background
+	Output           io.Writer // receives the stdout of the command in addition to the log file, ignored when running in the background
 }
 
 type Command interface {
@@ -44,8 +65,18 @@ type done struct {
 	err error
 }
 
-// Execute the given command
+// Execute the given command, commands which are not run in the background are
+// killed along with any child processes when the timeout is reached
 func (c *CommandImpl) Execute(config CommandConfig) (int, error) {
+	timeout := c.timeout
+	if config.Timeout != (0 * time.Millisecond) {
+		timeout = config.Timeout
+	}
+
+	if !config.RunInBackground {
+		return c.executeForeground(config, timeout)
+	}
+
 	mutex := sync.Mutex{}
 
 	lp := &gohup.LocalProcess{}
@@ -65,11 +96,6 @@ func (c *CommandImpl) Execute(config CommandConfig) (int, error) {
 	// done chan
 	doneCh := make(chan done)
 
-	timeout := c.timeout
-	if config.Timeout != (0 * time.Millisecond) {
-		timeout = config.Timeout
-	}
-
 	// wait for timeout
 	t := time.After(timeout)
 	var pidfile string
@@ -90,27 +116,8 @@ func (c *CommandImpl) Execute(config CommandConfig) (int, error) {
 
 		mutex.Lock()
 		pid, pidfile, err = lp.Start(o)
-		if err != nil {
-			doneCh <- done{err: err}
-		}
 		mutex.Unlock()
 
-		// if not background wait for complete
-		if !config.RunInBackground {
-			for {
-				s, err := lp.QueryStatus(pidfile)
-				if err != nil {
-					doneCh <- done{err: err, pid: pid}
-				}
-
-				if s == gohup.StatusStopped {
-					break
-				}
-
-				time.Sleep(200 * time.Millisecond)
-			}
-		}
-
 		doneCh <- done{err: err, pid: pid}
 	}()
 
@@ -126,6 +133,122 @@ func (c *CommandImpl) Execute(config CommandConfig) (int, error) {
 	}
 }
 
+// executeForeground runs the command and waits for it to complete, when the timeout
+// is reached the command and any child processes it started are killed
+func (c *CommandImpl) executeForeground(config CommandConfig, timeout time.Duration) (int, error) {
+	c.log.Debug(
+		"Running command",
+		"cmd", config.Command,
+		"args", config.Args,
+		"dir", config.WorkingDirectory,
+		"env", config.Env,
+		"timeout", timeout,
+		"pty", config.PTY,
+		"log_file", config.LogFilePath,
+	)
+
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = config.Env
+	cmd.Dir = config.WorkingDirectory
+
+	// output is discarded when there is no log file
+	var out io.Writer = ioutil.Discard
+	if config.LogFilePath != "" {
+		f, err := os.Create(config.LogFilePath)
+		if err != nil {
+			return -1, fmt.Errorf("Unable to open log file: %s", err)
+		}
+		defer f.Close()
+
+		out = f
+	}
+
+	// closed once all the output from the terminal has been written
+	copyDone := make(chan struct{})
+
+	if config.PTY {
+		// the pty starts the command in a new session so the
+		// command and its children can be killed as a group
+		tty, err := pty.Start(cmd)
+		if err != nil {
+			return -1, err
+		}
+
+		defer func() {
+			// wait for the remaining output to be copied before closing the terminal,
+			// background processes started by the command may keep the terminal open
+			select {
+			case <-copyDone:
+			case <-time.After(ptyDrainTimeout):
+			}
+
+			tty.Close()
+		}()
+
+		// the output must be read otherwise the command will block
+		// when the buffer for the terminal is full
+		// the terminal combines stdout and stderr so both are written to the output
+		var ttyOut io.Writer = out
+		if config.Output != nil {
+			ttyOut = io.MultiWriter(out, config.Output)
+		}
+
+		go func() {
+			io.Copy(ttyOut, tty)
+			close(copyDone)
+		}()
+	} else {
+		if f, ok := out.(*os.File); ok {
+			cmd.Stdout = f
+			cmd.Stderr = f
+		}
+
+		if config.Output != nil {
+			cmd.Stdout = io.MultiWriter(out, config.Output)
+		}
+
+		setProcessGroup(cmd)
+
+		err := cmd.Start()
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	pid := cmd.Process.Pid
+
+	doneCh := make(chan error, 1)
+	go func() {
+		doneCh <- cmd.Wait()
+	}()
+
+	select {
+	case <-time.After(timeout):
+		err := killProcessGroup(cmd.Process)
+		if err != nil {
+			c.log.Error("Unable to kill command", "pid", pid, "error", err)
+		}
+
+		<-doneCh
+
+		return pid, ErrorCommandTimeout
+	case err := <-doneCh:
+		// unless requested the exit code of the command is not checked,
+		// only errors running the command are returned
+		if ee, ok := err.(*exec.ExitError); ok {
+			c.log.Debug("Command exited with error", "pid", pid, "error", err)
+
+			if config.CheckExitCode {
+				return pid, ErrorCommandExited{ExitCode: ee.ExitCode()}
+			}
+
+			return pid, nil
+		}
+
+		return pid, err
+	}
+}
+
 // Kill a process with the given pid
 func (c *CommandImpl) Kill(pid int) error {
 	lp := gohup.LocalProcess{}
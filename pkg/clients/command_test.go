@@ -1,7 +1,15 @@
 package clients
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -54,6 +62,105 @@ func TestExecuteForgroundLongRunningTimesOut(t *testing.T) {
 	assert.Greater(t, p, 1)
 }
 
+func TestExecuteForgroundTimeoutKillsChildProcesses(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are not used on Windows")
+	}
+
+	// the child writes its pid so that we can check it has been killed
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+
+	e := NewCommand(1*time.Second, hclog.NewNullLogger())
+
+	_, err := e.Execute(CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", fmt.Sprintf("sleep 30 & echo $! > %s; wait", pidFile)},
+	})
+	assert.Equal(t, ErrorCommandTimeout, err)
+
+	d, err := ioutil.ReadFile(pidFile)
+	assert.NoError(t, err)
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(d)))
+	assert.NoError(t, err)
+
+	// sending signal 0 checks if the process exists
+	p, _ := os.FindProcess(pid)
+	assert.Eventually(t, func() bool {
+		return p.Signal(syscall.Signal(0)) != nil
+	}, 2*time.Second, 50*time.Millisecond)
+}
+
+func TestExecuteForgroundWithPTYRunsInTerminal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses sh to check for a terminal")
+	}
+
+	logFile := filepath.Join(t.TempDir(), "exec.log")
+
+	e := setupExecute(t)
+
+	_, err := e.Execute(CommandConfig{
+		Command:     "sh",
+		Args:        []string{"-c", "if [ -t 1 ]; then echo tty; else echo notty; fi"},
+		LogFilePath: logFile,
+		PTY:         true,
+	})
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(logFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "tty", strings.TrimSpace(string(d)))
+}
+
+func TestExecuteForgroundWritesStdoutToOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses sh to write to stdout and stderr")
+	}
+
+	logFile := filepath.Join(t.TempDir(), "exec.log")
+	out := bytes.NewBufferString("")
+
+	e := setupExecute(t)
+
+	_, err := e.Execute(CommandConfig{
+		Command:     "sh",
+		Args:        []string{"-c", "echo out; echo err >&2"},
+		LogFilePath: logFile,
+		Output:      out,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "out\n", out.String())
+
+	d, err := ioutil.ReadFile(logFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), "out")
+	assert.Contains(t, string(d), "err")
+}
+
+func TestExecuteForgroundIgnoresExitCodeByDefault(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses sh to exit with a code")
+	}
+
+	e := setupExecute(t)
+
+	_, err := e.Execute(CommandConfig{Command: "sh", Args: []string{"-c", "exit 3"}})
+	assert.NoError(t, err)
+}
+
+func TestExecuteForgroundWithCheckExitCodeReturnsError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses sh to exit with a code")
+	}
+
+	e := setupExecute(t)
+
+	_, err := e.Execute(CommandConfig{Command: "sh", Args: []string{"-c", "exit 3"}, CheckExitCode: true})
+	assert.Equal(t, ErrorCommandExited{ExitCode: 3}, err)
+}
+
 func TestExecuteInvalidCommandReturnsError(t *testing.T) {
 	e := setupExecute(t)
 
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package clients
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group so that
+// the command and any child processes can be killed together
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the process group which the process leads
+func killProcessGroup(p *os.Process) error {
+	err := syscall.Kill(-p.Pid, syscall.SIGKILL)
+	if err != nil {
+		return p.Kill()
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package clients
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// setProcessGroup is not required on Windows, the process tree is
+// killed using taskkill
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the process and any child processes
+func killProcessGroup(p *os.Process) error {
+	err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run()
+	if err != nil {
+		return p.Kill()
+	}
+
+	return nil
+}
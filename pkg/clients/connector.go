@@ -1,19 +1,26 @@
 package clients
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/shipyard-run/connector/crypto"
 	"github.com/shipyard-run/connector/protos/shipyard"
 	"github.com/shipyard-run/gohup"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -40,6 +47,33 @@ type Connector interface {
 	// CertBundle will be returned
 	GetLocalCertBundle(dir string) (*CertBundle, error)
 
+	// RotateLocalCerts generates a new leaf certificate for the local connector signed
+	// by the existing root CA in dir, when rotateCA is true a new root CA is also generated
+	RotateLocalCerts(dir string, rotateCA bool) (*CertBundle, error)
+
+	// ImportCA adds the PEM encoded CA certificate in file to the CAs trusted by the local
+	// connector so that connectors on other machines can authenticate
+	ImportCA(dir, name, file string) error
+
+	// CreateJoinToken creates a single use token which allows the connector on another
+	// machine to join the local connector, address is the host name or IP address
+	// of the local machine which is reachable from the other machine
+	CreateJoinToken(dir, address string) (*JoinToken, error)
+	// AcceptJoin validates the token in the join request and trusts the CA of the
+	// joining connector
+	AcceptJoin(dir string, req *JoinRequest) (*JoinResponse, error)
+	// Join exchanges CAs with the connector on another machine using a join token
+	// created on that machine, address is the address of the API of the other connector
+	Join(dir, address, token string) (*RemoteConnector, error)
+
+	// TrustCA installs the root CA in dir into the trust stores of the operating system
+	// and browsers so that https ingresses can be opened without certificate warnings
+	TrustCA(dir string) error
+	// UntrustCA removes the root CA in dir from the trust stores
+	UntrustCA(dir string) error
+	// CATrusted returns true when the root CA in dir is trusted by the operating system
+	CATrusted(dir string) bool
+
 	// Generates a Leaf certificate for securing a connector
 	GenerateLeafCert(
 		privateKey, rootCA string,
@@ -61,6 +95,80 @@ type Connector interface {
 
 	// ListServices returns a slice of active services
 	ListServices() ([]*shipyard.Service, error)
+
+	// ExposeAuthProxy starts a proxy in the connector which authenticates
+	// requests and rewrites headers before forwarding them to the upstream address.
+	// Returns the id of the proxy
+	ExposeAuthProxy(name, bindAddr, upstream string, auth *config.Auth, headers *config.Headers) (string, error)
+
+	// RemoveAuthProxy removes a previously created auth proxy
+	RemoveAuthProxy(id string) error
+
+	// ExposeRouter starts a reverse proxy in the connector which forwards
+	// requests to the destination of the matching route.
+	// Returns the id of the router
+	ExposeRouter(name, bindAddr string, routes []config.Route) (string, error)
+
+	// RemoveRouter removes a previously created router
+	RemoveRouter(id string) error
+
+	// ExposeStreamProxy starts a proxy in the connector which forwards raw tcp or udp
+	// traffic to the upstream address, when certFile and keyFile are set TLS is terminated
+	// by the proxy. Returns the id of the proxy
+	ExposeStreamProxy(name, protocol, bindAddr, upstream, certFile, keyFile string) (string, error)
+
+	// RemoveStreamProxy removes a previously created stream proxy
+	RemoveStreamProxy(id string) error
+
+	// ExposeSocksProxy starts a SOCKS5 proxy in the connector, when connectorAddr is set
+	// TCP connections are tunneled through the remote connector so that any address in
+	// the cluster can be reached. Returns the id of the proxy
+	ExposeSocksProxy(name, bindAddr, connectorAddr string) (string, error)
+
+	// RemoveSocksProxy removes a previously created SOCKS5 proxy
+	RemoveSocksProxy(id string) error
+
+	// Status returns the health, metrics, and tunnels of the running connector
+	Status() (*ConnectorStatus, error)
+
+	// InstallService registers the Connector with the operating systems
+	// service manager so that it is started at login and restarted on failure
+	InstallService(*CertBundle) error
+	// UninstallService stops the Connector service and removes it from the service manager
+	UninstallService() error
+	// ServiceInstalled returns true when the Connector is managed by the service manager
+	ServiceInstalled() bool
+}
+
+// ConnectorTunnel is a tunnel between the local connector and a remote connector
+type ConnectorTunnel struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Status          string `json:"status"`
+	SourcePort      int    `json:"source_port"`
+	RemoteAddr      string `json:"remote_addr"`
+	DestinationAddr string `json:"destination_addr"`
+}
+
+// ConnectorStatus is the status reported by the connector
+type ConnectorStatus struct {
+	Healthy           bool              `json:"healthy"`
+	Error             string            `json:"error,omitempty"`
+	Uptime            time.Duration     `json:"uptime"`
+	Connections       int64             `json:"connections"`
+	BytesReceived     uint64            `json:"bytes_received"`
+	BytesSent         uint64            `json:"bytes_sent"`
+	Reconnects        uint64            `json:"reconnects"`
+	ConnectFailures   uint64            `json:"connect_failures"`
+	ActiveTunnels     int               `json:"active_tunnels"`
+	ErroredTunnels    int               `json:"errored_tunnels"`
+	Tunnels           []ConnectorTunnel `json:"tunnels"`
+	AuthProxies       int               `json:"auth_proxies"`
+	Routers           int               `json:"routers"`
+	StreamProxies     int               `json:"stream_proxies"`
+	SocksProxies      int               `json:"socks_proxies"`
+	CertificateExpiry *time.Time        `json:"certificate_expiry,omitempty"`
 }
 
 var defaultArgs = []string{
@@ -79,8 +187,11 @@ type ConnectorOptions struct {
 	GrpcBind     string
 	HTTPBind     string
 	APIBind      string
-	LogLevel     string
-	PidFile      string
+	// EnvironmentBind is the address of the mTLS endpoint which reports the
+	// environment to 'shipyard fleet list' on joined machines
+	EnvironmentBind string
+	LogLevel        string
+	PidFile         string
 }
 
 type CertBundle struct {
@@ -90,17 +201,25 @@ type CertBundle struct {
 	LeafKeyPath  string
 }
 
-func DefaultConnectorOptions() ConnectorOptions {
+// DefaultConnectorOptions returns the options for a connector started by
+// the running Shipyard binary
+func DefaultConnectorOptions() (ConnectorOptions, error) {
+	bp, err := utils.GetShipyardBinaryPath()
+	if err != nil {
+		return ConnectorOptions{}, err
+	}
+
 	co := ConnectorOptions{}
 	co.LogDirectory = utils.LogsDir()
-	co.BinaryPath = utils.GetShipyardBinaryPath()
+	co.BinaryPath = bp
 	co.GrpcBind = ":30001"
 	co.HTTPBind = ":30002"
 	co.APIBind = ":30003"
+	co.EnvironmentBind = ":30004"
 	co.LogLevel = "info"
 	co.PidFile = utils.GetConnectorPIDFile()
 
-	return co
+	return co, nil
 }
 
 // NewConnector creates a new connector with the given options
@@ -110,26 +229,10 @@ func NewConnector(opts ConnectorOptions) Connector {
 
 // Start the Connector, returns an error on failure
 func (c *ConnectorImpl) Start(cb *CertBundle) error {
-	// get the log level from the environment variable
-	ll := os.Getenv("LOG_LEVEL")
-	if ll == "" {
-		ll = "info"
-	}
-
 	lp := &gohup.LocalProcess{}
 	o := gohup.Options{
-		Path: c.options.BinaryPath,
-		Args: []string{
-			"connector",
-			"run",
-			"--grpc-bind", c.options.GrpcBind,
-			"--http-bind", c.options.HTTPBind,
-			"--api-bind", c.options.APIBind,
-			"--root-cert-path", cb.RootCertPath,
-			"--server-cert-path", cb.LeafCertPath,
-			"--server-key-path", cb.LeafKeyPath,
-			"--log-level", ll,
-		},
+		Path:    c.options.BinaryPath,
+		Args:    c.runArgs(cb),
 		Logfile: filepath.Join(c.options.LogDirectory, "connector.log"),
 		Pidfile: c.options.PidFile,
 	}
@@ -147,6 +250,11 @@ func (c *ConnectorImpl) Stop() error {
 
 // IsRunning returns true when the Connector is running
 func (c *ConnectorImpl) IsRunning() bool {
+	// the service manager restarts the connector when it is not running
+	if c.ServiceInstalled() {
+		return true
+	}
+
 	lp := &gohup.LocalProcess{}
 	status, err := lp.QueryStatus(c.options.PidFile)
 	if err != nil {
@@ -160,6 +268,34 @@ func (c *ConnectorImpl) IsRunning() bool {
 	return false
 }
 
+// runArgs returns the arguments used to run the connector in the foreground
+func (c *ConnectorImpl) runArgs(cb *CertBundle) []string {
+	// get the log level from the environment variable
+	ll := os.Getenv("LOG_LEVEL")
+	if ll == "" {
+		ll = "info"
+	}
+
+	args := []string{
+		"connector",
+		"run",
+		"--grpc-bind", c.options.GrpcBind,
+		"--http-bind", c.options.HTTPBind,
+		"--api-bind", c.options.APIBind,
+		"--root-cert-path", cb.RootCertPath,
+		"--trusted-certs-path", TrustedCertsDir(filepath.Dir(cb.RootCertPath)),
+		"--server-cert-path", cb.LeafCertPath,
+		"--server-key-path", cb.LeafKeyPath,
+		"--log-level", ll,
+	}
+
+	if c.options.EnvironmentBind != "" {
+		args = append(args, "--environment-bind", c.options.EnvironmentBind)
+	}
+
+	return args
+}
+
 // creates a CA and local leaf cert
 func (c *ConnectorImpl) GenerateLocalCertBundle(out string) (*CertBundle, error) {
 	cb := &CertBundle{
@@ -192,17 +328,97 @@ func (c *ConnectorImpl) GenerateLocalCertBundle(out string) (*CertBundle, error)
 		return nil, err
 	}
 
+	return c.GenerateLeafCert(cb.RootKeyPath, cb.RootCertPath, c.localHosts(out), c.localIPs(out), out)
+}
+
+// localHosts returns the hosts added to the leaf certificate of the local connector,
+// including the addresses advertised to connectors on other machines
+func (c *ConnectorImpl) localHosts(dir string) []string {
 	grcpParts := strings.Split(c.options.GrpcBind, ":")
 	httpParts := strings.Split(c.options.GrpcBind, ":")
 
-	ips := utils.GetLocalIPAddresses()
-	host := []string{
+	hosts := []string{
 		utils.GetHostname(),
 		fmt.Sprintf("localhost:%s", grcpParts[1]),
 		fmt.Sprintf("localhost:%s", httpParts[1]),
 	}
 
-	return c.GenerateLeafCert(cb.RootKeyPath, cb.RootCertPath, host, ips, out)
+	for _, a := range advertisedAddresses(dir) {
+		hosts = append(hosts, a, fmt.Sprintf("%s:%s", a, grcpParts[1]))
+	}
+
+	return hosts
+}
+
+// localIPs returns the IP addresses added to the leaf certificate of the local connector
+func (c *ConnectorImpl) localIPs(dir string) []string {
+	ips := utils.GetLocalIPAddresses()
+
+	for _, a := range advertisedAddresses(dir) {
+		if net.ParseIP(a) != nil {
+			ips = append(ips, a)
+		}
+	}
+
+	return ips
+}
+
+// RotateLocalCerts generates a new leaf certificate for the local connector, connectors
+// which trust the root CA continue to trust the local connector. When rotateCA is true
+// the root CA is replaced, clusters created with the previous CA must be recreated
+func (c *ConnectorImpl) RotateLocalCerts(dir string, rotateCA bool) (*CertBundle, error) {
+	if rotateCA {
+		return c.GenerateLocalCertBundle(dir)
+	}
+
+	cb, err := c.GetLocalCertBundle(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GenerateLeafCert(cb.RootKeyPath, cb.RootCertPath, c.localHosts(dir), c.localIPs(dir), dir)
+}
+
+// ImportCA copies the CA certificate to the trusted folder in dir, the certificate
+// is trusted by the local connector for connections from other connectors
+func (c *ConnectorImpl) ImportCA(dir, name, file string) error {
+	ca := &crypto.X509{}
+	err := ca.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("unable to read CA certificate %s: %s", file, err)
+	}
+
+	if !ca.IsCA {
+		return fmt.Errorf("certificate %s is not a CA certificate", file)
+	}
+
+	td := TrustedCertsDir(dir)
+	err = os.MkdirAll(td, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	out := filepath.Join(td, fmt.Sprintf("%s.cert", name))
+	os.Remove(out)
+
+	return ca.WriteFile(out)
+}
+
+// TrustedCertsDir returns the folder containing the CAs trusted by the
+// connector in addition to the root CA
+func TrustedCertsDir(dir string) string {
+	return filepath.Join(dir, "trusted")
+}
+
+// CertExpiry returns the time the PEM encoded certificate expires
+func CertExpiry(file string) (time.Time, error) {
+	c := &crypto.X509{}
+	err := c.ReadFile(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return c.NotAfter, nil
 }
 
 func (c *ConnectorImpl) GetLocalCertBundle(dir string) (*CertBundle, error) {
@@ -386,6 +602,270 @@ func (c *ConnectorImpl) ListServices() ([]*shipyard.Service, error) {
 	return lr.Services, nil
 }
 
+// ExposeAuthProxy starts a proxy in the connector which authenticates requests
+// and rewrites headers
+func (c *ConnectorImpl) ExposeAuthProxy(name, bindAddr, upstream string, auth *config.Auth, headers *config.Headers) (string, error) {
+	req := struct {
+		Name     string          `json:"name"`
+		BindAddr string          `json:"bind_addr"`
+		Upstream string          `json:"upstream"`
+		Auth     *config.Auth    `json:"auth"`
+		Headers  *config.Headers `json:"headers"`
+	}{name, bindAddr, upstream, auth, headers}
+
+	d, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.Post(c.apiAddress()+"/auth_proxies", "application/json", bytes.NewReader(d))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("unable to create auth proxy, status %d: %s", resp.StatusCode, string(body))
+	}
+
+	pr := struct {
+		ID string `json:"id"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&pr)
+	if err != nil {
+		return "", err
+	}
+
+	return pr.ID, nil
+}
+
+// RemoveAuthProxy removes a previously created auth proxy
+func (c *ConnectorImpl) RemoveAuthProxy(id string) error {
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/auth_proxies/%s", c.apiAddress(), url.PathEscape(id)), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to remove auth proxy, status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ExposeRouter starts a reverse proxy in the connector which routes requests
+// to multiple destinations
+func (c *ConnectorImpl) ExposeRouter(name, bindAddr string, routes []config.Route) (string, error) {
+	req := struct {
+		Name     string         `json:"name"`
+		BindAddr string         `json:"bind_addr"`
+		Routes   []config.Route `json:"routes"`
+	}{name, bindAddr, routes}
+
+	d, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.Post(c.apiAddress()+"/routers", "application/json", bytes.NewReader(d))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("unable to create router, status %d: %s", resp.StatusCode, string(body))
+	}
+
+	rr := struct {
+		ID string `json:"id"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&rr)
+	if err != nil {
+		return "", err
+	}
+
+	return rr.ID, nil
+}
+
+// RemoveRouter removes a previously created router
+func (c *ConnectorImpl) RemoveRouter(id string) error {
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/routers/%s", c.apiAddress(), url.PathEscape(id)), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to remove router, status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ExposeStreamProxy starts a proxy in the connector which forwards tcp or udp traffic
+func (c *ConnectorImpl) ExposeStreamProxy(name, protocol, bindAddr, upstream, certFile, keyFile string) (string, error) {
+	req := struct {
+		Name     string `json:"name"`
+		Protocol string `json:"protocol"`
+		BindAddr string `json:"bind_addr"`
+		Upstream string `json:"upstream"`
+		CertFile string `json:"cert_file"`
+		KeyFile  string `json:"key_file"`
+	}{name, protocol, bindAddr, upstream, certFile, keyFile}
+
+	d, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.Post(c.apiAddress()+"/stream_proxies", "application/json", bytes.NewReader(d))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("unable to create stream proxy, status %d: %s", resp.StatusCode, string(body))
+	}
+
+	pr := struct {
+		ID string `json:"id"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&pr)
+	if err != nil {
+		return "", err
+	}
+
+	return pr.ID, nil
+}
+
+// RemoveStreamProxy removes a previously created stream proxy
+func (c *ConnectorImpl) RemoveStreamProxy(id string) error {
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/stream_proxies/%s", c.apiAddress(), url.PathEscape(id)), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to remove stream proxy, status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ExposeSocksProxy starts a SOCKS5 proxy in the connector
+func (c *ConnectorImpl) ExposeSocksProxy(name, bindAddr, connectorAddr string) (string, error) {
+	req := struct {
+		Name          string `json:"name"`
+		BindAddr      string `json:"bind_addr"`
+		ConnectorAddr string `json:"connector_addr"`
+	}{name, bindAddr, connectorAddr}
+
+	d, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.Post(c.apiAddress()+"/socks_proxies", "application/json", bytes.NewReader(d))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("unable to create socks proxy, status %d: %s", resp.StatusCode, string(body))
+	}
+
+	pr := struct {
+		ID string `json:"id"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&pr)
+	if err != nil {
+		return "", err
+	}
+
+	return pr.ID, nil
+}
+
+// RemoveSocksProxy removes a previously created SOCKS5 proxy
+func (c *ConnectorImpl) RemoveSocksProxy(id string) error {
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/socks_proxies/%s", c.apiAddress(), url.PathEscape(id)), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to remove socks proxy, status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Status returns the health, metrics, and tunnels of the running connector
+func (c *ConnectorImpl) Status() (*ConnectorStatus, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Get(c.apiAddress() + "/status")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to get connector status, status %d", resp.StatusCode)
+	}
+
+	st := &ConnectorStatus{}
+	err = json.NewDecoder(resp.Body).Decode(st)
+	if err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
+
+// apiAddress returns the address of the local API server
+func (c *ConnectorImpl) apiAddress() string {
+	_, port, err := net.SplitHostPort(c.options.APIBind)
+	if err != nil {
+		port = strings.TrimPrefix(c.options.APIBind, ":")
+	}
+
+	return fmt.Sprintf("http://localhost:%s", port)
+}
+
 func getClient(cert *CertBundle, uri string) (shipyard.RemoteConnectionClient, error) {
 	// if we are using TLS create a TLS client
 	certificate, err := tls.LoadX509KeyPair(cert.LeafCertPath, cert.LeafKeyPath)
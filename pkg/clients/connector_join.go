@@ -0,0 +1,422 @@
+package clients
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/connector/crypto"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// JoinTokenTTL is the time a join token can be used for
+var JoinTokenTTL = 15 * time.Minute
+
+// ErrInvalidJoinToken is returned when a join request uses a token which does
+// not exist, has expired, or has already been used
+var ErrInvalidJoinToken = fmt.Errorf("invalid or expired join token")
+
+// JoinToken allows a connector on another machine to join the local connector,
+// a token can only be used once
+type JoinToken struct {
+	Token string `json:"token"`
+	// CAFingerprint is the SHA256 fingerprint of the root CA of the local connector,
+	// the joining connector only trusts a CA which matches the fingerprint
+	CAFingerprint string `json:"ca_fingerprint"`
+	// ConnectorAddress is the address of the gRPC endpoint of the local connector
+	// reachable from the other machine
+	ConnectorAddress string `json:"connector_address"`
+	// EnvironmentAddress is the address of the mTLS endpoint which reports the
+	// environment of the local connector to 'shipyard fleet list'
+	EnvironmentAddress string    `json:"environment_address,omitempty"`
+	Expires            time.Time `json:"expires"`
+}
+
+// String returns the value of the token which is passed to 'shipyard connector join',
+// it contains the fingerprint of the CA and the secret
+func (jt *JoinToken) String() string {
+	return fmt.Sprintf("%s.%s", jt.CAFingerprint, jt.Token)
+}
+
+// JoinRequest is sent by a connector joining a connector on another machine
+type JoinRequest struct {
+	// MAC is the HMAC of the CA keyed with the join token, it proves the joining
+	// connector holds the token without sending the token
+	MAC string `json:"mac"`
+	// Name is the name of the joining machine, the CA is trusted with this name
+	Name string `json:"name"`
+	// CA is the PEM encoded root CA of the joining connector
+	CA string `json:"ca"`
+}
+
+// JoinResponse is returned to a connector which has joined
+type JoinResponse struct {
+	// Name is the name of the machine which has been joined
+	Name string `json:"name"`
+	// CA is the PEM encoded root CA of the connector which has been joined
+	CA                 string `json:"ca"`
+	ConnectorAddress   string `json:"connector_address"`
+	EnvironmentAddress string `json:"environment_address,omitempty"`
+}
+
+// RemoteConnector is a connector on another machine which has been
+// joined, ingresses with the remote driver tunnel through the connector
+type RemoteConnector struct {
+	Name             string `json:"name"`
+	APIAddress       string `json:"api_address"`
+	ConnectorAddress string `json:"connector_address"`
+	// EnvironmentAddress is empty for machines joined before the environment
+	// was reported, they must be joined again
+	EnvironmentAddress string    `json:"environment_address,omitempty"`
+	Joined             time.Time `json:"joined"`
+}
+
+// JoinTokenPath returns the location of the join token in the certificate folder dir
+func JoinTokenPath(dir string) string {
+	return filepath.Join(dir, "join.token")
+}
+
+// advertisedAddressesPath returns the location of the file containing the addresses
+// which are added to the leaf certificate so that other machines can connect
+func advertisedAddressesPath(dir string) string {
+	return filepath.Join(dir, "advertise_addresses")
+}
+
+// advertisedAddresses returns the addresses advertised by the connector with the
+// certificates in dir
+func advertisedAddresses(dir string) []string {
+	d, err := ioutil.ReadFile(advertisedAddressesPath(dir))
+	if err != nil {
+		return nil
+	}
+
+	return strings.Fields(string(d))
+}
+
+// CreateJoinToken creates a token which allows a connector on another machine to join
+// the local connector. The address is the host name or IP address the other machine
+// uses to reach this machine, it is added to the leaf certificate. A running connector
+// loads the new certificate without restarting.
+func (c *ConnectorImpl) CreateJoinToken(dir, address string) (*JoinToken, error) {
+	if address == "" || strings.Contains(address, ":") && net.ParseIP(address) == nil {
+		return nil, fmt.Errorf("address must be a host name or IP address without a port, got '%s'", address)
+	}
+
+	cb, err := c.GetLocalCertBundle(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := advertisedAddresses(dir)
+	if !contains(addrs, address) {
+		addrs = append(addrs, address)
+
+		err = ioutil.WriteFile(advertisedAddressesPath(dir), []byte(strings.Join(addrs, "\n")), 0644)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write advertised addresses: %s", err)
+		}
+
+		_, err = c.GenerateLeafCert(cb.RootKeyPath, cb.RootCertPath, c.localHosts(dir), c.localIPs(dir), dir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to add the address to the leaf certificate: %s", err)
+		}
+	}
+
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate join token: %s", err)
+	}
+
+	_, port, err := net.SplitHostPort(c.options.GrpcBind)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine the connector port: %s", err)
+	}
+
+	ca, err := ioutil.ReadFile(cb.RootCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read root certificate: %s", err)
+	}
+
+	fp, err := joinCAFingerprint(string(ca))
+	if err != nil {
+		return nil, err
+	}
+
+	jt := &JoinToken{
+		Token:            hex.EncodeToString(b),
+		CAFingerprint:    fp,
+		ConnectorAddress: net.JoinHostPort(address, port),
+		Expires:          time.Now().Add(JoinTokenTTL),
+	}
+
+	if _, port, err := net.SplitHostPort(c.options.EnvironmentBind); err == nil {
+		jt.EnvironmentAddress = net.JoinHostPort(address, port)
+	}
+
+	d, err := json.Marshal(jt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(JoinTokenPath(dir), d, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write join token: %s", err)
+	}
+
+	return jt, nil
+}
+
+// AcceptJoin validates the MAC of a join request and trusts the CA of the joining
+// connector, the token is removed so that it can not be used again. The MAC is
+// calculated over the CA so a CA replaced in transit is rejected.
+func (c *ConnectorImpl) AcceptJoin(dir string, req *JoinRequest) (*JoinResponse, error) {
+	d, err := ioutil.ReadFile(JoinTokenPath(dir))
+	if err != nil {
+		return nil, ErrInvalidJoinToken
+	}
+
+	jt := &JoinToken{}
+	err = json.Unmarshal(d, jt)
+	if err != nil || !hmac.Equal([]byte(joinMAC(jt.Token, req.CA)), []byte(req.MAC)) {
+		return nil, ErrInvalidJoinToken
+	}
+
+	// the token is single use, remove it before it can be used by a second request
+	os.Remove(JoinTokenPath(dir))
+
+	if time.Now().After(jt.Expires) {
+		return nil, ErrInvalidJoinToken
+	}
+
+	name, err := utils.ReplaceNonURIChars(req.Name)
+	if err != nil || name == "" {
+		return nil, fmt.Errorf("invalid machine name '%s'", req.Name)
+	}
+
+	f, err := ioutil.TempFile("", "*.cert")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString(req.CA)
+	f.Close()
+
+	err = c.ImportCA(dir, name, f.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := ioutil.ReadFile(filepath.Join(dir, "root.cert"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read root certificate: %s", err)
+	}
+
+	return &JoinResponse{
+		Name:               utils.GetHostname(),
+		CA:                 string(ca),
+		ConnectorAddress:   jt.ConnectorAddress,
+		EnvironmentAddress: jt.EnvironmentAddress,
+	}, nil
+}
+
+// Join exchanges CAs with the connector on another machine using the join token
+// created on that machine. The address is the address of the API server of the
+// other connector e.g. lab.local:30003. The token is never sent, the request is
+// signed with the token and the CA returned by the other connector must match
+// the fingerprint in the token.
+func (c *ConnectorImpl) Join(dir, address, token string) (*RemoteConnector, error) {
+	fingerprint, secret, err := parseJoinToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := ioutil.ReadFile(filepath.Join(dir, "root.cert"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read root certificate: %s", err)
+	}
+
+	d, err := json.Marshal(&JoinRequest{MAC: joinMAC(secret, string(ca)), Name: utils.GetHostname(), CA: string(ca)})
+	if err != nil {
+		return nil, err
+	}
+
+	client := http.Client{Timeout: 30 * time.Second}
+
+	resp, err := client.Post(fmt.Sprintf("http://%s/join", address), "application/json", bytes.NewReader(d))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unable to join connector, status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	jr := &JoinResponse{}
+	err = json.NewDecoder(resp.Body).Decode(jr)
+	if err != nil {
+		return nil, err
+	}
+
+	fp, err := joinCAFingerprint(jr.CA)
+	if err != nil {
+		return nil, fmt.Errorf("connector returned an invalid CA: %s", err)
+	}
+
+	if !hmac.Equal([]byte(fp), []byte(fingerprint)) {
+		return nil, fmt.Errorf("the CA returned by the connector does not match the join token, the connection may have been intercepted")
+	}
+
+	x := &crypto.X509{}
+	f, err := ioutil.TempFile("", "*.cert")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString(jr.CA)
+	f.Close()
+
+	err = x.ReadFile(f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("connector returned an invalid CA: %s", err)
+	}
+
+	name, err := utils.ReplaceNonURIChars(jr.Name)
+	if err != nil || name == "" {
+		return nil, fmt.Errorf("connector returned an invalid machine name '%s'", jr.Name)
+	}
+
+	err = c.ImportCA(dir, name, f.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &RemoteConnector{
+		Name:               name,
+		APIAddress:         address,
+		ConnectorAddress:   jr.ConnectorAddress,
+		EnvironmentAddress: jr.EnvironmentAddress,
+		Joined:             time.Now(),
+	}
+
+	err = SaveRemoteConnector(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	return rc, nil
+}
+
+// SaveRemoteConnector writes the details of a joined connector to the remotes folder
+func SaveRemoteConnector(rc *RemoteConnector) error {
+	err := os.MkdirAll(utils.RemoteConnectorsDir(), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	d, err := json.MarshalIndent(rc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(utils.RemoteConnectorsDir(), rc.Name+".json"), d, 0644)
+}
+
+// LoadRemoteConnector returns the details of the joined connector with the given name
+func LoadRemoteConnector(name string) (*RemoteConnector, error) {
+	d, err := ioutil.ReadFile(filepath.Join(utils.RemoteConnectorsDir(), name+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("machine '%s' has not been joined, run 'shipyard connector join' first", name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &RemoteConnector{}
+	err = json.Unmarshal(d, rc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read remote connector %s: %s", name, err)
+	}
+
+	return rc, nil
+}
+
+// ListRemoteConnectors returns the joined connectors sorted by name
+func ListRemoteConnectors() ([]*RemoteConnector, error) {
+	files, err := filepath.Glob(filepath.Join(utils.RemoteConnectorsDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+
+	rcs := []*RemoteConnector{}
+	for _, f := range files {
+		rc, err := LoadRemoteConnector(strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			return nil, err
+		}
+
+		rcs = append(rcs, rc)
+	}
+
+	return rcs, nil
+}
+
+// parseJoinToken returns the CA fingerprint and secret from the value of a join token
+func parseJoinToken(token string) (string, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid join token, create a token with 'shipyard connector join-token' on the other machine")
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// joinMAC returns the hex encoded HMAC-SHA256 of the CA keyed with the join token
+func joinMAC(token, ca string) string {
+	h := hmac.New(sha256.New, []byte(token))
+	h.Write([]byte(ca))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// joinCAFingerprint returns the hex encoded SHA256 of the first certificate in the PEM data
+func joinCAFingerprint(ca string) (string, error) {
+	b, _ := pem.Decode([]byte(ca))
+	if b == nil {
+		return "", fmt.Errorf("CA is not a PEM encoded certificate")
+	}
+
+	s := sha256.Sum256(b.Bytes)
+
+	return hex.EncodeToString(s[:]), nil
+}
+
+func contains(s []string, v string) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+
+	return false
+}
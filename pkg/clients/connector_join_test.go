@@ -0,0 +1,245 @@
+package clients
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupJoinTests(t *testing.T) (*ConnectorImpl, string) {
+	home := os.Getenv(utils.HomeEnvName())
+	os.Setenv(utils.HomeEnvName(), t.TempDir())
+	t.Cleanup(func() {
+		os.Setenv(utils.HomeEnvName(), home)
+	})
+
+	c := NewConnector(ConnectorOptions{GrpcBind: ":30001", HTTPBind: ":30002"}).(*ConnectorImpl)
+
+	dir := t.TempDir()
+	_, err := c.GenerateLocalCertBundle(dir)
+	assert.NoError(t, err)
+
+	return c, dir
+}
+
+func TestCreateJoinTokenAddsAddressToLeaf(t *testing.T) {
+	c, dir := setupJoinTests(t)
+
+	jt, err := c.CreateJoinToken(dir, "lab.local")
+	assert.NoError(t, err)
+
+	assert.Len(t, jt.Token, 64)
+	assert.Len(t, jt.CAFingerprint, 64)
+	assert.Equal(t, jt.CAFingerprint+"."+jt.Token, jt.String())
+	assert.Equal(t, "lab.local:30001", jt.ConnectorAddress)
+	assert.FileExists(t, JoinTokenPath(dir))
+
+	leaf, err := loadLeaf(filepath.Join(dir, "leaf.cert"))
+	assert.NoError(t, err)
+	assert.Contains(t, leaf.DNSNames, "lab.local:30001")
+
+	// the address is kept when the certificate is rotated
+	_, err = c.RotateLocalCerts(dir, false)
+	assert.NoError(t, err)
+
+	leaf, err = loadLeaf(filepath.Join(dir, "leaf.cert"))
+	assert.NoError(t, err)
+	assert.Contains(t, leaf.DNSNames, "lab.local:30001")
+}
+
+func TestCreateJoinTokenRejectsAddressWithPort(t *testing.T) {
+	c, dir := setupJoinTests(t)
+
+	_, err := c.CreateJoinToken(dir, "lab.local:30003")
+	assert.Error(t, err)
+}
+
+func TestAcceptJoinTrustsCAAndRemovesToken(t *testing.T) {
+	c, dir := setupJoinTests(t)
+
+	jt, err := c.CreateJoinToken(dir, "10.0.0.5")
+	assert.NoError(t, err)
+
+	ca, err := ioutil.ReadFile(filepath.Join(dir, "root.cert"))
+	assert.NoError(t, err)
+
+	resp, err := c.AcceptJoin(dir, &JoinRequest{MAC: joinMAC(jt.Token, string(ca)), Name: "laptop", CA: string(ca)})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "10.0.0.5:30001", resp.ConnectorAddress)
+	assert.Equal(t, string(ca), resp.CA)
+	assert.FileExists(t, filepath.Join(TrustedCertsDir(dir), "laptop.cert"))
+	assert.NoFileExists(t, JoinTokenPath(dir))
+
+	// tokens can only be used once
+	_, err = c.AcceptJoin(dir, &JoinRequest{MAC: joinMAC(jt.Token, string(ca)), Name: "laptop", CA: string(ca)})
+	assert.Equal(t, ErrInvalidJoinToken, err)
+}
+
+func TestAcceptJoinRejectsInvalidToken(t *testing.T) {
+	c, dir := setupJoinTests(t)
+
+	_, err := c.CreateJoinToken(dir, "lab.local")
+	assert.NoError(t, err)
+
+	_, err = c.AcceptJoin(dir, &JoinRequest{MAC: joinMAC("wrong", "ca"), Name: "laptop", CA: "ca"})
+	assert.Equal(t, ErrInvalidJoinToken, err)
+
+	// a wrong token does not use the token
+	assert.FileExists(t, JoinTokenPath(dir))
+}
+
+func TestAcceptJoinRejectsTamperedCA(t *testing.T) {
+	c, dir := setupJoinTests(t)
+
+	jt, err := c.CreateJoinToken(dir, "lab.local")
+	assert.NoError(t, err)
+
+	// the CA has been replaced after the request was signed
+	_, err = c.AcceptJoin(dir, &JoinRequest{MAC: joinMAC(jt.Token, "laptop-ca"), Name: "laptop", CA: "attacker-ca"})
+	assert.Equal(t, ErrInvalidJoinToken, err)
+
+	assert.NoFileExists(t, filepath.Join(TrustedCertsDir(dir), "laptop.cert"))
+	assert.FileExists(t, JoinTokenPath(dir))
+}
+
+func TestAcceptJoinRejectsExpiredToken(t *testing.T) {
+	c, dir := setupJoinTests(t)
+
+	ttl := JoinTokenTTL
+	JoinTokenTTL = -time.Minute
+	t.Cleanup(func() { JoinTokenTTL = ttl })
+
+	jt, err := c.CreateJoinToken(dir, "lab.local")
+	assert.NoError(t, err)
+
+	_, err = c.AcceptJoin(dir, &JoinRequest{MAC: joinMAC(jt.Token, "ca"), Name: "laptop", CA: "ca"})
+	assert.Equal(t, ErrInvalidJoinToken, err)
+}
+
+func setupJoinServer(t *testing.T, lab *ConnectorImpl, labDir string, tamper func(resp *JoinResponse)) string {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		req := &JoinRequest{}
+		json.NewDecoder(r.Body).Decode(req)
+
+		resp, err := lab.AcceptJoin(labDir, req)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		if tamper != nil {
+			tamper(resp)
+		}
+
+		json.NewEncoder(rw).Encode(resp)
+	}))
+	t.Cleanup(ts.Close)
+
+	return strings.TrimPrefix(ts.URL, "http://")
+}
+
+func setupLaptop(t *testing.T) (*ConnectorImpl, string) {
+	laptop := NewConnector(ConnectorOptions{GrpcBind: ":30001"}).(*ConnectorImpl)
+	laptopDir := t.TempDir()
+	_, err := laptop.GenerateLocalCertBundle(laptopDir)
+	assert.NoError(t, err)
+
+	return laptop, laptopDir
+}
+
+func TestJoinExchangesCAsAndSavesRemote(t *testing.T) {
+	lab, labDir := setupJoinTests(t)
+	laptop, laptopDir := setupLaptop(t)
+
+	jt, err := lab.CreateJoinToken(labDir, "lab.local")
+	assert.NoError(t, err)
+
+	addr := setupJoinServer(t, lab, labDir, nil)
+
+	rc, err := laptop.Join(laptopDir, addr, jt.String())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "lab.local:30001", rc.ConnectorAddress)
+	assert.Equal(t, addr, rc.APIAddress)
+
+	// both machines trust the CA of the other machine
+	assert.FileExists(t, filepath.Join(TrustedCertsDir(laptopDir), rc.Name+".cert"))
+	assert.FileExists(t, filepath.Join(TrustedCertsDir(labDir), rc.Name+".cert"))
+
+	rcs, err := ListRemoteConnectors()
+	assert.NoError(t, err)
+	assert.Len(t, rcs, 1)
+	assert.Equal(t, rc.Name, rcs[0].Name)
+
+	// the token has been used
+	_, err = laptop.Join(laptopDir, addr, jt.String())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+func TestJoinRejectsCAWhichDoesNotMatchToken(t *testing.T) {
+	lab, labDir := setupJoinTests(t)
+	laptop, laptopDir := setupLaptop(t)
+
+	// a CA generated by an attacker between the machines
+	_, attackerDir := setupLaptop(t)
+	attackerCA, err := ioutil.ReadFile(filepath.Join(attackerDir, "root.cert"))
+	assert.NoError(t, err)
+
+	jt, err := lab.CreateJoinToken(labDir, "lab.local")
+	assert.NoError(t, err)
+
+	addr := setupJoinServer(t, lab, labDir, func(resp *JoinResponse) {
+		resp.CA = string(attackerCA)
+	})
+
+	_, err = laptop.Join(laptopDir, addr, jt.String())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match the join token")
+
+	files, _ := filepath.Glob(filepath.Join(TrustedCertsDir(laptopDir), "*.cert"))
+	assert.Len(t, files, 0)
+
+	rcs, err := ListRemoteConnectors()
+	assert.NoError(t, err)
+	assert.Len(t, rcs, 0)
+}
+
+func TestJoinRejectsInvalidToken(t *testing.T) {
+	laptop, laptopDir := setupLaptop(t)
+
+	_, err := laptop.Join(laptopDir, "lab.local:30003", "3f8a")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid join token")
+}
+
+func TestLoadRemoteConnectorReturnsErrorWhenNotJoined(t *testing.T) {
+	setupJoinTests(t)
+
+	_, err := LoadRemoteConnector("lab")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "shipyard connector join")
+}
+
+func loadLeaf(file string) (*x509.Certificate, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	b, _ := pem.Decode(d)
+
+	return x509.ParseCertificate(b.Bytes)
+}
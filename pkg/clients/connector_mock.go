@@ -2,6 +2,7 @@ package clients
 
 import (
 	"github.com/shipyard-run/connector/protos/shipyard"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -90,3 +91,121 @@ func (m *ConnectorMock) ListServices() ([]*shipyard.Service, error) {
 
 	return nil, args.Error(1)
 }
+
+func (m *ConnectorMock) ExposeAuthProxy(name, bindAddr, upstream string, auth *config.Auth, headers *config.Headers) (string, error) {
+	args := m.Called(name, bindAddr, upstream, auth, headers)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *ConnectorMock) RemoveAuthProxy(id string) error {
+	return m.Called(id).Error(0)
+}
+
+func (m *ConnectorMock) ExposeRouter(name, bindAddr string, routes []config.Route) (string, error) {
+	args := m.Called(name, bindAddr, routes)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *ConnectorMock) RemoveRouter(id string) error {
+	return m.Called(id).Error(0)
+}
+
+func (m *ConnectorMock) ExposeStreamProxy(name, protocol, bindAddr, upstream, certFile, keyFile string) (string, error) {
+	args := m.Called(name, protocol, bindAddr, upstream, certFile, keyFile)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *ConnectorMock) RemoveStreamProxy(id string) error {
+	return m.Called(id).Error(0)
+}
+
+func (m *ConnectorMock) ExposeSocksProxy(name, bindAddr, connectorAddr string) (string, error) {
+	args := m.Called(name, bindAddr, connectorAddr)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *ConnectorMock) RemoveSocksProxy(id string) error {
+	return m.Called(id).Error(0)
+}
+
+func (m *ConnectorMock) Status() (*ConnectorStatus, error) {
+	args := m.Called()
+
+	if st, ok := args.Get(0).(*ConnectorStatus); ok {
+		return st, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *ConnectorMock) RotateLocalCerts(dir string, rotateCA bool) (*CertBundle, error) {
+	args := m.Called(dir, rotateCA)
+
+	if cb, ok := args.Get(0).(*CertBundle); ok {
+		return cb, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *ConnectorMock) ImportCA(dir, name, file string) error {
+	return m.Called(dir, name, file).Error(0)
+}
+
+func (m *ConnectorMock) CreateJoinToken(dir, address string) (*JoinToken, error) {
+	args := m.Called(dir, address)
+
+	if jt, ok := args.Get(0).(*JoinToken); ok {
+		return jt, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *ConnectorMock) AcceptJoin(dir string, req *JoinRequest) (*JoinResponse, error) {
+	args := m.Called(dir, req)
+
+	if jr, ok := args.Get(0).(*JoinResponse); ok {
+		return jr, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *ConnectorMock) Join(dir, address, token string) (*RemoteConnector, error) {
+	args := m.Called(dir, address, token)
+
+	if rc, ok := args.Get(0).(*RemoteConnector); ok {
+		return rc, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *ConnectorMock) InstallService(cb *CertBundle) error {
+	return m.Called(cb).Error(0)
+}
+
+func (m *ConnectorMock) UninstallService() error {
+	return m.Called().Error(0)
+}
+
+func (m *ConnectorMock) ServiceInstalled() bool {
+	return m.Called().Bool(0)
+}
+
+func (m *ConnectorMock) TrustCA(dir string) error {
+	return m.Called(dir).Error(0)
+}
+
+func (m *ConnectorMock) UntrustCA(dir string) error {
+	return m.Called(dir).Error(0)
+}
+
+func (m *ConnectorMock) CATrusted(dir string) bool {
+	return m.Called(dir).Bool(0)
+}
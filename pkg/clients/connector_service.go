@@ -0,0 +1,25 @@
+package clients
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ConnectorServiceName is the name the connector is registered with in the service manager
+const ConnectorServiceName = "shipyard-connector"
+
+// serviceCommand creates the commands used to interact with the service manager,
+// it is replaced in tests
+var serviceCommand = exec.Command
+
+// runServiceCommand runs a service manager command, the output of the command
+// is returned in the error when the command fails
+func runServiceCommand(name string, args ...string) error {
+	out, err := serviceCommand(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Unable to run '%s %s': %s %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
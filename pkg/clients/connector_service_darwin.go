@@ -0,0 +1,99 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// connectorServiceLabel is the launchd label for the connector agent
+const connectorServiceLabel = "run.shipyard.connector"
+
+// connectorPlist is the launchd agent definition used to run the connector
+var connectorPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+  <key>Label</key>
+  <string>%s</string>
+  <key>ProgramArguments</key>
+  <array>
+%s  </array>
+  <key>EnvironmentVariables</key>
+  <dict>
+    <key>HOME</key>
+    <string>%s</string>
+  </dict>
+  <key>RunAtLoad</key>
+  <true/>
+  <key>KeepAlive</key>
+  <true/>
+  <key>StandardOutPath</key>
+  <string>%s</string>
+  <key>StandardErrorPath</key>
+  <string>%s</string>
+</dict>
+</plist>
+`
+
+// connectorPlistPath returns the path of the launchd agent for the connector
+func connectorPlistPath() string {
+	return filepath.Join(utils.HomeFolder(), "Library", "LaunchAgents", connectorServiceLabel+".plist")
+}
+
+// InstallService registers the Connector as a launchd agent
+func (c *ConnectorImpl) InstallService(cb *CertBundle) error {
+	args := ""
+	for _, a := range append([]string{c.options.BinaryPath}, c.runArgs(cb)...) {
+		args += fmt.Sprintf("    <string>%s</string>\n", xmlEscape(a))
+	}
+
+	logFile := xmlEscape(filepath.Join(c.options.LogDirectory, "connector_service.log"))
+	plist := fmt.Sprintf(connectorPlist, connectorServiceLabel, args, xmlEscape(utils.HomeFolder()), logFile, logFile)
+
+	path := connectorPlistPath()
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("Unable to create launchd agent folder: %s", err)
+	}
+
+	err = ioutil.WriteFile(path, []byte(plist), 0644)
+	if err != nil {
+		return fmt.Errorf("Unable to write launchd agent %s: %s", path, err)
+	}
+
+	return runServiceCommand("launchctl", "load", "-w", path)
+}
+
+// UninstallService stops the Connector and removes the launchd agent
+func (c *ConnectorImpl) UninstallService() error {
+	err := runServiceCommand("launchctl", "unload", "-w", connectorPlistPath())
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(connectorPlistPath())
+	if err != nil {
+		return fmt.Errorf("Unable to remove launchd agent %s: %s", connectorPlistPath(), err)
+	}
+
+	return nil
+}
+
+// ServiceInstalled returns true when the launchd agent for the Connector exists
+func (c *ConnectorImpl) ServiceInstalled() bool {
+	_, err := os.Stat(connectorPlistPath())
+	return err == nil
+}
+
+func xmlEscape(s string) string {
+	b := &bytes.Buffer{}
+	xml.EscapeText(b, []byte(s))
+
+	return b.String()
+}
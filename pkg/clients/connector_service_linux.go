@@ -0,0 +1,81 @@
+package clients
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// connectorUnit is the systemd user unit used to run the connector
+var connectorUnit = `[Unit]
+Description=Shipyard Connector
+After=network-online.target
+
+[Service]
+ExecStart=%s
+Environment=HOME=%s
+Restart=always
+RestartSec=5
+
+[Install]
+WantedBy=default.target
+`
+
+// connectorUnitPath returns the path of the systemd user unit for the connector
+func connectorUnitPath() string {
+	return filepath.Join(utils.HomeFolder(), ".config", "systemd", "user", ConnectorServiceName+".service")
+}
+
+// InstallService registers the Connector as a systemd user unit
+func (c *ConnectorImpl) InstallService(cb *CertBundle) error {
+	command := []string{}
+	for _, a := range append([]string{c.options.BinaryPath}, c.runArgs(cb)...) {
+		// % is used for specifiers in systemd units and must be escaped
+		command = append(command, strings.ReplaceAll(strconv.Quote(a), "%", "%%"))
+	}
+
+	path := connectorUnitPath()
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("Unable to create systemd unit folder: %s", err)
+	}
+
+	unit := fmt.Sprintf(connectorUnit, strings.Join(command, " "), utils.HomeFolder())
+	err = ioutil.WriteFile(path, []byte(unit), 0644)
+	if err != nil {
+		return fmt.Errorf("Unable to write systemd unit %s: %s", path, err)
+	}
+
+	err = runServiceCommand("systemctl", "--user", "daemon-reload")
+	if err != nil {
+		return err
+	}
+
+	return runServiceCommand("systemctl", "--user", "enable", "--now", ConnectorServiceName+".service")
+}
+
+// UninstallService stops the Connector and removes the systemd user unit
+func (c *ConnectorImpl) UninstallService() error {
+	err := runServiceCommand("systemctl", "--user", "disable", "--now", ConnectorServiceName+".service")
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(connectorUnitPath())
+	if err != nil {
+		return fmt.Errorf("Unable to remove systemd unit %s: %s", connectorUnitPath(), err)
+	}
+
+	return runServiceCommand("systemctl", "--user", "daemon-reload")
+}
+
+// ServiceInstalled returns true when the systemd user unit for the Connector exists
+func (c *ConnectorImpl) ServiceInstalled() bool {
+	_, err := os.Stat(connectorUnitPath())
+	return err == nil
+}
@@ -0,0 +1,99 @@
+package clients
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupConnectorService(t *testing.T) (*ConnectorImpl, *[]string) {
+	home := os.Getenv(utils.HomeEnvName())
+	os.Setenv(utils.HomeEnvName(), t.TempDir())
+
+	commands := []string{}
+	serviceCommand = func(name string, args ...string) *exec.Cmd {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		return exec.Command("true")
+	}
+
+	t.Cleanup(func() {
+		os.Setenv(utils.HomeEnvName(), home)
+		serviceCommand = exec.Command
+	})
+
+	co, err := DefaultConnectorOptions()
+	assert.NoError(t, err)
+
+	c := &ConnectorImpl{options: co}
+	c.options.BinaryPath = "/usr/local/bin/shipyard"
+
+	return c, &commands
+}
+
+func TestConnectorInstallServiceWritesUnit(t *testing.T) {
+	c, _ := setupConnectorService(t)
+
+	err := c.InstallService(&CertBundle{RootCertPath: "/certs/root.cert", LeafCertPath: "/certs/leaf.cert", LeafKeyPath: "/certs/leaf.key"})
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(connectorUnitPath())
+	assert.NoError(t, err)
+
+	unit := string(d)
+	assert.Contains(t, unit, `ExecStart="/usr/local/bin/shipyard" "connector" "run"`)
+	assert.Contains(t, unit, `"--root-cert-path" "/certs/root.cert"`)
+	assert.Contains(t, unit, "Environment=HOME="+utils.HomeFolder())
+	assert.Contains(t, unit, "Restart=always")
+
+	assert.True(t, c.ServiceInstalled())
+}
+
+func TestConnectorInstallServiceEnablesUnit(t *testing.T) {
+	c, commands := setupConnectorService(t)
+
+	err := c.InstallService(&CertBundle{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"systemctl --user daemon-reload",
+		"systemctl --user enable --now shipyard-connector.service",
+	}, *commands)
+}
+
+func TestConnectorInstallServiceReturnsErrorWhenCommandFails(t *testing.T) {
+	c, _ := setupConnectorService(t)
+	serviceCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("false")
+	}
+
+	err := c.InstallService(&CertBundle{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "systemctl --user daemon-reload")
+}
+
+func TestConnectorUninstallServiceRemovesUnit(t *testing.T) {
+	c, commands := setupConnectorService(t)
+
+	err := c.InstallService(&CertBundle{})
+	assert.NoError(t, err)
+
+	err = c.UninstallService()
+	assert.NoError(t, err)
+
+	assert.False(t, c.ServiceInstalled())
+	assert.Contains(t, *commands, "systemctl --user disable --now shipyard-connector.service")
+}
+
+func TestConnectorIsRunningWhenServiceInstalled(t *testing.T) {
+	c, _ := setupConnectorService(t)
+
+	err := c.InstallService(&CertBundle{})
+	assert.NoError(t, err)
+
+	assert.True(t, c.IsRunning())
+}
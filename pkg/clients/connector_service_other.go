@@ -0,0 +1,24 @@
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package clients
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// InstallService is not supported on this platform
+func (c *ConnectorImpl) InstallService(cb *CertBundle) error {
+	return fmt.Errorf("Installing the connector as a service is not supported on %s", runtime.GOOS)
+}
+
+// UninstallService is not supported on this platform
+func (c *ConnectorImpl) UninstallService() error {
+	return fmt.Errorf("Installing the connector as a service is not supported on %s", runtime.GOOS)
+}
+
+// ServiceInstalled always returns false as services are not supported on this platform
+func (c *ConnectorImpl) ServiceInstalled() bool {
+	return false
+}
@@ -0,0 +1,45 @@
+package clients
+
+import (
+	"strings"
+	"syscall"
+)
+
+// InstallService registers the Connector as a Windows service which is
+// started automatically and restarted on failure
+func (c *ConnectorImpl) InstallService(cb *CertBundle) error {
+	command := []string{}
+	for _, a := range append([]string{c.options.BinaryPath}, c.runArgs(cb)...) {
+		command = append(command, syscall.EscapeArg(a))
+	}
+
+	err := runServiceCommand(
+		"sc.exe", "create", ConnectorServiceName,
+		"binPath=", strings.Join(command, " "),
+		"start=", "auto",
+		"DisplayName=", "Shipyard Connector",
+	)
+	if err != nil {
+		return err
+	}
+
+	err = runServiceCommand("sc.exe", "failure", ConnectorServiceName, "reset=", "0", "actions=", "restart/5000")
+	if err != nil {
+		return err
+	}
+
+	return runServiceCommand("sc.exe", "start", ConnectorServiceName)
+}
+
+// UninstallService stops the Connector and deletes the Windows service
+func (c *ConnectorImpl) UninstallService() error {
+	// the service might already be stopped
+	runServiceCommand("sc.exe", "stop", ConnectorServiceName)
+
+	return runServiceCommand("sc.exe", "delete", ConnectorServiceName)
+}
+
+// ServiceInstalled returns true when the Windows service for the Connector exists
+func (c *ConnectorImpl) ServiceInstalled() bool {
+	return serviceCommand("sc.exe", "query", ConnectorServiceName).Run() == nil
+}
@@ -2,6 +2,7 @@ package clients
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"net"
 	"os"
@@ -23,7 +24,10 @@ var suiteOptions ConnectorOptions
 
 func TestConnectorSuite(t *testing.T) {
 	suiteTemp = t.TempDir()
-	suiteBinary = utils.GetShipyardBinaryPath()
+	sb, err := utils.GetShipyardBinaryPath()
+	assert.NoError(t, err)
+
+	suiteBinary = sb
 
 	home := os.Getenv(utils.HomeEnvName())
 	os.Setenv(utils.HomeEnvName(), suiteTemp)
@@ -39,6 +43,8 @@ func TestConnectorSuite(t *testing.T) {
 	t.Run("Generates certificates", testGenerateCreatesBundle)
 	t.Run("Fetches certificates", testFetchesLocalCertBundle)
 	t.Run("Generates a leaf certificate", testGenerateCreatesLeaf)
+	t.Run("Rotates the leaf certificate", testRotateReplacesLeaf)
+	t.Run("Imports a CA", testImportCACopiesCA)
 	t.Run("Starts Connector correctly", testStartsConnector)
 	t.Run("Calls expose", testExposeServiceCallsExpose)
 	t.Run("Calls remove", testRemoveServiceCallsRemove)
@@ -88,6 +94,39 @@ func testGenerateCreatesLeaf(t *testing.T) {
 	assert.FileExists(t, leafCertBundle.LeafCertPath)
 }
 
+func testRotateReplacesLeaf(t *testing.T) {
+	c := NewConnector(suiteOptions)
+
+	root, _ := ioutil.ReadFile(suiteCertBundle.RootCertPath)
+	leaf, _ := ioutil.ReadFile(suiteCertBundle.LeafCertPath)
+
+	cb, err := c.RotateLocalCerts(utils.CertsDir(""), false)
+	assert.NoError(t, err)
+
+	newRoot, _ := ioutil.ReadFile(cb.RootCertPath)
+	newLeaf, _ := ioutil.ReadFile(cb.LeafCertPath)
+
+	assert.Equal(t, root, newRoot)
+	assert.NotEqual(t, leaf, newLeaf)
+
+	exp, err := CertExpiry(cb.LeafCertPath)
+	assert.NoError(t, err)
+	assert.True(t, exp.After(time.Now()))
+}
+
+func testImportCACopiesCA(t *testing.T) {
+	c := NewConnector(suiteOptions)
+	dir := t.TempDir()
+
+	err := c.ImportCA(dir, "peer", suiteCertBundle.RootCertPath)
+	assert.NoError(t, err)
+	assert.FileExists(t, path.Join(TrustedCertsDir(dir), "peer.cert"))
+
+	// leaf certificates can not be imported
+	err = c.ImportCA(dir, "leaf", suiteCertBundle.LeafCertPath)
+	assert.Error(t, err)
+}
+
 func testStartsConnector(t *testing.T) {
 	c := NewConnector(suiteOptions)
 
@@ -0,0 +1,42 @@
+package clients
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/shipyard-run/connector/crypto"
+)
+
+// caTrustName is the name the root CA is installed with in the trust stores
+const caTrustName = "Shipyard Root CA"
+
+// lookPath finds the binaries used to manage trust stores, it is replaced in tests
+var lookPath = exec.LookPath
+
+// rootCAPath returns the path of the root CA in the certificate folder
+func rootCAPath(dir string) string {
+	return filepath.Join(dir, "root.cert")
+}
+
+// readRootCA reads the root CA from the certificate folder
+func readRootCA(dir string) (*crypto.X509, error) {
+	ca := &crypto.X509{}
+	err := ca.ReadFile(rootCAPath(dir))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the root CA %s: %s", rootCAPath(dir), err)
+	}
+
+	return ca, nil
+}
+
+// caSerial returns the serial number of the CA used to identify it in the Windows trust store
+func caSerial(ca *crypto.X509) string {
+	return fmt.Sprintf("%x", ca.SerialNumber)
+}
+
+// caFingerprint returns the SHA-1 fingerprint of the CA used to identify it in the macOS keychain
+func caFingerprint(ca *crypto.X509) string {
+	return fmt.Sprintf("%X", sha1.Sum(ca.Raw))
+}
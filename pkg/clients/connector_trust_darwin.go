@@ -0,0 +1,41 @@
+package clients
+
+import (
+	"path/filepath"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// loginKeychainPath returns the path of the login keychain of the current user
+func loginKeychainPath() string {
+	return filepath.Join(utils.HomeFolder(), "Library", "Keychains", "login.keychain-db")
+}
+
+// TrustCA adds the root CA to the login keychain as a trusted root, macOS
+// prompts for the password of the user to change the trust settings
+func (c *ConnectorImpl) TrustCA(dir string) error {
+	_, err := readRootCA(dir)
+	if err != nil {
+		return err
+	}
+
+	return runServiceCommand("security", "add-trusted-cert", "-r", "trustRoot", "-k", loginKeychainPath(), rootCAPath(dir))
+}
+
+// UntrustCA removes the trust settings for the root CA and deletes it from the login keychain
+func (c *ConnectorImpl) UntrustCA(dir string) error {
+	ca, err := readRootCA(dir)
+	if err != nil {
+		return err
+	}
+
+	// the trust settings might already have been removed
+	runServiceCommand("security", "remove-trusted-cert", rootCAPath(dir))
+
+	return runServiceCommand("security", "delete-certificate", "-Z", caFingerprint(ca), loginKeychainPath())
+}
+
+// CATrusted returns true when the root CA is trusted by the system
+func (c *ConnectorImpl) CATrusted(dir string) bool {
+	return serviceCommand("security", "verify-cert", "-c", rootCAPath(dir)).Run() == nil
+}
@@ -0,0 +1,164 @@
+package clients
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// linuxTrustStore is the system trust store of a Linux distribution, the CA
+// is copied to the anchors folder and the update command regenerates the bundle
+type linuxTrustStore struct {
+	dir    string
+	file   string
+	update []string
+}
+
+func (s linuxTrustStore) path() string {
+	return filepath.Join(s.dir, s.file)
+}
+
+// linuxTrustStores are the system trust stores for Debian, Red Hat, and Arch based distributions
+var linuxTrustStores = []linuxTrustStore{
+	{dir: "/usr/local/share/ca-certificates", file: "shipyard-root-ca.crt", update: []string{"update-ca-certificates"}},
+	{dir: "/etc/pki/ca-trust/source/anchors", file: "shipyard-root-ca.pem", update: []string{"update-ca-trust", "extract"}},
+	{dir: "/etc/ca-certificates/trust-source/anchors", file: "shipyard-root-ca.crt", update: []string{"trust", "extract-compat"}},
+}
+
+// nssDatabases are the NSS databases, relative to the home folder, used by
+// Firefox and Chrome which do not use the system trust store
+var nssDatabases = []string{
+	".pki/nssdb",
+	"snap/chromium/current/.pki/nssdb",
+	".mozilla/firefox/*",
+	"snap/firefox/common/.mozilla/firefox/*",
+}
+
+// geteuid returns the user id used to determine if commands need sudo, it is replaced in tests
+var geteuid = os.Geteuid
+
+// TrustCA installs the root CA into the system trust store, and the NSS databases
+// used by Firefox and Chrome when certutil is installed
+func (c *ConnectorImpl) TrustCA(dir string) error {
+	_, err := readRootCA(dir)
+	if err != nil {
+		return err
+	}
+
+	s, err := systemTrustStore()
+	if err != nil {
+		return err
+	}
+
+	err = runPrivileged("install", "-m", "0644", rootCAPath(dir), s.path())
+	if err != nil {
+		return err
+	}
+
+	err = runPrivileged(s.update...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := lookPath("certutil"); err != nil {
+		return nil
+	}
+
+	for _, db := range findNSSDatabases() {
+		err := runServiceCommand("certutil", "-A", "-d", "sql:"+db, "-t", "C,,", "-n", caTrustName, "-i", rootCAPath(dir))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UntrustCA removes the root CA from the system trust store and the NSS databases
+func (c *ConnectorImpl) UntrustCA(dir string) error {
+	s, err := systemTrustStore()
+	if err != nil {
+		return err
+	}
+
+	err = runPrivileged("rm", "-f", s.path())
+	if err != nil {
+		return err
+	}
+
+	err = runPrivileged(s.update...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := lookPath("certutil"); err != nil {
+		return nil
+	}
+
+	for _, db := range findNSSDatabases() {
+		// the CA might not have been added to the database
+		runServiceCommand("certutil", "-D", "-d", "sql:"+db, "-n", caTrustName)
+	}
+
+	return nil
+}
+
+// CATrusted returns true when the root CA is installed in the system trust store
+func (c *ConnectorImpl) CATrusted(dir string) bool {
+	s, err := systemTrustStore()
+	if err != nil {
+		return false
+	}
+
+	installed, err := ioutil.ReadFile(s.path())
+	if err != nil {
+		return false
+	}
+
+	ca, err := ioutil.ReadFile(rootCAPath(dir))
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(installed, ca)
+}
+
+// systemTrustStore returns the first trust store which exists on the system
+func systemTrustStore() (linuxTrustStore, error) {
+	for _, s := range linuxTrustStores {
+		if fi, err := os.Stat(s.dir); err == nil && fi.IsDir() {
+			return s, nil
+		}
+	}
+
+	return linuxTrustStore{}, fmt.Errorf("Unable to find the system trust store, the CA can be trusted manually by adding the certificate at $HOME/.shipyard/certs/root.cert")
+}
+
+// findNSSDatabases returns the NSS databases in the home folder
+func findNSSDatabases() []string {
+	dbs := []string{}
+
+	for _, p := range nssDatabases {
+		matches, _ := filepath.Glob(filepath.Join(utils.HomeFolder(), p))
+		for _, m := range matches {
+			if _, err := os.Stat(filepath.Join(m, "cert9.db")); err == nil {
+				dbs = append(dbs, m)
+			}
+		}
+	}
+
+	return dbs
+}
+
+// runPrivileged runs the command with sudo when not running as root
+func runPrivileged(args ...string) error {
+	if geteuid() == 0 {
+		return runServiceCommand(args[0], args[1:]...)
+	}
+
+	return runServiceCommand("sudo", args...)
+}
@@ -0,0 +1,103 @@
+package clients
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/connector/crypto"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupConnectorTrust(t *testing.T) (*ConnectorImpl, *[]string, string, linuxTrustStore) {
+	c, commands := setupConnectorService(t)
+
+	// write a root CA to the certs folder
+	dir := t.TempDir()
+	k, err := crypto.GenerateKeyPair()
+	assert.NoError(t, err)
+
+	ca, err := crypto.GenerateCA(k.Private)
+	assert.NoError(t, err)
+	assert.NoError(t, ca.WriteFile(filepath.Join(dir, "root.cert")))
+
+	store := linuxTrustStore{dir: t.TempDir(), file: "shipyard-root-ca.crt", update: []string{"update-ca-certificates"}}
+
+	stores := linuxTrustStores
+	linuxTrustStores = []linuxTrustStore{{dir: "/does/not/exist"}, store}
+
+	euid := geteuid
+	geteuid = func() int { return 1000 }
+
+	lp := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+
+	t.Cleanup(func() {
+		linuxTrustStores = stores
+		geteuid = euid
+		lookPath = lp
+	})
+
+	return c, commands, dir, store
+}
+
+func TestConnectorTrustCAInstallsInSystemStoreWithSudo(t *testing.T) {
+	c, commands, dir, store := setupConnectorTrust(t)
+
+	err := c.TrustCA(dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("sudo install -m 0644 %s %s", filepath.Join(dir, "root.cert"), store.path()),
+		"sudo update-ca-certificates",
+	}, *commands)
+}
+
+func TestConnectorTrustCAInstallsInNSSDatabases(t *testing.T) {
+	c, commands, dir, _ := setupConnectorTrust(t)
+
+	db := filepath.Join(utils.HomeFolder(), ".pki", "nssdb")
+	assert.NoError(t, os.MkdirAll(db, os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(db, "cert9.db"), []byte(""), os.ModePerm))
+
+	err := c.TrustCA(dir)
+	assert.NoError(t, err)
+
+	assert.Len(t, *commands, 3)
+	assert.Equal(t, fmt.Sprintf("certutil -A -d sql:%s -t C,, -n Shipyard Root CA -i %s", db, filepath.Join(dir, "root.cert")), (*commands)[2])
+}
+
+func TestConnectorTrustCAWithoutRootCAReturnsError(t *testing.T) {
+	c, commands, _, _ := setupConnectorTrust(t)
+
+	err := c.TrustCA(t.TempDir())
+	assert.Error(t, err)
+	assert.Empty(t, *commands)
+}
+
+func TestConnectorUntrustCARemovesFromSystemStore(t *testing.T) {
+	c, commands, dir, store := setupConnectorTrust(t)
+
+	err := c.UntrustCA(dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("sudo rm -f %s", store.path()),
+		"sudo update-ca-certificates",
+	}, *commands)
+}
+
+func TestConnectorCATrustedComparesInstalledCA(t *testing.T) {
+	c, _, dir, store := setupConnectorTrust(t)
+
+	assert.False(t, c.CATrusted(dir))
+
+	d, err := ioutil.ReadFile(filepath.Join(dir, "root.cert"))
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(store.path(), d, os.ModePerm))
+
+	assert.True(t, c.CATrusted(dir))
+}
@@ -0,0 +1,24 @@
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package clients
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// TrustCA is not supported on this platform
+func (c *ConnectorImpl) TrustCA(dir string) error {
+	return fmt.Errorf("Trusting the Shipyard root CA is not supported on %s", runtime.GOOS)
+}
+
+// UntrustCA is not supported on this platform
+func (c *ConnectorImpl) UntrustCA(dir string) error {
+	return fmt.Errorf("Trusting the Shipyard root CA is not supported on %s", runtime.GOOS)
+}
+
+// CATrusted always returns false as trust stores are not supported on this platform
+func (c *ConnectorImpl) CATrusted(dir string) bool {
+	return false
+}
@@ -0,0 +1,32 @@
+package clients
+
+// TrustCA adds the root CA to the trusted roots of the current user,
+// Windows prompts the user to confirm the CA is trusted
+func (c *ConnectorImpl) TrustCA(dir string) error {
+	_, err := readRootCA(dir)
+	if err != nil {
+		return err
+	}
+
+	return runServiceCommand("certutil.exe", "-user", "-addstore", "Root", rootCAPath(dir))
+}
+
+// UntrustCA removes the root CA from the trusted roots of the current user
+func (c *ConnectorImpl) UntrustCA(dir string) error {
+	ca, err := readRootCA(dir)
+	if err != nil {
+		return err
+	}
+
+	return runServiceCommand("certutil.exe", "-user", "-delstore", "Root", caSerial(ca))
+}
+
+// CATrusted returns true when the root CA is in the trusted roots of the current user
+func (c *ConnectorImpl) CATrusted(dir string) bool {
+	ca, err := readRootCA(dir)
+	if err != nil {
+		return false
+	}
+
+	return serviceCommand("certutil.exe", "-user", "-verifystore", "Root", caSerial(ca)).Run() == nil
+}
@@ -2,6 +2,8 @@ package clients
 
 import (
 	"io"
+	"os"
+	"time"
 
 	"github.com/shipyard-run/shipyard/pkg/config"
 )
@@ -16,6 +18,12 @@ import (
 // this may be composed of many individual SDK calls.
 type ContainerTasks interface {
 	SetForcePull(bool)
+	// SetOffline prevents images being pulled from remote registries, when set
+	// PullImage returns an error for any image which is not in the local cache
+	SetOffline(bool)
+	// SetDefaultPortBind sets the host interface ports are bound to when the port
+	// does not set bind, when blank ports are bound to all interfaces
+	SetDefaultPortBind(string)
 	// CreateContainer creates a new container for the given configuration
 	// if successful CreateContainer returns the ID of the created container and a nil error
 	// if not successful CreateContainer returns a blank string for the id and an error message
@@ -25,6 +33,13 @@ type ContainerTasks interface {
 	ContainerInfo(id string) (interface{}, error)
 	// RemoveContainer stops and removes a running container
 	RemoveContainer(id string, force bool) error
+	// WaitForContainer blocks until the container with the given id exits
+	// and returns the exit code. An error is returned if the container
+	// does not exit within the timeout.
+	WaitForContainer(id string, timeout time.Duration) (exitCode int64, err error)
+	// CommitContainer creates an image with the given name from the filesystem of the container,
+	// any existing image with the same name is replaced
+	CommitContainer(id, image string) error
 	// BuildContainer builds a container based on the given configuration
 	// If a cahced image already exists Build will noop
 	// When force is specificed BuildContainer will rebuild the container regardless of cached images
@@ -42,6 +57,20 @@ type ContainerTasks interface {
 	// If the force parameter is set then PullImage will pull regardless of the image already
 	// being cached locally.
 	PullImage(image config.Image, force bool) error
+	// PushImage tags the local image with the name of the target and pushes it
+	// to the targets registry.
+	// If the Username and Password of the target are set they are used to
+	// authenticate with the registry.
+	PushImage(image string, target config.Image) error
+	// ExportImages writes the given images from the local cache to the writer as
+	// a tar archive which can be loaded with ImportImages or docker load
+	ExportImages(images []string, w io.Writer) error
+	// ImportImages loads the images in the tar archive into the local cache
+	// and returns the names of the loaded images
+	ImportImages(r io.Reader) ([]string, error)
+	// FindImageID returns the id of the image with the given name in the local cache,
+	// the id changes every time a new image is built with the same tag
+	FindImageID(name string) (string, error)
 	// FindContainerIDs returns the Container IDs for the given identifier
 	FindContainerIDs(name string, typeName config.ResourceType) ([]string, error)
 	// ContainerLogs attaches to the container and streams the logs to the returned
@@ -60,6 +89,22 @@ type ContainerTasks interface {
 
 	//CopyFilesToVolume copies the files to the path in a Docker volume
 	CopyFilesToVolume(volume string, files []string, path string, force bool) ([]string, error)
+	// CopyPathToContainer copies the file or the contents of the directory at src to the directory
+	// dst in the container, when mode is not zero the permissions of the files are set to mode
+	CopyPathToContainer(id, src, dst string, mode os.FileMode) error
+	// CopyPathToVolume copies the file or the contents of the directory at src to the directory
+	// dst in the Docker volume, when mode is not zero the permissions of the files are set to mode
+	CopyPathToVolume(volume, src, dst string, mode os.FileMode) error
+	// ExportVolume writes the contents of the Docker volume to the writer as a tar archive
+	// which can be loaded with ImportVolume
+	ExportVolume(volume string, w io.Writer) error
+	// ImportVolume replaces the contents of the Docker volume with the contents of the
+	// tar archive created by ExportVolume, the volume is created if it does not exist
+	ImportVolume(volume string, r io.Reader) error
+	// ImportImagesToContainerd streams the local Docker images directly into the containerd
+	// instance running in the container id, the images are imported to the given containerd namespace.
+	// writer [optional] will be used to write any output from the import.
+	ImportImagesToContainerd(id string, images []string, namespace string, writer io.Writer) error
 	// Execute command allows the execution of commands in a running docker container
 	// id is the id of the container to execute the command in
 	// command is a slice of strings to execute
@@ -3,8 +3,10 @@ package clients
 import (
 	"context"
 	"io"
+	"strings"
 	"time"
 
+	"github.com/docker/cli/cli/connhelper"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
@@ -12,6 +14,7 @@ import (
 	volumetypes "github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 )
 
 // Docker defines an interface for a Docker client
@@ -27,6 +30,7 @@ type Docker interface {
 	ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error)
 	ContainerStart(context.Context, string, types.ContainerStartOptions) error
 	ContainerStop(ctx context.Context, containerID string, timeout *time.Duration) error
+	ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.ContainerWaitOKBody, <-chan error)
 	ContainerRemove(ctx context.Context, containerID string, options types.ContainerRemoveOptions) error
 	ContainerLogs(ctx context.Context, container string, options types.ContainerLogsOptions) (io.ReadCloser, error)
 	ContainerExecCreate(ctx context.Context, container string, config types.ExecConfig) (types.IDResponse, error)
@@ -35,6 +39,8 @@ type Docker interface {
 	ContainerExecInspect(ctx context.Context, execID string) (types.ContainerExecInspect, error)
 	ContainerExecResize(ctx context.Context, execID string, config types.ResizeOptions) error
 	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	ContainerStats(ctx context.Context, containerID string, stream bool) (types.ContainerStats, error)
+	ContainerCommit(ctx context.Context, container string, options types.ContainerCommitOptions) (types.IDResponse, error)
 
 	CopyToContainer(ctx context.Context, container, path string, content io.Reader, options types.CopyToContainerOptions) error
 	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error)
@@ -51,19 +57,63 @@ type Docker interface {
 
 	ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error)
 	ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error)
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
 	ImageSave(ctx context.Context, imageIDs []string) (io.ReadCloser, error)
+	ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error)
 	ImageRemove(ctx context.Context, imageID string, options types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error)
 	ImageBuild(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error)
+	ImageTag(ctx context.Context, source, target string) error
+	ImagePush(ctx context.Context, image string, options types.ImagePushOptions) (io.ReadCloser, error)
 
 	ServerVersion(ctx context.Context) (types.Version, error)
+	Info(ctx context.Context) (types.Info, error)
 }
 
-// NewDocker creates a new Docker client
+// NewDocker creates a new Docker client, when the engine is Podman a client
+// which handles the differences between Docker and Podman is returned
 func NewDocker() (Docker, error) {
-	cli, err := client.NewEnvClient()
+	return NewDockerWithMonitor(nil)
+}
+
+// NewDockerWithMonitor creates a new Docker client which uses the EngineMonitor
+// to wait for the engine when it restarts, when m is nil connections are not retried
+func NewDockerWithMonitor(m *EngineMonitor) (Docker, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+
+	// the host may be set by DOCKER_HOST, the active docker context,
+	// or be the detected socket for Docker or Podman
+	host := utils.GetDockerHost()
+	if !strings.Contains(host, "://") {
+		host = "unix://" + host
+	}
+
+	// ssh hosts are dialed using the ssh command in the same way as the Docker CLI
+	helper, err := connhelper.GetConnectionHelper(host)
 	if err != nil {
 		return nil, err
 	}
 
+	if helper != nil {
+		opts = append(opts, client.WithHost(helper.Host), client.WithDialContext(helper.Dialer))
+	} else {
+		opts = append(opts, client.WithHost(host))
+	}
+
+	if m != nil {
+		opts = append(opts, withEngineMonitor(m))
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// if we can not determine the engine type return the default client,
+	// errors connecting to the engine are reported by the preflight checks
+	ver, err := cli.ServerVersion(context.Background())
+	if err == nil && isPodman(ver) {
+		return NewPodman(cli, utils.IsRootlessPodmanSocket(host)), nil
+	}
+
 	return cli, nil
 }
@@ -5,13 +5,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	gosignal "os/signal"
 	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -25,9 +29,12 @@ import (
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/docker/pkg/signal"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/docker/pkg/term"
 	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
 	"github.com/hashicorp/go-hclog"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/shipyard-run/shipyard/pkg/clients/streams"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/utils"
@@ -48,6 +55,27 @@ type DockerTasks struct {
 	l          hclog.Logger
 	tg         *TarGz
 	force      bool
+	offline    bool
+	portBind   string
+
+	capsOnce sync.Once
+	caps     *EngineCapabilities
+
+	proxyOnce sync.Once
+
+	runner   *RunnerContainer
+	pulls    *ImagePulls
+	fixtures *Fixtures
+}
+
+// ImageNotFoundOfflineError is returned when an image does not exist in the
+// local cache and can not be pulled as the DockerTasks are offline
+type ImageNotFoundOfflineError struct {
+	Image string
+}
+
+func (e ImageNotFoundOfflineError) Error() string {
+	return fmt.Sprintf("Image %s does not exist in the local cache and can not be pulled when running offline, import the image with 'shipyard images import'", e.Image)
 }
 
 // NewDockerTasks creates a DockerTasks with the given Docker client
@@ -74,12 +102,90 @@ func NewDockerTasks(c Docker, il ImageLog, tg *TarGz, l hclog.Logger) *DockerTas
 	return &DockerTasks{EngineType: t, c: c, il: il, tg: tg, l: l}
 }
 
+// Capabilities returns the capabilities of the engine, the engine is only
+// probed once, if the engine can not be probed it is assumed to be running as root
+func (d *DockerTasks) Capabilities() *EngineCapabilities {
+	d.capsOnce.Do(func() {
+		caps, err := ProbeCapabilities(d.c)
+		if err != nil {
+			d.l.Debug("Unable to determine engine capabilities", "error", err)
+			caps = &EngineCapabilities{}
+		}
+
+		d.caps = caps
+	})
+
+	return d.caps
+}
+
+// warnEngineProxy logs a warning when a proxy has been configured for Shipyard but not
+// for the engine, images are pulled by the engine which does not use the proxy settings
+// of Shipyard. The engine is only checked once.
+func (d *DockerTasks) warnEngineProxy() {
+	if !ProxyEnabled() {
+		return
+	}
+
+	d.proxyOnce.Do(func() {
+		info, err := d.c.Info(context.Background())
+		if err != nil {
+			d.l.Debug("Unable to check the proxy settings of the engine", "error", err)
+			return
+		}
+
+		if info.HTTPProxy != "" || info.HTTPSProxy != "" {
+			return
+		}
+
+		d.l.Warn(
+			"A proxy is configured for Shipyard but not for the container engine, image pulls do not use the proxy. Configure the proxy and CA in the Docker daemon or Docker Desktop settings",
+			"http_proxy", proxyEnv("HTTP_PROXY"),
+			"https_proxy", proxyEnv("HTTPS_PROXY"),
+		)
+	})
+}
+
 // SetForcePull sets a global override for the DockerTasks, when set to true
 // Images will always be pulled from remote registries
 func (d *DockerTasks) SetForcePull(force bool) {
 	d.force = force
 }
 
+// SetOffline sets a global override for the DockerTasks, when set to true
+// images are never pulled from remote registries and must exist in the local cache
+func (d *DockerTasks) SetOffline(offline bool) {
+	d.offline = offline
+}
+
+// SetDefaultPortBind sets the host interface ports are bound to when the port
+// does not specify an address, when blank ports are bound to all interfaces
+func (d *DockerTasks) SetDefaultPortBind(ip string) {
+	d.portBind = ip
+}
+
+// SetImagePulls sets the recorder which the timing of image pulls is added to
+func (d *DockerTasks) SetImagePulls(p *ImagePulls) {
+	d.pulls = p
+}
+
+// SetFixtures sets the fixtures the digests of pulled images are recorded to, or
+// replayed from
+func (d *DockerTasks) SetFixtures(f *Fixtures) {
+	d.fixtures = f
+}
+
+// SetRunner sets the container Shipyard is running in, when set the sources of
+// bind mounts are translated to the paths on the engine host
+func (d *DockerTasks) SetRunner(r *RunnerContainer) {
+	d.runner = r
+}
+
+// Runner returns the container Shipyard is running in, nil when
+// Shipyard is not running in a container
+func (d *DockerTasks) Runner() *RunnerContainer {
+	return d.runner
+}
+
 // CreateContainer creates a new Docker container for the given configuation
 func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 	d.l.Debug("Creating Docker Container", "ref", c.Name)
@@ -104,10 +210,40 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	// set the timezone and locale from the blueprint so that all containers and
+	// cluster nodes behave the same on every machine, the container env takes precedence
+	vols := c.Volumes
+	if bp := containerBlueprint(c); bp != nil {
+		for k, v := range bp.LocaleEnv() {
+			if !hasEnv(env, k) {
+				env = append(env, fmt.Sprintf("%s=%s", k, v))
+			}
+		}
+
+		// Windows containers do not use the zone info files
+		if src := localtimeSource(bp.Timezone); src != "" && !d.windowsContainer(c) && !hasVolume(vols, "/etc/localtime") {
+			vols = append(append([]config.Volume{}, vols...), config.Volume{Source: src, Destination: "/etc/localtime", ReadOnly: true})
+		}
+	}
+
+	// copy the host environment variables which have been explicitly allowed,
+	// only the names are logged as the values are often credentials
+	for k, v := range config.PassthroughEnv(containerBlueprint(c), c.EnvPassthrough) {
+		if !hasEnv(env, k) {
+			d.l.Debug("Passing host environment variable to container", "ref", c.Name, "name", k)
+			env = append(env, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+
 	// set the user details
 	var user string
 	if c.RunAs != nil {
-		user = fmt.Sprintf("%s:%s", c.RunAs.User, c.RunAs.Group)
+		user = c.RunAs.User
+
+		// group is optional for compose services which only specify a user
+		if c.RunAs.Group != "" {
+			user = fmt.Sprintf("%s:%s", c.RunAs.User, c.RunAs.Group)
+		}
 	}
 
 	// create the container config
@@ -131,16 +267,62 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		hc.RestartPolicy = container.RestartPolicy{Name: "on-failure", MaximumRetryCount: c.MaxRestartCount}
 	}
 
+	if c.Restart != "" {
+		hc.RestartPolicy = container.RestartPolicy{Name: c.Restart}
+
+		// retry count is only valid for the on-failure policy
+		if c.Restart == "on-failure" {
+			hc.RestartPolicy.MaximumRetryCount = c.MaxRestartCount
+		}
+	}
+
+	hc.CapAdd = c.CapAdd
+	hc.CapDrop = c.CapDrop
+
+	so, err := securityOpts(c.SecurityOpt)
+	if err != nil {
+		return "", err
+	}
+
+	hc.SecurityOpt = so
+
 	// https: //docs.docker.com/config/containers/resource_constraints/#cpu
 	rc := container.Resources{}
 	if c.Resources != nil {
-		// set memory if set
-		if c.Resources.Memory > 0 {
-			rc.Memory = int64(c.Resources.Memory) * 1000000 // docker specifies memory in bytes, shipyard megabytes
+		// set memory if set, docker specifies memory in bytes
+		mem, err := c.Resources.MemoryBytes()
+		if err != nil {
+			return "", err
+		}
+
+		if mem > 0 {
+			rc.Memory = mem
+		}
+
+		// the default cpu period is 100000 microseconds, 1000 millicpu = 1 CPU
+		cpu, err := c.Resources.MilliCPU()
+		if err != nil {
+			return "", err
 		}
 
-		if c.Resources.CPU > 0 {
-			rc.CPUQuota = int64(c.Resources.CPU) * 100
+		if cpu > 0 {
+			rc.CPUQuota = cpu * 100
+		}
+
+		// GPUs are added using device requests which are handled by the driver
+		if gpu := c.Resources.GPU; gpu != nil {
+			dr := container.DeviceRequest{
+				Driver:       gpuDriver(gpu),
+				DeviceIDs:    gpu.DeviceIDs,
+				Capabilities: [][]string{{"gpu"}},
+			}
+
+			// request all the GPUs when no ids are specified
+			if len(gpu.DeviceIDs) == 0 {
+				dr.Count = -1
+			}
+
+			rc.DeviceRequests = []container.DeviceRequest{dr}
 		}
 
 		// cupsets are not supported on windows
@@ -156,6 +338,40 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		hc.Resources = rc
 	}
 
+	// set any ulimits for the container
+	for _, u := range c.Ulimits {
+		hc.Resources.Ulimits = append(hc.Resources.Ulimits, &units.Ulimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+
+	// set namespaced kernel parameters
+	if len(c.Sysctls) > 0 {
+		hc.Sysctls = c.Sysctls
+	}
+
+	// configure name resolution
+	hc.DNS = c.DNS
+	hc.DNSSearch = c.DNSSearch
+
+	for h, ip := range c.ExtraHosts {
+		hc.ExtraHosts = append(hc.ExtraHosts, fmt.Sprintf("%s:%s", h, ip))
+	}
+	sort.Strings(hc.ExtraHosts)
+
+	// add any host devices
+	for _, dv := range c.Devices {
+		dm := container.DeviceMapping{PathOnHost: dv.Source, PathInContainer: dv.Destination, CgroupPermissions: dv.Permissions}
+
+		if dm.PathInContainer == "" {
+			dm.PathInContainer = dv.Source
+		}
+
+		if dm.CgroupPermissions == "" {
+			dm.CgroupPermissions = "rwm"
+		}
+
+		hc.Resources.Devices = append(hc.Resources.Devices, dm)
+	}
+
 	// by default the container should NOT be attached to a network
 	nc.EndpointsConfig = make(map[string]*network.EndpointSettings)
 
@@ -163,7 +379,7 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 	mounts := make([]mount.Mount, 0)
 	volumes := []string{}
 
-	for _, vc := range c.Volumes {
+	for _, vc := range vols {
 		// default mount type to bind
 		t := mount.TypeBind
 
@@ -181,6 +397,8 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		switch vc.BindPropagation {
 		case "shared":
 			bp = mount.PropagationShared
+		case "rshared":
+			bp = mount.PropagationRShared
 		case "slave":
 			bp = mount.PropagationSlave
 		case "private":
@@ -194,6 +412,9 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		// if we have a bind type mount then ensure that the local folder exists or
 		// an error will be raised when creating
 		if t == mount.TypeBind {
+			// rootless engines do not use the default location for the Docker socket
+			vc.Source = d.rootlessSocket(vc.Source)
+
 			// check to see id the source exists
 			_, err := os.Stat(vc.Source)
 			if err != nil {
@@ -204,6 +425,15 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 					return "", xerrors.Errorf("Source for Volume %s does not exist, error creating directory: %w", err)
 				}
 			}
+
+			// when running in a container the engine resolves the source on its host
+			vc.Source = filepath.FromSlash(d.hostPath(vc.Source))
+		}
+
+		// Windows containers use Windows paths, destinations are commonly written
+		// with forward slashes in the config e.g. C:/data
+		if d.windowsContainer(c) {
+			vc.Destination = windowsPath(vc.Destination)
 		}
 
 		var bindOptions *mount.BindOptions
@@ -211,6 +441,20 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 			bindOptions = &mount.BindOptions{Propagation: bp, NonRecursive: vc.BindPropagationNonRecursive}
 		}
 
+		// external volumes are not managed by Shipyard and must exist before the container
+		// is created, otherwise Docker would silently create an empty volume
+		if t == mount.TypeVolume && vc.External {
+			err := d.checkVolumeExists(vc.Source)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		// tmpfs mounts do not have a source
+		if t == mount.TypeTmpfs {
+			vc.Source = ""
+		}
+
 		// Volumes in podman are mounted read only by default, we need to add the :z parameter to
 		// ensure that the correct selinux flags are set so that we can write to these volumes
 		if t == mount.TypeVolume {
@@ -237,12 +481,12 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 	hc.Binds = volumes
 
 	// create the ports config
-	ports := createPublishedPorts(c.Ports)
+	ports := createPublishedPorts(c.Ports, d.portBind)
 	dc.ExposedPorts = ports.ExposedPorts
 	hc.PortBindings = ports.PortBindings
 
 	// create the port ranges
-	portRanges, err := createPublishedPortRanges(c.PortRanges)
+	portRanges, err := createPublishedPortRanges(c.PortRanges, d.portBind)
 	if err != nil {
 		return "", xerrors.Errorf("Unable to attach to container network, invalid port range: %w", err)
 	}
@@ -258,6 +502,11 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 	// is this a priviledged container
 	hc.Privileged = c.Privileged
 
+	// allow sidecars to join the IPC namespace
+	if c.ShareableIPC {
+		hc.IpcMode = container.IpcMode("shareable")
+	}
+
 	// are we attaching the container to a sidecar network?
 	for _, n := range c.Networks {
 		net, err := c.FindDependentResource(n.Name)
@@ -282,7 +531,31 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 			hc.NetworkMode = container.NetworkMode(fmt.Sprintf("container:%s", ids[0]))
 			// when using container networking can not use a hostname
 			dc.Hostname = ""
+
+			if c.SharePID {
+				hc.PidMode = container.PidMode(fmt.Sprintf("container:%s", ids[0]))
+			}
+
+			if c.ShareIPC {
+				hc.IpcMode = container.IpcMode(fmt.Sprintf("container:%s", ids[0]))
+			}
+		}
+	}
+
+	err = d.applyCapabilities(c, hc)
+	if err != nil {
+		return "", err
+	}
+
+	// select the platform when the image is multi-arch
+	var platform *specs.Platform
+	if c.Image != nil && c.Image.Platform != "" {
+		pos, arch, variant, err := c.Image.PlatformParts()
+		if err != nil {
+			return "", err
 		}
+
+		platform = &specs.Platform{OS: pos, Architecture: arch, Variant: variant}
 	}
 
 	cont, err := d.c.ContainerCreate(
@@ -290,7 +563,7 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		dc,
 		hc,
 		nc,
-		nil,
+		platform,
 		utils.FQDN(c.Name, string(c.Type)),
 	)
 	if err != nil {
@@ -324,6 +597,17 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 				return "", xerrors.Errorf("Network not found: %w", err)
 			}
 
+			// check the static ip is in the networks subnet before connecting, Docker
+			// returns an unhelpful error when the address is invalid
+			if nc, ok := net.(*config.Network); ok && n.IPAddress != "" && !nc.Contains(n.IPAddress) {
+				errRemove := d.RemoveContainer(cont.ID, false)
+				if errRemove != nil {
+					return "", xerrors.Errorf("Unable to connect container to network %s, unable to roll back container: %w", n.Name, errRemove)
+				}
+
+				return "", xerrors.Errorf("Unable to connect container to network %s: ip_address %s is not in the subnet %s", n.Name, n.IPAddress, nc.Subnet)
+			}
+
 			err = d.AttachNetwork(net.Info().Name, cont.ID, n.Aliases, n.IPAddress)
 
 			if err != nil {
@@ -349,6 +633,19 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		}
 	}
 
+	// the trust stores are updated before starting so the CAs are available to the entrypoint
+	if bp := containerBlueprint(c); bp != nil && len(bp.TrustedCAFiles) > 0 {
+		err = d.addTrustedCAs(cont.ID, bp.TrustedCAFiles)
+		if err != nil {
+			errRemove := d.RemoveContainer(cont.ID, false)
+			if errRemove != nil {
+				return "", xerrors.Errorf("Unable to add trusted CAs to container, unable to roll back container: %w", err)
+			}
+
+			return "", xerrors.Errorf("Unable to add trusted CAs to container: %w", err)
+		}
+	}
+
 	err = d.c.ContainerStart(context.Background(), cont.ID, types.ContainerStartOptions{})
 	if err != nil {
 		return "", err
@@ -374,11 +671,59 @@ func (d *DockerTasks) PullImage(image config.Image, force bool) error {
 		return nil
 	}
 
+	if d.fixtures.Replaying() {
+		return d.pullFixtureImage(image, force)
+	}
+
+	err := d.pullImage(image, force)
+	if err != nil {
+		return err
+	}
+
+	if d.fixtures.Recording() {
+		ii, _, err := d.c.ImageInspectWithRaw(context.Background(), makeImageCanonical(image.Name))
+		if err != nil {
+			return xerrors.Errorf("unable to inspect image %s: %w", image.Name, err)
+		}
+
+		return d.fixtures.RecordImage(image.Name, ii.RepoDigests)
+	}
+
+	return nil
+}
+
+// pullFixtureImage pulls the digest recorded in the fixtures for the image and tags it
+// with the image name, the tag is never resolved against the registry
+func (d *DockerTasks) pullFixtureImage(image config.Image, force bool) error {
+	ref, err := d.fixtures.ImageDigest(image.Name)
+	if err != nil {
+		return err
+	}
+
+	d.l.Debug("Using image digest from fixtures", "image", image.Name, "digest", ref)
+
+	fi := image
+	fi.Name = ref
+
+	err = d.pullImage(fi, force)
+	if err != nil {
+		return err
+	}
+
+	err = d.c.ImageTag(context.Background(), ref, makeImageCanonical(image.Name))
+	if err != nil {
+		return xerrors.Errorf("unable to tag image %s as %s: %w", ref, image.Name, err)
+	}
+
+	return nil
+}
+
+func (d *DockerTasks) pullImage(image config.Image, force bool) error {
 	in := makeImageCanonical(image.Name)
 
 	// only pull if image is not in current registry so check to see if the image is present
-	// if force then skil this check
-	if !force && !d.force {
+	// if force then skil this check, unless offline as the image can not be pulled
+	if (!force && !d.force) || d.offline {
 		args := filters.NewArgs()
 		args.Add("reference", image.Name)
 
@@ -388,7 +733,7 @@ func (d *DockerTasks) PullImage(image config.Image, force bool) error {
 		}
 
 		// we have images do not pull
-		if len(sum) > 0 {
+		if len(sum) > 0 && d.imageMatchesPlatform(sum[0].ID, image) {
 			d.l.Debug("Image exists in local cache", "image", image.Name)
 
 			return nil
@@ -404,24 +749,62 @@ func (d *DockerTasks) PullImage(image config.Image, force bool) error {
 		}
 
 		// we have images do not pull
-		if len(sum) > 0 {
+		if len(sum) > 0 && d.imageMatchesPlatform(sum[0].ID, image) {
 			d.l.Debug("Image exists in local cache", "image", image.Name)
 
 			return nil
 		}
 	}
 
-	ipo := types.ImagePullOptions{}
+	if d.offline {
+		return ImageNotFoundOfflineError{Image: image.Name}
+	}
 
-	// if the username and password is not null make an authenticated
+	ipo := types.ImagePullOptions{Platform: image.Platform}
+
+	// if credentials can be found for the registry make an authenticated
 	// image pull
-	if image.Username != "" && image.Password != "" {
-		ipo.RegistryAuth = createRegistryAuth(image.Username, image.Password)
+	if rc := LookupRegistryCredentials(image); rc != nil {
+		d.l.Debug("Using credentials for registry", "image", in, "registry", rc.Registry, "username", rc.Username)
+		ipo.RegistryAuth = createRegistryAuth(rc.Username, rc.Password)
+	}
+
+	d.warnEngineProxy()
+
+	d.l.Debug("Pulling image", "image", in, "platform", image.Platform)
+
+	start := time.Now()
+	var size int64
+
+	// the timeout applies to all the attempts to pull the image
+	ctx := context.Background()
+	if image.PullTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, image.PullTimeout)
+		defer cancel()
+	}
+
+	attempts, err := retryPull(ctx, in, pullRetryPolicy(image.PullRetry), d.l, func() error {
+		out, err := d.c.ImagePull(ctx, in, ipo)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		p := newPullProgress(in, d.pulls, d.l)
+		err = p.Render(out)
+		size = p.Bytes()
+
+		return err
+	})
+
+	rec := ImagePullRecord{Image: in, Duration: time.Since(start), Attempts: attempts, Bytes: size}
+	if err != nil {
+		rec.Error = err.Error()
 	}
 
-	d.l.Debug("Pulling image", "image", in)
+	d.pulls.Record(rec)
 
-	out, err := d.c.ImagePull(context.Background(), in, ipo)
 	if err != nil {
 		return xerrors.Errorf("Error pulling image: %w", err)
 	}
@@ -432,113 +815,602 @@ func (d *DockerTasks) PullImage(image config.Image, force bool) error {
 		d.l.Error("Unable to add image name to cache", "error", err)
 	}
 
-	// write the output to the debug log
-	io.Copy(d.l.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}), out)
+	return nil
+}
+
+// PushImage tags the local image with the name of the target and pushes it
+// to the targets registry
+func (d *DockerTasks) PushImage(image string, target config.Image) error {
+	in := makeImageCanonical(image)
+
+	d.l.Debug("Tagging image", "image", in, "target", target.Name)
+
+	err := d.c.ImageTag(context.Background(), in, target.Name)
+	if err != nil {
+		return xerrors.Errorf("Error tagging image: %w", err)
+	}
+
+	// the Docker engine requires the auth to be set even when the
+	// registry does not require authentication
+	ipo := types.ImagePushOptions{RegistryAuth: createRegistryAuth("", "")}
+	if rc := LookupRegistryCredentials(target); rc != nil {
+		d.l.Debug("Using credentials for registry", "image", target.Name, "registry", rc.Registry, "username", rc.Username)
+		ipo.RegistryAuth = createRegistryAuth(rc.Username, rc.Password)
+	}
+
+	d.l.Debug("Pushing image", "image", target.Name)
+
+	out, err := d.c.ImagePush(context.Background(), target.Name, ipo)
+	if err != nil {
+		return xerrors.Errorf("Error pushing image: %w", err)
+	}
+	defer out.Close()
+
+	// the push only fails once the progress has been read
+	err = jsonmessage.DisplayJSONMessagesStream(out, d.l.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}), 0, false, nil)
+	if err != nil {
+		return xerrors.Errorf("Error pushing image: %w", err)
+	}
+
+	return nil
+}
+
+// ExportImages writes the images from the local cache to the writer as a tar archive
+func (d *DockerTasks) ExportImages(images []string, w io.Writer) error {
+	d.l.Debug("Exporting images", "images", images)
+
+	canonical := []string{}
+	for _, i := range images {
+		canonical = append(canonical, makeImageCanonical(i))
+	}
+
+	rc, err := d.c.ImageSave(context.Background(), canonical)
+	if err != nil {
+		return xerrors.Errorf("unable to save images %v: %w", images, err)
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(w, rc)
+	if err != nil {
+		return xerrors.Errorf("unable to write image archive: %w", err)
+	}
+
+	return nil
+}
+
+// FindImageID returns the id of the image with the given name in the local cache
+func (d *DockerTasks) FindImageID(name string) (string, error) {
+	ii, _, err := d.c.ImageInspectWithRaw(context.Background(), name)
+	if err != nil {
+		return "", xerrors.Errorf("unable to inspect image %s: %w", name, err)
+	}
+
+	return ii.ID, nil
+}
+
+// ImportImages loads the images in the tar archive into the local cache
+func (d *DockerTasks) ImportImages(r io.Reader) ([]string, error) {
+	resp, err := d.c.ImageLoad(context.Background(), r, true)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to load images: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// the response is a stream of json messages containing the names of
+	// the loaded images e.g. {"stream":"Loaded image: consul:1.10.0\n"}
+	images := []string{}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var m jsonmessage.JSONMessage
+		err := dec.Decode(&m)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, xerrors.Errorf("unable to read output from image load: %w", err)
+		}
+
+		if m.Error != nil {
+			return nil, xerrors.Errorf("unable to load images: %s", m.Error.Message)
+		}
+
+		for _, prefix := range []string{"Loaded image: ", "Loaded image ID: "} {
+			if strings.HasPrefix(m.Stream, prefix) {
+				images = append(images, strings.TrimSpace(strings.TrimPrefix(m.Stream, prefix)))
+			}
+		}
+	}
+
+	for _, i := range images {
+		err := d.il.Log(i, ImageTypeDocker)
+		if err != nil {
+			d.l.Error("Unable to add image name to cache", "error", err)
+		}
+	}
+
+	return images, nil
+}
+
+// imageMatchesPlatform returns true when the local image was built for the platform
+// set in the image config, images which do not specify a platform always match
+func (d *DockerTasks) imageMatchesPlatform(id string, image config.Image) bool {
+	if image.Platform == "" {
+		return true
+	}
+
+	pos, arch, variant, err := image.PlatformParts()
+	if err != nil {
+		return true
+	}
+
+	ii, _, err := d.c.ImageInspectWithRaw(context.Background(), id)
+	if err != nil {
+		d.l.Debug("Unable to inspect image, image will be pulled", "image", image.Name, "error", err)
+		return false
+	}
+
+	if ii.Os != pos || (arch != "" && ii.Architecture != arch) || (variant != "" && ii.Variant != variant) {
+		d.l.Debug("Image in local cache does not match platform", "image", image.Name, "platform", image.Platform, "os", ii.Os, "arch", ii.Architecture)
+		return false
+	}
+
+	return true
+}
+
+// FindContainerIDs returns the Container IDs for the given identifier
+func (d *DockerTasks) FindContainerIDs(containerName string, typeName config.ResourceType) ([]string, error) {
+	fullName := utils.FQDN(containerName, string(typeName))
+
+	args := filters.NewArgs()
+	// By default Docker will wildcard searches, use regex to return the absolute
+	args.Add("name", fmt.Sprintf("^%s$", fullName))
+
+	opts := types.ContainerListOptions{Filters: args, All: true}
+
+	cl, err := d.c.ContainerList(context.Background(), opts)
+	if err != nil || cl == nil {
+		return nil, err
+	}
+
+	if len(cl) > 0 {
+		ids := []string{}
+		for _, c := range cl {
+			ids = append(ids, c.ID)
+		}
+
+		return ids, nil
+	}
+
+	return nil, nil
+}
+
+// RemoveContainer with the given id
+func (d *DockerTasks) RemoveContainer(id string, force bool) error {
+	var err error
+	if !force {
+		// try and shutdown graceful
+		timeout := 30 * time.Second
+		err = d.c.ContainerStop(context.Background(), id, &timeout)
+		if err == nil {
+			d.l.Debug("Container stopped gracefully, removing", "container", id)
+			err = d.c.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: false, RemoveVolumes: true})
+			if err == nil {
+				return nil
+			}
+		}
+
+		d.l.Debug("Unable to stop container gracefully, trying force", "container", id, "error", err)
+	}
+
+	// unable to shutdown graceful try force
+	d.l.Debug("Forcefully remove", "container", id)
+	return d.c.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
+}
+
+// WaitForContainer blocks until the container exits and returns the exit code
+func (d *DockerTasks) WaitForContainer(id string, timeout time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	wc, ec := d.c.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+
+	select {
+	case w := <-wc:
+		if w.Error != nil {
+			return w.StatusCode, fmt.Errorf("Error waiting for container: %s", w.Error.Message)
+		}
+
+		return w.StatusCode, nil
+	case err := <-ec:
+		if ctx.Err() == context.DeadlineExceeded {
+			return -1, fmt.Errorf("Timeout waiting for container to exit after %s", timeout)
+		}
+
+		return -1, err
+	}
+}
+
+// CommitContainer creates an image from the filesystem of the container, the container
+// is paused while the image is created so that the filesystem is consistent
+func (d *DockerTasks) CommitContainer(id, image string) error {
+	image = makeImageCanonical(image)
+
+	d.l.Debug("Committing container", "container", id, "image", image)
+
+	_, err := d.c.ContainerCommit(context.Background(), id, types.ContainerCommitOptions{Reference: image, Pause: true})
+	if err != nil {
+		return xerrors.Errorf("unable to commit container %s to image %s: %w", id, image, err)
+	}
+
+	return nil
+}
+
+func (d *DockerTasks) BuildContainer(config *config.Container, force bool) (string, error) {
+	imageName := fmt.Sprintf("shipyard.run/localcache/%s:%s", config.Name, config.Build.Tag)
+	imageName = makeImageCanonical(imageName)
+
+	args := filters.NewArgs()
+	args.Add("reference", imageName)
+
+	// check if the image already exists, if so do not rebuild unless force
+	if !force && !d.force {
+		sum, err := d.c.ImageList(context.Background(), types.ImageListOptions{Filters: args})
+		if err != nil {
+			return "", xerrors.Errorf("unable to list images in local Docker cache: %w", err)
+		}
+
+		// if we have images do not pull
+		if len(sum) > 0 {
+			d.l.Debug("Image exists in local cache, skip build", "image", imageName)
+
+			return imageName, nil
+		}
+	}
+
+	// if the Dockerfile is not set, set to default
+	if config.Build.File == "" {
+		config.Build.File = "./Dockerfile"
+	}
+
+	// secrets and ssh forwarding require a BuildKit session which is
+	// provided by the Docker CLI
+	if config.Build.UsesBuildKit() {
+		err := d.buildWithBuildKit(imageName, config.Build)
+		if err != nil {
+			return "", xerrors.Errorf("unable to build image using BuildKit: %w", err)
+		}
+
+		return imageName, nil
+	}
+
+	// tar the build context folder and send to the server
+	buildOpts := types.ImageBuildOptions{
+		Dockerfile: config.Build.File,
+		Tags:       []string{imageName},
+		Target:     config.Build.Target,
+		CacheFrom:  config.Build.CacheFrom,
+	}
+
+	if len(config.Build.Args) > 0 {
+		buildOpts.BuildArgs = map[string]*string{}
+		for k, v := range config.Build.Args {
+			// take a copy as the build args are a map of pointers
+			arg := v
+			buildOpts.BuildArgs[k] = &arg
+		}
+	}
+
+	var buf bytes.Buffer
+	d.tg.Compress(&buf, &TarGzOptions{OmitRoot: true}, config.Build.Context)
+
+	resp, err := d.c.ImageBuild(context.Background(), &buf, buildOpts)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	out := d.l.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug})
+	termFd, _ := term.GetFdInfo(out)
+	err = jsonmessage.DisplayJSONMessagesStream(resp.Body, out, termFd, false, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return imageName, nil
+}
+
+// gpuDriver returns the driver for the GPU, defaults to nvidia
+func gpuDriver(g *config.GPU) string {
+	if g.Driver == "" {
+		return "nvidia"
+	}
+
+	return g.Driver
+}
+
+// zoneInfoDir is the location of the timezone database on the host
+var zoneInfoDir = "/usr/share/zoneinfo"
+
+// containerBlueprint returns the blueprint for the config the container belongs to
+func containerBlueprint(c *config.Container) *config.Blueprint {
+	if c.Config == nil {
+		return nil
+	}
+
+	return c.Config.Blueprint
+}
+
+// localtimeSource returns the zone info file on the host for the timezone which can
+// be mounted to /etc/localtime, only Linux hosts share the zone info with the engine
+func localtimeSource(timezone string) string {
+	if timezone == "" || runtime.GOOS != "linux" {
+		return ""
+	}
+
+	p := filepath.Join(zoneInfoDir, filepath.Clean("/"+timezone))
+
+	fi, err := os.Stat(p)
+	if err != nil || fi.IsDir() {
+		return ""
+	}
+
+	return p
+}
+
+func hasEnv(env []string, key string) bool {
+	for _, e := range env {
+		if strings.HasPrefix(e, key+"=") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasVolume(volumes []config.Volume, destination string) bool {
+	for _, v := range volumes {
+		if v.Destination == destination {
+			return true
+		}
+	}
+
+	return false
+}
+
+// windowsContainer returns true when the container is created from a Windows image
+// or the engine is running Windows containers
+func (d *DockerTasks) windowsContainer(c *config.Container) bool {
+	if c.Image != nil && c.Image.IsWindows() {
+		return true
+	}
+
+	return d.Capabilities().WindowsContainers()
+}
+
+// windowsPath converts the path to a Windows path, the engine may be running on a
+// different host to Shipyard so filepath.FromSlash can not be used
+func windowsPath(p string) string {
+	return strings.ReplaceAll(p, "/", `\`)
+}
+
+// applyCapabilities adjusts the host config for engines which are not running as root,
+// when the container can not be created an error explaining the limitation is returned
+func (d *DockerTasks) applyCapabilities(c *config.Container, hc *container.HostConfig) error {
+	caps := d.Capabilities()
+
+	if c.Resources != nil && c.Resources.GPU != nil {
+		driver := gpuDriver(c.Resources.GPU)
+		if !caps.HasRuntime(driver) {
+			return fmt.Errorf("unable to create container %s with GPU, the %s container runtime is not installed, see https://docs.nvidia.com/datacenter/cloud-native/container-toolkit/install-guide.html", c.Name, driver)
+		}
+	}
+
+	if c.Privileged && caps.WindowsContainers() {
+		return fmt.Errorf("unable to create privileged container %s, the engine is running Windows containers, privileged containers such as k3s and Nomad clusters require Linux containers", c.Name)
+	}
+
+	if c.Privileged && !caps.PrivilegedContainers() {
+		return fmt.Errorf("unable to create privileged container %s, rootless Docker requires cgroup v2 to run privileged containers such as k3s and Nomad clusters", c.Name)
+	}
+
+	// privileged containers can not be used with user namespaces,
+	// run the container in the host user namespace
+	if c.Privileged && caps.UserNamespaces {
+		hc.UsernsMode = "host"
+	}
+
+	if !caps.PrivilegedPorts() {
+		for _, bindings := range hc.PortBindings {
+			for _, b := range bindings {
+				port, _ := strconv.Atoi(b.HostPort)
+				if port > 0 && port < 1024 {
+					return fmt.Errorf(
+						"unable to bind container %s to the privileged port %d, rootless Docker can only bind ports below 1024 when the kernel parameter net.ipv4.ip_unprivileged_port_start=%d is set",
+						c.Name,
+						port,
+						port,
+					)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// securityOpts returns the security options for the container, the Docker API expects
+// the contents of a seccomp profile rather than the path so any profiles are read from disk
+func securityOpts(opts []string) ([]string, error) {
+	var out []string
+
+	for _, o := range opts {
+		if strings.HasPrefix(o, "seccomp=") && o != "seccomp=unconfined" {
+			f := strings.TrimPrefix(o, "seccomp=")
+
+			d, err := ioutil.ReadFile(f)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to read seccomp profile %s: %w", f, err)
+			}
+
+			o = "seccomp=" + string(d)
+		}
+
+		out = append(out, o)
+	}
+
+	return out, nil
+}
+
+// rootlessSocket returns the location of the engine socket when the source of a volume
+// is the default Docker socket and the engine is rootless, rootless engines do not use
+// the default location. In all other cases the source is returned
+func (d *DockerTasks) rootlessSocket(source string) string {
+	if source != "/var/run/docker.sock" || !d.Capabilities().Rootless {
+		return source
+	}
+
+	socket := strings.TrimPrefix(utils.GetDockerHost(), "unix://")
+	if !strings.HasPrefix(socket, "/") {
+		return source
+	}
+
+	return socket
+}
+
+// hostPath returns the location on the engine host for the source of a bind mount
+// when Shipyard is running in a container which uses the engine of the host
+func (d *DockerTasks) hostPath(source string) string {
+	if d.runner == nil {
+		return source
+	}
+
+	p, ok := d.runner.HostPath(source)
+	if !ok {
+		d.l.Warn("Volume source is not in a volume mounted into the runner container, the source must exist on the engine host", "source", source)
+	}
+
+	return p
+}
+
+// dockerCommand creates the command used to execute the Docker CLI,
+// it is replaced in tests
+var dockerCommand = exec.Command
+
+// buildWithBuildKit builds the image using the Docker CLI with BuildKit enabled
+func (d *DockerTasks) buildWithBuildKit(imageName string, b *config.Build) error {
+	args, cleanup, err := buildKitArgs(imageName, b)
+	defer cleanup()
+
+	if err != nil {
+		return err
+	}
+
+	d.l.Debug("Building image with BuildKit", "image", imageName, "args", args)
+
+	out := d.l.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug})
 
-	return nil
+	cmd := dockerCommand("docker", args...)
+	cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	return cmd.Run()
 }
 
-// FindContainerIDs returns the Container IDs for the given identifier
-func (d *DockerTasks) FindContainerIDs(containerName string, typeName config.ResourceType) ([]string, error) {
-	fullName := utils.FQDN(containerName, string(typeName))
+// buildKitArgs returns the arguments for the Docker CLI to build the image.
+// Secrets which are read from environment variables are written to temporary
+// files, the returned cleanup function removes these files and must always be called.
+func buildKitArgs(imageName string, b *config.Build) ([]string, func(), error) {
+	tmpFiles := []string{}
+	cleanup := func() {
+		for _, f := range tmpFiles {
+			os.Remove(f)
+		}
+	}
 
-	args := filters.NewArgs()
-	// By default Docker will wildcard searches, use regex to return the absolute
-	args.Add("name", fmt.Sprintf("^%s$", fullName))
+	dockerfile := b.File
+	if !filepath.IsAbs(dockerfile) {
+		dockerfile = filepath.Join(b.Context, dockerfile)
+	}
 
-	opts := types.ContainerListOptions{Filters: args, All: true}
+	args := []string{"build", "--progress", "plain", "--file", dockerfile, "--tag", imageName}
 
-	cl, err := d.c.ContainerList(context.Background(), opts)
-	if err != nil || cl == nil {
-		return nil, err
+	if b.Target != "" {
+		args = append(args, "--target", b.Target)
 	}
 
-	if len(cl) > 0 {
-		ids := []string{}
-		for _, c := range cl {
-			ids = append(ids, c.ID)
-		}
-
-		return ids, nil
+	for _, c := range b.CacheFrom {
+		args = append(args, "--cache-from", c)
 	}
 
-	return nil, nil
-}
+	if b.InlineCache {
+		args = append(args, "--build-arg", "BUILDKIT_INLINE_CACHE=1")
+	}
 
-// RemoveContainer with the given id
-func (d *DockerTasks) RemoveContainer(id string, force bool) error {
-	var err error
-	if !force {
-		// try and shutdown graceful
-		timeout := 30 * time.Second
-		err = d.c.ContainerStop(context.Background(), id, &timeout)
-		if err == nil {
-			d.l.Debug("Container stopped gracefully, removing", "container", id)
-			err = d.c.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: false, RemoveVolumes: true})
-			if err == nil {
-				return nil
-			}
-		}
+	// sort the build args so the command is always the same
+	keys := []string{}
+	for k := range b.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-		d.l.Debug("Unable to stop container gracefully, trying force", "container", id, "error", err)
+	for _, k := range keys {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", k, b.Args[k]))
 	}
 
-	// unable to shutdown graceful try force
-	d.l.Debug("Forcefully remove", "container", id)
-	return d.c.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
-}
+	for _, s := range b.Secrets {
+		src := s.File
 
-func (d *DockerTasks) BuildContainer(config *config.Container, force bool) (string, error) {
-	imageName := fmt.Sprintf("shipyard.run/localcache/%s:%s", config.Name, config.Build.Tag)
-	imageName = makeImageCanonical(imageName)
+		if s.Environment != "" {
+			f, err := ioutil.TempFile("", "secret")
+			if err != nil {
+				return nil, cleanup, xerrors.Errorf("unable to create temporary file for secret %s: %w", s.ID, err)
+			}
 
-	args := filters.NewArgs()
-	args.Add("reference", imageName)
+			tmpFiles = append(tmpFiles, f.Name())
 
-	// check if the image already exists, if so do not rebuild unless force
-	if !force && !d.force {
-		sum, err := d.c.ImageList(context.Background(), types.ImageListOptions{Filters: args})
-		if err != nil {
-			return "", xerrors.Errorf("unable to list images in local Docker cache: %w", err)
-		}
+			f.WriteString(os.Getenv(s.Environment))
+			f.Close()
 
-		// if we have images do not pull
-		if len(sum) > 0 {
-			d.l.Debug("Image exists in local cache, skip build", "image", imageName)
+			src = f.Name()
+		}
 
-			return imageName, nil
+		if src == "" {
+			return nil, cleanup, fmt.Errorf("secret %s must specify either a file or an environment variable", s.ID)
 		}
-	}
 
-	// if the Dockerfile is not set, set to default
-	if config.Build.File == "" {
-		config.Build.File = "./Dockerfile"
+		args = append(args, "--secret", fmt.Sprintf("id=%s,src=%s", s.ID, src))
 	}
 
-	// tar the build context folder and send to the server
-	buildOpts := types.ImageBuildOptions{
-		Dockerfile: config.Build.File,
-		Tags:       []string{imageName},
+	for _, s := range b.SSH {
+		args = append(args, "--ssh", s)
 	}
 
-	var buf bytes.Buffer
-	d.tg.Compress(&buf, &TarGzOptions{OmitRoot: true}, config.Build.Context)
+	args = append(args, b.Context)
 
-	resp, err := d.c.ImageBuild(context.Background(), &buf, buildOpts)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+	return args, cleanup, nil
+}
 
-	out := d.l.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug})
-	termFd, _ := term.GetFdInfo(out)
-	err = jsonmessage.DisplayJSONMessagesStream(resp.Body, out, termFd, false, nil)
+// checkVolumeExists returns an error when the named Docker volume does not exist
+func (d *DockerTasks) checkVolumeExists(name string) error {
+	args := filters.NewArgs()
+	args.Add("name", name)
 
+	ops, err := d.c.VolumeList(context.Background(), args)
 	if err != nil {
-		return "", err
+		return xerrors.Errorf("Unable to lookup external volume %s: %w", name, err)
 	}
 
-	return imageName, nil
+	// Docker wildcards the name filter so check for an exact match
+	for _, v := range ops.Volumes {
+		if v != nil && v.Name == name {
+			return nil
+		}
+	}
+
+	return xerrors.Errorf("External volume %s does not exist, external volumes must be created before they can be used", name)
 }
 
 // CreateVolume creates a Docker volume for a cluster
@@ -689,13 +1561,13 @@ func (d *DockerTasks) CopyLocalDockerImagesToVolume(images []string, volume stri
 	return d.CopyFilesToVolume(volume, savedImages, "/images", force)
 }
 
-// CopyFileToVolume copies a file to a Docker volume
-// returns the names of the stored files
-func (d *DockerTasks) CopyFilesToVolume(volumeID string, filenames []string, path string, force bool) ([]string, error) {
+// createVolumeContainer creates a temporary container with the volume mounted at /cache
+// which is used to copy files to the volume, the caller must remove the container
+func (d *DockerTasks) createVolumeContainer(volumeID string) (string, error) {
 	// make sure we have the alpine image needed to copy
 	err := d.PullImage(config.Image{Name: "alpine:latest"}, false)
 	if err != nil {
-		return nil, xerrors.Errorf("Unable pull alpine:latest for importing images: %w", err)
+		return "", xerrors.Errorf("Unable pull alpine:latest for importing images: %w", err)
 	}
 
 	// create a dummy container to import to volume
@@ -715,9 +1587,8 @@ func (d *DockerTasks) CopyFilesToVolume(volumeID string, filenames []string, pat
 
 	tmpID, err := d.CreateContainer(cc)
 	if err != nil {
-		return nil, xerrors.Errorf("Unable to create dummy container for importing files: %w", err)
+		return "", xerrors.Errorf("Unable to create dummy container for importing files: %w", err)
 	}
-	defer d.RemoveContainer(tmpID, true)
 
 	// wait for container to start
 	successCount := 0
@@ -752,12 +1623,25 @@ func (d *DockerTasks) CopyFilesToVolume(volumeID string, filenames []string, pat
 			d.l.Error("Timeout waiting for container to start", "ref", tmpID, "error", err)
 			startError = fmt.Errorf("timeout waiting for container to start: %s", startError)
 
-			return nil, startError
+			d.RemoveContainer(tmpID, true)
+			return "", startError
 		}
 
 		time.Sleep(1 * time.Second)
 	}
 
+	return tmpID, nil
+}
+
+// CopyFileToVolume copies a file to a Docker volume
+// returns the names of the stored files
+func (d *DockerTasks) CopyFilesToVolume(volumeID string, filenames []string, path string, force bool) ([]string, error) {
+	tmpID, err := d.createVolumeContainer(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer d.RemoveContainer(tmpID, true)
+
 	// create the directory paths ensure unix paths for containers
 	destPath := filepath.ToSlash(filepath.Join("/cache", path))
 	err = d.ExecuteCommand(tmpID, []string{"mkdir", "-p", destPath}, nil, "/", "", "", nil)
@@ -783,7 +1667,7 @@ func (d *DockerTasks) CopyFilesToVolume(volumeID string, filenames []string, pat
 			}
 		}
 
-		err = d.CopyFileToContainer(utils.FQDN(cc.Name, string(cc.Type)), f, destPath)
+		err = d.CopyFileToContainer(tmpID, f, destPath)
 		if err != nil {
 			return nil, fmt.Errorf("Unable to copy file %s to container: %s", f, err)
 		}
@@ -851,6 +1735,175 @@ func (d *DockerTasks) CopyFileToContainer(containerID, filename, path string) er
 	return nil
 }
 
+// CopyPathToContainer copies the file or the contents of the directory at src to the
+// directory dst in the container, dst is created when it does not exist. Files are owned
+// by root, when mode is not zero the permissions of the copied files are set to mode
+func (d *DockerTasks) CopyPathToContainer(containerID, src, dst string, mode os.FileMode) error {
+	tmpTarFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		return xerrors.Errorf("unable to create temporary file: %w for tar achive", err)
+	}
+
+	defer func() {
+		tmpTarFile.Close()
+		os.Remove(tmpTarFile.Name())
+	}()
+
+	err = tarPath(tmpTarFile, src, dst, mode)
+	if err != nil {
+		return xerrors.Errorf("unable to create tar archive for %s: %w", src, err)
+	}
+
+	tmpTarFile.Seek(0, 0)
+
+	// the names in the archive include the destination so the parent
+	// directories are created when the archive is extracted at the root
+	err = d.c.CopyToContainer(context.Background(), containerID, "/", tmpTarFile, types.CopyToContainerOptions{})
+	if err != nil {
+		return xerrors.Errorf("unable to copy %s to container: %w", src, err)
+	}
+
+	return nil
+}
+
+// CopyPathToVolume copies the file or the contents of the directory at
+// src to the directory dst in the Docker volume
+func (d *DockerTasks) CopyPathToVolume(volumeID, src, dst string, mode os.FileMode) error {
+	tmpID, err := d.createVolumeContainer(volumeID)
+	if err != nil {
+		return err
+	}
+	defer d.RemoveContainer(tmpID, true)
+
+	return d.CopyPathToContainer(tmpID, src, path.Join("/cache", filepath.ToSlash(dst)), mode)
+}
+
+// ExportVolume writes the contents of the Docker volume to the writer as a tar archive,
+// the names in the archive are relative to the root of the volume
+func (d *DockerTasks) ExportVolume(volume string, w io.Writer) error {
+	d.l.Debug("Exporting volume", "volume", volume)
+
+	tmpID, err := d.createVolumeContainer(volume)
+	if err != nil {
+		return err
+	}
+	defer d.RemoveContainer(tmpID, true)
+
+	rc, _, err := d.c.CopyFromContainer(context.Background(), tmpID, "/cache/.")
+	if err != nil {
+		return xerrors.Errorf("unable to export volume %s: %w", volume, err)
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(w, rc)
+	if err != nil {
+		return xerrors.Errorf("unable to write volume archive: %w", err)
+	}
+
+	return nil
+}
+
+// ImportVolume removes the contents of the Docker volume and extracts the tar archive
+// created by ExportVolume to the volume
+func (d *DockerTasks) ImportVolume(volume string, r io.Reader) error {
+	d.l.Debug("Importing volume", "volume", volume)
+
+	tmpID, err := d.createVolumeContainer(volume)
+	if err != nil {
+		return err
+	}
+	defer d.RemoveContainer(tmpID, true)
+
+	err = d.ExecuteCommand(tmpID, []string{"find", "/cache", "-mindepth", "1", "-delete"}, nil, "/", "", "", nil)
+	if err != nil {
+		return xerrors.Errorf("unable to remove the contents of volume %s: %w", volume, err)
+	}
+
+	err = d.c.CopyToContainer(context.Background(), tmpID, "/cache", r, types.CopyToContainerOptions{})
+	if err != nil {
+		return xerrors.Errorf("unable to import volume %s: %w", volume, err)
+	}
+
+	return nil
+}
+
+// tarPath writes a tar archive containing the file or the contents of the directory
+// at src, the names in the archive are relative to the root of the container
+func tarPath(w io.Writer, src, dst string, mode os.FileMode) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	// the contents of a directory are copied, a file is copied with its name
+	base := src
+	if !fi.IsDir() {
+		base = filepath.Dir(src)
+	}
+
+	root := strings.TrimLeft(path.Clean("/"+filepath.ToSlash(dst)), "/")
+
+	tw := tar.NewWriter(w)
+
+	err = filepath.Walk(src, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(base, file)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		link := ""
+		if fi.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(file)
+			if err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = path.Join(root, filepath.ToSlash(rel))
+		hdr.Uid = 0
+		hdr.Gid = 0
+		hdr.Uname = ""
+		hdr.Gname = ""
+
+		if mode != 0 && fi.Mode().IsRegular() {
+			hdr.Mode = int64(mode.Perm())
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
 // ExecuteCommand allows the execution of commands in a running docker container
 // id is the id of the container to execute the command in
 // command is a slice of strings to execute
@@ -909,8 +1962,74 @@ func (d *DockerTasks) ExecuteCommand(id string, command []string, env []string,
 	}
 
 	// loop until the container finishes execution
+	return d.waitForExec(execid.ID)
+}
+
+// ImportImagesToContainerd streams the local Docker images directly into the containerd
+// instance running in the container id, the image archive is piped to ctr so no
+// intermediate files are written to the host or to a volume
+func (d *DockerTasks) ImportImagesToContainerd(id string, images []string, namespace string, writer io.Writer) error {
+	d.l.Debug("Importing images to containerd", "id", id, "images", images, "namespace", namespace)
+
+	rc, err := d.c.ImageSave(context.Background(), images)
+	if err != nil {
+		return xerrors.Errorf("unable to save images %v: %w", images, err)
+	}
+	defer rc.Close()
+
+	execid, err := d.c.ContainerExecCreate(context.Background(), id, types.ExecConfig{
+		Cmd:          []string{"ctr", "-n", namespace, "image", "import", "-"},
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+
+	if err != nil {
+		return xerrors.Errorf("unable to create container exec: %w", err)
+	}
+
+	stream, err := d.c.ContainerExecAttach(context.Background(), execid.ID, types.ExecStartCheck{})
+	if err != nil {
+		return xerrors.Errorf("unable to attach to exec process: %w", err)
+	}
+
+	defer stream.Close()
+
+	if writer == nil {
+		writer = ioutil.Discard
+	}
+
+	// read the output in the background so that ctr does not block writing to stdout
+	outCh := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(writer, writer, stream.Reader)
+		outCh <- err
+	}()
+
+	_, err = io.Copy(stream.Conn, rc)
+	if err != nil {
+		return xerrors.Errorf("unable to stream images to containerd: %w", err)
+	}
+
+	// close stdin so that ctr knows the archive is complete
+	err = stream.CloseWrite()
+	if err != nil {
+		return xerrors.Errorf("unable to stream images to containerd: %w", err)
+	}
+
+	err = <-outCh
+	if err != nil {
+		return xerrors.Errorf("unable to read output from containerd import: %w", err)
+	}
+
+	return d.waitForExec(execid.ID)
+}
+
+// waitForExec blocks until the exec process has completed, an error is returned
+// when the process exits with a non zero exit code
+func (d *DockerTasks) waitForExec(id string) error {
 	for {
-		i, err := d.c.ContainerExecInspect(context.Background(), execid.ID)
+		i, err := d.c.ContainerExecInspect(context.Background(), id)
 		if err != nil {
 			return xerrors.Errorf("unable to determine status of exec process: %w", err)
 		}
@@ -1064,6 +2183,11 @@ func (d *DockerTasks) AttachNetwork(net, containerid string, aliases []string, i
 	if ipaddress != "" {
 		d.l.Debug("Assigning static ip address", "ref", containerid, "network", net, "ip_address", ipaddress)
 		es.IPAMConfig = &network.EndpointIPAMConfig{IPv4Address: ipaddress}
+
+		// the address is an IPv6 address
+		if strings.Contains(ipaddress, ":") {
+			es.IPAMConfig = &network.EndpointIPAMConfig{IPv6Address: ipaddress}
+		}
 	}
 
 	return d.c.NetworkConnect(context.Background(), net, containerid, es)
@@ -1094,8 +2218,22 @@ type publishedPorts struct {
 	PortBindings map[nat.Port][]nat.PortBinding
 }
 
+// bindAddress returns the address a port is bound to, ports which do not set
+// an address use the default, when there is no default all interfaces are used
+func bindAddress(bind, defaultBind string) string {
+	if bind != "" {
+		return bind
+	}
+
+	if defaultBind != "" {
+		return defaultBind
+	}
+
+	return "0.0.0.0"
+}
+
 // createPublishedPorts converts a list of config.Port to Docker publishedPorts type
-func createPublishedPorts(ps []config.Port) publishedPorts {
+func createPublishedPorts(ps []config.Port, defaultBind string) publishedPorts {
 	pp := publishedPorts{
 		ExposedPorts: make(map[nat.Port]struct{}, 0),
 		PortBindings: make(map[nat.Port][]nat.PortBinding, 0),
@@ -1111,7 +2249,7 @@ func createPublishedPorts(ps []config.Port) publishedPorts {
 
 		pb := []nat.PortBinding{
 			nat.PortBinding{
-				HostIP:   "0.0.0.0",
+				HostIP:   bindAddress(p.BindAddress(), defaultBind),
 				HostPort: p.Host,
 			},
 		}
@@ -1122,7 +2260,7 @@ func createPublishedPorts(ps []config.Port) publishedPorts {
 	return pp
 }
 
-func createPublishedPortRanges(ps []config.PortRange) (publishedPorts, error) {
+func createPublishedPortRanges(ps []config.PortRange, defaultBind string) (publishedPorts, error) {
 	pp := publishedPorts{
 		ExposedPorts: make(map[nat.Port]struct{}, 0),
 		PortBindings: make(map[nat.Port][]nat.PortBinding, 0),
@@ -1160,7 +2298,7 @@ func createPublishedPortRanges(ps []config.PortRange) (publishedPorts, error) {
 			if p.EnableHost {
 				pb := []nat.PortBinding{
 					nat.PortBinding{
-						HostIP:   "0.0.0.0",
+						HostIP:   bindAddress(p.Bind, defaultBind),
 						HostPort: port,
 					},
 				}
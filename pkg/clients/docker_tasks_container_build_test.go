@@ -3,6 +3,8 @@ package clients
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
+	"os/exec"
 	"strings"
 	"testing"
 
@@ -94,3 +96,103 @@ func TestBuildListsImagesAndBuildsWhenNotExistsCustomDockerfile(t *testing.T) {
 	params := getCalls(&md.Mock, "ImageBuild")[0].Arguments[2].(types.ImageBuildOptions)
 	assert.Equal(t, "./Dockerfile-test", params.Dockerfile)
 }
+
+func TestBuildPassesTargetAndCacheFrom(t *testing.T) {
+	md := testBuildMockSetup()
+	removeOn(&md.Mock, "ImageList")
+	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	cc := config.NewContainer("test")
+	cc.Build = &config.Build{Context: "./context", Tag: "latest", Target: "release", CacheFrom: []string{"myimage:latest"}}
+
+	dt := NewDockerTasks(md, nil, &TarGz{}, hclog.NewNullLogger())
+
+	_, err := dt.BuildContainer(cc, false)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ImageBuild")[0].Arguments[2].(types.ImageBuildOptions)
+	assert.Equal(t, "release", params.Target)
+	assert.Equal(t, []string{"myimage:latest"}, params.CacheFrom)
+}
+
+func TestBuildWithSecretsUsesBuildKit(t *testing.T) {
+	md := testBuildMockSetup()
+	removeOn(&md.Mock, "ImageList")
+	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	var cmdArgs []string
+	dockerCommand = func(name string, args ...string) *exec.Cmd {
+		cmdArgs = args
+		return exec.Command("true")
+	}
+	t.Cleanup(func() { dockerCommand = exec.Command })
+
+	cc := config.NewContainer("test")
+	cc.Build = &config.Build{
+		Context: "/context",
+		File:    "./Dockerfile",
+		Tag:     "latest",
+		Secrets: []config.BuildSecret{{ID: "npmrc", File: "/home/.npmrc"}},
+	}
+
+	dt := NewDockerTasks(md, nil, &TarGz{}, hclog.NewNullLogger())
+
+	_, err := dt.BuildContainer(cc, false)
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "ImageBuild", mock.Anything, mock.Anything, mock.Anything)
+	assert.Contains(t, cmdArgs, "id=npmrc,src=/home/.npmrc")
+	assert.Equal(t, "/context", cmdArgs[len(cmdArgs)-1])
+}
+
+func TestBuildKitArgsAddsOptions(t *testing.T) {
+	os.Setenv("BUILD_TOKEN", "secret")
+	t.Cleanup(func() { os.Unsetenv("BUILD_TOKEN") })
+
+	b := &config.Build{
+		Context:     "/context",
+		File:        "Dockerfile.dev",
+		Target:      "dev",
+		CacheFrom:   []string{"cache:latest"},
+		InlineCache: true,
+		Args:        map[string]string{"B": "2", "A": "1"},
+		Secrets:     []config.BuildSecret{{ID: "token", Environment: "BUILD_TOKEN"}},
+		SSH:         []string{"default"},
+	}
+
+	args, cleanup, err := buildKitArgs("shipyard.run/localcache/test:latest", b)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"build", "--progress", "plain", "--file", "/context/Dockerfile.dev", "--tag", "shipyard.run/localcache/test:latest"}, args[:7])
+	assert.Contains(t, strings.Join(args, " "), "--target dev")
+	assert.Contains(t, strings.Join(args, " "), "--cache-from cache:latest")
+	assert.Contains(t, strings.Join(args, " "), "--build-arg BUILDKIT_INLINE_CACHE=1 --build-arg A=1 --build-arg B=2")
+	assert.Contains(t, strings.Join(args, " "), "--ssh default")
+
+	// secrets from the environment are written to a temporary file
+	var secretFile string
+	for i, a := range args {
+		if a == "--secret" {
+			secretFile = strings.TrimPrefix(args[i+1], "id=token,src=")
+		}
+	}
+
+	d, err := ioutil.ReadFile(secretFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(d))
+
+	cleanup()
+	assert.NoFileExists(t, secretFile)
+}
+
+func TestBuildKitArgsReturnsErrorWhenSecretHasNoSource(t *testing.T) {
+	b := &config.Build{
+		Context: "/context",
+		Secrets: []config.BuildSecret{{ID: "token"}},
+	}
+
+	_, cleanup, err := buildKitArgs("shipyard.run/localcache/test:latest", b)
+	defer cleanup()
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package clients
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupContainerCommit(t *testing.T) (*DockerTasks, *mocks.MockDocker) {
+	md := &mocks.MockDocker{}
+	md.On("ServerVersion", mock.Anything).Return(types.Version{}, nil)
+	md.On("ContainerCommit", mock.Anything, mock.Anything, mock.Anything).Return(types.IDResponse{ID: "sha256:abc"}, nil)
+
+	return NewDockerTasks(md, &mocks.ImageLog{}, &TarGz{}, hclog.NewNullLogger()), md
+}
+
+func TestContainerCommitPausesAndTagsImage(t *testing.T) {
+	dt, md := setupContainerCommit(t)
+
+	err := dt.CommitContainer("test", "shipyard.run/localcache/grafana-persisted:latest")
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ContainerCommit", mock.Anything, "test", types.ContainerCommitOptions{Reference: "shipyard.run/localcache/grafana-persisted:latest", Pause: true})
+}
+
+func TestContainerCommitReturnsErrorOnFail(t *testing.T) {
+	dt, md := setupContainerCommit(t)
+	removeOn(&md.Mock, "ContainerCommit")
+	md.On("ContainerCommit", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	err := dt.CommitContainer("test", "shipyard.run/localcache/grafana-persisted:latest")
+	assert.Error(t, err)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -12,6 +14,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/go-connections/nat"
 	"github.com/hashicorp/go-hclog"
 	"github.com/mohae/deepcopy"
@@ -68,8 +71,8 @@ var containerConfig = &config.Container{
 		},
 	},
 	Resources: &config.Resources{
-		CPU:    1000,
-		Memory: 1000,
+		CPU:    "1000",
+		Memory: "1000",
 		CPUPin: []int{1, 4},
 	},
 	Networks: []config.NetworkAttachment{
@@ -100,6 +103,7 @@ func createContainerConfig() (*config.Container, *config.Network, *config.Networ
 func setupContainerMocks() (*clients.MockDocker, *clients.ImageLog) {
 	md := &clients.MockDocker{}
 	md.On("ServerVersion", mock.Anything).Return(types.Version{}, nil)
+	md.On("Info", mock.Anything).Return(types.Info{}, nil)
 	md.On("ContainerInspect", mock.Anything, mock.Anything).Return(types.ContainerJSON{NetworkSettings: &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{"bridge": nil}}}, nil)
 	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
 	md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(
@@ -208,6 +212,51 @@ func TestContainerAttachesToContainerNetwork(t *testing.T) {
 	assert.Equal(t, hc.NetworkMode, container.NetworkMode("container:abc"))
 }
 
+func TestContainerSharesPIDAndIPCWithContainer(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: "container.testcontainer2"}}
+	cc.SharePID = true
+	cc.ShareIPC = true
+	md.On("ContainerList", mock.Anything, mock.Anything).Return([]types.Container{types.Container{ID: "abc"}})
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Equal(t, container.PidMode("container:abc"), hc.PidMode)
+	assert.Equal(t, container.IpcMode("container:abc"), hc.IpcMode)
+}
+
+func TestContainerDoesNotSharePIDAndIPCByDefault(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: "container.testcontainer2"}}
+	md.On("ContainerList", mock.Anything, mock.Anything).Return([]types.Container{types.Container{ID: "abc"}})
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Equal(t, container.PidMode(""), hc.PidMode)
+	assert.Equal(t, container.IpcMode(""), hc.IpcMode)
+}
+
+func TestContainerWithShareableIPCSetsIPCMode(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.ShareableIPC = true
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Equal(t, container.IpcMode("shareable"), hc.IpcMode)
+}
+
 func TestContainerAttachesToContainerNetworkReturnsErrorWhenListError(t *testing.T) {
 	cc, _, _, md, mic := createContainerConfig()
 	cc.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: "container.testcontainer2"}}
@@ -250,7 +299,7 @@ func TestContainerDoesNOTAttachesToUserNetworkWhenNil(t *testing.T) {
 
 func TestContainerAssignsIPToUserNetwork(t *testing.T) {
 	cc, _, _, md, mic := createContainerConfig()
-	cc.Networks[0].IPAddress = "192.168.1.123"
+	cc.Networks[0].IPAddress = "192.168.4.123"
 
 	err := setupContainer(t, cc, md, mic)
 	assert.NoError(t, err)
@@ -261,6 +310,33 @@ func TestContainerAssignsIPToUserNetwork(t *testing.T) {
 	assert.Equal(t, cc.Networks[0].IPAddress, nc.IPAMConfig.IPv4Address)
 }
 
+func TestContainerAssignsIPv6ToUserNetwork(t *testing.T) {
+	cc, cn, _, md, mic := createContainerConfig()
+	cn.IPv6Subnet = "fd00:4::/64"
+	cc.Networks[0].IPAddress = "fd00:4::10"
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "NetworkConnect")[0].Arguments
+	nc := params[3].(*network.EndpointSettings)
+
+	assert.Equal(t, "fd00:4::10", nc.IPAMConfig.IPv6Address)
+	assert.Empty(t, nc.IPAMConfig.IPv4Address)
+}
+
+func TestContainerWithIPOutsideSubnetReturnsError(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Networks[0].IPAddress = "192.168.1.123"
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not in the subnet")
+
+	md.AssertNotCalled(t, "NetworkConnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	md.AssertCalled(t, "ContainerRemove", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestContainerAssignsAliasesToUserNetwork(t *testing.T) {
 	cc, _, _, md, mic := createContainerConfig()
 	cc.Networks[0].Aliases = []string{"abc", "123"}
@@ -274,6 +350,30 @@ func TestContainerAssignsAliasesToUserNetwork(t *testing.T) {
 	assert.Equal(t, cc.Networks[0].Aliases, nc.Aliases)
 }
 
+func TestContainerAttachesToMultipleNetworksWithAliasesAndIPs(t *testing.T) {
+	cc, cn, wn, md, mic := createContainerConfig()
+	cc.Networks[0].Aliases = []string{"backend"}
+	cc.Networks[0].IPAddress = "192.168.4.10"
+	cc.Networks[1].Aliases = []string{"frontend"}
+	cc.Networks[1].IPAddress = "192.168.6.10"
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	calls := getCalls(&md.Mock, "NetworkConnect")
+	assert.Len(t, calls, 2)
+
+	assert.Equal(t, cn.Name, calls[0].Arguments[1])
+	nc := calls[0].Arguments[3].(*network.EndpointSettings)
+	assert.Equal(t, []string{"backend"}, nc.Aliases)
+	assert.Equal(t, "192.168.4.10", nc.IPAMConfig.IPv4Address)
+
+	assert.Equal(t, wn.Name, calls[1].Arguments[1])
+	nc = calls[1].Arguments[3].(*network.EndpointSettings)
+	assert.Equal(t, []string{"frontend"}, nc.Aliases)
+	assert.Equal(t, "192.168.6.10", nc.IPAMConfig.IPv4Address)
+}
+
 func TestContainerRollsbackWhenUnableToConnectToWANNetwork(t *testing.T) {
 	cc, _, _, md, mic := createContainerConfig()
 	removeOn(&md.Mock, "NetworkConnect")
@@ -334,10 +434,72 @@ func TestContainerSetsReadOnlyForVolumeTypeVolume(t *testing.T) {
 	assert.Equal(t, "/tmp:/data:z:ro", hc.Binds[0])
 }
 
+func TestContainerMountsTmpfsWithoutSource(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Volumes[0].Type = "tmpfs"
+	cc.Volumes[0].ReadOnly = true
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Len(t, hc.Mounts, 1)
+	assert.Equal(t, mount.TypeTmpfs, hc.Mounts[0].Type)
+	assert.Empty(t, hc.Mounts[0].Source)
+	assert.Equal(t, "/data", hc.Mounts[0].Target)
+	assert.True(t, hc.Mounts[0].ReadOnly)
+	assert.Nil(t, hc.Mounts[0].BindOptions)
+}
+
+func TestContainerReturnsErrorWhenExternalVolumeDoesNotExist(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Volumes[0].Type = "volume"
+	cc.Volumes[0].Source = "secrets"
+	cc.Volumes[0].External = true
+
+	removeOn(&md.Mock, "VolumeList")
+	md.On("VolumeList", mock.Anything, mock.Anything).Return(
+		volume.VolumeListOKBody{Volumes: []*types.Volume{{Name: "secrets_old"}}},
+		nil,
+	)
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not exist")
+
+	md.AssertNotCalled(t, "ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestContainerMountsExternalVolume(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Volumes[0].Type = "volume"
+	cc.Volumes[0].Source = "secrets"
+	cc.Volumes[0].External = true
+	cc.Volumes[0].ReadOnly = true
+
+	removeOn(&md.Mock, "VolumeList")
+	md.On("VolumeList", mock.Anything, mock.Anything).Return(
+		volume.VolumeListOKBody{Volumes: []*types.Volume{{Name: "secrets"}}},
+		nil,
+	)
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Len(t, hc.Binds, 1)
+	assert.Equal(t, "secrets:/data:z:ro", hc.Binds[0])
+}
+
 func TestContainerSetsBindOptionsForVolumeTypeBind(t *testing.T) {
 	tt := map[string]mount.Propagation{
 		"":         mount.PropagationRPrivate,
 		"shared":   mount.PropagationShared,
+		"rshared":  mount.PropagationRShared,
 		"slave":    mount.PropagationSlave,
 		"private":  mount.PropagationPrivate,
 		"rslave":   mount.PropagationRSlave,
@@ -359,27 +521,33 @@ func TestContainerSetsBindOptionsForVolumeTypeBind(t *testing.T) {
 }
 
 func TestContainerCreatesDirectoryForVolume(t *testing.T) {
-	tmpFolder := fmt.Sprintf("%s/%d", utils.ShipyardTemp(), time.Now().UnixNano())
+	tmp, err := utils.ShipyardTemp()
+	assert.NoError(t, err)
+
+	tmpFolder := filepath.Join(tmp, fmt.Sprintf("%d", time.Now().UnixNano()))
 	defer os.RemoveAll(tmpFolder)
 
 	cc, _, _, md, mic := createContainerConfig()
 	cc.Volumes[0].Source = tmpFolder
 
-	err := setupContainer(t, cc, md, mic)
+	err = setupContainer(t, cc, md, mic)
 	assert.NoError(t, err)
 
 	assert.DirExists(t, tmpFolder)
 }
 
 func TestContainerDoesNotCreatesDirectoryForVolumeWhenNotBind(t *testing.T) {
-	tmpFolder := fmt.Sprintf("%s/%d", utils.ShipyardTemp(), time.Now().UnixNano())
+	tmp, err := utils.ShipyardTemp()
+	assert.NoError(t, err)
+
+	tmpFolder := filepath.Join(tmp, fmt.Sprintf("%d", time.Now().UnixNano()))
 	defer os.RemoveAll(tmpFolder)
 
 	cc, _, _, md, mic := createContainerConfig()
 	cc.Volumes[0].Source = tmpFolder
 	cc.Volumes[0].Type = "volume"
 
-	err := setupContainer(t, cc, md, mic)
+	err = setupContainer(t, cc, md, mic)
 	assert.NoError(t, err)
 
 	assert.NoDirExists(t, tmpFolder)
@@ -414,6 +582,47 @@ func TestContainerPublishesPorts(t *testing.T) {
 	assert.Equal(t, "0.0.0.0", hc.PortBindings[exp][0].HostIP)
 }
 
+func TestContainerPublishesPortsOnBindAddress(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Ports[0].Bind = "127.0.0.1"
+	cc.Ports[1].HostIP = "10.0.0.1"
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	hc := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[2].(*container.HostConfig)
+
+	exp, _ := nat.NewPort(cc.Ports[0].Protocol, cc.Ports[0].Local)
+	assert.Equal(t, "127.0.0.1", hc.PortBindings[exp][0].HostIP)
+
+	exp, _ = nat.NewPort(cc.Ports[1].Protocol, cc.Ports[1].Local)
+	assert.Equal(t, "10.0.0.1", hc.PortBindings[exp][0].HostIP)
+}
+
+func TestContainerPublishesPortsOnDefaultBindAddress(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Ports[1].Bind = "0.0.0.0"
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+	p.SetDefaultPortBind("127.0.0.1")
+
+	_, err := p.CreateContainer(cc)
+	assert.NoError(t, err)
+
+	hc := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[2].(*container.HostConfig)
+
+	exp, _ := nat.NewPort(cc.Ports[0].Protocol, cc.Ports[0].Local)
+	assert.Equal(t, "127.0.0.1", hc.PortBindings[exp][0].HostIP)
+
+	// ports which set bind are not changed by the default
+	exp, _ = nat.NewPort(cc.Ports[1].Protocol, cc.Ports[1].Local)
+	assert.Equal(t, "0.0.0.0", hc.PortBindings[exp][0].HostIP)
+
+	// port ranges use the default
+	exp, _ = nat.NewPort("tcp", "9002")
+	assert.Equal(t, "127.0.0.1", hc.PortBindings[exp][0].HostIP)
+}
+
 func TestContainerPublishesPortsRanges(t *testing.T) {
 	cc, _, _, md, mic := createContainerConfig()
 
@@ -466,6 +675,28 @@ func TestContainerConfiguresResources(t *testing.T) {
 	assert.Equal(t, hc.Resources.CpusetCpus, "1,4")
 }
 
+func TestContainerConfiguresResourcesWithUnits(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Resources = &config.Resources{CPU: "500m", Memory: "512Mi"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Equal(t, int64(512*1024*1024), hc.Resources.Memory)
+	assert.Equal(t, int64(50000), hc.Resources.CPUQuota)
+}
+
+func TestContainerWithInvalidResourcesReturnsError(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Resources = &config.Resources{Memory: "lots"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+}
+
 func TestContainerConfiguresRetryWhenCountGreater0(t *testing.T) {
 	cc, _, _, md, mic := createContainerConfig()
 	cc.MaxRestartCount = 10
@@ -509,6 +740,105 @@ func TestContainerAddUserWhenSpecified(t *testing.T) {
 	assert.Equal(t, "1010:1011", dc.User)
 }
 
+func TestContainerConfiguresUlimitsAndSysctls(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Ulimits = []config.Ulimit{
+		config.Ulimit{Name: "nofile", Soft: 65536, Hard: 65536},
+	}
+	cc.Sysctls = map[string]string{"net.core.somaxconn": "1024"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Len(t, hc.Resources.Ulimits, 1)
+	assert.Equal(t, "nofile", hc.Resources.Ulimits[0].Name)
+	assert.Equal(t, int64(65536), hc.Resources.Ulimits[0].Soft)
+	assert.Equal(t, int64(65536), hc.Resources.Ulimits[0].Hard)
+	assert.Equal(t, "1024", hc.Sysctls["net.core.somaxconn"])
+}
+
+func TestContainerConfiguresDNSAndExtraHosts(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.DNS = []string{"1.1.1.1"}
+	cc.DNSSearch = []string{"shipyard.run"}
+	cc.ExtraHosts = map[string]string{"db.local": "10.5.0.2", "host.local": "host-gateway"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Equal(t, []string{"1.1.1.1"}, hc.DNS)
+	assert.Equal(t, []string{"shipyard.run"}, hc.DNSSearch)
+	assert.Equal(t, []string{"db.local:10.5.0.2", "host.local:host-gateway"}, hc.ExtraHosts)
+}
+
+func TestContainerConfiguresRestartPolicy(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Restart = "unless-stopped"
+	cc.MaxRestartCount = 10
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Equal(t, "unless-stopped", hc.RestartPolicy.Name)
+	assert.Equal(t, 0, hc.RestartPolicy.MaximumRetryCount)
+}
+
+func TestContainerConfiguresCapabilitiesAndDevices(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.CapAdd = []string{"NET_ADMIN"}
+	cc.CapDrop = []string{"MKNOD"}
+	cc.Devices = []config.Device{
+		config.Device{Source: "/dev/net/tun"},
+		config.Device{Source: "/dev/fuse", Destination: "/dev/myfuse", Permissions: "r"},
+	}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Equal(t, []string{"NET_ADMIN"}, []string(hc.CapAdd))
+	assert.Equal(t, []string{"MKNOD"}, []string(hc.CapDrop))
+
+	assert.Equal(t, container.DeviceMapping{PathOnHost: "/dev/net/tun", PathInContainer: "/dev/net/tun", CgroupPermissions: "rwm"}, hc.Resources.Devices[0])
+	assert.Equal(t, container.DeviceMapping{PathOnHost: "/dev/fuse", PathInContainer: "/dev/myfuse", CgroupPermissions: "r"}, hc.Resources.Devices[1])
+}
+
+func TestContainerReadsSeccompProfile(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+
+	profile := filepath.Join(t.TempDir(), "profile.json")
+	ioutil.WriteFile(profile, []byte(`{"defaultAction": "SCMP_ACT_ALLOW"}`), os.ModePerm)
+
+	cc.SecurityOpt = []string{"seccomp=" + profile, "apparmor=unconfined"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Equal(t, []string{`seccomp={"defaultAction": "SCMP_ACT_ALLOW"}`, "apparmor=unconfined"}, hc.SecurityOpt)
+}
+
+func TestContainerReturnsErrorWhenSeccompProfileMissing(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.SecurityOpt = []string{"seccomp=/not/exist.json"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+}
+
 // removeOn is a utility function for removing Expectations from mock objects
 func removeOn(m *mock.Mock, method string) {
 	ec := m.ExpectedCalls
@@ -533,3 +863,264 @@ func getCalls(m *mock.Mock, method string) []mock.Call {
 
 	return rc
 }
+
+func TestContainerConvertsVolumeDestinationForWindowsContainers(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Image.Platform = "windows"
+	cc.Volumes[0].Destination = "C:/data/files"
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	assert.Equal(t, `C:\data\files`, hc.Mounts[0].Target)
+}
+
+func TestContainerReturnsErrorForPrivilegedWhenWindowsEngine(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	removeOn(&md.Mock, "Info")
+	md.On("Info", mock.Anything).Return(types.Info{OSType: "windows"}, nil)
+
+	cc.Privileged = true
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "require Linux containers")
+
+	md.AssertNotCalled(t, "ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestContainerReturnsErrorForPrivilegedPortWhenRootless(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	removeOn(&md.Mock, "Info")
+	md.On("Info", mock.Anything).Return(types.Info{SecurityOptions: []string{"name=rootless"}, CgroupVersion: "2"}, nil)
+
+	cc.Ports = []config.Port{{Local: "80", Host: "80", Protocol: "tcp"}}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+
+	md.AssertNotCalled(t, "ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestContainerReturnsErrorForPrivilegedWhenRootlessWithCgroupV1(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	removeOn(&md.Mock, "Info")
+	md.On("Info", mock.Anything).Return(types.Info{SecurityOptions: []string{"name=rootless"}, CgroupVersion: "1"}, nil)
+
+	cc.Privileged = true
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+}
+
+func TestContainerUsesHostUsernsForPrivilegedWhenUserNamespaces(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	removeOn(&md.Mock, "Info")
+	md.On("Info", mock.Anything).Return(types.Info{SecurityOptions: []string{"name=userns"}}, nil)
+
+	cc.Privileged = true
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[2].(*container.HostConfig)
+	assert.Equal(t, container.UsernsMode("host"), params.UsernsMode)
+}
+
+func TestContainerAddsGPUDeviceRequests(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	removeOn(&md.Mock, "Info")
+	md.On("Info", mock.Anything).Return(types.Info{Runtimes: map[string]types.Runtime{"nvidia": {}}}, nil)
+
+	cc.Resources = &config.Resources{GPU: &config.GPU{DeviceIDs: []string{"0", "1"}}}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[2].(*container.HostConfig)
+	assert.Len(t, params.DeviceRequests, 1)
+	assert.Equal(t, "nvidia", params.DeviceRequests[0].Driver)
+	assert.Equal(t, []string{"0", "1"}, params.DeviceRequests[0].DeviceIDs)
+	assert.Equal(t, 0, params.DeviceRequests[0].Count)
+	assert.Equal(t, [][]string{{"gpu"}}, params.DeviceRequests[0].Capabilities)
+}
+
+func TestContainerAddsAllGPUsWhenNoDeviceIDs(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	removeOn(&md.Mock, "Info")
+	md.On("Info", mock.Anything).Return(types.Info{Runtimes: map[string]types.Runtime{"nvidia": {}}}, nil)
+
+	cc.Resources = &config.Resources{GPU: &config.GPU{}}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[2].(*container.HostConfig)
+	assert.Equal(t, -1, params.DeviceRequests[0].Count)
+}
+
+func TestContainerReturnsErrorForGPUWithoutNvidiaRuntime(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+
+	cc.Resources = &config.Resources{GPU: &config.GPU{}}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "nvidia container runtime is not installed")
+
+	md.AssertNotCalled(t, "ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestContainerReplacesDockerSocketWhenRootless(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	removeOn(&md.Mock, "Info")
+	md.On("Info", mock.Anything).Return(types.Info{SecurityOptions: []string{"name=rootless"}, CgroupVersion: "2"}, nil)
+
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	ioutil.WriteFile(socket, []byte(""), os.ModePerm)
+
+	dh := os.Getenv("DOCKER_HOST")
+	os.Setenv("DOCKER_HOST", "unix://"+socket)
+	t.Cleanup(func() {
+		os.Setenv("DOCKER_HOST", dh)
+	})
+
+	cc.Volumes = []config.Volume{{Source: "/var/run/docker.sock", Destination: "/var/run/docker.sock"}}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[2].(*container.HostConfig)
+	assert.Equal(t, socket, params.Mounts[0].Source)
+}
+
+func TestContainerCreateSetsBlueprintLocale(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Config.Blueprint = &config.Blueprint{Timezone: "Europe/London", Locale: "en_GB.UTF-8"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	cfg := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[1].(*container.Config)
+	assert.Contains(t, cfg.Env, "TZ=Europe/London")
+	assert.Contains(t, cfg.Env, "LANG=en_GB.UTF-8")
+	assert.Contains(t, cfg.Env, "LC_ALL=en_GB.UTF-8")
+}
+
+func TestContainerCreateEnvOverridesBlueprintLocale(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Config.Blueprint = &config.Blueprint{Timezone: "Europe/London"}
+	cc.EnvVar["TZ"] = "UTC"
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	cfg := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[1].(*container.Config)
+	assert.Contains(t, cfg.Env, "TZ=UTC")
+	assert.NotContains(t, cfg.Env, "TZ=Europe/London")
+}
+
+func TestContainerCreateMountsLocaltimeForBlueprintTimezone(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("zone info is only mounted on Linux hosts")
+	}
+
+	dir := t.TempDir()
+	zi := zoneInfoDir
+	zoneInfoDir = dir
+	t.Cleanup(func() {
+		zoneInfoDir = zi
+	})
+
+	os.MkdirAll(filepath.Join(dir, "Europe"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "Europe", "London"), []byte("TZif"), os.ModePerm)
+
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Config.Blueprint = &config.Blueprint{Timezone: "Europe/London"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	hc := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[2].(*container.HostConfig)
+	assert.Len(t, hc.Mounts, 2)
+	assert.Equal(t, filepath.Join(dir, "Europe", "London"), hc.Mounts[1].Source)
+	assert.Equal(t, "/etc/localtime", hc.Mounts[1].Target)
+	assert.True(t, hc.Mounts[1].ReadOnly)
+
+	// the config must not be changed as it is persisted to the state
+	assert.Len(t, cc.Volumes, 1)
+}
+
+func TestContainerCreateDoesNotMountUnknownTimezone(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Config.Blueprint = &config.Blueprint{Timezone: "Nowhere/Unknown"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	hc := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[2].(*container.HostConfig)
+	assert.Len(t, hc.Mounts, 1)
+}
+
+func TestContainerCreateAddsBlueprintTrustedCAs(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Config.Blueprint = &config.Blueprint{TrustedCAFiles: []string{createTestCA(t)}}
+
+	md.On("CopyFromContainer", mock.Anything, mock.Anything, mock.Anything).Return(
+		testTarFile(t, "ca-certificates.crt", "existing\n"),
+		types.ContainerPathStat{Mode: 0644},
+		nil,
+	)
+	md.On("CopyToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CopyToContainer", mock.Anything, "test", "/etc/ssl/certs", mock.Anything, mock.Anything)
+	md.AssertCalled(t, "ContainerStart", mock.Anything, "test", mock.Anything)
+}
+
+func TestContainerCreateWithInvalidTrustedCAsRemovesContainer(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Config.Blueprint = &config.Blueprint{TrustedCAFiles: []string{"/missing/ca.pem"}}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+
+	md.AssertCalled(t, "ContainerRemove", mock.Anything, "test", mock.Anything)
+	md.AssertNotCalled(t, "ContainerStart", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestContainerCreatePassesThroughHostEnv(t *testing.T) {
+	t.Setenv("SY_TEST_TOKEN", "secret")
+	t.Setenv("SY_TEST_REGION", "eu-west-1")
+
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Config.Blueprint = &config.Blueprint{EnvPassthrough: []string{"SY_TEST_REGION"}}
+	cc.EnvPassthrough = []string{"SY_TEST_T*"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	cfg := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[1].(*container.Config)
+	assert.Contains(t, cfg.Env, "SY_TEST_TOKEN=secret")
+	assert.Contains(t, cfg.Env, "SY_TEST_REGION=eu-west-1")
+}
+
+func TestContainerCreateEnvOverridesHostEnv(t *testing.T) {
+	t.Setenv("SY_TEST_TOKEN", "secret")
+
+	cc, _, _, md, mic := createContainerConfig()
+	cc.EnvPassthrough = []string{"SY_TEST_TOKEN"}
+	cc.EnvVar["SY_TEST_TOKEN"] = "local"
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	cfg := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[1].(*container.Config)
+	assert.Contains(t, cfg.Env, "SY_TEST_TOKEN=local")
+	assert.NotContains(t, cfg.Env, "SY_TEST_TOKEN=secret")
+}
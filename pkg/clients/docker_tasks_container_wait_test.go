@@ -0,0 +1,58 @@
+package clients
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupContainerWait(t *testing.T) (*DockerTasks, chan container.ContainerWaitOKBody, chan error) {
+	md := &mocks.MockDocker{}
+	md.On("ServerVersion", mock.Anything).Return(types.Version{}, nil)
+
+	wc := make(chan container.ContainerWaitOKBody, 1)
+	ec := make(chan error, 1)
+
+	md.On("ContainerWait", mock.Anything, "test", container.WaitConditionNotRunning).Run(func(args mock.Arguments) {
+		// mimic the Docker client which returns an error when the context is done
+		ctx := args.Get(0).(context.Context)
+		go func() {
+			<-ctx.Done()
+			ec <- ctx.Err()
+		}()
+	}).Return(wc, ec)
+
+	return NewDockerTasks(md, &mocks.ImageLog{}, &TarGz{}, hclog.NewNullLogger()), wc, ec
+}
+
+func TestContainerWaitReturnsExitCode(t *testing.T) {
+	dt, wc, _ := setupContainerWait(t)
+	wc <- container.ContainerWaitOKBody{StatusCode: 2}
+
+	code, err := dt.WaitForContainer("test", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), code)
+}
+
+func TestContainerWaitReturnsErrorOnTimeout(t *testing.T) {
+	dt, _, _ := setupContainerWait(t)
+
+	_, err := dt.WaitForContainer("test", 10*time.Millisecond)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Timeout")
+}
+
+func TestContainerWaitReturnsErrorWhenWaitFails(t *testing.T) {
+	dt, wc, _ := setupContainerWait(t)
+	wc <- container.ContainerWaitOKBody{StatusCode: 0, Error: &container.ContainerWaitOKBodyError{Message: "boom"}}
+
+	_, err := dt.WaitForContainer("test", time.Second)
+	assert.Error(t, err)
+}
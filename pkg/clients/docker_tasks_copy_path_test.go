@@ -0,0 +1,89 @@
+package clients
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func createCopyPathFiles(t *testing.T) string {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "sub"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "config.hcl"), []byte("config"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "sub", "data.json"), []byte("{}"), 0600)
+
+	return dir
+}
+
+func readTarHeaders(t *testing.T, r io.Reader) map[string]*tar.Header {
+	hdrs := map[string]*tar.Header{}
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		assert.NoError(t, err)
+		hdrs[hdr.Name] = hdr
+	}
+
+	return hdrs
+}
+
+func TestTarPathAddsContentsOfDirectory(t *testing.T) {
+	dir := createCopyPathFiles(t)
+	buf := bytes.NewBuffer(nil)
+
+	err := tarPath(buf, dir, "/etc/consul.d", 0)
+	assert.NoError(t, err)
+
+	hdrs := readTarHeaders(t, buf)
+	assert.Len(t, hdrs, 3)
+	assert.Contains(t, hdrs, "etc/consul.d/config.hcl")
+	assert.Contains(t, hdrs, "etc/consul.d/sub")
+	assert.Contains(t, hdrs, "etc/consul.d/sub/data.json")
+	assert.Equal(t, int64(0600), hdrs["etc/consul.d/config.hcl"].Mode&0777)
+	assert.Equal(t, 0, hdrs["etc/consul.d/config.hcl"].Uid)
+}
+
+func TestTarPathAddsFileWithName(t *testing.T) {
+	dir := createCopyPathFiles(t)
+	buf := bytes.NewBuffer(nil)
+
+	err := tarPath(buf, filepath.Join(dir, "config.hcl"), "/etc/consul.d", 0644)
+	assert.NoError(t, err)
+
+	hdrs := readTarHeaders(t, buf)
+	assert.Len(t, hdrs, 1)
+	assert.Equal(t, int64(0644), hdrs["etc/consul.d/config.hcl"].Mode)
+}
+
+func TestTarPathWithMissingSourceReturnsError(t *testing.T) {
+	err := tarPath(bytes.NewBuffer(nil), "/missing", "/etc/consul.d", 0)
+	assert.Error(t, err)
+}
+
+func TestCopyPathToContainerCopiesToRoot(t *testing.T) {
+	dir := createCopyPathFiles(t)
+
+	md, mic := setupContainerMocks()
+	md.On("CopyToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	dt := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+
+	err := dt.CopyPathToContainer("1234", dir, "/etc/consul.d", 0)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CopyToContainer", mock.Anything, "1234", "/", mock.Anything, mock.Anything)
+}
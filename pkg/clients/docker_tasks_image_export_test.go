@@ -0,0 +1,84 @@
+package clients
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupImageExport(t *testing.T) (*DockerTasks, *mocks.MockDocker, *mocks.ImageLog) {
+	md := &mocks.MockDocker{}
+	md.On("ServerVersion", mock.Anything).Return(types.Version{}, nil)
+	md.On("ImageSave", mock.Anything, mock.Anything).Return(ioutil.NopCloser(strings.NewReader("archive")), nil)
+	md.On("ImageLoad", mock.Anything, mock.Anything, true).Return(types.ImageLoadResponse{
+		Body: ioutil.NopCloser(strings.NewReader(imageLoadOutput)),
+	}, nil)
+
+	mic := &mocks.ImageLog{}
+	mic.On("Log", mock.Anything, mock.Anything).Return(nil)
+
+	return NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger()), md, mic
+}
+
+func TestExportImagesSavesCanonicalImages(t *testing.T) {
+	dt, md, _ := setupImageExport(t)
+
+	out := bytes.NewBuffer(nil)
+	err := dt.ExportImages([]string{"consul:1.10.0", "ghcr.io/shipyard-run/envoy:latest"}, out)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ImageSave", mock.Anything, []string{"docker.io/library/consul:1.10.0", "ghcr.io/shipyard-run/envoy:latest"})
+	assert.Equal(t, "archive", out.String())
+}
+
+func TestImportImagesReturnsLoadedImages(t *testing.T) {
+	dt, _, mic := setupImageExport(t)
+
+	images, err := dt.ImportImages(strings.NewReader("archive"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"consul:1.10.0", "shipyardrun/k3s:v1.22.4"}, images)
+	mic.AssertNumberOfCalls(t, "Log", 2)
+}
+
+func TestImportImagesReturnsErrorFromLoad(t *testing.T) {
+	dt, md, _ := setupImageExport(t)
+
+	removeOn(&md.Mock, "ImageLoad")
+	md.On("ImageLoad", mock.Anything, mock.Anything, true).Return(types.ImageLoadResponse{
+		Body: ioutil.NopCloser(strings.NewReader(`{"errorDetail":{"message":"invalid tar header"},"error":"invalid tar header"}`)),
+	}, nil)
+
+	_, err := dt.ImportImages(strings.NewReader("archive"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid tar header")
+}
+
+var imageLoadOutput = `{"stream":"Loaded image: consul:1.10.0\n"}
+{"stream":"Loaded image: shipyardrun/k3s:v1.22.4\n"}
+`
+
+func TestFindImageIDReturnsID(t *testing.T) {
+	dt, md, _ := setupImageExport(t)
+	md.On("ImageInspectWithRaw", mock.Anything, "myapp:dev").Return(types.ImageInspect{ID: "sha256:abc"}, nil)
+
+	id, err := dt.FindImageID("myapp:dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:abc", id)
+}
+
+func TestFindImageIDReturnsErrorWhenNotFound(t *testing.T) {
+	dt, md, _ := setupImageExport(t)
+	md.On("ImageInspectWithRaw", mock.Anything, "myapp:dev").Return(nil, fmt.Errorf("not found"))
+
+	_, err := dt.FindImageID("myapp:dev")
+	assert.Error(t, err)
+}
@@ -1,13 +1,19 @@
 package clients
 
 import (
+	"bytes"
 	"encoding/base64"
+	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
@@ -120,6 +126,42 @@ func TestPullImageNothingWhenCached(t *testing.T) {
 	mic.AssertNotCalled(t, "Log", mock.Anything, mock.Anything)
 }
 
+func TestPullImageWithPlatformSetsPlatform(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	cc.Platform = "linux/amd64"
+
+	setupImagePull(t, cc, md, mic, false)
+
+	ipo := getCalls(&md.Mock, "ImagePull")[0].Arguments[2].(types.ImagePullOptions)
+	assert.Equal(t, "linux/amd64", ipo.Platform)
+}
+
+func TestPullImageWhenCachedWithDifferentPlatform(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	cc.Platform = "linux/amd64"
+
+	removeOn(&md.Mock, "ImageList")
+	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return([]types.ImageSummary{types.ImageSummary{ID: "abc"}}, nil)
+	md.On("ImageInspectWithRaw", mock.Anything, "abc").Return(types.ImageInspect{Os: "linux", Architecture: "arm64"}, nil)
+
+	setupImagePull(t, cc, md, mic, false)
+
+	md.AssertCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPullImageNothingWhenCachedWithMatchingPlatform(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	cc.Platform = "linux/arm64/v8"
+
+	removeOn(&md.Mock, "ImageList")
+	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return([]types.ImageSummary{types.ImageSummary{ID: "abc"}}, nil)
+	md.On("ImageInspectWithRaw", mock.Anything, "abc").Return(types.ImageInspect{Os: "linux", Architecture: "arm64", Variant: "v8"}, nil)
+
+	setupImagePull(t, cc, md, mic, false)
+
+	md.AssertNotCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestPullImageAlwaysWhenForce(t *testing.T) {
 	cc, md, mic := createImagePullConfig()
 
@@ -129,3 +171,339 @@ func TestPullImageAlwaysWhenForce(t *testing.T) {
 	md.AssertCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
 	mic.AssertCalled(t, "Log", mock.Anything, mock.Anything)
 }
+
+func TestPullImageOfflineReturnsErrorWhenNOTCached(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+	p.SetOffline(true)
+
+	err := p.PullImage(cc, false)
+	assert.Error(t, err)
+	assert.IsType(t, ImageNotFoundOfflineError{}, err)
+
+	md.AssertNotCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPullImageOfflineDoesNotPullWhenForced(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+
+	removeOn(&md.Mock, "ImageList")
+	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return([]types.ImageSummary{types.ImageSummary{}}, nil)
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+	p.SetOffline(true)
+
+	err := p.PullImage(cc, true)
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func setupPullRetry(t *testing.T) {
+	b := pullBackoff
+	pullBackoff = time.Millisecond
+
+	t.Cleanup(func() { pullBackoff = b })
+}
+
+func TestPullImageRetriesOnError(t *testing.T) {
+	setupPullRetry(t)
+	cc, md, mic := createImagePullConfig()
+
+	removeOn(&md.Mock, "ImagePull")
+	md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom")).Once()
+	md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(
+		ioutil.NopCloser(strings.NewReader(pullOutput)),
+		nil,
+	)
+
+	setupImagePull(t, cc, md, mic, false)
+
+	md.AssertNumberOfCalls(t, "ImagePull", 2)
+}
+
+func TestPullImageReturnsErrorAfterRetries(t *testing.T) {
+	setupPullRetry(t)
+	cc, md, mic := createImagePullConfig()
+
+	removeOn(&md.Mock, "ImagePull")
+	md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+
+	err := p.PullImage(cc, false)
+	assert.Error(t, err)
+
+	md.AssertNumberOfCalls(t, "ImagePull", pullAttempts)
+	mic.AssertNotCalled(t, "Log", mock.Anything, mock.Anything)
+}
+
+func TestPullImageDoesNotRetryWhenNotFound(t *testing.T) {
+	setupPullRetry(t)
+	cc, md, mic := createImagePullConfig()
+
+	removeOn(&md.Mock, "ImagePull")
+	md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(nil, errdefs.NotFound(fmt.Errorf("not found")))
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+
+	err := p.PullImage(cc, false)
+	assert.Error(t, err)
+
+	md.AssertNumberOfCalls(t, "ImagePull", 1)
+}
+
+func TestPullImageUsesRetryPolicyFromImage(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	cc.PullRetry = &config.Retry{Attempts: 5, Delay: "1ms"}
+
+	removeOn(&md.Mock, "ImagePull")
+	md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+
+	err := p.PullImage(cc, false)
+	assert.Error(t, err)
+
+	md.AssertNumberOfCalls(t, "ImagePull", 5)
+}
+
+func TestPullImageStopsRetryingWhenTimeoutExceeded(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	cc.PullRetry = &config.Retry{Attempts: 5, Delay: "1s"}
+	cc.PullTimeout = 10 * time.Millisecond
+
+	removeOn(&md.Mock, "ImagePull")
+	md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+
+	err := p.PullImage(cc, false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout pulling image")
+
+	md.AssertNumberOfCalls(t, "ImagePull", 1)
+}
+
+func TestPullImageReturnsErrorFromPullOutput(t *testing.T) {
+	setupPullRetry(t)
+	cc, md, mic := createImagePullConfig()
+
+	removeOn(&md.Mock, "ImagePull")
+	for i := 0; i < pullAttempts; i++ {
+		md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(
+			ioutil.NopCloser(strings.NewReader(`{"errorDetail":{"message":"unexpected EOF"},"error":"unexpected EOF"}`)),
+			nil,
+		).Once()
+	}
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+
+	err := p.PullImage(cc, false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected EOF")
+
+	md.AssertNumberOfCalls(t, "ImagePull", pullAttempts)
+}
+
+func TestPullImageRecordsPull(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+
+	removeOn(&md.Mock, "ImagePull")
+	md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(
+		ioutil.NopCloser(strings.NewReader(pullOutput)),
+		nil,
+	)
+
+	ip := NewImagePulls()
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+	p.SetImagePulls(ip)
+
+	err := p.PullImage(cc, false)
+	assert.NoError(t, err)
+
+	r := ip.Records()
+	assert.Len(t, r, 1)
+	assert.Equal(t, makeImageCanonical(cc.Name), r[0].Image)
+	assert.Equal(t, 1, r[0].Attempts)
+	assert.Equal(t, int64(300), r[0].Bytes)
+	assert.Empty(t, r[0].Error)
+}
+
+func TestPullImageRemovesActivePullWhenComplete(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+
+	removeOn(&md.Mock, "ImagePull")
+	md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(
+		ioutil.NopCloser(strings.NewReader(pullOutput)),
+		nil,
+	)
+
+	ip := NewImagePulls()
+	ip.Progress(ImagePullProgress{Image: "docker.io/library/vault:1.9.0", Current: 10, Total: 100})
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+	p.SetImagePulls(ip)
+
+	err := p.PullImage(cc, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []ImagePullProgress{{Image: "docker.io/library/vault:1.9.0", Current: 10, Total: 100}}, ip.Active())
+}
+
+func TestPullProgressUpdatesActivePull(t *testing.T) {
+	ip := NewImagePulls()
+
+	p := newPullProgress("docker.io/library/consul:1.6.1", ip, hclog.NewNullLogger())
+	p.update(jsonmessage.JSONMessage{ID: "abc", Status: "Downloading", Progress: &jsonmessage.JSONProgress{Current: 50, Total: 100}})
+	p.update(jsonmessage.JSONMessage{ID: "def", Status: "Downloading", Progress: &jsonmessage.JSONProgress{Current: 20, Total: 200}})
+
+	assert.Equal(t, []ImagePullProgress{{Image: "docker.io/library/consul:1.6.1", Current: 70, Total: 300}}, ip.Active())
+}
+
+func TestPullImageDoesNotRecordCachedImage(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+
+	removeOn(&md.Mock, "ImageList")
+	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return([]types.ImageSummary{types.ImageSummary{}}, nil)
+
+	ip := NewImagePulls()
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+	p.SetImagePulls(ip)
+
+	err := p.PullImage(cc, false)
+	assert.NoError(t, err)
+
+	assert.Empty(t, ip.Records())
+}
+
+func TestPullImageRecordsDigestToFixtures(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	md.On("ImageInspectWithRaw", mock.Anything, makeImageCanonical(cc.Name)).Return(
+		types.ImageInspect{RepoDigests: []string{"consul@sha256:4fdb8a3dab7df2d0a6b4bd6e44d8fe0a0e1d2f1a8e1c1f5e7c3b2a190817e6d5"}},
+		nil,
+		nil,
+	)
+
+	f := NewFixtures()
+	err := f.Record(t.TempDir())
+	assert.NoError(t, err)
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+	p.SetFixtures(f)
+
+	err = p.PullImage(cc, false)
+	assert.NoError(t, err)
+
+	d, err := f.ImageDigest(cc.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, "docker.io/library/consul@sha256:4fdb8a3dab7df2d0a6b4bd6e44d8fe0a0e1d2f1a8e1c1f5e7c3b2a190817e6d5", d)
+}
+
+func TestPullImageReplaysDigestFromFixtures(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	md.On("ImageTag", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, FixturesIndexFile), []byte(`{"images": {"consul:1.6.1": "docker.io/library/consul@sha256:abc"}}`), 0644)
+	assert.NoError(t, err)
+
+	f := NewFixtures()
+	err = f.Replay(dir)
+	assert.NoError(t, err)
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+	p.SetFixtures(f)
+
+	err = p.PullImage(cc, false)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ImagePull", mock.Anything, "docker.io/library/consul@sha256:abc", types.ImagePullOptions{})
+	md.AssertCalled(t, "ImageTag", mock.Anything, "docker.io/library/consul@sha256:abc", makeImageCanonical(cc.Name))
+}
+
+func TestPullImageReturnsErrorWhenNotInFixtures(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, FixturesIndexFile), []byte(`{}`), 0644)
+	assert.NoError(t, err)
+
+	f := NewFixtures()
+	err = f.Replay(dir)
+	assert.NoError(t, err)
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+	p.SetFixtures(f)
+
+	err = p.PullImage(cc, false)
+	assert.Error(t, err)
+
+	md.AssertNotCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
+var pullOutput = `{"status":"Pulling from library/consul","id":"1.6.1"}
+{"status":"Downloading","progressDetail":{"current":50,"total":100},"id":"abc"}
+{"status":"Downloading","progressDetail":{"current":100,"total":200},"id":"def"}
+{"status":"Download complete","progressDetail":{},"id":"abc"}
+{"status":"Download complete","progressDetail":{},"id":"def"}
+{"status":"Status: Downloaded newer image for consul:1.6.1"}
+`
+
+func setupEngineProxy(t *testing.T, md *mocks.MockDocker, info types.Info) (*DockerTasks, *bytes.Buffer) {
+	t.Setenv("HTTP_PROXY", "http://proxy.corp:3128")
+
+	proxyLock.Lock()
+	proxyEnabled = true
+	proxyLock.Unlock()
+
+	t.Cleanup(func() {
+		proxyLock.Lock()
+		proxyEnabled = false
+		proxyLock.Unlock()
+	})
+
+	md.On("Info", mock.Anything).Return(info, nil)
+
+	out := bytes.NewBuffer(nil)
+	_, mic := setupImagePullMocks()
+
+	return NewDockerTasks(md, mic, &TarGz{}, hclog.New(&hclog.LoggerOptions{Output: out})), out
+}
+
+func TestPullImageWarnsWhenEngineHasNoProxy(t *testing.T) {
+	md, _ := setupImagePullMocks()
+	dt, out := setupEngineProxy(t, md, types.Info{})
+
+	err := dt.PullImage(config.Image{Name: "consul:1.6.1"}, false)
+	assert.NoError(t, err)
+
+	err = dt.PullImage(config.Image{Name: "consul:1.6.1"}, false)
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "not for the container engine")
+	assert.Contains(t, out.String(), "http://proxy.corp:3128")
+
+	// the engine is only checked once
+	md.AssertNumberOfCalls(t, "Info", 1)
+}
+
+func TestPullImageDoesNotWarnWhenEngineHasProxy(t *testing.T) {
+	md, _ := setupImagePullMocks()
+	dt, out := setupEngineProxy(t, md, types.Info{HTTPProxy: "http://proxy.corp:3128"})
+
+	err := dt.PullImage(config.Image{Name: "consul:1.6.1"}, false)
+	assert.NoError(t, err)
+
+	assert.NotContains(t, out.String(), "not for the container engine")
+}
+
+func TestPullImageDoesNotCheckEngineWithoutProxy(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	setupImagePull(t, cc, md, mic, false)
+
+	md.AssertNotCalled(t, "Info", mock.Anything)
+}
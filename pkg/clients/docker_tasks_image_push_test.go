@@ -0,0 +1,78 @@
+package clients
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupImagePushMocks() (*DockerTasks, *mocks.MockDocker) {
+	md := &mocks.MockDocker{}
+	md.On("ServerVersion", mock.Anything).Return(types.Version{}, nil)
+	md.On("ImageTag", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	md.On("ImagePush", mock.Anything, mock.Anything, mock.Anything).Return(
+		ioutil.NopCloser(strings.NewReader(`{"status":"Pushed"}`)),
+		nil,
+	)
+
+	mic := &mocks.ImageLog{}
+
+	return NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger()), md
+}
+
+func TestPushImageTagsImage(t *testing.T) {
+	p, md := setupImagePushMocks()
+
+	err := p.PushImage("myapp:dev", config.Image{Name: "localhost:5000/library/myapp:dev"})
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ImageTag", mock.Anything, "docker.io/library/myapp:dev", "localhost:5000/library/myapp:dev")
+}
+
+func TestPushImagePushesTarget(t *testing.T) {
+	p, md := setupImagePushMocks()
+
+	err := p.PushImage("myapp:dev", config.Image{Name: "localhost:5000/library/myapp:dev"})
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ImagePush", mock.Anything, "localhost:5000/library/myapp:dev", types.ImagePushOptions{RegistryAuth: createRegistryAuth("", "")})
+}
+
+func TestPushImageWithCredentialsSetsAuth(t *testing.T) {
+	p, md := setupImagePushMocks()
+
+	err := p.PushImage("myapp:dev", config.Image{Name: "localhost:5000/library/myapp:dev", Username: "admin", Password: "secret"})
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ImagePush", mock.Anything, "localhost:5000/library/myapp:dev", types.ImagePushOptions{RegistryAuth: createRegistryAuth("admin", "secret")})
+}
+
+func TestPushImageTagErrorReturnsError(t *testing.T) {
+	p, md := setupImagePushMocks()
+	removeOn(&md.Mock, "ImageTag")
+	md.On("ImageTag", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := p.PushImage("myapp:dev", config.Image{Name: "localhost:5000/library/myapp:dev"})
+	assert.Error(t, err)
+}
+
+func TestPushImageStreamErrorReturnsError(t *testing.T) {
+	p, md := setupImagePushMocks()
+	removeOn(&md.Mock, "ImagePush")
+	md.On("ImagePush", mock.Anything, mock.Anything, mock.Anything).Return(
+		ioutil.NopCloser(strings.NewReader(`{"errorDetail":{"message":"denied"},"error":"denied"}`)),
+		nil,
+	)
+
+	err := p.PushImage("myapp:dev", config.Image{Name: "localhost:5000/library/myapp:dev"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "denied")
+}
@@ -0,0 +1,116 @@
+package clients
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+// testImportContainerdMockSetup returns the mock client and a function which returns
+// the data written to the exec stdin once the connection has been closed
+func testImportContainerdMockSetup(t *testing.T) (*mocks.MockDocker, func() string) {
+	// use a pipe for the exec connection so the data written to stdin can be inspected
+	client, server := net.Pipe()
+	stdin := bytes.NewBufferString("")
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		stdin.ReadFrom(server)
+	}()
+
+	t.Cleanup(func() {
+		client.Close()
+		<-done
+		server.Close()
+	})
+
+	mk := &mocks.MockDocker{}
+	mk.On("ServerVersion", mock.Anything).Return(types.Version{}, nil)
+	mk.On("ImageSave", mock.Anything, mock.Anything).Return(
+		ioutil.NopCloser(strings.NewReader("image archive")),
+		nil,
+	)
+	mk.On("ContainerExecCreate", mock.Anything, mock.Anything, mock.Anything).Return(types.IDResponse{ID: "abc"}, nil)
+	mk.On("ContainerExecAttach", mock.Anything, mock.Anything, mock.Anything).Return(
+		types.HijackedResponse{
+			Conn:   client,
+			Reader: bufio.NewReader(bytes.NewReader([]byte{})),
+		},
+		nil,
+	)
+	mk.On("ContainerExecInspect", mock.Anything, mock.Anything, mock.Anything).Return(types.ContainerExecInspect{Running: false, ExitCode: 0}, nil)
+
+	return mk, func() string {
+		<-done
+		return stdin.String()
+	}
+}
+
+func TestImportImagesToContainerdSavesImages(t *testing.T) {
+	mk, _ := testImportContainerdMockSetup(t)
+	md := NewDockerTasks(mk, &mocks.ImageLog{}, &TarGz{}, hclog.NewNullLogger())
+
+	err := md.ImportImagesToContainerd("testcontainer", []string{"consul:1.10.1"}, "k8s.io", nil)
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "ImageSave", mock.Anything, []string{"consul:1.10.1"})
+}
+
+func TestImportImagesToContainerdRunsCtrInNamespace(t *testing.T) {
+	mk, _ := testImportContainerdMockSetup(t)
+	md := NewDockerTasks(mk, &mocks.ImageLog{}, &TarGz{}, hclog.NewNullLogger())
+
+	err := md.ImportImagesToContainerd("testcontainer", []string{"consul:1.10.1"}, "shipyard", nil)
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "ContainerExecCreate", mock.Anything, "testcontainer", mock.Anything)
+	params := getCalls(&mk.Mock, "ContainerExecCreate")[0].Arguments[2].(types.ExecConfig)
+
+	assert.Equal(t, []string{"ctr", "-n", "shipyard", "image", "import", "-"}, params.Cmd)
+	assert.True(t, params.AttachStdin)
+}
+
+func TestImportImagesToContainerdStreamsArchiveToExec(t *testing.T) {
+	mk, stdin := testImportContainerdMockSetup(t)
+	md := NewDockerTasks(mk, &mocks.ImageLog{}, &TarGz{}, hclog.NewNullLogger())
+
+	err := md.ImportImagesToContainerd("testcontainer", []string{"consul:1.10.1"}, "k8s.io", nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "image archive", stdin())
+}
+
+func TestImportImagesToContainerdSaveFailReturnsError(t *testing.T) {
+	mk, _ := testImportContainerdMockSetup(t)
+	removeOn(&mk.Mock, "ImageSave")
+	mk.On("ImageSave", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	md := NewDockerTasks(mk, &mocks.ImageLog{}, &TarGz{}, hclog.NewNullLogger())
+
+	err := md.ImportImagesToContainerd("testcontainer", []string{"consul:1.10.1"}, "k8s.io", nil)
+	assert.Error(t, err)
+
+	mk.AssertNotCalled(t, "ContainerExecCreate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestImportImagesToContainerdExecFailReturnsError(t *testing.T) {
+	mk, _ := testImportContainerdMockSetup(t)
+	removeOn(&mk.Mock, "ContainerExecInspect")
+	mk.On("ContainerExecInspect", mock.Anything, mock.Anything, mock.Anything).Return(types.ContainerExecInspect{Running: false, ExitCode: 1}, nil)
+
+	md := NewDockerTasks(mk, &mocks.ImageLog{}, &TarGz{}, hclog.NewNullLogger())
+
+	err := md.ImportImagesToContainerd("testcontainer", []string{"consul:1.10.1"}, "k8s.io", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package clients
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/hashicorp/go-hclog"
+	clients "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExportVolumeCopiesContentsOfVolume(t *testing.T) {
+	mk := testCreateCopyLocalMocks()
+	mk.On("CopyFromContainer", mock.Anything, "myid", "/cache/.").
+		Return(ioutil.NopCloser(bytes.NewBufferString("archive")), types.ContainerPathStat{}, nil)
+
+	mic := &clients.ImageLog{}
+	dt := NewDockerTasks(mk, mic, &TarGz{}, hclog.NewNullLogger())
+
+	out := bytes.NewBuffer(nil)
+	err := dt.ExportVolume("data", out)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "archive", out.String())
+	mk.AssertCalled(t, "ContainerRemove", mock.Anything, "myid", mock.Anything)
+}
+
+func TestExportVolumeCopyFailReturnsError(t *testing.T) {
+	mk := testCreateCopyLocalMocks()
+	mk.On("CopyFromContainer", mock.Anything, "myid", "/cache/.").
+		Return(nil, types.ContainerPathStat{}, fmt.Errorf("boom"))
+
+	mic := &clients.ImageLog{}
+	dt := NewDockerTasks(mk, mic, &TarGz{}, hclog.NewNullLogger())
+
+	err := dt.ExportVolume("data", bytes.NewBuffer(nil))
+	assert.Error(t, err)
+}
+
+func TestImportVolumeRemovesContentsAndCopiesArchive(t *testing.T) {
+	mk := testCreateCopyLocalMocks()
+	mic := &clients.ImageLog{}
+	dt := NewDockerTasks(mk, mic, &TarGz{}, hclog.NewNullLogger())
+
+	in := bytes.NewBufferString("archive")
+	err := dt.ImportVolume("data", in)
+	assert.NoError(t, err)
+
+	params := getCalls(&mk.Mock, "ContainerExecCreate")[0].Arguments[2].(types.ExecConfig)
+	assert.Equal(t, []string{"find", "/cache", "-mindepth", "1", "-delete"}, params.Cmd)
+
+	mk.AssertCalled(t, "CopyToContainer", mock.Anything, "myid", "/cache", in, mock.Anything)
+	mk.AssertCalled(t, "ContainerRemove", mock.Anything, "myid", mock.Anything)
+}
+
+func TestImportVolumeCopyFailReturnsError(t *testing.T) {
+	mk := testCreateCopyLocalMocks()
+	removeOn(&mk.Mock, "CopyToContainer")
+	mk.On("CopyToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	mic := &clients.ImageLog{}
+	dt := NewDockerTasks(mk, mic, &TarGz{}, hclog.NewNullLogger())
+
+	err := dt.ImportVolume("data", bytes.NewBufferString("archive"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,223 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/hashicorp/go-hclog"
+)
+
+// engineReconnectTimeout is the maximum time to wait for the Docker engine
+// to become available again after the connection is lost
+var engineReconnectTimeout = 2 * time.Minute
+
+// engineReconnectBackoff is the initial time to wait between attempts to
+// connect to the Docker engine, the time is doubled up to engineReconnectMaxBackoff
+var engineReconnectBackoff = 500 * time.Millisecond
+
+var engineReconnectMaxBackoff = 10 * time.Second
+
+// EngineUnavailableError is returned when the Docker engine does not
+// become available within the reconnect timeout
+type EngineUnavailableError struct {
+	Waited time.Duration
+	Err    error
+}
+
+func (e EngineUnavailableError) Error() string {
+	return fmt.Sprintf("the Docker engine is not available, waited %s for it to restart: %s", e.Waited.Round(time.Second), e.Err)
+}
+
+func (e EngineUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// EngineMonitor wraps the connections to the Docker engine, when the engine is
+// unavailable, e.g. Docker Desktop is restarting after an update, new connections
+// are retried with backoff rather than failing. The times the engine became available
+// again are recorded so that operations which were interrupted by a restart can be detected.
+type EngineMonitor struct {
+	l hclog.Logger
+
+	lock        sync.Mutex
+	seen        bool // a connection to the engine has succeeded
+	unavailable bool // the last connection attempt failed
+	gaveUp      bool // the engine did not become available within the timeout
+	restarts    []time.Time
+}
+
+// NewEngineMonitor creates a new EngineMonitor
+func NewEngineMonitor(l hclog.Logger) *EngineMonitor {
+	return &EngineMonitor{l: l}
+}
+
+// Restarts returns the number of times the engine became available after being unavailable
+func (m *EngineMonitor) Restarts() int {
+	if m == nil {
+		return 0
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return len(m.restarts)
+}
+
+// RestartedSince returns true when the engine was unavailable and became
+// available again, or is still unavailable, after the given time
+func (m *EngineMonitor) RestartedSince(t time.Time) bool {
+	if m == nil {
+		return false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.unavailable {
+		return true
+	}
+
+	for _, r := range m.restarts {
+		if r.After(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DialContext wraps the given dial function retrying connections which fail
+// because the engine is not running, connections are only retried once a
+// connection to the engine has succeeded so that an engine which is not
+// running at all is reported immediately
+func (m *EngineMonitor) DialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err == nil {
+			m.connected()
+			return conn, nil
+		}
+
+		if !isEngineUnavailable(err) {
+			return nil, err
+		}
+
+		m.lock.Lock()
+		if !m.seen {
+			m.lock.Unlock()
+			return nil, err
+		}
+
+		gaveUp := m.gaveUp
+		wasUnavailable := m.unavailable
+		m.unavailable = true
+		l := m.l
+		m.lock.Unlock()
+
+		// do not wait again once the engine has not returned within the timeout
+		// so that the remaining operations fail fast with the same error
+		if gaveUp {
+			return nil, EngineUnavailableError{Waited: engineReconnectTimeout, Err: err}
+		}
+
+		if !wasUnavailable && l != nil {
+			l.Warn("Unable to connect to the Docker engine, waiting for it to restart", "timeout", engineReconnectTimeout, "error", err)
+		}
+
+		start := time.Now()
+		backoff := engineReconnectBackoff
+
+		for time.Since(start) < engineReconnectTimeout {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+
+			conn, err = dial(ctx, network, addr)
+			if err == nil {
+				m.connected()
+				return conn, nil
+			}
+
+			if !isEngineUnavailable(err) {
+				return nil, err
+			}
+
+			backoff = backoff * 2
+			if backoff > engineReconnectMaxBackoff {
+				backoff = engineReconnectMaxBackoff
+			}
+		}
+
+		m.lock.Lock()
+		m.gaveUp = true
+		m.lock.Unlock()
+
+		return nil, EngineUnavailableError{Waited: time.Since(start), Err: err}
+	}
+}
+
+// connected records a successful connection, when the engine was
+// previously unavailable a restart is recorded
+func (m *EngineMonitor) connected() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.seen = true
+
+	if !m.unavailable {
+		return
+	}
+
+	m.unavailable = false
+	m.gaveUp = false
+	m.restarts = append(m.restarts, time.Now())
+
+	if m.l != nil {
+		m.l.Info("Reconnected to the Docker engine")
+	}
+}
+
+// withEngineMonitor wraps the dialer of the Docker client transport, this option
+// must be applied after the options which set the host and the dialer
+func withEngineMonitor(m *EngineMonitor) client.Opt {
+	return func(c *client.Client) error {
+		t, ok := c.HTTPClient().Transport.(*http.Transport)
+		if !ok {
+			return nil
+		}
+
+		if t.DialContext != nil {
+			t.DialContext = m.DialContext(t.DialContext)
+			return nil
+		}
+
+		if t.Dial != nil {
+			dial := t.Dial
+			t.Dial = nil
+			t.DialContext = m.DialContext(func(_ context.Context, network, addr string) (net.Conn, error) {
+				return dial(network, addr)
+			})
+		}
+
+		return nil
+	}
+}
+
+// isEngineUnavailable returns true when the error is caused by the engine not
+// listening, the socket is removed or refuses connections while the engine restarts
+func isEngineUnavailable(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ENOENT) ||
+		errors.Is(err, os.ErrNotExist)
+}
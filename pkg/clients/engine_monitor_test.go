@@ -0,0 +1,114 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	assert "github.com/stretchr/testify/require"
+)
+
+// setupEngineDial returns a dial function which fails with connection
+// refused for the given number of calls after the first successful call
+func setupEngineDial(t *testing.T, failures int) (func(ctx context.Context, network, addr string) (net.Conn, error), *int) {
+	b := engineReconnectBackoff
+	engineReconnectBackoff = time.Millisecond
+
+	to := engineReconnectTimeout
+	engineReconnectTimeout = 100 * time.Millisecond
+
+	t.Cleanup(func() {
+		engineReconnectBackoff = b
+		engineReconnectTimeout = to
+	})
+
+	calls := 0
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		calls++
+		if calls > 1 && calls <= failures+1 {
+			return nil, &net.OpError{Op: "dial", Net: "unix", Err: syscall.ECONNREFUSED}
+		}
+
+		c, _ := net.Pipe()
+		return c, nil
+	}, &calls
+}
+
+func TestEngineMonitorDoesNotRetryBeforeFirstConnection(t *testing.T) {
+	setupEngineDial(t, 0)
+
+	m := NewEngineMonitor(hclog.NewNullLogger())
+	dial := m.DialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, &net.OpError{Op: "dial", Net: "unix", Err: syscall.ECONNREFUSED}
+	})
+
+	_, err := dial(context.Background(), "unix", "docker.sock")
+	assert.Error(t, err)
+	assert.False(t, m.RestartedSince(time.Time{}))
+}
+
+func TestEngineMonitorRetriesAndRecordsRestart(t *testing.T) {
+	d, calls := setupEngineDial(t, 3)
+	start := time.Now()
+
+	m := NewEngineMonitor(hclog.NewNullLogger())
+	dial := m.DialContext(d)
+
+	_, err := dial(context.Background(), "unix", "docker.sock")
+	assert.NoError(t, err)
+	assert.False(t, m.RestartedSince(start))
+
+	_, err = dial(context.Background(), "unix", "docker.sock")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 5, *calls)
+	assert.Equal(t, 1, m.Restarts())
+	assert.True(t, m.RestartedSince(start))
+	assert.False(t, m.RestartedSince(time.Now()))
+}
+
+func TestEngineMonitorReturnsErrorWhenEngineDoesNotRestart(t *testing.T) {
+	d, calls := setupEngineDial(t, 1000)
+
+	m := NewEngineMonitor(hclog.NewNullLogger())
+	dial := m.DialContext(d)
+
+	_, err := dial(context.Background(), "unix", "docker.sock")
+	assert.NoError(t, err)
+
+	_, err = dial(context.Background(), "unix", "docker.sock")
+	assert.Error(t, err)
+	assert.IsType(t, EngineUnavailableError{}, err)
+	assert.True(t, m.RestartedSince(time.Now()))
+
+	// once the timeout has passed further connections fail immediately
+	c := *calls
+	_, err = dial(context.Background(), "unix", "docker.sock")
+	assert.Error(t, err)
+	assert.Equal(t, c+1, *calls)
+}
+
+func TestEngineMonitorDoesNotRetryOtherErrors(t *testing.T) {
+	setupEngineDial(t, 0)
+
+	calls := 0
+	m := NewEngineMonitor(hclog.NewNullLogger())
+	dial := m.DialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		calls++
+		if calls == 1 {
+			c, _ := net.Pipe()
+			return c, nil
+		}
+
+		return nil, fmt.Errorf("permission denied")
+	})
+
+	dial(context.Background(), "unix", "docker.sock")
+	_, err := dial(context.Background(), "unix", "docker.sock")
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+}
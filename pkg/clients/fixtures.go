@@ -0,0 +1,390 @@
+package clients
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/docker/distribution/reference"
+)
+
+// FixturesIndexFile is the file in the fixtures directory which lists the recorded fetches
+const FixturesIndexFile = "fixtures.json"
+
+// FixtureFile is a file or folder which has been recorded in the fixtures directory
+type FixtureFile struct {
+	Path string `json:"path"` // location of the content relative to the fixtures directory
+	SHA  string `json:"sha"`  // sha256 of the content, used to detect modified fixtures
+}
+
+// FixturesIndex lists the external fetches recorded in a fixtures directory
+type FixturesIndex struct {
+	Images  map[string]string      `json:"images"`  // image name to the reference of the image digest e.g. consul:1.8.1 -> consul@sha256:...
+	Sources map[string]FixtureFile `json:"sources"` // blueprints and files fetched by the Getter keyed by URI
+	Charts  map[string]FixtureFile `json:"charts"`  // helm chart archives keyed by chart@version
+}
+
+// Fixtures records the external fetches made when creating resources, images resolved
+// to digests, blueprints and files, and Helm charts, into a fixtures directory. The
+// fixtures can be replayed so that later runs use exactly the same content without
+// resolving tags or downloading files, making runs of a blueprint in CI hermetic.
+//
+// Fixtures are disabled until Record or Replay is called, Recording, Replaying and
+// the Record methods can be called on a nil Fixtures.
+type Fixtures struct {
+	lock   sync.Mutex
+	dir    string
+	replay bool
+	index  *FixturesIndex
+}
+
+// NewFixtures creates a disabled Fixtures
+func NewFixtures() *Fixtures {
+	return &Fixtures{}
+}
+
+// Record writes the external fetches to the given directory, fetches are added
+// to any fixtures already recorded in the directory
+func (f *Fixtures) Record(dir string) error {
+	if f == nil {
+		return fmt.Errorf("fixtures are not supported")
+	}
+
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("unable to create fixtures directory %s: %s", dir, err)
+	}
+
+	i := newFixturesIndex()
+	if _, err := os.Stat(filepath.Join(dir, FixturesIndexFile)); err == nil {
+		i, err = loadFixturesIndex(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.dir = dir
+	f.replay = false
+	f.index = i
+
+	return nil
+}
+
+// Replay serves the external fetches from the fixtures recorded in the given
+// directory, fetches which have not been recorded fail
+func (f *Fixtures) Replay(dir string) error {
+	if f == nil {
+		return fmt.Errorf("fixtures are not supported")
+	}
+
+	i, err := loadFixturesIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.dir = dir
+	f.replay = true
+	f.index = i
+
+	return nil
+}
+
+// Recording returns true when fetches are being recorded
+func (f *Fixtures) Recording() bool {
+	if f == nil {
+		return false
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.index != nil && !f.replay
+}
+
+// Replaying returns true when fetches are replayed from the fixtures
+func (f *Fixtures) Replaying() bool {
+	if f == nil {
+		return false
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.index != nil && f.replay
+}
+
+// RecordImage records the digest an image name was resolved to, repoDigests
+// are the digests returned by the engine when inspecting the image
+func (f *Fixtures) RecordImage(image string, repoDigests []string) error {
+	if !f.Recording() {
+		return nil
+	}
+
+	d := imageDigest(image, repoDigests)
+	if d == "" {
+		return fmt.Errorf("image %s does not have a registry digest and can not be recorded", image)
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.index.Images[image] = d
+
+	return f.save()
+}
+
+// ImageDigest returns the reference of the digest recorded for an image
+func (f *Fixtures) ImageDigest(image string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	d, ok := f.index.Images[image]
+	if !ok {
+		return "", fmt.Errorf("image %s has not been recorded in the fixtures %s", image, f.dir)
+	}
+
+	return d, nil
+}
+
+// RecordSource copies the files fetched from uri to the fixtures
+func (f *Fixtures) RecordSource(uri, src string) error {
+	if !f.Recording() {
+		return nil
+	}
+
+	return f.recordFile(f.index.Sources, "sources", uri, src)
+}
+
+// ReplaySource copies the files recorded for uri to dst
+func (f *Fixtures) ReplaySource(uri, dst string) error {
+	p, err := f.fixturePath(f.index.Sources, uri)
+	if err != nil {
+		return err
+	}
+
+	return copyFixture(p, dst)
+}
+
+// RecordChart copies the chart archive downloaded for chart and version to the fixtures
+func (f *Fixtures) RecordChart(chart, version, src string) error {
+	if !f.Recording() {
+		return nil
+	}
+
+	return f.recordFile(f.index.Charts, "charts", chartKey(chart, version), src)
+}
+
+// ChartPath returns the location of the chart archive recorded for chart and version
+func (f *Fixtures) ChartPath(chart, version string) (string, error) {
+	return f.fixturePath(f.index.Charts, chartKey(chart, version))
+}
+
+// recordFile copies src into the folder for the given kind of fixture and adds it
+// to the index with the hash of the content
+func (f *Fixtures) recordFile(files map[string]FixtureFile, kind, key, src string) error {
+	sha, err := hashFixture(src)
+	if err != nil {
+		return fmt.Errorf("unable to hash %s: %s", src, err)
+	}
+
+	rel := filepath.Join(kind, sha[:16], filepath.Base(src))
+
+	dst := filepath.Join(f.dir, rel)
+	os.RemoveAll(dst)
+
+	err = copyFixture(src, dst)
+	if err != nil {
+		return fmt.Errorf("unable to record %s: %s", key, err)
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	files[key] = FixtureFile{Path: filepath.ToSlash(rel), SHA: sha}
+
+	return f.save()
+}
+
+// fixturePath returns the location of a recorded fixture, an error is returned
+// when the fixture has not been recorded or has been modified since it was recorded
+func (f *Fixtures) fixturePath(files map[string]FixtureFile, key string) (string, error) {
+	f.lock.Lock()
+	ff, ok := files[key]
+	f.lock.Unlock()
+
+	if !ok {
+		return "", fmt.Errorf("%s has not been recorded in the fixtures %s", key, f.dir)
+	}
+
+	p := filepath.Join(f.dir, filepath.FromSlash(ff.Path))
+
+	sha, err := hashFixture(p)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the fixture for %s: %s", key, err)
+	}
+
+	if sha != ff.SHA {
+		return "", fmt.Errorf("the fixture for %s has been modified, expected sha %s, got %s", key, ff.SHA, sha)
+	}
+
+	return p, nil
+}
+
+// save writes the index to the fixtures directory, the lock must be held
+func (f *Fixtures) save() error {
+	d, err := json.MarshalIndent(f.index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(f.dir, FixturesIndexFile), d, 0644)
+}
+
+func newFixturesIndex() *FixturesIndex {
+	return &FixturesIndex{
+		Images:  map[string]string{},
+		Sources: map[string]FixtureFile{},
+		Charts:  map[string]FixtureFile{},
+	}
+}
+
+func loadFixturesIndex(dir string) (*FixturesIndex, error) {
+	d, err := ioutil.ReadFile(filepath.Join(dir, FixturesIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read fixtures from %s: %s", dir, err)
+	}
+
+	i := newFixturesIndex()
+	err = json.Unmarshal(d, i)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse fixtures %s: %s", filepath.Join(dir, FixturesIndexFile), err)
+	}
+
+	return i, nil
+}
+
+func chartKey(chart, version string) string {
+	if version == "" {
+		return chart
+	}
+
+	return fmt.Sprintf("%s@%s", chart, version)
+}
+
+// imageDigest returns the repo digest for the repository of the image
+func imageDigest(image string, repoDigests []string) string {
+	in, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+
+	for _, rd := range repoDigests {
+		r, err := reference.ParseNormalizedNamed(rd)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := r.(reference.Canonical); ok && r.Name() == in.Name() {
+			return r.String()
+		}
+	}
+
+	return ""
+}
+
+// hashFixture returns the sha256 of a file or the files in a folder, files in a
+// folder are hashed in a stable order with their relative paths
+func hashFixture(src string) (string, error) {
+	h := sha256.New()
+
+	files := []string{}
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			files = append(files, p)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(files)
+
+	for _, p := range files {
+		rel, _ := filepath.Rel(src, p)
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+
+		fi, err := os.Open(p)
+		if err != nil {
+			return "", err
+		}
+
+		_, err = io.Copy(h, fi)
+		fi.Close()
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copyFixture copies a file or folder from src to dst
+func copyFixture(src, dst string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(src, p)
+		t := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(t, info.Mode()|0700)
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		err = os.MkdirAll(filepath.Dir(t), os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(t, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		_, err = io.Copy(out, in)
+		return err
+	})
+}
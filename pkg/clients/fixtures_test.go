@@ -0,0 +1,162 @@
+package clients
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFixtureSource(t *testing.T) string {
+	src := filepath.Join(t.TempDir(), "consul")
+	err := os.MkdirAll(filepath.Join(src, "modules"), os.ModePerm)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(src, "main.hcl"), []byte(`container "consul" {}`), 0644)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(src, "modules", "vars.hcl"), []byte(`variable "version" {}`), 0644)
+	assert.NoError(t, err)
+
+	return src
+}
+
+func TestFixturesRecordsAndReplaysSource(t *testing.T) {
+	dir := t.TempDir()
+	src := writeFixtureSource(t)
+	uri := "github.com/shipyard-run/blueprints//consul?ref=v0.1.0"
+
+	f := NewFixtures()
+	err := f.Record(dir)
+	assert.NoError(t, err)
+	assert.True(t, f.Recording())
+
+	err = f.RecordSource(uri, src)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, FixturesIndexFile))
+
+	r := NewFixtures()
+	err = r.Replay(dir)
+	assert.NoError(t, err)
+	assert.True(t, r.Replaying())
+
+	dst := filepath.Join(t.TempDir(), "consul")
+	err = r.ReplaySource(uri, dst)
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(filepath.Join(dst, "modules", "vars.hcl"))
+	assert.NoError(t, err)
+	assert.Equal(t, `variable "version" {}`, string(d))
+}
+
+func TestFixturesReplayReturnsErrorWhenModified(t *testing.T) {
+	dir := t.TempDir()
+	src := writeFixtureSource(t)
+	uri := "github.com/shipyard-run/blueprints//consul?ref=v0.1.0"
+
+	f := NewFixtures()
+	err := f.Record(dir)
+	assert.NoError(t, err)
+
+	err = f.RecordSource(uri, src)
+	assert.NoError(t, err)
+
+	ff := f.index.Sources[uri]
+	err = ioutil.WriteFile(filepath.Join(dir, ff.Path, "main.hcl"), []byte(`container "vault" {}`), 0644)
+	assert.NoError(t, err)
+
+	r := NewFixtures()
+	err = r.Replay(dir)
+	assert.NoError(t, err)
+
+	err = r.ReplaySource(uri, filepath.Join(t.TempDir(), "consul"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "has been modified")
+}
+
+func TestFixturesReplayReturnsErrorWhenNotRecorded(t *testing.T) {
+	f := NewFixtures()
+	err := f.Record(t.TempDir())
+	assert.NoError(t, err)
+
+	err = f.ReplaySource("github.com/shipyard-run/blueprints//vault", t.TempDir())
+	assert.Error(t, err)
+
+	_, err = f.ChartPath("hashicorp/vault", "0.20.0")
+	assert.Error(t, err)
+}
+
+func TestFixturesRecordsChart(t *testing.T) {
+	dir := t.TempDir()
+	chart := filepath.Join(t.TempDir(), "vault-0.20.0.tgz")
+	err := ioutil.WriteFile(chart, []byte("chart"), 0644)
+	assert.NoError(t, err)
+
+	f := NewFixtures()
+	err = f.Record(dir)
+	assert.NoError(t, err)
+
+	err = f.RecordChart("hashicorp/vault", "0.20.0", chart)
+	assert.NoError(t, err)
+
+	r := NewFixtures()
+	err = r.Replay(dir)
+	assert.NoError(t, err)
+
+	p, err := r.ChartPath("hashicorp/vault", "0.20.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-0.20.0.tgz", filepath.Base(p))
+	assert.FileExists(t, p)
+}
+
+func TestFixturesRecordAddsToExistingFixtures(t *testing.T) {
+	dir := t.TempDir()
+
+	f := NewFixtures()
+	err := f.Record(dir)
+	assert.NoError(t, err)
+
+	err = f.RecordImage("consul:1.8.1", []string{"consul@sha256:4fdb8a3dab7df2d0a6b4bd6e44d8fe0a0e1d2f1a8e1c1f5e7c3b2a190817e6d5"})
+	assert.NoError(t, err)
+
+	f = NewFixtures()
+	err = f.Record(dir)
+	assert.NoError(t, err)
+
+	err = f.RecordImage("vault:1.6.1", []string{"vault@sha256:9d1c3b4ab6e0a1f4d1c2e3b4a5f6e7d8c9b0a1f2e3d4c5b6a7f8e9d0c1b2a3f4"})
+	assert.NoError(t, err)
+
+	_, err = f.ImageDigest("consul:1.8.1")
+	assert.NoError(t, err)
+
+	_, err = f.ImageDigest("vault:1.6.1")
+	assert.NoError(t, err)
+}
+
+func TestFixturesRecordImageReturnsErrorWithoutDigest(t *testing.T) {
+	f := NewFixtures()
+	err := f.Record(t.TempDir())
+	assert.NoError(t, err)
+
+	// digests for other repositories are ignored
+	err = f.RecordImage("consul:1.8.1", []string{"vault@sha256:9d1c3b4ab6e0a1f4d1c2e3b4a5f6e7d8c9b0a1f2e3d4c5b6a7f8e9d0c1b2a3f4"})
+	assert.Error(t, err)
+}
+
+func TestFixturesReplayReturnsErrorWhenIndexMissing(t *testing.T) {
+	f := NewFixtures()
+	err := f.Replay(t.TempDir())
+	assert.Error(t, err)
+	assert.False(t, f.Replaying())
+}
+
+func TestFixturesDisabledDoesNotRecord(t *testing.T) {
+	var f *Fixtures
+	assert.False(t, f.Recording())
+	assert.False(t, f.Replaying())
+
+	err := f.RecordSource("github.com/shipyard-run/blueprints//consul", t.TempDir())
+	assert.NoError(t, err)
+}
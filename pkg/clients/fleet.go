@@ -0,0 +1,190 @@
+package clients
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// EnvironmentHealthy is reported when all the resources of an environment have been applied
+const EnvironmentHealthy = "healthy"
+
+// EnvironmentDegraded is reported when one or more resources of an environment have failed
+const EnvironmentDegraded = "degraded"
+
+// EnvironmentUnreachable is reported when the connector of a remote machine can not be reached
+const EnvironmentUnreachable = "unreachable"
+
+// Environment describes the environment running on a machine, it is returned by
+// the /environment endpoint of the connector API so that 'shipyard fleet list'
+// can list the environments on all the joined machines
+type Environment struct {
+	Host      string `json:"host"`
+	Owner     string `json:"owner"`
+	Blueprint string `json:"blueprint,omitempty"`
+	// Created is the time of the first apply since the environment was last destroyed
+	Created   *time.Time `json:"created,omitempty"`
+	Resources int        `json:"resources"`
+	Failed    int        `json:"failed"`
+	Health    string     `json:"health"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// LocalEnvironment returns the environment described by the state at statePath,
+// the blueprint and the creation time are read from the history, h can be nil.
+// An environment with no resources is returned when the state does not exist.
+func LocalEnvironment(statePath string, h History) (*Environment, error) {
+	env := &Environment{Host: utils.GetHostname(), Owner: currentOwner(), Health: EnvironmentHealthy}
+
+	c := config.New()
+	err := c.FromJSON(statePath)
+	if err != nil && err != config.StateNotFoundError {
+		return nil, fmt.Errorf("unable to load state: %s", err)
+	}
+
+	for _, r := range c.Resources {
+		if r.Info().Status == config.Destroyed {
+			continue
+		}
+
+		env.Resources++
+		if r.Info().Status == config.Failed {
+			env.Failed++
+		}
+	}
+
+	if env.Failed > 0 {
+		env.Health = EnvironmentDegraded
+	}
+
+	if c.Blueprint != nil {
+		env.Blueprint = c.Blueprint.Title
+	}
+
+	if h == nil || env.Resources == 0 {
+		return env, nil
+	}
+
+	entries, err := h.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read history: %s", err)
+	}
+
+	// the environment was created by the first apply after the last destroy
+	for _, e := range entries {
+		switch e.Command {
+		case "destroy":
+			if e.Result == HistoryResultSuccess {
+				env.Created = nil
+			}
+		case "apply":
+			if env.Created == nil {
+				t := e.Time
+				env.Created = &t
+			}
+
+			if e.Blueprint != "" {
+				env.Blueprint = e.Blueprint
+			}
+		}
+	}
+
+	return env, nil
+}
+
+// GetRemoteEnvironment returns the environment running on the machine of a joined
+// connector, dir is the folder containing the certificates of the local connector.
+// The environment is requested over mTLS, the local leaf certificate is presented
+// and only the CA exchanged when the machine was joined is trusted. An unreachable
+// environment is returned when the environment can not be requested.
+func GetRemoteEnvironment(dir string, rc *RemoteConnector, timeout time.Duration) *Environment {
+	env := &Environment{Host: rc.Name, Health: EnvironmentUnreachable}
+
+	if rc.EnvironmentAddress == "" {
+		env.Error = "the machine was joined by an older version of Shipyard, join it again to report its environment"
+		return env
+	}
+
+	tc, err := remoteTLSConfig(dir, rc)
+	if err != nil {
+		env.Error = err.Error()
+		return env
+	}
+
+	client := http.Client{Timeout: timeout, Transport: &http.Transport{TLSClientConfig: tc}}
+
+	resp, err := client.Get(fmt.Sprintf("https://%s/environment", rc.EnvironmentAddress))
+	if err != nil {
+		env.Error = err.Error()
+		return env
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		env.Error = fmt.Sprintf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return env
+	}
+
+	re := &Environment{}
+	err = json.NewDecoder(resp.Body).Decode(re)
+	if err != nil {
+		env.Error = fmt.Sprintf("unable to decode environment: %s", err)
+		return env
+	}
+
+	// the machine is listed with the name it was joined with
+	re.Host = rc.Name
+
+	return re
+}
+
+// remoteTLSConfig returns the TLS config which presents the leaf certificate of the
+// local connector and only trusts the CA imported when the remote connector was joined
+func remoteTLSConfig(dir string, rc *RemoteConnector) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, "leaf.cert"), filepath.Join(dir, "leaf.key"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the local certificate: %s", err)
+	}
+
+	ca, err := ioutil.ReadFile(filepath.Join(TrustedCertsDir(dir), rc.Name+".cert"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the CA of %s: %s", rc.Name, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid CA for %s", rc.Name)
+	}
+
+	host, _, err := net.SplitHostPort(rc.EnvironmentAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		ServerName:   host,
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+	}, nil
+}
+
+func currentOwner() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
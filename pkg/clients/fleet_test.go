@@ -0,0 +1,162 @@
+package clients
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupFleetTests(t *testing.T, statuses ...config.Status) *HistoryMock {
+	home := os.Getenv(utils.HomeEnvName())
+	os.Setenv(utils.HomeEnvName(), t.TempDir())
+	t.Cleanup(func() {
+		os.Setenv(utils.HomeEnvName(), home)
+	})
+
+	if len(statuses) > 0 {
+		c := config.New()
+		for i, s := range statuses {
+			r := config.NewContainer(string(rune('a' + i)))
+			r.Status = s
+			c.AddResource(r)
+		}
+
+		err := c.ToJSON(utils.StatePath())
+		assert.NoError(t, err)
+	}
+
+	created := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	hm := &HistoryMock{}
+	hm.On("Read").Return([]HistoryEntry{
+		{Time: created.Add(-48 * time.Hour), Command: "apply", Blueprint: "github.com/org/old", Result: HistoryResultSuccess},
+		{Time: created.Add(-24 * time.Hour), Command: "destroy", Blueprint: "github.com/org/old", Result: HistoryResultSuccess},
+		{Time: created, Command: "apply", Blueprint: "github.com/org/consul", Result: HistoryResultSuccess},
+		{Time: created.Add(time.Hour), Command: "apply", Blueprint: "github.com/org/consul", Result: HistoryResultSuccess},
+	}, nil)
+
+	return hm
+}
+
+func TestLocalEnvironmentReturnsHealthyEnvironment(t *testing.T) {
+	hm := setupFleetTests(t, config.Applied, config.Applied)
+
+	env, err := LocalEnvironment(utils.StatePath(), hm)
+	assert.NoError(t, err)
+
+	assert.Equal(t, utils.GetHostname(), env.Host)
+	assert.NotEmpty(t, env.Owner)
+	assert.Equal(t, 2, env.Resources)
+	assert.Equal(t, EnvironmentHealthy, env.Health)
+	assert.Equal(t, "github.com/org/consul", env.Blueprint)
+	assert.Equal(t, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), env.Created.UTC())
+}
+
+func TestLocalEnvironmentReturnsDegradedWithFailedResources(t *testing.T) {
+	hm := setupFleetTests(t, config.Applied, config.Failed)
+
+	env, err := LocalEnvironment(utils.StatePath(), hm)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, env.Failed)
+	assert.Equal(t, EnvironmentDegraded, env.Health)
+}
+
+func TestLocalEnvironmentReturnsEmptyWithNoState(t *testing.T) {
+	hm := setupFleetTests(t)
+
+	env, err := LocalEnvironment(utils.StatePath(), hm)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, env.Resources)
+	assert.Nil(t, env.Created)
+	hm.AssertNotCalled(t, "Read")
+}
+
+// setupRemoteEnvironment joins a laptop to a lab machine and starts an mTLS server on
+// the lab machine which serves the environment, the laptop connector is returned
+func setupRemoteEnvironment(t *testing.T, env *Environment) (string, *RemoteConnector) {
+	lab, labDir := setupJoinTests(t)
+	lab.options.EnvironmentBind = ":30004"
+	laptop, laptopDir := setupLaptop(t)
+
+	jt, err := lab.CreateJoinToken(labDir, "127.0.0.1")
+	assert.NoError(t, err)
+
+	rc, err := laptop.Join(laptopDir, setupJoinServer(t, lab, labDir, nil), jt.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:30004", rc.EnvironmentAddress)
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join(labDir, "leaf.cert"), filepath.Join(labDir, "leaf.key"))
+	assert.NoError(t, err)
+
+	ca, err := ioutil.ReadFile(filepath.Join(TrustedCertsDir(labDir), rc.Name+".cert"))
+	assert.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/environment", r.URL.Path)
+		json.NewEncoder(rw).Encode(env)
+	}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	rc.EnvironmentAddress = strings.TrimPrefix(ts.URL, "https://")
+
+	return laptopDir, rc
+}
+
+func TestGetRemoteEnvironmentUsesMutualTLS(t *testing.T) {
+	dir, rc := setupRemoteEnvironment(t, &Environment{Host: "lab-01.internal", Owner: "nic", Resources: 3, Health: EnvironmentHealthy})
+
+	env := GetRemoteEnvironment(dir, rc, time.Second)
+
+	assert.Empty(t, env.Error)
+	assert.Equal(t, rc.Name, env.Host)
+	assert.Equal(t, "nic", env.Owner)
+	assert.Equal(t, 3, env.Resources)
+	assert.Equal(t, EnvironmentHealthy, env.Health)
+}
+
+func TestGetRemoteEnvironmentRejectsMachineWhichHasNotJoined(t *testing.T) {
+	laptopDir, rc := setupRemoteEnvironment(t, &Environment{Owner: "nic", Resources: 3, Health: EnvironmentHealthy})
+
+	// a machine which has not joined trusts the lab CA but presents a certificate
+	// signed by a CA which the lab does not trust
+	other := t.TempDir()
+	_, err := NewConnector(ConnectorOptions{GrpcBind: ":30001"}).(*ConnectorImpl).GenerateLocalCertBundle(other)
+	assert.NoError(t, err)
+
+	ca, err := ioutil.ReadFile(filepath.Join(TrustedCertsDir(laptopDir), rc.Name+".cert"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(TrustedCertsDir(other), os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(TrustedCertsDir(other), rc.Name+".cert"), ca, 0644))
+
+	env := GetRemoteEnvironment(other, rc, time.Second)
+
+	assert.Equal(t, EnvironmentUnreachable, env.Health)
+	assert.Empty(t, env.Owner)
+}
+
+func TestGetRemoteEnvironmentReturnsUnreachableWhenJoinedByOlderVersion(t *testing.T) {
+	env := GetRemoteEnvironment(t.TempDir(), &RemoteConnector{Name: "lab", APIAddress: "lab.local:30003"}, time.Second)
+
+	assert.Equal(t, "lab", env.Host)
+	assert.Equal(t, EnvironmentUnreachable, env.Health)
+	assert.Contains(t, env.Error, "join it again")
+}
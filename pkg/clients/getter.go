@@ -2,7 +2,9 @@ package clients
 
 import (
 	"context"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/hashicorp/go-getter"
 	"golang.org/x/xerrors"
@@ -18,15 +20,18 @@ type Getter interface {
 // GetterImpl is a concrete implementation of the Getter interface
 type GetterImpl struct {
 	//
-	force bool
-	get   func(uri, dst, pwd string) error
+	force    bool
+	get      func(uri, dst, pwd string) error
+	resolve  func(src, pwd string) (string, string, error)
+	fixtures *Fixtures
+	lock     *SourceLock
 }
 
 // NewGetter creates a new Getter
 func NewGetter(force bool) *GetterImpl {
 	gi := &GetterImpl{
-		force,
-		func(uri, dst, pwd string) error {
+		force: force,
+		get: func(uri, dst, pwd string) error {
 			// if the argument is a url fetch it first
 			c := &getter.Client{
 				Ctx:     context.Background(),
@@ -34,35 +39,105 @@ func NewGetter(force bool) *GetterImpl {
 				Dst:     dst,
 				Pwd:     pwd,
 				Mode:    getter.ClientModeAny,
+				Getters: getters(),
 				Options: []getter.ClientOption{},
 			}
 
 			return c.Get()
 		},
+		resolve: resolveGitCommit,
 	}
 
 	return gi
 }
 
+// getters returns the getters used to fetch files, git repositories are cloned
+// with the access token for the host and blueprints are pulled from OCI
+// registries. When trusted CAs have been set the HTTP getter uses a client
+// which trusts the CAs
+func getters() map[string]getter.Getter {
+	g := map[string]getter.Getter{}
+	for k, v := range getter.Getters {
+		g[k] = v
+	}
+
+	g["git"] = &authGitGetter{}
+	g["oci"] = &ociGetter{registry: NewBlueprintRegistry()}
+
+	if t := trustedTransport(); t != nil {
+		hg := &getter.HttpGetter{Netrc: true, Client: &http.Client{Transport: t}}
+		g["http"] = hg
+		g["https"] = hg
+	}
+
+	return g
+}
+
 // SetForce sets the force flag causing all downloads to overwrite the destination
 func (g *GetterImpl) SetForce(force bool) {
 	g.force = force
 }
 
+// SetFixtures sets the fixtures downloaded files are recorded to, or replayed from
+func (g *GetterImpl) SetFixtures(f *Fixtures) {
+	g.fixtures = f
+}
+
+// SetLock sets the lock file which records the version of the fetched sources
+func (g *GetterImpl) SetLock(l *SourceLock) {
+	g.lock = l
+}
+
 // Get attempts to retrieve a folder
 // from a remote location and stores it at the destination.
 //
 // If force was set to true when creating a Getter then
 // the destination folder will automatically be overwritten.
 //
+// When a lock has been set the commit of git sources is recorded in the lock,
+// when the destination folder has been removed the source is fetched again
+// at the locked commit unless force is set.
+//
+// When replaying fixtures the files recorded for the uri are always copied
+// to the destination and nothing is downloaded.
+//
 // Returns error on failure
 func (g *GetterImpl) Get(uri, dst string) error {
+	if g.fixtures.Replaying() {
+		err := os.RemoveAll(dst)
+		if err != nil {
+			return xerrors.Errorf("Destination folder exists, unable to delete: %w", err)
+		}
+
+		return g.fixtures.ReplaySource(uri, dst)
+	}
+
+	err := g.fetch(uri, dst)
+	if err != nil {
+		return err
+	}
+
+	return g.fixtures.RecordSource(uri, dst)
+}
+
+func (g *GetterImpl) fetch(uri, dst string) error {
+	locked, err := g.lock.Find(uri)
+	if err != nil {
+		return err
+	}
+
 	// check to see if a folder exists at the destination and exit if force is not
 	// equal to true
-	_, err := os.Stat(dst)
+	_, err = os.Stat(dst)
 	if err == nil {
 		// we already have files at the destination do we want to overwrite?
 		if g.force == false {
+			// sources cached before the lock was used are added with the
+			// commit which is checked out
+			if g.lock != nil && locked == nil {
+				return g.lock.Add(LockedSource{Source: uri, Path: dst, Commit: localGitCommit(dst), FetchedAt: time.Now()})
+			}
+
 			return nil
 		}
 
@@ -77,10 +152,34 @@ func (g *GetterImpl) Get(uri, dst string) error {
 		return err
 	}
 
-	err = g.get(uri, dst, pwd)
+	src := normalizeSource(uri)
+	ls := LockedSource{Source: uri, Path: dst}
+
+	if g.lock != nil {
+		if locked != nil && locked.Commit != "" && !g.force {
+			// the cached copy has been removed, fetch the version in the lock
+			ls.Ref = locked.Ref
+			ls.Commit = locked.Commit
+			src = withRef(src, locked.Commit)
+		} else {
+			ls.Ref, ls.Commit, err = g.resolve(src, pwd)
+			if err != nil {
+				return xerrors.Errorf("unable to fetch files from %s: %w", uri, err)
+			}
+
+			// fetch the resolved commit so that the lock matches the files
+			if ls.Commit != "" {
+				src = withRef(src, ls.Commit)
+			}
+		}
+	}
+
+	err = g.get(src, dst, pwd)
 	if err != nil {
 		return xerrors.Errorf("unable to fetch files from %s: %w", uri, err)
 	}
 
-	return nil
+	ls.FetchedAt = time.Now()
+
+	return g.lock.Add(ls)
 }
@@ -0,0 +1,225 @@
+package clients
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-getter"
+)
+
+// sourcePrefixes maps the git URI schemes used by other tools such as pip
+// to the forced getters used by go-getter
+var sourcePrefixes = map[string]string{
+	"git+ssh://":   "git::ssh://",
+	"git+https://": "git::https://",
+}
+
+// gitTokens are the environment variables containing the access token
+// used to clone private repositories from a host over HTTPS, and the
+// username which is sent with the token
+var gitTokens = map[string]struct{ env, username string }{
+	"github.com": {"GITHUB_TOKEN", "x-access-token"},
+	"gitlab.com": {"GITLAB_TOKEN", "oauth2"},
+}
+
+// authErrors are the messages written by git and ssh when the credentials
+// for a repository are missing or are not valid
+var authErrors = []string{
+	"Authentication failed",
+	"could not read Username",
+	"terminal prompts disabled",
+	"Permission denied (publickey",
+	"Host key verification failed",
+	"Repository not found",
+	"The requested URL returned error: 401",
+	"The requested URL returned error: 403",
+}
+
+// GetterAuthError is returned when a source can not be fetched as the
+// credentials for the repository are missing or are not valid
+type GetterAuthError struct {
+	URL    string
+	SSH    bool // true when the repository was fetched with SSH
+	Reason string
+}
+
+func (e GetterAuthError) Error() string {
+	help := "set GITHUB_TOKEN or GITLAB_TOKEN, add the credentials for the host to ~/.netrc, or use an SSH URL e.g. git::ssh://git@github.com/org/repo"
+	if e.SSH {
+		help = "check that your SSH key has access to the repository and has been added to the SSH agent with ssh-add"
+		if os.Getenv("SSH_AUTH_SOCK") == "" {
+			help = "no SSH agent is running, start ssh-agent and add your key with ssh-add"
+		}
+	}
+
+	return fmt.Sprintf("Unable to authenticate with the repository %s, the repository does not exist or the credentials are not valid, %s: %s", e.URL, help, e.Reason)
+}
+
+// normalizeSource converts the git URI schemes used by other tools to the
+// format used by go-getter e.g. git+ssh://git@github.com/org/repo becomes
+// git::ssh://git@github.com/org/repo
+//
+// S3 sources which only contain the bucket and key e.g. s3::bucket/key are
+// converted to the S3 URL for the region set in AWS_REGION
+func normalizeSource(uri string) string {
+	for p, r := range sourcePrefixes {
+		if strings.HasPrefix(uri, p) {
+			return r + strings.TrimPrefix(uri, p)
+		}
+	}
+
+	if strings.HasPrefix(uri, "s3::") && !strings.Contains(uri, "://") {
+		return "s3::https://" + s3Host() + "/" + strings.TrimPrefix(uri, "s3::")
+	}
+
+	return uri
+}
+
+// s3Host returns the path style S3 host for the region in the environment,
+// go-getter reads the region from the host
+func s3Host() string {
+	r := os.Getenv("AWS_REGION")
+	if r == "" {
+		r = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	if r == "" {
+		return "s3.amazonaws.com"
+	}
+
+	return "s3-" + r + ".amazonaws.com"
+}
+
+// authGitGetter is a git getter which adds the access token for the host
+// to HTTPS URLs, SSH URLs use the keys in the users SSH agent.
+// git is run without prompting for credentials so that a missing
+// credential returns an error rather than waiting for input.
+type authGitGetter struct {
+	getter.GitGetter
+}
+
+// Get clones the repository to dst, the token is removed from the remote
+// of the cloned repository so that it is not stored on disk
+func (g *authGitGetter) Get(dst string, u *url.URL) error {
+	disableGitPrompts()
+
+	au, token := withGitToken(u)
+
+	err := g.GitGetter.Get(dst, au)
+	if err != nil {
+		return gitError(u, token, err)
+	}
+
+	if token != "" {
+		if _, err := os.Stat(filepath.Join(dst, ".git")); err == nil {
+			return removeGitCredentials(dst)
+		}
+	}
+
+	return nil
+}
+
+// GetFile downloads a single file from the repository, the repository
+// is cloned to a temporary folder which is removed
+func (g *authGitGetter) GetFile(dst string, u *url.URL) error {
+	disableGitPrompts()
+
+	au, token := withGitToken(u)
+
+	err := g.GitGetter.GetFile(dst, au)
+	if err != nil {
+		return gitError(u, token, err)
+	}
+
+	return nil
+}
+
+// withGitToken returns a copy of the URL with the access token for the host
+// and the token, the URL is not changed when it is not HTTPS, already has
+// credentials, or no token is set for the host
+func withGitToken(u *url.URL) (*url.URL, string) {
+	if u.Scheme != "https" || u.User != nil {
+		return u, ""
+	}
+
+	t, ok := gitTokens[u.Hostname()]
+	if !ok {
+		return u, ""
+	}
+
+	token := os.Getenv(t.env)
+	if token == "" {
+		return u, ""
+	}
+
+	au := *u
+	au.User = url.UserPassword(t.username, token)
+
+	return &au, token
+}
+
+// removeGitCredentials removes the credentials from the URL of the
+// origin remote for the repository
+func removeGitCredentials(dir string) error {
+	cmd := exec.Command("git", "remote", "get-url", "origin")
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("unable to read the remote for repository %s: %s", dir, err)
+	}
+
+	u, err := url.Parse(strings.TrimSpace(string(out)))
+	if err != nil {
+		return fmt.Errorf("unable to parse the remote for repository %s: %s", dir, err)
+	}
+
+	u.User = nil
+
+	cmd = exec.Command("git", "remote", "set-url", "origin", u.String())
+	cmd.Dir = dir
+
+	out, err = cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to remove credentials from repository %s: %s", dir, out)
+	}
+
+	return nil
+}
+
+// gitError returns a GetterAuthError when the error from git is caused by
+// missing or invalid credentials, the token is removed from the error
+func gitError(u *url.URL, token string, err error) error {
+	msg := err.Error()
+	if token != "" {
+		msg = strings.ReplaceAll(msg, token, "***")
+	}
+
+	for _, a := range authErrors {
+		if strings.Contains(msg, a) {
+			return GetterAuthError{URL: u.Redacted(), SSH: u.Scheme == "ssh", Reason: msg}
+		}
+	}
+
+	if token != "" {
+		return fmt.Errorf("%s", msg)
+	}
+
+	return err
+}
+
+// disableGitPrompts stops git and ssh from prompting for credentials or to
+// accept host keys, settings in the users environment are not changed
+func disableGitPrompts() {
+	if _, ok := os.LookupEnv("GIT_TERMINAL_PROMPT"); !ok {
+		os.Setenv("GIT_TERMINAL_PROMPT", "0")
+	}
+
+	if _, ok := os.LookupEnv("GIT_SSH_COMMAND"); !ok {
+		os.Setenv("GIT_SSH_COMMAND", "ssh -o BatchMode=yes")
+	}
+}
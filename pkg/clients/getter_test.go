@@ -84,3 +84,100 @@ func TestGetFunctional(t *testing.T) {
 	assert.NoError(t, err)
 	assert.FileExists(t, filepath.Join(dir, "values.yaml"))
 }
+
+func TestGetReplaysFromFixturesWithoutFetching(t *testing.T) {
+	tmpDir, g, gs, _ := setupGetter(t, false, nil)
+	url := "github.com/shipyard-run/blueprints//consul-nomad?ref=v0.0.1"
+
+	fd := t.TempDir()
+	f := NewFixtures()
+	err := f.Record(fd)
+	assert.NoError(t, err)
+
+	err = f.RecordSource(url, writeFixtureSource(t))
+	assert.NoError(t, err)
+
+	err = f.Replay(fd)
+	assert.NoError(t, err)
+
+	g.(*GetterImpl).SetFixtures(f)
+
+	outDir := filepath.Join(tmpDir, "consul")
+	err = g.Get(url, outDir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "", *gs)
+	assert.FileExists(t, filepath.Join(outDir, "main.hcl"))
+}
+
+func setupLockedGetter(t *testing.T, force bool, commit string) (*GetterImpl, *SourceLock, *string) {
+	_, gi, gs, _ := setupGetter(t, force, nil)
+
+	g := gi.(*GetterImpl)
+	g.resolve = func(src, pwd string) (string, string, error) {
+		return "main", commit, nil
+	}
+
+	l := NewSourceLock(filepath.Join(t.TempDir(), "sources.lock.json"))
+	g.SetLock(l)
+
+	return g, l, gs
+}
+
+func TestGetRecordsCommitInLock(t *testing.T) {
+	g, l, gs := setupLockedGetter(t, false, "abc")
+	outDir := filepath.Join(t.TempDir(), "consul")
+	url := "github.com/shipyard-run/blueprints//consul-nomad?ref=main"
+
+	err := g.Get(url, outDir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "github.com/shipyard-run/blueprints//consul-nomad?ref=abc", *gs)
+
+	s, _ := l.Find(url)
+	assert.Equal(t, "abc", s.Commit)
+	assert.Equal(t, "main", s.Ref)
+	assert.Equal(t, outDir, s.Path)
+}
+
+func TestGetFetchesLockedCommitWhenCacheRemoved(t *testing.T) {
+	g, l, gs := setupLockedGetter(t, false, "def")
+	outDir := filepath.Join(t.TempDir(), "consul")
+	url := "github.com/shipyard-run/blueprints//consul-nomad?ref=main"
+	l.Add(LockedSource{Source: url, Path: outDir, Ref: "main", Commit: "abc"})
+
+	err := g.Get(url, outDir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "github.com/shipyard-run/blueprints//consul-nomad?ref=abc", *gs)
+}
+
+func TestGetWithForceUpdatesLockedCommit(t *testing.T) {
+	g, l, gs := setupLockedGetter(t, true, "def")
+	outDir := filepath.Join(t.TempDir(), "consul")
+	url := "github.com/shipyard-run/blueprints//consul-nomad?ref=main"
+	l.Add(LockedSource{Source: url, Path: outDir, Ref: "main", Commit: "abc"})
+
+	err := g.Get(url, outDir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "github.com/shipyard-run/blueprints//consul-nomad?ref=def", *gs)
+
+	s, _ := l.Find(url)
+	assert.Equal(t, "def", s.Commit)
+}
+
+func TestGetAddsExistingCacheToLock(t *testing.T) {
+	g, l, gs := setupLockedGetter(t, false, "def")
+	outDir := filepath.Join(t.TempDir(), "consul")
+	url := "github.com/shipyard-run/blueprints//consul-nomad?ref=main"
+	os.MkdirAll(outDir, os.ModePerm)
+
+	err := g.Get(url, outDir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "", *gs)
+
+	s, _ := l.Find(url)
+	assert.NotNil(t, s)
+}
@@ -2,9 +2,12 @@ package clients
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/pkg/errors"
@@ -18,7 +21,11 @@ import (
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/apimachinery/pkg/api/meta"
+	cliresource "k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
 )
 
 var helmLock sync.Mutex
@@ -31,14 +38,20 @@ func init() {
 
 // Helm defines an interface for a client which can manage Helm charts
 type Helm interface {
-	// CreateFromRepository creates a Helm install from a repository
-	Create(kubeConfig, name, namespace string, createNamespace bool, skipCRDs bool, chart, version, valuesPath string, valuesString map[string]string) error
+	// CreateFromRepository creates a Helm install from a repository, chart can be a local path,
+	// a chart in a configured repository e.g. hashicorp/vault, or an OCI reference e.g. oci://ghcr.io/org/charts/vault
+	Create(kubeConfig, name, namespace string, createNamespace bool, skipCRDs bool, chart, version, valuesPath string, valuesMap map[string]interface{}, valuesString map[string]string) error
 
-	// Destroy the given chart
-	Destroy(kubeConfig, name, namespace string) error
+	// Destroy the given chart and wait for the chart resources to be removed from the cluster,
+	// when force is true finalizers are removed from any resources remaining after the timeout
+	Destroy(kubeConfig, name, namespace string, timeout time.Duration, force bool) error
 
-	//UpsertChartRepository configures the remote chart repository
-	UpsertChartRepository(name, url string) error
+	//UpsertChartRepository configures the remote chart repository, username and password are
+	// optional and are used to authenticate with private repositories
+	UpsertChartRepository(name, url, username, password string) error
+
+	// RegistryLogin stores the credentials for an OCI registry which are used when pulling charts
+	RegistryLogin(url, username, password string) error
 }
 
 type HelmImpl struct {
@@ -47,6 +60,32 @@ type HelmImpl struct {
 	cachePath  string
 	dataPath   string
 	configPath string
+	fixtures   *Fixtures
+}
+
+// locateChart returns the path of the chart, charts from repositories and OCI
+// registries are recorded to the fixtures, or read from them when replaying
+func (h *HelmImpl) locateChart(cpa action.ChartPathOptions, chart, version string, settings *cli.EnvSettings) (string, error) {
+	// local charts, including charts fetched by the getter, are not downloaded
+	if _, err := os.Stat(chart); err == nil {
+		return cpa.LocateChart(chart, settings)
+	}
+
+	if h.fixtures.Replaying() {
+		return h.fixtures.ChartPath(chart, version)
+	}
+
+	cp, err := cpa.LocateChart(chart, settings)
+	if err != nil {
+		return "", err
+	}
+
+	return cp, h.fixtures.RecordChart(chart, version, cp)
+}
+
+// registryCredentials returns the location of the credentials for OCI registries
+func (h *HelmImpl) registryCredentials() string {
+	return path.Join(h.configPath, "registry.json")
 }
 
 func NewHelm(l hclog.Logger) Helm {
@@ -71,10 +110,16 @@ func NewHelm(l hclog.Logger) Helm {
 	// try to load the default config
 	helmStorage, _ = repo.LoadFile(helmRepoConfig)
 
-	return &HelmImpl{l, helmRepoConfig, helmCachePath, helmDataPath, helmConfigPath}
+	return &HelmImpl{log: l, repoPath: helmRepoConfig, cachePath: helmCachePath, dataPath: helmDataPath, configPath: helmConfigPath}
 }
 
-func (h *HelmImpl) Create(kubeConfig, name, namespace string, createNamespace bool, skipCRDs bool, chart, version, valuesPath string, valuesString map[string]string) error {
+// SetFixtures sets the fixtures the charts downloaded from repositories are recorded to,
+// or replayed from
+func (h *HelmImpl) SetFixtures(f *Fixtures) {
+	h.fixtures = f
+}
+
+func (h *HelmImpl) Create(kubeConfig, name, namespace string, createNamespace bool, skipCRDs bool, chart, version, valuesPath string, valuesMap map[string]interface{}, valuesString map[string]string) error {
 	// set the kubeclient for Helm
 	s := kube.GetConfig(kubeConfig, "default", namespace)
 	cfg := &action.Configuration{}
@@ -86,6 +131,12 @@ func (h *HelmImpl) Create(kubeConfig, name, namespace string, createNamespace bo
 		return xerrors.Errorf("unable to initialize Helm: %w", err)
 	}
 
+	// the registry client is required to pull charts from OCI registries
+	cfg.RegistryClient, err = registry.NewClient(registry.ClientOptCredentialsFile(h.registryCredentials()))
+	if err != nil {
+		return xerrors.Errorf("unable to create Helm registry client: %w", err)
+	}
+
 	client := action.NewInstall(cfg)
 	client.ReleaseName = name
 	client.Namespace = namespace
@@ -99,12 +150,18 @@ func (h *HelmImpl) Create(kubeConfig, name, namespace string, createNamespace bo
 	cpa := client.ChartPathOptions
 	cpa.Version = version
 
-	cp, err := cpa.LocateChart(chart, &settings)
+	// LocateChart creates its own getters so the trusted CAs are passed as a bundle
+	cpa.CaFile, err = trustedCABundle()
+	if err != nil {
+		return xerrors.Errorf("Error locating chart: %w", err)
+	}
+
+	cp, err := h.locateChart(cpa, chart, version, &settings)
 	if err != nil {
 		return xerrors.Errorf("Error locating chart: %w", err)
 	}
 
-	p := getter.All(&settings)
+	p := getterProviders(&settings)
 	vo := values.Options{}
 	vo.StringValues = []string{}
 
@@ -118,6 +175,17 @@ func (h *HelmImpl) Create(kubeConfig, name, namespace string, createNamespace bo
 		vo.ValueFiles = []string{valuesPath}
 	}
 
+	// the values map is merged on top of the values file
+	if len(valuesMap) > 0 {
+		vf, err := writeValuesMap(valuesMap)
+		if err != nil {
+			return xerrors.Errorf("Error writing Helm values: %w", err)
+		}
+		defer os.Remove(vf)
+
+		vo.ValueFiles = append(vo.ValueFiles, vf)
+	}
+
 	vals, err := vo.MergeValues(p)
 	if err != nil {
 		return xerrors.Errorf("Error merging Helm values: %w", err)
@@ -187,7 +255,7 @@ func checkIfInstallable(ch *chart.Chart) error {
 }
 
 // Destroy removes an installed Helm chart from the system
-func (h *HelmImpl) Destroy(kubeConfig, name, namespace string) error {
+func (h *HelmImpl) Destroy(kubeConfig, name, namespace string, timeout time.Duration, force bool) error {
 	s := kube.GetConfig(kubeConfig, "default", namespace)
 	cfg := &action.Configuration{}
 	err := cfg.Init(s, namespace, "", func(format string, v ...interface{}) {
@@ -198,19 +266,45 @@ func (h *HelmImpl) Destroy(kubeConfig, name, namespace string) error {
 	//p := getter.All(&settings)
 	//vo := values.Options{}
 	client := action.NewUninstall(cfg)
-	_, err = client.Run(name)
+	res, err := client.Run(name)
 	if err != nil {
 		h.log.Debug("Unable to remove chart, exit silently", "err", err)
 		return err
 	}
 
-	return nil
+	// uninstall does not wait for the resources to be removed, build the
+	// resources from the release manifest and wait for them to be deleted
+	if res == nil || res.Release == nil {
+		return nil
+	}
+
+	kc := kube.New(s)
+	kc.Namespace = namespace
+
+	resources, err := kc.Build(strings.NewReader(res.Release.Manifest), false)
+	if err != nil {
+		return xerrors.Errorf("Unable to build resources for chart %s: %w", name, err)
+	}
+
+	// resources annotated with the keep resource policy are not removed by uninstall
+	resources = resources.Filter(func(r *cliresource.Info) bool {
+		a, err := meta.Accessor(r.Object)
+		if err != nil {
+			return true
+		}
+
+		return strings.ToLower(strings.TrimSpace(a.GetAnnotations()[kube.ResourcePolicyAnno])) != kube.KeepPolicy
+	})
+
+	return waitForDeletion(kc, resources, timeout, force, h.log)
 }
 
-func (h *HelmImpl) UpsertChartRepository(name, url string) error {
+func (h *HelmImpl) UpsertChartRepository(name, url, username, password string) error {
 	r := repo.Entry{
 		Name:                  name,
 		URL:                   url,
+		Username:              username,
+		Password:              password,
 		InsecureSkipTLSverify: true,
 	}
 
@@ -224,7 +318,7 @@ func (h *HelmImpl) UpsertChartRepository(name, url string) error {
 	}
 
 	settings := h.getSettings()
-	p := getter.All(&settings)
+	p := getterProviders(&settings)
 
 	chartRepo, err := repo.NewChartRepository(&r, p)
 	if err != nil {
@@ -247,6 +341,82 @@ func (h *HelmImpl) UpsertChartRepository(name, url string) error {
 	return nil
 }
 
+// RegistryLogin stores the credentials for the OCI registry
+func (h *HelmImpl) RegistryLogin(url, username, password string) error {
+	rc, err := registry.NewClient(registry.ClientOptCredentialsFile(h.registryCredentials()))
+	if err != nil {
+		return fmt.Errorf("unable to create Helm registry client: %s", err)
+	}
+
+	host := registryHost(url)
+
+	helmLock.Lock()
+	defer helmLock.Unlock()
+
+	err = rc.Login(host, registry.LoginOptBasicAuth(username, password))
+	if err != nil {
+		return fmt.Errorf("unable to login to registry %s: %s", host, err)
+	}
+
+	return nil
+}
+
+// registryHost returns the host for an OCI reference e.g. oci://ghcr.io/org/charts returns ghcr.io
+func registryHost(url string) string {
+	return strings.SplitN(strings.TrimPrefix(url, "oci://"), "/", 2)[0]
+}
+
+// writeValuesMap writes the values to a temporary file so that they can be
+// merged with the other values files by Helm
+func writeValuesMap(values map[string]interface{}) (string, error) {
+	d, err := yaml.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := utils.ShipyardTemp()
+	if err != nil {
+		return "", err
+	}
+
+	f, err := ioutil.TempFile(tmp, "helm-values-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = f.Write(d)
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// getterProviders returns the Helm getters, when trusted CAs have been set the
+// HTTP getters use a transport which trusts the CAs
+func getterProviders(settings *cli.EnvSettings) getter.Providers {
+	p := getter.All(settings)
+
+	for i, g := range p {
+		if !g.Provides("https") {
+			continue
+		}
+
+		newGetter := g.New
+		p[i].New = func(options ...getter.Option) (getter.Getter, error) {
+			if t := trustedTransport(); t != nil {
+				options = append(options, getter.WithTransport(t))
+			}
+
+			return newGetter(options...)
+		}
+	}
+
+	return p
+}
+
 func (h *HelmImpl) getSettings() cli.EnvSettings {
 	settings := cli.EnvSettings{}
 	settings.RepositoryConfig = h.repoPath
@@ -1,6 +1,7 @@
 package clients
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 
@@ -17,6 +18,27 @@ func TestUpsertChartRepository(t *testing.T) {
 	})
 
 	hc := NewHelm(hclog.Default())
-	err := hc.UpsertChartRepository("hashicorp", "https://helm.releases.hashicorp.com")
+	err := hc.UpsertChartRepository("hashicorp", "https://helm.releases.hashicorp.com", "", "")
 	require.NoError(t, err)
 }
+
+func TestRegistryHostReturnsHost(t *testing.T) {
+	require.Equal(t, "ghcr.io", registryHost("oci://ghcr.io/org/charts"))
+	require.Equal(t, "localhost:5000", registryHost("oci://localhost:5000"))
+}
+
+func TestWriteValuesMapWritesYAML(t *testing.T) {
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", t.TempDir())
+
+	t.Cleanup(func() {
+		os.Setenv("HOME", home)
+	})
+
+	f, err := writeValuesMap(map[string]interface{}{"server": map[string]interface{}{"replicas": 3}})
+	require.NoError(t, err)
+
+	d, err := ioutil.ReadFile(f)
+	require.NoError(t, err)
+	require.Equal(t, "server:\n  replicas: 3\n", string(d))
+}
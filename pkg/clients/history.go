@@ -0,0 +1,157 @@
+package clients
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryResultSuccess is recorded when a command completes without error
+const HistoryResultSuccess = "success"
+
+// HistoryResultFailed is recorded when a command returns an error
+const HistoryResultFailed = "failed"
+
+// HistoryEntry is a record of a single apply or destroy
+type HistoryEntry struct {
+	Time          time.Time         `json:"time"`
+	Command       string            `json:"command"`
+	Blueprint     string            `json:"blueprint"`
+	Ref           string            `json:"ref,omitempty"`
+	VariablesHash string            `json:"variables_hash,omitempty"`
+	Variables     map[string]string `json:"variables,omitempty"`      // variables set on the command line, used to roll back
+	VariablesFile string            `json:"variables_file,omitempty"` // absolute path of the variables file, used to roll back
+	Overlay       string            `json:"overlay,omitempty"`        // environment overlay merged on top of the blueprint
+	Duration      time.Duration     `json:"duration"`
+	Result        string            `json:"result"`
+	Error         string            `json:"error,omitempty"`
+	Usage         *ResourceUsage    `json:"usage,omitempty"`       // resource usage of the environment sampled after the command
+	ImagePulls    []ImagePullRecord `json:"image_pulls,omitempty"` // images pulled by the command
+}
+
+// History records the commands which have been run so that a user
+// can see which blueprints and variables were applied
+type History interface {
+	Log(HistoryEntry) error
+	Read() ([]HistoryEntry, error)
+}
+
+// HistoryFileLog is a History which uses a file as the
+// underlying Datastore
+type HistoryFileLog struct {
+	f string
+}
+
+// NewHistoryFileLog creates a History which stores entries in the given file
+func NewHistoryFileLog(file string) *HistoryFileLog {
+	return &HistoryFileLog{file}
+}
+
+// Log appends an entry to the history
+func (h *HistoryFileLog) Log(e HistoryEntry) error {
+	err := os.MkdirAll(filepath.Dir(h.f), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.f, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	d, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteString(string(d) + "\n")
+	return err
+}
+
+// Read returns all entries in the history, oldest first
+func (h *HistoryFileLog) Read() ([]HistoryEntry, error) {
+	output := []HistoryEntry{}
+
+	f, err := os.Open(h.f)
+	if err != nil {
+		// no history has been recorded
+		if os.IsNotExist(err) {
+			return output, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		e := HistoryEntry{}
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse history entry: %s", err)
+		}
+
+		output = append(output, e)
+	}
+
+	return output, scanner.Err()
+}
+
+// BlueprintRef returns the version reference for a remote blueprint
+// source e.g. github.com/shipyard-run/blueprints//consul?ref=v0.1.0 returns v0.1.0
+func BlueprintRef(source string) string {
+	i := strings.Index(source, "ref=")
+	if i == -1 {
+		return ""
+	}
+
+	ref := source[i+4:]
+	if j := strings.Index(ref, "&"); j > -1 {
+		ref = ref[:j]
+	}
+
+	return ref
+}
+
+// HashVariables returns a hash of the variables and the contents of the variables file
+// which allows runs with the same variables to be identified without storing
+// the values, returns an empty string when no variables are set
+func HashVariables(vars map[string]string, variablesFile string) string {
+	if len(vars) == 0 && variablesFile == "" {
+		return ""
+	}
+
+	h := sha256.New()
+
+	keys := []string{}
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, vars[k])
+	}
+
+	if variablesFile != "" {
+		d, err := ioutil.ReadFile(variablesFile)
+		if err == nil {
+			h.Write(d)
+		}
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))[:12]
+}
@@ -0,0 +1,25 @@
+package clients
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type HistoryMock struct {
+	mock.Mock
+}
+
+// Log an entry in the history
+func (m *HistoryMock) Log(e HistoryEntry) error {
+	return m.Called(e).Error(0)
+}
+
+// Read the entries from the history
+func (m *HistoryMock) Read() ([]HistoryEntry, error) {
+	args := m.Called()
+
+	if e, ok := args.Get(0).([]HistoryEntry); ok {
+		return e, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
@@ -0,0 +1,59 @@
+package clients
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryReadReturnsEmptyWhenNoFile(t *testing.T) {
+	h := NewHistoryFileLog(filepath.Join(t.TempDir(), "history.log"))
+
+	e, err := h.Read()
+	assert.NoError(t, err)
+	assert.Len(t, e, 0)
+}
+
+func TestHistoryLogAppendsEntries(t *testing.T) {
+	h := NewHistoryFileLog(filepath.Join(t.TempDir(), "history.log"))
+
+	err := h.Log(HistoryEntry{Command: "apply", Blueprint: "./", Duration: 2 * time.Second, Result: HistoryResultSuccess})
+	assert.NoError(t, err)
+
+	err = h.Log(HistoryEntry{Command: "destroy", Blueprint: "./", Result: HistoryResultFailed, Error: "boom"})
+	assert.NoError(t, err)
+
+	e, err := h.Read()
+	assert.NoError(t, err)
+	assert.Len(t, e, 2)
+	assert.Equal(t, "apply", e[0].Command)
+	assert.Equal(t, 2*time.Second, e[0].Duration)
+	assert.Equal(t, "boom", e[1].Error)
+}
+
+func TestBlueprintRefReturnsRef(t *testing.T) {
+	assert.Equal(t, "v0.1.0", BlueprintRef("github.com/shipyard-run/blueprints//consul?ref=v0.1.0"))
+	assert.Equal(t, "main", BlueprintRef("github.com/shipyard-run/blueprints//consul?ref=main&depth=1"))
+	assert.Equal(t, "", BlueprintRef("./"))
+}
+
+func TestHashVariablesIsStable(t *testing.T) {
+	f, err := ioutil.TempFile("", "*.vars")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	f.WriteString(`version = "1.9.0"`)
+	f.Close()
+
+	h1 := HashVariables(map[string]string{"a": "1", "b": "2"}, f.Name())
+	h2 := HashVariables(map[string]string{"b": "2", "a": "1"}, f.Name())
+	h3 := HashVariables(map[string]string{"a": "1", "b": "3"}, f.Name())
+
+	assert.Equal(t, h1, h2)
+	assert.NotEqual(t, h1, h3)
+	assert.Equal(t, "", HashVariables(nil, ""))
+}
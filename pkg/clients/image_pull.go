@@ -0,0 +1,285 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/go-units"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// pullAttempts is the number of times an image pull is attempted before failing
+var pullAttempts = 3
+
+// pullBackoff is the time to wait before the first retry of a failed pull,
+// the time is doubled for each following retry
+var pullBackoff = 2 * time.Second
+
+// pullProgressInterval is the minimum time between progress messages for a pull
+var pullProgressInterval = 5 * time.Second
+
+// ImagePullRecord is the timing of a single image pull
+type ImagePullRecord struct {
+	Image    string        `json:"image"`
+	Duration time.Duration `json:"duration"`
+	Attempts int           `json:"attempts"`
+	Bytes    int64         `json:"bytes,omitempty"` // total size of the layers which were downloaded
+	Error    string        `json:"error,omitempty"`
+}
+
+// ImagePullProgress is the progress of an image pull which is in progress
+type ImagePullProgress struct {
+	Image   string `json:"image"`
+	Current int64  `json:"current"` // bytes downloaded for the layers which have started
+	Total   int64  `json:"total"`   // total size of the layers which have started
+}
+
+// ImagePulls records the image pulls made by the ContainerTasks so that
+// the time spent pulling images can be reported
+type ImagePulls struct {
+	lock    sync.Mutex
+	records []ImagePullRecord
+	active  map[string]ImagePullProgress
+}
+
+// NewImagePulls creates an empty ImagePulls
+func NewImagePulls() *ImagePulls {
+	return &ImagePulls{active: map[string]ImagePullProgress{}}
+}
+
+// Record adds a pull to the list of pulls
+func (i *ImagePulls) Record(r ImagePullRecord) {
+	if i == nil {
+		return
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.records = append(i.records, r)
+	delete(i.active, r.Image)
+}
+
+// Progress updates the progress of a pull, the pull is active until
+// it is recorded
+func (i *ImagePulls) Progress(p ImagePullProgress) {
+	if i == nil {
+		return
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.active == nil {
+		i.active = map[string]ImagePullProgress{}
+	}
+
+	i.active[p.Image] = p
+}
+
+// Active returns the progress of the pulls which have not completed
+// ordered by image name
+func (i *ImagePulls) Active() []ImagePullProgress {
+	if i == nil {
+		return nil
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	a := []ImagePullProgress{}
+	for _, p := range i.active {
+		a = append(a, p)
+	}
+
+	sort.Slice(a, func(x, y int) bool { return a[x].Image < a[y].Image })
+
+	return a
+}
+
+// Records returns the pulls in the order they completed
+func (i *ImagePulls) Records() []ImagePullRecord {
+	if i == nil {
+		return nil
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if len(i.records) == 0 {
+		return nil
+	}
+
+	r := make([]ImagePullRecord, len(i.records))
+	copy(r, i.records)
+
+	return r
+}
+
+// Reset removes all the recorded pulls
+func (i *ImagePulls) Reset() {
+	if i == nil {
+		return
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.records = nil
+	i.active = map[string]ImagePullProgress{}
+}
+
+// pullRetryPolicy returns the policy used for a pull, values which are not set
+// for the image use the defaults for pulls
+func pullRetryPolicy(r *config.Retry) *config.Retry {
+	return r.Merge(&config.Retry{Attempts: pullAttempts, Delay: pullBackoff.String(), Backoff: 2})
+}
+
+// retryPull calls f until it succeeds, the maximum number of attempts is
+// reached, or the context is done, errors which will not succeed on retry
+// are returned immediately. Returns the number of attempts made.
+func retryPull(ctx context.Context, image string, r *config.Retry, l hclog.Logger, f func() error) (int, error) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = f()
+		if err == nil || attempt >= r.MaxAttempts() || !isRetryablePullError(err) {
+			return attempt, err
+		}
+
+		backoff := r.Wait(attempt)
+		l.Warn("Unable to pull image, retrying", "image", image, "attempt", attempt, "backoff", backoff, "error", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return attempt, xerrors.Errorf("timeout pulling image after %d attempts: %w", attempt, err)
+		}
+	}
+}
+
+func isRetryablePullError(err error) bool {
+	return !errdefs.IsNotFound(err) &&
+		!errdefs.IsUnauthorized(err) &&
+		!errdefs.IsForbidden(err) &&
+		!errdefs.IsInvalidParameter(err)
+}
+
+// pullProgress renders the output of a Docker image pull, the progress
+// of the individual layers is combined into a single progress for the image
+type pullProgress struct {
+	image  string
+	pulls  *ImagePulls
+	l      hclog.Logger
+	layers map[string]*jsonmessage.JSONProgress
+	last   time.Time
+}
+
+func newPullProgress(image string, pulls *ImagePulls, l hclog.Logger) *pullProgress {
+	return &pullProgress{
+		image:  image,
+		pulls:  pulls,
+		l:      l,
+		layers: map[string]*jsonmessage.JSONProgress{},
+		last:   time.Now(),
+	}
+}
+
+// Render reads the pull output until it completes, returns an error when
+// the output contains an error or the stream is interrupted
+func (p *pullProgress) Render(r io.Reader) error {
+	dec := json.NewDecoder(r)
+
+	for {
+		var m jsonmessage.JSONMessage
+		err := dec.Decode(&m)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				// output which is not a progress stream can not be rendered,
+				// read the remainder so the pull completes
+				p.l.Debug("Unable to decode image pull output", "image", p.image, "error", err)
+				io.Copy(ioutil.Discard, r)
+
+				return nil
+			}
+
+			return fmt.Errorf("unable to read image pull output: %w", err)
+		}
+
+		if m.Error != nil {
+			return m.Error
+		}
+
+		if m.ErrorMessage != "" {
+			return errors.New(m.ErrorMessage)
+		}
+
+		p.update(m)
+	}
+
+	if len(p.layers) > 0 {
+		p.l.Info("Pulled image", "image", p.image, "size", units.HumanSize(float64(p.Bytes())))
+	}
+
+	return nil
+}
+
+// Bytes returns the total size of the layers downloaded by the pull
+func (p *pullProgress) Bytes() int64 {
+	var total int64
+	for _, l := range p.layers {
+		total += l.Total
+	}
+
+	return total
+}
+
+func (p *pullProgress) update(m jsonmessage.JSONMessage) {
+	p.l.Debug("Pulling image", "image", p.image, "layer", m.ID, "status", m.Status)
+
+	// the final progress of a layer is not always sent
+	if l, ok := p.layers[m.ID]; ok && m.Status == "Download complete" {
+		l.Current = l.Total
+		return
+	}
+
+	if m.ID == "" || m.Progress == nil || m.Progress.Total == 0 || m.Status != "Downloading" {
+		return
+	}
+
+	p.layers[m.ID] = m.Progress
+
+	var current int64
+	for _, l := range p.layers {
+		current += l.Current
+	}
+
+	p.pulls.Progress(ImagePullProgress{Image: p.image, Current: current, Total: p.Bytes()})
+
+	if time.Since(p.last) < pullProgressInterval {
+		return
+	}
+
+	p.last = time.Now()
+
+	p.l.Info(
+		"Pulling image",
+		"image", p.image,
+		"progress", fmt.Sprintf("%s/%s", units.HumanSize(float64(current)), units.HumanSize(float64(p.Bytes()))),
+		"layers", len(p.layers),
+	)
+}
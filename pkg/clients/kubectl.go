@@ -2,18 +2,24 @@ package clients
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"golang.org/x/xerrors"
 	"helm.sh/helm/v3/pkg/kube"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cliresource "k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/clientcmd"
@@ -26,7 +32,24 @@ type Kubernetes interface {
 	HealthCheckPods(selectors []string, timeout time.Duration) error
 	Apply(files []string, waitUntilReady bool) error
 	Delete(files []string) error
+	// WaitForDeletion blocks until the resources defined in the files have been removed from the cluster,
+	// when force is true finalizers are removed from any resources remaining after the timeout
+	WaitForDeletion(files []string, timeout time.Duration, force bool) error
 	GetPodLogs(ctx context.Context, podName, nameSpace string) (io.ReadCloser, error)
+	// StreamPodLogs returns the logs for a container in the pod, when Follow is set
+	// in the options the logs are streamed until the context is cancelled
+	StreamPodLogs(ctx context.Context, podName, nameSpace string, opts *v1.PodLogOptions) (io.ReadCloser, error)
+
+	// ApplyNamespace creates the namespace or updates the labels and annotations when it exists
+	ApplyNamespace(ns *v1.Namespace) error
+	// DeleteNamespace deletes the namespace and all the resources in it
+	DeleteNamespace(name string) error
+	// ApplyResourceQuota creates or updates the resource quota
+	ApplyResourceQuota(q *v1.ResourceQuota) error
+	// ApplyNetworkPolicy creates or updates the network policy
+	ApplyNetworkPolicy(p *networkingv1.NetworkPolicy) error
+	// GetService returns the service with the given name in the namespace
+	GetService(name, namespace string) (*v1.Service, error)
 }
 
 // KubernetesImpl is a concrete implementation of a Kubernetes client
@@ -96,6 +119,11 @@ func (k *KubernetesImpl) GetPodLogs(ctx context.Context, podName, nameSpace stri
 	return k.clientset.CoreV1().Pods(nameSpace).GetLogs(podName, &plOpts).Stream(ctx)
 }
 
+// StreamPodLogs returns a io.ReadCloser for the logs of the pod using the given options
+func (k *KubernetesImpl) StreamPodLogs(ctx context.Context, podName, nameSpace string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+	return k.clientset.CoreV1().Pods(nameSpace).GetLogs(podName, opts).Stream(ctx)
+}
+
 // GetPods returns the Kubernetes pods based on the label selector
 func (k *KubernetesImpl) GetPods(selector string) (*v1.PodList, error) {
 	lo := metav1.ListOptions{
@@ -155,6 +183,116 @@ func (k *KubernetesImpl) Delete(files []string) error {
 	return nil
 }
 
+// WaitForDeletion blocks until the resources defined in the files have been removed from the cluster.
+// Resources with finalizers, such as namespaces, can remain in a Terminating state; when force is
+// true the finalizers are removed from any resources remaining after the timeout
+func (k *KubernetesImpl) WaitForDeletion(files []string, timeout time.Duration, force bool) error {
+	allFiles, err := buildFileList(files)
+	if err != nil {
+		return err
+	}
+
+	s := kube.GetConfig(k.configPath, "default", "default")
+	kc := kube.New(s)
+
+	resources := kube.ResourceList{}
+	for _, f := range allFiles {
+		r, err := buildFile(f, kc)
+		if err != nil {
+			return err
+		}
+
+		resources = append(resources, r...)
+	}
+
+	return waitForDeletion(kc, resources, timeout, force, k.l)
+}
+
+// ApplyNamespace creates the namespace or updates the labels and annotations when it exists
+func (k *KubernetesImpl) ApplyNamespace(ns *v1.Namespace) error {
+	ctx := context.Background()
+
+	existing, err := k.client.Namespaces().Get(ctx, ns.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		k.l.Debug("Creating namespace", "namespace", ns.Name)
+
+		_, err = k.client.Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	k.l.Debug("Updating namespace", "namespace", ns.Name)
+
+	existing.Labels = ns.Labels
+	existing.Annotations = ns.Annotations
+
+	_, err = k.client.Namespaces().Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// DeleteNamespace deletes the namespace and all the resources in it,
+// no error is returned when the namespace does not exist
+func (k *KubernetesImpl) DeleteNamespace(name string) error {
+	k.l.Debug("Deleting namespace", "namespace", name)
+
+	err := k.client.Namespaces().Delete(context.Background(), name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+// ApplyResourceQuota creates or updates the resource quota
+func (k *KubernetesImpl) ApplyResourceQuota(q *v1.ResourceQuota) error {
+	ctx := context.Background()
+	rq := k.client.ResourceQuotas(q.Namespace)
+
+	existing, err := rq.Get(ctx, q.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = rq.Create(ctx, q, metav1.CreateOptions{})
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	existing.Spec = q.Spec
+
+	_, err = rq.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// ApplyNetworkPolicy creates or updates the network policy
+func (k *KubernetesImpl) ApplyNetworkPolicy(p *networkingv1.NetworkPolicy) error {
+	ctx := context.Background()
+	np := k.clientset.NetworkingV1().NetworkPolicies(p.Namespace)
+
+	existing, err := np.Get(ctx, p.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = np.Create(ctx, p, metav1.CreateOptions{})
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	existing.Spec = p.Spec
+
+	_, err = np.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// GetService returns the service with the given name in the namespace
+func (k *KubernetesImpl) GetService(name, namespace string) (*v1.Service, error) {
+	return k.client.Services(namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
 // HealthCheckPods uses the given selector to check that all pods are started
 // and running.
 // selectors are checked sequentially
@@ -279,14 +417,18 @@ func applyFile(path string, waitUntilReady bool, kc *kube.Client) error {
 	return nil
 }
 
-func deleteFile(path string, kc *kube.Client) error {
+func buildFile(path string, kc *kube.Client) (kube.ResourceList, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
-	r, err := kc.Build(f, false)
+	return kc.Build(f, false)
+}
+
+func deleteFile(path string, kc *kube.Client) error {
+	r, err := buildFile(path, kc)
 	if err != nil {
 		return err
 	}
@@ -299,3 +441,118 @@ func deleteFile(path string, kc *kube.Client) error {
 
 	return nil
 }
+
+// finalizerTimeout is the time to wait for resources to be deleted once
+// the finalizers have been removed
+var finalizerTimeout = 30 * time.Second
+
+// DeletionTimeoutError is returned when Kubernetes resources have not been
+// removed from the cluster within the timeout
+type DeletionTimeoutError struct {
+	Resources []string
+}
+
+func (e DeletionTimeoutError) Error() string {
+	return fmt.Sprintf("Timeout waiting for resources to be deleted: %s", strings.Join(e.Resources, ", "))
+}
+
+// waitForDeletion waits until the resources have been removed from the cluster, when force is true
+// and the resources have not been removed within the timeout the finalizers are removed
+func waitForDeletion(kc *kube.Client, resources kube.ResourceList, timeout time.Duration, force bool, l hclog.Logger) error {
+	if len(resources) == 0 {
+		return nil
+	}
+
+	l.Debug("Waiting for resources to be deleted", "resources", len(resources), "timeout", timeout)
+
+	err := kc.WaitForDelete(resources, timeout)
+	if err == nil {
+		return nil
+	}
+
+	remaining := remainingResources(resources)
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	if !force {
+		return DeletionTimeoutError{resourceNames(remaining)}
+	}
+
+	for _, r := range remaining {
+		l.Warn("Resource has not been deleted, removing finalizers", "resource", resourceName(r))
+
+		err := removeFinalizers(r)
+		if err != nil {
+			return xerrors.Errorf("Unable to remove finalizers from %s: %w", resourceName(r), err)
+		}
+	}
+
+	err = kc.WaitForDelete(remaining, finalizerTimeout)
+	if err != nil {
+		return DeletionTimeoutError{resourceNames(remainingResources(remaining))}
+	}
+
+	return nil
+}
+
+// remainingResources returns the resources which still exist in the cluster
+func remainingResources(resources kube.ResourceList) kube.ResourceList {
+	remaining := kube.ResourceList{}
+	for _, r := range resources {
+		if err := r.Get(); !errors.IsNotFound(err) {
+			remaining = append(remaining, r)
+		}
+	}
+
+	return remaining
+}
+
+// removeFinalizers removes the metadata finalizers from the resource, namespaces also
+// have their spec finalizers removed using the finalize subresource
+func removeFinalizers(r *cliresource.Info) error {
+	h := cliresource.NewHelper(r.Client, r.Mapping)
+
+	_, err := h.Patch(r.Namespace, r.Name, types.MergePatchType, []byte(`{"metadata":{"finalizers":null}}`), nil)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if r.Mapping.GroupVersionKind.Kind != "Namespace" {
+		return nil
+	}
+
+	ns := &v1.Namespace{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: r.Name},
+	}
+
+	d, err := json.Marshal(ns)
+	if err != nil {
+		return err
+	}
+
+	err = r.Client.Put().Resource("namespaces").Name(r.Name).SubResource("finalize").Body(d).Do(context.Background()).Error()
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func resourceName(r *cliresource.Info) string {
+	if r.Namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.ToLower(r.Mapping.GroupVersionKind.Kind), r.Namespace, r.Name)
+	}
+
+	return fmt.Sprintf("%s/%s", strings.ToLower(r.Mapping.GroupVersionKind.Kind), r.Name)
+}
+
+func resourceNames(resources kube.ResourceList) []string {
+	names := []string{}
+	for _, r := range resources {
+		names = append(names, resourceName(r))
+	}
+
+	return names
+}
@@ -9,6 +9,7 @@ import (
 	
 	"github.com/stretchr/testify/mock"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 )
 
 type MockKubernetes struct {
@@ -37,6 +38,16 @@ func (m *MockKubernetes) GetPodLogs(ctx context.Context, podName, nameSpace stri
 	return ior, args.Error(1)
 }
 
+func (m *MockKubernetes) StreamPodLogs(ctx context.Context, podName, nameSpace string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+	args := m.Called(ctx, podName, nameSpace, opts)
+
+	if rc, ok := args.Get(0).(io.ReadCloser); ok {
+		return rc, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
 func (m *MockKubernetes) Apply(files []string, waitUntilReady bool) error {
 	args := m.Called(files, waitUntilReady)
 
@@ -49,8 +60,48 @@ func (m *MockKubernetes) Delete(files []string) error {
 	return args.Error(0)
 }
 
+func (m *MockKubernetes) WaitForDeletion(files []string, timeout time.Duration, force bool) error {
+	args := m.Called(files, timeout, force)
+
+	return args.Error(0)
+}
+
 func (m *MockKubernetes) HealthCheckPods(selectors []string, timeout time.Duration) error {
 	args := m.Called(selectors, timeout)
 
 	return args.Error(0)
 }
+
+func (m *MockKubernetes) ApplyNamespace(ns *v1.Namespace) error {
+	args := m.Called(ns)
+
+	return args.Error(0)
+}
+
+func (m *MockKubernetes) DeleteNamespace(name string) error {
+	args := m.Called(name)
+
+	return args.Error(0)
+}
+
+func (m *MockKubernetes) ApplyResourceQuota(q *v1.ResourceQuota) error {
+	args := m.Called(q)
+
+	return args.Error(0)
+}
+
+func (m *MockKubernetes) ApplyNetworkPolicy(p *networkingv1.NetworkPolicy) error {
+	args := m.Called(p)
+
+	return args.Error(0)
+}
+
+func (m *MockKubernetes) GetService(name, namespace string) (*v1.Service, error) {
+	args := m.Called(name, namespace)
+
+	if s, ok := args.Get(0).(*v1.Service); ok {
+		return s, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
@@ -0,0 +1,21 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type BlueprintRegistry struct {
+	mock.Mock
+}
+
+func (r *BlueprintRegistry) Push(ctx context.Context, dir, ref string) (string, error) {
+	args := r.Called(ctx, dir, ref)
+
+	return args.String(0), args.Error(1)
+}
+
+func (r *BlueprintRegistry) Pull(ctx context.Context, ref, dst string) error {
+	return r.Called(ctx, ref, dst).Error(0)
+}
@@ -2,6 +2,8 @@ package mocks
 
 import (
 	"io"
+	"os"
+	"time"
 
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/stretchr/testify/mock"
@@ -15,6 +17,14 @@ func (m *MockContainerTasks) SetForcePull(f bool) {
 	m.Called(f)
 }
 
+func (m *MockContainerTasks) SetOffline(o bool) {
+	m.Called(o)
+}
+
+func (m *MockContainerTasks) SetDefaultPortBind(ip string) {
+	m.Called(ip)
+}
+
 func (m *MockContainerTasks) CreateContainer(c *config.Container) (id string, err error) {
 	args := m.Called(c)
 
@@ -27,12 +37,24 @@ func (m *MockContainerTasks) ContainerInfo(id string) (interface{}, error) {
 	return args.Get(0), args.Error(1)
 }
 
+func (m *MockContainerTasks) WaitForContainer(id string, timeout time.Duration) (int64, error) {
+	args := m.Called(id, timeout)
+
+	return int64(args.Int(0)), args.Error(1)
+}
+
 func (m *MockContainerTasks) RemoveContainer(id string, force bool) error {
 	args := m.Called(id, force)
 
 	return args.Error(0)
 }
 
+func (m *MockContainerTasks) CommitContainer(id, image string) error {
+	args := m.Called(id, image)
+
+	return args.Error(0)
+}
+
 func (m *MockContainerTasks) BuildContainer(config *config.Container, force bool) (string, error) {
 	args := m.Called(config, force)
 	return args.String(0), args.Error(1)
@@ -56,6 +78,34 @@ func (m *MockContainerTasks) PullImage(i config.Image, f bool) error {
 	return args.Error(0)
 }
 
+func (m *MockContainerTasks) PushImage(image string, target config.Image) error {
+	args := m.Called(image, target)
+
+	return args.Error(0)
+}
+
+func (m *MockContainerTasks) ExportImages(images []string, w io.Writer) error {
+	args := m.Called(images, w)
+
+	return args.Error(0)
+}
+
+func (m *MockContainerTasks) FindImageID(name string) (string, error) {
+	args := m.Called(name)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockContainerTasks) ImportImages(r io.Reader) ([]string, error) {
+	args := m.Called(r)
+
+	if i, ok := args.Get(0).([]string); ok {
+		return i, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
 func (m *MockContainerTasks) FindContainerIDs(name string, typeName config.ResourceType) ([]string, error) {
 	args := m.Called(name, typeName)
 
@@ -108,6 +158,36 @@ func (d *MockContainerTasks) CopyFilesToVolume(volume string, files []string, pa
 	return nil, args.Error(1)
 }
 
+func (d *MockContainerTasks) CopyPathToContainer(id, src, dst string, mode os.FileMode) error {
+	args := d.Called(id, src, dst, mode)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) ExportVolume(volume string, w io.Writer) error {
+	args := d.Called(volume, w)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) ImportVolume(volume string, r io.Reader) error {
+	args := d.Called(volume, r)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) CopyPathToVolume(volume, src, dst string, mode os.FileMode) error {
+	args := d.Called(volume, src, dst, mode)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) ImportImagesToContainerd(id string, images []string, namespace string, writer io.Writer) error {
+	args := d.Called(id, images, namespace, writer)
+
+	return args.Error(0)
+}
+
 func (d *MockContainerTasks) ExecuteCommand(id string, command []string, env []string, workingDirectory string, user, group string, writer io.Writer) error {
 	args := d.Called(id, command, env, workingDirectory, user, group, writer)
 
@@ -57,6 +57,16 @@ func (m *MockDocker) ContainerList(ctx context.Context, options types.ContainerL
 	return nil, args.Error(1)
 }
 
+func (m *MockDocker) ContainerStats(ctx context.Context, containerID string, stream bool) (types.ContainerStats, error) {
+	args := m.Called(ctx, containerID, stream)
+
+	if s, ok := args.Get(0).(types.ContainerStats); ok {
+		return s, args.Error(1)
+	}
+
+	return types.ContainerStats{}, args.Error(1)
+}
+
 func (m *MockDocker) ContainerStart(ctx context.Context, ID string, opts types.ContainerStartOptions) error {
 	args := m.Called(ctx, ID, opts)
 
@@ -69,6 +79,16 @@ func (m *MockDocker) ContainerStop(ctx context.Context, containerID string, time
 	return args.Error(0)
 }
 
+func (m *MockDocker) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.ContainerWaitOKBody, <-chan error) {
+	args := m.Called(ctx, containerID, condition)
+
+	if wc, ok := args.Get(0).(chan container.ContainerWaitOKBody); ok {
+		return wc, args.Get(1).(chan error)
+	}
+
+	return args.Get(0).(<-chan container.ContainerWaitOKBody), args.Get(1).(<-chan error)
+}
+
 func (m *MockDocker) ContainerRemove(ctx context.Context, containerID string, options types.ContainerRemoveOptions) error {
 	args := m.Called(ctx, containerID, options)
 
@@ -99,6 +119,16 @@ func (m *MockDocker) ContainerExecCreate(ctx context.Context, container string,
 	return types.IDResponse{}, args.Error(1)
 }
 
+func (m *MockDocker) ContainerCommit(ctx context.Context, container string, options types.ContainerCommitOptions) (types.IDResponse, error) {
+	args := m.Called(ctx, container, options)
+
+	if idr, ok := args.Get(0).(types.IDResponse); ok {
+		return idr, args.Error(1)
+	}
+
+	return types.IDResponse{}, args.Error(1)
+}
+
 func (m *MockDocker) ContainerExecStart(ctx context.Context, execID string, config types.ExecStartCheck) error {
 	args := m.Called(ctx, execID, config)
 
@@ -223,6 +253,16 @@ func (m *MockDocker) ImageSave(ctx context.Context, imageIDs []string) (io.ReadC
 	return nil, args.Error(1)
 }
 
+func (m *MockDocker) ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error) {
+	args := m.Called(ctx, input, quiet)
+
+	if ir, ok := args.Get(0).(types.ImageLoadResponse); ok {
+		return ir, args.Error(1)
+	}
+
+	return types.ImageLoadResponse{}, args.Error(1)
+}
+
 func (m *MockDocker) ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error) {
 	args := m.Called(ctx, options)
 
@@ -233,6 +273,16 @@ func (m *MockDocker) ImageList(ctx context.Context, options types.ImageListOptio
 	return []types.ImageSummary{}, args.Error(1)
 }
 
+func (m *MockDocker) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	args := m.Called(ctx, imageID)
+
+	if ii, ok := args.Get(0).(types.ImageInspect); ok {
+		return ii, nil, args.Error(1)
+	}
+
+	return types.ImageInspect{}, nil, args.Error(1)
+}
+
 func (m *MockDocker) ImageRemove(ctx context.Context, imageID string, options types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error) {
 	args := m.Called(ctx, imageID, options)
 
@@ -243,6 +293,22 @@ func (m *MockDocker) ImageRemove(ctx context.Context, imageID string, options ty
 	return nil, args.Error(1)
 }
 
+func (m *MockDocker) ImageTag(ctx context.Context, source, target string) error {
+	args := m.Called(ctx, source, target)
+
+	return args.Error(0)
+}
+
+func (m *MockDocker) ImagePush(ctx context.Context, image string, options types.ImagePushOptions) (io.ReadCloser, error) {
+	args := m.Called(ctx, image, options)
+
+	if rc, ok := args.Get(0).(io.ReadCloser); ok {
+		return rc, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
 func (m *MockDocker) ImageBuild(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error) {
 	args := m.Called(ctx, buildContext, options)
 
@@ -253,6 +319,16 @@ func (m *MockDocker) ImageBuild(ctx context.Context, buildContext io.Reader, opt
 	return types.ImageBuildResponse{}, args.Error(1)
 }
 
+func (m *MockDocker) Info(ctx context.Context) (types.Info, error) {
+	args := m.Called(ctx)
+
+	if info, ok := args.Get(0).(types.Info); ok {
+		return info, args.Error(1)
+	}
+
+	return types.Info{}, args.Error(1)
+}
+
 func (m *MockDocker) ServerVersion(ctx context.Context) (types.Version, error) {
 	args := m.Called(ctx)
 
@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"time"
+
 	"github.com/stretchr/testify/mock"
 )
 
@@ -8,20 +10,26 @@ type MockHelm struct {
 	mock.Mock
 }
 
-func (h *MockHelm) Create(kubeConfig, name, namespace string, createNamespace bool, skipCRDs bool, chart, version, valuesPath string, valueString map[string]string) error {
-	args := h.Called(kubeConfig, name, namespace, createNamespace, skipCRDs, chart, version, valuesPath, valueString)
+func (h *MockHelm) Create(kubeConfig, name, namespace string, createNamespace bool, skipCRDs bool, chart, version, valuesPath string, valuesMap map[string]interface{}, valueString map[string]string) error {
+	args := h.Called(kubeConfig, name, namespace, createNamespace, skipCRDs, chart, version, valuesPath, valuesMap, valueString)
+
+	return args.Error(0)
+}
+
+func (h *MockHelm) Destroy(kubeConfig, name, namespace string, timeout time.Duration, force bool) error {
+	args := h.Called(kubeConfig, name, namespace, timeout, force)
 
 	return args.Error(0)
 }
 
-func (h *MockHelm) Destroy(kubeConfig, name, namespace string) error {
-	args := h.Called(kubeConfig, name, namespace)
+func (h *MockHelm) UpsertChartRepository(name, url, username, password string) error {
+	args := h.Called(name, url, username, password)
 
 	return args.Error(0)
 }
 
-func (h *MockHelm) UpsertChartRepository(name, url string) error {
-	args := h.Called(name, url)
+func (h *MockHelm) RegistryLogin(url, username, password string) error {
+	args := h.Called(url, username, password)
 
 	return args.Error(0)
 }
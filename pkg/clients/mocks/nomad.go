@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+	"io"
 	"time"
 
 	"github.com/shipyard-run/shipyard/pkg/utils"
@@ -41,6 +43,18 @@ func (m *MockNomad) JobRunning(job string) (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockNomad) JobHealthy(job string) (bool, error) {
+	args := m.Called(job)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNomad) JobID(file string) (string, error) {
+	args := m.Called(file)
+
+	return args.String(0), args.Error(1)
+}
+
 func (m *MockNomad) Endpoints(job, group, task string) ([]map[string]string, error) {
 	args := m.Called(job, group, task)
 
@@ -56,3 +70,23 @@ func (m *MockNomad) HealthCheckAPI(timeout time.Duration) error {
 
 	return args.Error(0)
 }
+
+func (m *MockNomad) JobAllocations(job string) (map[string][]string, error) {
+	args := m.Called(job)
+
+	if a, ok := args.Get(0).(map[string][]string); ok {
+		return a, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockNomad) AllocationLogs(ctx context.Context, allocID, task, logType string, follow bool, offset int64) (io.ReadCloser, error) {
+	args := m.Called(ctx, allocID, task, logType, follow, offset)
+
+	if rc, ok := args.Get(0).(io.ReadCloser); ok {
+		return rc, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
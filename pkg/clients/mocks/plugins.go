@@ -0,0 +1,54 @@
+package mocks
+
+import (
+	"io"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPlugins struct {
+	mock.Mock
+}
+
+func (m *MockPlugins) Discover() (map[string][]string, error) {
+	args := m.Called()
+
+	if p, ok := args.Get(0).(map[string][]string); ok {
+		return p, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPlugins) Validate(r *config.PluginResource) error {
+	return m.Called(r).Error(0)
+}
+
+func (m *MockPlugins) Create(r *config.PluginResource) error {
+	return m.Called(r).Error(0)
+}
+
+func (m *MockPlugins) Destroy(r *config.PluginResource) error {
+	return m.Called(r).Error(0)
+}
+
+func (m *MockPlugins) Lookup(r *config.PluginResource) ([]string, error) {
+	args := m.Called(r)
+
+	if ids, ok := args.Get(0).([]string); ok {
+		return ids, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockPlugins) Logs(r *config.PluginResource, follow bool, tail string) (io.ReadCloser, error) {
+	args := m.Called(r, follow, tail)
+
+	if rc, ok := args.Get(0).(io.ReadCloser); ok {
+		return rc, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
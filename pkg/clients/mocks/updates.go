@@ -0,0 +1,39 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUpdates struct {
+	mock.Mock
+}
+
+func (m *MockUpdates) ImageTags(image string) ([]string, error) {
+	args := m.Called(image)
+
+	if t, ok := args.Get(0).([]string); ok {
+		return t, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockUpdates) ChartVersions(repository, chart string) ([]string, error) {
+	args := m.Called(repository, chart)
+
+	if v, ok := args.Get(0).([]string); ok {
+		return v, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockUpdates) GitTags(repository string) ([]string, error) {
+	args := m.Called(repository)
+
+	if t, ok := args.Get(0).([]string); ok {
+		return t, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
@@ -2,10 +2,15 @@ package clients
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
@@ -25,12 +30,24 @@ type Nomad interface {
 	ParseJob(file string) ([]byte, error)
 	// JobRunning returns true if all allocations for a job are running
 	JobRunning(job string) (bool, error)
+	// JobHealthy returns true when the latest deployment for the job is successful,
+	// an error is returned when the deployment or the allocations have failed
+	JobHealthy(job string) (bool, error)
+	// JobID returns the ID of the job defined in the given file
+	JobID(file string) (string, error)
 	// HealthCheckAPI uses the Nomad API to check that all servers and nodes
 	// are ready. The function will block until either all nodes are healthy or the
 	// timeout period elapses.
 	HealthCheckAPI(time.Duration) error
 	// Endpoints returns a list of endpoints for a cluster
 	Endpoints(job, group, task string) ([]map[string]string, error)
+	// JobAllocations returns the IDs of the running allocations for the job
+	// and the names of the tasks in each allocation
+	JobAllocations(job string) (map[string][]string, error)
+	// AllocationLogs returns the stdout or stderr logs for a task in the allocation, when follow
+	// is true the logs are streamed until the context is cancelled. Offset is the number of bytes
+	// from the end of the log to start reading from, when 0 the complete log is returned.
+	AllocationLogs(ctx context.Context, allocID, task, logType string, follow bool, offset int64) (io.ReadCloser, error)
 }
 
 // NomadImpl is an implementation of the Nomad interface
@@ -182,7 +199,7 @@ func (n *NomadImpl) Create(files []string) error {
 // Stop the jobs defined in the files for the referenced Nomad cluster
 func (n *NomadImpl) Stop(files []string) error {
 	for _, f := range files {
-		id, err := n.getJobID(f)
+		id, err := n.JobID(f)
 		if err != nil {
 			return err
 		}
@@ -284,6 +301,53 @@ func (n *NomadImpl) JobRunning(job string) (bool, error) {
 	return true, nil
 }
 
+// JobHealthy returns true when the latest deployment for a job is successful.
+// Jobs which do not create deployments e.g. batch jobs are healthy when all
+// allocations are running or complete.
+func (n *NomadImpl) JobHealthy(job string) (bool, error) {
+	d, err := n.getJobDeployment(job)
+	if err != nil {
+		return false, err
+	}
+
+	if d != nil {
+		n.l.Debug("Job deployment status", "job", job, "deployment", d.ID, "status", d.Status)
+
+		switch d.Status {
+		case "successful":
+			return true, nil
+		case "failed", "cancelled":
+			return false, xerrors.Errorf("Deployment %s for job %s %s: %s", d.ID, job, d.Status, d.StatusDescription)
+		default:
+			return false, nil
+		}
+	}
+
+	allocs, err := n.getJobAllocations(job)
+	if err != nil {
+		return false, err
+	}
+
+	if len(allocs) < 1 {
+		return false, nil
+	}
+
+	healthy := true
+	for _, a := range allocs {
+		status, _ := a["ClientStatus"].(string)
+
+		switch status {
+		case "running", "complete":
+		case "failed", "lost":
+			return false, xerrors.Errorf("Allocation %s for job %s is %s", a["ID"], job, status)
+		default:
+			healthy = false
+		}
+	}
+
+	return healthy, nil
+}
+
 // Endpoints returns a list of endpoints for a cluster
 func (n *NomadImpl) Endpoints(job, group, task string) ([]map[string]string, error) {
 	jobs, err := n.getJobAllocations(job)
@@ -378,6 +442,75 @@ func (n *NomadImpl) Endpoints(job, group, task string) ([]map[string]string, err
 	return endpoints, nil
 }
 
+// JobAllocations returns the running allocations for the job
+func (n *NomadImpl) JobAllocations(job string) (map[string][]string, error) {
+	jobs, err := n.getJobAllocations(job)
+	if err != nil {
+		return nil, err
+	}
+
+	allocs := map[string][]string{}
+	for _, j := range jobs {
+		if j["ClientStatus"] != "running" {
+			continue
+		}
+
+		tasks := []string{}
+		if ts, ok := j["TaskStates"].(map[string]interface{}); ok {
+			for t := range ts {
+				tasks = append(tasks, t)
+			}
+		}
+
+		sort.Strings(tasks)
+		allocs[fmt.Sprintf("%v", j["ID"])] = tasks
+	}
+
+	return allocs, nil
+}
+
+// AllocationLogs returns the logs for a task in the allocation using the Nomad fs API
+func (n *NomadImpl) AllocationLogs(ctx context.Context, allocID, task, logType string, follow bool, offset int64) (io.ReadCloser, error) {
+	q := url.Values{}
+	q.Set("task", task)
+	q.Set("type", logType)
+	q.Set("plain", "true")
+	q.Set("follow", strconv.FormatBool(follow))
+
+	if offset > 0 {
+		q.Set("origin", "end")
+		q.Set("offset", strconv.FormatInt(offset, 10))
+	}
+
+	r, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/v1/client/fs/logs/%s?%s", n.c.APIAddress(utils.Context(n.context)), allocID, q.Encode()),
+		nil,
+	)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create http request: %w", err)
+	}
+
+	resp, err := n.httpClient.Do(r)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to get allocation logs: %w", err)
+	}
+
+	if resp.Body == nil {
+		return nil, xerrors.Errorf("No body returned from Nomad API")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+
+		return nil, xerrors.Errorf("Error getting allocation logs, got status code %d: %s", resp.StatusCode, string(b))
+	}
+
+	return resp.Body, nil
+}
+
 func (n *NomadImpl) getJobAllocations(job string) ([]map[string]interface{}, error) {
 	// get the allocations for the job
 	r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/job/%s/allocations", n.c.APIAddress(utils.Context(n.context)), job), nil)
@@ -405,7 +538,40 @@ func (n *NomadImpl) getJobAllocations(job string) ([]map[string]interface{}, err
 	return jobDetail, err
 }
 
-func (n *NomadImpl) getJobID(file string) (string, error) {
+// getJobDeployment returns the latest deployment for the job or nil when the
+// job has no deployments
+func (n *NomadImpl) getJobDeployment(job string) (*deployment, error) {
+	r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/job/%s/deployment", n.c.APIAddress(utils.Context(n.context)), job), nil)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create http request: %w", err)
+	}
+
+	resp, err := n.httpClient.Do(r)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to query job deployment: %w", err)
+	}
+
+	if resp.Body == nil {
+		return nil, xerrors.Errorf("No body returned from Nomad API")
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("Error querying job deployment, got status code %d", resp.StatusCode)
+	}
+
+	var d *deployment
+	err = json.NewDecoder(resp.Body).Decode(&d)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to query job deployment in Nomad server: %s: %s", n.c.APIAddress(utils.Context(n.context)), err)
+	}
+
+	return d, nil
+}
+
+// JobID parses the job in the given file and returns its ID
+func (n *NomadImpl) JobID(file string) (string, error) {
 	// parse the job
 	jsonJob, err := n.ParseJob(file)
 	if err != nil {
@@ -422,6 +588,12 @@ func (n *NomadImpl) getJobID(file string) (string, error) {
 	return jobMap["ID"].(string), nil
 }
 
+type deployment struct {
+	ID                string
+	Status            string
+	StatusDescription string
+}
+
 type allocation struct {
 	ID        string
 	Job       job
@@ -2,6 +2,7 @@ package clients
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -38,7 +39,7 @@ func setupNomadTests(t *testing.T) (utils.ClusterConfig, string, *mocks.MockHTTP
 		nil,
 	)
 
-	clusterConfig, _ := utils.GetClusterConfig("nomad_cluster." + "testing")
+	clusterConfig, _, _ := utils.GetClusterConfig("nomad_cluster." + "testing")
 	clusterConfig.NodeCount = 2
 
 	return clusterConfig, tmpDir, mh
@@ -288,6 +289,93 @@ func TestNomadJobStatusReturnsErrorWhenPending(t *testing.T) {
 	assert.False(t, s)
 }
 
+func setupNomadJobHealthMocks(mh *mocks.MockHTTP, responses ...string) {
+	removeOn(&mh.Mock, "Do")
+
+	for _, r := range responses {
+		mh.On("Do", mock.Anything, mock.Anything, mock.Anything).Return(
+			&http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(r))),
+			},
+			nil,
+		).Once()
+	}
+}
+
+func TestNomadJobHealthyReturnsTrueWhenDeploymentSuccessful(t *testing.T) {
+	fp, _, mh := setupNomadTests(t)
+	setupNomadJobHealthMocks(mh, jobDeploymentSuccessfulResponse)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp, "local")
+
+	s, err := c.JobHealthy("example_1")
+	assert.NoError(t, err)
+	assert.True(t, s)
+
+	assert.Equal(t, "/v1/job/example_1/deployment", getCalls(&mh.Mock, "Do")[0].Arguments[0].(*http.Request).URL.Path)
+}
+
+func TestNomadJobHealthyReturnsFalseWhenDeploymentRunning(t *testing.T) {
+	fp, _, mh := setupNomadTests(t)
+	setupNomadJobHealthMocks(mh, jobDeploymentRunningResponse)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp, "local")
+
+	s, err := c.JobHealthy("example_1")
+	assert.NoError(t, err)
+	assert.False(t, s)
+}
+
+func TestNomadJobHealthyReturnsErrorWhenDeploymentFailed(t *testing.T) {
+	fp, _, mh := setupNomadTests(t)
+	setupNomadJobHealthMocks(mh, jobDeploymentFailedResponse)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp, "local")
+
+	_, err := c.JobHealthy("example_1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed due to unhealthy allocations")
+}
+
+func TestNomadJobHealthyWithoutDeploymentChecksAllocations(t *testing.T) {
+	fp, _, mh := setupNomadTests(t)
+	setupNomadJobHealthMocks(mh, "null", jobAllocationsResponse)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp, "local")
+
+	s, err := c.JobHealthy("example_1")
+	assert.NoError(t, err)
+	assert.True(t, s)
+}
+
+func TestNomadJobHealthyWithoutDeploymentReturnsFalseWhenPending(t *testing.T) {
+	fp, _, mh := setupNomadTests(t)
+	setupNomadJobHealthMocks(mh, "null", jobAllocationsPendingResponse)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp, "local")
+
+	s, err := c.JobHealthy("example_1")
+	assert.NoError(t, err)
+	assert.False(t, s)
+}
+
+func TestNomadJobHealthyWithoutDeploymentReturnsErrorWhenAllocationFailed(t *testing.T) {
+	fp, _, mh := setupNomadTests(t)
+	setupNomadJobHealthMocks(mh, "null", jobAllocationsFailedResponse)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp, "local")
+
+	_, err := c.JobHealthy("example_1")
+	assert.Error(t, err)
+}
+
 func TestNomadHealthCallsAPI(t *testing.T) {
 	fp, _, mh := setupNomadTests(t)
 
@@ -498,6 +586,78 @@ func TestNomadEndpointsReturnsConnectEndpoints(t *testing.T) {
 	assert.Equal(t, "10.5.0.4:9090", e[0]["http"])
 }
 
+func TestNomadJobAllocationsReturnsRunningAllocations(t *testing.T) {
+	fp, _, mh := setupNomadTests(t)
+
+	removeOn(&mh.Mock, "Do")
+	mh.On("Do", mock.Anything, mock.Anything, mock.Anything).Return(
+		&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(jobAllocationsTasksResponse))),
+		},
+		nil,
+	)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp, "local")
+
+	a, err := c.JobAllocations("example_1")
+	assert.NoError(t, err)
+	assert.Len(t, a, 1)
+
+	assert.Equal(t, []string{"envoy", "fake_service"}, a["e6d4fe8c-1ba3-2248-cf89-18760af8c278"])
+}
+
+func TestNomadAllocationLogsCallsAPI(t *testing.T) {
+	fp, _, mh := setupNomadTests(t)
+
+	removeOn(&mh.Mock, "Do")
+	mh.On("Do", mock.Anything, mock.Anything, mock.Anything).Return(
+		&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("hello\n"))),
+		},
+		nil,
+	)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp, "local")
+
+	rc, err := c.AllocationLogs(context.Background(), "abc", "fake_service", "stderr", true, 4096)
+	assert.NoError(t, err)
+
+	d, _ := ioutil.ReadAll(rc)
+	assert.Equal(t, "hello\n", string(d))
+
+	r := mh.Calls[0].Arguments.Get(0).(*http.Request)
+	assert.Equal(t, "/v1/client/fs/logs/abc", r.URL.Path)
+	assert.Equal(t, "fake_service", r.URL.Query().Get("task"))
+	assert.Equal(t, "stderr", r.URL.Query().Get("type"))
+	assert.Equal(t, "true", r.URL.Query().Get("follow"))
+	assert.Equal(t, "end", r.URL.Query().Get("origin"))
+	assert.Equal(t, "4096", r.URL.Query().Get("offset"))
+}
+
+func TestNomadAllocationLogsReturnsErrorWhenNot200(t *testing.T) {
+	fp, _, mh := setupNomadTests(t)
+
+	removeOn(&mh.Mock, "Do")
+	mh.On("Do", mock.Anything, mock.Anything, mock.Anything).Return(
+		&http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("unknown allocation"))),
+		},
+		nil,
+	)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp, "local")
+
+	_, err := c.AllocationLogs(context.Background(), "abc", "fake_service", "stdout", false, 0)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown allocation")
+}
+
 var aliveResponse = `
 [
 	{
@@ -694,6 +854,49 @@ var jobAllocationsPendingResponse = `
 ]
 `
 
+var jobAllocationsFailedResponse = `
+[
+  {
+    "ID": "da975cd1-8b04-6bce-9d5c-03e47353768c",
+    "JobID": "example_1",
+    "JobType": "batch",
+    "TaskGroup": "fake_service",
+    "DesiredStatus": "run",
+    "ClientStatus": "failed"
+  }
+]
+`
+
+var jobDeploymentSuccessfulResponse = `
+{
+  "ID": "70638f62-5c19-193e-30d6-f9d6e689ab8e",
+  "JobID": "example_1",
+  "JobVersion": 1,
+  "Status": "successful",
+  "StatusDescription": "Deployment completed successfully"
+}
+`
+
+var jobDeploymentRunningResponse = `
+{
+  "ID": "70638f62-5c19-193e-30d6-f9d6e689ab8e",
+  "JobID": "example_1",
+  "JobVersion": 1,
+  "Status": "running",
+  "StatusDescription": "Deployment is running"
+}
+`
+
+var jobDeploymentFailedResponse = `
+{
+  "ID": "70638f62-5c19-193e-30d6-f9d6e689ab8e",
+  "JobID": "example_1",
+  "JobVersion": 1,
+  "Status": "failed",
+  "StatusDescription": "Failed due to unhealthy allocations"
+}
+`
+
 var allocationsResponse1 = `
 {
   "ID": "da975cd1-8b04-6bce-9d5c-03e47353768c",
@@ -1062,3 +1265,29 @@ var allocationsConnectResponse = `
   "ModifyTime": 1616397645647263000
 }
 `
+
+var jobAllocationsTasksResponse = `
+[
+  {
+    "ID": "da975cd1-8b04-6bce-9d5c-03e47353768c",
+    "Name": "example_1.fake_service[0]",
+    "JobID": "example_1",
+    "TaskGroup": "fake_service",
+    "ClientStatus": "complete",
+    "TaskStates": {
+      "fake_service": {"State": "dead"}
+    }
+  },
+  {
+    "ID": "e6d4fe8c-1ba3-2248-cf89-18760af8c278",
+    "Name": "example_1.fake_service[1]",
+    "JobID": "example_1",
+    "TaskGroup": "fake_service",
+    "ClientStatus": "running",
+    "TaskStates": {
+      "fake_service": {"State": "running"},
+      "envoy": {"State": "running"}
+    }
+  }
+]
+`
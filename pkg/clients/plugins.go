@@ -0,0 +1,222 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/plugin"
+	"golang.org/x/xerrors"
+)
+
+// Plugins defines an interface for a client which runs the plugins that provide
+// additional resource types
+type Plugins interface {
+	// Discover returns the resource types for each plugin in the plugins folder,
+	// plugins which can not be loaded are logged and ignored
+	Discover() (map[string][]string, error)
+
+	// Validate checks the config for the resource using the plugin
+	Validate(r *config.PluginResource) error
+
+	// Create the resource using the plugin
+	Create(r *config.PluginResource) error
+
+	// Destroy the resource using the plugin
+	Destroy(r *config.PluginResource) error
+
+	// Lookup returns the ids of the objects created for the resource
+	Lookup(r *config.PluginResource) ([]string, error)
+
+	// Logs returns a stream of the logs for the resource, closing the stream
+	// stops the plugin
+	Logs(r *config.PluginResource, follow bool, tail string) (io.ReadCloser, error)
+}
+
+// PluginsImpl is a concrete implementation of the Plugins interface which runs
+// the plugin executables in a folder
+type PluginsImpl struct {
+	dir string
+	log hclog.Logger
+}
+
+// NewPlugins creates a Plugins client for the plugin executables in dir
+func NewPlugins(dir string, l hclog.Logger) Plugins {
+	return &PluginsImpl{dir, l}
+}
+
+// Discover returns the resource types for each plugin in the plugins folder
+func (p *PluginsImpl) Discover() (map[string][]string, error) {
+	plugins := map[string][]string{}
+
+	files, err := ioutil.ReadDir(p.dir)
+	if os.IsNotExist(err) {
+		return plugins, nil
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read plugins folder %s: %w", p.dir, err)
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), plugin.ExecutablePrefix) {
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(f.Name(), plugin.ExecutablePrefix), ".exe")
+
+		out, err := p.run(name, plugin.CommandSchema, nil)
+		if err != nil {
+			p.log.Warn("Unable to load plugin", "plugin", name, "error", err)
+			continue
+		}
+
+		s := plugin.Schema{}
+		err = json.Unmarshal(out, &s)
+		if err != nil {
+			p.log.Warn("Unable to read schema for plugin", "plugin", name, "error", err)
+			continue
+		}
+
+		if s.ProtocolVersion != plugin.ProtocolVersion {
+			p.log.Warn("Plugin uses an unsupported protocol version", "plugin", name, "version", s.ProtocolVersion, "supported", plugin.ProtocolVersion)
+			continue
+		}
+
+		p.log.Debug("Loaded plugin", "plugin", name, "types", s.Types)
+		plugins[name] = s.Types
+	}
+
+	return plugins, nil
+}
+
+// Validate checks the config for the resource using the plugin
+func (p *PluginsImpl) Validate(r *config.PluginResource) error {
+	_, err := p.run(r.Plugin, plugin.CommandValidate, &plugin.Request{Resource: r})
+	return err
+}
+
+// Create the resource using the plugin
+func (p *PluginsImpl) Create(r *config.PluginResource) error {
+	_, err := p.run(r.Plugin, plugin.CommandCreate, &plugin.Request{Resource: r})
+	return err
+}
+
+// Destroy the resource using the plugin
+func (p *PluginsImpl) Destroy(r *config.PluginResource) error {
+	_, err := p.run(r.Plugin, plugin.CommandDestroy, &plugin.Request{Resource: r})
+	return err
+}
+
+// Lookup returns the ids of the objects created for the resource
+func (p *PluginsImpl) Lookup(r *config.PluginResource) ([]string, error) {
+	out, err := p.run(r.Plugin, plugin.CommandLookup, &plugin.Request{Resource: r})
+	if err != nil {
+		return nil, err
+	}
+
+	lr := plugin.LookupResponse{}
+	err = json.Unmarshal(out, &lr)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to read response from plugin %s: %w", r.Plugin, err)
+	}
+
+	return lr.IDs, nil
+}
+
+// Logs returns a stream of the logs for the resource
+func (p *PluginsImpl) Logs(r *config.PluginResource, follow bool, tail string) (io.ReadCloser, error) {
+	cmd, err := p.command(r.Plugin, plugin.CommandLogs, &plugin.Request{Resource: r, Follow: follow, Tail: tail})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Stderr = p.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug})
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to start plugin %s: %w", r.Plugin, err)
+	}
+
+	return &pluginStream{out, cmd}, nil
+}
+
+// command creates the command to run the plugin writing the request to stdin
+func (p *PluginsImpl) command(name, command string, req *plugin.Request) (*exec.Cmd, error) {
+	path := filepath.Join(p.dir, plugin.ExecutablePrefix+name)
+	if runtime.GOOS == "windows" {
+		path += ".exe"
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("plugin %s is not installed, plugins must be in %s", name, p.dir)
+	}
+
+	cmd := exec.Command(path, command)
+
+	if req != nil {
+		d, err := json.Marshal(req)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to create request for plugin %s: %w", name, err)
+		}
+
+		cmd.Stdin = bytes.NewReader(d)
+	}
+
+	return cmd, nil
+}
+
+// run the command returning stdout, the error contains stderr when the
+// plugin exits with a non zero exit code
+func (p *PluginsImpl) run(name, command string, req *plugin.Request) ([]byte, error) {
+	cmd, err := p.command(name, command, req)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	p.log.Debug("Running plugin", "plugin", name, "command", command)
+
+	err = cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+
+		return nil, fmt.Errorf("plugin %s failed to %s: %s", name, command, msg)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// pluginStream is the stdout of a running plugin, closing the stream stops the plugin
+type pluginStream struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (s *pluginStream) Close() error {
+	s.cmd.Process.Kill()
+	s.cmd.Wait()
+
+	return nil
+}
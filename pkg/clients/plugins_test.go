@@ -0,0 +1,129 @@
+package clients
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+// testPlugin is a plugin which echos the request for create so that the
+// test can check the request sent by the client
+const testPlugin = `#!/bin/sh
+case "$1" in
+  schema)
+    echo '{"protocol_version": 1, "types": ["firecracker_vm"]}'
+    ;;
+  create)
+    cat > "$(dirname "$0")/request.json"
+    ;;
+  destroy)
+    echo "unable to stop vm" >&2
+    exit 1
+    ;;
+  lookup)
+    echo '{"ids": ["vm-1"]}'
+    ;;
+  logs)
+    echo "vm booted"
+    ;;
+esac
+`
+
+const oldPlugin = `#!/bin/sh
+echo '{"protocol_version": 0, "types": ["old_vm"]}'
+`
+
+func setupPlugins(t *testing.T) (*PluginsImpl, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test plugins are shell scripts")
+	}
+
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "shipyard-plugin-firecracker"), []byte(testPlugin), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "shipyard-plugin-old"), []byte(oldPlugin), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a plugin"), 0644)
+
+	return NewPlugins(dir, hclog.NewNullLogger()).(*PluginsImpl), dir
+}
+
+func testPluginResource() *config.PluginResource {
+	r := config.NewPluginResource("dev", "firecracker_vm", "firecracker")
+	r.Attributes["memory"] = 1024
+
+	return r
+}
+
+func TestPluginsDiscoverReturnsTypes(t *testing.T) {
+	p, _ := setupPlugins(t)
+
+	plugins, err := p.Discover()
+	assert.NoError(t, err)
+
+	// plugins using a different protocol version are ignored
+	assert.Equal(t, map[string][]string{"firecracker": {"firecracker_vm"}}, plugins)
+}
+
+func TestPluginsDiscoverWithMissingFolderReturnsEmpty(t *testing.T) {
+	p := NewPlugins(filepath.Join(t.TempDir(), "missing"), hclog.NewNullLogger())
+
+	plugins, err := p.Discover()
+	assert.NoError(t, err)
+	assert.Empty(t, plugins)
+}
+
+func TestPluginsCreateSendsResource(t *testing.T) {
+	p, dir := setupPlugins(t)
+
+	err := p.Create(testPluginResource())
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(filepath.Join(dir, "request.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), `"name":"dev"`)
+	assert.Contains(t, string(d), `"memory":1024`)
+}
+
+func TestPluginsDestroyReturnsStderrWhenPluginFails(t *testing.T) {
+	p, _ := setupPlugins(t)
+
+	err := p.Destroy(testPluginResource())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to stop vm")
+}
+
+func TestPluginsLookupReturnsIDs(t *testing.T) {
+	p, _ := setupPlugins(t)
+
+	ids, err := p.Lookup(testPluginResource())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vm-1"}, ids)
+}
+
+func TestPluginsLogsStreamsOutput(t *testing.T) {
+	p, _ := setupPlugins(t)
+
+	rc, err := p.Logs(testPluginResource(), false, "40")
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "vm booted\n", string(d))
+
+	rc.Close()
+}
+
+func TestPluginsReturnsErrorWhenPluginNotInstalled(t *testing.T) {
+	p, _ := setupPlugins(t)
+
+	r := testPluginResource()
+	r.Plugin = "missing"
+
+	err := p.Create(r)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not installed")
+}
@@ -0,0 +1,90 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Podman is a Docker client for the Podman compatible API, it handles the
+// differences in behaviour between the Docker and Podman engines
+type Podman struct {
+	Docker
+	rootless bool
+}
+
+// NewPodman creates a Podman client which wraps the given Docker client,
+// rootless should be set when the Podman API is running as the current user
+func NewPodman(c Docker, rootless bool) *Podman {
+	return &Podman{c, rootless}
+}
+
+// Rootless returns true when Podman is running without root privileges
+func (p *Podman) Rootless() bool {
+	return p.rootless
+}
+
+// ContainerCreate creates a new container, rootless Podman is unable to bind
+// privileged ports, rather than fail when starting the container an error
+// explaining the problem is returned
+func (p *Podman) ContainerCreate(
+	ctx context.Context,
+	config *container.Config,
+	hostConfig *container.HostConfig,
+	networkingConfig *network.NetworkingConfig,
+	platform *specs.Platform,
+	containerName string,
+) (container.ContainerCreateCreatedBody, error) {
+	if p.rootless && hostConfig != nil {
+		for _, bindings := range hostConfig.PortBindings {
+			for _, b := range bindings {
+				port, _ := strconv.Atoi(b.HostPort)
+				if port > 0 && port < 1024 {
+					return container.ContainerCreateCreatedBody{}, fmt.Errorf(
+						"rootless Podman is unable to bind to the privileged port %d, either use a port above 1023 or set the kernel parameter net.ipv4.ip_unprivileged_port_start=%d",
+						port,
+						port,
+					)
+				}
+			}
+		}
+	}
+
+	return p.Docker.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+}
+
+// NetworkCreate creates a new network, Podman only supports the bridge
+// and macvlan drivers and rootless Podman does not support macvlan
+func (p *Podman) NetworkCreate(ctx context.Context, name string, options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+	switch options.Driver {
+	case "", "bridge":
+	case "macvlan":
+		if p.rootless {
+			return types.NetworkCreateResponse{}, fmt.Errorf("rootless Podman does not support the macvlan network driver")
+		}
+	default:
+		return types.NetworkCreateResponse{}, fmt.Errorf("Podman does not support the %s network driver", options.Driver)
+	}
+
+	// Podman does not implement attachable networks, all networks can be
+	// attached to containers
+	options.Attachable = false
+
+	return p.Docker.NetworkCreate(ctx, name, options)
+}
+
+// isPodman returns true when the server version is for a Podman engine
+func isPodman(ver types.Version) bool {
+	for _, c := range ver.Components {
+		if c.Name == EngineTypePodman {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package clients
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupPodmanTests(rootless bool) (*Podman, *mocks.MockDocker) {
+	md := &mocks.MockDocker{}
+	md.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(container.ContainerCreateCreatedBody{ID: "abc"}, nil)
+	md.On("NetworkCreate", mock.Anything, mock.Anything, mock.Anything).Return(types.NetworkCreateResponse{}, nil)
+
+	return NewPodman(md, rootless), md
+}
+
+func TestPodmanIsDetectedFromServerVersion(t *testing.T) {
+	assert.True(t, isPodman(types.Version{Components: []types.ComponentVersion{{Name: EngineTypePodman}}}))
+	assert.False(t, isPodman(types.Version{Components: []types.ComponentVersion{{Name: EngineTypeDocker}}}))
+}
+
+func TestPodmanRootlessContainerCreateReturnsErrorForPrivilegedPort(t *testing.T) {
+	p, md := setupPodmanTests(true)
+
+	hc := &container.HostConfig{PortBindings: nat.PortMap{"80/tcp": []nat.PortBinding{{HostPort: "80"}}}}
+	_, err := p.ContainerCreate(nil, &container.Config{}, hc, nil, nil, "test")
+
+	assert.Error(t, err)
+	md.AssertNotCalled(t, "ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPodmanContainerCreateAllowsPrivilegedPortWhenRoot(t *testing.T) {
+	p, md := setupPodmanTests(false)
+
+	hc := &container.HostConfig{PortBindings: nat.PortMap{"80/tcp": []nat.PortBinding{{HostPort: "80"}}}}
+	_, err := p.ContainerCreate(nil, &container.Config{}, hc, nil, nil, "test")
+
+	assert.NoError(t, err)
+	md.AssertCalled(t, "ContainerCreate", mock.Anything, mock.Anything, hc, mock.Anything, "test")
+}
+
+func TestPodmanNetworkCreateReturnsErrorForNatDriver(t *testing.T) {
+	p, md := setupPodmanTests(false)
+
+	_, err := p.NetworkCreate(nil, "test", types.NetworkCreate{Driver: "nat"})
+
+	assert.Error(t, err)
+	md.AssertNotCalled(t, "NetworkCreate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPodmanRootlessNetworkCreateReturnsErrorForMacvlan(t *testing.T) {
+	p, _ := setupPodmanTests(true)
+
+	_, err := p.NetworkCreate(nil, "test", types.NetworkCreate{Driver: "macvlan"})
+
+	assert.Error(t, err)
+}
+
+func TestPodmanNetworkCreateRemovesAttachable(t *testing.T) {
+	p, md := setupPodmanTests(false)
+
+	_, err := p.NetworkCreate(nil, "test", types.NetworkCreate{Driver: "bridge", Attachable: true})
+	assert.NoError(t, err)
+
+	opts := getCalls(&md.Mock, "NetworkCreate")[0].Arguments[2].(types.NetworkCreate)
+	assert.False(t, opts.Attachable)
+}
@@ -0,0 +1,121 @@
+package clients
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+var proxyCAFiles []string
+var proxyEnabled bool
+var proxyLock sync.Mutex
+
+// SetProxy configures the proxy for the outbound connections made by Shipyard and the
+// processes it starts, e.g. git and the connector. The proxy is set in the environment
+// which is read by the HTTP and gRPC clients, values which are not set in the config
+// are read from the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables.
+//
+// The HTTP client reads the environment once so SetProxy must be called before any
+// connections are made. Connections to Shipyard resources are never proxied.
+func SetProxy(p *config.Proxy) error {
+	if p == nil {
+		p = &config.Proxy{}
+	}
+
+	if p.HTTP != "" {
+		setProxyEnv("HTTP_PROXY", p.HTTP)
+	}
+
+	if p.HTTPS != "" {
+		setProxyEnv("HTTPS_PROXY", p.HTTPS)
+	}
+
+	if proxyEnv("HTTP_PROXY") != "" || proxyEnv("HTTPS_PROXY") != "" {
+		setProxyEnv("NO_PROXY", utils.MergeNoProxy(proxyEnv("NO_PROXY"), strings.Join(p.NoProxy, ","), utils.ProxyBypass))
+	}
+
+	files := []string{}
+	if p.CAFile != "" {
+		files = append(files, p.CAFile)
+	}
+
+	_, err := ReadTrustedCAs(files)
+	if err != nil {
+		return err
+	}
+
+	proxyLock.Lock()
+	proxyCAFiles = files
+	proxyEnabled = proxyEnv("HTTP_PROXY") != "" || proxyEnv("HTTPS_PROXY") != ""
+	proxyLock.Unlock()
+
+	// the blueprint has not been read so only the CAs of the proxy are trusted
+	err = SetTrustedCAs(nil)
+	if err != nil {
+		return err
+	}
+
+	// git only accepts a CA file, settings in the users environment are not changed
+	if len(files) > 0 {
+		if _, ok := os.LookupEnv("GIT_SSL_CAINFO"); !ok {
+			bundle, err := trustedCABundle()
+			if err != nil {
+				return fmt.Errorf("unable to create CA bundle for git: %s", err)
+			}
+
+			if bundle != "" {
+				os.Setenv("GIT_SSL_CAINFO", bundle)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ProxyCAFiles returns the CA bundles of the proxy which are trusted by all outbound
+// connections and by the nodes of clusters
+func ProxyCAFiles() []string {
+	proxyLock.Lock()
+	defer proxyLock.Unlock()
+
+	return append([]string{}, proxyCAFiles...)
+}
+
+// ProxyEnabled returns true when SetProxy configured a HTTP or HTTPS proxy
+func ProxyEnabled() bool {
+	proxyLock.Lock()
+	defer proxyLock.Unlock()
+
+	return proxyEnabled
+}
+
+// ProxyCAs returns the PEM encoded CAs of the proxy, nil is returned when no CAs
+// have been configured
+func ProxyCAs() ([]byte, error) {
+	files := ProxyCAFiles()
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	return ReadTrustedCAs(files)
+}
+
+// proxyEnv returns the value of the environment variable, the lower case
+// variant used by some tools is returned when the variable is not set
+func proxyEnv(name string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+
+	return os.Getenv(strings.ToLower(name))
+}
+
+// setProxyEnv sets the upper and lower case variants of the environment variable
+func setProxyEnv(name, value string) {
+	os.Setenv(name, value)
+	os.Setenv(strings.ToLower(name), value)
+}
@@ -0,0 +1,107 @@
+package clients
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+// setupProxyTests clears the proxy environment, SetProxy changes the environment of
+// the process so t.Setenv is used to restore the original values after the test
+func setupProxyTests(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for _, e := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy", "GIT_SSL_CAINFO"} {
+		t.Setenv(e, "")
+		os.Unsetenv(e)
+	}
+
+	t.Cleanup(func() {
+		proxyLock.Lock()
+		proxyCAFiles = nil
+		proxyEnabled = false
+		proxyLock.Unlock()
+
+		SetTrustedCAs(nil)
+	})
+}
+
+func TestSetProxySetsEnvironmentFromConfig(t *testing.T) {
+	setupProxyTests(t)
+
+	err := SetProxy(&config.Proxy{HTTP: "http://proxy.corp:3128", HTTPS: "http://proxy.corp:3129", NoProxy: []string{".corp"}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "http://proxy.corp:3128", os.Getenv("HTTP_PROXY"))
+	assert.Equal(t, "http://proxy.corp:3128", os.Getenv("http_proxy"))
+	assert.Equal(t, "http://proxy.corp:3129", os.Getenv("HTTPS_PROXY"))
+	assert.Equal(t, utils.MergeNoProxy(".corp", utils.ProxyBypass), os.Getenv("NO_PROXY"))
+	assert.True(t, ProxyEnabled())
+}
+
+func TestSetProxyMergesNoProxyFromEnvironment(t *testing.T) {
+	setupProxyTests(t)
+	t.Setenv("https_proxy", "http://proxy.corp:3128")
+	t.Setenv("NO_PROXY", "internal.corp")
+
+	err := SetProxy(nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, utils.MergeNoProxy("internal.corp", utils.ProxyBypass), os.Getenv("NO_PROXY"))
+	assert.Empty(t, os.Getenv("HTTP_PROXY"))
+}
+
+func TestSetProxyDoesNotSetNoProxyWithoutProxy(t *testing.T) {
+	setupProxyTests(t)
+
+	err := SetProxy(&config.Proxy{NoProxy: []string{".corp"}})
+	assert.NoError(t, err)
+
+	assert.Empty(t, os.Getenv("NO_PROXY"))
+	assert.False(t, ProxyEnabled())
+}
+
+func TestSetProxyTrustsCA(t *testing.T) {
+	setupProxyTests(t)
+	ca := createTestCA(t)
+
+	err := SetProxy(&config.Proxy{CAFile: ca})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{ca}, ProxyCAFiles())
+	assert.NotNil(t, trustedTransport())
+
+	pca, err := ProxyCAs()
+	assert.NoError(t, err)
+
+	d, _ := ioutil.ReadFile(ca)
+	assert.Equal(t, d, pca)
+
+	// the CA of the proxy is trusted with the CAs from the blueprint
+	err = SetTrustedCAs(nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, trustedTransport())
+
+	// git uses a bundle containing the CA
+	bundle := os.Getenv("GIT_SSL_CAINFO")
+	if bundle != "" {
+		bd, err := ioutil.ReadFile(bundle)
+		assert.NoError(t, err)
+		assert.Contains(t, string(bd), string(d))
+	}
+}
+
+func TestSetProxyWithInvalidCAReturnsError(t *testing.T) {
+	setupProxyTests(t)
+
+	f := t.TempDir() + "/ca.pem"
+	ioutil.WriteFile(f, []byte("not a cert"), 0644)
+
+	err := SetProxy(&config.Proxy{CAFile: f})
+	assert.Error(t, err)
+	assert.Empty(t, ProxyCAFiles())
+}
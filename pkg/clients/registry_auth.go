@@ -0,0 +1,122 @@
+package clients
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/credentials"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// dockerHubAuthServer is the key Docker uses to store Docker Hub credentials
+const dockerHubAuthServer = "https://index.docker.io/v1/"
+
+// RegistryCredentials holds the credentials used to authenticate with
+// a Docker registry
+type RegistryCredentials struct {
+	Registry string
+	Username string
+	Password string
+}
+
+// ImageRegistry returns the host of the registry for the given image
+// e.g. ghcr.io/shipyard-run/envoy:latest -> ghcr.io, consul:1.10.0 -> docker.io
+func ImageRegistry(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 {
+		return "docker.io"
+	}
+
+	// the first part of the image is only a registry when it looks like a host
+	if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return "docker.io"
+	}
+
+	return parts[0]
+}
+
+// LookupRegistryCredentials returns the credentials for the registry which hosts
+// the given image. Credentials are resolved in the following order:
+//
+//  1. username and password set on the image block
+//  2. SHIPYARD_REGISTRY_[HOST]_USERNAME and SHIPYARD_REGISTRY_[HOST]_PASSWORD environment variables
+//     where HOST is the registry host in upper case with any non alphanumeric characters replaced by _
+//     e.g. SHIPYARD_REGISTRY_GHCR_IO_USERNAME
+//  3. credentials stored by docker login in the Docker config.json or credential helper
+//
+// nil is returned when no credentials can be found
+func LookupRegistryCredentials(image config.Image) *RegistryCredentials {
+	registry := ImageRegistry(image.Name)
+
+	if image.Username != "" && image.Password != "" {
+		return &RegistryCredentials{registry, image.Username, image.Password}
+	}
+
+	return LookupRegistryHostCredentials(registry)
+}
+
+// LookupRegistryHostCredentials returns the credentials for the given registry host
+// from the environment or the Docker config, nil is returned when no credentials
+// can be found
+func LookupRegistryHostCredentials(registry string) *RegistryCredentials {
+	if u, p := registryEnvCredentials(registry); u != "" && p != "" {
+		return &RegistryCredentials{registry, u, p}
+	}
+
+	if u, p := registryDockerCredentials(registry); u != "" && p != "" {
+		return &RegistryCredentials{registry, u, p}
+	}
+
+	return nil
+}
+
+// RegistryEnvVar returns the name of the environment variable which holds the
+// given credential for a registry e.g. SHIPYARD_REGISTRY_GHCR_IO_USERNAME
+func RegistryEnvVar(registry, credential string) string {
+	host := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+
+		return '_'
+	}, registry)
+
+	return strings.ToUpper(fmt.Sprintf("SHIPYARD_REGISTRY_%s_%s", host, credential))
+}
+
+func registryEnvCredentials(registry string) (string, string) {
+	return os.Getenv(RegistryEnvVar(registry, "username")), os.Getenv(RegistryEnvVar(registry, "password"))
+}
+
+// registryDockerCredentials reads the credentials for the registry from the Docker config
+// the location of the config can be overridden with the DOCKER_CONFIG environment variable
+func registryDockerCredentials(registry string) (string, string) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		dir = filepath.Join(utils.HomeFolder(), ".docker")
+	}
+
+	cf, err := dockerconfig.Load(dir)
+	if err != nil {
+		return "", ""
+	}
+
+	if !cf.ContainsAuth() {
+		cf.CredentialsStore = credentials.DetectDefaultStore(cf.CredentialsStore)
+	}
+
+	if registry == "docker.io" {
+		registry = dockerHubAuthServer
+	}
+
+	ac, err := cf.GetAuthConfig(registry)
+	if err != nil {
+		return "", ""
+	}
+
+	return ac.Username, ac.Password
+}
@@ -0,0 +1,98 @@
+package clients
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupRegistryAuthTests(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+
+	return dir
+}
+
+func writeDockerConfig(t *testing.T, dir, registry, username, password string) {
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
+	cfg := fmt.Sprintf(`{"auths": {"%s": {"auth": "%s"}}}`, registry, auth)
+
+	err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(cfg), os.ModePerm)
+	assert.NoError(t, err)
+}
+
+func TestImageRegistryReturnsHost(t *testing.T) {
+	assert.Equal(t, "docker.io", ImageRegistry("consul:1.10.0"))
+	assert.Equal(t, "docker.io", ImageRegistry("shipyardrun/ingress:latest"))
+	assert.Equal(t, "ghcr.io", ImageRegistry("ghcr.io/shipyard-run/envoy:latest"))
+	assert.Equal(t, "localhost:5000", ImageRegistry("localhost:5000/app"))
+	assert.Equal(t, "localhost", ImageRegistry("localhost/app"))
+}
+
+func TestRegistryEnvVarReturnsSanitizedName(t *testing.T) {
+	assert.Equal(t, "SHIPYARD_REGISTRY_GHCR_IO_USERNAME", RegistryEnvVar("ghcr.io", "username"))
+	assert.Equal(t, "SHIPYARD_REGISTRY_LOCALHOST_5000_PASSWORD", RegistryEnvVar("localhost:5000", "password"))
+}
+
+func TestLookupRegistryCredentialsReturnsNilWhenNotFound(t *testing.T) {
+	setupRegistryAuthTests(t)
+
+	assert.Nil(t, LookupRegistryCredentials(config.Image{Name: "ghcr.io/acme/app"}))
+}
+
+func TestLookupRegistryCredentialsUsesImageCredentials(t *testing.T) {
+	dir := setupRegistryAuthTests(t)
+	writeDockerConfig(t, dir, "ghcr.io", "docker", "dockerpass")
+	t.Setenv("SHIPYARD_REGISTRY_GHCR_IO_USERNAME", "env")
+	t.Setenv("SHIPYARD_REGISTRY_GHCR_IO_PASSWORD", "envpass")
+
+	rc := LookupRegistryCredentials(config.Image{Name: "ghcr.io/acme/app", Username: "image", Password: "imagepass"})
+	assert.Equal(t, &RegistryCredentials{"ghcr.io", "image", "imagepass"}, rc)
+}
+
+func TestLookupRegistryCredentialsUsesEnvironment(t *testing.T) {
+	dir := setupRegistryAuthTests(t)
+	writeDockerConfig(t, dir, "ghcr.io", "docker", "dockerpass")
+	t.Setenv("SHIPYARD_REGISTRY_GHCR_IO_USERNAME", "env")
+	t.Setenv("SHIPYARD_REGISTRY_GHCR_IO_PASSWORD", "envpass")
+
+	rc := LookupRegistryCredentials(config.Image{Name: "ghcr.io/acme/app"})
+	assert.Equal(t, &RegistryCredentials{"ghcr.io", "env", "envpass"}, rc)
+}
+
+func TestLookupRegistryCredentialsUsesDockerConfig(t *testing.T) {
+	dir := setupRegistryAuthTests(t)
+	writeDockerConfig(t, dir, "ghcr.io", "docker", "dockerpass")
+
+	rc := LookupRegistryCredentials(config.Image{Name: "ghcr.io/acme/app"})
+	assert.Equal(t, &RegistryCredentials{"ghcr.io", "docker", "dockerpass"}, rc)
+}
+
+func TestLookupRegistryCredentialsUsesDockerHubConfig(t *testing.T) {
+	dir := setupRegistryAuthTests(t)
+	writeDockerConfig(t, dir, dockerHubAuthServer, "docker", "dockerpass")
+
+	rc := LookupRegistryCredentials(config.Image{Name: "acme/app"})
+	assert.Equal(t, &RegistryCredentials{"docker.io", "docker", "dockerpass"}, rc)
+}
+
+func TestPullImageWithDockerConfigCredentials(t *testing.T) {
+	dir := setupRegistryAuthTests(t)
+	writeDockerConfig(t, dir, "ghcr.io", "docker", "dockerpass")
+
+	cc, md, mic := createImagePullConfig()
+	cc.Name = "ghcr.io/acme/app:latest"
+
+	setupImagePull(t, cc, md, mic, false)
+
+	ipo := types.ImagePullOptions{RegistryAuth: createRegistryAuth("docker", "dockerpass")}
+	md.AssertCalled(t, "ImagePull", mock.Anything, cc.Name, ipo)
+}
@@ -0,0 +1,173 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// ResourceUsage is the resource consumption of the containers in an environment,
+// CPU and network are cumulative since the containers were started
+type ResourceUsage struct {
+	Containers int     `json:"containers"`
+	CPUSeconds float64 `json:"cpu_seconds"`
+	PeakMemory uint64  `json:"peak_memory"` // sum of the peak memory of each container in bytes
+	Disk       uint64  `json:"disk"`        // size of the writable layer of the containers in bytes
+	NetworkRx  uint64  `json:"network_rx"`
+	NetworkTx  uint64  `json:"network_tx"`
+}
+
+// Merge combines two samples of the same environment, as the values are cumulative
+// the largest of each value is kept
+func (r *ResourceUsage) Merge(o *ResourceUsage) {
+	if o == nil {
+		return
+	}
+
+	r.Containers = maxInt(r.Containers, o.Containers)
+	r.CPUSeconds = maxFloat(r.CPUSeconds, o.CPUSeconds)
+	r.PeakMemory = maxUint(r.PeakMemory, o.PeakMemory)
+	r.Disk = maxUint(r.Disk, o.Disk)
+	r.NetworkRx = maxUint(r.NetworkRx, o.NetworkRx)
+	r.NetworkTx = maxUint(r.NetworkTx, o.NetworkTx)
+}
+
+// SampleResourceUsage reads the stats for the running Shipyard containers
+// from the Docker engine and returns the total usage
+func SampleResourceUsage(c Docker) (*ResourceUsage, error) {
+	args := filters.NewArgs()
+	args.Add("name", "shipyard")
+
+	cl, err := c.ContainerList(context.Background(), types.ContainerListOptions{Filters: args, Size: true})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list containers: %s", err)
+	}
+
+	u := &ResourceUsage{}
+
+	for _, con := range cl {
+		s, err := containerStats(c, con.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		u.Containers++
+		u.CPUSeconds += float64(s.CPUStats.CPUUsage.TotalUsage) / 1e9
+		u.Disk += uint64(con.SizeRw)
+
+		// max usage is only reported by cgroup v1, fall back to the current usage
+		if s.MemoryStats.MaxUsage > 0 {
+			u.PeakMemory += s.MemoryStats.MaxUsage
+		} else {
+			u.PeakMemory += s.MemoryStats.Usage
+		}
+
+		for _, n := range s.Networks {
+			u.NetworkRx += n.RxBytes
+			u.NetworkTx += n.TxBytes
+		}
+	}
+
+	return u, nil
+}
+
+// MemoryUsage is the memory used by the running containers on the Docker engine
+type MemoryUsage struct {
+	Used  uint64 `json:"used"`  // memory used by the containers in bytes, excluding the page cache
+	Total uint64 `json:"total"` // memory available to the Docker engine in bytes
+}
+
+// Percent returns the percentage of the engine memory used by the containers
+func (m *MemoryUsage) Percent() float64 {
+	if m.Total == 0 {
+		return 0
+	}
+
+	return float64(m.Used) / float64(m.Total) * 100
+}
+
+// SampleMemoryUsage reads the memory used by all the running containers on the
+// Docker engine, not only the Shipyard containers, as they all share the engine memory
+func SampleMemoryUsage(c Docker) (*MemoryUsage, error) {
+	info, err := c.Info(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Docker engine info: %s", err)
+	}
+
+	cl, err := c.ContainerList(context.Background(), types.ContainerListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list containers: %s", err)
+	}
+
+	u := &MemoryUsage{Total: uint64(info.MemTotal)}
+
+	for _, con := range cl {
+		s, err := containerStats(c, con.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		u.Used += workingSet(s.MemoryStats)
+	}
+
+	return u, nil
+}
+
+// workingSet returns the memory used by a container excluding inactive page cache
+// which the kernel can reclaim, this is the same value reported by docker stats
+func workingSet(m types.MemoryStats) uint64 {
+	// cgroup v1 reports total_inactive_file, cgroup v2 inactive_file
+	inactive, ok := m.Stats["total_inactive_file"]
+	if !ok {
+		inactive = m.Stats["inactive_file"]
+	}
+
+	if inactive > m.Usage {
+		return 0
+	}
+
+	return m.Usage - inactive
+}
+
+func containerStats(c Docker, id string) (*types.StatsJSON, error) {
+	resp, err := c.ContainerStats(context.Background(), id, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read stats for container %s: %s", id, err)
+	}
+	defer resp.Body.Close()
+
+	s := &types.StatsJSON{}
+	err = json.NewDecoder(resp.Body).Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode stats for container %s: %s", id, err)
+	}
+
+	return s, nil
+}
+
+func maxInt(a, b int) int {
+	if b > a {
+		return b
+	}
+
+	return a
+}
+
+func maxUint(a, b uint64) uint64 {
+	if b > a {
+		return b
+	}
+
+	return a
+}
+
+func maxFloat(a, b float64) float64 {
+	if b > a {
+		return b
+	}
+
+	return a
+}
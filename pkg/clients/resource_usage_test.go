@@ -0,0 +1,129 @@
+package clients
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupResourceUsageMocks(stats ...string) *mocks.MockDocker {
+	md := &mocks.MockDocker{}
+
+	containers := []types.Container{}
+	for i, s := range stats {
+		id := fmt.Sprintf("container%d", i)
+		containers = append(containers, types.Container{ID: id, SizeRw: 1024})
+
+		md.On("ContainerStats", mock.Anything, id, false).Return(
+			types.ContainerStats{Body: ioutil.NopCloser(strings.NewReader(s))},
+			nil,
+		)
+	}
+
+	md.On("ContainerList", mock.Anything, mock.Anything).Return(containers, nil)
+
+	return md
+}
+
+func TestSampleResourceUsageListsShipyardContainers(t *testing.T) {
+	md := setupResourceUsageMocks()
+
+	_, err := SampleResourceUsage(md)
+	assert.NoError(t, err)
+
+	opts := getCalls(&md.Mock, "ContainerList")[0].Arguments[1].(types.ContainerListOptions)
+	assert.True(t, opts.Size)
+	assert.Equal(t, []string{"shipyard"}, opts.Filters.Get("name"))
+}
+
+func TestSampleResourceUsageTotalsContainers(t *testing.T) {
+	md := setupResourceUsageMocks(containerStatsCgroupV1, containerStatsCgroupV2)
+
+	u, err := SampleResourceUsage(md)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, u.Containers)
+	assert.Equal(t, 3.5, u.CPUSeconds)
+	assert.Equal(t, uint64(3000), u.PeakMemory)
+	assert.Equal(t, uint64(2048), u.Disk)
+	assert.Equal(t, uint64(300), u.NetworkRx)
+	assert.Equal(t, uint64(30), u.NetworkTx)
+}
+
+func TestSampleResourceUsageReturnsErrorOnStatsError(t *testing.T) {
+	md := setupResourceUsageMocks(containerStatsCgroupV1)
+	removeOn(&md.Mock, "ContainerStats")
+	md.On("ContainerStats", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	_, err := SampleResourceUsage(md)
+	assert.Error(t, err)
+}
+
+func TestResourceUsageMergeKeepsLargestValues(t *testing.T) {
+	u := &ResourceUsage{Containers: 2, CPUSeconds: 10, PeakMemory: 100, NetworkRx: 5}
+	u.Merge(&ResourceUsage{Containers: 1, CPUSeconds: 20, PeakMemory: 50, Disk: 10})
+
+	assert.Equal(t, &ResourceUsage{Containers: 2, CPUSeconds: 20, PeakMemory: 100, Disk: 10, NetworkRx: 5}, u)
+}
+
+func TestSampleMemoryUsageTotalsAllContainers(t *testing.T) {
+	md := setupResourceUsageMocks(containerMemoryCgroupV1, containerMemoryCgroupV2)
+	md.On("Info", mock.Anything).Return(types.Info{MemTotal: 4000}, nil)
+
+	u, err := SampleMemoryUsage(md)
+	assert.NoError(t, err)
+
+	// the inactive page cache is not included
+	assert.Equal(t, &MemoryUsage{Used: 2000, Total: 4000}, u)
+	assert.Equal(t, 50.0, u.Percent())
+
+	opts := getCalls(&md.Mock, "ContainerList")[0].Arguments[1].(types.ContainerListOptions)
+	assert.Equal(t, 0, opts.Filters.Len())
+}
+
+func TestSampleMemoryUsageReturnsErrorOnInfoError(t *testing.T) {
+	md := setupResourceUsageMocks(containerMemoryCgroupV1)
+	md.On("Info", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	_, err := SampleMemoryUsage(md)
+	assert.Error(t, err)
+}
+
+var containerStatsCgroupV1 = `
+{
+  "cpu_stats": { "cpu_usage": { "total_usage": 1500000000 } },
+  "memory_stats": { "usage": 1000, "max_usage": 2000 },
+  "networks": {
+    "eth0": { "rx_bytes": 100, "tx_bytes": 10 },
+    "eth1": { "rx_bytes": 100, "tx_bytes": 10 }
+  }
+}
+`
+
+var containerStatsCgroupV2 = `
+{
+  "cpu_stats": { "cpu_usage": { "total_usage": 2000000000 } },
+  "memory_stats": { "usage": 1000 },
+  "networks": {
+    "eth0": { "rx_bytes": 100, "tx_bytes": 10 }
+  }
+}
+`
+
+var containerMemoryCgroupV1 = `
+{
+  "memory_stats": { "usage": 1500, "stats": { "total_inactive_file": 500 } }
+}
+`
+
+var containerMemoryCgroupV2 = `
+{
+  "memory_stats": { "usage": 1200, "stats": { "inactive_file": 200 } }
+}
+`
@@ -0,0 +1,136 @@
+package clients
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+const (
+	// RunnerModeHostEngine is used when the runner container uses the engine of the host
+	// through a mounted socket, often called Docker outside of Docker. Paths in the runner
+	// are translated to the paths on the host using the mounts of the runner container
+	RunnerModeHostEngine = "host_engine"
+	// RunnerModeDind is used when the engine is not running on the host of the runner
+	// container, e.g. a docker:dind service. Volume sources must exist on the engine host
+	RunnerModeDind = "dind"
+)
+
+// RunnerContainer describes the container Shipyard is running in, e.g. the container
+// for a job running on a CI runner with a Docker executor
+type RunnerContainer struct {
+	// ID of the runner container, blank when the engine does not know the container
+	ID string
+	// Mode is the relationship between the runner container and the engine
+	Mode string
+	// Gateway is the address of the engine host from inside the runner container,
+	// ports published by the engine are reachable at this address
+	Gateway string
+	// Mounts are the volumes mounted into the runner container
+	Mounts []types.MountPoint
+}
+
+// DetectRunner returns the container Shipyard is running in, when Shipyard
+// is not running in a container nil is returned
+func DetectRunner(c Docker) (*RunnerContainer, error) {
+	if !utils.InContainer() {
+		return nil, nil
+	}
+
+	id := utils.ContainerID()
+	if id == "" {
+		return &RunnerContainer{Mode: RunnerModeDind}, nil
+	}
+
+	info, err := c.ContainerInspect(context.Background(), id)
+	if err != nil {
+		// the engine does not know the container, the engine is not running on the
+		// same host as the runner
+		if client.IsErrNotFound(err) {
+			return &RunnerContainer{Mode: RunnerModeDind}, nil
+		}
+
+		return nil, err
+	}
+
+	rc := &RunnerContainer{ID: id, Mode: RunnerModeHostEngine, Mounts: info.Mounts}
+
+	// use the full id as the short id may become ambiguous
+	if info.ContainerJSONBase != nil && info.ID != "" {
+		rc.ID = info.ID
+	}
+
+	if info.NetworkSettings != nil {
+		rc.Gateway = runnerGateway(info.NetworkSettings.Networks)
+	}
+
+	return rc, nil
+}
+
+// runnerGateway returns the gateway for the default bridge network, or the first
+// network with a gateway. Runners using the host network do not have a gateway
+func runnerGateway(nets map[string]*network.EndpointSettings) string {
+	if n, ok := nets["bridge"]; ok && n != nil && n.Gateway != "" {
+		return n.Gateway
+	}
+
+	names := []string{}
+	for k := range nets {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	for _, k := range names {
+		if nets[k] != nil && nets[k].Gateway != "" {
+			return nets[k].Gateway
+		}
+	}
+
+	return ""
+}
+
+// HostPath returns the path on the engine host for the given path in the runner
+// container, false is returned when the path is not in a volume mounted into the runner
+func (r *RunnerContainer) HostPath(p string) (string, bool) {
+	if r == nil || r.Mode != RunnerModeHostEngine {
+		return p, false
+	}
+
+	// use the most specific mount as mounts can be nested
+	var match *types.MountPoint
+	for i, m := range r.Mounts {
+		if m.Source == "" || !isSubPath(m.Destination, p) {
+			continue
+		}
+
+		if match == nil || len(m.Destination) > len(match.Destination) {
+			match = &r.Mounts[i]
+		}
+	}
+
+	if match == nil {
+		return p, false
+	}
+
+	rel, err := filepath.Rel(match.Destination, p)
+	if err != nil {
+		return p, false
+	}
+
+	return filepath.Join(match.Source, rel), true
+}
+
+// isSubPath returns true when p is the directory dir or is in dir
+func isSubPath(dir, p string) bool {
+	dir = filepath.Clean(dir)
+	p = filepath.Clean(p)
+
+	return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -0,0 +1,148 @@
+package clients
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var runnerMounts = []types.MountPoint{
+	types.MountPoint{Type: mount.TypeBind, Source: "/var/run/docker.sock", Destination: "/var/run/docker.sock"},
+	types.MountPoint{Type: mount.TypeVolume, Source: "/var/lib/docker/volumes/builds/_data", Destination: "/builds"},
+	types.MountPoint{Type: mount.TypeBind, Source: "/srv/cache", Destination: "/builds/cache"},
+}
+
+func setupRunnerTests(t *testing.T, inContainer string, err error) *mocks.MockDocker {
+	ic := os.Getenv("SHIPYARD_IN_CONTAINER")
+	id := os.Getenv("SHIPYARD_CONTAINER_ID")
+	t.Cleanup(func() {
+		os.Setenv("SHIPYARD_IN_CONTAINER", ic)
+		os.Setenv("SHIPYARD_CONTAINER_ID", id)
+	})
+
+	os.Setenv("SHIPYARD_IN_CONTAINER", inContainer)
+	os.Setenv("SHIPYARD_CONTAINER_ID", "abc123")
+
+	md := &mocks.MockDocker{}
+	md.On("ContainerInspect", mock.Anything, mock.Anything).Return(
+		types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{ID: "abc123def456"},
+			Mounts:            runnerMounts,
+			NetworkSettings: &types.NetworkSettings{
+				Networks: map[string]*network.EndpointSettings{
+					"ci":     &network.EndpointSettings{Gateway: "10.10.0.1"},
+					"bridge": &network.EndpointSettings{Gateway: "172.17.0.1"},
+				},
+			},
+		},
+		err,
+	)
+
+	return md
+}
+
+func TestDetectRunnerReturnsNilWhenNotInContainer(t *testing.T) {
+	md := setupRunnerTests(t, "false", nil)
+
+	rc, err := DetectRunner(md)
+	assert.NoError(t, err)
+	assert.Nil(t, rc)
+
+	md.AssertNotCalled(t, "ContainerInspect", mock.Anything, mock.Anything)
+}
+
+func TestDetectRunnerReturnsHostEngineWhenEngineKnowsContainer(t *testing.T) {
+	md := setupRunnerTests(t, "true", nil)
+
+	rc, err := DetectRunner(md)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ContainerInspect", mock.Anything, "abc123")
+
+	assert.Equal(t, RunnerModeHostEngine, rc.Mode)
+	assert.Equal(t, "abc123def456", rc.ID)
+	assert.Equal(t, "172.17.0.1", rc.Gateway)
+	assert.Len(t, rc.Mounts, 3)
+}
+
+func TestDetectRunnerReturnsDindWhenEngineDoesNotKnowContainer(t *testing.T) {
+	md := setupRunnerTests(t, "true", errdefs.NotFound(fmt.Errorf("no such container")))
+
+	rc, err := DetectRunner(md)
+	assert.NoError(t, err)
+
+	assert.Equal(t, RunnerModeDind, rc.Mode)
+	assert.Empty(t, rc.ID)
+}
+
+func TestDetectRunnerReturnsErrorWhenInspectFails(t *testing.T) {
+	md := setupRunnerTests(t, "true", fmt.Errorf("boom"))
+
+	_, err := DetectRunner(md)
+	assert.Error(t, err)
+}
+
+func TestRunnerGatewayUsesFirstNetworkWithoutBridge(t *testing.T) {
+	gw := runnerGateway(map[string]*network.EndpointSettings{
+		"z":  &network.EndpointSettings{Gateway: "10.20.0.1"},
+		"ci": &network.EndpointSettings{Gateway: "10.10.0.1"},
+		"a":  &network.EndpointSettings{},
+	})
+
+	assert.Equal(t, "10.10.0.1", gw)
+}
+
+func TestRunnerHostPathTranslatesPaths(t *testing.T) {
+	rc := &RunnerContainer{Mode: RunnerModeHostEngine, Mounts: runnerMounts}
+
+	tt := map[string]string{
+		"/builds/project/config":  "/var/lib/docker/volumes/builds/_data/project/config",
+		"/builds":                 "/var/lib/docker/volumes/builds/_data",
+		"/builds/cache/images":    "/srv/cache/images",
+		"/var/run/docker.sock":    "/var/run/docker.sock",
+		"/builds-other/something": "/builds-other/something",
+	}
+
+	for in, out := range tt {
+		p, _ := rc.HostPath(in)
+		assert.Equal(t, out, p, in)
+	}
+
+	_, ok := rc.HostPath("/root/.shipyard/config")
+	assert.False(t, ok)
+}
+
+func TestRunnerHostPathDoesNotTranslateForDind(t *testing.T) {
+	rc := &RunnerContainer{Mode: RunnerModeDind, Mounts: runnerMounts}
+
+	p, ok := rc.HostPath("/builds/project")
+	assert.False(t, ok)
+	assert.Equal(t, "/builds/project", p)
+}
+
+func TestContainerTranslatesVolumeSourceForRunner(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Volumes[0].Source = t.TempDir()
+
+	p := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+	p.SetRunner(&RunnerContainer{
+		Mode:   RunnerModeHostEngine,
+		Mounts: []types.MountPoint{types.MountPoint{Source: "/host/tmp", Destination: cc.Volumes[0].Source}},
+	})
+
+	_, err := p.CreateContainer(cc)
+	assert.NoError(t, err)
+
+	hc := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[2].(*container.HostConfig)
+	assert.Equal(t, "/host/tmp", hc.Mounts[0].Source)
+}
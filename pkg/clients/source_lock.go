@@ -0,0 +1,273 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-getter"
+)
+
+// LockedSource is the version of a blueprint, Helm chart, or file fetched by
+// the Getter and stored in the cache
+type LockedSource struct {
+	Source    string    `json:"source"`           // source as written in the blueprint e.g. github.com/org/repo//consul?ref=main
+	Path      string    `json:"path"`             // location of the cached copy
+	Ref       string    `json:"ref,omitempty"`    // ref which was requested, blank for the default branch
+	Commit    string    `json:"commit,omitempty"` // commit the ref resolved to when the source was fetched, only set for git sources
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+type sourceLockFile struct {
+	Sources map[string]LockedSource `json:"sources"`
+}
+
+// SourceLock records the commit of every source fetched by the Getter so that
+// the cached copy can be fetched again at the same version when it is removed,
+// sources are only updated when they are deliberately fetched again.
+//
+// All the methods can be called on a nil SourceLock.
+type SourceLock struct {
+	file string
+	lock sync.Mutex
+}
+
+// NewSourceLock creates a SourceLock which is stored in the given file
+func NewSourceLock(file string) *SourceLock {
+	return &SourceLock{file: file}
+}
+
+// Sources returns the locked sources ordered by source
+func (l *SourceLock) Sources() ([]LockedSource, error) {
+	if l == nil {
+		return nil, nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	f, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	s := []LockedSource{}
+	for _, ls := range f.Sources {
+		s = append(s, ls)
+	}
+
+	sort.Slice(s, func(i, j int) bool { return s[i].Source < s[j].Source })
+
+	return s, nil
+}
+
+// Find returns the locked version of the source, nil is returned when the
+// source has not been fetched
+func (l *SourceLock) Find(source string) (*LockedSource, error) {
+	if l == nil {
+		return nil, nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	f, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	ls, ok := f.Sources[source]
+	if !ok {
+		return nil, nil
+	}
+
+	return &ls, nil
+}
+
+// Add adds or replaces the locked version of a source
+func (l *SourceLock) Add(s LockedSource) error {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	f, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	f.Sources[s.Source] = s
+
+	return l.save(f)
+}
+
+// Remove removes the locked version of a source
+func (l *SourceLock) Remove(source string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	f, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	delete(f.Sources, source)
+
+	return l.save(f)
+}
+
+func (l *SourceLock) load() (*sourceLockFile, error) {
+	f := &sourceLockFile{Sources: map[string]LockedSource{}}
+
+	d, err := ioutil.ReadFile(l.file)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read lock file %s: %s", l.file, err)
+	}
+
+	err = json.Unmarshal(d, f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse lock file %s: %s", l.file, err)
+	}
+
+	if f.Sources == nil {
+		f.Sources = map[string]LockedSource{}
+	}
+
+	return f, nil
+}
+
+func (l *SourceLock) save(f *sourceLockFile) error {
+	d, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(l.file), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("unable to create directory for lock file %s: %s", l.file, err)
+	}
+
+	err = ioutil.WriteFile(l.file, d, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write lock file %s: %s", l.file, err)
+	}
+
+	return nil
+}
+
+// forcedGetter matches the getter prefix of a source e.g. git::https://github.com/org/repo
+var forcedGetter = regexp.MustCompile(`^([A-Za-z0-9]+)::(.+)$`)
+
+// gitCommitID matches a full git commit ID
+var gitCommitID = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// resolveGitCommit returns the ref and the commit the ref currently resolves to
+// for git sources, a blank commit is returned for sources which are not git
+// repositories
+func resolveGitCommit(src, pwd string) (string, string, error) {
+	s, err := getter.Detect(src, pwd, getter.Detectors)
+	if err != nil {
+		return "", "", err
+	}
+
+	m := forcedGetter.FindStringSubmatch(s)
+	if m == nil || m[1] != "git" {
+		return "", "", nil
+	}
+
+	s, _ = getter.SourceDirSubdir(m[2])
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", "", err
+	}
+
+	ref := u.Query().Get("ref")
+	if gitCommitID.MatchString(ref) {
+		return ref, ref, nil
+	}
+
+	u.RawQuery = ""
+
+	disableGitPrompts()
+	au, token := withGitToken(u)
+
+	pattern := "HEAD"
+	if ref != "" {
+		pattern = ref
+	}
+
+	out, err := exec.Command("git", "ls-remote", au.String(), pattern).Output()
+	if err != nil {
+		return "", "", gitError(u, token, fmt.Errorf("unable to resolve ref '%s': %s", pattern, err))
+	}
+
+	commit := ""
+	for _, l := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.Fields(l)
+		if len(parts) != 2 {
+			continue
+		}
+
+		// annotated tags are listed twice, the peeled ref is the commit
+		if commit == "" || strings.HasSuffix(parts[1], "^{}") {
+			commit = parts[0]
+		}
+	}
+
+	if commit == "" {
+		return "", "", fmt.Errorf("ref '%s' does not exist", pattern)
+	}
+
+	return ref, commit, nil
+}
+
+// localGitCommit returns the commit checked out in the folder, a blank commit
+// is returned when the folder is not a git repository
+func localGitCommit(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return ""
+	}
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// withRef returns the source with the ref query parameter set to the given ref
+func withRef(src, ref string) string {
+	base, query := src, ""
+	if i := strings.Index(src, "?"); i >= 0 {
+		base, query = src[:i], src[i+1:]
+	}
+
+	q, _ := url.ParseQuery(query)
+	q.Set("ref", ref)
+
+	return base + "?" + q.Encode()
+}
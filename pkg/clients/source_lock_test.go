@@ -0,0 +1,102 @@
+package clients
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSourceLock(t *testing.T) *SourceLock {
+	return NewSourceLock(filepath.Join(t.TempDir(), "sources.lock.json"))
+}
+
+func TestSourceLockAddsAndFindsSource(t *testing.T) {
+	l := setupSourceLock(t)
+
+	err := l.Add(LockedSource{Source: "github.com/org/repo//consul", Path: "/tmp/consul", Commit: "abc", FetchedAt: time.Now()})
+	require.NoError(t, err)
+
+	s, err := l.Find("github.com/org/repo//consul")
+	require.NoError(t, err)
+	require.NotNil(t, s)
+
+	assert.Equal(t, "abc", s.Commit)
+	assert.Equal(t, "/tmp/consul", s.Path)
+}
+
+func TestSourceLockFindReturnsNilWhenNotLocked(t *testing.T) {
+	l := setupSourceLock(t)
+
+	s, err := l.Find("github.com/org/repo//consul")
+	require.NoError(t, err)
+
+	assert.Nil(t, s)
+}
+
+func TestSourceLockListsSourcesInOrder(t *testing.T) {
+	l := setupSourceLock(t)
+	l.Add(LockedSource{Source: "github.com/org/repo//vault"})
+	l.Add(LockedSource{Source: "github.com/org/repo//consul"})
+
+	s, err := l.Sources()
+	require.NoError(t, err)
+	require.Len(t, s, 2)
+
+	assert.Equal(t, "github.com/org/repo//consul", s[0].Source)
+	assert.Equal(t, "github.com/org/repo//vault", s[1].Source)
+}
+
+func TestSourceLockRemovesSource(t *testing.T) {
+	l := setupSourceLock(t)
+	l.Add(LockedSource{Source: "github.com/org/repo//consul"})
+
+	err := l.Remove("github.com/org/repo//consul")
+	require.NoError(t, err)
+
+	s, _ := l.Sources()
+	assert.Len(t, s, 0)
+}
+
+func TestSourceLockReturnsErrorWhenFileInvalid(t *testing.T) {
+	l := setupSourceLock(t)
+	os.WriteFile(l.file, []byte("nope"), 0644)
+
+	_, err := l.Sources()
+	assert.Error(t, err)
+}
+
+func TestNilSourceLockDoesNothing(t *testing.T) {
+	var l *SourceLock
+
+	assert.NoError(t, l.Add(LockedSource{Source: "abc"}))
+
+	s, err := l.Find("abc")
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+}
+
+func TestResolveGitCommitReturnsCommitWhenRefIsCommit(t *testing.T) {
+	commit := "0123456789abcdef0123456789abcdef01234567"
+
+	ref, c, err := resolveGitCommit("github.com/org/repo//consul?ref="+commit, "/tmp")
+	require.NoError(t, err)
+
+	assert.Equal(t, commit, ref)
+	assert.Equal(t, commit, c)
+}
+
+func TestResolveGitCommitReturnsBlankForNonGitSources(t *testing.T) {
+	_, c, err := resolveGitCommit("https://example.com/files.zip", "/tmp")
+	require.NoError(t, err)
+
+	assert.Equal(t, "", c)
+}
+
+func TestWithRefSetsRef(t *testing.T) {
+	assert.Equal(t, "github.com/org/repo//consul?ref=abc", withRef("github.com/org/repo//consul", "abc"))
+	assert.Equal(t, "github.com/org/repo//consul?ref=abc", withRef("github.com/org/repo//consul?ref=main", "abc"))
+}
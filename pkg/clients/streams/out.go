@@ -41,8 +41,20 @@ func (o *Out) GetTtySize() (uint, uint) {
 	return uint(ws.Height), uint(ws.Width)
 }
 
+// fdWriter is a Writer which is backed by a file descriptor, writers which
+// wrap a terminal can implement Fd so that the terminal is still detected
+type fdWriter interface {
+	io.Writer
+	Fd() uintptr
+}
+
 // NewOut returns a new Out object from a Writer
 func NewOut(out io.Writer) *Out {
 	fd, isTerminal := term.GetFdInfo(out)
+	if fw, ok := out.(fdWriter); ok && !isTerminal {
+		fd = fw.Fd()
+		isTerminal = term.IsTerminal(fd)
+	}
+
 	return &Out{commonStream: commonStream{fd: fd, isTerminal: isTerminal}, out: out}
 }
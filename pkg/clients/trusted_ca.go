@@ -0,0 +1,229 @@
+package clients
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// trustBundles are the locations of the CA bundles for the common base images and
+// hosts, Debian, Ubuntu and Alpine, RHEL and CentOS, and OpenSSL and macOS
+var trustBundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/cert.pem",
+}
+
+var trustedCAs []byte
+var trustedCAsLock sync.Mutex
+
+// ReadTrustedCAs reads the PEM encoded certificates from the given files,
+// an error is returned when a file does not contain a certificate
+func ReadTrustedCAs(files []string) ([]byte, error) {
+	cas := bytes.NewBuffer(nil)
+
+	for _, f := range files {
+		d, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %s: %s", f, err)
+		}
+
+		count := 0
+		for rest := d; ; {
+			var b *pem.Block
+			b, rest = pem.Decode(rest)
+			if b == nil {
+				break
+			}
+
+			if b.Type != "CERTIFICATE" {
+				continue
+			}
+
+			if _, err := x509.ParseCertificate(b.Bytes); err != nil {
+				return nil, fmt.Errorf("CA file %s contains an invalid certificate: %s", f, err)
+			}
+
+			pem.Encode(cas, b)
+			count++
+		}
+
+		if count == 0 {
+			return nil, fmt.Errorf("CA file %s does not contain any PEM encoded certificates", f)
+		}
+	}
+
+	return cas.Bytes(), nil
+}
+
+// SetTrustedCAs sets the certificate authorities which are trusted by the HTTP clients
+// in addition to the system roots, e.g. the root CA for a TLS intercepting proxy.
+// The CAs of the proxy set with SetProxy are always trusted, calling SetTrustedCAs
+// with no files removes the other trusted CAs
+func SetTrustedCAs(files []string) error {
+	cas, err := ReadTrustedCAs(append(ProxyCAFiles(), files...))
+	if err != nil {
+		return err
+	}
+
+	trustedCAsLock.Lock()
+	defer trustedCAsLock.Unlock()
+
+	trustedCAs = cas
+
+	return nil
+}
+
+func getTrustedCAs() []byte {
+	trustedCAsLock.Lock()
+	defer trustedCAsLock.Unlock()
+
+	return trustedCAs
+}
+
+// trustedTransport returns a new transport which trusts the system roots and the
+// trusted CAs, nil is returned when no CAs have been set
+func trustedTransport() *http.Transport {
+	cas := getTrustedCAs()
+	if len(cas) == 0 {
+		return nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	pool.AppendCertsFromPEM(cas)
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{RootCAs: pool}
+
+	return t
+}
+
+// trustedCABundle writes the system roots of the host and the trusted CAs to a bundle
+// for clients which only accept a CA file, an empty path is returned when no CAs have
+// been set or the host does not have a CA bundle
+func trustedCABundle() (string, error) {
+	cas := getTrustedCAs()
+	if len(cas) == 0 {
+		return "", nil
+	}
+
+	for _, b := range trustBundles {
+		d, err := ioutil.ReadFile(b)
+		if err != nil {
+			continue
+		}
+
+		tmp, err := utils.ShipyardTemp()
+		if err != nil {
+			return "", err
+		}
+
+		p := filepath.Join(tmp, "trusted_ca_bundle.pem")
+
+		err = ioutil.WriteFile(p, appendTrustedCAs(d, cas), 0644)
+		if err != nil {
+			return "", fmt.Errorf("unable to write CA bundle: %s", err)
+		}
+
+		return p, nil
+	}
+
+	return "", nil
+}
+
+// appendTrustedCAs adds the CAs to the end of the bundle
+func appendTrustedCAs(bundle, cas []byte) []byte {
+	if bytes.Contains(bundle, cas) {
+		return bundle
+	}
+
+	out := append([]byte{}, bundle...)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+
+	return append(out, cas...)
+}
+
+// addTrustedCAs appends the CAs in the given files to the trust stores in a container which
+// has been created but not started. Only the bundles which exist in the image are updated,
+// the image is not required to contain a bundle
+func (d *DockerTasks) addTrustedCAs(id string, files []string) error {
+	cas, err := ReadTrustedCAs(files)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range trustBundles {
+		bundle, mode, ok := d.readContainerFile(id, b)
+		if !ok {
+			continue
+		}
+
+		out := appendTrustedCAs(bundle, cas)
+
+		buf := bytes.NewBuffer(nil)
+		ta := tar.NewWriter(buf)
+
+		err := ta.WriteHeader(&tar.Header{Name: filepath.Base(b), Mode: mode, Size: int64(len(out))})
+		if err != nil {
+			return fmt.Errorf("unable to write tar header: %s", err)
+		}
+
+		ta.Write(out)
+		ta.Close()
+
+		err = d.c.CopyToContainer(context.Background(), id, filepath.Dir(b), buf, types.CopyToContainerOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to write CA bundle %s: %s", b, err)
+		}
+
+		d.l.Debug("Added trusted CAs to container", "id", id, "bundle", b)
+	}
+
+	return nil
+}
+
+// readContainerFile returns the contents and mode of a regular file in the container,
+// false is returned when the file does not exist or is a link
+func (d *DockerTasks) readContainerFile(id, path string) ([]byte, int64, bool) {
+	rc, stat, err := d.c.CopyFromContainer(context.Background(), id, path)
+	if err != nil || rc == nil {
+		return nil, 0, false
+	}
+	defer rc.Close()
+
+	if stat.Mode&os.ModeSymlink != 0 {
+		return nil, 0, false
+	}
+
+	tr := tar.NewReader(rc)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Typeflag != tar.TypeReg {
+		return nil, 0, false
+	}
+
+	data, err := ioutil.ReadAll(tr)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	return data, hdr.Mode, true
+}
@@ -0,0 +1,147 @@
+package clients
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+// createTestCA writes a self signed CA certificate to a temporary file
+func createTestCA(t *testing.T) string {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Corporate Root CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	d, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &k.PublicKey, k)
+	assert.NoError(t, err)
+
+	f := filepath.Join(t.TempDir(), "ca.pem")
+	err = ioutil.WriteFile(f, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: d}), 0644)
+	assert.NoError(t, err)
+
+	return f
+}
+
+func testTarFile(t *testing.T, name, contents string) io.ReadCloser {
+	buf := bytes.NewBuffer(nil)
+	ta := tar.NewWriter(buf)
+
+	err := ta.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg})
+	assert.NoError(t, err)
+
+	ta.Write([]byte(contents))
+	ta.Close()
+
+	return ioutil.NopCloser(buf)
+}
+
+func readTarFile(t *testing.T, r io.Reader) (string, string) {
+	tr := tar.NewReader(r)
+
+	hdr, err := tr.Next()
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadAll(tr)
+	assert.NoError(t, err)
+
+	return hdr.Name, string(d)
+}
+
+func TestReadTrustedCAsReturnsCertificates(t *testing.T) {
+	f := createTestCA(t)
+
+	cas, err := ReadTrustedCAs([]string{f})
+	assert.NoError(t, err)
+	assert.Contains(t, string(cas), "BEGIN CERTIFICATE")
+}
+
+func TestReadTrustedCAsWithMissingFileReturnsError(t *testing.T) {
+	_, err := ReadTrustedCAs([]string{"/missing/ca.pem"})
+	assert.Error(t, err)
+}
+
+func TestReadTrustedCAsWithNoCertificatesReturnsError(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "ca.pem")
+	ioutil.WriteFile(f, []byte("not a certificate"), 0644)
+
+	_, err := ReadTrustedCAs([]string{f})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not contain")
+}
+
+func TestTrustedTransportIsNilWithoutCAs(t *testing.T) {
+	err := SetTrustedCAs(nil)
+	assert.NoError(t, err)
+
+	assert.Nil(t, trustedTransport())
+}
+
+func TestTrustedTransportTrustsCAs(t *testing.T) {
+	err := SetTrustedCAs([]string{createTestCA(t)})
+	assert.NoError(t, err)
+	t.Cleanup(func() { SetTrustedCAs(nil) })
+
+	tr := trustedTransport()
+	assert.NotNil(t, tr)
+	assert.NotNil(t, tr.TLSClientConfig.RootCAs)
+}
+
+func TestAppendTrustedCAsDoesNotDuplicate(t *testing.T) {
+	out := appendTrustedCAs([]byte("bundle"), []byte("ca\n"))
+	assert.Equal(t, "bundle\nca\n", string(out))
+
+	out = appendTrustedCAs(out, []byte("ca\n"))
+	assert.Equal(t, "bundle\nca\n", string(out))
+}
+
+func TestAddTrustedCAsAppendsToExistingBundles(t *testing.T) {
+	f := createTestCA(t)
+
+	md, mic := setupContainerMocks()
+	md.On("CopyFromContainer", mock.Anything, "test", "/etc/ssl/certs/ca-certificates.crt").Return(
+		testTarFile(t, "ca-certificates.crt", "existing\n"),
+		types.ContainerPathStat{Mode: 0644},
+		nil,
+	)
+	md.On("CopyFromContainer", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil, fmt.Errorf("not found"))
+	md.On("CopyToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	dt := NewDockerTasks(md, mic, &TarGz{}, hclog.NewNullLogger())
+
+	err := dt.addTrustedCAs("test", []string{f})
+	assert.NoError(t, err)
+
+	md.AssertNumberOfCalls(t, "CopyToContainer", 1)
+
+	call := getCalls(&md.Mock, "CopyToContainer")[0]
+	assert.Equal(t, "/etc/ssl/certs", call.Arguments[2])
+
+	name, contents := readTarFile(t, call.Arguments[3].(io.Reader))
+	assert.Equal(t, "ca-certificates.crt", name)
+	assert.Contains(t, contents, "existing\n-----BEGIN CERTIFICATE-----")
+}
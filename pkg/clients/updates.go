@@ -0,0 +1,290 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/docker/distribution/reference"
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/xerrors"
+	"sigs.k8s.io/yaml"
+)
+
+// Updates defines an interface for a client which looks up the versions
+// which are available upstream for blueprints, modules, Helm charts, and images
+type Updates interface {
+	// ImageTags returns the tags for the given image using the registry API
+	// image is a Docker image reference e.g. consul:1.10.1 or ghcr.io/org/app:v1
+	ImageTags(image string) ([]string, error)
+
+	// ChartVersions returns the versions for a chart in the given Helm repository
+	ChartVersions(repository, chart string) ([]string, error)
+
+	// GitTags returns the tags for the given git repository
+	GitTags(repository string) ([]string, error)
+}
+
+// UpdatesImpl is a concrete implementation of the Updates interface
+type UpdatesImpl struct {
+	client *http.Client
+	log    hclog.Logger
+}
+
+// NewUpdates creates a new Updates client
+func NewUpdates(timeout time.Duration, l hclog.Logger) Updates {
+	return &UpdatesImpl{&http.Client{Timeout: timeout}, l}
+}
+
+var bearerParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// ImageTags returns the tags for the given image using the registry API
+func (u *UpdatesImpl) ImageTags(image string) ([]string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse image %s: %w", image, err)
+	}
+
+	domain := reference.Domain(named)
+	if domain == "docker.io" {
+		domain = "registry-1.docker.io"
+	}
+
+	next := fmt.Sprintf("https://%s/v2/%s/tags/list", domain, reference.Path(named))
+	token := ""
+	tags := []string{}
+
+	// the registry API is paginated, follow the next links until all tags have been read
+	for next != "" {
+		resp, err := u.registryGet(next, &token)
+		if err != nil {
+			return nil, xerrors.Errorf("unable to list tags for image %s: %w", image, err)
+		}
+
+		tl := struct {
+			Tags []string `json:"tags"`
+		}{}
+
+		err = json.NewDecoder(resp.Body).Decode(&tl)
+		resp.Body.Close()
+		if err != nil {
+			return nil, xerrors.Errorf("unable to decode tags for image %s: %w", image, err)
+		}
+
+		tags = append(tags, tl.Tags...)
+		next = ""
+
+		if m := nextLink.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			n, err := resp.Request.URL.Parse(m[1])
+			if err == nil {
+				next = n.String()
+			}
+		}
+	}
+
+	return tags, nil
+}
+
+// registryGet makes a request to the registry, when the registry requires a bearer token
+// one is requested using the challenge returned by the registry and stored in token
+func (u *UpdatesImpl) registryGet(uri string, token *string) (*http.Response, error) {
+	resp, err := u.get(uri, *token)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+
+		*token, err = u.registryToken(resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err = u.get(uri, *token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry returned status %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// registryToken requests an anonymous pull token using the Bearer challenge from the registry
+func (u *UpdatesImpl) registryToken(challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("registry requires authentication which is not supported: %s", challenge)
+	}
+
+	params := map[string]string{}
+	for _, m := range bearerParam.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("registry returned an invalid authentication challenge: %s", challenge)
+	}
+
+	q := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			q.Set(k, params[k])
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	resp, err := u.get(realm.String(), "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to get token for registry, status %d", resp.StatusCode)
+	}
+
+	t := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&t)
+	if err != nil {
+		return "", xerrors.Errorf("unable to decode registry token: %w", err)
+	}
+
+	if t.Token != "" {
+		return t.Token, nil
+	}
+
+	return t.AccessToken, nil
+}
+
+func (u *UpdatesImpl) get(uri, token string) (*http.Response, error) {
+	u.log.Debug("Checking for updates", "url", uri)
+
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return u.client.Do(req)
+}
+
+// ChartVersions returns the versions for a chart in the given Helm repository
+func (u *UpdatesImpl) ChartVersions(repository, chart string) ([]string, error) {
+	resp, err := u.get(strings.TrimSuffix(repository, "/")+"/index.yaml", "")
+	if err != nil {
+		return nil, xerrors.Errorf("unable to download index for Helm repository %s: %w", repository, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to download index for Helm repository %s, status %d", repository, resp.StatusCode)
+	}
+
+	d, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to download index for Helm repository %s: %w", repository, err)
+	}
+
+	index := struct {
+		Entries map[string][]struct {
+			Version string `json:"version"`
+		} `json:"entries"`
+	}{}
+
+	err = yaml.Unmarshal(d, &index)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to parse index for Helm repository %s: %w", repository, err)
+	}
+
+	entries, ok := index.Entries[chart]
+	if !ok {
+		return nil, fmt.Errorf("chart %s not found in Helm repository %s", chart, repository)
+	}
+
+	versions := []string{}
+	for _, e := range entries {
+		versions = append(versions, e.Version)
+	}
+
+	return versions, nil
+}
+
+// GitTags returns the tags for the given git repository
+func (u *UpdatesImpl) GitTags(repository string) ([]string, error) {
+	u.log.Debug("Checking for updates", "repository", repository)
+
+	out, err := exec.Command("git", "ls-remote", "--tags", "--refs", repository).Output()
+	if err != nil {
+		return nil, xerrors.Errorf("unable to list tags for repository %s: %w", repository, err)
+	}
+
+	tags := []string{}
+	for _, l := range strings.Split(string(out), "\n") {
+		parts := strings.Fields(l)
+		if len(parts) == 2 && strings.HasPrefix(parts[1], "refs/tags/") {
+			tags = append(tags, strings.TrimPrefix(parts[1], "refs/tags/"))
+		}
+	}
+
+	return tags, nil
+}
+
+// LatestVersion returns the newest version in versions which is greater than current,
+// versions which are not semantic versions are ignored, pre-releases are only considered
+// when the current version is a pre-release.
+// If there is no newer version or current is not a semantic version an empty string is returned.
+func LatestVersion(current string, versions []string) string {
+	cv, err := semver.NewVersion(current)
+	if err != nil {
+		return ""
+	}
+
+	newer := []*semver.Version{}
+	original := map[*semver.Version]string{}
+
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v)
+		if err != nil {
+			continue
+		}
+
+		if sv.Prerelease() != "" && cv.Prerelease() == "" {
+			continue
+		}
+
+		if sv.GreaterThan(cv) {
+			newer = append(newer, sv)
+			original[sv] = v
+		}
+	}
+
+	if len(newer) == 0 {
+		return ""
+	}
+
+	sort.Sort(semver.Collection(newer))
+
+	return original[newer[len(newer)-1]]
+}
@@ -0,0 +1,121 @@
+package clients
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupRegistry(t *testing.T) (*httptest.Server, *UpdatesImpl) {
+	var ts *httptest.Server
+	ts = httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			assert.Equal(t, "registry.test", r.URL.Query().Get("service"))
+			assert.Equal(t, "repository:library/consul:pull", r.URL.Query().Get("scope"))
+			fmt.Fprint(rw, `{"token": "abc"}`)
+
+		case "/v2/library/consul/tags/list":
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.test",scope="repository:library/consul:pull"`, ts.URL))
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if r.URL.Query().Get("last") == "" {
+				rw.Header().Set("Link", `</v2/library/consul/tags/list?last=1.10.1>; rel="next"`)
+				fmt.Fprint(rw, `{"name": "library/consul", "tags": ["1.9.0", "1.10.1"]}`)
+				return
+			}
+
+			fmt.Fprint(rw, `{"name": "library/consul", "tags": ["1.11.0", "latest"]}`)
+
+		case "/charts/index.yaml":
+			fmt.Fprint(rw, "apiVersion: v1\nentries:\n  consul:\n  - version: 0.41.0\n  - version: 0.40.0\n")
+
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return ts, &UpdatesImpl{ts.Client(), hclog.NewNullLogger()}
+}
+
+func TestImageTagsRequestsTokenAndFollowsPages(t *testing.T) {
+	ts, u := setupRegistry(t)
+
+	tags, err := u.ImageTags(fmt.Sprintf("%s/library/consul:1.10.1", strings.TrimPrefix(ts.URL, "https://")))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"1.9.0", "1.10.1", "1.11.0", "latest"}, tags)
+}
+
+func TestImageTagsReturnsErrorWhenNotFound(t *testing.T) {
+	ts, u := setupRegistry(t)
+
+	_, err := u.ImageTags(fmt.Sprintf("%s/library/vault:1.10.1", strings.TrimPrefix(ts.URL, "https://")))
+	assert.Error(t, err)
+}
+
+func TestChartVersionsReadsIndex(t *testing.T) {
+	ts, u := setupRegistry(t)
+
+	v, err := u.ChartVersions(ts.URL+"/charts/", "consul")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"0.41.0", "0.40.0"}, v)
+}
+
+func TestChartVersionsReturnsErrorWhenChartNotFound(t *testing.T) {
+	ts, u := setupRegistry(t)
+
+	_, err := u.ChartVersions(ts.URL+"/charts", "vault")
+	assert.Error(t, err)
+}
+
+func TestGitTagsListsRepositoryTags(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, c := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@test", "commit", "-q", "--allow-empty", "-m", "init"},
+		{"tag", "v0.1.0"},
+		{"tag", "v0.2.0"},
+	} {
+		out, err := exec.Command("git", append([]string{"-C", dir}, c...)...).CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+
+	u := NewUpdates(0, hclog.NewNullLogger())
+
+	tags, err := u.GitTags(dir)
+	assert.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"v0.1.0", "v0.2.0"}, tags)
+}
+
+func TestLatestVersionReturnsNewestVersion(t *testing.T) {
+	tt := []struct {
+		current  string
+		versions []string
+		latest   string
+	}{
+		{"1.10.1", []string{"1.9.0", "1.10.1", "1.11.0", "latest"}, "1.11.0"},
+		{"v0.1.0", []string{"v0.1.0", "v0.2.0", "v0.3.0-beta.1"}, "v0.2.0"},
+		{"v0.3.0-beta.1", []string{"v0.3.0-beta.2"}, "v0.3.0-beta.2"},
+		{"1.11.0", []string{"1.9.0", "1.11.0"}, ""},
+		{"latest", []string{"1.9.0", "1.11.0"}, ""},
+	}
+
+	for _, tc := range tt {
+		assert.Equal(t, tc.latest, LatestVersion(tc.current, tc.versions), tc.current)
+	}
+}
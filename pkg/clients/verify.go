@@ -0,0 +1,154 @@
+package clients
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/crypto/openpgp"
+)
+
+// cosignCommand creates the command used to verify cosign signatures
+var cosignCommand = exec.Command
+
+// ChecksumError is returned when a downloaded file does not match the
+// checksum declared in the blueprint
+type ChecksumError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e ChecksumError) Error() string {
+	return fmt.Sprintf("checksum of %s does not match, expected %s, got %s", e.Path, e.Expected, e.Actual)
+}
+
+// VerifyFile checks the file at path matches the checksum and signature, the
+// signature and key must be local files
+func VerifyFile(path string, v *config.Verify) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() {
+		return fmt.Errorf("%s is a folder, only files can be verified, add ?archive=false to the source to verify an archive", path)
+	}
+
+	if v.Checksum != "" {
+		err := verifyChecksum(path, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	if v.Signature == "" {
+		return nil
+	}
+
+	if v.SignatureType == config.SignatureTypeCosign {
+		return verifyCosign(path, v.Signature, v.Key)
+	}
+
+	return verifyGPG(path, v.Signature, v.Key)
+}
+
+func verifyChecksum(path string, v *config.Verify) error {
+	alg, sum := v.ChecksumAlgorithm()
+
+	var h hash.Hash
+	switch alg {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported checksum algorithm %s", alg)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+
+	// vendors publish checksums in upper and lower case
+	actual := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(actual, sum) {
+		return ChecksumError{Path: path, Expected: alg + ":" + sum, Actual: alg + ":" + actual}
+	}
+
+	return nil
+}
+
+// verifyGPG checks the detached signature, armored and binary signatures and keys are supported
+func verifyGPG(path, sig, key string) error {
+	kd, err := ioutil.ReadFile(key)
+	if err != nil {
+		return fmt.Errorf("unable to read public key: %s", err)
+	}
+
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(kd))
+	if err != nil {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(kd))
+		if err != nil {
+			return fmt.Errorf("unable to read public key %s: %s", key, err)
+		}
+	}
+
+	sd, err := ioutil.ReadFile(sig)
+	if err != nil {
+		return fmt.Errorf("unable to read signature: %s", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if bytes.Contains(sd, []byte("-----BEGIN PGP SIGNATURE-----")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, f, bytes.NewReader(sd))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, f, bytes.NewReader(sd))
+	}
+
+	if err != nil {
+		return fmt.Errorf("signature of %s is not valid: %s", path, err)
+	}
+
+	return nil
+}
+
+// verifyCosign checks the signature with the cosign CLI
+func verifyCosign(path, sig, key string) error {
+	stderr := bytes.NewBuffer(nil)
+
+	cmd := cosignCommand("cosign", "verify-blob", "--key", key, "--signature", sig, path)
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if _, ok := err.(*exec.Error); ok {
+			return fmt.Errorf("unable to run cosign, install cosign from https://docs.sigstore.dev to verify cosign signatures: %s", err)
+		}
+
+		return fmt.Errorf("signature of %s is not valid: %s", path, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package clients
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func setupVerifyTests(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tool.tar.gz")
+
+	err := ioutil.WriteFile(file, []byte("shipyard"), 0644)
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte("shipyard"))
+
+	return file, hex.EncodeToString(sum[:])
+}
+
+// signFile creates a GPG key and a detached signature for the file
+// returns the paths of the public key and the signature
+func signFile(t *testing.T, file string, armored bool) (string, string) {
+	dir := t.TempDir()
+
+	e, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	require.NoError(t, err)
+
+	kb := bytes.NewBuffer(nil)
+	w, err := armor.Encode(kb, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, e.Serialize(w))
+	w.Close()
+
+	key := filepath.Join(dir, "key.asc")
+	require.NoError(t, ioutil.WriteFile(key, kb.Bytes(), 0644))
+
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+
+	sb := bytes.NewBuffer(nil)
+	if armored {
+		err = openpgp.ArmoredDetachSign(sb, e, f, nil)
+	} else {
+		err = openpgp.DetachSign(sb, e, f, nil)
+	}
+	require.NoError(t, err)
+
+	sig := filepath.Join(dir, "tool.sig")
+	require.NoError(t, ioutil.WriteFile(sig, sb.Bytes(), 0644))
+
+	return key, sig
+}
+
+func TestVerifyFileWithMatchingChecksum(t *testing.T) {
+	file, sum := setupVerifyTests(t)
+
+	err := VerifyFile(file, &config.Verify{Checksum: "sha256:" + sum})
+	assert.NoError(t, err)
+
+	err = VerifyFile(file, &config.Verify{Checksum: sum})
+	assert.NoError(t, err)
+}
+
+func TestVerifyFileWithUpperCaseChecksum(t *testing.T) {
+	file, sum := setupVerifyTests(t)
+
+	err := VerifyFile(file, &config.Verify{Checksum: "SHA256:" + strings.ToUpper(sum)})
+	assert.NoError(t, err)
+}
+
+func TestVerifyFileWithIncorrectChecksumReturnsError(t *testing.T) {
+	file, _ := setupVerifyTests(t)
+
+	sum := sha256.Sum256([]byte("tampered"))
+
+	err := VerifyFile(file, &config.Verify{Checksum: "sha256:" + hex.EncodeToString(sum[:])})
+	assert.Error(t, err)
+	assert.IsType(t, ChecksumError{}, err)
+}
+
+func TestVerifyFileWithFolderReturnsError(t *testing.T) {
+	err := VerifyFile(t.TempDir(), &config.Verify{Checksum: "abc"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "archive=false")
+}
+
+func TestVerifyFileWithValidGPGSignature(t *testing.T) {
+	file, _ := setupVerifyTests(t)
+
+	key, sig := signFile(t, file, true)
+	err := VerifyFile(file, &config.Verify{Signature: sig, Key: key})
+	assert.NoError(t, err)
+
+	key, sig = signFile(t, file, false)
+	err = VerifyFile(file, &config.Verify{Signature: sig, Key: key, SignatureType: config.SignatureTypeGPG})
+	assert.NoError(t, err)
+}
+
+func TestVerifyFileWithInvalidGPGSignatureReturnsError(t *testing.T) {
+	file, _ := setupVerifyTests(t)
+	key, sig := signFile(t, file, true)
+
+	err := ioutil.WriteFile(file, []byte("tampered"), 0644)
+	require.NoError(t, err)
+
+	err = VerifyFile(file, &config.Verify{Signature: sig, Key: key})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "signature of")
+}
+
+func TestVerifyFileWithCosignRunsCosign(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses the true and false commands")
+	}
+
+	file, _ := setupVerifyTests(t)
+
+	args := []string{}
+	cosignCommand = func(name string, arg ...string) *exec.Cmd {
+		args = append([]string{name}, arg...)
+		return exec.Command("true")
+	}
+	t.Cleanup(func() {
+		cosignCommand = exec.Command
+	})
+
+	err := VerifyFile(file, &config.Verify{Signature: "/tmp/tool.sig", Key: "/tmp/cosign.pub", SignatureType: config.SignatureTypeCosign})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cosign", "verify-blob", "--key", "/tmp/cosign.pub", "--signature", "/tmp/tool.sig", file}, args)
+
+	cosignCommand = func(name string, arg ...string) *exec.Cmd {
+		return exec.Command("false")
+	}
+
+	err = VerifyFile(file, &config.Verify{Signature: "/tmp/tool.sig", Key: "/tmp/cosign.pub", SignatureType: config.SignatureTypeCosign})
+	assert.Error(t, err)
+}
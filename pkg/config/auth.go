@@ -0,0 +1,35 @@
+package config
+
+import "fmt"
+
+// Auth protects an endpoint exposed by an ingress or docs resource, requests are
+// authenticated by the connector before they are forwarded to the service.
+// Either a basic or an oidc block must be specified.
+type Auth struct {
+	Basic *BasicAuth `hcl:"basic,block" json:"basic,omitempty"` // authenticate using HTTP basic authentication
+	OIDC  *OIDCAuth  `hcl:"oidc,block" json:"oidc,omitempty"`   // authenticate using an OpenID Connect provider
+}
+
+// BasicAuth authenticates requests using a single username and password
+type BasicAuth struct {
+	Username string `hcl:"username" json:"username"`
+	Password string `hcl:"password" json:"password"`
+}
+
+// OIDCAuth authenticates requests using the OpenID Connect authorization code flow
+type OIDCAuth struct {
+	Issuer        string   `hcl:"issuer" json:"issuer"`                                                                  // URL of the OpenID provider e.g. https://accounts.google.com
+	ClientID      string   `hcl:"client_id" json:"client_id" mapstructure:"client_id"`                                   // OAuth client id
+	ClientSecret  string   `hcl:"client_secret" json:"client_secret" mapstructure:"client_secret"`                       // OAuth client secret
+	RedirectURL   string   `hcl:"redirect_url,optional" json:"redirect_url,omitempty" mapstructure:"redirect_url"`       // external URL for the callback, defaults to http://[host]/_shipyard/oidc/callback
+	AllowedEmails []string `hcl:"allowed_emails,optional" json:"allowed_emails,omitempty" mapstructure:"allowed_emails"` // email addresses or domains e.g. @example.com allowed access, all users when empty
+}
+
+// Validate the auth config
+func (a *Auth) Validate() error {
+	if (a.Basic == nil) == (a.OIDC == nil) {
+		return fmt.Errorf("auth must specify one of either basic or oidc")
+	}
+
+	return nil
+}
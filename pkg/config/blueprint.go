@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"os"
+	"path"
+	"strings"
 )
 
 // Blueprint defines a stack blueprint for defining yard configs
@@ -15,6 +18,100 @@ type Blueprint struct {
 	HealthCheckTimeout string   `hcl:"health_check_timeout,optional" json:"health_check_timeout,omitempty" mapstructure:"health_check_timeout"`
 	Environment        []KV     `hcl:"env,block" json:"environment,omitempty"`
 	ShipyardVersion    string   `hcl:"shipyard_version,optional" json:"shipyard_version,omitempty"`
+
+	// Timezone sets TZ in all containers and cluster nodes e.g. Europe/London, on Linux
+	// hosts the zone info for the timezone is also mounted to /etc/localtime
+	Timezone string `hcl:"timezone,optional" json:"timezone,omitempty"`
+	// Locale sets LANG and LC_ALL in all containers and cluster nodes e.g. en_GB.UTF-8
+	Locale string `hcl:"locale,optional" json:"locale,omitempty"`
+
+	// TrustedCAFiles are PEM encoded certificate authorities which are added to the trust
+	// stores of all containers and cluster nodes, and trusted by the HTTP clients which fetch
+	// Helm charts and remote files when resources are applied, e.g. the root CA of a TLS
+	// intercepting proxy
+	TrustedCAFiles []string `hcl:"trusted_ca_files,optional" json:"trusted_ca_files,omitempty" mapstructure:"trusted_ca_files"`
+
+	// EnvPassthrough is a list of host environment variables which are copied to all
+	// containers and exec processes, e.g. ["AWS_*", "GITHUB_TOKEN"]
+	EnvPassthrough []string `hcl:"env_passthrough,optional" json:"env_passthrough,omitempty" mapstructure:"env_passthrough"`
+
+	Profiles []Profile `hcl:"profile,block" json:"profiles,omitempty"`
+
+	// Notifications send the events for the apply and destroy to webhooks
+	Notifications []Notification `hcl:"notification,block" json:"notifications,omitempty"`
+}
+
+// Profile is a named set of variables which can be selected when running
+// a blueprint, e.g. shipyard run --profile minimal
+type Profile struct {
+	Name        string            `hcl:"name,label" json:"name"`
+	Description string            `hcl:"description,optional" json:"description,omitempty"`
+	Variables   map[string]string `hcl:"variables,optional" json:"variables,omitempty"`
+}
+
+// Profile returns the profile with the given name
+func (b *Blueprint) Profile(name string) (*Profile, error) {
+	names := []string{}
+	for i, p := range b.Profiles {
+		if p.Name == name {
+			return &b.Profiles[i], nil
+		}
+
+		names = append(names, p.Name)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("profile '%s' not found, the blueprint does not define any profiles", name)
+	}
+
+	return nil, fmt.Errorf("profile '%s' not found, available profiles: %s", name, strings.Join(names, ", "))
+}
+
+// LocaleEnv returns the environment variables which set the timezone
+// and locale for containers
+func (b *Blueprint) LocaleEnv() map[string]string {
+	env := map[string]string{}
+
+	if b.Timezone != "" {
+		env["TZ"] = b.Timezone
+	}
+
+	if b.Locale != "" {
+		env["LANG"] = b.Locale
+		env["LC_ALL"] = b.Locale
+	}
+
+	return env
+}
+
+// PassthroughEnv returns the host environment variables which match the given patterns
+// and the blueprint EnvPassthrough patterns. Patterns are the name of a variable or a
+// shell pattern e.g. AWS_*, the blueprint can be nil
+func PassthroughEnv(b *Blueprint, patterns []string) map[string]string {
+	if b != nil {
+		patterns = append(append([]string{}, b.EnvPassthrough...), patterns...)
+	}
+
+	env := map[string]string{}
+	if len(patterns) == 0 {
+		return env
+	}
+
+	for _, e := range os.Environ() {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, parts[0]); ok {
+				env[parts[0]] = parts[1]
+				break
+			}
+		}
+	}
+
+	return env
 }
 
 // Validate the Blueprint and return errors
@@ -38,5 +135,15 @@ func (b *Blueprint) Validate() []error {
 		}
 	}
 
+	// profile names are used to select the profile so must be unique
+	profiles := map[string]bool{}
+	for _, p := range b.Profiles {
+		if profiles[p.Name] {
+			errors = append(errors, fmt.Errorf("duplicate profile: %s", p.Name))
+		}
+
+		profiles[p.Name] = true
+	}
+
 	return errors
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,6 +42,105 @@ func TestBlueprintValidationInvalidBrowser(t *testing.T) {
 	assert.Len(t, errs, 1)
 }
 
+func TestBlueprintParsesProfiles(t *testing.T) {
+	c := setupBlueprints(t, blueprintProfiles)
+
+	p, err := c.Blueprint.Profile("minimal")
+	assert.NoError(t, err)
+	assert.Equal(t, "Single node without monitoring", p.Description)
+	assert.Equal(t, "1", p.Variables["nodes"])
+	assert.Equal(t, "false", p.Variables["monitoring"])
+}
+
+func TestBlueprintProfileReturnsErrorWhenNotFound(t *testing.T) {
+	c := setupBlueprints(t, blueprintProfiles)
+
+	_, err := c.Blueprint.Profile("tiny")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "minimal, full")
+}
+
+func TestBlueprintValidationDuplicateProfiles(t *testing.T) {
+	bp := &Blueprint{Profiles: []Profile{{Name: "minimal"}, {Name: "minimal"}}}
+
+	errs := bp.Validate()
+	assert.Len(t, errs, 1)
+}
+
+func TestBlueprintParsesTimezoneAndLocale(t *testing.T) {
+	c := setupBlueprints(t, blueprintLocale)
+
+	assert.Equal(t, "Europe/London", c.Blueprint.Timezone)
+	assert.Equal(t, "en_GB.UTF-8", c.Blueprint.Locale)
+
+	assert.Equal(t, map[string]string{
+		"TZ":     "Europe/London",
+		"LANG":   "en_GB.UTF-8",
+		"LC_ALL": "en_GB.UTF-8",
+	}, c.Blueprint.LocaleEnv())
+}
+
+func TestBlueprintLocaleEnvEmptyWhenNotSet(t *testing.T) {
+	bp := &Blueprint{}
+
+	assert.Empty(t, bp.LocaleEnv())
+}
+
+func TestBlueprintMakesTrustedCAFilesAbsolute(t *testing.T) {
+	dir := CreateTestFiles(t)
+	createNamedFile(t, dir, "*.yard", blueprintTrustedCAs)
+
+	c := &Config{}
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{filepath.Join(dir, "certs/root.pem"), "/etc/corp/ca.pem"}, c.Blueprint.TrustedCAFiles)
+}
+
+func TestPassthroughEnvReturnsMatchingHostVariables(t *testing.T) {
+	t.Setenv("SY_TEST_AWS_KEY", "key")
+	t.Setenv("SY_TEST_AWS_SECRET", "secret")
+	t.Setenv("SY_TEST_TOKEN", "token")
+	t.Setenv("SY_TEST_OTHER", "other")
+
+	bp := &Blueprint{EnvPassthrough: []string{"SY_TEST_AWS_*"}}
+
+	env := PassthroughEnv(bp, []string{"SY_TEST_TOKEN"})
+	assert.Equal(t, "key", env["SY_TEST_AWS_KEY"])
+	assert.Equal(t, "secret", env["SY_TEST_AWS_SECRET"])
+	assert.Equal(t, "token", env["SY_TEST_TOKEN"])
+	assert.NotContains(t, env, "SY_TEST_OTHER")
+}
+
+func TestPassthroughEnvEmptyWithoutPatterns(t *testing.T) {
+	assert.Empty(t, PassthroughEnv(nil, nil))
+}
+
+func TestBlueprintParsesEnvPassthrough(t *testing.T) {
+	c := setupBlueprints(t, blueprintEnvPassthrough)
+
+	assert.Equal(t, []string{"AWS_*", "GITHUB_TOKEN"}, c.Blueprint.EnvPassthrough)
+}
+
+var blueprintEnvPassthrough = `
+title = "passthrough blueprint"
+
+env_passthrough = ["AWS_*", "GITHUB_TOKEN"]
+`
+
+var blueprintTrustedCAs = `
+title = "trusted ca blueprint"
+
+trusted_ca_files = ["./certs/root.pem", "/etc/corp/ca.pem"]
+`
+
+var blueprintLocale = `
+title = "locale blueprint"
+
+timezone = "Europe/London"
+locale   = "en_GB.UTF-8"
+`
+
 var blueprintDefault = `
 title = "default blueprint"
 author = "Keyser Söze"
@@ -70,3 +170,71 @@ browser_windows = [
 	"https://www.something.com",
 ]
 `
+
+var blueprintProfiles = `
+title = "profiles"
+
+profile "minimal" {
+	description = "Single node without monitoring"
+
+	variables = {
+		nodes = 1
+		monitoring = false
+	}
+}
+
+profile "full" {
+	variables = {
+		nodes = 3
+	}
+}
+`
+
+func TestBlueprintParsesNotifications(t *testing.T) {
+	t.Setenv("SY_TEST_SLACK_URL", "https://hooks.slack.com/services/T000/B000/XXX")
+
+	c := setupBlueprints(t, blueprintNotifications)
+
+	n := c.Blueprint.Notifications
+	assert.Len(t, n, 2)
+	assert.Equal(t, NotificationWebhook, n[0].NotificationType())
+	assert.Equal(t, "Bearer abc", n[0].Headers["Authorization"])
+	assert.Equal(t, NotificationSlack, n[1].NotificationType())
+	assert.Equal(t, "https://hooks.slack.com/services/T000/B000/XXX", n[1].URL)
+	assert.Equal(t, []string{"resource_create_failed", "apply_complete"}, n[1].Events)
+}
+
+func TestBlueprintWithInvalidNotificationReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t)
+	createNamedFile(t, dir, "*.yard", blueprintInvalidNotification)
+
+	c := &Config{}
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "notification 'ci' invalid type 'email'")
+}
+
+var blueprintNotifications = `
+title = "notifications blueprint"
+
+notification "ci" {
+  url = "https://ci.example.com/events"
+
+  headers = {
+    Authorization = "Bearer abc"
+  }
+}
+
+notification "team" {
+  type   = "slack"
+  url    = env("SY_TEST_SLACK_URL")
+  events = ["resource_create_failed", "apply_complete"]
+}
+`
+
+var blueprintInvalidNotification = `
+notification "ci" {
+  type = "email"
+  url  = "https://ci.example.com/events"
+}
+`
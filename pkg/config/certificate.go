@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+)
+
+// TypeCertificateCA is the resource string for a CertificateCA resource
+const TypeCertificateCA ResourceType = "certificate_ca"
+
+// TypeCertificateLeaf is the resource string for a CertificateLeaf resource
+const TypeCertificateLeaf ResourceType = "certificate_leaf"
+
+// CertificateCA generates a root certificate authority and private key which
+// can be used to sign leaf certificates
+type CertificateCA struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	// Output is the directory the certificate and key are written to,
+	// by default the files are written to $HOME/.shipyard/certs/[name]
+	Output string `hcl:"output,optional" json:"output,omitempty"`
+
+	// CertPath is the location of the PEM encoded certificate
+	CertPath string `json:"cert_path,omitempty"`
+	// KeyPath is the location of the PEM encoded private key
+	KeyPath string `json:"key_path,omitempty"`
+}
+
+// NewCertificateCA creates a CertificateCA resource with the default values
+func NewCertificateCA(name string) *CertificateCA {
+	return &CertificateCA{ResourceInfo: ResourceInfo{Name: name, Type: TypeCertificateCA, Status: PendingCreation}}
+}
+
+// SetPaths sets the location of the certificate and key in the output directory
+func (c *CertificateCA) SetPaths() {
+	c.CertPath = filepath.Join(c.Output, fmt.Sprintf("%s.ca.cert", c.Name))
+	c.KeyPath = filepath.Join(c.Output, fmt.Sprintf("%s.ca.key", c.Name))
+}
+
+// CertificateLeaf generates a certificate and private key signed by a CertificateCA,
+// the certificate can be used for server and client authentication
+type CertificateLeaf struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	// CA is the certificate_ca resource used to sign the certificate e.g. certificate_ca.root
+	CA string `hcl:"ca" json:"ca"`
+
+	// DNSNames and IPAddresses are the subject alternative names for the certificate
+	DNSNames    []string `hcl:"dns_names,optional" json:"dns_names,omitempty"`
+	IPAddresses []string `hcl:"ip_addresses,optional" json:"ip_addresses,omitempty"`
+
+	// Output is the directory the certificate and key are written to,
+	// by default the files are written to $HOME/.shipyard/certs/[name]
+	Output string `hcl:"output,optional" json:"output,omitempty"`
+
+	// CertPath is the location of the PEM encoded certificate
+	CertPath string `json:"cert_path,omitempty"`
+	// KeyPath is the location of the PEM encoded private key
+	KeyPath string `json:"key_path,omitempty"`
+}
+
+// NewCertificateLeaf creates a CertificateLeaf resource with the default values
+func NewCertificateLeaf(name string) *CertificateLeaf {
+	return &CertificateLeaf{ResourceInfo: ResourceInfo{Name: name, Type: TypeCertificateLeaf, Status: PendingCreation}}
+}
+
+// SetPaths sets the location of the certificate and key in the output directory
+func (c *CertificateLeaf) SetPaths() {
+	c.CertPath = filepath.Join(c.Output, fmt.Sprintf("%s.cert", c.Name))
+	c.KeyPath = filepath.Join(c.Output, fmt.Sprintf("%s.key", c.Name))
+}
+
+// Validate the CertificateLeaf and return errors
+func (c *CertificateLeaf) Validate() error {
+	if !strings.HasPrefix(c.CA, string(TypeCertificateCA)+".") {
+		return fmt.Errorf("ca must be a certificate_ca resource e.g. certificate_ca.root, got %s", c.CA)
+	}
+
+	for _, ip := range c.IPAddresses {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("ip_addresses must contain valid IP addresses, got %s", ip)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreatesCertificates(t *testing.T) {
+	ca := NewCertificateCA("root")
+	assert.Equal(t, "root", ca.Name)
+	assert.Equal(t, TypeCertificateCA, ca.Type)
+
+	l := NewCertificateLeaf("web")
+	assert.Equal(t, "web", l.Name)
+	assert.Equal(t, TypeCertificateLeaf, l.Type)
+}
+
+func TestCertificateCACreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, certificateValid)
+
+	r, err := c.FindResource("certificate_ca.root")
+	assert.NoError(t, err)
+
+	ca := r.(*CertificateCA)
+	assert.Equal(t, PendingCreation, ca.Status)
+	assert.Equal(t, filepath.Join(utils.ShipyardHome(), "certs", "root"), ca.Output)
+	assert.Equal(t, filepath.Join(ca.Output, "root.ca.cert"), ca.CertPath)
+	assert.Equal(t, filepath.Join(ca.Output, "root.ca.key"), ca.KeyPath)
+}
+
+func TestCertificateLeafCreatesCorrectly(t *testing.T) {
+	c, dir := CreateConfigFromStrings(t, certificateValid)
+
+	r, err := c.FindResource("certificate_leaf.web")
+	assert.NoError(t, err)
+
+	l := r.(*CertificateLeaf)
+	assert.Equal(t, []string{"web.container.shipyard.run", "localhost"}, l.DNSNames)
+	assert.Equal(t, []string{"127.0.0.1"}, l.IPAddresses)
+	assert.Equal(t, filepath.Join(dir, "certs"), l.Output)
+	assert.Equal(t, filepath.Join(dir, "certs", "web.cert"), l.CertPath)
+	assert.Equal(t, filepath.Join(dir, "certs", "web.key"), l.KeyPath)
+}
+
+func TestCertificateLeafDependsOnCA(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, certificateValid)
+
+	r, err := c.FindResource("certificate_leaf.web")
+	assert.NoError(t, err)
+
+	assert.Contains(t, r.Info().DependsOn, "certificate_ca.root")
+}
+
+func TestCertificateLeafWithInvalidCAReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, certificateInvalidCA)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ca must be")
+}
+
+func TestCertificateLeafWithInvalidIPReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, certificateInvalidIP)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ip_addresses")
+}
+
+func TestTemplateReferencingCertificateDependsOnCertificate(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, certificateValid)
+
+	tmpl, err := c.FindResource("template.tls")
+	assert.NoError(t, err)
+
+	assert.Contains(t, tmpl.Info().DependsOn, "certificate_leaf.web")
+}
+
+func TestResourceEvalContextContainsCertificates(t *testing.T) {
+	c, dir := CreateConfigFromStrings(t, certificateValid)
+
+	os.MkdirAll(filepath.Join(dir, "certs"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "certs", "web.cert"), []byte("cert"), os.ModePerm)
+
+	ec := GetResourceEvalContext(c)
+
+	leaf := ec.Variables["certificate_leaf"].GetAttr("web")
+	assert.Equal(t, "cert", leaf.GetAttr("cert_pem").AsString())
+	assert.Equal(t, "", leaf.GetAttr("key_pem").AsString())
+	assert.Equal(t, filepath.Join(dir, "certs", "web.key"), leaf.GetAttr("key_path").AsString())
+
+	ca := ec.Variables["certificate_ca"].GetAttr("root")
+	assert.Contains(t, ca.GetAttr("cert_path").AsString(), "root.ca.cert")
+}
+
+var certificateValid = `
+certificate_ca "root" {}
+
+certificate_leaf "web" {
+  ca = "certificate_ca.root"
+
+  dns_names = ["web.container.shipyard.run", "localhost"]
+  ip_addresses = ["127.0.0.1"]
+
+  output = "./certs"
+}
+
+template "tls" {
+  source = "#{{ .Vars.cert }}"
+  destination = "./tls.pem"
+
+  vars = {
+    cert = certificate_leaf.web.cert_pem
+  }
+}
+`
+
+var certificateInvalidCA = `
+certificate_leaf "web" {
+  ca = "container.root"
+}
+`
+
+var certificateInvalidIP = `
+certificate_ca "root" {}
+
+certificate_leaf "web" {
+  ca = "certificate_ca.root"
+  ip_addresses = ["localhost"]
+}
+`
@@ -0,0 +1,49 @@
+package config
+
+import "fmt"
+
+// TypeCompose is the resource string for a Compose resource
+const TypeCompose ResourceType = "compose"
+
+// Compose launches the services defined in a docker-compose file as Shipyard managed
+// containers. Each service is created as a container named [service].[name].compose.shipyard.run
+// and is attached to the given networks using the service name as an alias, this allows
+// services to resolve each other by name as they would when started with docker-compose.
+type Compose struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	Networks []NetworkAttachment `hcl:"network,block" json:"networks,omitempty"` // networks to attach the service containers to
+
+	File string `hcl:"file" json:"file"` // path to the docker-compose file
+
+	// Services are the names of the services which have been created from the compose file
+	Services []string `json:"services,omitempty" state:"true"`
+}
+
+// NewCompose creates a Compose resource with the default values
+func NewCompose(name string) *Compose {
+	return &Compose{ResourceInfo: ResourceInfo{Name: name, Type: TypeCompose, Status: PendingCreation}}
+}
+
+// Validate the config
+func (c *Compose) Validate() error {
+	if c.File == "" {
+		return fmt.Errorf("file must be specified")
+	}
+
+	for _, n := range c.Networks {
+		// all services share the network attachment so a static ip can not be used
+		if n.IPAddress != "" {
+			return fmt.Errorf("network '%s' has an ip_address, static ip addresses are not supported for compose services", n.Name)
+		}
+	}
+
+	return nil
+}
+
+// ServiceContainerName returns the name of the container which is created for the given service
+func (c *Compose) ServiceContainerName(service string) string {
+	return fmt.Sprintf("%s.%s", service, c.Name)
+}
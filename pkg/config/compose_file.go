@@ -0,0 +1,570 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/shlex"
+	"sigs.k8s.io/yaml"
+)
+
+// composeFile is the subset of the docker-compose file format which is supported
+// by the compose resource, unsupported keys are ignored
+type composeFile struct {
+	Services map[string]composeService `json:"services"`
+	Volumes  map[string]*composeVolume `json:"volumes"`
+}
+
+type composeService struct {
+	Image       string         `json:"image"`
+	Build       *composeBuild  `json:"build"`
+	Command     composeCommand `json:"command"`
+	Entrypoint  composeCommand `json:"entrypoint"`
+	Environment composeMap     `json:"environment"`
+	EnvFile     composeList    `json:"env_file"`
+	Ports       []composePort  `json:"ports"`
+	Volumes     []composeMount `json:"volumes"`
+	DependsOn   composeList    `json:"depends_on"`
+	Restart     string         `json:"restart"`
+	Privileged  bool           `json:"privileged"`
+	User        string         `json:"user"`
+	CapAdd      []string       `json:"cap_add"`
+	CapDrop     []string       `json:"cap_drop"`
+	SecurityOpt []string       `json:"security_opt"`
+	Sysctls     composeMap     `json:"sysctls"`
+}
+
+type composeVolume struct {
+	Name     string `json:"name"`
+	External bool   `json:"external"`
+}
+
+type composeBuild struct {
+	Context    string     `json:"context"`
+	Dockerfile string     `json:"dockerfile"`
+	Args       composeMap `json:"args"`
+	Target     string     `json:"target"`
+}
+
+// UnmarshalJSON allows the build to be specified as the path to the context
+func (b *composeBuild) UnmarshalJSON(d []byte) error {
+	var context string
+	if json.Unmarshal(d, &context) == nil {
+		b.Context = context
+		return nil
+	}
+
+	type build composeBuild
+	return json.Unmarshal(d, (*build)(b))
+}
+
+// composeCommand is a command which can be specified as a string or a list
+type composeCommand []string
+
+// UnmarshalJSON splits commands which are specified as a string using shell syntax
+func (c *composeCommand) UnmarshalJSON(d []byte) error {
+	var command string
+	if json.Unmarshal(d, &command) == nil {
+		parts, err := shlex.Split(command)
+		if err != nil {
+			return fmt.Errorf("unable to parse command '%s': %s", command, err)
+		}
+
+		*c = parts
+		return nil
+	}
+
+	return json.Unmarshal(d, (*[]string)(c))
+}
+
+// composeList is a list which can be specified as a string, a list, or a map
+// where only the keys are used e.g. depends_on with conditions
+type composeList []string
+
+// UnmarshalJSON reads the list from any of the supported formats
+func (l *composeList) UnmarshalJSON(d []byte) error {
+	var s string
+	if json.Unmarshal(d, &s) == nil {
+		*l = []string{s}
+		return nil
+	}
+
+	m := map[string]interface{}{}
+	if json.Unmarshal(d, &m) == nil {
+		for k := range m {
+			*l = append(*l, k)
+		}
+
+		sort.Strings(*l)
+		return nil
+	}
+
+	return json.Unmarshal(d, (*[]string)(l))
+}
+
+// composeMap is a map which can be specified as a map or a list of KEY=value
+type composeMap map[string]string
+
+// UnmarshalJSON reads the map from either of the supported formats
+func (m *composeMap) UnmarshalJSON(d []byte) error {
+	*m = composeMap{}
+
+	list := []string{}
+	if json.Unmarshal(d, &list) == nil {
+		for _, kv := range list {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) == 1 {
+				// values without a value are read from the environment
+				(*m)[parts[0]] = os.Getenv(parts[0])
+				continue
+			}
+
+			(*m)[parts[0]] = parts[1]
+		}
+
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(d))
+	dec.UseNumber()
+
+	values := map[string]interface{}{}
+	err := dec.Decode(&values)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range values {
+		if v == nil {
+			(*m)[k] = os.Getenv(k)
+			continue
+		}
+
+		(*m)[k] = fmt.Sprint(v)
+	}
+
+	return nil
+}
+
+// composePort is a port which can be specified using the short or long syntax
+type composePort struct {
+	Target    string
+	Published string
+	Protocol  string
+	HostIP    string
+}
+
+// UnmarshalJSON reads the port from either of the supported formats
+func (p *composePort) UnmarshalJSON(d []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(d))
+	dec.UseNumber()
+
+	var v interface{}
+	err := dec.Decode(&v)
+	if err != nil {
+		return err
+	}
+
+	switch pv := v.(type) {
+	case string:
+		return p.parse(pv)
+	case json.Number:
+		return p.parse(pv.String())
+	case map[string]interface{}:
+		for k, f := range map[string]*string{"target": &p.Target, "published": &p.Published, "protocol": &p.Protocol, "host_ip": &p.HostIP} {
+			if pv[k] != nil {
+				*f = fmt.Sprint(pv[k])
+			}
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("invalid port %s", string(d))
+}
+
+// parse the short syntax [host_ip:][published:]target[/protocol]
+func (p *composePort) parse(s string) error {
+	if i := strings.LastIndex(s, "/"); i > -1 {
+		p.Protocol = s[i+1:]
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ":")
+	p.Target = parts[len(parts)-1]
+
+	if len(parts) > 1 {
+		p.Published = parts[len(parts)-2]
+	}
+
+	if len(parts) > 2 {
+		p.HostIP = strings.Trim(strings.Join(parts[:len(parts)-2], ":"), "[]")
+	}
+
+	return nil
+}
+
+// composeMount is a volume which can be specified using the short or long syntax
+type composeMount struct {
+	Type        string
+	Source      string
+	Target      string
+	ReadOnly    bool
+	Propagation string
+}
+
+// UnmarshalJSON reads the volume from either of the supported formats
+func (m *composeMount) UnmarshalJSON(d []byte) error {
+	var s string
+	if json.Unmarshal(d, &s) == nil {
+		return m.parse(s)
+	}
+
+	lv := struct {
+		Type     string `json:"type"`
+		Source   string `json:"source"`
+		Target   string `json:"target"`
+		ReadOnly bool   `json:"read_only"`
+		Bind     struct {
+			Propagation string `json:"propagation"`
+		} `json:"bind"`
+	}{}
+
+	err := json.Unmarshal(d, &lv)
+	if err != nil {
+		return err
+	}
+
+	m.Type = lv.Type
+	m.Source = lv.Source
+	m.Target = lv.Target
+	m.ReadOnly = lv.ReadOnly
+	m.Propagation = lv.Bind.Propagation
+
+	return nil
+}
+
+// parse the short syntax source:target[:options]
+func (m *composeMount) parse(s string) error {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 {
+		return fmt.Errorf("invalid volume '%s', anonymous volumes are not supported", s)
+	}
+
+	m.Source = parts[0]
+	m.Target = parts[1]
+	m.Type = "volume"
+
+	if isComposePath(m.Source) {
+		m.Type = "bind"
+	}
+
+	if len(parts) > 2 {
+		for _, o := range strings.Split(parts[2], ",") {
+			switch o {
+			case "ro":
+				m.ReadOnly = true
+			case "shared", "rshared", "private", "rprivate", "slave", "rslave":
+				m.Propagation = o
+			}
+		}
+	}
+
+	return nil
+}
+
+// isComposePath returns true when the source of a volume is a path and not the name of a volume
+func isComposePath(s string) bool {
+	return strings.HasPrefix(s, ".") || strings.HasPrefix(s, "/") || strings.HasPrefix(s, "~")
+}
+
+// Containers reads the compose file and returns a container for each of the services,
+// containers are returned in the order they need to be created so that the services
+// in depends_on are started first
+func (c *Compose) Containers() ([]*Container, error) {
+	d, err := ioutil.ReadFile(c.File)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read compose file '%s': %s", c.File, err)
+	}
+
+	// replace any environment variables in the file before parsing
+	d = []byte(os.Expand(string(d), composeVariable))
+
+	cf := composeFile{}
+	err = yaml.Unmarshal(d, &cf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse compose file '%s': %s", c.File, err)
+	}
+
+	if len(cf.Services) == 0 {
+		return nil, fmt.Errorf("compose file '%s' does not contain any services", c.File)
+	}
+
+	order, err := composeOrder(cf.Services)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := []*Container{}
+	for _, name := range order {
+		cc, err := c.serviceContainer(name, cf.Services[name], cf.Volumes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create container for service '%s': %s", name, err)
+		}
+
+		containers = append(containers, cc)
+	}
+
+	return containers, nil
+}
+
+func (c *Compose) serviceContainer(name string, s composeService, volumes map[string]*composeVolume) (*Container, error) {
+	dir := filepath.Dir(c.File)
+
+	cc := NewContainer(c.ServiceContainerName(name))
+	c.ResourceInfo.AddChild(cc)
+
+	for _, n := range c.Networks {
+		cc.Networks = append(cc.Networks, NetworkAttachment{Name: n.Name, Aliases: append(append([]string{}, n.Aliases...), name)})
+	}
+
+	switch {
+	case s.Build != nil:
+		cc.Build = &Build{
+			Context: composeAbsolute(s.Build.Context, dir),
+			File:    s.Build.Dockerfile,
+			Args:    s.Build.Args,
+			Target:  s.Build.Target,
+		}
+	case s.Image != "":
+		cc.Image = &Image{Name: s.Image}
+	default:
+		return nil, fmt.Errorf("image or build must be specified")
+	}
+
+	cc.Command = s.Command
+	cc.Entrypoint = s.Entrypoint
+	cc.Privileged = s.Privileged
+	cc.CapAdd = s.CapAdd
+	cc.CapDrop = s.CapDrop
+	cc.SecurityOpt = s.SecurityOpt
+
+	if len(s.Sysctls) > 0 {
+		cc.Sysctls = s.Sysctls
+	}
+
+	// env files are loaded first so that environment takes precedence
+	cc.EnvVar = map[string]string{}
+	for _, f := range s.EnvFile {
+		env, err := readComposeEnvFile(composeAbsolute(f, dir))
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range env {
+			cc.EnvVar[k] = v
+		}
+	}
+
+	for k, v := range s.Environment {
+		cc.EnvVar[k] = v
+	}
+
+	if s.Restart != "" {
+		parts := strings.SplitN(s.Restart, ":", 2)
+		cc.Restart = parts[0]
+
+		if len(parts) == 2 {
+			i, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid restart policy '%s'", s.Restart)
+			}
+
+			cc.MaxRestartCount = i
+		}
+	}
+
+	if s.User != "" {
+		parts := strings.SplitN(s.User, ":", 2)
+		cc.RunAs = &User{User: parts[0]}
+
+		if len(parts) == 2 {
+			cc.RunAs.Group = parts[1]
+		}
+	}
+
+	for _, p := range s.Ports {
+		if strings.Contains(p.Target, "-") {
+			if p.Published != "" && p.Published != p.Target {
+				return nil, fmt.Errorf("invalid port range '%s:%s', published ports must match the target ports", p.Published, p.Target)
+			}
+
+			cc.PortRanges = append(cc.PortRanges, PortRange{Range: p.Target, EnableHost: p.Published != "", Protocol: p.Protocol})
+			continue
+		}
+
+		cc.Ports = append(cc.Ports, Port{Local: p.Target, Host: p.Published, Protocol: p.Protocol, HostIP: p.HostIP})
+	}
+
+	for _, m := range s.Volumes {
+		v := Volume{Type: m.Type, Source: m.Source, Destination: m.Target, ReadOnly: m.ReadOnly, BindPropagation: m.Propagation}
+
+		switch m.Type {
+		case "bind", "":
+			v.Source = composeAbsolute(m.Source, dir)
+		case "volume":
+			// named volumes are scoped to the resource unless they are external
+			v.Source = fmt.Sprintf("%s_%s", c.Name, m.Source)
+
+			if vol := volumes[m.Source]; vol != nil {
+				if vol.External {
+					v.Source = m.Source
+					v.External = true
+				}
+
+				if vol.Name != "" {
+					v.Source = vol.Name
+				}
+			}
+		}
+
+		cc.Volumes = append(cc.Volumes, v)
+	}
+
+	err := validateVolumes(cc.Volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	return cc, nil
+}
+
+// composeOrder returns the names of the services ordered so that
+// dependencies are created before the services which depend on them
+func composeOrder(services map[string]composeService) ([]string, error) {
+	names := []string{}
+	for n := range services {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	order := []string{}
+	state := map[string]int{} // 1 visiting, 2 done
+
+	var visit func(n string, path []string) error
+	visit = func(n string, path []string) error {
+		switch state[n] {
+		case 1:
+			return fmt.Errorf("services have a circular dependency: %s", strings.Join(append(path, n), " -> "))
+		case 2:
+			return nil
+		}
+
+		state[n] = 1
+		for _, dep := range services[n].DependsOn {
+			if _, ok := services[dep]; !ok {
+				return fmt.Errorf("service '%s' depends on unknown service '%s'", n, dep)
+			}
+
+			err := visit(dep, append(path, n))
+			if err != nil {
+				return err
+			}
+		}
+
+		state[n] = 2
+		order = append(order, n)
+
+		return nil
+	}
+
+	for _, n := range names {
+		err := visit(n, []string{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// composeVariable returns the value for a variable in the compose file,
+// default values can be set using ${VAR:-default} or ${VAR-default}
+func composeVariable(name string) string {
+	// $$ is an escaped $
+	if name == "$" {
+		return "$"
+	}
+
+	if i := strings.Index(name, ":-"); i > -1 {
+		if v := os.Getenv(name[:i]); v != "" {
+			return v
+		}
+
+		return name[i+2:]
+	}
+
+	if i := strings.Index(name, "-"); i > -1 {
+		if v, ok := os.LookupEnv(name[:i]); ok {
+			return v
+		}
+
+		return name[i+1:]
+	}
+
+	return os.Getenv(name)
+}
+
+// composeAbsolute returns the absolute path for a path in the compose file,
+// relative paths are relative to the folder containing the compose file
+func composeAbsolute(path, dir string) string {
+	if strings.HasPrefix(path, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+// readComposeEnvFile reads the KEY=value pairs from an env file
+func readComposeEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read env_file '%s': %s", path, err)
+	}
+	defer f.Close()
+
+	env := map[string]string{}
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(l, "=", 2)
+		if len(parts) == 1 {
+			env[parts[0]] = os.Getenv(parts[0])
+			continue
+		}
+
+		env[parts[0]] = parts[1]
+	}
+
+	return env, s.Err()
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupComposeFile(t *testing.T, contents string) *Compose {
+	dir := t.TempDir()
+
+	err := ioutil.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(contents), os.ModePerm)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "web.env"), []byte("# web\nLOG_LEVEL=info\nPORT=8080\n"), os.ModePerm)
+	assert.NoError(t, err)
+
+	c := NewCompose("app")
+	c.File = filepath.Join(dir, "docker-compose.yml")
+	c.Networks = []NetworkAttachment{{Name: "network.local"}}
+
+	return c
+}
+
+func TestNewCreatesCompose(t *testing.T) {
+	c := NewCompose("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeCompose, c.Type)
+}
+
+func TestComposeCreatesCorrectly(t *testing.T) {
+	c, dir := CreateConfigFromStrings(t, composeDefault)
+
+	cl, err := c.FindResource("compose.app")
+	assert.NoError(t, err)
+
+	co := cl.(*Compose)
+	assert.Equal(t, filepath.Join(dir, "docker-compose.yml"), co.File)
+	assert.Equal(t, PendingCreation, co.Status)
+	assert.Contains(t, co.DependsOn, "network.local")
+}
+
+func TestComposeWithStaticIPReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, composeStaticIP)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "static ip addresses are not supported")
+}
+
+func TestComposeContainersReturnsServicesInDependencyOrder(t *testing.T) {
+	c := setupComposeFile(t, composeFileDefault)
+
+	cc, err := c.Containers()
+	assert.NoError(t, err)
+
+	assert.Len(t, cc, 2)
+	assert.Equal(t, "db.app", cc[0].Name)
+	assert.Equal(t, "web.app", cc[1].Name)
+	assert.Equal(t, TypeCompose, cc[0].Type)
+}
+
+func TestComposeContainersConvertsServices(t *testing.T) {
+	os.Setenv("COMPOSE_TEST_TAG", "14")
+	t.Cleanup(func() { os.Unsetenv("COMPOSE_TEST_TAG") })
+
+	c := setupComposeFile(t, composeFileDefault)
+	dir := filepath.Dir(c.File)
+
+	cc, err := c.Containers()
+	assert.NoError(t, err)
+
+	db := cc[0]
+	assert.Equal(t, "postgres:14", db.Image.Name)
+	assert.Equal(t, "secret", db.EnvVar["POSTGRES_PASSWORD"])
+	assert.Equal(t, "5432", db.EnvVar["PGPORT"])
+	assert.Equal(t, []string{"postgres", "-c", "log_statement=all"}, db.Command)
+	assert.Equal(t, "volume", db.Volumes[0].Type)
+	assert.Equal(t, "app_data", db.Volumes[0].Source)
+	assert.Equal(t, "shared-cache", db.Volumes[1].Source)
+	assert.True(t, db.Volumes[1].External)
+	assert.Equal(t, []string{"db"}, db.Networks[0].Aliases)
+
+	web := cc[1]
+	assert.Nil(t, web.Image)
+	assert.Equal(t, filepath.Join(dir, "web"), web.Build.Context)
+	assert.Equal(t, "Dockerfile.dev", web.Build.File)
+	assert.Equal(t, "info", web.EnvVar["LOG_LEVEL"])
+	assert.Equal(t, "9090", web.EnvVar["PORT"])
+	assert.Equal(t, []string{"/bin/sh", "-c"}, web.Entrypoint)
+	assert.Equal(t, "on-failure", web.Restart)
+	assert.Equal(t, 3, web.MaxRestartCount)
+	assert.Equal(t, "1000", web.RunAs.User)
+	assert.Empty(t, web.RunAs.Group)
+
+	assert.Equal(t, Port{Local: "80", Host: "8080"}, web.Ports[0])
+	assert.Equal(t, Port{Local: "53", Host: "5353", Protocol: "udp", HostIP: "127.0.0.1"}, web.Ports[1])
+	assert.Equal(t, Port{Local: "443", Host: "8443", Protocol: "tcp"}, web.Ports[2])
+	assert.Equal(t, PortRange{Range: "9000-9002", EnableHost: true}, web.PortRanges[0])
+
+	assert.Equal(t, "bind", web.Volumes[0].Type)
+	assert.Equal(t, filepath.Join(dir, "src"), web.Volumes[0].Source)
+	assert.True(t, web.Volumes[0].ReadOnly)
+	assert.Equal(t, "rshared", web.Volumes[0].BindPropagation)
+	assert.Equal(t, "tmpfs", web.Volumes[1].Type)
+}
+
+func TestComposeContainersWithUnknownDependencyReturnsError(t *testing.T) {
+	c := setupComposeFile(t, composeFileUnknownDependency)
+
+	_, err := c.Containers()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown service 'cache'")
+}
+
+func TestComposeContainersWithCircularDependencyReturnsError(t *testing.T) {
+	c := setupComposeFile(t, composeFileCircular)
+
+	_, err := c.Containers()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "circular dependency")
+}
+
+func TestComposeContainersWithoutImageReturnsError(t *testing.T) {
+	c := setupComposeFile(t, composeFileNoImage)
+
+	_, err := c.Containers()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "image or build must be specified")
+}
+
+const composeDefault = `
+network "local" {
+	subnet = "10.0.0.0/16"
+}
+
+compose "app" {
+	network {
+		name = "network.local"
+	}
+
+	file = "./docker-compose.yml"
+}
+`
+
+const composeStaticIP = `
+compose "app" {
+	network {
+		name       = "network.local"
+		ip_address = "10.0.0.10"
+	}
+
+	file = "./docker-compose.yml"
+}
+`
+
+const composeFileDefault = `
+version: "3.8"
+services:
+  web:
+    build:
+      context: ./web
+      dockerfile: Dockerfile.dev
+    entrypoint: ["/bin/sh", "-c"]
+    env_file: web.env
+    environment:
+      - PORT=9090
+    ports:
+      - "8080:80"
+      - "127.0.0.1:5353:53/udp"
+      - target: 443
+        published: 8443
+        protocol: tcp
+      - "9000-9002:9000-9002"
+    volumes:
+      - ./src:/src:ro,rshared
+      - type: tmpfs
+        target: /tmp
+    depends_on:
+      db:
+        condition: service_healthy
+    restart: on-failure:3
+    user: "1000"
+  db:
+    image: postgres:${COMPOSE_TEST_TAG:-13}
+    command: postgres -c "log_statement=all"
+    environment:
+      POSTGRES_PASSWORD: secret
+      PGPORT: 5432
+    volumes:
+      - data:/var/lib/postgresql/data
+      - cache:/cache
+volumes:
+  data:
+  cache:
+    external: true
+    name: shared-cache
+`
+
+const composeFileUnknownDependency = `
+services:
+  web:
+    image: nginx
+    depends_on:
+      - cache
+`
+
+const composeFileCircular = `
+services:
+  web:
+    image: nginx
+    depends_on:
+      - api
+  api:
+    image: api
+    depends_on:
+      - web
+`
+
+const composeFileNoImage = `
+services:
+  web:
+    ports:
+      - "80"
+`
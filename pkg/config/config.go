@@ -1,7 +1,10 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/terraform/dag"
@@ -37,6 +40,10 @@ const Failed Status = "failed"
 // Destroyed means the resource has been destroyed
 const Destroyed Status = "destroyed"
 
+// Paused means the containers for the resource have been stopped with shipyard pause,
+// the containers are started again with shipyard resume
+const Paused Status = "paused"
+
 // Disabled means the resource will be ignored by the engine and no resources
 // will be created or destroyed
 const Disabled Status = "disabled"
@@ -61,6 +68,22 @@ type ResourceInfo struct {
 	Module string `json:"module,omitempty"`
 	// Enabled determines if a resource is enabled and should be processed
 	Disabled bool `hcl:"disabled,optional" json:"disabled,omitempty"`
+	// When is a condition evaluated when the blueprint is parsed e.g. var.profile == "full",
+	// the resource is disabled when the condition is false
+	When *bool `hcl:"when,optional" json:"when,omitempty"`
+	// WaitFor are conditions which must be met after the resource has been created
+	// before the resources which depend on it are created
+	WaitFor []WaitFor `hcl:"wait_for,block" json:"wait_for,omitempty" mapstructure:"wait_for"`
+	// ResourceID is a stable identifier for the resource generated from the module, type, and name
+	ResourceID string `json:"resource_id,omitempty" mapstructure:"resource_id"`
+	// BlueprintSource is the blueprint the resource was created from, several blueprints
+	// can be merged into the same state and a resource can only be defined by one of them
+	BlueprintSource string `json:"blueprint_source,omitempty" mapstructure:"blueprint_source"`
+	// ConfigHash is the hash of the configuration which was last applied, it is used
+	// to detect changes to the resource when the blueprint is applied again
+	ConfigHash string `json:"config_hash,omitempty" mapstructure:"config_hash"`
+	// Timing records the time taken by the last create or destroy of the resource
+	Timing *Timing `json:"timing,omitempty" mapstructure:"timing"`
 
 	// parent container
 	Config *Config `json:"-"`
@@ -84,6 +107,7 @@ func (r *ResourceInfo) AddChild(c Resource) {
 
 // Config defines the stack config
 type Config struct {
+	Version   int        `json:"version"` // version of the state schema, see StateVersion
 	Blueprint *Blueprint `json:"blueprint"`
 	Resources []Resource `json:"resources"`
 }
@@ -182,11 +206,40 @@ func (c *Config) AddResource(r Resource) error {
 	}
 
 	r.Info().Config = c
+	r.Info().ResourceID = ResourceID(r.Info().Module, r.Info().Type, r.Info().Name)
 	c.Resources = append(c.Resources, r)
 
 	return nil
 }
 
+// ResourceID returns a stable identifier for a resource, the same
+// resource always has the same id regardless of the machine or the
+// order in which the config was parsed
+func ResourceID(module string, t ResourceType, name string) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s/%s.%s", module, t, name)))
+
+	return hex.EncodeToString(h[:8])
+}
+
+// Sort orders the resources by module, type, and name so that the
+// order is the same for every run
+func (c *Config) Sort() {
+	sort.SliceStable(c.Resources, func(i, j int) bool {
+		a := c.Resources[i].Info()
+		b := c.Resources[j].Info()
+
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+
+		return a.Name < b.Name
+	})
+}
+
 func (c *Config) RemoveResource(rf Resource) error {
 	pos := -1
 	for i, r := range c.Resources {
@@ -0,0 +1,330 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	"github.com/hashicorp/hcl2/hclparse"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"sigs.k8s.io/yaml"
+)
+
+// configFiles returns the files in the folder which contain resources, JSON and YAML
+// files use the same structure as HCL files, blocks are objects keyed by the resource
+// type and name e.g.
+//
+//	container:
+//	  consul:
+//	    image:
+//	      name: consul:1.10.1
+//
+// JSON and YAML files which do not contain resources, such as Kubernetes manifests,
+// are ignored
+func configFiles(folder string) ([]string, error) {
+	files := []string{}
+
+	for _, ext := range utils.ConfigExtensions {
+		found, err := filepath.Glob(filepath.Join(folder, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range found {
+			if ext != ".hcl" && !isResourceDocument(f) {
+				continue
+			}
+
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
+}
+
+// isResourceDocument returns true when all the top level keys of the
+// JSON or YAML file are the types of blocks
+func isResourceDocument(file string) bool {
+	doc, err := readDocument(file)
+	if err != nil || len(doc) == 0 {
+		return false
+	}
+
+	for k := range doc {
+		if _, ok := PluginType(ResourceType(k)); !ok && k != BlockLocals && blockValue(k) == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseConfigFile parses a HCL, JSON, or YAML config file, JSON and YAML files are
+// converted to the equivalent HCL so that all formats are decoded in the same way
+func parseConfigFile(file string) (*hclsyntax.Body, hcl.Diagnostics) {
+	var f *hcl.File
+	var diag hcl.Diagnostics
+
+	switch filepath.Ext(file) {
+	case ".json", ".yaml", ".yml":
+		src, err := documentToHCL(file)
+		if err != nil {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid config file",
+				Detail:   err.Error(),
+				Subject:  &hcl.Range{Filename: file},
+			}}
+		}
+
+		f, diag = hclsyntax.ParseConfig(src, file, hcl.Pos{Line: 1, Column: 1})
+	default:
+		f, diag = hclparse.NewParser().ParseHCLFile(file)
+	}
+
+	if diag.HasErrors() {
+		return nil, diag
+	}
+
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "Error getting body", Subject: &hcl.Range{Filename: file}}}
+	}
+
+	return body, nil
+}
+
+// readDocument reads a JSON or YAML file into a map, numbers are
+// kept as json.Number so that they are written without changes
+func readDocument(file string) (map[string]interface{}, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := yaml.YAMLToJSON(d)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+
+	doc := map[string]interface{}{}
+	err = dec.Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("file must contain an object keyed by resource type: %s", err)
+	}
+
+	return doc, nil
+}
+
+// documentToHCL converts a JSON or YAML file into HCL, the fields of the resource
+// structs are used to determine which keys are blocks and which are attributes
+func documentToHCL(file string) ([]byte, error) {
+	doc, err := readDocument(file)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	for _, typ := range sortedKeys(doc) {
+		body, ok := doc[typ].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("'%s' must be an object", typ)
+		}
+
+		if typ == BlockLocals {
+			err := writeBlock(buf, typ, "", body, nil, "")
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		var t reflect.Type
+		if v := blockValue(typ); v != nil {
+			t = reflect.TypeOf(v)
+		}
+
+		for _, name := range sortedKeys(body) {
+			b, ok := body[name].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("resource '%s.%s' must be an object", typ, name)
+			}
+
+			err := writeBlock(buf, typ, name, b, t, "")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeBlock writes a block with an optional label, t is the struct the block is decoded
+// into, when t is nil all the keys in the body are written as attributes
+func writeBlock(buf *bytes.Buffer, typ, label string, body map[string]interface{}, t reflect.Type, indent string) error {
+	if label != "" {
+		fmt.Fprintf(buf, "%s%s %s {\n", indent, typ, quoteString(label))
+	} else {
+		fmt.Fprintf(buf, "%s%s {\n", indent, typ)
+	}
+
+	for _, k := range sortedKeys(body) {
+		bt := blockField(t, k)
+		if bt == nil {
+			fmt.Fprintf(buf, "%s  %s = %s\n", indent, k, hclValue(body[k]))
+			continue
+		}
+
+		blocks := []interface{}{body[k]}
+		if l, ok := body[k].([]interface{}); ok {
+			blocks = l
+		}
+
+		for _, b := range blocks {
+			m, ok := b.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("'%s' must be an object or a list of objects", k)
+			}
+
+			err := writeBlock(buf, k, "", m, bt, indent+"  ")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Fprintf(buf, "%s}\n", indent)
+
+	return nil
+}
+
+// blockField returns the type of the nested block with the given name,
+// nil is returned when the name is an attribute
+func blockField(t reflect.Type, name string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		parts := strings.Split(f.Tag.Get("hcl"), ",")
+		if len(parts) < 2 {
+			continue
+		}
+
+		// fields embedded with remain such as ResourceInfo can contain blocks
+		if parts[0] == "" && parts[1] == "remain" {
+			if bt := blockField(f.Type, name); bt != nil {
+				return bt
+			}
+
+			continue
+		}
+
+		if parts[0] == name && parts[1] == "block" {
+			bt := f.Type
+			for bt.Kind() == reflect.Ptr || bt.Kind() == reflect.Slice {
+				bt = bt.Elem()
+			}
+
+			return bt
+		}
+	}
+
+	return nil
+}
+
+// hclValue returns the HCL expression for a value, strings are written as templates
+// so that they can contain interpolations e.g. "${var.version}"
+func hclValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprintf("%t", val)
+	case json.Number:
+		return val.String()
+	case string:
+		return quoteString(val)
+	case []interface{}:
+		items := []string{}
+		for _, i := range val {
+			items = append(items, hclValue(i))
+		}
+
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		items := []string{}
+		for _, k := range sortedKeys(val) {
+			items = append(items, fmt.Sprintf("%s = %s", quoteString(k), hclValue(val[k])))
+		}
+
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+
+	return quoteString(fmt.Sprintf("%v", v))
+}
+
+// quoteString quotes a string as a HCL template, interpolation sequences are not escaped
+func quoteString(s string) string {
+	sb := strings.Builder{}
+	sb.WriteString(`"`)
+
+	for _, r := range s {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&sb, `\u%04x`, r)
+				continue
+			}
+
+			sb.WriteRune(r)
+		}
+	}
+
+	sb.WriteString(`"`)
+
+	return sb.String()
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, dir, name, contents string) string {
+	f := filepath.Join(dir, name)
+
+	err := ioutil.WriteFile(f, []byte(contents), os.ModePerm)
+	assert.NoError(t, err)
+
+	return f
+}
+
+func TestParsesYAMLFile(t *testing.T) {
+	f := writeConfigFile(t, t.TempDir(), "container.yaml", containerYAML)
+
+	c := New()
+	err := ParseSingleFile(f, c, nil, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	cc := r.(*Container)
+	assert.Equal(t, "consul:1.10.1", cc.Image.Name)
+	assert.Equal(t, []string{"consul", "agent", "-dev"}, cc.Command)
+	assert.Equal(t, map[string]string{"DC": "dc1"}, cc.EnvVar)
+	assert.Len(t, cc.Networks, 1)
+	assert.Equal(t, "network.cloud", cc.Networks[0].Name)
+	assert.Len(t, cc.Ports, 2)
+	assert.Equal(t, "8500", cc.Ports[0].Host)
+	assert.Equal(t, "512", cc.Resources.Memory)
+}
+
+func TestParsesJSONFile(t *testing.T) {
+	f := writeConfigFile(t, t.TempDir(), "network.json", networkJSON)
+
+	c := New()
+	err := ParseSingleFile(f, c, nil, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("network.cloud")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.5.0.0/16", r.(*Network).Subnet)
+}
+
+func TestParsesYAMLWithVariablesAndInterpolation(t *testing.T) {
+	f := writeConfigFile(t, t.TempDir(), "container.yaml", containerYAMLVariables)
+
+	c := New()
+	err := ParseSingleFile(f, c, map[string]string{"consul_version": "1.9.0"}, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+	assert.Equal(t, "consul:1.9.0", r.(*Container).Image.Name)
+	assert.Equal(t, map[string]string{"DC": "dc2"}, r.(*Container).EnvVar)
+}
+
+func TestParseFolderMixesFormatsAndIgnoresOtherYAML(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "network.json", networkJSON)
+	writeConfigFile(t, dir, "container.yml", containerYAML)
+	writeConfigFile(t, dir, "deployment.yaml", k8sManifestYAML)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	_, err = c.FindResource("network.cloud")
+	assert.NoError(t, err)
+
+	_, err = c.FindResource("container.consul")
+	assert.NoError(t, err)
+}
+
+func TestParseYAMLWithUnknownAttributeReturnsError(t *testing.T) {
+	f := writeConfigFile(t, t.TempDir(), "container.yaml", containerYAMLInvalid)
+
+	c := New()
+	err := ParseSingleFile(f, c, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "imag")
+}
+
+func TestParseYAMLWithInvalidStructureReturnsError(t *testing.T) {
+	f := writeConfigFile(t, t.TempDir(), "container.yaml", "container: consul")
+
+	c := New()
+	err := ParseSingleFile(f, c, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "'container' must be an object")
+}
+
+func TestQuoteStringKeepsInterpolation(t *testing.T) {
+	assert.Equal(t, `"a \"${var.b}\"\n"`, quoteString("a \"${var.b}\"\n"))
+}
+
+const containerYAML = `
+container:
+  consul:
+    image:
+      name: consul:1.10.1
+    command: ["consul", "agent", "-dev"]
+    env_var:
+      DC: dc1
+    network:
+      name: network.cloud
+    port:
+    - local: 8500
+      remote: 8500
+      host: 8500
+    - local: 8600
+      remote: 8600
+      host: 8600
+      protocol: udp
+    resources:
+      memory: 512
+`
+
+const networkJSON = `
+{
+  "network": {
+    "cloud": {
+      "subnet": "10.5.0.0/16"
+    }
+  }
+}
+`
+
+const containerYAMLVariables = `
+variable:
+  consul_version:
+    default: "1.10.1"
+  dc:
+    default: dc2
+
+locals:
+  image: "consul:${var.consul_version}"
+
+container:
+  consul:
+    image:
+      name: ${local.image}
+    env_var:
+      DC: ${var.dc}
+`
+
+const containerYAMLInvalid = `
+container:
+  consul:
+    imag:
+      name: consul:1.10.1
+`
+
+const k8sManifestYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`
@@ -97,6 +97,41 @@ func TestAddResourceExistsReturnsError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestAddResourceSetsStableResourceID(t *testing.T) {
+	c := testSetupModuleConfig(t)
+
+	assert.Equal(t, ResourceID("test", TypeNetwork, "cloud"), c.Resources[0].Info().ResourceID)
+	assert.Equal(t, ResourceID("", TypeK8sCluster, "test.dev"), c.Resources[1].Info().ResourceID)
+	assert.Len(t, c.Resources[0].Info().ResourceID, 16)
+}
+
+func TestResourceIDIsUniqueForModuleTypeAndName(t *testing.T) {
+	id := ResourceID("", TypeContainer, "consul")
+
+	assert.Equal(t, id, ResourceID("", TypeContainer, "consul"))
+	assert.NotEqual(t, id, ResourceID("consul", TypeContainer, "consul"))
+	assert.NotEqual(t, id, ResourceID("", TypeSidecar, "consul"))
+	assert.NotEqual(t, id, ResourceID("", TypeContainer, "vault"))
+}
+
+func TestSortOrdersResourcesByModuleTypeAndName(t *testing.T) {
+	c := New()
+	c.AddResource(NewNetwork("wan"))
+	c.AddResource(NewContainer("vault"))
+	c.AddResource(NewContainer("consul"))
+
+	mc := NewContainer("app")
+	mc.Module = "web"
+	c.AddResource(mc)
+
+	c.Sort()
+
+	assert.Equal(t, "consul", c.Resources[0].Info().Name)
+	assert.Equal(t, "vault", c.Resources[1].Info().Name)
+	assert.Equal(t, "wan", c.Resources[2].Info().Name)
+	assert.Equal(t, "app", c.Resources[3].Info().Name)
+}
+
 func TestRemoveResourceRemoves(t *testing.T) {
 	c := testSetupConfig(t)
 
@@ -167,3 +202,86 @@ func TestDoYaLikeDAGWithUnresolvedDependencyReturnsError(t *testing.T) {
 	_, err := c.DoYaLikeDAGs()
 	assert.Error(t, err)
 }
+
+func TestResourceDisabledWithExpression(t *testing.T) {
+	dir := CreateTestFiles(t, conditionalResources)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, map[string]string{"skip_monitoring": "true"}, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.prometheus")
+	assert.NoError(t, err)
+	assert.True(t, r.Info().Disabled)
+	assert.Equal(t, Disabled, r.Info().Status)
+}
+
+func TestResourceDisabledWhenConditionIsFalse(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, conditionalResources)
+
+	r, err := c.FindResource("container.prometheus")
+	assert.NoError(t, err)
+	assert.False(t, r.Info().Disabled)
+
+	r, err = c.FindResource("container.grafana")
+	assert.NoError(t, err)
+	assert.True(t, r.Info().Disabled)
+	assert.Equal(t, Disabled, r.Info().Status)
+}
+
+func TestResourceEnabledWhenConditionIsTrue(t *testing.T) {
+	dir := CreateTestFiles(t, conditionalResources)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, map[string]string{"profile": "full"}, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.grafana")
+	assert.NoError(t, err)
+	assert.False(t, r.Info().Disabled)
+	assert.Equal(t, PendingCreation, r.Info().Status)
+}
+
+func TestResourceWithInvalidConditionReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, conditionalInvalid)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+}
+
+const conditionalResources = `
+variable "skip_monitoring" {
+  default = false
+}
+
+variable "profile" {
+  default = "minimal"
+}
+
+container "prometheus" {
+  disabled = var.skip_monitoring
+
+  image {
+    name = "prom/prometheus:latest"
+  }
+}
+
+container "grafana" {
+  when = var.profile == "full"
+
+  image {
+    name = "grafana/grafana:latest"
+  }
+}
+`
+
+const conditionalInvalid = `
+container "grafana" {
+  when = "sometimes"
+
+  image {
+    name = "grafana/grafana:latest"
+  }
+}
+`
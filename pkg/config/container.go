@@ -1,5 +1,12 @@
 package config
 
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
 // TypeContainer is the resource string for a Container resource
 const TypeContainer ResourceType = "container"
 
@@ -22,18 +29,90 @@ type Container struct {
 	Ports       []Port            `hcl:"port,block" json:"ports,omitempty"`                                        // ports to expose
 	PortRanges  []PortRange       `hcl:"port_range,block" json:"port_ranges,omitempty" mapstructure:"port_ranges"` // range of ports to expose
 
+	// EnvPassthrough is a list of host environment variables which are copied to the
+	// container when it is created e.g. ["AWS_*", "GITHUB_TOKEN"]
+	EnvPassthrough []string `hcl:"env_passthrough,optional" json:"env_passthrough,omitempty" mapstructure:"env_passthrough"`
+
 	Privileged bool `hcl:"privileged,optional" json:"privileged,omitempty"` // run the container in privileged mode?
 
+	Ulimits []Ulimit          `hcl:"ulimit,block" json:"ulimits,omitempty"`     // ulimits to set for the container e.g. nofile, nproc
+	Sysctls map[string]string `hcl:"sysctls,optional" json:"sysctls,omitempty"` // namespaced kernel parameters to set for the container e.g. vm.max_map_count
+
+	DNS        []string          `hcl:"dns,optional" json:"dns,omitempty"`                                            // DNS servers used by the container e.g. 1.1.1.1
+	DNSSearch  []string          `hcl:"dns_search,optional" json:"dns_search,omitempty" mapstructure:"dns_search"`    // DNS search domains used by the container
+	ExtraHosts map[string]string `hcl:"extra_hosts,optional" json:"extra_hosts,omitempty" mapstructure:"extra_hosts"` // entries added to /etc/hosts e.g. { "db.local" = "10.5.0.2" }, host-gateway is the IP of the host
+
 	// resource constraints
 	Resources *Resources `hcl:"resources,block" json:"resources,omitempty"` // resource constraints for the container
 
 	// health checks for the container
 	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty" mapstructure:"health_check"`
 
-	MaxRestartCount int `hcl:"max_restart_count,optional" json:"max_restart_count,omitempty" mapstructure:"max_restart_count"`
+	MaxRestartCount int    `hcl:"max_restart_count,optional" json:"max_restart_count,omitempty" mapstructure:"max_restart_count"`
+	Restart         string `hcl:"restart,optional" json:"restart,omitempty"` // restart policy for the container [no, always, on-failure, unless-stopped]
+
+	CapAdd      []string `hcl:"cap_add,optional" json:"cap_add,omitempty" mapstructure:"cap_add"`                // linux capabilities to add to the container e.g. NET_ADMIN
+	CapDrop     []string `hcl:"cap_drop,optional" json:"cap_drop,omitempty" mapstructure:"cap_drop"`             // linux capabilities to drop from the container
+	SecurityOpt []string `hcl:"security_opt,optional" json:"security_opt,omitempty" mapstructure:"security_opt"` // security options e.g. seccomp=./profile.json, apparmor=unconfined
+	Devices     []Device `hcl:"device,block" json:"devices,omitempty"`                                           // host devices to add to the container
 
 	// User block for mapping the user id and group id inside the container
 	RunAs *User `hcl:"run_as,block" json:"run_as,omitempty" mapstructure:"run_as"`
+
+	// one-shot containers which must complete before the container is started
+	InitContainers []InitContainer `hcl:"init_container,block" json:"init_containers,omitempty" mapstructure:"init_containers"`
+
+	// sidecars defined in the container are created as sidecar resources, the
+	// sidecars are stored in the state as separate resources
+	Sidecars []ContainerSidecar `hcl:"sidecar,block" json:"-" mapstructure:"-"`
+
+	// commit the container to an image on destroy and create the container from the image on the next run
+	PersistOnDestroy bool `hcl:"persist_on_destroy,optional" json:"persist_on_destroy,omitempty" mapstructure:"persist_on_destroy"`
+
+	// alternate variants of the container selected using the capabilities of the host
+	Fallbacks []Fallback `hcl:"fallback,block" json:"fallbacks,omitempty"`
+
+	// Timeout is the maximum time to pull the image e.g. 10m, Retry is the policy
+	// for retrying a failed pull, both default to the values in the user config
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
+	Retry   *Retry `hcl:"retry,block" json:"retry,omitempty"`
+
+	// SelectedFallback is the condition of the fallback which was applied to the container
+	SelectedFallback string `json:"selected_fallback,omitempty" mapstructure:"selected_fallback"`
+
+	// ShareableIPC is set when a sidecar joins the IPC namespace of the container
+	ShareableIPC bool `json:"shareable_ipc,omitempty" mapstructure:"shareable_ipc"`
+
+	// SharePID and ShareIPC are set for the containers created for sidecars
+	SharePID bool `json:"share_pid,omitempty" mapstructure:"share_pid"`
+	ShareIPC bool `json:"share_ipc,omitempty" mapstructure:"share_ipc"`
+}
+
+// ContainerSidecar is a sidecar defined in a container, it is created as the
+// resource sidecar.[container]-[name] which targets the container
+type ContainerSidecar struct {
+	Name string `hcl:"name,label" json:"name"`
+
+	Sidecar `hcl:",remain"`
+}
+
+// SidecarName returns the name of the sidecar resource created for a sidecar
+// defined in the container
+func (c *Container) SidecarName(name string) string {
+	return fmt.Sprintf("%s-%s", c.Name, name)
+}
+
+// InitContainer defines a container which runs to completion before the main
+// container is started, such as database migrations or config renderers.
+// Init containers are attached to the same networks and volumes as the main container.
+type InitContainer struct {
+	Name       string            `hcl:"name" json:"name"`                                                 // name of the init container, must be unique for the container
+	Image      Image             `hcl:"image,block" json:"image"`                                         // image to use for the init container
+	Entrypoint []string          `hcl:"entrypoint,optional" json:"entrypoint,omitempty"`                  // entrypoint to use when starting the init container
+	Command    []string          `hcl:"command,optional" json:"command,omitempty"`                        // command to run
+	EnvVar     map[string]string `hcl:"env_var,optional" json:"env_var,omitempty" mapstructure:"env_var"` // environment variables, merged with the main container environment
+	Volumes    []Volume          `hcl:"volume,block" json:"volumes,omitempty"`                            // additional volumes to attach to the init container
+	Timeout    string            `hcl:"timeout,optional" json:"timeout,omitempty"`                        // maximum time to wait for the init container to complete, default 300s
 }
 
 type User struct {
@@ -48,6 +127,12 @@ func NewContainer(name string) *Container {
 	return &Container{ResourceInfo: ResourceInfo{Name: name, Type: TypeContainer, Status: PendingCreation}}
 }
 
+// PersistedImage returns the name of the image in the local cache the container
+// is committed to when PersistOnDestroy is set
+func (c *Container) PersistedImage() string {
+	return fmt.Sprintf("shipyard.run/localcache/%s-persisted:latest", c.Name)
+}
+
 type NetworkAttachment struct {
 	Name      string   `hcl:"name" json:"name"`
 	IPAddress string   `hcl:"ip_address,optional" json:"ip_address,omitempty" mapstructure:"ip_address"`
@@ -56,18 +141,111 @@ type NetworkAttachment struct {
 
 // Resources allows the setting of resource constraints for the Container
 type Resources struct {
-	CPU    int   `hcl:"cpu,optional" json:"cpu,omitempty"`                                // cpu limit for the container where 1 CPU = 1000
-	CPUPin []int `hcl:"cpu_pin,optional" json:"cpu_pin,omitempty" mapstructure:"cpu_pin"` // pin the container to one or more cpu cores
-	Memory int   `hcl:"memory,optional" json:"memory,omitempty"`                          // max memory the container can consume in MB
+	CPU    string `hcl:"cpu,optional" json:"cpu,omitempty"`                                // cpu limit for the container e.g. 500m, values without a unit are millicpu where 1 CPU = 1000
+	CPUPin []int  `hcl:"cpu_pin,optional" json:"cpu_pin,omitempty" mapstructure:"cpu_pin"` // pin the container to one or more cpu cores
+	Memory string `hcl:"memory,optional" json:"memory,omitempty"`                          // max memory the container can consume e.g. 512Mi, 1Gi, values without a unit are MB
+	GPU    *GPU   `hcl:"gpu,block" json:"gpu,omitempty"`                                   // pass host GPUs through to the container
+}
+
+// GPU defines the host GPUs which are made available to the container
+type GPU struct {
+	Driver    string   `hcl:"driver,optional" json:"driver,omitempty"`                                   // device driver for the GPUs, defaults to nvidia
+	DeviceIDs []string `hcl:"device_ids,optional" json:"device_ids,omitempty" mapstructure:"device_ids"` // ids of the GPUs to add to the container, all GPUs are added when empty
+}
+
+// MilliCPU returns the cpu limit in millicpu, 0 is returned when no limit is set
+func (r *Resources) MilliCPU() (int64, error) {
+	if r.CPU == "" {
+		return 0, nil
+	}
+
+	v := strings.TrimSuffix(r.CPU, "m")
+
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid cpu '%s', cpu must be specified in millicpu e.g. 500m", r.CPU)
+	}
+
+	return i, nil
+}
+
+// memoryUnits are the multipliers for the suffixes allowed for memory
+var memoryUnits = map[string]int64{
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"K":  1000,
+	"M":  1000 * 1000,
+	"G":  1000 * 1000 * 1000,
+}
+
+// MemoryBytes returns the memory limit in bytes, 0 is returned when no limit is set
+func (r *Resources) MemoryBytes() (int64, error) {
+	if r.Memory == "" {
+		return 0, nil
+	}
+
+	v := r.Memory
+
+	// values without a unit are megabytes
+	mul := memoryUnits["M"]
+	for u, m := range memoryUnits {
+		if strings.HasSuffix(v, u) {
+			v = strings.TrimSuffix(v, u)
+			mul = m
+			break
+		}
+	}
+
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid memory '%s', memory must be specified with a unit e.g. 512Mi, 1Gi", r.Memory)
+	}
+
+	return i * mul, nil
+}
+
+// Validate the resources
+func (r *Resources) Validate() error {
+	_, err := r.MilliCPU()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.MemoryBytes()
+	if err != nil {
+		return err
+	}
+
+	if r.GPU != nil && r.GPU.Driver != "" && r.GPU.Driver != "nvidia" {
+		return fmt.Errorf("invalid gpu driver '%s', only nvidia GPUs are supported", r.GPU.Driver)
+	}
+
+	return nil
+}
+
+// Ulimit defines a resource limit for processes running in the container
+type Ulimit struct {
+	Name string `hcl:"name" json:"name"` // name of the limit e.g. nofile, nproc, memlock
+	Soft int64  `hcl:"soft" json:"soft"` // soft limit
+	Hard int64  `hcl:"hard" json:"hard"` // hard limit
+}
+
+// Device defines a host device which is added to the container
+type Device struct {
+	Source      string `hcl:"source" json:"source"`                              // path of the device on the host e.g. /dev/net/tun
+	Destination string `hcl:"destination,optional" json:"destination,omitempty"` // path of the device in the container, defaults to the source
+	Permissions string `hcl:"permissions,optional" json:"permissions,omitempty"` // cgroup permissions for the device, defaults to rwm
 }
 
 // Volume defines a folder, Docker volume, or temp folder to mount to the Container
 type Volume struct {
-	Source                      string `hcl:"source" json:"source"`                                                                                                                  // source path on the local machine for the volume
+	Source                      string `hcl:"source,optional" json:"source,omitempty"`                                                                                               // source path on the local machine or name of the Docker volume, not used for tmpfs
 	Destination                 string `hcl:"destination" json:"destination"`                                                                                                        // path to mount the volume inside the container
 	Type                        string `hcl:"type,optional" json:"type,omitempty"`                                                                                                   // type of the volume to mount [bind, volume, tmpfs]
 	ReadOnly                    bool   `hcl:"read_only,optional" json:"read_only,omitempty" mapstructure:"read_only"`                                                                // specify that the volume is mounted read only
-	BindPropagation             string `hcl:"bind_propagation,optional" json:"bind_propagation,omitempty" mapstructure:"bind_propagation"`                                           // propagation mode for bind mounts [shared, private, slave, rslave, rprivate]
+	External                    bool   `hcl:"external,optional" json:"external,omitempty"`                                                                                           // the Docker volume already exists and is not managed by Shipyard, only valid for type volume
+	BindPropagation             string `hcl:"bind_propagation,optional" json:"bind_propagation,omitempty" mapstructure:"bind_propagation"`                                           // propagation mode for bind mounts [shared, rshared, private, rprivate, slave, rslave]
 	BindPropagationNonRecursive bool   `hcl:"bind_propagation_non_recursive,optional" json:"bind_propagation_non_recursive,omitempty" mapstructure:"bind_propagation_non_recursive"` // recursive bind mount, default true
 }
 
@@ -83,9 +261,212 @@ type Build struct {
 	File    string `hcl:"file,optional" json:"file,omitempty"` // Location of build file inside build context defaults to ./Dockerfile
 	Context string `hcl:"context" json:"context"`              // Path to build context
 	Tag     string `hcl:"tag,optional" json:"tag,omitempty"`   // Image tag, defaults to latest
+
+	Args map[string]string `hcl:"args,optional" json:"args,omitempty"` // Build arguments to pass to the Dockerfile
+
+	Target      string        `hcl:"target,optional" json:"target,omitempty"`                                         // Build stage to target in a multi-stage Dockerfile
+	CacheFrom   []string      `hcl:"cache_from,optional" json:"cache_from,omitempty" mapstructure:"cache_from"`       // Images to use as a cache source
+	InlineCache bool          `hcl:"inline_cache,optional" json:"inline_cache,omitempty" mapstructure:"inline_cache"` // Write cache metadata into the image so it can be used with cache_from
+	BuildKit    bool          `hcl:"buildkit,optional" json:"buildkit,omitempty"`                                     // Build the image using BuildKit, enabled automatically when secrets, ssh, or inline_cache are set
+	Secrets     []BuildSecret `hcl:"secret,block" json:"secrets,omitempty"`                                           // Secrets exposed to RUN --mount=type=secret instructions, requires BuildKit
+	SSH         []string      `hcl:"ssh,optional" json:"ssh,omitempty"`                                               // SSH agent sockets or keys exposed to RUN --mount=type=ssh instructions, requires BuildKit
+}
+
+// BuildSecret defines a secret which is exposed to the build without
+// being stored in the image layers
+type BuildSecret struct {
+	ID          string `hcl:"id" json:"id"`                                                                 // ID of the secret referenced in the Dockerfile
+	File        string `hcl:"file,optional" json:"file,omitempty"`                                          // Path to a file containing the secret
+	Environment string `hcl:"environment,optional" json:"environment,omitempty" mapstructure:"environment"` // Environment variable containing the secret
+}
+
+// UsesBuildKit returns true when the build requires BuildKit
+func (b *Build) UsesBuildKit() bool {
+	return b.BuildKit || b.InlineCache || len(b.Secrets) > 0 || len(b.SSH) > 0
 }
 
 // Validate the config
 func (c *Container) Validate() error {
+	if c.Resources != nil {
+		err := c.Resources.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := validateVolumes(c.Volumes)
+	if err != nil {
+		return err
+	}
+
+	err = validatePorts(c.Ports, c.PortRanges)
+	if err != nil {
+		return err
+	}
+
+	err = validateNetworkAttachments(c.Networks)
+	if err != nil {
+		return err
+	}
+
+	if c.Image != nil {
+		err := c.Image.Validate()
+		if err != nil {
+			return err
+		}
+
+		if c.Image.IsWindows() && c.Privileged {
+			return fmt.Errorf("container %s uses a Windows image, Windows containers can not be privileged", c.Name)
+		}
+	}
+
+	for _, ic := range c.InitContainers {
+		err := validateVolumes(ic.Volumes)
+		if err != nil {
+			return err
+		}
+
+		err = ic.Image.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = validateFallbacks(c.Fallbacks)
+	if err != nil {
+		return err
+	}
+
+	err = validateDNS(c.DNS, c.ExtraHosts)
+	if err != nil {
+		return err
+	}
+
+	err = validateTimeoutAndRetry(c.Timeout, c.Retry)
+	if err != nil {
+		return err
+	}
+
+	return validateRestartPolicy(c.Restart)
+}
+
+// ApplyFallback replaces the values of the container with the first fallback which
+// matches the capabilities of the host, a fallback image replaces any build
+func (c *Container) ApplyFallback() {
+	f := selectFallback(c.Fallbacks)
+	if f == nil {
+		return
+	}
+
+	if f.Image != "" {
+		if c.Image == nil {
+			c.Image = &Image{}
+			c.Build = nil
+		}
+
+		c.Image.Name = f.Image
+	}
+
+	applyFallback(f, &c.Command, &c.EnvVar, &c.Resources)
+	c.SelectedFallback = f.When
+}
+
+// Validate the volume
+func (v *Volume) Validate() error {
+	switch v.Type {
+	case "", "bind", "volume":
+		if v.Source == "" {
+			return fmt.Errorf("volume '%s' requires a source", v.Destination)
+		}
+	case "tmpfs":
+		if v.Source != "" {
+			return fmt.Errorf("volume '%s' has type tmpfs, source can not be set for tmpfs volumes", v.Destination)
+		}
+	default:
+		return fmt.Errorf("volume '%s' has invalid type '%s', valid options are bind, volume, tmpfs", v.Destination, v.Type)
+	}
+
+	if v.External && v.Type != "volume" {
+		return fmt.Errorf("volume '%s' is external, external is only valid for volumes with type volume", v.Destination)
+	}
+
+	switch v.BindPropagation {
+	case "", "shared", "rshared", "private", "rprivate", "slave", "rslave":
+	default:
+		return fmt.Errorf("volume '%s' has invalid bind_propagation '%s', valid options are shared, rshared, private, rprivate, slave, rslave", v.Destination, v.BindPropagation)
+	}
+
+	if (v.BindPropagation != "" || v.BindPropagationNonRecursive) && v.Type != "" && v.Type != "bind" {
+		return fmt.Errorf("volume '%s' has type %s, bind propagation options are only valid for bind mounts", v.Destination, v.Type)
+	}
+
+	return nil
+}
+
+// validateNetworkAttachments ensures a resource is only attached to a network once, and that
+// resources sharing the network of another container are not attached to any other networks
+func validateNetworkAttachments(nets []NetworkAttachment) error {
+	seen := map[string]bool{}
+
+	for _, n := range nets {
+		if seen[n.Name] {
+			return fmt.Errorf("network '%s' is defined more than once, each network can only be attached once", n.Name)
+		}
+
+		seen[n.Name] = true
+
+		if strings.HasPrefix(n.Name, string(TypeContainer)+".") && len(nets) > 1 {
+			return fmt.Errorf("network '%s' shares the network of another container and can not be combined with other networks", n.Name)
+		}
+	}
+
+	return nil
+}
+
+func validateVolumes(vols []Volume) error {
+	for _, v := range vols {
+		err := v.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateRestartPolicy(p string) error {
+	switch p {
+	case "", "no", "always", "on-failure", "unless-stopped":
+		return nil
+	}
+
+	return fmt.Errorf("invalid restart policy '%s', valid options are no, always, on-failure, unless-stopped", p)
+}
+
+// absoluteSecurityOpts ensures that the path to any seccomp profiles is absolute
+func absoluteSecurityOpts(opts []string, file string) []string {
+	for i, o := range opts {
+		if strings.HasPrefix(o, "seccomp=") && o != "seccomp=unconfined" {
+			opts[i] = "seccomp=" + ensureAbsolute(strings.TrimPrefix(o, "seccomp="), file)
+		}
+	}
+
+	return opts
+}
+
+// validateDNS checks the DNS servers and the addresses of the extra hosts are IP addresses
+func validateDNS(servers []string, hosts map[string]string) error {
+	for _, s := range servers {
+		if net.ParseIP(s) == nil {
+			return fmt.Errorf("dns server must be an IP address, got '%s'", s)
+		}
+	}
+
+	for h, ip := range hosts {
+		if ip != "host-gateway" && net.ParseIP(ip) == nil {
+			return fmt.Errorf("extra_hosts address for '%s' must be an IP address or host-gateway, got '%s'", h, ip)
+		}
+	}
+
 	return nil
 }
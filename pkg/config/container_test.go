@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -34,6 +36,336 @@ func TestContainerSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, co.Info().Status)
 }
 
+func TestContainerParsesUlimitsAndSysctls(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerUlimits)
+
+	co, err := c.FindResource("container.elastic")
+	assert.NoError(t, err)
+
+	cc := co.(*Container)
+	assert.Equal(t, "nofile", cc.Ulimits[0].Name)
+	assert.Equal(t, int64(65535), cc.Ulimits[0].Soft)
+	assert.Equal(t, int64(65535), cc.Ulimits[0].Hard)
+	assert.Equal(t, "262144", cc.Sysctls["vm.max_map_count"])
+}
+
+func TestContainerParsesDNS(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerDNS)
+
+	co, err := c.FindResource("container.app")
+	assert.NoError(t, err)
+
+	cc := co.(*Container)
+	assert.Equal(t, []string{"1.1.1.1", "8.8.8.8"}, cc.DNS)
+	assert.Equal(t, []string{"container.shipyard.run"}, cc.DNSSearch)
+	assert.Equal(t, "10.5.0.2", cc.ExtraHosts["db.local"])
+	assert.Equal(t, "host-gateway", cc.ExtraHosts["host.local"])
+}
+
+func TestContainerWithInvalidDNSReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, containerInvalidDNS)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "extra_hosts address for 'db.local'")
+}
+
+func TestContainerParsesBuildKitOptions(t *testing.T) {
+	c, base := CreateConfigFromStrings(t, containerBuildKit)
+
+	co, err := c.FindResource("container.app")
+	assert.NoError(t, err)
+
+	cc := co.(*Container)
+	assert.Equal(t, "release", cc.Build.Target)
+	assert.Equal(t, []string{"app:cache"}, cc.Build.CacheFrom)
+	assert.True(t, cc.Build.InlineCache)
+	assert.Equal(t, []string{"default"}, cc.Build.SSH)
+	assert.Equal(t, "npmrc", cc.Build.Secrets[0].ID)
+	assert.Equal(t, filepath.Join(base, ".npmrc"), cc.Build.Secrets[0].File)
+	assert.Equal(t, "GITHUB_TOKEN", cc.Build.Secrets[1].Environment)
+	assert.True(t, cc.Build.UsesBuildKit())
+}
+
+func TestContainerParsesInitContainers(t *testing.T) {
+	c, base := CreateConfigFromStrings(t, containerInit)
+
+	co, err := c.FindResource("container.app")
+	assert.NoError(t, err)
+
+	cc := co.(*Container)
+	assert.Len(t, cc.InitContainers, 2)
+
+	assert.Equal(t, "migrate", cc.InitContainers[0].Name)
+	assert.Equal(t, "migrate/migrate:v4.15.1", cc.InitContainers[0].Image.Name)
+	assert.Equal(t, []string{"up"}, cc.InitContainers[0].Command)
+	assert.Equal(t, "60s", cc.InitContainers[0].Timeout)
+	assert.Equal(t, filepath.Join(base, "migrations"), cc.InitContainers[0].Volumes[0].Source)
+
+	assert.Equal(t, "render", cc.InitContainers[1].Name)
+	assert.Equal(t, "true", cc.InitContainers[1].EnvVar["RENDER"])
+}
+
+func TestContainerParsesSecurityOptions(t *testing.T) {
+	c, base := CreateConfigFromStrings(t, containerSecurity)
+
+	co, err := c.FindResource("container.vpn")
+	assert.NoError(t, err)
+
+	cc := co.(*Container)
+	assert.Equal(t, "unless-stopped", cc.Restart)
+	assert.Equal(t, []string{"NET_ADMIN"}, cc.CapAdd)
+	assert.Equal(t, []string{"MKNOD"}, cc.CapDrop)
+	assert.Equal(t, "seccomp="+filepath.Join(base, "profile.json"), cc.SecurityOpt[0])
+	assert.Equal(t, "apparmor=unconfined", cc.SecurityOpt[1])
+	assert.Equal(t, "/dev/net/tun", cc.Devices[0].Source)
+	assert.Equal(t, "rw", cc.Devices[0].Permissions)
+}
+
+func TestResourcesConvertsUnits(t *testing.T) {
+	tests := []struct {
+		cpu      string
+		memory   string
+		milliCPU int64
+		bytes    int64
+	}{
+		{"", "", 0, 0},
+		{"1000", "1000", 1000, 1000 * 1000 * 1000},
+		{"500m", "512Mi", 500, 512 * 1024 * 1024},
+		{"2000m", "2Gi", 2000, 2 * 1024 * 1024 * 1024},
+		{"250m", "100M", 250, 100 * 1000 * 1000},
+		{"1m", "64Ki", 1, 64 * 1024},
+	}
+
+	for _, tc := range tests {
+		r := &Resources{CPU: tc.cpu, Memory: tc.memory}
+
+		cpu, err := r.MilliCPU()
+		assert.NoError(t, err)
+		assert.Equal(t, tc.milliCPU, cpu)
+
+		mem, err := r.MemoryBytes()
+		assert.NoError(t, err)
+		assert.Equal(t, tc.bytes, mem)
+	}
+}
+
+func TestResourcesReturnsErrorForInvalidUnits(t *testing.T) {
+	assert.Error(t, (&Resources{CPU: "1.5"}).Validate())
+	assert.Error(t, (&Resources{CPU: "abc"}).Validate())
+	assert.Error(t, (&Resources{Memory: "1Ti"}).Validate())
+	assert.Error(t, (&Resources{Memory: "-1Mi"}).Validate())
+}
+
+func TestContainerParsesGPU(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerGPU)
+
+	cl, err := c.FindResource("container.ml")
+	assert.NoError(t, err)
+
+	cc := cl.(*Container)
+	assert.Equal(t, "nvidia", cc.Resources.GPU.Driver)
+	assert.Equal(t, []string{"0"}, cc.Resources.GPU.DeviceIDs)
+}
+
+func TestResourcesReturnsErrorForUnsupportedGPUDriver(t *testing.T) {
+	assert.Error(t, (&Resources{GPU: &GPU{Driver: "amd"}}).Validate())
+}
+
+func TestContainerWithInvalidRestartPolicyReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, containerInvalidRestart)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid restart policy")
+}
+
+func TestContainerParsesPortBind(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerPortBind)
+
+	cl, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	cc := cl.(*Container)
+	assert.Equal(t, "127.0.0.1", cc.Ports[0].BindAddress())
+	assert.Equal(t, "10.0.0.1", cc.Ports[1].BindAddress())
+	assert.Equal(t, "127.0.0.1", cc.PortRanges[0].Bind)
+}
+
+func TestContainerWithInvalidPortBindReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, containerInvalidPortBind)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid bind address 'localhost'")
+}
+
+func TestContainerParsesVolumeTypes(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerVolumes)
+
+	cl, err := c.FindResource("container.vault")
+	assert.NoError(t, err)
+
+	cc := cl.(*Container)
+	assert.Len(t, cc.Volumes, 3)
+
+	assert.True(t, filepath.IsAbs(cc.Volumes[0].Source))
+	assert.True(t, cc.Volumes[0].ReadOnly)
+
+	assert.Equal(t, "tmpfs", cc.Volumes[1].Type)
+	assert.Empty(t, cc.Volumes[1].Source)
+
+	assert.Equal(t, "volume", cc.Volumes[2].Type)
+	assert.Equal(t, "vault-data", cc.Volumes[2].Source)
+	assert.True(t, cc.Volumes[2].External)
+}
+
+func TestVolumeValidateReturnsErrorForInvalidConfig(t *testing.T) {
+	assert.Error(t, (&Volume{Source: "/tmp", Destination: "/data", Type: "nfs"}).Validate())
+	assert.Error(t, (&Volume{Destination: "/data"}).Validate())
+	assert.Error(t, (&Volume{Source: "/tmp", Destination: "/data", Type: "tmpfs"}).Validate())
+	assert.Error(t, (&Volume{Source: "/tmp", Destination: "/data", External: true}).Validate())
+	assert.Error(t, (&Volume{Source: "/tmp", Destination: "/data", BindPropagation: "master"}).Validate())
+	assert.Error(t, (&Volume{Source: "data", Destination: "/data", Type: "volume", BindPropagation: "shared"}).Validate())
+	assert.Error(t, (&Volume{Destination: "/data", Type: "tmpfs", BindPropagationNonRecursive: true}).Validate())
+	assert.NoError(t, (&Volume{Source: "/tmp", Destination: "/data", Type: "bind", BindPropagation: "rshared", ReadOnly: true}).Validate())
+}
+
+func TestImageValidateReturnsErrorForInvalidPlatform(t *testing.T) {
+	assert.NoError(t, (&Image{Name: "consul"}).Validate())
+	assert.NoError(t, (&Image{Name: "consul", Platform: "linux/amd64"}).Validate())
+	assert.NoError(t, (&Image{Name: "consul", Platform: "linux/arm64/v8"}).Validate())
+	assert.NoError(t, (&Image{Name: "consul", Platform: "windows"}).Validate())
+	assert.NoError(t, (&Image{Name: "consul", Platform: "windows/amd64"}).Validate())
+	assert.Error(t, (&Image{Name: "consul", Platform: "amd64"}).Validate())
+	assert.Error(t, (&Image{Name: "consul", Platform: "linux/"}).Validate())
+	assert.Error(t, (&Image{Name: "consul", Platform: "linux/arm/v7/extra"}).Validate())
+}
+
+func TestContainerWithInvalidImagePlatformReturnsError(t *testing.T) {
+	cc := NewContainer("test")
+	cc.Image = &Image{Name: "consul", Platform: "arm64"}
+
+	assert.Error(t, cc.Validate())
+}
+
+func TestImageIsWindows(t *testing.T) {
+	assert.True(t, (&Image{Name: "iis", Platform: "windows"}).IsWindows())
+	assert.True(t, (&Image{Name: "iis", Platform: "windows/amd64"}).IsWindows())
+	assert.False(t, (&Image{Name: "consul", Platform: "linux/amd64"}).IsWindows())
+	assert.False(t, (&Image{Name: "consul"}).IsWindows())
+}
+
+func TestWindowsContainerWithPrivilegedReturnsError(t *testing.T) {
+	cc := NewContainer("test")
+	cc.Image = &Image{Name: "mcr.microsoft.com/windows/servercore/iis", Platform: "windows"}
+	cc.Privileged = true
+
+	err := cc.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Windows containers can not be privileged")
+}
+
+func TestContainerWithInvalidVolumeTypeReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, containerInvalidVolume)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid type 'nfs'")
+}
+
+func TestContainerParsesMultipleNetworks(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerMultipleNetworks)
+
+	cl, err := c.FindResource("container.proxy")
+	assert.NoError(t, err)
+
+	cc := cl.(*Container)
+	assert.Len(t, cc.Networks, 2)
+
+	assert.Equal(t, "network.frontend", cc.Networks[0].Name)
+	assert.Equal(t, "10.5.0.200", cc.Networks[0].IPAddress)
+	assert.Equal(t, []string{"proxy.frontend"}, cc.Networks[0].Aliases)
+
+	assert.Equal(t, "network.backend", cc.Networks[1].Name)
+	assert.Equal(t, []string{"proxy.backend"}, cc.Networks[1].Aliases)
+
+	assert.Contains(t, cc.DependsOn, "network.frontend")
+	assert.Contains(t, cc.DependsOn, "network.backend")
+}
+
+func TestContainerWithDuplicateNetworksReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, containerDuplicateNetworks)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "more than once")
+}
+
+func TestContainerWithContainerAndOtherNetworksReturnsError(t *testing.T) {
+	cc := &Container{
+		Networks: []NetworkAttachment{
+			{Name: "container.app"},
+			{Name: "network.backend"},
+		},
+	}
+
+	err := cc.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "can not be combined")
+}
+
+const containerUlimits = `
+container "elastic" {
+	image {
+		name = "elasticsearch:7.17.0"
+	}
+
+	ulimit {
+		name = "nofile"
+		soft = 65535
+		hard = 65535
+	}
+
+	sysctls = {
+		"vm.max_map_count" = "262144"
+	}
+}
+`
+
+const containerDNS = `
+container "app" {
+	image {
+		name = "alpine:latest"
+	}
+
+	dns        = ["1.1.1.1", "8.8.8.8"]
+	dns_search = ["container.shipyard.run"]
+
+	extra_hosts = {
+		"db.local"   = "10.5.0.2"
+		"host.local" = "host-gateway"
+	}
+}
+`
+
+const containerInvalidDNS = `
+container "app" {
+	image {
+		name = "alpine:latest"
+	}
+
+	extra_hosts = {
+		"db.local" = "database"
+	}
+}
+`
+
 const containerDefault = `
 network "test" {
 	subnet = "10.0.0.0/24"
@@ -65,3 +397,332 @@ container "testing" {
 	}
 }
 `
+
+func TestContainerWithCountCreatesMultipleResources(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerCount)
+
+	for i := 0; i < 3; i++ {
+		co, err := c.FindResource(fmt.Sprintf("container.worker-%d", i))
+		assert.NoError(t, err)
+
+		assert.Equal(t, fmt.Sprintf("worker-%d", i), co.(*Container).Command[0])
+		assert.Equal(t, fmt.Sprintf("%d", 8080+i), co.(*Container).Ports[0].Host)
+	}
+
+	_, err := c.FindResource("container.worker")
+	assert.Error(t, err)
+}
+
+func TestContainerWithForEachCreatesMultipleResources(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerForEach)
+
+	co, err := c.FindResource("container.worker-api")
+	assert.NoError(t, err)
+	assert.Equal(t, "9090", co.(*Container).EnvVar["PORT"])
+
+	co, err = c.FindResource("container.worker-web")
+	assert.NoError(t, err)
+	assert.Equal(t, "8080", co.(*Container).EnvVar["PORT"])
+}
+
+func TestContainerWithCountAndForEachReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, containerCountAndForEach)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+}
+
+const containerCount = `
+container "worker" {
+	count = 3
+
+	image {
+		name = "consul"
+	}
+
+	command = ["worker-${count.index}"]
+
+	port {
+		local  = 8080
+		remote = 8080
+		host   = 8080 + count.index
+	}
+}
+`
+
+const containerForEach = `
+container "worker" {
+	for_each = {
+		web = 8080
+		api = 9090
+	}
+
+	image {
+		name = "consul"
+	}
+
+	env_var = {
+		PORT = each.value
+	}
+}
+`
+
+const containerCountAndForEach = `
+container "worker" {
+	count    = 2
+	for_each = { web = 8080 }
+
+	image {
+		name = "consul"
+	}
+}
+`
+
+const containerBuildKit = `
+container "app" {
+	build {
+		context      = "./"
+		target       = "release"
+		cache_from   = ["app:cache"]
+		inline_cache = true
+		ssh          = ["default"]
+
+		secret {
+			id   = "npmrc"
+			file = "./.npmrc"
+		}
+
+		secret {
+			id          = "token"
+			environment = "GITHUB_TOKEN"
+		}
+	}
+}
+`
+
+const containerInit = `
+container "app" {
+	image {
+		name = "app:latest"
+	}
+
+	init_container {
+		name    = "migrate"
+		command = ["up"]
+		timeout = "60s"
+
+		image {
+			name = "migrate/migrate:v4.15.1"
+		}
+
+		volume {
+			source      = "./migrations"
+			destination = "/migrations"
+		}
+	}
+
+	init_container {
+		name = "render"
+
+		image {
+			name = "hashicorp/consul-template:0.27.2"
+		}
+
+		env_var = {
+			RENDER = "true"
+		}
+	}
+}
+`
+
+const containerSecurity = `
+container "vpn" {
+	image {
+		name = "wireguard:latest"
+	}
+
+	restart      = "unless-stopped"
+	cap_add      = ["NET_ADMIN"]
+	cap_drop     = ["MKNOD"]
+	security_opt = ["seccomp=./profile.json", "apparmor=unconfined"]
+
+	device {
+		source      = "/dev/net/tun"
+		permissions = "rw"
+	}
+}
+`
+
+const containerInvalidRestart = `
+container "vpn" {
+	image {
+		name = "wireguard:latest"
+	}
+
+	restart = "sometimes"
+}
+`
+
+const containerPortBind = `
+container "consul" {
+	image {
+		name = "consul:1.10.0"
+	}
+
+	port {
+		local  = 8500
+		remote = 8500
+		host   = 8500
+		bind   = "127.0.0.1"
+	}
+
+	port {
+		local   = 8600
+		remote  = 8600
+		host    = 8600
+		host_ip = "10.0.0.1"
+	}
+
+	port_range {
+		range       = "9000-9002"
+		enable_host = true
+		bind        = "127.0.0.1"
+	}
+}
+`
+
+const containerInvalidPortBind = `
+container "consul" {
+	image {
+		name = "consul:1.10.0"
+	}
+
+	port {
+		local  = 8500
+		remote = 8500
+		host   = 8500
+		bind   = "localhost"
+	}
+}
+`
+
+const containerGPU = `
+container "ml" {
+	image {
+		name = "tensorflow/tensorflow:latest-gpu"
+	}
+
+	resources {
+		memory = "4Gi"
+
+		gpu {
+			driver = "nvidia"
+			device_ids = ["0"]
+		}
+	}
+}
+`
+
+const containerVolumes = `
+container "vault" {
+	image {
+		name = "vault:1.11.0"
+	}
+
+	volume {
+		source      = "./certs"
+		destination = "/certs"
+		read_only   = true
+	}
+
+	volume {
+		type        = "tmpfs"
+		destination = "/secrets"
+	}
+
+	volume {
+		type        = "volume"
+		source      = "vault-data"
+		destination = "/vault/data"
+		external    = true
+	}
+}
+`
+
+const containerInvalidVolume = `
+container "vault" {
+	image {
+		name = "vault:1.11.0"
+	}
+
+	volume {
+		type        = "nfs"
+		source      = "./data"
+		destination = "/vault/data"
+	}
+}
+`
+
+const containerMultipleNetworks = `
+network "frontend" {
+	subnet = "10.5.0.0/16"
+}
+
+network "backend" {
+	subnet = "10.6.0.0/16"
+}
+
+container "proxy" {
+	image {
+		name = "envoyproxy/envoy:v1.22.0"
+	}
+
+	network {
+		name       = "network.frontend"
+		ip_address = "10.5.0.200"
+		aliases    = ["proxy.frontend"]
+	}
+
+	network {
+		name    = "network.backend"
+		aliases = ["proxy.backend"]
+	}
+}
+`
+
+const containerDuplicateNetworks = `
+network "backend" {
+	subnet = "10.6.0.0/16"
+}
+
+container "proxy" {
+	image {
+		name = "envoyproxy/envoy:v1.22.0"
+	}
+
+	network {
+		name = "network.backend"
+	}
+
+	network {
+		name = "network.backend"
+	}
+}
+`
+
+func TestHostPortsReturnsPortsWithHost(t *testing.T) {
+	c := NewContainer("test")
+	c.Ports = []Port{
+		{Local: "80", Remote: "80", Host: "8080"},
+		{Local: "443", Remote: "443"},
+	}
+
+	p := HostPorts(c)
+	assert.Len(t, p, 1)
+	assert.Equal(t, "8080", p[0].Host)
+
+	// ports are returned by reference so the host can be set
+	p[0].Host = "9090"
+	assert.Equal(t, "9090", c.Ports[0].Host)
+}
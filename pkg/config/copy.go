@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// TypeCopy is the resource string for a Copy resource
+const TypeCopy ResourceType = "copy"
+
+// Copy copies files and directories into a container or a Docker volume, unlike a
+// volume mount the files are added to the existing contents of the destination
+type Copy struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	// Source is a local file or directory, or a URL which is downloaded using go-getter
+	// e.g. github.com/org/repo//config. The contents of a directory are copied
+	Source string `hcl:"source" json:"source"`
+	// Destination is the directory in the container or volume, it is created when it does not exist
+	Destination string `hcl:"destination" json:"destination"`
+
+	// Target is the resource to copy the files to, container, sidecar, k8s_cluster, or nomad_cluster,
+	// files are copied to the server node of a cluster
+	Target string `hcl:"target,optional" json:"target,omitempty"`
+	// Volume is the name of a Docker volume to copy the files to
+	Volume string `hcl:"volume,optional" json:"volume,omitempty"`
+
+	// Permissions for the copied files in octal e.g. 0644, by default the permissions of the source are used
+	Permissions string `hcl:"permissions,optional" json:"permissions,omitempty"`
+
+	// Verify declares the checksum or signature a remote source must match before it is copied
+	Verify *Verify `hcl:"verify,block" json:"verify,omitempty"`
+}
+
+// NewCopy creates a Copy resource with the default values
+func NewCopy(name string) *Copy {
+	return &Copy{ResourceInfo: ResourceInfo{Name: name, Type: TypeCopy, Status: PendingCreation}}
+}
+
+// FileMode returns the permissions for the copied files, zero when
+// the permissions of the source files should be used
+func (c *Copy) FileMode() (os.FileMode, error) {
+	if c.Permissions == "" {
+		return 0, nil
+	}
+
+	m, err := strconv.ParseUint(c.Permissions, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("permissions must be an octal file mode e.g. 0644, got %s", c.Permissions)
+	}
+
+	return os.FileMode(m), nil
+}
+
+// Validate the Copy and return errors
+func (c *Copy) Validate() error {
+	if (c.Target == "") == (c.Volume == "") {
+		return fmt.Errorf("one of target or volume must be set")
+	}
+
+	if c.Target != "" {
+		valid := false
+		for _, t := range []ResourceType{TypeContainer, TypeSidecar, TypeK8sCluster, TypeNomadCluster} {
+			if strings.HasPrefix(c.Target, string(t)+".") {
+				valid = true
+			}
+		}
+
+		if !valid {
+			return fmt.Errorf("target must be a container, sidecar, k8s_cluster, or nomad_cluster, got %s", c.Target)
+		}
+	}
+
+	if !path.IsAbs(c.Destination) {
+		return fmt.Errorf("destination must be an absolute path, got %s", c.Destination)
+	}
+
+	_, err := c.FileMode()
+	if err != nil {
+		return err
+	}
+
+	if c.Verify != nil {
+		if utils.IsLocalFolder(c.Source) {
+			return fmt.Errorf("verify can only be used with remote sources")
+		}
+
+		if c.Verify.Path != "" && c.Verify.Path != c.Source {
+			return fmt.Errorf("verify path must be the source %s, got %s", c.Source, c.Verify.Path)
+		}
+
+		return c.Verify.Validate()
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreatesCopy(t *testing.T) {
+	c := NewCopy("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeCopy, c.Type)
+}
+
+func TestCopyCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, copyContainer)
+
+	cp, err := c.FindResource("copy.config")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "config", cp.Info().Name)
+	assert.Equal(t, TypeCopy, cp.Info().Type)
+	assert.Equal(t, PendingCreation, cp.Info().Status)
+	assert.Equal(t, "github.com/org/repo//config", cp.(*Copy).Source)
+	assert.Equal(t, "/etc/consul.d", cp.(*Copy).Destination)
+
+	m, err := cp.(*Copy).FileMode()
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), m)
+}
+
+func TestCopyDependsOnTarget(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, copyContainer)
+
+	cp, err := c.FindResource("copy.config")
+	assert.NoError(t, err)
+
+	assert.Contains(t, cp.Info().DependsOn, "container.consul")
+}
+
+func TestCopyWithTargetAndVolumeReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, copyTargetAndVolume)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "one of target or volume")
+}
+
+func TestCopyWithInvalidTargetReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, copyInvalidTarget)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "target must be")
+}
+
+func TestCopyWithInvalidPermissionsReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, copyInvalidPermissions)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "permissions")
+}
+
+func TestCopyParsesVerify(t *testing.T) {
+	c, dir := CreateConfigFromStrings(t, copyVerify)
+
+	cp, err := c.FindResource("copy.config")
+	assert.NoError(t, err)
+
+	v := cp.(*Copy).Verify
+	assert.NotNil(t, v)
+	assert.Equal(t, "sha256:"+strings.Repeat("a", 64), v.Checksum)
+	assert.Equal(t, "https://example.com/tool.tar.gz.asc", v.Signature)
+	assert.Equal(t, filepath.Join(dir, "keys/release.asc"), v.Key)
+}
+
+func TestCopyWithInvalidVerifyReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, copyInvalidVerify)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "signature and key must be set together")
+}
+
+func TestVerifyValidatesChecksum(t *testing.T) {
+	v := &Verify{Checksum: "md5:abc"}
+	assert.Error(t, v.Validate())
+
+	v = &Verify{Checksum: "sha256:abc"}
+	assert.Error(t, v.Validate())
+
+	v = &Verify{Checksum: strings.Repeat("a", 64)}
+	assert.NoError(t, v.Validate())
+
+	alg, sum := v.ChecksumAlgorithm()
+	assert.Equal(t, "sha256", alg)
+	assert.Equal(t, strings.Repeat("a", 64), sum)
+
+	v = &Verify{Checksum: "SHA512:" + strings.Repeat("A", 128)}
+	assert.NoError(t, v.Validate())
+}
+
+func TestVerifyValidatesSignatureType(t *testing.T) {
+	v := &Verify{Signature: "./sig", Key: "./key", SignatureType: "minisign"}
+	assert.Error(t, v.Validate())
+
+	v.SignatureType = SignatureTypeCosign
+	assert.NoError(t, v.Validate())
+
+	v = &Verify{}
+	assert.Error(t, v.Validate())
+}
+
+var copyContainer = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+copy "config" {
+  source = "github.com/org/repo//config"
+  destination = "/etc/consul.d"
+  target = "container.consul"
+  permissions = "0644"
+}
+`
+
+var copyTargetAndVolume = `
+copy "config" {
+  source = "./config"
+  destination = "/etc/consul.d"
+  target = "container.consul"
+  volume = "config"
+}
+`
+
+var copyInvalidTarget = `
+copy "config" {
+  source = "./config"
+  destination = "/etc/consul.d"
+  target = "exec_local.consul"
+}
+`
+
+var copyInvalidPermissions = `
+copy "config" {
+  source = "./config"
+  destination = "/etc/consul.d"
+  volume = "config"
+  permissions = "rwx"
+}
+`
+
+var copyVerify = `
+copy "config" {
+  source = "https://example.com/tool.tar.gz?archive=false"
+  destination = "/opt/tool"
+  volume = "tools"
+
+  verify {
+    checksum = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+    signature = "https://example.com/tool.tar.gz.asc"
+    key = "./keys/release.asc"
+  }
+}
+`
+
+var copyInvalidVerify = `
+copy "config" {
+  source = "https://example.com/tool.tar.gz?archive=false"
+  destination = "/opt/tool"
+  volume = "tools"
+
+  verify {
+    signature = "https://example.com/tool.tar.gz.asc"
+  }
+}
+`
@@ -0,0 +1,43 @@
+package config
+
+import "fmt"
+
+// TypeDockerImage is the resource string for a DockerImage resource
+const TypeDockerImage ResourceType = "docker_image"
+
+// DockerImage builds a Docker image from a local Dockerfile and build context.
+// The built image is stored in the local Docker cache with the name
+// shipyard.run/localcache/[name]:[tag] and can be used by container and
+// cluster resources.
+type DockerImage struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	File    string            `hcl:"file,optional" json:"file,omitempty"` // Location of build file inside build context defaults to ./Dockerfile
+	Context string            `hcl:"context" json:"context"`              // Path to build context
+	Tag     string            `hcl:"tag,optional" json:"tag,omitempty"`   // Image tag, defaults to latest
+	Args    map[string]string `hcl:"args,optional" json:"args,omitempty"` // Build arguments to pass to the Dockerfile
+
+	Target      string        `hcl:"target,optional" json:"target,omitempty"`                                         // Build stage to target in a multi-stage Dockerfile
+	CacheFrom   []string      `hcl:"cache_from,optional" json:"cache_from,omitempty" mapstructure:"cache_from"`       // Images to use as a cache source
+	InlineCache bool          `hcl:"inline_cache,optional" json:"inline_cache,omitempty" mapstructure:"inline_cache"` // Write cache metadata into the image so it can be used with cache_from
+	BuildKit    bool          `hcl:"buildkit,optional" json:"buildkit,omitempty"`                                     // Build the image using BuildKit, enabled automatically when secrets, ssh, or inline_cache are set
+	Secrets     []BuildSecret `hcl:"secret,block" json:"secrets,omitempty"`                                           // Secrets exposed to RUN --mount=type=secret instructions
+	SSH         []string      `hcl:"ssh,optional" json:"ssh,omitempty"`                                               // SSH agent sockets or keys exposed to RUN --mount=type=ssh instructions
+}
+
+// NewDockerImage creates a DockerImage resource with the default values
+func NewDockerImage(name string) *DockerImage {
+	return &DockerImage{ResourceInfo: ResourceInfo{Name: name, Type: TypeDockerImage, Status: PendingCreation}}
+}
+
+// ImageName returns the name of the image which is created by the resource
+func (d *DockerImage) ImageName() string {
+	tag := d.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+
+	return fmt.Sprintf("shipyard.run/localcache/%s:%s", d.Name, tag)
+}
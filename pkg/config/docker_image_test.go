@@ -0,0 +1,86 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreatesDockerImage(t *testing.T) {
+	c := NewDockerImage("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeDockerImage, c.Type)
+}
+
+func TestDockerImageCreatesCorrectly(t *testing.T) {
+	c, dir := CreateConfigFromStrings(t, dockerImageDefault)
+
+	cl, err := c.FindResource("docker_image.app")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "app", cl.Info().Name)
+	assert.Equal(t, TypeDockerImage, cl.Info().Type)
+	assert.Equal(t, PendingCreation, cl.Info().Status)
+	assert.Equal(t, dir+"/src", cl.(*DockerImage).Context)
+	assert.Equal(t, "shipyard.run/localcache/app:v1", cl.(*DockerImage).ImageName())
+}
+
+func TestDockerImageIsAddedAsDependencyOfContainer(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, dockerImageDefault)
+
+	co, err := c.FindResource("container.app")
+	assert.NoError(t, err)
+
+	assert.Contains(t, co.Info().DependsOn, "docker_image.app")
+}
+
+func TestDockerImageIsAddedAsDependencyOfClusterCopyImages(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, dockerImageCopyImages)
+
+	cl, err := c.FindResource("k8s_cluster.k3s")
+	assert.NoError(t, err)
+
+	assert.Contains(t, cl.Info().DependsOn, "docker_image.app")
+}
+
+func TestDockerImageSetsDisabled(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, dockerImageDisabled)
+
+	cl, err := c.FindResource("docker_image.app")
+	assert.NoError(t, err)
+
+	assert.Equal(t, Disabled, cl.Info().Status)
+}
+
+const dockerImageDefault = `
+docker_image "app" {
+	context = "./src"
+	tag     = "v1"
+}
+
+container "app" {
+	image {
+		name = "shipyard.run/localcache/app:v1"
+	}
+}
+`
+
+const dockerImageDisabled = `
+docker_image "app" {
+	disabled = true
+	context  = "./src"
+}
+`
+
+const dockerImageCopyImages = `
+docker_image "app" {
+	context = "./src"
+	tag     = "v1"
+}
+
+k8s_cluster "k3s" {
+	driver      = "k3s"
+	copy_images = ["shipyard.run/localcache/app:v1"]
+}
+`
@@ -1,5 +1,10 @@
 package config
 
+import (
+	"fmt"
+	"strings"
+)
+
 // TypeDocs is the resource string for a Docs resource
 const TypeDocs ResourceType = "docs"
 
@@ -21,9 +26,75 @@ type Docs struct {
 
 	IndexTitle string   `hcl:"index_title,optional" json:"index_title" mapstructure:"index_title"`
 	IndexPages []string `hcl:"index_pages,optional" json:"index_pages,omitempty" mapstructure:"index_pages"`
+
+	// Navigation defines the categories of the sidebar, categories are shown
+	// after the index pages in the order they are defined
+	Navigation []DocsNavigation `hcl:"navigation,block" json:"navigation,omitempty"`
+
+	// Terminals which can be embedded in the documentation using the terminal name
+	Terminals []DocsTerminal `hcl:"terminal,block" json:"terminals,omitempty"`
+
+	// Auth requires requests to the documentation to be authenticated
+	Auth *Auth `hcl:"auth,block" json:"auth,omitempty"`
+
+	// AuthId stores the ID of the auth proxy created in the connector
+	AuthId string `json:"auth_id,omitempty" mapstructure:"auth_id" state:"true"`
 }
 
 // NewDocs creates a new Docs config resource
 func NewDocs(name string) *Docs {
 	return &Docs{ResourceInfo: ResourceInfo{Name: name, Type: TypeDocs, Status: PendingCreation}}
 }
+
+// DocsNavigation is a category of pages in the documentation sidebar
+type DocsNavigation struct {
+	Title string   `hcl:"title,label" json:"title"`
+	Pages []string `hcl:"pages" json:"pages"`
+}
+
+// DocsTerminal is an interactive terminal which is attached to a resource using
+// the same path as 'shipyard exec', when Target is not set a local shell is started
+type DocsTerminal struct {
+	Name    string `hcl:"name,label" json:"name"`
+	Target  string `hcl:"target,optional" json:"target,omitempty"`   // resource to attach to e.g. container.web
+	User    string `hcl:"user,optional" json:"user,omitempty"`       // user to run the shell as, defaults to root
+	WorkDir string `hcl:"workdir,optional" json:"workdir,omitempty"` // working directory for the shell
+	Shell   string `hcl:"shell,optional" json:"shell,omitempty"`     // shell to start, defaults to sh
+}
+
+// Validate the navigation and terminals of the documentation
+func (d *Docs) Validate() error {
+	titles := map[string]bool{}
+	for _, n := range d.Navigation {
+		if len(n.Pages) == 0 {
+			return fmt.Errorf("navigation '%s' must contain at least one page", n.Title)
+		}
+
+		if titles[n.Title] || n.Title == d.IndexTitle {
+			return fmt.Errorf("navigation '%s' is defined more than once", n.Title)
+		}
+
+		titles[n.Title] = true
+	}
+
+	names := map[string]bool{}
+	for _, t := range d.Terminals {
+		if names[t.Name] {
+			return fmt.Errorf("terminal '%s' is defined more than once", t.Name)
+		}
+
+		names[t.Name] = true
+
+		if t.Target == "" {
+			continue
+		}
+
+		switch ResourceType(strings.Split(t.Target, ".")[0]) {
+		case TypeContainer, TypeSidecar, TypeK8sCluster, TypeNomadCluster:
+		default:
+			return fmt.Errorf("terminal '%s' target '%s' must be a container, sidecar, k8s_cluster, or nomad_cluster", t.Name, t.Target)
+		}
+	}
+
+	return nil
+}
@@ -34,6 +34,57 @@ func TestDocsSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, cl.Info().Status)
 }
 
+func TestDocsWithOIDCAuthCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, docsOIDCAuth)
+
+	cl, err := c.FindResource("docs.testing")
+	assert.NoError(t, err)
+
+	d := cl.(*Docs)
+	assert.Equal(t, "https://accounts.google.com", d.Auth.OIDC.Issuer)
+	assert.Equal(t, "client", d.Auth.OIDC.ClientID)
+	assert.Equal(t, []string{"@example.com"}, d.Auth.OIDC.AllowedEmails)
+}
+
+func TestDocsWithTerminalsAndNavigationCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, docsTerminals)
+
+	cl, err := c.FindResource("docs.testing")
+	assert.NoError(t, err)
+
+	d := cl.(*Docs)
+	assert.Len(t, d.Navigation, 2)
+	assert.Equal(t, "Setup", d.Navigation[0].Title)
+	assert.Equal(t, []string{"install", "configure"}, d.Navigation[0].Pages)
+
+	assert.Len(t, d.Terminals, 2)
+	assert.Equal(t, "web", d.Terminals[0].Name)
+	assert.Equal(t, "container.web", d.Terminals[0].Target)
+	assert.Equal(t, "/app", d.Terminals[0].WorkDir)
+	assert.Equal(t, "bash", d.Terminals[0].Shell)
+	assert.Equal(t, "", d.Terminals[1].Target)
+
+	assert.Contains(t, d.DependsOn, "container.web")
+}
+
+func TestDocsWithInvalidTerminalTargetReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, docsInvalidTerminal)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "terminal 'web' target 'network.local'")
+}
+
+func TestDocsWithDuplicateNavigationReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, docsDuplicateNavigation)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "navigation 'test' is defined more than once")
+}
+
 const docsDefault = `
 docs "testing" {
 	path = "/"
@@ -52,3 +103,73 @@ docs "testing" {
 	index_pages = ["test"]
 }
 `
+const docsOIDCAuth = `
+docs "testing" {
+	path = "/"
+	port = "80"
+	index_title = "test"
+	index_pages = ["test"]
+
+	auth {
+		oidc {
+			issuer = "https://accounts.google.com"
+			client_id = "client"
+			client_secret = "secret"
+			allowed_emails = ["@example.com"]
+		}
+	}
+}
+`
+
+const docsTerminals = `
+container "web" {
+	image {
+		name = "nginx"
+	}
+}
+
+docs "testing" {
+	path = "/"
+	port = "80"
+
+	navigation "Setup" {
+		pages = ["install", "configure"]
+	}
+
+	navigation "Usage" {
+		pages = ["run"]
+	}
+
+	terminal "web" {
+		target = "container.web"
+		workdir = "/app"
+		shell = "bash"
+	}
+
+	terminal "local" {}
+}
+`
+
+const docsInvalidTerminal = `
+docs "testing" {
+	path = "/"
+	port = "80"
+
+	terminal "web" {
+		target = "network.local"
+	}
+}
+`
+
+const docsDuplicateNavigation = `
+docs "testing" {
+	path = "/"
+	port = "80"
+	index_title = "test"
+	index_pages = ["test"]
+
+	navigation "test" {
+		pages = ["other"]
+	}
+}
+`
@@ -1,5 +1,10 @@
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
 // TypeExecLocal is the resource string for a LocalExec resource
 const TypeExecLocal ResourceType = "exec_local"
 
@@ -10,6 +15,12 @@ type ExecLocal struct {
 	// Id stores the ID of the created connector service
 	Pid int `json:"pid,omitempty" state:"true"`
 
+	// Output is the stdout of the command with trailing new lines removed, it is only
+	// captured for commands which do not run as a daemon. The output can be referenced
+	// by attributes which are evaluated when a resource is created, e.g. template vars
+	// exec_local.<name>.output
+	Output string `json:"output,omitempty" state:"true"`
+
 	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
 
 	Command          string   `hcl:"cmd,optional" json:"cmd,omitempty" mapstructure:"cmd"`                                           // Command to execute
@@ -17,12 +28,55 @@ type ExecLocal struct {
 	WorkingDirectory string   `hcl:"working_directory,optional" json:"working_directory,omitempty" mapstructure:"working_directory"` // Working directory to execute commands
 	Daemon           bool     `hcl:"daemon,optional" json:"daemon,omitempty"`                                                        // Should the process run as a daemon
 	Timeout          string   `hcl:"timeout,optional" json:"timeout,omitempty"`                                                      // Set the timeout for the command
+	PTY              bool     `hcl:"pty,optional" json:"pty,omitempty"`                                                              // Allocate a pseudo terminal for commands which require a terminal
+
+	// Retries is the number of times to retry the command when it fails to run, times out
+	// or exits with a non zero exit code. When set a non zero exit code fails the resource
+	Retries int `hcl:"retries,optional" json:"retries,omitempty"`
+	// RetryInterval is the time to wait between retries, defaults to 1s
+	RetryInterval string `hcl:"retry_interval,optional" json:"retry_interval,omitempty" mapstructure:"retry_interval"`
 
 	Environment []KV              `hcl:"env,block" json:"env" mapstructure:"env"`                          // environment variables to set
 	EnvVar      map[string]string `hcl:"env_var,optional" json:"env_var,omitempty" mapstructure:"env_var"` // environment variables to set
+
+	// EnvPassthrough is a list of host environment variables which are copied to the
+	// environment of the command e.g. ["AWS_*", "GITHUB_TOKEN"]
+	EnvPassthrough []string `hcl:"env_passthrough,optional" json:"env_passthrough,omitempty" mapstructure:"env_passthrough"`
 }
 
 // NewExecLocal creates a LocalExec resource with the default values
 func NewExecLocal(name string) *ExecLocal {
 	return &ExecLocal{ResourceInfo: ResourceInfo{Name: name, Type: TypeExecLocal, Status: PendingCreation}}
 }
+
+// RetryDuration returns the time to wait between retries
+func (e *ExecLocal) RetryDuration() (time.Duration, error) {
+	if e.RetryInterval == "" {
+		return time.Second, nil
+	}
+
+	return time.ParseDuration(e.RetryInterval)
+}
+
+// Validate the ExecLocal and return errors
+func (e *ExecLocal) Validate() error {
+	if e.Timeout != "" {
+		if _, err := time.ParseDuration(e.Timeout); err != nil {
+			return fmt.Errorf("timeout is not a valid duration: %s", err)
+		}
+	}
+
+	if _, err := e.RetryDuration(); err != nil {
+		return fmt.Errorf("retry_interval is not a valid duration: %s", err)
+	}
+
+	if e.Retries < 0 {
+		return fmt.Errorf("retries must not be negative, got %d", e.Retries)
+	}
+
+	if e.Daemon && (e.Retries > 0 || e.RetryInterval != "") {
+		return fmt.Errorf("retries can not be set when the command runs as a daemon")
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -35,6 +36,52 @@ func TestExecLocalSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, ex.Info().Status)
 }
 
+func TestExecLocalSetsRetries(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, execLocalRetries)
+
+	ex, err := c.FindResource("exec_local.setup_vault")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, ex.(*ExecLocal).Retries)
+
+	d, err := ex.(*ExecLocal).RetryDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, d)
+}
+
+func TestExecLocalRetryDurationDefaults(t *testing.T) {
+	d, err := NewExecLocal("abc").RetryDuration()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, d)
+}
+
+func TestExecLocalWithInvalidRetryIntervalReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, execLocalInvalidRetryInterval)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "retry_interval")
+}
+
+func TestExecLocalWithRetriesAndDaemonReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, execLocalDaemonRetries)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "daemon")
+}
+
+func TestTemplateReferencingExecLocalOutputDependsOnExecLocal(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, execLocalOutputReference)
+
+	tmpl, err := c.FindResource("template.config")
+	assert.NoError(t, err)
+
+	assert.Contains(t, tmpl.Info().DependsOn, "exec_local.setup_vault")
+}
+
 var execLocalRelative = `
 exec_local "setup_vault" {
   cmd = "./scripts/setup_vault.sh"
@@ -53,3 +100,42 @@ exec_local "setup_vault" {
   daemon = true
 }
 `
+
+var execLocalRetries = `
+exec_local "setup_vault" {
+  cmd = "./scripts/setup_vault.sh"
+  retries = 3
+  retry_interval = "5s"
+}
+`
+
+var execLocalInvalidRetryInterval = `
+exec_local "setup_vault" {
+  cmd = "./scripts/setup_vault.sh"
+  retries = 3
+  retry_interval = "often"
+}
+`
+
+var execLocalDaemonRetries = `
+exec_local "setup_vault" {
+  cmd = "./scripts/setup_vault.sh"
+  daemon = true
+  retries = 3
+}
+`
+
+var execLocalOutputReference = `
+exec_local "setup_vault" {
+  cmd = "./scripts/setup_vault.sh"
+}
+
+template "config" {
+  source = "token = #{{ .Vars.token }}"
+  destination = "./out.txt"
+
+  vars = {
+    token = exec_local.setup_vault.output
+  }
+}
+`
@@ -1,5 +1,7 @@
 package config
 
+import "fmt"
+
 // TypeExecRemote is the resource string for a ExecRemote resource
 const TypeExecRemote ResourceType = "exec_remote"
 
@@ -15,6 +17,11 @@ type ExecRemote struct {
 	Image  *Image `hcl:"image,block" json:"image,omitempty"`      // Create a new container and exec
 	Target string `hcl:"target,optional" json:"target,omitempty"` // Attach to a running target and exec
 
+	// Pod selects a pod in the Kubernetes cluster Target to execute the command in
+	Pod *ExecRemotePod `hcl:"pod,block" json:"pod,omitempty"`
+	// Allocation selects an allocation in the Nomad cluster Target to execute the command in
+	Allocation *ExecRemoteAllocation `hcl:"allocation,block" json:"allocation,omitempty"`
+
 	// Either Script or Command must be specified
 	//Script    string   `hcl:"script,optional" json:"script,omitempty"` // Path to a script to execute
 	Command          string   `hcl:"cmd,optional" json:"cmd,omitempty" mapstructure:"cmd"`                                           // Command to execute
@@ -25,6 +32,10 @@ type ExecRemote struct {
 	Environment []KV              `hcl:"env,block" json:"env,omitempty" mapstructure:"env"`                // Environment varialbes to set
 	EnvVar      map[string]string `hcl:"env_var,optional" json:"env_var,omitempty" mapstructure:"env_var"` // environment variables to set when starting the container
 
+	// EnvPassthrough is a list of host environment variables which are copied to the
+	// environment of the command e.g. ["AWS_*", "GITHUB_TOKEN"]
+	EnvPassthrough []string `hcl:"env_passthrough,optional" json:"env_passthrough,omitempty" mapstructure:"env_passthrough"`
+
 	// User block for mapping the user id and group id inside the container
 	RunAs *User `hcl:"run_as,block" json:"run_as,omitempty" mapstructure:"run_as"`
 }
@@ -33,3 +44,50 @@ type ExecRemote struct {
 func NewExecRemote(name string) *ExecRemote {
 	return &ExecRemote{ResourceInfo: ResourceInfo{Name: name, Type: TypeExecRemote, Status: PendingCreation}}
 }
+
+// ExecRemotePod selects a running pod by label
+type ExecRemotePod struct {
+	Namespace string `hcl:"namespace,optional" json:"namespace,omitempty"` // Namespace of the pod, defaults to default
+	Selector  string `hcl:"selector" json:"selector"`                      // Label selector for the pod e.g. app=postgres
+	Container string `hcl:"container,optional" json:"container,omitempty"` // Container in the pod, defaults to the first container
+}
+
+// ExecRemoteAllocation selects a running allocation of a Nomad job
+type ExecRemoteAllocation struct {
+	Job   string `hcl:"job" json:"job"`                        // Job the allocation belongs to
+	Group string `hcl:"group,optional" json:"group,omitempty"` // Task group of the allocation, when not set any group is used
+	Task  string `hcl:"task" json:"task"`                      // Task in the allocation to execute the command in
+}
+
+// Validate the ExecRemote and return errors
+func (e *ExecRemote) Validate() error {
+	if e.Pod == nil && e.Allocation == nil {
+		return nil
+	}
+
+	if e.Pod != nil && e.Allocation != nil {
+		return fmt.Errorf("only one of pod or allocation can be specified")
+	}
+
+	if e.Image != nil {
+		return fmt.Errorf("image can not be used with pod or allocation")
+	}
+
+	if e.RunAs != nil {
+		return fmt.Errorf("run_as can not be used with pod or allocation")
+	}
+
+	if e.Pod != nil && !e.targetIs(TypeK8sCluster) {
+		return fmt.Errorf("pod requires the target to be a %s", TypeK8sCluster)
+	}
+
+	if e.Allocation != nil && !e.targetIs(TypeNomadCluster) {
+		return fmt.Errorf("allocation requires the target to be a %s", TypeNomadCluster)
+	}
+
+	return nil
+}
+
+func (e *ExecRemote) targetIs(t ResourceType) bool {
+	return len(e.Target) > len(t) && e.Target[:len(t)+1] == string(t)+"."
+}
@@ -39,6 +39,75 @@ func TestExecRemoteSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, ex.Info().Status)
 }
 
+func TestExecRemoteParsesPod(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, execRemotePod)
+
+	ex, err := c.FindResource("exec_remote.seed")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "db", ex.(*ExecRemote).Pod.Namespace)
+	assert.Equal(t, "app=postgres", ex.(*ExecRemote).Pod.Selector)
+}
+
+func TestExecRemoteWithPodAndNomadTargetReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, execRemotePodNomadTarget)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "pod requires")
+}
+
+func TestExecRemoteValidatesAllocation(t *testing.T) {
+	e := NewExecRemote("seed")
+	e.Target = "nomad_cluster.dev"
+	e.Allocation = &ExecRemoteAllocation{Job: "db", Task: "postgres"}
+
+	assert.NoError(t, e.Validate())
+
+	e.Target = "container.db"
+	assert.Error(t, e.Validate())
+
+	e.Target = "nomad_cluster.dev"
+	e.RunAs = &User{User: "1000"}
+	assert.Error(t, e.Validate())
+}
+
+func TestExecRemoteWithPodAndAllocationReturnsError(t *testing.T) {
+	e := NewExecRemote("seed")
+	e.Target = "k8s_cluster.k3s"
+	e.Pod = &ExecRemotePod{Selector: "app=postgres"}
+	e.Allocation = &ExecRemoteAllocation{Job: "db", Task: "postgres"}
+
+	assert.Error(t, e.Validate())
+}
+
+var execRemotePod = `
+exec_remote "seed" {
+  target = "k8s_cluster.k3s"
+
+  pod {
+    namespace = "db"
+    selector  = "app=postgres"
+  }
+
+  cmd = "psql"
+  args = ["-f", "/seed.sql"]
+}
+`
+
+var execRemotePodNomadTarget = `
+exec_remote "seed" {
+  target = "nomad_cluster.dev"
+
+  pod {
+    selector  = "app=postgres"
+  }
+
+  cmd = "psql"
+}
+`
+
 var execRemoteRelative = `
 network "cloud" {
 	subnet = "192.158.32.12"
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+)
+
+// HostCapabilities are the capabilities of the host resources are created on,
+// they are used to select the fallback variant of a resource
+type HostCapabilities struct {
+	GPU    bool   // GPUs can be passed to containers
+	Arch   string // architecture of the engine e.g. amd64, arm64
+	Memory int64  // total memory available to the engine in bytes
+}
+
+// hostCapabilities are the capabilities fallbacks are selected with, fallbacks
+// are not applied when the capabilities are not known
+var hostCapabilities *HostCapabilities
+
+// SetHostCapabilities sets the capabilities of the host which are used to select
+// the fallback variants of resources when parsing, setting nil disables fallbacks
+func SetHostCapabilities(h *HostCapabilities) {
+	hostCapabilities = h
+}
+
+// Fallback is an alternate variant of a resource which is used when the condition
+// matches the capabilities of the host, e.g. a CPU only image on hosts without a GPU.
+// The first fallback with a matching condition replaces the values in the resource.
+//
+//	fallback {
+//	  when  = "!gpu"
+//	  image = "ollama/ollama:cpu"
+//	}
+type Fallback struct {
+	When      string            `hcl:"when" json:"when"`                                                 // condition for the host e.g. !gpu, arch == arm64, memory < 16GB, terms can be combined with &&
+	Image     string            `hcl:"image,optional" json:"image,omitempty"`                            // image which replaces the image of the resource
+	Command   []string          `hcl:"command,optional" json:"command,omitempty"`                        // command which replaces the command of the resource
+	EnvVar    map[string]string `hcl:"env_var,optional" json:"env_var,omitempty" mapstructure:"env_var"` // environment variables merged with the environment of the resource
+	Resources *Resources        `hcl:"resources,block" json:"resources,omitempty"`                       // resource constraints which replace the constraints of the resource
+}
+
+// Validate the fallback condition
+func (f *Fallback) Validate() error {
+	_, err := f.Matches(&HostCapabilities{})
+	return err
+}
+
+// Matches returns true when every term in the condition is true for the host
+func (f *Fallback) Matches(h *HostCapabilities) (bool, error) {
+	if strings.TrimSpace(f.When) == "" {
+		return false, fmt.Errorf("fallback condition can not be empty")
+	}
+
+	match := true
+
+	for _, t := range strings.Split(f.When, "&&") {
+		ok, err := matchCondition(strings.TrimSpace(t), h)
+		if err != nil {
+			return false, fmt.Errorf("invalid fallback condition '%s', %s", f.When, err)
+		}
+
+		match = match && ok
+	}
+
+	return match, nil
+}
+
+// matchCondition evaluates a single term of a fallback condition
+func matchCondition(t string, h *HostCapabilities) (bool, error) {
+	switch t {
+	case "gpu":
+		return h.GPU, nil
+	case "!gpu":
+		return !h.GPU, nil
+	}
+
+	parts := strings.Fields(t)
+	if len(parts) != 3 {
+		return false, fmt.Errorf("expected 'gpu', '!gpu', 'arch [==, !=] [arch]' or 'memory [<, <=, >, >=] [size]', got '%s'", t)
+	}
+
+	switch parts[0] {
+	case "arch":
+		switch parts[1] {
+		case "==":
+			return h.Arch == parts[2], nil
+		case "!=":
+			return h.Arch != parts[2], nil
+		}
+
+		return false, fmt.Errorf("arch can only be compared with == or !=")
+
+	case "memory":
+		size, err := units.RAMInBytes(parts[2])
+		if err != nil {
+			return false, fmt.Errorf("invalid memory size '%s'", parts[2])
+		}
+
+		switch parts[1] {
+		case "<":
+			return h.Memory < size, nil
+		case "<=":
+			return h.Memory <= size, nil
+		case ">":
+			return h.Memory > size, nil
+		case ">=":
+			return h.Memory >= size, nil
+		}
+
+		return false, fmt.Errorf("memory can only be compared with <, <=, > or >=")
+	}
+
+	return false, fmt.Errorf("unknown capability '%s', capabilities are gpu, arch or memory", parts[0])
+}
+
+// selectFallback returns the first fallback which matches the capabilities of the host,
+// nil is returned when no fallback matches or the capabilities are not known
+func selectFallback(fallbacks []Fallback) *Fallback {
+	if hostCapabilities == nil {
+		return nil
+	}
+
+	for i := range fallbacks {
+		if ok, _ := fallbacks[i].Matches(hostCapabilities); ok {
+			return &fallbacks[i]
+		}
+	}
+
+	return nil
+}
+
+// validateFallbacks checks the conditions of the fallbacks for a resource
+func validateFallbacks(fallbacks []Fallback) error {
+	for _, f := range fallbacks {
+		err := f.Validate()
+		if err != nil {
+			return err
+		}
+
+		if f.Resources != nil {
+			err := f.Resources.Validate()
+			if err != nil {
+				return fmt.Errorf("fallback '%s' %s", f.When, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyFallback replaces the values of a resource with the values set in the fallback,
+// the image is replaced by the resource as the image is optional for some resources
+func applyFallback(f *Fallback, command *[]string, envVar *map[string]string, resources **Resources) {
+	if len(f.Command) > 0 {
+		*command = f.Command
+	}
+
+	if len(f.EnvVar) > 0 {
+		if *envVar == nil {
+			*envVar = map[string]string{}
+		}
+
+		for k, v := range f.EnvVar {
+			(*envVar)[k] = v
+		}
+	}
+
+	if f.Resources != nil {
+		*resources = f.Resources
+	}
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackMatchesConditions(t *testing.T) {
+	h := &HostCapabilities{GPU: false, Arch: "arm64", Memory: 8 * 1024 * 1024 * 1024}
+
+	tt := map[string]bool{
+		"!gpu":                       true,
+		"gpu":                        false,
+		"arch == arm64":              true,
+		"arch != arm64":              false,
+		"memory < 16GB":              true,
+		"memory >= 8GB":              true,
+		"memory > 8GB":               false,
+		"!gpu && arch == arm64":      true,
+		"!gpu && memory <= 4096MB":   false,
+		"arch == amd64 && !gpu":      false,
+		"  !gpu&&arch == arm64     ": true,
+	}
+
+	for when, expected := range tt {
+		f := Fallback{When: when}
+
+		ok, err := f.Matches(h)
+		require.NoError(t, err, when)
+		require.Equal(t, expected, ok, when)
+	}
+}
+
+func TestFallbackInvalidConditionReturnsError(t *testing.T) {
+	for _, when := range []string{"", "gpus", "arch > arm64", "memory == 8GB", "memory < lots", "disk < 10GB"} {
+		f := Fallback{When: when}
+
+		err := f.Validate()
+		require.Error(t, err, when)
+	}
+}
+
+func TestFallbackIsAppliedToContainerWhenConditionMatches(t *testing.T) {
+	SetHostCapabilities(&HostCapabilities{GPU: false, Arch: "amd64"})
+	t.Cleanup(func() { SetHostCapabilities(nil) })
+
+	c, _ := CreateConfigFromStrings(t, fallbackContainer)
+
+	r, err := c.FindResource("container.ollama")
+	require.NoError(t, err)
+
+	co := r.(*Container)
+	require.Equal(t, "ollama/ollama:cpu", co.Image.Name)
+	require.Equal(t, []string{"serve", "--cpu"}, co.Command)
+	require.Equal(t, map[string]string{"OLLAMA_HOST": "0.0.0.0", "OLLAMA_NUM_PARALLEL": "1"}, co.EnvVar)
+	require.Nil(t, co.Resources.GPU)
+	require.Equal(t, "4096", co.Resources.Memory)
+	require.Equal(t, "!gpu", co.SelectedFallback)
+}
+
+func TestFallbackIsNotAppliedWhenConditionDoesNotMatch(t *testing.T) {
+	SetHostCapabilities(&HostCapabilities{GPU: true, Arch: "amd64"})
+	t.Cleanup(func() { SetHostCapabilities(nil) })
+
+	c, _ := CreateConfigFromStrings(t, fallbackContainer)
+
+	r, err := c.FindResource("container.ollama")
+	require.NoError(t, err)
+
+	co := r.(*Container)
+	require.Equal(t, "ollama/ollama:latest", co.Image.Name)
+	require.NotNil(t, co.Resources.GPU)
+	require.Empty(t, co.SelectedFallback)
+}
+
+func TestFallbackFirstMatchingFallbackIsApplied(t *testing.T) {
+	SetHostCapabilities(&HostCapabilities{GPU: false, Arch: "arm64"})
+	t.Cleanup(func() { SetHostCapabilities(nil) })
+
+	c, _ := CreateConfigFromStrings(t, fallbackContainer)
+
+	r, err := c.FindResource("container.ollama")
+	require.NoError(t, err)
+
+	require.Equal(t, "ollama/ollama:arm64", r.(*Container).Image.Name)
+}
+
+func TestFallbackIsNotAppliedWithoutHostCapabilities(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, fallbackContainer)
+
+	r, err := c.FindResource("container.ollama")
+	require.NoError(t, err)
+
+	require.Equal(t, "ollama/ollama:latest", r.(*Container).Image.Name)
+}
+
+func TestFallbackIsAppliedToSidecar(t *testing.T) {
+	SetHostCapabilities(&HostCapabilities{Memory: 2 * 1024 * 1024 * 1024})
+	t.Cleanup(func() { SetHostCapabilities(nil) })
+
+	c, _ := CreateConfigFromStrings(t, fallbackSidecar)
+
+	r, err := c.FindResource("sidecar.envoy")
+	require.NoError(t, err)
+
+	require.Equal(t, "envoyproxy/envoy-distroless:v1.24.0", r.(*Sidecar).Image.Name)
+	require.Equal(t, "memory < 4GB", r.(*Sidecar).SelectedFallback)
+}
+
+func TestFallbackWithInvalidConditionReturnsParseError(t *testing.T) {
+	dir := CreateTestFiles(t, fallbackInvalid)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid fallback condition")
+}
+
+const fallbackContainer = `
+container "ollama" {
+  image {
+    name = "ollama/ollama:latest"
+  }
+
+  command = ["serve"]
+
+  env_var = {
+    OLLAMA_HOST = "0.0.0.0"
+  }
+
+  resources {
+    gpu {}
+  }
+
+  fallback {
+    when  = "!gpu && arch == arm64"
+    image = "ollama/ollama:arm64"
+  }
+
+  fallback {
+    when    = "!gpu"
+    image   = "ollama/ollama:cpu"
+    command = ["serve", "--cpu"]
+
+    env_var = {
+      OLLAMA_NUM_PARALLEL = "1"
+    }
+
+    resources {
+      memory = 4096
+    }
+  }
+}
+`
+
+const fallbackSidecar = `
+container "api" {
+  image {
+    name = "api:latest"
+  }
+}
+
+sidecar "envoy" {
+  target = "container.api"
+
+  image {
+    name = "envoyproxy/envoy:v1.24.0"
+  }
+
+  fallback {
+    when  = "memory < 4GB"
+    image = "envoyproxy/envoy-distroless:v1.24.0"
+  }
+}
+`
+
+const fallbackInvalid = `
+container "ollama" {
+  image {
+    name = "ollama/ollama:latest"
+  }
+
+  fallback {
+    when  = "gpus == 0"
+    image = "ollama/ollama:cpu"
+  }
+}
+`
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Headers modifies the requests and responses which pass through an ingress,
+// the headers are rewritten by the connector before the request is forwarded
+// to the service and before the response is returned to the client
+type Headers struct {
+	CORS        *CORS        `hcl:"cors,block" json:"cors,omitempty"`                                                // add CORS headers and answer preflight requests
+	Request     *HeaderRules `hcl:"request,block" json:"request,omitempty"`                                          // rules applied to the request before it is forwarded
+	Response    *HeaderRules `hcl:"response,block" json:"response,omitempty"`                                        // rules applied to the response before it is returned
+	RewriteHost string       `hcl:"rewrite_host,optional" json:"rewrite_host,omitempty" mapstructure:"rewrite_host"` // replace the Host header of the request e.g. api.example.com
+}
+
+// HeaderRules sets and removes headers, headers are removed before they are set
+type HeaderRules struct {
+	Set    map[string]string `hcl:"set,optional" json:"set,omitempty"`       // headers to add, existing values are replaced
+	Remove []string          `hcl:"remove,optional" json:"remove,omitempty"` // headers to remove e.g. Authorization
+}
+
+// CORS configures the Cross-Origin Resource Sharing headers returned to the browser
+type CORS struct {
+	AllowedOrigins   []string `hcl:"allowed_origins,optional" json:"allowed_origins,omitempty" mapstructure:"allowed_origins"`       // origins allowed to make requests, defaults to *
+	AllowedMethods   []string `hcl:"allowed_methods,optional" json:"allowed_methods,omitempty" mapstructure:"allowed_methods"`       // methods allowed in requests, defaults to the common methods
+	AllowedHeaders   []string `hcl:"allowed_headers,optional" json:"allowed_headers,omitempty" mapstructure:"allowed_headers"`       // headers allowed in requests, defaults to the headers requested by the browser
+	AllowCredentials bool     `hcl:"allow_credentials,optional" json:"allow_credentials,omitempty" mapstructure:"allow_credentials"` // allow cookies and authorization headers to be sent
+}
+
+// Validate the header config
+func (h *Headers) Validate() error {
+	for _, r := range []*HeaderRules{h.Request, h.Response} {
+		if r == nil {
+			continue
+		}
+
+		for k := range r.Set {
+			if k == "" {
+				return fmt.Errorf("header name can not be empty")
+			}
+
+			if http.CanonicalHeaderKey(k) == "Host" {
+				return fmt.Errorf("the Host header can not be set, use rewrite_host")
+			}
+		}
+	}
+
+	if h.CORS != nil && h.CORS.AllowCredentials {
+		for _, o := range h.CORS.AllowedOrigins {
+			if o == "*" {
+				return fmt.Errorf("allowed_origins can not contain * when allow_credentials is true")
+			}
+		}
+
+		if len(h.CORS.AllowedOrigins) == 0 {
+			return fmt.Errorf("allowed_origins must be specified when allow_credentials is true")
+		}
+	}
+
+	return nil
+}
@@ -1,5 +1,12 @@
 package config
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
 // TypeHelm is the string representation of the ResourceType
 const TypeHelm ResourceType = "helm"
 
@@ -23,9 +30,17 @@ type Helm struct {
 	// semver of the chart to install
 	Version string `hcl:"version,optional" json:"version,omitempty"`
 
+	// Verify declares the checksum or signature a remote chart archive must match before it is
+	// installed, add ?archive=false to the chart so that the archive is verified before it is used
+	Verify *Verify `hcl:"verify,block" json:"verify,omitempty"`
+
 	Values       string            `hcl:"values,optional" json:"values"`
 	ValuesString map[string]string `hcl:"values_string,optional" json:"values_string" mapstructure:"values_string"`
 
+	// ValuesMap are values for the chart defined in HCL, the map is merged on top of
+	// the values file. When parsed the attribute is converted to a map[string]interface{}
+	ValuesMap interface{} `hcl:"values_map,optional" json:"values_map,omitempty" mapstructure:"values_map"`
+
 	// Namespace is the Kubernetes namespace
 	Namespace string `hcl:"namespace,optional" json:"namespace,omitempty"`
 
@@ -38,12 +53,59 @@ type Helm struct {
 	// Retry the install n number of times
 	Retry int `hcl:"retry,optional" json:"retry,omitempty" mapstructure:"retry"`
 
+	// Timeout is the maximum time to install the chart including retries e.g. 5m
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
+
 	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty" mapstructure:"health_check"`
+
+	// Destroy configures how the chart resources are removed when the chart is destroyed
+	Destroy *K8sDestroy `hcl:"destroy,block" json:"destroy,omitempty"`
 }
 
+// HelmRepository defines a chart repository, the URL can be a Helm repository
+// e.g. https://helm.releases.hashicorp.com or an OCI registry e.g. oci://ghcr.io/org/charts
 type HelmRepository struct {
-	Name string `hcl:"name" json:"name"`
-	URL  string `hcl:"url" json:"url"`
+	Name     string `hcl:"name" json:"name"`
+	URL      string `hcl:"url" json:"url"`
+	Username string `hcl:"username,optional" json:"username,omitempty"` // username used to authenticate with a private repository
+	Password string `hcl:"password,optional" json:"password,omitempty"` // password or token used to authenticate with a private repository
+}
+
+// IsOCI returns true when the repository is an OCI registry
+func (r *HelmRepository) IsOCI() bool {
+	return strings.HasPrefix(r.URL, "oci://")
+}
+
+// Validate the repository config
+func (r *HelmRepository) Validate() error {
+	if (r.Username == "") != (r.Password == "") {
+		return fmt.Errorf("repository username and password must both be specified")
+	}
+
+	return nil
+}
+
+// ValidateVerify checks that verify is only used with charts which are downloaded
+func (h *Helm) ValidateVerify() error {
+	if h.Verify == nil {
+		return nil
+	}
+
+	if h.Repository != nil || strings.HasPrefix(h.Chart, "oci://") || utils.IsLocalFolder(h.Chart) {
+		return fmt.Errorf("verify can only be used with charts downloaded from a remote source")
+	}
+
+	if h.Verify.Path != "" && h.Verify.Path != h.Chart {
+		return fmt.Errorf("verify path must be the chart %s, got %s", h.Chart, h.Verify.Path)
+	}
+
+	return h.Verify.Validate()
+}
+
+// ValuesMapValue returns the values map for the chart
+func (h *Helm) ValuesMapValue() map[string]interface{} {
+	m, _ := h.ValuesMap.(map[string]interface{})
+	return m
 }
 
 // NewHelm creates a new Helm resource with the correct defaults
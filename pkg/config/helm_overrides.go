@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// helmOverrides are the values set on the command line which are merged on
+// top of the values of a helm resource, keyed by the name of the resource
+var helmOverrides map[string]map[string]interface{}
+
+// HelmOverride is a single value set on the command line for a helm resource
+type HelmOverride struct {
+	Resource string      // name of the helm resource e.g. vault
+	Path     string      // dot separated path of the value e.g. server.dev.enabled
+	Value    interface{} // value converted to a bool, int, or string
+}
+
+// String returns the override in the format used on the command line
+func (h HelmOverride) String() string {
+	return fmt.Sprintf("%s.%s.values.%s=%v", TypeHelm, h.Resource, h.Path, h.Value)
+}
+
+// ParseHelmOverride parses an override in the format helm.[name].values.[path]=[value]
+// e.g. helm.vault.values.server.dev.enabled=true
+func ParseHelmOverride(s string) (HelmOverride, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return HelmOverride{}, fmt.Errorf("invalid helm value '%s', values must be in the format helm.[name].values.[path]=[value]", s)
+	}
+
+	key := strings.Split(parts[0], ".")
+	if len(key) < 4 || key[0] != string(TypeHelm) || key[1] == "" || key[2] != "values" {
+		return HelmOverride{}, fmt.Errorf("invalid helm value '%s', values must be in the format helm.[name].values.[path]=[value]", s)
+	}
+
+	for _, k := range key[3:] {
+		if k == "" {
+			return HelmOverride{}, fmt.Errorf("invalid helm value '%s', the path must not contain empty keys", s)
+		}
+	}
+
+	return HelmOverride{
+		Resource: key[1],
+		Path:     strings.Join(key[3:], "."),
+		Value:    parseHelmValue(parts[1]),
+	}, nil
+}
+
+// SetHelmOverrides sets the values which are merged on top of the values of helm
+// resources when parsing the config, values set in the same path as the values_map
+// of the resource replace the values_map. Setting no overrides removes any existing overrides.
+func SetHelmOverrides(overrides []string) ([]HelmOverride, error) {
+	helmOverrides = nil
+
+	ho := []HelmOverride{}
+	for _, s := range overrides {
+		o, err := ParseHelmOverride(s)
+		if err != nil {
+			return nil, err
+		}
+
+		ho = append(ho, o)
+	}
+
+	// values are applied in order so that the last value for a path wins
+	for _, o := range ho {
+		if helmOverrides == nil {
+			helmOverrides = map[string]map[string]interface{}{}
+		}
+
+		if helmOverrides[o.Resource] == nil {
+			helmOverrides[o.Resource] = map[string]interface{}{}
+		}
+
+		setValuePath(helmOverrides[o.Resource], strings.Split(o.Path, "."), o.Value)
+	}
+
+	return ho, nil
+}
+
+// applyHelmOverrides merges the values set on the command line on top of the values map
+func applyHelmOverrides(h *Helm) {
+	o, ok := helmOverrides[h.Name]
+	if !ok {
+		return
+	}
+
+	m := h.ValuesMapValue()
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+
+	h.ValuesMap = mergeValues(m, o)
+}
+
+// mergeValues merges the override map on top of the base map, nested maps
+// are merged, all other values are replaced
+func mergeValues(base, override map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range base {
+		out[k] = v
+	}
+
+	for k, v := range override {
+		if om, ok := v.(map[string]interface{}); ok {
+			if bm, ok := out[k].(map[string]interface{}); ok {
+				out[k] = mergeValues(bm, om)
+				continue
+			}
+		}
+
+		out[k] = v
+	}
+
+	return out
+}
+
+func setValuePath(m map[string]interface{}, path []string, v interface{}) {
+	if len(path) == 1 {
+		m[path[0]] = v
+		return
+	}
+
+	next, ok := m[path[0]].(map[string]interface{})
+	if !ok {
+		next = map[string]interface{}{}
+		m[path[0]] = next
+	}
+
+	setValuePath(next, path[1:], v)
+}
+
+// parseHelmValue converts the value to a bool or int in the same way as helm --set,
+// all other values are returned as a string
+func parseHelmValue(s string) interface{} {
+	if s == "true" || s == "false" {
+		return s == "true"
+	}
+
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+
+	return s
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupHelmOverrides(t *testing.T, overrides ...string) []HelmOverride {
+	ho, err := SetHelmOverrides(overrides)
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { SetHelmOverrides(nil) })
+
+	return ho
+}
+
+func TestParseHelmOverrideReturnsOverride(t *testing.T) {
+	o, err := ParseHelmOverride("helm.vault.values.server.dev.enabled=true")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "vault", o.Resource)
+	assert.Equal(t, "server.dev.enabled", o.Path)
+	assert.Equal(t, true, o.Value)
+	assert.Equal(t, "helm.vault.values.server.dev.enabled=true", o.String())
+}
+
+func TestParseHelmOverrideConvertsValues(t *testing.T) {
+	o, err := ParseHelmOverride("helm.vault.values.server.replicas=3")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), o.Value)
+
+	o, err = ParseHelmOverride("helm.vault.values.server.image=vault:1.9=latest")
+	assert.NoError(t, err)
+	assert.Equal(t, "vault:1.9=latest", o.Value)
+}
+
+func TestParseHelmOverrideWithInvalidFormatReturnsError(t *testing.T) {
+	for _, s := range []string{
+		"helm.vault.values.server.dev.enabled",
+		"container.vault.values.server=true",
+		"helm.vault.server=true",
+		"helm.vault.values=true",
+		"helm.vault.values.server..enabled=true",
+	} {
+		_, err := ParseHelmOverride(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestHelmOverridesAreMergedWithValuesMap(t *testing.T) {
+	setupHelmOverrides(t,
+		"helm.testing.values.server.replicas=1",
+		"helm.testing.values.server.dev.enabled=true",
+		"helm.other.values.ui.enabled=false",
+	)
+
+	c, _ := CreateConfigFromStrings(t, helmValuesMap)
+
+	h, err := c.FindResource("helm.testing")
+	assert.NoError(t, err)
+
+	v := h.(*Helm).ValuesMapValue()
+	assert.Equal(t, "consul", v["global"].(map[string]interface{})["name"])
+	assert.Equal(t, int64(1), v["server"].(map[string]interface{})["replicas"])
+	assert.Equal(t, true, v["server"].(map[string]interface{})["dev"].(map[string]interface{})["enabled"])
+	assert.Equal(t, true, v["ui"].(map[string]interface{})["enabled"])
+}
+
+func TestHelmOverridesSetValuesMapWhenNotDefined(t *testing.T) {
+	setupHelmOverrides(t, "helm.testing.values.server.dev.enabled=true")
+
+	c, _ := CreateConfigFromStrings(t, helmDefault)
+
+	h, err := c.FindResource("helm.testing")
+	assert.NoError(t, err)
+
+	v := h.(*Helm).ValuesMapValue()
+	assert.Equal(t, true, v["server"].(map[string]interface{})["dev"].(map[string]interface{})["enabled"])
+}
+
+func TestSetHelmOverridesWithNoValuesRemovesOverrides(t *testing.T) {
+	setupHelmOverrides(t, "helm.testing.values.server.dev.enabled=true")
+	setupHelmOverrides(t)
+
+	c, _ := CreateConfigFromStrings(t, helmDefault)
+
+	h, err := c.FindResource("helm.testing")
+	assert.NoError(t, err)
+
+	assert.Nil(t, h.(*Helm).ValuesMapValue())
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,6 +34,94 @@ func TestHelmSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, h.Info().Status)
 }
 
+func TestHelmParsesValuesMap(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, helmValuesMap)
+
+	h, err := c.FindResource("helm.testing")
+	assert.NoError(t, err)
+
+	v := h.(*Helm).ValuesMapValue()
+	assert.Equal(t, "consul", v["global"].(map[string]interface{})["name"])
+	assert.Equal(t, float64(3), v["server"].(map[string]interface{})["replicas"])
+	assert.Equal(t, true, v["ui"].(map[string]interface{})["enabled"])
+	assert.Equal(t, []interface{}{"a", "b"}, v["tags"])
+}
+
+func TestHelmValuesMapIsPreservedInState(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, helmValuesMap)
+
+	d, err := json.Marshal(c)
+	assert.NoError(t, err)
+
+	nc := New()
+	err = json.Unmarshal(d, nc)
+	assert.NoError(t, err)
+
+	h, err := nc.FindResource("helm.testing")
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(3), h.(*Helm).ValuesMapValue()["server"].(map[string]interface{})["replicas"])
+}
+
+func TestHelmWithInvalidValuesMapReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, helmInvalidValuesMap)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "values_map must be a map")
+}
+
+func TestHelmParsesRepositoryCredentials(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, helmOCIRepository)
+
+	h, err := c.FindResource("helm.testing")
+	assert.NoError(t, err)
+
+	r := h.(*Helm).Repository
+	assert.True(t, r.IsOCI())
+	assert.Equal(t, "admin", r.Username)
+	assert.Equal(t, "token", r.Password)
+}
+
+func TestHelmRepositoryWithoutPasswordReturnsError(t *testing.T) {
+	r := &HelmRepository{Name: "private", URL: "https://charts.example.com", Username: "admin"}
+
+	assert.Error(t, r.Validate())
+}
+
+func TestHelmParsesVerify(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, helmVerify)
+
+	h, err := c.FindResource("helm.testing")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "sha256:0b6f1c1b5d0e3bd5c4e9b1e0e5a2f0d4b1a2c3d4e5f60718293a4b5c6d7e8f90", h.(*Helm).Verify.Checksum)
+}
+
+func TestHelmVerifyWithRepositoryReturnsError(t *testing.T) {
+	h := NewHelm("testing")
+	h.Chart = "consul"
+	h.Repository = &HelmRepository{Name: "hashicorp", URL: "https://helm.releases.hashicorp.com"}
+	h.Verify = &Verify{Checksum: "sha256:0b6f1c1b5d0e3bd5c4e9b1e0e5a2f0d4b1a2c3d4e5f60718293a4b5c6d7e8f90"}
+
+	err := h.ValidateVerify()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "remote source")
+}
+
+const helmVerify = `
+helm "testing" {
+	cluster = "cluster.k3s"
+
+	chart = "https://example.com/charts/consul-0.1.0.tgz?archive=false"
+
+	verify {
+		checksum = "sha256:0b6f1c1b5d0e3bd5c4e9b1e0e5a2f0d4b1a2c3d4e5f60718293a4b5c6d7e8f90"
+	}
+}
+`
+
 const helmDefault = `
 helm "testing" {
 	cluster = "cluster.k3s"
@@ -52,3 +141,53 @@ helm "testing" {
 	values = "test"
 }
 `
+
+const helmValuesMap = `
+helm "testing" {
+	cluster = "cluster.k3s"
+
+	chart = "test"
+
+	values_map = {
+		global = {
+			name = "consul"
+		}
+
+		server = {
+			replicas = 3
+		}
+
+		ui = {
+			enabled = true
+		}
+
+		tags = ["a", "b"]
+	}
+}
+`
+
+const helmInvalidValuesMap = `
+helm "testing" {
+	cluster = "cluster.k3s"
+
+	chart = "test"
+
+	values_map = "./values.yaml"
+}
+`
+
+const helmOCIRepository = `
+helm "testing" {
+	cluster = "cluster.k3s"
+
+	repository {
+		name = "org"
+		url = "oci://ghcr.io/org/charts"
+		username = "admin"
+		password = "token"
+	}
+
+	chart = "app"
+	version = "1.2.0"
+}
+`
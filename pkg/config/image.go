@@ -1,5 +1,11 @@
 package config
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Image defines a docker image which will be pushed to the clusters Docker
 // registry
 type Image struct {
@@ -8,4 +14,122 @@ type Image struct {
 	Username string `hcl:"username,optional" json:"username,omitempty"`
 	// Password is the Docker registry password to use for private repositories
 	Password string `hcl:"password,optional" json:"password,omitempty"`
+	// Platform selects the image to use from a multi-arch image e.g. linux/amd64, linux/arm64/v8,
+	// or windows for Windows containers, when not set the platform of the Docker engine is used
+	Platform string `hcl:"platform,optional" json:"platform,omitempty"`
+
+	// PullTimeout and PullRetry are the policy used when pulling the image, they
+	// are set by the provider from the resource which uses the image
+	PullTimeout time.Duration `json:"-" mapstructure:"-"`
+	PullRetry   *Retry        `json:"-" mapstructure:"-"`
+}
+
+// Validate the image
+func (i *Image) Validate() error {
+	if i.Platform == "" {
+		return nil
+	}
+
+	_, _, _, err := i.PlatformParts()
+	return err
+}
+
+// PlatformParts returns the os, architecture, and optional variant of the image platform,
+// the architecture is blank for Windows images which only set the os
+func (i *Image) PlatformParts() (string, string, string, error) {
+	if i.Platform == PlatformWindows {
+		return PlatformWindows, "", "", nil
+	}
+
+	parts := strings.Split(i.Platform, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("invalid platform '%s' for image %s, platform must be in the format os/arch[/variant] e.g. linux/amd64, or windows", i.Platform, i.Name)
+	}
+
+	variant := ""
+	if len(parts) == 3 {
+		variant = parts[2]
+	}
+
+	return parts[0], parts[1], variant, nil
+}
+
+// IsWindows returns true when the image is for Windows containers
+func (i *Image) IsWindows() bool {
+	pos, _, _, err := i.PlatformParts()
+	return err == nil && pos == PlatformWindows
+}
+
+// clusterImages returns the images which are imported into a cluster, the
+// image blocks and the locally built images set with copy_images
+func clusterImages(images []Image, copyImages []string) []Image {
+	all := append([]Image{}, images...)
+	for _, i := range copyImages {
+		all = append(all, Image{Name: i})
+	}
+
+	return all
+}
+
+func validateImages(images []Image) error {
+	for _, i := range images {
+		err := i.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Image strategies define how the images for a cluster are added to the nodes
+const (
+	// ImageStrategyCache pulls the images in each node through the image cache
+	ImageStrategyCache = "cache"
+	// ImageStrategyRegistry pushes the images to the first registry the cluster uses
+	ImageStrategyRegistry = "registry"
+	// ImageStrategyLoad saves the images from the local Docker engine and loads them into each node
+	ImageStrategyLoad = "load"
+)
+
+// ImageStrategies are the strategies which can be set for a cluster
+var ImageStrategies = []string{ImageStrategyCache, ImageStrategyRegistry, ImageStrategyLoad}
+
+// imageStrategies returns the strategies used to add images to a cluster,
+// images are loaded into the nodes when no strategy is set
+func imageStrategies(strategies []string) []string {
+	if len(strategies) == 0 {
+		return []string{ImageStrategyLoad}
+	}
+
+	return strategies
+}
+
+func validateImageStrategy(strategies []string, registries []string) error {
+	seen := map[string]bool{}
+
+	for _, s := range strategies {
+		valid := false
+		for _, is := range ImageStrategies {
+			if s == is {
+				valid = true
+			}
+		}
+
+		if !valid {
+			return fmt.Errorf("invalid image_strategy '%s', must be one of %s", s, strings.Join(ImageStrategies, ", "))
+		}
+
+		if seen[s] {
+			return fmt.Errorf("image_strategy '%s' is set more than once", s)
+		}
+
+		seen[s] = true
+
+		if s == ImageStrategyRegistry && len(registries) == 0 {
+			return fmt.Errorf("image_strategy '%s' requires a registry to be set in registries", s)
+		}
+	}
+
+	return nil
 }
@@ -1,5 +1,13 @@
 package config
 
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
 // TypeIngress is the resource string for the type
 const TypeIngress ResourceType = "ingress"
 
@@ -7,6 +15,7 @@ const (
 	IngressSourceLocal  = "local"
 	IngressSourceK8s    = "k8s"
 	IngressSourceDocker = "docker"
+	IngressSourceRemote = "remote"
 )
 
 // Ingress defines an ingress service mapping ports between local host and resources like containers and kube cluster
@@ -20,6 +29,53 @@ type Ingress struct {
 
 	Destination Traffic `hcl:"destination,block" json:"destination"`
 	Source      Traffic `hcl:"source,block" json:"source"`
+
+	// Auth requires requests to the exposed service to be authenticated
+	Auth *Auth `hcl:"auth,block" json:"auth,omitempty"`
+
+	// Headers rewrites the headers of requests and responses to the exposed service
+	Headers *Headers `hcl:"headers,block" json:"headers,omitempty"`
+
+	// AuthId stores the ID of the auth proxy created in the connector
+	AuthId string `json:"auth_id,omitempty" mapstructure:"auth_id" state:"true"`
+
+	// TLS terminates TLS for the exposed service, only used when the destination is k8s
+	TLS *IngressTLS `hcl:"tls,block" json:"tls,omitempty"`
+
+	// StreamId stores the ID of the stream proxy created in the connector to terminate TLS
+	StreamId string `json:"stream_id,omitempty" mapstructure:"stream_id" state:"true"`
+}
+
+// IngressTLS configures an ingress to terminate TLS, by default the leaf certificate
+// generated by Shipyard in $HOME/.shipyard/certs is used
+type IngressTLS struct {
+	// Certificate is a certificate_leaf resource used to terminate TLS e.g. certificate_leaf.kafka
+	Certificate string `hcl:"certificate,optional" json:"certificate,omitempty"`
+}
+
+// Validate the IngressTLS and return errors
+func (t *IngressTLS) Validate() error {
+	if t.Certificate != "" && !strings.HasPrefix(t.Certificate, string(TypeCertificateLeaf)+".") {
+		return fmt.Errorf("tls certificate must be a certificate_leaf resource e.g. certificate_leaf.web, got %s", t.Certificate)
+	}
+
+	return nil
+}
+
+// Files returns the location of the certificate and private key used to terminate TLS
+func (t *IngressTLS) Files(c *Config) (string, string, error) {
+	if t.Certificate == "" {
+		return filepath.Join(utils.CertsDir(""), "leaf.cert"), filepath.Join(utils.CertsDir(""), "leaf.key"), nil
+	}
+
+	r, err := c.FindResource(t.Certificate)
+	if err != nil {
+		return "", "", err
+	}
+
+	l := r.(*CertificateLeaf)
+
+	return l.CertPath, l.KeyPath, nil
 }
 
 // Traffic defines either a source or a destination block for ingress traffic
@@ -33,7 +89,9 @@ type Traffic struct {
 
 // TrafficConfig defines the parameters for the traffic
 type TrafficConfig struct {
-	Cluster       string `hcl:"cluster,optional" json:"cluster,omitempty"`
+	Cluster string `hcl:"cluster,optional" json:"cluster,omitempty"`
+	// Remote is the name of a machine joined with shipyard connector join, used by the remote driver
+	Remote        string `hcl:"remote,optional" json:"remote,omitempty"`
 	Address       string `hcl:"address,optional" json:"address,omitempty"`
 	Port          string `hcl:"port" json:"port"`
 	OpenInBrowser string `hcl:"open_in_browser,optional" json:"open_in_browser,omitempty" mapstructure:"open_in_browser"`
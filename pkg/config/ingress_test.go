@@ -1,8 +1,10 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
 
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,6 +33,91 @@ func TestIngressSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, cl.Info().Status)
 }
 
+func TestIngressWithBasicAuthCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, ingressBasicAuth)
+
+	cl, err := c.FindResource("ingress.testing")
+	assert.NoError(t, err)
+
+	i := cl.(*Ingress)
+	assert.Equal(t, "admin", i.Auth.Basic.Username)
+	assert.Equal(t, "secret", i.Auth.Basic.Password)
+	assert.Nil(t, i.Auth.OIDC)
+}
+
+func TestIngressWithInvalidAuthReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, ingressInvalidAuth)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "one of either basic or oidc")
+}
+
+func TestIngressWithHeadersCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, ingressHeaders)
+
+	cl, err := c.FindResource("ingress.testing")
+	assert.NoError(t, err)
+
+	h := cl.(*Ingress).Headers
+	assert.Equal(t, []string{"http://localhost:3000"}, h.CORS.AllowedOrigins)
+	assert.True(t, h.CORS.AllowCredentials)
+	assert.Equal(t, "api.example.com", h.RewriteHost)
+	assert.Equal(t, []string{"Authorization"}, h.Request.Remove)
+	assert.Equal(t, "local", h.Request.Set["X-Env"])
+	assert.Equal(t, "no-store", h.Response.Set["Cache-Control"])
+}
+
+func TestIngressWithWildcardCredentialedCORSReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, ingressInvalidCORS)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "allowed_origins can not contain *")
+}
+
+func TestHeadersSettingHostReturnsError(t *testing.T) {
+	h := &Headers{Request: &HeaderRules{Set: map[string]string{"host": "api.example.com"}}}
+
+	assert.Error(t, h.Validate())
+}
+
+func TestIngressWithTLSDependsOnCertificate(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, ingressTLS)
+
+	cl, err := c.FindResource("ingress.testing")
+	assert.NoError(t, err)
+
+	i := cl.(*Ingress)
+	assert.Equal(t, "certificate_leaf.kafka", i.TLS.Certificate)
+	assert.Contains(t, i.DependsOn, "certificate_leaf.kafka")
+
+	cert, key, err := i.TLS.Files(c)
+	assert.NoError(t, err)
+	assert.Contains(t, cert, "kafka.cert")
+	assert.Contains(t, key, "kafka.key")
+}
+
+func TestIngressTLSDefaultsToShipyardCertificate(t *testing.T) {
+	tls := &IngressTLS{}
+
+	cert, key, err := tls.Files(New())
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(utils.CertsDir(""), "leaf.cert"), cert)
+	assert.Equal(t, filepath.Join(utils.CertsDir(""), "leaf.key"), key)
+}
+
+func TestIngressWithInvalidTLSCertificateReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, ingressInvalidTLS)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "tls certificate must be")
+}
+
 const ingressDefault = `
 network "test" {
 	subnet = "10.0.0.0/24"
@@ -90,3 +177,168 @@ ingress "testing" {
 	}
 }
 `
+
+const ingressBasicAuth = `
+ingress "testing" {
+	source {
+		driver = "local"
+		config {
+			port = 8080
+		}
+	}
+
+	destination {
+		driver = "local"
+		config {
+			address = "localhost"
+			port = 9090
+		}
+	}
+
+	auth {
+		basic {
+			username = "admin"
+			password = "secret"
+		}
+	}
+}
+`
+
+const ingressInvalidAuth = `
+ingress "testing" {
+	source {
+		driver = "local"
+		config {
+			port = 8080
+		}
+	}
+
+	destination {
+		driver = "local"
+		config {
+			address = "localhost"
+			port = 9090
+		}
+	}
+
+	auth {}
+}
+`
+
+const ingressHeaders = `
+ingress "testing" {
+	source {
+		driver = "local"
+		config {
+			port = 8080
+		}
+	}
+
+	destination {
+		driver = "local"
+		config {
+			address = "localhost"
+			port = 9090
+		}
+	}
+
+	headers {
+		rewrite_host = "api.example.com"
+
+		cors {
+			allowed_origins = ["http://localhost:3000"]
+			allow_credentials = true
+		}
+
+		request {
+			set = {
+				X-Env = "local"
+			}
+			remove = ["Authorization"]
+		}
+
+		response {
+			set = {
+				Cache-Control = "no-store"
+			}
+		}
+	}
+}
+`
+
+const ingressInvalidCORS = `
+ingress "testing" {
+	source {
+		driver = "local"
+		config {
+			port = 8080
+		}
+	}
+
+	destination {
+		driver = "local"
+		config {
+			address = "localhost"
+			port = 9090
+		}
+	}
+
+	headers {
+		cors {
+			allowed_origins = ["*"]
+			allow_credentials = true
+		}
+	}
+}
+`
+
+const ingressTLS = `
+certificate_ca "root" {}
+
+certificate_leaf "kafka" {
+	ca = "certificate_ca.root"
+	dns_names = ["localhost"]
+}
+
+ingress "testing" {
+	destination {
+		driver = "k8s"
+		config {
+			port = 9092
+		}
+	}
+
+	source {
+		driver = "local"
+		config {
+			port = 9093
+		}
+	}
+
+	tls {
+		certificate = "certificate_leaf.kafka"
+	}
+}
+`
+
+const ingressInvalidTLS = `
+ingress "testing" {
+	destination {
+		driver = "k8s"
+		config {
+			port = 9092
+		}
+	}
+
+	source {
+		driver = "local"
+		config {
+			port = 9093
+		}
+	}
+
+	tls {
+		certificate = "certificate_ca.root"
+	}
+}
+`
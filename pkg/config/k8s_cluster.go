@@ -1,8 +1,20 @@
 package config
 
+import (
+	"fmt"
+	"strings"
+)
+
 // TypeK8sCluster is the resource string for a Cluster resource
 const TypeK8sCluster ResourceType = "k8s_cluster"
 
+const (
+	// K8sDriverK3s creates the cluster with k3s
+	K8sDriverK3s = "k3s"
+	// K8sDriverKind creates the cluster with kind node images and kubeadm
+	K8sDriverKind = "kind"
+)
+
 // K8sCluster is a config stanza which defines a Kubernetes or a Nomad cluster
 type K8sCluster struct {
 	// embedded type holding name, etc.
@@ -12,16 +24,146 @@ type K8sCluster struct {
 
 	Networks []NetworkAttachment `hcl:"network,block" json:"networks,omitempty"` // Attach to the correct network // only when Image is specified
 
-	Driver  string   `hcl:"driver" json:"driver,omitempty"`
-	Version string   `hcl:"version,optional" json:"version,omitempty"`
-	Nodes   int      `hcl:"nodes,optional" json:"nodes,omitempty"`
-	Images  []Image  `hcl:"image,block" json:"images,omitempty"`
-	Volumes []Volume `hcl:"volume,block" json:"volumes,omitempty"` // volumes to attach to the cluster
+	Driver      string   `hcl:"driver" json:"driver,omitempty"` // k3s or kind
+	Version     string   `hcl:"version,optional" json:"version,omitempty"`
+	Nodes       int      `hcl:"nodes,optional" json:"nodes,omitempty"`
+	WorkerNodes int      `hcl:"worker_nodes,optional" json:"worker_nodes,omitempty" mapstructure:"worker_nodes"` // number of agent nodes joined to the server
+	Images      []Image  `hcl:"image,block" json:"images,omitempty"`
+	CopyImages  []string `hcl:"copy_images,optional" json:"copy_images,omitempty" mapstructure:"copy_images"` // locally built images which are imported and kept in sync with shipyard push --watch
+	Volumes     []Volume `hcl:"volume,block" json:"volumes,omitempty"`                                        // volumes to attach to the cluster
 
 	Ports      []Port      `hcl:"port,block" json:"ports,omitempty"`                                       // ports to expose
 	PortRanges []PortRange `hcl:"port_range,block" json:"port_ranges,omitempty" mapstructure:"port_range"` // range of ports to expose
 
 	EnvVar map[string]string `hcl:"env_var,optional" json:"env_var,omitempty" mapstructure:"env_var"` // environment variables to set when starting the container
+
+	Ulimits []Ulimit          `hcl:"ulimit,block" json:"ulimits,omitempty"`     // ulimits to set for the cluster nodes
+	Sysctls map[string]string `hcl:"sysctls,optional" json:"sysctls,omitempty"` // namespaced kernel parameters to set for the cluster nodes
+
+	Resources *Resources `hcl:"resources,block" json:"resources,omitempty"` // resource constraints for each cluster node
+
+	ContainerdNamespace string `hcl:"containerd_namespace,optional" json:"containerd_namespace,omitempty" mapstructure:"containerd_namespace"` // containerd namespace images are imported to, defaults to k8s.io
+
+	Registries []string `hcl:"registries,optional" json:"registries,omitempty"` // registry resources the cluster trusts and uses as mirrors e.g. registry.local
+
+	ImageStrategy []string `hcl:"image_strategy,optional" json:"image_strategy,omitempty" mapstructure:"image_strategy"` // strategies used to add images to the nodes in order of preference, cache, registry or load, defaults to load
+
+	K3s *K3sConfig `hcl:"k3s,block" json:"k3s,omitempty"` // custom configuration for the k3s driver
+
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"` // maximum time for the cluster to start e.g. 10m, defaults to 300s
+	Retry   *Retry `hcl:"retry,block" json:"retry,omitempty"`        // policy for retrying failed image pulls
+}
+
+// Validate the cluster config
+func (k *K8sCluster) Validate() error {
+	if k.Driver != K8sDriverK3s && k.Driver != K8sDriverKind {
+		return fmt.Errorf("invalid driver '%s', must be %s or %s", k.Driver, K8sDriverK3s, K8sDriverKind)
+	}
+
+	err := validatePorts(k.Ports, k.PortRanges)
+	if err != nil {
+		return err
+	}
+
+	err = validateImageStrategy(k.ImageStrategy, k.Registries)
+	if err != nil {
+		return err
+	}
+
+	err = validateTimeoutAndRetry(k.Timeout, k.Retry)
+	if err != nil {
+		return err
+	}
+
+	if k.Driver == K8sDriverKind {
+		if k.K3s != nil {
+			return fmt.Errorf("the k3s block can only be used with the %s driver", K8sDriverK3s)
+		}
+
+		if len(k.Registries) > 0 {
+			return fmt.Errorf("registries are only supported by the %s driver", K8sDriverK3s)
+		}
+	}
+
+	return nil
+}
+
+// K3sConfig defines custom configuration for the k3s server and agents
+type K3sConfig struct {
+	ServerArgs   []string        `hcl:"server_args,optional" json:"server_args,omitempty" mapstructure:"server_args"`       // additional arguments for the k3s server
+	AgentArgs    []string        `hcl:"agent_args,optional" json:"agent_args,omitempty" mapstructure:"agent_args"`          // additional arguments for the k3s agents
+	FeatureGates map[string]bool `hcl:"feature_gates,optional" json:"feature_gates,omitempty" mapstructure:"feature_gates"` // Kubernetes feature gates to set for all components
+	Disable      []string        `hcl:"disable,optional" json:"disable,omitempty"`                                          // packaged components to disable e.g. servicelb, metrics-server
+	CNI          string          `hcl:"cni,optional" json:"cni,omitempty"`                                                  // flannel or none to install an alternate CNI
+}
+
+// K3sComponents are the packaged components which can be disabled
+var K3sComponents = []string{"coredns", "servicelb", "traefik", "local-storage", "metrics-server"}
+
+const (
+	// CNIFlannel is the default CNI bundled with k3s
+	CNIFlannel = "flannel"
+	// CNINone disables flannel so that an alternate CNI can be installed
+	CNINone = "none"
+)
+
+// Validate the k3s config
+func (k *K3sConfig) Validate() error {
+	for _, d := range k.Disable {
+		valid := false
+		for _, c := range K3sComponents {
+			if d == c {
+				valid = true
+			}
+		}
+
+		if !valid {
+			return fmt.Errorf("invalid component '%s' in disable, must be one of %s", d, strings.Join(K3sComponents, ", "))
+		}
+	}
+
+	if k.CNI != "" && k.CNI != CNIFlannel && k.CNI != CNINone {
+		return fmt.Errorf("invalid cni '%s', must be %s or %s", k.CNI, CNIFlannel, CNINone)
+	}
+
+	return nil
+}
+
+// Disabled returns true when the packaged component is disabled
+func (k *K3sConfig) Disabled(component string) bool {
+	if k == nil {
+		return false
+	}
+
+	for _, d := range k.Disable {
+		if d == component {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DefaultContainerdNamespace is the namespace used by Kubernetes to run images with containerd
+const DefaultContainerdNamespace = "k8s.io"
+
+// ClusterImages returns the images which are imported into the cluster
+func (k *K8sCluster) ClusterImages() []Image {
+	return clusterImages(k.Images, k.CopyImages)
+}
+
+// ImageStrategies returns the strategies used to add images to the nodes
+func (k *K8sCluster) ImageStrategies() []string {
+	return imageStrategies(k.ImageStrategy)
+}
+
+// Namespace returns the containerd namespace that images should be imported to
+func (k *K8sCluster) Namespace() string {
+	if k.ContainerdNamespace == "" {
+		return DefaultContainerdNamespace
+	}
+
+	return k.ContainerdNamespace
 }
 
 // NewK8sCluster creates new Cluster config with the correct defaults
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,6 +34,197 @@ func TestK8sClusterSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, cl.Info().Status)
 }
 
+func TestK8sClusterWithResourcesCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, clusterResources)
+
+	cl, err := c.FindResource("k8s_cluster.testing")
+	assert.NoError(t, err)
+
+	k := cl.(*K8sCluster)
+	assert.Equal(t, "1000m", k.Resources.CPU)
+	assert.Equal(t, "2Gi", k.Resources.Memory)
+}
+
+func TestK8sClusterWithInvalidResourcesReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, clusterInvalidResources)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid memory")
+}
+
+func TestK8sClusterDefaultsContainerdNamespace(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, clusterDefault)
+
+	cl, err := c.FindResource("k8s_cluster.testing")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "k8s.io", cl.(*K8sCluster).Namespace())
+}
+
+func TestK8sClusterWithContainerdNamespaceCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, clusterContainerdNamespace)
+
+	cl, err := c.FindResource("k8s_cluster.testing")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "shipyard", cl.(*K8sCluster).Namespace())
+}
+
+func TestK8sClusterParsesVolumeOptions(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, clusterVolumes)
+
+	cl, err := c.FindResource("k8s_cluster.testing")
+	assert.NoError(t, err)
+
+	k := cl.(*K8sCluster)
+	assert.Len(t, k.Volumes, 2)
+
+	assert.True(t, filepath.IsAbs(k.Volumes[0].Source))
+	assert.True(t, k.Volumes[0].ReadOnly)
+	assert.Equal(t, "rshared", k.Volumes[0].BindPropagation)
+
+	assert.Equal(t, "cache", k.Volumes[1].Source)
+}
+
+func TestK8sClusterWithInvalidVolumeReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, clusterInvalidVolume)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid bind_propagation 'master'")
+}
+
+func TestK8sClusterParsesImagePlatform(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, clusterImagePlatform)
+
+	cl, err := c.FindResource("k8s_cluster.testing")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "linux/amd64", cl.(*K8sCluster).Images[0].Platform)
+}
+
+func TestK8sClusterClusterImagesIncludesCopyImages(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, clusterCopyImages)
+
+	cl, err := c.FindResource("k8s_cluster.testing")
+	assert.NoError(t, err)
+
+	k := cl.(*K8sCluster)
+	assert.Equal(t, []string{"myapp:dev"}, k.CopyImages)
+	assert.Equal(t, []Image{{Name: "consul:1.10.0"}, {Name: "myapp:dev"}}, k.ClusterImages())
+}
+
+func TestK8sClusterWithInvalidImagePlatformReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, clusterInvalidImagePlatform)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid platform 'amd64'")
+}
+
+func TestK8sClusterParsesK3sConfig(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, clusterK3sConfig)
+
+	cl, err := c.FindResource("k8s_cluster.testing")
+	assert.NoError(t, err)
+
+	k := cl.(*K8sCluster).K3s
+	assert.Equal(t, []string{"--kube-apiserver-arg=v=2"}, k.ServerArgs)
+	assert.Equal(t, []string{"--node-label=tier=worker"}, k.AgentArgs)
+	assert.Equal(t, map[string]bool{"EphemeralContainers": true}, k.FeatureGates)
+	assert.Equal(t, []string{"servicelb", "metrics-server"}, k.Disable)
+	assert.Equal(t, CNINone, k.CNI)
+
+	assert.True(t, k.Disabled("servicelb"))
+	assert.False(t, k.Disabled("coredns"))
+}
+
+func TestK8sClusterWithInvalidK3sComponentReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, clusterInvalidK3sComponent)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid component 'flannel'")
+}
+
+func TestK8sClusterWithInvalidCNIReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, clusterInvalidCNI)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid cni 'cilium'")
+}
+
+func TestK8sClusterParsesKindDriver(t *testing.T) {
+	dir := CreateTestFiles(t, clusterKind)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	cl, err := c.FindResource("k8s_cluster.testing")
+	assert.NoError(t, err)
+	assert.Equal(t, K8sDriverKind, cl.(*K8sCluster).Driver)
+}
+
+func TestK8sClusterWithInvalidDriverReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, clusterInvalidDriver)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid driver 'minikube'")
+}
+
+func TestK8sClusterWithKindAndK3sConfigReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, clusterKindK3sConfig)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the k3s block can only be used with the k3s driver")
+}
+
+func TestK8sClusterImageStrategiesDefaultsToLoad(t *testing.T) {
+	k := &K8sCluster{}
+
+	assert.Equal(t, []string{ImageStrategyLoad}, k.ImageStrategies())
+}
+
+func TestK8sClusterWithInvalidImageStrategyReturnsError(t *testing.T) {
+	k := &K8sCluster{Driver: K8sDriverK3s, ImageStrategy: []string{"scp"}}
+
+	err := k.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid image_strategy 'scp'")
+}
+
+func TestK8sClusterWithRegistryImageStrategyAndNoRegistriesReturnsError(t *testing.T) {
+	k := &K8sCluster{Driver: K8sDriverK3s, ImageStrategy: []string{ImageStrategyRegistry, ImageStrategyLoad}}
+
+	err := k.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requires a registry")
+}
+
+func TestK8sClusterWithDuplicateImageStrategyReturnsError(t *testing.T) {
+	k := &K8sCluster{Driver: K8sDriverK3s, ImageStrategy: []string{ImageStrategyLoad, ImageStrategyLoad}}
+
+	assert.Error(t, k.Validate())
+}
+
+func TestK3sConfigDisabledWhenNil(t *testing.T) {
+	var k *K3sConfig
+
+	assert.False(t, k.Disabled("traefik"))
+}
+
 const clusterDefault = `
 k8s_cluster "testing" {
 	network {
@@ -51,3 +243,160 @@ k8s_cluster "testing" {
 	driver = "k3s"
 }
 `
+
+const clusterResources = `
+k8s_cluster "testing" {
+	network {
+		name = "network.test"
+	}
+	driver = "k3s"
+
+	resources {
+		cpu = "1000m"
+		memory = "2Gi"
+	}
+}
+`
+
+const clusterInvalidResources = `
+k8s_cluster "testing" {
+	network {
+		name = "network.test"
+	}
+	driver = "k3s"
+
+	resources {
+		memory = "2GB"
+	}
+}
+`
+
+const clusterContainerdNamespace = `
+k8s_cluster "testing" {
+	driver = "k3s"
+
+	containerd_namespace = "shipyard"
+}
+`
+
+const clusterVolumes = `
+k8s_cluster "testing" {
+	driver = "k3s"
+
+	volume {
+		source           = "./src"
+		destination      = "/src"
+		read_only        = true
+		bind_propagation = "rshared"
+	}
+
+	volume {
+		source      = "cache"
+		destination = "/cache"
+		type        = "volume"
+	}
+}
+`
+
+const clusterInvalidVolume = `
+k8s_cluster "testing" {
+	driver = "k3s"
+
+	volume {
+		source           = "./src"
+		destination      = "/src"
+		bind_propagation = "master"
+	}
+}
+`
+
+const clusterImagePlatform = `
+k8s_cluster "testing" {
+	driver = "k3s"
+
+	image {
+		name     = "consul:1.10.0"
+		platform = "linux/amd64"
+	}
+}
+`
+
+const clusterInvalidImagePlatform = `
+k8s_cluster "testing" {
+	driver = "k3s"
+
+	image {
+		name     = "consul:1.10.0"
+		platform = "amd64"
+	}
+}
+`
+
+const clusterK3sConfig = `
+k8s_cluster "testing" {
+	driver = "k3s"
+
+	k3s {
+		server_args   = ["--kube-apiserver-arg=v=2"]
+		agent_args    = ["--node-label=tier=worker"]
+		feature_gates = { EphemeralContainers = true }
+		disable       = ["servicelb", "metrics-server"]
+		cni           = "none"
+	}
+}
+`
+
+const clusterInvalidK3sComponent = `
+k8s_cluster "testing" {
+	driver = "k3s"
+
+	k3s {
+		disable = ["flannel"]
+	}
+}
+`
+
+const clusterInvalidCNI = `
+k8s_cluster "testing" {
+	driver = "k3s"
+
+	k3s {
+		cni = "cilium"
+	}
+}
+`
+
+const clusterKind = `
+k8s_cluster "testing" {
+	driver = "kind"
+	worker_nodes = 2
+}
+`
+
+const clusterInvalidDriver = `
+k8s_cluster "testing" {
+	driver = "minikube"
+}
+`
+
+const clusterKindK3sConfig = `
+k8s_cluster "testing" {
+	driver = "kind"
+
+	k3s {
+		cni = "none"
+	}
+}
+`
+
+const clusterCopyImages = `
+k8s_cluster "testing" {
+	driver = "k3s"
+
+	image {
+		name = "consul:1.10.0"
+	}
+
+	copy_images = ["myapp:dev"]
+}
+`
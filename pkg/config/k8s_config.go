@@ -1,5 +1,11 @@
 package config
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // TypeK8sConfig defines the string type for the Kubernetes config resource
 const TypeK8sConfig ResourceType = "k8s_config"
 
@@ -11,13 +17,69 @@ type K8sConfig struct {
 
 	// Cluster is the name of the cluster to apply configuration to
 	Cluster string `hcl:"cluster" json:"cluster"`
-	// Path of a file or directory of Kubernetes config files to apply
-	Paths []string `hcl:"paths" validator:"filepath" json:"paths"`
+	// Path of a file or directory of Kubernetes config files to apply, paths can be
+	// glob patterns e.g. manifests/**/*.yaml or URLs which are downloaded when applied
+	Paths []string `hcl:"paths,optional" validator:"filepath" json:"paths,omitempty"`
+	// Kustomize is the path of a directory containing a kustomization which is rendered and applied
+	Kustomize string `hcl:"kustomize,optional" json:"kustomize,omitempty"`
 	// WaitUntilReady when set to true waits until all resources have been created and are in a "Running" state
 	WaitUntilReady bool `hcl:"wait_until_ready" json:"wait_until_ready" mapstructure:"wait_until_ready"`
 
 	// HealthCheck defines a health check for the resource
 	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty" mapstructure:"health_check"`
+
+	// Destroy configures how the resources are removed when the config is destroyed
+	Destroy *K8sDestroy `hcl:"destroy,block" json:"destroy,omitempty"`
+
+	// Verify declares the checksums or signatures the files downloaded from paths must match before they are applied
+	Verify []Verify `hcl:"verify,block" json:"verify,omitempty"`
+}
+
+// DefaultK8sDestroyTimeout is the time to wait for Kubernetes resources to be deleted
+const DefaultK8sDestroyTimeout = 120 * time.Second
+
+// K8sDestroy configures how Kubernetes resources are removed, destroy waits
+// until the resources have been deleted from the cluster
+type K8sDestroy struct {
+	// Timeout is the maximum time to wait for the resources to be deleted, default 120s
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
+
+	// ForceRemoveFinalizers removes the finalizers from any resources which have not been
+	// deleted when the timeout expires, e.g. namespaces stuck in Terminating
+	ForceRemoveFinalizers bool `hcl:"force_remove_finalizers,optional" json:"force_remove_finalizers,omitempty" mapstructure:"force_remove_finalizers"`
+}
+
+// Validate the destroy options
+func (d *K8sDestroy) Validate() error {
+	if d == nil || d.Timeout == "" {
+		return nil
+	}
+
+	if _, err := time.ParseDuration(d.Timeout); err != nil {
+		return fmt.Errorf("invalid destroy timeout '%s', %s", d.Timeout, err)
+	}
+
+	return nil
+}
+
+// TimeoutDuration returns the time to wait for resources to be deleted
+func (d *K8sDestroy) TimeoutDuration() time.Duration {
+	if d == nil || d.Timeout == "" {
+		return DefaultK8sDestroyTimeout
+	}
+
+	to, err := time.ParseDuration(d.Timeout)
+	if err != nil {
+		return DefaultK8sDestroyTimeout
+	}
+
+	return to
+}
+
+// ForceRemove returns true when finalizers should be removed from resources
+// which are not deleted within the timeout
+func (d *K8sDestroy) ForceRemove() bool {
+	return d != nil && d.ForceRemoveFinalizers
 }
 
 // NewK8sConfig creates a kubernetes config resource with the correct defaults
@@ -26,6 +88,47 @@ func NewK8sConfig(name string) *K8sConfig {
 }
 
 // Validate the K8sConfig and return errors
-func (b *K8sConfig) Validate() []error {
+func (b *K8sConfig) Validate() error {
+	if len(b.Paths) == 0 && b.Kustomize == "" {
+		return fmt.Errorf("paths or kustomize must be specified")
+	}
+
+	for i := range b.Verify {
+		v := &b.Verify[i]
+
+		found := false
+		for _, p := range b.Paths {
+			if IsRemotePath(p) && p == v.Path {
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("verify path must be one of the remote paths, got '%s'", v.Path)
+		}
+
+		err := v.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.Destroy.Validate()
+}
+
+// VerifyFor returns the verification declared for the path, nil when the path is not verified
+func (b *K8sConfig) VerifyFor(p string) *Verify {
+	for i := range b.Verify {
+		if b.Verify[i].Path == p {
+			return &b.Verify[i]
+		}
+	}
+
 	return nil
 }
+
+// IsRemotePath returns true when the path is a URL which must be downloaded
+// e.g. https://example.com/manifest.yaml or github.com/org/repo//manifests
+func IsRemotePath(p string) bool {
+	return strings.Contains(p, "://") || strings.Contains(p, "::") || strings.HasPrefix(p, "github.com/")
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -45,6 +47,87 @@ func TestMakesPathAbsolute(t *testing.T) {
 	assert.Contains(t, kc.(*K8sConfig).Paths[1], base)
 }
 
+func TestK8sConfigKeepsRemotePathsAndMakesGlobsAbsolute(t *testing.T) {
+	c, base := CreateConfigFromStrings(t, k8sConfigRemoteAndGlob)
+
+	kc, err := c.FindResource("k8s_config.test")
+	assert.NoError(t, err)
+
+	paths := kc.(*K8sConfig).Paths
+	assert.Equal(t, "https://example.com/manifests/app.yaml", paths[0])
+	assert.Equal(t, "github.com/shipyard-run/blueprints//manifests", paths[1])
+	assert.Equal(t, filepath.Join(base, "manifests", "**", "*.yaml"), paths[2])
+}
+
+func TestK8sConfigParsesDestroyOptions(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, k8sConfigDestroy)
+
+	cc, err := c.FindResource("k8s_config.test")
+	assert.NoError(t, err)
+
+	d := cc.(*K8sConfig).Destroy
+	assert.Equal(t, 30*time.Second, d.TimeoutDuration())
+	assert.True(t, d.ForceRemove())
+}
+
+func TestK8sConfigWithInvalidDestroyTimeoutReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, k8sConfigInvalidDestroy)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid destroy timeout 'soon'")
+}
+
+func TestK8sConfigMakesKustomizePathAbsolute(t *testing.T) {
+	c, base := CreateConfigFromStrings(t, k8sConfigKustomize)
+
+	kc, err := c.FindResource("k8s_config.test")
+	assert.NoError(t, err)
+
+	assert.Empty(t, kc.(*K8sConfig).Paths)
+	assert.Equal(t, filepath.Join(base, "overlays/dev"), kc.(*K8sConfig).Kustomize)
+}
+
+func TestK8sConfigWithoutPathsOrKustomizeReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, k8sConfigNoPaths)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "paths or kustomize must be specified")
+}
+
+func TestK8sConfigParsesVerify(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, k8sConfigVerify)
+
+	kc, err := c.FindResource("k8s_config.test")
+	assert.NoError(t, err)
+
+	v := kc.(*K8sConfig).VerifyFor("https://example.com/manifests/app.yaml")
+	assert.NotNil(t, v)
+	assert.Equal(t, "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", v.Checksum)
+
+	assert.Nil(t, kc.(*K8sConfig).VerifyFor("./manifests/app.yaml"))
+}
+
+func TestK8sConfigWithVerifyForUnknownPathReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, k8sConfigVerifyUnknownPath)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "verify path must be one of the remote paths")
+}
+
+func TestK8sDestroyDefaults(t *testing.T) {
+	var d *K8sDestroy
+
+	assert.Equal(t, DefaultK8sDestroyTimeout, d.TimeoutDuration())
+	assert.False(t, d.ForceRemove())
+	assert.NoError(t, d.Validate())
+}
+
 var k8sConfigValid = `
 k8s_cluster "cloud" {
   driver  = "k3s" // default
@@ -93,3 +176,109 @@ k8s_config "test" {
 	}
 }
 `
+
+var k8sConfigDestroy = `
+k8s_cluster "cloud" {
+  driver  = "k3s" // default
+
+  network {
+	  name = "network.k8s"
+  }
+}
+
+k8s_config "test" {
+	cluster = "cluster.cloud"
+	paths = ["/tmp/files"]
+	wait_until_ready = true
+
+	destroy {
+		timeout = "30s"
+		force_remove_finalizers = true
+	}
+}
+`
+
+var k8sConfigInvalidDestroy = `
+k8s_cluster "cloud" {
+  driver  = "k3s" // default
+
+  network {
+	  name = "network.k8s"
+  }
+}
+
+k8s_config "test" {
+	cluster = "cluster.cloud"
+	paths = ["/tmp/files"]
+	wait_until_ready = true
+
+	destroy {
+		timeout = "soon"
+	}
+}
+`
+
+var k8sConfigKustomize = `
+k8s_config "test" {
+	cluster = "cluster.cloud"
+	kustomize = "./overlays/dev"
+	wait_until_ready = true
+}
+`
+
+var k8sConfigNoPaths = `
+k8s_config "test" {
+	cluster = "cluster.cloud"
+	wait_until_ready = false
+}
+`
+
+const k8sConfigRemoteAndGlob = `
+k8s_cluster "cluster1" {
+	driver = "k3s"
+}
+
+k8s_config "test" {
+	cluster = "k8s_cluster.cluster1"
+	paths = [
+		"https://example.com/manifests/app.yaml",
+		"github.com/shipyard-run/blueprints//manifests",
+		"./manifests/**/*.yaml",
+	]
+	wait_until_ready = true
+}
+`
+
+const k8sConfigVerify = `
+k8s_cluster "cluster1" {
+	driver = "k3s"
+}
+
+k8s_config "test" {
+	cluster = "k8s_cluster.cluster1"
+	paths = ["https://example.com/manifests/app.yaml"]
+	wait_until_ready = true
+
+	verify {
+		path = "https://example.com/manifests/app.yaml"
+		checksum = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	}
+}
+`
+
+const k8sConfigVerifyUnknownPath = `
+k8s_cluster "cluster1" {
+	driver = "k3s"
+}
+
+k8s_config "test" {
+	cluster = "k8s_cluster.cluster1"
+	paths = ["https://example.com/manifests/app.yaml"]
+	wait_until_ready = true
+
+	verify {
+		path = "https://example.com/manifests/other.yaml"
+		checksum = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	}
+}
+`
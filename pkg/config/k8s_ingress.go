@@ -1,5 +1,7 @@
 package config
 
+import "fmt"
+
 // TypeK8sIngress is the resource string for the type
 const TypeK8sIngress ResourceType = "k8s_ingress"
 
@@ -35,9 +37,55 @@ type K8sIngress struct {
 	// Namespace is the Kubernetes namespace
 	Namespace string `hcl:"namespace,optional" json:"namespace,omitempty"`
 
+	// Ports to expose, when no ports are set for a service the ports
+	// are discovered from the service when the ingress is created
+	Ports []Port `hcl:"port,block" json:"ports,omitempty"`
+
+	// Targets are additional services exposed by the ingress, each target
+	// is proxied by its own container named [service].[name]
+	Targets []K8sIngressTarget `hcl:"target,block" json:"targets,omitempty"`
+
+	// Connector routes the host ports through the connector rather than
+	// publishing them from the ingress containers
+	Connector bool `hcl:"connector,optional" json:"connector,omitempty"`
+}
+
+// K8sIngressTarget defines a Kubernetes service exposed by an ingress
+type K8sIngressTarget struct {
+	Service   string `hcl:"service" json:"service"`
+	Namespace string `hcl:"namespace,optional" json:"namespace,omitempty"` // defaults to the namespace of the ingress
+
+	// Ports to expose, when not set the ports are discovered from the service
 	Ports []Port `hcl:"port,block" json:"ports,omitempty"`
 }
 
+// Validate the config
+func (i *K8sIngress) Validate() error {
+	if (i.Deployment != "" || i.Pod != "") && len(i.Ports) == 0 {
+		return fmt.Errorf("ports must be specified for deployments and pods, only the ports of services can be discovered")
+	}
+
+	err := validatePorts(i.Ports, nil)
+	if err != nil {
+		return err
+	}
+
+	services := map[string]bool{}
+	for _, t := range i.Targets {
+		if services[t.Service] {
+			return fmt.Errorf("service %s is defined by more than one target", t.Service)
+		}
+		services[t.Service] = true
+
+		err := validatePorts(t.Ports, nil)
+		if err != nil {
+			return fmt.Errorf("target %s %s", t.Service, err)
+		}
+	}
+
+	return nil
+}
+
 // NewK8sIngress creates a new ingress with the correct defaults
 func NewK8sIngress(name string) *K8sIngress {
 	return &K8sIngress{ResourceInfo: ResourceInfo{Name: name, Type: TypeK8sIngress, Status: PendingCreation}}
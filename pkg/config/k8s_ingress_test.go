@@ -32,6 +32,65 @@ func TestK8sIngressSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, cl.Info().Status)
 }
 
+func TestK8sIngressWithTLSAndUDPPortsCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, k8sIngressTLSAndUDP)
+
+	cl, err := c.FindResource("k8s_ingress.testing")
+	assert.NoError(t, err)
+
+	i := cl.(*K8sIngress)
+	assert.NotNil(t, i.Ports[0].TLS)
+	assert.Equal(t, "certificate_leaf.kafka", i.Ports[0].TLS.Certificate)
+	assert.Equal(t, "udp", i.Ports[1].Protocol)
+
+	assert.Contains(t, i.DependsOn, "certificate_leaf.kafka")
+}
+
+func TestK8sIngressWithTLSOnUDPPortReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, k8sIngressTLSOnUDP)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "tls can not be used with udp")
+}
+
+func TestK8sIngressWithTargetsCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, k8sIngressTargets)
+
+	cl, err := c.FindResource("k8s_ingress.testing")
+	assert.NoError(t, err)
+
+	i := cl.(*K8sIngress)
+	assert.True(t, i.Connector)
+	assert.Len(t, i.Targets, 2)
+	assert.Equal(t, "web", i.Targets[0].Service)
+	assert.Empty(t, i.Targets[0].Ports)
+	assert.Equal(t, "backend", i.Targets[1].Namespace)
+	assert.Equal(t, "8080", i.Targets[1].Ports[0].Host)
+
+	// the host ports of the targets are checked for conflicts
+	assert.Len(t, HostPorts(i), 1)
+}
+
+func TestK8sIngressWithDuplicateTargetsReturnsError(t *testing.T) {
+	i := NewK8sIngress("testing")
+	i.Targets = []K8sIngressTarget{{Service: "web"}, {Service: "web"}}
+
+	err := i.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "more than one target")
+}
+
+func TestK8sIngressWithDeploymentAndNoPortsReturnsError(t *testing.T) {
+	i := NewK8sIngress("testing")
+	i.Deployment = "web"
+
+	err := i.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ports must be specified")
+}
+
 const k8sIngressDefault = `
 network "test" {
 	subnet = "10.0.0.0/24"
@@ -65,3 +124,83 @@ k8s_ingress "testing" {
 	cluster = "k8s_cluster.testing"
 }
 `
+
+const k8sIngressTLSAndUDP = `
+certificate_ca "root" {}
+
+certificate_leaf "kafka" {
+	ca = "certificate_ca.root"
+	dns_names = ["localhost"]
+}
+
+k8s_cluster "testing" {
+	driver = "k3s"
+}
+
+k8s_ingress "testing" {
+	cluster = "k8s_cluster.testing"
+	service = "kafka"
+
+	port {
+		local = 9093
+		remote = 9092
+		host = 9093
+
+		tls {
+			certificate = "certificate_leaf.kafka"
+		}
+	}
+
+	port {
+		local = 53
+		remote = 53
+		host = 5353
+		protocol = "udp"
+	}
+}
+`
+
+const k8sIngressTLSOnUDP = `
+k8s_cluster "testing" {
+	driver = "k3s"
+}
+
+k8s_ingress "testing" {
+	cluster = "k8s_cluster.testing"
+
+	port {
+		local = 53
+		remote = 53
+		host = 5353
+		protocol = "udp"
+
+		tls {}
+	}
+}
+`
+
+const k8sIngressTargets = `
+k8s_cluster "testing" {
+	driver = "k3s"
+}
+
+k8s_ingress "testing" {
+	cluster   = "k8s_cluster.testing"
+	connector = true
+
+	target {
+		service = "web"
+	}
+
+	target {
+		service   = "api"
+		namespace = "backend"
+
+		port {
+			local  = 8080
+			remote = 8080
+			host   = 8080
+		}
+	}
+}
+`
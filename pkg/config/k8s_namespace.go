@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// TypeK8sNamespace defines the string type for the Kubernetes namespace resource
+const TypeK8sNamespace ResourceType = "k8s_namespace"
+
+// NetworkPolicyDenyAll blocks all ingress and egress traffic for pods in the namespace
+const NetworkPolicyDenyAll = "deny_all"
+
+// NetworkPolicyDenyIngress blocks all ingress traffic for pods in the namespace
+const NetworkPolicyDenyIngress = "deny_ingress"
+
+// NetworkPolicyAllowSameNamespace only allows ingress traffic from pods in the same namespace
+const NetworkPolicyAllowSameNamespace = "allow_same_namespace"
+
+// K8sNamespace creates a Kubernetes namespace, deleting the namespace when the
+// resource is destroyed removes all the resources which were created in it
+type K8sNamespace struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	// Cluster is the name of the cluster to create the namespace in
+	Cluster string `hcl:"cluster" json:"cluster"`
+
+	// Namespace is the name of the namespace, if blank uses the resource name
+	Namespace string `hcl:"namespace,optional" json:"namespace,omitempty"`
+
+	Labels      map[string]string `hcl:"labels,optional" json:"labels,omitempty"`           // labels to add to the namespace
+	Annotations map[string]string `hcl:"annotations,optional" json:"annotations,omitempty"` // annotations to add to the namespace
+
+	// ResourceQuota sets hard limits for the namespace e.g. pods = "10", "limits.memory" = "2Gi"
+	ResourceQuota map[string]string `hcl:"resource_quota,optional" json:"resource_quota,omitempty" mapstructure:"resource_quota"`
+
+	// DefaultNetworkPolicy is created for all pods in the namespace [deny_all, deny_ingress, allow_same_namespace]
+	DefaultNetworkPolicy string `hcl:"default_network_policy,optional" json:"default_network_policy,omitempty" mapstructure:"default_network_policy"`
+}
+
+// NewK8sNamespace creates a kubernetes namespace resource with the correct defaults
+func NewK8sNamespace(name string) *K8sNamespace {
+	return &K8sNamespace{ResourceInfo: ResourceInfo{Name: name, Type: TypeK8sNamespace, Status: PendingCreation}}
+}
+
+// NamespaceName returns the name of the namespace which is created in the cluster
+func (n *K8sNamespace) NamespaceName() string {
+	if n.Namespace != "" {
+		return n.Namespace
+	}
+
+	return n.Name
+}
+
+// Validate the config
+func (n *K8sNamespace) Validate() error {
+	if errs := validation.IsDNS1123Label(n.NamespaceName()); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace '%s', %s", n.NamespaceName(), errs[0])
+	}
+
+	for k, v := range n.ResourceQuota {
+		_, err := resource.ParseQuantity(v)
+		if err != nil {
+			return fmt.Errorf("invalid resource_quota '%s' for %s, %s", v, k, err)
+		}
+	}
+
+	switch n.DefaultNetworkPolicy {
+	case "", NetworkPolicyDenyAll, NetworkPolicyDenyIngress, NetworkPolicyAllowSameNamespace:
+	default:
+		return fmt.Errorf("invalid default_network_policy '%s', valid options are %s, %s, %s", n.DefaultNetworkPolicy, NetworkPolicyDenyAll, NetworkPolicyDenyIngress, NetworkPolicyAllowSameNamespace)
+	}
+
+	return nil
+}
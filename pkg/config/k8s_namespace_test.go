@@ -0,0 +1,91 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreatesK8sNamespace(t *testing.T) {
+	c := NewK8sNamespace("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeK8sNamespace, c.Type)
+	assert.Equal(t, "abc", c.NamespaceName())
+}
+
+func TestK8sNamespaceCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, k8sNamespaceValid)
+
+	r, err := c.FindResource("k8s_namespace.apps")
+	assert.NoError(t, err)
+
+	ns := r.(*K8sNamespace)
+	assert.Equal(t, "my-apps", ns.NamespaceName())
+	assert.Equal(t, "platform", ns.Labels["team"])
+	assert.Equal(t, "10", ns.ResourceQuota["pods"])
+	assert.Equal(t, NetworkPolicyDenyIngress, ns.DefaultNetworkPolicy)
+	assert.Contains(t, ns.DependsOn, "k8s_cluster.k3s")
+}
+
+func TestHelmInManagedNamespaceDependsOnNamespace(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, k8sNamespaceValid)
+
+	r, err := c.FindResource("helm.consul")
+	assert.NoError(t, err)
+
+	assert.Contains(t, r.Info().DependsOn, "k8s_namespace.apps")
+}
+
+func TestK8sNamespaceWithInvalidNameReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, k8sNamespaceInvalidName)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid namespace 'my_apps'")
+}
+
+func TestK8sNamespaceValidateReturnsErrorForInvalidConfig(t *testing.T) {
+	ns := NewK8sNamespace("apps")
+	ns.ResourceQuota = map[string]string{"limits.memory": "2GB"}
+	assert.Error(t, ns.Validate())
+
+	ns = NewK8sNamespace("apps")
+	ns.DefaultNetworkPolicy = "allow_all"
+	assert.Error(t, ns.Validate())
+}
+
+const k8sNamespaceValid = `
+k8s_cluster "k3s" {
+	driver = "k3s"
+}
+
+k8s_namespace "apps" {
+	cluster   = "k8s_cluster.k3s"
+	namespace = "my-apps"
+
+	labels = {
+		team = "platform"
+	}
+
+	resource_quota = {
+		pods            = "10"
+		"limits.memory" = "2Gi"
+	}
+
+	default_network_policy = "deny_ingress"
+}
+
+helm "consul" {
+	cluster   = "k8s_cluster.k3s"
+	chart     = "hashicorp/consul"
+	namespace = "my-apps"
+}
+`
+
+const k8sNamespaceInvalidName = `
+k8s_namespace "my_apps" {
+	cluster = "k8s_cluster.k3s"
+}
+`
@@ -20,6 +20,9 @@ type LegacyIngress struct {
 	Service   string `hcl:"service,optional" json:"service,omitempty"`
 	Namespace string `hcl:"namespace,optional" json:"namespace,omitempty"`
 	Ports     []Port `hcl:"port,block" json:"ports,omitempty"`
+
+	// Connector routes the host ports through the connector, set by the k8s_ingress
+	Connector bool `json:"connector,omitempty"`
 }
 
 // NewIngress creates a new ingress with the correct defaults
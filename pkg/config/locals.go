@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// BlockLocals is the block which defines local values, locals are not
+// resources, they are evaluated when the blueprint is parsed and can be
+// referenced by any resource in the same folder as local.[name]
+//
+//	locals {
+//	  consul_version = "1.8.0"
+//	  consul_image   = "consul:${local.consul_version}"
+//	}
+const BlockLocals = "locals"
+
+// localValue is a value defined in a locals block which has not been evaluated
+type localValue struct {
+	file string
+	attr *hclsyntax.Attribute
+}
+
+// parseLocals evaluates the locals blocks in the given files and adds the values
+// to the context. Locals can reference variables, random values, and other locals
+// in any of the files, values are evaluated once the locals they reference are known.
+func parseLocals(files []string) error {
+	pending := map[string]localValue{}
+
+	for _, f := range files {
+		body, err := parseHCLBody(f)
+		if err != nil {
+			return err
+		}
+
+		body = applyOverlay(f, body)
+
+		for _, b := range body.Blocks {
+			if b.Type != BlockLocals {
+				continue
+			}
+
+			if len(b.Labels) > 0 {
+				return fmt.Errorf("Error in file '%s': locals blocks do not have a name, please specify locals using the syntax 'locals {}'", f)
+			}
+
+			if len(b.Body.Blocks) > 0 {
+				return fmt.Errorf("Error in file '%s': locals blocks can only contain attributes", f)
+			}
+
+			for name, a := range b.Body.Attributes {
+				// locals in the environment overlay replace the values in the blueprint
+				if p, ok := pending[name]; ok && (currentOverlay == nil || f != currentOverlay.file) {
+					return fmt.Errorf("Error in file '%s': local '%s' is already defined in file '%s'", f, name, p.file)
+				}
+
+				pending[name] = localValue{file: f, attr: a}
+			}
+		}
+	}
+
+	for len(pending) > 0 {
+		evaluated := false
+
+		for _, name := range sortedLocals(pending) {
+			lv := pending[name]
+
+			if referencesLocals(lv.attr, pending) {
+				continue
+			}
+
+			setFileContext(lv.file)
+
+			val, diag := lv.attr.Expr.Value(ctx)
+			if diag.HasErrors() {
+				return fmt.Errorf("Error in file '%s': local '%s' %s", lv.file, name, diag.Error())
+			}
+
+			setContextLocal(name, val)
+			delete(pending, name)
+
+			evaluated = true
+		}
+
+		// the remaining locals reference each other
+		if !evaluated {
+			return fmt.Errorf("Unable to evaluate locals %s, the values reference each other", strings.Join(sortedLocals(pending), ", "))
+		}
+	}
+
+	return nil
+}
+
+// referencesLocals returns true when the attribute references any of the given locals
+func referencesLocals(a *hclsyntax.Attribute, locals map[string]localValue) bool {
+	for _, t := range a.Expr.Variables() {
+		if t.RootName() != "local" || len(t) < 2 {
+			continue
+		}
+
+		if n, ok := t[1].(hcl.TraverseAttr); ok {
+			if _, ok := locals[n.Name]; ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func sortedLocals(locals map[string]localValue) []string {
+	names := []string{}
+	for k := range locals {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// scopeLocals removes the locals from the context so that the locals defined in a
+// folder are only visible to the resources in that folder, the returned function
+// restores the locals of the parent folder
+func scopeLocals() func() {
+	parent, ok := ctx.Variables["local"]
+	delete(ctx.Variables, "local")
+
+	return func() {
+		if ok {
+			ctx.Variables["local"] = parent
+			return
+		}
+
+		delete(ctx.Variables, "local")
+	}
+}
+
+func setContextLocal(key string, value cty.Value) {
+	valMap := map[string]cty.Value{}
+
+	// get the existing map
+	if m, ok := ctx.Variables["local"]; ok {
+		valMap = m.AsValueMap()
+	}
+
+	valMap[key] = value
+
+	ctx.Variables["local"] = cty.ObjectVal(valMap)
+}
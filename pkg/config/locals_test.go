@@ -0,0 +1,195 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalsAreInterpolatedInResources(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, localsValid)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	cc := r.(*Container)
+	assert.Equal(t, "consul:1.8.0", cc.Image.Name)
+	assert.Equal(t, map[string]string{"CONSUL_VERSION": "1.8.0", "DC": "dc1"}, cc.EnvVar)
+}
+
+func TestLocalsCanReferenceLocalsInOtherFiles(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, localsContainer, localsImage, localsVersion)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	cc := r.(*Container)
+	assert.Equal(t, "consul:1.9.0", cc.Image.Name)
+}
+
+func TestLocalsCanReferenceVariablesOverriddenWithEnv(t *testing.T) {
+	t.Setenv("SY_VAR_consul_version", "1.10.0")
+
+	c, _ := CreateConfigFromStrings(t, localsValid)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	cc := r.(*Container)
+	assert.Equal(t, "consul:1.10.0", cc.Image.Name)
+}
+
+func TestLocalsWithCycleReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, localsCycle)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "a, b")
+}
+
+func TestLocalsDefinedTwiceReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, localsVersion, localsVersion)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+}
+
+func TestLocalsWithNameReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, localsWithName)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+}
+
+func TestLocalsAreScopedToModules(t *testing.T) {
+	dir := CreateTestFiles(t, localsWithModule)
+
+	mod := filepath.Join(dir, "module")
+	err := os.MkdirAll(mod, os.ModePerm)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(mod, "module.hcl"), []byte(localsModule), os.ModePerm)
+	assert.NoError(t, err)
+
+	c := New()
+	err = ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.vault")
+	assert.NoError(t, err)
+	assert.Equal(t, "vault:1.6.0", r.(*Container).Image.Name)
+
+	// the locals of the module do not replace the locals of the blueprint
+	r, err = c.FindResource("container.consul")
+	assert.NoError(t, err)
+	assert.Equal(t, "consul:1.8.0", r.(*Container).Image.Name)
+}
+
+func TestOverlayReplacesLocals(t *testing.T) {
+	dir := setupOverlay(t, "staging", localsOverlay, localsValid)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+	assert.Equal(t, "consul-enterprise:1.8.0", r.(*Container).Image.Name)
+}
+
+const localsValid = `
+variable "consul_version" {
+  default = "1.8.0"
+}
+
+locals {
+  image = "${local.repo}:${var.consul_version}"
+  repo  = "consul"
+
+  env = {
+    CONSUL_VERSION = var.consul_version
+    DC             = "dc1"
+  }
+}
+
+container "consul" {
+  image {
+    name = local.image
+  }
+
+  env_var = local.env
+}
+`
+
+const localsContainer = `
+container "consul" {
+  image {
+    name = local.image
+  }
+}
+`
+
+const localsImage = `
+locals {
+  image = "consul:${local.version}"
+}
+`
+
+const localsVersion = `
+locals {
+  version = "1.9.0"
+}
+`
+
+const localsCycle = `
+locals {
+  a = local.b
+  b = local.a
+}
+`
+
+const localsWithName = `
+locals "consul" {
+  version = "1.9.0"
+}
+`
+
+const localsWithModule = `
+locals {
+  version = "1.8.0"
+}
+
+module "vault" {
+  source = "./module"
+}
+
+container "consul" {
+  image {
+    name = "consul:${local.version}"
+  }
+}
+`
+
+const localsModule = `
+locals {
+  version = "1.6.0"
+}
+
+container "vault" {
+  image {
+    name = "vault:${local.version}"
+  }
+}
+`
+
+const localsOverlay = `
+locals {
+  repo = "consul-enterprise"
+}
+`
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// TypeLogSink is the resource string for a LogSink resource
+const TypeLogSink ResourceType = "log_sink"
+
+// LogSink runs a log shipper which continuously sends the logs of the resources in the
+// environment, the same streams read by the log command, to Loki, syslog, or files on
+// the host for the life of the environment
+type LogSink struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	Networks []NetworkAttachment `hcl:"network,block" json:"networks,omitempty"` // networks to attach the log shipper to, needed to reach sinks running in the environment
+
+	Image     *Image   `hcl:"image,block" json:"image,omitempty"`            // override the default log shipper image
+	Resources []string `hcl:"resources,optional" json:"resources,omitempty"` // glob patterns for the resources to ship the logs for e.g. container.*, all resources when not set
+
+	Loki   *LokiSink   `hcl:"loki,block" json:"loki,omitempty"`     // send the logs to Loki
+	Syslog *SyslogSink `hcl:"syslog,block" json:"syslog,omitempty"` // send the logs to a syslog server
+	File   *FileSink   `hcl:"file,block" json:"file,omitempty"`     // write the logs to files on the host
+}
+
+// LokiSink sends the logs to the push API of a Loki server
+type LokiSink struct {
+	Address string            `hcl:"address" json:"address"`                  // address of the Loki server e.g. http://loki.container.shipyard.run:3100
+	Labels  map[string]string `hcl:"labels,optional" json:"labels,omitempty"` // additional labels to add to the log streams
+}
+
+// SyslogSink sends the logs to a syslog server in RFC 5424 format
+type SyslogSink struct {
+	Address  string `hcl:"address" json:"address"`                      // address of the syslog server e.g. syslog.container.shipyard.run:514
+	Protocol string `hcl:"protocol,optional" json:"protocol,omitempty"` // tcp or udp, defaults to udp
+}
+
+// FileSink writes the logs for each container to a separate file in a folder on the host
+type FileSink struct {
+	Path string `hcl:"path" json:"path"` // folder to write the log files to
+}
+
+// NewLogSink creates a LogSink resource with the default values
+func NewLogSink(name string) *LogSink {
+	return &LogSink{ResourceInfo: ResourceInfo{Name: name, Type: TypeLogSink, Status: PendingCreation}}
+}
+
+// Validate the config
+func (l *LogSink) Validate() error {
+	if l.Loki == nil && l.Syslog == nil && l.File == nil {
+		return fmt.Errorf("at least one loki, syslog, or file sink must be defined")
+	}
+
+	for _, r := range l.Resources {
+		if _, err := path.Match(r, ""); err != nil {
+			return fmt.Errorf("invalid resource pattern '%s': %s", r, err)
+		}
+	}
+
+	if l.Loki != nil {
+		u, err := url.Parse(l.Loki.Address)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid loki address '%s', address must be a http or https URL", l.Loki.Address)
+		}
+	}
+
+	if l.Syslog != nil {
+		if l.Syslog.Address == "" {
+			return fmt.Errorf("syslog address must be set")
+		}
+
+		if p := l.Syslog.Protocol; p != "" && p != "tcp" && p != "udp" {
+			return fmt.Errorf("invalid syslog protocol '%s', protocol must be tcp or udp", p)
+		}
+	}
+
+	if l.File != nil && l.File.Path == "" {
+		return fmt.Errorf("file path must be set")
+	}
+
+	return nil
+}
+
+// LogContainers returns the names of the containers for a resource which have logs,
+// these are the containers read by the log command and shipped by log sinks
+func LogContainers(r Resource) []string {
+	if r.Info().Disabled {
+		return nil
+	}
+
+	fqdn := utils.FQDN(r.Info().Name, string(r.Info().Type))
+
+	switch r.Info().Type {
+	case TypeContainer, TypeSidecar, TypeK8sIngress, TypeNomadIngress, TypeContainerIngress, TypeRegistry, TypeImageCache, TypeLogSink:
+		return []string{fqdn}
+	case TypeK8sCluster:
+		return []string{fmt.Sprintf("server.%s", fqdn)}
+	case TypeNomadCluster:
+		containers := []string{fmt.Sprintf("server.%s", fqdn)}
+
+		// add the client nodes
+		nomad := r.(*NomadCluster)
+		for n := 0; n < nomad.ClientNodes; n++ {
+			containers = append(containers, fmt.Sprintf("%d.client.%s", n+1, fqdn))
+		}
+
+		return containers
+	case TypeCompose:
+		containers := []string{}
+
+		compose := r.(*Compose)
+		for _, s := range compose.Services {
+			containers = append(containers, utils.FQDN(compose.ServiceContainerName(s), string(r.Info().Type)))
+		}
+
+		return containers
+	}
+
+	return nil
+}
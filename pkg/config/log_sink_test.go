@@ -0,0 +1,112 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreatesLogSink(t *testing.T) {
+	c := NewLogSink("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeLogSink, c.Type)
+}
+
+func TestLogSinkCreatesCorrectly(t *testing.T) {
+	c, dir := CreateConfigFromStrings(t, logSinkDefault)
+
+	r, err := c.FindResource("log_sink.ship")
+	assert.NoError(t, err)
+
+	ls := r.(*LogSink)
+	assert.Equal(t, "http://loki.container.shipyard.run:3100", ls.Loki.Address)
+	assert.Equal(t, "dev", ls.Loki.Labels["env"])
+	assert.Equal(t, "udp", ls.Syslog.Protocol)
+	assert.Equal(t, filepath.Join(dir, "logs"), ls.File.Path)
+	assert.Equal(t, []string{"container.*"}, ls.Resources)
+	assert.Contains(t, ls.DependsOn, "network.local")
+}
+
+func TestLogSinkWithoutSinksReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, logSinkNoSinks)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "at least one loki, syslog, or file sink")
+}
+
+func TestLogSinkWithInvalidLokiAddressReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, logSinkInvalidLoki)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid loki address")
+}
+
+func TestLogContainersReturnsNodesForClusters(t *testing.T) {
+	nc := NewNomadCluster("dev")
+	nc.ClientNodes = 2
+
+	assert.Equal(t, []string{
+		"server.dev.nomad-cluster.shipyard.run",
+		"1.client.dev.nomad-cluster.shipyard.run",
+		"2.client.dev.nomad-cluster.shipyard.run",
+	}, LogContainers(nc))
+
+	assert.Equal(t, []string{"server.k3s.k8s-cluster.shipyard.run"}, LogContainers(NewK8sCluster("k3s")))
+}
+
+func TestLogContainersReturnsNothingForDisabledResources(t *testing.T) {
+	c := NewContainer("consul")
+	c.Disabled = true
+
+	assert.Empty(t, LogContainers(c))
+	assert.Empty(t, LogContainers(NewNetwork("local")))
+}
+
+const logSinkDefault = `
+network "local" {
+	subnet = "10.0.0.0/16"
+}
+
+log_sink "ship" {
+	network {
+		name = "network.local"
+	}
+
+	resources = ["container.*"]
+
+	loki {
+		address = "http://loki.container.shipyard.run:3100"
+		labels = {
+			env = "dev"
+		}
+	}
+
+	syslog {
+		address  = "syslog.container.shipyard.run:514"
+		protocol = "udp"
+	}
+
+	file {
+		path = "./logs"
+	}
+}
+`
+
+const logSinkNoSinks = `
+log_sink "ship" {
+}
+`
+
+const logSinkInvalidLoki = `
+log_sink "ship" {
+	loki {
+		address = "loki:3100"
+	}
+}
+`
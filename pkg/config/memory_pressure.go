@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// DefaultMemoryPressureInterval is the interval memory pressure is checked when no interval is set
+const DefaultMemoryPressureInterval = 10 * time.Second
+
+// MemoryPressure is the policy the connector uses to stop resources when the memory
+// used by the containers on the Docker engine is close to the memory available to the
+// engine, stopping the least important resources protects the remaining resources, like
+// a Kubernetes server, from the OOM killer.
+type MemoryPressure struct {
+	Threshold int      `hcl:"threshold" json:"threshold"`                  // percentage of the engine memory used by containers before resources are stopped e.g. 90
+	Interval  string   `hcl:"interval,optional" json:"interval,omitempty"` // interval memory pressure is checked e.g. 30s, defaults to 10s
+	Stop      []string `hcl:"stop" json:"stop"`                            // glob patterns for the resources which can be stopped ordered lowest priority first e.g. container.grafana, nomad_cluster.*
+}
+
+// Validate the memory pressure policy
+func (m *MemoryPressure) Validate() error {
+	if m.Threshold < 1 || m.Threshold > 100 {
+		return fmt.Errorf("threshold must be a percentage between 1 and 100")
+	}
+
+	if m.Interval != "" {
+		if _, err := time.ParseDuration(m.Interval); err != nil {
+			return fmt.Errorf("invalid interval '%s', %s", m.Interval, err)
+		}
+	}
+
+	if len(m.Stop) == 0 {
+		return fmt.Errorf("stop must contain at least one resource pattern")
+	}
+
+	for _, s := range m.Stop {
+		if _, err := path.Match(s, ""); err != nil {
+			return fmt.Errorf("invalid resource pattern '%s': %s", s, err)
+		}
+	}
+
+	return nil
+}
+
+// IntervalDuration returns the interval memory pressure is checked
+func (m *MemoryPressure) IntervalDuration() time.Duration {
+	if m.Interval == "" {
+		return DefaultMemoryPressureInterval
+	}
+
+	d, _ := time.ParseDuration(m.Interval)
+	return d
+}
+
+// StopOrder returns the resources in the config which can be stopped when memory
+// pressure is exceeded, ordered by the position of the first pattern they match
+func (m *MemoryPressure) StopOrder(c *Config) []Resource {
+	resources := []Resource{}
+	added := map[Resource]bool{}
+
+	for _, p := range m.Stop {
+		for _, r := range c.Resources {
+			if added[r] || r.Info().Status != Applied {
+				continue
+			}
+
+			if ok, _ := path.Match(p, fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)); !ok {
+				continue
+			}
+
+			added[r] = true
+			resources = append(resources, r)
+		}
+	}
+
+	return resources
+}
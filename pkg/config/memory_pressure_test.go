@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryPressureValidateReturnsErrorForInvalidPolicy(t *testing.T) {
+	assert.Error(t, (&MemoryPressure{Threshold: 0, Stop: []string{"container.*"}}).Validate())
+	assert.Error(t, (&MemoryPressure{Threshold: 90}).Validate())
+	assert.Error(t, (&MemoryPressure{Threshold: 90, Stop: []string{"container.["}}).Validate())
+	assert.Error(t, (&MemoryPressure{Threshold: 90, Interval: "soon", Stop: []string{"container.*"}}).Validate())
+
+	assert.NoError(t, (&MemoryPressure{Threshold: 90, Stop: []string{"container.*"}}).Validate())
+}
+
+func TestMemoryPressureIntervalDefaults(t *testing.T) {
+	assert.Equal(t, DefaultMemoryPressureInterval, (&MemoryPressure{}).IntervalDuration())
+}
+
+func TestMemoryPressureStopOrderFollowsPatterns(t *testing.T) {
+	c := New()
+
+	for _, n := range []string{"consul", "grafana", "prometheus"} {
+		cc := NewContainer(n)
+		cc.Status = Applied
+		c.AddResource(cc)
+	}
+
+	pending := NewContainer("vault")
+	c.AddResource(pending)
+
+	k3s := NewK8sCluster("k3s")
+	k3s.Status = Applied
+	c.AddResource(k3s)
+
+	p := &MemoryPressure{Threshold: 90, Stop: []string{"container.grafana", "container.*"}}
+
+	names := []string{}
+	for _, r := range p.StopOrder(c) {
+		names = append(names, r.Info().Name)
+	}
+
+	// resources which have not been created and resources not matched are never stopped
+	assert.Equal(t, []string{"grafana", "consul", "prometheus"}, names)
+}
@@ -11,6 +11,10 @@ type Module struct {
 	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
 
 	Source string `hcl:"source" json:"source"`
+
+	// RemoteSource is the original Go Getter source when the module has been
+	// downloaded from a remote location, Source is set to the local folder
+	RemoteSource string `json:"remote_source,omitempty" mapstructure:"remote_source"`
 }
 
 // NewModule creates a new Module config resource
@@ -1,6 +1,10 @@
 package config
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -21,6 +25,65 @@ func TestModuleCreatesCorrectly(t *testing.T) {
 
 }
 
+func setupModulePathTests(t *testing.T) (string, string) {
+	dir := CreateTestFiles(t, modulePathRoot)
+
+	mod := filepath.Join(dir, "modules", "vault")
+	os.MkdirAll(mod, os.ModePerm)
+	createNamedFile(t, mod, "*.hcl", modulePathModule)
+	ioutil.WriteFile(filepath.Join(mod, "token.txt"), []byte("root"), os.ModePerm)
+
+	return dir, mod
+}
+
+func TestModulePathVariablesResolveRelativeToModule(t *testing.T) {
+	dir, mod := setupModulePathTests(t)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	cwd, _ := os.Getwd()
+
+	r, err := c.FindResource("exec_local.module")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(mod, "scripts", "setup.sh"), r.(*ExecLocal).Command)
+	assert.Equal(t, fmt.Sprintf("%s,%s", dir, cwd), r.(*ExecLocal).Arguments[0])
+
+	r, err = c.FindResource("exec_local.root")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "scripts", "setup.sh"), r.(*ExecLocal).Command)
+}
+
+func TestModuleFileFunctionResolvesRelativeToModule(t *testing.T) {
+	dir, _ := setupModulePathTests(t)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("exec_local.module")
+	assert.NoError(t, err)
+	assert.Equal(t, "root", r.(*ExecLocal).Arguments[1])
+}
+
+const modulePathRoot = `
+module "vault" {
+  source = "${path.module}/modules/vault"
+}
+
+exec_local "root" {
+  cmd = "${path.module}/scripts/setup.sh"
+}
+`
+
+const modulePathModule = `
+exec_local "module" {
+  cmd = "${path.module}/scripts/setup.sh"
+  args = ["${path.root},${path.cwd}", file("./token.txt")]
+}
+`
+
 const moduleDefault = `
 module "testing" {
 	source = "../../examples/single_file"
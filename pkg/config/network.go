@@ -1,5 +1,10 @@
 package config
 
+import (
+	"fmt"
+	"net"
+)
+
 // TypeNetwork is the string resource type for Network resources
 const TypeNetwork ResourceType = "network"
 
@@ -7,10 +12,47 @@ const TypeNetwork ResourceType = "network"
 type Network struct {
 	ResourceInfo `hcl:",remain" mapstructure:",squash"`
 
-	Subnet string `hcl:"subnet" json:"subnet"`
+	Subnet     string `hcl:"subnet,optional" json:"subnet"`
+	IPv6Subnet string `hcl:"ipv6_subnet,optional" json:"ipv6_subnet,omitempty" mapstructure:"ipv6_subnet"` // enables IPv6 for the network using the given subnet
+
+	// External attaches to an existing Docker network with the same name, the network is not
+	// created or removed by Shipyard, the subnet is read from the existing network when not set
+	External bool `hcl:"external,optional" json:"external,omitempty"`
 }
 
 // NewNetwork creates a new Network resource with the correct defaults
 func NewNetwork(name string) *Network {
 	return &Network{ResourceInfo: ResourceInfo{Name: name, Type: TypeNetwork, Status: PendingCreation}}
 }
+
+// Validate the config
+func (n *Network) Validate() error {
+	if n.Subnet == "" && !n.External {
+		return fmt.Errorf("subnet is required, only external networks can omit the subnet")
+	}
+
+	if n.IPv6Subnet != "" {
+		ip, _, err := net.ParseCIDR(n.IPv6Subnet)
+		if err != nil || ip.To4() != nil {
+			return fmt.Errorf("invalid ipv6_subnet '%s', subnet must be an IPv6 CIDR e.g. fd00:5::/64", n.IPv6Subnet)
+		}
+	}
+
+	return nil
+}
+
+// Contains returns true when the given ip address is in one of the networks subnets
+func (n *Network) Contains(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, s := range []string{n.Subnet, n.IPv6Subnet} {
+		if _, cidr, err := net.ParseCIDR(s); err == nil && cidr.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
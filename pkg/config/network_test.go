@@ -33,6 +33,42 @@ func TestNetworkSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, cl.Info().Status)
 }
 
+func TestNetworkWithIPv6CreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, networkIPv6)
+
+	cl, err := c.FindResource("network.test")
+	assert.NoError(t, err)
+
+	n := cl.(*Network)
+	assert.Equal(t, "fd00:10::/64", n.IPv6Subnet)
+	assert.True(t, n.Contains("fd00:10::5"))
+	assert.True(t, n.Contains("10.0.0.5"))
+	assert.False(t, n.Contains("10.0.1.5"))
+}
+
+func TestNetworkExternalDoesNotRequireSubnet(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, networkExternal)
+
+	cl, err := c.FindResource("network.compose_default")
+	assert.NoError(t, err)
+
+	assert.True(t, cl.(*Network).External)
+}
+
+func TestNetworkWithoutSubnetReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, networkNoSubnet)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "subnet is required")
+}
+
+func TestNetworkValidateReturnsErrorForInvalidIPv6Subnet(t *testing.T) {
+	assert.Error(t, (&Network{Subnet: "10.0.0.0/24", IPv6Subnet: "10.1.0.0/24"}).Validate())
+	assert.Error(t, (&Network{Subnet: "10.0.0.0/24", IPv6Subnet: "abc"}).Validate())
+}
+
 const networkDefault = `
 network "test" {
 	subnet = "10.0.0.0/24"
@@ -45,3 +81,21 @@ network "test" {
 	subnet = "10.0.0.0/24"
 }
 `
+
+const networkIPv6 = `
+network "test" {
+	subnet      = "10.0.0.0/24"
+	ipv6_subnet = "fd00:10::/64"
+}
+`
+
+const networkExternal = `
+network "compose_default" {
+	external = true
+}
+`
+
+const networkNoSubnet = `
+network "test" {
+}
+`
@@ -1,5 +1,7 @@
 package config
 
+import "fmt"
+
 // TypeCluster is the resource string for a Cluster resource
 const TypeNomadCluster ResourceType = "nomad_cluster"
 
@@ -17,11 +19,138 @@ type NomadCluster struct {
 	Nodes         int      `hcl:"nodes,optional" json:"nodes,omitempty"`
 	Environment   []KV     `hcl:"env,block" json:"environment,omitempty" mapstructure:"environment"`
 	Images        []Image  `hcl:"image,block" json:"images,omitempty"`
+	CopyImages    []string `hcl:"copy_images,optional" json:"copy_images,omitempty" mapstructure:"copy_images"` // locally built images which are imported and kept in sync with shipyard push --watch
 	ServerConfig  string   `hcl:"server_config,optional" json:"server_config,omitempty" mapstructure:"server_config"`
 	ClientConfig  string   `hcl:"client_config,optional" json:"client_config,omitempty" mapstructure:"client_config"`
 	ConsulConfig  string   `hcl:"consul_config,optional" json:"consul_config,omitempty" mapstructure:"consul_config"`
 	Volumes       []Volume `hcl:"volume,block" json:"volumes,omitempty"`                                                    // volumes to attach to the cluster
 	OpenInBrowser bool     `hcl:"open_in_browser,optional" json:"open_in_browser,omitempty" mapstructure:"open_in_browser"` // open the UI in the browser after creation
+
+	Ulimits []Ulimit          `hcl:"ulimit,block" json:"ulimits,omitempty"`     // ulimits to set for the server and client nodes
+	Sysctls map[string]string `hcl:"sysctls,optional" json:"sysctls,omitempty"` // namespaced kernel parameters to set for the server and client nodes
+
+	Resources *Resources `hcl:"resources,block" json:"resources,omitempty"` // resource constraints for the server and each client node
+
+	Registries []string `hcl:"registries,optional" json:"registries,omitempty"` // registry resources the cluster trusts and uses as mirrors e.g. registry.local
+
+	ImageStrategy []string `hcl:"image_strategy,optional" json:"image_strategy,omitempty" mapstructure:"image_strategy"` // strategies used to add images to the nodes in order of preference, cache, registry or load, defaults to load
+
+	// ClientNode configures groups of client nodes, the nodes for each block are created in
+	// order, client nodes which are not configured by a block use the default config
+	ClientNode []NomadClientNode `hcl:"client_node,block" json:"client_node,omitempty" mapstructure:"client_node"`
+
+	Consul *NomadConsul `hcl:"consul,block" json:"consul,omitempty"` // run a Consul server with the cluster and configure Nomad to use it
+	Vault  *NomadVault  `hcl:"vault,block" json:"vault,omitempty"`   // configure Nomad to use an existing Vault server
+
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"` // maximum time for the cluster to start e.g. 10m, defaults to 300s
+	Retry   *Retry `hcl:"retry,block" json:"retry,omitempty"`        // policy for retrying failed image pulls
+
+	// ConsulHTTPAddr is the address of the Consul server on the local machine
+	ConsulHTTPAddr string `json:"consul_http_addr,omitempty" mapstructure:"consul_http_addr" state:"true"`
+
+	// ConsulACLToken is the bootstrap token created when ACLs are enabled
+	ConsulACLToken string `json:"consul_acl_token,omitempty" mapstructure:"consul_acl_token" state:"true"`
+}
+
+// NomadConsul defines the Consul server which is created with the cluster
+type NomadConsul struct {
+	Version    string `hcl:"version,optional" json:"version,omitempty"`                                    // version of Consul to run, defaults to 1.12.2
+	Port       int    `hcl:"port,optional" json:"port,omitempty"`                                          // local port for the Consul HTTP API, defaults to 18500
+	ACLEnabled bool   `hcl:"acl_enabled,optional" json:"acl_enabled,omitempty" mapstructure:"acl_enabled"` // enable ACLs and bootstrap a management token
+}
+
+// NomadClientNode configures the Nomad agent for a group of client nodes
+type NomadClientNode struct {
+	Count      int               `hcl:"count,optional" json:"count,omitempty"`                                     // number of client nodes with this config, defaults to 1
+	Datacenter string            `hcl:"datacenter,optional" json:"datacenter,omitempty"`                           // datacenter the nodes register in, defaults to dc1
+	NodeClass  string            `hcl:"node_class,optional" json:"node_class,omitempty" mapstructure:"node_class"` // class of the nodes used by scheduling constraints
+	Meta       map[string]string `hcl:"meta,optional" json:"meta,omitempty"`                                       // metadata for the nodes used by scheduling constraints
+	Reserved   *NomadReserved    `hcl:"reserved,block" json:"reserved,omitempty"`                                  // resources reserved on the nodes which can not be used by jobs
+	Config     string            `hcl:"config,optional" json:"config,omitempty"`                                   // HCL which is added to the agent config for the nodes
+}
+
+// NomadReserved defines the resources reserved on a client node
+type NomadReserved struct {
+	CPU           int    `hcl:"cpu,optional" json:"cpu,omitempty"`                                                     // CPU in MHz
+	Memory        int    `hcl:"memory,optional" json:"memory,omitempty"`                                               // memory in MB
+	Disk          int    `hcl:"disk,optional" json:"disk,omitempty"`                                                   // disk in MB
+	ReservedPorts string `hcl:"reserved_ports,optional" json:"reserved_ports,omitempty" mapstructure:"reserved_ports"` // ports which can not be allocated e.g. 22,80,8000-8100
+}
+
+// NomadVault configures the vault stanza for the Nomad servers and clients
+type NomadVault struct {
+	Address        string `hcl:"address" json:"address"`                                                                      // address of the Vault server e.g. http://vault.container.shipyard.run:8200
+	Token          string `hcl:"token,optional" json:"token,omitempty"`                                                       // token used by the Nomad servers to create tokens for tasks
+	CreateFromRole string `hcl:"create_from_role,optional" json:"create_from_role,omitempty" mapstructure:"create_from_role"` // role used to create tokens for tasks
+}
+
+// ClusterImages returns the images which are imported into the cluster
+func (n *NomadCluster) ClusterImages() []Image {
+	return clusterImages(n.Images, n.CopyImages)
+}
+
+// ImageStrategies returns the strategies used to add images to the nodes
+func (n *NomadCluster) ImageStrategies() []string {
+	return imageStrategies(n.ImageStrategy)
+}
+
+// ClientNodeCount returns the number of client nodes configured by the client_node blocks
+func (n *NomadCluster) ClientNodeCount() int {
+	count := 0
+	for _, cn := range n.ClientNode {
+		count += cn.nodes()
+	}
+
+	return count
+}
+
+// ClientNodeConfig returns the config for the client node with the given index starting
+// at 1, nil is returned when the node is not configured by a client_node block
+func (n *NomadCluster) ClientNodeConfig(index int) *NomadClientNode {
+	for i := range n.ClientNode {
+		index -= n.ClientNode[i].nodes()
+		if index <= 0 {
+			return &n.ClientNode[i]
+		}
+	}
+
+	return nil
+}
+
+func (cn *NomadClientNode) nodes() int {
+	if cn.Count == 0 {
+		return 1
+	}
+
+	return cn.Count
+}
+
+// Validate the config
+func (n *NomadCluster) Validate() error {
+	for _, cn := range n.ClientNode {
+		if cn.Count < 0 {
+			return fmt.Errorf("invalid client_node count %d, count must be greater than 0", cn.Count)
+		}
+	}
+
+	if n.ClientNodes > 0 && n.ClientNodes < n.ClientNodeCount() {
+		return fmt.Errorf("client_nodes is %d but client_node blocks configure %d nodes", n.ClientNodes, n.ClientNodeCount())
+	}
+
+	if n.Consul != nil && (n.Consul.Port < 0 || n.Consul.Port > 65535) {
+		return fmt.Errorf("invalid consul port %d, port must be between 1 and 65535", n.Consul.Port)
+	}
+
+	if n.Vault != nil && n.Vault.Address == "" {
+		return fmt.Errorf("vault address must be specified")
+	}
+
+	err := validateImageStrategy(n.ImageStrategy, n.Registries)
+	if err != nil {
+		return err
+	}
+
+	return validateTimeoutAndRetry(n.Timeout, n.Retry)
 }
 
 // NewCluster creates new Cluster config with the correct defaults
@@ -33,6 +33,73 @@ func TestNomadClusterSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, cl.Info().Status)
 }
 
+func TestNomadClusterParsesConsulAndVault(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, nomadClusterIntegrations)
+
+	cl, err := c.FindResource("nomad_cluster.test")
+	assert.NoError(t, err)
+
+	nc := cl.(*NomadCluster)
+	assert.Equal(t, "1.12.0", nc.Consul.Version)
+	assert.True(t, nc.Consul.ACLEnabled)
+	assert.Equal(t, "http://vault.container.shipyard.run:8200", nc.Vault.Address)
+	assert.Equal(t, "root", nc.Vault.Token)
+	assert.Equal(t, "nomad-cluster", nc.Vault.CreateFromRole)
+}
+
+func TestNomadClusterWithInvalidConsulPortReturnsError(t *testing.T) {
+	nc := NewNomadCluster("test")
+	nc.Consul = &NomadConsul{Port: 70000}
+
+	err := nc.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid consul port")
+}
+
+func TestNomadClusterWithInvalidImageStrategyReturnsError(t *testing.T) {
+	nc := NewNomadCluster("test")
+	nc.ImageStrategy = []string{"scp"}
+
+	assert.Error(t, nc.Validate())
+}
+
+func TestNomadClusterWithoutVaultAddressReturnsError(t *testing.T) {
+	nc := NewNomadCluster("test")
+	nc.Vault = &NomadVault{}
+
+	assert.Error(t, nc.Validate())
+}
+
+func TestNomadClusterParsesClientNodes(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, nomadClusterClientNodes)
+
+	cl, err := c.FindResource("nomad_cluster.test")
+	assert.NoError(t, err)
+
+	nc := cl.(*NomadCluster)
+	assert.Equal(t, 3, nc.ClientNodes)
+	assert.Len(t, nc.ClientNode, 2)
+
+	assert.Equal(t, "dc1", nc.ClientNodeConfig(1).Datacenter)
+	assert.Equal(t, "dc2", nc.ClientNodeConfig(2).Datacenter)
+	assert.Equal(t, "dc2", nc.ClientNodeConfig(3).Datacenter)
+	assert.Nil(t, nc.ClientNodeConfig(4))
+
+	assert.Equal(t, "gpu", nc.ClientNode[1].NodeClass)
+	assert.Equal(t, map[string]string{"zone": "b"}, nc.ClientNode[1].Meta)
+	assert.Equal(t, 512, nc.ClientNode[1].Reserved.Memory)
+}
+
+func TestNomadClusterWithTooFewClientNodesReturnsError(t *testing.T) {
+	nc := NewNomadCluster("test")
+	nc.ClientNodes = 1
+	nc.ClientNode = []NomadClientNode{{Count: 2}}
+
+	err := nc.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "client_node blocks configure 2 nodes")
+}
+
 const nomadClusterDefault = `
 network "test" {
 	subnet = "10.0.0.0/24"
@@ -51,3 +118,44 @@ nomad_cluster "test" {
 	disabled = true
 }
 `
+
+const nomadClusterIntegrations = `
+network "test" {
+	subnet = "10.0.0.0/24"
+}
+
+nomad_cluster "test" {
+	consul {
+		version     = "1.12.0"
+		acl_enabled = true
+	}
+
+	vault {
+		address          = "http://vault.container.shipyard.run:8200"
+		token            = "root"
+		create_from_role = "nomad-cluster"
+	}
+}
+`
+
+const nomadClusterClientNodes = `
+nomad_cluster "test" {
+	client_node {
+		datacenter = "dc1"
+	}
+
+	client_node {
+		count      = 2
+		datacenter = "dc2"
+		node_class = "gpu"
+
+		meta = {
+			zone = "b"
+		}
+
+		reserved {
+			memory = 512
+		}
+	}
+}
+`
@@ -1,8 +1,19 @@
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
 // TypeNomadJob defines the string type for the Kubernetes config resource
 const TypeNomadJob ResourceType = "nomad_job"
 
+// NomadJobWaitHealthy waits for the deployments and allocations of the jobs to be healthy
+const NomadJobWaitHealthy = "healthy"
+
+// defaultNomadJobTimeout is the time to wait for jobs to become healthy when no timeout is set
+const defaultNomadJobTimeout = "300s"
+
 // NomadJob applies and deletes and deletes Nomad cluster jobs
 type NomadJob struct {
 	ResourceInfo `hcl:",remain" mapstructure:",squash"`
@@ -14,6 +25,18 @@ type NomadJob struct {
 	// Path of a file or directory of Job files to apply
 	Paths []string `hcl:"paths" validator:"filepath" json:"paths"`
 
+	// Vars are passed to the job files which are processed as templates when set,
+	// as well as the Vars the templates can reference the blueprint Variables and Outputs
+	// e.g. #{{ .Vars.version }}, #{{ .Variables.datacenter }}, #{{ .Outputs.db_address }}.
+	// When parsed the attribute is converted to a map[string]interface{}
+	Vars interface{} `hcl:"vars,optional" json:"vars,omitempty"`
+
+	// WaitFor waits for the jobs to reach the given state after they have been submitted,
+	// the only supported value is "healthy"
+	WaitFor string `hcl:"wait_for,optional" json:"wait_for,omitempty" mapstructure:"wait_for"`
+	// Timeout for WaitFor, defaults to 300s
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
+
 	// HealthCheck defines a health check for the resource
 	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty" mapstructure:"health_check"`
 }
@@ -23,7 +46,30 @@ func NewNomadJob(name string) *NomadJob {
 	return &NomadJob{ResourceInfo: ResourceInfo{Name: name, Type: TypeNomadJob, Status: PendingCreation}}
 }
 
-// Validate the K8sConfig and return errors
-func (b *NomadJob) Validate() []error {
+// VarsValue returns the vars for the job templates, nil when no vars are set
+func (b *NomadJob) VarsValue() map[string]interface{} {
+	m, _ := b.Vars.(map[string]interface{})
+	return m
+}
+
+// WaitTimeout returns the duration to wait for the jobs to become healthy
+func (b *NomadJob) WaitTimeout() (time.Duration, error) {
+	if b.Timeout == "" {
+		return time.ParseDuration(defaultNomadJobTimeout)
+	}
+
+	return time.ParseDuration(b.Timeout)
+}
+
+// Validate the NomadJob and return errors
+func (b *NomadJob) Validate() error {
+	if b.WaitFor != "" && b.WaitFor != NomadJobWaitHealthy {
+		return fmt.Errorf("wait_for must be %s, got %s", NomadJobWaitHealthy, b.WaitFor)
+	}
+
+	if _, err := b.WaitTimeout(); err != nil {
+		return fmt.Errorf("timeout is not a valid duration: %s", err)
+	}
+
 	return nil
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,6 +34,54 @@ func TestNomadJobSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, cl.Info().Status)
 }
 
+func TestNomadJobParsesVars(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, nomadJobVars)
+
+	cl, err := c.FindResource("nomad_job.test")
+	assert.NoError(t, err)
+
+	v := cl.(*NomadJob).VarsValue()
+	assert.Equal(t, "1.2.0", v["version"])
+	assert.Equal(t, float64(3), v["count"])
+}
+
+func TestNomadJobSetsWaitFor(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, nomadJobVars)
+
+	cl, err := c.FindResource("nomad_job.test")
+	assert.NoError(t, err)
+
+	assert.Equal(t, NomadJobWaitHealthy, cl.(*NomadJob).WaitFor)
+
+	d, err := cl.(*NomadJob).WaitTimeout()
+	assert.NoError(t, err)
+	assert.Equal(t, 120*time.Second, d)
+}
+
+func TestNomadJobWaitTimeoutDefaults(t *testing.T) {
+	c := NewNomadJob("test")
+
+	d, err := c.WaitTimeout()
+	assert.NoError(t, err)
+	assert.Equal(t, 300*time.Second, d)
+}
+
+func TestNomadJobWithInvalidWaitForReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, nomadJobInvalidWaitFor)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "wait_for")
+}
+
+func TestNomadJobWithInvalidTimeoutReturnsError(t *testing.T) {
+	c := NewNomadJob("test")
+	c.Timeout = "abc"
+
+	assert.Error(t, c.Validate())
+}
+
 const nomadJobDefault = `
 network "test" {
 	subnet = "10.0.0.0/24"
@@ -65,3 +114,33 @@ nomad_job "test" {
   }
 }
 `
+
+const nomadJobVars = `
+variable "app_version" {
+	default = "1.2.0"
+}
+
+nomad_job "test" {
+  cluster = "nomad_cluster.dev"
+
+  paths = ["./app_config/example2.nomad"]
+
+  vars = {
+    version = var.app_version
+    count = 3
+  }
+
+  wait_for = "healthy"
+  timeout = "120s"
+}
+`
+
+const nomadJobInvalidWaitFor = `
+nomad_job "test" {
+  cluster = "nomad_cluster.dev"
+
+  paths = ["./app_config/example2.nomad"]
+
+  wait_for = "running"
+}
+`
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Notification types
+const (
+	// NotificationWebhook posts each event as JSON to the URL
+	NotificationWebhook = "webhook"
+	// NotificationSlack posts a message for each event to a Slack incoming webhook
+	NotificationSlack = "slack"
+)
+
+// Notification sends the lifecycle events published by the engine to a webhook,
+// notifications are defined in the blueprint file or with the --webhook and
+// --slack-webhook flags.
+// example config:
+//
+//	notification "ci" {
+//	  type   = "slack"
+//	  url    = env("SLACK_WEBHOOK_URL")
+//	  events = ["resource_create_failed", "apply_complete"]
+//	}
+type Notification struct {
+	Name    string            `hcl:"name,label" json:"name"`
+	Type    string            `hcl:"type,optional" json:"type,omitempty"` // webhook or slack, defaults to webhook
+	URL     string            `hcl:"url" json:"url"`
+	Events  []string          `hcl:"events,optional" json:"events,omitempty"`   // events to send, defaults to all events
+	Headers map[string]string `hcl:"headers,optional" json:"headers,omitempty"` // headers added to webhook requests e.g. Authorization
+}
+
+// Validate the config
+func (n *Notification) Validate() error {
+	if n.Type != "" && n.Type != NotificationWebhook && n.Type != NotificationSlack {
+		return fmt.Errorf("invalid type '%s', must be one of %s, %s", n.Type, NotificationWebhook, NotificationSlack)
+	}
+
+	u, err := url.Parse(n.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid url, url must be a http or https URL")
+	}
+
+	return nil
+}
+
+// NotificationType returns the type of the notification
+func (n *Notification) NotificationType() string {
+	if n.Type == "" {
+		return NotificationWebhook
+	}
+
+	return n.Type
+}
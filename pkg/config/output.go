@@ -6,7 +6,8 @@ const TypeOutput ResourceType = "output"
 type Output struct {
 	ResourceInfo `hcl:",remain" mapstructure:",squash"`
 
-	Value string `hcl:"value,optional" json:"value,omitempty"` // command to use when starting the container
+	Value     string `hcl:"value,optional" json:"value,omitempty"`         // command to use when starting the container
+	Sensitive bool   `hcl:"sensitive,optional" json:"sensitive,omitempty"` // redact the value when outputs are listed and from logs
 }
 
 // NewOutput creates a new output variable
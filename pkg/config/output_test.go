@@ -3,6 +3,7 @@ package config
 import (
 	"testing"
 
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -33,6 +34,18 @@ func TestOutputSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, cl.Info().Status)
 }
 
+func TestSensitiveOutputAndVariableAreRedacted(t *testing.T) {
+	t.Cleanup(utils.ClearSensitiveValues)
+
+	c, _ := CreateConfigFromStrings(t, outputSensitive)
+
+	cl, err := c.FindResource("output.token")
+	assert.NoError(t, err)
+	assert.True(t, cl.(*Output).Sensitive)
+
+	assert.Equal(t, "token=(sensitive) password=(sensitive)", utils.Redact("token=root-s3cret password=db-s3cret"))
+}
+
 const outputDefault = `
 output "test" {
 	value = "abcc"
@@ -45,3 +58,19 @@ output "test" {
 	value = "abcc"
 }
 `
+
+const outputSensitive = `
+variable "db_password" {
+	default = "db-s3cret"
+	sensitive = true
+}
+
+output "token" {
+	value = "root-s3cret"
+	sensitive = true
+}
+
+output "password" {
+	value = var.db_password
+}
+`
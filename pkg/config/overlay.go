@@ -0,0 +1,213 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+)
+
+// OverlayFolder is the folder in a blueprint which contains the environment overlays
+const OverlayFolder = "overrides"
+
+// overlayName is the name of the overlay which is merged on top of the
+// root blueprint folder, no overlay is applied when blank
+var overlayName string
+
+// currentOverlay is the overlay loaded for the folder which is being parsed
+var currentOverlay *overlay
+
+// overlay holds the blocks from an environment overlay file,
+// blocks in the overlay are merged on top of the blocks with the same type
+// and name in the base blueprint, blocks which do not exist in the base blueprint
+// are added as new resources.
+type overlay struct {
+	folder string                      // blueprint folder the overlay is applied to
+	file   string                      // path to the overlay file
+	blocks map[string]*hclsyntax.Block // overlay blocks keyed by type.name
+	merged map[string]bool             // overlay blocks which exist in the base blueprint
+}
+
+// SetOverlay sets the name of the overlay which will be merged on top of the blueprint
+// when parsing a folder, the overlay is read from the file overrides/[name].hcl.
+// Setting a blank name removes the overlay.
+func SetOverlay(name string) {
+	overlayName = name
+}
+
+// OverlayPath returns the path of the overlay file for the given blueprint folder
+func OverlayPath(folder, name string) string {
+	return filepath.Join(folder, OverlayFolder, fmt.Sprintf("%s.hcl", name))
+}
+
+// loadOverlay reads the overlay for the blueprint folder and determines
+// which blocks override the existing blocks in the base files
+func loadOverlay(folder string) error {
+	currentOverlay = nil
+
+	if overlayName == "" {
+		return nil
+	}
+
+	file := OverlayPath(folder, overlayName)
+	if _, err := os.Stat(file); err != nil {
+		return fmt.Errorf("Unable to find overlay '%s', expected file %s", overlayName, file)
+	}
+
+	body, err := parseHCLBody(file)
+	if err != nil {
+		return err
+	}
+
+	o := &overlay{folder: folder, file: file, blocks: map[string]*hclsyntax.Block{}, merged: map[string]bool{}}
+	for _, b := range body.Blocks {
+		k, ok := blockKey(b)
+		if !ok {
+			continue
+		}
+
+		if _, ok := o.blocks[k]; ok {
+			return fmt.Errorf("Error in file '%s': block '%s' is defined more than once", file, k)
+		}
+
+		o.blocks[k] = b
+	}
+
+	// find the blocks which exist in the base files
+	files, err := configFiles(folder)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		body, err := parseHCLBody(f)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range body.Blocks {
+			if k, ok := blockKey(b); ok {
+				if _, ok := o.blocks[k]; ok {
+					o.merged[k] = true
+				}
+			}
+		}
+	}
+
+	currentOverlay = o
+
+	return nil
+}
+
+// overlayFiles adds the overlay file to the list of files to parse
+// when the folder is the folder the overlay applies to
+func overlayFiles(folder string, files []string) []string {
+	if currentOverlay == nil || currentOverlay.folder != folder {
+		return files
+	}
+
+	return append(files, currentOverlay.file)
+}
+
+// applyOverlay merges the overlay on top of the blocks parsed from the given file.
+// The overlay file itself only returns blocks which do not exist in the base blueprint
+// as the others have been merged into the base files.
+func applyOverlay(file string, body *hclsyntax.Body) *hclsyntax.Body {
+	o := currentOverlay
+	if o == nil {
+		return body
+	}
+
+	if file == o.file {
+		blocks := hclsyntax.Blocks{}
+		for _, b := range body.Blocks {
+			if k, ok := blockKey(b); ok && o.merged[k] {
+				continue
+			}
+
+			blocks = append(blocks, b)
+		}
+
+		nb := *body
+		nb.Blocks = blocks
+
+		return &nb
+	}
+
+	if filepath.Dir(file) != o.folder {
+		return body
+	}
+
+	blocks := hclsyntax.Blocks{}
+	for _, b := range body.Blocks {
+		if k, ok := blockKey(b); ok {
+			if ob, ok := o.blocks[k]; ok {
+				b = mergeBlocks(b, ob)
+			}
+		}
+
+		blocks = append(blocks, b)
+	}
+
+	nb := *body
+	nb.Blocks = blocks
+
+	return &nb
+}
+
+// mergeBlocks returns a copy of the base block with the overlay merged on top.
+// Attributes in the overlay replace the attributes in the base, nested blocks in
+// the overlay replace all the nested blocks of the same type in the base.
+func mergeBlocks(base, over *hclsyntax.Block) *hclsyntax.Block {
+	attrs := hclsyntax.Attributes{}
+	for k, v := range base.Body.Attributes {
+		attrs[k] = v
+	}
+
+	for k, v := range over.Body.Attributes {
+		attrs[k] = v
+	}
+
+	replaced := map[string]bool{}
+	for _, b := range over.Body.Blocks {
+		replaced[b.Type] = true
+	}
+
+	blocks := hclsyntax.Blocks{}
+	for _, b := range base.Body.Blocks {
+		if !replaced[b.Type] {
+			blocks = append(blocks, b)
+		}
+	}
+
+	blocks = append(blocks, over.Body.Blocks...)
+
+	body := *base.Body
+	body.Attributes = attrs
+	body.Blocks = blocks
+
+	mb := *base
+	mb.Body = &body
+
+	return &mb
+}
+
+// blockKey returns the type and name of a block e.g. container.consul
+func blockKey(b *hclsyntax.Block) (string, bool) {
+	if len(b.Labels) == 0 {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s.%s", b.Type, b.Labels[0]), true
+}
+
+func parseHCLBody(file string) (*hclsyntax.Body, error) {
+	body, diag := parseConfigFile(file)
+	if diag.HasErrors() {
+		return nil, errors.New(diag.Error())
+	}
+
+	return body, nil
+}
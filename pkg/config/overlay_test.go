@@ -0,0 +1,188 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupOverlay(t *testing.T, name, overlay string, contents ...string) string {
+	dir := CreateTestFiles(t, contents...)
+
+	err := os.MkdirAll(filepath.Join(dir, OverlayFolder), os.ModePerm)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(OverlayPath(dir, name), []byte(overlay), os.ModePerm)
+	assert.NoError(t, err)
+
+	SetOverlay(name)
+	t.Cleanup(func() { SetOverlay("") })
+
+	return dir
+}
+
+func TestOverlayReplacesAttributes(t *testing.T) {
+	dir := setupOverlay(t, "staging", overlayContainer, baseContainer)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	cc := r.(*Container)
+	assert.Equal(t, "consul:1.10.0", cc.Image.Name)
+	assert.Equal(t, []string{"consul", "agent", "-dev"}, cc.Command)
+}
+
+func TestOverlayReplacesNestedBlocksOfTheSameType(t *testing.T) {
+	dir := setupOverlay(t, "staging", overlayContainer, baseContainer)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	cc := r.(*Container)
+	assert.Len(t, cc.Ports, 1)
+	assert.Equal(t, "18500", cc.Ports[0].Host)
+
+	// blocks of other types are retained from the base
+	assert.Len(t, cc.Networks, 1)
+}
+
+func TestOverlayAddsNewResources(t *testing.T) {
+	dir := setupOverlay(t, "staging", overlayNewResource, baseContainer)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	_, err = c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	_, err = c.FindResource("container.vault")
+	assert.NoError(t, err)
+}
+
+func TestOverlayOverridesVariableDefaults(t *testing.T) {
+	dir := setupOverlay(t, "staging", overlayVariable, baseVariable)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "consul:1.10.0", r.(*Container).Image.Name)
+}
+
+func TestOverlayNotAppliedWhenNotSet(t *testing.T) {
+	dir := setupOverlay(t, "staging", overlayContainer, baseContainer)
+	SetOverlay("")
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "consul:1.9.0", r.(*Container).Image.Name)
+}
+
+func TestOverlayReturnsErrorWhenNotFound(t *testing.T) {
+	dir := setupOverlay(t, "staging", overlayContainer, baseContainer)
+	SetOverlay("production")
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to find overlay 'production'")
+}
+
+func TestOverlayReturnsErrorWhenBlockDefinedTwice(t *testing.T) {
+	dir := setupOverlay(t, "staging", overlayContainer+overlayContainer, baseContainer)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "defined more than once")
+}
+
+const baseContainer = `
+network "onprem" {
+  subnet = "10.6.0.0/16"
+}
+
+container "consul" {
+  image {
+    name = "consul:1.9.0"
+  }
+
+  command = ["consul", "agent", "-dev"]
+
+  network {
+    name = "network.onprem"
+  }
+
+  port {
+    local  = 8500
+    remote = 8500
+    host   = 8500
+  }
+
+  port {
+    local  = 8600
+    remote = 8600
+    host   = 8600
+  }
+}
+`
+
+const overlayContainer = `
+container "consul" {
+  image {
+    name = "consul:1.10.0"
+  }
+
+  port {
+    local  = 8500
+    remote = 8500
+    host   = 18500
+  }
+}
+`
+
+const overlayNewResource = `
+container "vault" {
+  image {
+    name = "vault:1.7.0"
+  }
+}
+`
+
+const baseVariable = `
+variable "consul_version" {
+  default = "1.9.0"
+}
+
+container "consul" {
+  image {
+    name = "consul:${var.consul_version}"
+  }
+}
+`
+
+const overlayVariable = `
+variable "consul_version" {
+  default = "1.10.0"
+}
+`
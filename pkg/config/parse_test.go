@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -465,10 +466,10 @@ func TestParseProcessesShipyardFunctions(t *testing.T) {
 	absoluteVarsPath, err := filepath.Abs("../../examples/override.vars")
 	assert.NoError(t, err)
 
-	_, kubeConfigFile, kubeConfigDockerFile := utils.CreateKubeConfigPath("dc1")
+	_, kubeConfigFile, kubeConfigDockerFile, _ := utils.CreateKubeConfigPath("dc1")
 
 	ip, _ := utils.GetLocalIPAndHostname()
-	clusterConf, _ := utils.GetClusterConfig("nomad_cluster.dc1")
+	clusterConf, _, _ := utils.GetClusterConfig("nomad_cluster.dc1")
 	clusterIP := clusterConf.APIAddress(utils.LocalContext)
 
 	c := New()
@@ -495,8 +496,56 @@ func TestParseProcessesShipyardFunctions(t *testing.T) {
 	assert.Equal(t, ip, cc.EnvVar["shipyard_ip"])
 	assert.Equal(t, clusterIP, cc.EnvVar["cluster_api"])
 	assert.Equal(t, "2", cc.EnvVar["var_len"])
+	assert.Equal(t, "6", cc.EnvVar["string_len"])
+	assert.Equal(t, "3", cc.EnvVar["map_len"])
+	assert.Equal(t, fmt.Sprintf("datacenter = \"dc1\"\nport 8500\nport 8600\nhome = \"%s\"\n", utils.HomeFolder()), cc.EnvVar["template"])
 }
 
+func TestParseTemplateFileWithInvalidVarsReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, templateFileInvalidVars)
+
+	err := ioutil.WriteFile(filepath.Join(dir, "config.tpl"), []byte("dc = ${dc}"), os.ModePerm)
+	assert.NoError(t, err)
+
+	c := New()
+	err = ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must be a map")
+}
+
+func TestParseTemplateFileWithMissingFileReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, templateFileMissing)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "missing.tpl")
+}
+
+const templateFileInvalidVars = `
+container "consul" {
+  image {
+    name = "consul:1.10.1"
+  }
+
+  env_var = {
+    config = templatefile("./config.tpl", ["dc1"])
+  }
+}
+`
+
+const templateFileMissing = `
+container "consul" {
+  image {
+    name = "consul:1.10.1"
+  }
+
+  env_var = {
+    config = templatefile("./missing.tpl", {})
+  }
+}
+`
+
 /*
 func TestSingleKubernetesCluster(t *testing.T) {
 	absoluteFolderPath, err := filepath.Abs("./examples/single-cluster-k8s")
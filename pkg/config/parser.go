@@ -2,15 +2,18 @@ package config
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gernest/front"
 	"github.com/hashicorp/go-getter"
@@ -20,18 +23,107 @@ import (
 	"github.com/hashicorp/hcl2/hclparse"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	"github.com/zclconf/go-cty/cty/gocty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 	"golang.org/x/xerrors"
 )
 
 var ctx *hcl.EvalContext
 
+// rootFolder is the folder of the blueprint being parsed and currentFile
+// is the file being parsed, relative paths are resolved from this file
+var rootFolder string
+var currentFile string
+
 // GetEvalContext gets the context parsed from the configuration
 // this contains all the variables and helper functions
 func GetEvalContext() *hcl.EvalContext {
 	return ctx
 }
 
+// GetResourceEvalContext returns a child of the eval context which contains the
+// values set by resources when they are created, e.g. exec_local.<name>.output,
+// terraform.<name>.output.<output>, or certificate_leaf.<name>.cert_pem. These values are only known once a resource has
+// been applied so they can only be used by attributes which are evaluated by the providers
+func GetResourceEvalContext(c *Config) *hcl.EvalContext {
+	ec := &hcl.EvalContext{}
+	if ctx != nil {
+		ec = ctx.NewChild()
+	}
+
+	ec.Variables = map[string]cty.Value{}
+
+	if c == nil {
+		return ec
+	}
+
+	execs := map[string]cty.Value{}
+	for _, r := range c.FindResourcesByType(string(TypeExecLocal)) {
+		execs[r.Info().Name] = cty.ObjectVal(map[string]cty.Value{
+			"output": cty.StringVal(r.(*ExecLocal).Output),
+		})
+	}
+
+	if len(execs) > 0 {
+		ec.Variables[string(TypeExecLocal)] = cty.ObjectVal(execs)
+	}
+
+	modules := map[string]cty.Value{}
+	for _, r := range c.FindResourcesByType(string(TypeTerraform)) {
+		outputs := map[string]cty.Value{}
+		for k, v := range r.(*Terraform).Outputs {
+			outputs[k] = cty.StringVal(v)
+		}
+
+		modules[r.Info().Name] = cty.ObjectVal(map[string]cty.Value{
+			"output": cty.ObjectVal(outputs),
+		})
+	}
+
+	if len(modules) > 0 {
+		ec.Variables[string(TypeTerraform)] = cty.ObjectVal(modules)
+	}
+
+	for _, t := range []ResourceType{TypeCertificateCA, TypeCertificateLeaf} {
+		certs := map[string]cty.Value{}
+		for _, r := range c.FindResourcesByType(string(t)) {
+			certs[r.Info().Name] = certificateValue(r)
+		}
+
+		if len(certs) > 0 {
+			ec.Variables[string(t)] = cty.ObjectVal(certs)
+		}
+	}
+
+	return ec
+}
+
+// certificateValue returns the paths and the PEM encoded contents of a certificate
+// and its key, the contents are blank until the certificate has been generated
+func certificateValue(r Resource) cty.Value {
+	var certPath, keyPath string
+
+	switch c := r.(type) {
+	case *CertificateCA:
+		certPath, keyPath = c.CertPath, c.KeyPath
+	case *CertificateLeaf:
+		certPath, keyPath = c.CertPath, c.KeyPath
+	}
+
+	certPEM, _ := ioutil.ReadFile(certPath)
+	keyPEM, _ := ioutil.ReadFile(keyPath)
+
+	return cty.ObjectVal(map[string]cty.Value{
+		"cert_path": cty.StringVal(certPath),
+		"key_path":  cty.StringVal(keyPath),
+		"cert_pem":  cty.StringVal(string(certPEM)),
+		"key_pem":   cty.StringVal(string(keyPEM)),
+	})
+}
+
 type ResourceTypeNotExistError struct {
 	Type string
 	File string
@@ -43,6 +135,11 @@ func (r ResourceTypeNotExistError) Error() string {
 
 func ParseSingleFile(file string, c *Config, variables map[string]string, variablesFile string) error {
 	ctx = buildContext()
+	currentOverlay = nil
+
+	abs, _ := filepath.Abs(file)
+	rootFolder = filepath.Dir(abs)
+
 	return parseFile(file, c, variables, variablesFile)
 }
 
@@ -64,6 +161,9 @@ func ParseFolder(
 	variablesFile string) error {
 
 	ctx = buildContext()
+	currentOverlay = nil
+	rootFolder, _ = filepath.Abs(folder)
+
 	return parseFolder(
 		folder,
 		c,
@@ -90,6 +190,11 @@ func parseFile(file string, c *Config, variables map[string]string, variablesFil
 		return err
 	}
 
+	err = parseLocals([]string{file})
+	if err != nil {
+		return err
+	}
+
 	err = parseHCLFile(file, c, "", false, []string{})
 	if err != nil {
 		return err
@@ -110,6 +215,9 @@ func parseFolder(
 
 	abs, _ := filepath.Abs(folder)
 
+	// locals are only visible to the resources in the folder they are defined in
+	defer scopeLocals()()
+
 	// load the variables from the root of the blueprint
 	if !onlyResources {
 		variableFiles, err := filepath.Glob(path.Join(abs, "*.vars"))
@@ -135,6 +243,12 @@ func parseFolder(
 		// setup any variables which are passed as environment variables or in the collection
 		SetVariables(variables)
 
+		// load the environment overlay which is merged on top of the blueprint
+		err = loadOverlay(abs)
+		if err != nil {
+			return err
+		}
+
 		// pick up the blueprint file
 		yardFilesHCL, err := filepath.Glob(path.Join(abs, "*.yard"))
 		if err != nil {
@@ -200,8 +314,7 @@ func LoadValuesFile(path string) error {
 
 	// add the file functions to the context with a reference to the
 	// current file
-	ctx.Functions["file_path"] = getFilePathFunc(path)
-	ctx.Functions["file_dir"] = getFileDirFunc(path)
+	setFileContext(path)
 
 	attrs, _ := f.Body.JustAttributes()
 	for name, attr := range attrs {
@@ -250,19 +363,14 @@ func valueFromString(v string) cty.Value {
 
 // ParseVariableFile parses a config file for variables
 func parseVariableFile(file string, c *Config) error {
-	parser := hclparse.NewParser()
-	ctx.Functions["file_path"] = getFilePathFunc(file)
-	ctx.Functions["file_dir"] = getFileDirFunc(file)
+	setFileContext(file)
 
-	f, diag := parser.ParseHCLFile(file)
-	if diag.HasErrors() {
-		return errors.New(diag.Error())
+	body, err := parseHCLBody(file)
+	if err != nil {
+		return err
 	}
 
-	body, ok := f.Body.(*hclsyntax.Body)
-	if !ok {
-		return errors.New("Error getting body")
-	}
+	body = applyOverlay(file, body)
 
 	for _, b := range body.Blocks {
 		switch b.Type {
@@ -276,29 +384,206 @@ func parseVariableFile(file string, c *Config) error {
 
 			val, _ := v.Default.(*hcl.Attribute).Expr.Value(ctx)
 			setContextVariableIfMissing(v.Name, val)
+
+			if v.Sensitive {
+				addSensitiveValue(ctx.Variables["var"].AsValueMap()[v.Name])
+			}
+
+			// validate the value of the variable, this is the default or the
+			// value set with a vars file or an environment variable
+			err = validateVariable(file, v)
+			if err != nil {
+				return err
+			}
+
+		case string(TypeRandomID), string(TypeRandomPassword):
+			// random values are generated before the resources are parsed
+			// so that they can be referenced by any resource
+			err := setRandomVariable(file, b)
+			if err != nil {
+				return err
+			}
+
+		case BlockRemoteEnvironment:
+			// the outputs of remote environments are read before the resources
+			// are parsed so that they can be referenced by locals and resources
+			err := parseRemoteEnvironment(file, b)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
-// parseHCLFile parses a config file and adds it to the config
-func parseHCLFile(file string, c *Config, moduleName string, disabled bool, dependsOn []string) error {
-	parser := hclparse.NewParser()
-	ctx.Functions["file_path"] = getFilePathFunc(file)
-	ctx.Functions["file_dir"] = getFileDirFunc(file)
+// validateVariable checks the current value of the variable satisfies the validation rules
+func validateVariable(file string, v *Variable) error {
+	for _, vr := range v.Validation {
+		val, diag := vr.Condition.Value(ctx)
+		if diag.HasErrors() {
+			return fmt.Errorf("Error in file '%s': variable '%s' invalid validation condition: %s", file, v.Name, diag.Error())
+		}
 
-	f, diag := parser.ParseHCLFile(file)
-	if diag.HasErrors() {
-		return errors.New(diag.Error())
+		val, err := convert.Convert(val, cty.Bool)
+		if err != nil || val.IsNull() || !val.IsKnown() {
+			return fmt.Errorf("Error in file '%s': variable '%s' validation condition must return true or false", file, v.Name)
+		}
+
+		if val.False() {
+			return fmt.Errorf("Error in file '%s': invalid value for variable '%s', %s", file, v.Name, vr.ErrorMessage)
+		}
 	}
 
-	body, ok := f.Body.(*hclsyntax.Body)
+	return nil
+}
+
+// randomResource is a resource which generates a random value
+type randomResource interface {
+	Resource
+	Validate() error
+	Generate() error
+}
+
+// setRandomVariable adds the value of a random_id or random_password resource to the context,
+// values are read from the state when the resource has already been created so that they
+// do not change when the blueprint is re-applied, otherwise a new value is generated
+func setRandomVariable(file string, b *hclsyntax.Block) error {
+	name := b.Labels[0]
+
+	var r randomResource
+
+	switch b.Type {
+	case string(TypeRandomID):
+		r = NewRandomID(name)
+	case string(TypeRandomPassword):
+		r = NewRandomPassword(name)
+	}
+
+	err := decodeBody(file, b, r)
+	if err != nil {
+		return err
+	}
+
+	err = r.Validate()
+	if err != nil {
+		return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+	}
+
+	if sr := stateResource(b.Type, name); sr != nil {
+		r = sr.(randomResource)
+	} else {
+		err = r.Generate()
+		if err != nil {
+			return fmt.Errorf("Unable to generate value for resource %s.%s in file %s: %s", b.Type, name, file, err)
+		}
+	}
+
+	valMap := map[string]cty.Value{}
+	if m, ok := ctx.Variables[b.Type]; ok {
+		valMap = m.AsValueMap()
+	}
+
+	valMap[name] = randomValue(r)
+	ctx.Variables[b.Type] = cty.ObjectVal(valMap)
+
+	// generated passwords are always redacted from the logs
+	if p, ok := r.(*RandomPassword); ok {
+		utils.AddSensitiveValue(p.Result)
+	}
+
+	return nil
+}
+
+// addSensitiveValue adds the value of a sensitive variable to the values
+// redacted from the logs, values which are not strings or numbers are ignored
+func addSensitiveValue(v cty.Value) {
+	if v.IsNull() || !v.IsKnown() {
+		return
+	}
+
+	s, err := convert.Convert(v, cty.String)
+	if err != nil {
+		return
+	}
+
+	utils.AddSensitiveValue(s.AsString())
+}
+
+// randomVariable returns the values of a random resource which have been added to the context
+func randomVariable(t, name string) map[string]string {
+	values := map[string]string{}
+
+	m, ok := ctx.Variables[t]
 	if !ok {
-		return errors.New("Error getting body")
+		return values
+	}
+
+	v, ok := m.AsValueMap()[name]
+	if !ok {
+		return values
+	}
+
+	for k, a := range v.AsValueMap() {
+		values[k] = a.AsString()
+	}
+
+	return values
+}
+
+// stateResource returns the resource with the given type and name from the
+// state file, if the state does not exist or contain the resource nil is returned
+func stateResource(t, name string) Resource {
+	sc := New()
+	err := sc.FromJSON(utils.StatePath())
+	if err != nil {
+		return nil
+	}
+
+	r, err := sc.FindResource(fmt.Sprintf("%s.%s", t, name))
+	if err != nil {
+		return nil
+	}
+
+	return r
+}
+
+// randomValue returns the values of a random resource which can be referenced
+// in the configuration e.g. random_password.db.result
+func randomValue(r Resource) cty.Value {
+	switch v := r.(type) {
+	case *RandomID:
+		return cty.ObjectVal(map[string]cty.Value{
+			"hex": cty.StringVal(v.Hex),
+			"dec": cty.StringVal(v.Dec),
+		})
+	case *RandomPassword:
+		return cty.ObjectVal(map[string]cty.Value{
+			"result": cty.StringVal(v.Result),
+		})
+	}
+
+	return cty.EmptyObjectVal
+}
+
+// parseHCLFile parses a config file and adds it to the config
+func parseHCLFile(file string, c *Config, moduleName string, disabled bool, dependsOn []string) error {
+	setFileContext(file)
+
+	body, err := parseHCLBody(file)
+	if err != nil {
+		return err
 	}
 
+	// merge any environment overlay on top of the file
+	body = applyOverlay(file, body)
+
 	for _, b := range body.Blocks {
+		// locals and remote environments are evaluated before the resources are parsed
+		if b.Type == BlockLocals || b.Type == BlockRemoteEnvironment {
+			continue
+		}
+
 		// check the resource has a name
 		if len(b.Labels) == 0 {
 			return fmt.Errorf("Error in file '%s': resource '%s' has no name, please specify resources using the syntax 'resource_type \"name\" {}'", file, b.Type)
@@ -328,9 +613,40 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 			}
 
 			// Process volumes
-			// make sure mount paths are absolute
+			// make sure mount paths are absolute when type is bind
 			for i, v := range cl.Volumes {
-				cl.Volumes[i].Source = ensureAbsolute(v.Source, file)
+				if v.Type == "" || v.Type == "bind" {
+					cl.Volumes[i].Source = ensureAbsolute(v.Source, file)
+				}
+			}
+
+			err = validateVolumes(cl.Volumes)
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			err = validateImages(cl.Images)
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			if cl.Resources != nil {
+				err := cl.Resources.Validate()
+				if err != nil {
+					return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+				}
+			}
+
+			err = cl.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			if cl.K3s != nil {
+				err := cl.K3s.Validate()
+				if err != nil {
+					return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+				}
 			}
 
 			setDisabled(cl, disabled)
@@ -356,9 +672,52 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				return err
 			}
 
-			// make all the paths absolute
+			// make all the local paths and globs absolute, urls are fetched when applied
 			for i, p := range h.Paths {
-				h.Paths[i] = ensureAbsolute(p, file)
+				if !IsRemotePath(p) {
+					h.Paths[i] = ensureAbsolute(p, file)
+				}
+			}
+
+			if h.Kustomize != "" {
+				h.Kustomize = ensureAbsolute(h.Kustomize, file)
+			}
+
+			for i := range h.Verify {
+				makeVerifyAbsolute(&h.Verify[i], file)
+			}
+
+			err = h.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			setDisabled(h, disabled)
+
+			err = c.AddResource(h)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeK8sNamespace):
+			h := NewK8sNamespace(name)
+			h.Info().Module = moduleName
+			h.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, h)
+			if err != nil {
+				return err
+			}
+
+			err = h.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
 			}
 
 			setDisabled(h, disabled)
@@ -399,6 +758,49 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				h.Values = ensureAbsolute(h.Values, file)
 			}
 
+			// values_map is decoded as an attribute, replace it with the evaluated map
+			if a, ok := h.ValuesMap.(*hcl.Attribute); ok {
+				h.ValuesMap = nil
+
+				if a != nil {
+					m, err := attributeToMap(a)
+					if err != nil {
+						return fmt.Errorf("Error in file '%s': resource '%s.%s' values_map %s", file, b.Type, name, err)
+					}
+
+					h.ValuesMap = m
+				}
+			}
+
+			// values set on the command line with --helm-set replace the values map
+			applyHelmOverrides(h)
+
+			if h.Repository != nil {
+				err = h.Repository.Validate()
+				if err != nil {
+					return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+				}
+			}
+
+			err = h.Destroy.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			if h.Verify != nil {
+				makeVerifyAbsolute(h.Verify, file)
+			}
+
+			err = h.ValidateVerify()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			err = validateTimeoutAndRetry(h.Timeout, nil)
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
 			setDisabled(h, disabled)
 
 			err = c.AddResource(h)
@@ -422,6 +824,11 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				return err
 			}
 
+			err = i.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
 			setDisabled(i, disabled)
 
 			err = c.AddResource(i)
@@ -458,9 +865,38 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 			}
 
 			// Process volumes
-			// make sure mount paths are absolute
+			// make sure mount paths are absolute when type is bind
 			for i, v := range cl.Volumes {
-				cl.Volumes[i].Source = ensureAbsolute(v.Source, file)
+				if v.Type == "" || v.Type == "bind" {
+					cl.Volumes[i].Source = ensureAbsolute(v.Source, file)
+				}
+			}
+
+			err = validateVolumes(cl.Volumes)
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			err = validateImages(cl.Images)
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			if cl.Resources != nil {
+				err := cl.Resources.Validate()
+				if err != nil {
+					return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+				}
+			}
+
+			err = cl.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			// when client_nodes is not set the number of client nodes is set by the client_node blocks
+			if cl.ClientNodes == 0 {
+				cl.ClientNodes = cl.ClientNodeCount()
 			}
 
 			setDisabled(cl, disabled)
@@ -491,6 +927,24 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				h.Paths[i] = ensureAbsolute(p, file)
 			}
 
+			if a, ok := h.Vars.(*hcl.Attribute); ok {
+				h.Vars = nil
+
+				if a != nil {
+					m, err := attributeToMap(a)
+					if err != nil {
+						return fmt.Errorf("Error in file '%s': resource '%s.%s' vars %s", file, b.Type, name, err)
+					}
+
+					h.Vars = m
+				}
+			}
+
+			err = h.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
 			setDisabled(h, disabled)
 
 			err = c.AddResource(h)
@@ -514,6 +968,11 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				return err
 			}
 
+			err = validatePorts(i.Ports, nil)
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
 			setDisabled(i, disabled)
 
 			err = c.AddResource(i)
@@ -537,6 +996,11 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				return err
 			}
 
+			err = n.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
 			setDisabled(n, disabled)
 
 			err = c.AddResource(n)
@@ -567,6 +1031,27 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				return err
 			}
 
+			if i.Auth != nil {
+				err := i.Auth.Validate()
+				if err != nil {
+					return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+				}
+			}
+
+			if i.Headers != nil {
+				err := i.Headers.Validate()
+				if err != nil {
+					return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+				}
+			}
+
+			if i.TLS != nil {
+				err := i.TLS.Validate()
+				if err != nil {
+					return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+				}
+			}
+
 			setDisabled(i, disabled)
 
 			err = c.AddResource(i)
@@ -581,31 +1066,115 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 			}
 
 		case string(TypeContainer):
-			co := NewContainer(name)
-			co.Info().Module = moduleName
-			co.Info().DependsOn = dependsOn
-
-			err := decodeBody(file, b, co)
+			reps, eb, err := expandBlock(b)
 			if err != nil {
-				return err
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
 			}
 
-			// process volumes
-			for i, v := range co.Volumes {
-				// make sure mount paths are absolute when type is bind
-				if v.Type == "" || v.Type == "bind" {
-					co.Volumes[i].Source = ensureAbsolute(v.Source, file)
+			for _, rep := range reps {
+				co := NewContainer(rep.name(name))
+				co.Info().Module = moduleName
+				co.Info().DependsOn = dependsOn
+
+				err := decodeRepetition(file, eb, co, rep)
+				if err != nil {
+					return err
+				}
+
+				// process volumes
+				for i, v := range co.Volumes {
+					// make sure mount paths are absolute when type is bind
+					if v.Type == "" || v.Type == "bind" {
+						co.Volumes[i].Source = ensureAbsolute(v.Source, file)
+					}
 				}
+
+				err = co.Validate()
+				if err != nil {
+					return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, co.Name, err)
+				}
+
+				co.ApplyFallback()
+
+				co.SecurityOpt = absoluteSecurityOpts(co.SecurityOpt, file)
+
+				for i, ic := range co.InitContainers {
+					for j, v := range ic.Volumes {
+						if v.Type == "" || v.Type == "bind" {
+							co.InitContainers[i].Volumes[j].Source = ensureAbsolute(v.Source, file)
+						}
+					}
+				}
+
+				// make sure build paths are absolute
+				if co.Build != nil {
+					co.Build.Context = ensureAbsolute(co.Build.Context, file)
+
+					for i, s := range co.Build.Secrets {
+						if s.File != "" {
+							co.Build.Secrets[i].File = ensureAbsolute(s.File, file)
+						}
+					}
+				}
+
+				setDisabled(co, disabled)
+
+				err = c.AddResource(co)
+				if err != nil {
+					return fmt.Errorf(
+						"Unable to add resource %s.%s in file %s: %s",
+						b.Type,
+						co.Name,
+						file,
+						err,
+					)
+				}
+
+				// sidecars defined in the container target the container and
+				// are disabled with it
+				for _, cs := range co.Sidecars {
+					s := cs.Sidecar
+					s.ResourceInfo = ResourceInfo{
+						Name:      co.SidecarName(cs.Name),
+						Type:      TypeSidecar,
+						Status:    PendingCreation,
+						Module:    moduleName,
+						DependsOn: dependsOn,
+						Disabled:  cs.Disabled,
+						When:      cs.When,
+						WaitFor:   cs.WaitFor,
+					}
+					s.Target = fmt.Sprintf("%s.%s", TypeContainer, co.Name)
+
+					err = addSidecar(c, file, &s, co.Disabled)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+		case string(TypeDockerImage):
+			i := NewDockerImage(name)
+			i.Info().Module = moduleName
+			i.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, i)
+			if err != nil {
+				return err
 			}
 
 			// make sure build paths are absolute
-			if co.Build != nil {
-				co.Build.Context = ensureAbsolute(co.Build.Context, file)
+			i.Context = ensureAbsolute(i.Context, file)
+
+			for n, s := range i.Secrets {
+				if s.File != "" {
+					i.Secrets[n].File = ensureAbsolute(s.File, file)
+				}
 			}
 
-			setDisabled(co, disabled)
+			setDisabled(i, disabled)
 
-			err = c.AddResource(co)
+			err = c.AddResource(i)
 			if err != nil {
 				return fmt.Errorf(
 					"Unable to add resource %s.%s in file %s: %s",
@@ -626,6 +1195,11 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				return err
 			}
 
+			err = validatePorts(i.Ports, nil)
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
 			setDisabled(i, disabled)
 
 			err = c.AddResource(i)
@@ -640,22 +1214,54 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 			}
 
 		case string(TypeSidecar):
-			s := NewSidecar(name)
-			s.Info().Module = moduleName
-			s.Info().DependsOn = dependsOn
+			reps, eb, err := expandBlock(b)
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			for _, rep := range reps {
+				s := NewSidecar(rep.name(name))
+				s.Info().Module = moduleName
+				s.Info().DependsOn = dependsOn
 
-			err := decodeBody(file, b, s)
+				err := decodeRepetition(file, eb, s, rep)
+				if err != nil {
+					return err
+				}
+
+				err = addSidecar(c, file, s, disabled)
+				if err != nil {
+					return err
+				}
+			}
+
+		case string(TypeDocs):
+			do := NewDocs(name)
+			do.Info().Module = moduleName
+			do.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, do)
 			if err != nil {
 				return err
 			}
 
-			for i, v := range s.Volumes {
-				s.Volumes[i].Source = ensureAbsolute(v.Source, file)
+			do.Path = ensureAbsolute(do.Path, file)
+
+			err = do.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			if do.Auth != nil {
+				err := do.Auth.Validate()
+				if err != nil {
+					return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+				}
 			}
 
-			setDisabled(s, disabled)
+			setDisabled(do, disabled)
 
-			err = c.AddResource(s)
+			c.AddResource(do)
 			if err != nil {
 				return fmt.Errorf(
 					"Unable to add resource %s.%s in file %s: %s",
@@ -666,21 +1272,24 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				)
 			}
 
-		case string(TypeDocs):
-			do := NewDocs(name)
-			do.Info().Module = moduleName
-			do.Info().DependsOn = dependsOn
+		case string(TypeExecLocal):
+			h := NewExecLocal(name)
+			h.Info().Module = moduleName
+			h.Info().DependsOn = dependsOn
 
-			err := decodeBody(file, b, do)
+			err := decodeBody(file, b, h)
 			if err != nil {
 				return err
 			}
 
-			do.Path = ensureAbsolute(do.Path, file)
+			err = h.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
 
-			setDisabled(do, disabled)
+			setDisabled(h, disabled)
 
-			c.AddResource(do)
+			err = c.AddResource(h)
 			if err != nil {
 				return fmt.Errorf(
 					"Unable to add resource %s.%s in file %s: %s",
@@ -691,8 +1300,8 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				)
 			}
 
-		case string(TypeExecLocal):
-			h := NewExecLocal(name)
+		case string(TypeCertificateCA):
+			h := NewCertificateCA(name)
 			h.Info().Module = moduleName
 			h.Info().DependsOn = dependsOn
 
@@ -701,6 +1310,131 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				return err
 			}
 
+			h.Output = certificateOutput(h.Output, name, file)
+			h.SetPaths()
+
+			setDisabled(h, disabled)
+
+			err = c.AddResource(h)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeCertificateLeaf):
+			h := NewCertificateLeaf(name)
+			h.Info().Module = moduleName
+			h.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, h)
+			if err != nil {
+				return err
+			}
+
+			err = h.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			h.Output = certificateOutput(h.Output, name, file)
+			h.SetPaths()
+
+			setDisabled(h, disabled)
+
+			err = c.AddResource(h)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeRandomID):
+			h := NewRandomID(name)
+			h.Info().Module = moduleName
+			h.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, h)
+			if err != nil {
+				return err
+			}
+
+			// the value was generated or read from the state when parsing variables
+			v := randomVariable(b.Type, name)
+			h.Hex = v["hex"]
+			h.Dec = v["dec"]
+
+			setDisabled(h, disabled)
+
+			err = c.AddResource(h)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeRandomPassword):
+			h := NewRandomPassword(name)
+			h.Info().Module = moduleName
+			h.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, h)
+			if err != nil {
+				return err
+			}
+
+			// the value was generated or read from the state when parsing variables
+			h.Result = randomVariable(b.Type, name)["result"]
+
+			setDisabled(h, disabled)
+
+			err = c.AddResource(h)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeCopy):
+			h := NewCopy(name)
+			h.Info().Module = moduleName
+			h.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, h)
+			if err != nil {
+				return err
+			}
+
+			// sources which do not exist locally are downloaded
+			if utils.IsLocalFolder(ensureAbsolute(h.Source, file)) {
+				h.Source = ensureAbsolute(h.Source, file)
+			}
+
+			if h.Verify != nil {
+				makeVerifyAbsolute(h.Verify, file)
+			}
+
+			err = h.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
 			setDisabled(h, disabled)
 
 			err = c.AddResource(h)
@@ -730,6 +1464,11 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				h.Volumes[i].Source = ensureAbsolute(v.Source, file)
 			}
 
+			err = h.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
 			setDisabled(h, disabled)
 
 			err = c.AddResource(h)
@@ -753,7 +1492,242 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 				return err
 			}
 
-			i.Destination = ensureAbsolute(i.Destination, file)
+			// destinations in a volume are not on the local filesystem
+			if i.Volume == "" {
+				i.Destination = ensureAbsolute(i.Destination, file)
+			}
+
+			setDisabled(i, disabled)
+
+			err = c.AddResource(i)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeTunnel):
+			i := NewTunnel(name)
+			i.Info().Module = moduleName
+			i.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, i)
+			if err != nil {
+				return err
+			}
+
+			err = i.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			setDisabled(i, disabled)
+
+			err = c.AddResource(i)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeRouter):
+			i := NewRouter(name)
+			i.Info().Module = moduleName
+			i.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, i)
+			if err != nil {
+				return err
+			}
+
+			err = i.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			setDisabled(i, disabled)
+
+			err = c.AddResource(i)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeSocksProxy):
+			i := NewSocksProxy(name)
+			i.Info().Module = moduleName
+			i.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, i)
+			if err != nil {
+				return err
+			}
+
+			err = i.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			setDisabled(i, disabled)
+
+			err = c.AddResource(i)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeLogSink):
+			i := NewLogSink(name)
+			i.Info().Module = moduleName
+			i.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, i)
+			if err != nil {
+				return err
+			}
+
+			if i.File != nil && i.File.Path != "" {
+				i.File.Path = ensureAbsolute(i.File.Path, file)
+			}
+
+			err = i.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			setDisabled(i, disabled)
+
+			err = c.AddResource(i)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeRegistry):
+			i := NewRegistry(name)
+			i.Info().Module = moduleName
+			i.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, i)
+			if err != nil {
+				return err
+			}
+
+			err = i.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			setDisabled(i, disabled)
+
+			err = c.AddResource(i)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeCompose):
+			i := NewCompose(name)
+			i.Info().Module = moduleName
+			i.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, i)
+			if err != nil {
+				return err
+			}
+
+			i.File = ensureAbsolute(i.File, file)
+
+			err = i.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			setDisabled(i, disabled)
+
+			err = c.AddResource(i)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeTerraform):
+			i := NewTerraform(name)
+			i.Info().Module = moduleName
+			i.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, i)
+			if err != nil {
+				return err
+			}
+
+			i.Source = ensureAbsolute(i.Source, file)
+
+			err = i.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
+
+			setDisabled(i, disabled)
+
+			err = c.AddResource(i)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
+
+		case string(TypeTest):
+			i := NewTest(name)
+			i.Info().Module = moduleName
+			i.Info().DependsOn = dependsOn
+
+			err := decodeBody(file, b, i)
+			if err != nil {
+				return err
+			}
+
+			err = i.Validate()
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+			}
 
 			setDisabled(i, disabled)
 
@@ -782,12 +1756,13 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 			if !utils.IsLocalFolder(ensureAbsolute(m.Source, file)) {
 				// get the details
 				dst := utils.GetBlueprintLocalFolder(m.Source)
-				err := getFiles(m.Source, dst)
+				err := moduleGetter(m.Source, dst)
 				if err != nil {
 					return err
 				}
 
 				// set the source to the local folder
+				m.RemoteSource = m.Source
 				m.Source = dst
 			}
 
@@ -808,11 +1783,42 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 			// into other folders. They should have a separate context but
 			// for now just reset the file path to ensure any other resources
 			// parsed after the module have the correct path
-			ctx.Functions["file_path"] = getFilePathFunc(file)
-			ctx.Functions["file_dir"] = getFileDirFunc(file)
+			setFileContext(file)
 
 		default:
-			return ResourceTypeNotExistError{string(b.Type), file}
+			plugin, ok := PluginType(ResourceType(b.Type))
+			if !ok {
+				return ResourceTypeNotExistError{string(b.Type), file}
+			}
+
+			p := NewPluginResource(name, ResourceType(b.Type), plugin)
+			p.Info().Module = moduleName
+			p.Info().DependsOn = dependsOn
+
+			err := decodePluginBody(file, b, p)
+			if err != nil {
+				return err
+			}
+
+			if pluginValidator != nil {
+				err = pluginValidator(p)
+				if err != nil {
+					return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, b.Type, name, err)
+				}
+			}
+
+			setDisabled(p, disabled)
+
+			err = c.AddResource(p)
+			if err != nil {
+				return fmt.Errorf(
+					"Unable to add resource %s.%s in file %s: %s",
+					b.Type,
+					b.Labels[0],
+					file,
+					err,
+				)
+			}
 		}
 	}
 
@@ -822,6 +1828,19 @@ func parseHCLFile(file string, c *Config, moduleName string, disabled bool, depe
 // ParseReferences links the object references in config elements
 func ParseReferences(c *Config) error {
 	for _, r := range c.Resources {
+		// the containers checked by wait_for must exist before the resource
+		for _, w := range r.Info().WaitFor {
+			if w.Container != "" {
+				r.Info().DependsOn = append(r.Info().DependsOn, w.Container)
+			}
+		}
+
+		// the type of plugin resources is not known until the plugins are loaded
+		if p, ok := r.(*PluginResource); ok {
+			p.DependsOn = append(p.DependsOn, p.Depends...)
+			continue
+		}
+
 		switch r.Info().Type {
 		case TypeContainer:
 			c := r.(*Container)
@@ -830,6 +1849,21 @@ func ParseReferences(c *Config) error {
 			}
 			c.DependsOn = append(c.DependsOn, c.Depends...)
 
+			images := []Image{}
+			if c.Image != nil {
+				images = append(images, *c.Image)
+			}
+
+			for _, ic := range c.InitContainers {
+				images = append(images, ic.Image)
+			}
+
+			c.DependsOn = append(c.DependsOn, dockerImageDependencies(r.Info().Config, images)...)
+
+		case TypeDockerImage:
+			c := r.(*DockerImage)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
 		case TypeContainerIngress:
 			c := r.(*ContainerIngress)
 			for _, n := range c.Networks {
@@ -837,11 +1871,23 @@ func ParseReferences(c *Config) error {
 			}
 			c.DependsOn = append(c.DependsOn, c.Target)
 			c.DependsOn = append(c.DependsOn, c.Depends...)
+			c.DependsOn = append(c.DependsOn, portCertificateDependencies(c.Ports)...)
 
 		case TypeSidecar:
 			c := r.(*Sidecar)
 			c.DependsOn = append(c.DependsOn, c.Target)
 			c.DependsOn = append(c.DependsOn, c.Depends...)
+			c.DependsOn = append(c.DependsOn, dockerImageDependencies(r.Info().Config, []Image{c.Image})...)
+
+			// Docker only allows other containers to join the IPC namespace
+			// of a shareable container
+			if c.ShareIPC {
+				if t, err := c.FindDependentResource(c.Target); err == nil {
+					if tc, ok := t.(*Container); ok {
+						tc.ShareableIPC = true
+					}
+				}
+			}
 
 		case TypeDocs:
 			c := r.(*Docs)
@@ -850,27 +1896,115 @@ func ParseReferences(c *Config) error {
 			}
 			c.DependsOn = append(c.DependsOn, c.Depends...)
 
-		case TypeExecRemote:
-			c := r.(*ExecRemote)
+			// terminals can only be attached once the target exists
+			for _, t := range c.Terminals {
+				if t.Target != "" {
+					c.DependsOn = append(c.DependsOn, t.Target)
+				}
+			}
+
+		case TypeCertificateCA:
+			c := r.(*CertificateCA)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
+		case TypeCertificateLeaf:
+			c := r.(*CertificateLeaf)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+			c.DependsOn = append(c.DependsOn, c.CA)
+
+		case TypeRandomID:
+			c := r.(*RandomID)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
+		case TypeRandomPassword:
+			c := r.(*RandomPassword)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
+		case TypeCopy:
+			c := r.(*Copy)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
+			// volumes are created by the containers which mount them
+			if c.Target != "" {
+				c.DependsOn = append(c.DependsOn, c.Target)
+			}
+
+		case TypeExecRemote:
+			c := r.(*ExecRemote)
+			for _, n := range c.Networks {
+				c.DependsOn = append(c.DependsOn, n.Name)
+			}
+
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
+			// target is optional
+			if c.Target != "" {
+				c.DependsOn = append(c.DependsOn, c.Target)
+			}
+
+		case TypeExecLocal:
+			c := r.(*ExecLocal)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
+		case TypeTemplate:
+			c := r.(*Template)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+			c.DependsOn = append(c.DependsOn, resourceValueDependencies(c.Vars)...)
+
+		case TypeTunnel:
+			c := r.(*Tunnel)
+			for _, n := range c.Networks {
+				c.DependsOn = append(c.DependsOn, n.Name)
+			}
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
+		case TypeLogSink:
+			c := r.(*LogSink)
+			for _, n := range c.Networks {
+				c.DependsOn = append(c.DependsOn, n.Name)
+			}
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
+		case TypeRegistry:
+			c := r.(*Registry)
 			for _, n := range c.Networks {
 				c.DependsOn = append(c.DependsOn, n.Name)
 			}
+			c.DependsOn = append(c.DependsOn, c.Depends...)
 
+		case TypeRouter:
+			c := r.(*Router)
 			c.DependsOn = append(c.DependsOn, c.Depends...)
 
-			// target is optional
-			if c.Target != "" {
-				c.DependsOn = append(c.DependsOn, c.Target)
+		case TypeSocksProxy:
+			c := r.(*SocksProxy)
+			if c.Cluster != "" {
+				c.DependsOn = append(c.DependsOn, c.Cluster)
 			}
+			c.DependsOn = append(c.DependsOn, c.Depends...)
 
-		case TypeExecLocal:
-			c := r.(*ExecLocal)
+		case TypeTest:
+			c := r.(*Test)
+			for _, p := range c.PodReady {
+				c.DependsOn = append(c.DependsOn, p.Cluster)
+			}
 			c.DependsOn = append(c.DependsOn, c.Depends...)
 
-		case TypeTemplate:
-			c := r.(*Template)
+		case TypeCompose:
+			c := r.(*Compose)
+			for _, n := range c.Networks {
+				c.DependsOn = append(c.DependsOn, n.Name)
+			}
 			c.DependsOn = append(c.DependsOn, c.Depends...)
 
+		case TypeTerraform:
+			c := r.(*Terraform)
+			for _, n := range c.Networks {
+				c.DependsOn = append(c.DependsOn, n.Name)
+			}
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+			c.DependsOn = append(c.DependsOn, resourceValueDependencies(c.Variables)...)
+
 		case TypeIngress:
 			c := r.(*Ingress)
 			if c.Source.Config.Cluster != "" {
@@ -881,6 +2015,10 @@ func ParseReferences(c *Config) error {
 				c.DependsOn = append(c.DependsOn, c.Destination.Config.Cluster)
 			}
 
+			if c.TLS != nil && c.TLS.Certificate != "" {
+				c.DependsOn = append(c.DependsOn, c.TLS.Certificate)
+			}
+
 			c.DependsOn = append(c.DependsOn, c.Depends...)
 
 		case TypeK8sCluster:
@@ -889,6 +2027,8 @@ func ParseReferences(c *Config) error {
 				c.DependsOn = append(c.DependsOn, n.Name)
 			}
 			c.DependsOn = append(c.DependsOn, c.Depends...)
+			c.DependsOn = append(c.DependsOn, c.Registries...)
+			c.DependsOn = append(c.DependsOn, dockerImageDependencies(r.Info().Config, c.ClusterImages())...)
 
 			// always add a dependency of the cache as this is
 			// required by all clusters
@@ -898,12 +2038,18 @@ func ParseReferences(c *Config) error {
 			c := r.(*Helm)
 			c.DependsOn = append(c.DependsOn, c.Cluster)
 			c.DependsOn = append(c.DependsOn, c.Depends...)
+			c.DependsOn = append(c.DependsOn, namespaceDependencies(r.Info().Config, c.Cluster, c.Namespace)...)
 
 		case TypeK8sConfig:
 			c := r.(*K8sConfig)
 			c.DependsOn = append(c.DependsOn, c.Cluster)
 			c.DependsOn = append(c.DependsOn, c.Depends...)
 
+		case TypeK8sNamespace:
+			c := r.(*K8sNamespace)
+			c.DependsOn = append(c.DependsOn, c.Cluster)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
 		case TypeK8sIngress:
 			c := r.(*K8sIngress)
 			for _, n := range c.Networks {
@@ -911,6 +2057,7 @@ func ParseReferences(c *Config) error {
 			}
 			c.DependsOn = append(c.DependsOn, c.Cluster)
 			c.DependsOn = append(c.DependsOn, c.Depends...)
+			c.DependsOn = append(c.DependsOn, portCertificateDependencies(c.Ports)...)
 
 		case TypeNomadCluster:
 			c := r.(*NomadCluster)
@@ -918,6 +2065,8 @@ func ParseReferences(c *Config) error {
 				c.DependsOn = append(c.DependsOn, n.Name)
 			}
 			c.DependsOn = append(c.DependsOn, c.Depends...)
+			c.DependsOn = append(c.DependsOn, c.Registries...)
+			c.DependsOn = append(c.DependsOn, dockerImageDependencies(r.Info().Config, c.ClusterImages())...)
 			// always add a dependency of the cache as this is
 			// required by all clusters
 			c.DependsOn = append(c.DependsOn, fmt.Sprintf("%s.%s", TypeImageCache, utils.CacheResourceName))
@@ -929,6 +2078,7 @@ func ParseReferences(c *Config) error {
 			}
 			c.DependsOn = append(c.DependsOn, c.Cluster)
 			c.DependsOn = append(c.DependsOn, c.Depends...)
+			c.DependsOn = append(c.DependsOn, portCertificateDependencies(c.Ports)...)
 
 		case TypeNomadJob:
 			c := r.(*NomadJob)
@@ -939,12 +2089,103 @@ func ParseReferences(c *Config) error {
 	return nil
 }
 
+// dockerImageDependencies returns the docker_image resources which build
+// any of the given images
+func dockerImageDependencies(c *Config, images []Image) []string {
+	deps := []string{}
+
+	for _, r := range c.FindResourcesByType(string(TypeDockerImage)) {
+		di := r.(*DockerImage)
+
+		for _, i := range images {
+			if i.Name == di.ImageName() {
+				deps = append(deps, fmt.Sprintf("%s.%s", TypeDockerImage, di.Name))
+				break
+			}
+		}
+	}
+
+	return deps
+}
+
+// resourceValueDependencies returns the resources referenced by an attribute which
+// are only set once the resource has been created, e.g. exec_local.setup.output, or
+// certificate_leaf.web.cert_pem, the resource must be created first
+func resourceValueDependencies(v interface{}) []string {
+	deps := []string{}
+
+	a, ok := v.(*hcl.Attribute)
+	if !ok {
+		return deps
+	}
+
+	for _, t := range a.Expr.Variables() {
+		switch ResourceType(t.RootName()) {
+		case TypeExecLocal, TypeTerraform, TypeCertificateCA, TypeCertificateLeaf:
+		default:
+			continue
+		}
+
+		if len(t) < 2 {
+			continue
+		}
+
+		if n, ok := t[1].(hcl.TraverseAttr); ok {
+			deps = append(deps, fmt.Sprintf("%s.%s", t.RootName(), n.Name))
+		}
+	}
+
+	return deps
+}
+
+// portCertificateDependencies returns the certificates used to terminate TLS for the ports
+func portCertificateDependencies(ports []Port) []string {
+	deps := []string{}
+
+	for _, p := range ports {
+		if p.TLS != nil && p.TLS.Certificate != "" {
+			deps = append(deps, p.TLS.Certificate)
+		}
+	}
+
+	return deps
+}
+
+// certificateOutput returns the absolute location of the output directory
+// for a certificate, by default certificates are written to the certs directory
+func certificateOutput(output, name, file string) string {
+	if output == "" {
+		return filepath.Join(utils.ShipyardHome(), "certs", name)
+	}
+
+	return ensureAbsolute(output, file)
+}
+
+// namespaceDependencies returns the k8s_namespace resources which create the
+// given namespace in the cluster, charts installed into a managed namespace
+// must be created after the namespace and destroyed before it
+func namespaceDependencies(c *Config, cluster, namespace string) []string {
+	deps := []string{}
+
+	for _, r := range c.FindResourcesByType(string(TypeK8sNamespace)) {
+		ns := r.(*K8sNamespace)
+
+		if ns.Cluster == cluster && ns.NamespaceName() == namespace {
+			deps = append(deps, fmt.Sprintf("%s.%s", TypeK8sNamespace, ns.Name))
+		}
+	}
+
+	return deps
+}
+
 func parseVariables(abs string, c *Config) error {
-	files, err := filepath.Glob(path.Join(abs, "*.hcl"))
+	files, err := configFiles(abs)
 	if err != nil {
 		return err
 	}
 
+	files = overlayFiles(abs, files)
+
 	for _, f := range files {
 		err := parseVariableFile(f, c)
 		if err != nil {
@@ -952,15 +2193,18 @@ func parseVariables(abs string, c *Config) error {
 		}
 	}
 
-	return nil
+	// locals can reference variables so they are evaluated once all variables are known
+	return parseLocals(files)
 }
 
 func parseOutputs(abs string, disabled bool, c *Config) error {
-	files, err := filepath.Glob(path.Join(abs, "*.hcl"))
+	files, err := configFiles(abs)
 	if err != nil {
 		return err
 	}
 
+	files = overlayFiles(abs, files)
+
 	for _, f := range files {
 		err := parseOutputFile(f, disabled, c)
 		if err != nil {
@@ -972,19 +2216,14 @@ func parseOutputs(abs string, disabled bool, c *Config) error {
 }
 
 func parseOutputFile(file string, disabled bool, c *Config) error {
-	parser := hclparse.NewParser()
-	ctx.Functions["file_path"] = getFilePathFunc(file)
-	ctx.Functions["file_dir"] = getFileDirFunc(file)
+	setFileContext(file)
 
-	f, diag := parser.ParseHCLFile(file)
-	if diag.HasErrors() {
-		return errors.New(diag.Error())
+	body, err := parseHCLBody(file)
+	if err != nil {
+		return err
 	}
 
-	body, ok := f.Body.(*hclsyntax.Body)
-	if !ok {
-		return errors.New("Error getting body")
-	}
+	body = applyOverlay(file, body)
 
 	for _, b := range body.Blocks {
 		switch b.Type {
@@ -996,6 +2235,10 @@ func parseOutputFile(file string, disabled bool, c *Config) error {
 				return err
 			}
 
+			if v.Sensitive {
+				utils.AddSensitiveValue(v.Value)
+			}
+
 			setDisabled(v, disabled)
 
 			c.AddResource(v)
@@ -1006,11 +2249,13 @@ func parseOutputFile(file string, disabled bool, c *Config) error {
 }
 
 func parseResources(abs string, c *Config, moduleName string, disabled bool, dependsOn []string) error {
-	files, err := filepath.Glob(path.Join(abs, "*.hcl"))
+	files, err := configFiles(abs)
 	if err != nil {
 		return err
 	}
 
+	files = overlayFiles(abs, files)
+
 	for _, f := range files {
 		err := parseHCLFile(f, c, moduleName, disabled, dependsOn)
 		if err != nil {
@@ -1064,6 +2309,17 @@ func parseYardHCL(file string, c *Config) error {
 		return errors.New(diag.Error())
 	}
 
+	for i, f := range bp.TrustedCAFiles {
+		bp.TrustedCAFiles[i] = ensureAbsolute(f, file)
+	}
+
+	for _, n := range bp.Notifications {
+		err := n.Validate()
+		if err != nil {
+			return fmt.Errorf("Error in file '%s': notification '%s' %s", file, n.Name, err)
+		}
+	}
+
 	c.Blueprint = bp
 
 	return nil
@@ -1192,7 +2448,11 @@ func buildContext() *hcl.EvalContext {
 		},
 		Type: function.StaticReturnType(cty.String),
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
-			_, kcp, _ := utils.CreateKubeConfigPath(args[0].AsString())
+			_, kcp, _, err := utils.CreateKubeConfigPath(args[0].AsString())
+			if err != nil {
+				return cty.StringVal(""), err
+			}
+
 			return cty.StringVal(kcp), nil
 		},
 	})
@@ -1207,7 +2467,11 @@ func buildContext() *hcl.EvalContext {
 		},
 		Type: function.StaticReturnType(cty.String),
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
-			_, _, kcp := utils.CreateKubeConfigPath(args[0].AsString())
+			_, _, kcp, err := utils.CreateKubeConfigPath(args[0].AsString())
+			if err != nil {
+				return cty.StringVal(""), err
+			}
+
 			return cty.StringVal(kcp), nil
 		},
 	})
@@ -1222,10 +2486,8 @@ func buildContext() *hcl.EvalContext {
 		},
 		Type: function.StaticReturnType(cty.String),
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
-			// get the current file path from the context
-			path := ctx.Variables["path"].AsString()
-			// conver the file path to an absolute
-			fp := ensureAbsolute(args[0].AsString(), path)
+			// conver the file path to an absolute using the current file
+			fp := ensureAbsolute(args[0].AsString(), currentFile)
 
 			// read the contents of the file
 			d, err := ioutil.ReadFile(fp)
@@ -1261,12 +2523,30 @@ func buildContext() *hcl.EvalContext {
 		},
 		Type: function.StaticReturnType(cty.String),
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
-			conf, _ := utils.GetClusterConfig(args[0].AsString())
+			conf, _, err := utils.GetClusterConfig(args[0].AsString())
+			if err != nil {
+				return cty.StringVal(""), err
+			}
 
 			return cty.StringVal(conf.APIAddress(utils.LocalContext)), nil
 		},
 	})
 
+	var IsCIDRFunc = function.New(&function.Spec{
+		Params: []function.Parameter{
+			{
+				Name: "cidr",
+				Type: cty.String,
+			},
+		},
+		Type: function.StaticReturnType(cty.Bool),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			_, _, err := net.ParseCIDR(args[0].AsString())
+
+			return cty.BoolVal(err == nil), nil
+		},
+	})
+
 	var LenFunc = function.New(&function.Spec{
 		Params: []function.Parameter{
 			{
@@ -1277,14 +2557,48 @@ func buildContext() *hcl.EvalContext {
 		},
 		Type: function.StaticReturnType(cty.Number),
 		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
-			if len(args) == 1 && args[0].Type().IsCollectionType() || args[0].Type().IsTupleType() {
-				i := args[0].ElementIterator()
-				if i.Next() {
-					return args[0].Length(), nil
-				}
+			v := args[0]
+			if v.IsNull() {
+				return cty.NumberIntVal(0), nil
 			}
 
-			return cty.NumberIntVal(0), nil
+			if !v.IsKnown() {
+				return cty.UnknownVal(cty.Number), nil
+			}
+
+			// strings return the number of characters, lists, maps, and objects
+			// return the number of elements
+			switch {
+			case v.Type() == cty.String:
+				return cty.NumberIntVal(int64(utf8.RuneCountInString(v.AsString()))), nil
+			case v.Type().IsCollectionType(), v.Type().IsTupleType(), v.Type().IsObjectType():
+				return cty.NumberIntVal(int64(v.LengthInt())), nil
+			}
+
+			return cty.NumberIntVal(0), fmt.Errorf("len can not be used with a value of type %s", v.Type().FriendlyName())
+		},
+	})
+
+	var TemplateFileFunc = function.New(&function.Spec{
+		Params: []function.Parameter{
+			{
+				Name:             "path",
+				Type:             cty.String,
+				AllowDynamicType: true,
+			},
+			{
+				Name:             "vars",
+				Type:             cty.DynamicPseudoType,
+				AllowDynamicType: true,
+				AllowNull:        true,
+			},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			// template paths are relative to the current file like file
+			fp := ensureAbsolute(args[0].AsString(), currentFile)
+
+			return renderTemplateFile(fp, args[1])
 		},
 	})
 
@@ -1305,6 +2619,9 @@ func buildContext() *hcl.EvalContext {
 	ctx.Functions["docker_host"] = DockerHostFunc
 	ctx.Functions["shipyard_ip"] = ShipyardIPFunc
 	ctx.Functions["cluster_api"] = ClusterAPIFunc
+	ctx.Functions["contains"] = stdlib.ContainsFunc
+	ctx.Functions["is_cidr"] = IsCIDRFunc
+	ctx.Functions["templatefile"] = TemplateFileFunc
 
 	// the functions file_path and file_dir are added dynamically when processing a file
 	// this is because the need a reference to the current file
@@ -1312,6 +2629,86 @@ func buildContext() *hcl.EvalContext {
 	return ctx
 }
 
+// renderTemplateFile renders the HCL template in the file, the attributes of vars are the
+// variables available to the template. Templates can use all the functions in the
+// context except templatefile, variables from the blueprint are not available
+func renderTemplateFile(path string, vars cty.Value) (cty.Value, error) {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cty.StringVal(""), err
+	}
+
+	expr, diag := hclsyntax.ParseTemplate(d, path, hcl.Pos{Line: 1, Column: 1})
+	if diag.HasErrors() {
+		return cty.StringVal(""), errors.New(diag.Error())
+	}
+
+	tv := map[string]cty.Value{}
+	if !vars.IsNull() {
+		if !vars.Type().IsObjectType() && !vars.Type().IsMapType() {
+			return cty.StringVal(""), fmt.Errorf("the variables for the template %s must be a map", path)
+		}
+
+		if !vars.IsWhollyKnown() {
+			return cty.UnknownVal(cty.String), nil
+		}
+
+		for k, v := range vars.AsValueMap() {
+			tv[k] = v
+		}
+	}
+
+	tctx := &hcl.EvalContext{Variables: tv, Functions: map[string]function.Function{}}
+	for k, f := range ctx.Functions {
+		if k != "templatefile" {
+			tctx.Functions[k] = f
+		}
+	}
+
+	val, diag := expr.Value(tctx)
+	if diag.HasErrors() {
+		return cty.StringVal(""), errors.New(diag.Error())
+	}
+
+	val, err = convert.Convert(val, cty.String)
+	if err != nil {
+		return cty.StringVal(""), fmt.Errorf("the template %s must return a string: %s", path, err)
+	}
+
+	return val, nil
+}
+
+// setFileContext adds the file functions and the path variable to
+// the context with a reference to the current file
+func setFileContext(file string) {
+	ctx.Functions["file_path"] = getFilePathFunc(file)
+	ctx.Functions["file_dir"] = getFileDirFunc(file)
+
+	setPathVariable(file)
+}
+
+// setPathVariable sets the path variable for the current file, path.module is the
+// folder containing the file, path.root is the folder of the blueprint being parsed,
+// and path.cwd is the current working directory. Files in modules can use path.module
+// to reference files relative to the module rather than the working directory
+func setPathVariable(file string) {
+	currentFile = file
+
+	abs, _ := filepath.Abs(file)
+	cwd, _ := os.Getwd()
+
+	root := rootFolder
+	if root == "" {
+		root = filepath.Dir(abs)
+	}
+
+	ctx.Variables["path"] = cty.ObjectVal(map[string]cty.Value{
+		"module": cty.StringVal(filepath.Dir(abs)),
+		"root":   cty.StringVal(root),
+		"cwd":    cty.StringVal(cwd),
+	})
+}
+
 func getFilePathFunc(path string) function.Function {
 	return function.New(&function.Spec{
 		Type: function.StaticReturnType(cty.String),
@@ -1337,16 +2734,180 @@ func decodeBody(path string, b *hclsyntax.Block, p interface{}) error {
 	// add the current file path to the context.
 	// this allows any functions which require absolute paths to be able to
 	// build them from relative paths.
-	ctx.Variables["path"] = cty.StringVal(path)
+	setPathVariable(path)
 
 	diag := gohcl.DecodeBody(b.Body, ctx, p)
 	if diag.HasErrors() {
 		return errors.New(diag.Error())
 	}
 
+	// wait_for can be defined on every resource
+	if r, ok := p.(Resource); ok {
+		for _, w := range r.Info().WaitFor {
+			err := w.Validate(r)
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", path, b.Type, r.Info().Name, err)
+			}
+		}
+	}
+
 	return nil
 }
 
+// attributeToMap evaluates an attribute decoded into an interface{} field
+// and converts the HCL object into a map of generic Go types
+func attributeToMap(a *hcl.Attribute) (map[string]interface{}, error) {
+	v, diag := a.Expr.Value(ctx)
+	if diag.HasErrors() {
+		return nil, errors.New(diag.Error())
+	}
+
+	if !v.Type().IsObjectType() && !v.Type().IsMapType() {
+		return nil, fmt.Errorf("must be a map")
+	}
+
+	d, err := ctyjson.Marshal(v, v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]interface{}{}
+	err = json.Unmarshal(d, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// repetition defines a single instance of a resource which has been
+// expanded using the count or for_each meta arguments
+type repetition struct {
+	// suffix is appended to the resource name to make it unique
+	suffix string
+	// variables are added to the eval context when decoding the instance
+	// i.e. count.index, each.key, each.value
+	variables map[string]cty.Value
+}
+
+// name returns the name for the resource instance
+func (r repetition) name(base string) string {
+	if r.suffix == "" {
+		return base
+	}
+
+	return fmt.Sprintf("%s-%s", base, r.suffix)
+}
+
+// expandBlock checks the block for the meta arguments count and for_each and
+// returns a repetition for every instance of the resource that should be created.
+// When neither argument is set a single repetition is returned.
+// The returned block is a copy of the original with the meta arguments removed
+// so that it can be decoded into the resource type.
+func expandBlock(b *hclsyntax.Block) ([]repetition, *hclsyntax.Block, error) {
+	countAttr, hasCount := b.Body.Attributes["count"]
+	forEachAttr, hasForEach := b.Body.Attributes["for_each"]
+
+	if !hasCount && !hasForEach {
+		return []repetition{repetition{}}, b, nil
+	}
+
+	if hasCount && hasForEach {
+		return nil, nil, fmt.Errorf("can not set both count and for_each")
+	}
+
+	// copy the block, removing the meta arguments
+	attrs := hclsyntax.Attributes{}
+	for k, v := range b.Body.Attributes {
+		if k != "count" && k != "for_each" {
+			attrs[k] = v
+		}
+	}
+
+	body := *b.Body
+	body.Attributes = attrs
+
+	eb := *b
+	eb.Body = &body
+
+	reps := []repetition{}
+
+	if hasCount {
+		val, diag := countAttr.Expr.Value(ctx)
+		if diag.HasErrors() {
+			return nil, nil, errors.New(diag.Error())
+		}
+
+		var count int
+		err := gocty.FromCtyValue(val, &count)
+		if err != nil {
+			return nil, nil, fmt.Errorf("count must be a whole number: %s", err)
+		}
+
+		for i := 0; i < count; i++ {
+			reps = append(reps, repetition{
+				suffix: strconv.Itoa(i),
+				variables: map[string]cty.Value{
+					"count": cty.ObjectVal(map[string]cty.Value{"index": cty.NumberIntVal(int64(i))}),
+				},
+			})
+		}
+
+		return reps, &eb, nil
+	}
+
+	val, diag := forEachAttr.Expr.Value(ctx)
+	if diag.HasErrors() {
+		return nil, nil, errors.New(diag.Error())
+	}
+
+	ty := val.Type()
+	if val.IsNull() || !(ty.IsMapType() || ty.IsObjectType() || ty.IsSetType()) {
+		return nil, nil, fmt.Errorf("for_each must be a map or a set of strings")
+	}
+
+	// elements are returned in lexical order of the keys so the
+	// expanded resources are always created in the same order
+	it := val.ElementIterator()
+	for it.Next() {
+		k, v := it.Element()
+
+		// sets do not have keys, use the value as the key
+		if ty.IsSetType() {
+			k = v
+		}
+
+		if k.Type() != cty.String {
+			return nil, nil, fmt.Errorf("for_each set must only contain strings")
+		}
+
+		reps = append(reps, repetition{
+			suffix: k.AsString(),
+			variables: map[string]cty.Value{
+				"each": cty.ObjectVal(map[string]cty.Value{"key": k, "value": v}),
+			},
+		})
+	}
+
+	return reps, &eb, nil
+}
+
+// decodeRepetition decodes the block adding the variables for the repetition
+// to the context
+func decodeRepetition(path string, b *hclsyntax.Block, p interface{}, r repetition) error {
+	for k, v := range r.variables {
+		ctx.Variables[k] = v
+	}
+
+	defer func() {
+		for k := range r.variables {
+			delete(ctx.Variables, k)
+		}
+	}()
+
+	return decodeBody(path, b, p)
+}
+
 // ensureAbsolute ensure that the given path is either absolute or
 // if relative is converted to abasolute based on the path of the config
 func ensureAbsolute(path, file string) string {
@@ -1366,6 +2927,20 @@ func ensureAbsolute(path, file string) string {
 	return filepath.Join(baseDir, path)
 }
 
+// moduleGetter fetches the source of remote modules
+var moduleGetter = getFiles
+
+// SetModuleGetter sets the function used to fetch the source of remote modules,
+// setting nil restores the default which downloads the source with go-getter
+func SetModuleGetter(f func(source, dest string) error) {
+	if f == nil {
+		moduleGetter = getFiles
+		return
+	}
+
+	moduleGetter = f
+}
+
 func getFiles(source, dest string) error {
 	pwd, err := os.Getwd()
 	if err != nil {
@@ -1392,11 +2967,50 @@ func getFiles(source, dest string) error {
 
 // setDisabled sets the disabled flag on a resource when the
 // parent is disabled
+// addSidecar validates a decoded sidecar and adds it to the config
+func addSidecar(c *Config, file string, s *Sidecar, disabled bool) error {
+	for i, v := range s.Volumes {
+		// make sure mount paths are absolute when type is bind
+		if v.Type == "" || v.Type == "bind" {
+			s.Volumes[i].Source = ensureAbsolute(v.Source, file)
+		}
+	}
+
+	err := s.Validate()
+	if err != nil {
+		return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", file, TypeSidecar, s.Name, err)
+	}
+
+	s.ApplyFallback()
+
+	s.SecurityOpt = absoluteSecurityOpts(s.SecurityOpt, file)
+
+	setDisabled(s, disabled)
+
+	err = c.AddResource(s)
+	if err != nil {
+		return fmt.Errorf(
+			"Unable to add resource %s.%s in file %s: %s",
+			TypeSidecar,
+			s.Name,
+			file,
+			err,
+		)
+	}
+
+	return nil
+}
+
 func setDisabled(r Resource, parentDisabled bool) {
 	if parentDisabled {
 		r.Info().Disabled = true
 	}
 
+	// resources are only enabled when the when condition is true
+	if w := r.Info().When; w != nil && !*w {
+		r.Info().Disabled = true
+	}
+
 	// when the resource is disabled set the status
 	// so the engine will not create or delete it
 	if r.Info().Disabled {
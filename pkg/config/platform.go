@@ -0,0 +1,32 @@
+package config
+
+// PlatformWindows is the platform of images for Windows containers
+const PlatformWindows = "windows"
+
+// linuxOnlyTypes are the resources which are created from Linux images provided by
+// Shipyard, they can not be created when the engine is running Windows containers
+var linuxOnlyTypes = []ResourceType{
+	TypeK8sCluster,
+	TypeNomadCluster,
+	TypeIngress,
+	TypeLegacyIngress,
+	TypeContainerIngress,
+	TypeK8sIngress,
+	TypeNomadIngress,
+	TypeImageCache,
+	TypeDocs,
+	TypeRegistry,
+	TypeTerraform,
+}
+
+// IsLinuxOnly returns true when resources of the given type require an engine
+// which runs Linux containers
+func IsLinuxOnly(t ResourceType) bool {
+	for _, l := range linuxOnlyTypes {
+		if l == t {
+			return true
+		}
+	}
+
+	return false
+}
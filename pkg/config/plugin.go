@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	"github.com/mitchellh/mapstructure"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// PluginResource is a resource whose type is provided by a plugin, the attributes
+// and blocks in the resource are passed to the plugin as Attributes.
+// example config, where the type firecracker_vm is provided by a plugin:
+//
+//	firecracker_vm "dev" {
+//	  depends_on = ["network.cloud"]
+//
+//	  kernel = "./vmlinux"
+//	  memory = 1024
+//
+//	  drive {
+//	    path = "./rootfs.ext4"
+//	  }
+//	}
+type PluginResource struct {
+	ResourceInfo `mapstructure:",squash"`
+
+	Depends []string `json:"depends,omitempty"`
+
+	// Plugin is the name of the plugin which provides the resource type
+	Plugin string `json:"plugin"`
+
+	// Attributes contains the attributes of the resource, nested blocks are a list of objects
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// NewPluginResource creates a resource of a type provided by the plugin
+func NewPluginResource(name string, t ResourceType, plugin string) *PluginResource {
+	return &PluginResource{
+		ResourceInfo: ResourceInfo{Name: name, Type: t, Status: PendingCreation},
+		Plugin:       plugin,
+		Attributes:   map[string]interface{}{},
+	}
+}
+
+var pluginMutex = sync.Mutex{}
+
+// pluginTypes maps the resource types provided by plugins to the plugin name
+var pluginTypes = map[ResourceType]string{}
+
+// pluginValidator checks the config for a plugin resource when it is parsed
+var pluginValidator func(r *PluginResource) error
+
+// RegisterPluginType registers a resource type provided by a plugin so that
+// blocks of the type can be parsed, plugins can not replace built in types
+func RegisterPluginType(t ResourceType, plugin string) error {
+	if blockValue(string(t)) != nil || t == TypeModule || t == TypeVariable || t == TypeOutput {
+		return fmt.Errorf("type %s is a built in resource type", t)
+	}
+
+	pluginMutex.Lock()
+	defer pluginMutex.Unlock()
+
+	if p, ok := pluginTypes[t]; ok && p != plugin {
+		return fmt.Errorf("type %s is already provided by the plugin %s", t, p)
+	}
+
+	pluginTypes[t] = plugin
+
+	return nil
+}
+
+// PluginType returns the name of the plugin which provides the resource type
+func PluginType(t ResourceType) (string, bool) {
+	pluginMutex.Lock()
+	defer pluginMutex.Unlock()
+
+	p, ok := pluginTypes[t]
+	return p, ok
+}
+
+// ClearPluginTypes removes the registered plugin types
+func ClearPluginTypes() {
+	pluginMutex.Lock()
+	defer pluginMutex.Unlock()
+
+	pluginTypes = map[ResourceType]string{}
+}
+
+// SetPluginValidator sets the function used to validate plugin resources
+// when they are parsed, the function is usually provided by the plugin client
+func SetPluginValidator(f func(r *PluginResource) error) {
+	pluginValidator = f
+}
+
+// decodePluginBody evaluates the attributes and blocks in the resource into
+// generic Go types which can be serialized and sent to the plugin
+func decodePluginBody(path string, b *hclsyntax.Block, r *PluginResource) error {
+	setPathVariable(path)
+
+	m, err := bodyToMap(b.Body)
+	if err != nil {
+		return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", path, b.Type, r.Name, err)
+	}
+
+	// meta arguments are set on the resource rather than passed to the plugin
+	if d, ok := m["depends_on"]; ok {
+		l, ok := d.([]interface{})
+		if !ok {
+			return fmt.Errorf("Error in file '%s': resource '%s.%s' depends_on must be a list of resources", path, b.Type, r.Name)
+		}
+
+		for _, v := range l {
+			r.Depends = append(r.Depends, fmt.Sprintf("%v", v))
+		}
+
+		delete(m, "depends_on")
+	}
+
+	if d, ok := m["disabled"].(bool); ok {
+		r.Disabled = d
+		delete(m, "disabled")
+	}
+
+	if w, ok := m["when"].(bool); ok {
+		r.When = &w
+		delete(m, "when")
+	}
+
+	if w, ok := m["wait_for"]; ok {
+		err := mapstructure.Decode(w, &r.WaitFor)
+		if err != nil {
+			return fmt.Errorf("Error in file '%s': resource '%s.%s' wait_for %s", path, b.Type, r.Name, err)
+		}
+
+		for _, wf := range r.WaitFor {
+			err := wf.Validate(r)
+			if err != nil {
+				return fmt.Errorf("Error in file '%s': resource '%s.%s' %s", path, b.Type, r.Name, err)
+			}
+		}
+
+		delete(m, "wait_for")
+	}
+
+	r.Attributes = m
+
+	return nil
+}
+
+// bodyToMap converts the attributes in the body into a map, nested blocks are
+// added as a list of objects keyed by the block type
+func bodyToMap(body *hclsyntax.Body) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+
+	for n, a := range body.Attributes {
+		v, diag := a.Expr.Value(ctx)
+		if diag.HasErrors() {
+			return nil, errors.New(diag.Error())
+		}
+
+		d, err := ctyjson.Marshal(v, v.Type())
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert attribute %s: %s", n, err)
+		}
+
+		var i interface{}
+		err = json.Unmarshal(d, &i)
+		if err != nil {
+			return nil, err
+		}
+
+		m[n] = i
+	}
+
+	for _, b := range body.Blocks {
+		bm, err := bodyToMap(b.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		l, _ := m[b.Type].([]interface{})
+		m[b.Type] = append(l, bm)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPluginTypes(t *testing.T) {
+	err := RegisterPluginType("firecracker_vm", "firecracker")
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		ClearPluginTypes()
+		SetPluginValidator(nil)
+	})
+}
+
+func TestRegisterPluginTypeReturnsErrorForBuiltInTypes(t *testing.T) {
+	err := RegisterPluginType(TypeContainer, "firecracker")
+	assert.Error(t, err)
+
+	err = RegisterPluginType(TypeModule, "firecracker")
+	assert.Error(t, err)
+}
+
+func TestRegisterPluginTypeReturnsErrorWhenTypeProvidedByOtherPlugin(t *testing.T) {
+	setupPluginTypes(t)
+
+	err := RegisterPluginType("firecracker_vm", "other")
+	assert.Error(t, err)
+}
+
+func TestPluginResourceCreatesCorrectly(t *testing.T) {
+	setupPluginTypes(t)
+
+	c, _ := CreateConfigFromStrings(t, pluginDefault)
+
+	r, err := c.FindResource("firecracker_vm.dev")
+	assert.NoError(t, err)
+
+	p := r.(*PluginResource)
+	assert.Equal(t, "firecracker", p.Plugin)
+	assert.Equal(t, float64(1024), p.Attributes["memory"])
+	assert.Equal(t, "2", p.Attributes["cpus"])
+	assert.Len(t, p.Attributes["drive"], 2)
+	assert.NotContains(t, p.Attributes, "depends_on")
+	assert.Contains(t, p.DependsOn, "network.cloud")
+}
+
+func TestPluginResourceWithUnregisteredTypeReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, pluginDefault)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.IsType(t, ResourceTypeNotExistError{}, err)
+}
+
+func TestPluginResourceReturnsErrorWhenPluginValidationFails(t *testing.T) {
+	setupPluginTypes(t)
+	SetPluginValidator(func(r *PluginResource) error { return fmt.Errorf("memory must be a power of 2") })
+
+	dir := CreateTestFiles(t, pluginDefault)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "memory must be a power of 2")
+}
+
+func TestPluginResourceSerializesToState(t *testing.T) {
+	setupPluginTypes(t)
+
+	c, _ := CreateConfigFromStrings(t, pluginDefault)
+
+	d, err := json.Marshal(c)
+	assert.NoError(t, err)
+
+	// state is loaded without the plugin being registered
+	ClearPluginTypes()
+
+	sc := New()
+	err = json.Unmarshal(d, sc)
+	assert.NoError(t, err)
+
+	r, err := sc.FindResource("firecracker_vm.dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "firecracker", r.(*PluginResource).Plugin)
+	assert.Equal(t, float64(1024), r.(*PluginResource).Attributes["memory"])
+}
+
+func TestValidateAcceptsPluginResources(t *testing.T) {
+	setupPluginTypes(t)
+
+	dir := CreateTestFiles(t, pluginDefault)
+
+	errs := Validate(dir, nil, "")
+	assert.Empty(t, errs)
+}
+
+const pluginDefault = `
+network "cloud" {
+  subnet = "10.5.0.0/16"
+}
+
+firecracker_vm "dev" {
+  depends_on = ["network.cloud"]
+
+  memory = 1024
+  cpus   = "2"
+
+  drive {
+    path = "./rootfs.ext4"
+  }
+
+  drive {
+    path = "./data.ext4"
+  }
+}
+`
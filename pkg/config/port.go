@@ -1,12 +1,22 @@
 package config
 
+import (
+	"fmt"
+	"net"
+)
+
 // Port is a port mapping
 type Port struct {
 	Local         string `hcl:"local" json:"local"`                                                             // Local port in the container
 	Remote        string `hcl:"remote" json:"remote"`                                                           // Remote port of the service
 	Host          string `hcl:"host,optional" json:"host,omitempty"`                                            // Host port
 	Protocol      string `hcl:"protocol,optional" json:"protocol,omitempty"`                                    // Protocol tcp, udp
+	Bind          string `hcl:"bind,optional" json:"bind,omitempty"`                                            // Host interface to bind the port to e.g. 127.0.0.1, defaults to the global default or 0.0.0.0
+	HostIP        string `hcl:"host_ip,optional" json:"host_ip,omitempty" mapstructure:"host_ip"`               // Deprecated, use bind
 	OpenInBrowser string `hcl:"open_in_browser,optional" json:"open_in_browser" mapstructure:"open_in_browser"` // When a host port is defined open this port with the given path in a browser
+
+	// TLS terminates TLS for traffic to the host port, only used by the ingress resources
+	TLS *IngressTLS `hcl:"tls,block" json:"tls,omitempty"`
 }
 
 // PortRange allows a range of ports to be mapped
@@ -14,4 +24,103 @@ type PortRange struct {
 	Range      string `hcl:"range" json:"local" mapstructure:"local"`                                      // Local port in the container
 	EnableHost bool   `hcl:"enable_host,optional" json:"enable_host,omitempty" mapstructure:"enable_host"` // Host port
 	Protocol   string `hcl:"protocol,optional" json:"protocol,omitempty"`                                  // Protocol tcp, udp
+	Bind       string `hcl:"bind,optional" json:"bind,omitempty"`                                          // Host interface to bind the ports to when enable_host is set
+}
+
+// BindAddress returns the host interface the port is bound to, a blank
+// address is returned when the port uses the default
+func (p *Port) BindAddress() string {
+	if p.Bind != "" {
+		return p.Bind
+	}
+
+	return p.HostIP
+}
+
+// validateBind checks that the bind address is an ip address
+func validateBind(bind string) error {
+	if bind != "" && net.ParseIP(bind) == nil {
+		return fmt.Errorf("invalid bind address '%s', bind must be an ip address e.g. 127.0.0.1 or 0.0.0.0", bind)
+	}
+
+	return nil
+}
+
+func validatePorts(ports []Port, ranges []PortRange) error {
+	for _, p := range ports {
+		err := validateBind(p.BindAddress())
+		if err != nil {
+			return err
+		}
+
+		if p.Protocol != "" && p.Protocol != "tcp" && p.Protocol != "udp" {
+			return fmt.Errorf("invalid protocol '%s' for port %s, protocol must be tcp or udp", p.Protocol, p.Local)
+		}
+
+		if p.TLS != nil {
+			if p.Protocol == "udp" {
+				return fmt.Errorf("tls can not be used with udp port %s", p.Local)
+			}
+
+			if p.Host == "" {
+				return fmt.Errorf("tls requires a host port for port %s", p.Local)
+			}
+
+			err := p.TLS.Validate()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range ranges {
+		err := validateBind(p.Bind)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// HostPorts returns the ports the resource binds on the host, ports
+// without a host port are not returned
+func HostPorts(r Resource) []*Port {
+	var ports []*Port
+
+	switch v := r.(type) {
+	case *Container:
+		ports = portRefs(v.Ports)
+	case *K8sCluster:
+		ports = portRefs(v.Ports)
+	case *ContainerIngress:
+		ports = portRefs(v.Ports)
+	case *K8sIngress:
+		ports = portRefs(v.Ports)
+		for i := range v.Targets {
+			ports = append(ports, portRefs(v.Targets[i].Ports)...)
+		}
+	case *NomadIngress:
+		ports = portRefs(v.Ports)
+	case *LegacyIngress:
+		ports = portRefs(v.Ports)
+	}
+
+	host := []*Port{}
+	for _, p := range ports {
+		if p.Host != "" {
+			host = append(host, p)
+		}
+	}
+
+	return host
+}
+
+func portRefs(ports []Port) []*Port {
+	refs := []*Port{}
+	for i := range ports {
+		refs = append(refs, &ports[i])
+	}
+
+	return refs
 }
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+)
+
+// Quota limits the resources which can be created in a workspace, a workspace is the
+// state of the Shipyard home folder, on a shared server each user has their own workspace.
+// Limits which are not set are not enforced.
+type Quota struct {
+	MaxContainers int    `hcl:"max_containers,optional" json:"max_containers,omitempty" mapstructure:"max_containers"` // maximum number of containers including cluster nodes
+	MaxMemory     string `hcl:"max_memory,optional" json:"max_memory,omitempty" mapstructure:"max_memory"`             // maximum total memory reserved by resources e.g. 16Gi
+	MaxClusters   int    `hcl:"max_clusters,optional" json:"max_clusters,omitempty" mapstructure:"max_clusters"`       // maximum number of Kubernetes and Nomad clusters
+}
+
+// QuotaUsage is the amount of each resource limited by a Quota used by a workspace
+type QuotaUsage struct {
+	Containers  int
+	MemoryBytes int64
+	Clusters    int
+}
+
+// Validate the quota
+func (q *Quota) Validate() error {
+	if q.MaxContainers < 0 {
+		return fmt.Errorf("max_containers must be 0 or greater")
+	}
+
+	if q.MaxClusters < 0 {
+		return fmt.Errorf("max_clusters must be 0 or greater")
+	}
+
+	_, err := q.MaxMemoryBytes()
+	return err
+}
+
+// MaxMemoryBytes returns the maximum memory in bytes, 0 is returned when no limit is set
+func (q *Quota) MaxMemoryBytes() (int64, error) {
+	r := &Resources{Memory: q.MaxMemory}
+
+	b, err := r.MemoryBytes()
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_memory '%s', memory must be specified with a unit e.g. 16Gi", q.MaxMemory)
+	}
+
+	return b, nil
+}
+
+// Check returns an error describing every limit the usage exceeds
+func (q *Quota) Check(u QuotaUsage) error {
+	exceeded := []string{}
+
+	if q.MaxContainers > 0 && u.Containers > q.MaxContainers {
+		exceeded = append(exceeded, fmt.Sprintf("%d containers (max_containers %d)", u.Containers, q.MaxContainers))
+	}
+
+	if mm, _ := q.MaxMemoryBytes(); mm > 0 && u.MemoryBytes > mm {
+		exceeded = append(exceeded, fmt.Sprintf("%s memory reserved (max_memory %s)", units.BytesSize(float64(u.MemoryBytes)), q.MaxMemory))
+	}
+
+	if q.MaxClusters > 0 && u.Clusters > q.MaxClusters {
+		exceeded = append(exceeded, fmt.Sprintf("%d clusters (max_clusters %d)", u.Clusters, q.MaxClusters))
+	}
+
+	if len(exceeded) == 0 {
+		return nil
+	}
+
+	return QuotaExceededError{Exceeded: exceeded}
+}
+
+// QuotaExceededError is returned when a workspace would use more resources than the quota allows
+type QuotaExceededError struct {
+	Exceeded []string
+}
+
+func (e QuotaExceededError) Error() string {
+	return fmt.Sprintf("the workspace quota would be exceeded, the workspace would use %s", strings.Join(e.Exceeded, ", "))
+}
+
+// CalculateQuotaUsage returns the resources used by the resources in the config
+// which are not disabled. The image cache is not included as it is created by Shipyard,
+// the memory is the total of the memory limits set for containers and cluster nodes.
+func CalculateQuotaUsage(c *Config) (QuotaUsage, error) {
+	u := QuotaUsage{}
+
+	for _, r := range c.Resources {
+		if r.Info().Status == Disabled {
+			continue
+		}
+
+		var res *Resources
+		nodes := 0
+
+		switch v := r.(type) {
+		case *Container:
+			nodes = 1
+			res = v.Resources
+		case *Sidecar:
+			nodes = 1
+			res = v.Resources
+		case *K8sCluster:
+			nodes = 1 + v.WorkerNodes
+			res = v.Resources
+			u.Clusters++
+		case *NomadCluster:
+			nodes = 1 + v.ClientNodes
+			res = v.Resources
+			u.Clusters++
+		case *Compose:
+			// the services are only known once the compose file has been applied
+			nodes = len(v.Services)
+			if nodes == 0 {
+				nodes = 1
+			}
+		case *ContainerIngress, *LegacyIngress, *Registry, *Docs, *Tunnel:
+			nodes = 1
+		}
+
+		u.Containers += nodes
+
+		if res == nil {
+			continue
+		}
+
+		m, err := res.MemoryBytes()
+		if err != nil {
+			return u, fmt.Errorf("resource '%s' %s", r.Info().Name, err)
+		}
+
+		u.MemoryBytes += m * int64(nodes)
+	}
+
+	return u, nil
+}
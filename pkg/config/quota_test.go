@@ -0,0 +1,101 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateQuotaUsageCountsContainersAndClusters(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, quotaResources)
+
+	u, err := CalculateQuotaUsage(c)
+	assert.NoError(t, err)
+
+	// consul, the k8s server and 2 workers, and the nomad server and 1 client
+	assert.Equal(t, 6, u.Containers)
+	assert.Equal(t, 2, u.Clusters)
+
+	// 512Mi for consul and 1Gi for each of the k8s nodes
+	assert.Equal(t, int64(512<<20+3*(1<<30)), u.MemoryBytes)
+}
+
+func TestCalculateQuotaUsageIgnoresDisabledResources(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, quotaResources)
+
+	r, err := c.FindResource("k8s_cluster.k3s")
+	assert.NoError(t, err)
+	r.Info().Status = Disabled
+
+	u, err := CalculateQuotaUsage(c)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, u.Containers)
+	assert.Equal(t, 1, u.Clusters)
+	assert.Equal(t, int64(512<<20), u.MemoryBytes)
+}
+
+func TestQuotaCheckReturnsErrorForEachExceededLimit(t *testing.T) {
+	q := &Quota{MaxContainers: 5, MaxMemory: "1Gi", MaxClusters: 1}
+
+	err := q.Check(QuotaUsage{Containers: 6, MemoryBytes: 2 << 30, Clusters: 1})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "6 containers (max_containers 5)")
+	assert.Contains(t, err.Error(), "2GiB memory reserved (max_memory 1Gi)")
+	assert.NotContains(t, err.Error(), "clusters")
+}
+
+func TestQuotaCheckIgnoresUnsetLimits(t *testing.T) {
+	q := &Quota{MaxClusters: 1}
+
+	err := q.Check(QuotaUsage{Containers: 100, MemoryBytes: 100 << 30, Clusters: 1})
+	assert.NoError(t, err)
+}
+
+func TestQuotaValidateReturnsErrorForNegativeLimits(t *testing.T) {
+	q := &Quota{MaxContainers: -1}
+
+	err := q.Validate()
+	assert.Error(t, err)
+}
+
+const quotaResources = `
+network "cloud" {
+  subnet = "10.0.0.0/16"
+}
+
+container "consul" {
+  image {
+    name = "consul:1.10.1"
+  }
+
+  network {
+    name = "network.cloud"
+  }
+
+  resources {
+    memory = "512Mi"
+  }
+}
+
+k8s_cluster "k3s" {
+  driver  = "k3s"
+  worker_nodes = 2
+
+  network {
+    name = "network.cloud"
+  }
+
+  resources {
+    memory = "1Gi"
+  }
+}
+
+nomad_cluster "dev" {
+  client_nodes = 1
+
+  network {
+    name = "network.cloud"
+  }
+}
+`
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// TypeRandomID is the resource string for a RandomID resource
+const TypeRandomID ResourceType = "random_id"
+
+// TypeRandomPassword is the resource string for a RandomPassword resource
+const TypeRandomPassword ResourceType = "random_password"
+
+const (
+	passwordLower   = "abcdefghijklmnopqrstuvwxyz"
+	passwordUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordNumeric = "0123456789"
+	passwordSpecial = "!#$%&*()-_=+[]{}<>:?"
+)
+
+// RandomID generates a random identifier which can be used as a unique suffix
+// for resource names. The value is generated once and stored in the state, it
+// does not change when the blueprint is re-applied.
+// The value can be referenced with random_id.<name>.hex or random_id.<name>.dec
+type RandomID struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	// ByteLength is the number of random bytes in the identifier, default 8
+	ByteLength int `hcl:"byte_length,optional" json:"byte_length" mapstructure:"byte_length"`
+	// Prefix is added to the start of the hex value
+	Prefix string `hcl:"prefix,optional" json:"prefix,omitempty"`
+
+	// Hex is the generated identifier encoded as a hex string including the prefix
+	Hex string `json:"hex,omitempty" state:"true"`
+	// Dec is the generated identifier as a decimal string
+	Dec string `json:"dec,omitempty" state:"true"`
+}
+
+// NewRandomID creates a RandomID resource with the default values
+func NewRandomID(name string) *RandomID {
+	return &RandomID{ResourceInfo: ResourceInfo{Name: name, Type: TypeRandomID, Status: PendingCreation}, ByteLength: 8}
+}
+
+// Validate the RandomID and return errors
+func (r *RandomID) Validate() error {
+	if r.ByteLength < 1 {
+		return fmt.Errorf("byte_length must be greater than 0, got %d", r.ByteLength)
+	}
+
+	return nil
+}
+
+// Generate sets a new random value for the identifier
+func (r *RandomID) Generate() error {
+	b := make([]byte, r.ByteLength)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return err
+	}
+
+	r.Hex = r.Prefix + hex.EncodeToString(b)
+	r.Dec = new(big.Int).SetBytes(b).String()
+
+	return nil
+}
+
+// RandomPassword generates a random password which can be used to seed credentials
+// for databases and other services. The value is generated once and stored in the state,
+// it does not change when the blueprint is re-applied.
+// The value can be referenced with random_password.<name>.result
+type RandomPassword struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	// Length of the password, default 16
+	Length int `hcl:"length,optional" json:"length"`
+	// Special adds special characters to the password, default true
+	Special bool `hcl:"special,optional" json:"special"`
+	// OverrideSpecial replaces the set of special characters used in the password
+	OverrideSpecial string `hcl:"override_special,optional" json:"override_special,omitempty" mapstructure:"override_special"`
+
+	// Result is the generated password
+	Result string `json:"result,omitempty" state:"true"`
+}
+
+// NewRandomPassword creates a RandomPassword resource with the default values
+func NewRandomPassword(name string) *RandomPassword {
+	return &RandomPassword{ResourceInfo: ResourceInfo{Name: name, Type: TypeRandomPassword, Status: PendingCreation}, Length: 16, Special: true}
+}
+
+// Validate the RandomPassword and return errors
+func (r *RandomPassword) Validate() error {
+	if r.Length < 1 {
+		return fmt.Errorf("length must be greater than 0, got %d", r.Length)
+	}
+
+	return nil
+}
+
+// Generate sets a new random password
+func (r *RandomPassword) Generate() error {
+	chars := passwordLower + passwordUpper + passwordNumeric
+	if r.Special {
+		if r.OverrideSpecial != "" {
+			chars += r.OverrideSpecial
+		} else {
+			chars += passwordSpecial
+		}
+	}
+
+	p := make([]byte, r.Length)
+	for i := range p {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		if err != nil {
+			return err
+		}
+
+		p[i] = chars[n.Int64()]
+	}
+
+	r.Result = string(p)
+
+	return nil
+}
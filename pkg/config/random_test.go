@@ -0,0 +1,142 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRandomTests(t *testing.T) {
+	// use a clean home folder so that values are not read from an existing state
+	t.Setenv("HOME", t.TempDir())
+}
+
+func TestNewCreatesRandomResources(t *testing.T) {
+	id := NewRandomID("suffix")
+	assert.Equal(t, "suffix", id.Name)
+	assert.Equal(t, TypeRandomID, id.Type)
+	assert.Equal(t, 8, id.ByteLength)
+
+	p := NewRandomPassword("db")
+	assert.Equal(t, "db", p.Name)
+	assert.Equal(t, TypeRandomPassword, p.Type)
+	assert.Equal(t, 16, p.Length)
+	assert.True(t, p.Special)
+}
+
+func TestRandomPasswordGeneratesWithoutSpecial(t *testing.T) {
+	p := NewRandomPassword("db")
+	p.Length = 64
+	p.Special = false
+
+	err := p.Generate()
+	assert.NoError(t, err)
+
+	assert.Len(t, p.Result, 64)
+	assert.Regexp(t, "^[a-zA-Z0-9]+$", p.Result)
+}
+
+func TestRandomResourcesCreateCorrectly(t *testing.T) {
+	setupRandomTests(t)
+	c, _ := CreateConfigFromStrings(t, randomValid)
+
+	r, err := c.FindResource("random_id.suffix")
+	assert.NoError(t, err)
+
+	id := r.(*RandomID)
+	assert.Equal(t, PendingCreation, id.Status)
+	assert.Regexp(t, "^app-[0-9a-f]{8}$", id.Hex)
+	assert.NotEmpty(t, id.Dec)
+
+	r, err = c.FindResource("random_password.db")
+	assert.NoError(t, err)
+
+	p := r.(*RandomPassword)
+	assert.Len(t, p.Result, 24)
+	assert.Regexp(t, "^[a-zA-Z0-9!@]+$", p.Result)
+}
+
+func TestRandomValuesCanBeReferenced(t *testing.T) {
+	setupRandomTests(t)
+	c, _ := CreateConfigFromStrings(t, randomValid)
+
+	r, err := c.FindResource("random_password.db")
+	assert.NoError(t, err)
+	p := r.(*RandomPassword)
+
+	r, err = c.FindResource("random_id.suffix")
+	assert.NoError(t, err)
+	id := r.(*RandomID)
+
+	co, err := c.FindResource("container.db")
+	assert.NoError(t, err)
+
+	assert.Equal(t, p.Result, co.(*Container).EnvVar["POSTGRES_PASSWORD"])
+	assert.Equal(t, "db-"+id.Hex, co.(*Container).EnvVar["NAME"])
+}
+
+func TestRandomValuesAreReadFromState(t *testing.T) {
+	setupRandomTests(t)
+
+	sc := New()
+	id := NewRandomID("suffix")
+	id.Hex = "app-abcd"
+	sc.AddResource(id)
+
+	p := NewRandomPassword("db")
+	p.Result = "secret"
+	sc.AddResource(p)
+
+	os.MkdirAll(utils.StateDir(), os.ModePerm)
+	err := sc.ToJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	c, _ := CreateConfigFromStrings(t, randomValid)
+
+	co, err := c.FindResource("container.db")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "secret", co.(*Container).EnvVar["POSTGRES_PASSWORD"])
+	assert.Equal(t, "db-app-abcd", co.(*Container).EnvVar["NAME"])
+}
+
+func TestRandomPasswordWithInvalidLengthReturnsError(t *testing.T) {
+	setupRandomTests(t)
+	dir := CreateTestFiles(t, randomInvalidLength)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "length must be")
+}
+
+var randomValid = `
+random_id "suffix" {
+  byte_length = 4
+  prefix = "app-"
+}
+
+random_password "db" {
+  length = 24
+  override_special = "!@"
+}
+
+container "db" {
+  image {
+    name = "postgres:13"
+  }
+
+  env_var = {
+    POSTGRES_PASSWORD = random_password.db.result
+    NAME = "db-${random_id.suffix.hex}"
+  }
+}
+`
+
+var randomInvalidLength = `
+random_password "db" {
+  length = 0
+}
+`
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// TypeRegistry is the resource string for a Registry resource
+const TypeRegistry ResourceType = "registry"
+
+// RegistryPort is the port the registry listens on inside the container
+const RegistryPort = 5000
+
+// Registry runs a local Docker registry, the registry can optionally be configured as
+// a pull-through cache for an upstream registry.
+// Clusters which reference the registry in registries are configured to
+// trust the registry and to use it as a mirror for the upstream registry.
+type Registry struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	Networks []NetworkAttachment `hcl:"network,block" json:"networks,omitempty"` // networks to attach the registry container to
+
+	Image *Image `hcl:"image,block" json:"image,omitempty"`  // override the default registry image
+	Port  int    `hcl:"port,optional" json:"port,omitempty"` // host port to expose the registry on, the registry is only reachable from the networks when not set
+
+	Proxy *RegistryProxy `hcl:"proxy,block" json:"proxy,omitempty"` // configure the registry as a pull-through cache
+	Auth  *RegistryAuth  `hcl:"auth,block" json:"auth,omitempty"`   // require authentication to push and pull from the registry
+}
+
+// RegistryProxy configures the registry as a pull-through cache for an upstream registry
+type RegistryProxy struct {
+	RemoteURL string `hcl:"remote_url" json:"remote_url" mapstructure:"remote_url"` // URL of the upstream registry e.g. https://registry-1.docker.io
+	Username  string `hcl:"username,optional" json:"username,omitempty"`            // username for the upstream registry
+	Password  string `hcl:"password,optional" json:"password,omitempty"`            // password or token for the upstream registry
+}
+
+// RegistryAuth defines the credentials which are required to use the registry
+type RegistryAuth struct {
+	Username string `hcl:"username" json:"username"`
+	Password string `hcl:"password" json:"password"`
+}
+
+// NewRegistry creates a Registry resource with the default values
+func NewRegistry(name string) *Registry {
+	return &Registry{ResourceInfo: ResourceInfo{Name: name, Type: TypeRegistry, Status: PendingCreation}}
+}
+
+// Validate the config
+func (r *Registry) Validate() error {
+	if r.Port < 0 || r.Port > 65535 {
+		return fmt.Errorf("invalid port %d", r.Port)
+	}
+
+	if r.Proxy != nil {
+		u, err := url.Parse(r.Proxy.RemoteURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid proxy remote_url '%s', remote_url must be a http or https URL", r.Proxy.RemoteURL)
+		}
+
+		if r.Proxy.Password != "" && r.Proxy.Username == "" {
+			return fmt.Errorf("proxy username must be specified when password is set")
+		}
+	}
+
+	if r.Auth != nil && (r.Auth.Username == "" || r.Auth.Password == "") {
+		return fmt.Errorf("auth username and password must be specified")
+	}
+
+	return nil
+}
+
+// Address returns the address of the registry on the Docker networks
+// e.g. local.registry.shipyard.run:5000
+func (r *Registry) Address() string {
+	return fmt.Sprintf("%s:%d", utils.FQDN(r.Name, string(r.Type)), RegistryPort)
+}
+
+// Mirrors returns the name of the upstream registry when the registry is a
+// pull-through cache, an empty string is returned when there is no proxy.
+// The Docker Hub registry is returned as docker.io.
+func (r *Registry) Mirrors() string {
+	if r.Proxy == nil {
+		return ""
+	}
+
+	u, err := url.Parse(r.Proxy.RemoteURL)
+	if err != nil {
+		return ""
+	}
+
+	switch u.Host {
+	case "registry-1.docker.io", "index.docker.io", "registry.hub.docker.com":
+		return "docker.io"
+	}
+
+	return u.Host
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreatesRegistry(t *testing.T) {
+	c := NewRegistry("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeRegistry, c.Type)
+	assert.Equal(t, "abc.registry.shipyard.run:5000", c.Address())
+}
+
+func TestRegistryCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, registryDefault)
+
+	r, err := c.FindResource("registry.hub")
+	assert.NoError(t, err)
+
+	reg := r.(*Registry)
+	assert.Equal(t, 5001, reg.Port)
+	assert.Equal(t, "https://registry-1.docker.io", reg.Proxy.RemoteURL)
+	assert.Equal(t, "docker.io", reg.Mirrors())
+	assert.Equal(t, "admin", reg.Auth.Username)
+	assert.Contains(t, reg.DependsOn, "network.local")
+}
+
+func TestClusterWithRegistriesDependsOnRegistry(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, registryDefault)
+
+	r, err := c.FindResource("k8s_cluster.k3s")
+	assert.NoError(t, err)
+	assert.Contains(t, r.Info().DependsOn, "registry.hub")
+
+	r, err = c.FindResource("nomad_cluster.dev")
+	assert.NoError(t, err)
+	assert.Contains(t, r.Info().DependsOn, "registry.hub")
+}
+
+func TestRegistryWithInvalidRemoteURLReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, registryInvalidProxy)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid proxy remote_url")
+}
+
+func TestRegistryMirrorsReturnsUpstreamHost(t *testing.T) {
+	r := NewRegistry("abc")
+	assert.Empty(t, r.Mirrors())
+
+	r.Proxy = &RegistryProxy{RemoteURL: "https://ghcr.io"}
+	assert.Equal(t, "ghcr.io", r.Mirrors())
+}
+
+const registryDefault = `
+network "local" {
+	subnet = "10.0.0.0/16"
+}
+
+registry "hub" {
+	network {
+		name = "network.local"
+	}
+
+	port = 5001
+
+	proxy {
+		remote_url = "https://registry-1.docker.io"
+	}
+
+	auth {
+		username = "admin"
+		password = "password"
+	}
+}
+
+k8s_cluster "k3s" {
+	driver = "k3s"
+
+	network {
+		name = "network.local"
+	}
+
+	registries = ["registry.hub"]
+}
+
+nomad_cluster "dev" {
+	network {
+		name = "network.local"
+	}
+
+	registries = ["registry.hub"]
+}
+`
+
+const registryInvalidProxy = `
+registry "hub" {
+	proxy {
+		remote_url = "registry-1.docker.io"
+	}
+}
+`
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// BlockRemoteEnvironment is the block which references the state of another
+// running environment, remote environments are not resources, the outputs of the
+// environment are read when the blueprint is parsed and can be referenced by any
+// resource as remote_environment.[name].output.[output]
+//
+//	remote_environment "shared" {
+//	  state = "/home/shared/.shipyard"
+//	}
+//
+//	container "api" {
+//	  env_var = {
+//	    VAULT_ADDR = remote_environment.shared.output.vault_addr
+//	  }
+//	}
+const BlockRemoteEnvironment = "remote_environment"
+
+// RemoteEnvironment defines the location of the state for another environment
+type RemoteEnvironment struct {
+	// Shipyard home folder for the environment e.g. /home/shared/.shipyard, or the path
+	// to its state file
+	State string `hcl:"state"`
+}
+
+// statePath returns the location of the state file for the environment
+func (r *RemoteEnvironment) statePath() string {
+	if fi, err := os.Stat(r.State); err == nil && fi.IsDir() {
+		return filepath.Join(r.State, "state", "state.json")
+	}
+
+	return r.State
+}
+
+// parseRemoteEnvironment reads the outputs from the state of the referenced
+// environment and adds them to the context
+func parseRemoteEnvironment(file string, b *hclsyntax.Block) error {
+	if len(b.Labels) == 0 {
+		return fmt.Errorf("Error in file '%s': remote_environment has no name, please specify remote environments using the syntax 'remote_environment \"name\" {}'", file)
+	}
+
+	name := b.Labels[0]
+
+	re := &RemoteEnvironment{}
+	err := decodeBody(file, b, re)
+	if err != nil {
+		return err
+	}
+
+	re.State = ensureAbsolute(re.State, file)
+
+	sc := New()
+	err = sc.FromJSON(re.statePath())
+	if err != nil {
+		return fmt.Errorf("Error in file '%s': unable to read the state for remote_environment '%s' from '%s', make sure the environment is running: %s", file, name, re.statePath(), err)
+	}
+
+	outputs := map[string]cty.Value{}
+	for _, r := range sc.FindResourcesByType(string(TypeOutput)) {
+		if r.Info().Disabled {
+			continue
+		}
+
+		outputs[r.Info().Name] = cty.StringVal(r.(*Output).Value)
+	}
+
+	setContextRemoteEnvironment(name, cty.ObjectVal(map[string]cty.Value{
+		"output": cty.ObjectVal(outputs),
+	}))
+
+	return nil
+}
+
+func setContextRemoteEnvironment(key string, value cty.Value) {
+	valMap := map[string]cty.Value{}
+
+	// get the existing map
+	if m, ok := ctx.Variables[BlockRemoteEnvironment]; ok {
+		valMap = m.AsValueMap()
+	}
+
+	valMap[key] = value
+
+	ctx.Variables[BlockRemoteEnvironment] = cty.ObjectVal(valMap)
+}
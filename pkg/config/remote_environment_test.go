@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRemoteEnvironment writes the state for a shared environment to a
+// Shipyard home folder and returns the folder
+func setupRemoteEnvironment(t *testing.T) string {
+	// the state is always written to the Shipyard home folder for the current user
+	t.Setenv(utils.HomeEnvName(), t.TempDir())
+
+	addr := NewOutput("vault_addr")
+	addr.Value = "http://vault.container.shipyard.run:8200"
+
+	token := NewOutput("vault_token")
+	token.Value = "root"
+
+	sc := New()
+	sc.AddResource(addr)
+	sc.AddResource(token)
+
+	err := sc.ToJSON(utils.StatePath())
+	require.NoError(t, err)
+
+	return utils.ShipyardHome()
+}
+
+func TestRemoteEnvironmentOutputsAreInterpolatedInResources(t *testing.T) {
+	home := setupRemoteEnvironment(t)
+
+	c, _ := CreateConfigFromStrings(t, fmt.Sprintf(remoteEnvironmentValid, home))
+
+	r, err := c.FindResource("container.api")
+	assert.NoError(t, err)
+
+	cc := r.(*Container)
+	assert.Equal(t, "http://vault.container.shipyard.run:8200", cc.EnvVar["VAULT_ADDR"])
+	assert.Equal(t, "root", cc.EnvVar["VAULT_TOKEN"])
+}
+
+func TestRemoteEnvironmentReadsStateFile(t *testing.T) {
+	home := setupRemoteEnvironment(t)
+
+	c, _ := CreateConfigFromStrings(t, fmt.Sprintf(remoteEnvironmentValid, filepath.Join(home, "state", "state.json")))
+
+	r, err := c.FindResource("container.api")
+	assert.NoError(t, err)
+
+	cc := r.(*Container)
+	assert.Equal(t, "root", cc.EnvVar["VAULT_TOKEN"])
+}
+
+func TestRemoteEnvironmentOutputsCanBeReferencedByLocals(t *testing.T) {
+	home := setupRemoteEnvironment(t)
+
+	c, _ := CreateConfigFromStrings(t, fmt.Sprintf(remoteEnvironmentLocals, home))
+
+	r, err := c.FindResource("container.api")
+	assert.NoError(t, err)
+
+	cc := r.(*Container)
+	assert.Equal(t, "http://vault.container.shipyard.run:8200/v1", cc.EnvVar["VAULT_API"])
+}
+
+func TestRemoteEnvironmentWithMissingStateReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, fmt.Sprintf(remoteEnvironmentValid, t.TempDir()))
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "remote_environment 'shared'")
+}
+
+func TestRemoteEnvironmentWithNoNameReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, remoteEnvironmentNoName)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+}
+
+const remoteEnvironmentValid = `
+remote_environment "shared" {
+  state = "%s"
+}
+
+container "api" {
+  image {
+    name = "api:latest"
+  }
+
+  env_var = {
+    VAULT_ADDR  = remote_environment.shared.output.vault_addr
+    VAULT_TOKEN = remote_environment.shared.output.vault_token
+  }
+}
+`
+
+const remoteEnvironmentLocals = `
+remote_environment "shared" {
+  state = "%s"
+}
+
+locals {
+  vault_api = "${remote_environment.shared.output.vault_addr}/v1"
+}
+
+container "api" {
+  image {
+    name = "api:latest"
+  }
+
+  env_var = {
+    VAULT_API = local.vault_api
+  }
+}
+`
+
+const remoteEnvironmentNoName = `
+remote_environment {
+  state = "/tmp/shared"
+}
+`
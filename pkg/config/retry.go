@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults for the retry policy used when neither the resource or
+// the user config set a value
+const (
+	DefaultRetryAttempts = 3
+	DefaultRetryDelay    = 2 * time.Second
+	DefaultRetryBackoff  = 2.0
+)
+
+// Retry is the policy for retrying an operation which fails such as an
+// image pull or a Helm install
+type Retry struct {
+	Attempts int     `hcl:"attempts,optional" json:"attempts,omitempty"` // total number of attempts including the first, defaults to 3
+	Delay    string  `hcl:"delay,optional" json:"delay,omitempty"`       // time to wait before the first retry e.g. 5s, defaults to 2s
+	Backoff  float64 `hcl:"backoff,optional" json:"backoff,omitempty"`   // multiplier applied to the delay for each following retry, defaults to 2
+}
+
+// Validate the retry policy
+func (r *Retry) Validate() error {
+	if r.Attempts < 0 {
+		return fmt.Errorf("retry attempts must not be negative")
+	}
+
+	if r.Delay != "" {
+		d, err := time.ParseDuration(r.Delay)
+		if err != nil {
+			return fmt.Errorf("invalid retry delay '%s', %s", r.Delay, err)
+		}
+
+		if d < 0 {
+			return fmt.Errorf("retry delay must not be negative")
+		}
+	}
+
+	if r.Backoff != 0 && r.Backoff < 1 {
+		return fmt.Errorf("retry backoff must be 1 or greater")
+	}
+
+	return nil
+}
+
+// Merge returns a copy of the policy where the values which are not set are
+// taken from the given defaults, either policy can be nil
+func (r *Retry) Merge(defaults *Retry) *Retry {
+	m := &Retry{}
+	if defaults != nil {
+		*m = *defaults
+	}
+
+	if r == nil {
+		return m
+	}
+
+	if r.Attempts > 0 {
+		m.Attempts = r.Attempts
+	}
+
+	if r.Delay != "" {
+		m.Delay = r.Delay
+	}
+
+	if r.Backoff > 0 {
+		m.Backoff = r.Backoff
+	}
+
+	return m
+}
+
+// MaxAttempts returns the total number of attempts
+func (r *Retry) MaxAttempts() int {
+	if r == nil || r.Attempts == 0 {
+		return DefaultRetryAttempts
+	}
+
+	return r.Attempts
+}
+
+// DelayDuration returns the time to wait before the first retry
+func (r *Retry) DelayDuration() time.Duration {
+	if r == nil || r.Delay == "" {
+		return DefaultRetryDelay
+	}
+
+	d, _ := time.ParseDuration(r.Delay)
+	return d
+}
+
+// BackoffMultiplier returns the multiplier applied to the delay for each retry
+func (r *Retry) BackoffMultiplier() float64 {
+	if r == nil || r.Backoff == 0 {
+		return DefaultRetryBackoff
+	}
+
+	return r.Backoff
+}
+
+// Wait returns the time to wait after the given failed attempt, attempts start at 1
+func (r *Retry) Wait(attempt int) time.Duration {
+	d := float64(r.DelayDuration())
+	for i := 1; i < attempt; i++ {
+		d = d * r.BackoffMultiplier()
+	}
+
+	return time.Duration(d)
+}
+
+// ResourceDefaults are the timeout and retry policy used by resources
+// which do not set their own
+type ResourceDefaults struct {
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"` // maximum time for image pulls, cluster boots, and helm installs e.g. 10m
+	Retry   *Retry `hcl:"retry,block" json:"retry,omitempty"`        // policy for retrying image pulls and helm installs
+}
+
+// Validate the defaults
+func (d *ResourceDefaults) Validate() error {
+	return validateTimeoutAndRetry(d.Timeout, d.Retry)
+}
+
+// TimeoutDuration returns the timeout or 0 when it is not set
+func (d *ResourceDefaults) TimeoutDuration() time.Duration {
+	if d == nil {
+		return 0
+	}
+
+	return ParseTimeout(d.Timeout)
+}
+
+// RetryPolicy returns the retry policy or nil when it is not set
+func (d *ResourceDefaults) RetryPolicy() *Retry {
+	if d == nil {
+		return nil
+	}
+
+	return d.Retry
+}
+
+// ParseTimeout returns the duration for a timeout which has been validated,
+// 0 is returned when the timeout is not set
+func ParseTimeout(timeout string) time.Duration {
+	if timeout == "" {
+		return 0
+	}
+
+	d, _ := time.ParseDuration(timeout)
+	return d
+}
+
+// validateTimeoutAndRetry validates the timeout and retry settings of a resource
+func validateTimeoutAndRetry(timeout string, r *Retry) error {
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout '%s', %s", timeout, err)
+		}
+
+		if d <= 0 {
+			return fmt.Errorf("timeout must be greater than 0")
+		}
+	}
+
+	if r != nil {
+		return r.Validate()
+	}
+
+	return nil
+}
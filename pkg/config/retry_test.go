@@ -0,0 +1,111 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryValidateReturnsErrorForInvalidPolicy(t *testing.T) {
+	assert.Error(t, (&Retry{Attempts: -1}).Validate())
+	assert.Error(t, (&Retry{Delay: "soon"}).Validate())
+	assert.Error(t, (&Retry{Backoff: 0.5}).Validate())
+
+	assert.NoError(t, (&Retry{Attempts: 5, Delay: "10s", Backoff: 1.5}).Validate())
+}
+
+func TestRetryReturnsDefaultsWhenNotSet(t *testing.T) {
+	var r *Retry
+
+	assert.Equal(t, DefaultRetryAttempts, r.MaxAttempts())
+	assert.Equal(t, DefaultRetryDelay, r.DelayDuration())
+	assert.Equal(t, DefaultRetryBackoff, r.BackoffMultiplier())
+}
+
+func TestRetryWaitAppliesBackoff(t *testing.T) {
+	r := &Retry{Delay: "1s", Backoff: 3}
+
+	assert.Equal(t, 1*time.Second, r.Wait(1))
+	assert.Equal(t, 3*time.Second, r.Wait(2))
+	assert.Equal(t, 9*time.Second, r.Wait(3))
+}
+
+func TestRetryMergeUsesDefaultsForValuesNotSet(t *testing.T) {
+	r := &Retry{Attempts: 10}
+
+	m := r.Merge(&Retry{Attempts: 5, Delay: "30s"})
+	assert.Equal(t, &Retry{Attempts: 10, Delay: "30s"}, m)
+
+	// the policy is not modified
+	assert.Equal(t, "", r.Delay)
+
+	var empty *Retry
+	assert.Equal(t, &Retry{Delay: "30s"}, empty.Merge(&Retry{Delay: "30s"}))
+}
+
+func TestContainerParsesTimeoutAndRetry(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerRetry)
+
+	co, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	cc := co.(*Container)
+	assert.Equal(t, "10m", cc.Timeout)
+	assert.Equal(t, 5, cc.Retry.Attempts)
+	assert.Equal(t, 10*time.Second, cc.Retry.DelayDuration())
+	assert.Equal(t, 1.5, cc.Retry.BackoffMultiplier())
+}
+
+func TestK8sClusterWithInvalidTimeoutReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, clusterInvalidTimeout)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid timeout 'soon'")
+}
+
+func TestHelmWithInvalidTimeoutReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, helmInvalidTimeout)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid timeout '5'")
+}
+
+const containerRetry = `
+container "consul" {
+	image {
+		name = "consul:1.10.0"
+	}
+
+	timeout = "10m"
+
+	retry {
+		attempts = 5
+		delay    = "10s"
+		backoff  = 1.5
+	}
+}
+`
+
+const clusterInvalidTimeout = `
+k8s_cluster "k3s" {
+	driver  = "k3s"
+	timeout = "soon"
+}
+`
+
+const helmInvalidTimeout = `
+k8s_cluster "k3s" {
+	driver = "k3s"
+}
+
+helm "consul" {
+	cluster = "k8s_cluster.k3s"
+	chart   = "github.com/hashicorp/consul-helm?ref=v0.27.0"
+	timeout = "5"
+}
+`
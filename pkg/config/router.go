@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeRouter is the resource string for a Router resource
+const TypeRouter ResourceType = "router"
+
+// Router is a reverse proxy served by the connector on a single local port,
+// requests are forwarded to a destination based on the host and path
+type Router struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	Port   int     `hcl:"port" json:"port"`                    // local port the router listens on
+	Routes []Route `hcl:"route,block" json:"routes,omitempty"` // rules used to select the destination for a request
+
+	// RouterId stores the ID of the router created in the connector
+	RouterId string `json:"router_id,omitempty" mapstructure:"router_id" state:"true"`
+}
+
+// Route defines a rule which forwards matching requests to a destination,
+// when multiple routes match a request the route with the longest path is used
+type Route struct {
+	Host        string `hcl:"host,optional" json:"host,omitempty"`                                             // host header to match e.g. api.local.jmpd.in, matches all hosts when blank
+	Path        string `hcl:"path,optional" json:"path,omitempty"`                                             // path prefix to match e.g. /api, defaults to /
+	Destination string `hcl:"destination" json:"destination"`                                                  // address of the service to forward requests to e.g. localhost:9090
+	StripPrefix bool   `hcl:"strip_prefix,optional" json:"strip_prefix,omitempty" mapstructure:"strip_prefix"` // remove the path prefix before forwarding the request
+}
+
+// NewRouter creates a Router resource with the default values
+func NewRouter(name string) *Router {
+	return &Router{ResourceInfo: ResourceInfo{Name: name, Type: TypeRouter, Status: PendingCreation}}
+}
+
+// Validate the config
+func (r *Router) Validate() error {
+	if r.Port < 1 || r.Port > 65535 {
+		return fmt.Errorf("invalid port %d, port must be between 1 and 65535", r.Port)
+	}
+
+	if len(r.Routes) == 0 {
+		return fmt.Errorf("at least one route must be specified")
+	}
+
+	routes := map[string]bool{}
+	for i := range r.Routes {
+		rt := &r.Routes[i]
+
+		if rt.Path == "" {
+			rt.Path = "/"
+		}
+
+		if !strings.HasPrefix(rt.Path, "/") {
+			return fmt.Errorf("invalid path '%s' for route, path must start with /", rt.Path)
+		}
+
+		if rt.Destination == "" {
+			return fmt.Errorf("destination must be specified for route '%s%s'", rt.Host, rt.Path)
+		}
+
+		key := rt.Host + rt.Path
+		if routes[key] {
+			return fmt.Errorf("duplicate route '%s%s', routes must have a unique host and path", rt.Host, rt.Path)
+		}
+
+		routes[key] = true
+	}
+
+	return nil
+}
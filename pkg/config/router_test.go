@@ -0,0 +1,113 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreatesRouter(t *testing.T) {
+	c := NewRouter("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeRouter, c.Type)
+	assert.Equal(t, PendingCreation, c.Status)
+}
+
+func TestRouterCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, routerDefault)
+
+	cl, err := c.FindResource("router.frontend")
+	assert.NoError(t, err)
+
+	r := cl.(*Router)
+	assert.Equal(t, 8080, r.Port)
+	assert.Len(t, r.Routes, 3)
+
+	assert.Equal(t, "/", r.Routes[0].Path)
+	assert.Equal(t, "localhost:3000", r.Routes[0].Destination)
+
+	assert.Equal(t, "/api", r.Routes[1].Path)
+	assert.True(t, r.Routes[1].StripPrefix)
+
+	assert.Equal(t, "auth.local.jmpd.in", r.Routes[2].Host)
+	assert.Contains(t, r.DependsOn, "container.api")
+}
+
+func TestRouterWithoutRoutesReturnsError(t *testing.T) {
+	r := NewRouter("abc")
+	r.Port = 8080
+
+	assert.Error(t, r.Validate())
+}
+
+func TestRouterWithInvalidPortReturnsError(t *testing.T) {
+	r := NewRouter("abc")
+	r.Routes = []Route{{Destination: "localhost:9090"}}
+
+	assert.Error(t, r.Validate())
+}
+
+func TestRouterWithInvalidPathReturnsError(t *testing.T) {
+	r := NewRouter("abc")
+	r.Port = 8080
+	r.Routes = []Route{{Path: "api", Destination: "localhost:9090"}}
+
+	err := r.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must start with /")
+}
+
+func TestRouterWithDuplicateRoutesReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, routerDuplicate)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate route")
+}
+
+const routerDefault = `
+container "api" {
+	image {
+		name = "nicholasjackson/fake-service:v0.20.0"
+	}
+}
+
+router "frontend" {
+	depends_on = ["container.api"]
+
+	port = 8080
+
+	route {
+		destination = "localhost:3000"
+	}
+
+	route {
+		path = "/api"
+		destination = "localhost:9090"
+		strip_prefix = true
+	}
+
+	route {
+		host = "auth.local.jmpd.in"
+		destination = "localhost:9091"
+	}
+}
+`
+
+const routerDuplicate = `
+router "frontend" {
+	port = 8080
+
+	route {
+		path = "/api"
+		destination = "localhost:9090"
+	}
+
+	route {
+		path = "/api"
+		destination = "localhost:9091"
+	}
+}
+`
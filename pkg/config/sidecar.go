@@ -1,5 +1,7 @@
 package config
 
+import "fmt"
+
 // TypeSidecar is the resource string for a Sidecar resource
 const TypeSidecar ResourceType = "sidecar"
 
@@ -10,7 +12,14 @@ type Sidecar struct {
 
 	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
 
-	Target string `hcl:"target" json:"target"`
+	// Target is the container the sidecar shares the network namespace with, it is
+	// set automatically for sidecars defined in a container
+	Target string `hcl:"target,optional" json:"target"`
+
+	// SharePID and ShareIPC join the PID and IPC namespaces of the target so that the
+	// sidecar can see and signal the processes of the target e.g. for profilers
+	SharePID bool `hcl:"share_pid,optional" json:"share_pid,omitempty" mapstructure:"share_pid"`
+	ShareIPC bool `hcl:"share_ipc,optional" json:"share_ipc,omitempty" mapstructure:"share_ipc"`
 
 	Image       Image             `hcl:"image,block" json:"image"`                                         // image to use for the container
 	Entrypoint  []string          `hcl:"entrypoint,optional" json:"entrypoint,omitempty"`                  // entrypoint to use when starting the container
@@ -19,18 +28,84 @@ type Sidecar struct {
 	EnvVar      map[string]string `hcl:"env_var,optional" json:"env_var,omitempty" mapstructure:"env_var"` // environment variables to set when starting the container
 	Volumes     []Volume          `hcl:"volume,block" json:"volumes,omitempty"`                            // volumes to attach to the container
 
+	// EnvPassthrough is a list of host environment variables which are copied to the
+	// container when it is created e.g. ["AWS_*", "GITHUB_TOKEN"]
+	EnvPassthrough []string `hcl:"env_passthrough,optional" json:"env_passthrough,omitempty" mapstructure:"env_passthrough"`
+
 	Privileged bool `hcl:"privileged,optional" json:"privileged,omitempty"` // run the container in privileged mode?
 
+	Ulimits []Ulimit          `hcl:"ulimit,block" json:"ulimits,omitempty"`     // ulimits to set for the container e.g. nofile, nproc
+	Sysctls map[string]string `hcl:"sysctls,optional" json:"sysctls,omitempty"` // namespaced kernel parameters to set for the container
+
 	// resource constraints
 	Resources *Resources `hcl:"resources,block" json:"resources,omitempty"` // resource constraints for the container
 
 	// health checks for the container
 	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty" mapstructure:"health_check"`
 
-	MaxRestartCount int `hcl:"max_restart_count,optional" json:"max_restart_count,omitempty" mapstructure:"max_restart_count"`
+	MaxRestartCount int    `hcl:"max_restart_count,optional" json:"max_restart_count,omitempty" mapstructure:"max_restart_count"`
+	Restart         string `hcl:"restart,optional" json:"restart,omitempty"` // restart policy for the container [no, always, on-failure, unless-stopped]
+
+	CapAdd      []string `hcl:"cap_add,optional" json:"cap_add,omitempty" mapstructure:"cap_add"`                // linux capabilities to add to the container e.g. NET_ADMIN
+	CapDrop     []string `hcl:"cap_drop,optional" json:"cap_drop,omitempty" mapstructure:"cap_drop"`             // linux capabilities to drop from the container
+	SecurityOpt []string `hcl:"security_opt,optional" json:"security_opt,omitempty" mapstructure:"security_opt"` // security options e.g. seccomp=./profile.json, apparmor=unconfined
+	Devices     []Device `hcl:"device,block" json:"devices,omitempty"`                                           // host devices to add to the container
+
+	// alternate variants of the sidecar selected using the capabilities of the host
+	Fallbacks []Fallback `hcl:"fallback,block" json:"fallbacks,omitempty"`
+
+	// SelectedFallback is the condition of the fallback which was applied to the sidecar
+	SelectedFallback string `json:"selected_fallback,omitempty" mapstructure:"selected_fallback"`
 }
 
 // NewSidecar returns a new Container resource with the correct default options
 func NewSidecar(name string) *Sidecar {
 	return &Sidecar{ResourceInfo: ResourceInfo{Name: name, Type: TypeSidecar, Status: PendingCreation}}
 }
+
+// Validate the config
+func (s *Sidecar) Validate() error {
+	if s.Target == "" {
+		return fmt.Errorf("target must be specified")
+	}
+
+	if s.Resources != nil {
+		err := s.Resources.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := validateVolumes(s.Volumes)
+	if err != nil {
+		return err
+	}
+
+	err = s.Image.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = validateFallbacks(s.Fallbacks)
+	if err != nil {
+		return err
+	}
+
+	return validateRestartPolicy(s.Restart)
+}
+
+// ApplyFallback replaces the values of the sidecar with the first fallback which
+// matches the capabilities of the host
+func (s *Sidecar) ApplyFallback() {
+	f := selectFallback(s.Fallbacks)
+	if f == nil {
+		return
+	}
+
+	if f.Image != "" {
+		s.Image.Name = f.Image
+	}
+
+	applyFallback(f, &s.Command, &s.EnvVar, &s.Resources)
+	s.SelectedFallback = f.When
+}
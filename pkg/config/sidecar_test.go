@@ -33,6 +33,29 @@ func TestSidecarSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, cl.Info().Status)
 }
 
+func TestSidecarWithCountCreatesMultipleResources(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, sidecarCount)
+
+	cl, err := c.FindResource("sidecar.envoy-0")
+	assert.NoError(t, err)
+	assert.Equal(t, "container.app-0", cl.(*Sidecar).Target)
+
+	cl, err = c.FindResource("sidecar.envoy-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "container.app-1", cl.(*Sidecar).Target)
+}
+
+const sidecarCount = `
+sidecar "envoy" {
+	count  = 2
+	target = "container.app-${count.index}"
+
+	image {
+		name = "envoyproxy/envoy"
+	}
+}
+`
+
 const sidecarDefault = `
 sidecar "test" {
 	target = "container.test"
@@ -51,3 +74,124 @@ sidecar "test" {
 	}
 }
 `
+
+func TestSidecarWithoutTargetReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, sidecarNoTarget)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "target must be specified")
+}
+
+func TestContainerSidecarsCreateSidecarResources(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerSidecars)
+
+	r, err := c.FindResource("sidecar.app-envoy")
+	assert.NoError(t, err)
+
+	s := r.(*Sidecar)
+	assert.Equal(t, "container.app", s.Target)
+	assert.Equal(t, "envoyproxy/envoy:v1.24.0", s.Image.Name)
+	assert.Contains(t, s.DependsOn, "container.app")
+
+	r, err = c.FindResource("sidecar.app-profiler")
+	assert.NoError(t, err)
+
+	s = r.(*Sidecar)
+	assert.True(t, s.SharePID)
+	assert.True(t, s.ShareIPC)
+	assert.Equal(t, PendingCreation, s.Status)
+}
+
+func TestSidecarSharingIPCMakesTargetShareable(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerSidecars)
+
+	r, err := c.FindResource("container.app")
+	assert.NoError(t, err)
+
+	assert.True(t, r.(*Container).ShareableIPC)
+}
+
+func TestContainerSidecarsAreDisabledWithContainer(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, containerSidecarsDisabled)
+
+	r, err := c.FindResource("sidecar.app-envoy")
+	assert.NoError(t, err)
+
+	assert.Equal(t, Disabled, r.Info().Status)
+}
+
+const sidecarNoTarget = `
+sidecar "test" {
+	image {
+		name = "consul"
+	}
+}
+`
+
+const containerSidecars = `
+container "app" {
+	image {
+		name = "python:3.11"
+	}
+
+	sidecar "envoy" {
+		image {
+			name = "envoyproxy/envoy:v1.24.0"
+		}
+	}
+
+	sidecar "profiler" {
+		share_pid = true
+		share_ipc = true
+
+		image {
+			name = "shipyardrun/py-spy:latest"
+		}
+	}
+}
+`
+
+const containerSidecarsDisabled = `
+container "app" {
+	disabled = true
+
+	image {
+		name = "python:3.11"
+	}
+
+	sidecar "envoy" {
+		image {
+			name = "envoyproxy/envoy:v1.24.0"
+		}
+	}
+}
+`
+
+func TestValidateChecksContainerSidecars(t *testing.T) {
+	dir := CreateTestFiles(t, containerSidecars)
+
+	errs := Validate(dir, nil, "")
+	assert.Empty(t, errs)
+
+	dir = CreateTestFiles(t, `
+container "app" {
+	image {
+		name = "python:3.11"
+	}
+
+	sidecar "envoy" {
+		share_network = true
+
+		image {
+			name = "envoyproxy/envoy:v1.24.0"
+		}
+	}
+}
+`)
+
+	errs = Validate(dir, nil, "")
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), `An argument named "share_network" is not expected here.`)
+}
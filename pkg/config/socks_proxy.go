@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeSocksProxy is the resource string for a SocksProxy resource
+const TypeSocksProxy ResourceType = "socks_proxy"
+
+// SocksProxy is a SOCKS5 proxy served by the connector on a single local port,
+// when a cluster is set TCP connections are tunneled into the cluster so that any
+// in-cluster address can be reached. UDP is relayed from the local machine
+type SocksProxy struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	Port    int    `hcl:"port" json:"port"`                          // local port the proxy listens on
+	Cluster string `hcl:"cluster,optional" json:"cluster,omitempty"` // cluster to tunnel connections into e.g. k8s_cluster.k3s
+
+	// ProxyId stores the ID of the proxy created in the connector
+	ProxyId string `json:"proxy_id,omitempty" mapstructure:"proxy_id" state:"true"`
+}
+
+// NewSocksProxy creates a SocksProxy resource with the default values
+func NewSocksProxy(name string) *SocksProxy {
+	return &SocksProxy{ResourceInfo: ResourceInfo{Name: name, Type: TypeSocksProxy, Status: PendingCreation}}
+}
+
+// Validate the config
+func (s *SocksProxy) Validate() error {
+	if s.Port < 1 || s.Port > 65535 {
+		return fmt.Errorf("invalid port %d, port must be between 1 and 65535", s.Port)
+	}
+
+	if s.Cluster != "" &&
+		!strings.HasPrefix(s.Cluster, string(TypeK8sCluster)+".") &&
+		!strings.HasPrefix(s.Cluster, string(TypeNomadCluster)+".") {
+		return fmt.Errorf("invalid cluster '%s', cluster must be a k8s_cluster or nomad_cluster", s.Cluster)
+	}
+
+	return nil
+}
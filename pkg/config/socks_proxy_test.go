@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreatesSocksProxy(t *testing.T) {
+	c := NewSocksProxy("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeSocksProxy, c.Type)
+	assert.Equal(t, PendingCreation, c.Status)
+}
+
+func TestSocksProxyCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, socksProxyDefault)
+
+	cl, err := c.FindResource("socks_proxy.cluster")
+	assert.NoError(t, err)
+
+	s := cl.(*SocksProxy)
+	assert.Equal(t, 1080, s.Port)
+	assert.Equal(t, "k8s_cluster.k3s", s.Cluster)
+	assert.Contains(t, s.DependsOn, "k8s_cluster.k3s")
+}
+
+func TestSocksProxyWithInvalidPortReturnsError(t *testing.T) {
+	s := NewSocksProxy("abc")
+
+	assert.Error(t, s.Validate())
+}
+
+func TestSocksProxyWithInvalidClusterReturnsError(t *testing.T) {
+	s := NewSocksProxy("abc")
+	s.Port = 1080
+	s.Cluster = "container.abc"
+
+	assert.Error(t, s.Validate())
+}
+
+var socksProxyDefault = `
+k8s_cluster "k3s" {
+  driver = "k3s"
+
+  network {
+    name = "network.cloud"
+  }
+}
+
+network "cloud" {
+  subnet = "10.0.0.0/16"
+}
+
+socks_proxy "cluster" {
+  cluster = "k8s_cluster.k3s"
+  port    = 1080
+}
+`
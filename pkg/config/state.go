@@ -1,10 +1,15 @@
 package config
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/shipyard-run/shipyard/pkg/utils"
@@ -24,46 +29,74 @@ func (c *Config) ToJSON(path string) error {
 		os.MkdirAll(sd, os.ModePerm)
 	}
 
-	// if the statefile exists overwrite it
-	_, err = os.Stat(sp)
-	if err == nil {
-		// delete the old state
-		os.Remove(sp)
+	// write the state to a temporary file and replace the existing state
+	// so that the state can be read while it is being saved
+	f, err := ioutil.TempFile(sd, "state-*.json")
+	if err != nil {
+		return err
 	}
+	defer os.Remove(f.Name())
 
-	// serialize the state to json and write to a file
-	f, err := os.Create(sp)
+	// serialize the state to json, encrypt when a key has been set, and
+	// write to a file
+	c.Version = StateVersion
+	d, err := json.Marshal(c)
 	if err != nil {
+		f.Close()
 		return err
 	}
-	defer f.Close()
 
-	ne := json.NewEncoder(f)
-	return ne.Encode(c)
+	d, err = encryptState(d)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to encrypt state: %s", err)
+	}
+
+	_, err = f.Write(d)
+	f.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), sp)
 }
 
-// FromJSON attempts to rehydrate the config from a JSON formatted statefile
+// FromJSON attempts to rehydrate the config from a JSON formatted statefile,
+// encrypted state is decrypted with the key set in the environment
 func (c *Config) FromJSON(path string) error {
 	// it is fine that the state might not exist
-	f, err := os.Open(path)
+	d, err := ioutil.ReadFile(path)
 	if err != nil {
 		return StateNotFoundError
 	}
-	defer f.Close()
 
-	jd := json.NewDecoder(f)
-	return jd.Decode(c)
+	d, err = decryptState(d)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(d, c)
 }
 
 // UnmarshalJSON is a cusom Unmarshaler to deal with
-// converting the objects back into their main type
+// converting the objects back into their main type.
+// States written by older versions of Shipyard are migrated to the
+// current StateVersion before they are decoded.
 func (c *Config) UnmarshalJSON(b []byte) error {
+	b, _, err := MigrateState(b)
+	if err != nil {
+		return err
+	}
+
 	var objMap map[string]*json.RawMessage
-	err := json.Unmarshal(b, &objMap)
+	err = json.Unmarshal(b, &objMap)
 	if err != nil {
 		return err
 	}
 
+	c.Version = StateVersion
+
 	if objMap["blueprint"] != nil {
 		var rawBlueprint *json.RawMessage
 		json.Unmarshal(*objMap["blueprint"], &rawBlueprint)
@@ -89,12 +122,22 @@ func (c *Config) UnmarshalJSON(b []byte) error {
 
 		var out interface{}
 		switch rt := ResourceType(mm["type"].(string)); rt {
+		case TypeCertificateCA:
+			out = &CertificateCA{}
+		case TypeCertificateLeaf:
+			out = &CertificateLeaf{}
 		case TypeContainerIngress:
 			out = &ContainerIngress{}
+		case TypeCompose:
+			out = &Compose{}
 		case TypeContainer:
 			out = &Container{}
+		case TypeCopy:
+			out = &Copy{}
 		case TypeDocs:
 			out = &Docs{}
+		case TypeDockerImage:
+			out = &DockerImage{}
 		case TypeExecLocal:
 			out = &ExecLocal{}
 		case TypeExecRemote:
@@ -111,6 +154,10 @@ func (c *Config) UnmarshalJSON(b []byte) error {
 			out = &K8sConfig{}
 		case TypeK8sIngress:
 			out = &K8sIngress{}
+		case TypeK8sNamespace:
+			out = &K8sNamespace{}
+		case TypeLogSink:
+			out = &LogSink{}
 		case TypeModule:
 			out = &Module{}
 		case TypeNetwork:
@@ -123,13 +170,36 @@ func (c *Config) UnmarshalJSON(b []byte) error {
 			out = &NomadJob{}
 		case TypeOutput:
 			out = &Output{}
+		case TypeRandomID:
+			out = &RandomID{}
+		case TypeRandomPassword:
+			out = &RandomPassword{}
+		case TypeRegistry:
+			out = &Registry{}
+		case TypeRouter:
+			out = &Router{}
 		case TypeSidecar:
 			out = &Sidecar{}
+		case TypeSocksProxy:
+			out = &SocksProxy{}
 		case TypeTemplate:
 			out = &Template{}
+		case TypeTerraform:
+			out = &Terraform{}
+		case TypeTest:
+			out = &Test{}
+		case TypeTunnel:
+			out = &Tunnel{}
 		case TypeVariable:
 			out = &Variable{}
 		default:
+			// resources provided by plugins record the plugin so they can be
+			// destroyed even if the plugin is no longer registered
+			if _, ok := mm["plugin"]; ok {
+				out = &PluginResource{}
+				break
+			}
+
 			return fmt.Errorf("Unable to convert to type %s, please define types in UnmarshalJSON function", rt)
 		}
 
@@ -148,6 +218,7 @@ func (c *Config) decodeAndAdd(in map[string]interface{}, out interface{}) error
 		&mapstructure.DecoderConfig{
 			Result:      out,
 			ErrorUnused: true,
+			DecodeHook:  mapstructure.StringToTimeHookFunc(time.RFC3339Nano),
 		},
 	)
 	if err != nil {
@@ -189,6 +260,9 @@ func (c *Config) Merge(c2 *Config) {
 				// make sure the reference is the world view not the local view
 				c.Resources[i].Info().Config = c
 
+				// keep the timing of the last operation for resources which are not recreated
+				c.Resources[i].Info().Timing = cc.Info().Timing
+
 				// we need to preserve any data elements which are used to store state values
 				vOld := reflect.ValueOf(cc).Elem()
 				vNew := reflect.ValueOf(cc2).Elem()
@@ -243,3 +317,175 @@ func (c *Config) Merge(c2 *Config) {
 		c.Blueprint = c2.Blueprint
 	}
 }
+
+// Diff returns the resources in c2 which exist in the config c and have a
+// different configuration. The status and the values of the state fields of
+// the resources are not compared. The image cache is always recreated by Merge
+// and is never returned.
+func (c *Config) Diff(c2 *Config) ([]Resource, error) {
+	changed := []Resource{}
+
+	for _, r2 := range c2.Resources {
+		if r2.Info().Type == TypeImageCache {
+			continue
+		}
+
+		for _, r := range c.Resources {
+			if r.Info().Name != r2.Info().Name || r.Info().Type != r2.Info().Type {
+				continue
+			}
+
+			v, err := configValues(r)
+			if err != nil {
+				return nil, err
+			}
+
+			v2, err := configValues(r2)
+			if err != nil {
+				return nil, err
+			}
+
+			if !reflect.DeepEqual(v, v2) {
+				changed = append(changed, r2)
+			}
+
+			break
+		}
+	}
+
+	return changed, nil
+}
+
+// Conflicts returns the resources in c2 which already exist in the config c and
+// were created from a different blueprint. Resources in c which do not record the
+// blueprint they were created from never conflict.
+func (c *Config) Conflicts(c2 *Config) []Resource {
+	conflicts := []Resource{}
+
+	for _, r2 := range c2.Resources {
+		if r2.Info().Type == TypeImageCache {
+			continue
+		}
+
+		for _, r := range c.Resources {
+			if r.Info().Name != r2.Info().Name || r.Info().Type != r2.Info().Type {
+				continue
+			}
+
+			if r.Info().BlueprintSource != "" && r.Info().BlueprintSource != r2.Info().BlueprintSource {
+				conflicts = append(conflicts, r)
+			}
+
+			break
+		}
+	}
+
+	return conflicts
+}
+
+// updateInPlace are the attributes for each type of resource which can be
+// changed by recreating the resource, any data in named volumes is kept
+var updateInPlace = map[ResourceType][]string{
+	TypeContainer: {"environment", "env_var", "env_passthrough", "ports", "port_ranges"},
+}
+
+// ConfigHash returns a hash of the configuration of the resource, the status
+// and the values of the state fields are not part of the hash
+func ConfigHash(r Resource) (string, error) {
+	v, err := configValues(r)
+	if err != nil {
+		return "", err
+	}
+
+	// maps are serialized with sorted keys so the hash is stable
+	d, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(d)), nil
+}
+
+// ChangedAttributes returns the sorted names of the top level attributes
+// which are different between the resources r and r2
+func ChangedAttributes(r, r2 Resource) ([]string, error) {
+	v, err := configValues(r)
+	if err != nil {
+		return nil, err
+	}
+
+	v2, err := configValues(r2)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for k := range v {
+		if !reflect.DeepEqual(v[k], v2[k]) {
+			changed = append(changed, k)
+		}
+	}
+
+	for k := range v2 {
+		if _, ok := v[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed, nil
+}
+
+// CanUpdateInPlace returns true when all of the changed attributes of a
+// resource can be applied by recreating the resource
+func CanUpdateInPlace(t ResourceType, changed []string) bool {
+	if len(changed) == 0 {
+		return false
+	}
+
+	for _, c := range changed {
+		found := false
+		for _, a := range updateInPlace[t] {
+			if a == c {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// configValues returns the values of a resource as a map without the status,
+// the blueprint it was created from, the applied hash and timing, and the fields which store state
+func configValues(r Resource) (map[string]interface{}, error) {
+	d, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to serialize resource %s.%s: %s", r.Info().Type, r.Info().Name, err)
+	}
+
+	v := map[string]interface{}{}
+	err = json.Unmarshal(d, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(v, "status")
+	delete(v, "blueprint_source")
+	delete(v, "config_hash")
+	delete(v, "timing")
+
+	t := reflect.TypeOf(r).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("state") == "true" {
+			delete(v, strings.Split(t.Field(i).Tag.Get("json"), ",")[0])
+		}
+	}
+
+	return v, nil
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Environment variables which enable encryption of the state file, when the
+// age identity is set it is used in preference to the passphrase
+const (
+	StatePassphraseEnv  = "SHIPYARD_STATE_PASSPHRASE"
+	StateAgeIdentityEnv = "SHIPYARD_STATE_AGE_IDENTITY"
+)
+
+// Methods used to encrypt the state
+const (
+	stateEncryptionPassphrase = "passphrase"
+	stateEncryptionAge        = "age"
+)
+
+// stateEncryptionHeader is the first line of an encrypted state file
+// followed by the method used to encrypt the state
+const stateEncryptionHeader = "shipyard.state.encrypted/v1 "
+
+// scrypt parameters for deriving the key from the passphrase
+const (
+	scryptN       = 32768
+	scryptR       = 8
+	scryptP       = 1
+	scryptSaltLen = 16
+)
+
+// StateEncryptedError is returned when the state is encrypted and the
+// key used to encrypt it has not been set
+type StateEncryptedError struct {
+	Method string
+}
+
+func (e StateEncryptedError) Error() string {
+	env := StatePassphraseEnv
+	if e.Method == stateEncryptionAge {
+		env = StateAgeIdentityEnv
+	}
+
+	return fmt.Sprintf("State is encrypted with %s, set %s to decrypt it", e.Method, env)
+}
+
+// encryptState encrypts the state with the age identity or passphrase set in
+// the environment, the state is returned unchanged when neither are set
+func encryptState(data []byte) ([]byte, error) {
+	if id := os.Getenv(StateAgeIdentityEnv); id != "" {
+		out, err := runAge(data, "-e", "-i", id)
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]byte(stateEncryptionHeader+stateEncryptionAge+"\n"), out...), nil
+	}
+
+	if pass := os.Getenv(StatePassphraseEnv); pass != "" {
+		salt := make([]byte, scryptSaltLen)
+		_, err := rand.Read(salt)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := passphraseCipher(pass, salt)
+		if err != nil {
+			return nil, err
+		}
+
+		nonce := make([]byte, aead.NonceSize())
+		_, err = rand.Read(nonce)
+		if err != nil {
+			return nil, err
+		}
+
+		out := []byte(stateEncryptionHeader + stateEncryptionPassphrase + "\n")
+		out = append(out, salt...)
+		out = append(out, nonce...)
+
+		return aead.Seal(out, nonce, data, nil), nil
+	}
+
+	return data, nil
+}
+
+// decryptState decrypts state which has been encrypted by encryptState,
+// state which is not encrypted is returned unchanged
+func decryptState(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(stateEncryptionHeader)) {
+		return data, nil
+	}
+
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid encrypted state")
+	}
+
+	method := strings.TrimPrefix(string(data[:i]), stateEncryptionHeader)
+	data = data[i+1:]
+
+	switch method {
+	case stateEncryptionAge:
+		id := os.Getenv(StateAgeIdentityEnv)
+		if id == "" {
+			return nil, StateEncryptedError{method}
+		}
+
+		return runAge(data, "-d", "-i", id)
+
+	case stateEncryptionPassphrase:
+		pass := os.Getenv(StatePassphraseEnv)
+		if pass == "" {
+			return nil, StateEncryptedError{method}
+		}
+
+		if len(data) < scryptSaltLen+chacha20poly1305.NonceSizeX {
+			return nil, fmt.Errorf("invalid encrypted state")
+		}
+
+		aead, err := passphraseCipher(pass, data[:scryptSaltLen])
+		if err != nil {
+			return nil, err
+		}
+
+		nonce := data[scryptSaltLen : scryptSaltLen+aead.NonceSize()]
+		sealed := data[scryptSaltLen+aead.NonceSize():]
+
+		out, err := aead.Open(nil, nonce, sealed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt state, check %s is the passphrase used to encrypt the state", StatePassphraseEnv)
+		}
+
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("state is encrypted with an unknown method '%s'", method)
+}
+
+// passphraseCipher returns the XChaCha20-Poly1305 cipher for the key derived
+// from the passphrase and salt
+func passphraseCipher(pass string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(pass), salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	return chacha20poly1305.NewX(key)
+}
+
+// runAge runs the age CLI with the given arguments and data as stdin
+func runAge(data []byte, args ...string) ([]byte, error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+
+	cmd := exec.Command("age", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if _, ok := err.(*exec.Error); ok {
+			return nil, fmt.Errorf("unable to run age, install age from https://age-encryption.org to encrypt the state with %s: %s", StateAgeIdentityEnv, err)
+		}
+
+		return nil, fmt.Errorf("age failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateIsEncryptedWithPassphrase(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	t.Setenv(StatePassphraseEnv, "correct horse")
+	t.Setenv(StateAgeIdentityEnv, "")
+
+	err := c.ToJSON(utils.StatePath())
+	require.NoError(t, err)
+
+	d, err := ioutil.ReadFile(utils.StatePath())
+	require.NoError(t, err)
+	require.NotContains(t, string(d), "docker-cache")
+
+	c2 := New()
+	err = c2.FromJSON(utils.StatePath())
+	require.NoError(t, err)
+	require.Len(t, c2.Resources, c.ResourceCount())
+}
+
+func TestEncryptedStateReturnsErrorWithoutPassphrase(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	t.Setenv(StatePassphraseEnv, "correct horse")
+	t.Setenv(StateAgeIdentityEnv, "")
+	c.ToJSON(utils.StatePath())
+
+	os.Unsetenv(StatePassphraseEnv)
+
+	err := New().FromJSON(utils.StatePath())
+	require.Error(t, err)
+	require.IsType(t, StateEncryptedError{}, err)
+}
+
+func TestEncryptedStateReturnsErrorWithWrongPassphrase(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	t.Setenv(StatePassphraseEnv, "correct horse")
+	t.Setenv(StateAgeIdentityEnv, "")
+	c.ToJSON(utils.StatePath())
+
+	t.Setenv(StatePassphraseEnv, "battery staple")
+
+	err := New().FromJSON(utils.StatePath())
+	require.Error(t, err)
+}
+
+func TestPlainStateIsReadWhenPassphraseSet(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	t.Setenv(StatePassphraseEnv, "")
+	t.Setenv(StateAgeIdentityEnv, "")
+	c.ToJSON(utils.StatePath())
+
+	t.Setenv(StatePassphraseEnv, "correct horse")
+
+	c2 := New()
+	err := c2.FromJSON(utils.StatePath())
+	require.NoError(t, err)
+	require.Len(t, c2.Resources, c.ResourceCount())
+}
+
+func TestStateIsEncryptedWithAgeIdentity(t *testing.T) {
+	if _, err := exec.LookPath("age-keygen"); err != nil {
+		t.Skip("age is not installed")
+	}
+
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	id := filepath.Join(t.TempDir(), "key.txt")
+	err := exec.Command("age-keygen", "-o", id).Run()
+	require.NoError(t, err)
+
+	t.Setenv(StateAgeIdentityEnv, id)
+
+	err = c.ToJSON(utils.StatePath())
+	require.NoError(t, err)
+
+	c2 := New()
+	err = c2.FromJSON(utils.StatePath())
+	require.NoError(t, err)
+	require.Len(t, c2.Resources, c.ResourceCount())
+}
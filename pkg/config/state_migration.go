@@ -0,0 +1,185 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// StateVersion is the version of the state file schema written by this
+// version of Shipyard, increment the version and add a StateMigration
+// when the format of the state changes
+const StateVersion = 1
+
+// StateMigration upgrades a state file from the previous version of the schema
+// to Version, migrations operate on the decoded JSON so that they do not
+// depend on the current resource types
+type StateMigration struct {
+	Version     int
+	Description string
+	Migrate     func(state map[string]interface{}) error
+}
+
+// stateMigrations are applied in order to states with a lower version,
+// state files written before versioning was added are version 0
+var stateMigrations = []StateMigration{
+	{
+		Version:     1,
+		Description: "Convert cpu and memory limits written as numbers to strings",
+		Migrate:     migrateResourceLimits,
+	},
+}
+
+// StateVersionError is returned when the state was written by a newer
+// version of Shipyard which uses a schema this version does not understand
+type StateVersionError struct {
+	Version int
+}
+
+func (e StateVersionError) Error() string {
+	return fmt.Sprintf("State file version %d was written by a newer version of Shipyard, this version supports state version %d, please upgrade Shipyard", e.Version, StateVersion)
+}
+
+// MigrateState upgrades the JSON state to the current StateVersion,
+// returns the migrated state and the migrations which were applied
+func MigrateState(data []byte) ([]byte, []StateMigration, error) {
+	s := map[string]interface{}{}
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v := stateVersion(s)
+	if v > StateVersion {
+		return nil, nil, StateVersionError{v}
+	}
+
+	if v == StateVersion {
+		return data, nil, nil
+	}
+
+	applied := []StateMigration{}
+	for _, m := range stateMigrations {
+		if m.Version <= v {
+			continue
+		}
+
+		err := m.Migrate(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to migrate state to version %d, %s: %s", m.Version, m.Description, err)
+		}
+
+		applied = append(applied, m)
+	}
+
+	s["version"] = StateVersion
+
+	d, err := json.Marshal(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return d, applied, nil
+}
+
+// MigrateStateFile upgrades the state file at path to the current StateVersion,
+// a copy of the original file is saved with the suffix .v[version].bak before
+// the file is replaced. Returns the migrations which were applied.
+func MigrateStateFile(path string) ([]StateMigration, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, StateNotFoundError
+	}
+
+	d, err := decryptState(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	md, applied, err := MigrateState(d)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(applied) == 0 {
+		return applied, nil
+	}
+
+	s := map[string]interface{}{}
+	json.Unmarshal(d, &s)
+
+	err = ioutil.WriteFile(fmt.Sprintf("%s.v%d.bak", path, stateVersion(s)), raw, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to back up state file: %s", err)
+	}
+
+	md, err = encryptState(md)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encrypt state: %s", err)
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "state-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(md)
+	f.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, os.Rename(f.Name(), path)
+}
+
+// stateVersion returns the schema version of the decoded state
+func stateVersion(s map[string]interface{}) int {
+	if v, ok := s["version"].(float64); ok {
+		return int(v)
+	}
+
+	if v, ok := s["version"].(int); ok {
+		return v
+	}
+
+	return 0
+}
+
+// migrateResourceLimits converts the cpu and memory limits of the resources
+// block written as numbers before limits accepted units e.g. 512Mi
+func migrateResourceLimits(s map[string]interface{}) error {
+	rs, _ := s["resources"].([]interface{})
+	for _, r := range rs {
+		convertResourceLimits(r)
+	}
+
+	return nil
+}
+
+// convertResourceLimits walks the resource converting the limits in
+// any resources block, including those of fallbacks
+func convertResourceLimits(v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if l, ok := val.(map[string]interface{}); ok && k == "resources" {
+				for _, f := range []string{"cpu", "memory"} {
+					if n, ok := l[f].(float64); ok {
+						l[f] = strconv.FormatFloat(n, 'f', -1, 64)
+					}
+				}
+			}
+
+			convertResourceLimits(val)
+		}
+	case []interface{}:
+		for _, val := range t {
+			convertResourceLimits(val)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var unversionedState = `
+{
+  "blueprint": null,
+  "resources": [
+    {
+      "name": "consul",
+      "type": "container",
+      "status": "applied",
+      "resources": { "cpu": 2000, "memory": 1024 },
+      "fallbacks": [
+        { "resources": { "memory": 512 } }
+      ]
+    }
+  ]
+}
+`
+
+func TestMigrateStateUpgradesUnversionedState(t *testing.T) {
+	d, applied, err := MigrateState([]byte(unversionedState))
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+
+	s := map[string]interface{}{}
+	json.Unmarshal(d, &s)
+
+	require.Equal(t, float64(StateVersion), s["version"])
+
+	r := s["resources"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "2000", r["resources"].(map[string]interface{})["cpu"])
+	require.Equal(t, "1024", r["resources"].(map[string]interface{})["memory"])
+
+	f := r["fallbacks"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "512", f["resources"].(map[string]interface{})["memory"])
+}
+
+func TestMigrateStateDoesNothingWhenCurrent(t *testing.T) {
+	state := `{"version": 1, "resources": []}`
+
+	d, applied, err := MigrateState([]byte(state))
+	require.NoError(t, err)
+
+	require.Len(t, applied, 0)
+	require.Equal(t, state, string(d))
+}
+
+func TestMigrateStateReturnsErrorWhenNewer(t *testing.T) {
+	_, _, err := MigrateState([]byte(`{"version": 99, "resources": []}`))
+	require.Error(t, err)
+
+	require.IsType(t, StateVersionError{}, err)
+}
+
+func TestMigrateStateFileBacksUpAndReplacesState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	ioutil.WriteFile(path, []byte(unversionedState), 0644)
+
+	applied, err := MigrateStateFile(path)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+
+	b, err := ioutil.ReadFile(path + ".v0.bak")
+	require.NoError(t, err)
+	require.Equal(t, unversionedState, string(b))
+
+	c := New()
+	err = c.FromJSON(path)
+	require.NoError(t, err)
+
+	r, err := c.FindResource("container.consul")
+	require.NoError(t, err)
+	require.Equal(t, "2000", r.(*Container).Resources.CPU)
+}
+
+func TestMigrateStateFileReturnsNotFound(t *testing.T) {
+	_, err := MigrateStateFile(filepath.Join(t.TempDir(), "state.json"))
+	require.Equal(t, StateNotFoundError, err)
+}
+
+func TestFromJSONReturnsErrorWhenStateNewer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	ioutil.WriteFile(path, []byte(`{"version": 99, "resources": []}`), 0644)
+
+	c := New()
+	err := c.FromJSON(path)
+	require.Error(t, err)
+}
@@ -66,6 +66,7 @@ func TestConfigSerializesToJSON(t *testing.T) {
 	err = json.Unmarshal(d, c2)
 	assert.NoError(t, err)
 	assert.Len(t, c2.Resources, c.ResourceCount())
+	assert.Contains(t, string(d), `"version":1`)
 }
 
 func TestConfigDeSerializesFromJSON(t *testing.T) {
@@ -85,6 +86,17 @@ func TestConfigDeSerializesFromJSON(t *testing.T) {
 	r, err := c.FindResource("image_cache.docker-cache")
 	assert.NoError(t, err)
 	assert.NotNil(t, r)
+
+	// check state fields are decoded
+	r, err = c.FindResource("ingress.consul-lan")
+	assert.NoError(t, err)
+	assert.Equal(t, "ingress.consul-lan", r.(*Ingress).AuthId)
+
+	// check resources written as numbers are decoded
+	r, err = c.FindResource("container.consul")
+	assert.NoError(t, err)
+	assert.Equal(t, "2000", r.(*Container).Resources.CPU)
+	assert.Equal(t, "1024", r.(*Container).Resources.Memory)
 }
 
 func TestConfigMergesAddingItems(t *testing.T) {
@@ -184,6 +196,142 @@ func TestConfigMergesWithExistingItemAppendsDependencyOnCache(t *testing.T) {
 	assert.Len(t, cache.Info().DependsOn, 2)
 }
 
+func TestConfigDiffReturnsChangedResources(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	c.Resources[1].Info().Status = Applied
+
+	con := NewContainer("config")
+	con.Info().Module = "tester"
+	con.Image = &Image{Name: "consul:1.10.0"}
+
+	c2 := New()
+	c2.AddResource(con)
+	c2.AddResource(NewNetwork("config"))
+	c2.AddResource(NewNetwork("new"))
+
+	changed, err := c.Diff(c2)
+	assert.NoError(t, err)
+
+	assert.Len(t, changed, 1)
+	assert.Equal(t, "config", changed[0].Info().Name)
+	assert.Equal(t, TypeContainer, changed[0].Info().Type)
+}
+
+func TestConfigDiffIgnoresStatusAndStateFields(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	c.Resources[2].Info().Status = Applied
+
+	c2 := New()
+	c2.AddResource(NewIngress("config"))
+
+	cacheNew := NewImageCache("docker-cache")
+	c2.AddResource(cacheNew)
+
+	changed, err := c.Diff(c2)
+	assert.NoError(t, err)
+
+	assert.Len(t, changed, 0)
+}
+
+func TestConfigDiffIgnoresSource(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	c.Resources[2].Info().BlueprintSource = "/blueprints/base"
+
+	c2 := New()
+	c2.AddResource(NewIngress("config"))
+
+	changed, err := c.Diff(c2)
+	assert.NoError(t, err)
+
+	assert.Len(t, changed, 0)
+}
+
+func TestConfigConflictsReturnsResourcesFromOtherBlueprints(t *testing.T) {
+	c := New()
+
+	base := NewNetwork("platform")
+	base.BlueprintSource = "/blueprints/base"
+	c.AddResource(base)
+
+	legacy := NewNetwork("legacy")
+	c.AddResource(legacy)
+
+	same := NewContainer("api")
+	same.BlueprintSource = "/blueprints/team"
+	c.AddResource(same)
+
+	n := NewNetwork("platform")
+	n.BlueprintSource = "/blueprints/team"
+
+	l := NewNetwork("legacy")
+	l.BlueprintSource = "/blueprints/team"
+
+	a := NewContainer("api")
+	a.BlueprintSource = "/blueprints/team"
+
+	c2 := New()
+	c2.AddResource(n)
+	c2.AddResource(l)
+	c2.AddResource(a)
+	c2.AddResource(NewContainer("new"))
+
+	conflicts := c.Conflicts(c2)
+
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "platform", conflicts[0].Info().Name)
+	assert.Equal(t, "/blueprints/base", conflicts[0].Info().BlueprintSource)
+}
+
+func TestConfigHashIgnoresStatusAndHash(t *testing.T) {
+	c := NewContainer("api")
+	c.Image = &Image{Name: "nginx"}
+
+	h, err := ConfigHash(c)
+	assert.NoError(t, err)
+
+	c.Status = Applied
+	c.ConfigHash = h
+
+	h2, err := ConfigHash(c)
+	assert.NoError(t, err)
+	assert.Equal(t, h, h2)
+
+	c.EnvVar = map[string]string{"FOO": "bar"}
+
+	h3, err := ConfigHash(c)
+	assert.NoError(t, err)
+	assert.NotEqual(t, h, h3)
+}
+
+func TestChangedAttributesReturnsSortedChanges(t *testing.T) {
+	c := NewContainer("api")
+	c.Image = &Image{Name: "nginx"}
+	c.Ports = []Port{{Local: "80", Host: "8080"}}
+
+	c2 := NewContainer("api")
+	c2.Image = &Image{Name: "nginx"}
+	c2.Ports = []Port{{Local: "80", Host: "9090"}}
+	c2.EnvVar = map[string]string{"FOO": "bar"}
+
+	changed, err := ChangedAttributes(c, c2)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"env_var", "ports"}, changed)
+}
+
+func TestCanUpdateInPlace(t *testing.T) {
+	assert.True(t, CanUpdateInPlace(TypeContainer, []string{"env_var", "ports"}))
+	assert.False(t, CanUpdateInPlace(TypeContainer, []string{"env_var", "image"}))
+	assert.False(t, CanUpdateInPlace(TypeContainer, []string{}))
+	assert.False(t, CanUpdateInPlace(TypeK8sCluster, []string{"ports"}))
+}
+
 var complexState = `
 {
   "blueprint": null,
@@ -282,6 +430,7 @@ var complexState = `
         },
         "driver": "k8s"
       },
+      "auth_id": "ingress.consul-lan",
       "id": "db729439-5bef-48da-9d5a-089d54d3dc83",
       "module": "k8s",
       "name": "consul-lan",
@@ -3,7 +3,9 @@ package config
 // TypeTemplate is the resource string for a Template resource
 const TypeTemplate ResourceType = "template"
 
-// Template allows the process of user defined templates
+// Template allows the process of user defined templates, when Vars are set the Source is
+// processed as a Go template which can reference the Vars, the blueprint Variables, and
+// Outputs e.g. #{{ .Vars.port }}, #{{ .Variables.version }}, #{{ .Outputs.db_address }}
 type Template struct {
 	ResourceInfo `hcl:",remain" mapstructure:",squash"`
 
@@ -12,6 +14,11 @@ type Template struct {
 	Source      string      `hcl:"source" json:"source"`                // Source template to be processed as string
 	Destination string      `hcl:"destination" json:"destination"`      // Desintation filename to write
 	Vars        interface{} `hcl:"vars,optional" json:"vars,omitempty"` // Variables to be processed in the template
+
+	// Volume is the name of a Docker volume to write the rendered file to, when set
+	// Destination is the path of the file in the volume e.g. /config/app.hcl.
+	// Files written to a volume are not removed on destroy, they are removed with the volume
+	Volume string `hcl:"volume,optional" json:"volume,omitempty"`
 }
 
 // NewTemplate creates a Template resource with the default values
@@ -33,6 +33,24 @@ func TestTemplateSetsDisabled(t *testing.T) {
 	assert.Equal(t, Disabled, cl.Info().Status)
 }
 
+func TestTemplateWithVolumeDoesNotMakeDestinationAbsolute(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, templateVolume)
+
+	cl, err := c.FindResource("template.test")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "config", cl.(*Template).Volume)
+	assert.Equal(t, "app/config.hcl", cl.(*Template).Destination)
+}
+
+const templateVolume = `
+template "test" {
+	source = "./container.test"
+	destination = "app/config.hcl"
+	volume = "config"
+}
+`
+
 const templateDefault = `
 template "test" {
 	source = "./container.test"
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// TypeTerraform is the resource string for a Terraform resource
+const TypeTerraform ResourceType = "terraform"
+
+// TerraformDefaultVersion is the version of Terraform used when a version is not specified
+const TerraformDefaultVersion = "1.3.9"
+
+// Terraform applies a Terraform module in a container, the outputs of the module can
+// be referenced by templates as terraform.[name].output.[output] and are shown by the
+// output command. The module is destroyed when the resource is destroyed.
+type Terraform struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	Networks []NetworkAttachment `hcl:"network,block" json:"networks,omitempty"` // networks to attach the Terraform container to
+
+	Source  string `hcl:"source" json:"source"`                      // path to the folder containing the Terraform module
+	Version string `hcl:"version,optional" json:"version,omitempty"` // version of Terraform, defaults to TerraformDefaultVersion
+
+	// Variables are the values for the variables of the module, they are evaluated when
+	// the module is applied so they can reference the values of other resources
+	Variables interface{} `hcl:"variables,optional" json:"variables,omitempty"`
+
+	EnvVar map[string]string `hcl:"env_var,optional" json:"env_var,omitempty" mapstructure:"env_var"` // environment variables to set when running Terraform
+
+	// EnvPassthrough is a list of host environment variables which are copied to the
+	// environment of Terraform e.g. ["AWS_*", "GOOGLE_CREDENTIALS"]
+	EnvPassthrough []string `hcl:"env_passthrough,optional" json:"env_passthrough,omitempty" mapstructure:"env_passthrough"`
+
+	// Outputs are the outputs of the module once it has been applied, values which
+	// are not strings are JSON encoded
+	Outputs map[string]string `json:"outputs,omitempty" state:"true"`
+
+	// SensitiveOutputs are the names of the outputs which are redacted from the logs and output command
+	SensitiveOutputs []string `json:"sensitive_outputs,omitempty" mapstructure:"sensitive_outputs" state:"true"`
+}
+
+// NewTerraform creates a Terraform resource with the default values
+func NewTerraform(name string) *Terraform {
+	return &Terraform{ResourceInfo: ResourceInfo{Name: name, Type: TypeTerraform, Status: PendingCreation}}
+}
+
+// Validate the config
+func (t *Terraform) Validate() error {
+	if t.Source == "" {
+		return fmt.Errorf("source must be specified")
+	}
+
+	if !utils.IsLocalFolder(t.Source) {
+		return fmt.Errorf("source '%s' must be a folder containing a Terraform module", t.Source)
+	}
+
+	return nil
+}
+
+// Image returns the Terraform image for the version
+func (t *Terraform) Image() Image {
+	v := t.Version
+	if v == "" {
+		v = TerraformDefaultVersion
+	}
+
+	return Image{Name: fmt.Sprintf("hashicorp/terraform:%s", v)}
+}
+
+// StateFolder returns the folder containing the Terraform state and variables for the
+// resource, the folder is kept between applies so that the module can be destroyed
+func (t *Terraform) StateFolder() string {
+	name := t.Name
+	if t.Module != "" {
+		name = fmt.Sprintf("%s.%s", t.Module, t.Name)
+	}
+
+	return utils.GetDataFolder(filepath.Join("terraform", name))
+}
+
+// IsSensitive returns true when the output is sensitive
+func (t *Terraform) IsSensitive(output string) bool {
+	for _, s := range t.SensitiveOutputs {
+		if s == output {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestNewCreatesTerraform(t *testing.T) {
+	c := NewTerraform("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeTerraform, c.Type)
+}
+
+func TestTerraformCreatesCorrectly(t *testing.T) {
+	c, dir := CreateConfigFromStrings(t, terraformDefault)
+
+	r, err := c.FindResource("terraform.cloud")
+	assert.NoError(t, err)
+
+	tf := r.(*Terraform)
+	assert.Equal(t, dir, tf.Source)
+	assert.Equal(t, PendingCreation, tf.Status)
+	assert.Contains(t, tf.DependsOn, "network.local")
+	assert.Contains(t, tf.DependsOn, "exec_local.setup")
+	assert.Equal(t, Image{Name: "hashicorp/terraform:1.2.0"}, tf.Image())
+}
+
+func TestTerraformWithMissingSourceReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, terraformMissingSource)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must be a folder containing a Terraform module")
+}
+
+func TestTerraformImageUsesDefaultVersion(t *testing.T) {
+	tf := NewTerraform("cloud")
+
+	assert.Equal(t, Image{Name: "hashicorp/terraform:" + TerraformDefaultVersion}, tf.Image())
+}
+
+func TestResourceEvalContextContainsTerraformOutputs(t *testing.T) {
+	tf := NewTerraform("cloud")
+	tf.Outputs = map[string]string{"address": "10.0.0.1"}
+
+	c := New()
+	c.AddResource(tf)
+
+	ec := GetResourceEvalContext(c)
+
+	v := ec.Variables["terraform"].GetAttr("cloud").GetAttr("output").GetAttr("address")
+	assert.Equal(t, cty.StringVal("10.0.0.1"), v)
+}
+
+const terraformDefault = `
+network "local" {
+  subnet = "10.0.0.0/16"
+}
+
+exec_local "setup" {
+  cmd = "echo"
+}
+
+terraform "cloud" {
+  source  = "./"
+  version = "1.2.0"
+
+  network {
+    name = "network.local"
+  }
+
+  variables = {
+    region = "eu-west-1"
+    token  = exec_local.setup.output
+  }
+}
+`
+
+const terraformMissingSource = `
+terraform "cloud" {
+  source = "./missing"
+}
+`
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TypeTest is the resource string for a Test resource
+const TypeTest ResourceType = "test"
+
+// DefaultTestTimeout is the time assertions are retried for when a timeout is not set
+const DefaultTestTimeout = 60 * time.Second
+
+// Test defines assertions which are run against the blueprint by 'shipyard test',
+// tests are not run when the blueprint is applied with 'shipyard run'.
+// example config:
+//
+//	test "api" {
+//	  http {
+//	    address       = "http://localhost:8080/health"
+//	    success_codes = [200]
+//	    body_contains = "ok"
+//	  }
+//
+//	  script {
+//	    script        = "curl -s localhost:8080/version"
+//	    exit_code     = 0
+//	    output_contains = "v1"
+//	  }
+//
+//	  pod_ready {
+//	    cluster = "k8s_cluster.k3s"
+//	    pods    = ["app=api"]
+//	  }
+//	}
+type Test struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"` // time to retry each assertion for before it fails, defaults to 60s
+
+	HTTP     []TestHTTP     `hcl:"http,block" json:"http,omitempty"`                                    // HTTP endpoints which must return a successful response
+	Script   []TestScript   `hcl:"script,block" json:"script,omitempty"`                                // scripts which must complete with the expected exit code
+	PodReady []TestPodReady `hcl:"pod_ready,block" json:"pod_ready,omitempty" mapstructure:"pod_ready"` // Kubernetes pods which must be running and ready
+}
+
+// TestHTTP asserts that a HTTP endpoint returns one of the success codes
+type TestHTTP struct {
+	Address      string `hcl:"address" json:"address"`
+	Method       string `hcl:"method,optional" json:"method,omitempty"`                                            // HTTP method, defaults to GET
+	SuccessCodes []int  `hcl:"success_codes,optional" json:"success_codes,omitempty" mapstructure:"success_codes"` // defaults to 200
+	BodyContains string `hcl:"body_contains,optional" json:"body_contains,omitempty" mapstructure:"body_contains"`
+}
+
+// TestScript asserts that a command or script completes with the expected exit code,
+// scripts are run with sh in the blueprint folder
+type TestScript struct {
+	Command        []string `hcl:"command,optional" json:"command,omitempty"`
+	Script         string   `hcl:"script,optional" json:"script,omitempty"`
+	ExitCode       int      `hcl:"exit_code,optional" json:"exit_code" mapstructure:"exit_code"`
+	OutputContains string   `hcl:"output_contains,optional" json:"output_contains,omitempty" mapstructure:"output_contains"`
+}
+
+// TestPodReady asserts that the pods matching the selectors are running in the cluster
+type TestPodReady struct {
+	Cluster string   `hcl:"cluster" json:"cluster"`
+	Pods    []string `hcl:"pods" json:"pods"` // label selectors for the pods e.g. app=api
+}
+
+// NewTest creates a Test resource with the default values
+func NewTest(name string) *Test {
+	return &Test{ResourceInfo: ResourceInfo{Name: name, Type: TypeTest, Status: PendingCreation}}
+}
+
+// Validate the config
+func (t *Test) Validate() error {
+	if t.Timeout != "" {
+		if _, err := time.ParseDuration(t.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout '%s', timeout must be a duration e.g. 60s", t.Timeout)
+		}
+	}
+
+	if len(t.HTTP) == 0 && len(t.Script) == 0 && len(t.PodReady) == 0 {
+		return fmt.Errorf("at least one http, script, or pod_ready assertion must be defined")
+	}
+
+	for _, h := range t.HTTP {
+		u, err := url.Parse(h.Address)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid http address '%s', address must be a http or https URL", h.Address)
+		}
+	}
+
+	for _, s := range t.Script {
+		if (len(s.Command) == 0) == (s.Script == "") {
+			return fmt.Errorf("script assertions must set one of command or script")
+		}
+	}
+
+	for _, p := range t.PodReady {
+		if !strings.HasPrefix(p.Cluster, fmt.Sprintf("%s.", TypeK8sCluster)) {
+			return fmt.Errorf("invalid pod_ready cluster '%s', cluster must reference a k8s_cluster e.g. k8s_cluster.k3s", p.Cluster)
+		}
+
+		if len(p.Pods) == 0 {
+			return fmt.Errorf("pod_ready for cluster %s must specify at least one pod selector", p.Cluster)
+		}
+	}
+
+	return nil
+}
+
+// TimeoutDuration returns the time assertions are retried for
+func (t *Test) TimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(t.Timeout)
+	if err != nil || d <= 0 {
+		return DefaultTestTimeout
+	}
+
+	return d
+}
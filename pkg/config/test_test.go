@@ -0,0 +1,92 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreatesTest(t *testing.T) {
+	c := NewTest("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeTest, c.Type)
+	assert.Equal(t, DefaultTestTimeout, c.TimeoutDuration())
+}
+
+func TestTestCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, testDefault)
+
+	r, err := c.FindResource("test.api")
+	assert.NoError(t, err)
+
+	tst := r.(*Test)
+	assert.Equal(t, 30*time.Second, tst.TimeoutDuration())
+	assert.Equal(t, "http://localhost:8080/health", tst.HTTP[0].Address)
+	assert.Equal(t, []int{200, 204}, tst.HTTP[0].SuccessCodes)
+	assert.Equal(t, "ok", tst.HTTP[0].BodyContains)
+	assert.Equal(t, 1, tst.Script[0].ExitCode)
+	assert.Equal(t, []string{"app=api"}, tst.PodReady[0].Pods)
+	assert.Contains(t, tst.DependsOn, "k8s_cluster.k3s")
+}
+
+func TestTestWithoutAssertionsReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, testNoAssertions)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "at least one http, script, or pod_ready assertion")
+}
+
+func TestTestValidateReturnsErrors(t *testing.T) {
+	tst := NewTest("abc")
+	tst.Script = []TestScript{{Script: "exit 0", Command: []string{"ls"}}}
+	assert.Error(t, tst.Validate())
+
+	tst = NewTest("abc")
+	tst.HTTP = []TestHTTP{{Address: "localhost:8080"}}
+	assert.Error(t, tst.Validate())
+
+	tst = NewTest("abc")
+	tst.PodReady = []TestPodReady{{Cluster: "nomad_cluster.dev", Pods: []string{"app=api"}}}
+	assert.Error(t, tst.Validate())
+
+	tst = NewTest("abc")
+	tst.Timeout = "soon"
+	tst.Script = []TestScript{{Script: "exit 0"}}
+	assert.Error(t, tst.Validate())
+}
+
+const testDefault = `
+k8s_cluster "k3s" {
+  driver = "k3s"
+}
+
+test "api" {
+  timeout = "30s"
+
+  http {
+    address       = "http://localhost:8080/health"
+    success_codes = [200, 204]
+    body_contains = "ok"
+  }
+
+  script {
+    script    = "exit 1"
+    exit_code = 1
+  }
+
+  pod_ready {
+    cluster = "k8s_cluster.k3s"
+    pods    = ["app=api"]
+  }
+}
+`
+
+const testNoAssertions = `
+test "api" {
+  timeout = "30s"
+}
+`
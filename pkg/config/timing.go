@@ -0,0 +1,82 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// phases of creating a resource recorded in the Timing
+const (
+	PhasePull        = "pull"
+	PhaseBuild       = "build"
+	PhaseHealthCheck = "health_check"
+	PhaseWaitFor     = "wait_for"
+)
+
+// Timing records when the last create or destroy of a resource started and
+// finished, and the time spent in each phase of the operation
+type Timing struct {
+	// Operation is create or destroy
+	Operation string                   `json:"operation"`
+	Started   time.Time                `json:"started"`
+	Finished  time.Time                `json:"finished"`
+	Phases    map[string]time.Duration `json:"phases,omitempty"`
+
+	lock sync.Mutex
+}
+
+// NewTiming creates a Timing for an operation which starts now
+func NewTiming(operation string) *Timing {
+	return &Timing{Operation: operation, Started: time.Now(), Phases: map[string]time.Duration{}}
+}
+
+// Record adds the duration to the time spent in the phase, phases which
+// run several times e.g. pulling multiple images are totalled
+func (t *Timing) Record(phase string, d time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.Phases == nil {
+		t.Phases = map[string]time.Duration{}
+	}
+
+	t.Phases[phase] += d
+}
+
+// Phase returns the time spent in the phase
+func (t *Timing) Phase(phase string) time.Duration {
+	if t == nil {
+		return 0
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.Phases[phase]
+}
+
+// Finish records the time the operation finished
+func (t *Timing) Finish() {
+	if t == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.Finished = time.Now()
+}
+
+// Duration returns the time taken by the operation, zero when the
+// operation has not finished
+func (t *Timing) Duration() time.Duration {
+	if t == nil || t.Finished.IsZero() {
+		return 0
+	}
+
+	return t.Finished.Sub(t.Started)
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestTimingRecordTotalsPhases(t *testing.T) {
+	tm := NewTiming("create")
+
+	tm.Record(PhasePull, 2*time.Second)
+	tm.Record(PhasePull, 3*time.Second)
+	tm.Record(PhaseHealthCheck, time.Second)
+
+	assert.Equal(t, 5*time.Second, tm.Phase(PhasePull))
+	assert.Equal(t, time.Second, tm.Phase(PhaseHealthCheck))
+	assert.Equal(t, time.Duration(0), tm.Phase(PhaseBuild))
+}
+
+func TestTimingDurationIsZeroUntilFinished(t *testing.T) {
+	tm := NewTiming("create")
+	assert.Equal(t, time.Duration(0), tm.Duration())
+
+	tm.Finish()
+	assert.True(t, tm.Duration() >= 0)
+	assert.False(t, tm.Finished.IsZero())
+}
+
+func TestTimingIsNilSafe(t *testing.T) {
+	var tm *Timing
+
+	tm.Record(PhasePull, time.Second)
+	tm.Finish()
+
+	assert.Equal(t, time.Duration(0), tm.Phase(PhasePull))
+	assert.Equal(t, time.Duration(0), tm.Duration())
+}
+
+func TestTimingIsSavedInState(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	con, err := c.FindResource("container.config")
+	assert.NoError(t, err)
+
+	tm := NewTiming("create")
+	tm.Record(PhasePull, 2*time.Second)
+	tm.Finish()
+	con.Info().Timing = tm
+
+	err = c.ToJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	sc := New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	sr, err := sc.FindResource("container.config")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "create", sr.Info().Timing.Operation)
+	assert.True(t, tm.Started.Equal(sr.Info().Timing.Started))
+	assert.True(t, tm.Finished.Equal(sr.Info().Timing.Finished))
+	assert.Equal(t, 2*time.Second, sr.Info().Timing.Phase(PhasePull))
+}
+
+func TestTimingDoesNotChangeConfigHash(t *testing.T) {
+	con := NewContainer("test")
+	con.Image = &Image{Name: "nginx"}
+
+	h1, err := ConfigHash(con)
+	assert.NoError(t, err)
+
+	con.Timing = NewTiming("create")
+	h2, err := ConfigHash(con)
+	assert.NoError(t, err)
+
+	assert.Equal(t, h1, h2)
+}
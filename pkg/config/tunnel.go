@@ -0,0 +1,54 @@
+package config
+
+import "fmt"
+
+// TypeTunnel is the resource string for a Tunnel resource
+const TypeTunnel ResourceType = "tunnel"
+
+// TunnelProviderCloudflared publishes the service using a Cloudflare quick tunnel
+const TunnelProviderCloudflared = "cloudflared"
+
+// TunnelProviderNgrok publishes the service using ngrok
+const TunnelProviderNgrok = "ngrok"
+
+// Tunnel publishes a service to the internet using a tunnel provider,
+// the public URL for the tunnel is shown by the output command
+type Tunnel struct {
+	ResourceInfo `hcl:",remain" mapstructure:",squash"`
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	Networks []NetworkAttachment `hcl:"network,block" json:"networks,omitempty"` // networks to attach the tunnel container to
+
+	Provider  string `hcl:"provider,optional" json:"provider,omitempty"`                               // tunnel provider [cloudflared, ngrok], defaults to cloudflared
+	Address   string `hcl:"address" json:"address"`                                                    // address of the HTTP service to publish e.g. web.container.shipyard.run:8080
+	AuthToken string `hcl:"auth_token,optional" json:"auth_token,omitempty" mapstructure:"auth_token"` // auth token for the provider, required for ngrok
+	Image     *Image `hcl:"image,block" json:"image,omitempty"`                                        // override the default image for the provider
+
+	// PublicURL is the URL assigned by the tunnel provider
+	PublicURL string `json:"public_url,omitempty" mapstructure:"public_url" state:"true"`
+}
+
+// NewTunnel creates a Tunnel resource with the default values
+func NewTunnel(name string) *Tunnel {
+	return &Tunnel{ResourceInfo: ResourceInfo{Name: name, Type: TypeTunnel, Status: PendingCreation}, Provider: TunnelProviderCloudflared}
+}
+
+// Validate the config
+func (t *Tunnel) Validate() error {
+	switch t.Provider {
+	case TunnelProviderCloudflared:
+	case TunnelProviderNgrok:
+		if t.AuthToken == "" {
+			return fmt.Errorf("auth_token must be specified for the ngrok provider")
+		}
+	default:
+		return fmt.Errorf("invalid provider '%s', valid options are cloudflared, ngrok", t.Provider)
+	}
+
+	if t.Address == "" {
+		return fmt.Errorf("address must be specified")
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCreatesTunnel(t *testing.T) {
+	c := NewTunnel("abc")
+
+	assert.Equal(t, "abc", c.Name)
+	assert.Equal(t, TypeTunnel, c.Type)
+	assert.Equal(t, TunnelProviderCloudflared, c.Provider)
+}
+
+func TestTunnelCreatesCorrectly(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, tunnelDefault)
+
+	cl, err := c.FindResource("tunnel.demo")
+	assert.NoError(t, err)
+
+	tu := cl.(*Tunnel)
+	assert.Equal(t, TunnelProviderCloudflared, tu.Provider)
+	assert.Equal(t, "web.container.shipyard.run:8080", tu.Address)
+	assert.Equal(t, PendingCreation, tu.Status)
+	assert.Contains(t, tu.DependsOn, "network.local")
+}
+
+func TestTunnelWithNgrokWithoutTokenReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, tunnelNgrokNoToken)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "auth_token")
+}
+
+func TestTunnelWithInvalidProviderReturnsError(t *testing.T) {
+	tu := NewTunnel("abc")
+	tu.Address = "localhost:8080"
+	tu.Provider = "magic"
+
+	assert.Error(t, tu.Validate())
+}
+
+const tunnelDefault = `
+network "local" {
+	subnet = "10.0.0.0/16"
+}
+
+tunnel "demo" {
+	network {
+		name = "network.local"
+	}
+
+	address = "web.container.shipyard.run:8080"
+}
+`
+
+const tunnelNgrokNoToken = `
+tunnel "demo" {
+	provider = "ngrok"
+	address = "web.container.shipyard.run:8080"
+}
+`
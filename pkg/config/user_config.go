@@ -0,0 +1,230 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl2/gohcl"
+	"github.com/hashicorp/hcl2/hclparse"
+)
+
+// Hook events which are raised by the engine
+const (
+	HookPreRun      = "pre_run"
+	HookPostRun     = "post_run"
+	HookPreDestroy  = "pre_destroy"
+	HookPostDestroy = "post_destroy"
+)
+
+// HookEvents are the events which hooks can be configured for
+var HookEvents = []string{HookPreRun, HookPostRun, HookPreDestroy, HookPostDestroy}
+
+// DefaultHookTimeout is the maximum time a hook can run when no timeout is set
+const DefaultHookTimeout = 60 * time.Second
+
+// UserConfig is the global configuration which applies to all blueprints,
+// it is read from $HOME/.shipyard/config.hcl
+type UserConfig struct {
+	Hooks []Hook        `hcl:"hook,block" json:"hooks,omitempty"`
+	Ports *PortDefaults `hcl:"ports,block" json:"ports,omitempty"`
+	Exec  *ExecDefaults `hcl:"exec,block" json:"exec,omitempty"`
+	Quota *Quota        `hcl:"quota,block" json:"quota,omitempty"`
+
+	MemoryPressure *MemoryPressure `hcl:"memory_pressure,block" json:"memory_pressure,omitempty"`
+
+	Defaults *ResourceDefaults `hcl:"defaults,block" json:"defaults,omitempty"`
+
+	Proxy *Proxy `hcl:"proxy,block" json:"proxy,omitempty"`
+}
+
+// Proxy configures the HTTP proxy used for the outbound connections made by Shipyard and
+// the clusters it creates, the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables
+// are used when the values are not set
+type Proxy struct {
+	HTTP    string   `hcl:"http,optional" json:"http,omitempty"`                                 // proxy for HTTP requests e.g. http://proxy.corp:3128
+	HTTPS   string   `hcl:"https,optional" json:"https,omitempty"`                               // proxy for HTTPS requests
+	NoProxy []string `hcl:"no_proxy,optional" json:"no_proxy,omitempty" mapstructure:"no_proxy"` // hosts and domains which are not proxied e.g. [".corp", "10.0.0.0/8"]
+	CAFile  string   `hcl:"ca_file,optional" json:"ca_file,omitempty" mapstructure:"ca_file"`    // PEM encoded CA bundle for a TLS intercepting proxy
+}
+
+// Validate the proxy config
+func (p *Proxy) Validate() error {
+	for _, u := range []string{p.HTTP, p.HTTPS} {
+		if u == "" {
+			continue
+		}
+
+		pu, err := url.Parse(u)
+		if err != nil || pu.Host == "" {
+			return fmt.Errorf("invalid proxy address '%s', the address must be a URL e.g. http://proxy.corp:3128", u)
+		}
+
+		if pu.Scheme != "http" && pu.Scheme != "https" && pu.Scheme != "socks5" {
+			return fmt.Errorf("invalid proxy address '%s', the scheme must be http, https, or socks5", u)
+		}
+	}
+
+	if p.CAFile != "" {
+		if _, err := os.Stat(p.CAFile); err != nil {
+			return fmt.Errorf("unable to read ca_file '%s': %s", p.CAFile, err)
+		}
+	}
+
+	return nil
+}
+
+// ExecDefaults configure the behaviour of the exec command
+type ExecDefaults struct {
+	Record bool `hcl:"record,optional" json:"record,omitempty"` // record exec sessions in asciinema format to $HOME/.shipyard/logs/sessions
+}
+
+// PortDefaults are the defaults for ports which are published on the host
+type PortDefaults struct {
+	Bind string `hcl:"bind,optional" json:"bind,omitempty"` // interface ports are bound to when not set on the port e.g. 127.0.0.1
+}
+
+// DefaultPortBind returns the interface ports are bound to when the
+// port does not set bind, a blank address binds to all interfaces
+func (u *UserConfig) DefaultPortBind() string {
+	if u.Ports == nil {
+		return ""
+	}
+
+	return u.Ports.Bind
+}
+
+// RecordExec returns true when exec sessions should be recorded
+func (u *UserConfig) RecordExec() bool {
+	if u.Exec == nil {
+		return false
+	}
+
+	return u.Exec.Record
+}
+
+// Hook is a command which is executed by the engine when an event occurs
+// e.g. running a compliance script before every apply
+type Hook struct {
+	Name string `hcl:"name,label" json:"name"`
+
+	Event   string            `hcl:"event" json:"event"`                                               // event which runs the hook pre_run, post_run, pre_destroy, post_destroy
+	Command string            `hcl:"command" json:"command"`                                           // command to execute
+	Args    []string          `hcl:"args,optional" json:"args,omitempty"`                              // arguments for the command
+	EnvVar  map[string]string `hcl:"env_var,optional" json:"env_var,omitempty" mapstructure:"env_var"` // additional environment variables to set for the command
+	Timeout string            `hcl:"timeout,optional" json:"timeout,omitempty"`                        // maximum time the hook can run e.g. 30s, defaults to 60s
+}
+
+// Validate the hook
+func (h *Hook) Validate() error {
+	valid := false
+	for _, e := range HookEvents {
+		if h.Event == e {
+			valid = true
+		}
+	}
+
+	if !valid {
+		return fmt.Errorf("invalid event '%s', must be one of %s", h.Event, strings.Join(HookEvents, ", "))
+	}
+
+	if h.Timeout != "" {
+		if _, err := time.ParseDuration(h.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout '%s', %s", h.Timeout, err)
+		}
+	}
+
+	return nil
+}
+
+// TimeoutDuration returns the maximum time the hook can run
+func (h *Hook) TimeoutDuration() time.Duration {
+	if h.Timeout == "" {
+		return DefaultHookTimeout
+	}
+
+	d, _ := time.ParseDuration(h.Timeout)
+	return d
+}
+
+// HooksForEvent returns the hooks which run for the given event in the
+// order they are defined
+func (u *UserConfig) HooksForEvent(event string) []Hook {
+	hooks := []Hook{}
+	for _, h := range u.Hooks {
+		if h.Event == event {
+			hooks = append(hooks, h)
+		}
+	}
+
+	return hooks
+}
+
+// LoadUserConfig reads the user config from the given file, an empty
+// config is returned when the file does not exist
+func LoadUserConfig(file string) (*UserConfig, error) {
+	uc := &UserConfig{}
+
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return uc, nil
+	}
+
+	parser := hclparse.NewParser()
+
+	f, diag := parser.ParseHCLFile(file)
+	if diag.HasErrors() {
+		return nil, errors.New(diag.Error())
+	}
+
+	diag = gohcl.DecodeBody(f.Body, nil, uc)
+	if diag.HasErrors() {
+		return nil, errors.New(diag.Error())
+	}
+
+	for _, h := range uc.Hooks {
+		err := h.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("Error in file '%s': hook '%s' %s", file, h.Name, err)
+		}
+	}
+
+	if uc.Ports != nil {
+		err := validateBind(uc.Ports.Bind)
+		if err != nil {
+			return nil, fmt.Errorf("Error in file '%s': ports %s", file, err)
+		}
+	}
+
+	if uc.Quota != nil {
+		err := uc.Quota.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("Error in file '%s': quota %s", file, err)
+		}
+	}
+
+	if uc.MemoryPressure != nil {
+		err := uc.MemoryPressure.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("Error in file '%s': memory_pressure %s", file, err)
+		}
+	}
+
+	if uc.Defaults != nil {
+		err := uc.Defaults.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("Error in file '%s': defaults %s", file, err)
+		}
+	}
+
+	if uc.Proxy != nil {
+		err := uc.Proxy.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("Error in file '%s': proxy %s", file, err)
+		}
+	}
+
+	return uc, nil
+}
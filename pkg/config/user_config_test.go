@@ -0,0 +1,257 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeUserConfig(t *testing.T, contents string) string {
+	file := filepath.Join(t.TempDir(), "config.hcl")
+	err := ioutil.WriteFile(file, []byte(contents), os.ModePerm)
+	assert.NoError(t, err)
+
+	return file
+}
+
+func TestLoadUserConfigReturnsEmptyWhenNotExist(t *testing.T) {
+	uc, err := LoadUserConfig(filepath.Join(t.TempDir(), "config.hcl"))
+	assert.NoError(t, err)
+
+	assert.Empty(t, uc.Hooks)
+}
+
+func TestLoadUserConfigParsesHooks(t *testing.T) {
+	uc, err := LoadUserConfig(writeUserConfig(t, userConfigHooks))
+	assert.NoError(t, err)
+
+	assert.Len(t, uc.Hooks, 2)
+	assert.Equal(t, "compliance", uc.Hooks[0].Name)
+	assert.Equal(t, []string{"--check"}, uc.Hooks[0].Args)
+	assert.Equal(t, 30*time.Second, uc.Hooks[0].TimeoutDuration())
+	assert.Equal(t, DefaultHookTimeout, uc.Hooks[1].TimeoutDuration())
+
+	hooks := uc.HooksForEvent(HookPostDestroy)
+	assert.Len(t, hooks, 1)
+	assert.Equal(t, "inventory", hooks[0].Name)
+}
+
+func TestLoadUserConfigWithInvalidTimeoutReturnsError(t *testing.T) {
+	_, err := LoadUserConfig(writeUserConfig(t, userConfigInvalidTimeout))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid timeout 'soon'")
+}
+
+func TestLoadUserConfigParsesPortDefaults(t *testing.T) {
+	uc, err := LoadUserConfig(writeUserConfig(t, userConfigPorts))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "127.0.0.1", uc.DefaultPortBind())
+}
+
+func TestLoadUserConfigWithInvalidPortBindReturnsError(t *testing.T) {
+	_, err := LoadUserConfig(writeUserConfig(t, userConfigInvalidPorts))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid bind address 'localhost'")
+}
+
+func TestLoadUserConfigParsesProxy(t *testing.T) {
+	uc, err := LoadUserConfig(writeUserConfig(t, userConfigProxy))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "http://proxy.corp:3128", uc.Proxy.HTTP)
+	assert.Equal(t, "http://proxy.corp:3129", uc.Proxy.HTTPS)
+	assert.Equal(t, []string{".corp", "10.0.0.0/8"}, uc.Proxy.NoProxy)
+}
+
+func TestLoadUserConfigWithInvalidProxyReturnsError(t *testing.T) {
+	_, err := LoadUserConfig(writeUserConfig(t, userConfigInvalidProxy))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid proxy address 'proxy.corp:3128'")
+}
+
+func TestLoadUserConfigWithMissingProxyCAReturnsError(t *testing.T) {
+	_, err := LoadUserConfig(writeUserConfig(t, userConfigMissingProxyCA))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to read ca_file")
+}
+
+func TestUserConfigDefaultPortBindIsBlankWhenNotSet(t *testing.T) {
+	uc := &UserConfig{}
+
+	assert.Equal(t, "", uc.DefaultPortBind())
+}
+
+func TestLoadUserConfigParsesExecDefaults(t *testing.T) {
+	uc, err := LoadUserConfig(writeUserConfig(t, userConfigExec))
+	assert.NoError(t, err)
+
+	assert.True(t, uc.RecordExec())
+}
+
+func TestUserConfigRecordExecIsFalseWhenNotSet(t *testing.T) {
+	uc := &UserConfig{}
+
+	assert.False(t, uc.RecordExec())
+}
+
+func TestLoadUserConfigParsesQuota(t *testing.T) {
+	uc, err := LoadUserConfig(writeUserConfig(t, userConfigQuota))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 10, uc.Quota.MaxContainers)
+	assert.Equal(t, 2, uc.Quota.MaxClusters)
+
+	m, err := uc.Quota.MaxMemoryBytes()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(16<<30), m)
+}
+
+func TestLoadUserConfigWithInvalidQuotaReturnsError(t *testing.T) {
+	_, err := LoadUserConfig(writeUserConfig(t, userConfigInvalidQuota))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid max_memory 'lots'")
+}
+
+func TestLoadUserConfigParsesMemoryPressure(t *testing.T) {
+	uc, err := LoadUserConfig(writeUserConfig(t, userConfigMemoryPressure))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 90, uc.MemoryPressure.Threshold)
+	assert.Equal(t, 30*time.Second, uc.MemoryPressure.IntervalDuration())
+	assert.Equal(t, []string{"container.grafana", "nomad_cluster.*"}, uc.MemoryPressure.Stop)
+}
+
+func TestLoadUserConfigWithInvalidMemoryPressureReturnsError(t *testing.T) {
+	_, err := LoadUserConfig(writeUserConfig(t, userConfigInvalidMemoryPressure))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "memory_pressure threshold")
+}
+
+func TestLoadUserConfigParsesResourceDefaults(t *testing.T) {
+	uc, err := LoadUserConfig(writeUserConfig(t, userConfigDefaults))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 15*time.Minute, uc.Defaults.TimeoutDuration())
+	assert.Equal(t, 5, uc.Defaults.RetryPolicy().MaxAttempts())
+	assert.Equal(t, 10*time.Second, uc.Defaults.RetryPolicy().DelayDuration())
+}
+
+func TestLoadUserConfigWithInvalidResourceDefaultsReturnsError(t *testing.T) {
+	_, err := LoadUserConfig(writeUserConfig(t, userConfigInvalidDefaults))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "defaults retry backoff")
+}
+
+const userConfigHooks = `
+hook "compliance" {
+  event   = "pre_run"
+  command = "/usr/local/bin/compliance"
+  args    = ["--check"]
+  timeout = "30s"
+}
+
+hook "inventory" {
+  event   = "post_destroy"
+  command = "/usr/local/bin/inventory"
+}
+`
+
+const userConfigInvalidTimeout = `
+hook "compliance" {
+  event   = "pre_run"
+  command = "/usr/local/bin/compliance"
+  timeout = "soon"
+}
+`
+
+const userConfigPorts = `
+ports {
+	bind = "127.0.0.1"
+}
+`
+
+const userConfigInvalidPorts = `
+ports {
+	bind = "localhost"
+}
+`
+
+const userConfigExec = `
+exec {
+	record = true
+}
+`
+
+const userConfigQuota = `
+quota {
+	max_containers = 10
+	max_memory     = "16Gi"
+	max_clusters   = 2
+}
+`
+
+const userConfigInvalidQuota = `
+quota {
+	max_memory = "lots"
+}
+`
+
+const userConfigMemoryPressure = `
+memory_pressure {
+	threshold = 90
+	interval  = "30s"
+	stop      = ["container.grafana", "nomad_cluster.*"]
+}
+`
+
+const userConfigInvalidMemoryPressure = `
+memory_pressure {
+	threshold = 150
+	stop      = ["container.grafana"]
+}
+`
+
+const userConfigDefaults = `
+defaults {
+	timeout = "15m"
+
+	retry {
+		attempts = 5
+		delay    = "10s"
+	}
+}
+`
+
+const userConfigInvalidDefaults = `
+defaults {
+	retry {
+		backoff = 0.5
+	}
+}
+`
+
+const userConfigProxy = `
+proxy {
+	http     = "http://proxy.corp:3128"
+	https    = "http://proxy.corp:3129"
+	no_proxy = [".corp", "10.0.0.0/8"]
+}
+`
+
+const userConfigInvalidProxy = `
+proxy {
+	http = "proxy.corp:3128"
+}
+`
+
+const userConfigMissingProxyCA = `
+proxy {
+	https   = "http://proxy.corp:3128"
+	ca_file = "/does/not/exist/ca.pem"
+}
+`
@@ -0,0 +1,407 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl2/gohcl"
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// ValidationError is a problem found in a blueprint by Validate
+type ValidationError struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Resource string `json:"resource,omitempty"` // address of the resource e.g. container.consul
+	Message  string `json:"message"`
+}
+
+func (v ValidationError) Error() string {
+	loc := v.File
+	if v.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", v.File, v.Line)
+	}
+
+	msg := v.Message
+	if v.Resource != "" {
+		msg = fmt.Sprintf("resource '%s' %s", v.Resource, v.Message)
+	}
+
+	if loc == "" {
+		return msg
+	}
+
+	return fmt.Sprintf("%s: %s", loc, msg)
+}
+
+// Validate checks the blueprint file or folder at path without creating any resources and
+// returns every problem found. Unknown attributes and blocks, missing required attributes,
+// invalid or duplicate resource names are reported for all the files in the blueprint, when
+// the files are well formed the blueprint is parsed and the references between the
+// resources are checked.
+func Validate(path string, variables map[string]string, variablesFile string) []ValidationError {
+	files, err := blueprintFiles(path)
+	if err != nil {
+		return []ValidationError{{File: path, Message: err.Error()}}
+	}
+
+	errs := []ValidationError{}
+	defined := map[string]ValidationError{}
+
+	for _, f := range files {
+		errs = append(errs, validateFile(f, defined)...)
+	}
+
+	// the parser stops at the first error, only parse when the files are well formed
+	// so that the problems found in the files are not reported twice
+	if len(errs) > 0 {
+		return errs
+	}
+
+	c := New()
+
+	// resources which pull images depend on the image cache which is created by the engine
+	c.AddResource(NewImageCache(utils.CacheResourceName))
+
+	if utils.IsConfigFile(path) {
+		err = ParseSingleFile(path, c, variables, variablesFile)
+	} else {
+		err = ParseFolder(path, c, false, "", false, []string{}, variables, variablesFile)
+	}
+
+	if err != nil {
+		return []ValidationError{{Message: err.Error()}}
+	}
+
+	err = ParseReferences(c)
+	if err != nil {
+		return []ValidationError{{Message: err.Error()}}
+	}
+
+	errs = append(errs, validateReferences(c)...)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	d, err := c.DoYaLikeDAGs()
+	if err == nil {
+		err = d.Validate()
+	}
+
+	if err != nil {
+		return []ValidationError{{Message: fmt.Sprintf("invalid dependency graph: %s", err)}}
+	}
+
+	return nil
+}
+
+// blueprintFiles returns the config files for a blueprint file or folder
+func blueprintFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find blueprint")
+	}
+
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	files, err := configFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// validateFile checks the blocks in a file against the schema of the resources, defined
+// contains the resources found in the previous files and is used to detect duplicates
+func validateFile(file string, defined map[string]ValidationError) []ValidationError {
+	errs := []ValidationError{}
+
+	body, diag := parseConfigFile(file)
+	if diag.HasErrors() {
+		return diagnosticErrors(diag, "")
+	}
+
+	for _, a := range body.Attributes {
+		errs = append(errs, ValidationError{File: file, Line: a.SrcRange.Start.Line, Message: fmt.Sprintf("unexpected attribute '%s', attributes must be defined in a resource", a.Name)})
+	}
+
+	for _, b := range body.Blocks {
+		line := b.TypeRange.Start.Line
+
+		// locals contain any attributes
+		if b.Type == BlockLocals {
+			continue
+		}
+
+		v := blockValue(b.Type)
+		_, plugin := PluginType(ResourceType(b.Type))
+		if v == nil && !plugin {
+			errs = append(errs, ValidationError{File: file, Line: line, Message: fmt.Sprintf("unknown resource type '%s'", b.Type)})
+			continue
+		}
+
+		if len(b.Labels) == 0 {
+			errs = append(errs, ValidationError{File: file, Line: line, Message: fmt.Sprintf("resource '%s' has no name, please specify resources using the syntax 'resource_type \"name\" {}'", b.Type)})
+			continue
+		}
+
+		address := fmt.Sprintf("%s.%s", b.Type, b.Labels[0])
+
+		if _, err := utils.ValidateName(b.Labels[0]); err != nil {
+			errs = append(errs, ValidationError{File: file, Line: line, Resource: address, Message: fmt.Sprintf("has an invalid name: %s", err)})
+		}
+
+		if first, ok := defined[address]; ok {
+			errs = append(errs, ValidationError{File: file, Line: line, Resource: address, Message: fmt.Sprintf("is already defined at %s:%d", first.File, first.Line)})
+		} else {
+			defined[address] = ValidationError{File: file, Line: line}
+		}
+
+		// the schema for plugin resources is checked by the plugin when the resource is parsed
+		if plugin {
+			continue
+		}
+
+		errs = append(errs, diagnosticErrors(validateBody(b.Body, v, true), address)...)
+	}
+
+	return errs
+}
+
+// validateBody checks the attributes and blocks in body against the hcl tags of val,
+// nested blocks are checked against the type of the field they are decoded into
+func validateBody(body hcl.Body, val interface{}, resource bool) hcl.Diagnostics {
+	schema, partial := bodySchema(reflect.TypeOf(val).Elem())
+
+	// count and for_each are removed before the resource is decoded
+	if resource {
+		schema.Attributes = append(schema.Attributes, hcl.AttributeSchema{Name: "count"}, hcl.AttributeSchema{Name: "for_each"})
+	}
+
+	var content *hcl.BodyContent
+	var diags hcl.Diagnostics
+
+	if partial {
+		content, _, diags = body.PartialContent(schema)
+	} else {
+		content, diags = body.Content(schema)
+	}
+
+	if content == nil {
+		return diags
+	}
+
+	fields := blockFields(reflect.TypeOf(val).Elem())
+	for _, b := range content.Blocks {
+		t, ok := fields[b.Type]
+		if !ok {
+			continue
+		}
+
+		diags = append(diags, validateBody(b.Body, reflect.New(t).Interface(), false)...)
+	}
+
+	return diags
+}
+
+// bodySchema returns the schema for a struct including the schema of any struct which
+// the remaining body is decoded into, partial is true when the struct accepts any
+// remaining attributes and blocks
+func bodySchema(t reflect.Type) (*hcl.BodySchema, bool) {
+	schema, _ := gohcl.ImpliedBodySchema(reflect.New(t).Interface())
+	partial := false
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !strings.HasSuffix(f.Tag.Get("hcl"), ",remain") {
+			continue
+		}
+
+		if f.Type.Kind() != reflect.Struct {
+			partial = true
+			continue
+		}
+
+		rs, rp := bodySchema(f.Type)
+		schema.Attributes = append(schema.Attributes, rs.Attributes...)
+		schema.Blocks = append(schema.Blocks, rs.Blocks...)
+		partial = partial || rp
+	}
+
+	return schema, partial
+}
+
+// blockFields returns the struct type each nested block is decoded into
+func blockFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("hcl"), ",")
+
+		if len(tag) == 2 && tag[1] == "remain" && f.Type.Kind() == reflect.Struct {
+			for k, v := range blockFields(f.Type) {
+				fields[k] = v
+			}
+
+			continue
+		}
+
+		if len(tag) != 2 || tag[1] != "block" {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct {
+			fields[tag[0]] = ft
+		}
+	}
+
+	return fields
+}
+
+// validateReferences checks the resources each resource depends on exist
+func validateReferences(c *Config) []ValidationError {
+	errs := []ValidationError{}
+
+	for _, r := range c.Resources {
+		for _, d := range r.Info().DependsOn {
+			var err error
+			if strings.HasPrefix(d, "module.") {
+				_, err = c.FindModuleResources(d)
+			} else {
+				_, err = c.FindResource(d)
+			}
+
+			if err != nil {
+				address := fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)
+				if r.Info().Module != "" {
+					address = fmt.Sprintf("module.%s.%s", r.Info().Module, address)
+				}
+
+				errs = append(errs, ValidationError{Resource: address, Message: fmt.Sprintf("references '%s' which does not exist", d)})
+			}
+		}
+	}
+
+	return errs
+}
+
+func diagnosticErrors(diags hcl.Diagnostics, resource string) []ValidationError {
+	errs := []ValidationError{}
+
+	for _, d := range diags {
+		if d.Severity != hcl.DiagError {
+			continue
+		}
+
+		e := ValidationError{Resource: resource, Message: d.Summary}
+		if d.Detail != "" {
+			e.Message = fmt.Sprintf("%s; %s", d.Summary, d.Detail)
+		}
+
+		if d.Subject != nil {
+			e.File = d.Subject.Filename
+			e.Line = d.Subject.Start.Line
+		}
+
+		errs = append(errs, e)
+	}
+
+	return errs
+}
+
+// blockValue returns an empty value of the type a top level block is decoded into,
+// nil is returned for unknown block types
+func blockValue(t string) interface{} {
+	switch t {
+	case BlockRemoteEnvironment:
+		return &RemoteEnvironment{}
+	}
+
+	switch ResourceType(t) {
+	case TypeCertificateCA:
+		return &CertificateCA{}
+	case TypeCertificateLeaf:
+		return &CertificateLeaf{}
+	case TypeContainerIngress:
+		return &ContainerIngress{}
+	case TypeCompose:
+		return &Compose{}
+	case TypeContainer:
+		return &Container{}
+	case TypeCopy:
+		return &Copy{}
+	case TypeDocs:
+		return &Docs{}
+	case TypeDockerImage:
+		return &DockerImage{}
+	case TypeExecLocal:
+		return &ExecLocal{}
+	case TypeExecRemote:
+		return &ExecRemote{}
+	case TypeHelm:
+		return &Helm{}
+	case TypeIngress:
+		return &Ingress{}
+	case TypeK8sCluster:
+		return &K8sCluster{}
+	case TypeK8sConfig:
+		return &K8sConfig{}
+	case TypeK8sIngress:
+		return &K8sIngress{}
+	case TypeK8sNamespace:
+		return &K8sNamespace{}
+	case TypeLogSink:
+		return &LogSink{}
+	case TypeModule:
+		return &Module{}
+	case TypeNetwork:
+		return &Network{}
+	case TypeNomadCluster:
+		return &NomadCluster{}
+	case TypeNomadIngress:
+		return &NomadIngress{}
+	case TypeNomadJob:
+		return &NomadJob{}
+	case TypeOutput:
+		return &Output{}
+	case TypeRandomID:
+		return &RandomID{}
+	case TypeRandomPassword:
+		return &RandomPassword{}
+	case TypeRegistry:
+		return &Registry{}
+	case TypeRouter:
+		return &Router{}
+	case TypeSidecar:
+		return &Sidecar{}
+	case TypeSocksProxy:
+		return &SocksProxy{}
+	case TypeTemplate:
+		return &Template{}
+	case TypeTerraform:
+		return &Terraform{}
+	case TypeTest:
+		return &Test{}
+	case TypeTunnel:
+		return &Tunnel{}
+	case TypeVariable:
+		return &Variable{}
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func validationMessages(errs []ValidationError) []string {
+	m := []string{}
+	for _, e := range errs {
+		m = append(m, e.Error())
+	}
+
+	return m
+}
+
+func TestValidateValidBlueprintReturnsNoErrors(t *testing.T) {
+	dir := CreateTestFiles(t, validateValid)
+
+	errs := Validate(dir, nil, "")
+	require.Empty(t, errs)
+}
+
+func TestValidateReportsAllUnknownAttributesAndBlocks(t *testing.T) {
+	dir := CreateTestFiles(t, validateUnknown)
+
+	errs := Validate(dir, nil, "")
+	require.Len(t, errs, 3)
+
+	m := validationMessages(errs)
+	require.Contains(t, m[0], `resource 'container.consul' Unsupported argument; An argument named "command_line" is not expected here.`)
+	require.Contains(t, m[1], `Blocks of type "volumes" are not expected here.`)
+
+	// attributes in nested blocks are checked against the type of the block
+	require.Contains(t, m[2], `An argument named "tag" is not expected here.`)
+	require.Equal(t, 5, errs[2].Line)
+}
+
+func TestValidateReportsMissingRequiredAttributes(t *testing.T) {
+	dir := CreateTestFiles(t, validateMissingRequired)
+
+	errs := Validate(dir, nil, "")
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Message, `The argument "name" is required`)
+}
+
+func TestValidateReportsInvalidNamesAndDuplicates(t *testing.T) {
+	dir := CreateTestFiles(t, validateNames)
+
+	errs := Validate(dir, nil, "")
+	require.Len(t, errs, 2)
+
+	m := validationMessages(errs)
+	require.Contains(t, m[0], "resource 'container.consul.server' has an invalid name")
+	require.Contains(t, m[1], "resource 'network.local' is already defined at")
+}
+
+func TestValidateReportsUnknownResourceType(t *testing.T) {
+	dir := CreateTestFiles(t, validateUnknownType)
+
+	errs := Validate(dir, nil, "")
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Message, "unknown resource type 'cluster'")
+}
+
+func TestValidateReportsBrokenReferences(t *testing.T) {
+	dir := CreateTestFiles(t, validateBrokenReference)
+
+	errs := Validate(dir, nil, "")
+	require.Len(t, errs, 2)
+	require.Equal(t, "container.consul", errs[0].Resource)
+	require.Equal(t, "references 'network.missing' which does not exist", errs[0].Message)
+	require.Equal(t, "references 'container.vault' which does not exist", errs[1].Message)
+}
+
+func TestValidateMissingBlueprintReturnsError(t *testing.T) {
+	errs := Validate("/not/a/blueprint", nil, "")
+	require.Len(t, errs, 1)
+}
+
+const validateValid = `
+variable "version" {
+  default = "1.8.1"
+}
+
+network "local" {
+  subnet = "10.6.0.0/16"
+}
+
+container "consul" {
+  count = 2
+
+  image {
+    name = "consul:${var.version}"
+  }
+
+  network {
+    name = "network.local"
+  }
+}
+`
+
+const validateUnknown = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+    tag  = "latest"
+  }
+
+  command_line = ["consul", "agent"]
+
+  volumes {
+    source = "./data"
+  }
+}
+`
+
+const validateMissingRequired = `
+container "consul" {
+  image {
+  }
+}
+`
+
+const validateNames = `
+container "consul.server" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+network "local" {
+  subnet = "10.6.0.0/16"
+}
+
+network "local" {
+  subnet = "10.7.0.0/16"
+}
+`
+
+const validateUnknownType = `
+cluster "k3s" {
+  driver = "k3s"
+}
+`
+
+const validateBrokenReference = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+
+  network {
+    name = "network.missing"
+  }
+
+  depends_on = ["container.vault"]
+}
+`
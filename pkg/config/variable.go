@@ -1,5 +1,7 @@
 package config
 
+import "github.com/hashicorp/hcl2/hcl"
+
 const TypeVariable ResourceType = "variable"
 
 // Output defines an output variable which can be set by a module
@@ -7,6 +9,17 @@ type Variable struct {
 	ResourceInfo `mapstructure:",squash"`
 	Default      interface{} `hcl:"default" json:"default"`                            // default value for a variable
 	Description  string      `hcl:"description,optional" json:"description,omitempty"` // description of the variable
+	Sensitive    bool        `hcl:"sensitive,optional" json:"sensitive,omitempty"`     // redact the value from logs and output
+
+	// Validation rules for the value of the variable, the rules are checked
+	// when the blueprint is parsed
+	Validation []VariableValidation `hcl:"validation,block" json:"validation,omitempty"`
+}
+
+// VariableValidation is a rule which the value of a variable must satisfy
+type VariableValidation struct {
+	Condition    hcl.Expression `hcl:"condition" json:"-"`                 // expression which returns true when the value is valid e.g. contains(["1.8.0", "1.9.0"], var.version)
+	ErrorMessage string         `hcl:"error_message" json:"error_message"` // message returned to the user when the condition is false
 }
 
 // NewOutput creates a new output variable
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariableValidationPassesWithValidDefault(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, variableValidation)
+
+	r, err := c.FindResource("network.onprem")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.6.0.0/16", r.(*Network).Subnet)
+}
+
+func TestVariableValidationFailsWithInvalidValue(t *testing.T) {
+	t.Setenv("SY_VAR_consul_version", "1.6.0")
+
+	dir := CreateTestFiles(t, variableValidation)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid value for variable 'consul_version', consul_version must be 1.8.0 or 1.9.0")
+}
+
+func TestVariableValidationChecksEveryRule(t *testing.T) {
+	dir := CreateTestFiles(t, variableValidation)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, map[string]string{"subnet": "10.6.0.0"}, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid value for variable 'subnet', subnet must be a CIDR e.g. 10.6.0.0/16")
+}
+
+func TestVariableValidationWithNonBoolConditionReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, variableValidationNotBool)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must return true or false")
+}
+
+const variableValidation = `
+variable "consul_version" {
+  default = "1.8.0"
+
+  validation {
+    condition     = contains(["1.8.0", "1.9.0"], var.consul_version)
+    error_message = "consul_version must be 1.8.0 or 1.9.0"
+  }
+}
+
+variable "subnet" {
+  default = "10.6.0.0/16"
+
+  validation {
+    condition     = var.subnet != ""
+    error_message = "subnet must be set"
+  }
+
+  validation {
+    condition     = is_cidr(var.subnet)
+    error_message = "subnet must be a CIDR e.g. 10.6.0.0/16"
+  }
+}
+
+network "onprem" {
+  subnet = var.subnet
+}
+`
+
+const variableValidationNotBool = `
+variable "consul_version" {
+  default = "1.8.0"
+
+  validation {
+    condition     = var.consul_version
+    error_message = "consul_version must be 1.8.0 or 1.9.0"
+  }
+}
+`
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Signature types supported by Verify
+const (
+	SignatureTypeGPG    = "gpg"
+	SignatureTypeCosign = "cosign"
+)
+
+// Verify declares the checksum and signature a downloaded file must match before it is used,
+// remote sources which are verified must be files, to verify an archive add ?archive=false
+// to the source so that it is not extracted.
+// example config:
+//
+//	verify {
+//	  checksum  = "sha256:6c3e8b2e..."
+//	  signature = "https://example.com/releases/tool.tar.gz.asc"
+//	  key       = "./keys/release.asc"
+//	}
+type Verify struct {
+	// Path is the URL of the file to verify, required when the resource downloads more than one file
+	Path string `hcl:"path,optional" json:"path,omitempty"`
+
+	// Checksum of the file in the form algorithm:hex e.g. sha256:6c3e..., sha256 and sha512 are supported,
+	// a checksum without an algorithm is a sha256 checksum
+	Checksum string `hcl:"checksum,optional" json:"checksum,omitempty"`
+
+	// Signature is the path or URL of the detached signature for the file
+	Signature string `hcl:"signature,optional" json:"signature,omitempty"`
+	// Key is the path or URL of the public key used to check the signature
+	Key string `hcl:"key,optional" json:"key,omitempty"`
+	// SignatureType is the tool used to create the signature, gpg or cosign, default gpg
+	SignatureType string `hcl:"signature_type,optional" json:"signature_type,omitempty" mapstructure:"signature_type"`
+}
+
+// ChecksumAlgorithm returns the algorithm and the hex encoded sum of the checksum
+func (v *Verify) ChecksumAlgorithm() (string, string) {
+	if i := strings.Index(v.Checksum, ":"); i > -1 {
+		return strings.ToLower(v.Checksum[:i]), strings.ToLower(v.Checksum[i+1:])
+	}
+
+	return "sha256", strings.ToLower(v.Checksum)
+}
+
+// Validate the checksum and signature
+func (v *Verify) Validate() error {
+	if v.Checksum == "" && v.Signature == "" {
+		return fmt.Errorf("verify must set a checksum or signature")
+	}
+
+	if v.Checksum != "" {
+		alg, sum := v.ChecksumAlgorithm()
+
+		size := 0
+		switch alg {
+		case "sha256":
+			size = 32
+		case "sha512":
+			size = 64
+		default:
+			return fmt.Errorf("verify checksum algorithm must be sha256 or sha512, got %s", alg)
+		}
+
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != size {
+			return fmt.Errorf("verify checksum must be a hex encoded %s sum, got %s", alg, sum)
+		}
+	}
+
+	if (v.Signature == "") != (v.Key == "") {
+		return fmt.Errorf("verify signature and key must be set together")
+	}
+
+	if v.SignatureType != "" && v.SignatureType != SignatureTypeGPG && v.SignatureType != SignatureTypeCosign {
+		return fmt.Errorf("verify signature_type must be gpg or cosign, got %s", v.SignatureType)
+	}
+
+	return nil
+}
+
+// makeVerifyAbsolute makes the local paths of the signature and key absolute
+func makeVerifyAbsolute(v *Verify, file string) {
+	if v.Signature != "" && !IsRemotePath(v.Signature) {
+		v.Signature = ensureAbsolute(v.Signature, file)
+	}
+
+	if v.Key != "" && !IsRemotePath(v.Key) {
+		v.Key = ensureAbsolute(v.Key, file)
+	}
+}
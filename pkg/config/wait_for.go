@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WaitFor is a condition which must be met after a resource has been created
+// before the resources which depend on it are created, each block defines one
+// condition
+// example config:
+//
+//	tcp                = "localhost:5432"                  // can a TCP connection be made
+//	http               = "http://localhost:8200/v1/health" // does the URL return a success code
+//	http_success_codes = [200, 429]                        // status codes for http, default 200
+//	file               = "/data/ready"                     // does the file exist in the container
+//	log                = "database system is ready"        // does a line in the container logs match the regex
+//	container          = "container.db"                    // container for file and log, default the resource
+type WaitFor struct {
+	TCP              string `hcl:"tcp,optional" json:"tcp,omitempty"`
+	HTTP             string `hcl:"http,optional" json:"http,omitempty"`
+	HTTPSuccessCodes []int  `hcl:"http_success_codes,optional" json:"http_success_codes,omitempty" mapstructure:"http_success_codes"`
+	File             string `hcl:"file,optional" json:"file,omitempty"`
+	Log              string `hcl:"log,optional" json:"log,omitempty"`
+	Container        string `hcl:"container,optional" json:"container,omitempty"`
+
+	Timeout  string `hcl:"timeout,optional" json:"timeout,omitempty"`   // default 60s
+	Interval string `hcl:"interval,optional" json:"interval,omitempty"` // default 1s
+}
+
+// default timeout and polling interval for wait_for conditions
+const (
+	defaultWaitForTimeout  = 60 * time.Second
+	defaultWaitForInterval = 1 * time.Second
+)
+
+// String returns a description of the condition
+func (w WaitFor) String() string {
+	switch {
+	case w.TCP != "":
+		return fmt.Sprintf("tcp %s", w.TCP)
+	case w.HTTP != "":
+		return fmt.Sprintf("http %s", w.HTTP)
+	case w.File != "":
+		return fmt.Sprintf("file %s", w.File)
+	default:
+		return fmt.Sprintf("log '%s'", w.Log)
+	}
+}
+
+// TimeoutDuration returns the time to wait for the condition
+func (w WaitFor) TimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(w.Timeout)
+	if err != nil || w.Timeout == "" {
+		return defaultWaitForTimeout
+	}
+
+	return d
+}
+
+// IntervalDuration returns the time between checks of the condition
+func (w WaitFor) IntervalDuration() time.Duration {
+	d, err := time.ParseDuration(w.Interval)
+	if err != nil || w.Interval == "" {
+		return defaultWaitForInterval
+	}
+
+	return d
+}
+
+// SuccessCodes returns the HTTP status codes which meet the condition
+func (w WaitFor) SuccessCodes() []int {
+	if len(w.HTTPSuccessCodes) == 0 {
+		return []int{200}
+	}
+
+	return w.HTTPSuccessCodes
+}
+
+// ContainerResource returns the resource the file and log conditions are checked
+// in, when no container is set the condition is checked in the resource r
+func (w WaitFor) ContainerResource(r Resource) string {
+	if w.Container != "" {
+		return w.Container
+	}
+
+	return fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)
+}
+
+// Validate the condition for the resource r
+func (w WaitFor) Validate(r Resource) error {
+	set := 0
+	for _, v := range []string{w.TCP, w.HTTP, w.File, w.Log} {
+		if v != "" {
+			set++
+		}
+	}
+
+	if set != 1 {
+		return fmt.Errorf("wait_for must define exactly one of tcp, http, file, or log")
+	}
+
+	for n, v := range map[string]string{"timeout": w.Timeout, "interval": w.Interval} {
+		if v == "" {
+			continue
+		}
+
+		if _, err := time.ParseDuration(v); err != nil {
+			return fmt.Errorf("wait_for %s '%s' is not a valid duration, e.g. 30s", n, v)
+		}
+	}
+
+	if w.Log != "" {
+		if _, err := regexp.Compile(w.Log); err != nil {
+			return fmt.Errorf("wait_for log '%s' is not a valid regular expression: %s", w.Log, err)
+		}
+	}
+
+	if w.File == "" && w.Log == "" {
+		if w.Container != "" {
+			return fmt.Errorf("wait_for container can only be used with file or log")
+		}
+
+		return nil
+	}
+
+	switch ResourceType(strings.Split(w.ContainerResource(r), ".")[0]) {
+	case TypeContainer, TypeSidecar, TypeK8sCluster, TypeNomadCluster:
+		return nil
+	}
+
+	if w.Container == "" {
+		return fmt.Errorf("wait_for %s requires a container, resource %s.%s does not run a container", w.String(), r.Info().Type, r.Info().Name)
+	}
+
+	return fmt.Errorf("wait_for container '%s' must be a container, sidecar, k8s_cluster, or nomad_cluster", w.Container)
+}
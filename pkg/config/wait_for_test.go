@@ -0,0 +1,138 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForIsParsedForResources(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, waitForValid)
+
+	r, err := c.FindResource("container.db")
+	assert.NoError(t, err)
+
+	w := r.Info().WaitFor
+	assert.Len(t, w, 2)
+	assert.Equal(t, "database system is ready", w[0].Log)
+	assert.Equal(t, 2*time.Minute, w[0].TimeoutDuration())
+	assert.Equal(t, "localhost:5432", w[1].TCP)
+	assert.Equal(t, defaultWaitForTimeout, w[1].TimeoutDuration())
+	assert.Equal(t, defaultWaitForInterval, w[1].IntervalDuration())
+
+	r, err = c.FindResource("exec_remote.migrate")
+	assert.NoError(t, err)
+
+	w = r.Info().WaitFor
+	assert.Len(t, w, 2)
+	assert.Equal(t, "http://localhost:8080/health", w[0].HTTP)
+	assert.Equal(t, []int{200}, w[0].SuccessCodes())
+	assert.Equal(t, "/data/migrated", w[1].File)
+	assert.Equal(t, "container.db", w[1].ContainerResource(r))
+}
+
+func TestWaitForContainerAddsDependency(t *testing.T) {
+	c, _ := CreateConfigFromStrings(t, waitForValid)
+
+	r, err := c.FindResource("exec_remote.migrate")
+	assert.NoError(t, err)
+
+	assert.Contains(t, r.Info().DependsOn, "container.db")
+}
+
+func TestWaitForWithMultipleConditionsReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, waitForMultiple)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exactly one of tcp, http, file, or log")
+}
+
+func TestWaitForFileWithoutContainerReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, waitForFileNoContainer)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "resource network.cloud does not run a container")
+}
+
+func TestWaitForWithInvalidLogReturnsError(t *testing.T) {
+	dir := CreateTestFiles(t, waitForInvalidLog)
+
+	c := New()
+	err := ParseFolder(dir, c, false, "", false, []string{}, nil, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not a valid regular expression")
+}
+
+const waitForValid = `
+container "db" {
+	image {
+		name = "postgres:13"
+	}
+
+	wait_for {
+		log = "database system is ready"
+		timeout = "2m"
+	}
+
+	wait_for {
+		tcp = "localhost:5432"
+	}
+}
+
+exec_remote "migrate" {
+	image {
+		name = "migrate:latest"
+	}
+
+	cmd = "migrate"
+
+	wait_for {
+		http = "http://localhost:8080/health"
+	}
+
+	wait_for {
+		file = "/data/migrated"
+		container = "container.db"
+	}
+}
+`
+
+const waitForMultiple = `
+container "db" {
+	image {
+		name = "postgres:13"
+	}
+
+	wait_for {
+		tcp = "localhost:5432"
+		http = "http://localhost:5432"
+	}
+}
+`
+
+const waitForFileNoContainer = `
+network "cloud" {
+	subnet = "10.0.0.0/16"
+
+	wait_for {
+		file = "/tmp/ready"
+	}
+}
+`
+
+const waitForInvalidLog = `
+container "db" {
+	image {
+		name = "postgres:13"
+	}
+
+	wait_for {
+		log = "ready("
+	}
+}
+`
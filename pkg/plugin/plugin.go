@@ -0,0 +1,134 @@
+// Package plugin allows third parties to add resource types to Shipyard.
+//
+// A plugin is an executable named shipyard-plugin-<name> in $HOME/.shipyard/plugins.
+// Shipyard runs the executable with a command as the first argument and writes a
+// JSON encoded Request to stdin. Commands which return data write JSON to stdout,
+// errors are reported by exiting with a non zero exit code and writing the message
+// to stderr. Go plugins implement the Provider interface and call Serve from main:
+//
+//	func main() {
+//		os.Exit(plugin.Serve(&firecracker{}, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+//	}
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// ExecutablePrefix is the prefix of the file name for plugin executables
+const ExecutablePrefix = "shipyard-plugin-"
+
+// ProtocolVersion is the version of the protocol spoken by Shipyard, it is
+// incremented when a change is made which existing plugins do not support
+const ProtocolVersion = 1
+
+// Commands sent to the plugin as the first argument
+const (
+	// CommandSchema writes a Schema to stdout, no Request is sent
+	CommandSchema = "schema"
+	// CommandValidate checks the config for a resource when it is parsed
+	CommandValidate = "validate"
+	// CommandCreate creates the resource
+	CommandCreate = "create"
+	// CommandDestroy destroys the resource
+	CommandDestroy = "destroy"
+	// CommandLookup writes a LookupResponse to stdout
+	CommandLookup = "lookup"
+	// CommandLogs writes the logs for the resource to stdout
+	CommandLogs = "logs"
+)
+
+// Schema describes the plugin
+type Schema struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Types           []string `json:"types"` // resource types provided by the plugin e.g. firecracker_vm
+}
+
+// Request is written to stdin for all commands except schema
+type Request struct {
+	Resource *config.PluginResource `json:"resource"`
+
+	Follow bool   `json:"follow,omitempty"` // logs only, keep writing new lines until the process is stopped
+	Tail   string `json:"tail,omitempty"`   // logs only, number of lines from the end of the logs or all
+}
+
+// LookupResponse contains the ids of the objects created for the resource
+// e.g. VM or container ids
+type LookupResponse struct {
+	IDs []string `json:"ids"`
+}
+
+// Provider is implemented by Go plugins
+type Provider interface {
+	// Types returns the resource types provided by the plugin
+	Types() []string
+
+	// Validate checks the config for the resource, returning an error stops
+	// the blueprint from being parsed
+	Validate(r *config.PluginResource) error
+
+	Create(r *config.PluginResource) error
+	Destroy(r *config.PluginResource) error
+	Lookup(r *config.PluginResource) ([]string, error)
+
+	// Logs writes the logs for the resource to w, when follow is true Logs
+	// should write new lines until the process is stopped
+	Logs(r *config.PluginResource, follow bool, tail string, w io.Writer) error
+}
+
+// Serve handles a command from Shipyard using the provider and returns the
+// exit code for the plugin process
+func Serve(p Provider, args []string, in io.Reader, out, errOut io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(errOut, "This is a Shipyard plugin, it is run by Shipyard and can not be run directly")
+		return 1
+	}
+
+	err := serve(p, args[0], in, out)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+
+	return 0
+}
+
+func serve(p Provider, command string, in io.Reader, out io.Writer) error {
+	if command == CommandSchema {
+		return json.NewEncoder(out).Encode(Schema{ProtocolVersion: ProtocolVersion, Types: p.Types()})
+	}
+
+	req := Request{}
+	err := json.NewDecoder(in).Decode(&req)
+	if err != nil {
+		return fmt.Errorf("unable to read request: %s", err)
+	}
+
+	if req.Resource == nil {
+		return fmt.Errorf("request does not contain a resource")
+	}
+
+	switch command {
+	case CommandValidate:
+		return p.Validate(req.Resource)
+	case CommandCreate:
+		return p.Create(req.Resource)
+	case CommandDestroy:
+		return p.Destroy(req.Resource)
+	case CommandLookup:
+		ids, err := p.Lookup(req.Resource)
+		if err != nil {
+			return err
+		}
+
+		return json.NewEncoder(out).Encode(LookupResponse{IDs: ids})
+	case CommandLogs:
+		return p.Logs(req.Resource, req.Follow, req.Tail, out)
+	}
+
+	return fmt.Errorf("unknown command %s", command)
+}
@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+type testProvider struct {
+	created *config.PluginResource
+}
+
+func (p *testProvider) Types() []string { return []string{"firecracker_vm"} }
+
+func (p *testProvider) Validate(r *config.PluginResource) error {
+	if _, ok := r.Attributes["memory"]; !ok {
+		return fmt.Errorf("memory is required")
+	}
+
+	return nil
+}
+
+func (p *testProvider) Create(r *config.PluginResource) error {
+	p.created = r
+	return nil
+}
+
+func (p *testProvider) Destroy(r *config.PluginResource) error { return nil }
+
+func (p *testProvider) Lookup(r *config.PluginResource) ([]string, error) {
+	return []string{"vm-1"}, nil
+}
+
+func (p *testProvider) Logs(r *config.PluginResource, follow bool, tail string, w io.Writer) error {
+	fmt.Fprintf(w, "follow=%t tail=%s\n", follow, tail)
+	return nil
+}
+
+func serveTest(p Provider, command, request string) (int, string, string) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+
+	code := Serve(p, []string{command}, bytes.NewBufferString(request), out, errOut)
+
+	return code, out.String(), errOut.String()
+}
+
+const testRequest = `{"resource": {"name": "dev", "type": "firecracker_vm", "plugin": "firecracker", "attributes": {"memory": 1024}}, "follow": true, "tail": "10"}`
+
+func TestServeSchemaWritesTypes(t *testing.T) {
+	code, out, _ := serveTest(&testProvider{}, CommandSchema, "")
+
+	assert.Equal(t, 0, code)
+	assert.JSONEq(t, `{"protocol_version": 1, "types": ["firecracker_vm"]}`, out)
+}
+
+func TestServeCreateDecodesResource(t *testing.T) {
+	p := &testProvider{}
+	code, _, _ := serveTest(p, CommandCreate, testRequest)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "dev", p.created.Name)
+	assert.Equal(t, float64(1024), p.created.Attributes["memory"])
+}
+
+func TestServeValidateWritesErrorAndFails(t *testing.T) {
+	code, _, errOut := serveTest(&testProvider{}, CommandValidate, `{"resource": {"name": "dev", "type": "firecracker_vm"}}`)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "memory is required")
+}
+
+func TestServeLookupWritesIDs(t *testing.T) {
+	code, out, _ := serveTest(&testProvider{}, CommandLookup, testRequest)
+
+	assert.Equal(t, 0, code)
+	assert.JSONEq(t, `{"ids": ["vm-1"]}`, out)
+}
+
+func TestServeLogsPassesOptions(t *testing.T) {
+	code, out, _ := serveTest(&testProvider{}, CommandLogs, testRequest)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "follow=true tail=10\n", out)
+}
+
+func TestServeUnknownCommandFails(t *testing.T) {
+	code, _, errOut := serveTest(&testProvider{}, "upgrade", testRequest)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "unknown command")
+}
+
+func TestServeWithoutCommandFails(t *testing.T) {
+	code := Serve(&testProvider{}, []string{}, &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{})
+
+	assert.Equal(t, 1, code)
+}
@@ -0,0 +1,141 @@
+package providers
+
+import (
+	"os"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/connector/crypto"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// CertificateCA provider generates a root certificate authority
+type CertificateCA struct {
+	config *config.CertificateCA
+	log    hclog.Logger
+}
+
+// NewCertificateCA creates a new CertificateCA provider
+func NewCertificateCA(c *config.CertificateCA, l hclog.Logger) *CertificateCA {
+	return &CertificateCA{c, l}
+}
+
+// Create generates the CA certificate and private key
+func (c *CertificateCA) Create() error {
+	c.log.Info("Generating root certificate", "ref", c.config.Name, "output", c.config.Output)
+
+	err := os.MkdirAll(c.config.Output, os.ModePerm)
+	if err != nil {
+		return xerrors.Errorf("Unable to create output directory: %w", err)
+	}
+
+	k, err := crypto.GenerateKeyPair()
+	if err != nil {
+		return xerrors.Errorf("Unable to generate private key: %w", err)
+	}
+
+	ca, err := crypto.GenerateCA(k.Private)
+	if err != nil {
+		return xerrors.Errorf("Unable to generate root certificate: %w", err)
+	}
+
+	// the files are written read only and can not be overwritten
+	os.Remove(c.config.KeyPath)
+	err = k.Private.WriteFile(c.config.KeyPath)
+	if err != nil {
+		return err
+	}
+
+	os.Remove(c.config.CertPath)
+	return ca.WriteFile(c.config.CertPath)
+}
+
+// Destroy removes the certificate and private key
+func (c *CertificateCA) Destroy() error {
+	c.log.Info("Removing root certificate", "ref", c.config.Name)
+
+	os.Remove(c.config.CertPath)
+	os.Remove(c.config.KeyPath)
+
+	return nil
+}
+
+// Lookup statisfies the interface method but is not implemented by CertificateCA
+func (c *CertificateCA) Lookup() ([]string, error) {
+	return []string{}, nil
+}
+
+// CertificateLeaf provider generates a certificate signed by a CertificateCA
+type CertificateLeaf struct {
+	config *config.CertificateLeaf
+	log    hclog.Logger
+}
+
+// NewCertificateLeaf creates a new CertificateLeaf provider
+func NewCertificateLeaf(c *config.CertificateLeaf, l hclog.Logger) *CertificateLeaf {
+	return &CertificateLeaf{c, l}
+}
+
+// Create generates the certificate and private key
+func (c *CertificateLeaf) Create() error {
+	c.log.Info("Generating leaf certificate", "ref", c.config.Name, "ca", c.config.CA, "output", c.config.Output)
+
+	r, err := c.config.FindDependentResource(c.config.CA)
+	if err != nil {
+		return xerrors.Errorf("Unable to find root certificate: %w", err)
+	}
+
+	caConfig := r.(*config.CertificateCA)
+
+	rk := &crypto.PrivateKey{}
+	err = rk.ReadFile(caConfig.KeyPath)
+	if err != nil {
+		return xerrors.Errorf("Unable to read root key: %w", err)
+	}
+
+	ca := &crypto.X509{}
+	err = ca.ReadFile(caConfig.CertPath)
+	if err != nil {
+		return xerrors.Errorf("Unable to read root certificate: %w", err)
+	}
+
+	err = os.MkdirAll(c.config.Output, os.ModePerm)
+	if err != nil {
+		return xerrors.Errorf("Unable to create output directory: %w", err)
+	}
+
+	k, err := crypto.GenerateKeyPair()
+	if err != nil {
+		return xerrors.Errorf("Unable to generate private key: %w", err)
+	}
+
+	lc, err := crypto.GenerateLeaf(c.config.IPAddresses, c.config.DNSNames, ca, rk, k.Private)
+	if err != nil {
+		return xerrors.Errorf("Unable to generate leaf certificate: %w", err)
+	}
+
+	// the files are written read only and can not be overwritten
+	os.Remove(c.config.KeyPath)
+	err = k.Private.WriteFile(c.config.KeyPath)
+	if err != nil {
+		return err
+	}
+
+	os.Remove(c.config.CertPath)
+	return lc.WriteFile(c.config.CertPath)
+}
+
+// Destroy removes the certificate and private key
+func (c *CertificateLeaf) Destroy() error {
+	c.log.Info("Removing leaf certificate", "ref", c.config.Name)
+
+	os.Remove(c.config.CertPath)
+	os.Remove(c.config.KeyPath)
+
+	return nil
+}
+
+// Lookup statisfies the interface method but is not implemented by CertificateLeaf
+func (c *CertificateLeaf) Lookup() ([]string, error) {
+	return []string{}, nil
+}
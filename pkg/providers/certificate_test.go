@@ -0,0 +1,83 @@
+package providers
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/connector/crypto"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCertificateTests(t *testing.T) (*config.CertificateCA, *config.CertificateLeaf) {
+	dir := t.TempDir()
+
+	ca := config.NewCertificateCA("root")
+	ca.Output = filepath.Join(dir, "ca")
+	ca.SetPaths()
+
+	leaf := config.NewCertificateLeaf("web")
+	leaf.CA = "certificate_ca.root"
+	leaf.DNSNames = []string{"web.container.shipyard.run"}
+	leaf.IPAddresses = []string{"127.0.0.1"}
+	leaf.Output = filepath.Join(dir, "leaf")
+	leaf.SetPaths()
+
+	c := config.New()
+	c.AddResource(ca)
+	c.AddResource(leaf)
+
+	return ca, leaf
+}
+
+func TestCertificateCAAndLeafAreGenerated(t *testing.T) {
+	ca, leaf := setupCertificateTests(t)
+
+	err := NewCertificateCA(ca, hclog.NewNullLogger()).Create()
+	assert.NoError(t, err)
+	assert.FileExists(t, ca.KeyPath)
+
+	err = NewCertificateLeaf(leaf, hclog.NewNullLogger()).Create()
+	assert.NoError(t, err)
+	assert.FileExists(t, leaf.KeyPath)
+
+	root := &crypto.X509{}
+	err = root.ReadFile(ca.CertPath)
+	assert.NoError(t, err)
+	assert.True(t, root.IsCA)
+
+	lc := &crypto.X509{}
+	err = lc.ReadFile(leaf.CertPath)
+	assert.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(root.Certificate)
+
+	_, err = lc.Verify(x509.VerifyOptions{Roots: pool, DNSName: "web.container.shipyard.run"})
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", lc.IPAddresses[0].String())
+}
+
+func TestCertificateLeafWithoutCAReturnsError(t *testing.T) {
+	_, leaf := setupCertificateTests(t)
+
+	err := NewCertificateLeaf(leaf, hclog.NewNullLogger()).Create()
+	assert.Error(t, err)
+}
+
+func TestCertificateDestroyRemovesFiles(t *testing.T) {
+	ca, _ := setupCertificateTests(t)
+	os.MkdirAll(ca.Output, os.ModePerm)
+	ioutil.WriteFile(ca.CertPath, []byte("cert"), 0400)
+	ioutil.WriteFile(ca.KeyPath, []byte("key"), 0400)
+
+	err := NewCertificateCA(ca, hclog.NewNullLogger()).Destroy()
+	assert.NoError(t, err)
+
+	assert.NoFileExists(t, ca.CertPath)
+	assert.NoFileExists(t, ca.KeyPath)
+}
@@ -10,7 +10,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/semver"
@@ -26,6 +28,14 @@ import (
 const k3sBaseImage = "shipyardrun/k3s"
 const k3sBaseVersion = "v1.22.4"
 
+// k3sClusterSecret is the token agent nodes use to join the server
+const k3sClusterSecret = "mysupersecret"
+
+// k3sKubeConfig is the kubeconfig written by the k3s server
+const k3sKubeConfig = "/output/kubeconfig.yaml"
+
+// startTimeout is the maximum time for a cluster to start when no timeout
+// is set for the resource or in the user config
 var startTimeout = (300 * time.Second)
 
 // K8sCluster defines a provider which can create Kubernetes clusters
@@ -46,8 +56,10 @@ func NewK8sCluster(c *config.K8sCluster, cc clients.ContainerTasks, kc clients.K
 // Create implements interface method to create a cluster of the specified type
 func (c *K8sCluster) Create() error {
 	switch c.config.Driver {
-	case "k3s":
+	case config.K8sDriverK3s:
 		return c.createK3s()
+	case config.K8sDriverKind:
+		return c.createKind()
 	default:
 		return ErrorClusterDriverNotImplemented
 	}
@@ -56,8 +68,8 @@ func (c *K8sCluster) Create() error {
 // Destroy implements interface method to destroy a cluster
 func (c *K8sCluster) Destroy() error {
 	switch c.config.Driver {
-	case "k3s":
-		return c.destroyK3s()
+	case config.K8sDriverK3s, config.K8sDriverKind:
+		return c.destroyCluster()
 	default:
 		return ErrorClusterDriverNotImplemented
 	}
@@ -65,7 +77,21 @@ func (c *K8sCluster) Destroy() error {
 
 // Lookup the a clusters current state
 func (c *K8sCluster) Lookup() ([]string, error) {
-	return c.client.FindContainerIDs(fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
+	ids, err := c.client.FindContainerIDs(fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < c.config.WorkerNodes; i++ {
+		aids, err := c.client.FindContainerIDs(fmt.Sprintf("%d.agent.%s", i+1, c.config.Name), c.config.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, aids...)
+	}
+
+	return ids, nil
 }
 
 func (c *K8sCluster) createK3s() error {
@@ -92,7 +118,7 @@ func (c *K8sCluster) createK3s() error {
 	image := fmt.Sprintf("%s:%s", k3sBaseImage, c.config.Version)
 
 	// pull the container image
-	err = c.client.PullImage(config.Image{Name: image}, false)
+	err = c.client.PullImage(withPullPolicy(config.Image{Name: image}, "", c.config.Retry), false)
 	if err != nil {
 		return err
 	}
@@ -111,6 +137,9 @@ func (c *K8sCluster) createK3s() error {
 	cc.Image = &config.Image{Name: image}
 	cc.Networks = c.config.Networks
 	cc.Privileged = true // k3s must run Privlidged
+	cc.Ulimits = c.config.Ulimits
+	cc.Sysctls = c.config.Sysctls
+	cc.Resources = c.config.Resources
 
 	// set the volume mount for the images
 	cc.Volumes = []config.Volume{
@@ -126,12 +155,23 @@ func (c *K8sCluster) createK3s() error {
 		cc.Volumes = append(cc.Volumes, v)
 	}
 
+	// configure containerd to trust and mirror the registries
+	if len(c.config.Registries) > 0 {
+		rv, err := c.registriesVolume()
+		if err != nil {
+			return err
+		}
+
+		cc.Volumes = append(cc.Volumes, rv)
+	}
+
 	// Add any custom environment variables
 	cc.EnvVar = map[string]string{}
 
 	// set the environment variables for the K3S_KUBECONFIG_OUTPUT and K3S_CLUSTER_SECRET
-	cc.EnvVar["K3S_KUBECONFIG_OUTPUT"] = "/output/kubeconfig.yaml"
-	cc.EnvVar["K3S_CLUSTER_SECRET"] = "mysupersecret"
+	cc.EnvVar["K3S_KUBECONFIG_OUTPUT"] = k3sKubeConfig
+	cc.EnvVar["K3S_CLUSTER_SECRET"] = k3sClusterSecret
+	cc.EnvVar["K3S_TOKEN"] = k3sClusterSecret
 
 	// only add the variables for the cache when the kubernetes version is >= v1.18.16
 	sv, err := semver.NewConstraint(">= v1.18.16")
@@ -146,16 +186,14 @@ func (c *K8sCluster) createK3s() error {
 	}
 
 	if sv.Check(v) {
-		// load the CA from a file
-		ca, err := ioutil.ReadFile(filepath.Join(utils.CertsDir(""), "/root.cert"))
+		env, err := clusterProxyEnv()
 		if err != nil {
-			return fmt.Errorf("Unable to read root CA for proxy: %s", err)
+			return err
 		}
 
-		cc.EnvVar["HTTP_PROXY"] = utils.HTTPProxyAddress()
-		cc.EnvVar["HTTPS_PROXY"] = utils.HTTPSProxyAddress()
-		cc.EnvVar["NO_PROXY"] = utils.ProxyBypass
-		cc.EnvVar["PROXY_CA"] = string(ca)
+		for k, v := range env {
+			cc.EnvVar[k] = v
+		}
 	}
 
 	// add any custom environment variables
@@ -164,7 +202,10 @@ func (c *K8sCluster) createK3s() error {
 	}
 
 	// set the API server port to a random number
-	clusterConfig, _ := utils.GetClusterConfig(string(config.TypeK8sCluster) + "." + c.config.Name)
+	clusterConfig, _, err := utils.GetClusterConfig(string(config.TypeK8sCluster) + "." + c.config.Name)
+	if err != nil {
+		return err
+	}
 
 	// Set the default startup args
 	// Also set netfilter settings to fix behaviour introduced in Linux Kernel 5.12
@@ -176,25 +217,10 @@ func (c *K8sCluster) createK3s() error {
 		"--no-deploy=traefik",
 	}
 
-	// expose the API server and Connector ports
-	cc.Ports = []config.Port{
-		config.Port{
-			Local:    fmt.Sprintf("%d", clusterConfig.APIPort),
-			Host:     fmt.Sprintf("%d", clusterConfig.APIPort),
-			Protocol: "tcp",
-		},
-		config.Port{
-			Local:    fmt.Sprintf("%d", clusterConfig.ConnectorPort),
-			Host:     fmt.Sprintf("%d", clusterConfig.ConnectorPort),
-			Protocol: "tcp",
-		},
-		config.Port{
-			Local:    fmt.Sprintf("%d", clusterConfig.ConnectorPort+1),
-			Host:     fmt.Sprintf("%d", clusterConfig.ConnectorPort+1),
-			Protocol: "tcp",
-		},
-	}
+	args = append(args, k3sServerArgs(c.config.K3s)...)
 
+	// expose the API server and Connector ports
+	cc.Ports = serverPorts(clusterConfig)
 	cc.PortRanges = c.config.PortRanges
 	cc.Ports = append(cc.Ports, c.config.Ports...)
 
@@ -211,6 +237,12 @@ func (c *K8sCluster) createK3s() error {
 		return err
 	}
 
+	// create the agent nodes which join the server
+	agents, err := c.createAgentNodes(cc, clusterConfig.APIPort)
+	if err != nil {
+		return xerrors.Errorf("Unable to create agent nodes: %w", err)
+	}
+
 	// get the Kubernetes config file and drop it in a temp folder
 	kc, err := c.copyKubeConfig(id)
 	if err != nil {
@@ -243,7 +275,7 @@ func (c *K8sCluster) createK3s() error {
 	}
 
 	// ensure essential pods have started before announcing the resource is available
-	err = c.kubeClient.HealthCheckPods([]string{"app=local-path-provisioner", "k8s-app=kube-dns"}, startTimeout)
+	err = c.kubeClient.HealthCheckPods(k3sDefaultPods(c.config.K3s), resourceTimeout(c.config.Timeout, startTimeout))
 	if err != nil {
 		// fetch the logs from the container before exit
 		lr, lerr := c.client.ContainerLogs(id, true, true)
@@ -257,13 +289,11 @@ func (c *K8sCluster) createK3s() error {
 		return xerrors.Errorf("Error while waiting for Kubernetes default pods: %w", err)
 	}
 
-	// import the images to the servers container d instance
-	// importing images means that k3s does not need to pull from a remote docker hub
-	if c.config.Images != nil && len(c.config.Images) > 0 {
-		err := c.ImportLocalDockerImages(utils.ImageVolumeName, id, c.config.Images, false)
-		if err != nil {
-			return xerrors.Errorf("Error importing Docker images: %w", err)
-		}
+	// add the images to the server and agents containerd instances
+	// adding images means that k3s does not need to pull from a remote docker hub
+	err = c.injectImages(append([]string{id}, agents...))
+	if err != nil {
+		return xerrors.Errorf("Error importing Docker images: %w", err)
 	}
 
 	// start the connectorService
@@ -271,12 +301,206 @@ func (c *K8sCluster) createK3s() error {
 	return c.deployConnector(clusterConfig.ConnectorPort, clusterConfig.ConnectorPort+1)
 }
 
+// createAgentNodes creates the worker nodes for the cluster asynchronously, agents
+// share the volumes and resource settings of the server container
+func (c *K8sCluster) createAgentNodes(server *config.Container, apiPort int) ([]string, error) {
+	aMutex := sync.Mutex{}
+	ids := []string{}
+	aWait := sync.WaitGroup{}
+	aWait.Add(c.config.WorkerNodes)
+
+	var agentError error
+	for i := 0; i < c.config.WorkerNodes; i++ {
+		go func(i int) {
+			defer aWait.Done()
+
+			id, err := c.createAgentNode(i, server, apiPort)
+
+			aMutex.Lock()
+			defer aMutex.Unlock()
+
+			if err != nil {
+				agentError = err
+				return
+			}
+
+			ids = append(ids, id)
+		}(i + 1)
+	}
+
+	aWait.Wait()
+
+	return ids, agentError
+}
+
+func (c *K8sCluster) createAgentNode(index int, server *config.Container, apiPort int) (string, error) {
+	cc := config.NewContainer(fmt.Sprintf("%d.agent.%s", index, c.config.Name))
+	c.config.ResourceInfo.AddChild(cc)
+
+	cc.Image = server.Image
+	cc.Networks = server.Networks
+	cc.Privileged = true // k3s must run Privlidged
+	cc.Ulimits = server.Ulimits
+	cc.Sysctls = server.Sysctls
+	cc.Resources = server.Resources
+	cc.Volumes = server.Volumes
+
+	// agents use the same proxy and custom environment as the server
+	cc.EnvVar = map[string]string{}
+	for k, v := range server.EnvVar {
+		cc.EnvVar[k] = v
+	}
+
+	delete(cc.EnvVar, "K3S_KUBECONFIG_OUTPUT")
+	delete(cc.EnvVar, "K3S_CLUSTER_SECRET")
+
+	cc.EnvVar["K3S_URL"] = fmt.Sprintf("https://server.%s:%d", utils.FQDN(c.config.Name, string(c.config.Type)), apiPort)
+	cc.EnvVar["K3S_TOKEN"] = k3sClusterSecret
+
+	cc.Command = []string{
+		"agent",
+		"--kube-proxy-arg=conntrack-max-per-core=0",
+	}
+
+	cc.Command = append(cc.Command, k3sAgentArgs(c.config.K3s)...)
+
+	c.log.Debug("Creating agent node", "ref", cc.Name)
+
+	id, err := c.client.CreateContainer(cc)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.waitForStart(id)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// k3sServerArgs returns the arguments for the k3s server from the custom config
+func k3sServerArgs(k *config.K3sConfig) []string {
+	if k == nil {
+		return nil
+	}
+
+	args := []string{}
+	for _, d := range k.Disable {
+		// traefik is always disabled
+		if d != "traefik" {
+			args = append(args, fmt.Sprintf("--disable=%s", d))
+		}
+	}
+
+	// disable flannel and the network policy controller so that an alternate CNI can be installed
+	if k.CNI == config.CNINone {
+		args = append(args, "--flannel-backend=none", "--disable-network-policy")
+	}
+
+	if fg := featureGates(k.FeatureGates); fg != "" {
+		args = append(args,
+			fmt.Sprintf("--kube-apiserver-arg=feature-gates=%s", fg),
+			fmt.Sprintf("--kube-controller-manager-arg=feature-gates=%s", fg),
+			fmt.Sprintf("--kube-scheduler-arg=feature-gates=%s", fg),
+			fmt.Sprintf("--kubelet-arg=feature-gates=%s", fg),
+			fmt.Sprintf("--kube-proxy-arg=feature-gates=%s", fg),
+		)
+	}
+
+	return append(args, k.ServerArgs...)
+}
+
+// k3sAgentArgs returns the arguments for the k3s agents from the custom config
+func k3sAgentArgs(k *config.K3sConfig) []string {
+	if k == nil {
+		return nil
+	}
+
+	args := []string{}
+	if fg := featureGates(k.FeatureGates); fg != "" {
+		args = append(args,
+			fmt.Sprintf("--kubelet-arg=feature-gates=%s", fg),
+			fmt.Sprintf("--kube-proxy-arg=feature-gates=%s", fg),
+		)
+	}
+
+	return append(args, k.AgentArgs...)
+}
+
+// k3sDefaultPods returns the selectors for the packaged pods which must be running
+// before the cluster is available, no pods are scheduled until a CNI is installed
+// when flannel is disabled
+func k3sDefaultPods(k *config.K3sConfig) []string {
+	if k != nil && k.CNI == config.CNINone {
+		return []string{}
+	}
+
+	pods := []string{}
+	if !k.Disabled("local-storage") {
+		pods = append(pods, "app=local-path-provisioner")
+	}
+
+	if !k.Disabled("coredns") {
+		pods = append(pods, "k8s-app=kube-dns")
+	}
+
+	return pods
+}
+
+// featureGates returns the feature gates formatted for the Kubernetes components
+// e.g. EphemeralContainers=true,TTLAfterFinished=false
+func featureGates(gates map[string]bool) string {
+	keys := []string{}
+	for k := range gates {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	fg := []string{}
+	for _, k := range keys {
+		fg = append(fg, fmt.Sprintf("%s=%t", k, gates[k]))
+	}
+
+	return strings.Join(fg, ",")
+}
+
+// serverPorts returns the API server and Connector ports which are exposed
+// on the server node
+func serverPorts(cc utils.ClusterConfig) []config.Port {
+	return []config.Port{
+		config.Port{
+			Local:    fmt.Sprintf("%d", cc.APIPort),
+			Host:     fmt.Sprintf("%d", cc.APIPort),
+			Protocol: "tcp",
+		},
+		config.Port{
+			Local:    fmt.Sprintf("%d", cc.ConnectorPort),
+			Host:     fmt.Sprintf("%d", cc.ConnectorPort),
+			Protocol: "tcp",
+		},
+		config.Port{
+			Local:    fmt.Sprintf("%d", cc.ConnectorPort+1),
+			Host:     fmt.Sprintf("%d", cc.ConnectorPort+1),
+			Protocol: "tcp",
+		},
+	}
+}
+
 func (c *K8sCluster) waitForStart(id string) error {
+	return c.waitForLog(id, "Running kubelet")
+}
+
+// waitForLog blocks until the container logs contain the message
+// or the start timeout is exceeded
+func (c *K8sCluster) waitForLog(id, message string) error {
 	start := time.Now()
+	timeout := resourceTimeout(c.config.Timeout, startTimeout)
 
 	for {
 		// not running after timeout exceeded? Rollback and delete everything.
-		if startTimeout != 0 && time.Now().After(start.Add(startTimeout)) {
+		if timeout != 0 && time.Now().After(start.Add(timeout)) {
 			//deleteCluster()
 			return errors.New("Cluster creation exceeded specified timeout")
 		}
@@ -293,7 +517,7 @@ func (c *K8sCluster) waitForStart(id string) error {
 		nRead, _ := buf.ReadFrom(out)
 		out.Close()
 		output := buf.String()
-		if nRead > 0 && strings.Contains(string(output), "Running kubelet") {
+		if nRead > 0 && strings.Contains(string(output), message) {
 			break
 		}
 
@@ -306,10 +530,13 @@ func (c *K8sCluster) waitForStart(id string) error {
 
 func (c *K8sCluster) copyKubeConfig(id string) (string, error) {
 	// create destination kubeconfig file paths
-	_, kubePath, _ := utils.CreateKubeConfigPath(c.config.Name)
+	_, kubePath, _, err := utils.CreateKubeConfigPath(c.config.Name)
+	if err != nil {
+		return "", err
+	}
 
 	// get kubeconfig file from container and read contents
-	err := c.client.CopyFromContainer(id, "/output/kubeconfig.yaml", kubePath)
+	err = c.client.CopyFromContainer(id, k3sKubeConfig, kubePath)
 	if err != nil {
 		return "", err
 	}
@@ -319,9 +546,12 @@ func (c *K8sCluster) copyKubeConfig(id string) (string, error) {
 
 func (c *K8sCluster) createLocalKubeConfig(kubeconfig string) (string, error) {
 	ip := utils.GetDockerIP()
-	_, kubePath, _ := utils.CreateKubeConfigPath(c.config.Name)
+	_, kubePath, _, err := utils.CreateKubeConfigPath(c.config.Name)
+	if err != nil {
+		return "", err
+	}
 
-	err := c.changeServerAddressInK8sConfig(
+	err = c.changeServerAddressInK8sConfig(
 		fmt.Sprintf("https://%s", ip),
 		kubeconfig,
 		kubePath,
@@ -334,7 +564,10 @@ func (c *K8sCluster) createLocalKubeConfig(kubeconfig string) (string, error) {
 }
 
 func (c *K8sCluster) createDockerKubeConfig(kubeconfig string) error {
-	_, _, dockerPath := utils.CreateKubeConfigPath(c.config.Name)
+	_, _, dockerPath, err := utils.CreateKubeConfigPath(c.config.Name)
+	if err != nil {
+		return err
+	}
 
 	return c.changeServerAddressInK8sConfig(
 		fmt.Sprintf("https://server.%s", utils.FQDN(c.config.Name, string(c.config.Type))),
@@ -460,6 +693,42 @@ func (c *K8sCluster) deployConnector(grpcPort, httpPort int) error {
 	return nil
 }
 
+// injectImages adds the clusters images to each of the nodes using the clusters
+// image strategies, the next strategy is used when images can not be added
+func (c *K8sCluster) injectImages(nodes []string) error {
+	images := c.config.ClusterImages()
+	if len(images) == 0 {
+		return nil
+	}
+
+	return injectImages(c.log, c.config.Name, c.config.ImageStrategies(), images, map[string]imageInjector{
+		config.ImageStrategyLoad: func(images []config.Image) ([]config.Image, error) {
+			// nodes share the images volume, import the cached images to each nodes containerd instance
+			for _, n := range nodes {
+				err := c.ImportLocalDockerImages(utils.ImageVolumeName, n, images, false)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return nil, nil
+		},
+		config.ImageStrategyCache: func(images []config.Image) ([]config.Image, error) {
+			return pullImagesInNodes(c.client, c.log, nodes, images, func(image string) []string {
+				return []string{"crictl", "pull", image}
+			})
+		},
+		config.ImageStrategyRegistry: func(images []config.Image) ([]config.Image, error) {
+			reg, err := imageRegistry(&c.config.ResourceInfo, c.config.Registries)
+			if err != nil {
+				return nil, err
+			}
+
+			return pushImagesToRegistry(c.client, c.log, reg, images, nil)
+		},
+	})
+}
+
 // ImportLocalDockerImages fetches Docker images stored on the local client and imports them into the cluster
 func (c *K8sCluster) ImportLocalDockerImages(name string, id string, images []config.Image, force bool) error {
 	imgs := []string{}
@@ -470,7 +739,7 @@ func (c *K8sCluster) ImportLocalDockerImages(name string, id string, images []co
 			continue
 		}
 
-		err := c.client.PullImage(i, false)
+		err := c.client.PullImage(withPullPolicy(i, "", c.config.Retry), false)
 		if err != nil {
 			return err
 		}
@@ -488,7 +757,7 @@ func (c *K8sCluster) ImportLocalDockerImages(name string, id string, images []co
 	for _, i := range imagesFile {
 		// execute the command to import the image
 		// write any command output to the logger
-		err = c.client.ExecuteCommand(id, []string{"ctr", "image", "import", i}, nil, "/", "", "", c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
+		err = c.client.ExecuteCommand(id, []string{"ctr", "-n", c.config.Namespace(), "image", "import", i}, nil, "/", "", "", c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
 		if err != nil {
 			return err
 		}
@@ -497,7 +766,35 @@ func (c *K8sCluster) ImportLocalDockerImages(name string, id string, images []co
 	return nil
 }
 
-func (c *K8sCluster) destroyK3s() error {
+// PushLocalDockerImages streams Docker images stored on the local client directly into the
+// clusters containerd namespace without caching them in the images volume
+func (c *K8sCluster) PushLocalDockerImages(id string, images []config.Image) error {
+	imgs := []string{}
+
+	for _, i := range images {
+		// do nothing when the image name is empty
+		if i.Name == "" {
+			continue
+		}
+
+		err := c.client.PullImage(withPullPolicy(i, "", c.config.Retry), false)
+		if err != nil {
+			return err
+		}
+
+		imgs = append(imgs, i.Name)
+	}
+
+	if len(imgs) == 0 {
+		return nil
+	}
+
+	return c.client.ImportImagesToContainerd(id, imgs, c.config.Namespace(), c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
+}
+
+// destroyCluster removes the server and agent nodes, the node containers
+// have the same names for all drivers
+func (c *K8sCluster) destroyCluster() error {
 	c.log.Info("Destroy Cluster", "ref", c.config.Name)
 
 	ids, err := c.client.FindContainerIDs(fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
@@ -505,6 +802,16 @@ func (c *K8sCluster) destroyK3s() error {
 		return err
 	}
 
+	// destroy the agents
+	for i := 0; i < c.config.WorkerNodes; i++ {
+		aids, err := c.client.FindContainerIDs(fmt.Sprintf("%d.agent.%s", i+1, c.config.Name), c.config.Type)
+		if err != nil {
+			return err
+		}
+
+		ids = append(ids, aids...)
+	}
+
 	for _, i := range ids {
 		err := c.client.RemoveContainer(i, false)
 		if err != nil {
@@ -512,7 +819,11 @@ func (c *K8sCluster) destroyK3s() error {
 		}
 	}
 
-	_, path := utils.GetClusterConfig(string(c.config.Type) + "." + c.config.Name)
+	_, path, err := utils.GetClusterConfig(string(c.config.Type) + "." + c.config.Name)
+	if err != nil {
+		return err
+	}
+
 	os.RemoveAll(path)
 
 	return nil
@@ -684,3 +995,38 @@ metadata:
   name: connector-certs
   namespace: shipyard
 `
+
+// registriesVolume writes the k3s registries.yaml for the clusters registries
+// and returns a volume which mounts the file into the server
+func (c *K8sCluster) registriesVolume() (config.Volume, error) {
+	registries, err := clusterRegistries(&c.config.ResourceInfo, c.config.Registries)
+	if err != nil {
+		return config.Volume{}, err
+	}
+
+	// images pushed to the registry are pulled using their original
+	// name so the registry must mirror the domains of the images
+	domains := []string{}
+	if hasImageStrategy(c.config.ImageStrategies(), config.ImageStrategyRegistry) {
+		domains = imageDomains(c.config.ClusterImages())
+	}
+
+	rc, err := k3sRegistriesConfig(registries, domains)
+	if err != nil {
+		return config.Volume{}, xerrors.Errorf("Unable to create registries config: %w", err)
+	}
+
+	_, configDir, err := utils.GetClusterConfig(string(config.TypeK8sCluster) + "." + c.config.Name)
+	if err != nil {
+		return config.Volume{}, err
+	}
+
+	path := filepath.Join(configDir, "registries.yaml")
+
+	err = ioutil.WriteFile(path, rc, os.ModePerm)
+	if err != nil {
+		return config.Volume{}, xerrors.Errorf("Unable to write registries config: %w", err)
+	}
+
+	return config.Volume{Source: path, Destination: "/etc/rancher/k3s/registries.yaml", Type: "bind", ReadOnly: true}, nil
+}
@@ -37,6 +37,7 @@ func setupClusterMocks(t *testing.T) (
 	md.On("CopyFromContainer", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	md.On("CopyLocalDockerImagesToVolume", mock.Anything, mock.Anything, mock.Anything).Return([]string{"/images/file.tar.gz"}, nil)
 	md.On("ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	md.On("ImportImagesToContainerd", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	md.On("RemoveContainer", mock.Anything, mock.Anything).Return(nil)
 	md.On("RemoveVolume", mock.Anything).Return(nil)
 	md.On("DetachNetwork", mock.Anything, mock.Anything, mock.Anything).Return(nil)
@@ -56,7 +57,7 @@ func setupClusterMocks(t *testing.T) (
 	cf.Close()
 
 	// write the kubeconfig
-	_, kubePath, _ := utils.CreateKubeConfigPath(clusterConfig.Name)
+	_, kubePath, _, _ := utils.CreateKubeConfigPath(clusterConfig.Name)
 	kcf, err := os.Create(kubePath)
 	if err != nil {
 		panic(err)
@@ -96,6 +97,19 @@ func setupClusterMocks(t *testing.T) (
 	return cc, md, mk, mc
 }
 
+// setupNodeLogs returns a new log reader for each node as the
+// reader is consumed when waiting for the node to start
+func setupNodeLogs(md *mocks.MockContainerTasks, nodes int) {
+	removeOn(&md.Mock, "ContainerLogs")
+
+	for i := 0; i < nodes; i++ {
+		md.On("ContainerLogs", mock.Anything, true, true).Return(
+			ioutil.NopCloser(bytes.NewBufferString("Running kubelet")),
+			nil,
+		).Once()
+	}
+}
+
 func TestClusterK3ErrorsWhenUnableToLookupIDs(t *testing.T) {
 	md := &mocks.MockContainerTasks{}
 	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
@@ -265,6 +279,212 @@ func TestClusterK3CreatesAServerWithAdditionalPorts(t *testing.T) {
 	assert.True(t, params.PortRanges[0].EnableHost)
 }
 
+func TestClusterK3CreatesAServerWithUlimitsAndSysctls(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+
+	cc.Ulimits = []config.Ulimit{{Name: "nofile", Soft: 1024, Hard: 2048}}
+	cc.Sysctls = map[string]string{"vm.max_map_count": "262144"}
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.Equal(t, cc.Ulimits, params.Ulimits)
+	assert.Equal(t, cc.Sysctls, params.Sysctls)
+}
+
+func TestClusterK3CreatesAServerWithResources(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.Resources = &config.Resources{CPU: "2000m", Memory: "2Gi"}
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.Equal(t, cc.Resources, params.Resources)
+}
+
+func TestClusterK3CreatesAServerWithK3sConfig(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.K3s = &config.K3sConfig{
+		ServerArgs:   []string{"--kube-apiserver-arg=v=2"},
+		FeatureGates: map[string]bool{"TTLAfterFinished": false, "EphemeralContainers": true},
+		Disable:      []string{"traefik", "servicelb"},
+		CNI:          config.CNINone,
+	}
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.Equal(t, []string{
+		"--disable=servicelb",
+		"--flannel-backend=none",
+		"--disable-network-policy",
+		"--kube-apiserver-arg=feature-gates=EphemeralContainers=true,TTLAfterFinished=false",
+		"--kube-controller-manager-arg=feature-gates=EphemeralContainers=true,TTLAfterFinished=false",
+		"--kube-scheduler-arg=feature-gates=EphemeralContainers=true,TTLAfterFinished=false",
+		"--kubelet-arg=feature-gates=EphemeralContainers=true,TTLAfterFinished=false",
+		"--kube-proxy-arg=feature-gates=EphemeralContainers=true,TTLAfterFinished=false",
+		"--kube-apiserver-arg=v=2",
+	}, params.Command[4:])
+}
+
+func TestClusterK3CreatesAgentNodesWithK3sConfig(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.WorkerNodes = 1
+	cc.K3s = &config.K3sConfig{
+		AgentArgs:    []string{"--node-label=tier=worker"},
+		FeatureGates: map[string]bool{"EphemeralContainers": true},
+	}
+
+	setupNodeLogs(md, 2)
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[1].Arguments[0].(*config.Container)
+
+	assert.Equal(t, []string{
+		"--kubelet-arg=feature-gates=EphemeralContainers=true",
+		"--kube-proxy-arg=feature-gates=EphemeralContainers=true",
+		"--node-label=tier=worker",
+	}, params.Command[2:])
+}
+
+func TestClusterK3sWaitsForEnabledPods(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.K3s = &config.K3sConfig{Disable: []string{"local-storage"}}
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "HealthCheckPods", []string{"k8s-app=kube-dns"}, startTimeout)
+}
+
+func TestClusterK3sWaitsForPodsWithResourceTimeout(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.Timeout = "10m"
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "HealthCheckPods", mock.Anything, 10*time.Minute)
+}
+
+func TestClusterK3sDoesNotWaitForPodsWithoutCNI(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.K3s = &config.K3sConfig{CNI: config.CNINone}
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "HealthCheckPods", []string{}, startTimeout)
+}
+
+func TestClusterK3CreatesAgentNodes(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.WorkerNodes = 2
+
+	setupNodeLogs(md, 3)
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertNumberOfCalls(t, "CreateContainer", 3)
+}
+
+func TestClusterK3CreatesAgentNodesWithCorrectDetails(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.WorkerNodes = 1
+	cc.Volumes = []config.Volume{config.Volume{Source: "./files", Destination: "/files"}}
+	cc.EnvVar = map[string]string{"FOO": "bar"}
+
+	setupNodeLogs(md, 2)
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	server := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	params := getCalls(&md.Mock, "CreateContainer")[1].Arguments[0].(*config.Container)
+
+	// validate the basic details for the agent container
+	assert.Equal(t, "1.agent.test", params.Name)
+	assert.Equal(t, server.Image.Name, params.Image.Name)
+	assert.Equal(t, clusterNetwork.Name, params.Networks[0].Name)
+	assert.True(t, params.Privileged)
+
+	// validate the agent shares the server volumes
+	assert.Equal(t, "123", params.Volumes[0].Source)
+	assert.Equal(t, "/cache", params.Volumes[0].Destination)
+	assert.Equal(t, "./files", params.Volumes[1].Source)
+
+	// validate the agent joins the server
+	assert.Equal(t, "agent", params.Command[0])
+	assert.Equal(t, fmt.Sprintf("https://server.test.k8s-cluster.shipyard.run:%s", server.Ports[0].Local), params.EnvVar["K3S_URL"])
+	assert.Equal(t, server.EnvVar["K3S_TOKEN"], params.EnvVar["K3S_TOKEN"])
+	assert.Equal(t, "bar", params.EnvVar["FOO"])
+	assert.NotContains(t, params.EnvVar, "K3S_KUBECONFIG_OUTPUT")
+
+	// agents are not exposed on the host
+	assert.Empty(t, params.Ports)
+}
+
+func TestClusterK3ErrorsWhenUnableToCreateAgentNode(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.WorkerNodes = 1
+
+	removeOn(&md.Mock, "CreateContainer")
+	md.On("CreateContainer", mock.MatchedBy(func(c *config.Container) bool { return c.Name == "server.test" })).Return("containerid", nil)
+	md.On("CreateContainer", mock.Anything).Return("", fmt.Errorf("boom"))
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to create agent nodes")
+}
+
+func TestClusterK3sImportDockerImagesToAgentNodes(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.WorkerNodes = 2
+
+	removeOn(&md.Mock, "CreateContainer")
+	md.On("CreateContainer", mock.MatchedBy(func(c *config.Container) bool { return c.Name == "server.test" })).Return("server", nil)
+	md.On("CreateContainer", mock.Anything).Return("agent", nil)
+
+	setupNodeLogs(md, 3)
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ExecuteCommand", "server", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	md.AssertNumberOfCalls(t, "ExecuteCommand", 3)
+}
+
 func TestClusterK3sErrorsIfServerNOTStart(t *testing.T) {
 	cc, md, mk, mc := setupClusterMocks(t)
 
@@ -283,7 +503,7 @@ func TestClusterK3sErrorsIfServerNOTStart(t *testing.T) {
 
 func TestClusterK3sDownloadsConfig(t *testing.T) {
 	cc, md, mk, mc := setupClusterMocks(t)
-	_, kubePath, _ := utils.CreateKubeConfigPath(cc.Name)
+	_, kubePath, _, _ := utils.CreateKubeConfigPath(cc.Name)
 
 	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
 
@@ -326,7 +546,7 @@ func TestClusterK3sSetsServerInConfig(t *testing.T) {
 	// check the kubeconfig file for docker uses a network ip not localhost
 
 	// check file has been written
-	_, kubePath, _ := utils.CreateKubeConfigPath(clusterConfig.Name)
+	_, kubePath, _, _ := utils.CreateKubeConfigPath(clusterConfig.Name)
 	f, err := os.Open(kubePath)
 	assert.NoError(t, err)
 	defer f.Close()
@@ -348,7 +568,7 @@ func TestClusterK3sCreatesDockerConfig(t *testing.T) {
 	// check the kubeconfig file for docker uses a network ip not localhost
 
 	// check file has been written
-	_, _, dockerPath := utils.CreateKubeConfigPath(clusterConfig.Name)
+	_, _, dockerPath, _ := utils.CreateKubeConfigPath(clusterConfig.Name)
 	f, err := os.Open(dockerPath)
 	assert.NoError(t, err)
 	defer f.Close()
@@ -454,6 +674,17 @@ func TestClusterK3sImportDockerCopiesImages(t *testing.T) {
 	md.AssertCalled(t, "CopyLocalDockerImagesToVolume", []string{"consul:1.6.1", "vault:1.6.1"}, utils.FQDNVolumeName(utils.ImageVolumeName), false)
 }
 
+func TestClusterK3sImportDockerCopiesCopyImages(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.CopyImages = []string{"myapp:dev"}
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+	md.AssertCalled(t, "CopyLocalDockerImagesToVolume", []string{"consul:1.6.1", "vault:1.6.1", "myapp:dev"}, utils.FQDNVolumeName(utils.ImageVolumeName), false)
+}
+
 func TestClusterK3sImportDockerCopyImageFailReturnsError(t *testing.T) {
 	cc, md, mk, mc := setupClusterMocks(t)
 	removeOn(&md.Mock, "CopyLocalDockerImagesToVolume")
@@ -475,6 +706,40 @@ func TestClusterK3sImportDockerRunsExecCommand(t *testing.T) {
 	md.AssertCalled(t, "ExecuteCommand", "containerid", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
+func TestClusterK3sImportDockerUsesContainerdNamespace(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.ContainerdNamespace = "shipyard"
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ExecuteCommand")[0].Arguments
+	assert.Equal(t, []string{"ctr", "-n", "shipyard", "image", "import", "/images/file.tar.gz"}, params[1])
+}
+
+func TestClusterK3sPushLocalDockerImagesImportsToContainerd(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+	err := p.PushLocalDockerImages("containerid", []config.Image{{Name: "consul:1.6.1"}, {Name: ""}})
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "PullImage", config.Image{Name: "consul:1.6.1"}, false)
+	md.AssertCalled(t, "ImportImagesToContainerd", "containerid", []string{"consul:1.6.1"}, config.DefaultContainerdNamespace, mock.Anything)
+	md.AssertNotCalled(t, "CopyLocalDockerImagesToVolume", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestClusterK3sPushLocalDockerImagesImportFailReturnsError(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	removeOn(&md.Mock, "ImportImagesToContainerd")
+	md.On("ImportImagesToContainerd", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+	err := p.PushLocalDockerImages("containerid", []config.Image{{Name: "consul:1.6.1"}})
+	assert.Error(t, err)
+}
+
 func TestClusterK3sImportDockerExecFailReturnsError(t *testing.T) {
 	cc, md, mk, mc := setupClusterMocks(t)
 	removeOn(&md.Mock, "ExecuteCommand")
@@ -606,7 +871,7 @@ func TestClusterK3sDestroyRemovesConfig(t *testing.T) {
 	removeOn(&md.Mock, "FindContainerIDs")
 	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"found"}, nil)
 
-	_, dir := utils.GetClusterConfig(string(cc.Info().Type) + "." + cc.Info().Name)
+	_, dir, _ := utils.GetClusterConfig(string(cc.Info().Type) + "." + cc.Info().Name)
 
 	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
 
@@ -617,6 +882,33 @@ func TestClusterK3sDestroyRemovesConfig(t *testing.T) {
 	assert.NoDirExists(t, dir)
 }
 
+func TestClusterK3sDestroyRemovesAgentNodes(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.WorkerNodes = 2
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+	md.AssertCalled(t, "FindContainerIDs", "1.agent."+clusterConfig.Name, clusterConfig.Type)
+	md.AssertCalled(t, "FindContainerIDs", "2.agent."+clusterConfig.Name, clusterConfig.Type)
+}
+
+func TestLookupReturnsAgentIDs(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.WorkerNodes = 1
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+	removeOn(&md.Mock, "FindContainerIDs")
+	md.On("FindContainerIDs", "server."+clusterConfig.Name, mock.Anything).Return([]string{"server"}, nil)
+	md.On("FindContainerIDs", "1.agent."+clusterConfig.Name, mock.Anything).Return([]string{"agent"}, nil)
+
+	ids, err := p.Lookup()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"server", "agent"}, ids)
+}
+
 func TestLookupReturnsIDs(t *testing.T) {
 	cc, md, mk, mc := setupClusterMocks(t)
 	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
@@ -653,3 +945,72 @@ dRRQpBd0lDcERBUEJnTlZIUk1CQWY4RUJUQURBUUgvTUFvR0NDcUdTTTQ5QkFNQ0EwY0FNRVFDSUhFYl
 K3lkNVNQOEUKUmQ4OGxRWW9oRnV2enc9PQotLS0tLUVORCBDRVJUSUZJQ0FURS0tLS0tCg==
     server: https://127.0.0.1:64674
 `
+
+func TestClusterK3sMountsRegistriesConfig(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+
+	reg := config.NewRegistry("local")
+	cc.Config.AddResource(reg)
+	cc.Registries = []string{"registry.local"}
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	v := params.Volumes[len(params.Volumes)-1]
+	assert.Equal(t, "/etc/rancher/k3s/registries.yaml", v.Destination)
+
+	d, err := ioutil.ReadFile(v.Source)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), "http://local.registry.shipyard.run:5000")
+}
+
+func TestClusterK3sWithUnknownRegistryReturnsError(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.Registries = []string{"registry.missing"}
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+}
+
+func TestClusterK3sRegistryImageStrategyPushesImagesAndAddsMirrors(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	md.On("PushImage", mock.Anything, mock.Anything).Return(nil)
+
+	reg := config.NewRegistry("local")
+	reg.Port = 5001
+	cc.Config.AddResource(reg)
+	cc.Registries = []string{"registry.local"}
+	cc.ImageStrategy = []string{config.ImageStrategyRegistry, config.ImageStrategyLoad}
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "PushImage", "consul:1.6.1", config.Image{Name: "localhost:5001/library/consul:1.6.1"})
+	md.AssertNotCalled(t, "CopyLocalDockerImagesToVolume", mock.Anything, mock.Anything, mock.Anything)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	d, err := ioutil.ReadFile(params.Volumes[len(params.Volumes)-1].Source)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), "docker.io")
+}
+
+func TestClusterK3sCacheImageStrategyPullsImagesInNodes(t *testing.T) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.ImageStrategy = []string{config.ImageStrategyCache}
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ExecuteCommand", mock.Anything, []string{"crictl", "pull", "consul:1.6.1"}, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	md.AssertNotCalled(t, "CopyLocalDockerImagesToVolume", mock.Anything, mock.Anything, mock.Anything)
+}
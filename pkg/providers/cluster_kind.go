@@ -0,0 +1,394 @@
+package providers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+const kindBaseImage = "kindest/node"
+const kindBaseVersion = "v1.24.0"
+
+// kindBootstrapToken is the kubeadm token worker nodes use to join the server
+const kindBootstrapToken = "abcdef.0123456789abcdef"
+
+// kindKubeConfig is the admin kubeconfig written by kubeadm
+const kindKubeConfig = "/etc/kubernetes/admin.conf"
+
+// kindDefaultPods are the pods which must be running before the cluster is available
+var kindDefaultPods = []string{"app=kindnet", "k8s-app=kube-dns"}
+
+// createKind creates a cluster using the kind node images, the nodes are bootstrapped
+// with kubeadm so that the cluster behaves the same as an upstream Kubernetes cluster
+func (c *K8sCluster) createKind() error {
+	// create a named log
+	c.log = c.log.Named(c.config.Name)
+
+	c.log.Info("Creating Cluster", "ref", c.config.Name, "driver", config.K8sDriverKind)
+
+	// check the cluster does not already exist
+	ids, err := c.client.FindContainerIDs(fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		return ErrorClusterExists
+	}
+
+	if c.config.Version == "" {
+		c.config.Version = kindBaseVersion
+	}
+
+	image := fmt.Sprintf("%s:%s", kindBaseImage, c.config.Version)
+
+	err = c.client.PullImage(withPullPolicy(config.Image{Name: image}, "", c.config.Retry), false)
+	if err != nil {
+		return err
+	}
+
+	// create the volume for the cluster
+	volID, err := c.client.CreateVolume("images")
+	if err != nil {
+		return err
+	}
+
+	clusterConfig, _, err := utils.GetClusterConfig(string(config.TypeK8sCluster) + "." + c.config.Name)
+	if err != nil {
+		return err
+	}
+
+	cc := c.kindNode(fmt.Sprintf("server.%s", c.config.Name), image, volID)
+	cc.Ports = serverPorts(clusterConfig)
+	cc.PortRanges = c.config.PortRanges
+	cc.Ports = append(cc.Ports, c.config.Ports...)
+
+	id, err := c.startKindNode(cc)
+	if err != nil {
+		return err
+	}
+
+	// initialize the control plane
+	kc, err := kindConfig(kindInitConfig, c.kindConfigValues(clusterConfig.APIPort))
+	if err != nil {
+		return xerrors.Errorf("Unable to create kubeadm config: %w", err)
+	}
+
+	err = c.kindExec(id, kc, "kubeadm", "init", "--skip-phases=preflight", "--config=/kind/kubeadm.conf", "--skip-token-print")
+	if err != nil {
+		return xerrors.Errorf("Unable to initialize control plane: %w", err)
+	}
+
+	// install the CNI and storage bundled with the node image
+	err = c.kindExec(id, "", "sh", "-c", fmt.Sprintf("sed 's|{{ .PodSubnet }}|%s|g' /kind/manifests/default-cni.yaml | kubectl --kubeconfig=%s apply -f -", kindPodSubnet, kindKubeConfig))
+	if err != nil {
+		return xerrors.Errorf("Unable to install CNI: %w", err)
+	}
+
+	err = c.kindExec(id, "", "kubectl", fmt.Sprintf("--kubeconfig=%s", kindKubeConfig), "apply", "-f", "/kind/manifests/default-storage.yaml")
+	if err != nil {
+		return xerrors.Errorf("Unable to install storage: %w", err)
+	}
+
+	// workloads can only run on the control plane when there are no workers
+	if c.config.WorkerNodes == 0 {
+		err = c.kindExec(id, "", "sh", "-c", fmt.Sprintf("kubectl --kubeconfig=%s taint nodes --all node-role.kubernetes.io/master- node-role.kubernetes.io/control-plane- || true", kindKubeConfig))
+		if err != nil {
+			return xerrors.Errorf("Unable to remove control plane taint: %w", err)
+		}
+	}
+
+	// create the worker nodes which join the server
+	agents := []string{}
+	for i := 0; i < c.config.WorkerNodes; i++ {
+		aid, err := c.createKindWorker(i+1, image, volID, clusterConfig.APIPort)
+		if err != nil {
+			return xerrors.Errorf("Unable to create agent nodes: %w", err)
+		}
+
+		agents = append(agents, aid)
+	}
+
+	// get the Kubernetes config file and replace the server address
+	// with the local and docker addresses in the same way as k3s
+	_, kubePath, _, err := utils.CreateKubeConfigPath(c.config.Name)
+	if err != nil {
+		return err
+	}
+
+	err = c.client.CopyFromContainer(id, kindKubeConfig, kubePath)
+	if err != nil {
+		return xerrors.Errorf("Error copying Kubernetes config: %w", err)
+	}
+
+	err = replaceInFile(
+		kubePath,
+		fmt.Sprintf("server: https://server.%s", utils.FQDN(c.config.Name, string(c.config.Type))),
+		"server: https://127.0.0.1",
+	)
+	if err != nil {
+		return xerrors.Errorf("Error copying Kubernetes config: %w", err)
+	}
+
+	config, err := c.createLocalKubeConfig(kubePath)
+	if err != nil {
+		return xerrors.Errorf("Error creating Local Kubernetes config: %w", err)
+	}
+
+	err = c.createDockerKubeConfig(kubePath)
+	if err != nil {
+		return xerrors.Errorf("Error creating Docker Kubernetes config: %w", err)
+	}
+
+	c.kubeClient, err = c.kubeClient.SetConfig(config)
+	if err != nil {
+		return err
+	}
+
+	err = c.kubeClient.HealthCheckPods(kindDefaultPods, resourceTimeout(c.config.Timeout, startTimeout))
+	if err != nil {
+		// fetch the logs from the container before exit
+		lr, lerr := c.client.ContainerLogs(id, true, true)
+		if lerr != nil {
+			c.log.Error("Unable to get logs from container", "error", lerr)
+		} else {
+			io.Copy(c.log.StandardWriter(&hclog.StandardLoggerOptions{}), lr)
+		}
+
+		return xerrors.Errorf("Error while waiting for Kubernetes default pods: %w", err)
+	}
+
+	// add the images to each nodes containerd instance
+	err = c.injectImages(append([]string{id}, agents...))
+	if err != nil {
+		return xerrors.Errorf("Error importing Docker images: %w", err)
+	}
+
+	c.log.Debug("Deploying connector")
+	return c.deployConnector(clusterConfig.ConnectorPort, clusterConfig.ConnectorPort+1)
+}
+
+// createKindWorker creates a worker node and joins it to the server
+func (c *K8sCluster) createKindWorker(index int, image, volID string, apiPort int) (string, error) {
+	cc := c.kindNode(fmt.Sprintf("%d.agent.%s", index, c.config.Name), image, volID)
+	c.log.Debug("Creating agent node", "ref", cc.Name)
+
+	id, err := c.startKindNode(cc)
+	if err != nil {
+		return "", err
+	}
+
+	jc, err := kindConfig(kindJoinConfig, c.kindConfigValues(apiPort))
+	if err != nil {
+		return "", xerrors.Errorf("Unable to create kubeadm config: %w", err)
+	}
+
+	err = c.kindExec(id, jc, "kubeadm", "join", "--skip-phases=preflight", "--config=/kind/kubeadm.conf")
+	if err != nil {
+		return "", xerrors.Errorf("Unable to join node to the cluster: %w", err)
+	}
+
+	return id, nil
+}
+
+// kindNode returns the container config for a kind node, nodes run systemd
+// and use the image cache as a proxy in the same way as k3s
+func (c *K8sCluster) kindNode(name, image, volID string) *config.Container {
+	cc := config.NewContainer(name)
+	c.config.ResourceInfo.AddChild(cc)
+
+	cc.Image = &config.Image{Name: image}
+	cc.Networks = c.config.Networks
+	cc.Privileged = true // kind nodes must run Privileged
+	cc.Ulimits = c.config.Ulimits
+	cc.Sysctls = c.config.Sysctls
+	cc.Resources = c.config.Resources
+
+	cc.Volumes = []config.Volume{
+		config.Volume{Source: volID, Destination: "/cache", Type: "volume"},
+		config.Volume{Destination: "/tmp", Type: "tmpfs"},
+		config.Volume{Destination: "/run", Type: "tmpfs"},
+		config.Volume{Source: "/lib/modules", Destination: "/lib/modules", Type: "bind", ReadOnly: true},
+		config.Volume{Source: filepath.Join(utils.CertsDir(""), "root.cert"), Destination: "/usr/local/share/ca-certificates/shipyard.crt", Type: "bind", ReadOnly: true},
+	}
+
+	// the CAs of the proxy are trusted by containerd
+	for i, f := range clients.ProxyCAFiles() {
+		cc.Volumes = append(cc.Volumes, config.Volume{Source: f, Destination: fmt.Sprintf("/usr/local/share/ca-certificates/proxy-%d.crt", i), Type: "bind", ReadOnly: true})
+	}
+
+	cc.Volumes = append(cc.Volumes, c.config.Volumes...)
+
+	// the node entrypoint configures systemd to use the proxy environment
+	cc.EnvVar = map[string]string{
+		"container":   "docker",
+		"HTTP_PROXY":  utils.HTTPProxyAddress(),
+		"HTTPS_PROXY": utils.HTTPSProxyAddress(),
+		"NO_PROXY":    utils.NoProxy(),
+	}
+
+	for k, v := range c.config.EnvVar {
+		cc.EnvVar[k] = v
+	}
+
+	return cc
+}
+
+// startKindNode creates the node and waits for systemd to start, the image
+// cache CA is trusted by containerd before the node is used
+func (c *K8sCluster) startKindNode(cc *config.Container) (string, error) {
+	id, err := c.client.CreateContainer(cc)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.waitForLog(id, "Multi-User System")
+	if err != nil {
+		return "", err
+	}
+
+	err = c.kindExec(id, "", "sh", "-c", "update-ca-certificates && systemctl restart containerd")
+	if err != nil {
+		return "", xerrors.Errorf("Unable to configure certificates for the image cache: %w", err)
+	}
+
+	return id, nil
+}
+
+// kindExec executes the command in the node, when the kubeadm config is
+// not empty it is copied to /kind/kubeadm.conf before the command is run
+func (c *K8sCluster) kindExec(id, kubeadmConfig string, command ...string) error {
+	if kubeadmConfig != "" {
+		_, configDir, err := utils.GetClusterConfig(string(config.TypeK8sCluster) + "." + c.config.Name)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(configDir, "kubeadm.conf")
+
+		err = ioutil.WriteFile(path, []byte(kubeadmConfig), os.ModePerm)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(path)
+
+		err = c.client.CopyFileToContainer(id, path, "/kind")
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.client.ExecuteCommand(id, command, nil, "/", "", "", c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
+}
+
+type kindConfigValues struct {
+	Name     string
+	Version  string
+	Server   string
+	DockerIP string
+	APIPort  int
+	Token    string
+	Subnet   string
+}
+
+func (c *K8sCluster) kindConfigValues(apiPort int) kindConfigValues {
+	return kindConfigValues{
+		Name:     c.config.Name,
+		Version:  c.config.Version,
+		Server:   fmt.Sprintf("server.%s", utils.FQDN(c.config.Name, string(c.config.Type))),
+		DockerIP: utils.GetDockerIP(),
+		APIPort:  apiPort,
+		Token:    kindBootstrapToken,
+		Subnet:   kindPodSubnet,
+	}
+}
+
+// kindConfig renders the kubeadm config template
+func kindConfig(tmpl string, v kindConfigValues) (string, error) {
+	t, err := template.New("kubeadm").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	b := bytes.NewBuffer(nil)
+	err = t.Execute(b, v)
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// replaceInFile replaces all instances of old with new in the file
+func replaceInFile(path, old, new string) error {
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, []byte(strings.Replace(string(d), old, new, -1)), os.ModePerm)
+}
+
+const kindPodSubnet = "10.244.0.0/16"
+
+var kindInitConfig = `
+apiVersion: kubeadm.k8s.io/v1beta3
+kind: ClusterConfiguration
+clusterName: {{ .Name }}
+kubernetesVersion: {{ .Version }}
+controlPlaneEndpoint: {{ .Server }}:{{ .APIPort }}
+apiServer:
+  certSANs:
+  - localhost
+  - 127.0.0.1
+  - {{ .DockerIP }}
+  - {{ .Server }}
+networking:
+  podSubnet: {{ .Subnet }}
+---
+apiVersion: kubeadm.k8s.io/v1beta3
+kind: InitConfiguration
+bootstrapTokens:
+- token: {{ .Token }}
+localAPIEndpoint:
+  bindPort: {{ .APIPort }}
+nodeRegistration:
+  criSocket: unix:///run/containerd/containerd.sock
+---
+apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+failSwapOn: false
+imageGCHighThresholdPercent: 100
+evictionHard:
+  nodefs.available: "0%"
+  nodefs.inodesFree: "0%"
+  imagefs.available: "0%"
+---
+apiVersion: kubeproxy.config.k8s.io/v1alpha1
+kind: KubeProxyConfiguration
+conntrack:
+  maxPerCore: 0
+`
+
+var kindJoinConfig = `
+apiVersion: kubeadm.k8s.io/v1beta3
+kind: JoinConfiguration
+discovery:
+  bootstrapToken:
+    apiServerEndpoint: {{ .Server }}:{{ .APIPort }}
+    token: {{ .Token }}
+    unsafeSkipCAVerification: true
+nodeRegistration:
+  criSocket: unix:///run/containerd/containerd.sock
+`
@@ -0,0 +1,161 @@
+package providers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupKindMocks(t *testing.T, workers int) (*config.K8sCluster, *mocks.MockContainerTasks, *clients.MockKubernetes, *clients.ConnectorMock) {
+	cc, md, mk, mc := setupClusterMocks(t)
+	cc.Driver = config.K8sDriverKind
+	cc.WorkerNodes = workers
+
+	md.On("CopyFileToContainer", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// kind nodes are ready when systemd has started
+	removeOn(&md.Mock, "ContainerLogs")
+	for i := 0; i < workers+1; i++ {
+		md.On("ContainerLogs", mock.Anything, true, true).Return(
+			ioutil.NopCloser(bytes.NewBufferString("Reached target Multi-User System.")),
+			nil,
+		).Once()
+	}
+
+	return cc, md, mk, mc
+}
+
+// execCommands returns the commands executed in the nodes joined with spaces
+func execCommands(md *mocks.MockContainerTasks) []string {
+	cmds := []string{}
+	for _, c := range getCalls(&md.Mock, "ExecuteCommand") {
+		cmds = append(cmds, strings.Join(c.Arguments[1].([]string), " "))
+	}
+
+	return cmds
+}
+
+func TestClusterKindCreatesServerWithNodeImage(t *testing.T) {
+	cc, md, mk, mc := setupKindMocks(t, 0)
+	cc.Version = ""
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.Equal(t, "server.test", params.Name)
+	assert.Equal(t, kindBaseImage+":"+kindBaseVersion, params.Image.Name)
+	assert.True(t, params.Privileged)
+	assert.Equal(t, cc.Networks, params.Networks)
+	assert.Equal(t, utils.HTTPProxyAddress(), params.EnvVar["HTTP_PROXY"])
+
+	// ports for the api server and the connector
+	assert.Len(t, params.Ports, 3)
+
+	// systemd requires tmpfs for /run and /tmp
+	assert.Equal(t, "/cache", params.Volumes[0].Destination)
+	assert.Equal(t, "tmpfs", params.Volumes[1].Type)
+	assert.Equal(t, "tmpfs", params.Volumes[2].Type)
+}
+
+func TestClusterKindInitializesControlPlane(t *testing.T) {
+	cc, md, mk, mc := setupKindMocks(t, 0)
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CopyFileToContainer", "containerid", mock.Anything, "/kind")
+
+	cmds := execCommands(md)
+	assert.Contains(t, cmds, "kubeadm init --skip-phases=preflight --config=/kind/kubeadm.conf --skip-token-print")
+	assert.Contains(t, cmds, "kubectl --kubeconfig=/etc/kubernetes/admin.conf apply -f /kind/manifests/default-storage.yaml")
+
+	// the taint is removed from the control plane as there are no workers
+	assert.Contains(t, strings.Join(cmds, "\n"), "taint nodes --all")
+
+	mk.AssertCalled(t, "HealthCheckPods", kindDefaultPods, startTimeout)
+}
+
+func TestClusterKindCreatesAndJoinsWorkers(t *testing.T) {
+	cc, md, mk, mc := setupKindMocks(t, 2)
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	calls := getCalls(&md.Mock, "CreateContainer")
+	assert.Len(t, calls, 3)
+	assert.Equal(t, "1.agent.test", calls[1].Arguments[0].(*config.Container).Name)
+	assert.Equal(t, "2.agent.test", calls[2].Arguments[0].(*config.Container).Name)
+
+	joins := 0
+	for _, c := range execCommands(md) {
+		if strings.HasPrefix(c, "kubeadm join") {
+			joins++
+		}
+
+		assert.NotContains(t, c, "taint nodes")
+	}
+
+	assert.Equal(t, 2, joins)
+}
+
+func TestClusterKindImportsImagesToAllNodes(t *testing.T) {
+	cc, md, mk, mc := setupKindMocks(t, 1)
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	imports := 0
+	for _, c := range execCommands(md) {
+		if strings.HasPrefix(c, "ctr -n k8s.io image import") {
+			imports++
+		}
+	}
+
+	assert.Equal(t, 2, imports)
+}
+
+func TestClusterKindDestroyRemovesNodes(t *testing.T) {
+	cc, md, mk, mc := setupKindMocks(t, 1)
+	removeOn(&md.Mock, "FindContainerIDs")
+	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"found"}, nil)
+
+	p := NewK8sCluster(cc, md, mk, nil, mc, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertNumberOfCalls(t, "RemoveContainer", 2)
+}
+
+func TestKindConfigRendersServerAddress(t *testing.T) {
+	v := kindConfigValues{Name: "test", Version: "v1.24.0", Server: "server.test.k8s-cluster.shipyard.run", DockerIP: "127.0.0.1", APIPort: 64674, Token: kindBootstrapToken, Subnet: kindPodSubnet}
+
+	c, err := kindConfig(kindInitConfig, v)
+	assert.NoError(t, err)
+	assert.Contains(t, c, "controlPlaneEndpoint: server.test.k8s-cluster.shipyard.run:64674")
+	assert.Contains(t, c, "bindPort: 64674")
+
+	c, err = kindConfig(kindJoinConfig, v)
+	assert.NoError(t, err)
+	assert.Contains(t, c, "apiServerEndpoint: server.test.k8s-cluster.shipyard.run:64674")
+	assert.Contains(t, c, "token: "+kindBootstrapToken)
+}
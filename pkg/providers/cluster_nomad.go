@@ -1,12 +1,17 @@
 package providers
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/hashicorp/go-hclog"
@@ -33,7 +38,7 @@ server {
 const clientConfig = `
 client {
 	enabled = true
-
+%s
 	server_join {
 		retry_join = ["%s"]
 	}
@@ -46,6 +51,37 @@ plugin "raw_exec" {
 }
 `
 
+const consulBaseImage = "consul"
+const consulBaseVersion = "1.12.2"
+const consulDefaultPort = 18500
+
+const consulServerConfig = `
+data_dir = "/consul/data"
+server = true
+bootstrap_expect = 1
+client_addr = "0.0.0.0"
+
+ui_config {
+  enabled = true
+}
+
+ports {
+  grpc = 8502
+}
+
+connect {
+  enabled = true
+}
+`
+
+const consulACLConfig = `
+acl {
+  enabled = true
+  default_policy = "deny"
+  enable_token_persistence = true
+}
+`
+
 // NomadCluster defines a provider which can create Kubernetes clusters
 type NomadCluster struct {
 	config      *config.NomadCluster
@@ -108,7 +144,7 @@ func (c *NomadCluster) createNomad() error {
 	image := fmt.Sprintf("%s:%s", nomadBaseImage, c.config.Version)
 
 	// pull the container image
-	err = c.client.PullImage(config.Image{Name: image}, false)
+	err = c.client.PullImage(withPullPolicy(config.Image{Name: image}, "", c.config.Retry), false)
 	if err != nil {
 		return err
 	}
@@ -119,6 +155,15 @@ func (c *NomadCluster) createNomad() error {
 		return err
 	}
 
+	// the Consul server must be running before Nomad is started so that
+	// the ACL token can be added to the Nomad config
+	if c.config.Consul != nil {
+		err := c.createConsulServer()
+		if err != nil {
+			return xerrors.Errorf("Unable to create Consul server: %w", err)
+		}
+	}
+
 	isClient := true
 	if c.config.ClientNodes > 0 {
 		isClient = false
@@ -158,43 +203,77 @@ func (c *NomadCluster) createNomad() error {
 
 	// ensure all client nodes are up
 	c.nomadClient.SetConfig(clusterConfig, string(utils.LocalContext))
-	err = c.nomadClient.HealthCheckAPI(startTimeout)
+	err = c.nomadClient.HealthCheckAPI(resourceTimeout(c.config.Timeout, startTimeout))
 	if err != nil {
 		return err
 	}
 
-	// import the images to the servers container d instance
-	// importing images means that Nomad does not need to pull from a remote docker hub
-	if c.config.Images != nil && len(c.config.Images) > 0 {
-		// import into the server
-		err := c.ImportLocalDockerImages("images", serverID, c.config.Images, false)
-		if err != nil {
-			return xerrors.Errorf("Error importing Docker images: %w", err)
-		}
+	// add the images to the servers and clients Docker engine
+	// adding images means that Nomad does not need to pull from a remote docker hub
+	err = c.injectImages(serverID, cls)
+	if err != nil {
+		return xerrors.Errorf("Error importing Docker images: %w", err)
+	}
 
-		// import cached images to the clients asynchronously
-		clWait := sync.WaitGroup{}
-		clWait.Add(c.config.ClientNodes)
-		var importErr error
-		for _, id := range cls {
-			go func(id string) {
-				err := c.ImportLocalDockerImages("images", id, c.config.Images, false)
-				clWait.Done()
-				if err != nil {
-					cMutex.Lock()
-					importErr = xerrors.Errorf("Error importing Docker images: %w", err)
-					cMutex.Unlock()
-				}
-			}(id)
-		}
+	return nil
+}
 
-		clWait.Wait()
-		if importErr != nil {
-			return importErr
-		}
+// injectImages adds the clusters images to each of the nodes using the clusters
+// image strategies, the next strategy is used when images can not be added
+func (c *NomadCluster) injectImages(serverID string, clients []string) error {
+	images := c.config.ClusterImages()
+	if len(images) == 0 {
+		return nil
 	}
 
-	return nil
+	nodes := append([]string{serverID}, clients...)
+
+	return injectImages(c.log, c.config.Name, c.config.ImageStrategies(), images, map[string]imageInjector{
+		config.ImageStrategyLoad: func(images []config.Image) ([]config.Image, error) {
+			// import into the server
+			err := c.ImportLocalDockerImages("images", serverID, images, false)
+			if err != nil {
+				return nil, err
+			}
+
+			// import cached images to the clients asynchronously
+			mutex := sync.Mutex{}
+			wait := sync.WaitGroup{}
+			wait.Add(len(clients))
+
+			var importErr error
+			for _, id := range clients {
+				go func(id string) {
+					defer wait.Done()
+
+					err := c.ImportLocalDockerImages("images", id, images, false)
+					if err != nil {
+						mutex.Lock()
+						importErr = err
+						mutex.Unlock()
+					}
+				}(id)
+			}
+
+			wait.Wait()
+
+			return nil, importErr
+		},
+		config.ImageStrategyCache: func(images []config.Image) ([]config.Image, error) {
+			return pullImagesInNodes(c.client, c.log, nodes, images, func(image string) []string {
+				return []string{"docker", "pull", image}
+			})
+		},
+		config.ImageStrategyRegistry: func(images []config.Image) ([]config.Image, error) {
+			reg, err := imageRegistry(&c.config.ResourceInfo, c.config.Registries)
+			if err != nil {
+				return nil, err
+			}
+
+			// the Docker engine only supports mirrors for Docker Hub
+			return pushImagesToRegistry(c.client, c.log, reg, images, []string{"docker.io"})
+		},
+	})
 }
 
 func (c *NomadCluster) createServerNode(image, volumeID string, isClient bool) (string, utils.ClusterConfig, string, error) {
@@ -204,20 +283,28 @@ func (c *NomadCluster) createServerNode(image, volumeID string, isClient bool) (
 		nodeCount = c.config.ClientNodes
 	}
 
-	conf, configDir := utils.GetClusterConfig(string(config.TypeNomadCluster) + "." + c.config.Name)
+	conf, configDir, err := utils.GetClusterConfig(string(config.TypeNomadCluster) + "." + c.config.Name)
+	if err != nil {
+		return "", utils.ClusterConfig{}, "", err
+	}
 
 	// add the nodecount to the config and save
 	conf.NodeCount = nodeCount
-	conf.Save(filepath.Join(configDir, "config.json"))
+	err = conf.Save(filepath.Join(configDir, "config.json"))
+	if err != nil {
+		return "", utils.ClusterConfig{}, "", xerrors.Errorf("Unable to save cluster config: %w", err)
+	}
 
 	// generate the server config
 	sc := dataDir + "\n" + serverConfig
 
 	// if the server also functions as a client
 	if isClient {
-		sc = sc + "\n" + fmt.Sprintf(clientConfig, "localhost")
+		sc = sc + "\n" + nomadClientConfig("localhost", nil)
 	}
 
+	sc = sc + c.integrationConfig(true)
+
 	// write the nomad config to a file
 	serverConfigPath := path.Join(configDir, "server_config.hcl")
 	ioutil.WriteFile(serverConfigPath, []byte(sc), os.ModePerm)
@@ -230,6 +317,9 @@ func (c *NomadCluster) createServerNode(image, volumeID string, isClient bool) (
 	cc.Image = &config.Image{Name: image}
 	cc.Networks = c.config.Networks
 	cc.Privileged = true // nomad must run Privileged as Docker needs to manipulate ip tables and stuff
+	cc.Ulimits = c.config.Ulimits
+	cc.Sysctls = c.config.Sysctls
+	cc.Resources = c.config.Resources
 
 	// set the volume mount for the images and the config
 	cc.Volumes = []config.Volume{
@@ -283,6 +373,16 @@ func (c *NomadCluster) createServerNode(image, volumeID string, isClient bool) (
 		cc.Volumes = append(cc.Volumes, v)
 	}
 
+	// configure Docker to trust and mirror the registries
+	if len(c.config.Registries) > 0 {
+		rv, err := c.registriesVolume(configDir)
+		if err != nil {
+			return "", utils.ClusterConfig{}, "", err
+		}
+
+		cc.Volumes = append(cc.Volumes, rv)
+	}
+
 	cc.Environment = c.config.Environment
 
 	// expose the API server port
@@ -300,7 +400,7 @@ func (c *NomadCluster) createServerNode(image, volumeID string, isClient bool) (
 	}
 
 	cc.EnvVar = map[string]string{}
-	err := c.appendProxyEnv(cc)
+	err = c.appendProxyEnv(cc)
 	if err != nil {
 		return "", utils.ClusterConfig{}, "", err
 	}
@@ -315,10 +415,11 @@ func (c *NomadCluster) createServerNode(image, volumeID string, isClient bool) (
 
 func (c *NomadCluster) createClientNode(index int, image, volumeID, configDir, serverID string) (string, error) {
 	// generate the client config
-	sc := dataDir + "\n" + fmt.Sprintf(clientConfig, serverID)
+	sc := dataDir + "\n" + nomadClientConfig(serverID, c.config.ClientNodeConfig(index)) + c.integrationConfig(false)
 
-	// write the default config to a file
-	clientConfigPath := path.Join(configDir, "client_config.hcl")
+	// write the config to a file, each node has its own config as
+	// the nodes can be configured with client_node blocks
+	clientConfigPath := path.Join(configDir, fmt.Sprintf("client_config.%d.hcl", index))
 	ioutil.WriteFile(clientConfigPath, []byte(sc), os.ModePerm)
 
 	// create the server
@@ -329,6 +430,9 @@ func (c *NomadCluster) createClientNode(index int, image, volumeID, configDir, s
 	cc.Image = &config.Image{Name: image}
 	cc.Networks = c.config.Networks
 	cc.Privileged = true // nomad must run Privileged as Docker needs to manipulate ip tables and stuff
+	cc.Ulimits = c.config.Ulimits
+	cc.Sysctls = c.config.Sysctls
+	cc.Resources = c.config.Resources
 
 	// set the volume mount for the images and the config
 	cc.Volumes = []config.Volume{
@@ -371,6 +475,16 @@ func (c *NomadCluster) createClientNode(index int, image, volumeID, configDir, s
 		cc.Volumes = append(cc.Volumes, v)
 	}
 
+	// configure Docker to trust and mirror the registries
+	if len(c.config.Registries) > 0 {
+		rv, err := c.registriesVolume(configDir)
+		if err != nil {
+			return "", err
+		}
+
+		cc.Volumes = append(cc.Volumes, rv)
+	}
+
 	cc.Environment = c.config.Environment
 
 	cc.EnvVar = map[string]string{}
@@ -382,6 +496,213 @@ func (c *NomadCluster) createClientNode(index int, image, volumeID, configDir, s
 	return c.client.CreateContainer(cc)
 }
 
+// nomadClientConfig returns the agent config for a client node which joins the
+// given server, the config for the node is added when n is not nil
+func nomadClientConfig(server string, n *config.NomadClientNode) string {
+	if n == nil {
+		return fmt.Sprintf(clientConfig, "", server)
+	}
+
+	client := ""
+	if n.NodeClass != "" {
+		client += fmt.Sprintf("\tnode_class = %q\n", n.NodeClass)
+	}
+
+	if len(n.Meta) > 0 {
+		keys := []string{}
+		for k := range n.Meta {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		client += "\n\tmeta {\n"
+		for _, k := range keys {
+			client += fmt.Sprintf("\t\t%q = %q\n", k, n.Meta[k])
+		}
+		client += "\t}\n"
+	}
+
+	if r := n.Reserved; r != nil {
+		client += "\n\treserved {\n"
+		if r.CPU > 0 {
+			client += fmt.Sprintf("\t\tcpu = %d\n", r.CPU)
+		}
+
+		if r.Memory > 0 {
+			client += fmt.Sprintf("\t\tmemory = %d\n", r.Memory)
+		}
+
+		if r.Disk > 0 {
+			client += fmt.Sprintf("\t\tdisk = %d\n", r.Disk)
+		}
+
+		if r.ReservedPorts != "" {
+			client += fmt.Sprintf("\t\treserved_ports = %q\n", r.ReservedPorts)
+		}
+		client += "\t}\n"
+	}
+
+	sc := fmt.Sprintf(clientConfig, client, server)
+
+	// datacenter is set for the agent not the client
+	if n.Datacenter != "" {
+		sc = fmt.Sprintf("\ndatacenter = %q\n", n.Datacenter) + sc
+	}
+
+	if n.Config != "" {
+		sc = sc + "\n" + n.Config + "\n"
+	}
+
+	return sc
+}
+
+// createConsulServer creates a Consul server on the same networks as the
+// cluster, when ACLs are enabled the bootstrap token is stored in the config
+func (c *NomadCluster) createConsulServer() error {
+	c.log.Info("Creating Consul server", "ref", c.config.Name)
+
+	version := c.config.Consul.Version
+	if version == "" {
+		version = consulBaseVersion
+	}
+
+	port := c.config.Consul.Port
+	if port == 0 {
+		port = consulDefaultPort
+	}
+
+	image := config.Image{Name: fmt.Sprintf("%s:%s", consulBaseImage, version)}
+
+	err := c.client.PullImage(withPullPolicy(image, "", c.config.Retry), false)
+	if err != nil {
+		return err
+	}
+
+	_, configDir, err := utils.GetClusterConfig(string(config.TypeNomadCluster) + "." + c.config.Name)
+	if err != nil {
+		return err
+	}
+
+	cs := consulServerConfig
+	if c.config.Consul.ACLEnabled {
+		cs = cs + consulACLConfig
+	}
+
+	consulConfigPath := path.Join(configDir, "consul_config.hcl")
+	err = ioutil.WriteFile(consulConfigPath, []byte(cs), os.ModePerm)
+	if err != nil {
+		return xerrors.Errorf("Unable to write Consul config: %w", err)
+	}
+
+	cc := config.NewContainer(fmt.Sprintf("consul.%s", c.config.Name))
+	c.config.ResourceInfo.AddChild(cc)
+
+	cc.Image = &image
+	cc.Networks = c.config.Networks
+	cc.Command = []string{"consul", "agent", "-config-file=/config/consul.hcl"}
+
+	cc.Volumes = []config.Volume{
+		config.Volume{
+			Source:      consulConfigPath,
+			Destination: "/config/consul.hcl",
+			Type:        "bind",
+		},
+	}
+
+	cc.Ports = []config.Port{
+		config.Port{
+			Local:    "8500",
+			Host:     fmt.Sprintf("%d", port),
+			Protocol: "tcp",
+		},
+	}
+
+	id, err := c.client.CreateContainer(cc)
+	if err != nil {
+		return err
+	}
+
+	c.config.ConsulHTTPAddr = fmt.Sprintf("http://localhost:%d", port)
+
+	if !c.config.Consul.ACLEnabled {
+		return nil
+	}
+
+	token, err := c.bootstrapConsulACL(id)
+	if err != nil {
+		return err
+	}
+
+	c.config.ConsulACLToken = token
+
+	return nil
+}
+
+// bootstrapConsulACL creates the initial management token, bootstrap fails
+// until the server has elected a leader so the command is retried
+func (c *NomadCluster) bootstrapConsulACL(id string) (string, error) {
+	st := time.Now()
+	for {
+		out := bytes.NewBuffer(nil)
+
+		err := c.client.ExecuteCommand(id, []string{"consul", "acl", "bootstrap", "-format=json"}, nil, "/", "", "", out)
+		if err == nil {
+			token := struct {
+				SecretID string
+			}{}
+
+			err := json.Unmarshal(out.Bytes(), &token)
+			if err != nil {
+				return "", xerrors.Errorf("Unable to parse Consul ACL bootstrap token: %w", err)
+			}
+
+			return token.SecretID, nil
+		}
+
+		if time.Now().After(st.Add(resourceTimeout(c.config.Timeout, startTimeout))) {
+			return "", xerrors.Errorf("Timeout waiting for Consul ACL bootstrap: %w", err)
+		}
+
+		c.log.Debug("Waiting for Consul to bootstrap ACLs", "ref", c.config.Name, "error", err)
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// integrationConfig returns the consul and vault stanzas for the Nomad config,
+// the Vault token is only added to the servers config
+func (c *NomadCluster) integrationConfig(server bool) string {
+	sb := strings.Builder{}
+
+	if c.config.Consul != nil {
+		sb.WriteString("\nconsul {\n")
+		sb.WriteString(fmt.Sprintf("  address = \"%s:8500\"\n", utils.FQDN(fmt.Sprintf("consul.%s", c.config.Name), string(config.TypeNomadCluster))))
+
+		if c.config.ConsulACLToken != "" {
+			sb.WriteString(fmt.Sprintf("  token = \"%s\"\n", c.config.ConsulACLToken))
+		}
+
+		sb.WriteString("}\n")
+	}
+
+	if c.config.Vault != nil {
+		sb.WriteString("\nvault {\n")
+		sb.WriteString("  enabled = true\n")
+		sb.WriteString(fmt.Sprintf("  address = \"%s\"\n", c.config.Vault.Address))
+
+		if server && c.config.Vault.Token != "" {
+			sb.WriteString(fmt.Sprintf("  token = \"%s\"\n", c.config.Vault.Token))
+		}
+
+		if server && c.config.Vault.CreateFromRole != "" {
+			sb.WriteString(fmt.Sprintf("  create_from_role = \"%s\"\n", c.config.Vault.CreateFromRole))
+		}
+
+		sb.WriteString("}\n")
+	}
+
+	return sb.String()
+}
+
 func (c *NomadCluster) appendProxyEnv(cc *config.Container) error {
 
 	// only add the variables for the cache when the nomad version is >= v0.11.8 or
@@ -406,16 +727,14 @@ func (c *NomadCluster) appendProxyEnv(cc *config.Container) error {
 	}
 
 	if usesCache {
-		// load the CA from a file
-		ca, err := ioutil.ReadFile(filepath.Join(utils.CertsDir(""), "/root.cert"))
+		env, err := clusterProxyEnv()
 		if err != nil {
-			return fmt.Errorf("Unable to read root CA for proxy: %s", err)
+			return err
 		}
 
-		cc.EnvVar["HTTP_PROXY"] = utils.HTTPProxyAddress()
-		cc.EnvVar["HTTPS_PROXY"] = utils.HTTPSProxyAddress()
-		cc.EnvVar["NO_PROXY"] = utils.ProxyBypass
-		cc.EnvVar["PROXY_CA"] = string(ca)
+		for k, v := range env {
+			cc.EnvVar[k] = v
+		}
 	}
 
 	return nil
@@ -431,7 +750,7 @@ func (c *NomadCluster) ImportLocalDockerImages(name string, id string, images []
 			continue
 		}
 
-		err := c.client.PullImage(i, false)
+		err := c.client.PullImage(withPullPolicy(i, "", c.config.Retry), false)
 		if err != nil {
 			return err
 		}
@@ -475,8 +794,20 @@ func (c *NomadCluster) destroyNomad() error {
 		}
 	}
 
+	// destroy the consul server
+	if c.config.Consul != nil {
+		err := c.destroyNode(fmt.Sprintf("consul.%s", c.config.Name))
+		if err != nil {
+			return err
+		}
+	}
+
 	// remove the config
-	_, path := utils.GetClusterConfig(string(c.config.Type) + "." + c.config.Name)
+	_, path, err := utils.GetClusterConfig(string(c.config.Type) + "." + c.config.Name)
+	if err != nil {
+		return err
+	}
+
 	os.RemoveAll(path)
 
 	return nil
@@ -507,3 +838,26 @@ func (c *NomadCluster) destroyNode(id string) error {
 
 	return nil
 }
+
+// registriesVolume writes the Docker daemon config for the clusters registries
+// and returns a volume which mounts the file into the node
+func (c *NomadCluster) registriesVolume(configDir string) (config.Volume, error) {
+	registries, err := clusterRegistries(&c.config.ResourceInfo, c.config.Registries)
+	if err != nil {
+		return config.Volume{}, err
+	}
+
+	dc, err := dockerDaemonConfig(registries, hasImageStrategy(c.config.ImageStrategies(), config.ImageStrategyRegistry))
+	if err != nil {
+		return config.Volume{}, xerrors.Errorf("Unable to create Docker daemon config: %w", err)
+	}
+
+	path := filepath.Join(configDir, "daemon.json")
+
+	err = ioutil.WriteFile(path, dc, os.ModePerm)
+	if err != nil {
+		return config.Volume{}, xerrors.Errorf("Unable to write Docker daemon config: %w", err)
+	}
+
+	return config.Volume{Source: path, Destination: "/etc/docker/daemon.json", Type: "bind", ReadOnly: true}, nil
+}
@@ -3,6 +3,7 @@ package providers
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -208,6 +209,23 @@ func TestClusterNomadCreatesClientNodes(t *testing.T) {
 	md.AssertNumberOfCalls(t, "CreateContainer", 4)
 }
 
+func TestClusterNomadCreatesNodesWithResources(t *testing.T) {
+	cc, md, mh := setupNomadClusterMocks(t)
+	cc.ClientNodes = 1
+	cc.Resources = &config.Resources{CPU: "1000m", Memory: "1Gi"}
+
+	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	server := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	client := getCalls(&md.Mock, "CreateContainer")[1].Arguments[0].(*config.Container)
+
+	assert.Equal(t, cc.Resources, server.Resources)
+	assert.Equal(t, cc.Resources, client.Resources)
+}
+
 func TestClusterNomadCreatesClientNodesWithCorrectDetails(t *testing.T) {
 	cc, md, mh := setupNomadClusterMocks(t)
 	cc.ClientNodes = 1
@@ -235,7 +253,7 @@ func TestClusterNomadCreatesClientNodesWithCorrectDetails(t *testing.T) {
 	assert.Equal(t, "volume", params.Volumes[0].Type)
 
 	// validate that the config volume has been added
-	assert.Contains(t, params.Volumes[1].Source, "test/client_config.hcl")
+	assert.Contains(t, params.Volumes[1].Source, "test/client_config.1.hcl")
 	assert.Equal(t, "/etc/nomad.d/config.hcl", params.Volumes[1].Destination)
 
 	// validate that the consul config is added
@@ -247,6 +265,45 @@ func TestClusterNomadCreatesClientNodesWithCorrectDetails(t *testing.T) {
 	assert.Equal(t, "/files", params.Volumes[3].Destination)
 }
 
+func TestClusterNomadConfiguresClientNodes(t *testing.T) {
+	cc, md, mh := setupNomadClusterMocks(t)
+	cc.ClientNodes = 3
+	cc.ClientNode = []config.NomadClientNode{
+		{
+			Count:      2,
+			Datacenter: "east",
+			NodeClass:  "compute",
+			Meta:       map[string]string{"rack": "r1", "gpu": "false"},
+			Reserved:   &config.NomadReserved{CPU: 500, Memory: 256, ReservedPorts: "22"},
+			Config:     `client { max_kill_timeout = "10s" }`,
+		},
+	}
+
+	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	_, configDir, _ := utils.GetClusterConfig(string(config.TypeNomadCluster) + "." + cc.Name)
+
+	for _, i := range []int{1, 2} {
+		d, err := ioutil.ReadFile(filepath.Join(configDir, fmt.Sprintf("client_config.%d.hcl", i)))
+		assert.NoError(t, err)
+
+		assert.Contains(t, string(d), `datacenter = "east"`)
+		assert.Contains(t, string(d), `node_class = "compute"`)
+		assert.Regexp(t, `(?s)meta \{\s+"gpu" = "false"\s+"rack" = "r1"\s+\}`, string(d))
+		assert.Regexp(t, `(?s)reserved \{\s+cpu = 500\s+memory = 256\s+reserved_ports = "22"\s+\}`, string(d))
+		assert.Contains(t, string(d), `client { max_kill_timeout = "10s" }`)
+	}
+
+	// the third node is not configured by a client_node block
+	d, err := ioutil.ReadFile(filepath.Join(configDir, "client_config.3.hcl"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(d), "datacenter")
+	assert.NotContains(t, string(d), "node_class")
+}
+
 func TestClusterNomadSetsNodeCountInConfig(t *testing.T) {
 	cc, md, mh := setupNomadClusterMocks(t)
 	cc.ClientNodes = 10
@@ -256,7 +313,7 @@ func TestClusterNomadSetsNodeCountInConfig(t *testing.T) {
 	err := p.Create()
 	assert.NoError(t, err)
 
-	conf, _ := utils.GetClusterConfig(string(config.TypeNomadCluster) + "." + cc.Name)
+	conf, _, _ := utils.GetClusterConfig(string(config.TypeNomadCluster) + "." + cc.Name)
 	assert.Equal(t, cc.ClientNodes, conf.NodeCount)
 }
 
@@ -466,7 +523,7 @@ func TestClusterNomadDestroyRemovesConfig(t *testing.T) {
 	removeOn(&md.Mock, "FindContainerIDs")
 	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"found"}, nil)
 
-	_, dir := utils.GetClusterConfig(string(cc.Info().Type) + "." + cc.Info().Name)
+	_, dir, _ := utils.GetClusterConfig(string(cc.Info().Type) + "." + cc.Info().Name)
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
@@ -487,3 +544,114 @@ var clusterNomadConfig = &config.NomadCluster{
 	Networks:     []config.NetworkAttachment{config.NetworkAttachment{Name: "cloud"}},
 	ConsulConfig: "./files/consul_config.hcl",
 }
+
+func TestClusterNomadMountsDockerDaemonConfigForRegistries(t *testing.T) {
+	cc, md, mh := setupNomadClusterMocks(t)
+	cc.ClientNodes = 1
+
+	reg := config.NewRegistry("local")
+	cc.Config.AddResource(reg)
+	cc.Registries = []string{"registry.local"}
+
+	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	for _, c := range getCalls(&md.Mock, "CreateContainer") {
+		params := c.Arguments[0].(*config.Container)
+
+		v := params.Volumes[len(params.Volumes)-1]
+		assert.Equal(t, "/etc/docker/daemon.json", v.Destination)
+
+		d, err := ioutil.ReadFile(v.Source)
+		assert.NoError(t, err)
+		assert.Contains(t, string(d), "local.registry.shipyard.run:5000")
+	}
+}
+
+func TestClusterNomadCreatesConsulServer(t *testing.T) {
+	cc, md, mh := setupNomadClusterMocks(t)
+	cc.Consul = &config.NomadConsul{}
+
+	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "PullImage", config.Image{Name: "consul:" + consulBaseVersion}, false)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "consul."+cc.Name, params.Name)
+	assert.Equal(t, "18500", params.Ports[0].Host)
+	assert.Equal(t, "http://localhost:18500", cc.ConsulHTTPAddr)
+
+	// check the nomad server is configured to use consul
+	params = getCalls(&md.Mock, "CreateContainer")[1].Arguments[0].(*config.Container)
+	d, err := ioutil.ReadFile(params.Volumes[1].Source)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), `address = "consul.test.nomad-cluster.shipyard.run:8500"`)
+	assert.NotContains(t, string(d), "token")
+}
+
+func TestClusterNomadBootstrapsConsulACLs(t *testing.T) {
+	cc, md, mh := setupNomadClusterMocks(t)
+	cc.Consul = &config.NomadConsul{ACLEnabled: true}
+
+	removeOn(&md.Mock, "ExecuteCommand")
+	md.On("ExecuteCommand", mock.Anything, []string{"consul", "acl", "bootstrap", "-format=json"}, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(6).(io.Writer).Write([]byte(`{"SecretID": "abc123"}`))
+	}).Return(nil)
+	md.On("ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "abc123", cc.ConsulACLToken)
+
+	params := getCalls(&md.Mock, "CreateContainer")[1].Arguments[0].(*config.Container)
+	d, err := ioutil.ReadFile(params.Volumes[1].Source)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), `token = "abc123"`)
+}
+
+func TestClusterNomadAddsVaultConfig(t *testing.T) {
+	cc, md, mh := setupNomadClusterMocks(t)
+	cc.ClientNodes = 1
+	cc.Vault = &config.NomadVault{Address: "http://vault:8200", Token: "root"}
+
+	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	server := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	d, err := ioutil.ReadFile(server.Volumes[1].Source)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), `address = "http://vault:8200"`)
+	assert.Contains(t, string(d), `token = "root"`)
+
+	// clients do not get the vault token
+	client := getCalls(&md.Mock, "CreateContainer")[1].Arguments[0].(*config.Container)
+	d, err = ioutil.ReadFile(client.Volumes[1].Source)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), `address = "http://vault:8200"`)
+	assert.NotContains(t, string(d), "token")
+}
+
+func TestClusterNomadDestroyRemovesConsulServer(t *testing.T) {
+	cc, md, mh := setupNomadClusterMocks(t)
+	cc.Consul = &config.NomadConsul{}
+
+	removeOn(&md.Mock, "FindContainerIDs")
+	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"found"}, nil)
+
+	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "FindContainerIDs", "consul."+cc.Name, cc.Type)
+}
@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// Compose is a provider which creates containers for the services in a docker-compose file
+type Compose struct {
+	config     *config.Compose
+	client     clients.ContainerTasks
+	httpClient clients.HTTP
+	log        hclog.Logger
+}
+
+// NewCompose creates a new Compose provider
+func NewCompose(c *config.Compose, cl clients.ContainerTasks, hc clients.HTTP, l hclog.Logger) *Compose {
+	return &Compose{c, cl, hc, l}
+}
+
+// Create reads the compose file and creates a container for each service
+func (c *Compose) Create() error {
+	c.log.Info("Creating Compose", "ref", c.config.Name, "file", c.config.File)
+
+	containers, err := c.config.Containers()
+	if err != nil {
+		return xerrors.Errorf("Unable to read compose file: %w", err)
+	}
+
+	c.config.Services = []string{}
+
+	for _, cc := range containers {
+		service := strings.TrimSuffix(cc.Name, "."+c.config.Name)
+
+		// record the service before it is created so that it
+		// is removed on destroy should the creation fail
+		c.config.Services = append(c.config.Services, service)
+
+		c.log.Debug("Creating service", "ref", c.config.Name, "service", service)
+
+		err := NewContainer(cc, c.client, c.httpClient, c.log).Create()
+		if err != nil {
+			return xerrors.Errorf("Unable to create service %s: %w", service, err)
+		}
+	}
+
+	return nil
+}
+
+// Destroy removes the containers for the services, containers
+// are removed in the reverse order to which they were created
+func (c *Compose) Destroy() error {
+	c.log.Info("Destroy Compose", "ref", c.config.Name)
+
+	for i := len(c.config.Services) - 1; i >= 0; i-- {
+		ids, err := c.client.FindContainerIDs(c.config.ServiceContainerName(c.config.Services[i]), c.config.Type)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			err := c.client.RemoveContainer(id, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Lookup the IDs of the service containers
+func (c *Compose) Lookup() ([]string, error) {
+	ids := []string{}
+
+	for _, s := range c.config.Services {
+		sids, err := c.client.FindContainerIDs(c.config.ServiceContainerName(s), c.config.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, sids...)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,119 @@
+package providers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupComposeTests(t *testing.T) (*config.Compose, *mocks.MockContainerTasks) {
+	dir := t.TempDir()
+
+	err := ioutil.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(composeFile), os.ModePerm)
+	assert.NoError(t, err)
+
+	cc := config.NewCompose("app")
+	cc.File = filepath.Join(dir, "docker-compose.yml")
+	cc.Networks = []config.NetworkAttachment{{Name: "network.local"}}
+
+	md := &mocks.MockContainerTasks{}
+	md.On("PullImage", mock.Anything, false).Return(nil)
+	md.On("CreateContainer", mock.Anything).Return("abc", nil)
+	md.On("FindContainerIDs", "web.app", config.TypeCompose).Return([]string{"web"}, nil)
+	md.On("FindContainerIDs", "db.app", config.TypeCompose).Return([]string{"db"}, nil)
+	md.On("RemoveContainer", mock.Anything, false).Return(nil)
+
+	return cc, md
+}
+
+func TestComposeCreatesContainerForEachService(t *testing.T) {
+	cc, md := setupComposeTests(t)
+
+	p := NewCompose(cc, md, nil, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	calls := getCalls(&md.Mock, "CreateContainer")
+	assert.Len(t, calls, 2)
+
+	db := calls[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "db.app", db.Name)
+	assert.Equal(t, config.TypeCompose, db.Type)
+	assert.Equal(t, "postgres:14", db.Image.Name)
+	assert.Equal(t, []string{"db"}, db.Networks[0].Aliases)
+
+	web := calls[1].Arguments[0].(*config.Container)
+	assert.Equal(t, "web.app", web.Name)
+
+	assert.Equal(t, []string{"db", "web"}, cc.Services)
+}
+
+func TestComposeCreateRecordsFailedService(t *testing.T) {
+	cc, md := setupComposeTests(t)
+	removeOn(&md.Mock, "CreateContainer")
+	md.On("CreateContainer", mock.Anything).Return("", fmt.Errorf("boom"))
+
+	p := NewCompose(cc, md, nil, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"db"}, cc.Services)
+}
+
+func TestComposeCreateWithInvalidFileReturnsError(t *testing.T) {
+	cc, md := setupComposeTests(t)
+	cc.File = "/does/not/exist.yml"
+
+	p := NewCompose(cc, md, nil, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+
+	md.AssertNotCalled(t, "CreateContainer", mock.Anything)
+}
+
+func TestComposeDestroyRemovesServicesInReverseOrder(t *testing.T) {
+	cc, md := setupComposeTests(t)
+	cc.Services = []string{"db", "web"}
+
+	p := NewCompose(cc, md, nil, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	calls := getCalls(&md.Mock, "RemoveContainer")
+	assert.Len(t, calls, 2)
+	assert.Equal(t, "web", calls[0].Arguments[0])
+	assert.Equal(t, "db", calls[1].Arguments[0])
+}
+
+func TestComposeLookupReturnsServiceIDs(t *testing.T) {
+	cc, md := setupComposeTests(t)
+	cc.Services = []string{"db", "web"}
+
+	p := NewCompose(cc, md, nil, hclog.NewNullLogger())
+
+	ids, err := p.Lookup()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db", "web"}, ids)
+}
+
+const composeFile = `
+services:
+  web:
+    image: nginx
+    depends_on:
+      - db
+  db:
+    image: postgres:14
+`
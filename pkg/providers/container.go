@@ -7,6 +7,7 @@ import (
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"golang.org/x/xerrors"
 )
 
@@ -32,13 +33,24 @@ func NewContainerSidecar(cs *config.Sidecar, cl clients.ContainerTasks, hc clien
 	co.Entrypoint = cs.Entrypoint
 	co.Environment = cs.Environment
 	co.EnvVar = cs.EnvVar
+	co.EnvPassthrough = cs.EnvPassthrough
 	co.HealthCheck = cs.HealthCheck
 	co.Image = &cs.Image
 	co.Privileged = cs.Privileged
+	co.Ulimits = cs.Ulimits
+	co.Sysctls = cs.Sysctls
 	co.Resources = cs.Resources
 	co.Type = cs.Type
 	co.Config = cs.Config
 	co.MaxRestartCount = cs.MaxRestartCount
+	co.Restart = cs.Restart
+	co.CapAdd = cs.CapAdd
+	co.CapDrop = cs.CapDrop
+	co.SecurityOpt = cs.SecurityOpt
+	co.Devices = cs.Devices
+	co.SelectedFallback = cs.SelectedFallback
+	co.SharePID = cs.SharePID
+	co.ShareIPC = cs.ShareIPC
 
 	return &Container{co, cl, hc, l}
 }
@@ -47,10 +59,25 @@ func NewContainerSidecar(cs *config.Sidecar, cl clients.ContainerTasks, hc clien
 func (c *Container) Create() error {
 	c.log.Info("Creating Container", "ref", c.config.Name)
 
+	if c.config.SelectedFallback != "" {
+		c.log.Info("Using fallback for Container", "ref", c.config.Name, "when", c.config.SelectedFallback)
+	}
+
 	return c.internalCreate()
 }
 
 func (c *Container) internalCreate() error {
+	// has the container been persisted on a previous destroy
+	if c.config.PersistOnDestroy {
+		if id, err := c.client.FindImageID(c.config.PersistedImage()); err == nil && id != "" {
+			c.log.Debug("Creating container from persisted image", "ref", c.config.Name, "image", c.config.PersistedImage())
+
+			c.config.Image = &config.Image{Name: c.config.PersistedImage()}
+
+			return c.createContainer()
+		}
+	}
+
 	// do we need to build an image
 	if c.config.Build != nil {
 
@@ -74,7 +101,7 @@ func (c *Container) internalCreate() error {
 		c.config.Image = &config.Image{Name: name}
 	} else {
 		// pull any images needed for this container
-		err := c.client.PullImage(*c.config.Image, false)
+		err := c.client.PullImage(withPullPolicy(*c.config.Image, c.config.Timeout, c.config.Retry), false)
 		if err != nil {
 			c.log.Error("Error pulling container image", "ref", c.config.Name, "image", c.config.Image.Name)
 
@@ -82,7 +109,16 @@ func (c *Container) internalCreate() error {
 		}
 	}
 
-	_, err := c.client.CreateContainer(c.config)
+	return c.createContainer()
+}
+
+func (c *Container) createContainer() error {
+	err := c.runInitContainers()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.CreateContainer(c.config)
 
 	if c.config.HealthCheck == nil {
 		return err
@@ -107,6 +143,116 @@ func (c *Container) internalCreate() error {
 	return nil
 }
 
+// runInitContainers runs the init containers in order, each init container
+// must exit successfully before the next one is started
+func (c *Container) runInitContainers() error {
+	for _, ic := range c.config.InitContainers {
+		c.log.Debug("Running init container", "ref", c.config.Name, "init", ic.Name, "image", ic.Image.Name)
+
+		timeout := 300 * time.Second
+		if ic.Timeout != "" {
+			d, err := time.ParseDuration(ic.Timeout)
+			if err != nil {
+				return xerrors.Errorf("Invalid timeout for init container %s: %w", ic.Name, err)
+			}
+
+			timeout = d
+		}
+
+		err := c.client.PullImage(withPullPolicy(ic.Image, c.config.Timeout, c.config.Retry), false)
+		if err != nil {
+			c.log.Error("Error pulling init container image", "ref", c.config.Name, "image", ic.Image.Name)
+
+			return err
+		}
+
+		name := c.initContainerName(ic)
+
+		// remove any container left from a previous failed run
+		err = c.removeInitContainer(name)
+		if err != nil {
+			return err
+		}
+
+		id, err := c.client.CreateContainer(c.initContainerConfig(name, ic))
+		if err != nil {
+			return xerrors.Errorf("Unable to create init container %s: %w", ic.Name, err)
+		}
+
+		code, err := c.client.WaitForContainer(id, timeout)
+		if err != nil {
+			return xerrors.Errorf("Init container %s did not complete: %w", ic.Name, err)
+		}
+
+		if code != 0 {
+			// do not remove the failed container so that the logs can be inspected
+			return xerrors.Errorf(
+				"Init container %s exited with code %d, use 'docker logs %s' to view the output",
+				ic.Name,
+				code,
+				utils.FQDN(name, string(c.config.Type)),
+			)
+		}
+
+		err = c.client.RemoveContainer(id, true)
+		if err != nil {
+			c.log.Warn("Unable to remove init container", "ref", c.config.Name, "init", ic.Name, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// initContainerConfig creates the container config for an init container, init containers
+// are attached to the same networks and volumes as the main container
+func (c *Container) initContainerConfig(name string, ic config.InitContainer) *config.Container {
+	cc := config.NewContainer(name)
+	cc.Type = c.config.Type
+	cc.Image = &ic.Image
+	cc.Entrypoint = ic.Entrypoint
+	cc.Command = ic.Command
+
+	// ip addresses and aliases belong to the main container
+	for _, n := range c.config.Networks {
+		cc.Networks = append(cc.Networks, config.NetworkAttachment{Name: n.Name})
+	}
+
+	cc.Volumes = append(cc.Volumes, c.config.Volumes...)
+	cc.Volumes = append(cc.Volumes, ic.Volumes...)
+
+	cc.Environment = c.config.Environment
+	cc.EnvVar = map[string]string{}
+	for k, v := range c.config.EnvVar {
+		cc.EnvVar[k] = v
+	}
+
+	for k, v := range ic.EnvVar {
+		cc.EnvVar[k] = v
+	}
+
+	return cc
+}
+
+func (c *Container) initContainerName(ic config.InitContainer) string {
+	return fmt.Sprintf("%s-init-%s", c.config.Name, ic.Name)
+}
+
+func (c *Container) removeInitContainer(name string) error {
+	ids, err := c.client.FindContainerIDs(name, c.config.Type)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := c.client.RemoveContainer(id, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Destroy stops and removes the container
 func (c *Container) Destroy() error {
 	c.log.Info("Destroy Container", "ref", c.config.Name)
@@ -115,12 +261,30 @@ func (c *Container) Destroy() error {
 }
 
 func (c *Container) internalDestroy() error {
+	// remove any init containers which were left after failing
+	for _, ic := range c.config.InitContainers {
+		err := c.removeInitContainer(c.initContainerName(ic))
+		if err != nil {
+			return err
+		}
+	}
+
 	ids, err := c.client.FindContainerIDs(c.config.Name, c.config.Type)
 	if err != nil {
 		return err
 	}
 
 	if len(ids) > 0 {
+		// commit the container before it is removed so the next create starts with the same filesystem
+		if c.config.PersistOnDestroy {
+			c.log.Debug("Persisting container", "ref", c.config.Name, "image", c.config.PersistedImage())
+
+			err := c.client.CommitContainer(ids[0], c.config.PersistedImage())
+			if err != nil {
+				return xerrors.Errorf("Unable to persist container %s: %w", c.config.Name, err)
+			}
+		}
+
 		for _, id := range ids {
 			err := c.client.RemoveContainer(id, false)
 
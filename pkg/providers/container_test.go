@@ -47,6 +47,12 @@ func TestContainerSidecarCreatesContainerSuccessfully(t *testing.T) {
 	cc.Resources = &config.Resources{}
 	cc.Config = &config.Config{}
 	cc.MaxRestartCount = 10
+	cc.Restart = "always"
+	cc.CapAdd = []string{"NET_ADMIN"}
+	cc.SecurityOpt = []string{"apparmor=unconfined"}
+	cc.Devices = []config.Device{config.Device{Source: "/dev/net/tun"}}
+	cc.SharePID = true
+	cc.ShareIPC = true
 
 	md.On("PullImage", cc.Image, false).Once().Return(nil)
 	md.On("CreateContainer", mock.Anything).Once().Return("", nil)
@@ -71,6 +77,12 @@ func TestContainerSidecarCreatesContainerSuccessfully(t *testing.T) {
 	assert.Equal(t, cc.Type, ac.Type)
 	assert.Equal(t, cc.Config, ac.Config)
 	assert.Equal(t, cc.MaxRestartCount, ac.MaxRestartCount)
+	assert.Equal(t, cc.Restart, ac.Restart)
+	assert.Equal(t, cc.CapAdd, ac.CapAdd)
+	assert.Equal(t, cc.SecurityOpt, ac.SecurityOpt)
+	assert.Equal(t, cc.Devices, ac.Devices)
+	assert.Equal(t, cc.SharePID, ac.SharePID)
+	assert.Equal(t, cc.ShareIPC, ac.ShareIPC)
 }
 
 func TestContainerRunsHTTPChecks(t *testing.T) {
@@ -138,6 +150,27 @@ func TestContainerDoesNOTCreateWhenPullImageFail(t *testing.T) {
 	assert.Equal(t, imageErr, err)
 }
 
+func TestContainerPullsImageWithTimeoutAndRetry(t *testing.T) {
+	cc := config.NewContainer("tests")
+	cc.Image = &config.Image{Name: "consul:1.10.0"}
+	cc.Timeout = "10m"
+	cc.Retry = &config.Retry{Attempts: 5}
+	md := &mocks.MockContainerTasks{}
+	hc := &mocks.MockHTTP{}
+	c := NewContainer(cc, md, hc, hclog.NewNullLogger())
+
+	md.On("PullImage", mock.Anything, false).Once().Return(nil)
+	md.On("CreateContainer", cc).Once().Return("", nil)
+
+	err := c.Create()
+	assert.NoError(t, err)
+
+	i := md.Calls[0].Arguments[0].(config.Image)
+	assert.Equal(t, "consul:1.10.0", i.Name)
+	assert.Equal(t, 10*time.Minute, i.PullTimeout)
+	assert.Equal(t, 5, i.PullRetry.MaxAttempts())
+}
+
 func TestContainerDestroysCorrectlyWhenContainerExists(t *testing.T) {
 	cc := config.NewContainer("tests")
 	cc.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: "cloud"}}
@@ -212,3 +245,173 @@ func TestContainerBuildsContainer(t *testing.T) {
 	conf := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
 	assert.Equal(t, "testimage", conf.Image.Name)
 }
+
+func setupInitContainer() (*config.Container, *mocks.MockContainerTasks, *Container) {
+	cc := config.NewContainer("app")
+	cc.Image = &config.Image{Name: "app:latest"}
+	cc.Networks = []config.NetworkAttachment{{Name: "network.cloud", IPAddress: "10.6.0.200", Aliases: []string{"app"}}}
+	cc.Volumes = []config.Volume{{Source: "/data", Destination: "/data"}}
+	cc.EnvVar = map[string]string{"DB": "postgres", "MODE": "app"}
+	cc.InitContainers = []config.InitContainer{
+		{
+			Name:    "migrate",
+			Image:   config.Image{Name: "migrate:latest"},
+			Command: []string{"up"},
+			EnvVar:  map[string]string{"MODE": "migrate"},
+			Volumes: []config.Volume{{Source: "/migrations", Destination: "/migrations"}},
+		},
+	}
+
+	md := &mocks.MockContainerTasks{}
+	md.On("PullImage", mock.Anything, false).Return(nil)
+	md.On("FindContainerIDs", "app-init-migrate", config.TypeContainer).Return(nil, nil)
+	md.On("CreateContainer", mock.Anything).Return("abc", nil)
+	md.On("WaitForContainer", "abc", 300*time.Second).Return(0, nil)
+	md.On("RemoveContainer", "abc", true).Return(nil)
+
+	c := NewContainer(cc, md, &mocks.MockHTTP{}, hclog.NewNullLogger())
+
+	return cc, md, c
+}
+
+func TestContainerRunsInitContainersBeforeContainer(t *testing.T) {
+	cc, md, c := setupInitContainer()
+
+	err := c.Create()
+	assert.NoError(t, err)
+
+	calls := getCalls(&md.Mock, "CreateContainer")
+	assert.Len(t, calls, 2)
+
+	ic := calls[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "app-init-migrate", ic.Name)
+	assert.Equal(t, "migrate:latest", ic.Image.Name)
+	assert.Equal(t, []string{"up"}, ic.Command)
+	assert.Equal(t, []config.NetworkAttachment{{Name: "network.cloud"}}, ic.Networks)
+	assert.Len(t, ic.Volumes, 2)
+	assert.Equal(t, "postgres", ic.EnvVar["DB"])
+	assert.Equal(t, "migrate", ic.EnvVar["MODE"])
+
+	assert.Equal(t, cc, calls[1].Arguments[0].(*config.Container))
+
+	md.AssertCalled(t, "WaitForContainer", "abc", 300*time.Second)
+	md.AssertCalled(t, "RemoveContainer", "abc", true)
+}
+
+func TestContainerUsesInitContainerTimeout(t *testing.T) {
+	cc, md, c := setupInitContainer()
+	cc.InitContainers[0].Timeout = "10s"
+	removeOn(&md.Mock, "WaitForContainer")
+	md.On("WaitForContainer", "abc", 10*time.Second).Return(0, nil)
+
+	err := c.Create()
+	assert.NoError(t, err)
+}
+
+func TestContainerFailsWhenInitContainerExitsWithError(t *testing.T) {
+	_, md, c := setupInitContainer()
+	removeOn(&md.Mock, "WaitForContainer")
+	md.On("WaitForContainer", "abc", mock.Anything).Return(1, nil)
+
+	err := c.Create()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exited with code 1")
+
+	// only the init container should be created and not removed
+	md.AssertNumberOfCalls(t, "CreateContainer", 1)
+	md.AssertNotCalled(t, "RemoveContainer", "abc", true)
+}
+
+func TestContainerFailsWhenInitContainerTimesOut(t *testing.T) {
+	_, md, c := setupInitContainer()
+	removeOn(&md.Mock, "WaitForContainer")
+	md.On("WaitForContainer", "abc", mock.Anything).Return(-1, fmt.Errorf("timeout"))
+
+	err := c.Create()
+	assert.Error(t, err)
+
+	md.AssertNumberOfCalls(t, "CreateContainer", 1)
+}
+
+func TestContainerDestroyRemovesFailedInitContainers(t *testing.T) {
+	_, md, c := setupInitContainer()
+	removeOn(&md.Mock, "FindContainerIDs")
+	md.On("FindContainerIDs", "app-init-migrate", config.TypeContainer).Return([]string{"init"}, nil)
+	md.On("FindContainerIDs", "app", config.TypeContainer).Return([]string{"main"}, nil)
+	md.On("RemoveContainer", mock.Anything, mock.Anything).Return(nil)
+
+	err := c.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "RemoveContainer", "init", true)
+	md.AssertCalled(t, "RemoveContainer", "main", false)
+}
+
+func setupPersistedContainer() (*config.Container, *mocks.MockContainerTasks, *Container) {
+	cc := config.NewContainer("grafana")
+	cc.Image = &config.Image{Name: "grafana/grafana:8.1.0"}
+	cc.PersistOnDestroy = true
+
+	md := &mocks.MockContainerTasks{}
+	md.On("PullImage", mock.Anything, false).Return(nil)
+	md.On("CreateContainer", mock.Anything).Return("", nil)
+	md.On("FindContainerIDs", cc.Name, cc.Type).Return([]string{"abc"}, nil)
+	md.On("CommitContainer", mock.Anything, mock.Anything).Return(nil)
+	md.On("RemoveContainer", mock.Anything, mock.Anything).Return(nil)
+
+	return cc, md, NewContainer(cc, md, &mocks.MockHTTP{}, hclog.NewNullLogger())
+}
+
+func TestContainerPersistedCreatesFromPersistedImage(t *testing.T) {
+	cc, md, c := setupPersistedContainer()
+	md.On("FindImageID", "shipyard.run/localcache/grafana-persisted:latest").Return("sha256:abc", nil)
+
+	err := c.Create()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "shipyard.run/localcache/grafana-persisted:latest", cc.Image.Name)
+	md.AssertNotCalled(t, "PullImage", mock.Anything, mock.Anything)
+	md.AssertCalled(t, "CreateContainer", cc)
+}
+
+func TestContainerPersistedCreatesFromImageWhenNotPersisted(t *testing.T) {
+	cc, md, c := setupPersistedContainer()
+	md.On("FindImageID", mock.Anything).Return("", fmt.Errorf("not found"))
+
+	err := c.Create()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "grafana/grafana:8.1.0", cc.Image.Name)
+	md.AssertCalled(t, "PullImage", *cc.Image, false)
+}
+
+func TestContainerPersistedCommitsContainerBeforeDestroy(t *testing.T) {
+	_, md, c := setupPersistedContainer()
+
+	err := c.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CommitContainer", "abc", "shipyard.run/localcache/grafana-persisted:latest")
+	md.AssertCalled(t, "RemoveContainer", "abc", false)
+}
+
+func TestContainerPersistedDoesNotRemoveWhenCommitFails(t *testing.T) {
+	_, md, c := setupPersistedContainer()
+	removeOn(&md.Mock, "CommitContainer")
+	md.On("CommitContainer", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := c.Destroy()
+	assert.Error(t, err)
+
+	md.AssertNotCalled(t, "RemoveContainer", mock.Anything, mock.Anything)
+}
+
+func TestContainerNotPersistedDoesNotCommit(t *testing.T) {
+	cc, md, c := setupPersistedContainer()
+	cc.PersistOnDestroy = false
+
+	err := c.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "CommitContainer", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,164 @@
+package providers
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// Copy provider copies files and directories into containers and volumes
+type Copy struct {
+	config *config.Copy
+	client clients.ContainerTasks
+	getter clients.Getter
+	log    hclog.Logger
+}
+
+// NewCopy creates a new Copy provider
+func NewCopy(c *config.Copy, cc clients.ContainerTasks, g clients.Getter, l hclog.Logger) *Copy {
+	return &Copy{c, cc, g, l}
+}
+
+// Create copies the source to the destination
+func (c *Copy) Create() error {
+	c.log.Info("Copying files", "ref", c.config.Name, "source", c.config.Source, "destination", c.config.Destination)
+
+	mode, err := c.config.FileMode()
+	if err != nil {
+		return err
+	}
+
+	src, err := c.source()
+	if err != nil {
+		return err
+	}
+
+	if c.config.Volume != "" {
+		err = c.client.CopyPathToVolume(c.config.Volume, src, c.config.Destination, mode)
+		if err != nil {
+			return xerrors.Errorf("Unable to copy files to volume %s: %w", c.config.Volume, err)
+		}
+
+		return nil
+	}
+
+	id, err := c.targetID()
+	if err != nil {
+		return err
+	}
+
+	err = c.client.CopyPathToContainer(id, src, c.config.Destination, mode)
+	if err != nil {
+		return xerrors.Errorf("Unable to copy files to %s: %w", c.config.Target, err)
+	}
+
+	return nil
+}
+
+// Destroy statisfies the interface method, the copied files are
+// removed when the target container or the volume is removed
+func (c *Copy) Destroy() error {
+	return nil
+}
+
+// Lookup statisfies the interface method but is not implemented by Copy
+func (c *Copy) Lookup() ([]string, error) {
+	return []string{}, nil
+}
+
+// source returns the local path of the files to copy, remote sources are downloaded
+func (c *Copy) source() (string, error) {
+	if utils.IsLocalFolder(c.config.Source) {
+		return c.config.Source, nil
+	}
+
+	c.log.Debug("Fetching remote source", "ref", c.config.Name, "source", c.config.Source)
+
+	// a file is downloaded to the destination path so it must keep its name, a
+	// directory or archive is downloaded to a folder and its contents are copied
+	tmp, err := utils.ShipyardTemp()
+	if err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(tmp, "copy", c.config.Name, sourceName(c.config.Source))
+
+	err = c.getter.Get(c.config.Source, dst)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to download source: %w", err)
+	}
+
+	if _, err := os.Stat(dst); err != nil {
+		return "", fmt.Errorf("Unable to find downloaded source: %s", err)
+	}
+
+	vf, err := verifyFolder(c.config)
+	if err != nil {
+		return "", err
+	}
+
+	err = verifyDownload(c.getter, c.config.Verify, dst, vf, c.log)
+	if err != nil {
+		return "", err
+	}
+
+	return dst, nil
+}
+
+// targetID returns the id of the container the files are copied to
+func (c *Copy) targetID() (string, error) {
+	target, err := c.config.FindDependentResource(c.config.Target)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to find target: %w", err)
+	}
+
+	name := target.Info().Name
+
+	// files are copied to the server of a cluster
+	if target.Info().Type == config.TypeK8sCluster || target.Info().Type == config.TypeNomadCluster {
+		name = fmt.Sprintf("server.%s", name)
+	}
+
+	ids, err := c.client.FindContainerIDs(name, target.Info().Type)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to find container for %s: %w", c.config.Target, err)
+	}
+
+	if len(ids) != 1 {
+		return "", fmt.Errorf("Unable to find container for %s", c.config.Target)
+	}
+
+	return ids[0], nil
+}
+
+// sourceName returns the last element of the path of a remote source
+// without the go-getter query string e.g. ?ref=main
+func sourceName(src string) string {
+	// remove the forced getter e.g. git::https://
+	if i := strings.Index(src, "::"); i > -1 {
+		src = src[i+2:]
+	}
+
+	p := src
+	if u, err := url.Parse(src); err == nil && u.Path != "" {
+		p = u.Path
+	} else if i := strings.Index(src, "?"); i > -1 {
+		p = src[:i]
+	}
+
+	name := path.Base(strings.TrimRight(p, "/"))
+	if name == "." || name == "/" || name == "" {
+		return "source"
+	}
+
+	return name
+}
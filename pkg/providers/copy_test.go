@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupCopyTests(t *testing.T) (*config.Copy, *mocks.MockContainerTasks) {
+	src := t.TempDir()
+
+	md := &mocks.MockContainerTasks{}
+	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"1234"}, nil)
+	md.On("CopyPathToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	md.On("CopyPathToVolume", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	cp := config.NewCopy("config")
+	cp.Source = src
+	cp.Destination = "/etc/consul.d"
+	cp.Target = "container.consul"
+
+	c := config.New()
+	c.AddResource(config.NewContainer("consul"))
+	c.AddResource(config.NewK8sCluster("k3s"))
+	c.AddResource(cp)
+
+	return cp, md
+}
+
+func TestCopyCopiesToContainer(t *testing.T) {
+	cp, md := setupCopyTests(t)
+	cp.Permissions = "0600"
+
+	p := NewCopy(cp, md, nil, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "FindContainerIDs", "consul", config.TypeContainer)
+	md.AssertCalled(t, "CopyPathToContainer", "1234", cp.Source, "/etc/consul.d", os.FileMode(0600))
+}
+
+func TestCopyCopiesToClusterServer(t *testing.T) {
+	cp, md := setupCopyTests(t)
+	cp.Target = "k8s_cluster.k3s"
+
+	p := NewCopy(cp, md, nil, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "FindContainerIDs", "server.k3s", config.TypeK8sCluster)
+}
+
+func TestCopyCopiesToVolume(t *testing.T) {
+	cp, md := setupCopyTests(t)
+	cp.Target = ""
+	cp.Volume = "config"
+
+	p := NewCopy(cp, md, nil, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CopyPathToVolume", "config", cp.Source, "/etc/consul.d", os.FileMode(0))
+	md.AssertNotCalled(t, "CopyPathToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCopyWithNoContainerReturnsError(t *testing.T) {
+	cp, md := setupCopyTests(t)
+	removeOn(&md.Mock, "FindContainerIDs")
+	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{}, nil)
+
+	p := NewCopy(cp, md, nil, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+}
+
+func TestCopyReturnsErrorWhenCopyFails(t *testing.T) {
+	cp, md := setupCopyTests(t)
+	removeOn(&md.Mock, "CopyPathToContainer")
+	md.On("CopyPathToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	p := NewCopy(cp, md, nil, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+}
+
+func TestCopySourceNameRemovesQuery(t *testing.T) {
+	assert.Equal(t, "config", sourceName("github.com/org/repo//config?ref=main"))
+	assert.Equal(t, "ca.pem", sourceName("https://example.com/certs/ca.pem"))
+	assert.Equal(t, "repo", sourceName("git::https://github.com/org/repo"))
+}
+
+func setupCopyVerifyTests(t *testing.T) (*config.Copy, *mocks.MockContainerTasks) {
+	cp, md := setupCopyTests(t)
+	cp.Source = "https://example.com/tool.tar.gz?archive=false"
+
+	sum := sha256.Sum256([]byte("shipyard"))
+	cp.Verify = &config.Verify{Checksum: "sha256:" + hex.EncodeToString(sum[:])}
+
+	return cp, md
+}
+
+func copyGetter(content string) *mocks.Getter {
+	mg := &mocks.Getter{}
+	mg.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		dst := args.String(1)
+		os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+		ioutil.WriteFile(dst, []byte(content), 0644)
+	}).Return(nil)
+
+	return mg
+}
+
+func TestCopyVerifiesRemoteSource(t *testing.T) {
+	cp, md := setupCopyVerifyTests(t)
+
+	p := NewCopy(cp, md, copyGetter("shipyard"), hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CopyPathToContainer", "1234", mock.Anything, "/etc/consul.d", os.FileMode(0))
+}
+
+func TestCopyWithIncorrectChecksumReturnsError(t *testing.T) {
+	cp, md := setupCopyVerifyTests(t)
+
+	p := NewCopy(cp, md, copyGetter("tampered"), hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum")
+
+	md.AssertNotCalled(t, "CopyPathToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// DockerImage is a provider for building Docker images from a local build context
+type DockerImage struct {
+	config *config.DockerImage
+	client clients.ContainerTasks
+	log    hclog.Logger
+}
+
+// NewDockerImage creates a new DockerImage provider with the given config and client
+func NewDockerImage(c *config.DockerImage, cl clients.ContainerTasks, l hclog.Logger) *DockerImage {
+	return &DockerImage{c, cl, l}
+}
+
+// Create builds the image and stores it in the local Docker cache
+func (d *DockerImage) Create() error {
+	d.log.Info("Building Docker Image", "ref", d.config.Name, "image", d.config.ImageName())
+
+	tag := d.config.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+
+	// the container tasks build images using the container config
+	// create a container config which only contains the build details
+	cc := config.NewContainer(d.config.Name)
+	cc.Build = &config.Build{
+		File:    d.config.File,
+		Context: d.config.Context,
+		Tag:     tag,
+		Args:    d.config.Args,
+
+		Target:      d.config.Target,
+		CacheFrom:   d.config.CacheFrom,
+		InlineCache: d.config.InlineCache,
+		BuildKit:    d.config.BuildKit,
+		Secrets:     d.config.Secrets,
+		SSH:         d.config.SSH,
+	}
+
+	// always build the image when the resource is created so
+	// that any changes to the build context are picked up
+	_, err := d.client.BuildContainer(cc, true)
+	if err != nil {
+		return xerrors.Errorf("Unable to build image: %w", err)
+	}
+
+	return nil
+}
+
+// Destroy is a noop, built images are retained in the local Docker cache
+// so that subsequent runs do not need to rebuild unchanged images
+func (d *DockerImage) Destroy() error {
+	d.log.Info("Destroy Docker Image", "ref", d.config.Name)
+
+	return nil
+}
+
+// Lookup is a noop for Docker images
+func (d *DockerImage) Lookup() ([]string, error) {
+	return nil, nil
+}
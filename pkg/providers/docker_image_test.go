@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupDockerImageTests() (*config.DockerImage, *mocks.MockContainerTasks) {
+	di := config.NewDockerImage("app")
+	di.Context = "./src"
+	di.Args = map[string]string{"VERSION": "1.0"}
+
+	md := &mocks.MockContainerTasks{}
+	md.On("BuildContainer", mock.Anything, true).Return("shipyard.run/localcache/app:latest", nil)
+
+	return di, md
+}
+
+func TestDockerImageBuildsImage(t *testing.T) {
+	di, md := setupDockerImageTests()
+
+	p := NewDockerImage(di, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	cc := getCalls(&md.Mock, "BuildContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "app", cc.Name)
+	assert.Equal(t, "./src", cc.Build.Context)
+	assert.Equal(t, "latest", cc.Build.Tag)
+	assert.Equal(t, "1.0", cc.Build.Args["VERSION"])
+}
+
+func TestDockerImageReturnsErrorWhenBuildFails(t *testing.T) {
+	di, md := setupDockerImageTests()
+	removeOn(&md.Mock, "BuildContainer")
+	md.On("BuildContainer", mock.Anything, true).Return("", fmt.Errorf("boom"))
+
+	p := NewDockerImage(di, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+}
@@ -1,9 +1,10 @@
 package providers
 
 import (
+	"encoding/json"
 	"fmt"
-	"html/template"
 	"io/ioutil"
+	"strings"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
@@ -20,14 +21,15 @@ const terminalVersion = "v0.2.0"
 
 // Docs defines a provider for creating documentation containers
 type Docs struct {
-	config *config.Docs
-	client clients.ContainerTasks
-	log    hclog.Logger
+	config    *config.Docs
+	client    clients.ContainerTasks
+	connector clients.Connector
+	log       hclog.Logger
 }
 
 // NewDocs creates a new Docs provider
-func NewDocs(c *config.Docs, cc clients.ContainerTasks, l hclog.Logger) *Docs {
-	return &Docs{c, cc, l}
+func NewDocs(c *config.Docs, cc clients.ContainerTasks, co clients.Connector, l hclog.Logger) *Docs {
+	return &Docs{c, cc, co, l}
 }
 
 // Create a new documentation container
@@ -83,10 +85,10 @@ func (i *Docs) createDocsContainer() error {
 		)
 	}
 
-	// if the index pages have been set
+	// if the index pages or navigation have been set
 	// generate the javascript
-	if i.config.IndexTitle != "" && len(i.config.IndexPages) > 0 {
-		indexPath, err := i.generateDocusaursIndex(i.config.IndexTitle, i.config.IndexPages)
+	if nav := i.navigation(); len(nav) > 0 {
+		indexPath, err := i.generateDocusaursIndex(nav)
 		if err != nil {
 			return xerrors.Errorf("Unable to generate index for documentation: %w", err)
 		}
@@ -100,14 +102,48 @@ func (i *Docs) createDocsContainer() error {
 		)
 	}
 
+	// generate the config for the terminals which can be embedded in the docs
+	if len(i.config.Terminals) > 0 {
+		terminalsPath, err := i.generateTerminals()
+		if err != nil {
+			return xerrors.Errorf("Unable to generate terminals for documentation: %w", err)
+		}
+
+		cc.Volumes = append(
+			cc.Volumes,
+			config.Volume{
+				Source:      terminalsPath,
+				Destination: "/shipyard/terminals.json",
+			},
+		)
+	}
+
+	docsPort := config.Port{
+		Local:  "80",
+		Remote: "80",
+		Host:   fmt.Sprintf("%d", i.config.Port),
+	}
+
+	// when auth is enabled the documentation is exposed on a random port
+	// and the connector authenticates requests to the documentation port
+	if i.config.Auth != nil {
+		p, err := utils.GetFreePort()
+		if err != nil {
+			return xerrors.Errorf("Unable to find a free port for documentation: %w", err)
+		}
+
+		docsPort.Host = fmt.Sprintf("%d", p)
+
+		// local engines bind to the loopback so the port can not be accessed directly
+		if utils.GetDockerIP() == "127.0.0.1" {
+			docsPort.HostIP = "127.0.0.1"
+		}
+	}
+
 	// add the ports
 	cc.Ports = []config.Port{
 		// set the doumentation port
-		config.Port{
-			Local:  "80",
-			Remote: "80",
-			Host:   fmt.Sprintf("%d", i.config.Port),
-		},
+		docsPort,
 		// set the livereload port
 		config.Port{
 			Local:  "37950",
@@ -125,13 +161,41 @@ func (i *Docs) createDocsContainer() error {
 	}
 
 	_, err = i.client.CreateContainer(cc)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if i.config.Auth != nil {
+		id, err := i.connector.ExposeAuthProxy(
+			fmt.Sprintf("%s.%s", i.config.Type, i.config.Name),
+			fmt.Sprintf(":%d", i.config.Port),
+			fmt.Sprintf("%s:%s", utils.GetDockerIP(), docsPort.Host),
+			i.config.Auth,
+			nil,
+		)
+
+		if err != nil {
+			return xerrors.Errorf("Unable to create auth proxy for documentation: %w", err)
+		}
+
+		i.config.AuthId = id
+	}
+
+	return nil
 }
 
 // Destroy the documentation container
 func (i *Docs) Destroy() error {
 	i.log.Info("Destroy Documentation", "ref", i.config.Name)
 
+	if i.config.AuthId != "" {
+		err := i.connector.RemoveAuthProxy(i.config.AuthId)
+		if err != nil {
+			// do not stop the destroy as the proxy is removed when the connector stops
+			i.log.Warn("Unable to remove auth proxy", "ref", i.config.Name, "id", i.config.AuthId, "error", err)
+		}
+	}
+
 	// remove the docs
 	ids, err := i.client.FindContainerIDs(i.config.Name, i.config.Type)
 	if err != nil {
@@ -162,43 +226,120 @@ func (i *Docs) Lookup() ([]string, error) {
 	return []string{}, nil
 }
 
-func (i *Docs) generateDocusaursIndex(title string, pages []string) (string, error) {
-	tmpFile, err := ioutil.TempFile(utils.ShipyardTemp(), "*.json")
+// navigation returns the categories for the sidebar, the index pages are
+// the first category followed by the navigation blocks
+func (i *Docs) navigation() []config.DocsNavigation {
+	nav := []config.DocsNavigation{}
+
+	if i.config.IndexTitle != "" && len(i.config.IndexPages) > 0 {
+		nav = append(nav, config.DocsNavigation{Title: i.config.IndexTitle, Pages: i.config.IndexPages})
+	}
+
+	return append(nav, i.config.Navigation...)
+}
+
+func (i *Docs) generateDocusaursIndex(nav []config.DocsNavigation) (string, error) {
+	sb := strings.Builder{}
+	sb.WriteString("module.exports = {\n  docs: {\n")
+
+	for n, c := range nav {
+		title, _ := json.Marshal(c.Title)
+		pages, _ := json.MarshalIndent(c.Pages, "    ", "  ")
+
+		sb.WriteString(fmt.Sprintf("    %s: %s", title, pages))
+		if n < len(nav)-1 {
+			sb.WriteString(",")
+		}
+
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("  },\n}\n")
+
+	return writeDocsTemp("*.js", []byte(sb.String()))
+}
+
+// docsTerminal is the config for a terminal read by the documentation, the
+// terminal connects to the terminal websocket of the connector API
+type docsTerminal struct {
+	Target  string `json:"target"`
+	User    string `json:"user"`
+	WorkDir string `json:"workdir"`
+	Shell   string `json:"shell"`
+}
+
+// generateTerminals writes the terminals keyed by name, the target is the
+// container which the connector executes the shell in
+func (i *Docs) generateTerminals() (string, error) {
+	terminals := map[string]docsTerminal{}
+
+	for _, t := range i.config.Terminals {
+		target, err := i.terminalTarget(t.Target)
+		if err != nil {
+			return "", xerrors.Errorf("Unable to find target for terminal %s: %w", t.Name, err)
+		}
+
+		dt := docsTerminal{Target: target, User: t.User, WorkDir: t.WorkDir, Shell: t.Shell}
+
+		if dt.User == "" {
+			dt.User = "root"
+		}
+
+		if dt.WorkDir == "" {
+			dt.WorkDir = "/"
+		}
+
+		if dt.Shell == "" && target != "local" {
+			dt.Shell = "sh"
+		}
+
+		terminals[t.Name] = dt
+	}
+
+	d, err := json.MarshalIndent(terminals, "", "  ")
 	if err != nil {
 		return "", err
 	}
 
-	data := struct {
-		Title string
-		Pages []string
-	}{
-		title,
-		pages,
+	return writeDocsTemp("*.json", d)
+}
+
+// terminalTarget returns the name of the container for the target using the same
+// rules as 'shipyard exec', clusters are attached to the server node
+func (i *Docs) terminalTarget(target string) (string, error) {
+	if target == "" {
+		return "local", nil
 	}
 
-	t := template.Must(template.New("pages").Parse(sideBarsTemplate))
-	err = t.Execute(tmpFile, data)
+	r, err := i.config.FindDependentResource(target)
 	if err != nil {
 		return "", err
 	}
 
-	return tmpFile.Name(), nil
+	switch r.Info().Type {
+	case config.TypeK8sCluster, config.TypeNomadCluster:
+		return utils.FQDN(fmt.Sprintf("server.%s", r.Info().Name), string(r.Info().Type)), nil
+	default:
+		return utils.FQDN(r.Info().Name, string(r.Info().Type)), nil
+	}
 }
 
-var sideBarsTemplate = `
-module.exports = {
-    docs: {
-      {{.Title}}: [
-		{{- $first := true -}}
-		{{- range .Pages -}}
-	 		{{- if $first -}}
-        		{{- $first = false -}}
-    		{{- else -}}
-        		,
-			{{- end}}
-			"{{- .}}"
-		{{- end}}	
-	  ]
-    },
-  }
-`
+func writeDocsTemp(pattern string, data []byte) (string, error) {
+	tmp, err := utils.ShipyardTemp()
+	if err != nil {
+		return "", err
+	}
+
+	tmpFile, err := ioutil.TempFile(tmp, pattern)
+	if err != nil {
+		return "", err
+	}
+	defer tmpFile.Close()
+
+	_, err = tmpFile.Write(data)
+	if err != nil {
+		return "", err
+	}
+
+	return tmpFile.Name(), nil
+}
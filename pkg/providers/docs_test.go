@@ -1,12 +1,14 @@
 package providers
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/utils"
@@ -15,6 +17,12 @@ import (
 )
 
 func setupDocs(t *testing.T) (*Docs, *mocks.MockContainerTasks) {
+	d, md, _ := setupDocsWithConnector(t)
+
+	return d, md
+}
+
+func setupDocsWithConnector(t *testing.T) (*Docs, *mocks.MockContainerTasks, *clients.ConnectorMock) {
 	cc := config.NewDocs("tests")
 	cc.IndexTitle = "test"
 	cc.IndexPages = []string{"abc", "123"}
@@ -27,7 +35,11 @@ func setupDocs(t *testing.T) (*Docs, *mocks.MockContainerTasks) {
 	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return(nil, nil)
 	md.On("RemoveContainer", mock.Anything, true).Return(nil)
 
-	d := NewDocs(cc, md, hclog.NewNullLogger())
+	mc := &clients.ConnectorMock{}
+	mc.On("ExposeAuthProxy", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("docs.tests", nil)
+	mc.On("RemoveAuthProxy", mock.Anything).Return(nil)
+
+	d := NewDocs(cc, md, mc, hclog.NewNullLogger())
 
 	dh := os.Getenv("DOCKER_HOST")
 	os.Unsetenv("DOCKER_HOST")
@@ -36,7 +48,7 @@ func setupDocs(t *testing.T) (*Docs, *mocks.MockContainerTasks) {
 		os.Setenv("DOCKER_HOST", dh)
 	})
 
-	return d, md
+	return d, md, mc
 }
 
 func TestDocsPullsDocsContainer(t *testing.T) {
@@ -85,6 +97,66 @@ func TestDocsGeneratesDocusaurusConfig(t *testing.T) {
 	assert.Contains(t, string(data), `"123"`)
 }
 
+func TestDocsGeneratesNavigationAfterIndex(t *testing.T) {
+	d, md := setupDocs(t)
+	d.config.Navigation = []config.DocsNavigation{
+		{Title: "Getting Started", Pages: []string{"install"}},
+	}
+
+	err := d.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	data, err := ioutil.ReadFile(params.Volumes[1].Source)
+	assert.NoError(t, err)
+	assert.Regexp(t, `(?s)"test": \[.*"abc",.*"123".*\],.*"Getting Started": \[.*"install".*\]`, string(data))
+}
+
+func TestDocsGeneratesTerminals(t *testing.T) {
+	d, md := setupDocs(t)
+
+	c := config.New()
+	c.AddResource(d.config)
+	c.AddResource(config.NewContainer("web"))
+	c.AddResource(config.NewK8sCluster("k3s"))
+
+	d.config.Terminals = []config.DocsTerminal{
+		{Name: "web", Target: "container.web", WorkDir: "/app", Shell: "bash"},
+		{Name: "cluster", Target: "k8s_cluster.k3s"},
+		{Name: "local"},
+	}
+
+	err := d.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "/shipyard/terminals.json", params.Volumes[2].Destination)
+
+	data, err := ioutil.ReadFile(params.Volumes[2].Source)
+	assert.NoError(t, err)
+
+	terminals := map[string]docsTerminal{}
+	err = json.Unmarshal(data, &terminals)
+	assert.NoError(t, err)
+
+	assert.Equal(t, docsTerminal{Target: "web.container.shipyard.run", User: "root", WorkDir: "/app", Shell: "bash"}, terminals["web"])
+	assert.Equal(t, docsTerminal{Target: "server.k3s.k8s-cluster.shipyard.run", User: "root", WorkDir: "/", Shell: "sh"}, terminals["cluster"])
+	assert.Equal(t, docsTerminal{Target: "local", User: "root", WorkDir: "/"}, terminals["local"])
+}
+
+func TestDocsWithMissingTerminalTargetReturnsError(t *testing.T) {
+	d, _ := setupDocs(t)
+
+	c := config.New()
+	c.AddResource(d.config)
+
+	d.config.Terminals = []config.DocsTerminal{{Name: "web", Target: "container.web"}}
+
+	err := d.Create()
+	assert.Error(t, err)
+}
+
 func TestDocsSetsDocsPorts(t *testing.T) {
 	d, md := setupDocs(t)
 
@@ -124,6 +196,30 @@ func TestDocsSetsDocsPortsWithCustomReload(t *testing.T) {
 	assert.Equal(t, "30000", params.Ports[1].Host)
 }
 
+func TestDocsWithAuthExposesDocsThroughProxy(t *testing.T) {
+	d, md, mc := setupDocsWithConnector(t)
+	d.config.Auth = &config.Auth{Basic: &config.BasicAuth{Username: "admin", Password: "secret"}}
+
+	err := d.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	// the container is not published on the docs port
+	assert.NotEqual(t, fmt.Sprintf("%d", d.config.Port), params.Ports[0].Host)
+	assert.Equal(t, "127.0.0.1", params.Ports[0].HostIP)
+
+	mc.AssertCalled(t, "ExposeAuthProxy",
+		"docs.tests",
+		fmt.Sprintf(":%d", d.config.Port),
+		"127.0.0.1:"+params.Ports[0].Host,
+		d.config.Auth,
+		(*config.Headers)(nil),
+	)
+
+	assert.Equal(t, "docs.tests", d.config.AuthId)
+}
+
 func TestDocsSetsTerminalPorts(t *testing.T) {
 	d, md := setupDocs(t)
 
@@ -152,3 +248,13 @@ func TestDestroyRemovesContainers(t *testing.T) {
 	md.AssertNumberOfCalls(t, "FindContainerIDs", 1)
 	md.AssertNumberOfCalls(t, "RemoveContainer", 1)
 }
+
+func TestDestroyRemovesAuthProxy(t *testing.T) {
+	d, _, mc := setupDocsWithConnector(t)
+	d.config.AuthId = "docs.tests"
+
+	err := d.Destroy()
+	assert.NoError(t, err)
+
+	mc.AssertCalled(t, "RemoveAuthProxy", "docs.tests")
+}
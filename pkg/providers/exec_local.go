@@ -1,8 +1,11 @@
 package providers
 
 import (
+	"bytes"
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -38,6 +41,8 @@ func (c *ExecLocal) Create() error {
 		envs = append(envs, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	envs = appendPassthroughEnv(envs, c.config.Config, c.config.EnvPassthrough, c.log)
+
 	// create the folders for logs and pids
 	logPath := filepath.Join(utils.LogsDir(), fmt.Sprintf("exec_%s.log", c.config.Name))
 
@@ -55,6 +60,10 @@ func (c *ExecLocal) Create() error {
 		}
 	}
 
+	if c.config.PTY && c.config.Daemon {
+		c.log.Warn("PTY will be ignored when exec is running in daemon mode")
+	}
+
 	// create the config
 	cc := clients.CommandConfig{
 		Command:          c.config.Command,
@@ -64,19 +73,76 @@ func (c *ExecLocal) Create() error {
 		RunInBackground:  c.config.Daemon,
 		LogFilePath:      logPath,
 		Timeout:          d,
+		PTY:              c.config.PTY,
 	}
 
-	// set the env vars
-	p, err := c.client.Execute(cc)
-	c.config.Pid = p
+	interval, err := c.config.RetryDuration()
+	if err != nil {
+		return fmt.Errorf("Unable to parse Duration for retry_interval: %s", err)
+	}
 
-	c.log.Debug("Started process", "ref", c.config.Name, "pid", c.config.Pid)
+	// retrying is only possible when failures are reported
+	cc.CheckExitCode = c.config.Retries > 0
 
-	if err != nil {
-		return err
+	for i := 0; ; i++ {
+		// capture the output for commands which run in the foreground
+		out := bytes.NewBufferString("")
+		if !c.config.Daemon {
+			cc.Output = out
+		}
+
+		p, err := c.client.Execute(cc)
+		c.config.Pid = p
+
+		c.log.Debug("Started process", "ref", c.config.Name, "pid", c.config.Pid)
+
+		if err == nil {
+			c.config.Output = strings.TrimRight(out.String(), "\r\n")
+			return nil
+		}
+
+		if i >= c.config.Retries {
+			return err
+		}
+
+		c.log.Warn("Command failed, retrying", "ref", c.config.Name, "attempt", i+1, "retries", c.config.Retries, "error", err)
+		time.Sleep(interval)
 	}
+}
 
-	return nil
+// appendPassthroughEnv adds the host environment variables allowed by the resource and
+// the blueprint to envs, variables which are already set take precedence
+func appendPassthroughEnv(envs []string, c *config.Config, patterns []string, l hclog.Logger) []string {
+	var bp *config.Blueprint
+	if c != nil {
+		bp = c.Blueprint
+	}
+
+	pe := config.PassthroughEnv(bp, patterns)
+
+	keys := []string{}
+	for k := range pe {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		set := false
+		for _, e := range envs {
+			if strings.HasPrefix(e, k+"=") {
+				set = true
+				break
+			}
+		}
+
+		if !set {
+			// only the names are logged as the values are often credentials
+			l.Debug("Passing host environment variable to command", "name", k)
+			envs = append(envs, fmt.Sprintf("%s=%s", k, pe[k]))
+		}
+	}
+
+	return envs
 }
 
 // Destroy statisfies the interface method but is not implemented by LocalExec
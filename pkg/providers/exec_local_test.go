@@ -43,6 +43,35 @@ func TestExecLocalExecutesCommandSuccessfully(t *testing.T) {
 	assert.Equal(t, filepath.Join(utils.LogsDir(), "exec_test.log"), params.LogFilePath)
 }
 
+func TestExecLocalPassesThroughHostEnv(t *testing.T) {
+	t.Setenv("SY_TEST_TOKEN", "secret")
+	t.Setenv("abc", "host")
+
+	c, mc := testLocalExecSetupMocks()
+	c.EnvPassthrough = []string{"SY_TEST_*", "abc"}
+
+	p := NewExecLocal(c, mc, hclog.Default())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := mc.Calls[0].Arguments[0].(clients.CommandConfig)
+	assert.Equal(t, []string{"abc=123", "SY_TEST_TOKEN=secret"}, params.Env)
+}
+
+func TestExecLocalExecutesCommandWithPTY(t *testing.T) {
+	c, mc := testLocalExecSetupMocks()
+	c.PTY = true
+
+	p := NewExecLocal(c, mc, hclog.Default())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := mc.Calls[0].Arguments[0].(clients.CommandConfig)
+	assert.True(t, params.PTY)
+}
+
 func TestExecLocalExecutesCommandAndSetsPid(t *testing.T) {
 	c, mc := testLocalExecSetupMocks()
 
@@ -66,6 +95,74 @@ func TestExecLocalExecuteFailsReturnsError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestExecLocalCapturesOutputWhenNotDaemon(t *testing.T) {
+	c, mc := testLocalExecSetupMocks()
+	c.Daemon = false
+
+	removeOn(&mc.Mock, "Execute")
+	mc.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(clients.CommandConfig).Output.Write([]byte("abc123\n"))
+	}).Return(123, nil)
+
+	p := NewExecLocal(c, mc, hclog.Default())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "abc123", c.Output)
+}
+
+func TestExecLocalDoesNotCaptureOutputWhenDaemon(t *testing.T) {
+	c, mc := testLocalExecSetupMocks()
+
+	p := NewExecLocal(c, mc, hclog.Default())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := mc.Calls[0].Arguments[0].(clients.CommandConfig)
+	assert.Nil(t, params.Output)
+	assert.False(t, params.CheckExitCode)
+}
+
+func TestExecLocalRetriesFailedCommand(t *testing.T) {
+	c, mc := testLocalExecSetupMocks()
+	c.Daemon = false
+	c.Retries = 2
+	c.RetryInterval = "1ms"
+
+	removeOn(&mc.Mock, "Execute")
+	mc.On("Execute", mock.Anything).Return(0, fmt.Errorf("boom")).Once()
+	mc.On("Execute", mock.Anything).Return(123, nil)
+
+	p := NewExecLocal(c, mc, hclog.Default())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	mc.AssertNumberOfCalls(t, "Execute", 2)
+
+	params := mc.Calls[0].Arguments[0].(clients.CommandConfig)
+	assert.True(t, params.CheckExitCode)
+}
+
+func TestExecLocalReturnsErrorWhenRetriesExhausted(t *testing.T) {
+	c, mc := testLocalExecSetupMocks()
+	c.Daemon = false
+	c.Retries = 2
+	c.RetryInterval = "1ms"
+
+	removeOn(&mc.Mock, "Execute")
+	mc.On("Execute", mock.Anything).Return(0, fmt.Errorf("boom"))
+
+	p := NewExecLocal(c, mc, hclog.Default())
+
+	err := p.Create()
+	assert.Error(t, err)
+
+	mc.AssertNumberOfCalls(t, "Execute", 3)
+}
+
 func TestExecLocalDestroyCallsStopWhenDaemon(t *testing.T) {
 	c, mc := testLocalExecSetupMocks()
 	c.Pid = 123
@@ -1,7 +1,9 @@
 package providers
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
@@ -51,13 +53,20 @@ func (c *ExecRemote) Create() error {
 			return xerrors.Errorf("Unable to find target: %w", err)
 		}
 
+		name := target.Info().Name
+
+		// pods and allocations are found using the server of the cluster
+		if c.config.Pod != nil || c.config.Allocation != nil {
+			name = fmt.Sprintf("server.%s", name)
+		}
+
 		switch target.Info().Type {
 		case config.TypeK8sCluster:
 			fallthrough
 		case config.TypeNomadCluster:
 			fallthrough
 		case config.TypeContainer:
-			ids, err := c.client.FindContainerIDs(target.Info().Name, target.Info().Type)
+			ids, err := c.client.FindContainerIDs(name, target.Info().Type)
 
 			if err != nil {
 				return xerrors.Errorf("Unable to find remote exec target: %w", err)
@@ -86,6 +95,8 @@ func (c *ExecRemote) Create() error {
 		envs = append(envs, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	envs = appendPassthroughEnv(envs, c.config.Config, c.config.EnvPassthrough, c.log)
+
 	user := ""
 	group := ""
 
@@ -94,7 +105,22 @@ func (c *ExecRemote) Create() error {
 		group = c.config.RunAs.Group
 	}
 
-	err := c.client.ExecuteCommand(targetID, command, envs, c.config.WorkingDirectory, user, group, c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
+	workingDirectory := c.config.WorkingDirectory
+
+	// commands in pods and allocations are executed with the cluster CLI in the server
+	// container, the environment and working directory are set by wrapping the command
+	if c.config.Pod != nil || c.config.Allocation != nil {
+		cmd, err := c.clusterExecCommand(targetID, wrapCommand(command, envs, workingDirectory))
+		if err != nil {
+			return err
+		}
+
+		command = cmd
+		envs = []string{}
+		workingDirectory = ""
+	}
+
+	err := c.client.ExecuteCommand(targetID, command, envs, workingDirectory, user, group, c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
 	if err != nil {
 		c.log.Error("Error executing command", "ref", c.config.Name, "image", c.config.Image, "command", c.config.Command, "args", c.config.Arguments)
 		err = xerrors.Errorf("Unable to execute command: in remote container: %w", err)
@@ -108,6 +134,115 @@ func (c *ExecRemote) Create() error {
 	return err
 }
 
+// clusterExecCommand returns the command which executes the given command in
+// the selected pod or allocation when run in the server container of the cluster
+func (c *ExecRemote) clusterExecCommand(serverID string, command []string) ([]string, error) {
+	target, err := c.config.FindDependentResource(c.config.Target)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to find target: %w", err)
+	}
+
+	if c.config.Pod != nil {
+		kc, ok := target.(*config.K8sCluster)
+		if !ok {
+			return nil, xerrors.Errorf("Target %s is not a Kubernetes cluster", c.config.Target)
+		}
+
+		kubeconfig := fmt.Sprintf("--kubeconfig=%s", k3sKubeConfig)
+		if kc.Driver == config.K8sDriverKind {
+			kubeconfig = fmt.Sprintf("--kubeconfig=%s", kindKubeConfig)
+		}
+
+		namespace := c.config.Pod.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+
+		out, err := c.clusterOutput(serverID, []string{
+			"kubectl", kubeconfig, "get", "pods",
+			"-n", namespace,
+			"-l", c.config.Pod.Selector,
+			"--field-selector=status.phase=Running",
+			"-o", "jsonpath={.items[*].metadata.name}",
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to list pods: %w", err)
+		}
+
+		pods := strings.Fields(out)
+		if len(pods) == 0 {
+			return nil, xerrors.Errorf("Unable to find a running pod in namespace %s matching %s", namespace, c.config.Pod.Selector)
+		}
+
+		c.log.Debug("Executing command in pod", "ref", c.config.Name, "pod", pods[0], "namespace", namespace)
+
+		exec := []string{"kubectl", kubeconfig, "exec", "-n", namespace, pods[0]}
+		if c.config.Pod.Container != "" {
+			exec = append(exec, "-c", c.config.Pod.Container)
+		}
+
+		return append(append(exec, "--"), command...), nil
+	}
+
+	a := c.config.Allocation
+
+	out, err := c.clusterOutput(serverID, []string{
+		"nomad", "job", "allocs",
+		"-t", `{{range .}}{{if eq .ClientStatus "running"}}{{.ID}} {{.TaskGroup}}{{"\n"}}{{end}}{{end}}`,
+		a.Job,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to list allocations: %w", err)
+	}
+
+	alloc := ""
+	for _, l := range strings.Split(out, "\n") {
+		parts := strings.Fields(l)
+		if len(parts) != 2 {
+			continue
+		}
+
+		if a.Group == "" || parts[1] == a.Group {
+			alloc = parts[0]
+			break
+		}
+	}
+
+	if alloc == "" {
+		return nil, xerrors.Errorf("Unable to find a running allocation for job %s", a.Job)
+	}
+
+	c.log.Debug("Executing command in allocation", "ref", c.config.Name, "allocation", alloc, "task", a.Task)
+
+	return append([]string{"nomad", "alloc", "exec", "-i=false", "-t=false", "-task", a.Task, alloc}, command...), nil
+}
+
+// clusterOutput executes the command in the server container and returns the output
+func (c *ExecRemote) clusterOutput(serverID string, command []string) (string, error) {
+	out := bytes.NewBufferString("")
+
+	err := c.client.ExecuteCommand(serverID, command, nil, "/", "", "", out)
+	if err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+// wrapCommand sets the environment variables and working directory for a command
+// in a target where they can not be passed to the exec
+func wrapCommand(command, envs []string, workingDirectory string) []string {
+	if len(envs) > 0 {
+		command = append(append([]string{"env"}, envs...), command...)
+	}
+
+	if workingDirectory != "" {
+		command = append([]string{"sh", "-c", `cd "$0" && exec "$@"`, workingDirectory}, command...)
+	}
+
+	return command
+}
+
 func (c *ExecRemote) createRemoteExecContainer() (string, error) {
 	// generate the ID for the new container based on the clock time and a string
 	cc := config.NewContainer(fmt.Sprintf("%s.remote_exec", c.config.Name))
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"fmt"
+	"io"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
@@ -191,3 +192,93 @@ func TestRemoteExecDoesNOTRemovesContainerWhenTarget(t *testing.T) {
 	assert.NoError(t, err)
 	md.AssertNotCalled(t, "RemoveContainer", mock.Anything)
 }
+
+func testRemoteExecClusterSetup(output string) (*config.ExecRemote, *mocks.MockContainerTasks) {
+	trex, _, md := testRemoteExecSetupMocks()
+	trex.Image = nil
+	trex.WorkingDirectory = "/data"
+
+	k8s := config.NewK8sCluster("k3s")
+	k8s.Driver = config.K8sDriverK3s
+	trex.Config.AddResource(k8s)
+
+	nomad := config.NewNomadCluster("dev")
+	trex.Config.AddResource(nomad)
+
+	removeOn(&md.Mock, "ExecuteCommand")
+	md.On("ExecuteCommand", mock.Anything, mock.MatchedBy(func(c []string) bool {
+		return c[0] == "kubectl" && c[2] == "get" || c[0] == "nomad" && c[2] == "allocs"
+	}), mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(6).(io.Writer).Write([]byte(output))
+	}).Return(nil)
+	md.On("ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	return trex, md
+}
+
+func TestRemoteExecWithPodExecutesInServer(t *testing.T) {
+	trex, md := testRemoteExecClusterSetup("postgres-0 postgres-1")
+	trex.Target = "k8s_cluster.k3s"
+	trex.Pod = &config.ExecRemotePod{Selector: "app=postgres", Container: "db"}
+
+	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+	md.AssertCalled(t, "FindContainerIDs", "server.k3s", config.TypeK8sCluster)
+
+	args := getCalls(&md.Mock, "ExecuteCommand")[0].Arguments
+	assert.Contains(t, args[1], "app=postgres")
+	assert.Contains(t, args[1], "default")
+
+	args = getCalls(&md.Mock, "ExecuteCommand")[1].Arguments
+	assert.Equal(t, []string{
+		"kubectl", "--kubeconfig=/output/kubeconfig.yaml", "exec", "-n", "default", "postgres-0", "-c", "db", "--",
+		"sh", "-c", `cd "$0" && exec "$@"`, "/data",
+		"env", "abc=123",
+		"tail", "-f", "/dev/null",
+	}, args[1])
+	assert.Empty(t, args[2])
+	assert.Equal(t, "", args[3])
+	md.AssertNotCalled(t, "RemoveContainer", mock.Anything, mock.Anything)
+}
+
+func TestRemoteExecWithPodNoRunningPodsReturnsError(t *testing.T) {
+	trex, md := testRemoteExecClusterSetup("")
+	trex.Target = "k8s_cluster.k3s"
+	trex.Pod = &config.ExecRemotePod{Selector: "app=postgres"}
+
+	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+	md.AssertNumberOfCalls(t, "ExecuteCommand", 1)
+}
+
+func TestRemoteExecWithAllocationExecutesInServer(t *testing.T) {
+	trex, md := testRemoteExecClusterSetup("abc123 web\ndef456 db\n")
+	trex.Target = "nomad_cluster.dev"
+	trex.WorkingDirectory = ""
+	trex.Environment = nil
+	trex.Allocation = &config.ExecRemoteAllocation{Job: "app", Group: "db", Task: "postgres"}
+
+	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+	md.AssertCalled(t, "FindContainerIDs", "server.dev", config.TypeNomadCluster)
+
+	args := getCalls(&md.Mock, "ExecuteCommand")[1].Arguments
+	assert.Equal(t, []string{"nomad", "alloc", "exec", "-i=false", "-t=false", "-task", "postgres", "def456", "tail", "-f", "/dev/null"}, args[1])
+}
+
+func TestRemoteExecWithAllocationNotFoundReturnsError(t *testing.T) {
+	trex, md := testRemoteExecClusterSetup("abc123 web\n")
+	trex.Target = "nomad_cluster.dev"
+	trex.Allocation = &config.ExecRemoteAllocation{Job: "app", Group: "db", Task: "postgres"}
+
+	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package providers
 
 import (
+	"path/filepath"
+	"strings"
 	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -38,29 +40,59 @@ func (h *Helm) Create() error {
 		h.config.Namespace = "default"
 	}
 
+	chart := h.config.Chart
+
 	// is this chart ot be loaded from a repository?
-	if h.config.Repository != nil {
+	if h.config.Repository != nil && h.config.Repository.IsOCI() {
+		// charts in OCI registries are referenced by the full path
+		chart = strings.TrimSuffix(h.config.Repository.URL, "/") + "/" + h.config.Chart
+
+		if h.config.Repository.Username != "" {
+			h.log.Debug("Logging in to Helm chart registry", "name", h.config.Repository.Name, "url", h.config.Repository.URL)
+
+			err := h.helmClient.RegistryLogin(h.config.Repository.URL, h.config.Repository.Username, h.config.Repository.Password)
+			if err != nil {
+				return xerrors.Errorf("unable to login to chart registry: %w", err)
+			}
+		}
+	} else if h.config.Repository != nil {
 		h.log.Debug("Updating Helm chart repository", "name", h.config.Repository.Name, "url", h.config.Repository.URL)
 
-		err := h.helmClient.UpsertChartRepository(h.config.Repository.Name, h.config.Repository.URL)
+		err := h.helmClient.UpsertChartRepository(h.config.Repository.Name, h.config.Repository.URL, h.config.Repository.Username, h.config.Repository.Password)
 		if err != nil {
 			return xerrors.Errorf("unable to initialize chart repository: %w", err)
 		}
 	}
 
 	// is the source a helm repo which should be downloaded?
-	if !utils.IsLocalFolder(h.config.Chart) && h.config.Repository == nil {
+	if !utils.IsLocalFolder(h.config.Chart) && h.config.Repository == nil && !strings.HasPrefix(h.config.Chart, "oci://") {
 		h.log.Debug("Fetching remote Helm chart", "ref", h.config.Name, "chart", h.config.Chart)
 
 		helmFolder := utils.GetHelmLocalFolder(h.config.Chart)
 
+		// a verified chart is downloaded as an archive which is installed without extracting it
+		if h.config.Verify != nil {
+			helmFolder = filepath.Join(helmFolder, sourceName(h.config.Chart))
+		}
+
 		err := h.getterClient.Get(h.config.Chart, helmFolder)
 		if err != nil {
 			return xerrors.Errorf("Unable to download remote chart: %w", err)
 		}
 
+		vf, err := verifyFolder(h.config)
+		if err != nil {
+			return err
+		}
+
+		err = verifyDownload(h.getterClient, h.config.Verify, helmFolder, vf, h.log)
+		if err != nil {
+			return err
+		}
+
 		// set the config to the local path
 		h.config.Chart = helmFolder
+		chart = helmFolder
 	}
 
 	// set the KubeConfig for the kubernetes client
@@ -75,27 +107,45 @@ func (h *Helm) Create() error {
 	newName, _ := utils.ReplaceNonURIChars(h.config.ChartName)
 	h.config.ChartName = newName
 
-	failCount := 0
+	// the number of attempts is set on the resource, the delay between
+	// attempts and the default attempts come from the user config
+	retry := resourceRetry(nil)
+	attempts := h.config.Retry
+	if attempts == 0 && retry != nil {
+		attempts = retry.MaxAttempts()
+	}
+
+	timeout := resourceTimeout(h.config.Timeout, 0)
+	start := time.Now()
 
-	for {
+	for attempt := 1; ; attempt++ {
 		err = h.helmClient.Create(
 			kcPath, h.config.ChartName,
 			h.config.Namespace, h.config.CreateNamespace,
 			h.config.SkipCRDs,
-			h.config.Chart, h.config.Version,
-			h.config.Values, h.config.ValuesString)
+			chart, h.config.Version,
+			h.config.Values, h.config.ValuesMapValue(), h.config.ValuesString)
 
 		if err == nil {
 			break
-
 		}
-		failCount++
 
-		if failCount >= h.config.Retry {
+		if attempt >= attempts {
 			return err
-		} else {
-			h.log.Debug("Chart apply failed, retrying", "error", err)
 		}
+
+		var wait time.Duration
+		if retry != nil {
+			wait = retry.Wait(attempt)
+		}
+
+		if timeout > 0 && time.Since(start)+wait > timeout {
+			return xerrors.Errorf("timeout installing chart after %d attempts: %w", attempt, err)
+		}
+
+		h.log.Debug("Chart apply failed, retrying", "error", err, "attempt", attempt, "backoff", wait)
+
+		time.Sleep(wait)
 	}
 
 	// we can now health check the install
@@ -132,7 +182,14 @@ func (h *Helm) Destroy() error {
 	h.config.ChartName = newName
 
 	// get the target cluster
-	h.helmClient.Destroy(kcPath, h.config.ChartName, h.config.Namespace)
+	err = h.helmClient.Destroy(kcPath, h.config.ChartName, h.config.Namespace, h.config.Destroy.TimeoutDuration(), h.config.Destroy.ForceRemove())
+
+	// only fail when the chart resources are not removed from the cluster, a chart
+	// which has already been removed should not cause the destroy to fail
+	var te clients.DeletionTimeoutError
+	if xerrors.As(err, &te) {
+		return xerrors.Errorf("Unable to destroy Helm chart: %w", err)
+	}
 
 	if err != nil {
 		h.log.Debug("There was a problem destroying Helm chart, logging message but ignoring error", "ref", h.config.Name, "error", err)
@@ -152,6 +209,10 @@ func (h *Helm) getKubeConfigPath() (string, error) {
 		return "", xerrors.Errorf("Unable to find cluster: %w", err)
 	}
 
-	_, destPath, _ := utils.CreateKubeConfigPath(target.Info().Name)
+	_, destPath, _, err := utils.CreateKubeConfigPath(target.Info().Name)
+	if err != nil {
+		return "", err
+	}
+
 	return destPath, nil
 }
@@ -1,10 +1,13 @@
 package providers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
@@ -17,13 +20,12 @@ import (
 
 func setupHelm() (*mocks.MockHelm, *clients.MockKubernetes, *mocks.Getter, *config.Config, *Helm) {
 	mh := &mocks.MockHelm{}
-	mh.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
-	mh.On("Destroy", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-	mh.On("UpsertChartRepository", mock.Anything, mock.Anything).Return(nil)
+	mh.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mh.On("Destroy", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mh.On("UpsertChartRepository", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mh.On("RegistryLogin", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-	kc := &clients.MockKubernetes{}
-	kc.On("SetConfig", mock.Anything).Return(nil)
-	kc.On("HealthCheckPods", mock.Anything, mock.Anything).Return(nil)
+	kc := setupHelmKubernetes()
 
 	mg := &mocks.Getter{}
 	mg.On("Get", mock.Anything, mock.Anything).Return(nil)
@@ -43,6 +45,14 @@ func setupHelm() (*mocks.MockHelm, *clients.MockKubernetes, *mocks.Getter, *conf
 	return mh, kc, mg, c, p
 }
 
+func setupHelmKubernetes() *clients.MockKubernetes {
+	kc := &clients.MockKubernetes{}
+	kc.On("SetConfig", mock.Anything).Return(nil)
+	kc.On("HealthCheckPods", mock.Anything, mock.Anything).Return(nil)
+
+	return kc
+}
+
 func TestHelmCreateCantFindClusterReturnsError(t *testing.T) {
 	_, _, _, c, p := setupHelm()
 	c.RemoveResource(c.Resources[0])
@@ -59,7 +69,7 @@ func TestHelmCreateSantisesChartName(t *testing.T) {
 	err := p.Create()
 	assert.NoError(t, err)
 
-	mh.AssertCalled(t, "Create", mock.Anything, "chart-test", mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mh.AssertCalled(t, "Create", mock.Anything, "chart-test", mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestHelmCreateGetsHelmRepo(t *testing.T) {
@@ -74,8 +84,65 @@ func TestHelmCreateGetsHelmRepo(t *testing.T) {
 	assert.NoError(t, err)
 
 	mg.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
-	mh.AssertCalled(t, "UpsertChartRepository", "hashicorp", "http://something.com")
-	mh.AssertCalled(t, "Create", mock.Anything, "test", mock.Anything, mock.Anything, true, "hashicorp/vault", "v1.0.0", mock.Anything, mock.Anything)
+	mh.AssertCalled(t, "UpsertChartRepository", "hashicorp", "http://something.com", "", "")
+	mh.AssertCalled(t, "Create", mock.Anything, "test", mock.Anything, mock.Anything, true, "hashicorp/vault", "v1.0.0", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHelmCreateGetsHelmRepoWithCredentials(t *testing.T) {
+	mh, _, _, c, p := setupHelm()
+	hc, _ := c.FindResource("helm.test")
+
+	hc.(*config.Helm).Repository = &config.HelmRepository{URL: "http://something.com", Name: "private", Username: "admin", Password: "secret"}
+	hc.(*config.Helm).Chart = "private/app"
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	mh.AssertCalled(t, "UpsertChartRepository", "private", "http://something.com", "admin", "secret")
+}
+
+func TestHelmCreateWithOCIRepoLogsInAndUsesFullReference(t *testing.T) {
+	mh, _, mg, c, p := setupHelm()
+	hc, _ := c.FindResource("helm.test")
+
+	hc.(*config.Helm).Repository = &config.HelmRepository{URL: "oci://ghcr.io/org/charts/", Name: "org", Username: "admin", Password: "token"}
+	hc.(*config.Helm).Chart = "app"
+	hc.(*config.Helm).Version = "1.2.0"
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	mg.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	mh.AssertNotCalled(t, "UpsertChartRepository", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mh.AssertCalled(t, "RegistryLogin", "oci://ghcr.io/org/charts/", "admin", "token")
+	mh.AssertCalled(t, "Create", mock.Anything, "test", mock.Anything, mock.Anything, true, "oci://ghcr.io/org/charts/app", "1.2.0", mock.Anything, mock.Anything, mock.Anything)
+
+	// the chart in the config is not modified
+	assert.Equal(t, "app", hc.(*config.Helm).Chart)
+}
+
+func TestHelmCreateWithOCIChartDoesNotDownload(t *testing.T) {
+	mh, _, mg, c, p := setupHelm()
+	hc, _ := c.FindResource("helm.test")
+	hc.(*config.Helm).Chart = "oci://ghcr.io/org/charts/app"
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	mg.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	mh.AssertNotCalled(t, "RegistryLogin", mock.Anything, mock.Anything, mock.Anything)
+	mh.AssertCalled(t, "Create", mock.Anything, "test", mock.Anything, mock.Anything, true, "oci://ghcr.io/org/charts/app", "", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHelmCreatePassesValuesMap(t *testing.T) {
+	mh, _, _, _, p := setupHelm()
+	values := map[string]interface{}{"server": map[string]interface{}{"replicas": float64(3)}}
+	p.config.ValuesMap = values
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	mh.AssertCalled(t, "Create", mock.Anything, "test", mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, values, mock.Anything)
 }
 
 func TestHelmCreateGetsRemoteRepo(t *testing.T) {
@@ -89,7 +156,43 @@ func TestHelmCreateGetsRemoteRepo(t *testing.T) {
 	assert.NoError(t, err)
 
 	mg.AssertCalled(t, "Get", mock.Anything, helmFolder)
-	mh.AssertCalled(t, "Create", mock.Anything, "test", mock.Anything, mock.Anything, true, helmFolder, "", mock.Anything, mock.Anything)
+	mh.AssertCalled(t, "Create", mock.Anything, "test", mock.Anything, mock.Anything, true, helmFolder, "", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHelmCreateVerifiesRemoteChart(t *testing.T) {
+	mh, _, _, c, _ := setupHelm()
+	hc, _ := c.FindResource("helm.test")
+	h := hc.(*config.Helm)
+	h.Chart = "https://example.com/charts/consul-0.1.0.tgz?archive=false"
+
+	sum := sha256.Sum256([]byte("chart"))
+	h.Verify = &config.Verify{Checksum: "sha256:" + hex.EncodeToString(sum[:])}
+
+	p := NewHelm(h, setupHelmKubernetes(), mh, copyGetter("chart"), hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	chart := filepath.Join(utils.GetHelmLocalFolder("https://example.com/charts/consul-0.1.0.tgz?archive=false"), "consul-0.1.0.tgz")
+	mh.AssertCalled(t, "Create", mock.Anything, "test", mock.Anything, mock.Anything, true, chart, "", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHelmCreateWithIncorrectChecksumDoesNotInstallChart(t *testing.T) {
+	mh, _, _, c, _ := setupHelm()
+	hc, _ := c.FindResource("helm.test")
+	h := hc.(*config.Helm)
+	h.Chart = "https://example.com/charts/consul-0.1.0.tgz?archive=false"
+
+	sum := sha256.Sum256([]byte("chart"))
+	h.Verify = &config.Verify{Checksum: "sha256:" + hex.EncodeToString(sum[:])}
+
+	p := NewHelm(h, setupHelmKubernetes(), mh, copyGetter("tampered"), hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum")
+
+	mh.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestHelmCreateSetsConfig(t *testing.T) {
@@ -98,7 +201,7 @@ func TestHelmCreateSetsConfig(t *testing.T) {
 	err := p.Create()
 	assert.NoError(t, err)
 
-	_, fp, _ := utils.CreateKubeConfigPath("tester")
+	_, fp, _, _ := utils.CreateKubeConfigPath("tester")
 	kc.AssertCalled(t, "SetConfig", fp)
 	mg.AssertNotCalled(t, "Get")
 }
@@ -129,6 +232,7 @@ func TestHelmCreateCallsCreateWithDefaultNamespace(t *testing.T) {
 		p.config.Chart,
 		"",
 		p.config.Values,
+		map[string]interface{}(nil),
 		p.config.ValuesString,
 	)
 }
@@ -151,6 +255,7 @@ func TestHelmCreateCallsCreateWithCustomNamespace(t *testing.T) {
 		p.config.Chart,
 		"",
 		p.config.Values,
+		map[string]interface{}(nil),
 		p.config.ValuesString,
 	)
 }
@@ -160,19 +265,46 @@ func TestHelmCreateCallCreateFailRetries(t *testing.T) {
 	p.config.Retry = 2
 
 	removeOn(&hm.Mock, "Create")
-	hm.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Once().Return(fmt.Errorf("boom"))
-	hm.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Once().Return(nil)
+	hm.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Once().Return(fmt.Errorf("boom"))
+	hm.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Once().Return(nil)
 
 	err := p.Create()
 	assert.NoError(t, err)
 	hm.AssertNumberOfCalls(t, "Create", 2)
 }
 
+func TestHelmCreateRetriesWithPolicyFromUserConfig(t *testing.T) {
+	setupResourceDefaults(t, &config.ResourceDefaults{Retry: &config.Retry{Attempts: 3, Delay: "1ms"}})
+	hm, _, _, _, p := setupHelm()
+
+	removeOn(&hm.Mock, "Create")
+	hm.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := p.Create()
+	assert.Error(t, err)
+	hm.AssertNumberOfCalls(t, "Create", 3)
+}
+
+func TestHelmCreateStopsRetryingWhenTimeoutExceeded(t *testing.T) {
+	setupResourceDefaults(t, &config.ResourceDefaults{Retry: &config.Retry{Delay: "1m"}})
+	hm, _, _, _, p := setupHelm()
+	p.config.Retry = 5
+	p.config.Timeout = "10s"
+
+	removeOn(&hm.Mock, "Create")
+	hm.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := p.Create()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout installing chart after 1 attempts")
+	hm.AssertNumberOfCalls(t, "Create", 1)
+}
+
 func TestHelmCreateCallCreateFailReturnsError(t *testing.T) {
 	hm, _, _, _, p := setupHelm()
 
 	removeOn(&hm.Mock, "Create")
-	hm.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+	hm.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, true, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
 
 	err := p.Create()
 	assert.Error(t, err)
@@ -219,18 +351,18 @@ func TestHelmDestroyCallsDestroyWithDefaultNamespace(t *testing.T) {
 
 	err := p.Destroy()
 	assert.NoError(t, err)
-	hm.AssertCalled(t, "Destroy", mock.Anything, mock.Anything, "default")
+	hm.AssertCalled(t, "Destroy", mock.Anything, mock.Anything, "default", config.DefaultK8sDestroyTimeout, false)
 }
 
 func TestHelmDestroyWithErrorSwallowsError(t *testing.T) {
 	hm, _, _, _, p := setupHelm()
 	p.config.Namespace = "custom"
 	removeOn(&hm.Mock, "Destroy")
-	hm.On("Destroy", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+	hm.On("Destroy", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
 
 	err := p.Destroy()
 	assert.NoError(t, err)
-	hm.AssertCalled(t, "Destroy", mock.Anything, mock.Anything, "custom")
+	hm.AssertCalled(t, "Destroy", mock.Anything, mock.Anything, "custom", mock.Anything, mock.Anything)
 }
 
 func TestHelmDestroySantisesChartName(t *testing.T) {
@@ -241,5 +373,23 @@ func TestHelmDestroySantisesChartName(t *testing.T) {
 	err := p.Destroy()
 	assert.NoError(t, err)
 
-	mh.AssertCalled(t, "Destroy", mock.Anything, "chart-test", mock.Anything)
+	mh.AssertCalled(t, "Destroy", mock.Anything, "chart-test", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHelmDestroyCallsDestroyWithDestroyOptions(t *testing.T) {
+	hm, _, _, _, p := setupHelm()
+	p.config.Destroy = &config.K8sDestroy{Timeout: "30s", ForceRemoveFinalizers: true}
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+	hm.AssertCalled(t, "Destroy", mock.Anything, mock.Anything, mock.Anything, 30*time.Second, true)
+}
+
+func TestHelmDestroyWithDeletionTimeoutReturnsError(t *testing.T) {
+	hm, _, _, _, p := setupHelm()
+	removeOn(&hm.Mock, "Destroy")
+	hm.On("Destroy", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(clients.DeletionTimeoutError{Resources: []string{"namespace/consul"}})
+
+	err := p.Destroy()
+	assert.Error(t, err)
 }
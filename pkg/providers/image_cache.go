@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"path/filepath"
+	"strings"
 
 	"github.com/docker/docker/api/types"
 	"github.com/hashicorp/go-hclog"
@@ -15,6 +16,10 @@ import (
 
 const cacheImage = "shipyardrun/docker-registry-proxy:0.6.3"
 
+// cacheRegistries are the registries which are always proxied by the cache
+// in addition to Docker Hub
+var cacheRegistries = []string{"k8s.gcr.io", "gcr.io", "asia.gcr.io", "eu.gcr.io", "us.gcr.io", "quay.io", "ghcr.io", "docker.pkg.github.com"}
+
 type ImageCache struct {
 	config     *config.ImageCache
 	client     clients.ContainerTasks
@@ -84,6 +89,8 @@ func (c *ImageCache) createImageCache(networks []string) (string, error) {
 		return "", err
 	}
 
+	registries, auth := c.registryAuth()
+
 	// create the container
 	cc := config.NewContainer(c.config.Name)
 	cc.Type = c.config.Type
@@ -102,10 +109,18 @@ func (c *ImageCache) createImageCache(networks []string) (string, error) {
 		"CA_CRT_FILE":           "/cache/ca/root.cert",
 		"DOCKER_MIRROR_CACHE":   "/cache/docker",
 		"ENABLE_MANIFEST_CACHE": "true",
-		"REGISTRIES":            "k8s.gcr.io gcr.io asia.gcr.io eu.gcr.io us.gcr.io quay.io ghcr.io docker.pkg.github.com",
+		"REGISTRIES":            strings.Join(registries, " "),
 		"ALLOW_PUSH":            "true",
 	}
 
+	// add the credentials for any private registries, custom delimiters are used
+	// as passwords and tokens can contain the default delimiters
+	if len(auth) > 0 {
+		cc.EnvVar["AUTH_REGISTRIES"] = strings.Join(auth, ";;;")
+		cc.EnvVar["AUTH_REGISTRIES_DELIMITER"] = ";;;"
+		cc.EnvVar["AUTH_REGISTRY_DELIMITER"] = ":::"
+	}
+
 	// expose the docker proxy port on a random port num
 	cc.Ports = []config.Port{
 		config.Port{
@@ -126,6 +141,71 @@ func (c *ImageCache) createImageCache(networks []string) (string, error) {
 	return c.client.CreateContainer(cc)
 }
 
+// registryAuth returns the registries the cache proxies and the credentials for
+// any of those registries which require authentication. As well as the default
+// registries, the cache proxies the registry of any image used in the config.
+func (c *ImageCache) registryAuth() ([]string, []string) {
+	registries := append([]string{}, cacheRegistries...)
+	images := []config.Image{}
+
+	if c.config.Config != nil {
+		for _, r := range c.config.Config.Resources {
+			switch v := r.(type) {
+			case *config.Container:
+				if v.Image != nil {
+					images = append(images, *v.Image)
+				}
+
+				for _, ic := range v.InitContainers {
+					images = append(images, ic.Image)
+				}
+			case *config.Sidecar:
+				images = append(images, v.Image)
+			case *config.K8sCluster:
+				images = append(images, v.Images...)
+			case *config.NomadCluster:
+				images = append(images, v.Images...)
+			}
+		}
+	}
+
+	// credentials set on an image block take precedence
+	creds := map[string]*clients.RegistryCredentials{}
+	for _, i := range images {
+		reg := clients.ImageRegistry(i.Name)
+		if reg != "docker.io" && !contains(registries, reg) {
+			registries = append(registries, reg)
+		}
+
+		if i.Username != "" && i.Password != "" && creds[reg] == nil {
+			creds[reg] = &clients.RegistryCredentials{Registry: reg, Username: i.Username, Password: i.Password}
+		}
+	}
+
+	auth := []string{}
+	for _, reg := range append([]string{"docker.io"}, registries...) {
+		rc := creds[reg]
+		if rc == nil {
+			rc = clients.LookupRegistryHostCredentials(reg)
+		}
+
+		if rc == nil {
+			continue
+		}
+
+		// the cache authenticates with Docker Hub using the auth server
+		host := reg
+		if host == "docker.io" {
+			host = "auth.docker.io"
+		}
+
+		c.log.Debug("Adding credentials for registry to cache", "registry", reg, "username", rc.Username)
+		auth = append(auth, strings.Join([]string{host, rc.Username, rc.Password}, ":::"))
+	}
+
+	return registries, auth
+}
+
 func (c *ImageCache) Destroy() error {
 	c.log.Info("Destroy ImageCache", "ref", c.config.Name)
 
@@ -106,6 +106,47 @@ func TestImageCacheCreateAddsEnvironmentVariables(t *testing.T) {
 	assert.Equal(t, conf.EnvVar["ALLOW_PUSH"], "true")
 }
 
+func TestImageCacheCreateAddsRegistriesAndCredentialsForImages(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	t.Setenv("SHIPYARD_REGISTRY_DOCKER_IO_USERNAME", "hubuser")
+	t.Setenv("SHIPYARD_REGISTRY_DOCKER_IO_PASSWORD", "hubpass")
+
+	cc, md, hc := setupImageCacheTests(t)
+
+	co := config.NewContainer("private")
+	co.Image = &config.Image{Name: "artifactory.acme.com/team/app:1.0", Username: "acme", Password: "s3cr:t"}
+	cc.Config.AddResource(co)
+
+	k8s := config.NewK8sCluster("k3s")
+	k8s.Images = []config.Image{{Name: "ghcr.io/acme/api:latest", Username: "gh", Password: "token"}}
+	cc.Config.AddResource(k8s)
+
+	c := NewImageCache(cc, md, hc, hclog.NewNullLogger())
+	err := c.Create()
+	assert.NoError(t, err)
+
+	conf := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.Equal(t, "k8s.gcr.io gcr.io asia.gcr.io eu.gcr.io us.gcr.io quay.io ghcr.io docker.pkg.github.com artifactory.acme.com", conf.EnvVar["REGISTRIES"])
+	assert.Equal(t, "auth.docker.io:::hubuser:::hubpass;;;ghcr.io:::gh:::token;;;artifactory.acme.com:::acme:::s3cr:t", conf.EnvVar["AUTH_REGISTRIES"])
+	assert.Equal(t, ";;;", conf.EnvVar["AUTH_REGISTRIES_DELIMITER"])
+	assert.Equal(t, ":::", conf.EnvVar["AUTH_REGISTRY_DELIMITER"])
+}
+
+func TestImageCacheCreateWithoutCredentialsDoesNotSetAuth(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	cc, md, hc := setupImageCacheTests(t)
+
+	c := NewImageCache(cc, md, hc, hclog.NewNullLogger())
+	err := c.Create()
+	assert.NoError(t, err)
+
+	conf := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.NotContains(t, conf.EnvVar, "AUTH_REGISTRIES")
+}
+
 func TestImageCacheCreateCopiesCerts(t *testing.T) {
 	cc, md, hc := setupImageCacheTests(t)
 
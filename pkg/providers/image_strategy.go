@@ -0,0 +1,203 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// imageInjector adds the images to the nodes of a cluster, any images which
+// could not be added are returned so that the next strategy can be tried.
+// An error is returned when the strategy can not be used for the cluster
+type imageInjector func(images []config.Image) ([]config.Image, error)
+
+// injectImages adds the images to a cluster using the strategies in order,
+// images which could not be added by a strategy fall back to the next strategy
+func injectImages(l hclog.Logger, ref string, strategies []string, images []config.Image, injectors map[string]imageInjector) error {
+	remaining := images
+	var lastErr error
+
+	for _, s := range strategies {
+		if len(remaining) == 0 {
+			break
+		}
+
+		inject, ok := injectors[s]
+		if !ok {
+			lastErr = fmt.Errorf("image strategy %s is not supported", s)
+			l.Debug("Image strategy not supported by cluster", "ref", ref, "strategy", s)
+			continue
+		}
+
+		l.Debug("Adding images to cluster", "ref", ref, "strategy", s, "images", imageNames(remaining))
+
+		failed, err := inject(remaining)
+		if err != nil {
+			lastErr = err
+			l.Warn("Unable to add images to cluster, trying next strategy", "ref", ref, "strategy", s, "error", err)
+			continue
+		}
+
+		if len(failed) > 0 {
+			lastErr = fmt.Errorf("unable to add images %s with strategy %s", strings.Join(imageNames(failed), ", "), s)
+			l.Warn("Unable to add images to cluster, trying next strategy", "ref", ref, "strategy", s, "images", imageNames(failed))
+		}
+
+		remaining = failed
+	}
+
+	if len(remaining) > 0 {
+		return xerrors.Errorf("Unable to add images %s: %w", strings.Join(imageNames(remaining), ", "), lastErr)
+	}
+
+	return nil
+}
+
+// pullImagesInNodes runs the pull command for each image in every node, the nodes
+// use the image cache as a proxy so the images are pulled through the cache
+func pullImagesInNodes(client clients.ContainerTasks, l hclog.Logger, nodes []string, images []config.Image, pull func(image string) []string) ([]config.Image, error) {
+	failed := []config.Image{}
+
+	for _, i := range images {
+		for _, n := range nodes {
+			err := client.ExecuteCommand(n, pull(i.Name), nil, "/", "", "", l.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
+			if err != nil {
+				l.Debug("Unable to pull image in node", "image", i.Name, "node", n, "error", err)
+				failed = append(failed, i)
+				break
+			}
+		}
+	}
+
+	return failed, nil
+}
+
+// pushImagesToRegistry pushes the images from the local Docker engine to the
+// registry, the registry must be published on a local port to be used.
+// When domains is not empty only images from the given domains are pushed
+// as the cluster can only pull these images from the registry
+func pushImagesToRegistry(client clients.ContainerTasks, l hclog.Logger, reg *config.Registry, images []config.Image, domains []string) ([]config.Image, error) {
+	if reg.Port == 0 {
+		return nil, fmt.Errorf("registry %s does not set a port, images can not be pushed", reg.Name)
+	}
+
+	failed := []config.Image{}
+
+	for _, i := range images {
+		domain, path, err := imageDomainPath(i.Name)
+		if err != nil || !domainAllowed(domain, domains) {
+			l.Debug("Image can not be added with a registry", "image", i.Name, "registry", reg.Name)
+			failed = append(failed, i)
+			continue
+		}
+
+		err = client.PullImage(i, false)
+		if err != nil {
+			failed = append(failed, i)
+			continue
+		}
+
+		target := config.Image{Name: fmt.Sprintf("localhost:%d/%s", reg.Port, path)}
+		if reg.Auth != nil {
+			target.Username = reg.Auth.Username
+			target.Password = reg.Auth.Password
+		}
+
+		err = client.PushImage(i.Name, target)
+		if err != nil {
+			l.Debug("Unable to push image to registry", "image", i.Name, "registry", reg.Name, "error", err)
+			failed = append(failed, i)
+		}
+	}
+
+	return failed, nil
+}
+
+// hasImageStrategy returns true when the strategy is one of the strategies
+func hasImageStrategy(strategies []string, strategy string) bool {
+	for _, s := range strategies {
+		if s == strategy {
+			return true
+		}
+	}
+
+	return false
+}
+
+// imageRegistry returns the first registry a cluster uses, images are pushed
+// to this registry by the registry image strategy
+func imageRegistry(r *config.ResourceInfo, names []string) (*config.Registry, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no registries are set for the cluster")
+	}
+
+	registries, err := clusterRegistries(r, names[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	return registries[0], nil
+}
+
+// imageDomains returns the registry domains of the images
+// e.g. consul:1.10.0 -> docker.io, ghcr.io/org/app:v1 -> ghcr.io
+func imageDomains(images []config.Image) []string {
+	domains := []string{}
+	seen := map[string]bool{}
+
+	for _, i := range images {
+		d, _, err := imageDomainPath(i.Name)
+		if err != nil || seen[d] {
+			continue
+		}
+
+		seen[d] = true
+		domains = append(domains, d)
+	}
+
+	return domains
+}
+
+// imageDomainPath splits an image into the registry domain and the path
+// including the tag e.g. consul:1.10.0 -> docker.io, library/consul:1.10.0
+func imageDomainPath(image string) (string, string, error) {
+	n, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", "", err
+	}
+
+	path := reference.Path(n)
+	if t, ok := reference.TagNameOnly(n).(reference.Tagged); ok {
+		path = fmt.Sprintf("%s:%s", path, t.Tag())
+	}
+
+	return reference.Domain(n), path, nil
+}
+
+func domainAllowed(domain string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+
+	for _, d := range domains {
+		if d == domain {
+			return true
+		}
+	}
+
+	return false
+}
+
+func imageNames(images []config.Image) []string {
+	names := []string{}
+	for _, i := range images {
+		names = append(names, i.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,148 @@
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var strategyImages = []config.Image{
+	config.Image{Name: "consul:1.10.0"},
+	config.Image{Name: "ghcr.io/org/app:v1"},
+}
+
+func TestInjectImagesUsesFirstStrategy(t *testing.T) {
+	calls := []string{}
+
+	err := injectImages(hclog.NewNullLogger(), "test", []string{config.ImageStrategyCache, config.ImageStrategyLoad}, strategyImages, map[string]imageInjector{
+		config.ImageStrategyCache: func(images []config.Image) ([]config.Image, error) {
+			calls = append(calls, config.ImageStrategyCache)
+			return nil, nil
+		},
+		config.ImageStrategyLoad: func(images []config.Image) ([]config.Image, error) {
+			calls = append(calls, config.ImageStrategyLoad)
+			return nil, nil
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{config.ImageStrategyCache}, calls)
+}
+
+func TestInjectImagesFallsBackWithFailedImages(t *testing.T) {
+	loaded := []config.Image{}
+
+	err := injectImages(hclog.NewNullLogger(), "test", []string{config.ImageStrategyCache, config.ImageStrategyLoad}, strategyImages, map[string]imageInjector{
+		config.ImageStrategyCache: func(images []config.Image) ([]config.Image, error) {
+			return images[1:], nil
+		},
+		config.ImageStrategyLoad: func(images []config.Image) ([]config.Image, error) {
+			loaded = images
+			return nil, nil
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, strategyImages[1:], loaded)
+}
+
+func TestInjectImagesFallsBackWhenStrategyErrors(t *testing.T) {
+	loaded := []config.Image{}
+
+	err := injectImages(hclog.NewNullLogger(), "test", []string{config.ImageStrategyRegistry, config.ImageStrategyLoad}, strategyImages, map[string]imageInjector{
+		config.ImageStrategyRegistry: func(images []config.Image) ([]config.Image, error) {
+			return nil, fmt.Errorf("boom")
+		},
+		config.ImageStrategyLoad: func(images []config.Image) ([]config.Image, error) {
+			loaded = images
+			return nil, nil
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, strategyImages, loaded)
+}
+
+func TestInjectImagesReturnsErrorWhenAllStrategiesFail(t *testing.T) {
+	err := injectImages(hclog.NewNullLogger(), "test", []string{config.ImageStrategyLoad}, strategyImages, map[string]imageInjector{
+		config.ImageStrategyLoad: func(images []config.Image) ([]config.Image, error) {
+			return nil, fmt.Errorf("boom")
+		},
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
+
+func TestPushImagesToRegistryWithoutPortReturnsError(t *testing.T) {
+	md := &mocks.MockContainerTasks{}
+	reg := config.NewRegistry("local")
+
+	_, err := pushImagesToRegistry(md, hclog.NewNullLogger(), reg, strategyImages, nil)
+	assert.Error(t, err)
+}
+
+func TestPushImagesToRegistryPushesToLocalPort(t *testing.T) {
+	md := &mocks.MockContainerTasks{}
+	md.On("PullImage", mock.Anything, mock.Anything).Return(nil)
+	md.On("PushImage", mock.Anything, mock.Anything).Return(nil)
+
+	reg := config.NewRegistry("local")
+	reg.Port = 5001
+	reg.Auth = &config.RegistryAuth{Username: "admin", Password: "secret"}
+
+	failed, err := pushImagesToRegistry(md, hclog.NewNullLogger(), reg, strategyImages, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+
+	md.AssertCalled(t, "PushImage", "consul:1.10.0", config.Image{Name: "localhost:5001/library/consul:1.10.0", Username: "admin", Password: "secret"})
+	md.AssertCalled(t, "PushImage", "ghcr.io/org/app:v1", config.Image{Name: "localhost:5001/org/app:v1", Username: "admin", Password: "secret"})
+}
+
+func TestPushImagesToRegistryReturnsImagesNotInDomains(t *testing.T) {
+	md := &mocks.MockContainerTasks{}
+	md.On("PullImage", mock.Anything, mock.Anything).Return(nil)
+	md.On("PushImage", mock.Anything, mock.Anything).Return(nil)
+
+	reg := config.NewRegistry("local")
+	reg.Port = 5001
+
+	failed, err := pushImagesToRegistry(md, hclog.NewNullLogger(), reg, strategyImages, []string{"docker.io"})
+	assert.NoError(t, err)
+	assert.Equal(t, strategyImages[1:], failed)
+
+	md.AssertNumberOfCalls(t, "PushImage", 1)
+}
+
+func TestPushImagesToRegistryReturnsFailedPushes(t *testing.T) {
+	md := &mocks.MockContainerTasks{}
+	md.On("PullImage", mock.Anything, mock.Anything).Return(nil)
+	md.On("PushImage", "consul:1.10.0", mock.Anything).Return(fmt.Errorf("boom"))
+	md.On("PushImage", mock.Anything, mock.Anything).Return(nil)
+
+	reg := config.NewRegistry("local")
+	reg.Port = 5001
+
+	failed, err := pushImagesToRegistry(md, hclog.NewNullLogger(), reg, strategyImages, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, strategyImages[:1], failed)
+}
+
+func TestPullImagesInNodesReturnsFailedImages(t *testing.T) {
+	md := &mocks.MockContainerTasks{}
+	md.On("ExecuteCommand", mock.Anything, []string{"crictl", "pull", "consul:1.10.0"}, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	md.On("ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	failed, err := pullImagesInNodes(md, hclog.NewNullLogger(), []string{"server", "agent"}, strategyImages, func(image string) []string {
+		return []string{"crictl", "pull", image}
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, strategyImages[1:], failed)
+	md.AssertNumberOfCalls(t, "ExecuteCommand", 3)
+}
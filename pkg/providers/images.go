@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"fmt"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// Images returns the images which are pulled by the provider when the
+// given resource is created, this includes any default images used by the
+// provider such as the cluster node images. Images which are built locally
+// are not returned.
+func Images(r config.Resource) []config.Image {
+	images := []config.Image{}
+	add := func(i *config.Image) {
+		if i != nil && i.Name != "" {
+			images = append(images, *i)
+		}
+	}
+
+	switch v := r.(type) {
+	case *config.Container:
+		if v.Build == nil {
+			add(v.Image)
+		}
+
+		for i := range v.InitContainers {
+			add(&v.InitContainers[i].Image)
+		}
+	case *config.Sidecar:
+		add(&v.Image)
+	case *config.ExecRemote:
+		add(v.Image)
+	case *config.Docs:
+		if v.Image != nil {
+			add(v.Image)
+		} else {
+			add(&config.Image{Name: fmt.Sprintf("%s:%s", docsImageName, docsVersion)})
+		}
+	case *config.K8sCluster:
+		image, version := k3sBaseImage, k3sBaseVersion
+		if v.Driver == config.K8sDriverKind {
+			image, version = kindBaseImage, kindBaseVersion
+		}
+
+		if v.Version != "" {
+			version = v.Version
+		}
+
+		add(&config.Image{Name: fmt.Sprintf("%s:%s", image, version)})
+		for i := range v.Images {
+			add(&v.Images[i])
+		}
+	case *config.NomadCluster:
+		version := v.Version
+		if version == "" {
+			version = nomadBaseVersion
+		}
+
+		add(&config.Image{Name: fmt.Sprintf("%s:%s", nomadBaseImage, version)})
+		for i := range v.Images {
+			add(&v.Images[i])
+		}
+	case *config.ImageCache:
+		add(&config.Image{Name: cacheImage})
+	case *config.LogSink:
+		if v.Image != nil {
+			add(v.Image)
+		} else {
+			add(&config.Image{Name: logSinkImage})
+		}
+	case *config.Registry:
+		if v.Image != nil {
+			add(v.Image)
+		} else {
+			add(&config.Image{Name: registryImage})
+		}
+	case *config.Tunnel:
+		switch {
+		case v.Image != nil:
+			add(v.Image)
+		case v.Provider == config.TunnelProviderNgrok:
+			add(&config.Image{Name: ngrokImage})
+		default:
+			add(&config.Image{Name: cloudflaredImage})
+		}
+	case *config.LegacyIngress:
+		add(&config.Image{Name: ingressImage})
+	}
+
+	return images
+}
@@ -0,0 +1,53 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestImagesReturnsContainerImages(t *testing.T) {
+	c := config.NewContainer("consul")
+	c.Image = &config.Image{Name: "consul:1.10.0"}
+	c.InitContainers = []config.InitContainer{{Image: config.Image{Name: "alpine:latest"}}}
+
+	assert.Equal(t, []config.Image{{Name: "consul:1.10.0"}, {Name: "alpine:latest"}}, Images(c))
+}
+
+func TestImagesIgnoresBuiltContainerImages(t *testing.T) {
+	c := config.NewContainer("app")
+	c.Build = &config.Build{Context: "./"}
+
+	assert.Empty(t, Images(c))
+}
+
+func TestImagesReturnsClusterNodeImage(t *testing.T) {
+	c := config.NewK8sCluster("k3s")
+	c.Images = []config.Image{{Name: "consul:1.10.0"}}
+
+	assert.Equal(t, []config.Image{{Name: k3sBaseImage + ":" + k3sBaseVersion}, {Name: "consul:1.10.0"}}, Images(c))
+}
+
+func TestImagesReturnsKindNodeImage(t *testing.T) {
+	c := config.NewK8sCluster("kind")
+	c.Driver = config.K8sDriverKind
+	c.Version = "v1.23.6"
+
+	assert.Equal(t, []config.Image{{Name: kindBaseImage + ":v1.23.6"}}, Images(c))
+}
+
+func TestImagesReturnsClusterNodeImageForVersion(t *testing.T) {
+	c := config.NewNomadCluster("dev")
+	c.Version = "1.2.0"
+
+	assert.Equal(t, []config.Image{{Name: nomadBaseImage + ":1.2.0"}}, Images(c))
+}
+
+func TestImagesReturnsOverriddenDefaultImage(t *testing.T) {
+	r := config.NewRegistry("local")
+	assert.Equal(t, []config.Image{{Name: registryImage}}, Images(r))
+
+	r.Image = &config.Image{Name: "registry:2.7.0"}
+	assert.Equal(t, []config.Image{{Name: "registry:2.7.0"}}, Images(r))
+}
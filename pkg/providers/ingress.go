@@ -32,6 +32,15 @@ func NewIngress(
 func (c *Ingress) Create() error {
 	c.log.Info("Create Ingress", "ref", c.config.Name)
 
+	// services are tunneled between the local machine and a joined machine
+	if c.config.Source.Driver == config.IngressSourceRemote {
+		return c.exposeOnRemote()
+	}
+
+	if c.config.Destination.Driver == config.IngressSourceRemote {
+		return c.exposeFromRemote()
+	}
+
 	if c.config.Destination.Driver == "local" {
 		return c.exposeLocal()
 	}
@@ -54,6 +63,20 @@ func (c *Ingress) Destroy() error {
 		c.log.Warn("Unable to remove local ingress", "ref", c.config.Name, "id", c.config.Id, "error", err)
 	}
 
+	if c.config.AuthId != "" {
+		err := c.connector.RemoveAuthProxy(c.config.AuthId)
+		if err != nil {
+			c.log.Warn("Unable to remove auth proxy", "ref", c.config.Name, "id", c.config.AuthId, "error", err)
+		}
+	}
+
+	if c.config.StreamId != "" {
+		err := c.connector.RemoveStreamProxy(c.config.StreamId)
+		if err != nil {
+			c.log.Warn("Unable to remove TLS proxy", "ref", c.config.Name, "id", c.config.StreamId, "error", err)
+		}
+	}
+
 	return nil
 }
 
@@ -89,7 +112,10 @@ func (c *Ingress) exposeLocal() error {
 	}
 
 	// get the address of the remote connector from the target
-	clusterConfig, _ := utils.GetClusterConfig(c.config.Source.Config.Cluster)
+	clusterConfig, _, err := utils.GetClusterConfig(c.config.Source.Config.Cluster)
+	if err != nil {
+		return err
+	}
 
 	if c.config.Destination.Config.Address == "" {
 		return xerrors.Errorf("The address config stanza field must be specified when type 'local'")
@@ -103,6 +129,24 @@ func (c *Ingress) exposeLocal() error {
 		return xerrors.Errorf("Unable to repace non URI characters in service name %s :%w", c.config.Name, err)
 	}
 
+	// requests from the cluster are authenticated and have their headers
+	// rewritten by a proxy in front of the local service
+	if c.useProxy() {
+		p, err := utils.GetFreePort()
+		if err != nil {
+			return xerrors.Errorf("Unable to find a free port for the auth proxy: %w", err)
+		}
+
+		proxyAddr := fmt.Sprintf("127.0.0.1:%d", p)
+
+		err = c.createAuthProxy(proxyAddr, destAddr)
+		if err != nil {
+			return err
+		}
+
+		destAddr = proxyAddr
+	}
+
 	// send the request
 	c.log.Debug(
 		"Calling connector to expose local service",
@@ -138,7 +182,10 @@ func (c *Ingress) exposeK8sRemote() error {
 	}
 
 	// get the address of the remote connector from the target
-	clusterConfig, _ := utils.GetClusterConfig(string(res.Info().Type) + "." + res.Info().Name)
+	clusterConfig, _, err := utils.GetClusterConfig(string(res.Info().Type) + "." + res.Info().Name)
+	if err != nil {
+		return err
+	}
 
 	if c.config.Destination.Config.Address == "" {
 		return xerrors.Errorf("Config parameter 'address' is required for desinations of type 'k8s'")
@@ -162,6 +209,26 @@ func (c *Ingress) exposeK8sRemote() error {
 		return xerrors.Errorf("Unable to repace non URI characters in service name %s :%w", c.config.Name, err)
 	}
 
+	// when auth, headers, or TLS are enabled the service is exposed on a random port
+	// and the proxies listen on the requested port
+	exposePort := localPort
+	if c.useProxy() || c.config.TLS != nil {
+		exposePort, err = utils.GetFreePort()
+		if err != nil {
+			return xerrors.Errorf("Unable to find a free port for the auth proxy: %w", err)
+		}
+	}
+
+	// when TLS is terminated in front of the auth proxy, the auth proxy
+	// listens on a random port
+	proxyPort := localPort
+	if c.useProxy() && c.config.TLS != nil {
+		proxyPort, err = utils.GetFreePort()
+		if err != nil {
+			return xerrors.Errorf("Unable to find a free port for the auth proxy: %w", err)
+		}
+	}
+
 	// send the request
 	c.log.Debug(
 		"Calling connector to expose remote service",
@@ -173,7 +240,7 @@ func (c *Ingress) exposeK8sRemote() error {
 
 	id, err := c.connector.ExposeService(
 		serviceName,
-		localPort,
+		exposePort,
 		clusterConfig.ConnectorAddress(utils.LocalContext),
 		destAddr,
 		"remote")
@@ -185,5 +252,146 @@ func (c *Ingress) exposeK8sRemote() error {
 	c.log.Debug("Successfully exposed service", "id", id)
 	c.config.Id = id
 
+	upstreamPort := exposePort
+	if c.useProxy() {
+		err = c.createAuthProxy(fmt.Sprintf(":%d", proxyPort), fmt.Sprintf("localhost:%d", exposePort))
+		if err != nil {
+			return err
+		}
+
+		upstreamPort = proxyPort
+	}
+
+	if c.config.TLS != nil {
+		return c.createTLSProxy(fmt.Sprintf(":%d", localPort), fmt.Sprintf("localhost:%d", upstreamPort))
+	}
+
+	return nil
+}
+
+// exposeOnRemote exposes a service on the local machine on a port of a
+// machine joined with shipyard connector join
+func (c *Ingress) exposeOnRemote() error {
+	rc, err := clients.LoadRemoteConnector(c.config.Source.Config.Remote)
+	if err != nil {
+		return err
+	}
+
+	remotePort, err := strconv.Atoi(c.config.Source.Config.Port)
+	if err != nil {
+		return xerrors.Errorf("Unable to parse remote port :%w", err)
+	}
+
+	if c.config.Destination.Config.Address == "" {
+		return xerrors.Errorf("The address config stanza field must be specified when the source is 'remote'")
+	}
+
+	destAddr := fmt.Sprintf("%s:%s", c.config.Destination.Config.Address, c.config.Destination.Config.Port)
+
+	return c.exposeRemoteConnector(rc, remotePort, destAddr, "local")
+}
+
+// exposeFromRemote exposes a service on a machine joined with shipyard connector
+// join on a local port, the address is resolved on the joined machine
+func (c *Ingress) exposeFromRemote() error {
+	rc, err := clients.LoadRemoteConnector(c.config.Destination.Config.Remote)
+	if err != nil {
+		return err
+	}
+
+	localPort, err := strconv.Atoi(c.config.Source.Config.Port)
+	if err != nil {
+		return xerrors.Errorf("Unable to parse local port :%w", err)
+	}
+
+	address := c.config.Destination.Config.Address
+	if address == "" {
+		address = "localhost"
+	}
+
+	destAddr := fmt.Sprintf("%s:%s", address, c.config.Destination.Config.Port)
+
+	return c.exposeRemoteConnector(rc, localPort, destAddr, "remote")
+}
+
+func (c *Ingress) exposeRemoteConnector(rc *clients.RemoteConnector, port int, destAddr, direction string) error {
+	serviceName, err := utils.ReplaceNonURIChars(c.config.Name)
+	if err != nil {
+		return xerrors.Errorf("Unable to repace non URI characters in service name %s :%w", c.config.Name, err)
+	}
+
+	c.log.Debug(
+		"Calling connector to expose service with remote machine",
+		"name", serviceName,
+		"port", port,
+		"remote", rc.Name,
+		"connector_addr", rc.ConnectorAddress,
+		"dest_addr", destAddr,
+		"direction", direction,
+	)
+
+	id, err := c.connector.ExposeService(serviceName, port, rc.ConnectorAddress, destAddr, direction)
+	if err != nil {
+		return xerrors.Errorf("Unable to expose service with remote machine %s :%w", rc.Name, err)
+	}
+
+	c.log.Debug("Successfully exposed service", "id", id)
+	c.config.Id = id
+
+	return nil
+}
+
+// createTLSProxy creates a proxy in the connector which terminates TLS and
+// forwards the decrypted traffic to the upstream address
+func (c *Ingress) createTLSProxy(bindAddr, upstream string) error {
+	cert, key, err := c.config.TLS.Files(c.config.Config)
+	if err != nil {
+		return xerrors.Errorf("Unable to find TLS certificate: %w", err)
+	}
+
+	c.log.Debug("Creating TLS proxy", "ref", c.config.Name, "bind_addr", bindAddr, "upstream", upstream, "cert", cert)
+
+	id, err := c.connector.ExposeStreamProxy(
+		fmt.Sprintf("%s.%s", c.config.Type, c.config.Name),
+		"tcp",
+		bindAddr,
+		upstream,
+		cert,
+		key,
+	)
+
+	if err != nil {
+		return xerrors.Errorf("Unable to create TLS proxy: %w", err)
+	}
+
+	c.config.StreamId = id
+
+	return nil
+}
+
+// useProxy returns true when requests must be handled by a proxy in the connector
+func (c *Ingress) useProxy() bool {
+	return c.config.Auth != nil || c.config.Headers != nil
+}
+
+// createAuthProxy creates a proxy in the connector which authenticates requests
+// and rewrites headers before forwarding them to the upstream address
+func (c *Ingress) createAuthProxy(bindAddr, upstream string) error {
+	c.log.Debug("Creating auth proxy", "ref", c.config.Name, "bind_addr", bindAddr, "upstream", upstream)
+
+	id, err := c.connector.ExposeAuthProxy(
+		fmt.Sprintf("%s.%s", c.config.Type, c.config.Name),
+		bindAddr,
+		upstream,
+		c.config.Auth,
+		c.config.Headers,
+	)
+
+	if err != nil {
+		return xerrors.Errorf("Unable to create auth proxy: %w", err)
+	}
+
+	c.config.AuthId = id
+
 	return nil
 }
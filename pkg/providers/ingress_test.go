@@ -2,6 +2,7 @@ package providers
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
@@ -28,6 +29,10 @@ func testIngressCreateMockConnector(t *testing.T, name string) *clients.Connecto
 	m := &clients.ConnectorMock{}
 	m.On("ExposeService", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("12345", nil)
 	m.On("RemoveService", mock.Anything).Return(nil)
+	m.On("ExposeAuthProxy", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("ingress.local-http", nil)
+	m.On("RemoveAuthProxy", mock.Anything).Return(nil)
+	m.On("ExposeStreamProxy", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("ingress.local-http", nil)
+	m.On("RemoveStreamProxy", mock.Anything).Return(nil)
 
 	return m
 }
@@ -116,7 +121,7 @@ func TestIngressExposeLocalCallsExpose(t *testing.T) {
 	tc := testIngressExposeK8sLocalConfig
 	c.AddResource(&tc)
 
-	clusterConfig, _ := utils.GetClusterConfig(testIngressExposeK8sLocalConfig.Source.Config.Cluster)
+	clusterConfig, _, _ := utils.GetClusterConfig(testIngressExposeK8sLocalConfig.Source.Config.Cluster)
 
 	p := NewIngress(&tc, md, mc, hclog.NewNullLogger())
 
@@ -205,7 +210,7 @@ func TestIngressExposeRemoteCallsExpose(t *testing.T) {
 	tc := testIngressExposesLocalK8sServiceConfig
 	c.AddResource(&tc)
 
-	clusterConfig, _ := utils.GetClusterConfig(testIngressExposeK8sLocalConfig.Source.Config.Cluster)
+	clusterConfig, _, _ := utils.GetClusterConfig(testIngressExposeK8sLocalConfig.Source.Config.Cluster)
 
 	p := NewIngress(&tc, md, mc, hclog.NewNullLogger())
 
@@ -239,6 +244,166 @@ func TestIngressDestroyCallsRemove(t *testing.T) {
 	mc.AssertCalled(t, "RemoveService", "12345")
 }
 
+func TestIngressExposeLocalWithAuthCallsExposeWithProxy(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	mc := testIngressCreateMockConnector(t, testIngressExposeK8sLocalConfig.Name)
+
+	tc := testIngressExposeK8sLocalConfig
+	tc.Auth = &config.Auth{Basic: &config.BasicAuth{Username: "admin", Password: "secret"}}
+	c.AddResource(&tc)
+
+	p := NewIngress(&tc, md, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	// the proxy forwards to the local service
+	proxyCall := getCalls(&mc.Mock, "ExposeAuthProxy")[0]
+	proxyAddr := proxyCall.Arguments.String(1)
+	assert.Equal(t, "ingress.local-http", proxyCall.Arguments.String(0))
+	assert.Equal(t, tc.Destination.Config.Address+":"+tc.Destination.Config.Port, proxyCall.Arguments.String(2))
+
+	// the cluster is connected to the proxy
+	exposeCall := getCalls(&mc.Mock, "ExposeService")[0]
+	assert.Equal(t, proxyAddr, exposeCall.Arguments.String(3))
+
+	assert.Equal(t, "ingress.local-http", tc.AuthId)
+}
+
+func TestIngressExposeRemoteWithAuthCallsExposeWithProxy(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	mc := testIngressCreateMockConnector(t, testIngressExposeK8sLocalConfig.Name)
+
+	tc := testIngressExposesLocalK8sServiceConfig
+	tc.Auth = &config.Auth{Basic: &config.BasicAuth{Username: "admin", Password: "secret"}}
+	c.AddResource(&tc)
+
+	p := NewIngress(&tc, md, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	// the service is exposed on a random port
+	exposeCall := getCalls(&mc.Mock, "ExposeService")[0]
+	port := exposeCall.Arguments.Int(1)
+	assert.NotEqual(t, tc.Source.Config.Port, strconv.Itoa(port))
+
+	// the proxy listens on the requested port
+	proxyCall := getCalls(&mc.Mock, "ExposeAuthProxy")[0]
+	assert.Equal(t, ":"+tc.Source.Config.Port, proxyCall.Arguments.String(1))
+	assert.Equal(t, "localhost:"+strconv.Itoa(port), proxyCall.Arguments.String(2))
+}
+
+func TestIngressExposeRemoteWithHeadersCallsExposeWithProxy(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	mc := testIngressCreateMockConnector(t, testIngressExposeK8sLocalConfig.Name)
+
+	tc := testIngressExposesLocalK8sServiceConfig
+	tc.Headers = &config.Headers{CORS: &config.CORS{AllowedOrigins: []string{"http://localhost:3000"}}}
+	c.AddResource(&tc)
+
+	p := NewIngress(&tc, md, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	proxyCall := getCalls(&mc.Mock, "ExposeAuthProxy")[0]
+	assert.Equal(t, ":"+tc.Source.Config.Port, proxyCall.Arguments.String(1))
+	assert.Nil(t, proxyCall.Arguments.Get(3))
+	assert.Equal(t, tc.Headers, proxyCall.Arguments.Get(4))
+}
+
+func TestIngressDestroyWithAuthRemovesProxy(t *testing.T) {
+	md, _ := testIngressCreateMocks()
+	mc := testIngressCreateMockConnector(t, testIngressExposeK8sLocalConfig.Name)
+
+	tc := testIngressExposesLocalK8sServiceConfig
+	tc.Id = "12345"
+	tc.AuthId = "ingress.local-http"
+
+	p := NewIngress(&tc, md, mc, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	mc.AssertCalled(t, "RemoveAuthProxy", "ingress.local-http")
+}
+
+func TestIngressExposeRemoteWithTLSCallsExposeWithTLSProxy(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	mc := testIngressCreateMockConnector(t, testIngressExposeK8sLocalConfig.Name)
+
+	tc := testIngressExposesLocalK8sServiceConfig
+	tc.TLS = &config.IngressTLS{}
+	c.AddResource(&tc)
+
+	p := NewIngress(&tc, md, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	// the service is exposed on a random port
+	exposeCall := getCalls(&mc.Mock, "ExposeService")[0]
+	port := exposeCall.Arguments.Int(1)
+	assert.NotEqual(t, tc.Source.Config.Port, strconv.Itoa(port))
+
+	// the TLS proxy listens on the requested port using the shipyard leaf certificate
+	proxyCall := getCalls(&mc.Mock, "ExposeStreamProxy")[0]
+	assert.Equal(t, "tcp", proxyCall.Arguments.String(1))
+	assert.Equal(t, ":"+tc.Source.Config.Port, proxyCall.Arguments.String(2))
+	assert.Equal(t, "localhost:"+strconv.Itoa(port), proxyCall.Arguments.String(3))
+	assert.Equal(t, filepath.Join(utils.CertsDir(""), "leaf.cert"), proxyCall.Arguments.String(4))
+
+	assert.Equal(t, "ingress.local-http", tc.StreamId)
+}
+
+func TestIngressExposeRemoteWithTLSAndAuthChainsProxies(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	mc := testIngressCreateMockConnector(t, testIngressExposeK8sLocalConfig.Name)
+
+	cert := config.NewCertificateLeaf("kafka")
+	cert.CertPath = "/certs/kafka.cert"
+	cert.KeyPath = "/certs/kafka.key"
+	c.AddResource(cert)
+
+	tc := testIngressExposesLocalK8sServiceConfig
+	tc.Auth = &config.Auth{Basic: &config.BasicAuth{Username: "admin", Password: "secret"}}
+	tc.TLS = &config.IngressTLS{Certificate: "certificate_leaf.kafka"}
+	c.AddResource(&tc)
+
+	p := NewIngress(&tc, md, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	// the auth proxy listens on a random port
+	authCall := getCalls(&mc.Mock, "ExposeAuthProxy")[0]
+	assert.NotEqual(t, ":"+tc.Source.Config.Port, authCall.Arguments.String(1))
+
+	// the TLS proxy forwards to the auth proxy
+	proxyCall := getCalls(&mc.Mock, "ExposeStreamProxy")[0]
+	assert.Equal(t, ":"+tc.Source.Config.Port, proxyCall.Arguments.String(2))
+	assert.Equal(t, "localhost"+authCall.Arguments.String(1), proxyCall.Arguments.String(3))
+	assert.Equal(t, "/certs/kafka.cert", proxyCall.Arguments.String(4))
+	assert.Equal(t, "/certs/kafka.key", proxyCall.Arguments.String(5))
+}
+
+func TestIngressDestroyWithTLSRemovesProxy(t *testing.T) {
+	md, _ := testIngressCreateMocks()
+	mc := testIngressCreateMockConnector(t, testIngressExposeK8sLocalConfig.Name)
+
+	tc := testIngressExposesLocalK8sServiceConfig
+	tc.Id = "12345"
+	tc.StreamId = "ingress.local-http"
+
+	p := NewIngress(&tc, md, mc, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	mc.AssertCalled(t, "RemoveStreamProxy", "ingress.local-http")
+}
+
 var testIngressExposeK8sLocalConfig = config.Ingress{
 	ResourceInfo: config.ResourceInfo{
 		Name: "local-http",
@@ -286,3 +451,61 @@ var testIngressExposesLocalK8sServiceConfig = config.Ingress{
 		},
 	},
 }
+
+func TestIngressExposeOnRemoteCallsExposeWithRemoteConnector(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	mc := testIngressCreateMockConnector(t, "api")
+
+	err := clients.SaveRemoteConnector(&clients.RemoteConnector{Name: "lab", ConnectorAddress: "lab.local:30001"})
+	assert.NoError(t, err)
+
+	tc := config.NewIngress("api")
+	tc.Source = config.Traffic{Driver: config.IngressSourceRemote, Config: config.TrafficConfig{Remote: "lab", Port: "9090"}}
+	tc.Destination = config.Traffic{Driver: config.IngressSourceLocal, Config: config.TrafficConfig{Address: "localhost", Port: "8080"}}
+	c.AddResource(tc)
+
+	p := NewIngress(tc, md, mc, hclog.NewNullLogger())
+
+	err = p.Create()
+	assert.NoError(t, err)
+
+	mc.AssertCalled(t, "ExposeService", "api", 9090, "lab.local:30001", "localhost:8080", "local")
+	assert.Equal(t, "12345", tc.Id)
+}
+
+func TestIngressExposeFromRemoteCallsExposeWithRemoteConnector(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	mc := testIngressCreateMockConnector(t, "vault")
+
+	err := clients.SaveRemoteConnector(&clients.RemoteConnector{Name: "lab", ConnectorAddress: "lab.local:30001"})
+	assert.NoError(t, err)
+
+	tc := config.NewIngress("vault")
+	tc.Source = config.Traffic{Driver: config.IngressSourceLocal, Config: config.TrafficConfig{Port: "8200"}}
+	tc.Destination = config.Traffic{Driver: config.IngressSourceRemote, Config: config.TrafficConfig{Remote: "lab", Port: "18200"}}
+	c.AddResource(tc)
+
+	p := NewIngress(tc, md, mc, hclog.NewNullLogger())
+
+	err = p.Create()
+	assert.NoError(t, err)
+
+	mc.AssertCalled(t, "ExposeService", "vault", 8200, "lab.local:30001", "localhost:18200", "remote")
+}
+
+func TestIngressExposeFromRemoteErrorsWhenNotJoined(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	mc := testIngressCreateMockConnector(t, "vault")
+
+	tc := config.NewIngress("vault")
+	tc.Source = config.Traffic{Driver: config.IngressSourceLocal, Config: config.TrafficConfig{Port: "8200"}}
+	tc.Destination = config.Traffic{Driver: config.IngressSourceRemote, Config: config.TrafficConfig{Remote: "lab", Port: "18200"}}
+	c.AddResource(tc)
+
+	p := NewIngress(tc, md, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+
+	mc.AssertNotCalled(t, "ExposeService", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
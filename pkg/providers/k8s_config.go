@@ -1,6 +1,10 @@
 package providers
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -8,29 +12,53 @@ import (
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"golang.org/x/xerrors"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
 )
 
 type K8sConfig struct {
 	config *config.K8sConfig
 	client clients.Kubernetes
+	getter clients.Getter
 	log    hclog.Logger
 }
 
 // NewK8sConfig creates a provider which can create and destroy kubernetes configuration
-func NewK8sConfig(c *config.K8sConfig, kc clients.Kubernetes, l hclog.Logger) *K8sConfig {
-	return &K8sConfig{c, kc, l}
+func NewK8sConfig(c *config.K8sConfig, kc clients.Kubernetes, g clients.Getter, l hclog.Logger) *K8sConfig {
+	return &K8sConfig{c, kc, g, l}
 }
 
 // Create the Kubernetes resources defined by the config
 func (c *K8sConfig) Create() error {
-	c.log.Info("Applying Kubernetes configuration", "ref", c.config.Name, "config", c.config.Paths)
+	c.log.Info("Applying Kubernetes configuration", "ref", c.config.Name, "config", c.config.Paths, "kustomize", c.config.Kustomize)
 
 	err := c.setup()
 	if err != nil {
 		return err
 	}
 
-	err = c.client.Apply(c.config.Paths, c.config.WaitUntilReady)
+	paths, err := c.paths()
+	if err != nil {
+		return err
+	}
+
+	if c.config.Kustomize != "" {
+		c.log.Debug("Rendering kustomization", "ref", c.config.Name, "kustomize", c.config.Kustomize)
+
+		kp, err := c.kustomizePath()
+		if err != nil {
+			return err
+		}
+
+		err = renderKustomization(c.config.Kustomize, kp)
+		if err != nil {
+			return xerrors.Errorf("Unable to render kustomization %s: %w", c.config.Kustomize, err)
+		}
+
+		paths = append(paths, kp)
+	}
+
+	err = c.client.Apply(paths, c.config.WaitUntilReady)
 	if err != nil {
 		return err
 	}
@@ -56,17 +84,51 @@ func (c *K8sConfig) Create() error {
 
 // Destroy the Kubernetes resources defined by the config
 func (c *K8sConfig) Destroy() error {
-	c.log.Info("Destroy Kubernetes configuration", "ref", c.config.Name, "config", c.config.Paths)
+	c.log.Info("Destroy Kubernetes configuration", "ref", c.config.Name, "config", c.config.Paths, "kustomize", c.config.Kustomize)
 
 	err := c.setup()
 	if err != nil {
 		return err
 	}
 
-	err = c.client.Delete(c.config.Paths)
+	paths, err := c.paths()
+	if err != nil {
+		return err
+	}
+
+	if c.config.Kustomize != "" {
+		// delete the resources which were applied, the kustomization is only
+		// rendered again when the output from the create no longer exists
+		kp, err := c.kustomizePath()
+		if err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(kp); err != nil {
+			err := renderKustomization(c.config.Kustomize, kp)
+			if err != nil {
+				return xerrors.Errorf("Unable to render kustomization %s: %w", c.config.Kustomize, err)
+			}
+		}
+
+		defer os.Remove(kp)
+
+		paths = append(paths, kp)
+	}
+
+	err = c.client.Delete(paths)
 	if err != nil {
 		c.log.Debug("There was a problem destroying Kubernetes config, logging message but ignoring error", "ref", c.config.Name, "error", err)
+		return nil
 	}
+
+	// wait for the resources to be removed so that a subsequent apply does not
+	// fail due to resources such as namespaces which are still terminating
+	err = c.client.WaitForDeletion(paths, c.config.Destroy.TimeoutDuration(), c.config.Destroy.ForceRemove())
+	if err != nil {
+		return xerrors.Errorf("Unable to destroy Kubernetes config: %w", err)
+	}
+
 	return nil
 }
 
@@ -81,7 +143,11 @@ func (c *K8sConfig) setup() error {
 		return xerrors.Errorf("Unable to find associated cluster: %w", cluster)
 	}
 
-	_, destPath, _ := utils.CreateKubeConfigPath(cluster.Info().Name)
+	_, destPath, _, err := utils.CreateKubeConfigPath(cluster.Info().Name)
+	if err != nil {
+		return err
+	}
+
 	c.client, err = c.client.SetConfig(destPath)
 	if err != nil {
 		return xerrors.Errorf("unable to create Kubernetes client: %w", err)
@@ -89,3 +155,91 @@ func (c *K8sConfig) setup() error {
 
 	return nil
 }
+
+// paths returns the files to apply, URLs are downloaded to a local cache and
+// glob patterns are expanded to the matching files sorted by name
+func (c *K8sConfig) paths() ([]string, error) {
+	paths := []string{}
+
+	for _, p := range c.config.Paths {
+		switch {
+		case config.IsRemotePath(p):
+			dst := utils.GetManifestLocalFolder(p)
+			c.log.Debug("Fetching remote Kubernetes config", "ref", c.config.Name, "url", p, "dest", dst)
+
+			// the getter does not download files which already exist in the cache
+			err := c.getter.Get(p, dst)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to download Kubernetes config %s: %w", p, err)
+			}
+
+			if v := c.config.VerifyFor(p); v != nil {
+				vf, err := verifyFolder(c.config)
+				if err != nil {
+					return nil, err
+				}
+
+				err = verifyDownload(c.getter, v, dst, filepath.Join(vf, filepath.Base(dst)), c.log)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			paths = append(paths, dst)
+
+		case utils.IsGlob(p):
+			files, err := utils.Glob(p)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to expand Kubernetes config paths: %w", err)
+			}
+
+			if len(files) == 0 {
+				return nil, fmt.Errorf("No Kubernetes config files match the pattern %s", p)
+			}
+
+			c.log.Debug("Expanded Kubernetes config paths", "ref", c.config.Name, "pattern", p, "files", files)
+
+			paths = append(paths, files...)
+
+		default:
+			paths = append(paths, p)
+		}
+	}
+
+	return paths, nil
+}
+
+// kustomizePath returns the location of the rendered kustomization
+func (c *K8sConfig) kustomizePath() (string, error) {
+	tmp, err := utils.ShipyardTemp()
+	if err != nil {
+		return "", err
+	}
+
+	i := c.config.Info()
+
+	return filepath.Join(tmp, "kustomize", fmt.Sprintf("%s.yaml", config.ResourceID(i.Module, i.Type, i.Name))), nil
+}
+
+// renderKustomization builds the kustomization in dir and writes
+// the resulting resources to a single YAML file at dest
+func renderKustomization(dir, dest string) error {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+
+	rm, err := k.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return err
+	}
+
+	y, err := rm.AsYaml()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(dest), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(dest, y, 0644)
+}
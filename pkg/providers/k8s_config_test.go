@@ -2,11 +2,15 @@ package providers
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/stretchr/testify/assert"
@@ -18,6 +22,7 @@ func setupK8sConfig() (*clients.MockKubernetes, *K8sConfig) {
 	mk.On("SetConfig", mock.Anything).Return(nil)
 	mk.On("Apply", mock.Anything, mock.Anything).Return(nil)
 	mk.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mk.On("WaitForDeletion", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 	c := config.NewK8sCluster("testcluster")
 	kc := config.NewK8sConfig("config")
@@ -28,7 +33,10 @@ func setupK8sConfig() (*clients.MockKubernetes, *K8sConfig) {
 	cc.AddResource(kc)
 	cc.AddResource(c)
 
-	p := NewK8sConfig(kc, mk, hclog.Default())
+	mg := &mocks.Getter{}
+	mg.On("Get", mock.Anything, mock.Anything).Return(nil)
+
+	p := NewK8sConfig(kc, mk, mg, hclog.Default())
 
 	return mk, p
 }
@@ -39,11 +47,146 @@ func TestCreatesCorrectly(t *testing.T) {
 	err := p.Create()
 	assert.NoError(t, err)
 
-	_, destPath, _ := utils.CreateKubeConfigPath("testcluster")
+	_, destPath, _, _ := utils.CreateKubeConfigPath("testcluster")
 	mk.AssertCalled(t, "SetConfig", destPath)
 	mk.AssertCalled(t, "Apply", p.config.Paths, p.config.WaitUntilReady)
 }
 
+func TestCreateReturnsErrorWhenUnableToCreateKubeConfigFolder(t *testing.T) {
+	mk, p := setupK8sConfig()
+
+	// folders can not be created when the Shipyard home is a file
+	home := filepath.Join(t.TempDir(), "home")
+	ioutil.WriteFile(home, []byte(""), os.ModePerm)
+
+	utils.SetShipyardHome(home)
+	t.Cleanup(func() {
+		utils.SetShipyardHome("")
+	})
+
+	err := p.Create()
+	assert.Error(t, err)
+
+	mk.AssertNotCalled(t, "SetConfig", mock.Anything)
+	mk.AssertNotCalled(t, "Apply", mock.Anything, mock.Anything)
+}
+
+func TestCreateFetchesRemotePaths(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.Paths = []string{"https://example.com/manifests/app.yaml"}
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	dst := utils.GetManifestLocalFolder("https://example.com/manifests/app.yaml")
+	p.getter.(*mocks.Getter).AssertCalled(t, "Get", "https://example.com/manifests/app.yaml", dst)
+	mk.AssertCalled(t, "Apply", []string{dst}, p.config.WaitUntilReady)
+}
+
+func TestCreateFetchRemotePathErrorReturnsError(t *testing.T) {
+	_, p := setupK8sConfig()
+	p.config.Paths = []string{"https://example.com/manifests/app.yaml"}
+
+	mg := &mocks.Getter{}
+	mg.On("Get", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+	p.getter = mg
+
+	err := p.Create()
+	assert.Error(t, err)
+}
+
+func TestCreateExpandsGlobPaths(t *testing.T) {
+	mk, p := setupK8sConfig()
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "manifests", "db"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "manifests", "web.yaml"), []byte(""), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "manifests", "db", "db.yaml"), []byte(""), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "manifests", "db", "README.md"), []byte(""), 0644)
+
+	p.config.Paths = []string{filepath.Join(dir, "manifests", "**", "*.yaml")}
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "Apply", []string{
+		filepath.Join(dir, "manifests", "db", "db.yaml"),
+		filepath.Join(dir, "manifests", "web.yaml"),
+	}, p.config.WaitUntilReady)
+}
+
+func TestCreateGlobWithNoMatchesReturnsError(t *testing.T) {
+	_, p := setupK8sConfig()
+	p.config.Paths = []string{filepath.Join(t.TempDir(), "*.yaml")}
+
+	err := p.Create()
+	assert.Error(t, err)
+}
+
+func setupKustomization(t *testing.T) string {
+	home := os.Getenv(utils.HomeEnvName())
+	os.Setenv(utils.HomeEnvName(), t.TempDir())
+
+	t.Cleanup(func() {
+		os.Setenv(utils.HomeEnvName(), home)
+	})
+
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(kustomization), 0644)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "configmap.yaml"), []byte(kustomizeConfigMap), 0644)
+	assert.NoError(t, err)
+
+	return dir
+}
+
+func TestCreateRendersKustomizationAndApplies(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.Paths = nil
+	p.config.Kustomize = setupKustomization(t)
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	kp, err := p.kustomizePath()
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "Apply", []string{kp}, p.config.WaitUntilReady)
+
+	d, err := ioutil.ReadFile(kp)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), "name: dev-app")
+	assert.Contains(t, string(d), "env: dev")
+}
+
+func TestCreateWithInvalidKustomizationReturnsError(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.Kustomize = t.TempDir()
+
+	err := p.Create()
+	assert.Error(t, err)
+
+	mk.AssertNotCalled(t, "Apply", mock.Anything, mock.Anything)
+}
+
+func TestDestroyDeletesKustomizationAndRemovesRenderedFile(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.Kustomize = setupKustomization(t)
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	kp, err := p.kustomizePath()
+	assert.NoError(t, err)
+
+	paths := []string{"/tmp/something", kp}
+	mk.AssertCalled(t, "Delete", paths)
+	mk.AssertCalled(t, "WaitForDeletion", paths, config.DefaultK8sDestroyTimeout, false)
+
+	assert.NoFileExists(t, kp)
+}
+
 func TestRunsHealthChecks(t *testing.T) {
 	mk, p := setupK8sConfig()
 	p.config.HealthCheck = &config.HealthCheck{
@@ -96,6 +239,37 @@ func TestDestroysCorrectly(t *testing.T) {
 	assert.NoError(t, err)
 
 	mk.AssertCalled(t, "Delete", p.config.Paths)
+	mk.AssertCalled(t, "WaitForDeletion", p.config.Paths, config.DefaultK8sDestroyTimeout, false)
+}
+
+func TestDestroyWaitsWithDestroyOptions(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.Destroy = &config.K8sDestroy{Timeout: "10s", ForceRemoveFinalizers: true}
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "WaitForDeletion", p.config.Paths, 10*time.Second, true)
+}
+
+func TestDestroyDeleteErrorDoesNotWait(t *testing.T) {
+	mk, p := setupK8sConfig()
+	removeOn(&mk.Mock, "Delete")
+	mk.On("Delete", mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	mk.AssertNotCalled(t, "WaitForDeletion", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDestroyWaitErrorReturnsError(t *testing.T) {
+	mk, p := setupK8sConfig()
+	removeOn(&mk.Mock, "WaitForDeletion")
+	mk.On("WaitForDeletion", mock.Anything, mock.Anything, mock.Anything).Return(clients.DeletionTimeoutError{Resources: []string{"namespace/app"}})
+
+	err := p.Destroy()
+	assert.Error(t, err)
 }
 
 func TestDestroySetupErrorReturnsError(t *testing.T) {
@@ -106,3 +280,22 @@ func TestDestroySetupErrorReturnsError(t *testing.T) {
 	err := p.Destroy()
 	assert.Error(t, err)
 }
+
+const kustomization = `
+resources:
+- configmap.yaml
+
+namePrefix: dev-
+
+commonLabels:
+  env: dev
+`
+
+const kustomizeConfigMap = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  port: "9090"
+`
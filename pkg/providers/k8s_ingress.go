@@ -0,0 +1,221 @@
+package providers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// K8sIngress is a provider which exposes Kubernetes services, deployments, and pods,
+// each service is proxied by an ingress container
+type K8sIngress struct {
+	config     *config.K8sIngress
+	client     clients.ContainerTasks
+	kubeClient clients.Kubernetes
+	connector  clients.Connector
+	log        hclog.Logger
+}
+
+// NewK8sIngress creates an Ingress from Kubernetes config
+func NewK8sIngress(c *config.K8sIngress, cc clients.ContainerTasks, kc clients.Kubernetes, co clients.Connector, l hclog.Logger) *K8sIngress {
+	return &K8sIngress{c, cc, kc, co, l}
+}
+
+// Create the ingress containers, the ports of services which do not define
+// any ports are discovered from the cluster
+func (i *K8sIngress) Create() error {
+	err := i.discoverPorts()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range i.proxies() {
+		err := p.Create()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Destroy the ingress containers
+func (i *K8sIngress) Destroy() error {
+	var destroyErr error
+
+	for _, p := range i.proxies() {
+		err := p.Destroy()
+		if err != nil && destroyErr == nil {
+			destroyErr = err
+		}
+	}
+
+	return destroyErr
+}
+
+// Lookup the id of the ingress
+func (i *K8sIngress) Lookup() ([]string, error) {
+	return []string{}, nil
+}
+
+// proxies returns an ingress for the service, deployment, or pod of the
+// resource and an ingress for each of the targets
+func (i *K8sIngress) proxies() []*LegacyIngress {
+	proxies := []*LegacyIngress{}
+
+	if i.config.Service != "" || i.config.Deployment != "" || i.config.Pod != "" || len(i.config.Targets) == 0 {
+		c := i.legacyConfig(i.config.Name, i.config.Namespace, i.config.Ports)
+
+		if i.config.Deployment != "" {
+			c.Service = fmt.Sprintf("deployment/%s", i.config.Deployment)
+		}
+
+		if i.config.Service != "" {
+			c.Service = fmt.Sprintf("svc/%s", i.config.Service)
+		}
+
+		if i.config.Pod != "" {
+			c.Service = i.config.Pod
+		}
+
+		proxies = append(proxies, &LegacyIngress{c, i.client, i.connector, i.log})
+	}
+
+	for _, t := range i.config.Targets {
+		c := i.legacyConfig(fmt.Sprintf("%s.%s", t.Service, i.config.Name), i.targetNamespace(t), t.Ports)
+		c.Service = fmt.Sprintf("svc/%s", t.Service)
+
+		proxies = append(proxies, &LegacyIngress{c, i.client, i.connector, i.log})
+	}
+
+	return proxies
+}
+
+func (i *K8sIngress) legacyConfig(name, namespace string, ports []config.Port) *config.LegacyIngress {
+	c := config.NewLegacyIngress(name)
+
+	c.Depends = i.config.Depends
+	c.Networks = i.config.Networks
+	c.Target = i.config.Cluster
+	c.Disabled = i.config.Disabled
+	c.Type = i.config.Type
+	c.Namespace = namespace
+	c.Ports = ports
+	c.Connector = i.config.Connector
+	c.Config = i.config.Config
+
+	return c
+}
+
+func (i *K8sIngress) targetNamespace(t config.K8sIngressTarget) string {
+	if t.Namespace != "" {
+		return t.Namespace
+	}
+
+	return i.config.Namespace
+}
+
+// discoverPorts sets the ports for the service and the targets which do not define any ports
+// using the ports of the Kubernetes service, the ports are stored in the config so that the
+// ingress can be destroyed
+func (i *K8sIngress) discoverPorts() error {
+	needed := i.config.Service != "" && len(i.config.Ports) == 0
+	for _, t := range i.config.Targets {
+		needed = needed || len(t.Ports) == 0
+	}
+
+	if !needed {
+		return nil
+	}
+
+	cluster, err := i.config.FindDependentResource(i.config.Cluster)
+	if err != nil {
+		return xerrors.Errorf("Unable to find associated cluster: %w", err)
+	}
+
+	_, kubeConfigPath, _, err := utils.CreateKubeConfigPath(cluster.Info().Name)
+	if err != nil {
+		return err
+	}
+
+	kc, err := i.kubeClient.SetConfig(kubeConfigPath)
+	if err != nil {
+		return xerrors.Errorf("Unable to create Kubernetes client: %w", err)
+	}
+
+	if i.config.Service != "" && len(i.config.Ports) == 0 {
+		i.config.Ports, err = i.servicePorts(kc, i.config.Service, i.config.Namespace)
+		if err != nil {
+			return err
+		}
+	}
+
+	for n, t := range i.config.Targets {
+		if len(t.Ports) > 0 {
+			continue
+		}
+
+		i.config.Targets[n].Ports, err = i.servicePorts(kc, t.Service, i.targetNamespace(t))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// servicePorts returns the ports for a Kubernetes service, the host port is the
+// node port for NodePort and LoadBalancer services and the service port for
+// ClusterIP services
+func (i *K8sIngress) servicePorts(kc clients.Kubernetes, name, namespace string) ([]config.Port, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	svc, err := kc.GetService(name, namespace)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to discover the ports for service %s in namespace %s: %w", name, namespace, err)
+	}
+
+	ports := []config.Port{}
+	for _, sp := range svc.Spec.Ports {
+		protocol := strings.ToLower(string(sp.Protocol))
+		if protocol == "" {
+			protocol = "tcp"
+		}
+
+		// the ingress only supports tcp and udp
+		if protocol != "tcp" && protocol != "udp" {
+			continue
+		}
+
+		host := sp.Port
+		if sp.NodePort > 0 && (svc.Spec.Type == v1.ServiceTypeNodePort || svc.Spec.Type == v1.ServiceTypeLoadBalancer) {
+			host = sp.NodePort
+		}
+
+		p := config.Port{
+			Local:    strconv.Itoa(int(sp.Port)),
+			Remote:   strconv.Itoa(int(sp.Port)),
+			Host:     strconv.Itoa(int(host)),
+			Protocol: protocol,
+		}
+
+		i.log.Debug("Discovered service port", "ref", i.config.Name, "service", name, "port", p.Remote, "host", p.Host, "protocol", p.Protocol)
+
+		ports = append(ports, p)
+	}
+
+	if len(ports) == 0 {
+		return nil, xerrors.Errorf("Service %s in namespace %s does not have any tcp or udp ports", name, namespace)
+	}
+
+	return ports, nil
+}
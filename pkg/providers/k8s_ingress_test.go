@@ -0,0 +1,162 @@
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+)
+
+// testK8sIngressKubeMock returns a Kubernetes client with a ClusterIP service named
+// web and a NodePort service named api, the service named missing does not exist
+// and all other services have a single http port
+func testK8sIngressKubeMock() *clients.MockKubernetes {
+	mk := &clients.MockKubernetes{}
+	mk.On("SetConfig", mock.Anything).Return(nil)
+
+	mk.On("GetService", "web", mock.Anything).Return(&v1.Service{
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeClusterIP,
+			Ports: []v1.ServicePort{
+				{Port: 80, Protocol: v1.ProtocolTCP},
+				{Port: 53, Protocol: v1.ProtocolUDP},
+				{Port: 9000, Protocol: v1.ProtocolSCTP},
+			},
+		},
+	}, nil)
+
+	mk.On("GetService", "api", mock.Anything).Return(&v1.Service{
+		Spec: v1.ServiceSpec{
+			Type:  v1.ServiceTypeNodePort,
+			Ports: []v1.ServicePort{{Port: 8080, NodePort: 30080, Protocol: v1.ProtocolTCP}},
+		},
+	}, nil)
+
+	mk.On("GetService", "missing", mock.Anything).Return(nil, fmt.Errorf("not found"))
+
+	mk.On("GetService", mock.Anything, mock.Anything).Return(&v1.Service{
+		Spec: v1.ServiceSpec{Ports: []v1.ServicePort{{Port: 80}}},
+	}, nil)
+
+	return mk
+}
+
+func setupK8sIngressTargets(t *testing.T) (*config.K8sIngress, *clients.MockKubernetes) {
+	_, c := testIngressCreateMocks()
+
+	conf, err := c.FindResource("k8s_ingress.web-http")
+	assert.NoError(t, err)
+
+	i := conf.(*config.K8sIngress)
+	i.Service = ""
+	i.Namespace = "apps"
+	i.Targets = []config.K8sIngressTarget{
+		{Service: "web"},
+		{Service: "api", Namespace: "backend"},
+		{Service: "db", Ports: []config.Port{{Local: "5432", Remote: "5432", Host: "15432"}}},
+	}
+
+	return i, testK8sIngressKubeMock()
+}
+
+func TestK8sIngressCreatesContainerForEachTarget(t *testing.T) {
+	md, _ := testIngressCreateMocks()
+	i, mk := setupK8sIngressTargets(t)
+
+	p := NewK8sIngress(i, md, mk, &clients.ConnectorMock{}, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	calls := getCalls(&md.Mock, "CreateContainer")
+	assert.Len(t, calls, 3)
+
+	web := calls[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "web.web-http", web.Name)
+	assert.Equal(t, config.TypeK8sIngress, web.Type)
+	assert.Equal(t, []string{"--proxy-type", "kubernetes", "--namespace", "apps", "--service-name", "svc/web", "--ports", "80:80", "--ports", "53:53/udp"}, web.Command)
+
+	api := calls[1].Arguments[0].(*config.Container)
+	assert.Equal(t, "api.web-http", api.Name)
+	assert.Equal(t, "backend", api.Command[3])
+
+	db := calls[2].Arguments[0].(*config.Container)
+	assert.Equal(t, "db.web-http", db.Name)
+	assert.Equal(t, "15432", db.Ports[0].Host)
+}
+
+func TestK8sIngressDiscoversServicePorts(t *testing.T) {
+	md, _ := testIngressCreateMocks()
+	i, mk := setupK8sIngressTargets(t)
+
+	p := NewK8sIngress(i, md, mk, &clients.ConnectorMock{}, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "GetService", "web", "apps")
+	mk.AssertCalled(t, "GetService", "api", "backend")
+	mk.AssertNotCalled(t, "GetService", "db", mock.Anything)
+
+	// ClusterIP services use the service port, sctp ports are not supported
+	assert.Equal(t, []config.Port{
+		{Local: "80", Remote: "80", Host: "80", Protocol: "tcp"},
+		{Local: "53", Remote: "53", Host: "53", Protocol: "udp"},
+	}, i.Targets[0].Ports)
+
+	// NodePort services use the node port
+	assert.Equal(t, []config.Port{{Local: "8080", Remote: "8080", Host: "30080", Protocol: "tcp"}}, i.Targets[1].Ports)
+}
+
+func TestK8sIngressReturnsErrorWhenServiceNotFound(t *testing.T) {
+	md, _ := testIngressCreateMocks()
+	i, mk := setupK8sIngressTargets(t)
+	i.Targets = []config.K8sIngressTarget{{Service: "missing"}}
+
+	p := NewK8sIngress(i, md, mk, &clients.ConnectorMock{}, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+
+	md.AssertNotCalled(t, "CreateContainer", mock.Anything)
+}
+
+func TestK8sIngressWithConnectorExposesPortsWithConnector(t *testing.T) {
+	md, _ := testIngressCreateMocks()
+	i, mk := setupK8sIngressTargets(t)
+	i.Connector = true
+	i.Targets = i.Targets[2:]
+
+	mc := &clients.ConnectorMock{}
+	mc.On("ExposeStreamProxy", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("abc", nil)
+
+	p := NewK8sIngress(i, md, mk, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	db := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "127.0.0.1", db.Ports[0].Bind)
+	assert.NotEqual(t, "15432", db.Ports[0].Host)
+
+	mc.AssertCalled(t, "ExposeStreamProxy", "k8s_ingress.db.web-http.15432", "tcp", ":15432", "127.0.0.1:"+db.Ports[0].Host, "", "")
+}
+
+func TestK8sIngressDestroysContainerForEachTarget(t *testing.T) {
+	md, _ := testIngressCreateMocks()
+	i, mk := setupK8sIngressTargets(t)
+
+	p := NewK8sIngress(i, md, mk, &clients.ConnectorMock{}, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "FindContainerIDs", "web.web-http", config.TypeK8sIngress)
+	md.AssertCalled(t, "FindContainerIDs", "api.web-http", config.TypeK8sIngress)
+	md.AssertCalled(t, "FindContainerIDs", "db.web-http", config.TypeK8sIngress)
+}
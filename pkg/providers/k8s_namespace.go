@@ -0,0 +1,150 @@
+package providers
+
+import (
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// namespaceManagedBy is the label added to namespaces created by Shipyard
+const namespaceManagedBy = "app.kubernetes.io/managed-by"
+
+// K8sNamespace is a provider which creates and destroys Kubernetes namespaces
+type K8sNamespace struct {
+	config *config.K8sNamespace
+	client clients.Kubernetes
+	log    hclog.Logger
+}
+
+// NewK8sNamespace creates a provider which can create and destroy Kubernetes namespaces
+func NewK8sNamespace(c *config.K8sNamespace, kc clients.Kubernetes, l hclog.Logger) *K8sNamespace {
+	return &K8sNamespace{c, kc, l}
+}
+
+// Create the namespace along with the resource quota and default network policy
+func (n *K8sNamespace) Create() error {
+	ns := n.config.NamespaceName()
+	n.log.Info("Creating Kubernetes namespace", "ref", n.config.Name, "namespace", ns)
+
+	err := n.setup()
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]string{namespaceManagedBy: "shipyard"}
+	for k, v := range n.config.Labels {
+		labels[k] = v
+	}
+
+	err = n.client.ApplyNamespace(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: ns, Labels: labels, Annotations: n.config.Annotations},
+	})
+	if err != nil {
+		return xerrors.Errorf("Unable to create namespace %s: %w", ns, err)
+	}
+
+	if len(n.config.ResourceQuota) > 0 {
+		hard := v1.ResourceList{}
+		for k, v := range n.config.ResourceQuota {
+			q, err := resource.ParseQuantity(v)
+			if err != nil {
+				return xerrors.Errorf("Invalid resource quota for %s: %w", k, err)
+			}
+
+			hard[v1.ResourceName(k)] = q
+		}
+
+		err = n.client.ApplyResourceQuota(&v1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: ns},
+			Spec:       v1.ResourceQuotaSpec{Hard: hard},
+		})
+		if err != nil {
+			return xerrors.Errorf("Unable to create resource quota for namespace %s: %w", ns, err)
+		}
+	}
+
+	if p := n.networkPolicy(); p != nil {
+		err = n.client.ApplyNetworkPolicy(p)
+		if err != nil {
+			return xerrors.Errorf("Unable to create network policy for namespace %s: %w", ns, err)
+		}
+	}
+
+	return nil
+}
+
+// networkPolicy returns the default network policy for the namespace,
+// nil is returned when no default policy is set
+func (n *K8sNamespace) networkPolicy() *networkingv1.NetworkPolicy {
+	spec := networkingv1.NetworkPolicySpec{
+		// an empty pod selector selects all pods in the namespace
+		PodSelector: metav1.LabelSelector{},
+	}
+
+	switch n.config.DefaultNetworkPolicy {
+	case config.NetworkPolicyDenyAll:
+		spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}
+	case config.NetworkPolicyDenyIngress:
+		spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	case config.NetworkPolicyAllowSameNamespace:
+		spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+		spec.Ingress = []networkingv1.NetworkPolicyIngressRule{
+			{From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}},
+		}
+	default:
+		return nil
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: n.config.NamespaceName()},
+		Spec:       spec,
+	}
+}
+
+// Destroy the namespace, all resources in the namespace are also removed
+func (n *K8sNamespace) Destroy() error {
+	ns := n.config.NamespaceName()
+	n.log.Info("Destroy Kubernetes namespace", "ref", n.config.Name, "namespace", ns)
+
+	err := n.setup()
+	if err != nil {
+		return err
+	}
+
+	err = n.client.DeleteNamespace(ns)
+	if err != nil {
+		n.log.Debug("There was a problem destroying the Kubernetes namespace, logging message but ignoring error", "ref", n.config.Name, "error", err)
+	}
+
+	return nil
+}
+
+// Lookup is a noop for Kubernetes namespaces
+func (n *K8sNamespace) Lookup() ([]string, error) {
+	return []string{}, nil
+}
+
+func (n *K8sNamespace) setup() error {
+	cluster, err := n.config.FindDependentResource(n.config.Cluster)
+	if err != nil {
+		return xerrors.Errorf("Unable to find associated cluster: %w", err)
+	}
+
+	_, destPath, _, err := utils.CreateKubeConfigPath(cluster.Info().Name)
+	if err != nil {
+		return err
+	}
+
+	n.client, err = n.client.SetConfig(destPath)
+	if err != nil {
+		return xerrors.Errorf("unable to create Kubernetes client: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func setupK8sNamespace() (*clients.MockKubernetes, *K8sNamespace) {
+	mk := &clients.MockKubernetes{}
+	mk.On("SetConfig", mock.Anything).Return(nil)
+	mk.On("ApplyNamespace", mock.Anything).Return(nil)
+	mk.On("ApplyResourceQuota", mock.Anything).Return(nil)
+	mk.On("ApplyNetworkPolicy", mock.Anything).Return(nil)
+	mk.On("DeleteNamespace", mock.Anything).Return(nil)
+
+	c := config.NewK8sCluster("testcluster")
+	ns := config.NewK8sNamespace("apps")
+	ns.Cluster = "k8s_cluster.testcluster"
+	ns.Labels = map[string]string{"team": "platform"}
+
+	cc := config.New()
+	cc.AddResource(ns)
+	cc.AddResource(c)
+
+	return mk, NewK8sNamespace(ns, mk, hclog.NewNullLogger())
+}
+
+func TestK8sNamespaceCreatesNamespace(t *testing.T) {
+	mk, p := setupK8sNamespace()
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	_, destPath, _, _ := utils.CreateKubeConfigPath("testcluster")
+	mk.AssertCalled(t, "SetConfig", destPath)
+
+	ns := getCalls(&mk.Mock, "ApplyNamespace")[0].Arguments[0].(*v1.Namespace)
+	assert.Equal(t, "apps", ns.Name)
+	assert.Equal(t, "platform", ns.Labels["team"])
+	assert.Equal(t, "shipyard", ns.Labels[namespaceManagedBy])
+
+	mk.AssertNotCalled(t, "ApplyResourceQuota", mock.Anything)
+	mk.AssertNotCalled(t, "ApplyNetworkPolicy", mock.Anything)
+}
+
+func TestK8sNamespaceCreatesResourceQuota(t *testing.T) {
+	mk, p := setupK8sNamespace()
+	p.config.ResourceQuota = map[string]string{"pods": "10", "limits.memory": "2Gi"}
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	q := getCalls(&mk.Mock, "ApplyResourceQuota")[0].Arguments[0].(*v1.ResourceQuota)
+	assert.Equal(t, "apps", q.Namespace)
+	assert.Equal(t, int64(10), q.Spec.Hard.Pods().Value())
+	assert.Equal(t, "2Gi", q.Spec.Hard.Name("limits.memory", "").String())
+}
+
+func TestK8sNamespaceCreatesDefaultNetworkPolicy(t *testing.T) {
+	tt := map[string][]networkingv1.PolicyType{
+		config.NetworkPolicyDenyAll:            {networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		config.NetworkPolicyDenyIngress:        {networkingv1.PolicyTypeIngress},
+		config.NetworkPolicyAllowSameNamespace: {networkingv1.PolicyTypeIngress},
+	}
+
+	for k, v := range tt {
+		mk, p := setupK8sNamespace()
+		p.config.DefaultNetworkPolicy = k
+
+		err := p.Create()
+		assert.NoError(t, err)
+
+		np := getCalls(&mk.Mock, "ApplyNetworkPolicy")[0].Arguments[0].(*networkingv1.NetworkPolicy)
+		assert.Equal(t, "apps", np.Namespace)
+		assert.Equal(t, v, np.Spec.PolicyTypes, k)
+
+		if k == config.NetworkPolicyAllowSameNamespace {
+			assert.Len(t, np.Spec.Ingress, 1)
+		} else {
+			assert.Empty(t, np.Spec.Ingress)
+		}
+	}
+}
+
+func TestK8sNamespaceCreateReturnsErrorWhenNamespaceFails(t *testing.T) {
+	mk, p := setupK8sNamespace()
+	removeOn(&mk.Mock, "ApplyNamespace")
+	mk.On("ApplyNamespace", mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := p.Create()
+	assert.Error(t, err)
+}
+
+func TestK8sNamespaceDestroyDeletesNamespace(t *testing.T) {
+	mk, p := setupK8sNamespace()
+	p.config.Namespace = "my-apps"
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "DeleteNamespace", "my-apps")
+}
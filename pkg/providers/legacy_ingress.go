@@ -2,6 +2,7 @@ package providers
 
 import (
 	"fmt"
+	"strconv"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
@@ -14,18 +15,19 @@ const ingressImage = "shipyardrun/ingress:v0.3.0"
 
 // Ingress defines a provider for handling connection ingress for a cluster
 type LegacyIngress struct {
-	config *config.LegacyIngress
-	client clients.ContainerTasks
-	log    hclog.Logger
+	config    *config.LegacyIngress
+	client    clients.ContainerTasks
+	connector clients.Connector
+	log       hclog.Logger
 }
 
 // NewIngress creates a new ingress provider
-func NewLegacyIngress(c *config.LegacyIngress, cc clients.ContainerTasks, l hclog.Logger) *LegacyIngress {
-	return &LegacyIngress{c, cc, l}
+func NewLegacyIngress(c *config.LegacyIngress, cc clients.ContainerTasks, co clients.Connector, l hclog.Logger) *LegacyIngress {
+	return &LegacyIngress{c, cc, co, l}
 }
 
 // NewContainerIngress creates a new ingress provider for a container
-func NewContainerIngress(ci *config.ContainerIngress, cc clients.ContainerTasks, l hclog.Logger) *LegacyIngress {
+func NewContainerIngress(ci *config.ContainerIngress, cc clients.ContainerTasks, co clients.Connector, l hclog.Logger) *LegacyIngress {
 	c := config.NewLegacyIngress(ci.Name)
 
 	c.Depends = ci.Depends
@@ -36,11 +38,11 @@ func NewContainerIngress(ci *config.ContainerIngress, cc clients.ContainerTasks,
 	c.Disabled = ci.Disabled
 	c.Type = ci.Type
 
-	return &LegacyIngress{c, cc, l}
+	return &LegacyIngress{c, cc, co, l}
 }
 
 // NewNomadIngress creates an ingress type for resources in a Nomad cluster
-func NewNomadIngress(ci *config.NomadIngress, cc clients.ContainerTasks, l hclog.Logger) *LegacyIngress {
+func NewNomadIngress(ci *config.NomadIngress, cc clients.ContainerTasks, co clients.Connector, l hclog.Logger) *LegacyIngress {
 	c := config.NewLegacyIngress(ci.Name)
 	c.Depends = ci.Depends
 	c.Networks = ci.Networks
@@ -52,38 +54,7 @@ func NewNomadIngress(ci *config.NomadIngress, cc clients.ContainerTasks, l hclog
 
 	c.Service = fmt.Sprintf("%s.%s.%s", ci.Job, ci.Group, ci.Task)
 
-	return &LegacyIngress{c, cc, l}
-}
-
-// NewK8sIngress creates an Ingress from Kubernetes config
-func NewK8sIngress(kc *config.K8sIngress, cc clients.ContainerTasks, l hclog.Logger) *LegacyIngress {
-	// convert the config
-	c := config.NewLegacyIngress(kc.Name)
-
-	c.Depends = kc.Depends
-	c.Networks = kc.Networks
-	c.Target = kc.Cluster
-	c.Disabled = kc.Disabled
-	c.Type = kc.Type
-
-	if kc.Deployment != "" {
-		c.Service = fmt.Sprintf("deployment/%s", kc.Deployment)
-	}
-
-	if kc.Service != "" {
-		c.Service = fmt.Sprintf("svc/%s", kc.Service)
-	}
-
-	if kc.Pod != "" {
-		c.Service = kc.Pod
-	}
-
-	c.Namespace = kc.Namespace
-	c.Ports = kc.Ports
-
-	c.Config = kc.Config
-
-	return &LegacyIngress{c, cc, l}
+	return &LegacyIngress{c, cc, co, l}
 }
 
 // Create the ingress
@@ -127,7 +98,11 @@ func (i *LegacyIngress) Create() error {
 		// if this is a nomad cluster we need to add the nomadconfig and
 		// make sure that the proxy runs in nomad mode
 		serviceName = i.config.Service
-		_, nomadConfigPath := utils.GetClusterConfig(string(config.TypeNomadCluster) + "." + v.Name)
+		_, nomadConfigPath, err := utils.GetClusterConfig(string(config.TypeNomadCluster) + "." + v.Name)
+		if err != nil {
+			return err
+		}
+
 		nomadConfigDestPath := "/.nomad/"
 
 		volumes = append(volumes, config.Volume{
@@ -169,9 +144,32 @@ func (i *LegacyIngress) Create() error {
 	command = append(command, serviceName)
 
 	// add the ports
+	ports := []config.Port{}
 	for _, p := range i.config.Ports {
 		command = append(command, "--ports")
-		command = append(command, fmt.Sprintf("%s:%s", p.Local, p.Remote))
+
+		// udp ports are suffixed with the protocol, tcp is the default
+		if p.Protocol == "udp" {
+			command = append(command, fmt.Sprintf("%s:%s/udp", p.Local, p.Remote))
+		} else {
+			command = append(command, fmt.Sprintf("%s:%s", p.Local, p.Remote))
+		}
+
+		// when TLS is terminated or the ports are routed through the connector the container
+		// port is published on a random local port and the stream proxy in the connector
+		// listens on the host port
+		if i.useConnector(p) {
+			fp, err := utils.GetFreePort()
+			if err != nil {
+				return xerrors.Errorf("Unable to find a free port for the connector: %w", err)
+			}
+
+			p.Host = strconv.Itoa(fp)
+			p.Bind = "127.0.0.1"
+			p.HostIP = ""
+		}
+
+		ports = append(ports, p)
 	}
 
 	// ingress simply crease a container with specific options
@@ -180,7 +178,7 @@ func (i *LegacyIngress) Create() error {
 	i.config.ResourceInfo.AddChild(c)
 
 	c.Networks = i.config.Networks
-	c.Ports = i.config.Ports
+	c.Ports = ports
 	c.Image = &config.Image{Name: ingressImage}
 	c.Command = command
 	c.Volumes = volumes
@@ -195,7 +193,11 @@ func (i *LegacyIngress) Create() error {
 	// to the container
 	if target.Info().Type == config.TypeK8sCluster {
 		v := target.(*config.K8sCluster)
-		_, _, kubeConfigPath := utils.CreateKubeConfigPath(v.Name)
+		_, _, kubeConfigPath, err := utils.CreateKubeConfigPath(v.Name)
+		if err != nil {
+			return err
+		}
+
 		i.log.Debug("Copy KubeConfig to container", "id", id, "file", kubeConfigPath)
 
 		err = i.client.CopyFileToContainer(id, kubeConfigPath, "/")
@@ -204,16 +206,80 @@ func (i *LegacyIngress) Create() error {
 		}
 	}
 
+	err = i.exposeStreams(ports)
+	if err != nil {
+		return err
+	}
+
 	// set the state
 	i.config.Status = config.Applied
 
 	return nil
 }
 
+// exposeStreams creates a stream proxy in the connector for each port which terminates TLS
+// or is routed through the connector, the proxy listens on the host port and forwards
+// traffic to the published container port
+func (i *LegacyIngress) exposeStreams(published []config.Port) error {
+	for n, p := range i.config.Ports {
+		if !i.useConnector(p) {
+			continue
+		}
+
+		cert, key := "", ""
+		if p.TLS != nil {
+			var err error
+			cert, key, err = p.TLS.Files(i.config.Config)
+			if err != nil {
+				return xerrors.Errorf("Unable to find TLS certificate for port %s: %w", p.Local, err)
+			}
+		}
+
+		protocol := "tcp"
+		if p.Protocol == "udp" {
+			protocol = "udp"
+		}
+
+		bindAddr := fmt.Sprintf("%s:%s", p.BindAddress(), p.Host)
+		upstream := fmt.Sprintf("127.0.0.1:%s", published[n].Host)
+
+		i.log.Debug("Exposing port with the connector", "ref", i.config.Name, "bind_addr", bindAddr, "upstream", upstream, "cert", cert)
+
+		_, err := i.connector.ExposeStreamProxy(i.streamName(p), protocol, bindAddr, upstream, cert, key)
+		if err != nil {
+			return xerrors.Errorf("Unable to expose port %s with the connector: %w", p.Local, err)
+		}
+	}
+
+	return nil
+}
+
+// useConnector returns true when the host port is served by a stream proxy in the connector
+func (i *LegacyIngress) useConnector(p config.Port) bool {
+	return p.Host != "" && (p.TLS != nil || i.config.Connector)
+}
+
+// streamName returns the name of the stream proxy which terminates TLS for the port
+func (i *LegacyIngress) streamName(p config.Port) string {
+	return fmt.Sprintf("%s.%s.%s", i.config.Type, i.config.Name, p.Host)
+}
+
 // Destroy the ingress
 func (i *LegacyIngress) Destroy() error {
 	i.log.Info("Destroy Ingress", "ref", i.config.Name, "type", i.config.Type)
 
+	for _, p := range i.config.Ports {
+		if !i.useConnector(p) {
+			continue
+		}
+
+		err := i.connector.RemoveStreamProxy(i.streamName(p))
+		if err != nil {
+			// do not stop the destroy as the proxy is removed when the connector stops
+			i.log.Warn("Unable to remove connector proxy", "ref", i.config.Name, "port", p.Host, "error", err)
+		}
+	}
+
 	ids, err := i.client.FindContainerIDs(i.config.Name, i.config.Type)
 	if err != nil {
 		return err
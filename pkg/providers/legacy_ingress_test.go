@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/utils"
@@ -54,7 +55,10 @@ func TestIngressK8sErrorsWhenUnableToLookupIDs(t *testing.T) {
 	md := &mocks.MockContainerTasks{}
 	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
 
-	p := NewK8sIngress(&testK8sIngressConfig, md, hclog.NewNullLogger())
+	_, c := testIngressCreateMocks()
+	conf, _ := c.FindResource("k8s_ingress.web-http")
+
+	p := NewK8sIngress(conf.(*config.K8sIngress), md, testK8sIngressKubeMock(), &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.Error(t, err)
@@ -64,7 +68,10 @@ func TestIngressK8sErrorsWhenClusterExists(t *testing.T) {
 	md := &mocks.MockContainerTasks{}
 	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"abc"}, nil)
 
-	p := NewK8sIngress(&testK8sIngressConfig, md, hclog.NewNullLogger())
+	_, c := testIngressCreateMocks()
+	conf, _ := c.FindResource("k8s_ingress.web-http")
+
+	p := NewK8sIngress(conf.(*config.K8sIngress), md, testK8sIngressKubeMock(), &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.Error(t, err)
@@ -74,7 +81,7 @@ func TestIngressK8sTargetPullsImage(t *testing.T) {
 	md, c := testIngressCreateMocks()
 	conf, _ := c.FindResource("k8s_ingress.web-http")
 
-	p := NewK8sIngress(conf.(*config.K8sIngress), md, hclog.NewNullLogger())
+	p := NewK8sIngress(conf.(*config.K8sIngress), md, testK8sIngressKubeMock(), &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -85,7 +92,7 @@ func TestIngressK8sTargetConfiguresCommand(t *testing.T) {
 	md, c := testIngressCreateMocks()
 	conf, _ := c.FindResource("k8s_ingress.web-http")
 
-	p := NewK8sIngress(conf.(*config.K8sIngress), md, hclog.NewNullLogger())
+	p := NewK8sIngress(conf.(*config.K8sIngress), md, testK8sIngressKubeMock(), &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -107,7 +114,7 @@ func TestIngressK8sTargetConfiguresKubeConfig(t *testing.T) {
 	md, c := testIngressCreateMocks()
 	conf, _ := c.FindResource("k8s_ingress.web-http")
 
-	p := NewK8sIngress(conf.(*config.K8sIngress), md, hclog.NewNullLogger())
+	p := NewK8sIngress(conf.(*config.K8sIngress), md, testK8sIngressKubeMock(), &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -118,7 +125,7 @@ func TestIngressK8sTargetConfiguresKubeConfig(t *testing.T) {
 	assert.Equal(t, "/kubeconfig-docker.yaml", container.Environment[0].Value)
 
 	// check that the kubeconfig has been copied to the container
-	_, _, kubeConfigPath := utils.CreateKubeConfigPath("test")
+	_, _, kubeConfigPath, _ := utils.CreateKubeConfigPath("test")
 	params := getCalls(&md.Mock, "CopyFileToContainer")[0].Arguments
 	assert.Equal(t, "ingress", params[0])
 	assert.Equal(t, params[1], kubeConfigPath)
@@ -130,7 +137,7 @@ func TestIngressK8sTargetWithNamespaceConfiguresCommand(t *testing.T) {
 	tc, _ := c.FindResource("k8s_ingress.web-http")
 
 	tc.(*config.K8sIngress).Namespace = "mine"
-	p := NewK8sIngress(tc.(*config.K8sIngress), md, hclog.NewNullLogger())
+	p := NewK8sIngress(tc.(*config.K8sIngress), md, testK8sIngressKubeMock(), &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -146,7 +153,7 @@ func TestIngressK8sTargetWithServiceConfiguresCommand(t *testing.T) {
 	tc, _ := c.FindResource("k8s_ingress.web-http")
 
 	tc.(*config.K8sIngress).Service = "myservice"
-	p := NewK8sIngress(tc.(*config.K8sIngress), md, hclog.NewNullLogger())
+	p := NewK8sIngress(tc.(*config.K8sIngress), md, testK8sIngressKubeMock(), &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -163,7 +170,7 @@ func TestIngressK8sTargetWithPodConfiguresCommand(t *testing.T) {
 
 	tc.(*config.K8sIngress).Service = ""
 	tc.(*config.K8sIngress).Pod = "mypod"
-	p := NewK8sIngress(tc.(*config.K8sIngress), md, hclog.NewNullLogger())
+	p := NewK8sIngress(tc.(*config.K8sIngress), md, testK8sIngressKubeMock(), &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -180,7 +187,7 @@ func TestIngressK8sTargetWithDeploymentConfiguresCommand(t *testing.T) {
 
 	tc.(*config.K8sIngress).Deployment = "mydeployment"
 	tc.(*config.K8sIngress).Service = ""
-	p := NewK8sIngress(tc.(*config.K8sIngress), md, hclog.NewNullLogger())
+	p := NewK8sIngress(tc.(*config.K8sIngress), md, testK8sIngressKubeMock(), &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -195,7 +202,7 @@ func TestIngressContainerTargetConfiguresCommand(t *testing.T) {
 	md, c := testIngressCreateMocks()
 	tc, _ := c.FindResource("container_ingress.web-http")
 
-	p := NewContainerIngress(tc.(*config.ContainerIngress), md, hclog.NewNullLogger())
+	p := NewContainerIngress(tc.(*config.ContainerIngress), md, &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -210,7 +217,7 @@ func TestIngressContainerAddsPorts(t *testing.T) {
 	md, c := testIngressCreateMocks()
 	tc, _ := c.FindResource("container_ingress.web-http")
 
-	p := NewContainerIngress(tc.(*config.ContainerIngress), md, hclog.NewNullLogger())
+	p := NewContainerIngress(tc.(*config.ContainerIngress), md, &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -226,13 +233,74 @@ func TestIngressContainerAddsPorts(t *testing.T) {
 	assert.Equal(t, testIngressContainerConfig.Ports, params.Ports)
 }
 
+func TestIngressContainerAddsUDPPorts(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	tc, _ := c.FindResource("container_ingress.web-http")
+	tc.(*config.ContainerIngress).Ports = []config.Port{{Local: "53", Remote: "53", Host: "5353", Protocol: "udp"}}
+
+	p := NewContainerIngress(tc.(*config.ContainerIngress), md, &clients.ConnectorMock{}, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "53:53/udp", params.Command[3])
+	assert.Equal(t, "udp", params.Ports[0].Protocol)
+}
+
+func TestIngressContainerWithTLSCreatesStreamProxy(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	tc, _ := c.FindResource("container_ingress.web-http")
+	tc.(*config.ContainerIngress).Ports = []config.Port{{Local: "9093", Remote: "9092", Host: "9093", TLS: &config.IngressTLS{}}}
+
+	mc := &clients.ConnectorMock{}
+	mc.On("ExposeStreamProxy", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+
+	p := NewContainerIngress(tc.(*config.ContainerIngress), md, mc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	// the container port is published on a random local port
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.NotEqual(t, "9093", params.Ports[0].Host)
+	assert.Equal(t, "127.0.0.1", params.Ports[0].Bind)
+
+	// the stream proxy listens on the host port
+	mc.AssertCalled(t,
+		"ExposeStreamProxy",
+		"container_ingress.web-http.9093",
+		"tcp",
+		":9093",
+		"127.0.0.1:"+params.Ports[0].Host,
+		mock.Anything,
+		mock.Anything,
+	)
+}
+
+func TestIngressContainerWithTLSDestroyRemovesStreamProxy(t *testing.T) {
+	md, c := testIngressCreateMocks()
+	tc, _ := c.FindResource("container_ingress.web-http")
+	tc.(*config.ContainerIngress).Ports = []config.Port{{Local: "9093", Remote: "9092", Host: "9093", TLS: &config.IngressTLS{}}}
+
+	mc := &clients.ConnectorMock{}
+	mc.On("RemoveStreamProxy", mock.Anything).Return(nil)
+
+	p := NewContainerIngress(tc.(*config.ContainerIngress), md, mc, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	mc.AssertCalled(t, "RemoveStreamProxy", "container_ingress.web-http.9093")
+}
+
 func TestIngressContainerFailReturnsError(t *testing.T) {
 	md, c := testIngressCreateMocks()
 	tc, _ := c.FindResource("container_ingress.web-http")
 
 	removeOn(&md.Mock, "CreateContainer")
 	md.On("CreateContainer", mock.Anything).Return("", fmt.Errorf("boom"))
-	p := NewContainerIngress(tc.(*config.ContainerIngress), md, hclog.NewNullLogger())
+	p := NewContainerIngress(tc.(*config.ContainerIngress), md, &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.Error(t, err)
@@ -244,7 +312,7 @@ func TestIngressK8sTargetDestroysContainer(t *testing.T) {
 
 	removeOn(&md.Mock, "FindContainerIDs")
 	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"ingress"}, nil)
-	p := NewLegacyIngress(tc.(*config.LegacyIngress), md, hclog.NewNullLogger())
+	p := NewLegacyIngress(tc.(*config.LegacyIngress), md, &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Destroy()
 	assert.NoError(t, err)
@@ -256,7 +324,7 @@ func TestIngressNomadTargetConfiguresNomadConfig(t *testing.T) {
 	md, c := testIngressCreateMocks()
 	tc, _ := c.FindResource("nomad_ingress.web-http")
 
-	p := NewNomadIngress(tc.(*config.NomadIngress), md, hclog.NewNullLogger())
+	p := NewNomadIngress(tc.(*config.NomadIngress), md, &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -264,7 +332,7 @@ func TestIngressNomadTargetConfiguresNomadConfig(t *testing.T) {
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
 
 	// check the volume mount is set
-	_, path := utils.GetClusterConfig(string(config.TypeNomadCluster) + "." + "test")
+	_, path, _ := utils.GetClusterConfig(string(config.TypeNomadCluster) + "." + "test")
 	assert.Equal(t, path, params.Volumes[0].Source)
 	assert.Equal(t, "/.nomad/", params.Volumes[0].Destination)
 }
@@ -273,7 +341,7 @@ func TestIngressNomadTargetConfiguresCommand(t *testing.T) {
 	md, c := testIngressCreateMocks()
 	tc, _ := c.FindResource("nomad_ingress.web-http")
 
-	p := NewNomadIngress(tc.(*config.NomadIngress), md, hclog.NewNullLogger())
+	p := NewNomadIngress(tc.(*config.NomadIngress), md, &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -295,7 +363,7 @@ func TestIngressNomadAddsPorts(t *testing.T) {
 	md, c := testIngressCreateMocks()
 	tc, _ := c.FindResource("nomad_ingress.web-http")
 
-	p := NewNomadIngress(tc.(*config.NomadIngress), md, hclog.NewNullLogger())
+	p := NewNomadIngress(tc.(*config.NomadIngress), md, &clients.ConnectorMock{}, hclog.NewNullLogger())
 
 	err := p.Create()
 	assert.NoError(t, err)
@@ -0,0 +1,268 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+const logSinkImage = "timberio/vector:0.25.1-debian"
+
+// location of the files written by the file sink inside the log shipper container
+const logSinkFilePath = "/var/log/shipyard"
+
+// syslogFormat converts the log events to RFC 5424 messages, stderr is sent
+// with the severity error and stdout with the severity info
+const syslogFormat = `pri = if .stream == "stderr" { "<11>" } else { "<14>" }
+.message = pri + "1 " + format_timestamp!(.timestamp, format: "%+") + " " + string!(.container_name) + " shipyard - - - " + string!(.message)`
+
+// LogSink is a provider which runs a Vector container that ships the logs
+// of the resources in the environment to the configured sinks
+type LogSink struct {
+	config *config.LogSink
+	client clients.ContainerTasks
+	log    hclog.Logger
+}
+
+// NewLogSink creates a new LogSink provider
+func NewLogSink(c *config.LogSink, cc clients.ContainerTasks, l hclog.Logger) *LogSink {
+	return &LogSink{c, cc, l}
+}
+
+// Create starts the log shipper container
+func (l *LogSink) Create() error {
+	l.log.Info("Creating Log Sink", "ref", l.config.Name)
+
+	containers, err := l.containers()
+	if err != nil {
+		return err
+	}
+
+	vc, err := l.vectorConfig(containers)
+	if err != nil {
+		return err
+	}
+
+	configPath, err := l.writeConfig(vc)
+	if err != nil {
+		return err
+	}
+
+	cc := config.NewContainer(l.config.Name)
+	l.config.ResourceInfo.AddChild(cc)
+
+	cc.Networks = l.config.Networks
+	cc.Image = &config.Image{Name: logSinkImage}
+
+	if l.config.Image != nil {
+		cc.Image = l.config.Image
+	}
+
+	cc.Command = []string{"--config-json", "/etc/shipyard/vector.json"}
+
+	// the logs are read from the Docker API
+	cc.Volumes = []config.Volume{
+		{Source: "/var/run/docker.sock", Destination: "/var/run/docker.sock", Type: "bind"},
+		{Source: configPath, Destination: "/etc/shipyard/vector.json", Type: "bind", ReadOnly: true},
+	}
+
+	if f := l.config.File; f != nil {
+		err := os.MkdirAll(f.Path, os.ModePerm)
+		if err != nil {
+			return xerrors.Errorf("Unable to create folder for log files: %w", err)
+		}
+
+		cc.Volumes = append(cc.Volumes, config.Volume{Source: f.Path, Destination: logSinkFilePath, Type: "bind"})
+	}
+
+	err = l.client.PullImage(*cc.Image, false)
+	if err != nil {
+		return xerrors.Errorf("Unable to pull image for log sink: %w", err)
+	}
+
+	_, err = l.client.CreateContainer(cc)
+	if err != nil {
+		return xerrors.Errorf("Unable to create log sink container: %w", err)
+	}
+
+	return nil
+}
+
+// Destroy removes the log shipper container
+func (l *LogSink) Destroy() error {
+	l.log.Info("Destroy Log Sink", "ref", l.config.Name)
+
+	ids, err := l.Lookup()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := l.client.RemoveContainer(id, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lookup the ID of the log shipper container
+func (l *LogSink) Lookup() ([]string, error) {
+	return l.client.FindContainerIDs(l.config.Name, l.config.Type)
+}
+
+// containers returns the names of the containers matching the resource patterns,
+// nil is returned when no patterns are set and the logs for all containers are shipped
+func (l *LogSink) containers() ([]string, error) {
+	if len(l.config.Resources) == 0 {
+		return nil, nil
+	}
+
+	containers := []string{}
+	for _, p := range l.config.Resources {
+		matched := false
+
+		for _, r := range l.config.Config.Resources {
+			if r == l.config {
+				continue
+			}
+
+			if m, _ := path.Match(p, fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)); !m {
+				continue
+			}
+
+			matched = true
+			containers = append(containers, config.LogContainers(r)...)
+		}
+
+		if !matched {
+			return nil, xerrors.Errorf("No resources match the pattern '%s'", p)
+		}
+	}
+
+	return containers, nil
+}
+
+// vectorConfig returns the Vector config which reads the logs for the Shipyard containers
+// from the Docker API and sends them to the sinks
+func (l *LogSink) vectorConfig(containers []string) ([]byte, error) {
+	source := map[string]interface{}{
+		"type":               "docker_logs",
+		"docker_host":        "unix:///var/run/docker.sock",
+		"exclude_containers": []string{utils.FQDN(l.config.Name, string(l.config.Type))},
+	}
+
+	if containers != nil {
+		source["include_containers"] = containers
+	}
+
+	transforms := map[string]interface{}{
+		// only ship the logs for the resources created by Shipyard
+		"shipyard": map[string]interface{}{
+			"type":      "filter",
+			"inputs":    []string{"docker"},
+			"condition": `ends_with(string!(.container_name), ".shipyard.run")`,
+		},
+	}
+
+	sinks := map[string]interface{}{}
+	text := map[string]string{"codec": "text"}
+
+	if s := l.config.Loki; s != nil {
+		labels := map[string]string{
+			"source":    "shipyard",
+			"container": "{{ container_name }}",
+			"stream":    "{{ stream }}",
+		}
+
+		for k, v := range s.Labels {
+			labels[k] = v
+		}
+
+		sinks["loki"] = map[string]interface{}{
+			"type":                "loki",
+			"inputs":              []string{"shipyard"},
+			"endpoint":            s.Address,
+			"encoding":            text,
+			"labels":              labels,
+			"out_of_order_action": "accept",
+		}
+	}
+
+	if s := l.config.Syslog; s != nil {
+		transforms["syslog"] = map[string]interface{}{
+			"type":   "remap",
+			"inputs": []string{"shipyard"},
+			"source": syslogFormat,
+		}
+
+		mode := s.Protocol
+		if mode == "" {
+			mode = "udp"
+		}
+
+		sink := map[string]interface{}{
+			"type":     "socket",
+			"inputs":   []string{"syslog"},
+			"address":  s.Address,
+			"mode":     mode,
+			"encoding": text,
+		}
+
+		if mode == "tcp" {
+			sink["framing"] = map[string]string{"method": "newline_delimited"}
+		}
+
+		sinks["syslog"] = sink
+	}
+
+	if l.config.File != nil {
+		sinks["file"] = map[string]interface{}{
+			"type":     "file",
+			"inputs":   []string{"shipyard"},
+			"path":     logSinkFilePath + "/{{ container_name }}.log",
+			"encoding": text,
+		}
+	}
+
+	d, err := json.MarshalIndent(map[string]interface{}{
+		"data_dir":   "/var/lib/vector",
+		"sources":    map[string]interface{}{"docker": source},
+		"transforms": transforms,
+		"sinks":      sinks,
+	}, "", "  ")
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create config for log sink: %w", err)
+	}
+
+	return d, nil
+}
+
+// writeConfig writes the Vector config to the config folder for the resource
+// and returns the path of the file
+func (l *LogSink) writeConfig(d []byte) (string, error) {
+	dir := filepath.Join(utils.ShipyardHome(), "config", fmt.Sprintf("%s.%s", l.config.Type, l.config.Name))
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to create config folder for log sink: %w", err)
+	}
+
+	file := filepath.Join(dir, "vector.json")
+	err = ioutil.WriteFile(file, d, 0644)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to write config for log sink: %w", err)
+	}
+
+	return file, nil
+}
@@ -0,0 +1,169 @@
+package providers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupLogSinkTests(t *testing.T) (*config.LogSink, *mocks.MockContainerTasks) {
+	c := config.New()
+
+	ls := config.NewLogSink("ship")
+	ls.Networks = []config.NetworkAttachment{{Name: "network.local"}}
+	ls.Loki = &config.LokiSink{Address: "http://loki.container.shipyard.run:3100", Labels: map[string]string{"env": "dev"}}
+	c.AddResource(ls)
+
+	consul := config.NewContainer("consul")
+	c.AddResource(consul)
+
+	nomad := config.NewNomadCluster("dev")
+	nomad.ClientNodes = 1
+	c.AddResource(nomad)
+
+	md := &mocks.MockContainerTasks{}
+	md.On("PullImage", mock.Anything, false).Return(nil)
+	md.On("CreateContainer", mock.Anything).Return("abc", nil)
+	md.On("FindContainerIDs", "ship", config.TypeLogSink).Return([]string{"abc"}, nil)
+	md.On("RemoveContainer", "abc", false).Return(nil)
+
+	currentHome := os.Getenv(utils.HomeEnvName())
+	os.Setenv(utils.HomeEnvName(), t.TempDir())
+
+	t.Cleanup(func() {
+		os.Setenv(utils.HomeEnvName(), currentHome)
+	})
+
+	return ls, md
+}
+
+// readVectorConfig reads the config written for the log sink
+func readVectorConfig(t *testing.T, cc *config.Container) map[string]interface{} {
+	d, err := ioutil.ReadFile(cc.Volumes[1].Source)
+	assert.NoError(t, err)
+
+	vc := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(d, &vc))
+
+	return vc
+}
+
+func TestLogSinkCreatesContainer(t *testing.T) {
+	ls, md := setupLogSinkTests(t)
+
+	p := NewLogSink(ls, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	cc := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, logSinkImage, cc.Image.Name)
+	assert.Equal(t, config.TypeLogSink, cc.Type)
+	assert.Equal(t, "network.local", cc.Networks[0].Name)
+	assert.Equal(t, "/var/run/docker.sock", cc.Volumes[0].Source)
+	assert.Equal(t, "/etc/shipyard/vector.json", cc.Volumes[1].Destination)
+	assert.Equal(t, []string{"--config-json", "/etc/shipyard/vector.json"}, cc.Command)
+}
+
+func TestLogSinkConfiguresLoki(t *testing.T) {
+	ls, md := setupLogSinkTests(t)
+
+	p := NewLogSink(ls, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	cc := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	vc := readVectorConfig(t, cc)
+
+	source := vc["sources"].(map[string]interface{})["docker"].(map[string]interface{})
+	assert.NotContains(t, source, "include_containers")
+	assert.Equal(t, []interface{}{"ship.log-sink.shipyard.run"}, source["exclude_containers"])
+
+	loki := vc["sinks"].(map[string]interface{})["loki"].(map[string]interface{})
+	assert.Equal(t, "http://loki.container.shipyard.run:3100", loki["endpoint"])
+	assert.Equal(t, "dev", loki["labels"].(map[string]interface{})["env"])
+	assert.Equal(t, "{{ container_name }}", loki["labels"].(map[string]interface{})["container"])
+}
+
+func TestLogSinkConfiguresSyslogAndFile(t *testing.T) {
+	ls, md := setupLogSinkTests(t)
+	ls.Loki = nil
+	ls.Syslog = &config.SyslogSink{Address: "syslog.container.shipyard.run:514", Protocol: "tcp"}
+	ls.File = &config.FileSink{Path: filepath.Join(t.TempDir(), "logs")}
+
+	p := NewLogSink(ls, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	cc := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	vc := readVectorConfig(t, cc)
+
+	sinks := vc["sinks"].(map[string]interface{})
+	assert.NotContains(t, sinks, "loki")
+
+	syslog := sinks["syslog"].(map[string]interface{})
+	assert.Equal(t, "tcp", syslog["mode"])
+	assert.Equal(t, []interface{}{"syslog"}, syslog["inputs"])
+	assert.Contains(t, vc["transforms"], "syslog")
+
+	file := sinks["file"].(map[string]interface{})
+	assert.Equal(t, "/var/log/shipyard/{{ container_name }}.log", file["path"])
+
+	assert.DirExists(t, ls.File.Path)
+	assert.Equal(t, ls.File.Path, cc.Volumes[2].Source)
+	assert.Equal(t, logSinkFilePath, cc.Volumes[2].Destination)
+}
+
+func TestLogSinkIncludesContainersForResources(t *testing.T) {
+	ls, md := setupLogSinkTests(t)
+	ls.Resources = []string{"container.*", "nomad_cluster.dev"}
+
+	p := NewLogSink(ls, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	cc := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	vc := readVectorConfig(t, cc)
+
+	source := vc["sources"].(map[string]interface{})["docker"].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		"consul.container.shipyard.run",
+		"server.dev.nomad-cluster.shipyard.run",
+		"1.client.dev.nomad-cluster.shipyard.run",
+	}, source["include_containers"])
+}
+
+func TestLogSinkWithUnmatchedResourceReturnsError(t *testing.T) {
+	ls, md := setupLogSinkTests(t)
+	ls.Resources = []string{"k8s_cluster.*"}
+
+	p := NewLogSink(ls, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+
+	md.AssertNotCalled(t, "CreateContainer", mock.Anything)
+}
+
+func TestLogSinkDestroyRemovesContainer(t *testing.T) {
+	ls, md := setupLogSinkTests(t)
+
+	p := NewLogSink(ls, md, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "RemoveContainer", "abc", false)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
@@ -18,18 +19,24 @@ import (
 type Network struct {
 	config *config.Network
 	client clients.Docker
+	runner *clients.RunnerContainer
 	log    hclog.Logger
 }
 
-// NewNetwork creates a new network with the given config and Docker client
-func NewNetwork(co *config.Network, cl clients.Docker, l hclog.Logger) *Network {
-	return &Network{co, cl, l}
+// NewNetwork creates a new network with the given config and Docker client, when
+// Shipyard is running in a container the runner is attached to the network
+func NewNetwork(co *config.Network, cl clients.Docker, r *clients.RunnerContainer, l hclog.Logger) *Network {
+	return &Network{co, cl, r, l}
 }
 
 // Create implements the provider interface method for creating new networks
 func (n *Network) Create() error {
 	n.log.Info("Creating Network", "ref", n.config.Name)
 
+	if n.config.External {
+		return n.attachExternal()
+	}
+
 	// validate the subnet
 	_, cidr, err := net.ParseCIDR(n.config.Subnet)
 	if err != nil {
@@ -49,12 +56,23 @@ func (n *Network) Create() error {
 				// check that the returned networks subnet matches the existing networks subnet
 				if ci.Subnet != n.config.Subnet {
 					n.log.Debug("Network already exists, skip creation", "ref", n.config.Name)
-					return nil
+					return n.attachRunner()
 				}
 			}
 		}
 	}
 
+	subnets := []*net.IPNet{cidr}
+
+	if n.config.IPv6Subnet != "" {
+		_, cidr6, err := net.ParseCIDR(n.config.IPv6Subnet)
+		if err != nil {
+			return fmt.Errorf("Unable to create network %s, invalid ipv6 subnet %s", n.config.Name, n.config.IPv6Subnet)
+		}
+
+		subnets = append(subnets, cidr6)
+	}
+
 	// check for overlapping subnets
 	for _, ne := range nets {
 		for _, ci := range ne.IPAM.Config {
@@ -64,8 +82,10 @@ func (n *Network) Create() error {
 				return err
 			}
 
-			if cidr.Contains(cidr2.IP) || cidr2.Contains(cidr.IP) {
-				return fmt.Errorf("Unable to create network %s, Network %s already exists with an overlapping subnet %s. Either remove the network '%s' or change the subnet for your network", n.config.Name, ne.Name, ci.Subnet, ne.Name)
+			for _, s := range subnets {
+				if s.Contains(cidr2.IP) || cidr2.Contains(s.IP) {
+					return fmt.Errorf("Unable to create network %s, Network %s already exists with an overlapping subnet %s. Either remove the network '%s' or change the subnet for your network", n.config.Name, ne.Name, ci.Subnet, ne.Name)
+				}
 			}
 		}
 	}
@@ -82,10 +102,70 @@ func (n *Network) Create() error {
 		}
 	}
 
+	err = n.attachRunner()
+	if err != nil {
+		return err
+	}
+
 	// set the state
 	n.config.Status = config.Applied
 
-	return err
+	return nil
+}
+
+// attachRunner attaches the container Shipyard is running in to the network so
+// that resources can be reached by their address on the network
+func (n *Network) attachRunner() error {
+	if n.runner == nil || n.runner.Mode != clients.RunnerModeHostEngine || n.runner.ID == "" {
+		return nil
+	}
+
+	n.log.Debug("Attaching runner container to network", "ref", n.config.Name, "id", n.runner.ID)
+
+	err := n.client.NetworkConnect(context.Background(), n.config.Name, n.runner.ID, &network.EndpointSettings{})
+	if err != nil && !strings.Contains(err.Error(), "already exists") {
+		return xerrors.Errorf("Unable to attach runner container to network %s: %w", n.config.Name, err)
+	}
+
+	return nil
+}
+
+// attachExternal checks that an existing network exists, the network is not created by Shipyard
+// if the subnets have not been specified they are set from the existing network
+func (n *Network) attachExternal() error {
+	nets, err := n.getNetworks(n.config.Name)
+	if err != nil {
+		return xerrors.Errorf("Unable to list networks: %w", err)
+	}
+
+	for _, ne := range nets {
+		// Docker wildcards the name filter so check for an exact match
+		if ne.Name != n.config.Name {
+			continue
+		}
+
+		for _, ci := range ne.IPAM.Config {
+			ip, _, err := net.ParseCIDR(ci.Subnet)
+			if err != nil {
+				continue
+			}
+
+			if ip.To4() != nil && n.config.Subnet == "" {
+				n.config.Subnet = ci.Subnet
+			}
+
+			if ip.To4() == nil && n.config.IPv6Subnet == "" {
+				n.config.IPv6Subnet = ci.Subnet
+			}
+		}
+
+		n.log.Debug("Using external network", "ref", n.config.Name, "subnet", n.config.Subnet, "ipv6_subnet", n.config.IPv6Subnet)
+		n.config.Status = config.Applied
+
+		return nil
+	}
+
+	return fmt.Errorf("Unable to find external network %s, external networks must be created before they can be used", n.config.Name)
 }
 
 func (n *Network) createWithDriver(driver string) error {
@@ -103,6 +183,11 @@ func (n *Network) createWithDriver(driver string) error {
 		Attachable: true,
 	}
 
+	if n.config.IPv6Subnet != "" {
+		opts.EnableIPv6 = true
+		opts.IPAM.Config = append(opts.IPAM.Config, network.IPAMConfig{Subnet: n.config.IPv6Subnet})
+	}
+
 	_, err := n.client.NetworkCreate(context.Background(), n.config.Name, opts)
 
 	return err
@@ -112,6 +197,12 @@ func (n *Network) createWithDriver(driver string) error {
 func (n *Network) Destroy() error {
 	n.log.Info("Destroy Network", "ref", n.config.Name)
 
+	// external networks are not managed by Shipyard
+	if n.config.External {
+		n.log.Debug("Network is external, skip removal", "ref", n.config.Name)
+		return nil
+	}
+
 	// check network exists if so remove
 	ids, err := n.Lookup()
 	if err != nil {
@@ -119,6 +210,14 @@ func (n *Network) Destroy() error {
 	}
 
 	if len(ids) == 1 {
+		// the network can not be removed while the runner container is attached
+		if n.runner != nil && n.runner.Mode == clients.RunnerModeHostEngine && n.runner.ID != "" {
+			err := n.client.NetworkDisconnect(context.Background(), n.config.Name, n.runner.ID, true)
+			if err != nil {
+				n.log.Debug("Unable to detach runner container from network", "ref", n.config.Name, "error", err)
+			}
+		}
+
 		return n.client.NetworkRemove(context.Background(), n.config.Name)
 	}
 
@@ -7,6 +7,7 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/network"
 	hclog "github.com/hashicorp/go-hclog"
+	sclients "github.com/shipyard-run/shipyard/pkg/clients"
 	clients "github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/stretchr/testify/mock"
@@ -26,7 +27,7 @@ func setupNetworkTests(c *config.Network) (*clients.MockDocker, *Network) {
 	md.On("NetworkCreate", mock.Anything, mock.Anything, mock.Anything).Return(types.NetworkCreateResponse{}, nil)
 	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{bridgeNetwork}, nil)
 
-	return md, NewNetwork(c, md, hclog.Default())
+	return md, NewNetwork(c, md, nil, hclog.Default())
 }
 
 func TestLookupReturnsID(t *testing.T) {
@@ -82,6 +83,51 @@ func TestNetworkCreatesCorrectly(t *testing.T) {
 	assert.Equal(t, c.Subnet, nco.IPAM.Config[0].Subnet)
 }
 
+func TestNetworkCreateAttachesRunnerContainer(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.Subnet = "10.1.2.0/24"
+
+	md, p := setupNetworkTests(c)
+	md.On("NetworkConnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	p.runner = &sclients.RunnerContainer{ID: "runner", Mode: sclients.RunnerModeHostEngine}
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "NetworkConnect", mock.Anything, "testnet", "runner", mock.Anything)
+}
+
+func TestNetworkCreateDoesNotAttachDindRunner(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.Subnet = "10.1.2.0/24"
+
+	md, p := setupNetworkTests(c)
+	p.runner = &sclients.RunnerContainer{Mode: sclients.RunnerModeDind}
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "NetworkConnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNetworkDestroyDetachesRunnerContainer(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.Subnet = "10.1.2.0/24"
+
+	md, p := setupNetworkTests(c)
+	removeOn(&md.Mock, "NetworkList")
+	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{types.NetworkResource{ID: "testnet", Name: "testnet"}}, nil)
+	md.On("NetworkDisconnect", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	md.On("NetworkRemove", mock.Anything, mock.Anything).Return(nil)
+	p.runner = &sclients.RunnerContainer{ID: "runner", Mode: sclients.RunnerModeHostEngine}
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "NetworkDisconnect", mock.Anything, "testnet", "runner", true)
+	md.AssertCalled(t, "NetworkRemove", mock.Anything, "testnet")
+}
+
 func TestNetworkCreatesNatWhenNoBridge(t *testing.T) {
 	c := config.NewNetwork("testnet")
 	c.Subnet = "10.1.2.0/24"
@@ -160,3 +206,96 @@ func TestCreateWithOverlappingSubnetReturnsError(t *testing.T) {
 	err := p.Create()
 	assert.Error(t, err)
 }
+
+func TestNetworkCreatesWithIPv6Subnet(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.Subnet = "10.1.2.0/24"
+	c.IPv6Subnet = "fd00:1::/64"
+
+	md, p := setupNetworkTests(c)
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "NetworkCreate")[0].Arguments
+	nco := params[2].(types.NetworkCreate)
+
+	assert.True(t, nco.EnableIPv6)
+	assert.Len(t, nco.IPAM.Config, 2)
+	assert.Equal(t, "fd00:1::/64", nco.IPAM.Config[1].Subnet)
+}
+
+func TestNetworkCreateWithOverlappingIPv6SubnetReturnsError(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.Subnet = "10.1.2.0/24"
+	c.IPv6Subnet = "fd00:1::/64"
+
+	md, p := setupNetworkTests(c)
+	removeOn(&md.Mock, "NetworkList")
+	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{
+		types.NetworkResource{
+			ID:   "abc",
+			Name: "compose_default",
+			IPAM: network.IPAM{
+				Config: []network.IPAMConfig{network.IPAMConfig{Subnet: "fd00:1::/48"}},
+			},
+		}, bridgeNetwork,
+	}, nil)
+
+	err := p.Create()
+	assert.Error(t, err)
+}
+
+func TestNetworkExternalDoesNotCreate(t *testing.T) {
+	c := config.NewNetwork("compose_default")
+	c.External = true
+
+	md, p := setupNetworkTests(c)
+	removeOn(&md.Mock, "NetworkList")
+	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{
+		types.NetworkResource{
+			ID:   "abc",
+			Name: "compose_default",
+			IPAM: network.IPAM{
+				Config: []network.IPAMConfig{
+					network.IPAMConfig{Subnet: "172.20.0.0/16"},
+					network.IPAMConfig{Subnet: "fd00:20::/64"},
+				},
+			},
+		},
+	}, nil)
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "NetworkCreate", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, "172.20.0.0/16", c.Subnet)
+	assert.Equal(t, "fd00:20::/64", c.IPv6Subnet)
+}
+
+func TestNetworkExternalNotFoundReturnsError(t *testing.T) {
+	c := config.NewNetwork("compose_default")
+	c.External = true
+
+	md, p := setupNetworkTests(c)
+	removeOn(&md.Mock, "NetworkList")
+	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{
+		types.NetworkResource{ID: "abc", Name: "compose_default_2"},
+	}, nil)
+
+	err := p.Create()
+	assert.Error(t, err)
+}
+
+func TestNetworkExternalDoesNotRemove(t *testing.T) {
+	c := config.NewNetwork("compose_default")
+	c.External = true
+
+	md, p := setupNetworkTests(c)
+	md.On("NetworkRemove", mock.Anything, mock.Anything).Return(nil)
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "NetworkRemove", mock.Anything, mock.Anything)
+}
@@ -1,6 +1,12 @@
 package providers
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
@@ -34,14 +40,31 @@ func (n *NomadJob) Create() error {
 	}
 
 	// load the config
-	clusterConfig, _ := utils.GetClusterConfig(string(cc.Info().Type) + "." + cc.Info().Name)
+	clusterConfig, _, err := utils.GetClusterConfig(string(cc.Info().Type) + "." + cc.Info().Name)
+	if err != nil {
+		return err
+	}
+
 	n.client.SetConfig(clusterConfig, string(utils.LocalContext))
 
-	err = n.client.Create(n.config.Paths)
+	files, err := n.jobFiles()
+	if err != nil {
+		return err
+	}
+
+	err = n.client.Create(files)
 	if err != nil {
 		return xerrors.Errorf("Unable to create Nomad jobs: %w", err)
 	}
 
+	// wait for the deployments to complete
+	if n.config.WaitFor == config.NomadJobWaitHealthy {
+		err = n.waitForHealthy(files)
+		if err != nil {
+			return err
+		}
+	}
+
 	// if health check defined wait for jobs
 	if n.config.HealthCheck != nil {
 		st := time.Now()
@@ -84,10 +107,20 @@ func (n *NomadJob) Destroy() error {
 	}
 
 	// load the config
-	clusterConfig, _ := utils.GetClusterConfig(n.config.Cluster)
+	clusterConfig, _, err := utils.GetClusterConfig(n.config.Cluster)
+	if err != nil {
+		return err
+	}
+
 	n.client.SetConfig(clusterConfig, string(utils.LocalContext))
 
-	err = n.client.Stop(n.config.Paths)
+	files, err := n.jobFiles()
+	if err != nil {
+		n.log.Error("Unable to destroy Nomad job", "error", err)
+		return nil
+	}
+
+	err = n.client.Stop(files)
 	if err != nil {
 		n.log.Error("Unable to destroy Nomad job", "error", err)
 		return nil
@@ -96,6 +129,123 @@ func (n *NomadJob) Destroy() error {
 	return nil
 }
 
+// waitForHealthy blocks until the jobs in the given files are healthy, an error
+// is returned when a deployment fails or the timeout elapses
+func (n *NomadJob) waitForHealthy(files []string) error {
+	st := time.Now()
+	dur, err := n.config.WaitTimeout()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		id, err := n.client.JobID(f)
+		if err != nil {
+			return xerrors.Errorf("Unable to read job ID: %w", err)
+		}
+
+		for {
+			if time.Now().Sub(st) >= dur {
+				return xerrors.Errorf("Timeout waiting for job '%s' to become healthy", id)
+			}
+
+			n.log.Debug("Waiting for job to become healthy", "ref", n.config.Name, "job", id)
+
+			ok, err := n.client.JobHealthy(id)
+			if err != nil {
+				return xerrors.Errorf("Job '%s' is not healthy: %w", id, err)
+			}
+
+			if ok {
+				n.log.Debug("Job is healthy", "ref", n.config.Name, "job", id)
+				break
+			}
+
+			time.Sleep(1 * time.Second)
+		}
+	}
+
+	return nil
+}
+
+// jobFiles returns the job files for the config, when vars are set the job files
+// are processed as templates and the paths of the rendered files are returned
+func (n *NomadJob) jobFiles() ([]string, error) {
+	if n.config.VarsValue() == nil {
+		return n.config.Paths, nil
+	}
+
+	data := newTemplateData(n.config.Config, n.config.VarsValue())
+
+	tmp, err := utils.ShipyardTemp()
+	if err != nil {
+		return nil, err
+	}
+
+	out := filepath.Join(tmp, "nomad_jobs", n.config.Name)
+
+	os.RemoveAll(out)
+	err = os.MkdirAll(out, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create directory for job templates: %s", err)
+	}
+
+	sources := []string{}
+	for _, p := range n.config.Paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read job file %s: %s", p, err)
+		}
+
+		if !fi.IsDir() {
+			sources = append(sources, p)
+			continue
+		}
+
+		entries, err := ioutil.ReadDir(p)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read job directory %s: %s", p, err)
+		}
+
+		for _, e := range entries {
+			if !e.IsDir() {
+				sources = append(sources, filepath.Join(p, e.Name()))
+			}
+		}
+	}
+
+	files := []string{}
+	for i, src := range sources {
+		d, err := ioutil.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read job file %s: %s", src, err)
+		}
+
+		t, err := template.New(filepath.Base(src)).Delims("#{{", "}}").Option("missingkey=error").Parse(string(d))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse job template %s: %s", src, err)
+		}
+
+		bs := bytes.NewBufferString("")
+		err = t.Execute(bs, data)
+		if err != nil {
+			return nil, fmt.Errorf("Error processing job template %s: %s", src, err)
+		}
+
+		// prefix the files with the index as files in different directories can have the same name
+		dest := filepath.Join(out, fmt.Sprintf("%d_%s", i, filepath.Base(src)))
+
+		err = ioutil.WriteFile(dest, bs.Bytes(), os.ModePerm)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to write job file %s: %s", dest, err)
+		}
+
+		files = append(files, dest)
+	}
+
+	return files, nil
+}
+
 // Lookup the Nomad jobs defined by the config
 func (n *NomadJob) Lookup() ([]string, error) {
 	return nil, nil
@@ -2,11 +2,15 @@ package providers
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -161,3 +165,133 @@ func TestNomadJobDestroyCallsStop(t *testing.T) {
 
 	mh.AssertCalled(t, "Stop", jc.Paths)
 }
+
+func setupNomadJobTemplate(t *testing.T, jc *config.NomadJob) {
+	h := os.Getenv(utils.HomeEnvName())
+	td := t.TempDir()
+
+	os.Setenv(utils.HomeEnvName(), td)
+
+	t.Cleanup(func() {
+		os.Setenv(utils.HomeEnvName(), h)
+	})
+
+	job := filepath.Join(td, "example.nomad")
+	err := ioutil.WriteFile(job, []byte(nomadJobTemplate), os.ModePerm)
+	assert.NoError(t, err)
+
+	jc.Paths = []string{job}
+	jc.Vars = map[string]interface{}{"version": "1.2.0"}
+
+	o := config.NewOutput("db_address")
+	o.Value = "10.5.0.2:5432"
+	jc.Config.AddResource(o)
+}
+
+func TestNomadJobWithVarsCreatesRenderedJob(t *testing.T) {
+	jc, mh := setupNomadJobMocks()
+	setupNomadJobTemplate(t, jc)
+
+	p := NewNomadJob(jc, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	files := getCalls(&mh.Mock, "Create")[0].Arguments[0].([]string)
+	assert.Len(t, files, 1)
+	assert.NotEqual(t, jc.Paths[0], files[0])
+
+	d, err := ioutil.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), `image = "app:1.2.0"`)
+	assert.Contains(t, string(d), `DB_ADDR = "10.5.0.2:5432"`)
+}
+
+func TestNomadJobWithInvalidTemplateReturnsError(t *testing.T) {
+	jc, mh := setupNomadJobMocks()
+	setupNomadJobTemplate(t, jc)
+	jc.Vars = map[string]interface{}{"other": "1.2.0"}
+
+	p := NewNomadJob(jc, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+	mh.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestNomadJobWaitForHealthyReturnsOKWhenHealthy(t *testing.T) {
+	jc, mh := setupNomadJobMocks()
+	jc.WaitFor = config.NomadJobWaitHealthy
+
+	mh.On("JobID", mock.Anything).Return("example_1", nil)
+	mh.On("JobHealthy", "example_1").Return(true, nil)
+
+	p := NewNomadJob(jc, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+	mh.AssertNumberOfCalls(t, "JobHealthy", 1)
+}
+
+func TestNomadJobWaitForHealthyReturnsErrorWhenDeploymentFails(t *testing.T) {
+	jc, mh := setupNomadJobMocks()
+	jc.WaitFor = config.NomadJobWaitHealthy
+
+	mh.On("JobID", mock.Anything).Return("example_1", nil)
+	mh.On("JobHealthy", "example_1").Return(false, fmt.Errorf("deployment failed"))
+
+	p := NewNomadJob(jc, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "deployment failed")
+}
+
+func TestNomadJobWaitForHealthyReturnsErrorOnTimeout(t *testing.T) {
+	jc, mh := setupNomadJobMocks()
+	jc.WaitFor = config.NomadJobWaitHealthy
+	jc.Timeout = "2s"
+
+	mh.On("JobID", mock.Anything).Return("example_1", nil)
+	mh.On("JobHealthy", "example_1").Return(false, nil)
+
+	p := NewNomadJob(jc, mh, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+	mh.AssertNumberOfCalls(t, "JobHealthy", 2)
+}
+
+func TestNomadJobDestroyWithVarsStopsRenderedJob(t *testing.T) {
+	jc, mh := setupNomadJobMocks()
+	setupNomadJobTemplate(t, jc)
+
+	mh.On("Stop", mock.Anything).Return(nil)
+
+	p := NewNomadJob(jc, mh, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	files := getCalls(&mh.Mock, "Stop")[0].Arguments[0].([]string)
+	assert.Len(t, files, 1)
+	assert.NotEqual(t, jc.Paths[0], files[0])
+}
+
+const nomadJobTemplate = `
+job "example_1" {
+  group "app" {
+    task "app" {
+      driver = "docker"
+
+      config {
+        image = "app:#{{ .Vars.version }}"
+      }
+
+      env {
+        DB_ADDR = "#{{ .Outputs.db_address }}"
+      }
+    }
+  }
+}
+`
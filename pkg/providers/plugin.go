@@ -0,0 +1,50 @@
+package providers
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// Plugin provider creates and destroys resources using the plugin which
+// provides the resource type
+type Plugin struct {
+	config *config.PluginResource
+	client clients.Plugins
+	log    hclog.Logger
+}
+
+// NewPlugin creates a new Plugin provider
+func NewPlugin(c *config.PluginResource, pc clients.Plugins, l hclog.Logger) *Plugin {
+	return &Plugin{c, pc, l}
+}
+
+// Create the resource using the plugin
+func (p *Plugin) Create() error {
+	p.log.Info("Creating Plugin Resource", "ref", p.config.Name, "type", p.config.Type, "plugin", p.config.Plugin)
+
+	err := p.client.Create(p.config)
+	if err != nil {
+		return xerrors.Errorf("Unable to create %s.%s: %w", p.config.Type, p.config.Name, err)
+	}
+
+	return nil
+}
+
+// Destroy the resource using the plugin
+func (p *Plugin) Destroy() error {
+	p.log.Info("Destroy Plugin Resource", "ref", p.config.Name, "type", p.config.Type, "plugin", p.config.Plugin)
+
+	err := p.client.Destroy(p.config)
+	if err != nil {
+		return xerrors.Errorf("Unable to destroy %s.%s: %w", p.config.Type, p.config.Name, err)
+	}
+
+	return nil
+}
+
+// Lookup returns the ids of the objects the plugin created for the resource
+func (p *Plugin) Lookup() ([]string, error) {
+	return p.client.Lookup(p.config)
+}
@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPluginTests() (*config.PluginResource, *mocks.MockPlugins) {
+	c := config.NewPluginResource("dev", "firecracker_vm", "firecracker")
+	pc := &mocks.MockPlugins{}
+
+	return c, pc
+}
+
+func TestPluginCreateCallsPlugin(t *testing.T) {
+	c, pc := setupPluginTests()
+	pc.On("Create", c).Return(nil)
+
+	p := NewPlugin(c, pc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	pc.AssertCalled(t, "Create", c)
+}
+
+func TestPluginCreateReturnsErrorWhenPluginFails(t *testing.T) {
+	c, pc := setupPluginTests()
+	pc.On("Create", c).Return(fmt.Errorf("boom"))
+
+	p := NewPlugin(c, pc, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "firecracker_vm.dev")
+}
+
+func TestPluginDestroyCallsPlugin(t *testing.T) {
+	c, pc := setupPluginTests()
+	pc.On("Destroy", c).Return(nil)
+
+	p := NewPlugin(c, pc, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	pc.AssertCalled(t, "Destroy", c)
+}
+
+func TestPluginLookupReturnsIDs(t *testing.T) {
+	c, pc := setupPluginTests()
+	pc.On("Lookup", c).Return([]string{"vm-1"}, nil)
+
+	p := NewPlugin(c, pc, hclog.NewNullLogger())
+
+	ids, err := p.Lookup()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vm-1"}, ids)
+}
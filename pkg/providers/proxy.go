@@ -0,0 +1,38 @@
+package providers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// clusterProxyEnv returns the proxy environment for the nodes of clusters. Images are
+// pulled through the image cache unless a proxy has been configured, PROXY_CA contains
+// the CA of the image cache and the CAs of the proxy which are trusted by the nodes
+func clusterProxyEnv() (map[string]string, error) {
+	ca, err := ioutil.ReadFile(filepath.Join(utils.CertsDir(""), "/root.cert"))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read root CA for proxy: %s", err)
+	}
+
+	pca, err := clients.ProxyCAs()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read proxy CA: %s", err)
+	}
+
+	if len(pca) > 0 {
+		ca = bytes.TrimRight(ca, "\n")
+		ca = append(append(ca, '\n'), pca...)
+	}
+
+	return map[string]string{
+		"HTTP_PROXY":  utils.HTTPProxyAddress(),
+		"HTTPS_PROXY": utils.HTTPSProxyAddress(),
+		"NO_PROXY":    utils.NoProxy(),
+		"PROXY_CA":    string(ca),
+	}, nil
+}
@@ -0,0 +1,81 @@
+package providers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupProxyCA(t *testing.T) string {
+	t.Setenv(utils.HomeEnvName(), t.TempDir())
+	t.Setenv("GIT_SSL_CAINFO", "")
+
+	err := ioutil.WriteFile(filepath.Join(utils.CertsDir(""), "root.cert"), []byte("CA\n"), 0644)
+	assert.NoError(t, err)
+
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Corporate Root CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	d, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &k.PublicKey, k)
+	assert.NoError(t, err)
+
+	f := filepath.Join(t.TempDir(), "ca.pem")
+	err = ioutil.WriteFile(f, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: d}), 0644)
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		clients.SetProxy(nil)
+	})
+
+	return f
+}
+
+func TestClusterProxyEnvReturnsImageCacheCA(t *testing.T) {
+	setupProxyCA(t)
+	t.Setenv("NO_PROXY", "")
+	os.Unsetenv("NO_PROXY")
+
+	env, err := clusterProxyEnv()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "CA\n", env["PROXY_CA"])
+	assert.Equal(t, utils.ProxyBypass, env["NO_PROXY"])
+}
+
+func TestClusterProxyEnvAddsProxyCA(t *testing.T) {
+	ca := setupProxyCA(t)
+	t.Setenv("NO_PROXY", "internal.corp")
+
+	err := clients.SetProxy(&config.Proxy{CAFile: ca})
+	assert.NoError(t, err)
+
+	env, err := clusterProxyEnv()
+	assert.NoError(t, err)
+
+	d, _ := ioutil.ReadFile(ca)
+	assert.Equal(t, "CA\n"+string(d), env["PROXY_CA"])
+	assert.Contains(t, env["NO_PROXY"], "internal.corp")
+}
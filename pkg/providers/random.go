@@ -0,0 +1,83 @@
+package providers
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// RandomID provider generates a random identifier
+type RandomID struct {
+	config *config.RandomID
+	log    hclog.Logger
+}
+
+// NewRandomID creates a new RandomID provider
+func NewRandomID(c *config.RandomID, l hclog.Logger) *RandomID {
+	return &RandomID{c, l}
+}
+
+// Create generates the identifier, values which have been generated when
+// parsing the config or are stored in the state are not changed
+func (r *RandomID) Create() error {
+	r.log.Info("Creating Random ID", "ref", r.config.Name)
+
+	if r.config.Hex != "" {
+		return nil
+	}
+
+	err := r.config.Generate()
+	if err != nil {
+		return xerrors.Errorf("Unable to generate random id: %w", err)
+	}
+
+	return nil
+}
+
+// Destroy statisfies the interface method but is not implemented by RandomID
+func (r *RandomID) Destroy() error {
+	return nil
+}
+
+// Lookup statisfies the interface method but is not implemented by RandomID
+func (r *RandomID) Lookup() ([]string, error) {
+	return []string{}, nil
+}
+
+// RandomPassword provider generates a random password
+type RandomPassword struct {
+	config *config.RandomPassword
+	log    hclog.Logger
+}
+
+// NewRandomPassword creates a new RandomPassword provider
+func NewRandomPassword(c *config.RandomPassword, l hclog.Logger) *RandomPassword {
+	return &RandomPassword{c, l}
+}
+
+// Create generates the password, values which have been generated when
+// parsing the config or are stored in the state are not changed
+func (r *RandomPassword) Create() error {
+	r.log.Info("Creating Random Password", "ref", r.config.Name)
+
+	if r.config.Result != "" {
+		return nil
+	}
+
+	err := r.config.Generate()
+	if err != nil {
+		return xerrors.Errorf("Unable to generate random password: %w", err)
+	}
+
+	return nil
+}
+
+// Destroy statisfies the interface method but is not implemented by RandomPassword
+func (r *RandomPassword) Destroy() error {
+	return nil
+}
+
+// Lookup statisfies the interface method but is not implemented by RandomPassword
+func (r *RandomPassword) Lookup() ([]string, error) {
+	return []string{}, nil
+}
@@ -0,0 +1,48 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomIDCreateGeneratesValue(t *testing.T) {
+	c := config.NewRandomID("suffix")
+
+	err := NewRandomID(c, hclog.NewNullLogger()).Create()
+	assert.NoError(t, err)
+
+	assert.Len(t, c.Hex, 16)
+	assert.NotEmpty(t, c.Dec)
+}
+
+func TestRandomIDCreateDoesNotChangeExistingValue(t *testing.T) {
+	c := config.NewRandomID("suffix")
+	c.Hex = "abcd"
+
+	err := NewRandomID(c, hclog.NewNullLogger()).Create()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "abcd", c.Hex)
+}
+
+func TestRandomPasswordCreateGeneratesValue(t *testing.T) {
+	c := config.NewRandomPassword("db")
+
+	err := NewRandomPassword(c, hclog.NewNullLogger()).Create()
+	assert.NoError(t, err)
+
+	assert.Len(t, c.Result, 16)
+}
+
+func TestRandomPasswordCreateDoesNotChangeExistingValue(t *testing.T) {
+	c := config.NewRandomPassword("db")
+	c.Result = "secret"
+
+	err := NewRandomPassword(c, hclog.NewNullLogger()).Create()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "secret", c.Result)
+}
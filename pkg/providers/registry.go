@@ -0,0 +1,266 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/xerrors"
+	"sigs.k8s.io/yaml"
+)
+
+const registryImage = "registry:2.8.1"
+
+// Registry is a provider which runs a local Docker registry
+type Registry struct {
+	config *config.Registry
+	client clients.ContainerTasks
+	log    hclog.Logger
+}
+
+// NewRegistry creates a new Registry provider
+func NewRegistry(c *config.Registry, cc clients.ContainerTasks, l hclog.Logger) *Registry {
+	return &Registry{c, cc, l}
+}
+
+// Create starts the registry container
+func (r *Registry) Create() error {
+	r.log.Info("Creating Registry", "ref", r.config.Name, "address", r.config.Address())
+
+	cc := config.NewContainer(r.config.Name)
+	r.config.ResourceInfo.AddChild(cc)
+
+	cc.Networks = r.config.Networks
+	cc.Image = &config.Image{Name: registryImage}
+
+	if r.config.Image != nil {
+		cc.Image = r.config.Image
+	}
+
+	// store the images in a volume so that they are retained
+	// when the registry is recreated
+	vol, err := r.client.CreateVolume(fmt.Sprintf("%s.%s", r.config.Name, r.config.Type))
+	if err != nil {
+		return xerrors.Errorf("Unable to create volume for registry: %w", err)
+	}
+
+	cc.Volumes = []config.Volume{
+		{Source: vol, Destination: "/var/lib/registry", Type: "volume"},
+	}
+
+	cc.EnvVar = map[string]string{
+		"REGISTRY_HTTP_ADDR": fmt.Sprintf("0.0.0.0:%d", config.RegistryPort),
+	}
+
+	if p := r.config.Proxy; p != nil {
+		cc.EnvVar["REGISTRY_PROXY_REMOTEURL"] = p.RemoteURL
+
+		if p.Username != "" {
+			cc.EnvVar["REGISTRY_PROXY_USERNAME"] = p.Username
+			cc.EnvVar["REGISTRY_PROXY_PASSWORD"] = p.Password
+		}
+	}
+
+	if a := r.config.Auth; a != nil {
+		htpasswd, err := r.writeHtpasswd(a)
+		if err != nil {
+			return err
+		}
+
+		cc.Volumes = append(cc.Volumes, config.Volume{Source: htpasswd, Destination: "/auth/htpasswd", Type: "bind", ReadOnly: true})
+
+		cc.EnvVar["REGISTRY_AUTH"] = "htpasswd"
+		cc.EnvVar["REGISTRY_AUTH_HTPASSWD_REALM"] = "Shipyard Registry"
+		cc.EnvVar["REGISTRY_AUTH_HTPASSWD_PATH"] = "/auth/htpasswd"
+	}
+
+	if r.config.Port > 0 {
+		cc.Ports = []config.Port{
+			{
+				Local:    fmt.Sprintf("%d", config.RegistryPort),
+				Host:     fmt.Sprintf("%d", r.config.Port),
+				Protocol: "tcp",
+			},
+		}
+	}
+
+	err = r.client.PullImage(*cc.Image, false)
+	if err != nil {
+		return xerrors.Errorf("Unable to pull image for registry: %w", err)
+	}
+
+	_, err = r.client.CreateContainer(cc)
+	if err != nil {
+		return xerrors.Errorf("Unable to create registry container: %w", err)
+	}
+
+	return nil
+}
+
+// writeHtpasswd writes the bcrypt hashed credentials to a htpasswd file
+// and returns the path of the file
+func (r *Registry) writeHtpasswd(a *config.RegistryAuth) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(a.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to hash registry password: %w", err)
+	}
+
+	dir := filepath.Join(utils.ShipyardHome(), "config", fmt.Sprintf("%s.%s", r.config.Type, r.config.Name))
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to create config folder for registry: %w", err)
+	}
+
+	path := filepath.Join(dir, "htpasswd")
+	err = ioutil.WriteFile(path, []byte(fmt.Sprintf("%s:%s\n", a.Username, hash)), 0600)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to write htpasswd for registry: %w", err)
+	}
+
+	return path, nil
+}
+
+// Destroy removes the registry container, the volume containing
+// the images is retained
+func (r *Registry) Destroy() error {
+	r.log.Info("Destroy Registry", "ref", r.config.Name)
+
+	ids, err := r.Lookup()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := r.client.RemoveContainer(id, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lookup the ID of the registry container
+func (r *Registry) Lookup() ([]string, error) {
+	return r.client.FindContainerIDs(r.config.Name, r.config.Type)
+}
+
+// k3sRegistries is the format of the registries.yaml file used by k3s
+// https://rancher.com/docs/k3s/latest/en/installation/private-registry/
+type k3sRegistries struct {
+	Mirrors map[string]k3sMirror         `json:"mirrors"`
+	Configs map[string]k3sRegistryConfig `json:"configs,omitempty"`
+}
+
+type k3sMirror struct {
+	Endpoint []string `json:"endpoint"`
+}
+
+type k3sRegistryConfig struct {
+	Auth *k3sRegistryAuth `json:"auth,omitempty"`
+}
+
+type k3sRegistryAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// k3sRegistriesConfig returns the k3s registries.yaml which configures containerd
+// to pull from the registries over http and to use the registry for any upstream
+// registry it is a pull-through cache for.
+// The first registry is added as the preferred mirror for the given domains,
+// images pushed to the registry are then pulled from it before the upstream
+func k3sRegistriesConfig(registries []*config.Registry, domains []string) ([]byte, error) {
+	rc := k3sRegistries{Mirrors: map[string]k3sMirror{}, Configs: map[string]k3sRegistryConfig{}}
+
+	for _, r := range registries {
+		endpoint := []string{fmt.Sprintf("http://%s", r.Address())}
+
+		rc.Mirrors[r.Address()] = k3sMirror{Endpoint: endpoint}
+
+		if m := r.Mirrors(); m != "" {
+			rc.Mirrors[m] = k3sMirror{Endpoint: endpoint}
+		}
+
+		if r.Auth != nil {
+			rc.Configs[r.Address()] = k3sRegistryConfig{Auth: &k3sRegistryAuth{Username: r.Auth.Username, Password: r.Auth.Password}}
+		}
+	}
+
+	if len(registries) > 0 {
+		endpoint := fmt.Sprintf("http://%s", registries[0].Address())
+
+		for _, d := range domains {
+			rc.Mirrors[d] = k3sMirror{Endpoint: prependEndpoint(endpoint, rc.Mirrors[d].Endpoint)}
+		}
+	}
+
+	return yaml.Marshal(rc)
+}
+
+// dockerDaemonConfig returns the Docker daemon.json which configures the Docker
+// engine to trust the registries and to use them as mirrors for Docker Hub.
+// When mirrorHub is true the first registry is added as the preferred mirror
+// for Docker Hub
+func dockerDaemonConfig(registries []*config.Registry, mirrorHub bool) ([]byte, error) {
+	dc := struct {
+		InsecureRegistries []string `json:"insecure-registries"`
+		RegistryMirrors    []string `json:"registry-mirrors,omitempty"`
+	}{}
+
+	for _, r := range registries {
+		dc.InsecureRegistries = append(dc.InsecureRegistries, r.Address())
+
+		// the Docker engine only supports mirrors for Docker Hub
+		if r.Mirrors() == "docker.io" {
+			dc.RegistryMirrors = append(dc.RegistryMirrors, fmt.Sprintf("http://%s", r.Address()))
+		}
+	}
+
+	if mirrorHub && len(registries) > 0 {
+		dc.RegistryMirrors = prependEndpoint(fmt.Sprintf("http://%s", registries[0].Address()), dc.RegistryMirrors)
+	}
+
+	return json.Marshal(dc)
+}
+
+// prependEndpoint adds the endpoint to the start of the list removing
+// any existing entry for the endpoint
+func prependEndpoint(endpoint string, endpoints []string) []string {
+	eps := []string{endpoint}
+	for _, e := range endpoints {
+		if e != endpoint {
+			eps = append(eps, e)
+		}
+	}
+
+	return eps
+}
+
+// clusterRegistries returns the registry resources referenced by a cluster
+func clusterRegistries(r *config.ResourceInfo, names []string) ([]*config.Registry, error) {
+	registries := []*config.Registry{}
+
+	for _, n := range names {
+		dr, err := r.FindDependentResource(n)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to find registry %s: %w", n, err)
+		}
+
+		reg, ok := dr.(*config.Registry)
+		if !ok {
+			return nil, fmt.Errorf("Resource %s is not a registry", n)
+		}
+
+		registries = append(registries, reg)
+	}
+
+	return registries, nil
+}
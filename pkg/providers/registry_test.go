@@ -0,0 +1,179 @@
+package providers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"sigs.k8s.io/yaml"
+)
+
+func setupRegistryTests(t *testing.T) (*config.Registry, *mocks.MockContainerTasks) {
+	rc := config.NewRegistry("local")
+	rc.Networks = []config.NetworkAttachment{{Name: "network.local"}}
+
+	md := &mocks.MockContainerTasks{}
+	md.On("CreateVolume", "local.registry").Return("local.registry", nil)
+	md.On("PullImage", mock.Anything, false).Return(nil)
+	md.On("CreateContainer", mock.Anything).Return("abc", nil)
+	md.On("FindContainerIDs", "local", config.TypeRegistry).Return([]string{"abc"}, nil)
+	md.On("RemoveContainer", "abc", false).Return(nil)
+
+	currentHome := os.Getenv(utils.HomeEnvName())
+	os.Setenv(utils.HomeEnvName(), t.TempDir())
+
+	t.Cleanup(func() {
+		os.Setenv(utils.HomeEnvName(), currentHome)
+	})
+
+	return rc, md
+}
+
+func TestRegistryCreatesContainer(t *testing.T) {
+	rc, md := setupRegistryTests(t)
+
+	p := NewRegistry(rc, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	cc := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, registryImage, cc.Image.Name)
+	assert.Equal(t, config.TypeRegistry, cc.Type)
+	assert.Equal(t, "network.local", cc.Networks[0].Name)
+	assert.Equal(t, "/var/lib/registry", cc.Volumes[0].Destination)
+	assert.Empty(t, cc.Ports)
+	assert.NotContains(t, cc.EnvVar, "REGISTRY_PROXY_REMOTEURL")
+	assert.NotContains(t, cc.EnvVar, "REGISTRY_AUTH")
+}
+
+func TestRegistryCreatesPullThroughCache(t *testing.T) {
+	rc, md := setupRegistryTests(t)
+	rc.Port = 5001
+	rc.Proxy = &config.RegistryProxy{RemoteURL: "https://registry-1.docker.io", Username: "nic", Password: "secret"}
+
+	p := NewRegistry(rc, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	cc := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "https://registry-1.docker.io", cc.EnvVar["REGISTRY_PROXY_REMOTEURL"])
+	assert.Equal(t, "nic", cc.EnvVar["REGISTRY_PROXY_USERNAME"])
+	assert.Equal(t, "secret", cc.EnvVar["REGISTRY_PROXY_PASSWORD"])
+	assert.Equal(t, "5001", cc.Ports[0].Host)
+	assert.Equal(t, "5000", cc.Ports[0].Local)
+}
+
+func TestRegistryCreatesHtpasswdForAuth(t *testing.T) {
+	rc, md := setupRegistryTests(t)
+	rc.Auth = &config.RegistryAuth{Username: "admin", Password: "password"}
+
+	p := NewRegistry(rc, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	cc := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "htpasswd", cc.EnvVar["REGISTRY_AUTH"])
+	assert.Equal(t, "/auth/htpasswd", cc.Volumes[1].Destination)
+
+	d, err := ioutil.ReadFile(cc.Volumes[1].Source)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^admin:\$2a\$`, string(d))
+
+	hash := string(d)[len("admin:") : len(d)-1]
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("password")))
+}
+
+func TestRegistryDestroyRemovesContainer(t *testing.T) {
+	rc, md := setupRegistryTests(t)
+
+	p := NewRegistry(rc, md, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "RemoveContainer", "abc", false)
+}
+
+func TestK3sRegistriesConfigAddsMirrors(t *testing.T) {
+	local := config.NewRegistry("local")
+	local.Auth = &config.RegistryAuth{Username: "admin", Password: "password"}
+
+	hub := config.NewRegistry("hub")
+	hub.Proxy = &config.RegistryProxy{RemoteURL: "https://registry-1.docker.io"}
+
+	d, err := k3sRegistriesConfig([]*config.Registry{local, hub}, nil)
+	assert.NoError(t, err)
+
+	rc := k3sRegistries{}
+	err = yaml.Unmarshal(d, &rc)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"http://local.registry.shipyard.run:5000"}, rc.Mirrors["local.registry.shipyard.run:5000"].Endpoint)
+	assert.Equal(t, []string{"http://hub.registry.shipyard.run:5000"}, rc.Mirrors["docker.io"].Endpoint)
+	assert.Equal(t, "admin", rc.Configs["local.registry.shipyard.run:5000"].Auth.Username)
+	assert.NotContains(t, rc.Configs, "hub.registry.shipyard.run:5000")
+}
+
+func TestDockerDaemonConfigAddsInsecureRegistriesAndMirrors(t *testing.T) {
+	local := config.NewRegistry("local")
+
+	hub := config.NewRegistry("hub")
+	hub.Proxy = &config.RegistryProxy{RemoteURL: "https://registry-1.docker.io"}
+
+	ghcr := config.NewRegistry("ghcr")
+	ghcr.Proxy = &config.RegistryProxy{RemoteURL: "https://ghcr.io"}
+
+	d, err := dockerDaemonConfig([]*config.Registry{local, hub, ghcr}, false)
+	assert.NoError(t, err)
+
+	dc := map[string][]string{}
+	err = json.Unmarshal(d, &dc)
+	assert.NoError(t, err)
+
+	assert.Len(t, dc["insecure-registries"], 3)
+	assert.Equal(t, []string{"http://hub.registry.shipyard.run:5000"}, dc["registry-mirrors"])
+}
+
+func TestK3sRegistriesConfigAddsFirstRegistryAsMirrorForDomains(t *testing.T) {
+	local := config.NewRegistry("local")
+
+	hub := config.NewRegistry("hub")
+	hub.Proxy = &config.RegistryProxy{RemoteURL: "https://registry-1.docker.io"}
+
+	d, err := k3sRegistriesConfig([]*config.Registry{local, hub}, []string{"docker.io", "ghcr.io"})
+	assert.NoError(t, err)
+
+	rc := k3sRegistries{}
+	err = yaml.Unmarshal(d, &rc)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"http://local.registry.shipyard.run:5000", "http://hub.registry.shipyard.run:5000"}, rc.Mirrors["docker.io"].Endpoint)
+	assert.Equal(t, []string{"http://local.registry.shipyard.run:5000"}, rc.Mirrors["ghcr.io"].Endpoint)
+}
+
+func TestDockerDaemonConfigAddsFirstRegistryAsHubMirror(t *testing.T) {
+	local := config.NewRegistry("local")
+
+	hub := config.NewRegistry("hub")
+	hub.Proxy = &config.RegistryProxy{RemoteURL: "https://registry-1.docker.io"}
+
+	d, err := dockerDaemonConfig([]*config.Registry{local, hub}, true)
+	assert.NoError(t, err)
+
+	dc := map[string][]string{}
+	err = json.Unmarshal(d, &dc)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"http://local.registry.shipyard.run:5000", "http://hub.registry.shipyard.run:5000"}, dc["registry-mirrors"])
+}
@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"fmt"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// Router is a provider which creates a reverse proxy in the connector
+type Router struct {
+	config    *config.Router
+	connector clients.Connector
+	log       hclog.Logger
+}
+
+// NewRouter creates a new Router provider
+func NewRouter(c *config.Router, co clients.Connector, l hclog.Logger) *Router {
+	return &Router{c, co, l}
+}
+
+// Create the router in the connector
+func (r *Router) Create() error {
+	r.log.Info("Creating Router", "ref", r.config.Name, "port", r.config.Port)
+
+	for _, rt := range r.config.Routes {
+		r.log.Debug("Adding route", "ref", r.config.Name, "host", rt.Host, "path", rt.Path, "destination", rt.Destination)
+	}
+
+	id, err := r.connector.ExposeRouter(
+		fmt.Sprintf("%s.%s", r.config.Type, r.config.Name),
+		fmt.Sprintf(":%d", r.config.Port),
+		r.config.Routes,
+	)
+
+	if err != nil {
+		return xerrors.Errorf("Unable to create router: %w", err)
+	}
+
+	r.config.RouterId = id
+
+	return nil
+}
+
+// Destroy the router
+func (r *Router) Destroy() error {
+	r.log.Info("Destroy Router", "ref", r.config.Name, "id", r.config.RouterId)
+
+	if r.config.RouterId == "" {
+		return nil
+	}
+
+	err := r.connector.RemoveRouter(r.config.RouterId)
+	if err != nil {
+		// do not stop the destroy as the router is removed when the connector stops
+		r.log.Warn("Unable to remove router", "ref", r.config.Name, "id", r.config.RouterId, "error", err)
+	}
+
+	return nil
+}
+
+// Lookup satisfies the interface requirements but is not used
+// as the router does not create any containers
+func (r *Router) Lookup() ([]string, error) {
+	return []string{}, nil
+}
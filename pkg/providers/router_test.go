@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupRouter() (*Router, *clients.ConnectorMock) {
+	c := config.NewRouter("frontend")
+	c.Port = 8080
+	c.Routes = []config.Route{
+		{Path: "/", Destination: "localhost:3000"},
+		{Path: "/api", Destination: "localhost:9090", StripPrefix: true},
+	}
+
+	mc := &clients.ConnectorMock{}
+	mc.On("ExposeRouter", mock.Anything, mock.Anything, mock.Anything).Return("router.frontend", nil)
+	mc.On("RemoveRouter", mock.Anything).Return(nil)
+
+	return NewRouter(c, mc, hclog.NewNullLogger()), mc
+}
+
+func TestRouterCreateExposesRouterInConnector(t *testing.T) {
+	r, mc := setupRouter()
+
+	err := r.Create()
+	assert.NoError(t, err)
+
+	mc.AssertCalled(t, "ExposeRouter", "router.frontend", ":8080", r.config.Routes)
+	assert.Equal(t, "router.frontend", r.config.RouterId)
+}
+
+func TestRouterCreateReturnsErrorWhenConnectorFails(t *testing.T) {
+	r, mc := setupRouter()
+	removeOn(&mc.Mock, "ExposeRouter")
+	mc.On("ExposeRouter", mock.Anything, mock.Anything, mock.Anything).Return("", fmt.Errorf("boom"))
+
+	err := r.Create()
+	assert.Error(t, err)
+}
+
+func TestRouterDestroyRemovesRouter(t *testing.T) {
+	r, mc := setupRouter()
+	r.config.RouterId = "router.frontend"
+
+	err := r.Destroy()
+	assert.NoError(t, err)
+
+	mc.AssertCalled(t, "RemoveRouter", "router.frontend")
+}
+
+func TestRouterDestroyDoesNotFailWhenRemoveFails(t *testing.T) {
+	r, mc := setupRouter()
+	r.config.RouterId = "router.frontend"
+	removeOn(&mc.Mock, "RemoveRouter")
+	mc.On("RemoveRouter", mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := r.Destroy()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"fmt"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// SocksProxy is a provider which creates a SOCKS5 proxy in the connector
+type SocksProxy struct {
+	config    *config.SocksProxy
+	connector clients.Connector
+	log       hclog.Logger
+}
+
+// NewSocksProxy creates a new SocksProxy provider
+func NewSocksProxy(c *config.SocksProxy, co clients.Connector, l hclog.Logger) *SocksProxy {
+	return &SocksProxy{c, co, l}
+}
+
+// Create the SOCKS5 proxy in the connector
+func (s *SocksProxy) Create() error {
+	s.log.Info("Creating SOCKS Proxy", "ref", s.config.Name, "port", s.config.Port, "cluster", s.config.Cluster)
+
+	// connections are tunneled through the connector running in the cluster
+	connectorAddr := ""
+	if s.config.Cluster != "" {
+		clusterConfig, _, err := utils.GetClusterConfig(s.config.Cluster)
+		if err != nil {
+			return err
+		}
+
+		connectorAddr = clusterConfig.ConnectorAddress(utils.LocalContext)
+	}
+
+	id, err := s.connector.ExposeSocksProxy(
+		fmt.Sprintf("%s.%s", s.config.Type, s.config.Name),
+		fmt.Sprintf(":%d", s.config.Port),
+		connectorAddr,
+	)
+
+	if err != nil {
+		return xerrors.Errorf("Unable to create SOCKS proxy: %w", err)
+	}
+
+	s.config.ProxyId = id
+
+	return nil
+}
+
+// Destroy the SOCKS5 proxy
+func (s *SocksProxy) Destroy() error {
+	s.log.Info("Destroy SOCKS Proxy", "ref", s.config.Name, "id", s.config.ProxyId)
+
+	if s.config.ProxyId == "" {
+		return nil
+	}
+
+	err := s.connector.RemoveSocksProxy(s.config.ProxyId)
+	if err != nil {
+		// do not stop the destroy as the proxy is removed when the connector stops
+		s.log.Warn("Unable to remove SOCKS proxy", "ref", s.config.Name, "id", s.config.ProxyId, "error", err)
+	}
+
+	return nil
+}
+
+// Lookup satisfies the interface requirements but is not used
+// as the proxy does not create any containers
+func (s *SocksProxy) Lookup() ([]string, error) {
+	return []string{}, nil
+}
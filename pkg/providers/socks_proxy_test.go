@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupSocksProxy(t *testing.T) (*SocksProxy, *clients.ConnectorMock) {
+	t.Setenv("HOME", t.TempDir())
+
+	c := config.NewSocksProxy("cluster")
+	c.Port = 1080
+
+	mc := &clients.ConnectorMock{}
+	mc.On("ExposeSocksProxy", mock.Anything, mock.Anything, mock.Anything).Return("socks_proxy.cluster", nil)
+	mc.On("RemoveSocksProxy", mock.Anything).Return(nil)
+
+	return NewSocksProxy(c, mc, hclog.NewNullLogger()), mc
+}
+
+func TestSocksProxyCreateExposesProxyInConnector(t *testing.T) {
+	s, mc := setupSocksProxy(t)
+
+	err := s.Create()
+	assert.NoError(t, err)
+
+	mc.AssertCalled(t, "ExposeSocksProxy", "socks_proxy.cluster", ":1080", "")
+	assert.Equal(t, "socks_proxy.cluster", s.config.ProxyId)
+}
+
+func TestSocksProxyCreateWithClusterTunnelsToConnector(t *testing.T) {
+	s, mc := setupSocksProxy(t)
+	s.config.Cluster = "k8s_cluster.k3s"
+
+	err := s.Create()
+	assert.NoError(t, err)
+
+	cc, _, _ := utils.GetClusterConfig("k8s_cluster.k3s")
+	mc.AssertCalled(t, "ExposeSocksProxy", "socks_proxy.cluster", ":1080", cc.ConnectorAddress(utils.LocalContext))
+}
+
+func TestSocksProxyCreateReturnsErrorWhenConnectorFails(t *testing.T) {
+	s, mc := setupSocksProxy(t)
+	removeOn(&mc.Mock, "ExposeSocksProxy")
+	mc.On("ExposeSocksProxy", mock.Anything, mock.Anything, mock.Anything).Return("", fmt.Errorf("boom"))
+
+	err := s.Create()
+	assert.Error(t, err)
+}
+
+func TestSocksProxyDestroyRemovesProxy(t *testing.T) {
+	s, mc := setupSocksProxy(t)
+	s.config.ProxyId = "socks_proxy.cluster"
+
+	err := s.Destroy()
+	assert.NoError(t, err)
+
+	mc.AssertCalled(t, "RemoveSocksProxy", "socks_proxy.cluster")
+}
+
+func TestSocksProxyDestroyWithNoIDDoesNothing(t *testing.T) {
+	s, mc := setupSocksProxy(t)
+
+	err := s.Destroy()
+	assert.NoError(t, err)
+
+	mc.AssertNotCalled(t, "RemoveSocksProxy", mock.Anything)
+}
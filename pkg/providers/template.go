@@ -3,25 +3,61 @@ package providers
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"text/template"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/hcl2/hcl"
+	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/zclconf/go-cty/cty"
 )
 
 // Template provider allows parsing and output of file based templates
 type Template struct {
 	config *config.Template
+	client clients.ContainerTasks
 	log    hclog.Logger
 }
 
 // NewTemplate creates a new Local Exec provider
-func NewTemplate(c *config.Template, l hclog.Logger) *Template {
-	return &Template{c, l}
+func NewTemplate(c *config.Template, cc clients.ContainerTasks, l hclog.Logger) *Template {
+	return &Template{c, cc, l}
+}
+
+// templateData is the data which can be referenced by templates
+type templateData struct {
+	Vars      map[string]interface{}
+	Variables map[string]interface{}
+	Outputs   map[string]string
+}
+
+// newTemplateData returns the data for a template with the given vars, the
+// blueprint variables and the values of the outputs in the config
+func newTemplateData(c *config.Config, vars map[string]interface{}) templateData {
+	data := templateData{
+		Vars:      vars,
+		Variables: map[string]interface{}{},
+		Outputs:   map[string]string{},
+	}
+
+	if ctx := config.GetEvalContext(); ctx != nil {
+		if v, ok := ctx.Variables["var"]; ok {
+			data.Variables = parseVars(v.AsValueMap())
+		}
+	}
+
+	if c != nil {
+		for _, r := range c.FindResourcesByType(string(config.TypeOutput)) {
+			data.Outputs[r.Info().Name] = r.(*config.Output).Value
+		}
+	}
+
+	return data
 }
 
 // parseVarse converts a map[string]cty.Value into map[string]interface
@@ -76,22 +112,51 @@ func (c *Template) Create() error {
 		return fmt.Errorf("Template source empty")
 	}
 
-	if _, ok := c.config.Vars.(*hcl.Attribute); !ok {
-		// no variables just write the file
-		f, err := os.Create(c.config.Destination)
+	out := c.config.Source
+
+	// templates are only processed when vars are set
+	if _, ok := c.config.Vars.(*hcl.Attribute); ok {
+		var err error
+		out, err = c.render()
 		if err != nil {
-			return fmt.Errorf("Unable to create destination file for template: %s", err)
+			return err
 		}
-		defer f.Close()
+	}
 
-		c.log.Debug("Template output", "ref", c.config.Name, "destination", c.config.Source)
-		_, err = f.WriteString(c.config.Source)
+	c.log.Debug("Template output", "ref", c.config.Name, "output", out)
 
-		return err
+	if c.config.Volume != "" {
+		return c.writeToVolume(out)
+	}
+
+	if fi, _ := os.Stat(c.config.Destination); fi != nil {
+		err := os.RemoveAll(c.config.Destination)
+		if err != nil {
+			return fmt.Errorf("Unable to delete destination file: %s", err)
+		}
+	}
+
+	err := os.MkdirAll(filepath.Dir(c.config.Destination), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("Unable to create destination directory for template: %s", err)
+	}
+
+	f, err := os.Create(c.config.Destination)
+	if err != nil {
+		return fmt.Errorf("Unable to create destination file for template: %s", err)
 	}
+	defer f.Close()
+
+	_, err = f.WriteString(out)
+
+	return err
+}
 
-	// convert the HCL types into Go map[string]interface that can be used by go template
-	val, _ := c.config.Vars.(*hcl.Attribute).Expr.Value(config.GetEvalContext())
+// render processes the source template with the vars
+func (c *Template) render() (string, error) {
+	// convert the HCL types into Go map[string]interface that can be used by go template,
+	// vars are evaluated now so that they can reference the values set by other resources
+	val, _ := c.config.Vars.(*hcl.Attribute).Expr.Value(config.GetResourceEvalContext(c.config.Config))
 	m := val.AsValueMap()
 	vars := parseVars(m)
 
@@ -99,41 +164,55 @@ func (c *Template) Create() error {
 
 	t, err := tmpl.Parse(c.config.Source)
 	if err != nil {
-		return fmt.Errorf("Unable to parse template: %s", err)
+		return "", fmt.Errorf("Unable to parse template: %s", err)
 	}
 
 	bs := bytes.NewBufferString("")
-	err = t.Execute(bs, struct{ Vars map[string]interface{} }{Vars: vars})
+	err = t.Execute(bs, newTemplateData(c.config.Config, vars))
 	if err != nil {
-		return fmt.Errorf("Error processing template: %s", err)
+		return "", fmt.Errorf("Error processing template: %s", err)
 	}
 
-	if fi, _ := os.Stat(c.config.Destination); fi != nil {
-		err = os.RemoveAll(c.config.Destination)
-		if err != nil {
-			return fmt.Errorf("Unable to delete destination file: %s", err)
-		}
-	}
+	return bs.String(), nil
+}
 
-	err = os.MkdirAll(filepath.Dir(c.config.Destination), os.ModePerm)
+// writeToVolume copies the rendered template to the destination in the Docker volume
+func (c *Template) writeToVolume(out string) error {
+	tmp, err := utils.ShipyardTemp()
 	if err != nil {
-		return fmt.Errorf("Unable to create destination directory for template: %s", err)
+		return err
 	}
 
-	f, err := os.Create(c.config.Destination)
+	dir := filepath.Join(tmp, "templates", c.config.Name)
+
+	os.RemoveAll(dir)
+	err = os.MkdirAll(dir, os.ModePerm)
 	if err != nil {
-		return fmt.Errorf("Unable to create destination file for template: %s", err)
+		return fmt.Errorf("Unable to create directory for template: %s", err)
 	}
-	defer f.Close()
 
-	f.WriteString(bs.String())
+	// the file is copied with its name so it must match the destination
+	f := filepath.Join(dir, path.Base(c.config.Destination))
 
-	c.log.Debug("Template output", "ref", c.config.Name, "destination", bs.String())
+	err = ioutil.WriteFile(f, []byte(out), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("Unable to write template: %s", err)
+	}
+
+	_, err = c.client.CopyFilesToVolume(c.config.Volume, []string{f}, path.Dir(c.config.Destination), true)
+	if err != nil {
+		return fmt.Errorf("Unable to copy template to volume %s: %s", c.config.Volume, err)
+	}
 
 	return nil
 }
 
 func (c *Template) Destroy() error {
+	if c.config.Volume != "" {
+		c.log.Debug("Template written to volume is removed with the volume", "ref", c.config.Name, "volume", c.config.Volume)
+		return nil
+	}
+
 	if _, err := os.Stat(c.config.Destination); !os.IsNotExist(err) {
 		err := os.RemoveAll(c.config.Destination)
 		if err != nil {
@@ -1,19 +1,23 @@
 package providers
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func setupTemplate(t *testing.T) (*config.Template, *Template) {
 	tmpl := createTemplate(t)
 
-	return tmpl, NewTemplate(tmpl, hclog.NewNullLogger())
+	return tmpl, NewTemplate(tmpl, nil, hclog.NewNullLogger())
 }
 
 func TestTemplateReturnsErrorWhenEmpty(t *testing.T) {
@@ -97,6 +101,78 @@ func TestTemplateDestroyRemovesDestination(t *testing.T) {
 	assert.NoFileExists(t, tmpl.Destination)
 }
 
+func TestTemplateVarsReferenceExecLocalOutput(t *testing.T) {
+	conf, _ := config.CreateConfigFromStrings(t, templateExecLocalOutput)
+
+	ex, err := conf.FindResource("exec_local.token")
+	assert.NoError(t, err)
+
+	// the output is set by the exec_local provider after the config has been parsed
+	ex.(*config.ExecLocal).Output = "abc123"
+
+	cr, err := conf.FindResource("template.config")
+	assert.NoError(t, err)
+
+	tmpl := cr.(*config.Template)
+	tmpl.Destination = filepath.Join(t.TempDir(), "out.txt")
+
+	err = NewTemplate(tmpl, nil, hclog.NewNullLogger()).Create()
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(tmpl.Destination)
+	assert.NoError(t, err)
+	assert.Equal(t, "token = abc123", string(d))
+}
+
+func TestTemplateReferencesVariablesAndOutputs(t *testing.T) {
+	conf, _ := config.CreateConfigFromStrings(t, templateVariablesOutputs)
+
+	cr, err := conf.FindResource("template.config")
+	assert.NoError(t, err)
+
+	tmpl := cr.(*config.Template)
+	tmpl.Destination = filepath.Join(t.TempDir(), "out.txt")
+
+	err = NewTemplate(tmpl, nil, hclog.NewNullLogger()).Create()
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(tmpl.Destination)
+	assert.NoError(t, err)
+	assert.Equal(t, "version = 1.2.0, addr = localhost:5432", string(d))
+}
+
+func TestTemplateWithVolumeCopiesToVolume(t *testing.T) {
+	tmpl, _ := setupTemplate(t)
+	tmpl.Volume = "config"
+	tmpl.Destination = "/app/config.hcl"
+
+	md := &mocks.MockContainerTasks{}
+	md.On("CopyFilesToVolume", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]string{"/cache/app/config.hcl"}, nil)
+
+	err := NewTemplate(tmpl, md, hclog.NewNullLogger()).Create()
+	assert.NoError(t, err)
+
+	files := md.Calls[0].Arguments[1].([]string)
+	assert.Equal(t, "config.hcl", filepath.Base(files[0]))
+	md.AssertCalled(t, "CopyFilesToVolume", "config", mock.Anything, "/app", true)
+
+	d, err := ioutil.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), `data_dir = "something"`)
+}
+
+func TestTemplateWithVolumeReturnsCopyError(t *testing.T) {
+	tmpl, _ := setupTemplate(t)
+	tmpl.Volume = "config"
+	tmpl.Destination = "/app/config.hcl"
+
+	md := &mocks.MockContainerTasks{}
+	md.On("CopyFilesToVolume", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	err := NewTemplate(tmpl, md, hclog.NewNullLogger()).Create()
+	assert.Error(t, err)
+}
+
 func createTemplate(t *testing.T) *config.Template {
 	tmpl := `
 variable "bool_var" {
@@ -177,3 +253,34 @@ template "fetch_consul_resources" {
 
 	return cr.(*config.Template)
 }
+
+var templateExecLocalOutput = `
+exec_local "token" {
+  cmd = "./token.sh"
+}
+
+template "config" {
+  source = "token = #{{ .Vars.token }}"
+  destination = "./out.txt"
+
+  vars = {
+    token = exec_local.token.output
+  }
+}
+`
+
+var templateVariablesOutputs = `
+variable "version" {
+  default = "1.2.0"
+}
+
+output "db_address" {
+  value = "localhost:5432"
+}
+
+template "config" {
+  source = "version = #{{ .Variables.version }}, addr = #{{ .Outputs.db_address }}"
+  destination = "./out.txt"
+  vars = {}
+}
+`
@@ -0,0 +1,240 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"golang.org/x/xerrors"
+)
+
+// locations of the module and the state in the Terraform container
+const (
+	terraformModulePath = "/terraform/module"
+	terraformStatePath  = "/terraform/state"
+)
+
+// files in the state folder of the resource
+const (
+	terraformStateFile     = "terraform.tfstate"
+	terraformVariablesFile = "terraform.tfvars.json"
+)
+
+// Terraform is a provider which applies a Terraform module in a container
+type Terraform struct {
+	config *config.Terraform
+	client clients.ContainerTasks
+	log    hclog.Logger
+}
+
+// NewTerraform creates a new Terraform provider
+func NewTerraform(c *config.Terraform, cc clients.ContainerTasks, l hclog.Logger) *Terraform {
+	return &Terraform{c, cc, l}
+}
+
+// Create applies the module and records the outputs
+func (t *Terraform) Create() error {
+	t.log.Info("Applying Terraform", "ref", t.config.Name, "source", t.config.Source)
+
+	err := t.writeVariables()
+	if err != nil {
+		return xerrors.Errorf("Unable to write variables for terraform.%s: %w", t.config.Name, err)
+	}
+
+	id, err := t.createContainer()
+	if err != nil {
+		return xerrors.Errorf("Unable to create container for terraform.%s: %w", t.config.Name, err)
+	}
+	defer t.client.RemoveContainer(id, true)
+
+	err = t.run(id, nil, "init", "-input=false")
+	if err != nil {
+		return xerrors.Errorf("Unable to initialize Terraform: %w", err)
+	}
+
+	err = t.run(id, nil, "apply", "-input=false", "-auto-approve", t.stateFlag(), t.varFileFlag())
+	if err != nil {
+		return xerrors.Errorf("Unable to apply Terraform: %w", err)
+	}
+
+	out := bytes.NewBufferString("")
+	err = t.run(id, out, "output", "-json", t.stateFlag())
+	if err != nil {
+		return xerrors.Errorf("Unable to read Terraform outputs: %w", err)
+	}
+
+	return t.setOutputs(out.Bytes())
+}
+
+// Destroy destroys the resources created by the module and removes the state
+func (t *Terraform) Destroy() error {
+	t.log.Info("Destroy Terraform", "ref", t.config.Name)
+
+	state := t.config.StateFolder()
+
+	// nothing to destroy when the module has never been applied
+	if _, err := os.Stat(filepath.Join(state, terraformStateFile)); err != nil {
+		return os.RemoveAll(state)
+	}
+
+	if !utils.IsLocalFolder(t.config.Source) {
+		return xerrors.Errorf("Unable to destroy terraform.%s, the module %s does not exist", t.config.Name, t.config.Source)
+	}
+
+	id, err := t.createContainer()
+	if err != nil {
+		return xerrors.Errorf("Unable to create container for terraform.%s: %w", t.config.Name, err)
+	}
+	defer t.client.RemoveContainer(id, true)
+
+	err = t.run(id, nil, "init", "-input=false")
+	if err != nil {
+		return xerrors.Errorf("Unable to initialize Terraform: %w", err)
+	}
+
+	err = t.run(id, nil, "destroy", "-input=false", "-auto-approve", t.stateFlag(), t.varFileFlag())
+	if err != nil {
+		return xerrors.Errorf("Unable to destroy Terraform: %w", err)
+	}
+
+	return os.RemoveAll(state)
+}
+
+// Lookup the ID of the Terraform container, the container only
+// exists while the module is being applied or destroyed
+func (t *Terraform) Lookup() ([]string, error) {
+	return t.client.FindContainerIDs(t.config.Name, t.config.Type)
+}
+
+// writeVariables writes the variables for the module to the state folder, the variables
+// from the last apply are kept so they can be used when the module is destroyed
+func (t *Terraform) writeVariables() error {
+	vars := []byte("{}")
+
+	if a, ok := t.config.Variables.(*hcl.Attribute); ok {
+		val, diag := a.Expr.Value(config.GetResourceEvalContext(t.config.Config))
+		if diag.HasErrors() {
+			return errors.New(diag.Error())
+		}
+
+		if !val.Type().IsObjectType() && !val.Type().IsMapType() {
+			return fmt.Errorf("variables must be a map")
+		}
+
+		d, err := ctyjson.Marshal(val, val.Type())
+		if err != nil {
+			return err
+		}
+
+		vars = d
+	}
+
+	return ioutil.WriteFile(filepath.Join(t.config.StateFolder(), terraformVariablesFile), vars, 0600)
+}
+
+// setOutputs records the outputs from the JSON returned by terraform output,
+// outputs which are not strings are JSON encoded
+func (t *Terraform) setOutputs(d []byte) error {
+	outputs := map[string]struct {
+		Sensitive bool            `json:"sensitive"`
+		Value     json.RawMessage `json:"value"`
+	}{}
+
+	err := json.Unmarshal(d, &outputs)
+	if err != nil {
+		return xerrors.Errorf("Unable to parse Terraform outputs: %w", err)
+	}
+
+	t.config.Outputs = map[string]string{}
+	t.config.SensitiveOutputs = []string{}
+
+	for k, o := range outputs {
+		v := string(o.Value)
+
+		var s string
+		if json.Unmarshal(o.Value, &s) == nil {
+			v = s
+		}
+
+		t.config.Outputs[k] = v
+
+		if o.Sensitive {
+			t.config.SensitiveOutputs = append(t.config.SensitiveOutputs, k)
+			utils.AddSensitiveValue(v)
+		}
+	}
+
+	sort.Strings(t.config.SensitiveOutputs)
+
+	return nil
+}
+
+// createContainer creates a container for running Terraform with the module
+// and the state folder mounted
+func (t *Terraform) createContainer() (string, error) {
+	cc := config.NewContainer(t.config.Name)
+	t.config.ResourceInfo.AddChild(cc)
+
+	img := t.config.Image()
+
+	cc.Networks = t.config.Networks
+	cc.Image = &img
+	cc.Entrypoint = []string{"tail", "-f", "/dev/null"} // ensure container does not immediately exit
+	cc.Command = []string{}
+	cc.Volumes = []config.Volume{
+		{Source: t.config.Source, Destination: terraformModulePath},
+		{Source: t.config.StateFolder(), Destination: terraformStatePath},
+	}
+
+	err := t.client.PullImage(img, false)
+	if err != nil {
+		t.log.Error("Error pulling container image", "ref", t.config.Name, "image", img.Name)
+
+		return "", err
+	}
+
+	return t.client.CreateContainer(cc)
+}
+
+// run executes terraform in the container, when out is nil the output is written to the log
+func (t *Terraform) run(id string, out io.Writer, args ...string) error {
+	envs := []string{
+		"TF_IN_AUTOMATION=true",
+		fmt.Sprintf("TF_DATA_DIR=%s", path.Join(terraformStatePath, ".terraform")),
+	}
+
+	for k, v := range t.config.EnvVar {
+		envs = append(envs, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	envs = appendPassthroughEnv(envs, t.config.Config, t.config.EnvPassthrough, t.log)
+
+	if out == nil {
+		out = t.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug})
+	}
+
+	command := append([]string{"terraform"}, args...)
+
+	return t.client.ExecuteCommand(id, command, envs, terraformModulePath, "", "", out)
+}
+
+func (t *Terraform) stateFlag() string {
+	return fmt.Sprintf("-state=%s", path.Join(terraformStatePath, terraformStateFile))
+}
+
+func (t *Terraform) varFileFlag() string {
+	return fmt.Sprintf("-var-file=%s", path.Join(terraformStatePath, terraformVariablesFile))
+}
@@ -0,0 +1,135 @@
+package providers
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl2/hcl"
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const terraformOutputJSON = `{
+  "address": {"sensitive": false, "type": "string", "value": "10.0.0.1"},
+  "ports": {"sensitive": false, "type": ["list", "number"], "value": [80, 443]},
+  "password": {"sensitive": true, "type": "string", "value": "secret"}
+}`
+
+func setupTerraform(t *testing.T) (*config.Terraform, *mocks.MockContainerTasks) {
+	currentHome := os.Getenv(utils.HomeEnvName())
+	os.Setenv(utils.HomeEnvName(), t.TempDir())
+
+	t.Cleanup(func() {
+		os.Setenv(utils.HomeEnvName(), currentHome)
+	})
+
+	expr, diag := hclsyntax.ParseExpression([]byte(`{ region = "eu-west-1", count = 2 }`), "test.hcl", hcl.Pos{Line: 1, Column: 1})
+	assert.False(t, diag.HasErrors())
+
+	tf := config.NewTerraform("cloud")
+	tf.Source = t.TempDir()
+	tf.Variables = &hcl.Attribute{Name: "variables", Expr: expr}
+
+	c := config.New()
+	c.AddResource(tf)
+
+	md := &mocks.MockContainerTasks{}
+	md.On("PullImage", mock.Anything, false).Return(nil)
+	md.On("CreateContainer", mock.Anything).Return("abc", nil)
+	md.On("RemoveContainer", "abc", true).Return(nil)
+	md.On("ExecuteCommand", "abc", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		if args.Get(1).([]string)[1] == "output" {
+			fmt.Fprint(args.Get(6).(io.Writer), terraformOutputJSON)
+		}
+	}).Return(nil)
+
+	return tf, md
+}
+
+func TestTerraformCreateAppliesModuleInContainer(t *testing.T) {
+	tf, md := setupTerraform(t)
+
+	p := NewTerraform(tf, md, hclog.NewNullLogger())
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "PullImage", config.Image{Name: "hashicorp/terraform:" + config.TerraformDefaultVersion}, false)
+
+	cc := md.Calls[1].Arguments.Get(0).(*config.Container)
+	assert.Equal(t, "cloud", cc.Name)
+	assert.Equal(t, config.TypeTerraform, cc.Type)
+	assert.Equal(t, tf.Source, cc.Volumes[0].Source)
+	assert.Equal(t, tf.StateFolder(), cc.Volumes[1].Source)
+
+	md.AssertCalled(t, "ExecuteCommand", "abc", []string{"terraform", "init", "-input=false"}, mock.Anything, "/terraform/module", "", "", mock.Anything)
+	md.AssertCalled(t, "ExecuteCommand", "abc", []string{"terraform", "apply", "-input=false", "-auto-approve", "-state=/terraform/state/terraform.tfstate", "-var-file=/terraform/state/terraform.tfvars.json"}, mock.Anything, "/terraform/module", "", "", mock.Anything)
+	md.AssertCalled(t, "RemoveContainer", "abc", true)
+}
+
+func TestTerraformCreateWritesVariables(t *testing.T) {
+	tf, md := setupTerraform(t)
+
+	p := NewTerraform(tf, md, hclog.NewNullLogger())
+	err := p.Create()
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(filepath.Join(tf.StateFolder(), "terraform.tfvars.json"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"region": "eu-west-1", "count": 2}`, string(d))
+}
+
+func TestTerraformCreateSetsOutputs(t *testing.T) {
+	tf, md := setupTerraform(t)
+
+	p := NewTerraform(tf, md, hclog.NewNullLogger())
+	err := p.Create()
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"address": "10.0.0.1", "ports": "[80, 443]", "password": "secret"}, tf.Outputs)
+	assert.Equal(t, []string{"password"}, tf.SensitiveOutputs)
+}
+
+func TestTerraformCreateReturnsErrorWhenApplyFails(t *testing.T) {
+	tf, md := setupTerraform(t)
+	removeOn(&md.Mock, "ExecuteCommand")
+	md.On("ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	p := NewTerraform(tf, md, hclog.NewNullLogger())
+	err := p.Create()
+	assert.Error(t, err)
+
+	md.AssertCalled(t, "RemoveContainer", "abc", true)
+}
+
+func TestTerraformDestroyDoesNothingWhenNotApplied(t *testing.T) {
+	tf, md := setupTerraform(t)
+
+	p := NewTerraform(tf, md, hclog.NewNullLogger())
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "CreateContainer", mock.Anything)
+}
+
+func TestTerraformDestroyDestroysModuleAndRemovesState(t *testing.T) {
+	tf, md := setupTerraform(t)
+
+	err := ioutil.WriteFile(filepath.Join(tf.StateFolder(), "terraform.tfstate"), []byte("{}"), os.ModePerm)
+	assert.NoError(t, err)
+
+	p := NewTerraform(tf, md, hclog.NewNullLogger())
+	err = p.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ExecuteCommand", "abc", []string{"terraform", "destroy", "-input=false", "-auto-approve", "-state=/terraform/state/terraform.tfstate", "-var-file=/terraform/state/terraform.tfvars.json"}, mock.Anything, "/terraform/module", "", "", mock.Anything)
+	assert.NoFileExists(t, filepath.Join(utils.ShipyardHome(), "data", "terraform", "cloud", "terraform.tfstate"))
+}
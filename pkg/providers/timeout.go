@@ -0,0 +1,51 @@
+package providers
+
+import (
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// resourceDefaults are the timeout and retry policy from the user config
+// which are used when a resource does not set its own
+var resourceDefaults *config.ResourceDefaults
+
+// SetResourceDefaults sets the timeout and retry policy used by the providers
+// for resources which do not set them
+func SetResourceDefaults(d *config.ResourceDefaults) {
+	resourceDefaults = d
+}
+
+// resourceTimeout returns the timeout set for the resource, when not set the
+// timeout from the user config or the given default is returned
+func resourceTimeout(timeout string, def time.Duration) time.Duration {
+	if d := config.ParseTimeout(timeout); d > 0 {
+		return d
+	}
+
+	if d := resourceDefaults.TimeoutDuration(); d > 0 {
+		return d
+	}
+
+	return def
+}
+
+// resourceRetry returns the retry policy for the resource merged with the
+// policy from the user config, nil is returned when neither are set
+func resourceRetry(r *config.Retry) *config.Retry {
+	d := resourceDefaults.RetryPolicy()
+	if r == nil && d == nil {
+		return nil
+	}
+
+	return r.Merge(d)
+}
+
+// withPullPolicy returns a copy of the image with the timeout and retry
+// policy of the resource which is pulling the image
+func withPullPolicy(i config.Image, timeout string, r *config.Retry) config.Image {
+	i.PullTimeout = resourceTimeout(timeout, 0)
+	i.PullRetry = resourceRetry(r)
+
+	return i
+}
@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupResourceDefaults(t *testing.T, d *config.ResourceDefaults) {
+	SetResourceDefaults(d)
+	t.Cleanup(func() { SetResourceDefaults(nil) })
+}
+
+func TestResourceTimeoutUsesResourceThenUserConfigThenDefault(t *testing.T) {
+	assert.Equal(t, startTimeout, resourceTimeout("", startTimeout))
+	assert.Equal(t, 10*time.Minute, resourceTimeout("10m", startTimeout))
+
+	setupResourceDefaults(t, &config.ResourceDefaults{Timeout: "20m"})
+
+	assert.Equal(t, 20*time.Minute, resourceTimeout("", startTimeout))
+	assert.Equal(t, 10*time.Minute, resourceTimeout("10m", startTimeout))
+}
+
+func TestResourceRetryMergesUserConfig(t *testing.T) {
+	assert.Nil(t, resourceRetry(nil))
+
+	setupResourceDefaults(t, &config.ResourceDefaults{Retry: &config.Retry{Attempts: 5, Delay: "10s"}})
+
+	assert.Equal(t, &config.Retry{Attempts: 5, Delay: "10s"}, resourceRetry(nil))
+	assert.Equal(t, &config.Retry{Attempts: 8, Delay: "10s"}, resourceRetry(&config.Retry{Attempts: 8}))
+}
+
+func TestWithPullPolicyDoesNotSetPolicyWhenNotConfigured(t *testing.T) {
+	i := config.Image{Name: "consul:1.10.0"}
+
+	assert.Equal(t, i, withPullPolicy(i, "", nil))
+}
@@ -0,0 +1,148 @@
+package providers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+const cloudflaredImage = "cloudflare/cloudflared:2022.7.1"
+const ngrokImage = "ngrok/ngrok:3"
+
+// cloudflared logs the URL of a quick tunnel, ngrok logs the URL as a json field
+var cloudflaredURL = regexp.MustCompile(`https://[a-z0-9-]+\.trycloudflare\.com`)
+var ngrokURL = regexp.MustCompile(`"url":"(https?://[^"]+)"`)
+
+// tunnelURLTimeout is the maximum time to wait for the provider to assign a URL
+var tunnelURLTimeout = 60 * time.Second
+
+// Tunnel is a provider which publishes a service to the internet using a tunnel provider
+type Tunnel struct {
+	config *config.Tunnel
+	client clients.ContainerTasks
+	log    hclog.Logger
+}
+
+// NewTunnel creates a new Tunnel provider
+func NewTunnel(c *config.Tunnel, cc clients.ContainerTasks, l hclog.Logger) *Tunnel {
+	return &Tunnel{c, cc, l}
+}
+
+// Create starts the tunnel container and waits for the public URL
+func (t *Tunnel) Create() error {
+	t.log.Info("Creating Tunnel", "ref", t.config.Name, "provider", t.config.Provider, "address", t.config.Address)
+
+	cc := config.NewContainer(t.config.Name)
+	t.config.ResourceInfo.AddChild(cc)
+
+	// set the type so that the container can be found using the tunnel name
+	cc.Type = t.config.Type
+	cc.Networks = t.config.Networks
+
+	switch t.config.Provider {
+	case config.TunnelProviderNgrok:
+		cc.Image = &config.Image{Name: ngrokImage}
+		cc.Command = []string{"http", t.config.Address, "--log", "stdout", "--log-format", "json"}
+		cc.EnvVar = map[string]string{"NGROK_AUTHTOKEN": t.config.AuthToken}
+	default:
+		cc.Image = &config.Image{Name: cloudflaredImage}
+		cc.Command = []string{"tunnel", "--no-autoupdate", "--url", fmt.Sprintf("http://%s", t.config.Address)}
+	}
+
+	if t.config.Image != nil {
+		cc.Image = t.config.Image
+	}
+
+	err := t.client.PullImage(*cc.Image, false)
+	if err != nil {
+		return xerrors.Errorf("Unable to pull image for tunnel: %w", err)
+	}
+
+	id, err := t.client.CreateContainer(cc)
+	if err != nil {
+		return xerrors.Errorf("Unable to create tunnel container: %w", err)
+	}
+
+	url, err := t.waitForURL(id)
+	if err != nil {
+		return err
+	}
+
+	t.log.Info("Tunnel created", "ref", t.config.Name, "url", url)
+	t.config.PublicURL = url
+
+	return nil
+}
+
+// waitForURL reads the container logs until the provider has logged the public URL
+func (t *Tunnel) waitForURL(id string) (string, error) {
+	st := time.Now()
+
+	for time.Since(st) < tunnelURLTimeout {
+		url, err := t.findURL(id)
+		if err != nil {
+			return "", err
+		}
+
+		if url != "" {
+			return url, nil
+		}
+
+		time.Sleep(1 * time.Second)
+	}
+
+	return "", xerrors.Errorf("Timeout waiting for %s to assign a URL, check the logs for the container %s.%s.shipyard.run", t.config.Provider, t.config.Name, t.config.Type)
+}
+
+func (t *Tunnel) findURL(id string) (string, error) {
+	rc, err := t.client.ContainerLogs(id, true, true)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to read logs for tunnel: %w", err)
+	}
+	defer rc.Close()
+
+	logs, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to read logs for tunnel: %w", err)
+	}
+
+	if t.config.Provider == config.TunnelProviderNgrok {
+		if m := ngrokURL.FindSubmatch(logs); m != nil {
+			return string(m[1]), nil
+		}
+
+		return "", nil
+	}
+
+	return string(cloudflaredURL.Find(logs)), nil
+}
+
+// Destroy removes the tunnel container
+func (t *Tunnel) Destroy() error {
+	t.log.Info("Destroy Tunnel", "ref", t.config.Name)
+
+	ids, err := t.Lookup()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := t.client.RemoveContainer(id, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lookup the ID of the tunnel container
+func (t *Tunnel) Lookup() ([]string, error) {
+	return t.client.FindContainerIDs(t.config.Name, t.config.Type)
+}
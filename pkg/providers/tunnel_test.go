@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupTunnelTests(t *testing.T, logs string) (*config.Tunnel, *mocks.MockContainerTasks) {
+	tc := config.NewTunnel("demo")
+	tc.Address = "web.container.shipyard.run:8080"
+
+	md := &mocks.MockContainerTasks{}
+	md.On("PullImage", mock.Anything, false).Return(nil)
+	md.On("CreateContainer", mock.Anything).Return("abc", nil)
+	md.On("ContainerLogs", "abc", true, true).Return(ioutil.NopCloser(strings.NewReader(logs)), nil)
+	md.On("FindContainerIDs", "demo", config.TypeTunnel).Return([]string{"abc"}, nil)
+	md.On("RemoveContainer", "abc", true).Return(nil)
+
+	timeout := tunnelURLTimeout
+	tunnelURLTimeout = 10 * time.Millisecond
+
+	t.Cleanup(func() {
+		tunnelURLTimeout = timeout
+	})
+
+	return tc, md
+}
+
+func TestTunnelCreatesCloudflaredContainer(t *testing.T) {
+	tc, md := setupTunnelTests(t, tunnelCloudflaredLogs)
+
+	p := NewTunnel(tc, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	cc := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, cloudflaredImage, cc.Image.Name)
+	assert.Equal(t, config.TypeTunnel, cc.Type)
+	assert.Contains(t, cc.Command, "http://web.container.shipyard.run:8080")
+
+	assert.Equal(t, "https://shiny-demo-words.trycloudflare.com", tc.PublicURL)
+}
+
+func TestTunnelCreatesNgrokContainer(t *testing.T) {
+	tc, md := setupTunnelTests(t, tunnelNgrokLogs)
+	tc.Provider = config.TunnelProviderNgrok
+	tc.AuthToken = "secret"
+
+	p := NewTunnel(tc, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	cc := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, ngrokImage, cc.Image.Name)
+	assert.Equal(t, "secret", cc.EnvVar["NGROK_AUTHTOKEN"])
+
+	assert.Equal(t, "https://1234-abcd.ngrok.io", tc.PublicURL)
+}
+
+func TestTunnelUsesCustomImage(t *testing.T) {
+	tc, md := setupTunnelTests(t, tunnelCloudflaredLogs)
+	tc.Image = &config.Image{Name: "myregistry/cloudflared:latest"}
+
+	p := NewTunnel(tc, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "PullImage", config.Image{Name: "myregistry/cloudflared:latest"}, false)
+}
+
+func TestTunnelReturnsErrorWhenNoURL(t *testing.T) {
+	tc, md := setupTunnelTests(t, "starting tunnel")
+
+	p := NewTunnel(tc, md, hclog.NewNullLogger())
+
+	err := p.Create()
+	assert.Error(t, err)
+}
+
+func TestTunnelDestroyRemovesContainer(t *testing.T) {
+	tc, md := setupTunnelTests(t, "")
+
+	p := NewTunnel(tc, md, hclog.NewNullLogger())
+
+	err := p.Destroy()
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "RemoveContainer", "abc", true)
+}
+
+var tunnelCloudflaredLogs = `
+2022-07-20T10:00:00Z INF Requesting new quick Tunnel on trycloudflare.com...
+2022-07-20T10:00:01Z INF +--------------------------------------------------------------------------------------------+
+2022-07-20T10:00:01Z INF |  Your quick Tunnel has been created! Visit it at (it may take some time to be reachable):  |
+2022-07-20T10:00:01Z INF |  https://shiny-demo-words.trycloudflare.com                                                 |
+2022-07-20T10:00:01Z INF +--------------------------------------------------------------------------------------------+
+`
+
+var tunnelNgrokLogs = `
+{"lvl":"info","msg":"starting web service","obj":"web","addr":"127.0.0.1:4040"}
+{"lvl":"info","msg":"started tunnel","obj":"tunnels","name":"command_line","addr":"http://web.container.shipyard.run:8080","url":"https://1234-abcd.ngrok.io"}
+`
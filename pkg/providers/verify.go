@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// verifyDownload checks the downloaded file matches the checksum and signature declared
+// in the blueprint, remote signatures and keys are downloaded to dir before the file is checked
+func verifyDownload(g clients.Getter, v *config.Verify, file, dir string, l hclog.Logger) error {
+	if v == nil {
+		return nil
+	}
+
+	l.Debug("Verifying download", "file", file, "checksum", v.Checksum, "signature", v.Signature)
+
+	lv := *v
+
+	if v.Signature != "" {
+		// always fetch the signature and key so that a changed signature is not ignored
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return err
+		}
+
+		lv.Signature, err = fetchVerifyFile(g, v.Signature, filepath.Join(dir, "signature"))
+		if err != nil {
+			return xerrors.Errorf("Unable to download signature: %w", err)
+		}
+
+		lv.Key, err = fetchVerifyFile(g, v.Key, filepath.Join(dir, "key"))
+		if err != nil {
+			return xerrors.Errorf("Unable to download public key: %w", err)
+		}
+	}
+
+	err := clients.VerifyFile(file, &lv)
+	if err != nil {
+		return fmt.Errorf("Unable to verify download, the file will not be used: %s", err)
+	}
+
+	return nil
+}
+
+// fetchVerifyFile returns the local path of a signature or key, remote files are downloaded to dst
+func fetchVerifyFile(g clients.Getter, src, dst string) (string, error) {
+	if !config.IsRemotePath(src) {
+		return src, nil
+	}
+
+	err := g.Get(src, dst)
+	if err != nil {
+		return "", err
+	}
+
+	return dst, nil
+}
+
+// verifyFolder returns the folder remote signatures and keys for the resource are downloaded to
+func verifyFolder(r config.Resource) (string, error) {
+	tmp, err := utils.ShipyardTemp()
+	if err != nil {
+		return "", err
+	}
+
+	i := r.Info()
+	return filepath.Join(tmp, "verify", config.ResourceID(i.Module, i.Type, i.Name)), nil
+}
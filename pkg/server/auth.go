@@ -0,0 +1,82 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// AuthProxyRequest is the request to create a new AuthProxy
+type AuthProxyRequest struct {
+	Name     string          `json:"name"`
+	BindAddr string          `json:"bind_addr"`
+	Upstream string          `json:"upstream"`
+	Auth     *config.Auth    `json:"auth"`
+	Headers  *config.Headers `json:"headers"`
+}
+
+// AuthProxyResponse is returned when an AuthProxy is created
+type AuthProxyResponse struct {
+	ID string `json:"id"`
+}
+
+// createAuthProxy starts a new AuthProxy, any existing proxy with the
+// same name is replaced
+func (s *API) createAuthProxy(c *fiber.Ctx) error {
+	req := &AuthProxyRequest{}
+	err := c.BodyParser(req)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if req.Name == "" || req.BindAddr == "" || req.Upstream == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "name, bind_addr, and upstream must be specified")
+	}
+
+	p, err := NewAuthProxy(req.Name, req.BindAddr, req.Upstream, req.Auth, req.Headers, s.log.Named("auth_proxy"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	s.proxyLock.Lock()
+	defer s.proxyLock.Unlock()
+
+	if ep, ok := s.proxies[req.Name]; ok {
+		ep.Stop()
+		delete(s.proxies, req.Name)
+	}
+
+	s.log.Info("Starting auth proxy", "name", req.Name, "bind_addr", req.BindAddr, "upstream", req.Upstream)
+
+	err = p.Start()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	s.proxies[req.Name] = p
+
+	return c.JSON(AuthProxyResponse{ID: req.Name})
+}
+
+// deleteAuthProxy stops and removes an AuthProxy
+func (s *API) deleteAuthProxy(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	s.proxyLock.Lock()
+	defer s.proxyLock.Unlock()
+
+	p, ok := s.proxies[id]
+	if !ok {
+		return fiber.NewError(fiber.StatusNotFound, "auth proxy not found")
+	}
+
+	s.log.Info("Stopping auth proxy", "name", id)
+
+	err := p.Stop()
+	delete(s.proxies, id)
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
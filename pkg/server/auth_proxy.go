@@ -0,0 +1,445 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+const oidcCallbackPath = "/_shipyard/oidc/callback"
+const sessionCookie = "shipyard_session"
+const stateCookie = "shipyard_oidc_state"
+const sessionDuration = 12 * time.Hour
+
+// AuthProxy is a reverse proxy which authenticates requests and rewrites
+// headers before forwarding them to the upstream service
+type AuthProxy struct {
+	name     string
+	bindAddr string
+	auth     *config.Auth
+	headers  *config.Headers
+	proxy    *httputil.ReverseProxy
+	server   *http.Server
+	secret   []byte // key used to sign session cookies
+	client   *http.Client
+	log      hclog.Logger
+
+	discoveryLock sync.Mutex
+	discovery     *oidcDiscovery
+}
+
+type oidcDiscovery struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// NewAuthProxy creates a new AuthProxy which listens on bindAddr and forwards
+// authenticated requests to the upstream address, auth is optional when
+// headers are specified
+func NewAuthProxy(name, bindAddr, upstream string, auth *config.Auth, headers *config.Headers, l hclog.Logger) (*AuthProxy, error) {
+	if auth == nil && headers == nil {
+		return nil, fmt.Errorf("auth or headers config must be specified")
+	}
+
+	if auth != nil {
+		err := auth.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if headers != nil {
+		err := headers.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !strings.HasPrefix(upstream, "http://") && !strings.HasPrefix(upstream, "https://") {
+		upstream = "http://" + upstream
+	}
+
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream address %s: %s", upstream, err)
+	}
+
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthProxy{
+		name:     name,
+		bindAddr: bindAddr,
+		auth:     auth,
+		headers:  headers,
+		proxy:    newHeaderProxy(u, headers),
+		secret:   secret,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		log:      l,
+	}, nil
+}
+
+// Start the proxy, Start does not block
+func (a *AuthProxy) Start() error {
+	l, err := net.Listen("tcp", a.bindAddr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %s", a.bindAddr, err)
+	}
+
+	a.server = &http.Server{Handler: a}
+
+	go func() {
+		err := a.server.Serve(l)
+		if err != nil && err != http.ErrServerClosed {
+			a.log.Error("Auth proxy stopped", "name", a.name, "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop the proxy
+func (a *AuthProxy) Stop() error {
+	if a.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return a.server.Shutdown(ctx)
+}
+
+// ServeHTTP authenticates the request and forwards it to the upstream
+func (a *AuthProxy) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if a.headers != nil && a.headers.CORS != nil {
+		// preflight requests do not contain credentials and are answered by the proxy
+		if writeCORS(rw, r, a.headers.CORS) {
+			return
+		}
+	}
+
+	if a.auth == nil {
+		a.proxy.ServeHTTP(rw, r)
+		return
+	}
+
+	if a.auth.Basic != nil {
+		a.serveBasic(rw, r)
+		return
+	}
+
+	a.serveOIDC(rw, r)
+}
+
+func (a *AuthProxy) serveBasic(rw http.ResponseWriter, r *http.Request) {
+	u, p, ok := r.BasicAuth()
+	if !ok ||
+		subtle.ConstantTimeCompare([]byte(u), []byte(a.auth.Basic.Username)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(p), []byte(a.auth.Basic.Password)) != 1 {
+
+		rw.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, a.name))
+		http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// do not leak the credentials to the upstream
+	r.Header.Del("Authorization")
+
+	a.proxy.ServeHTTP(rw, r)
+}
+
+func (a *AuthProxy) serveOIDC(rw http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == oidcCallbackPath {
+		a.oidcCallback(rw, r)
+		return
+	}
+
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		if a.validSession(c.Value) {
+			a.proxy.ServeHTTP(rw, r)
+			return
+		}
+	}
+
+	d, err := a.discover()
+	if err != nil {
+		a.log.Error("Unable to discover OIDC provider", "name", a.name, "issuer", a.auth.OIDC.Issuer, "error", err)
+		http.Error(rw, "Unable to contact identity provider", http.StatusBadGateway)
+		return
+	}
+
+	// the state protects against csrf and stores the location to return to after login
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+
+	state := hex.EncodeToString(nonce)
+	http.SetCookie(rw, &http.Cookie{
+		Name:     stateCookie,
+		Value:    a.sign(stateCookie, state+"|"+r.URL.RequestURI()),
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   600,
+	})
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", a.auth.OIDC.ClientID)
+	q.Set("redirect_uri", a.redirectURL(r))
+	q.Set("scope", "openid email")
+	q.Set("state", state)
+
+	http.Redirect(rw, r, d.AuthorizationEndpoint+"?"+q.Encode(), http.StatusFound)
+}
+
+func (a *AuthProxy) oidcCallback(rw http.ResponseWriter, r *http.Request) {
+	c, err := r.Cookie(stateCookie)
+	if err != nil {
+		http.Error(rw, "Missing login state", http.StatusBadRequest)
+		return
+	}
+
+	v, ok := a.verify(stateCookie, c.Value)
+	parts := strings.SplitN(v, "|", 2)
+	if !ok || len(parts) != 2 || parts[0] != r.URL.Query().Get("state") {
+		http.Error(rw, "Invalid login state", http.StatusBadRequest)
+		return
+	}
+
+	email, err := a.exchange(r.URL.Query().Get("code"), a.redirectURL(r))
+	if err != nil {
+		a.log.Error("Unable to complete OIDC login", "name", a.name, "error", err)
+		http.Error(rw, "Unable to complete login", http.StatusUnauthorized)
+		return
+	}
+
+	if !a.allowed(email) {
+		a.log.Debug("User is not allowed access", "name", a.name, "email", email)
+		http.Error(rw, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	expiry := time.Now().Add(sessionDuration)
+	http.SetCookie(rw, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    a.sign(sessionCookie, fmt.Sprintf("%s|%d", email, expiry.Unix())),
+		Path:     "/",
+		HttpOnly: true,
+		Expires:  expiry,
+	})
+
+	http.SetCookie(rw, &http.Cookie{Name: stateCookie, Path: "/", MaxAge: -1})
+
+	http.Redirect(rw, r, parts[1], http.StatusFound)
+}
+
+// exchange swaps the authorization code for a token and returns the
+// email address of the authenticated user
+func (a *AuthProxy) exchange(code, redirect string) (string, error) {
+	d, err := a.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirect)
+	form.Set("client_id", a.auth.OIDC.ClientID)
+	form.Set("client_secret", a.auth.OIDC.ClientSecret)
+
+	resp, err := a.client.PostForm(d.TokenEndpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	t := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+
+	err = json.NewDecoder(resp.Body).Decode(&t)
+	if err != nil {
+		return "", fmt.Errorf("unable to decode token: %s", err)
+	}
+
+	// the userinfo endpoint validates the token so the signature of the
+	// id token does not need to be checked
+	req, _ := http.NewRequest(http.MethodGet, d.UserinfoEndpoint, nil)
+	req.Header.Set("Authorization", "Bearer "+t.AccessToken)
+
+	uresp, err := a.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer uresp.Body.Close()
+
+	if uresp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("userinfo endpoint returned status %d", uresp.StatusCode)
+	}
+
+	ui := struct {
+		Email         string      `json:"email"`
+		EmailVerified interface{} `json:"email_verified"`
+	}{}
+
+	err = json.NewDecoder(uresp.Body).Decode(&ui)
+	if err != nil {
+		return "", fmt.Errorf("unable to decode user info: %s", err)
+	}
+
+	// some providers return email_verified as a string
+	if v, ok := ui.EmailVerified.(bool); ok && !v {
+		return "", fmt.Errorf("email %s has not been verified", ui.Email)
+	}
+
+	if v, ok := ui.EmailVerified.(string); ok && v != "true" {
+		return "", fmt.Errorf("email %s has not been verified", ui.Email)
+	}
+
+	return ui.Email, nil
+}
+
+func (a *AuthProxy) discover() (*oidcDiscovery, error) {
+	a.discoveryLock.Lock()
+	defer a.discoveryLock.Unlock()
+
+	// only cache successful responses so that discovery is retried on failure
+	if a.discovery != nil {
+		return a.discovery, nil
+	}
+
+	resp, err := a.client.Get(strings.TrimSuffix(a.auth.OIDC.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery endpoint returned status %d", resp.StatusCode)
+	}
+
+	d := &oidcDiscovery{}
+	err = json.NewDecoder(resp.Body).Decode(d)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode discovery document: %s", err)
+	}
+
+	a.discovery = d
+
+	return d, nil
+}
+
+func (a *AuthProxy) redirectURL(r *http.Request) string {
+	if a.auth.OIDC.RedirectURL != "" {
+		return a.auth.OIDC.RedirectURL
+	}
+
+	return fmt.Sprintf("http://%s%s", r.Host, oidcCallbackPath)
+}
+
+func (a *AuthProxy) allowed(email string) bool {
+	if len(a.auth.OIDC.AllowedEmails) == 0 {
+		return true
+	}
+
+	email = strings.ToLower(email)
+	for _, e := range a.auth.OIDC.AllowedEmails {
+		e = strings.ToLower(e)
+
+		// entries starting with @ allow all users in the domain
+		if strings.HasPrefix(e, "@") && strings.HasSuffix(email, e) {
+			return true
+		}
+
+		if e == email {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validSession returns true when the session cookie is signed and has not expired
+func (a *AuthProxy) validSession(s string) bool {
+	v, ok := a.verify(sessionCookie, s)
+	if !ok {
+		return false
+	}
+
+	// sessions are stored as email|expiry
+	i := strings.LastIndex(v, "|")
+	if i == -1 {
+		return false
+	}
+
+	exp, err := strconv.ParseInt(v[i+1:], 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return time.Now().Unix() < exp
+}
+
+// sign returns the value with an appended HMAC, the kind of value is included
+// in the HMAC so that values can not be used in place of each other
+func (a *AuthProxy) sign(kind, v string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(v)) + "." + base64.RawURLEncoding.EncodeToString(a.mac(kind, []byte(v)))
+}
+
+// verify checks the signature of a signed value and returns the value
+func (a *AuthProxy) verify(kind, s string) (string, bool) {
+	parts := strings.SplitN(s, ".", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	v, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", false
+	}
+
+	if !hmac.Equal(sig, a.mac(kind, v)) {
+		return "", false
+	}
+
+	return string(v), true
+}
+
+func (a *AuthProxy) mac(kind string, v []byte) []byte {
+	m := hmac.New(sha256.New, a.secret)
+	m.Write([]byte(kind + ":"))
+	m.Write(v)
+
+	return m.Sum(nil)
+}
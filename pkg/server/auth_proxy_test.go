@@ -0,0 +1,212 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupAuthProxy(t *testing.T, auth *config.Auth) (*AuthProxy, *[]*http.Request) {
+	requests := []*http.Request{}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		fmt.Fprint(rw, "upstream")
+	}))
+
+	t.Cleanup(upstream.Close)
+
+	p, err := NewAuthProxy("test", "127.0.0.1:0", upstream.URL, auth, nil, hclog.NewNullLogger())
+	assert.NoError(t, err)
+
+	return p, &requests
+}
+
+func setupOIDCProvider(t *testing.T, email string) *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(rw, `{"authorization_endpoint": "%[1]s/auth", "token_endpoint": "%[1]s/token", "userinfo_endpoint": "%[1]s/userinfo"}`, ts.URL)
+		case "/token":
+			assert.Equal(t, "abc", r.FormValue("code"))
+			assert.Equal(t, "secret", r.FormValue("client_secret"))
+			fmt.Fprint(rw, `{"access_token": "token"}`)
+		case "/userinfo":
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			fmt.Fprintf(rw, `{"email": "%s", "email_verified": true}`, email)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func testOIDCAuth(issuer string) *config.Auth {
+	return &config.Auth{
+		OIDC: &config.OIDCAuth{
+			Issuer:        issuer,
+			ClientID:      "client",
+			ClientSecret:  "secret",
+			AllowedEmails: []string{"@example.com"},
+		},
+	}
+}
+
+func TestAuthProxyReturnsErrorWithInvalidAuth(t *testing.T) {
+	_, err := NewAuthProxy("test", ":0", "localhost:8080", &config.Auth{}, nil, hclog.NewNullLogger())
+	assert.Error(t, err)
+}
+
+func TestAuthProxyBasicReturnsUnauthorizedWithoutCredentials(t *testing.T) {
+	p, requests := setupAuthProxy(t, &config.Auth{Basic: &config.BasicAuth{Username: "admin", Password: "secret"}})
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Basic")
+	assert.Len(t, *requests, 0)
+}
+
+func TestAuthProxyBasicReturnsUnauthorizedWithInvalidCredentials(t *testing.T) {
+	p, requests := setupAuthProxy(t, &config.Auth{Basic: &config.BasicAuth{Username: "admin", Password: "secret"}})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("admin", "wrong")
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Len(t, *requests, 0)
+}
+
+func TestAuthProxyBasicForwardsValidRequests(t *testing.T) {
+	p, requests := setupAuthProxy(t, &config.Auth{Basic: &config.BasicAuth{Username: "admin", Password: "secret"}})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("admin", "secret")
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "upstream", rr.Body.String())
+	assert.Len(t, *requests, 1)
+	assert.Empty(t, (*requests)[0].Header.Get("Authorization"))
+}
+
+func TestAuthProxyOIDCRedirectsToProvider(t *testing.T) {
+	op := setupOIDCProvider(t, "nic@example.com")
+	p, requests := setupAuthProxy(t, testOIDCAuth(op.URL))
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://docs.local/docs/index", nil))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Location"), op.URL+"/auth?"))
+	assert.Len(t, *requests, 0)
+}
+
+func TestAuthProxyOIDCLoginCreatesSession(t *testing.T) {
+	op := setupOIDCProvider(t, "nic@example.com")
+	p, requests := setupAuthProxy(t, testOIDCAuth(op.URL))
+
+	// start the login
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://docs.local/docs/index", nil))
+
+	loc, err := url.Parse(rr.Header().Get("Location"))
+	assert.NoError(t, err)
+
+	state := rr.Result().Cookies()[0]
+
+	// return from the provider
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("http://docs.local%s?code=abc&state=%s", oidcCallbackPath, loc.Query().Get("state")), nil)
+	r.AddCookie(state)
+
+	rr = httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "/docs/index", rr.Header().Get("Location"))
+
+	session := rr.Result().Cookies()[0]
+	assert.Equal(t, sessionCookie, session.Name)
+
+	// use the session
+	r = httptest.NewRequest(http.MethodGet, "http://docs.local/docs/index", nil)
+	r.AddCookie(session)
+
+	rr = httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, *requests, 1)
+}
+
+func TestAuthProxyOIDCReturnsForbiddenWhenEmailNotAllowed(t *testing.T) {
+	op := setupOIDCProvider(t, "nic@other.com")
+	p, _ := setupAuthProxy(t, testOIDCAuth(op.URL))
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://docs.local/", nil))
+
+	loc, _ := url.Parse(rr.Header().Get("Location"))
+	state := rr.Result().Cookies()[0]
+
+	r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("http://docs.local%s?code=abc&state=%s", oidcCallbackPath, loc.Query().Get("state")), nil)
+	r.AddCookie(state)
+
+	rr = httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestAuthProxyOIDCRejectsStateCookieAsSession(t *testing.T) {
+	op := setupOIDCProvider(t, "nic@example.com")
+	p, requests := setupAuthProxy(t, testOIDCAuth(op.URL))
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://docs.local/", nil))
+
+	state := rr.Result().Cookies()[0]
+
+	r := httptest.NewRequest(http.MethodGet, "http://docs.local/", nil)
+	r.AddCookie(&http.Cookie{Name: sessionCookie, Value: state.Value})
+
+	rr = httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Len(t, *requests, 0)
+}
+
+func TestAuthProxyOIDCRejectsExpiredSession(t *testing.T) {
+	op := setupOIDCProvider(t, "nic@example.com")
+	p, requests := setupAuthProxy(t, testOIDCAuth(op.URL))
+
+	v := p.sign(sessionCookie, fmt.Sprintf("nic@example.com|%d", time.Now().Add(-1*time.Minute).Unix()))
+
+	r := httptest.NewRequest(http.MethodGet, "http://docs.local/", nil)
+	r.AddCookie(&http.Cookie{Name: sessionCookie, Value: v})
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Len(t, *requests, 0)
+}
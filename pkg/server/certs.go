@@ -0,0 +1,256 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// RotateBefore is the time before the expiry of the leaf certificate
+// when the certificate is rotated
+const RotateBefore = 30 * 24 * time.Hour
+
+// Certificates holds the leaf certificate and the trusted CAs used by the connector,
+// the files are reloaded when they change so that rotated certificates and imported
+// CAs are used by new connections without restarting the connector
+type Certificates struct {
+	rootCert   string
+	trustedDir string
+	certFile   string
+	keyFile    string
+	log        hclog.Logger
+
+	lock    sync.RWMutex
+	cert    *tls.Certificate
+	leaf    *x509.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+	rotate  func() error
+
+	stop chan struct{}
+}
+
+// NewCertificates loads the leaf certificate and key, the root certificate,
+// and any PEM encoded certificates in trustedDir
+func NewCertificates(rootCert, trustedDir, certFile, keyFile string, l hclog.Logger) (*Certificates, error) {
+	c := &Certificates{
+		rootCert:   rootCert,
+		trustedDir: trustedDir,
+		certFile:   certFile,
+		keyFile:    keyFile,
+		log:        l,
+		cert:       &tls.Certificate{},
+	}
+
+	err := c.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// SetRotateFunc sets the function which is called to rotate the leaf
+// certificate when it expires within RotateBefore
+func (c *Certificates) SetRotateFunc(f func() error) {
+	c.rotate = f
+}
+
+// Certificate returns the leaf certificate, the returned certificate is
+// updated in place when the certificate is reloaded
+func (c *Certificates) Certificate() *tls.Certificate {
+	return c.cert
+}
+
+// CertPool returns the pool containing the root and trusted CAs
+func (c *Certificates) CertPool() *x509.CertPool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.pool
+}
+
+// Expiry returns the time the leaf certificate expires
+func (c *Certificates) Expiry() time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.leaf.NotAfter
+}
+
+// ServerTLSConfig returns a TLS config which requires clients to present a
+// certificate signed by the root or a trusted CA, the current certificates
+// are used for each connection
+func (c *Certificates) ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c.lock.RLock()
+			defer c.lock.RUnlock()
+
+			return &tls.Config{
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				Certificates: []tls.Certificate{*c.cert},
+				ClientCAs:    c.pool,
+			}, nil
+		},
+	}
+}
+
+// Reload the certificates from disk
+func (c *Certificates) Reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("could not load server key pair: %s", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("could not parse server certificate: %s", err)
+	}
+
+	pool, err := LoadCertPool(c.rootCert, c.trustedDir)
+	if err != nil {
+		return err
+	}
+
+	mt, err := c.filesModTime()
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// update the certificate in place as the gRPC server holds a reference
+	// which is used for connections to remote connectors
+	*c.cert = cert
+	c.leaf = leaf
+	c.pool = pool
+	c.modTime = mt
+
+	return nil
+}
+
+// Check rotates the leaf certificate when it is about to expire and reloads
+// the certificates when the files have changed
+func (c *Certificates) Check() error {
+	if c.rotate != nil && time.Until(c.Expiry()) < RotateBefore {
+		c.log.Info("Rotating connector certificate", "expiry", c.Expiry())
+
+		err := c.rotate()
+		if err != nil {
+			return fmt.Errorf("unable to rotate certificate: %s", err)
+		}
+
+		return c.Reload()
+	}
+
+	mt, err := c.filesModTime()
+	if err != nil {
+		return err
+	}
+
+	c.lock.RLock()
+	changed := !mt.Equal(c.modTime)
+	c.lock.RUnlock()
+
+	if !changed {
+		return nil
+	}
+
+	c.log.Info("Reloading connector certificate", "cert", c.certFile)
+
+	return c.Reload()
+}
+
+// filesModTime returns the latest modification time of the leaf certificate
+// and the trusted folder, adding or removing a trusted CA changes the folder
+func (c *Certificates) filesModTime() (time.Time, error) {
+	fi, err := os.Stat(c.certFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	mt := fi.ModTime()
+
+	if c.trustedDir != "" {
+		if fi, err := os.Stat(c.trustedDir); err == nil && fi.ModTime().After(mt) {
+			mt = fi.ModTime()
+		}
+	}
+
+	return mt, nil
+}
+
+// Start checking the certificates at the given interval, Start does not block
+func (c *Certificates) Start(interval time.Duration) {
+	c.stop = make(chan struct{})
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-t.C:
+				err := c.Check()
+				if err != nil {
+					c.log.Error("Unable to check connector certificates", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop checking the certificates
+func (c *Certificates) Stop() {
+	if c.stop != nil {
+		close(c.stop)
+	}
+}
+
+// LoadCertPool creates a pool from the root certificate and any PEM encoded
+// certificates in trustedDir, trustedDir is ignored when it does not exist
+func LoadCertPool(rootCert, trustedDir string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	ca, err := ioutil.ReadFile(rootCert)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ca certificate: %s", err)
+	}
+
+	if ok := pool.AppendCertsFromPEM(ca); !ok {
+		return nil, fmt.Errorf("failed to append client certs")
+	}
+
+	if trustedDir == "" {
+		return pool, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(trustedDir, "*.cert"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		d, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("could not read trusted certificate %s: %s", f, err)
+		}
+
+		if ok := pool.AppendCertsFromPEM(d); !ok {
+			return nil, fmt.Errorf("failed to append trusted certificate %s", f)
+		}
+	}
+
+	return pool, nil
+}
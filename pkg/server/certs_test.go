@@ -0,0 +1,158 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/connector/crypto"
+	assert "github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *crypto.X509
+	key  *crypto.PrivateKey
+}
+
+func setupCA(t *testing.T) *testCA {
+	k, err := crypto.GenerateKeyPair()
+	assert.NoError(t, err)
+
+	ca, err := crypto.GenerateCA(k.Private)
+	assert.NoError(t, err)
+
+	return &testCA{ca, k.Private}
+}
+
+// writeLeaf writes a leaf certificate signed by the CA which expires after the given duration
+func writeLeaf(t *testing.T, ca *testCA, expires time.Duration, certFile, keyFile string) *x509.Certificate {
+	k, err := crypto.GenerateKeyPair()
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{Organization: []string{"Shipyard"}},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(expires),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+	}
+
+	d, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert.Certificate, k.Private.Public(), ca.key.PrivateKey)
+	assert.NoError(t, err)
+
+	c, err := x509.ParseCertificate(d)
+	assert.NoError(t, err)
+
+	os.Remove(certFile)
+	os.Remove(keyFile)
+	assert.NoError(t, (&crypto.X509{Certificate: c}).WriteFile(certFile))
+	assert.NoError(t, k.Private.WriteFile(keyFile))
+
+	return c
+}
+
+func setupCertificateFiles(t *testing.T, expires time.Duration) (*testCA, string) {
+	dir := t.TempDir()
+	ca := setupCA(t)
+
+	assert.NoError(t, ca.cert.WriteFile(filepath.Join(dir, "root.cert")))
+	writeLeaf(t, ca, expires, filepath.Join(dir, "leaf.cert"), filepath.Join(dir, "leaf.key"))
+
+	return ca, dir
+}
+
+func newTestCertificates(t *testing.T, dir string) *Certificates {
+	c, err := NewCertificates(
+		filepath.Join(dir, "root.cert"),
+		filepath.Join(dir, "trusted"),
+		filepath.Join(dir, "leaf.cert"),
+		filepath.Join(dir, "leaf.key"),
+		hclog.NewNullLogger(),
+	)
+	assert.NoError(t, err)
+
+	return c
+}
+
+func TestCertificatesReturnsErrorWhenFilesDoNotExist(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewCertificates(filepath.Join(dir, "root.cert"), "", filepath.Join(dir, "leaf.cert"), filepath.Join(dir, "leaf.key"), hclog.NewNullLogger())
+	assert.Error(t, err)
+}
+
+func TestCertificatesTrustsImportedCAs(t *testing.T) {
+	_, dir := setupCertificateFiles(t, 24*time.Hour)
+
+	peer := setupCA(t)
+	os.MkdirAll(filepath.Join(dir, "trusted"), os.ModePerm)
+	assert.NoError(t, peer.cert.WriteFile(filepath.Join(dir, "trusted", "peer.cert")))
+
+	c := newTestCertificates(t, dir)
+
+	pl := writeLeaf(t, peer, time.Hour, filepath.Join(dir, "peer_leaf.cert"), filepath.Join(dir, "peer_leaf.key"))
+	_, err := pl.Verify(x509.VerifyOptions{Roots: c.CertPool(), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	assert.NoError(t, err)
+}
+
+func TestCertificatesCheckReloadsChangedCertificate(t *testing.T) {
+	ca, dir := setupCertificateFiles(t, 24*time.Hour)
+	c := newTestCertificates(t, dir)
+	cert := c.Certificate()
+	old := cert.Certificate[0]
+
+	nc := writeLeaf(t, ca, 48*time.Hour, filepath.Join(dir, "leaf.cert"), filepath.Join(dir, "leaf.key"))
+
+	// ensure the modification time changes on file systems with a low resolution
+	mt := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "leaf.cert"), mt, mt)
+
+	err := c.Check()
+	assert.NoError(t, err)
+
+	// the certificate is updated in place
+	assert.NotEqual(t, old, cert.Certificate[0])
+	assert.Equal(t, nc.Raw, cert.Certificate[0])
+}
+
+func TestCertificatesCheckRotatesExpiringCertificate(t *testing.T) {
+	ca, dir := setupCertificateFiles(t, 24*time.Hour)
+	c := newTestCertificates(t, dir)
+
+	rotated := false
+	c.SetRotateFunc(func() error {
+		rotated = true
+		writeLeaf(t, ca, 2*RotateBefore, filepath.Join(dir, "leaf.cert"), filepath.Join(dir, "leaf.key"))
+		return nil
+	})
+
+	err := c.Check()
+	assert.NoError(t, err)
+
+	assert.True(t, rotated)
+	assert.True(t, time.Until(c.Expiry()) > RotateBefore)
+}
+
+func TestCertificatesCheckDoesNotRotateValidCertificate(t *testing.T) {
+	_, dir := setupCertificateFiles(t, 2*RotateBefore)
+	c := newTestCertificates(t, dir)
+
+	rotated := false
+	c.SetRotateFunc(func() error {
+		rotated = true
+		return nil
+	})
+
+	err := c.Check()
+	assert.NoError(t, err)
+
+	assert.False(t, rotated)
+}
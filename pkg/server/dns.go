@@ -0,0 +1,199 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSDomain is the domain of the FQDNs of the resources created by Shipyard
+const DNSDomain = "shipyard.run"
+
+// dnsTTL is the time in seconds clients can cache the answers, containers are
+// often recreated with a new IP so the answers are only cached briefly
+const dnsTTL = 5
+
+// DNSLookup returns the IP addresses for the FQDN of a resource e.g.
+// consul.container.shipyard.run, no addresses are returned when the
+// resource does not exist
+type DNSLookup func(fqdn string) ([]net.IP, error)
+
+// DNS is a DNS server which resolves the *.shipyard.run FQDNs of resources
+// to the IP addresses of their containers so that services can be reached
+// from the host using the same names as inside the Docker network.
+// Queries for other domains are refused.
+type DNS struct {
+	bindAddr string
+	lookup   DNSLookup
+	log      hclog.Logger
+
+	conn net.PacketConn
+}
+
+// NewDNS creates a new DNS server which listens on bindAddr
+func NewDNS(bindAddr string, lookup DNSLookup, l hclog.Logger) *DNS {
+	return &DNS{bindAddr: bindAddr, lookup: lookup, log: l}
+}
+
+// Start the server, Start does not block
+func (d *DNS) Start() error {
+	c, err := net.ListenPacket("udp", d.bindAddr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %s", d.bindAddr, err)
+	}
+
+	d.conn = c
+	go d.serve()
+
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (d *DNS) Addr() string {
+	if d.conn == nil {
+		return d.bindAddr
+	}
+
+	return d.conn.LocalAddr().String()
+}
+
+// Stop the server
+func (d *DNS) Stop() error {
+	if d.conn == nil {
+		return nil
+	}
+
+	return d.conn.Close()
+}
+
+func (d *DNS) serve() {
+	buf := make([]byte, 512)
+
+	for {
+		n, addr, err := d.conn.ReadFrom(buf)
+		if err != nil {
+			// the connection has been closed
+			return
+		}
+
+		resp, err := d.handle(buf[:n])
+		if err != nil {
+			d.log.Debug("Unable to handle DNS query", "client", addr, "error", err)
+			continue
+		}
+
+		_, err = d.conn.WriteTo(resp, addr)
+		if err != nil {
+			d.log.Debug("Unable to write DNS response", "client", addr, "error", err)
+		}
+	}
+}
+
+// handle the query and return the packed response
+func (d *DNS) handle(query []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+
+	h, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	rh := dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true, RecursionDesired: h.RecursionDesired, RCode: dnsmessage.RCodeSuccess}
+	answers := []dnsmessage.Resource{}
+
+	if h.OpCode != 0 || len(questions) != 1 {
+		rh.RCode = dnsmessage.RCodeNotImplemented
+	} else {
+		rh.RCode, answers = d.answer(questions[0])
+	}
+
+	b := dnsmessage.NewBuilder(nil, rh)
+	b.EnableCompression()
+
+	err = b.StartQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, q := range questions {
+		err = b.Question(q)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = b.StartAnswers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range answers {
+		switch body := a.Body.(type) {
+		case *dnsmessage.AResource:
+			err = b.AResource(a.Header, *body)
+		case *dnsmessage.AAAAResource:
+			err = b.AAAAResource(a.Header, *body)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b.Finish()
+}
+
+// answer returns the response code and the answers for the question
+func (d *DNS) answer(q dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource) {
+	name := strings.ToLower(strings.TrimSuffix(q.Name.String(), "."))
+
+	if name != DNSDomain && !strings.HasSuffix(name, "."+DNSDomain) {
+		return dnsmessage.RCodeRefused, nil
+	}
+
+	ips, err := d.lookup(name)
+	if err != nil {
+		d.log.Debug("Unable to lookup resource", "name", name, "error", err)
+		return dnsmessage.RCodeServerFailure, nil
+	}
+
+	if len(ips) == 0 {
+		return dnsmessage.RCodeNameError, nil
+	}
+
+	d.log.Debug("Resolved resource", "name", name, "type", q.Type, "ips", ips)
+
+	answers := []dnsmessage.Resource{}
+	for _, ip := range ips {
+		rh := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: dnsTTL}
+
+		if ip4 := ip.To4(); ip4 != nil && (q.Type == dnsmessage.TypeA || q.Type == dnsmessage.TypeALL) {
+			rh.Type = dnsmessage.TypeA
+
+			a := &dnsmessage.AResource{}
+			copy(a.A[:], ip4)
+			answers = append(answers, dnsmessage.Resource{Header: rh, Body: a})
+
+			continue
+		}
+
+		if ip.To4() == nil && (q.Type == dnsmessage.TypeAAAA || q.Type == dnsmessage.TypeALL) {
+			rh.Type = dnsmessage.TypeAAAA
+
+			a := &dnsmessage.AAAAResource{}
+			copy(a.AAAA[:], ip.To16())
+			answers = append(answers, dnsmessage.Resource{Header: rh, Body: a})
+		}
+	}
+
+	// the name exists but has no records of the requested type
+	return dnsmessage.RCodeSuccess, answers
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	assert "github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func startDNS(t *testing.T, lookup DNSLookup) *DNS {
+	d := NewDNS("127.0.0.1:0", lookup, hclog.NewNullLogger())
+
+	err := d.Start()
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		d.Stop()
+	})
+
+	return d
+}
+
+func queryDNS(t *testing.T, addr, name string, qt dnsmessage.Type) (dnsmessage.Header, []dnsmessage.Resource) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 1, RecursionDesired: true})
+	assert.NoError(t, b.StartQuestions())
+	assert.NoError(t, b.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: qt, Class: dnsmessage.ClassINET}))
+
+	q, err := b.Finish()
+	assert.NoError(t, err)
+
+	c, err := net.Dial("udp", addr)
+	assert.NoError(t, err)
+	defer c.Close()
+
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = c.Write(q)
+	assert.NoError(t, err)
+
+	buf := make([]byte, 512)
+	n, err := c.Read(buf)
+	assert.NoError(t, err)
+
+	var m dnsmessage.Message
+	err = m.Unpack(buf[:n])
+	assert.NoError(t, err)
+
+	return m.Header, m.Answers
+}
+
+func testLookup(fqdn string) ([]net.IP, error) {
+	switch fqdn {
+	case "consul.container.shipyard.run":
+		return []net.IP{net.ParseIP("10.5.0.2"), net.ParseIP("fd00::2")}, nil
+	case "broken.container.shipyard.run":
+		return nil, fmt.Errorf("boom")
+	}
+
+	return nil, nil
+}
+
+func TestDNSResolvesResourceFQDN(t *testing.T) {
+	d := startDNS(t, testLookup)
+
+	h, answers := queryDNS(t, d.Addr(), "consul.container.shipyard.run.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeSuccess, h.RCode)
+	assert.True(t, h.Authoritative)
+	assert.Len(t, answers, 1)
+	assert.Equal(t, [4]byte{10, 5, 0, 2}, answers[0].Body.(*dnsmessage.AResource).A)
+}
+
+func TestDNSResolvesIPv6Addresses(t *testing.T) {
+	d := startDNS(t, testLookup)
+
+	h, answers := queryDNS(t, d.Addr(), "CONSUL.container.shipyard.run.", dnsmessage.TypeAAAA)
+	assert.Equal(t, dnsmessage.RCodeSuccess, h.RCode)
+	assert.Len(t, answers, 1)
+	assert.Equal(t, net.ParseIP("fd00::2").To16(), net.IP(answers[0].Body.(*dnsmessage.AAAAResource).AAAA[:]))
+}
+
+func TestDNSReturnsNameErrorForUnknownResource(t *testing.T) {
+	d := startDNS(t, testLookup)
+
+	h, answers := queryDNS(t, d.Addr(), "vault.container.shipyard.run.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeNameError, h.RCode)
+	assert.Len(t, answers, 0)
+}
+
+func TestDNSReturnsServerFailureWhenLookupFails(t *testing.T) {
+	d := startDNS(t, testLookup)
+
+	h, _ := queryDNS(t, d.Addr(), "broken.container.shipyard.run.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeServerFailure, h.RCode)
+}
+
+func TestDNSRefusesOtherDomains(t *testing.T) {
+	d := startDNS(t, testLookup)
+
+	h, _ := queryDNS(t, d.Addr(), "example.com.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeRefused, h.RCode)
+}
@@ -0,0 +1,489 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// Operation types and statuses reported by the EngineAPI
+const (
+	OperationApply   = "apply"
+	OperationDestroy = "destroy"
+
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+)
+
+// EnvironmentManager applies and destroys the environment for the EngineAPI,
+// the API ensures that only one operation runs at a time
+type EnvironmentManager interface {
+	// Apply creates the resources in the blueprint writing the output to log
+	Apply(req EnvironmentRequest, log io.Writer) error
+	// Destroy removes all the resources in the environment writing the output to log
+	Destroy(log io.Writer) error
+}
+
+// EnvironmentRequest is the request to create an environment from a blueprint
+type EnvironmentRequest struct {
+	// Blueprint is a local path or a remote blueprint e.g. github.com/shipyard-run/blueprints//vault-k8s
+	Blueprint     string            `json:"blueprint"`
+	Variables     map[string]string `json:"variables,omitempty"`
+	VariablesFile string            `json:"variables_file,omitempty"`
+}
+
+// Operation is an apply or destroy started with the API
+type Operation struct {
+	ID       string     `json:"id"`
+	Type     string     `json:"type"`
+	Status   string     `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Output   string     `json:"output,omitempty"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// ResourceStatus is the status of a resource in the environment
+type ResourceStatus struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Module string `json:"module,omitempty"`
+	Status string `json:"status"`
+}
+
+// EnvironmentStatus is the status of the environment returned by the API
+type EnvironmentStatus struct {
+	Blueprint string           `json:"blueprint,omitempty"`
+	Resources []ResourceStatus `json:"resources"`
+	Operation *Operation       `json:"operation,omitempty"`
+}
+
+// EngineAPI exposes the environment over a local HTTP API so that tools can manage
+// environments without running the CLI, all endpoints except health require the token
+// to be sent as a bearer token in the Authorization header
+type EngineAPI struct {
+	bindAddr string
+	token    string
+	env      EnvironmentManager
+	dc       clients.Docker
+	log      hclog.Logger
+	server   *http.Server
+
+	// pollInterval is the interval the state is checked for the events stream
+	pollInterval time.Duration
+
+	opLock     sync.Mutex
+	operation  *Operation
+	opOutput   *lockedBuffer
+	operations int
+}
+
+// NewEngineAPI creates a new EngineAPI which listens on the given address
+func NewEngineAPI(addr, token string, env EnvironmentManager, dc clients.Docker, l hclog.Logger) *EngineAPI {
+	return &EngineAPI{
+		bindAddr:     addr,
+		token:        token,
+		env:          env,
+		dc:           dc,
+		log:          l,
+		pollInterval: time.Second,
+	}
+}
+
+// Handler returns the http.Handler for the API
+func (a *EngineAPI) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v1/health", a.health)
+	mux.Handle("/v1/environment", a.authenticate(http.HandlerFunc(a.environment)))
+	mux.Handle("/v1/operation", a.authenticate(http.HandlerFunc(a.getOperation)))
+	mux.Handle("/v1/outputs", a.authenticate(http.HandlerFunc(a.getOutputs)))
+	mux.Handle("/v1/events", a.authenticate(http.HandlerFunc(a.streamEvents)))
+	mux.Handle("/v1/resources/", a.authenticate(http.HandlerFunc(a.streamLogs)))
+
+	return mux
+}
+
+// Start the API, Start blocks until the API is stopped
+func (a *EngineAPI) Start() error {
+	l, err := net.Listen("tcp", a.bindAddr)
+	if err != nil {
+		return err
+	}
+
+	a.server = &http.Server{Handler: a.Handler()}
+
+	a.log.Info("Starting API server", "addr", l.Addr().String())
+
+	err = a.server.Serve(l)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+// Stop the API, running operations are not cancelled
+func (a *EngineAPI) Stop(ctx context.Context) error {
+	if a.server == nil {
+		return nil
+	}
+
+	return a.server.Shutdown(ctx)
+}
+
+func (a *EngineAPI) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if a.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			writeAPIError(rw, http.StatusUnauthorized, "a valid token must be specified in the Authorization header")
+			return
+		}
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+func (a *EngineAPI) health(rw http.ResponseWriter, r *http.Request) {
+	writeJSON(rw, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (a *EngineAPI) environment(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(rw, http.StatusOK, a.environmentStatus())
+	case http.MethodPost:
+		req := EnvironmentRequest{}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			writeAPIError(rw, http.StatusBadRequest, fmt.Sprintf("unable to decode request: %s", err))
+			return
+		}
+
+		if req.Blueprint == "" {
+			writeAPIError(rw, http.StatusBadRequest, "blueprint must be specified")
+			return
+		}
+
+		a.startOperation(rw, OperationApply, func(w io.Writer) error {
+			return a.env.Apply(req, w)
+		})
+	case http.MethodDelete:
+		a.startOperation(rw, OperationDestroy, a.env.Destroy)
+	default:
+		writeAPIError(rw, http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed", r.Method))
+	}
+}
+
+// startOperation runs the operation in the background, only one operation can run
+// at a time as all operations change the same state
+func (a *EngineAPI) startOperation(rw http.ResponseWriter, opType string, f func(w io.Writer) error) {
+	a.opLock.Lock()
+	defer a.opLock.Unlock()
+
+	if a.operation != nil && a.operation.Status == OperationRunning {
+		writeAPIError(rw, http.StatusConflict, fmt.Sprintf("operation %s is running, wait for it to complete", a.operation.ID))
+		return
+	}
+
+	a.operations++
+	op := &Operation{
+		ID:      fmt.Sprintf("%s-%d", opType, a.operations),
+		Type:    opType,
+		Status:  OperationRunning,
+		Started: time.Now(),
+	}
+
+	out := &lockedBuffer{}
+	a.operation = op
+	a.opOutput = out
+
+	a.log.Info("Starting operation", "id", op.ID)
+
+	go func() {
+		err := f(out)
+
+		a.opLock.Lock()
+		defer a.opLock.Unlock()
+
+		now := time.Now()
+		op.Finished = &now
+		op.Status = OperationSucceeded
+
+		if err != nil {
+			op.Status = OperationFailed
+			op.Error = err.Error()
+		}
+
+		a.log.Info("Operation complete", "id", op.ID, "status", op.Status)
+	}()
+
+	writeJSON(rw, http.StatusAccepted, a.copyOperation())
+}
+
+// copyOperation returns a copy of the current operation including the output,
+// the caller must hold the operation lock
+func (a *EngineAPI) copyOperation() *Operation {
+	if a.operation == nil {
+		return nil
+	}
+
+	op := *a.operation
+	op.Output = a.opOutput.String()
+
+	return &op
+}
+
+func (a *EngineAPI) getOperation(rw http.ResponseWriter, r *http.Request) {
+	a.opLock.Lock()
+	op := a.copyOperation()
+	a.opLock.Unlock()
+
+	if op == nil {
+		writeAPIError(rw, http.StatusNotFound, "no operations have been started")
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, op)
+}
+
+func (a *EngineAPI) environmentStatus() EnvironmentStatus {
+	es := EnvironmentStatus{Resources: []ResourceStatus{}}
+
+	a.opLock.Lock()
+	if a.operation != nil {
+		// the output is returned by the operation endpoint
+		op := *a.operation
+		es.Operation = &op
+	}
+	a.opLock.Unlock()
+
+	c := config.New()
+	err := c.FromJSON(utils.StatePath())
+	if err != nil {
+		return es
+	}
+
+	if c.Blueprint != nil {
+		es.Blueprint = c.Blueprint.Title
+	}
+
+	for _, r := range c.Resources {
+		es.Resources = append(es.Resources, ResourceStatus{
+			ID:     r.Info().ResourceID,
+			Name:   r.Info().Name,
+			Type:   string(r.Info().Type),
+			Module: r.Info().Module,
+			Status: string(r.Info().Status),
+		})
+	}
+
+	return es
+}
+
+func (a *EngineAPI) getOutputs(rw http.ResponseWriter, r *http.Request) {
+	outputs := map[string]string{}
+
+	c := config.New()
+	if err := c.FromJSON(utils.StatePath()); err == nil {
+		for _, o := range c.FindResourcesByType(string(config.TypeOutput)) {
+			if o.Info().Disabled {
+				continue
+			}
+
+			outputs[o.Info().Name] = o.(*config.Output).Value
+			if o.(*config.Output).Sensitive {
+				outputs[o.Info().Name] = utils.RedactedValue
+			}
+		}
+	}
+
+	writeJSON(rw, http.StatusOK, outputs)
+}
+
+// streamEvents streams the status of the environment as server-sent events,
+// an event is sent when the stream is opened and each time the status changes
+func (a *EngineAPI) streamEvents(rw http.ResponseWriter, r *http.Request) {
+	f, ok := rw.(http.Flusher)
+	if !ok {
+		writeAPIError(rw, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+
+	last := []byte{}
+	t := time.NewTicker(a.pollInterval)
+	defer t.Stop()
+
+	for {
+		d, _ := json.Marshal(a.environmentStatus())
+		if !bytes.Equal(d, last) {
+			fmt.Fprintf(rw, "event: status\ndata: %s\n\n", d)
+			f.Flush()
+			last = d
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// streamLogs streams the logs for the containers of a resource,
+// the resource is specified in the path e.g. /v1/resources/container.consul/logs
+func (a *EngineAPI) streamLogs(rw http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/resources/"), "/")
+	if len(parts) != 2 || parts[1] != "logs" {
+		writeAPIError(rw, http.StatusNotFound, fmt.Sprintf("path %s not found", r.URL.Path))
+		return
+	}
+
+	c := config.New()
+	err := c.FromJSON(utils.StatePath())
+	if err != nil {
+		writeAPIError(rw, http.StatusNotFound, "no environment is running")
+		return
+	}
+
+	res, err := c.FindResource(parts[0])
+	if err != nil {
+		writeAPIError(rw, http.StatusNotFound, fmt.Sprintf("resource %s not found", parts[0]))
+		return
+	}
+
+	containers := config.LogContainers(res)
+	if len(containers) == 0 {
+		writeAPIError(rw, http.StatusBadRequest, fmt.Sprintf("resource %s does not have logs", parts[0]))
+		return
+	}
+
+	opts := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     r.URL.Query().Get("follow") == "true",
+		Tail:       r.URL.Query().Get("tail"),
+	}
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.WriteHeader(http.StatusOK)
+
+	out := &flushWriter{w: rw}
+	if f, ok := rw.(http.Flusher); ok {
+		out.f = f
+	}
+
+	wg := sync.WaitGroup{}
+	for _, name := range containers {
+		wg.Add(1)
+
+		go func(name string) {
+			defer wg.Done()
+
+			err := a.copyLogs(r.Context(), name, opts, out)
+			if err != nil && r.Context().Err() == nil {
+				a.log.Debug("Unable to stream logs", "container", name, "error", err)
+			}
+		}(name)
+	}
+
+	wg.Wait()
+}
+
+func (a *EngineAPI) copyLogs(ctx context.Context, name string, opts types.ContainerLogsOptions, w io.Writer) error {
+	info, err := a.dc.ContainerInspect(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	rc, err := a.dc.ContainerLogs(ctx, name, opts)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// logs for containers without a TTY are multiplexed
+	if info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(w, rc)
+		return err
+	}
+
+	_, err = stdcopy.StdCopy(w, w, rc)
+	return err
+}
+
+// flushWriter flushes each write so that logs are streamed to the client
+type flushWriter struct {
+	sync.Mutex
+	w io.Writer
+	f http.Flusher
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	fw.Lock()
+	defer fw.Unlock()
+
+	n, err := fw.w.Write(p)
+	if fw.f != nil {
+		fw.f.Flush()
+	}
+
+	return n, err
+}
+
+// lockedBuffer is a buffer which can be written by an operation while it is read
+type lockedBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.buf.String()
+}
+
+// APIError is the body returned by the EngineAPI when a request fails
+type APIError struct {
+	Error string `json:"error"`
+}
+
+func writeAPIError(rw http.ResponseWriter, status int, msg string) {
+	writeJSON(rw, status, APIError{Error: msg})
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(v)
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+type mockEnvironment struct {
+	mock.Mock
+	wait chan struct{}
+}
+
+func (m *mockEnvironment) Apply(req EnvironmentRequest, log io.Writer) error {
+	args := m.Called(req)
+	fmt.Fprint(log, "applying")
+
+	if m.wait != nil {
+		<-m.wait
+	}
+
+	return args.Error(0)
+}
+
+func (m *mockEnvironment) Destroy(log io.Writer) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func setupEngineAPI(t *testing.T, state string) (*httptest.Server, *mockEnvironment, *mocks.MockDocker) {
+	home := os.Getenv(utils.HomeEnvName())
+	os.Setenv(utils.HomeEnvName(), t.TempDir())
+	t.Cleanup(func() { os.Setenv(utils.HomeEnvName(), home) })
+
+	if state != "" {
+		os.MkdirAll(utils.StateDir(), os.ModePerm)
+		err := ioutil.WriteFile(utils.StatePath(), []byte(state), os.ModePerm)
+		assert.NoError(t, err)
+	}
+
+	env := &mockEnvironment{}
+	dc := &mocks.MockDocker{}
+
+	api := NewEngineAPI("", "secret", env, dc, hclog.NewNullLogger())
+	api.pollInterval = 10 * time.Millisecond
+
+	ts := httptest.NewServer(api.Handler())
+	t.Cleanup(ts.Close)
+
+	return ts, env, dc
+}
+
+func apiRequest(t *testing.T, method, url, body string) *http.Response {
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+
+	return resp
+}
+
+func waitForOperation(t *testing.T, url string) Operation {
+	op := Operation{}
+
+	assert.Eventually(t, func() bool {
+		resp := apiRequest(t, http.MethodGet, url+"/v1/operation", "")
+		defer resp.Body.Close()
+
+		json.NewDecoder(resp.Body).Decode(&op)
+		return op.Status != OperationRunning
+	}, time.Second, 10*time.Millisecond)
+
+	return op
+}
+
+func TestEngineAPIRejectsRequestsWithoutToken(t *testing.T) {
+	ts, _, _ := setupEngineAPI(t, "")
+
+	resp, err := http.Get(ts.URL + "/v1/environment")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// health does not require a token
+	resp, err = http.Get(ts.URL + "/v1/health")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestEngineAPIReturnsEnvironmentStatus(t *testing.T) {
+	ts, _, _ := setupEngineAPI(t, apiState)
+
+	resp := apiRequest(t, http.MethodGet, ts.URL+"/v1/environment", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	es := EnvironmentStatus{}
+	err := json.NewDecoder(resp.Body).Decode(&es)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Consul", es.Blueprint)
+	assert.Len(t, es.Resources, 2)
+	assert.Equal(t, "consul", es.Resources[0].Name)
+	assert.Equal(t, "applied", es.Resources[0].Status)
+}
+
+func TestEngineAPIReturnsOutputs(t *testing.T) {
+	ts, _, _ := setupEngineAPI(t, apiState)
+
+	resp := apiRequest(t, http.MethodGet, ts.URL+"/v1/outputs", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	outputs := map[string]string{}
+	err := json.NewDecoder(resp.Body).Decode(&outputs)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"CONSUL_HTTP_ADDR": "http://localhost:8500"}, outputs)
+}
+
+func TestEngineAPIAppliesBlueprint(t *testing.T) {
+	ts, env, _ := setupEngineAPI(t, "")
+	env.On("Apply", EnvironmentRequest{Blueprint: "./consul", Variables: map[string]string{"a": "b"}}).Return(nil)
+
+	resp := apiRequest(t, http.MethodPost, ts.URL+"/v1/environment", `{"blueprint": "./consul", "variables": {"a": "b"}}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	op := waitForOperation(t, ts.URL)
+	assert.Equal(t, OperationApply, op.Type)
+	assert.Equal(t, OperationSucceeded, op.Status)
+	assert.Equal(t, "applying", op.Output)
+}
+
+func TestEngineAPIReturnsErrorWhenBlueprintMissing(t *testing.T) {
+	ts, _, _ := setupEngineAPI(t, "")
+
+	resp := apiRequest(t, http.MethodPost, ts.URL+"/v1/environment", `{}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestEngineAPIReturnsConflictWhenOperationRunning(t *testing.T) {
+	ts, env, _ := setupEngineAPI(t, "")
+	env.wait = make(chan struct{})
+	env.On("Apply", mock.Anything).Return(nil)
+	env.On("Destroy").Return(nil)
+
+	resp := apiRequest(t, http.MethodPost, ts.URL+"/v1/environment", `{"blueprint": "./consul"}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp = apiRequest(t, http.MethodDelete, ts.URL+"/v1/environment", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	close(env.wait)
+	waitForOperation(t, ts.URL)
+
+	resp = apiRequest(t, http.MethodDelete, ts.URL+"/v1/environment", "")
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+func TestEngineAPIReportsFailedOperations(t *testing.T) {
+	ts, env, _ := setupEngineAPI(t, "")
+	env.On("Destroy").Return(fmt.Errorf("boom"))
+
+	resp := apiRequest(t, http.MethodDelete, ts.URL+"/v1/environment", "")
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	op := waitForOperation(t, ts.URL)
+	assert.Equal(t, OperationFailed, op.Status)
+	assert.Equal(t, "boom", op.Error)
+}
+
+func TestEngineAPIStreamsStatusEvents(t *testing.T) {
+	ts, _, _ := setupEngineAPI(t, apiState)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/v1/events", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(resp.Body)
+	line, err := r.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "event: status\n", line)
+
+	line, err = r.ReadString('\n')
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "data: "))
+	assert.Contains(t, line, `"name":"consul"`)
+}
+
+func TestEngineAPIStreamsLogs(t *testing.T) {
+	ts, _, dc := setupEngineAPI(t, apiState)
+
+	dc.On("ContainerInspect", mock.Anything, "consul.container.shipyard.run").Return(types.ContainerJSON{Config: &container.Config{Tty: true}}, nil)
+	dc.On("ContainerLogs", mock.Anything, "consul.container.shipyard.run", mock.Anything).Return(ioutil.NopCloser(bytes.NewBufferString("log line\n")), nil)
+
+	resp := apiRequest(t, http.MethodGet, ts.URL+"/v1/resources/container.consul/logs?tail=10", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	d, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "log line\n", string(d))
+
+	opts := dc.Calls[1].Arguments.Get(2).(types.ContainerLogsOptions)
+	assert.Equal(t, "10", opts.Tail)
+	assert.False(t, opts.Follow)
+}
+
+func TestEngineAPIReturnsNotFoundForUnknownResourceLogs(t *testing.T) {
+	ts, _, _ := setupEngineAPI(t, apiState)
+
+	resp := apiRequest(t, http.MethodGet, ts.URL+"/v1/resources/container.missing/logs", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+var apiState = `
+{
+  "blueprint": {
+    "title": "Consul"
+  },
+  "resources": [
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "container",
+      "image": {
+        "name": "consul:1.10.1"
+      }
+	},
+	{
+      "name": "CONSUL_HTTP_ADDR",
+      "status": "applied",
+      "value": "http://localhost:8500",
+      "type": "output"
+	}
+  ]
+}
+`
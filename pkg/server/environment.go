@@ -0,0 +1,60 @@
+package server
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+)
+
+// SetEnvironmentSource sets the state and the history used to describe the environment
+// running on this machine to 'shipyard fleet list' on other machines. The environment is
+// served on addr using the TLS config, which must require a client certificate signed by
+// the CA of a joined connector as the environment contains the local user name.
+func (s *API) SetEnvironmentSource(addr string, tc *tls.Config, statePath string, h clients.History) {
+	s.envAddr = addr
+	s.envTLS = tc
+	s.statePath = statePath
+	s.history = h
+}
+
+// startEnvironmentServer serves the environment on the mTLS listener
+func (s *API) startEnvironmentServer() {
+	if s.envAddr == "" || s.envTLS == nil {
+		return
+	}
+
+	l, err := tls.Listen("tcp", s.envAddr, s.envTLS)
+	if err != nil {
+		s.log.Error("Unable to start environment server", "bind_addr", s.envAddr, "error", err)
+		return
+	}
+
+	// the address is updated with the port when a random port is used
+	s.envAddr = l.Addr().String()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/environment", s.getEnvironment)
+
+	s.envServer = &http.Server{Handler: mux}
+	go s.envServer.Serve(l)
+}
+
+// getEnvironment returns the owner, blueprint, age, and health of the environment
+// running on this machine
+func (s *API) getEnvironment(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	env, err := clients.LocalEnvironment(s.statePath, s.history)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(env)
+}
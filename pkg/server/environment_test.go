@@ -0,0 +1,116 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupEnvironmentServer(t *testing.T) (*API, string) {
+	hm := &clients.HistoryMock{}
+	hm.On("Read").Return([]clients.HistoryEntry{}, nil)
+
+	_, dir := setupCertificateFiles(t, 24*time.Hour)
+
+	// the CA of a joined machine
+	peer := setupCA(t)
+	os.MkdirAll(filepath.Join(dir, "trusted"), os.ModePerm)
+	assert.NoError(t, peer.cert.WriteFile(filepath.Join(dir, "trusted", "peer.cert")))
+	writeLeaf(t, peer, time.Hour, filepath.Join(dir, "peer_leaf.cert"), filepath.Join(dir, "peer_leaf.key"))
+
+	c := newTestCertificates(t, dir)
+
+	s := New("", hclog.NewNullLogger())
+	s.SetEnvironmentSource("localhost:0", c.ServerTLSConfig(), filepath.Join(t.TempDir(), "state.json"), hm)
+	s.startEnvironmentServer()
+	t.Cleanup(func() { s.envServer.Close() })
+
+	return s, dir
+}
+
+func environmentClient(t *testing.T, dir, certFile, keyFile string) *http.Client {
+	ca, err := ioutil.ReadFile(filepath.Join(dir, "root.cert"))
+	assert.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	tc := &tls.Config{RootCAs: pool, ServerName: "localhost"}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		assert.NoError(t, err)
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{TLSClientConfig: tc}}
+}
+
+func TestEnvironmentServerReturnsEnvironmentToJoinedMachine(t *testing.T) {
+	s, dir := setupEnvironmentServer(t)
+
+	hc := environmentClient(t, dir, filepath.Join(dir, "peer_leaf.cert"), filepath.Join(dir, "peer_leaf.key"))
+
+	resp, err := hc.Get(fmt.Sprintf("https://%s/environment", s.envAddr))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+
+	env := &clients.Environment{}
+	err = json.NewDecoder(resp.Body).Decode(env)
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, env.Host)
+	assert.Equal(t, 0, env.Resources)
+	assert.Equal(t, clients.EnvironmentHealthy, env.Health)
+}
+
+func TestEnvironmentServerRejectsClientWithoutCertificate(t *testing.T) {
+	s, dir := setupEnvironmentServer(t)
+
+	hc := environmentClient(t, dir, "", "")
+
+	_, err := hc.Get(fmt.Sprintf("https://%s/environment", s.envAddr))
+	assert.Error(t, err)
+}
+
+func TestEnvironmentServerRejectsCertificateFromUntrustedCA(t *testing.T) {
+	s, dir := setupEnvironmentServer(t)
+
+	other := setupCA(t)
+	writeLeaf(t, other, time.Hour, filepath.Join(dir, "other_leaf.cert"), filepath.Join(dir, "other_leaf.key"))
+
+	hc := environmentClient(t, dir, filepath.Join(dir, "other_leaf.cert"), filepath.Join(dir, "other_leaf.key"))
+
+	_, err := hc.Get(fmt.Sprintf("https://%s/environment", s.envAddr))
+	assert.Error(t, err)
+}
+
+func TestEnvironmentServerNotStartedWithoutTLS(t *testing.T) {
+	s := New("", hclog.NewNullLogger())
+	s.SetEnvironmentSource("localhost:0", nil, "state.json", nil)
+	s.startEnvironmentServer()
+
+	assert.Nil(t, s.envServer)
+}
+
+func TestGetEnvironmentRejectsPost(t *testing.T) {
+	s := New("", hclog.NewNullLogger())
+
+	rw := httptest.NewRecorder()
+	s.getEnvironment(rw, httptest.NewRequest("POST", "/environment", nil))
+
+	assert.Equal(t, 405, rw.Code)
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// newHeaderProxy creates a reverse proxy for the upstream which applies the
+// header rules to the request and the response
+func newHeaderProxy(upstream *url.URL, headers *config.Headers) *httputil.ReverseProxy {
+	p := httputil.NewSingleHostReverseProxy(upstream)
+	if headers == nil {
+		return p
+	}
+
+	director := p.Director
+	p.Director = func(r *http.Request) {
+		director(r)
+
+		applyHeaderRules(r.Header, headers.Request)
+
+		if headers.RewriteHost != "" {
+			r.Host = headers.RewriteHost
+		}
+	}
+
+	p.ModifyResponse = func(resp *http.Response) error {
+		// the CORS headers are set by the proxy, remove any returned by
+		// the upstream so that the client does not receive duplicates
+		if headers.CORS != nil {
+			for k := range resp.Header {
+				if strings.HasPrefix(k, "Access-Control-") {
+					resp.Header.Del(k)
+				}
+			}
+		}
+
+		applyHeaderRules(resp.Header, headers.Response)
+
+		return nil
+	}
+
+	return p
+}
+
+// applyHeaderRules removes and then sets the headers defined in the rules
+func applyHeaderRules(h http.Header, rules *config.HeaderRules) {
+	if rules == nil {
+		return
+	}
+
+	for _, k := range rules.Remove {
+		h.Del(k)
+	}
+
+	for k, v := range rules.Set {
+		h.Set(k, v)
+	}
+}
+
+// writeCORS adds the CORS headers to the response when the origin of the
+// request is allowed, returns true when the request was a preflight request
+// which has been answered
+func writeCORS(rw http.ResponseWriter, r *http.Request, cors *config.CORS) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !originAllowed(origin, cors.AllowedOrigins) {
+		return false
+	}
+
+	h := rw.Header()
+	h.Add("Vary", "Origin")
+
+	if len(cors.AllowedOrigins) == 0 && !cors.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if cors.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+
+	methods := cors.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+	if len(cors.AllowedHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
+	} else if rh := r.Header.Get("Access-Control-Request-Headers"); rh != "" {
+		h.Set("Access-Control-Allow-Headers", rh)
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+
+	return true
+}
+
+func originAllowed(origin string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+
+	return false
+}
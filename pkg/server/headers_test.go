@@ -0,0 +1,120 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupHeaderProxy(t *testing.T, headers *config.Headers) (*AuthProxy, *[]*http.Request) {
+	requests := []*http.Request{}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		rw.Header().Set("Access-Control-Allow-Origin", "http://upstream")
+		rw.Header().Set("Server", "upstream")
+		fmt.Fprint(rw, "upstream")
+	}))
+
+	t.Cleanup(upstream.Close)
+
+	p, err := NewAuthProxy("test", "127.0.0.1:0", upstream.URL, nil, headers, hclog.NewNullLogger())
+	assert.NoError(t, err)
+
+	return p, &requests
+}
+
+func TestAuthProxyWithoutAuthOrHeadersReturnsError(t *testing.T) {
+	_, err := NewAuthProxy("test", ":0", "localhost:8080", nil, nil, hclog.NewNullLogger())
+	assert.Error(t, err)
+}
+
+func TestHeaderProxyAppliesRequestRules(t *testing.T) {
+	p, requests := setupHeaderProxy(t, &config.Headers{
+		RewriteHost: "api.example.com",
+		Request: &config.HeaderRules{
+			Set:    map[string]string{"X-Env": "local"},
+			Remove: []string{"Authorization"},
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer abc")
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, *requests, 1)
+	assert.Equal(t, "api.example.com", (*requests)[0].Host)
+	assert.Equal(t, "local", (*requests)[0].Header.Get("X-Env"))
+	assert.Empty(t, (*requests)[0].Header.Get("Authorization"))
+}
+
+func TestHeaderProxyAppliesResponseRules(t *testing.T) {
+	p, _ := setupHeaderProxy(t, &config.Headers{
+		Response: &config.HeaderRules{
+			Set:    map[string]string{"Cache-Control": "no-store"},
+			Remove: []string{"Server"},
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	assert.Empty(t, rr.Header().Get("Server"))
+}
+
+func TestHeaderProxyAnswersCORSPreflight(t *testing.T) {
+	p, requests := setupHeaderProxy(t, &config.Headers{
+		CORS: &config.CORS{AllowedOrigins: []string{"http://localhost:3000"}, AllowCredentials: true},
+	})
+
+	r := httptest.NewRequest(http.MethodOptions, "/api", nil)
+	r.Header.Set("Origin", "http://localhost:3000")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	r.Header.Set("Access-Control-Request-Headers", "Content-Type")
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Len(t, *requests, 0)
+	assert.Equal(t, "http://localhost:3000", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, rr.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+	assert.Equal(t, "Content-Type", rr.Header().Get("Access-Control-Allow-Headers"))
+}
+
+func TestHeaderProxyReplacesUpstreamCORSHeaders(t *testing.T) {
+	p, requests := setupHeaderProxy(t, &config.Headers{CORS: &config.CORS{}})
+
+	r := httptest.NewRequest(http.MethodGet, "/api", nil)
+	r.Header.Set("Origin", "http://localhost:3000")
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Len(t, *requests, 1)
+	assert.Equal(t, []string{"*"}, rr.Header().Values("Access-Control-Allow-Origin"))
+}
+
+func TestHeaderProxyDoesNotAddCORSForDisallowedOrigin(t *testing.T) {
+	p, _ := setupHeaderProxy(t, &config.Headers{
+		CORS: &config.CORS{AllowedOrigins: []string{"http://localhost:3000"}},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/api", nil)
+	r.Header.Set("Origin", "http://evil.com")
+
+	rr := httptest.NewRecorder()
+	p.ServeHTTP(rr, r)
+
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+}
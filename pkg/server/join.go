@@ -0,0 +1,50 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+)
+
+// Joiner accepts connectors on other machines which join the connector
+type Joiner interface {
+	AcceptJoin(dir string, req *clients.JoinRequest) (*clients.JoinResponse, error)
+}
+
+// SetJoiner sets the client which accepts join requests from connectors on
+// other machines, dir is the folder containing the certificates of the connector
+func (s *API) SetJoiner(j Joiner, dir string) {
+	s.joiner = j
+	s.certsDir = dir
+}
+
+// join trusts the CA of a connector on another machine which signed its CA with
+// a valid join token and returns the CA of this connector
+func (s *API) join(c *fiber.Ctx) error {
+	if s.joiner == nil {
+		return fiber.NewError(fiber.StatusNotFound, "joining is not enabled for this connector")
+	}
+
+	req := &clients.JoinRequest{}
+	err := c.BodyParser(req)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if req.MAC == "" || req.CA == "" || req.Name == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "mac, name, and ca must be specified")
+	}
+
+	resp, err := s.joiner.AcceptJoin(s.certsDir, req)
+	if err == clients.ErrInvalidJoinToken {
+		s.log.Warn("Rejected join request", "name", req.Name, "remote_addr", c.IP())
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	s.log.Info("Connector joined", "name", req.Name, "remote_addr", c.IP())
+
+	return c.JSON(resp)
+}
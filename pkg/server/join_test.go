@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupJoin(t *testing.T, err error) (*API, *clients.ConnectorMock) {
+	cm := &clients.ConnectorMock{}
+	cm.On("AcceptJoin", mock.Anything, mock.Anything).Return(&clients.JoinResponse{Name: "lab", CA: "ca", ConnectorAddress: "lab.local:30001"}, err)
+
+	s := New("", hclog.NewNullLogger())
+	s.SetJoiner(cm, "/certs")
+	s.app.Post("/join", s.join)
+
+	return s, cm
+}
+
+func joinRequest(t *testing.T, s *API, req *clients.JoinRequest) (int, *clients.JoinResponse) {
+	d, err := json.Marshal(req)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest("POST", "/join", bytes.NewReader(d))
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.app.Test(r)
+	assert.NoError(t, err)
+
+	jr := &clients.JoinResponse{}
+	json.NewDecoder(resp.Body).Decode(jr)
+
+	return resp.StatusCode, jr
+}
+
+func TestJoinReturnsCA(t *testing.T) {
+	s, cm := setupJoin(t, nil)
+
+	code, jr := joinRequest(t, s, &clients.JoinRequest{MAC: "abc", Name: "laptop", CA: "laptop-ca"})
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "lab.local:30001", jr.ConnectorAddress)
+
+	cm.AssertCalled(t, "AcceptJoin", "/certs", &clients.JoinRequest{MAC: "abc", Name: "laptop", CA: "laptop-ca"})
+}
+
+func TestJoinReturnsForbiddenWithInvalidToken(t *testing.T) {
+	s, _ := setupJoin(t, clients.ErrInvalidJoinToken)
+
+	code, _ := joinRequest(t, s, &clients.JoinRequest{MAC: "abc", Name: "laptop", CA: "laptop-ca"})
+	assert.Equal(t, 403, code)
+}
+
+func TestJoinReturnsBadRequestWithMissingFields(t *testing.T) {
+	s, cm := setupJoin(t, nil)
+
+	code, _ := joinRequest(t, s, &clients.JoinRequest{Name: "laptop"})
+	assert.Equal(t, 400, code)
+
+	cm.AssertNotCalled(t, "AcceptJoin", mock.Anything, mock.Anything)
+}
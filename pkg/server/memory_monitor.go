@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// memoryPressureStopTimeout is the time containers are given to shut down gracefully
+// before they are killed, this is the same timeout used by shipyard pause
+const memoryPressureStopTimeout = 20 * time.Second
+
+// MemoryMonitor checks the memory used by the containers on the Docker engine and
+// stops the resources in the memory pressure policy, lowest priority first, when the
+// threshold is exceeded. Stopped resources are restarted with shipyard resume.
+type MemoryMonitor struct {
+	policy    *config.MemoryPressure
+	docker    clients.Docker
+	statePath string
+	log       hclog.Logger
+
+	stop chan struct{}
+}
+
+// NewMemoryMonitor creates a monitor which applies the given policy to the resources
+// in the Shipyard state
+func NewMemoryMonitor(p *config.MemoryPressure, dc clients.Docker, l hclog.Logger) *MemoryMonitor {
+	return &MemoryMonitor{policy: p, docker: dc, statePath: utils.StatePath(), log: l}
+}
+
+// Check samples the memory usage and stops the lowest priority running resource when
+// the threshold is exceeded. A single resource is stopped for each check so that the
+// memory it releases is measured before another resource is stopped.
+func (m *MemoryMonitor) Check() error {
+	u, err := clients.SampleMemoryUsage(m.docker)
+	if err != nil {
+		return err
+	}
+
+	if u.Percent() < float64(m.policy.Threshold) {
+		return nil
+	}
+
+	m.log.Warn(
+		"Memory pressure threshold exceeded",
+		"used", units.BytesSize(float64(u.Used)),
+		"total", units.BytesSize(float64(u.Total)),
+		"percent", fmt.Sprintf("%.1f", u.Percent()),
+		"threshold", m.policy.Threshold,
+	)
+
+	c := config.New()
+	err = c.FromJSON(m.statePath)
+	if err != nil {
+		return fmt.Errorf("unable to load state: %s", err)
+	}
+
+	for _, r := range m.policy.StopOrder(c) {
+		ids := m.runningContainers(r)
+		if len(ids) == 0 {
+			continue
+		}
+
+		m.log.Warn("Stopping resource to reduce memory pressure, run 'shipyard resume' to restart it", "resource", fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name))
+
+		timeout := memoryPressureStopTimeout
+		for _, id := range ids {
+			err := m.docker.ContainerStop(context.Background(), id, &timeout)
+			if err != nil {
+				return fmt.Errorf("unable to stop container %s: %s", id, err)
+			}
+		}
+
+		return nil
+	}
+
+	m.log.Warn("Unable to reduce memory pressure, none of the resources in the memory_pressure policy are running")
+
+	return nil
+}
+
+// runningContainers returns the IDs of the running containers for a resource
+func (m *MemoryMonitor) runningContainers(r config.Resource) []string {
+	ids := []string{}
+
+	for _, name := range config.LogContainers(r) {
+		info, err := m.docker.ContainerInspect(context.Background(), name)
+		if err != nil || info.ContainerJSONBase == nil || info.State == nil {
+			continue
+		}
+
+		if info.State.Running {
+			ids = append(ids, info.ID)
+		}
+	}
+
+	return ids
+}
+
+// Start checking the memory pressure at the given interval
+func (m *MemoryMonitor) Start(interval time.Duration) {
+	m.stop = make(chan struct{})
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-t.C:
+				err := m.Check()
+				if err != nil {
+					m.log.Error("Unable to check memory pressure", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop checking the memory pressure
+func (m *MemoryMonitor) Stop() {
+	if m.stop != nil {
+		close(m.stop)
+	}
+}
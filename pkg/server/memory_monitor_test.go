@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func runningContainer(id string, running bool) types.ContainerJSON {
+	return types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{ID: id, State: &types.ContainerState{Running: running}}}
+}
+
+func setupMemoryMonitor(t *testing.T, used int) (*MemoryMonitor, *mocks.MockDocker) {
+	md := &mocks.MockDocker{}
+	md.On("Info", mock.Anything).Return(types.Info{MemTotal: 1000}, nil)
+	md.On("ContainerList", mock.Anything, mock.Anything).Return([]types.Container{{ID: "abc"}}, nil)
+	md.On("ContainerStats", mock.Anything, "abc", false).Return(
+		types.ContainerStats{Body: ioutil.NopCloser(strings.NewReader(fmt.Sprintf(`{"memory_stats": {"usage": %d}}`, used)))},
+		nil,
+	)
+
+	md.On("ContainerInspect", mock.Anything, "grafana.container.shipyard.run").Return(runningContainer("grafana", true), nil)
+	md.On("ContainerInspect", mock.Anything, "prometheus.container.shipyard.run").Return(runningContainer("prometheus", true), nil)
+	md.On("ContainerInspect", mock.Anything, "server.k3s.k8s-cluster.shipyard.run").Return(runningContainer("k3s", true), nil)
+	md.On("ContainerStop", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	state := filepath.Join(t.TempDir(), "state.json")
+	err := ioutil.WriteFile(state, []byte(memoryMonitorState), 0644)
+	assert.NoError(t, err)
+
+	p := &config.MemoryPressure{Threshold: 90, Stop: []string{"container.grafana", "container.*"}}
+
+	mm := NewMemoryMonitor(p, md, hclog.NewNullLogger())
+	mm.statePath = state
+
+	return mm, md
+}
+
+func TestMemoryMonitorDoesNothingBelowThreshold(t *testing.T) {
+	mm, md := setupMemoryMonitor(t, 800)
+
+	err := mm.Check()
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "ContainerStop", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMemoryMonitorStopsLowestPriorityResource(t *testing.T) {
+	mm, md := setupMemoryMonitor(t, 950)
+
+	err := mm.Check()
+	assert.NoError(t, err)
+
+	md.AssertNumberOfCalls(t, "ContainerStop", 1)
+	md.AssertCalled(t, "ContainerStop", mock.Anything, "grafana", mock.Anything)
+}
+
+func TestMemoryMonitorStopsNextResourceWhenStopped(t *testing.T) {
+	mm, md := setupMemoryMonitor(t, 950)
+	removeOn(&md.Mock, "ContainerInspect")
+	md.On("ContainerInspect", mock.Anything, "grafana.container.shipyard.run").Return(runningContainer("grafana", false), nil)
+	md.On("ContainerInspect", mock.Anything, "prometheus.container.shipyard.run").Return(runningContainer("prometheus", true), nil)
+
+	err := mm.Check()
+	assert.NoError(t, err)
+
+	md.AssertNumberOfCalls(t, "ContainerStop", 1)
+	md.AssertCalled(t, "ContainerStop", mock.Anything, "prometheus", mock.Anything)
+}
+
+func TestMemoryMonitorDoesNotStopResourcesNotInPolicy(t *testing.T) {
+	mm, md := setupMemoryMonitor(t, 950)
+	removeOn(&md.Mock, "ContainerInspect")
+	md.On("ContainerInspect", mock.Anything, mock.Anything).Return(runningContainer("", false), nil)
+	md.On("ContainerInspect", mock.Anything, "server.k3s.k8s-cluster.shipyard.run").Return(runningContainer("k3s", true), nil)
+
+	err := mm.Check()
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "ContainerStop", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMemoryMonitorReturnsErrorWhenStopFails(t *testing.T) {
+	mm, md := setupMemoryMonitor(t, 950)
+	removeOn(&md.Mock, "ContainerStop")
+	md.On("ContainerStop", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := mm.Check()
+	assert.Error(t, err)
+}
+
+func removeOn(m *mock.Mock, method string) {
+	ec := []*mock.Call{}
+
+	for _, c := range m.ExpectedCalls {
+		if c.Method != method {
+			ec = append(ec, c)
+		}
+	}
+
+	m.ExpectedCalls = ec
+}
+
+var memoryMonitorState = `
+{
+  "resources": [
+    {
+      "name": "k3s",
+      "type": "k8s_cluster",
+      "status": "applied"
+    },
+    {
+      "name": "prometheus",
+      "type": "container",
+      "status": "applied"
+    },
+    {
+      "name": "grafana",
+      "type": "container",
+      "status": "applied"
+    }
+  ]
+}
+`
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/stats"
+)
+
+// Metrics records the activity of the connector, the gRPC server reports the
+// connections and the data sent over tunnels using the stats.Handler interface,
+// connection attempts to remote connectors are read from the log of the gRPC server
+// using the hclog.SinkAdapter interface
+type Metrics struct {
+	start time.Time
+
+	connections   int64
+	bytesReceived uint64
+	bytesSent     uint64
+
+	lock     sync.Mutex
+	attempts map[string]uint64
+	failures uint64
+}
+
+// NewMetrics creates a new Metrics
+func NewMetrics() *Metrics {
+	return &Metrics{
+		start:    time.Now(),
+		attempts: map[string]uint64{},
+	}
+}
+
+// Uptime returns the time since the metrics were created
+func (m *Metrics) Uptime() time.Duration {
+	return time.Since(m.start)
+}
+
+// Connections returns the number of open gRPC connections
+func (m *Metrics) Connections() int64 {
+	return atomic.LoadInt64(&m.connections)
+}
+
+// BytesReceived returns the total payload bytes received on gRPC streams
+func (m *Metrics) BytesReceived() uint64 {
+	return atomic.LoadUint64(&m.bytesReceived)
+}
+
+// BytesSent returns the total payload bytes sent on gRPC streams
+func (m *Metrics) BytesSent() uint64 {
+	return atomic.LoadUint64(&m.bytesSent)
+}
+
+// Reconnects returns the number of connections to remote connectors
+// made after the first connection to each remote connector
+func (m *Metrics) Reconnects() uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var r uint64
+	for _, a := range m.attempts {
+		r += a - 1
+	}
+
+	return r
+}
+
+// ConnectFailures returns the number of failed connection attempts to remote connectors
+func (m *Metrics) ConnectFailures() uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.failures
+}
+
+// TagRPC implements stats.Handler
+func (m *Metrics) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+// HandleRPC implements stats.Handler and records the size of the messages
+func (m *Metrics) HandleRPC(_ context.Context, s stats.RPCStats) {
+	switch p := s.(type) {
+	case *stats.InPayload:
+		atomic.AddUint64(&m.bytesReceived, uint64(p.Length))
+	case *stats.OutPayload:
+		atomic.AddUint64(&m.bytesSent, uint64(p.Length))
+	}
+}
+
+// TagConn implements stats.Handler
+func (m *Metrics) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn implements stats.Handler and records the open connections
+func (m *Metrics) HandleConn(_ context.Context, s stats.ConnStats) {
+	switch s.(type) {
+	case *stats.ConnBegin:
+		atomic.AddInt64(&m.connections, 1)
+	case *stats.ConnEnd:
+		atomic.AddInt64(&m.connections, -1)
+	}
+}
+
+// Accept implements hclog.SinkAdapter, the connector logs the key message
+// with the event, attempts to connect to a remote connector are recorded
+func (m *Metrics) Accept(_ string, _ hclog.Level, _ string, args ...interface{}) {
+	var message, addr string
+	for i := 0; i+1 < len(args); i += 2 {
+		switch args[i] {
+		case "message":
+			message, _ = args[i+1].(string)
+		case "addr":
+			addr = fmt.Sprintf("%v", args[i+1])
+		}
+	}
+
+	switch message {
+	case "Connecting to remote server":
+		m.lock.Lock()
+		m.attempts[addr]++
+		m.lock.Unlock()
+	case "Unable to open remote connection":
+		m.lock.Lock()
+		m.failures++
+		m.lock.Unlock()
+	}
+}
+
+// WritePrometheus writes the metrics and the tunnel status in the
+// Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer, st *Status) {
+	writeMetric(w, "shipyard_connector_uptime_seconds", "gauge", "Time since the connector started", m.Uptime().Seconds())
+	writeMetric(w, "shipyard_connector_grpc_connections", "gauge", "Open gRPC connections", float64(m.Connections()))
+	writeMetric(w, "shipyard_connector_received_bytes_total", "counter", "Bytes received over tunnels", float64(m.BytesReceived()))
+	writeMetric(w, "shipyard_connector_sent_bytes_total", "counter", "Bytes sent over tunnels", float64(m.BytesSent()))
+	writeMetric(w, "shipyard_connector_reconnects_total", "counter", "Reconnections to remote connectors", float64(m.Reconnects()))
+	writeMetric(w, "shipyard_connector_connect_failures_total", "counter", "Failed connection attempts to remote connectors", float64(m.ConnectFailures()))
+
+	if st == nil {
+		return
+	}
+
+	writeMetric(w, "shipyard_connector_active_tunnels", "gauge", "Tunnels which are active", float64(st.ActiveTunnels))
+	writeMetric(w, "shipyard_connector_errored_tunnels", "gauge", "Tunnels which are in an error state", float64(st.ErroredTunnels))
+
+	fmt.Fprintln(w, "# HELP shipyard_connector_proxies Proxies running in the connector")
+	fmt.Fprintln(w, "# TYPE shipyard_connector_proxies gauge")
+	fmt.Fprintf(w, "shipyard_connector_proxies{type=\"auth\"} %d\n", st.AuthProxies)
+	fmt.Fprintf(w, "shipyard_connector_proxies{type=\"router\"} %d\n", st.Routers)
+	fmt.Fprintf(w, "shipyard_connector_proxies{type=\"stream\"} %d\n", st.StreamProxies)
+	fmt.Fprintf(w, "shipyard_connector_proxies{type=\"socks\"} %d\n", st.SocksProxies)
+
+	if st.CertificateExpiry != nil {
+		writeMetric(w, "shipyard_connector_certificate_expiry_timestamp_seconds", "gauge", "Time the connector certificate expires", float64(st.CertificateExpiry.Unix()))
+	}
+}
+
+func writeMetric(w io.Writer, name, kind, help string, v float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	fmt.Fprintf(w, "%s %g\n", name, v)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// PrometheusWriter writes metrics in the Prometheus text exposition format
+type PrometheusWriter interface {
+	WritePrometheus(w io.Writer)
+}
+
+// MetricsServer serves the metrics of the engine at /metrics so that
+// they can be scraped by Prometheus
+type MetricsServer struct {
+	bindAddr string
+	metrics  PrometheusWriter
+	log      hclog.Logger
+
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewMetricsServer creates a new MetricsServer which listens on bindAddr
+func NewMetricsServer(bindAddr string, m PrometheusWriter, l hclog.Logger) *MetricsServer {
+	return &MetricsServer{bindAddr: bindAddr, metrics: m, log: l}
+}
+
+// Handler returns the http.Handler for the metrics endpoint
+func (s *MetricsServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		s.metrics.WritePrometheus(rw)
+	})
+
+	return mux
+}
+
+// Start the server, Start does not block
+func (s *MetricsServer) Start() error {
+	l, err := net.Listen("tcp", s.bindAddr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %s", s.bindAddr, err)
+	}
+
+	s.listener = l
+	s.server = &http.Server{Handler: s.Handler()}
+
+	go func() {
+		err := s.server.Serve(l)
+		if err != nil && err != http.ErrServerClosed {
+			s.log.Error("Metrics server stopped", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *MetricsServer) Addr() string {
+	if s.listener == nil {
+		return s.bindAddr
+	}
+
+	return s.listener.Addr().String()
+}
+
+// Stop the server
+func (s *MetricsServer) Stop(ctx context.Context) error {
+	if s.server == nil {
+		return nil
+	}
+
+	return s.server.Shutdown(ctx)
+}
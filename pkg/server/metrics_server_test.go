@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	assert "github.com/stretchr/testify/require"
+)
+
+type prometheusWriterMock struct{}
+
+func (p *prometheusWriterMock) WritePrometheus(w io.Writer) {
+	fmt.Fprintln(w, "shipyard_applies_total 1")
+}
+
+func TestMetricsServerServesMetrics(t *testing.T) {
+	s := NewMetricsServer("127.0.0.1:0", &prometheusWriterMock{}, hclog.NewNullLogger())
+
+	err := s.Start()
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		s.Stop(context.Background())
+	})
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/metrics", s.Addr()))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	d, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+	assert.Equal(t, "shipyard_applies_total 1\n", string(d))
+}
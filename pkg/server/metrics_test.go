@@ -0,0 +1,134 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/connector/protos/shipyard"
+	assert "github.com/stretchr/testify/require"
+	"google.golang.org/grpc/stats"
+)
+
+type mockServiceLister struct {
+	services []*shipyard.Service
+	err      error
+}
+
+func (m *mockServiceLister) ListServices(ctx context.Context, _ *shipyard.NullMessage) (*shipyard.ListResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &shipyard.ListResponse{Services: m.services}, nil
+}
+
+func setupStatusAPI(t *testing.T, sl ServiceLister) (*API, *Metrics) {
+	m := NewMetrics()
+
+	api := New("127.0.0.1:0", hclog.NewNullLogger())
+	api.SetStatusSources(m, sl, nil)
+	api.Start()
+
+	t.Cleanup(api.Stop)
+
+	return api, m
+}
+
+func TestMetricsCountsReconnectsFromLog(t *testing.T) {
+	m := NewMetrics()
+
+	l := hclog.NewInterceptLogger(&hclog.LoggerOptions{Output: ioutil.Discard})
+	l.RegisterSink(m)
+
+	gl := l.Named("grpc_server")
+	gl.Info("local_server", "message", "Connecting to remote server", "addr", "10.0.0.1:9090")
+	gl.Error("local_server", "message", "Unable to open remote connection", "error", "boom")
+	gl.Info("local_server", "message", "Connecting to remote server", "addr", "10.0.0.1:9090")
+	gl.Info("local_server", "message", "Connecting to remote server", "addr", "10.0.0.2:9090")
+
+	assert.Equal(t, uint64(1), m.Reconnects())
+	assert.Equal(t, uint64(1), m.ConnectFailures())
+}
+
+func TestMetricsRecordsConnectionsAndBytes(t *testing.T) {
+	m := NewMetrics()
+
+	m.HandleConn(context.Background(), &stats.ConnBegin{})
+	m.HandleConn(context.Background(), &stats.ConnBegin{})
+	m.HandleConn(context.Background(), &stats.ConnEnd{})
+	m.HandleRPC(context.Background(), &stats.InPayload{Length: 10})
+	m.HandleRPC(context.Background(), &stats.OutPayload{Length: 20})
+
+	assert.Equal(t, int64(1), m.Connections())
+	assert.Equal(t, uint64(10), m.BytesReceived())
+	assert.Equal(t, uint64(20), m.BytesSent())
+}
+
+func TestMetricsWritesPrometheusFormat(t *testing.T) {
+	m := NewMetrics()
+	m.HandleRPC(context.Background(), &stats.InPayload{Length: 10})
+
+	b := &bytes.Buffer{}
+	m.WritePrometheus(b, &Status{ActiveTunnels: 2, StreamProxies: 1})
+
+	assert.Contains(t, b.String(), "# TYPE shipyard_connector_received_bytes_total counter\nshipyard_connector_received_bytes_total 10\n")
+	assert.Contains(t, b.String(), "shipyard_connector_active_tunnels 2\n")
+	assert.Contains(t, b.String(), "shipyard_connector_proxies{type=\"stream\"} 1\n")
+}
+
+func TestStatusReturnsTunnels(t *testing.T) {
+	api, _ := setupStatusAPI(t, &mockServiceLister{services: []*shipyard.Service{
+		{Id: "1", Name: "consul", Type: shipyard.ServiceType_REMOTE, Status: shipyard.ServiceStatus_COMPLETE, SourcePort: 8500},
+		{Id: "2", Name: "vault", Type: shipyard.ServiceType_LOCAL, Status: shipyard.ServiceStatus_ERROR},
+	}})
+
+	resp, err := api.app.Test(httptest.NewRequest("GET", "/status", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	st := &Status{}
+	err = json.NewDecoder(resp.Body).Decode(st)
+	assert.NoError(t, err)
+
+	assert.True(t, st.Healthy)
+	assert.Equal(t, 1, st.ActiveTunnels)
+	assert.Equal(t, 1, st.ErroredTunnels)
+	assert.Len(t, st.Tunnels, 2)
+	assert.Equal(t, "remote", st.Tunnels[0].Type)
+	assert.Equal(t, "complete", st.Tunnels[0].Status)
+}
+
+func TestHealthReturnsOKWhenHealthy(t *testing.T) {
+	api, _ := setupStatusAPI(t, &mockServiceLister{})
+
+	resp, err := api.app.Test(httptest.NewRequest("GET", "/health", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestHealthReturnsUnavailableWhenTunnelsCanNotBeListed(t *testing.T) {
+	api, _ := setupStatusAPI(t, &mockServiceLister{err: fmt.Errorf("boom")})
+
+	resp, err := api.app.Test(httptest.NewRequest("GET", "/health", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 503, resp.StatusCode)
+}
+
+func TestMetricsEndpointReturnsMetrics(t *testing.T) {
+	api, m := setupStatusAPI(t, &mockServiceLister{})
+	m.HandleRPC(context.Background(), &stats.OutPayload{Length: 20})
+
+	resp, err := api.app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	d, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), "shipyard_connector_sent_bytes_total 20")
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// Router is a reverse proxy which forwards requests to the destination
+// of the route matching the host and path of the request
+type Router struct {
+	name     string
+	bindAddr string
+	routes   []*route
+	server   *http.Server
+	log      hclog.Logger
+}
+
+type route struct {
+	host        string
+	path        string
+	stripPrefix bool
+	proxy       *httputil.ReverseProxy
+}
+
+// NewRouter creates a new Router which listens on bindAddr
+func NewRouter(name, bindAddr string, routes []config.Route, l hclog.Logger) (*Router, error) {
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("at least one route must be specified")
+	}
+
+	rs := []*route{}
+	for _, r := range routes {
+		dest := r.Destination
+		if !strings.HasPrefix(dest, "http://") && !strings.HasPrefix(dest, "https://") {
+			dest = "http://" + dest
+		}
+
+		u, err := url.Parse(dest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination %s: %s", r.Destination, err)
+		}
+
+		path := r.Path
+		if path == "" {
+			path = "/"
+		}
+
+		p := httputil.NewSingleHostReverseProxy(u)
+
+		// forward the original host so that the destination can generate
+		// links for the router origin
+		director := p.Director
+		p.Director = func(req *http.Request) {
+			host := req.Host
+			director(req)
+
+			req.Header.Set("X-Forwarded-Host", host)
+		}
+
+		rs = append(rs, &route{
+			host:        strings.ToLower(r.Host),
+			path:        path,
+			stripPrefix: r.StripPrefix,
+			proxy:       p,
+		})
+	}
+
+	// check the most specific routes first, routes with a host
+	// take precedence over routes with the same path which match all hosts
+	sort.SliceStable(rs, func(i, j int) bool {
+		if len(rs[i].path) != len(rs[j].path) {
+			return len(rs[i].path) > len(rs[j].path)
+		}
+
+		return rs[i].host != "" && rs[j].host == ""
+	})
+
+	return &Router{
+		name:     name,
+		bindAddr: bindAddr,
+		routes:   rs,
+		log:      l,
+	}, nil
+}
+
+// Start the router, Start does not block
+func (rt *Router) Start() error {
+	l, err := net.Listen("tcp", rt.bindAddr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %s", rt.bindAddr, err)
+	}
+
+	rt.server = &http.Server{Handler: rt}
+
+	go func() {
+		err := rt.server.Serve(l)
+		if err != nil && err != http.ErrServerClosed {
+			rt.log.Error("Router stopped", "name", rt.name, "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop the router
+func (rt *Router) Stop() error {
+	if rt.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return rt.server.Shutdown(ctx)
+}
+
+// ServeHTTP forwards the request to the destination of the first matching route
+func (rt *Router) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	ro := rt.match(r)
+	if ro == nil {
+		http.Error(rw, "No route for request", http.StatusNotFound)
+		return
+	}
+
+	if ro.stripPrefix && ro.path != "/" {
+		r.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(ro.path, "/")), "/")
+		r.URL.RawPath = ""
+	}
+
+	ro.proxy.ServeHTTP(rw, r)
+}
+
+// match returns the route for the request or nil when no route matches
+func (rt *Router) match(r *http.Request) *route {
+	host := strings.ToLower(r.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	for _, ro := range rt.routes {
+		if ro.host != "" && ro.host != host {
+			continue
+		}
+
+		if matchPath(ro.path, r.URL.Path) {
+			return ro
+		}
+	}
+
+	return nil
+}
+
+// matchPath returns true when the path is equal to the prefix or is a
+// sub path of the prefix, /api matches /api and /api/users but not /apis
+func matchPath(prefix, path string) bool {
+	if prefix == "/" || path == prefix {
+		return true
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	return strings.HasPrefix(path, prefix+"/")
+}
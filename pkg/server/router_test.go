@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupDestination(t *testing.T, name string) string {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(rw, "%s %s", name, r.URL.Path)
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return ts.URL
+}
+
+func setupRouter(t *testing.T) *Router {
+	routes := []config.Route{
+		{Destination: setupDestination(t, "web")},
+		{Path: "/api", Destination: setupDestination(t, "api"), StripPrefix: true},
+		{Path: "/api/payments", Destination: setupDestination(t, "payments")},
+		{Host: "auth.local.jmpd.in", Destination: setupDestination(t, "auth")},
+	}
+
+	r, err := NewRouter("test", "127.0.0.1:0", routes, hclog.NewNullLogger())
+	assert.NoError(t, err)
+
+	return r
+}
+
+func routeRequest(t *testing.T, r *Router, host, path string) (int, string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Host = host
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	b, err := ioutil.ReadAll(rr.Body)
+	assert.NoError(t, err)
+
+	return rr.Code, string(b)
+}
+
+func TestRouterReturnsErrorWithNoRoutes(t *testing.T) {
+	_, err := NewRouter("test", ":0", nil, hclog.NewNullLogger())
+	assert.Error(t, err)
+}
+
+func TestRouterRoutesToDefaultRoute(t *testing.T) {
+	r := setupRouter(t)
+
+	code, body := routeRequest(t, r, "localhost:8080", "/index.html")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "web /index.html", body)
+}
+
+func TestRouterRoutesToLongestPathAndStripsPrefix(t *testing.T) {
+	r := setupRouter(t)
+
+	_, body := routeRequest(t, r, "localhost:8080", "/api/users")
+	assert.Equal(t, "api /users", body)
+
+	_, body = routeRequest(t, r, "localhost:8080", "/api")
+	assert.Equal(t, "api /", body)
+
+	_, body = routeRequest(t, r, "localhost:8080", "/api/payments/1")
+	assert.Equal(t, "payments /api/payments/1", body)
+}
+
+func TestRouterDoesNotMatchPartialPathSegments(t *testing.T) {
+	r := setupRouter(t)
+
+	_, body := routeRequest(t, r, "localhost:8080", "/apis")
+	assert.Equal(t, "web /apis", body)
+}
+
+func TestRouterRoutesByHost(t *testing.T) {
+	r := setupRouter(t)
+
+	_, body := routeRequest(t, r, "auth.local.jmpd.in:8080", "/login")
+	assert.Equal(t, "auth /login", body)
+}
+
+func TestRouterReturnsNotFoundWhenNoRouteMatches(t *testing.T) {
+	r, err := NewRouter("test", ":0", []config.Route{{Path: "/api", Destination: "localhost:9090"}}, hclog.NewNullLogger())
+	assert.NoError(t, err)
+
+	code, _ := routeRequest(t, r, "localhost:8080", "/")
+	assert.Equal(t, http.StatusNotFound, code)
+}
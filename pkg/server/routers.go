@@ -0,0 +1,80 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// RouterRequest is the request to create a new Router
+type RouterRequest struct {
+	Name     string         `json:"name"`
+	BindAddr string         `json:"bind_addr"`
+	Routes   []config.Route `json:"routes"`
+}
+
+// RouterResponse is returned when a Router is created
+type RouterResponse struct {
+	ID string `json:"id"`
+}
+
+// createRouter starts a new Router, any existing router with the
+// same name is replaced
+func (s *API) createRouter(c *fiber.Ctx) error {
+	req := &RouterRequest{}
+	err := c.BodyParser(req)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if req.Name == "" || req.BindAddr == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "name and bind_addr must be specified")
+	}
+
+	r, err := NewRouter(req.Name, req.BindAddr, req.Routes, s.log.Named("router"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	s.routerLock.Lock()
+	defer s.routerLock.Unlock()
+
+	if er, ok := s.routers[req.Name]; ok {
+		er.Stop()
+		delete(s.routers, req.Name)
+	}
+
+	s.log.Info("Starting router", "name", req.Name, "bind_addr", req.BindAddr, "routes", len(req.Routes))
+
+	err = r.Start()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	s.routers[req.Name] = r
+
+	return c.JSON(RouterResponse{ID: req.Name})
+}
+
+// deleteRouter stops and removes a Router
+func (s *API) deleteRouter(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	s.routerLock.Lock()
+	defer s.routerLock.Unlock()
+
+	r, ok := s.routers[id]
+	if !ok {
+		return fiber.NewError(fiber.StatusNotFound, "router not found")
+	}
+
+	s.log.Info("Stopping router", "name", id)
+
+	err := r.Stop()
+	delete(s.routers, id)
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -1,8 +1,13 @@
 package server
 
 import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
 
 	"github.com/gofiber/websocket/v2"
 )
@@ -11,6 +16,33 @@ type API struct {
 	bindAddr string
 	app      *fiber.App
 	log      hclog.Logger
+
+	proxies   map[string]*AuthProxy
+	proxyLock sync.Mutex
+
+	routers    map[string]*Router
+	routerLock sync.Mutex
+
+	streams    map[string]*StreamProxy
+	streamLock sync.Mutex
+
+	socks     map[string]*SocksProxy
+	socksLock sync.Mutex
+
+	exposer ServiceExposer
+
+	joiner   Joiner
+	certsDir string
+
+	envAddr   string
+	envTLS    *tls.Config
+	envServer *http.Server
+	statePath string
+	history   clients.History
+
+	metrics  *Metrics
+	services ServiceLister
+	certs    *Certificates
 }
 
 // New creates a new server
@@ -23,9 +55,28 @@ func New(addr string, l hclog.Logger) *API {
 		bindAddr: addr,
 		app:      fiber.New(config),
 		log:      l,
+		proxies:  map[string]*AuthProxy{},
+		routers:  map[string]*Router{},
+		streams:  map[string]*StreamProxy{},
+		socks:    map[string]*SocksProxy{},
 	}
 }
 
+// SetServiceExposer sets the client used by SOCKS proxies to create
+// tunnels through the connector
+func (s *API) SetServiceExposer(e ServiceExposer) {
+	s.exposer = e
+}
+
+// SetStatusSources sets the metrics, the gRPC server which lists the tunnels, and the
+// certificates which are reported by the health, status, and metrics endpoints, any
+// of the sources can be nil
+func (s *API) SetStatusSources(m *Metrics, sl ServiceLister, c *Certificates) {
+	s.metrics = m
+	s.services = sl
+	s.certs = c
+}
+
 // Start the API server
 func (s *API) Start() {
 	s.log.Debug("Starting API server")
@@ -42,6 +93,27 @@ func (s *API) Start() {
 
 	s.app.Get("/terminal", websocket.New(s.terminalWebsocket))
 
+	s.app.Get("/health", s.health)
+	s.app.Get("/status", s.getStatus)
+	s.app.Get("/metrics", s.getMetrics)
+
+	s.app.Post("/auth_proxies", s.createAuthProxy)
+	s.app.Delete("/auth_proxies/:id", s.deleteAuthProxy)
+
+	s.app.Post("/routers", s.createRouter)
+	s.app.Delete("/routers/:id", s.deleteRouter)
+
+	s.app.Post("/stream_proxies", s.createStreamProxy)
+	s.app.Delete("/stream_proxies/:id", s.deleteStreamProxy)
+
+	s.app.Post("/socks_proxies", s.createSocksProxy)
+	s.app.Delete("/socks_proxies/:id", s.deleteSocksProxy)
+
+	s.app.Post("/join", s.join)
+
+	// the environment is only served to joined connectors over mTLS
+	s.startEnvironmentServer()
+
 	// Start the server but do not block
 	go s.app.Listen(s.bindAddr)
 }
@@ -49,4 +121,36 @@ func (s *API) Start() {
 // Stop the API server
 func (s *API) Stop() {
 	s.app.Shutdown()
+
+	if s.envServer != nil {
+		s.envServer.Close()
+	}
+
+	s.proxyLock.Lock()
+	defer s.proxyLock.Unlock()
+
+	for _, p := range s.proxies {
+		p.Stop()
+	}
+
+	s.routerLock.Lock()
+	defer s.routerLock.Unlock()
+
+	for _, r := range s.routers {
+		r.Stop()
+	}
+
+	s.streamLock.Lock()
+	defer s.streamLock.Unlock()
+
+	for _, p := range s.streams {
+		p.Stop()
+	}
+
+	s.socksLock.Lock()
+	defer s.socksLock.Unlock()
+
+	for _, p := range s.socks {
+		p.Stop()
+	}
 }
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// SocksProxyRequest is the request to create a new SocksProxy
+type SocksProxyRequest struct {
+	Name          string `json:"name"`
+	BindAddr      string `json:"bind_addr"`
+	ConnectorAddr string `json:"connector_addr"`
+}
+
+// SocksProxyResponse is returned when a SocksProxy is created
+type SocksProxyResponse struct {
+	ID string `json:"id"`
+}
+
+// createSocksProxy starts a new SocksProxy, any existing proxy with the
+// same name is replaced
+func (s *API) createSocksProxy(c *fiber.Ctx) error {
+	req := &SocksProxyRequest{}
+	err := c.BodyParser(req)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if req.Name == "" || req.BindAddr == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "name and bind_addr must be specified")
+	}
+
+	p, err := NewSocksProxy(req.Name, req.BindAddr, req.ConnectorAddr, s.exposer, s.log.Named("socks_proxy"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	s.socksLock.Lock()
+	defer s.socksLock.Unlock()
+
+	if ep, ok := s.socks[req.Name]; ok {
+		ep.Stop()
+		delete(s.socks, req.Name)
+	}
+
+	s.log.Info("Starting SOCKS proxy", "name", req.Name, "bind_addr", req.BindAddr, "connector_addr", req.ConnectorAddr)
+
+	err = p.Start()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	s.socks[req.Name] = p
+
+	return c.JSON(SocksProxyResponse{ID: req.Name})
+}
+
+// deleteSocksProxy stops and removes a SocksProxy
+func (s *API) deleteSocksProxy(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	s.socksLock.Lock()
+	defer s.socksLock.Unlock()
+
+	p, ok := s.socks[id]
+	if !ok {
+		return fiber.NewError(fiber.StatusNotFound, "socks proxy not found")
+	}
+
+	s.log.Info("Stopping SOCKS proxy", "name", id)
+
+	err := p.Stop()
+	delete(s.socks, id)
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,514 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	socksVersion = 0x05
+
+	socksMethodNoAuth       = 0x00
+	socksMethodNoAcceptable = 0xff
+
+	socksCmdConnect      = 0x01
+	socksCmdUDPAssociate = 0x03
+
+	socksAtypIPv4   = 0x01
+	socksAtypDomain = 0x03
+	socksAtypIPv6   = 0x04
+
+	socksRepSuccess             = 0x00
+	socksRepGeneralFailure      = 0x01
+	socksRepHostUnreachable     = 0x04
+	socksRepCmdNotSupported     = 0x07
+	socksRepAddrTypeUnsupported = 0x08
+)
+
+// ServiceExposer exposes a service from a remote connector on a local port,
+// it is implemented by the connector client
+type ServiceExposer interface {
+	ExposeService(name string, port int, remoteAddr, destAddr, direction string) (string, error)
+	RemoveService(id string) error
+}
+
+// SocksProxy is a SOCKS5 server which allows tools on the local machine to reach
+// any address through a single port. When a remote connector address is set TCP
+// connections are tunneled through the connector so that addresses inside a
+// cluster can be reached. UDP associations are relayed from the local machine
+// as the connector tunnel only supports TCP
+type SocksProxy struct {
+	name          string
+	bindAddr      string
+	connectorAddr string
+	exposer       ServiceExposer
+	log           hclog.Logger
+
+	listener net.Listener
+
+	tunnelLock sync.Mutex
+	tunnels    map[string]socksTunnel
+}
+
+// socksTunnel is a service exposed in the connector for a destination
+type socksTunnel struct {
+	id   string
+	addr string
+}
+
+// NewSocksProxy creates a new SocksProxy which listens on bindAddr, when
+// connectorAddr is set TCP connections are tunneled through the remote connector
+// using the given ServiceExposer
+func NewSocksProxy(name, bindAddr, connectorAddr string, e ServiceExposer, l hclog.Logger) (*SocksProxy, error) {
+	if connectorAddr != "" && e == nil {
+		return nil, fmt.Errorf("unable to tunnel connections to %s, the connector client has not been configured", connectorAddr)
+	}
+
+	return &SocksProxy{
+		name:          name,
+		bindAddr:      bindAddr,
+		connectorAddr: connectorAddr,
+		exposer:       e,
+		log:           l,
+		tunnels:       map[string]socksTunnel{},
+	}, nil
+}
+
+// Start the proxy, Start does not block
+func (s *SocksProxy) Start() error {
+	l, err := net.Listen("tcp", s.bindAddr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %s", s.bindAddr, err)
+	}
+
+	s.listener = l
+	go s.serve()
+
+	return nil
+}
+
+// Stop the proxy and remove any tunnels created in the connector
+func (s *SocksProxy) Stop() error {
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+
+	s.tunnelLock.Lock()
+	defer s.tunnelLock.Unlock()
+
+	for dest, t := range s.tunnels {
+		rerr := s.exposer.RemoveService(t.id)
+		if rerr != nil {
+			s.log.Warn("Unable to remove tunnel", "name", s.name, "destination", dest, "error", rerr)
+		}
+
+		delete(s.tunnels, dest)
+	}
+
+	return err
+}
+
+func (s *SocksProxy) serve() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			// the listener has been closed
+			return
+		}
+
+		go s.handle(c)
+	}
+}
+
+func (s *SocksProxy) handle(c net.Conn) {
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+
+	err := s.negotiate(r, c)
+	if err != nil {
+		s.log.Debug("Unable to negotiate SOCKS connection", "name", s.name, "client", c.RemoteAddr(), "error", err)
+		return
+	}
+
+	// read the request VER CMD RSV ATYP DST.ADDR DST.PORT
+	h := make([]byte, 3)
+	_, err = io.ReadFull(r, h)
+	if err != nil || h[0] != socksVersion {
+		return
+	}
+
+	dest, err := readSocksAddr(r)
+	if err != nil {
+		s.log.Debug("Unable to read destination", "name", s.name, "client", c.RemoteAddr(), "error", err)
+		writeSocksReply(c, socksRepAddrTypeUnsupported, nil)
+		return
+	}
+
+	switch h[1] {
+	case socksCmdConnect:
+		s.handleConnect(c, r, dest)
+	case socksCmdUDPAssociate:
+		s.handleUDPAssociate(c, r)
+	default:
+		writeSocksReply(c, socksRepCmdNotSupported, nil)
+	}
+}
+
+// negotiate the authentication method, only unauthenticated connections
+// are supported as the proxy is only intended for the local machine
+func (s *SocksProxy) negotiate(r *bufio.Reader, w io.Writer) error {
+	h := make([]byte, 2)
+	_, err := io.ReadFull(r, h)
+	if err != nil {
+		return err
+	}
+
+	if h[0] != socksVersion {
+		return fmt.Errorf("unsupported SOCKS version %d", h[0])
+	}
+
+	methods := make([]byte, h[1])
+	_, err = io.ReadFull(r, methods)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range methods {
+		if m == socksMethodNoAuth {
+			_, err = w.Write([]byte{socksVersion, socksMethodNoAuth})
+			return err
+		}
+	}
+
+	w.Write([]byte{socksVersion, socksMethodNoAcceptable})
+
+	return fmt.Errorf("client does not support unauthenticated connections")
+}
+
+func (s *SocksProxy) handleConnect(c net.Conn, r io.Reader, dest string) {
+	s.log.Debug("Connecting", "name", s.name, "client", c.RemoteAddr(), "destination", dest)
+
+	u, err := s.dial(dest)
+	if err != nil {
+		s.log.Error("Unable to connect to destination", "name", s.name, "destination", dest, "error", err)
+		writeSocksReply(c, socksRepHostUnreachable, nil)
+		return
+	}
+	defer u.Close()
+
+	err = writeSocksReply(c, socksRepSuccess, u.LocalAddr())
+	if err != nil {
+		return
+	}
+
+	done := make(chan struct{}, 2)
+
+	go func() {
+		// use the buffered reader as it may contain data sent after the request
+		io.Copy(u, r)
+		done <- struct{}{}
+	}()
+
+	go func() {
+		io.Copy(c, u)
+		done <- struct{}{}
+	}()
+
+	// close both connections when either side finishes
+	<-done
+}
+
+// dial the destination, when a connector address is set the connection is
+// made through a tunnel in the connector
+func (s *SocksProxy) dial(dest string) (net.Conn, error) {
+	if s.connectorAddr == "" {
+		return net.DialTimeout("tcp", dest, 10*time.Second)
+	}
+
+	addr, err := s.tunnel(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	// the tunnel is established in the background, retry until it is ready
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		u, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			return u, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// tunnel returns the local address of the tunnel for the destination,
+// the tunnel is created in the connector the first time it is requested
+func (s *SocksProxy) tunnel(dest string) (string, error) {
+	s.tunnelLock.Lock()
+	defer s.tunnelLock.Unlock()
+
+	if t, ok := s.tunnels[dest]; ok {
+		return t.addr, nil
+	}
+
+	port, err := freeLocalPort()
+	if err != nil {
+		return "", err
+	}
+
+	id, err := s.exposer.ExposeService(
+		fmt.Sprintf("%s-%d", s.name, port),
+		port,
+		s.connectorAddr,
+		dest,
+		"remote",
+	)
+
+	if err != nil {
+		return "", fmt.Errorf("unable to create tunnel to %s: %s", dest, err)
+	}
+
+	t := socksTunnel{id: id, addr: fmt.Sprintf("127.0.0.1:%d", port)}
+	s.tunnels[dest] = t
+
+	return t.addr, nil
+}
+
+// handleUDPAssociate relays datagrams for the client until the control
+// connection is closed
+func (s *SocksProxy) handleUDPAssociate(c net.Conn, r io.Reader) {
+	host, _, _ := net.SplitHostPort(c.LocalAddr().String())
+
+	// the client sends datagrams to the relay, the relay forwards them from
+	// the upstream connection
+	relay, err := net.ListenPacket("udp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		s.log.Error("Unable to create UDP relay", "name", s.name, "error", err)
+		writeSocksReply(c, socksRepGeneralFailure, nil)
+		return
+	}
+	defer relay.Close()
+
+	upstream, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		s.log.Error("Unable to create UDP relay", "name", s.name, "error", err)
+		writeSocksReply(c, socksRepGeneralFailure, nil)
+		return
+	}
+	defer upstream.Close()
+
+	err = writeSocksReply(c, socksRepSuccess, relay.LocalAddr())
+	if err != nil {
+		return
+	}
+
+	s.log.Debug("Relaying UDP", "name", s.name, "client", c.RemoteAddr(), "relay", relay.LocalAddr())
+
+	clientHost, _, _ := net.SplitHostPort(c.RemoteAddr().String())
+
+	var clientLock sync.Mutex
+	var client net.Addr
+
+	// datagrams from the client
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := relay.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			// only accept datagrams from the host which owns the association
+			if h, _, _ := net.SplitHostPort(addr.String()); h != clientHost {
+				continue
+			}
+
+			clientLock.Lock()
+			client = addr
+			clientLock.Unlock()
+
+			dest, data, err := parseSocksDatagram(buf[:n])
+			if err != nil {
+				s.log.Debug("Dropping datagram", "name", s.name, "client", addr, "error", err)
+				continue
+			}
+
+			ua, err := net.ResolveUDPAddr("udp", dest)
+			if err != nil {
+				s.log.Debug("Unable to resolve destination", "name", s.name, "destination", dest, "error", err)
+				continue
+			}
+
+			upstream.WriteTo(data, ua)
+		}
+	}()
+
+	// datagrams from the destinations
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := upstream.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			clientLock.Lock()
+			ca := client
+			clientLock.Unlock()
+
+			if ca == nil {
+				continue
+			}
+
+			relay.WriteTo(append(socksDatagramHeader(addr), buf[:n]...), ca)
+		}
+	}()
+
+	// the association ends when the control connection is closed
+	io.Copy(io.Discard, r)
+}
+
+// readSocksAddr reads ATYP DST.ADDR DST.PORT and returns the address as host:port
+func readSocksAddr(r io.Reader) (string, error) {
+	t := make([]byte, 1)
+	_, err := io.ReadFull(r, t)
+	if err != nil {
+		return "", err
+	}
+
+	var host string
+	switch t[0] {
+	case socksAtypIPv4, socksAtypIPv6:
+		ip := make([]byte, net.IPv4len)
+		if t[0] == socksAtypIPv6 {
+			ip = make([]byte, net.IPv6len)
+		}
+
+		_, err = io.ReadFull(r, ip)
+		if err != nil {
+			return "", err
+		}
+
+		host = net.IP(ip).String()
+	case socksAtypDomain:
+		l := make([]byte, 1)
+		_, err = io.ReadFull(r, l)
+		if err != nil {
+			return "", err
+		}
+
+		d := make([]byte, l[0])
+		_, err = io.ReadFull(r, d)
+		if err != nil {
+			return "", err
+		}
+
+		host = string(d)
+	default:
+		return "", fmt.Errorf("unsupported address type %d", t[0])
+	}
+
+	p := make([]byte, 2)
+	_, err = io.ReadFull(r, p)
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(p)))), nil
+}
+
+// encodeSocksAddr returns ATYP BND.ADDR BND.PORT for the address
+func encodeSocksAddr(addr net.Addr) []byte {
+	ip := net.IPv4zero
+	port := 0
+
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip, port = a.IP, a.Port
+	case *net.UDPAddr:
+		ip, port = a.IP, a.Port
+	}
+
+	var b []byte
+	if ip4 := ip.To4(); ip4 != nil {
+		b = append([]byte{socksAtypIPv4}, ip4...)
+	} else {
+		b = append([]byte{socksAtypIPv6}, ip.To16()...)
+	}
+
+	return append(b, byte(port>>8), byte(port))
+}
+
+func writeSocksReply(w io.Writer, rep byte, addr net.Addr) error {
+	_, err := w.Write(append([]byte{socksVersion, rep, 0x00}, encodeSocksAddr(addr)...))
+	return err
+}
+
+// parseSocksDatagram returns the destination and the data from a datagram
+// with the header RSV FRAG ATYP DST.ADDR DST.PORT
+func parseSocksDatagram(b []byte) (string, []byte, error) {
+	if len(b) < 4 {
+		return "", nil, fmt.Errorf("datagram is too short")
+	}
+
+	if b[2] != 0x00 {
+		return "", nil, fmt.Errorf("fragmented datagrams are not supported")
+	}
+
+	r := &byteCounter{b: b[3:]}
+
+	dest, err := readSocksAddr(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return dest, b[3+r.n:], nil
+}
+
+// socksDatagramHeader returns the header for a datagram received from addr
+func socksDatagramHeader(addr net.Addr) []byte {
+	return append([]byte{0x00, 0x00, 0x00}, encodeSocksAddr(addr)...)
+}
+
+// byteCounter is a reader which records the number of bytes read
+type byteCounter struct {
+	b []byte
+	n int
+}
+
+func (b *byteCounter) Read(p []byte) (int, error) {
+	if b.n >= len(b.b) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, b.b[b.n:])
+	b.n += n
+
+	return n, nil
+}
+
+// freeLocalPort returns a port which is free on the local machine
+func freeLocalPort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	assert "github.com/stretchr/testify/require"
+)
+
+// exposerMock creates a tcp proxy for each exposed service
+type exposerMock struct {
+	lock    sync.Mutex
+	exposed []string
+	removed []string
+	proxies map[string]*StreamProxy
+}
+
+func (e *exposerMock) ExposeService(name string, port int, remoteAddr, destAddr, direction string) (string, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	p, err := NewStreamProxy(name, "tcp", fmt.Sprintf("127.0.0.1:%d", port), destAddr, "", "", hclog.NewNullLogger())
+	if err != nil {
+		return "", err
+	}
+
+	err = p.Start()
+	if err != nil {
+		return "", err
+	}
+
+	e.exposed = append(e.exposed, destAddr)
+	e.proxies[name] = p
+
+	return name, nil
+}
+
+func (e *exposerMock) RemoveService(id string) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.removed = append(e.removed, id)
+
+	return e.proxies[id].Stop()
+}
+
+func startSocksProxy(t *testing.T, connectorAddr string, e ServiceExposer) *SocksProxy {
+	p, err := NewSocksProxy("test", "127.0.0.1:0", connectorAddr, e, hclog.NewNullLogger())
+	assert.NoError(t, err)
+
+	err = p.Start()
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { p.Stop() })
+
+	return p
+}
+
+// socksRequest connects to the proxy and sends a request for the given command,
+// returns the connection and the bound address from the reply
+func socksRequest(t *testing.T, p *SocksProxy, cmd byte, dest string) (net.Conn, string) {
+	c, err := net.Dial("tcp", p.listener.Addr().String())
+	assert.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+
+	c.Write([]byte{socksVersion, 1, socksMethodNoAuth})
+
+	m := make([]byte, 2)
+	_, err = io.ReadFull(c, m)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{socksVersion, socksMethodNoAuth}, m)
+
+	host, port, _ := net.SplitHostPort(dest)
+	pn, _ := strconv.Atoi(port)
+
+	req := []byte{socksVersion, cmd, 0x00, socksAtypDomain, byte(len(host))}
+	req = append(req, []byte(host)...)
+	req = append(req, byte(pn>>8), byte(pn))
+	c.Write(req)
+
+	r := make([]byte, 3)
+	_, err = io.ReadFull(c, r)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(socksRepSuccess), r[1])
+
+	bound, err := readSocksAddr(c)
+	assert.NoError(t, err)
+
+	return c, bound
+}
+
+func TestSocksProxyReturnsErrorWithConnectorAndNoExposer(t *testing.T) {
+	_, err := NewSocksProxy("test", ":0", "localhost:30001", nil, hclog.NewNullLogger())
+	assert.Error(t, err)
+}
+
+func TestSocksProxyRejectsAuthenticatedClients(t *testing.T) {
+	p := startSocksProxy(t, "", nil)
+
+	c, err := net.Dial("tcp", p.listener.Addr().String())
+	assert.NoError(t, err)
+	defer c.Close()
+
+	// only offer username and password authentication
+	c.Write([]byte{socksVersion, 1, 0x02})
+
+	m := make([]byte, 2)
+	_, err = io.ReadFull(c, m)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{socksVersion, socksMethodNoAcceptable}, m)
+}
+
+func TestSocksProxyConnectsDirectly(t *testing.T) {
+	p := startSocksProxy(t, "", nil)
+
+	c, _ := socksRequest(t, p, socksCmdConnect, setupTCPUpstream(t))
+
+	fmt.Fprint(c, "hello\n")
+
+	resp, err := bufio.NewReader(c).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "upstream hello\n", resp)
+}
+
+func TestSocksProxyConnectsThroughTunnel(t *testing.T) {
+	e := &exposerMock{proxies: map[string]*StreamProxy{}}
+	p := startSocksProxy(t, "localhost:30001", e)
+	upstream := setupTCPUpstream(t)
+
+	for i := 0; i < 2; i++ {
+		c, _ := socksRequest(t, p, socksCmdConnect, upstream)
+
+		fmt.Fprint(c, "hello\n")
+
+		resp, err := bufio.NewReader(c).ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, "upstream hello\n", resp)
+	}
+
+	// the tunnel is reused for the same destination
+	assert.Equal(t, []string{upstream}, e.exposed)
+
+	p.Stop()
+	assert.Len(t, e.removed, 1)
+}
+
+func TestSocksProxyRelaysUDP(t *testing.T) {
+	p := startSocksProxy(t, "", nil)
+	upstream := setupUDPUpstream(t)
+
+	_, relay := socksRequest(t, p, socksCmdUDPAssociate, "0.0.0.0:0")
+
+	c, err := net.Dial("udp", relay)
+	assert.NoError(t, err)
+	defer c.Close()
+
+	ua, _ := net.ResolveUDPAddr("udp", upstream)
+	d := append(socksDatagramHeader(ua), []byte("hello")...)
+
+	_, err = c.Write(d)
+	assert.NoError(t, err)
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 1024)
+	n, err := c.Read(buf)
+	assert.NoError(t, err)
+
+	from, data, err := parseSocksDatagram(buf[:n])
+	assert.NoError(t, err)
+	assert.Equal(t, upstream, from)
+	assert.Equal(t, "upstream hello", string(data))
+}
+
+func TestParseSocksDatagramRejectsFragments(t *testing.T) {
+	d := []byte{0x00, 0x00, 0x01, socksAtypIPv4, 127, 0, 0, 1, 0, 0}
+	binary.BigEndian.PutUint16(d[8:], 53)
+
+	_, _, err := parseSocksDatagram(d)
+	assert.Error(t, err)
+}
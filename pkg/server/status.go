@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shipyard-run/connector/protos/shipyard"
+)
+
+// ServiceLister lists the tunnels of the connector, implemented by the gRPC server
+type ServiceLister interface {
+	ListServices(ctx context.Context, m *shipyard.NullMessage) (*shipyard.ListResponse, error)
+}
+
+// TunnelStatus is the status of a tunnel between two connectors
+type TunnelStatus struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Status          string `json:"status"`
+	SourcePort      int    `json:"source_port"`
+	RemoteAddr      string `json:"remote_addr"`
+	DestinationAddr string `json:"destination_addr"`
+}
+
+// Status is the structured status of the connector returned by the /status endpoint
+type Status struct {
+	Healthy           bool           `json:"healthy"`
+	Error             string         `json:"error,omitempty"`
+	Uptime            time.Duration  `json:"uptime"`
+	Connections       int64          `json:"connections"`
+	BytesReceived     uint64         `json:"bytes_received"`
+	BytesSent         uint64         `json:"bytes_sent"`
+	Reconnects        uint64         `json:"reconnects"`
+	ConnectFailures   uint64         `json:"connect_failures"`
+	ActiveTunnels     int            `json:"active_tunnels"`
+	ErroredTunnels    int            `json:"errored_tunnels"`
+	Tunnels           []TunnelStatus `json:"tunnels"`
+	AuthProxies       int            `json:"auth_proxies"`
+	Routers           int            `json:"routers"`
+	StreamProxies     int            `json:"stream_proxies"`
+	SocksProxies      int            `json:"socks_proxies"`
+	CertificateExpiry *time.Time     `json:"certificate_expiry,omitempty"`
+}
+
+// status builds the status of the connector, the connector is healthy
+// when the tunnels can be listed from the gRPC server
+func (s *API) status() *Status {
+	st := &Status{Healthy: true, Tunnels: []TunnelStatus{}}
+
+	if s.metrics != nil {
+		st.Uptime = s.metrics.Uptime()
+		st.Connections = s.metrics.Connections()
+		st.BytesReceived = s.metrics.BytesReceived()
+		st.BytesSent = s.metrics.BytesSent()
+		st.Reconnects = s.metrics.Reconnects()
+		st.ConnectFailures = s.metrics.ConnectFailures()
+	}
+
+	if s.services != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		resp, err := s.services.ListServices(ctx, &shipyard.NullMessage{})
+		if err != nil {
+			st.Healthy = false
+			st.Error = err.Error()
+		}
+
+		if resp != nil {
+			for _, svc := range resp.Services {
+				switch svc.Status {
+				case shipyard.ServiceStatus_COMPLETE:
+					st.ActiveTunnels++
+				case shipyard.ServiceStatus_ERROR:
+					st.ErroredTunnels++
+				}
+
+				st.Tunnels = append(st.Tunnels, TunnelStatus{
+					ID:              svc.Id,
+					Name:            svc.Name,
+					Type:            strings.ToLower(svc.Type.String()),
+					Status:          strings.ToLower(svc.Status.String()),
+					SourcePort:      int(svc.SourcePort),
+					RemoteAddr:      svc.RemoteConnectorAddr,
+					DestinationAddr: svc.DestinationAddr,
+				})
+			}
+		}
+	}
+
+	if s.certs != nil {
+		exp := s.certs.Expiry()
+		st.CertificateExpiry = &exp
+	}
+
+	s.proxyLock.Lock()
+	st.AuthProxies = len(s.proxies)
+	s.proxyLock.Unlock()
+
+	s.routerLock.Lock()
+	st.Routers = len(s.routers)
+	s.routerLock.Unlock()
+
+	s.streamLock.Lock()
+	st.StreamProxies = len(s.streams)
+	s.streamLock.Unlock()
+
+	s.socksLock.Lock()
+	st.SocksProxies = len(s.socks)
+	s.socksLock.Unlock()
+
+	return st
+}
+
+// health returns 200 when the connector is healthy and 503 when it is not
+func (s *API) health(c *fiber.Ctx) error {
+	st := s.status()
+	if !st.Healthy {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "unhealthy", "error": st.Error})
+	}
+
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// getStatus returns the structured status of the connector
+func (s *API) getStatus(c *fiber.Ctx) error {
+	return c.JSON(s.status())
+}
+
+// getMetrics returns the metrics of the connector in the Prometheus format
+func (s *API) getMetrics(c *fiber.Ctx) error {
+	m := s.metrics
+	if m == nil {
+		m = NewMetrics()
+	}
+
+	b := &bytes.Buffer{}
+	m.WritePrometheus(b, s.status())
+
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4")
+	return c.Send(b.Bytes())
+}
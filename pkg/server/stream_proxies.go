@@ -0,0 +1,82 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// StreamProxyRequest is the request to create a new StreamProxy
+type StreamProxyRequest struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+	BindAddr string `json:"bind_addr"`
+	Upstream string `json:"upstream"`
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+}
+
+// StreamProxyResponse is returned when a StreamProxy is created
+type StreamProxyResponse struct {
+	ID string `json:"id"`
+}
+
+// createStreamProxy starts a new StreamProxy, any existing proxy with the
+// same name is replaced
+func (s *API) createStreamProxy(c *fiber.Ctx) error {
+	req := &StreamProxyRequest{}
+	err := c.BodyParser(req)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if req.Name == "" || req.BindAddr == "" || req.Upstream == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "name, bind_addr, and upstream must be specified")
+	}
+
+	p, err := NewStreamProxy(req.Name, req.Protocol, req.BindAddr, req.Upstream, req.CertFile, req.KeyFile, s.log.Named("stream_proxy"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	s.streamLock.Lock()
+	defer s.streamLock.Unlock()
+
+	if ep, ok := s.streams[req.Name]; ok {
+		ep.Stop()
+		delete(s.streams, req.Name)
+	}
+
+	s.log.Info("Starting stream proxy", "name", req.Name, "protocol", p.protocol, "bind_addr", req.BindAddr, "upstream", req.Upstream, "tls", p.tls != nil)
+
+	err = p.Start()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	s.streams[req.Name] = p
+
+	return c.JSON(StreamProxyResponse{ID: req.Name})
+}
+
+// deleteStreamProxy stops and removes a StreamProxy
+func (s *API) deleteStreamProxy(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	s.streamLock.Lock()
+	defer s.streamLock.Unlock()
+
+	p, ok := s.streams[id]
+	if !ok {
+		return fiber.NewError(fiber.StatusNotFound, "stream proxy not found")
+	}
+
+	s.log.Info("Stopping stream proxy", "name", id)
+
+	err := p.Stop()
+	delete(s.streams, id)
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// udpIdleTimeout is the time after which an inactive UDP session is closed
+const udpIdleTimeout = 60 * time.Second
+
+// StreamProxy forwards raw TCP connections or UDP datagrams to the upstream
+// address, TCP connections can optionally be terminated with TLS
+type StreamProxy struct {
+	name     string
+	protocol string
+	bindAddr string
+	upstream string
+	tls      *tls.Config
+	log      hclog.Logger
+
+	listener net.Listener
+	packet   net.PacketConn
+
+	sessionLock sync.Mutex
+	sessions    map[string]net.Conn
+}
+
+// NewStreamProxy creates a new StreamProxy which listens on bindAddr using the given
+// protocol, tcp or udp. When certFile and keyFile are set TLS is terminated by the proxy
+func NewStreamProxy(name, protocol, bindAddr, upstream, certFile, keyFile string, l hclog.Logger) (*StreamProxy, error) {
+	if protocol == "" {
+		protocol = "tcp"
+	}
+
+	if protocol != "tcp" && protocol != "udp" {
+		return nil, fmt.Errorf("invalid protocol %s, protocol must be tcp or udp", protocol)
+	}
+
+	p := &StreamProxy{
+		name:     name,
+		protocol: protocol,
+		bindAddr: bindAddr,
+		upstream: upstream,
+		log:      l,
+		sessions: map[string]net.Conn{},
+	}
+
+	if certFile != "" || keyFile != "" {
+		if protocol != "tcp" {
+			return nil, fmt.Errorf("TLS can only be terminated for tcp")
+		}
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load TLS certificate: %s", err)
+		}
+
+		p.tls = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	return p, nil
+}
+
+// Start the proxy, Start does not block
+func (s *StreamProxy) Start() error {
+	if s.protocol == "udp" {
+		pc, err := net.ListenPacket("udp", s.bindAddr)
+		if err != nil {
+			return fmt.Errorf("unable to listen on %s: %s", s.bindAddr, err)
+		}
+
+		s.packet = pc
+		go s.serveUDP()
+
+		return nil
+	}
+
+	l, err := net.Listen("tcp", s.bindAddr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %s", s.bindAddr, err)
+	}
+
+	if s.tls != nil {
+		l = tls.NewListener(l, s.tls)
+	}
+
+	s.listener = l
+	go s.serveTCP()
+
+	return nil
+}
+
+// Stop the proxy
+func (s *StreamProxy) Stop() error {
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+
+	if s.packet != nil {
+		s.sessionLock.Lock()
+		for _, c := range s.sessions {
+			c.Close()
+		}
+		s.sessionLock.Unlock()
+
+		return s.packet.Close()
+	}
+
+	return nil
+}
+
+func (s *StreamProxy) serveTCP() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			// the listener has been closed
+			return
+		}
+
+		go s.handleTCP(c)
+	}
+}
+
+func (s *StreamProxy) handleTCP(c net.Conn) {
+	defer c.Close()
+
+	u, err := net.DialTimeout("tcp", s.upstream, 10*time.Second)
+	if err != nil {
+		s.log.Error("Unable to connect to upstream", "name", s.name, "upstream", s.upstream, "error", err)
+		return
+	}
+	defer u.Close()
+
+	done := make(chan struct{}, 2)
+
+	go func() {
+		io.Copy(u, c)
+		done <- struct{}{}
+	}()
+
+	go func() {
+		io.Copy(c, u)
+		done <- struct{}{}
+	}()
+
+	// close both connections when either side finishes
+	<-done
+}
+
+func (s *StreamProxy) serveUDP() {
+	buf := make([]byte, 65535)
+
+	for {
+		n, addr, err := s.packet.ReadFrom(buf)
+		if err != nil {
+			// the listener has been closed
+			return
+		}
+
+		u, err := s.udpSession(addr)
+		if err != nil {
+			s.log.Error("Unable to connect to upstream", "name", s.name, "upstream", s.upstream, "error", err)
+			continue
+		}
+
+		u.SetDeadline(time.Now().Add(udpIdleTimeout))
+
+		_, err = u.Write(buf[:n])
+		if err != nil {
+			s.log.Debug("Unable to write to upstream", "name", s.name, "upstream", s.upstream, "error", err)
+		}
+	}
+}
+
+// udpSession returns the upstream connection for the client, responses from the
+// upstream are written back to the client until the session is idle
+func (s *StreamProxy) udpSession(addr net.Addr) (net.Conn, error) {
+	s.sessionLock.Lock()
+	defer s.sessionLock.Unlock()
+
+	if u, ok := s.sessions[addr.String()]; ok {
+		return u, nil
+	}
+
+	u, err := net.Dial("udp", s.upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	s.sessions[addr.String()] = u
+
+	go func() {
+		buf := make([]byte, 65535)
+
+		for {
+			n, err := u.Read(buf)
+			if err != nil {
+				break
+			}
+
+			u.SetDeadline(time.Now().Add(udpIdleTimeout))
+			s.packet.WriteTo(buf[:n], addr)
+		}
+
+		s.sessionLock.Lock()
+		delete(s.sessions, addr.String())
+		s.sessionLock.Unlock()
+
+		u.Close()
+	}()
+
+	return u, nil
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/connector/crypto"
+	assert "github.com/stretchr/testify/require"
+)
+
+// setupTCPUpstream starts a tcp server which echos lines prefixed with upstream
+func setupTCPUpstream(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer c.Close()
+
+				line, _ := bufio.NewReader(c).ReadString('\n')
+				fmt.Fprintf(c, "upstream %s", line)
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+// setupUDPUpstream starts a udp server which echos datagrams prefixed with upstream
+func setupUDPUpstream(t *testing.T) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			pc.WriteTo([]byte("upstream "+string(buf[:n])), addr)
+		}
+	}()
+
+	return pc.LocalAddr().String()
+}
+
+// setupCertificates generates a CA and a leaf certificate for localhost
+func setupCertificates(t *testing.T) (*x509.CertPool, string, string) {
+	dir := t.TempDir()
+
+	rk, err := crypto.GenerateKeyPair()
+	assert.NoError(t, err)
+
+	ca, err := crypto.GenerateCA(rk.Private)
+	assert.NoError(t, err)
+
+	lk, err := crypto.GenerateKeyPair()
+	assert.NoError(t, err)
+
+	lc, err := crypto.GenerateLeaf([]string{"127.0.0.1"}, []string{"localhost"}, ca, rk.Private, lk.Private)
+	assert.NoError(t, err)
+
+	cert := filepath.Join(dir, "leaf.cert")
+	key := filepath.Join(dir, "leaf.key")
+
+	assert.NoError(t, lc.WriteFile(cert))
+	assert.NoError(t, lk.Private.WriteFile(key))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+
+	return pool, cert, key
+}
+
+func startStreamProxy(t *testing.T, protocol, upstream, cert, key string) *StreamProxy {
+	p, err := NewStreamProxy("test", protocol, "127.0.0.1:0", upstream, cert, key, hclog.NewNullLogger())
+	assert.NoError(t, err)
+
+	err = p.Start()
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { p.Stop() })
+
+	return p
+}
+
+func TestStreamProxyReturnsErrorWithInvalidProtocol(t *testing.T) {
+	_, err := NewStreamProxy("test", "http", ":0", "localhost:80", "", "", hclog.NewNullLogger())
+	assert.Error(t, err)
+}
+
+func TestStreamProxyReturnsErrorWithUDPAndTLS(t *testing.T) {
+	_, cert, key := setupCertificates(t)
+
+	_, err := NewStreamProxy("test", "udp", ":0", "localhost:53", cert, key, hclog.NewNullLogger())
+	assert.Error(t, err)
+}
+
+func TestStreamProxyForwardsTCP(t *testing.T) {
+	p := startStreamProxy(t, "tcp", setupTCPUpstream(t), "", "")
+
+	c, err := net.Dial("tcp", p.listener.Addr().String())
+	assert.NoError(t, err)
+	defer c.Close()
+
+	fmt.Fprint(c, "hello\n")
+
+	resp, err := bufio.NewReader(c).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "upstream hello\n", resp)
+}
+
+func TestStreamProxyTerminatesTLS(t *testing.T) {
+	pool, cert, key := setupCertificates(t)
+	p := startStreamProxy(t, "tcp", setupTCPUpstream(t), cert, key)
+
+	c, err := tls.Dial("tcp", p.listener.Addr().String(), &tls.Config{RootCAs: pool, ServerName: "localhost"})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	fmt.Fprint(c, "hello\n")
+
+	resp, err := bufio.NewReader(c).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "upstream hello\n", resp)
+}
+
+func TestStreamProxyForwardsUDP(t *testing.T) {
+	p := startStreamProxy(t, "udp", setupUDPUpstream(t), "", "")
+
+	c, err := net.Dial("udp", p.packet.LocalAddr().String())
+	assert.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Write([]byte("hello"))
+	assert.NoError(t, err)
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 1024)
+	n, err := c.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "upstream hello", string(buf[:n]))
+}
@@ -0,0 +1,70 @@
+package shipyard
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/server"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// StartConnector creates the certificates for the connector, rotating them when
+// they are about to expire, and starts the connector when it is not running.
+// The connector must be running before blueprints which use ingress are applied.
+func StartConnector(cc clients.Connector, l hclog.Logger) error {
+	if cb, err := cc.GetLocalCertBundle(utils.CertsDir("")); err != nil || cb == nil {
+		// generate certs
+		l.Debug("Generating TLS Certificates for Ingress", "path", utils.CertsDir(""))
+		_, err := cc.GenerateLocalCertBundle(utils.CertsDir(""))
+		if err != nil {
+			return fmt.Errorf("Unable to generate connector certificates: %s", err)
+		}
+	} else if exp, err := clients.CertExpiry(cb.LeafCertPath); err == nil && time.Until(exp) < server.RotateBefore {
+		// rotate the certificate before it expires, a running connector
+		// loads the new certificate automatically
+		l.Debug("Rotating TLS Certificates for Ingress", "path", utils.CertsDir(""), "expiry", exp)
+		_, err := cc.RotateLocalCerts(utils.CertsDir(""), false)
+		if err != nil {
+			return fmt.Errorf("Unable to rotate connector certificates: %s", err)
+		}
+	}
+
+	if cc.IsRunning() {
+		return nil
+	}
+
+	cb, err := cc.GetLocalCertBundle(utils.CertsDir(""))
+	if err != nil {
+		return fmt.Errorf("Unable to get certificates to secure ingress: %s", err)
+	}
+
+	l.Debug("Starting Ingress")
+
+	err = cc.Start(cb)
+	if err != nil {
+		return fmt.Errorf("Unable to start ingress: %s", err)
+	}
+
+	return nil
+}
+
+// StopConnector stops the connector and removes its certificates once all the
+// resources have been destroyed, the connector is left running when it is
+// installed as a service as the service outlives the resources.
+func StopConnector(cc clients.Connector, l hclog.Logger) {
+	if cc.ServiceInstalled() {
+		return
+	}
+
+	os.RemoveAll(utils.CertsDir(""))
+
+	if cc.IsRunning() {
+		err := cc.Stop()
+		if err != nil {
+			l.Error("Unable to stop ingress", "error", err)
+		}
+	}
+}
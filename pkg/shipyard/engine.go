@@ -8,9 +8,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/docker/docker/api/types"
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/terraform/dag"
 	"github.com/hashicorp/terraform/tfdiags"
@@ -36,8 +38,24 @@ type Clients struct {
 	Getter         clients.Getter
 	Browser        clients.System
 	ImageLog       clients.ImageLog
+	History        clients.History
 	Connector      clients.Connector
 	TarGz          *clients.TarGz
+	Updates        clients.Updates
+	Plugins        clients.Plugins
+	Registry       clients.BlueprintRegistry
+
+	// ImagePulls records the timing of the images pulled by the ContainerTasks
+	ImagePulls *clients.ImagePulls
+
+	// Fixtures records or replays the images, files and charts fetched by the clients
+	Fixtures *clients.Fixtures
+
+	// Runner is the container Shipyard is running in, nil when not running in a container
+	Runner *clients.RunnerContainer
+
+	// EngineMonitor detects restarts of the Docker engine
+	EngineMonitor *clients.EngineMonitor
 }
 
 // Engine defines an interface for the Shipyard engine
@@ -55,6 +73,19 @@ type Engine interface {
 	ResourceCount() int
 	ResourceCountForType(string) int
 	Blueprint() *config.Blueprint
+
+	// Events returns the EventBus which extensions can use to subscribe to
+	// events published when resources are applied
+	Events() *EventBus
+
+	// SetNotifications sets notifications which receive the events for the apply
+	// or destroy in addition to the notifications defined in the blueprint
+	SetNotifications(n []config.Notification)
+
+	// SetMerge sets the engine to apply blueprints into the existing state alongside
+	// the resources from other blueprints, resources which are defined by another
+	// blueprint are reported as conflicts
+	SetMerge(merge bool)
 }
 
 // EngineImpl is responsible for creating and destroying resources
@@ -64,6 +95,11 @@ type EngineImpl struct {
 	log         hclog.Logger
 	getProvider getProviderFunc
 	sync        sync.Mutex
+	events      *EventBus
+
+	notifications []config.Notification
+
+	merge bool
 }
 
 // defines a function which is used for generating providers
@@ -72,7 +108,11 @@ type getProviderFunc func(c config.Resource, cl *Clients) providers.Provider
 
 // GenerateClients creates the various clients for creating and destroying resources
 func GenerateClients(l hclog.Logger) (*Clients, error) {
-	dc, err := clients.NewDocker()
+	// wait for the Docker engine when it restarts during an operation
+	// rather than failing, e.g. Docker Desktop restarting after an update
+	em := clients.NewEngineMonitor(l)
+
+	dc, err := clients.NewDockerWithMonitor(em)
 	if err != nil {
 		return nil, err
 	}
@@ -93,13 +133,57 @@ func GenerateClients(l hclog.Logger) (*Clients, error) {
 
 	il := clients.NewImageFileLog(utils.ImageCacheLog())
 
+	hl := clients.NewHistoryFileLog(utils.HistoryPath())
+
 	tgz := &clients.TarGz{}
 
 	ct := clients.NewDockerTasks(dc, il, tgz, l)
 
-	co := clients.DefaultConnectorOptions()
+	ip := clients.NewImagePulls()
+	fx := clients.NewFixtures()
+	if ct != nil {
+		ct.SetImagePulls(ip)
+		ct.SetFixtures(fx)
+	}
+
+	bp.SetFixtures(fx)
+	bp.SetLock(clients.NewSourceLock(utils.SourceLockPath()))
+
+	if h, ok := hec.(*clients.HelmImpl); ok {
+		h.SetFixtures(fx)
+	}
+
+	// when running in a container, e.g. a CI job, paths and addresses
+	// need to be translated to those of the engine host
+	rc, err := clients.DetectRunner(dc)
+	if err != nil {
+		l.Debug("Unable to determine runner container", "error", err)
+	}
+
+	if rc != nil {
+		l.Debug("Running in container", "id", rc.ID, "mode", rc.Mode, "gateway", rc.Gateway)
+
+		if ct != nil {
+			ct.SetRunner(rc)
+		}
+
+		// ports published by an engine on a local socket are not reachable on localhost
+		if rc.Mode == clients.RunnerModeHostEngine && rc.Gateway != "" && utils.GetDockerIP() == "127.0.0.1" {
+			utils.SetDockerIP(rc.Gateway)
+		}
+	}
+
+	co, err := clients.DefaultConnectorOptions()
+	if err != nil {
+		return nil, err
+	}
+
 	cc := clients.NewConnector(co)
 
+	uc := clients.NewUpdates(30*time.Second, l)
+
+	pc := clients.NewPlugins(utils.PluginsDir(), l)
+
 	return &Clients{
 		ContainerTasks: ct,
 		Docker:         dc,
@@ -112,8 +196,16 @@ func GenerateClients(l hclog.Logger) (*Clients, error) {
 		Getter:         bp,
 		Browser:        bc,
 		ImageLog:       il,
+		History:        hl,
 		Connector:      cc,
 		TarGz:          tgz,
+		Updates:        uc,
+		Plugins:        pc,
+		Registry:       clients.NewBlueprintRegistry(),
+		Runner:         rc,
+		ImagePulls:     ip,
+		Fixtures:       fx,
+		EngineMonitor:  em,
 	}, nil
 }
 
@@ -123,6 +215,7 @@ func New(l hclog.Logger) (Engine, error) {
 	e := &EngineImpl{}
 	e.log = l
 	e.getProvider = generateProviderImpl
+	e.events = NewEventBus()
 
 	// Set the standard writer to our logger as the DAG uses the standard library log.
 	log.SetOutput(l.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Trace}))
@@ -135,6 +228,9 @@ func New(l hclog.Logger) (Engine, error) {
 
 	e.clients = cl
 
+	// register the resource types provided by plugins so that they can be parsed
+	registerPlugins(cl.Plugins, l)
+
 	return e, nil
 }
 
@@ -143,6 +239,40 @@ func (e *EngineImpl) GetClients() *Clients {
 	return e.clients
 }
 
+// Events returns the EventBus for the engine
+func (e *EngineImpl) Events() *EventBus {
+	return e.events
+}
+
+// SetNotifications sets notifications which receive the events for the apply
+// or destroy in addition to the notifications defined in the blueprint
+func (e *EngineImpl) SetNotifications(n []config.Notification) {
+	e.notifications = n
+}
+
+// startNotifications subscribes the notifications from the blueprint and the
+// engine to the events, the returned function waits for the events to be sent
+func (e *EngineImpl) startNotifications() (func(), error) {
+	sinks := append([]config.Notification{}, e.notifications...)
+
+	title := ""
+	if e.config.Blueprint != nil {
+		sinks = append(sinks, e.config.Blueprint.Notifications...)
+		title = e.config.Blueprint.Title
+	}
+
+	if len(sinks) == 0 {
+		return func() {}, nil
+	}
+
+	n, err := NewNotifier(sinks, title, e.clients.HTTP, e.log)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.Subscribe(e.events), nil
+}
+
 // ParseConfig parses the given Shipyard files and creating the resource types but does
 // not apply or destroy the resources.
 // This function can be used to check the validity of a configuration without making changes
@@ -167,13 +297,16 @@ func (e *EngineImpl) Apply(path string) ([]config.Resource, error) {
 	return e.ApplyWithVariables(path, nil, "")
 }
 
-// ApplyWithVariables applies the current config creating the resources
+// ApplyWithVariables applies the current config creating the resources,
+// when path is empty only the resources in the state are applied
 func (e *EngineImpl) ApplyWithVariables(path string, vars map[string]string, variablesFile string) ([]config.Resource, error) {
 	// abs paths
 	var err error
-	path, err = filepath.Abs(path)
-	if err != nil {
-		return nil, err
+	if path != "" {
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	e.log.Info("Creating resources from configuration", "path", path)
@@ -190,8 +323,70 @@ func (e *EngineImpl) ApplyWithVariables(path string, vars map[string]string, var
 		return nil, err
 	}
 
+	// the containers for paused resources are stopped, applying would leave
+	// them stopped but mark them as created
+	for _, r := range e.config.Resources {
+		if r.Info().Status == config.Paused {
+			return nil, fmt.Errorf("The environment is paused, run 'shipyard resume' before applying the blueprint")
+		}
+	}
+
+	err = e.applyUserConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.applyBlueprintConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// refuse to create resources which would exceed the quota of the workspace
+	err = e.checkQuota()
+	if err != nil {
+		return nil, err
+	}
+
+	// resources which use Linux images can not be created by Windows engines
+	err = e.checkPlatform()
+	if err != nil {
+		return nil, err
+	}
+
+	// fail before creating any resources when a host port is in use
+	err = e.checkPorts()
+	if err != nil {
+		return nil, err
+	}
+
+	// pre run hooks can prevent the resources from being created
+	err = e.runHooks(config.HookPreRun, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	createdResource := []config.Resource{}
 
+	// send the events for the apply to the notifications
+	stopNotifications, err := e.startNotifications()
+	if err != nil {
+		return nil, err
+	}
+	defer stopNotifications()
+
+	// count the resources which will be created so that subscribers
+	// can report the progress of the apply
+	total := 0
+	for _, v := range d.Vertices() {
+		if r, ok := v.(config.Resource); ok && r.Info().Status != config.Disabled && r.Info().Status != config.PendingUpdate {
+			total++
+		}
+	}
+
+	e.events.Publish(Event{Type: ApplyStarted, Total: total})
+
+	applyStart := time.Now()
+
 	// walk the dag and apply the config
 	w := dag.Walker{}
 	w.Callback = func(v dag.Vertex) (diags tfdiags.Diagnostics) {
@@ -203,49 +398,69 @@ func (e *EngineImpl) ApplyWithVariables(path string, vars map[string]string, var
 			return nil
 		}
 
+		// disabled and unchanged resources are not created
+		previousStatus := r.Info().Status
+		creates := previousStatus != config.Disabled && previousStatus != config.PendingUpdate
+
+		// the clients of created resources record the time spent in each phase
+		cc := e.clients
+		if creates {
+			r.Info().Timing = config.NewTiming("create")
+			cc = timedClients(e.clients, r.Info().Timing)
+		}
+
 		// get the provider to create the resource
-		p := e.getProvider(r, e.clients)
+		p := e.getProvider(r, cc)
 
 		if p == nil {
 			r.Info().Status = config.Failed
 			return diags.Append(fmt.Errorf("Unable to create provider for resource Name: %s, Type: %s", r.Info().Name, r.Info().Type))
 		}
 
-		switch r.Info().Status {
-		// Normal case for PendingUpdate is do nothing
-		// PendingModification causes a resource to be
-		// destroyed before created
-		case config.PendingModification:
-			fallthrough
+		if creates {
+			e.events.Publish(Event{Type: ResourceCreateStarted, Resource: r})
+		}
 
-			// Always attempt to destroy and re-create failed resources
-		case config.Failed:
-			err = p.Destroy()
-			if err != nil {
+		resourceStart := time.Now()
+		createErr := e.applyResource(r, p)
+
+		// dependents are created once the wait_for conditions of the resource are met
+		if creates && createErr == nil {
+			waitStart := time.Now()
+			createErr = e.waitFor(r)
+			r.Info().Timing.Record(config.PhaseWaitFor, time.Since(waitStart))
+			if createErr != nil {
 				r.Info().Status = config.Failed
-				return diags.Append(err)
 			}
+		}
 
-			fallthrough // failed resources should always attempt recreation
+		// errors caused by a restart of the engine are not a problem with the resource
+		if createErr != nil && e.clients.EngineMonitor.RestartedSince(resourceStart) {
+			createErr = fmt.Errorf("The Docker engine restarted while creating the resource, run the command again to resume: %w", createErr)
+		}
+
+		// publish a health change when a failed resource recovers or
+		// a healthy resource fails
+		if (previousStatus == config.Failed) != (createErr != nil) {
+			e.events.Publish(Event{Type: HealthChanged, Resource: r, Healthy: createErr == nil, Error: createErr})
+		}
+
+		if creates {
+			r.Info().Timing.Finish()
 
-		// Create new resources
-		case config.PendingCreation:
-			createErr := p.Create()
 			if createErr != nil {
-				r.Info().Status = config.Failed
-				return diags.Append(createErr)
+				e.events.Publish(Event{Type: ResourceCreateFailed, Resource: r, Error: createErr})
+			} else {
+				e.events.Publish(Event{Type: ResourceCreateSucceeded, Resource: r})
 			}
 
-		case config.PendingUpdate:
-			// do nothing for pending updates
-
-		case config.Disabled:
-			// do nothing for disabled updates
+			// save the state as each resource is created so that the
+			// status of the resources can be watched during the apply
+			e.saveState()
 		}
 
-		// set the status only if not disabled
-		if r.Info().Status != config.Disabled {
-			r.Info().Status = config.Applied
+		if createErr != nil {
+			return diags.Append(createErr)
 		}
 
 		appendResources(&createdResource, r)
@@ -259,9 +474,26 @@ func (e *EngineImpl) ApplyWithVariables(path string, vars map[string]string, var
 		err = tf.Err()
 	}
 
+	// containers without a restart policy are stopped when the engine restarts,
+	// mark the resources as failed so that the state is accurate and the
+	// resources are recreated the next time the blueprint is applied
+	if e.clients.EngineMonitor.RestartedSince(applyStart) {
+		stopped := e.failStoppedResources(d, applyStart)
+		if err == nil && stopped > 0 {
+			err = fmt.Errorf("The Docker engine restarted during the apply and stopped %d resources, run the command again to recreate them", stopped)
+		}
+	}
+
+	e.events.Publish(Event{Type: ApplyComplete, Error: err})
+
+	herr := e.runHooks(config.HookPostRun, path, err)
+	if herr != nil {
+		e.log.Error("Unable to run post run hooks", "error", herr)
+	}
+
 	if len(e.config.Resources) > 0 {
 		// save the state regardless of error
-		jerr := e.config.ToJSON(utils.StatePath())
+		jerr := e.writeState()
 		if jerr != nil {
 			return createdResource, jerr
 		}
@@ -272,6 +504,102 @@ func (e *EngineImpl) ApplyWithVariables(path string, vars map[string]string, var
 	return nil, tf.Err()
 }
 
+// failStoppedResources sets the status of applied resources which have containers
+// that stopped after the given time to failed, returns the number of resources
+func (e *EngineImpl) failStoppedResources(d *dag.AcyclicGraph, since time.Time) int {
+	if e.clients.ContainerTasks == nil {
+		return 0
+	}
+
+	stopped := 0
+	for _, v := range d.Vertices() {
+		r, ok := v.(config.Resource)
+		if !ok || r.Info().Status != config.Applied {
+			continue
+		}
+
+		p := e.getProvider(r, e.clients)
+		if p == nil {
+			continue
+		}
+
+		ids, err := p.Lookup()
+		if err != nil {
+			continue
+		}
+
+		for _, id := range ids {
+			// resources such as networks do not have containers
+			info, err := e.clients.ContainerTasks.ContainerInfo(id)
+			if err != nil {
+				continue
+			}
+
+			cj, ok := info.(types.ContainerJSON)
+			if !ok || cj.ContainerJSONBase == nil || cj.State == nil || cj.State.Running {
+				continue
+			}
+
+			finished, err := time.Parse(time.RFC3339Nano, cj.State.FinishedAt)
+			if err != nil || finished.Before(since) {
+				continue
+			}
+
+			e.log.Warn("Resource stopped when the Docker engine restarted", "ref", r.Info().Name, "type", r.Info().Type, "id", id)
+
+			r.Info().Status = config.Failed
+			stopped++
+
+			break
+		}
+	}
+
+	return stopped
+}
+
+// applyResource calls the provider for the resource depending on its status
+// and sets the status of the resource
+func (e *EngineImpl) applyResource(r config.Resource, p providers.Provider) error {
+	switch r.Info().Status {
+	// Normal case for PendingUpdate is do nothing
+	// PendingModification causes a resource to be
+	// destroyed before created
+	case config.PendingModification:
+		fallthrough
+
+		// Always attempt to destroy and re-create failed resources
+	case config.Failed:
+		err := p.Destroy()
+		if err != nil {
+			r.Info().Status = config.Failed
+			return err
+		}
+
+		fallthrough // failed resources should always attempt recreation
+
+	// Create new resources
+	case config.PendingCreation:
+		err := p.Create()
+		if err != nil {
+			r.Info().Status = config.Failed
+			return err
+		}
+
+	case config.PendingUpdate:
+		// do nothing for pending updates
+
+	case config.Disabled:
+		// do nothing for disabled updates
+	}
+
+	// set the status only if not disabled
+	if r.Info().Status != config.Disabled {
+		r.Info().Status = config.Applied
+	}
+
+	return nil
+}
+
 // Destroy the resources defined by the config
 func (e *EngineImpl) Destroy(path string, allResources bool) error {
 	d, err := e.readConfig(path, nil, "")
@@ -279,6 +607,18 @@ func (e *EngineImpl) Destroy(path string, allResources bool) error {
 		return err
 	}
 
+	err = e.runHooks(config.HookPreDestroy, path, nil)
+	if err != nil {
+		return err
+	}
+
+	// send the events for the destroy to the notifications
+	stopNotifications, err := e.startNotifications()
+	if err != nil {
+		return err
+	}
+	defer stopNotifications()
+
 	// make sure we destroy everything
 	if allResources {
 		for _, i := range e.config.Resources {
@@ -304,7 +644,8 @@ func (e *EngineImpl) Destroy(path string, allResources bool) error {
 				}
 
 				// get the provider to create the resource
-				p := e.getProvider(r, e.clients)
+				r.Info().Timing = config.NewTiming("destroy")
+				p := e.getProvider(r, timedClients(e.clients, r.Info().Timing))
 				if p == nil {
 					r.Info().Status = config.Failed
 					return diags.Append(fmt.Errorf("Unable to create provider for resource Name: %s, Type: %s", r.Info().Name, r.Info().Type))
@@ -312,12 +653,16 @@ func (e *EngineImpl) Destroy(path string, allResources bool) error {
 
 				// execute
 				destroyErr := p.Destroy()
+				r.Info().Timing.Finish()
 				if destroyErr != nil {
 					r.Info().Status = config.Failed
+					e.saveState()
+
 					return diags.Append(destroyErr)
 				}
 
-				fallthrough
+				r.Info().Status = config.Destroyed
+				e.saveState()
 			case config.Disabled:
 				// set the status
 				r.Info().Status = config.Destroyed
@@ -352,9 +697,164 @@ func (e *EngineImpl) Destroy(path string, allResources bool) error {
 		os.RemoveAll(utils.StatePath())
 	}
 
+	e.events.Publish(Event{Type: DestroyComplete, Error: tf.Err()})
+
+	herr := e.runHooks(config.HookPostDestroy, path, tf.Err())
+	if herr != nil {
+		e.log.Error("Unable to run post destroy hooks", "error", herr)
+	}
+
 	return tf.Err()
 }
 
+// applyUserConfig sets the global defaults from the user config on the clients
+func (e *EngineImpl) applyUserConfig() error {
+	uc, err := config.LoadUserConfig(utils.UserConfigPath())
+	if err != nil {
+		return fmt.Errorf("Unable to load user config: %s", err)
+	}
+
+	if e.clients.ContainerTasks != nil {
+		e.clients.ContainerTasks.SetDefaultPortBind(uc.DefaultPortBind())
+	}
+
+	providers.SetResourceDefaults(uc.Defaults)
+
+	return nil
+}
+
+// applyBlueprintConfig configures the clients with the settings from the blueprint
+// which apply to all resources
+// checkQuota returns an error when the resources in the config exceed the
+// quota set in the user config, the config contains the resources in the state
+// so the usage is the total for the workspace once the blueprint is applied
+func (e *EngineImpl) checkQuota() error {
+	uc, err := config.LoadUserConfig(utils.UserConfigPath())
+	if err != nil {
+		return fmt.Errorf("Unable to load user config: %s", err)
+	}
+
+	if uc.Quota == nil {
+		return nil
+	}
+
+	u, err := config.CalculateQuotaUsage(e.config)
+	if err != nil {
+		return fmt.Errorf("Unable to calculate quota usage: %s", err)
+	}
+
+	e.log.Debug("Checking workspace quota", "containers", u.Containers, "memory", u.MemoryBytes, "clusters", u.Clusters)
+
+	err = uc.Quota.Check(u)
+	if err != nil {
+		return fmt.Errorf("%s, destroy resources or change the quota in %s", err, utils.UserConfigPath())
+	}
+
+	return nil
+}
+
+// checkPlatform returns an error when the engine is running Windows containers and
+// the config contains resources which can only be created with Linux containers
+func (e *EngineImpl) checkPlatform() error {
+	ct, ok := e.clients.ContainerTasks.(interface {
+		Capabilities() *clients.EngineCapabilities
+	})
+
+	if !ok || !ct.Capabilities().WindowsContainers() {
+		return nil
+	}
+
+	for _, r := range e.config.Resources {
+		i := r.Info()
+		if i.Disabled || !config.IsLinuxOnly(i.Type) {
+			continue
+		}
+
+		// the image cache is added to every config but is only used by clusters,
+		// containers pull their images directly
+		if i.Type == config.TypeImageCache {
+			if i.Status == config.PendingCreation {
+				e.log.Debug("Image cache is not created for Windows containers")
+				i.Status = config.Disabled
+			}
+
+			continue
+		}
+
+		return fmt.Errorf("Unable to create %s.%s, the Docker engine is running Windows containers and %s resources require Linux containers, switch Docker Desktop to Linux containers", i.Type, i.Name, i.Type)
+	}
+
+	return nil
+}
+
+func (e *EngineImpl) applyBlueprintConfig() error {
+	files := []string{}
+	if e.config.Blueprint != nil {
+		files = e.config.Blueprint.TrustedCAFiles
+	}
+
+	err := clients.SetTrustedCAs(files)
+	if err != nil {
+		return fmt.Errorf("Unable to load trusted CAs: %s", err)
+	}
+
+	return nil
+}
+
+// saveState writes the current state of the resources, it is called as
+// each resource is processed so that other processes can watch the status
+func (e *EngineImpl) saveState() {
+	e.sync.Lock()
+	defer e.sync.Unlock()
+
+	err := e.writeState()
+	if err != nil {
+		e.log.Debug("Unable to save state", "error", err)
+	}
+}
+
+// writeState writes the state to disk, when blueprints are merged other processes
+// can apply blueprints at the same time and the resources they have added to the
+// state since it was read are kept
+func (e *EngineImpl) writeState() error {
+	if !e.merge {
+		return e.config.ToJSON(utils.StatePath())
+	}
+
+	unlock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	sc := config.New()
+	if _, err := os.Stat(utils.StatePath()); err == nil {
+		err := sc.FromJSON(utils.StatePath())
+		if err != nil {
+			return fmt.Errorf("Error parsing state: %s", err)
+		}
+	}
+
+	// the resources of the engine are read by the providers while the state
+	// is written, the resources added by other processes are written to a copy
+	out := &config.Config{Blueprint: e.config.Blueprint}
+	out.Resources = append(out.Resources, e.config.Resources...)
+
+	for _, r := range sc.Resources {
+		_, err := e.config.FindResource(fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name))
+		if err != nil {
+			out.Resources = append(out.Resources, r)
+		}
+	}
+
+	return out.ToJSON(utils.StatePath())
+}
+
+// SetMerge sets the engine to merge blueprints into the existing state
+func (e *EngineImpl) SetMerge(merge bool) {
+	e.merge = merge
+}
+
 // ResourceCount defines the number of resources in a plan
 func (e *EngineImpl) ResourceCount() int {
 	return e.config.ResourceCount()
@@ -400,7 +900,7 @@ func (e *EngineImpl) readConfig(path string, variables map[string]string, variab
 	cc.AddResource(cache)
 
 	if path != "" {
-		if utils.IsHCLFile(path) {
+		if utils.IsConfigFile(path) {
 			err := config.ParseSingleFile(path, cc, variables, variablesFile)
 			if err != nil {
 				return nil, err
@@ -414,11 +914,41 @@ func (e *EngineImpl) readConfig(path string, variables map[string]string, variab
 
 		// if we are loading from files create the deps
 		config.ParseReferences(cc)
+
+		// record the blueprint which created the resources
+		for _, r := range cc.Resources {
+			if r.Info().Type != config.TypeImageCache {
+				r.Info().BlueprintSource = path
+			}
+		}
+	}
+
+	if e.merge {
+		err := mergeConflicts(sc, cc)
+		if err != nil {
+			return nil, err
+		}
+
+		// the blueprint of the environment is kept when other blueprints are merged
+		if sc.Blueprint != nil {
+			cc.Blueprint = nil
+		}
+	}
+
+	if path != "" {
+		err := e.detectChanges(sc, cc)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// merge the state and items to be created or deleted
 	sc.Merge(cc)
 
+	// sort the resources so that the state and the order resources are
+	// added to the graph is the same for every run
+	sc.Sort()
+
 	// set the config
 	e.config = sc
 
@@ -440,15 +970,29 @@ func (e *EngineImpl) readConfig(path string, variables map[string]string, variab
 
 // generateProviderImpl returns providers grouped together in order of execution
 func generateProviderImpl(c config.Resource, cc *Clients) providers.Provider {
+	if p, ok := c.(*config.PluginResource); ok {
+		return providers.NewPlugin(p, cc.Plugins, cc.Logger)
+	}
+
 	switch c.Info().Type {
+	case config.TypeCertificateCA:
+		return providers.NewCertificateCA(c.(*config.CertificateCA), cc.Logger)
+	case config.TypeCertificateLeaf:
+		return providers.NewCertificateLeaf(c.(*config.CertificateLeaf), cc.Logger)
+	case config.TypeCompose:
+		return providers.NewCompose(c.(*config.Compose), cc.ContainerTasks, cc.HTTP, cc.Logger)
 	case config.TypeContainer:
 		return providers.NewContainer(c.(*config.Container), cc.ContainerTasks, cc.HTTP, cc.Logger)
 	case config.TypeContainerIngress:
-		return providers.NewContainerIngress(c.(*config.ContainerIngress), cc.ContainerTasks, cc.Logger)
+		return providers.NewContainerIngress(c.(*config.ContainerIngress), cc.ContainerTasks, cc.Connector, cc.Logger)
 	case config.TypeSidecar:
 		return providers.NewContainerSidecar(c.(*config.Sidecar), cc.ContainerTasks, cc.HTTP, cc.Logger)
 	case config.TypeDocs:
-		return providers.NewDocs(c.(*config.Docs), cc.ContainerTasks, cc.Logger)
+		return providers.NewDocs(c.(*config.Docs), cc.ContainerTasks, cc.Connector, cc.Logger)
+	case config.TypeDockerImage:
+		return providers.NewDockerImage(c.(*config.DockerImage), cc.ContainerTasks, cc.Logger)
+	case config.TypeCopy:
+		return providers.NewCopy(c.(*config.Copy), cc.ContainerTasks, cc.Getter, cc.Logger)
 	case config.TypeExecRemote:
 		return providers.NewRemoteExec(c.(*config.ExecRemote), cc.ContainerTasks, cc.Logger)
 	case config.TypeExecLocal:
@@ -462,21 +1006,42 @@ func generateProviderImpl(c config.Resource, cc *Clients) providers.Provider {
 	case config.TypeK8sCluster:
 		return providers.NewK8sCluster(c.(*config.K8sCluster), cc.ContainerTasks, cc.Kubernetes, cc.HTTP, cc.Connector, cc.Logger)
 	case config.TypeK8sConfig:
-		return providers.NewK8sConfig(c.(*config.K8sConfig), cc.Kubernetes, cc.Logger)
+		return providers.NewK8sConfig(c.(*config.K8sConfig), cc.Kubernetes, cc.Getter, cc.Logger)
+	case config.TypeK8sNamespace:
+		return providers.NewK8sNamespace(c.(*config.K8sNamespace), cc.Kubernetes, cc.Logger)
 	case config.TypeK8sIngress:
-		return providers.NewK8sIngress(c.(*config.K8sIngress), cc.ContainerTasks, cc.Logger)
+		return providers.NewK8sIngress(c.(*config.K8sIngress), cc.ContainerTasks, cc.Kubernetes, cc.Connector, cc.Logger)
+	case config.TypeLogSink:
+		return providers.NewLogSink(c.(*config.LogSink), cc.ContainerTasks, cc.Logger)
 	case config.TypeNomadCluster:
 		return providers.NewNomadCluster(c.(*config.NomadCluster), cc.ContainerTasks, cc.Nomad, cc.Logger)
 	case config.TypeNomadIngress:
-		return providers.NewNomadIngress(c.(*config.NomadIngress), cc.ContainerTasks, cc.Logger)
+		return providers.NewNomadIngress(c.(*config.NomadIngress), cc.ContainerTasks, cc.Connector, cc.Logger)
 	case config.TypeNomadJob:
 		return providers.NewNomadJob(c.(*config.NomadJob), cc.Nomad, cc.Logger)
 	case config.TypeNetwork:
-		return providers.NewNetwork(c.(*config.Network), cc.Docker, cc.Logger)
+		return providers.NewNetwork(c.(*config.Network), cc.Docker, cc.Runner, cc.Logger)
 	case config.TypeOutput:
 		return providers.NewNull(c.Info(), cc.Logger)
+	case config.TypeRandomID:
+		return providers.NewRandomID(c.(*config.RandomID), cc.Logger)
+	case config.TypeRandomPassword:
+		return providers.NewRandomPassword(c.(*config.RandomPassword), cc.Logger)
+	case config.TypeRegistry:
+		return providers.NewRegistry(c.(*config.Registry), cc.ContainerTasks, cc.Logger)
+	case config.TypeRouter:
+		return providers.NewRouter(c.(*config.Router), cc.Connector, cc.Logger)
+	case config.TypeSocksProxy:
+		return providers.NewSocksProxy(c.(*config.SocksProxy), cc.Connector, cc.Logger)
 	case config.TypeTemplate:
-		return providers.NewTemplate(c.(*config.Template), cc.Logger)
+		return providers.NewTemplate(c.(*config.Template), cc.ContainerTasks, cc.Logger)
+	case config.TypeTerraform:
+		return providers.NewTerraform(c.(*config.Terraform), cc.ContainerTasks, cc.Logger)
+	case config.TypeTest:
+		// tests are run by the test command once the blueprint has been applied
+		return providers.NewNull(c.Info(), cc.Logger)
+	case config.TypeTunnel:
+		return providers.NewTunnel(c.(*config.Tunnel), cc.ContainerTasks, cc.Logger)
 	}
 
 	return nil
@@ -491,3 +1056,74 @@ func appendResources(cr *[]config.Resource, r config.Resource) {
 
 	*cr = append(*cr, r)
 }
+
+// mergeConflicts returns an error when the blueprint defines resources which
+// exist in the state and were created by a different blueprint
+func mergeConflicts(sc, cc *config.Config) error {
+	conflicts := sc.Conflicts(cc)
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	msgs := []string{}
+	for _, r := range conflicts {
+		msgs = append(msgs, fmt.Sprintf("%s.%s is defined in %s", r.Info().Type, r.Info().Name, r.Info().BlueprintSource))
+	}
+
+	return fmt.Errorf("Unable to merge blueprint, resources with the same name already exist in the environment: %s", strings.Join(msgs, ", "))
+}
+
+// detectChanges compares the hash of the configuration of the resources in the
+// blueprint with the hash which was applied. Resources where only attributes
+// which can be updated in place have changed are marked for recreation, other
+// changes are reported and the previously applied hash is kept so that the
+// change is reported until the resource is recreated.
+func (e *EngineImpl) detectChanges(sc, cc *config.Config) error {
+	for _, r := range cc.Resources {
+		h, err := config.ConfigHash(r)
+		if err != nil {
+			return err
+		}
+
+		r.Info().ConfigHash = h
+
+		if r.Info().Type == config.TypeImageCache {
+			continue
+		}
+
+		old, err := sc.FindResource(fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name))
+		if err != nil || old.Info().Status != config.Applied {
+			continue
+		}
+
+		// state created by older versions does not contain the hash
+		if old.Info().ConfigHash == "" || old.Info().ConfigHash == h {
+			continue
+		}
+
+		changed, err := config.ChangedAttributes(old, r)
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)
+
+		if config.CanUpdateInPlace(r.Info().Type, changed) {
+			e.log.Info("Recreating resource with changed attributes", "ref", name, "attributes", strings.Join(changed, ", "))
+
+			// Merge keeps the status of resources which are not applied
+			old.Info().Status = config.PendingModification
+			continue
+		}
+
+		e.log.Warn(
+			"Resource has changed and will not be updated, use --recreate to apply the changes",
+			"ref", name,
+			"attributes", strings.Join(changed, ", "),
+		)
+
+		r.Info().ConfigHash = old.Info().ConfigHash
+	}
+
+	return nil
+}
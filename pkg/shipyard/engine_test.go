@@ -7,16 +7,23 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/terraform/dag"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/providers"
 	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 
+	"github.com/stretchr/testify/mock"
 	assert "github.com/stretchr/testify/require"
 )
 
@@ -40,6 +47,7 @@ func setupTestsBase(t *testing.T, returnVals map[string]error, state string) (En
 		clients:     cl,
 		log:         hclog.NewNullLogger(),
 		getProvider: generateProviderMock(p, returnVals),
+		events:      NewEventBus(),
 	}
 
 	setupState(t, state)
@@ -117,6 +125,7 @@ func TestNewCreatesClients(t *testing.T) {
 	assert.NotNil(t, cl.Browser)
 	assert.NotNil(t, cl.ImageLog)
 	assert.NotNil(t, cl.Connector)
+	assert.NotNil(t, cl.Registry)
 }
 
 func TestApplyWithSingleFile(t *testing.T) {
@@ -203,6 +212,16 @@ func TestApplyNotCallsProviderDestroyAndCreateForResourcesDisabled(t *testing.T)
 	assert.Equal(t, config.Disabled, r.Info().Status)
 }
 
+func TestApplyReturnsErrorWhenResourcesPaused(t *testing.T) {
+	e, mp := setupTestsWithState(t, nil, pausedState)
+
+	_, err := e.Apply("")
+	assert.Error(t, err)
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+	testAssertMethodCalled(t, mp, "Destroy", 0)
+}
+
 func TestApplyCallsProviderDestroyAndCreateForResourcesFailed(t *testing.T) {
 	e, mp := setupTestsWithState(t, nil, failedState)
 
@@ -246,6 +265,55 @@ func TestApplyCallsProviderGenerateErrorStopsExecution(t *testing.T) {
 	testAssertMethodCalled(t, mp, "Create", 2)
 }
 
+func TestApplyPublishesEventsForEachResource(t *testing.T) {
+	e, _ := setupTests(t, nil)
+
+	events := map[EventType]int{}
+	e.Events().Subscribe(func(ev Event) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		events[ev.Type]++
+	})
+
+	_, err := e.Apply("../../examples/single_k3s_cluster")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 9, events[ResourceCreateStarted])
+	assert.Equal(t, 9, events[ResourceCreateSucceeded])
+	assert.Equal(t, 1, events[ApplyComplete])
+	assert.Equal(t, 0, events[HealthChanged])
+}
+
+func TestApplyPublishesHealthChangedWhenResourceFails(t *testing.T) {
+	e, _ := setupTests(t, map[string]error{"cloud": fmt.Errorf("boom")})
+
+	var health Event
+	var finished Event
+	e.Events().Subscribe(func(ev Event) { health = ev }, HealthChanged)
+	e.Events().Subscribe(func(ev Event) { finished = ev }, ApplyComplete)
+
+	_, err := e.Apply("../../examples/single_k3s_cluster")
+	assert.Error(t, err)
+
+	assert.Equal(t, "cloud", health.Resource.Info().Name)
+	assert.False(t, health.Healthy)
+	assert.Error(t, finished.Error)
+}
+
+func TestApplyPublishesHealthChangedWhenFailedResourceRecovers(t *testing.T) {
+	e, _ := setupTestsWithState(t, nil, failedState)
+
+	var health Event
+	e.Events().Subscribe(func(ev Event) { health = ev }, HealthChanged)
+
+	_, err := e.Apply("")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "dc1", health.Resource.Info().Name)
+	assert.True(t, health.Healthy)
+}
+
 func TestApplySetsStatusForEachResource(t *testing.T) {
 	e, mp := setupTestsWithState(t, nil, mergedState)
 
@@ -256,6 +324,59 @@ func TestApplySetsStatusForEachResource(t *testing.T) {
 	testAssertMethodCalled(t, mp, "Create", 1) // ImageCache is always created
 }
 
+func setupStoppedResources(t *testing.T, finished time.Time) (*EngineImpl, config.Resource, config.Resource) {
+	ct := &clientmocks.MockContainerTasks{}
+	ct.On("ContainerInfo", "running").Return(types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{Running: true}}}, nil)
+	ct.On("ContainerInfo", "stopped").Return(types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{State: &types.ContainerState{FinishedAt: finished.Format(time.RFC3339Nano)}}}, nil)
+
+	e := &EngineImpl{
+		clients: &Clients{ContainerTasks: ct},
+		log:     hclog.NewNullLogger(),
+		events:  NewEventBus(),
+		getProvider: func(c config.Resource, cc *Clients) providers.Provider {
+			m := mocks.New(c)
+			m.On("Lookup").Return([]string{c.Info().Name}, nil)
+			return m
+		},
+	}
+
+	running := config.NewContainer("running")
+	running.Status = config.Applied
+
+	stopped := config.NewContainer("stopped")
+	stopped.Status = config.Applied
+
+	return e, running, stopped
+}
+
+func TestFailStoppedResourcesSetsStatusForStoppedContainers(t *testing.T) {
+	start := time.Now()
+	e, running, stopped := setupStoppedResources(t, start.Add(time.Second))
+
+	d := &dag.AcyclicGraph{}
+	d.Add(running)
+	d.Add(stopped)
+
+	n := e.failStoppedResources(d, start)
+	assert.Equal(t, 1, n)
+
+	assert.Equal(t, config.Applied, running.Info().Status)
+	assert.Equal(t, config.Failed, stopped.Info().Status)
+}
+
+func TestFailStoppedResourcesIgnoresContainersStoppedBeforeApply(t *testing.T) {
+	start := time.Now()
+	e, _, stopped := setupStoppedResources(t, start.Add(-time.Minute))
+
+	d := &dag.AcyclicGraph{}
+	d.Add(stopped)
+
+	n := e.failStoppedResources(d, start)
+	assert.Equal(t, 0, n)
+
+	assert.Equal(t, config.Applied, stopped.Info().Status)
+}
+
 func TestParseConfig(t *testing.T) {
 	e, mp := setupTests(t, nil)
 
@@ -269,6 +390,31 @@ func TestParseConfig(t *testing.T) {
 	testAssertMethodCalled(t, mp, "Destroy", 0)
 }
 
+func TestParseConfigSortsResources(t *testing.T) {
+	e, _ := setupTests(t, nil)
+
+	err := e.ParseConfig("../../examples/single_k3s_cluster")
+	assert.NoError(t, err)
+
+	res := e.(*EngineImpl).config.Resources
+	sorted := sort.SliceIsSorted(res, func(i, j int) bool {
+		a := res[i].Info()
+		b := res[j].Info()
+
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+
+		return a.Name < b.Name
+	})
+
+	assert.True(t, sorted)
+}
+
 func TestParseWithVariables(t *testing.T) {
 	e, mp := setupTests(t, nil)
 
@@ -282,6 +428,206 @@ func TestParseWithVariables(t *testing.T) {
 	testAssertMethodCalled(t, mp, "Destroy", 0)
 }
 
+var mergeBlueprint = `
+container "extra" {
+  image {
+    name = "nginx"
+  }
+
+  network {
+    name = "network.onprem"
+  }
+}
+`
+
+var mergeConflictBlueprint = `
+network "onprem" {
+  subnet = "10.7.0.0/16"
+}
+`
+
+func writeMergeBlueprint(t *testing.T, contents string) string {
+	dir := t.TempDir()
+
+	err := ioutil.WriteFile(filepath.Join(dir, "extra.hcl"), []byte(contents), 0644)
+	assert.NoError(t, err)
+
+	return dir
+}
+
+func TestApplyWithMergeAddsResourcesToEnvironment(t *testing.T) {
+	e, mp := setupTests(t, nil)
+
+	_, err := e.Apply("../../examples/single_file/container.hcl")
+	assert.NoError(t, err)
+
+	dir := writeMergeBlueprint(t, mergeBlueprint)
+	*mp = nil
+
+	e.SetMerge(true)
+	_, err = e.Apply(dir)
+	assert.NoError(t, err)
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err := sc.FindResource("container.extra")
+	assert.NoError(t, err)
+	assert.Equal(t, dir, r.Info().BlueprintSource)
+
+	r, err = sc.FindResource("container.consul")
+	assert.NoError(t, err)
+	assert.Equal(t, config.Applied, r.Info().Status)
+
+	// the resources from the first blueprint are not created again
+	for _, m := range *mp {
+		if m.Config().Info().Name == "consul" || m.Config().Info().Name == "onprem" {
+			m.AssertNotCalled(t, "Create")
+		}
+	}
+}
+
+func TestApplyWithMergeReturnsErrorForConflictingResources(t *testing.T) {
+	e, _ := setupTests(t, nil)
+
+	_, err := e.Apply("../../examples/single_file/container.hcl")
+	assert.NoError(t, err)
+
+	dir := writeMergeBlueprint(t, mergeConflictBlueprint)
+
+	e.SetMerge(true)
+	_, err = e.Apply(dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "network.onprem is defined in")
+}
+
+func TestApplyWithoutMergeUpdatesResourcesFromOtherBlueprints(t *testing.T) {
+	e, _ := setupTests(t, nil)
+
+	_, err := e.Apply("../../examples/single_file/container.hcl")
+	assert.NoError(t, err)
+
+	dir := writeMergeBlueprint(t, mergeConflictBlueprint)
+
+	_, err = e.Apply(dir)
+	assert.NoError(t, err)
+}
+
+func TestWriteStateWithMergeKeepsResourcesAddedByOtherProcesses(t *testing.T) {
+	e, _ := setupTests(t, nil)
+	e.SetMerge(true)
+
+	ei := e.(*EngineImpl)
+	ei.config = config.New()
+	ei.config.AddResource(config.NewContainer("mine"))
+
+	// another process adds a resource to the state
+	other := config.New()
+	other.AddResource(config.NewContainer("mine"))
+	other.AddResource(config.NewContainer("theirs"))
+	err := other.ToJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	err = ei.writeState()
+	assert.NoError(t, err)
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	assert.Len(t, sc.Resources, 2)
+	assert.Len(t, ei.config.Resources, 1)
+	assert.NoFileExists(t, filepath.Join(utils.StateDir(), "state.lock"))
+}
+
+var updateBlueprint = `
+container "api" {
+  image {
+    name = "%s"
+  }
+
+  env_var = {
+    MESSAGE = "%s"
+  }
+}
+`
+
+func TestApplyRecreatesContainerWhenEnvironmentChanges(t *testing.T) {
+	e, mp := setupTests(t, nil)
+
+	dir := writeMergeBlueprint(t, fmt.Sprintf(updateBlueprint, "nginx", "hello"))
+
+	_, err := e.Apply(dir)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "extra.hcl"), []byte(fmt.Sprintf(updateBlueprint, "nginx", "world")), 0644)
+	assert.NoError(t, err)
+
+	*mp = nil
+	_, err = e.Apply(dir)
+	assert.NoError(t, err)
+
+	for _, m := range *mp {
+		if m.Config().Info().Name == "api" {
+			m.AssertCalled(t, "Destroy")
+			m.AssertCalled(t, "Create")
+		}
+	}
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err := sc.FindResource("container.api")
+	assert.NoError(t, err)
+
+	h, err := config.ConfigHash(r)
+	assert.NoError(t, err)
+	assert.Equal(t, h, r.Info().ConfigHash)
+	assert.Equal(t, config.Applied, r.Info().Status)
+}
+
+func TestApplyDoesNotRecreateContainerWhenOtherAttributesChange(t *testing.T) {
+	e, mp := setupTests(t, nil)
+
+	dir := writeMergeBlueprint(t, fmt.Sprintf(updateBlueprint, "nginx", "hello"))
+
+	_, err := e.Apply(dir)
+	assert.NoError(t, err)
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err := sc.FindResource("container.api")
+	assert.NoError(t, err)
+	applied := r.Info().ConfigHash
+
+	err = ioutil.WriteFile(filepath.Join(dir, "extra.hcl"), []byte(fmt.Sprintf(updateBlueprint, "consul", "world")), 0644)
+	assert.NoError(t, err)
+
+	*mp = nil
+	_, err = e.Apply(dir)
+	assert.NoError(t, err)
+
+	for _, m := range *mp {
+		if m.Config().Info().Name == "api" {
+			m.AssertNotCalled(t, "Destroy")
+			m.AssertNotCalled(t, "Create")
+		}
+	}
+
+	// the applied hash is kept so that the change is detected until applied
+	sc = config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err = sc.FindResource("container.api")
+	assert.NoError(t, err)
+	assert.Equal(t, applied, r.Info().ConfigHash)
+}
+
 func TestDestroyCallsProviderDestroyForEachProvider(t *testing.T) {
 	e, mp := setupTests(t, nil)
 
@@ -320,6 +666,85 @@ func TestDestroyCallsProviderGenerateErrorStopsExecution(t *testing.T) {
 	testAssertMethodCalled(t, mp, "Destroy", 7)
 }
 
+func TestApplySetsDefaultPortBindFromUserConfig(t *testing.T) {
+	e, _ := setupTests(t, nil)
+
+	ct := &clientmocks.MockContainerTasks{}
+	ct.On("SetDefaultPortBind", "127.0.0.1").Return()
+	e.(*EngineImpl).clients.ContainerTasks = ct
+
+	os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+	err := ioutil.WriteFile(utils.UserConfigPath(), []byte("ports {\n  bind = \"127.0.0.1\"\n}\n"), os.ModePerm)
+	assert.NoError(t, err)
+
+	_, err = e.Apply("../../examples/single_file/container.hcl")
+	assert.NoError(t, err)
+
+	ct.AssertCalled(t, "SetDefaultPortBind", "127.0.0.1")
+}
+
+func TestApplyReturnsErrorWhenQuotaExceeded(t *testing.T) {
+	// the workspace already contains a container
+	e, mp := setupTestsWithState(t, nil, existingContainerState)
+
+	os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+	err := ioutil.WriteFile(utils.UserConfigPath(), []byte("quota {\n  max_containers = 1\n}\n"), os.ModePerm)
+	assert.NoError(t, err)
+
+	_, err = e.Apply("../../examples/single_file/container.hcl")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "2 containers (max_containers 1)")
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+}
+
+func TestApplyCreatesResourcesWithinQuota(t *testing.T) {
+	e, _ := setupTests(t, nil)
+
+	os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+	err := ioutil.WriteFile(utils.UserConfigPath(), []byte("quota {\n  max_containers = 1\n}\n"), os.ModePerm)
+	assert.NoError(t, err)
+
+	_, err = e.Apply("../../examples/single_file/container.hcl")
+	assert.NoError(t, err)
+}
+
+// windowsContainerTasks are ContainerTasks for an engine running Windows containers
+type windowsContainerTasks struct {
+	*clientmocks.MockContainerTasks
+}
+
+func (w *windowsContainerTasks) Capabilities() *clients.EngineCapabilities {
+	return &clients.EngineCapabilities{OS: "windows"}
+}
+
+func setupWindowsEngine(t *testing.T) (Engine, *[]*mocks.MockProvider) {
+	e, mp := setupTests(t, nil)
+
+	ct := &clientmocks.MockContainerTasks{}
+	ct.On("SetDefaultPortBind", mock.Anything).Return()
+	e.(*EngineImpl).clients.ContainerTasks = &windowsContainerTasks{ct}
+
+	return e, mp
+}
+
+func TestApplyReturnsErrorForLinuxOnlyResourcesOnWindowsEngine(t *testing.T) {
+	e, mp := setupWindowsEngine(t)
+
+	_, err := e.Apply("../../examples/single_k3s_cluster")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "resources require Linux containers")
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+}
+
+func TestApplyCreatesContainersOnWindowsEngine(t *testing.T) {
+	e, _ := setupWindowsEngine(t)
+
+	_, err := e.Apply("../../examples/single_file/container.hcl")
+	assert.NoError(t, err)
+}
+
 func TestDestroyFailSetsStatus(t *testing.T) {
 	e, mp := setupTests(t, map[string]error{"cloud": fmt.Errorf("boom")})
 
@@ -427,3 +852,35 @@ var disabledState = `
   ]
 }
 `
+
+var pausedState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "vault",
+      "status": "paused",
+      "type": "container",
+      "image": {
+        "name": "vault:1.6.1"
+      }
+	}
+  ]
+}
+`
+
+var existingContainerState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "vault",
+      "status": "applied",
+      "type": "container",
+      "image": {
+        "name": "vault:1.6.1"
+      }
+	}
+  ]
+}
+`
@@ -0,0 +1,131 @@
+package shipyard
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// EventType defines the type of an event published by the engine
+type EventType string
+
+// ApplyStarted is published before any resources in an apply are processed, the
+// Total field of the event contains the number of resources which will be created
+const ApplyStarted EventType = "apply_started"
+
+// ResourceCreateStarted is published before the provider for a resource is called
+const ResourceCreateStarted EventType = "resource_create_started"
+
+// ResourceCreateSucceeded is published after the provider for a resource has
+// created the resource
+const ResourceCreateSucceeded EventType = "resource_create_succeeded"
+
+// ResourceCreateFailed is published when the provider for a resource returns
+// an error, the Error field of the event is set
+const ResourceCreateFailed EventType = "resource_create_failed"
+
+// ApplyComplete is published when all resources in an apply have been processed,
+// if the apply failed the Error field of the event is set
+const ApplyComplete EventType = "apply_complete"
+
+// DestroyComplete is published when all resources in a destroy have been processed,
+// if the destroy failed the Error field of the event is set
+const DestroyComplete EventType = "destroy_complete"
+
+// HealthChanged is published when the health of a resource changes
+const HealthChanged EventType = "health_changed"
+
+// Event is a message published on the EventBus
+type Event struct {
+	Type     EventType
+	Time     time.Time
+	Resource config.Resource // Resource the event relates to, nil for ApplyStarted, ApplyComplete, and DestroyComplete
+	Healthy  bool            // Healthy is set for HealthChanged events
+	Total    int             // Total number of resources which will be created, set for ApplyStarted events
+	Error    error           // Error contains any error which occurred when processing the resource or apply
+}
+
+// EventHandler is a function which is called when an event is published
+type EventHandler func(e Event)
+
+// EventBus allows extensions such as the TUI, webhooks, and telemetry
+// to subscribe to events published by the engine
+type EventBus struct {
+	sync        sync.RWMutex
+	subscribers []subscriber
+	nextID      int
+}
+
+type subscriber struct {
+	id      int
+	handler EventHandler
+	types   []EventType
+}
+
+// NewEventBus creates a new EventBus
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers the handler for the given event types, if no types are
+// specified the handler receives all events.
+// The returned function removes the subscription.
+func (b *EventBus) Subscribe(h EventHandler, types ...EventType) func() {
+	b.sync.Lock()
+	defer b.sync.Unlock()
+
+	id := b.nextID
+	b.nextID++
+
+	b.subscribers = append(b.subscribers, subscriber{id: id, handler: h, types: types})
+
+	return func() {
+		b.sync.Lock()
+		defer b.sync.Unlock()
+
+		for i, s := range b.subscribers {
+			if s.id == id {
+				b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish sends the event to all subscribers of its type, handlers are called
+// synchronously in the order they were registered
+func (b *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	// copy the handlers so that a handler can subscribe or unsubscribe
+	// without deadlocking the bus
+	b.sync.RLock()
+	handlers := []EventHandler{}
+	for _, s := range b.subscribers {
+		if s.handles(e.Type) {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	b.sync.RUnlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
+
+func (s subscriber) handles(t EventType) bool {
+	if len(s.types) == 0 {
+		return true
+	}
+
+	for _, st := range s.types {
+		if st == t {
+			return true
+		}
+	}
+
+	return false
+}
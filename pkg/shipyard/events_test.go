@@ -0,0 +1,55 @@
+package shipyard
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestEventBusPublishesToAllSubscribers(t *testing.T) {
+	eb := NewEventBus()
+
+	received := []EventType{}
+	eb.Subscribe(func(e Event) { received = append(received, e.Type) })
+	eb.Subscribe(func(e Event) { received = append(received, e.Type) })
+
+	eb.Publish(Event{Type: ApplyComplete})
+
+	assert.Equal(t, []EventType{ApplyComplete, ApplyComplete}, received)
+}
+
+func TestEventBusPublishesOnlySubscribedTypes(t *testing.T) {
+	eb := NewEventBus()
+
+	received := []EventType{}
+	eb.Subscribe(func(e Event) { received = append(received, e.Type) }, ResourceCreateSucceeded)
+
+	eb.Publish(Event{Type: ResourceCreateStarted})
+	eb.Publish(Event{Type: ResourceCreateSucceeded})
+
+	assert.Equal(t, []EventType{ResourceCreateSucceeded}, received)
+}
+
+func TestEventBusSetsEventTime(t *testing.T) {
+	eb := NewEventBus()
+
+	var received Event
+	eb.Subscribe(func(e Event) { received = e })
+
+	eb.Publish(Event{Type: ApplyComplete})
+
+	assert.False(t, received.Time.IsZero())
+}
+
+func TestEventBusUnsubscribeRemovesHandler(t *testing.T) {
+	eb := NewEventBus()
+
+	count := 0
+	unsubscribe := eb.Subscribe(func(e Event) { count++ })
+
+	eb.Publish(Event{Type: ApplyComplete})
+	unsubscribe()
+	eb.Publish(Event{Type: ApplyComplete})
+
+	assert.Equal(t, 1, count)
+}
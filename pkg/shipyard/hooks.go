@@ -0,0 +1,99 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// hookCommand creates the command used to execute a hook,
+// it is replaced in tests
+var hookCommand = exec.CommandContext
+
+// runHooks executes the hooks from the user config for the given event,
+// hooks are run in the order they are defined and stop at the first failure.
+// The details of the blueprint are passed to the hooks as environment variables,
+// runErr is the result of the apply or destroy for post hooks.
+func (e *EngineImpl) runHooks(event, path string, runErr error) error {
+	uc, err := config.LoadUserConfig(utils.UserConfigPath())
+	if err != nil {
+		return fmt.Errorf("Unable to load user config: %s", err)
+	}
+
+	for _, h := range uc.HooksForEvent(event) {
+		err := e.runHook(h, path, runErr)
+		if err != nil {
+			return fmt.Errorf("Hook '%s' for event %s failed: %s", h.Name, event, err)
+		}
+	}
+
+	return nil
+}
+
+func (e *EngineImpl) runHook(h config.Hook, path string, runErr error) error {
+	e.log.Info("Running hook", "name", h.Name, "event", h.Event)
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.TimeoutDuration())
+	defer cancel()
+
+	out := e.log.Named("hook").Named(h.Name).StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Info})
+
+	cmd := hookCommand(ctx, h.Command, h.Args...)
+	cmd.Env = append(os.Environ(), e.hookEnv(h, path, runErr)...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", h.TimeoutDuration())
+	}
+
+	return err
+}
+
+// hookEnv returns the environment variables which describe the blueprint
+// and the result of the run to the hook
+func (e *EngineImpl) hookEnv(h config.Hook, path string, runErr error) []string {
+	env := []string{
+		fmt.Sprintf("SHIPYARD_HOOK_NAME=%s", h.Name),
+		fmt.Sprintf("SHIPYARD_HOOK_EVENT=%s", h.Event),
+		fmt.Sprintf("SHIPYARD_BLUEPRINT=%s", path),
+		fmt.Sprintf("SHIPYARD_STATE=%s", utils.StatePath()),
+	}
+
+	if e.config != nil {
+		if e.config.Blueprint != nil {
+			env = append(env, fmt.Sprintf("SHIPYARD_BLUEPRINT_TITLE=%s", e.config.Blueprint.Title))
+		}
+
+		ids := []string{}
+		for _, r := range e.config.Resources {
+			if r.Info().Status != config.Disabled {
+				ids = append(ids, fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name))
+			}
+		}
+
+		env = append(env, fmt.Sprintf("SHIPYARD_RESOURCES=%s", strings.Join(ids, ",")))
+	}
+
+	// post hooks are told if the run succeeded
+	if h.Event == config.HookPostRun || h.Event == config.HookPostDestroy {
+		if runErr != nil {
+			env = append(env, "SHIPYARD_STATUS=failed", fmt.Sprintf("SHIPYARD_ERROR=%s", runErr))
+		} else {
+			env = append(env, "SHIPYARD_STATUS=success")
+		}
+	}
+
+	for k, v := range h.EnvVar {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	return env
+}
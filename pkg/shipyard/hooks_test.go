@@ -0,0 +1,166 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+// setupHooks writes the user config and replaces the hook command, the commands
+// which are run are recorded and exit with the result of the command name
+func setupHooks(t *testing.T, userConfig string, fail bool) *[]*exec.Cmd {
+	os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+	err := ioutil.WriteFile(utils.UserConfigPath(), []byte(userConfig), os.ModePerm)
+	assert.NoError(t, err)
+
+	cmds := []*exec.Cmd{}
+	hookCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		c := exec.CommandContext(ctx, "true")
+		if fail {
+			c = exec.CommandContext(ctx, "false")
+		}
+
+		cmds = append(cmds, c)
+		return c
+	}
+
+	t.Cleanup(func() { hookCommand = exec.CommandContext })
+
+	return &cmds
+}
+
+func TestApplyRunsPreAndPostRunHooks(t *testing.T) {
+	e, _ := setupTests(t, nil)
+	cmds := setupHooks(t, userConfigRunHooks, false)
+
+	_, err := e.Apply("../../examples/single_file/container.hcl")
+	assert.NoError(t, err)
+
+	assert.Len(t, *cmds, 2)
+	assert.Contains(t, (*cmds)[0].Env, "SHIPYARD_HOOK_EVENT=pre_run")
+	assert.Contains(t, (*cmds)[0].Env, "SHIPYARD_HOOK_NAME=compliance")
+	assert.Contains(t, (*cmds)[0].Env, "TEAM=platform")
+
+	assert.Contains(t, (*cmds)[1].Env, "SHIPYARD_HOOK_EVENT=post_run")
+	assert.Contains(t, (*cmds)[1].Env, "SHIPYARD_STATUS=success")
+}
+
+func TestApplyPassesBlueprintDetailsToHooks(t *testing.T) {
+	e, _ := setupTests(t, nil)
+	cmds := setupHooks(t, userConfigRunHooks, false)
+
+	_, err := e.Apply("../../examples/single_file/container.hcl")
+	assert.NoError(t, err)
+
+	env := (*cmds)[0].Env
+	assert.Contains(t, env, fmt.Sprintf("SHIPYARD_STATE=%s", utils.StatePath()))
+
+	resources := ""
+	for _, v := range env {
+		if strings.HasPrefix(v, "SHIPYARD_RESOURCES=") {
+			resources = strings.TrimPrefix(v, "SHIPYARD_RESOURCES=")
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"network.onprem", "container.consul", "image_cache.docker-cache"}, strings.Split(resources, ","))
+}
+
+func TestApplyPreRunHookFailureStopsApply(t *testing.T) {
+	e, mp := setupTests(t, nil)
+	cmds := setupHooks(t, userConfigRunHooks, true)
+
+	_, err := e.Apply("../../examples/single_file/container.hcl")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Hook 'compliance' for event pre_run failed")
+
+	// the post run hook is not run
+	assert.Len(t, *cmds, 1)
+	testAssertMethodCalled(t, mp, "Create", 0)
+}
+
+func TestApplyPostRunHookReceivesError(t *testing.T) {
+	e, _ := setupTests(t, map[string]error{"consul": fmt.Errorf("boom")})
+	cmds := setupHooks(t, userConfigRunHooks, false)
+
+	_, err := e.Apply("../../examples/single_file/container.hcl")
+	assert.Error(t, err)
+
+	assert.Contains(t, (*cmds)[1].Env, "SHIPYARD_STATUS=failed")
+}
+
+func TestApplyWithInvalidHookReturnsError(t *testing.T) {
+	e, mp := setupTests(t, nil)
+	setupHooks(t, userConfigInvalidEvent, false)
+
+	_, err := e.Apply("../../examples/single_file/container.hcl")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid event 'before_run'")
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+}
+
+func TestDestroyRunsPreAndPostDestroyHooks(t *testing.T) {
+	e, _ := setupTests(t, nil)
+	cmds := setupHooks(t, userConfigDestroyHooks, false)
+
+	err := e.Destroy("../../examples/single_k3s_cluster", true)
+	assert.NoError(t, err)
+
+	assert.Len(t, *cmds, 2)
+	assert.Contains(t, (*cmds)[0].Env, "SHIPYARD_HOOK_EVENT=pre_destroy")
+	assert.Contains(t, (*cmds)[1].Env, "SHIPYARD_HOOK_EVENT=post_destroy")
+	assert.Contains(t, (*cmds)[1].Env, "SHIPYARD_STATUS=success")
+}
+
+func TestDestroyPreDestroyHookFailureStopsDestroy(t *testing.T) {
+	e, mp := setupTests(t, nil)
+	setupHooks(t, userConfigDestroyHooks, true)
+
+	err := e.Destroy("../../examples/single_k3s_cluster", true)
+	assert.Error(t, err)
+
+	testAssertMethodCalled(t, mp, "Destroy", 0)
+}
+
+const userConfigRunHooks = `
+hook "compliance" {
+  event   = "pre_run"
+  command = "/usr/local/bin/compliance"
+  args    = ["--check"]
+
+  env_var = {
+    TEAM = "platform"
+  }
+}
+
+hook "inventory" {
+  event   = "post_run"
+  command = "/usr/local/bin/inventory"
+}
+`
+
+const userConfigDestroyHooks = `
+hook "before" {
+  event   = "pre_destroy"
+  command = "/usr/local/bin/before"
+}
+
+hook "inventory" {
+  event   = "post_destroy"
+  command = "/usr/local/bin/inventory"
+}
+`
+
+const userConfigInvalidEvent = `
+hook "compliance" {
+  event   = "before_run"
+  command = "/usr/local/bin/compliance"
+}
+`
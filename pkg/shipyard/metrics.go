@@ -0,0 +1,266 @@
+package shipyard
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// metricBuckets are the upper bounds in seconds of the histogram buckets for
+// the apply, resource, and image pull durations
+var metricBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600}
+
+// Metrics records the time spent applying resources using the events published by
+// the engine, and the image pulls made by the ContainerTasks, so that the activity
+// of the engine can be scraped by Prometheus
+type Metrics struct {
+	sync  sync.Mutex
+	pulls *clients.ImagePulls
+
+	applyStarted time.Time
+	applies      map[string]uint64 // completed applies keyed by result
+	destroys     map[string]uint64 // completed destroys keyed by result
+	applyTimes   *histogram
+
+	started   map[string]time.Time // resources being created keyed by id
+	resources map[config.ResourceType]*histogram
+	failures  map[config.ResourceType]uint64
+
+	// state returns the resources in the environment, defaults to reading the state file
+	state func() []config.Resource
+}
+
+// NewMetrics creates Metrics which subscribe to the events on the given bus,
+// the returned function removes the subscription
+func NewMetrics(b *EventBus, pulls *clients.ImagePulls) (*Metrics, func()) {
+	m := &Metrics{
+		pulls:      pulls,
+		applies:    map[string]uint64{},
+		destroys:   map[string]uint64{},
+		applyTimes: newHistogram(),
+		started:    map[string]time.Time{},
+		resources:  map[config.ResourceType]*histogram{},
+		failures:   map[config.ResourceType]uint64{},
+		state:      stateResources,
+	}
+
+	if b == nil {
+		return m, func() {}
+	}
+
+	return m, b.Subscribe(m.handle, ApplyStarted, ResourceCreateStarted, ResourceCreateSucceeded, ResourceCreateFailed, ApplyComplete, DestroyComplete)
+}
+
+func (m *Metrics) handle(e Event) {
+	m.sync.Lock()
+	defer m.sync.Unlock()
+
+	switch e.Type {
+	case ApplyStarted:
+		m.applyStarted = e.Time
+		m.started = map[string]time.Time{}
+
+	case ResourceCreateStarted:
+		i := e.Resource.Info()
+		m.started[config.ResourceID(i.Module, i.Type, i.Name)] = e.Time
+
+	case ResourceCreateSucceeded, ResourceCreateFailed:
+		i := e.Resource.Info()
+		id := config.ResourceID(i.Module, i.Type, i.Name)
+
+		if s, ok := m.started[id]; ok {
+			if m.resources[i.Type] == nil {
+				m.resources[i.Type] = newHistogram()
+			}
+
+			m.resources[i.Type].observe(e.Time.Sub(s).Seconds())
+			delete(m.started, id)
+		}
+
+		if e.Type == ResourceCreateFailed {
+			m.failures[i.Type]++
+		}
+
+	case ApplyComplete:
+		m.applies[metricResult(e.Error)]++
+
+		if !m.applyStarted.IsZero() {
+			m.applyTimes.observe(e.Time.Sub(m.applyStarted).Seconds())
+			m.applyStarted = time.Time{}
+		}
+
+	case DestroyComplete:
+		m.destroys[metricResult(e.Error)]++
+	}
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) {
+	m.sync.Lock()
+	defer m.sync.Unlock()
+
+	writeCounters(w, "shipyard_applies_total", "Applies which have completed", "result", m.applies)
+	m.applyTimes.write(w, "shipyard_apply_duration_seconds", "Time taken to apply a blueprint", "")
+
+	writeCounters(w, "shipyard_destroys_total", "Destroys which have completed", "result", m.destroys)
+
+	fmt.Fprintln(w, "# HELP shipyard_resource_create_duration_seconds Time taken to create resources")
+	fmt.Fprintln(w, "# TYPE shipyard_resource_create_duration_seconds histogram")
+	for _, t := range sortedTypes(m.resources) {
+		m.resources[t].writeSeries(w, "shipyard_resource_create_duration_seconds", fmt.Sprintf("type=%q", t))
+	}
+
+	failures := map[string]uint64{}
+	for t, f := range m.failures {
+		failures[string(t)] = f
+	}
+	writeCounters(w, "shipyard_resource_create_failures_total", "Resources which failed to be created", "type", failures)
+
+	m.writePulls(w)
+
+	active := 0
+	for _, r := range m.state() {
+		if r.Info().Status == config.Applied {
+			active = 1
+			break
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP shipyard_active_environments Environments which have applied resources")
+	fmt.Fprintln(w, "# TYPE shipyard_active_environments gauge")
+	fmt.Fprintf(w, "shipyard_active_environments %d\n", active)
+}
+
+// writePulls writes the metrics for the image pulls
+func (m *Metrics) writePulls(w io.Writer) {
+	h := newHistogram()
+	failures := map[string]uint64{"": 0}
+	var bytes int64
+
+	for _, r := range m.pulls.Records() {
+		if r.Error != "" {
+			failures[""]++
+			continue
+		}
+
+		h.observe(r.Duration.Seconds())
+		bytes += r.Bytes
+	}
+
+	h.write(w, "shipyard_image_pull_duration_seconds", "Time taken to pull images", "")
+	writeCounters(w, "shipyard_image_pull_failures_total", "Image pulls which failed", "", failures)
+
+	fmt.Fprintln(w, "# HELP shipyard_image_pull_bytes_total Bytes downloaded by image pulls")
+	fmt.Fprintln(w, "# TYPE shipyard_image_pull_bytes_total counter")
+	fmt.Fprintf(w, "shipyard_image_pull_bytes_total %d\n", bytes)
+}
+
+// stateResources returns the resources in the state file
+func stateResources() []config.Resource {
+	c := config.New()
+
+	err := c.FromJSON(utils.StatePath())
+	if err != nil {
+		return nil
+	}
+
+	return c.Resources
+}
+
+func metricResult(err error) string {
+	if err != nil {
+		return "failure"
+	}
+
+	return "success"
+}
+
+// writeCounters writes a counter with a series for each value of the label,
+// a value without a label name is written without labels
+func writeCounters(w io.Writer, name, help, label string, values map[string]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+
+	keys := []string{}
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if label == "" {
+			fmt.Fprintf(w, "%s %d\n", name, values[k])
+			continue
+		}
+
+		fmt.Fprintf(w, "%s{%s=%q} %d\n", name, label, k, values[k])
+	}
+}
+
+func sortedTypes(m map[config.ResourceType]*histogram) []config.ResourceType {
+	types := []config.ResourceType{}
+	for t := range m {
+		types = append(types, t)
+	}
+
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	return types
+}
+
+// histogram is a cumulative Prometheus histogram with the metricBuckets
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(metricBuckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range metricBuckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+
+	h.count++
+	h.sum += v
+}
+
+// write the histogram with the help and type
+func (h *histogram) write(w io.Writer, name, help, labels string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+
+	h.writeSeries(w, name, labels)
+}
+
+// writeSeries writes the buckets, sum, and count of the histogram
+func (h *histogram) writeSeries(w io.Writer, name, labels string) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+
+	for i, b := range metricBuckets {
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"%g\"} %d\n", name, labels, sep, b, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+
+	suffix := ""
+	if labels != "" {
+		suffix = "{" + labels + "}"
+	}
+
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, suffix, h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, suffix, h.count)
+}
@@ -0,0 +1,95 @@
+package shipyard
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupMetrics(t *testing.T) (*Metrics, *EventBus, *clients.ImagePulls) {
+	eb := NewEventBus()
+	ip := clients.NewImagePulls()
+
+	m, unsubscribe := NewMetrics(eb, ip)
+	t.Cleanup(unsubscribe)
+
+	m.state = func() []config.Resource { return nil }
+
+	return m, eb, ip
+}
+
+func writeMetrics(m *Metrics) string {
+	out := bytes.NewBuffer(nil)
+	m.WritePrometheus(out)
+
+	return out.String()
+}
+
+func TestMetricsRecordsResourceDurations(t *testing.T) {
+	m, eb, _ := setupMetrics(t)
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := config.NewContainer("web")
+	k := config.NewK8sCluster("k3s")
+
+	eb.Publish(Event{Type: ApplyStarted, Total: 2, Time: now})
+	eb.Publish(Event{Type: ResourceCreateStarted, Resource: c, Time: now})
+	eb.Publish(Event{Type: ResourceCreateStarted, Resource: k, Time: now})
+	eb.Publish(Event{Type: ResourceCreateSucceeded, Resource: c, Time: now.Add(3 * time.Second)})
+	eb.Publish(Event{Type: ResourceCreateFailed, Resource: k, Time: now.Add(90 * time.Second), Error: fmt.Errorf("boom")})
+	eb.Publish(Event{Type: ApplyComplete, Time: now.Add(100 * time.Second), Error: fmt.Errorf("boom")})
+
+	out := writeMetrics(m)
+
+	assert.Contains(t, out, `shipyard_applies_total{result="failure"} 1`)
+	assert.Contains(t, out, `shipyard_apply_duration_seconds_bucket{le="120"} 1`)
+	assert.Contains(t, out, `shipyard_apply_duration_seconds_bucket{le="60"} 0`)
+	assert.Contains(t, out, "shipyard_apply_duration_seconds_sum 100\n")
+
+	assert.Contains(t, out, `shipyard_resource_create_duration_seconds_bucket{type="container",le="5"} 1`)
+	assert.Contains(t, out, `shipyard_resource_create_duration_seconds_bucket{type="container",le="1"} 0`)
+	assert.Contains(t, out, `shipyard_resource_create_duration_seconds_sum{type="container"} 3`)
+	assert.Contains(t, out, `shipyard_resource_create_duration_seconds_count{type="k8s_cluster"} 1`)
+
+	assert.Contains(t, out, `shipyard_resource_create_failures_total{type="k8s_cluster"} 1`)
+	assert.NotContains(t, out, `shipyard_resource_create_failures_total{type="container"}`)
+}
+
+func TestMetricsRecordsDestroys(t *testing.T) {
+	m, eb, _ := setupMetrics(t)
+
+	eb.Publish(Event{Type: DestroyComplete})
+
+	assert.Contains(t, writeMetrics(m), `shipyard_destroys_total{result="success"} 1`)
+}
+
+func TestMetricsRecordsImagePulls(t *testing.T) {
+	m, _, ip := setupMetrics(t)
+
+	ip.Record(clients.ImagePullRecord{Image: "consul:1.10.0", Duration: 20 * time.Second, Bytes: 1024})
+	ip.Record(clients.ImagePullRecord{Image: "vault:1.8.0", Duration: 2 * time.Second, Error: "boom"})
+
+	out := writeMetrics(m)
+
+	assert.Contains(t, out, `shipyard_image_pull_duration_seconds_bucket{le="30"} 1`)
+	assert.Contains(t, out, "shipyard_image_pull_duration_seconds_count 1\n")
+	assert.Contains(t, out, "shipyard_image_pull_failures_total 1\n")
+	assert.Contains(t, out, "shipyard_image_pull_bytes_total 1024\n")
+}
+
+func TestMetricsReportsActiveEnvironment(t *testing.T) {
+	m, _, _ := setupMetrics(t)
+
+	assert.Contains(t, writeMetrics(m), "shipyard_active_environments 0\n")
+
+	c := config.NewContainer("web")
+	c.Status = config.Applied
+	m.state = func() []config.Resource { return []config.Resource{c} }
+
+	assert.Contains(t, writeMetrics(m), "shipyard_active_environments 1\n")
+}
@@ -73,3 +73,19 @@ func (e *Engine) ParseConfigWithVariables(path string, vars map[string]string, v
 	args := e.Called(path, vars, varsFile)
 	return args.Error(0)
 }
+
+func (e *Engine) Events() *shipyard.EventBus {
+	if eb, ok := e.Called().Get(0).(*shipyard.EventBus); ok {
+		return eb
+	}
+
+	return nil
+}
+
+func (e *Engine) SetNotifications(n []config.Notification) {
+	e.Called(n)
+}
+
+func (e *Engine) SetMerge(merge bool) {
+	e.Called(merge)
+}
@@ -0,0 +1,277 @@
+package shipyard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// EventTypes are the types of all the events published by the engine
+var EventTypes = []EventType{
+	ApplyStarted,
+	ResourceCreateStarted,
+	ResourceCreateSucceeded,
+	ResourceCreateFailed,
+	ApplyComplete,
+	DestroyComplete,
+	HealthChanged,
+}
+
+// notificationQueueSize is the number of events which can be waiting to be
+// sent before events are dropped
+const notificationQueueSize = 256
+
+// notificationTimeout is the maximum time to send a single event and the
+// time to wait for queued events to be sent when the notifier is stopped
+var notificationTimeout = 10 * time.Second
+
+// EventPayload is the JSON representation of an event which is sent to webhooks
+type EventPayload struct {
+	Type      EventType     `json:"type"`
+	Time      time.Time     `json:"time"`
+	Blueprint string        `json:"blueprint,omitempty"` // title of the blueprint
+	Resource  string        `json:"resource,omitempty"`  // e.g. container.consul
+	Module    string        `json:"module,omitempty"`
+	Status    config.Status `json:"status,omitempty"`
+	Total     int           `json:"total,omitempty"`   // set for apply_started
+	Healthy   *bool         `json:"healthy,omitempty"` // set for health_changed
+	Error     string        `json:"error,omitempty"`
+}
+
+// NewEventPayload creates the payload for the event
+func NewEventPayload(e Event, blueprint string) EventPayload {
+	p := EventPayload{Type: e.Type, Time: e.Time, Blueprint: blueprint, Total: e.Total}
+
+	if e.Resource != nil {
+		i := e.Resource.Info()
+		p.Resource = fmt.Sprintf("%s.%s", i.Type, i.Name)
+		p.Module = i.Module
+		p.Status = i.Status
+	}
+
+	if e.Type == HealthChanged {
+		h := e.Healthy
+		p.Healthy = &h
+	}
+
+	if e.Error != nil {
+		p.Error = e.Error.Error()
+	}
+
+	return p
+}
+
+// Notifier sends the events published on the EventBus to the webhooks defined by
+// notifications. Events are sent in the background so that a slow webhook does
+// not slow down the apply, failures to send an event are logged and do not fail
+// the apply.
+type Notifier struct {
+	sinks     []config.Notification
+	blueprint string
+	hc        clients.HTTP
+	log       hclog.Logger
+
+	queue chan notification
+	wg    sync.WaitGroup
+}
+
+type notification struct {
+	sink    config.Notification
+	payload EventPayload
+}
+
+// NewNotifier creates a Notifier for the notifications, blueprint is the title
+// of the blueprint which is added to each event
+func NewNotifier(sinks []config.Notification, blueprint string, hc clients.HTTP, l hclog.Logger) (*Notifier, error) {
+	for _, s := range sinks {
+		err := s.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("Notification '%s' %s", s.Name, err)
+		}
+
+		for _, e := range s.Events {
+			if !validEventType(EventType(e)) {
+				return nil, fmt.Errorf("Notification '%s' has an invalid event '%s', events must be one of %s", s.Name, e, eventTypeNames())
+			}
+		}
+	}
+
+	return &Notifier{
+		sinks:     sinks,
+		blueprint: blueprint,
+		hc:        hc,
+		log:       l,
+		queue:     make(chan notification, notificationQueueSize),
+	}, nil
+}
+
+// Subscribe starts sending the events published on the bus, the returned function
+// stops the notifier and waits for the queued events to be sent
+func (n *Notifier) Subscribe(b *EventBus) func() {
+	n.wg.Add(1)
+	go n.send()
+
+	unsubscribe := b.Subscribe(n.handle)
+
+	return func() {
+		unsubscribe()
+		close(n.queue)
+
+		done := make(chan struct{})
+		go func() {
+			n.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(notificationTimeout):
+			n.log.Warn("Timeout waiting for notifications to be sent")
+		}
+	}
+}
+
+func (n *Notifier) handle(e Event) {
+	p := NewEventPayload(e, n.blueprint)
+
+	for _, s := range n.sinks {
+		if !sinkHandles(s, e.Type) {
+			continue
+		}
+
+		select {
+		case n.queue <- notification{s, p}:
+		default:
+			n.log.Warn("Notification queue is full, dropping event", "notification", s.Name, "event", e.Type)
+		}
+	}
+}
+
+func (n *Notifier) send() {
+	defer n.wg.Done()
+
+	for nt := range n.queue {
+		err := n.post(nt)
+		if err != nil {
+			n.log.Warn("Unable to send notification", "notification", nt.sink.Name, "event", nt.payload.Type, "error", err)
+		}
+	}
+}
+
+func (n *Notifier) post(nt notification) error {
+	var body interface{} = nt.payload
+	if nt.sink.NotificationType() == config.NotificationSlack {
+		body = map[string]string{"text": slackMessage(nt.payload)}
+	}
+
+	d, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, nt.sink.URL, bytes.NewReader(d))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range nt.sink.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// slackMessage returns the text of the Slack message for the event
+func slackMessage(p EventPayload) string {
+	prefix := "Shipyard"
+	if p.Blueprint != "" {
+		prefix = fmt.Sprintf("Shipyard [%s]", p.Blueprint)
+	}
+
+	var msg string
+	switch p.Type {
+	case ApplyStarted:
+		msg = fmt.Sprintf("Apply started, creating %d resources", p.Total)
+	case ResourceCreateStarted:
+		msg = fmt.Sprintf("Creating `%s`", p.Resource)
+	case ResourceCreateSucceeded:
+		msg = fmt.Sprintf(":white_check_mark: Created `%s`", p.Resource)
+	case ResourceCreateFailed:
+		msg = fmt.Sprintf(":x: Failed to create `%s`: %s", p.Resource, p.Error)
+	case ApplyComplete:
+		msg = ":white_check_mark: Apply complete"
+		if p.Error != "" {
+			msg = fmt.Sprintf(":x: Apply failed: %s", p.Error)
+		}
+	case DestroyComplete:
+		msg = ":wastebasket: Destroy complete"
+		if p.Error != "" {
+			msg = fmt.Sprintf(":x: Destroy failed: %s", p.Error)
+		}
+	case HealthChanged:
+		msg = fmt.Sprintf(":x: `%s` is unhealthy", p.Resource)
+		if p.Healthy != nil && *p.Healthy {
+			msg = fmt.Sprintf(":white_check_mark: `%s` is healthy", p.Resource)
+		}
+	default:
+		msg = string(p.Type)
+	}
+
+	return fmt.Sprintf("%s: %s", prefix, msg)
+}
+
+func sinkHandles(s config.Notification, t EventType) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+
+	for _, e := range s.Events {
+		if EventType(e) == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validEventType(t EventType) bool {
+	for _, et := range EventTypes {
+		if et == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func eventTypeNames() string {
+	names := []string{}
+	for _, et := range EventTypes {
+		names = append(names, string(et))
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,141 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type webhookRequests struct {
+	sync.Mutex
+	bodies  []string
+	headers []http.Header
+}
+
+func setupWebhook(t *testing.T, status int) (*httptest.Server, *webhookRequests) {
+	wr := &webhookRequests{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		d, _ := ioutil.ReadAll(r.Body)
+
+		wr.Lock()
+		wr.bodies = append(wr.bodies, string(d))
+		wr.headers = append(wr.headers, r.Header)
+		wr.Unlock()
+
+		rw.WriteHeader(status)
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return ts, wr
+}
+
+func setupNotifier(t *testing.T, sinks []config.Notification) (*EventBus, func()) {
+	n, err := NewNotifier(sinks, "Test Blueprint", clients.NewHTTP(1*time.Millisecond, hclog.NewNullLogger()), hclog.NewNullLogger())
+	assert.NoError(t, err)
+
+	b := NewEventBus()
+
+	return b, n.Subscribe(b)
+}
+
+func TestNotifierPostsEventsAsJSON(t *testing.T) {
+	ts, wr := setupWebhook(t, http.StatusOK)
+	b, stop := setupNotifier(t, []config.Notification{
+		{Name: "ci", URL: ts.URL, Headers: map[string]string{"Authorization": "Bearer abc"}},
+	})
+
+	c := config.NewContainer("consul")
+	b.Publish(Event{Type: ResourceCreateFailed, Resource: c, Error: fmt.Errorf("boom")})
+	stop()
+
+	assert.Len(t, wr.bodies, 1)
+	assert.Equal(t, "Bearer abc", wr.headers[0].Get("Authorization"))
+
+	p := EventPayload{}
+	err := json.Unmarshal([]byte(wr.bodies[0]), &p)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ResourceCreateFailed, p.Type)
+	assert.Equal(t, "Test Blueprint", p.Blueprint)
+	assert.Equal(t, "container.consul", p.Resource)
+	assert.Equal(t, "boom", p.Error)
+}
+
+func TestNotifierPostsSlackMessage(t *testing.T) {
+	ts, wr := setupWebhook(t, http.StatusOK)
+	b, stop := setupNotifier(t, []config.Notification{
+		{Name: "chat", Type: config.NotificationSlack, URL: ts.URL},
+	})
+
+	b.Publish(Event{Type: ResourceCreateSucceeded, Resource: config.NewContainer("consul")})
+	stop()
+
+	assert.Len(t, wr.bodies, 1)
+	assert.JSONEq(t, `{"text": "Shipyard [Test Blueprint]: :white_check_mark: Created `+"`container.consul`"+`"}`, wr.bodies[0])
+}
+
+func TestNotifierOnlySendsSelectedEvents(t *testing.T) {
+	ts, wr := setupWebhook(t, http.StatusOK)
+	b, stop := setupNotifier(t, []config.Notification{
+		{Name: "ci", URL: ts.URL, Events: []string{string(ApplyComplete)}},
+	})
+
+	b.Publish(Event{Type: ApplyStarted, Total: 2})
+	b.Publish(Event{Type: ResourceCreateStarted, Resource: config.NewContainer("consul")})
+	b.Publish(Event{Type: ApplyComplete})
+	stop()
+
+	assert.Len(t, wr.bodies, 1)
+	assert.Contains(t, wr.bodies[0], `"type":"apply_complete"`)
+}
+
+func TestNotifierDoesNotFailWhenWebhookReturnsError(t *testing.T) {
+	ts, wr := setupWebhook(t, http.StatusInternalServerError)
+	b, stop := setupNotifier(t, []config.Notification{
+		{Name: "ci", URL: ts.URL},
+	})
+
+	b.Publish(Event{Type: ApplyStarted})
+	b.Publish(Event{Type: ApplyComplete})
+	stop()
+
+	assert.Len(t, wr.bodies, 2)
+}
+
+func TestNewNotifierWithInvalidEventReturnsError(t *testing.T) {
+	_, err := NewNotifier(
+		[]config.Notification{{Name: "ci", URL: "https://ci.example.com", Events: []string{"resource_created"}}},
+		"",
+		clients.NewHTTP(1*time.Millisecond, hclog.NewNullLogger()),
+		hclog.NewNullLogger(),
+	)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "resource_created")
+}
+
+func TestApplySendsNotificationsForBlueprint(t *testing.T) {
+	ts, wr := setupWebhook(t, http.StatusOK)
+
+	e, _ := setupTests(t, nil)
+	e.(*EngineImpl).clients.HTTP = clients.NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+	e.SetNotifications([]config.Notification{{Name: "ci", URL: ts.URL, Events: []string{string(ApplyComplete)}}})
+
+	_, err := e.Apply("../../examples/single_k3s_cluster")
+	assert.NoError(t, err)
+
+	assert.Len(t, wr.bodies, 1)
+	assert.Contains(t, wr.bodies[0], `"type":"apply_complete"`)
+}
@@ -0,0 +1,29 @@
+package shipyard
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// registerPlugins registers the resource types provided by the installed plugins
+// with the parser, plugins which can not be loaded are logged and ignored so that
+// a broken plugin does not stop blueprints which do not use it
+func registerPlugins(pc clients.Plugins, l hclog.Logger) {
+	config.SetPluginValidator(pc.Validate)
+
+	plugins, err := pc.Discover()
+	if err != nil {
+		l.Warn("Unable to load plugins", "error", err)
+		return
+	}
+
+	for name, types := range plugins {
+		for _, t := range types {
+			err := config.RegisterPluginType(config.ResourceType(t), name)
+			if err != nil {
+				l.Warn("Unable to register resource type for plugin", "plugin", name, "type", t, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package shipyard
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupPluginTests(t *testing.T) {
+	t.Cleanup(func() {
+		config.ClearPluginTypes()
+		config.SetPluginValidator(nil)
+	})
+}
+
+func TestRegisterPluginsRegistersTypes(t *testing.T) {
+	setupPluginTests(t)
+
+	mp := &clientmocks.MockPlugins{}
+	mp.On("Discover").Return(map[string][]string{"firecracker": {"firecracker_vm", "container"}}, nil)
+
+	registerPlugins(mp, hclog.NewNullLogger())
+
+	p, ok := config.PluginType("firecracker_vm")
+	assert.True(t, ok)
+	assert.Equal(t, "firecracker", p)
+
+	// built in types can not be replaced
+	_, ok = config.PluginType(config.TypeContainer)
+	assert.False(t, ok)
+}
+
+func TestRegisterPluginsIgnoresDiscoverErrors(t *testing.T) {
+	setupPluginTests(t)
+
+	mp := &clientmocks.MockPlugins{}
+	mp.On("Discover").Return(nil, fmt.Errorf("boom"))
+
+	registerPlugins(mp, hclog.NewNullLogger())
+
+	_, ok := config.PluginType("firecracker_vm")
+	assert.False(t, ok)
+}
+
+func TestGenerateProviderReturnsPluginProviderForPluginResources(t *testing.T) {
+	r := config.NewPluginResource("dev", "firecracker_vm", "firecracker")
+
+	p := generateProviderImpl(r, &Clients{Plugins: &clientmocks.MockPlugins{}, Logger: hclog.NewNullLogger()})
+	assert.NotNil(t, p)
+}
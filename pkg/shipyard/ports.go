@@ -0,0 +1,111 @@
+package shipyard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// checkPorts assigns free ports to host ports set to 0 and returns an error
+// when a host port for a resource which will be created is already in use,
+// checking before the resources are created avoids a partially created
+// environment when docker fails to bind the port
+func (e *EngineImpl) checkPorts() error {
+	conflicts := []string{}
+
+	for _, r := range e.config.Resources {
+		if r.Info().Status == config.Disabled {
+			continue
+		}
+
+		pending := r.Info().Status == config.PendingCreation
+		name := fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)
+
+		for _, p := range config.HostPorts(r) {
+			if p.Host == "0" {
+				err := e.assignPort(r, p)
+				if err != nil {
+					return err
+				}
+
+				// a newly assigned port is known to be free
+				continue
+			}
+
+			if !pending {
+				continue
+			}
+
+			if err := utils.CheckPortAvailable(p.Protocol, p.BindAddress(), p.Host); err != nil {
+				conflicts = append(conflicts, portConflict(name, p.Protocol, p.Host))
+			}
+		}
+
+		// the API server for a cluster is bound to a host port, the port is only known
+		// once the cluster config has been written, reading the config before would
+		// create it with a random port
+		if pending && (r.Info().Type == config.TypeK8sCluster || r.Info().Type == config.TypeNomadCluster) && utils.ClusterConfigExists(name) {
+			cc, _, err := utils.GetClusterConfig(name)
+			if err != nil {
+				return err
+			}
+
+			if cc.APIPort == 0 {
+				continue
+			}
+
+			port := strconv.Itoa(cc.APIPort)
+			if err := utils.CheckPortAvailable("tcp", "", port); err != nil {
+				conflicts = append(conflicts, portConflict(name, "tcp", port))
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("Unable to create resources, host ports are already in use:\n  %s", strings.Join(conflicts, "\n  "))
+	}
+
+	return nil
+}
+
+// assignPort sets the host port to a free port and records the port in an output
+// so that the same port is used when the blueprint is applied again
+func (e *EngineImpl) assignPort(r config.Resource, p *config.Port) error {
+	name := fmt.Sprintf("%s_%s_port_%s", r.Info().Type, r.Info().Name, p.Local)
+
+	if o, err := e.config.FindResource(fmt.Sprintf("%s.%s", config.TypeOutput, name)); err == nil {
+		p.Host = o.(*config.Output).Value
+		return nil
+	}
+
+	fp, err := utils.GetFreePort()
+	if err != nil {
+		return fmt.Errorf("Unable to assign a host port for %s.%s: %s", r.Info().Type, r.Info().Name, err)
+	}
+
+	p.Host = strconv.Itoa(fp)
+
+	o := config.NewOutput(name)
+	o.Value = p.Host
+	o.Status = config.Applied
+
+	e.log.Debug("Assigned host port", "resource", r.Info().Name, "local", p.Local, "host", p.Host)
+
+	return e.config.AddResource(o)
+}
+
+// portConflict returns a message for a port which is in use including the
+// process which owns the port when it can be determined
+func portConflict(resource, protocol, port string) string {
+	msg := fmt.Sprintf("%s: port %s is in use", resource, port)
+
+	pn, _ := strconv.Atoi(port)
+	if owner := utils.PortOwner(protocol, pn); owner != "" {
+		msg = fmt.Sprintf("%s by %s", msg, owner)
+	}
+
+	return msg
+}
@@ -0,0 +1,94 @@
+package shipyard
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+var portContainer = `
+container "web" {
+  image {
+    name = "nginx:latest"
+  }
+
+  port {
+    local  = "80"
+    remote = "80"
+    host   = "%s"
+  }
+}
+`
+
+var portCluster = `
+network "test" {
+  subnet = "10.0.0.0/16"
+}
+
+k8s_cluster "k3s" {
+  driver = "k3s"
+
+  network {
+    name = "network.test"
+  }
+}
+`
+
+func TestApplyReturnsErrorWhenHostPortInUse(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	port := fmt.Sprintf("%d", l.Addr().(*net.TCPAddr).Port)
+
+	e, mp := setupTests(t, nil)
+	dir := config.CreateTestFiles(t, fmt.Sprintf(portContainer, port))
+
+	_, err = e.Apply(dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("container.web: port %s is in use", port))
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+}
+
+func TestApplyAssignsFreePortWhenHostPortZero(t *testing.T) {
+	e, _ := setupTests(t, nil)
+	dir := config.CreateTestFiles(t, fmt.Sprintf(portContainer, "0"))
+
+	_, err := e.Apply(dir)
+	assert.NoError(t, err)
+
+	o, err := e.(*EngineImpl).config.FindResource("output.container_web_port_80")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, o.(*config.Output).Value)
+	assert.NotEqual(t, "0", o.(*config.Output).Value)
+
+	c, err := e.(*EngineImpl).config.FindResource("container.web")
+	assert.NoError(t, err)
+	assert.Equal(t, o.(*config.Output).Value, c.(*config.Container).Ports[0].Host)
+
+	// applying again uses the recorded port
+	_, err = e.Apply(dir)
+	assert.NoError(t, err)
+
+	c, err = e.(*EngineImpl).config.FindResource("container.web")
+	assert.NoError(t, err)
+	assert.Equal(t, o.(*config.Output).Value, c.(*config.Container).Ports[0].Host)
+}
+
+func TestApplyDoesNotCreateClusterConfigWhenCheckingPorts(t *testing.T) {
+	e, _ := setupTests(t, nil)
+	dir := config.CreateTestFiles(t, portCluster)
+
+	_, err := e.(*EngineImpl).readConfig(dir, nil, "")
+	assert.NoError(t, err)
+
+	err = e.(*EngineImpl).checkPorts()
+	assert.NoError(t, err)
+
+	assert.False(t, utils.ClusterConfigExists("k8s_cluster.k3s"))
+}
@@ -0,0 +1,288 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// ProgressState is a snapshot of the progress of an apply
+type ProgressState struct {
+	Running        bool               `json:"running"`
+	Total          int                `json:"total"`
+	Completed      int                `json:"completed"`
+	Failed         int                `json:"failed"`
+	Percent        float64            `json:"percent"`
+	StartedAt      time.Time          `json:"started_at,omitempty"`
+	ElapsedSeconds float64            `json:"elapsed_seconds"`
+	ETASeconds     float64            `json:"eta_seconds,omitempty"` // estimated time until the apply completes, 0 when unknown
+	Current        []ResourceProgress `json:"current"`               // resources which are currently being created
+	Error          string             `json:"error,omitempty"`
+}
+
+// ResourceProgress is the progress of a single resource which is being created
+type ResourceProgress struct {
+	Resource       string    `json:"resource"`         // e.g. k8s_cluster.k3s
+	Module         string    `json:"module,omitempty"` // module the resource was loaded from
+	StartedAt      time.Time `json:"started_at"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+	ETASeconds     float64   `json:"eta_seconds,omitempty"` // estimated from resources of the same type, 0 when unknown
+}
+
+// Progress tracks the progress of an apply using the events published by the engine,
+// it is safe to read the state while the apply is running
+type Progress struct {
+	sync      sync.Mutex
+	state     ProgressState
+	inflight  map[string]ResourceProgress
+	durations map[config.ResourceType][]time.Duration
+	watchers  map[int]chan ProgressState
+	nextID    int
+	now       func() time.Time
+}
+
+// NewProgress creates a Progress which subscribes to the events on the given bus,
+// the returned function removes the subscription
+func NewProgress(b *EventBus) (*Progress, func()) {
+	p := &Progress{
+		inflight:  map[string]ResourceProgress{},
+		durations: map[config.ResourceType][]time.Duration{},
+		watchers:  map[int]chan ProgressState{},
+		now:       time.Now,
+	}
+
+	if b == nil {
+		return p, func() {}
+	}
+
+	return p, b.Subscribe(p.handle, ApplyStarted, ResourceCreateStarted, ResourceCreateSucceeded, ResourceCreateFailed, ApplyComplete)
+}
+
+// State returns a snapshot of the current progress
+func (p *Progress) State() ProgressState {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	return p.snapshot()
+}
+
+// Watch returns a channel which receives the state whenever the progress changes,
+// slow readers only receive the latest state. The returned function stops the watch.
+func (p *Progress) Watch() (<-chan ProgressState, func()) {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	id := p.nextID
+	p.nextID++
+
+	c := make(chan ProgressState, 1)
+	p.watchers[id] = c
+
+	return c, func() {
+		p.sync.Lock()
+		defer p.sync.Unlock()
+
+		if _, ok := p.watchers[id]; ok {
+			delete(p.watchers, id)
+			close(c)
+		}
+	}
+}
+
+func (p *Progress) handle(e Event) {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	switch e.Type {
+	case ApplyStarted:
+		p.state = ProgressState{Running: true, Total: e.Total, StartedAt: e.Time}
+		p.inflight = map[string]ResourceProgress{}
+	case ResourceCreateStarted:
+		i := e.Resource.Info()
+		p.inflight[config.ResourceID(i.Module, i.Type, i.Name)] = ResourceProgress{
+			Resource:  fmt.Sprintf("%s.%s", i.Type, i.Name),
+			Module:    i.Module,
+			StartedAt: e.Time,
+		}
+	case ResourceCreateSucceeded, ResourceCreateFailed:
+		i := e.Resource.Info()
+		id := config.ResourceID(i.Module, i.Type, i.Name)
+		if rp, ok := p.inflight[id]; ok {
+			p.durations[i.Type] = append(p.durations[i.Type], e.Time.Sub(rp.StartedAt))
+			delete(p.inflight, id)
+		}
+
+		p.state.Completed++
+		if e.Error != nil {
+			p.state.Failed++
+		}
+	case ApplyComplete:
+		p.state.Running = false
+		p.inflight = map[string]ResourceProgress{}
+
+		if e.Error != nil {
+			p.state.Error = e.Error.Error()
+		}
+	}
+
+	s := p.snapshot()
+	for _, w := range p.watchers {
+		// replace any state the reader has not yet received
+		select {
+		case <-w:
+		default:
+		}
+
+		w <- s
+	}
+}
+
+// snapshot returns a copy of the state with the calculated fields set,
+// the caller must hold the lock
+func (p *Progress) snapshot() ProgressState {
+	s := p.state
+	now := p.now()
+
+	if !s.StartedAt.IsZero() {
+		s.ElapsedSeconds = now.Sub(s.StartedAt).Seconds()
+	}
+
+	if s.Total > 0 {
+		s.Percent = float64(s.Completed) / float64(s.Total) * 100
+	}
+
+	// estimate the remaining time from the average time taken
+	// to complete the resources so far
+	if s.Running && s.Completed > 0 && s.Completed < s.Total {
+		s.ETASeconds = s.ElapsedSeconds / float64(s.Completed) * float64(s.Total-s.Completed)
+	}
+
+	s.Current = []ResourceProgress{}
+	for _, rp := range p.inflight {
+		rp.ElapsedSeconds = now.Sub(rp.StartedAt).Seconds()
+
+		// estimate the remaining time from resources of the same type
+		if avg, ok := p.averageDuration(rp.Resource); ok && avg.Seconds() > rp.ElapsedSeconds {
+			rp.ETASeconds = avg.Seconds() - rp.ElapsedSeconds
+		}
+
+		s.Current = append(s.Current, rp)
+	}
+
+	sort.Slice(s.Current, func(i, j int) bool { return s.Current[i].StartedAt.Before(s.Current[j].StartedAt) })
+
+	return s
+}
+
+// averageDuration returns the average time taken to create resources
+// of the same type as the given resource
+func (p *Progress) averageDuration(resource string) (time.Duration, bool) {
+	t := config.ResourceType(strings.SplitN(resource, ".", 2)[0])
+
+	ds := p.durations[t]
+	if len(ds) == 0 {
+		return 0, false
+	}
+
+	var total time.Duration
+	for _, d := range ds {
+		total += d
+	}
+
+	return total / time.Duration(len(ds)), true
+}
+
+// ProgressServer exposes the progress of an apply over a unix socket so that
+// external tools can render the progress without parsing the output of the CLI.
+// Each connection receives the current state followed by every update as
+// newline delimited JSON.
+type ProgressServer struct {
+	progress *Progress
+	log      hclog.Logger
+	listener net.Listener
+	path     string
+}
+
+// NewProgressServer creates a ProgressServer for the given Progress
+func NewProgressServer(p *Progress, l hclog.Logger) *ProgressServer {
+	return &ProgressServer{progress: p, log: l}
+}
+
+// Start listening on the unix socket at the given path
+func (s *ProgressServer) Start(path string) error {
+	// remove any socket left behind by a previous run, if another
+	// apply is still listening on the socket return an error
+	if _, err := os.Stat(path); err == nil {
+		c, err := net.Dial("unix", path)
+		if err == nil {
+			c.Close()
+			return fmt.Errorf("progress socket %s is in use by another process", path)
+		}
+
+		os.Remove(path)
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return xerrors.Errorf("unable to listen on progress socket %s: %w", path, err)
+	}
+
+	s.listener = l
+	s.path = path
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				// listener has been closed
+				return
+			}
+
+			go s.handle(c)
+		}
+	}()
+
+	return nil
+}
+
+// Stop the server and remove the socket
+func (s *ProgressServer) Stop() {
+	if s.listener == nil {
+		return
+	}
+
+	s.listener.Close()
+	os.Remove(s.path)
+	s.listener = nil
+}
+
+func (s *ProgressServer) handle(c net.Conn) {
+	defer c.Close()
+
+	w, stop := s.progress.Watch()
+	defer stop()
+
+	enc := json.NewEncoder(c)
+
+	err := enc.Encode(s.progress.State())
+	if err != nil {
+		return
+	}
+
+	for ps := range w {
+		err := enc.Encode(ps)
+		if err != nil {
+			s.log.Debug("Unable to write progress, closing connection", "error", err)
+			return
+		}
+	}
+}
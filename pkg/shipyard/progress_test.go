@@ -0,0 +1,179 @@
+package shipyard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupProgress(t *testing.T) (*Progress, *EventBus, *time.Time) {
+	eb := NewEventBus()
+	p, unsubscribe := NewProgress(eb)
+	t.Cleanup(unsubscribe)
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	return p, eb, &now
+}
+
+func TestProgressSetsTotalOnApplyStarted(t *testing.T) {
+	p, eb, now := setupProgress(t)
+
+	eb.Publish(Event{Type: ApplyStarted, Total: 4, Time: *now})
+
+	s := p.State()
+	assert.True(t, s.Running)
+	assert.Equal(t, 4, s.Total)
+	assert.Equal(t, 0.0, s.Percent)
+	assert.Empty(t, s.Current)
+}
+
+func TestProgressTracksCurrentResources(t *testing.T) {
+	p, eb, now := setupProgress(t)
+
+	c := config.NewContainer("web")
+	eb.Publish(Event{Type: ApplyStarted, Total: 2, Time: *now})
+	eb.Publish(Event{Type: ResourceCreateStarted, Resource: c, Time: *now})
+
+	*now = now.Add(5 * time.Second)
+
+	s := p.State()
+	assert.Len(t, s.Current, 1)
+	assert.Equal(t, "container.web", s.Current[0].Resource)
+	assert.Equal(t, 5.0, s.Current[0].ElapsedSeconds)
+}
+
+func TestProgressCalculatesPercentAndETA(t *testing.T) {
+	p, eb, now := setupProgress(t)
+
+	c1 := config.NewContainer("one")
+	c2 := config.NewContainer("two")
+
+	eb.Publish(Event{Type: ApplyStarted, Total: 2, Time: *now})
+	eb.Publish(Event{Type: ResourceCreateStarted, Resource: c1, Time: *now})
+
+	*now = now.Add(10 * time.Second)
+	eb.Publish(Event{Type: ResourceCreateSucceeded, Resource: c1, Time: *now})
+	eb.Publish(Event{Type: ResourceCreateStarted, Resource: c2, Time: *now})
+
+	*now = now.Add(4 * time.Second)
+
+	s := p.State()
+	assert.Equal(t, 1, s.Completed)
+	assert.Equal(t, 50.0, s.Percent)
+	assert.Equal(t, 14.0, s.ETASeconds)
+
+	// the ETA for the resource is based on the time taken by the first container
+	assert.Len(t, s.Current, 1)
+	assert.Equal(t, "container.two", s.Current[0].Resource)
+	assert.Equal(t, 6.0, s.Current[0].ETASeconds)
+}
+
+func TestProgressRecordsFailures(t *testing.T) {
+	p, eb, now := setupProgress(t)
+
+	c := config.NewContainer("web")
+	eb.Publish(Event{Type: ApplyStarted, Total: 1, Time: *now})
+	eb.Publish(Event{Type: ResourceCreateStarted, Resource: c, Time: *now})
+	eb.Publish(Event{Type: ResourceCreateFailed, Resource: c, Error: fmt.Errorf("boom"), Time: *now})
+	eb.Publish(Event{Type: ApplyComplete, Error: fmt.Errorf("boom"), Time: *now})
+
+	s := p.State()
+	assert.False(t, s.Running)
+	assert.Equal(t, 1, s.Failed)
+	assert.Equal(t, "boom", s.Error)
+}
+
+func TestProgressWatchReceivesLatestState(t *testing.T) {
+	p, eb, now := setupProgress(t)
+
+	w, stop := p.Watch()
+
+	eb.Publish(Event{Type: ApplyStarted, Total: 3, Time: *now})
+	eb.Publish(Event{Type: ResourceCreateSucceeded, Resource: config.NewContainer("web"), Time: *now})
+
+	s := <-w
+	assert.Equal(t, 1, s.Completed)
+
+	stop()
+
+	_, ok := <-w
+	assert.False(t, ok)
+}
+
+func TestProgressServerWritesStateToSocket(t *testing.T) {
+	p, eb, now := setupProgress(t)
+
+	path := filepath.Join(t.TempDir(), "progress.sock")
+	ps := NewProgressServer(p, hclog.NewNullLogger())
+
+	err := ps.Start(path)
+	assert.NoError(t, err)
+	defer ps.Stop()
+
+	c, err := net.Dial("unix", path)
+	assert.NoError(t, err)
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+
+	// initial state is sent on connect
+	s := readProgress(t, r)
+	assert.False(t, s.Running)
+
+	eb.Publish(Event{Type: ApplyStarted, Total: 2, Time: *now})
+
+	s = readProgress(t, r)
+	assert.True(t, s.Running)
+	assert.Equal(t, 2, s.Total)
+}
+
+func TestProgressServerStopRemovesSocket(t *testing.T) {
+	p, _, _ := setupProgress(t)
+
+	path := filepath.Join(t.TempDir(), "progress.sock")
+	ps := NewProgressServer(p, hclog.NewNullLogger())
+
+	err := ps.Start(path)
+	assert.NoError(t, err)
+
+	ps.Stop()
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestProgressServerReplacesStaleSocket(t *testing.T) {
+	p, _, _ := setupProgress(t)
+
+	path := filepath.Join(t.TempDir(), "progress.sock")
+	err := os.WriteFile(path, []byte(""), os.ModePerm)
+	assert.NoError(t, err)
+
+	ps := NewProgressServer(p, hclog.NewNullLogger())
+
+	err = ps.Start(path)
+	assert.NoError(t, err)
+	ps.Stop()
+}
+
+func readProgress(t *testing.T, r *bufio.Reader) ProgressState {
+	line, err := r.ReadBytes('\n')
+	assert.NoError(t, err)
+
+	s := ProgressState{}
+	err = json.Unmarshal(line, &s)
+	assert.NoError(t, err)
+
+	return s
+}
@@ -0,0 +1,263 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// Options configures an Environment created with NewEnvironment
+type Options struct {
+	// Home is the folder used for the state, certificates, and downloaded blueprints,
+	// defaults to $HOME/.shipyard. The engine reads its folders from the process, so
+	// the folder is set for the whole process until the Environment is closed.
+	Home string
+
+	// Logger receives the log output from the engine, defaults to a logger which
+	// discards all output
+	Logger hclog.Logger
+
+	// Variables and VariablesFile override the variables in blueprints when they
+	// are applied
+	Variables     map[string]string
+	VariablesFile string
+}
+
+// ResourceStatus is the status of a resource in the environment
+type ResourceStatus struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Type   string        `json:"type"`
+	Module string        `json:"module,omitempty"`
+	Status config.Status `json:"status"`
+}
+
+// ErrEnvironmentOpen is returned by NewEnvironment when an Environment created
+// earlier has not been closed
+var ErrEnvironmentOpen = fmt.Errorf("an Environment is already open, Close it before creating another")
+
+// openEnvironment is the Environment which is open, the engine uses the Shipyard
+// folders of the process so only one Environment can be open at a time
+var openEnvironment struct {
+	sync.Mutex
+	env *Environment
+}
+
+// Environment allows Go programs to create and destroy Shipyard environments
+// without using the CLI.
+//
+//	env, err := shipyard.NewEnvironment(ctx, shipyard.Options{Home: "/tmp/shipyard"})
+//	if err != nil {
+//		return err
+//	}
+//	defer env.Close()
+//
+//	err = env.Apply("github.com/shipyard-run/blueprints//consul-docker")
+//	defer env.Destroy()
+//
+// An Environment is a singleton, the engine reads the state and certificates from
+// the Home folder set for the whole process, so only one Environment can be open at
+// a time and it must be closed before another is created. Apply, Destroy, and Status
+// run until they complete and can not be cancelled.
+//
+// Errors, including unexpected panics in the engine, are returned to the caller.
+type Environment struct {
+	engine  Engine
+	options Options
+}
+
+// NewEnvironment creates an Environment, ErrEnvironmentOpen is returned when an
+// Environment is already open. The context is only used to check that the Docker
+// engine is reachable.
+func NewEnvironment(ctx context.Context, o Options) (env *Environment, err error) {
+	defer recoverError(&err)
+
+	openEnvironment.Lock()
+	defer openEnvironment.Unlock()
+
+	if openEnvironment.env != nil {
+		return nil, ErrEnvironmentOpen
+	}
+
+	if o.Logger == nil {
+		o.Logger = hclog.NewNullLogger()
+	}
+
+	if o.VariablesFile != "" {
+		if _, err := os.Stat(o.VariablesFile); err != nil {
+			return nil, fmt.Errorf("Variables file %s, does not exist", o.VariablesFile)
+		}
+	}
+
+	// the engine reads its folders when it is created
+	utils.SetShipyardHome(o.Home)
+	defer func() {
+		if err != nil {
+			utils.SetShipyardHome("")
+		}
+	}()
+
+	e, err := New(o.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create engine: %s", err)
+	}
+
+	_, err = e.GetClients().Docker.ServerVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to Docker: %s", err)
+	}
+
+	openEnvironment.env = newEnvironment(e, o)
+
+	return openEnvironment.env, nil
+}
+
+// Close releases the Environment and the Home folder so that another Environment
+// can be created, the resources in the environment are not destroyed
+func (env *Environment) Close() error {
+	openEnvironment.Lock()
+	defer openEnvironment.Unlock()
+
+	if openEnvironment.env == env {
+		openEnvironment.env = nil
+		utils.SetShipyardHome("")
+	}
+
+	return nil
+}
+
+func newEnvironment(e Engine, o Options) *Environment {
+	return &Environment{engine: e, options: o}
+}
+
+// Engine returns the engine used by the Environment for programs which need
+// access to the clients or events
+func (env *Environment) Engine() Engine {
+	return env.engine
+}
+
+// Apply creates the resources in the blueprint, blueprint is the path to a local
+// folder or file, or the URL of a remote blueprint
+func (env *Environment) Apply(blueprint string) (err error) {
+	defer recoverError(&err)
+
+	cl := env.engine.GetClients()
+
+	utils.CreateFolders()
+
+	err = StartConnector(cl.Connector, env.options.Logger)
+	if err != nil {
+		return err
+	}
+
+	config.SetModuleGetter(cl.Getter.Get)
+
+	if blueprint == "" || blueprint == "." {
+		blueprint = "./"
+	}
+
+	dst := blueprint
+	if !utils.IsLocalFolder(dst) && !utils.IsConfigFile(dst) {
+		dst = utils.GetBlueprintLocalFolder(blueprint)
+
+		err = cl.Getter.Get(blueprint, dst)
+		if err != nil {
+			return fmt.Errorf("Unable to retrieve blueprint: %s", err)
+		}
+	}
+
+	_, err = env.engine.ApplyWithVariables(dst, env.options.Variables, env.options.VariablesFile)
+	return err
+}
+
+// Destroy removes all the resources in the environment, the connector is stopped
+// unless it is installed as a service
+func (env *Environment) Destroy() (err error) {
+	defer recoverError(&err)
+
+	err = env.engine.Destroy("", true)
+	if err != nil {
+		return fmt.Errorf("Unable to destroy environment: %s", err)
+	}
+
+	os.RemoveAll(utils.GetDataFolder(""))
+	StopConnector(env.engine.GetClients().Connector, env.options.Logger)
+
+	return nil
+}
+
+// Status returns the status of the resources in the environment sorted by ID,
+// an empty list is returned when the environment has not been created
+func (env *Environment) Status() (status []ResourceStatus, err error) {
+	defer recoverError(&err)
+
+	c, err := env.state()
+	if err != nil || c == nil {
+		return []ResourceStatus{}, err
+	}
+
+	status = []ResourceStatus{}
+	for _, r := range c.Resources {
+		i := r.Info()
+		status = append(status, ResourceStatus{
+			ID:     fmt.Sprintf("%s.%s", i.Type, i.Name),
+			Name:   i.Name,
+			Type:   string(i.Type),
+			Module: i.Module,
+			Status: i.Status,
+		})
+	}
+
+	sort.Slice(status, func(a, b int) bool { return status[a].ID < status[b].ID })
+
+	return status, nil
+}
+
+// Outputs returns the values of the outputs in the environment
+func (env *Environment) Outputs() (outputs map[string]string, err error) {
+	defer recoverError(&err)
+
+	outputs = map[string]string{}
+
+	c, err := env.state()
+	if err != nil || c == nil {
+		return outputs, err
+	}
+
+	for _, r := range c.Resources {
+		if o, ok := r.(*config.Output); ok {
+			outputs[o.Name] = o.Value
+		}
+	}
+
+	return outputs, nil
+}
+
+// state loads the state file, nil is returned when the state does not exist
+func (env *Environment) state() (*config.Config, error) {
+	if _, err := os.Stat(utils.StatePath()); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	c := config.New()
+	err := c.FromJSON(utils.StatePath())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read state: %s", err)
+	}
+
+	return c, nil
+}
+
+// recoverError converts a panic in the engine into an error so that programs
+// which embed the engine are not terminated
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("Unexpected error in engine: %v", r)
+	}
+}
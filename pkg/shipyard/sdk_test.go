@@ -0,0 +1,141 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+var outputState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "CONSUL_HTTP_ADDR",
+      "status": "applied",
+      "value": "http://localhost:8500",
+      "type": "output"
+	}
+  ]
+}
+`
+
+func setupEnvironment(t *testing.T, state string) (*Environment, *clients.ConnectorMock) {
+	e, _ := setupTestsWithState(t, nil, state)
+
+	cc := &clients.ConnectorMock{}
+	e.GetClients().Connector = cc
+
+	return newEnvironment(e, Options{Logger: hclog.NewNullLogger()}), cc
+}
+
+func TestEnvironmentStatusReturnsResources(t *testing.T) {
+	env, _ := setupEnvironment(t, disabledState)
+
+	s, err := env.Status()
+	assert.NoError(t, err)
+
+	assert.Len(t, s, 2)
+	assert.Equal(t, "container.dc1", s[0].ID)
+	assert.Equal(t, config.Disabled, s[0].Status)
+	assert.Equal(t, "network.dc1", s[1].ID)
+	assert.Equal(t, config.PendingCreation, s[1].Status)
+}
+
+func TestEnvironmentStatusReturnsEmptyWithoutState(t *testing.T) {
+	env, _ := setupEnvironment(t, "")
+
+	s, err := env.Status()
+	assert.NoError(t, err)
+	assert.Len(t, s, 0)
+}
+
+func TestEnvironmentOutputsReturnsValues(t *testing.T) {
+	env, _ := setupEnvironment(t, outputState)
+
+	o, err := env.Outputs()
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:8500", o["CONSUL_HTTP_ADDR"])
+}
+
+func TestEnvironmentDestroyStopsConnector(t *testing.T) {
+	env, cc := setupEnvironment(t, disabledState)
+	cc.On("ServiceInstalled").Return(false)
+	cc.On("IsRunning").Return(true)
+	cc.On("Stop").Return(nil)
+
+	err := env.Destroy()
+	assert.NoError(t, err)
+
+	cc.AssertCalled(t, "Stop")
+}
+
+func TestEnvironmentDestroyLeavesConnectorServiceRunning(t *testing.T) {
+	env, cc := setupEnvironment(t, disabledState)
+	cc.On("ServiceInstalled").Return(true)
+
+	err := env.Destroy()
+	assert.NoError(t, err)
+
+	cc.AssertNotCalled(t, "Stop")
+}
+
+func TestNewEnvironmentReturnsErrorWhenEnvironmentOpen(t *testing.T) {
+	env, _ := setupEnvironment(t, "")
+
+	openEnvironment.env = env
+	t.Cleanup(func() { openEnvironment.env = nil })
+
+	_, err := NewEnvironment(context.Background(), Options{Home: t.TempDir()})
+	assert.Equal(t, ErrEnvironmentOpen, err)
+}
+
+func TestEnvironmentCloseReleasesHome(t *testing.T) {
+	env, _ := setupEnvironment(t, "")
+	home := t.TempDir()
+
+	openEnvironment.env = env
+	utils.SetShipyardHome(home)
+	t.Cleanup(func() {
+		openEnvironment.env = nil
+		utils.SetShipyardHome("")
+	})
+
+	assert.Equal(t, home, utils.ShipyardHome())
+
+	err := env.Close()
+	assert.NoError(t, err)
+
+	assert.Nil(t, openEnvironment.env)
+	assert.NotEqual(t, home, utils.ShipyardHome())
+}
+
+func TestEnvironmentCloseDoesNotReleaseOtherEnvironment(t *testing.T) {
+	env, _ := setupEnvironment(t, "")
+	other, _ := setupEnvironment(t, "")
+
+	openEnvironment.env = env
+	t.Cleanup(func() { openEnvironment.env = nil })
+
+	err := other.Close()
+	assert.NoError(t, err)
+
+	assert.Equal(t, env, openEnvironment.env)
+}
+
+func TestRecoverErrorReturnsPanicAsError(t *testing.T) {
+	f := func() (err error) {
+		defer recoverError(&err)
+		panic(fmt.Errorf("boom"))
+	}
+
+	err := f()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
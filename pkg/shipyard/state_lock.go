@@ -0,0 +1,49 @@
+package shipyard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// stateLockTimeout is the time to wait for another process to release
+// the lock, the lock is only held while the state is written so a lock
+// which is held longer has been left behind by a process which has exited
+var stateLockTimeout = 10 * time.Second
+
+// lockState creates a lock file next to the state so that processes applying
+// blueprints at the same time do not overwrite each others changes, the returned
+// function releases the lock
+func lockState() (func(), error) {
+	err := os.MkdirAll(utils.StateDir(), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := filepath.Join(utils.StateDir(), "state.lock")
+	deadline := time.Now().Add(stateLockTimeout)
+
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("Unable to lock state: %s", err)
+		}
+
+		// remove the lock left behind by a process which has exited
+		if time.Now().After(deadline) {
+			os.Remove(lock)
+			deadline = time.Now().Add(stateLockTimeout)
+			continue
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
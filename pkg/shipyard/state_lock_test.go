@@ -0,0 +1,48 @@
+package shipyard
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestLockStateWaitsForLockToBeReleased(t *testing.T) {
+	setupState(t, "")
+
+	unlock, err := lockState()
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(utils.StateDir(), "state.lock"))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		unlock()
+	}()
+
+	start := time.Now()
+	unlock2, err := lockState()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	unlock2()
+	assert.NoFileExists(t, filepath.Join(utils.StateDir(), "state.lock"))
+}
+
+func TestLockStateRemovesStaleLock(t *testing.T) {
+	setupState(t, "")
+
+	timeout := stateLockTimeout
+	stateLockTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { stateLockTimeout = timeout })
+
+	// a lock which is never released
+	_, err := lockState()
+	assert.NoError(t, err)
+
+	unlock, err := lockState()
+	assert.NoError(t, err)
+
+	unlock()
+}
@@ -0,0 +1,101 @@
+package shipyard
+
+import (
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// timedClients returns a copy of the clients which record the time spent pulling
+// and building images, and health checking to the timing of a resource
+func timedClients(c *Clients, t *config.Timing) *Clients {
+	if c == nil || t == nil {
+		return c
+	}
+
+	tc := *c
+
+	if c.ContainerTasks != nil {
+		tc.ContainerTasks = &timedContainerTasks{c.ContainerTasks, t}
+	}
+
+	if c.HTTP != nil {
+		tc.HTTP = &timedHTTP{c.HTTP, t}
+	}
+
+	if c.Kubernetes != nil {
+		tc.Kubernetes = &timedKubernetes{c.Kubernetes, t}
+	}
+
+	if c.Nomad != nil {
+		tc.Nomad = &timedNomad{c.Nomad, t}
+	}
+
+	return &tc
+}
+
+type timedContainerTasks struct {
+	clients.ContainerTasks
+	timing *config.Timing
+}
+
+func (c *timedContainerTasks) PullImage(image config.Image, force bool) error {
+	defer recordPhase(c.timing, config.PhasePull, time.Now())
+
+	return c.ContainerTasks.PullImage(image, force)
+}
+
+func (c *timedContainerTasks) BuildContainer(cc *config.Container, force bool) (string, error) {
+	defer recordPhase(c.timing, config.PhaseBuild, time.Now())
+
+	return c.ContainerTasks.BuildContainer(cc, force)
+}
+
+type timedHTTP struct {
+	clients.HTTP
+	timing *config.Timing
+}
+
+func (h *timedHTTP) HealthCheckHTTP(uri string, codes []int, timeout time.Duration) error {
+	defer recordPhase(h.timing, config.PhaseHealthCheck, time.Now())
+
+	return h.HTTP.HealthCheckHTTP(uri, codes, timeout)
+}
+
+type timedKubernetes struct {
+	clients.Kubernetes
+	timing *config.Timing
+}
+
+// SetConfig returns a new client, the client is wrapped so that the
+// health checks made with the new client are recorded
+func (k *timedKubernetes) SetConfig(kubeconfig string) (clients.Kubernetes, error) {
+	kc, err := k.Kubernetes.SetConfig(kubeconfig)
+	if err != nil || kc == nil {
+		return kc, err
+	}
+
+	return &timedKubernetes{kc, k.timing}, nil
+}
+
+func (k *timedKubernetes) HealthCheckPods(selectors []string, timeout time.Duration) error {
+	defer recordPhase(k.timing, config.PhaseHealthCheck, time.Now())
+
+	return k.Kubernetes.HealthCheckPods(selectors, timeout)
+}
+
+type timedNomad struct {
+	clients.Nomad
+	timing *config.Timing
+}
+
+func (n *timedNomad) HealthCheckAPI(timeout time.Duration) error {
+	defer recordPhase(n.timing, config.PhaseHealthCheck, time.Now())
+
+	return n.Nomad.HealthCheckAPI(timeout)
+}
+
+func recordPhase(t *config.Timing, phase string, started time.Time) {
+	t.Record(phase, time.Since(started))
+}
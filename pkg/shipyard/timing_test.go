@@ -0,0 +1,56 @@
+package shipyard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestTimedClientsRecordPhases(t *testing.T) {
+	ct := &mocks.MockContainerTasks{}
+	ct.On("PullImage", mock.Anything, mock.Anything).Return(nil)
+
+	hc := &mocks.MockHTTP{}
+	hc.On("HealthCheckHTTP", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	kc := &clients.MockKubernetes{}
+	kc.On("SetConfig", mock.Anything).Return(nil)
+	kc.On("HealthCheckPods", mock.Anything, mock.Anything).Return(nil)
+
+	tm := config.NewTiming("create")
+	tc := timedClients(&Clients{ContainerTasks: ct, HTTP: hc, Kubernetes: kc}, tm)
+
+	err := tc.ContainerTasks.PullImage(config.Image{Name: "nginx"}, false)
+	assert.NoError(t, err)
+	ct.AssertCalled(t, "PullImage", config.Image{Name: "nginx"}, false)
+
+	err = tc.HTTP.HealthCheckHTTP("http://localhost", []int{200}, time.Second)
+	assert.NoError(t, err)
+
+	// health checks made with the client returned by SetConfig are recorded
+	k, err := tc.Kubernetes.SetConfig("kubeconfig")
+	assert.NoError(t, err)
+	assert.IsType(t, &timedKubernetes{}, k)
+
+	err = k.HealthCheckPods([]string{"app=test"}, time.Second)
+	assert.NoError(t, err)
+	kc.AssertCalled(t, "HealthCheckPods", []string{"app=test"}, time.Second)
+
+	assert.Contains(t, tm.Phases, config.PhasePull)
+	assert.Contains(t, tm.Phases, config.PhaseHealthCheck)
+	assert.NotContains(t, tm.Phases, config.PhaseBuild)
+}
+
+func TestTimedClientsLeavesNilClients(t *testing.T) {
+	tc := timedClients(&Clients{}, config.NewTiming("create"))
+
+	assert.Nil(t, tc.ContainerTasks)
+	assert.Nil(t, tc.HTTP)
+	assert.Nil(t, tc.Kubernetes)
+	assert.Nil(t, tc.Nomad)
+}
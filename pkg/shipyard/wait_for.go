@@ -0,0 +1,182 @@
+package shipyard
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// waitFor blocks until the wait_for conditions of the resource are met, an
+// error is returned when a condition is not met before its timeout
+func (e *EngineImpl) waitFor(r config.Resource) error {
+	for _, w := range r.Info().WaitFor {
+		e.log.Info("Waiting for condition", "ref", r.Info().Name, "type", r.Info().Type, "condition", w.String(), "timeout", w.TimeoutDuration())
+
+		check, err := e.waitForCheck(r, w)
+		if err != nil {
+			return err
+		}
+
+		err = poll(check, w.TimeoutDuration(), w.IntervalDuration())
+		if err != nil {
+			return fmt.Errorf("Timeout waiting for %s after %s: %s", w.String(), w.TimeoutDuration(), err)
+		}
+	}
+
+	return nil
+}
+
+// poll calls check every interval until it returns nil or the timeout elapses,
+// the last error returned by check is returned
+func poll(check func() error, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := check()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return err
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// waitForCheck returns a function which checks the condition once
+func (e *EngineImpl) waitForCheck(r config.Resource, w config.WaitFor) (func() error, error) {
+	switch {
+	case w.TCP != "":
+		return func() error {
+			c, err := net.DialTimeout("tcp", w.TCP, w.IntervalDuration())
+			if err != nil {
+				return err
+			}
+
+			return c.Close()
+		}, nil
+
+	case w.HTTP != "":
+		return func() error {
+			return e.checkHTTP(w.HTTP, w.SuccessCodes(), w.IntervalDuration())
+		}, nil
+
+	case w.File != "":
+		return func() error {
+			id, err := e.waitForContainerID(r, w)
+			if err != nil {
+				return err
+			}
+
+			return e.clients.ContainerTasks.ExecuteCommand(id, []string{"test", "-e", w.File}, nil, "/", "", "", ioutil.Discard)
+		}, nil
+
+	default:
+		re, err := regexp.Compile(w.Log)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() error {
+			id, err := e.waitForContainerID(r, w)
+			if err != nil {
+				return err
+			}
+
+			return e.checkLogs(id, re)
+		}, nil
+	}
+}
+
+func (e *EngineImpl) checkHTTP(uri string, codes []int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.clients.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	for _, c := range codes {
+		if resp.StatusCode == c {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("status code %d", resp.StatusCode)
+}
+
+func (e *EngineImpl) checkLogs(id string, re *regexp.Regexp) error {
+	rc, err := e.clients.ContainerTasks.ContainerLogs(id, true, true)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	d, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+
+	// the logs are multiplexed unless the container has a tty
+	buf := bytes.NewBuffer(nil)
+	_, err = stdcopy.StdCopy(buf, buf, bytes.NewReader(d))
+	if err != nil {
+		buf = bytes.NewBuffer(d)
+	}
+
+	s := bufio.NewScanner(buf)
+	for s.Scan() {
+		if re.MatchString(s.Text()) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no log line matches")
+}
+
+// waitForContainerID returns the ID of the container the file and log conditions are
+// checked in, clusters are checked in the server node
+func (e *EngineImpl) waitForContainerID(r config.Resource, w config.WaitFor) (string, error) {
+	ref := w.ContainerResource(r)
+	parts := strings.SplitN(ref, ".", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid container %s", ref)
+	}
+
+	name := parts[1]
+	typ := config.ResourceType(parts[0])
+
+	if typ == config.TypeK8sCluster || typ == config.TypeNomadCluster {
+		name = fmt.Sprintf("server.%s", name)
+	}
+
+	ids, err := e.clients.ContainerTasks.FindContainerIDs(name, typ)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ids) == 0 {
+		return "", fmt.Errorf("unable to find container for %s", ref)
+	}
+
+	return ids[0], nil
+}
@@ -0,0 +1,151 @@
+package shipyard
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func setupWaitFor(t *testing.T, w config.WaitFor) (*EngineImpl, config.Resource, *clientmocks.MockContainerTasks, *clientmocks.MockHTTP) {
+	mt := &clientmocks.MockContainerTasks{}
+	mt.On("FindContainerIDs", "db", config.TypeContainer).Return([]string{"abc"}, nil)
+
+	mh := &clientmocks.MockHTTP{}
+
+	e := &EngineImpl{
+		clients: &Clients{ContainerTasks: mt, HTTP: mh},
+		log:     hclog.NewNullLogger(),
+	}
+
+	if w.Timeout == "" {
+		w.Timeout = "50ms"
+	}
+	w.Interval = "10ms"
+
+	r := config.NewContainer("db")
+	r.WaitFor = []config.WaitFor{w}
+
+	return e, r, mt, mh
+}
+
+func TestWaitForTCPReturnsWhenPortIsOpen(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	e, r, _, _ := setupWaitFor(t, config.WaitFor{TCP: l.Addr().String()})
+
+	err = e.waitFor(r)
+	assert.NoError(t, err)
+}
+
+func TestWaitForTCPReturnsErrorWhenPortIsClosed(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	e, r, _, _ := setupWaitFor(t, config.WaitFor{TCP: addr})
+
+	err = e.waitFor(r)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Timeout waiting for tcp "+addr)
+}
+
+func TestWaitForHTTPPollsUntilSuccessCode(t *testing.T) {
+	e, r, _, mh := setupWaitFor(t, config.WaitFor{HTTP: "http://localhost/health", Timeout: "1s"})
+	mh.On("Do", mock.Anything).Return(&http.Response{StatusCode: 503, Body: ioutil.NopCloser(bytes.NewBufferString(""))}, nil).Once()
+	mh.On("Do", mock.Anything).Return(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(""))}, nil)
+
+	err := e.waitFor(r)
+	assert.NoError(t, err)
+	mh.AssertNumberOfCalls(t, "Do", 2)
+}
+
+func TestWaitForFileChecksFileInContainer(t *testing.T) {
+	e, r, mt, _ := setupWaitFor(t, config.WaitFor{File: "/data/ready", Timeout: "1s"})
+	mt.On("ExecuteCommand", "abc", []string{"test", "-e", "/data/ready"}, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("exit code 1")).Once()
+	mt.On("ExecuteCommand", "abc", []string{"test", "-e", "/data/ready"}, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	err := e.waitFor(r)
+	assert.NoError(t, err)
+	mt.AssertNumberOfCalls(t, "ExecuteCommand", 2)
+}
+
+func TestWaitForLogMatchesLine(t *testing.T) {
+	e, r, mt, _ := setupWaitFor(t, config.WaitFor{Log: "system is (ready|up)"})
+	mt.On("ContainerLogs", "abc", true, true).Return(ioutil.NopCloser(bytes.NewBufferString("starting\ndatabase system is ready to accept connections\n")), nil)
+
+	err := e.waitFor(r)
+	assert.NoError(t, err)
+}
+
+func TestWaitForLogReturnsErrorWhenNoLineMatches(t *testing.T) {
+	e, r, mt, _ := setupWaitFor(t, config.WaitFor{Log: "system is ready"})
+	mt.On("ContainerLogs", "abc", true, true).Return(ioutil.NopCloser(bytes.NewBufferString("starting\n")), nil)
+
+	err := e.waitFor(r)
+	assert.Error(t, err)
+}
+
+var waitForBlueprint = `
+container "db" {
+  image {
+    name = "postgres:13"
+  }
+
+  wait_for {
+    tcp     = "%s"
+    timeout = "50ms"
+  }
+}
+
+container "api" {
+  image {
+    name = "api:latest"
+  }
+
+  depends_on = ["container.db"]
+}
+`
+
+func TestApplyWithFailedWaitForDoesNotCreateDependents(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	e, mp := setupTests(t, nil)
+
+	dir := t.TempDir()
+	err = ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(fmt.Sprintf(waitForBlueprint, addr)), 0644)
+	assert.NoError(t, err)
+
+	_, err = e.Apply(dir)
+	assert.Error(t, err)
+
+	created := false
+	for _, m := range *mp {
+		if m.Config().Info().Name == "db" {
+			m.AssertCalled(t, "Create")
+			assert.Equal(t, config.Failed, m.Config().Info().Status)
+			created = true
+		}
+
+		if m.Config().Info().Name == "api" {
+			m.AssertNotCalled(t, "Create")
+		}
+	}
+
+	assert.True(t, created)
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogFilePrefix is the prefix for the names of the log files written by a LogFile
+const LogFilePrefix = "engine-"
+
+// LogFile is a Writer which writes to a new timestamped file in a folder for each
+// process. When the file reaches the maximum size a new file is started, and the
+// oldest files in the folder are removed so that only the newest files are kept.
+// The file is only created when the first message is written.
+type LogFile struct {
+	dir     string
+	maxSize int64
+	retain  int
+	now     func() time.Time
+
+	sync sync.Mutex
+	file *os.File
+	path string
+	size int64
+	part int
+}
+
+// NewLogFile creates a LogFile which writes to dir, files are rotated when they
+// are larger than maxSize bytes and the newest retain files are kept
+func NewLogFile(dir string, maxSize int64, retain int) *LogFile {
+	return &LogFile{dir: dir, maxSize: maxSize, retain: retain, now: time.Now}
+}
+
+// Write the data to the current file, rotating the file if it would exceed
+// the maximum size
+func (l *LogFile) Write(p []byte) (int, error) {
+	l.sync.Lock()
+	defer l.sync.Unlock()
+
+	if l.file == nil || (l.size > 0 && l.size+int64(len(p)) > l.maxSize) {
+		err := l.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+
+	return n, err
+}
+
+// Path returns the path of the file which is currently being written,
+// returns an empty string when nothing has been written
+func (l *LogFile) Path() string {
+	l.sync.Lock()
+	defer l.sync.Unlock()
+
+	return l.path
+}
+
+// Close the current file
+func (l *LogFile) Close() error {
+	l.sync.Lock()
+	defer l.sync.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+
+	return err
+}
+
+// rotate closes the current file and opens the next file, the caller
+// must hold the lock
+func (l *LogFile) rotate() error {
+	if l.file != nil {
+		l.file.Close()
+		l.part++
+	}
+
+	err := os.MkdirAll(l.dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s%s-%d", LogFilePrefix, l.now().Format("20060102-150405"), os.Getpid())
+	if l.part > 0 {
+		name = fmt.Sprintf("%s.%d", name, l.part)
+	}
+
+	l.path = filepath.Join(l.dir, name+".log")
+	l.size = 0
+
+	l.file, err = os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	l.removeOldFiles()
+
+	return nil
+}
+
+// removeOldFiles removes the oldest log files so that only the
+// newest files are kept
+func (l *LogFile) removeOldFiles() {
+	files, err := LogFiles(l.dir)
+	if err != nil || len(files) <= l.retain {
+		return
+	}
+
+	for _, f := range files[:len(files)-l.retain] {
+		if f != l.path {
+			os.Remove(f)
+		}
+	}
+}
+
+// LogFiles returns the paths of the log files in dir ordered from
+// the oldest to the newest
+func LogFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []os.FileInfo{}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), LogFilePrefix) && strings.HasSuffix(e.Name(), ".log") {
+			infos = append(infos, e)
+		}
+	}
+
+	// files written in the same second are ordered by name
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].ModTime().Equal(infos[j].ModTime()) {
+			return infos[i].Name() < infos[j].Name()
+		}
+
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+
+	files := []string{}
+	for _, i := range infos {
+		files = append(files, filepath.Join(dir, i.Name()))
+	}
+
+	return files, nil
+}
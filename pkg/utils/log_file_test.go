@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestLogFileIsNotCreatedUntilWritten(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "engine")
+
+	l := NewLogFile(dir, 100, 5)
+	assert.Empty(t, l.Path())
+
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLogFileRotatesWhenMaxSizeReached(t *testing.T) {
+	dir := t.TempDir()
+
+	l := NewLogFile(dir, 10, 5)
+	defer l.Close()
+
+	l.Write([]byte("12345678\n"))
+	first := l.Path()
+
+	l.Write([]byte("abcdefgh\n"))
+	assert.NotEqual(t, first, l.Path())
+
+	d, err := ioutil.ReadFile(first)
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678\n", string(d))
+
+	d, err = ioutil.ReadFile(l.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdefgh\n", string(d))
+}
+
+func TestLogFileRemovesOldestFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	// files from previous runs
+	for i, n := range []string{"engine-20220101-100000-1.log", "engine-20220102-100000-1.log", "engine-20220103-100000-1.log"} {
+		p := filepath.Join(dir, n)
+		ioutil.WriteFile(p, []byte("old"), 0644)
+
+		mt := time.Now().Add(time.Duration(i-10) * time.Hour)
+		os.Chtimes(p, mt, mt)
+	}
+
+	l := NewLogFile(dir, 100, 2)
+	defer l.Close()
+
+	l.Write([]byte("new\n"))
+
+	files, err := LogFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "engine-20220103-100000-1.log"), l.Path()}, files)
+}
+
+func TestLogFilesReturnsEmptyWhenFolderMissing(t *testing.T) {
+	files, err := LogFiles(filepath.Join(t.TempDir(), "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
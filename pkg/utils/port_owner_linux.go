@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PortOwner returns the name and pid of the process listening on the port,
+// a blank string is returned when the process can not be determined such as
+// when the process is owned by another user
+func PortOwner(protocol string, port int) string {
+	inodes := portInodes(protocol, port)
+	if len(inodes) == 0 {
+		return ""
+	}
+
+	procs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return ""
+	}
+
+	for _, p := range procs {
+		fds, err := ioutil.ReadDir(filepath.Join(p, "fd"))
+		if err != nil {
+			continue
+		}
+
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(p, "fd", fd.Name()))
+			if err != nil || !inodes[link] {
+				continue
+			}
+
+			name, _ := ioutil.ReadFile(filepath.Join(p, "comm"))
+
+			return fmt.Sprintf("%s (pid %s)", strings.TrimSpace(string(name)), filepath.Base(p))
+		}
+	}
+
+	return ""
+}
+
+// portInodes returns the socket inodes listening on the port from the
+// kernel socket tables, keys are formatted as the fd links socket:[inode]
+func portInodes(protocol string, port int) map[string]bool {
+	tables := []string{"/proc/net/tcp", "/proc/net/tcp6"}
+	listen := "0A"
+
+	if protocol == "udp" {
+		tables = []string{"/proc/net/udp", "/proc/net/udp6"}
+		listen = "07"
+	}
+
+	inodes := map[string]bool{}
+	for _, t := range tables {
+		d, err := ioutil.ReadFile(t)
+		if err != nil {
+			continue
+		}
+
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		for _, l := range strings.Split(string(d), "\n")[1:] {
+			f := strings.Fields(l)
+			if len(f) < 10 || f[3] != listen {
+				continue
+			}
+
+			parts := strings.Split(f[1], ":")
+			p, err := strconv.ParseInt(parts[len(parts)-1], 16, 32)
+			if err != nil || int(p) != port {
+				continue
+			}
+
+			inodes[fmt.Sprintf("socket:[%s]", f[9])] = true
+		}
+	}
+
+	return inodes
+}
@@ -0,0 +1,46 @@
+//go:build !linux
+// +build !linux
+
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// PortOwner returns the name and pid of the process listening on the port,
+// a blank string is returned when the process can not be determined such as
+// when lsof is not installed
+func PortOwner(protocol string, port int) string {
+	if _, err := exec.LookPath("lsof"); err != nil {
+		return ""
+	}
+
+	args := []string{"-nP", fmt.Sprintf("-i%s:%d", strings.ToUpper(protocol), port), "-Fpc"}
+	if protocol != "udp" {
+		args = append(args, "-sTCP:LISTEN")
+	}
+
+	out, err := exec.Command("lsof", args...).Output()
+	if err != nil {
+		return ""
+	}
+
+	// output is a field per line prefixed with the field name e.g. p1234, cnginx
+	pid, name := "", ""
+	for _, l := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(l, "p") && pid == "":
+			pid = l[1:]
+		case strings.HasPrefix(l, "c") && name == "":
+			name = l[1:]
+		}
+	}
+
+	if pid == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s (pid %s)", name, pid)
+}
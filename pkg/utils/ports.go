@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"net"
+)
+
+// CheckPortAvailable returns an error when the port can not be bound on the
+// host interface bind, when bind is blank the port is checked on all interfaces
+func CheckPortAvailable(protocol, bind, port string) error {
+	addr := net.JoinHostPort(bind, port)
+
+	if protocol == "udp" {
+		l, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return err
+		}
+
+		return l.Close()
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return l.Close()
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestCheckPortAvailableReturnsNilWhenFree(t *testing.T) {
+	p, err := GetFreePort()
+	assert.NoError(t, err)
+
+	err = CheckPortAvailable("tcp", "127.0.0.1", fmt.Sprintf("%d", p))
+	assert.NoError(t, err)
+}
+
+func TestCheckPortAvailableReturnsErrorWhenInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	err = CheckPortAvailable("tcp", "127.0.0.1", fmt.Sprintf("%d", l.Addr().(*net.TCPAddr).Port))
+	assert.Error(t, err)
+}
+
+func TestCheckPortAvailableReturnsErrorWhenUDPInUse(t *testing.T) {
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	err = CheckPortAvailable("udp", "127.0.0.1", fmt.Sprintf("%d", l.LocalAddr().(*net.UDPAddr).Port))
+	assert.Error(t, err)
+}
+
+func TestPortOwnerReturnsProcess(t *testing.T) {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("process table is not available")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	owner := PortOwner("tcp", l.Addr().(*net.TCPAddr).Port)
+	assert.Contains(t, owner, fmt.Sprintf("(pid %d)", os.Getpid()))
+}
+
+func TestPortOwnerReturnsBlankWhenNotInUse(t *testing.T) {
+	p, err := GetFreePort()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "", PortOwner("tcp", p))
+}
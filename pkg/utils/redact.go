@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces sensitive values in logs and output
+const RedactedValue = "(sensitive)"
+
+var sensitiveValues = map[string]bool{}
+var sensitiveLock sync.RWMutex
+
+// AddSensitiveValue adds a value which is replaced by Redact, such as the
+// value of a sensitive variable or output or a generated password
+func AddSensitiveValue(v string) {
+	if v == "" {
+		return
+	}
+
+	sensitiveLock.Lock()
+	defer sensitiveLock.Unlock()
+
+	sensitiveValues[v] = true
+}
+
+// ClearSensitiveValues removes all the values added with AddSensitiveValue
+func ClearSensitiveValues() {
+	sensitiveLock.Lock()
+	defer sensitiveLock.Unlock()
+
+	sensitiveValues = map[string]bool{}
+}
+
+// Redact replaces the sensitive values in s with RedactedValue
+func Redact(s string) string {
+	sensitiveLock.RLock()
+	defer sensitiveLock.RUnlock()
+
+	if len(sensitiveValues) == 0 {
+		return s
+	}
+
+	// replace the longest values first so that values which contain
+	// another value are completely redacted
+	values := make([]string, 0, len(sensitiveValues))
+	for v := range sensitiveValues {
+		values = append(values, v)
+	}
+
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	for _, v := range values {
+		s = strings.ReplaceAll(s, v, RedactedValue)
+	}
+
+	return s
+}
+
+// RedactingWriter is a writer which redacts sensitive values before
+// writing to the underlying writer, each write is redacted separately
+// so values are only redacted when they are not split across writes
+type RedactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter creates a RedactingWriter which writes to w
+func NewRedactingWriter(w io.Writer) *RedactingWriter {
+	return &RedactingWriter{w}
+}
+
+// Write the redacted data, the length of p is returned when the write
+// succeeds as the redacted data can be a different length
+func (r *RedactingWriter) Write(p []byte) (int, error) {
+	_, err := r.w.Write([]byte(Redact(string(p))))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
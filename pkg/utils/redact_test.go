@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactReplacesSensitiveValues(t *testing.T) {
+	t.Cleanup(ClearSensitiveValues)
+
+	AddSensitiveValue("s3cret")
+	AddSensitiveValue("s3cret-token")
+
+	assert.Equal(t, "password=(sensitive) token=(sensitive)", Redact("password=s3cret token=s3cret-token"))
+}
+
+func TestRedactIgnoresEmptyValues(t *testing.T) {
+	t.Cleanup(ClearSensitiveValues)
+
+	AddSensitiveValue("")
+
+	assert.Equal(t, "nothing to hide", Redact("nothing to hide"))
+}
+
+func TestRedactingWriterRedactsWrites(t *testing.T) {
+	t.Cleanup(ClearSensitiveValues)
+	AddSensitiveValue("s3cret")
+
+	out := bytes.NewBuffer(nil)
+	w := NewRedactingWriter(out)
+
+	n, err := w.Write([]byte("[INFO] password: s3cret\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 24, n)
+
+	assert.Equal(t, "[INFO] password: (sensitive)\n", out.String())
+}
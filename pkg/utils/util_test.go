@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
@@ -130,7 +133,8 @@ func TestCreateKubeConfigPathReturnsCorrectValues(t *testing.T) {
 	os.Setenv(HomeEnvName(), tmp)
 	defer os.Setenv(HomeEnvName(), home)
 
-	d, f, dp := CreateKubeConfigPath("testing")
+	d, f, dp, err := CreateKubeConfigPath("testing")
+	assert.NoError(t, err)
 
 	assert.Equal(t, filepath.Join(tmp, ".shipyard", "config", "testing"), d)
 	assert.Equal(t, filepath.Join(tmp, ".shipyard", "config", "testing", "kubeconfig.yaml"), f)
@@ -165,7 +169,8 @@ func TestGetClusterConfigReturnsExistingConfig(t *testing.T) {
 	err := cc.Save(filepath.Join(configDir, "config.json"))
 	assert.NoError(t, err)
 
-	conf, dir := GetClusterConfig("nomad_cluster.testing")
+	conf, dir, err := GetClusterConfig("nomad_cluster.testing")
+	assert.NoError(t, err)
 
 	assert.Equal(t, cc.LocalAddress, conf.LocalAddress)
 	assert.Equal(t, configDir, dir)
@@ -174,7 +179,8 @@ func TestGetClusterConfigReturnsExistingConfig(t *testing.T) {
 func TestGetClusterConfigReturnsEmptyWhenUnableToParseName(t *testing.T) {
 	setupClusterConfigTest(t)
 
-	conf, dir := GetClusterConfig("nomad")
+	conf, dir, err := GetClusterConfig("nomad")
+	assert.NoError(t, err)
 
 	assert.Equal(t, "", conf.LocalAddress)
 	assert.Equal(t, "", dir)
@@ -184,7 +190,8 @@ func TestGetClusterConfigCreatesNewNomadConfig(t *testing.T) {
 	setupClusterConfigTest(t)
 	configDir := filepath.Join(ShipyardHome(), "config", "testing")
 
-	conf, dir := GetClusterConfig("nomad_cluster.testing")
+	conf, dir, err := GetClusterConfig("nomad_cluster.testing")
+	assert.NoError(t, err)
 
 	assert.Contains(t, GetDockerIP(), conf.LocalAddress)
 	assert.Equal(t, 4646, conf.RemoteAPIPort)
@@ -196,8 +203,8 @@ func TestGetClusterConfigCreatesNewNomadConfig(t *testing.T) {
 func TestGetClusterConfigTwiceReturnsSameConfig(t *testing.T) {
 	setupClusterConfigTest(t)
 
-	conf, _ := GetClusterConfig("nomad_cluster.testing")
-	conf2, _ := GetClusterConfig("nomad_cluster.testing")
+	conf, _, _ := GetClusterConfig("nomad_cluster.testing")
+	conf2, _, _ := GetClusterConfig("nomad_cluster.testing")
 
 	assert.Equal(t, conf2.ConnectorAddress(LocalContext), conf.ConnectorAddress(LocalContext))
 }
@@ -206,7 +213,8 @@ func TestGetClusterConfigCreatesNewKubernetesConfig(t *testing.T) {
 	setupClusterConfigTest(t)
 	configDir := filepath.Join(ShipyardHome(), "config", "testing")
 
-	conf, dir := GetClusterConfig("k8s_cluster.testing")
+	conf, dir, err := GetClusterConfig("k8s_cluster.testing")
+	assert.NoError(t, err)
 
 	assert.Contains(t, GetDockerIP(), conf.LocalAddress)
 	assert.Equal(t, conf.APIPort, conf.RemoteAPIPort)
@@ -225,15 +233,58 @@ func TestShipyardTempReturnsPath(t *testing.T) {
 		os.RemoveAll(tmp)
 	})
 
-	st := ShipyardTemp()
+	st, err := ShipyardTemp()
+	assert.NoError(t, err)
 
-	assert.Equal(t, filepath.Join(tmp, ".shipyard", "/tmp"), st)
+	assert.Equal(t, filepath.Join(tmp, ".shipyard", "tmp"), st)
 
 	s, err := os.Stat(st)
 	assert.NoError(t, err)
 	assert.True(t, s.IsDir())
 }
 
+// setupReadOnlyShipyardHome replaces the Shipyard home with a file so
+// that folders can not be created in it
+func setupReadOnlyShipyardHome(t *testing.T) {
+	setupClusterConfigTest(t)
+
+	ioutil.WriteFile(ShipyardHome(), []byte(""), os.ModePerm)
+}
+
+func TestCreateKubeConfigPathReturnsErrorWhenUnableToCreateFolder(t *testing.T) {
+	setupReadOnlyShipyardHome(t)
+
+	_, _, _, err := CreateKubeConfigPath("testing")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to create the config folder for cluster testing")
+}
+
+func TestGetClusterConfigReturnsErrorWhenUnableToCreateFolder(t *testing.T) {
+	setupReadOnlyShipyardHome(t)
+
+	_, _, err := GetClusterConfig("k8s_cluster.testing")
+	assert.Error(t, err)
+}
+
+func TestGetClusterConfigReturnsErrorWhenConfigInvalid(t *testing.T) {
+	setupClusterConfigTest(t)
+
+	configDir := filepath.Join(ShipyardHome(), "config", "testing")
+	os.MkdirAll(configDir, os.ModePerm)
+	ioutil.WriteFile(filepath.Join(configDir, "config.json"), []byte("{"), os.ModePerm)
+
+	_, _, err := GetClusterConfig("k8s_cluster.testing")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to read the config for k8s_cluster.testing")
+}
+
+func TestShipyardTempReturnsErrorWhenUnableToCreateFolder(t *testing.T) {
+	setupReadOnlyShipyardHome(t)
+
+	_, err := ShipyardTemp()
+	assert.Error(t, err)
+}
+
 func TestShipyardDataReturnsPath(t *testing.T) {
 	home := os.Getenv(HomeEnvName())
 	tmp, _ := ioutil.TempDir("", "")
@@ -266,7 +317,7 @@ func TestShipyardReleasesReturnsPath(t *testing.T) {
 	assert.Equal(t, filepath.Join(os.Getenv(HomeEnvName()), ".shipyard", "/releases"), r)
 }
 
-func TestIsHCLFile(t *testing.T) {
+func TestIsConfigFile(t *testing.T) {
 	tests := []struct {
 		name string
 		path string
@@ -286,15 +337,19 @@ func TestIsHCLFile(t *testing.T) {
 			"../../examples/single_k3s_cluster/k8s.hcl",
 			true,
 		}, {
-			"False when other file",
+			"True when .yaml file",
 			"../../examples/single_k3s_cluster/helm/consul-values.yaml",
+			true,
+		}, {
+			"False when other file",
+			"../../examples/single_k3s_cluster/k3s.yard",
 			false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := IsHCLFile(tt.path); got != tt.want {
-				t.Errorf("IsHCLFile() = %v, want %v", got, tt.want)
+			if got := IsConfigFile(tt.path); got != tt.want {
+				t.Errorf("IsConfigFile() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -313,10 +368,149 @@ func TestDockerHostWithDefaultReturnsCorrectValue(t *testing.T) {
 		os.Setenv("DOCKER_HOST", dh)
 	})
 
+	setupDockerContext(t, "", "")
+
 	ds := GetDockerHost()
 	assert.Equal(t, "/var/run/docker.sock", ds)
 }
 
+func setupDockerContext(t *testing.T, name, host string) {
+	dir := t.TempDir()
+
+	dc := os.Getenv("DOCKER_CONFIG")
+	dctx := os.Getenv("DOCKER_CONTEXT")
+	t.Cleanup(func() {
+		os.Setenv("DOCKER_CONFIG", dc)
+		os.Setenv("DOCKER_CONTEXT", dctx)
+	})
+
+	os.Setenv("DOCKER_CONFIG", dir)
+	os.Unsetenv("DOCKER_CONTEXT")
+
+	if name == "" {
+		return
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(fmt.Sprintf(`{"currentContext": "%s"}`, name)), os.ModePerm)
+
+	meta := filepath.Join(dir, "contexts", "meta", fmt.Sprintf("%x", sha256.Sum256([]byte(name))))
+	os.MkdirAll(meta, os.ModePerm)
+	ioutil.WriteFile(
+		filepath.Join(meta, "meta.json"),
+		[]byte(fmt.Sprintf(`{"Name":"%s","Metadata":{},"Endpoints":{"docker":{"Host":"%s","SkipTLSVerify":false}}}`, name, host)),
+		os.ModePerm,
+	)
+}
+
+func TestDockerHostReturnsHostFromCurrentContext(t *testing.T) {
+	dh := os.Getenv("DOCKER_HOST")
+	os.Unsetenv("DOCKER_HOST")
+	t.Cleanup(func() {
+		os.Setenv("DOCKER_HOST", dh)
+	})
+
+	setupDockerContext(t, "remote", "ssh://nic@10.5.0.2")
+
+	assert.Equal(t, "ssh://nic@10.5.0.2", GetDockerHost())
+	assert.Equal(t, "10.5.0.2", GetDockerIP())
+}
+
+func TestDockerHostReturnsHostFromDockerContextEnv(t *testing.T) {
+	dh := os.Getenv("DOCKER_HOST")
+	os.Unsetenv("DOCKER_HOST")
+	t.Cleanup(func() {
+		os.Setenv("DOCKER_HOST", dh)
+	})
+
+	setupDockerContext(t, "remote", "npipe:////./pipe/docker_engine")
+
+	// the env var overrides the current context, unknown contexts are ignored
+	os.Setenv("DOCKER_CONTEXT", "other")
+	assert.NotEqual(t, "npipe:////./pipe/docker_engine", GetDockerHost())
+
+	os.Setenv("DOCKER_CONTEXT", "remote")
+	assert.Equal(t, "npipe:////./pipe/docker_engine", GetDockerHost())
+}
+
+func TestDockerHostEnvOverridesContext(t *testing.T) {
+	dh := os.Getenv("DOCKER_HOST")
+	os.Setenv("DOCKER_HOST", "tcp://localhost:2375")
+	t.Cleanup(func() {
+		os.Setenv("DOCKER_HOST", dh)
+	})
+
+	setupDockerContext(t, "remote", "ssh://nic@10.5.0.2")
+
+	assert.Equal(t, "tcp://localhost:2375", GetDockerHost())
+}
+
+func setupPodmanSocket(t *testing.T, rootless bool) string {
+	dir := t.TempDir()
+
+	oldDocker := dockerSocket
+	oldPodman := podmanRootSocket
+	xdg := os.Getenv("XDG_RUNTIME_DIR")
+	dh := os.Getenv("DOCKER_HOST")
+
+	t.Cleanup(func() {
+		dockerSocket = oldDocker
+		podmanRootSocket = oldPodman
+		os.Setenv("XDG_RUNTIME_DIR", xdg)
+		os.Setenv("DOCKER_HOST", dh)
+	})
+
+	os.Unsetenv("DOCKER_HOST")
+	setupDockerContext(t, "", "")
+	os.Setenv("XDG_RUNTIME_DIR", filepath.Join(dir, "user"))
+	dockerSocket = filepath.Join(dir, "docker.sock")
+	podmanRootSocket = filepath.Join(dir, "podman.sock")
+
+	socket := podmanRootSocket
+	if rootless {
+		socket = filepath.Join(dir, "user", "podman", "podman.sock")
+	}
+
+	os.MkdirAll(filepath.Dir(socket), os.ModePerm)
+	ioutil.WriteFile(socket, []byte(""), os.ModePerm)
+
+	return socket
+}
+
+func TestDockerHostReturnsRootlessPodmanSocketWhenNoDocker(t *testing.T) {
+	socket := setupPodmanSocket(t, true)
+
+	ds := GetDockerHost()
+	assert.Equal(t, socket, ds)
+	assert.True(t, IsRootlessPodmanSocket(ds))
+}
+
+func TestDockerHostReturnsPodmanSocketWhenNoDocker(t *testing.T) {
+	socket := setupPodmanSocket(t, false)
+
+	ds := GetDockerHost()
+	assert.Equal(t, socket, ds)
+	assert.False(t, IsRootlessPodmanSocket(ds))
+}
+
+func TestDockerHostReturnsRootlessDockerSocketWhenNoDocker(t *testing.T) {
+	socket := setupPodmanSocket(t, true)
+
+	rootless := filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), "docker.sock")
+	ioutil.WriteFile(rootless, []byte(""), os.ModePerm)
+
+	ds := GetDockerHost()
+	assert.Equal(t, rootless, ds)
+	assert.NotEqual(t, socket, ds)
+}
+
+func TestDockerHostReturnsDockerSocketWhenDockerAndPodman(t *testing.T) {
+	setupPodmanSocket(t, true)
+	ioutil.WriteFile(dockerSocket, []byte(""), os.ModePerm)
+
+	ds := GetDockerHost()
+	assert.Equal(t, dockerSocket, ds)
+}
+
 func TestGetLocalIPAndHostnameReturnsCorrectly(t *testing.T) {
 	ip, host := GetLocalIPAndHostname()
 
@@ -351,3 +545,189 @@ func TestHTTPSProxyAddressReturnsEnvWhenEnvSet(t *testing.T) {
 
 	assert.Equal(t, httpsProxy, proxy)
 }
+
+func TestMergeNoProxyRemovesDuplicatesAndEmptyEntries(t *testing.T) {
+	np := MergeNoProxy("a.corp, b.corp", "", "b.corp,,localhost")
+
+	assert.Equal(t, "a.corp,b.corp,localhost", np)
+}
+
+func TestNoProxyAddsEnvToBypass(t *testing.T) {
+	t.Setenv("NO_PROXY", "internal.corp")
+
+	assert.Equal(t, ProxyBypass+",internal.corp", NoProxy())
+}
+
+func TestGetFreePortReturnsUnusedPort(t *testing.T) {
+	p, err := GetFreePort()
+	assert.NoError(t, err)
+	assert.Greater(t, p, 0)
+
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", p))
+	assert.NoError(t, err)
+	l.Close()
+}
+
+func setupContainerEnv(t *testing.T) string {
+	dir := t.TempDir()
+
+	oldFiles := containerEnvFiles
+	oldMountInfo := mountInfoPath
+	ic := os.Getenv("SHIPYARD_IN_CONTAINER")
+	id := os.Getenv("SHIPYARD_CONTAINER_ID")
+
+	t.Cleanup(func() {
+		containerEnvFiles = oldFiles
+		mountInfoPath = oldMountInfo
+		os.Setenv("SHIPYARD_IN_CONTAINER", ic)
+		os.Setenv("SHIPYARD_CONTAINER_ID", id)
+	})
+
+	os.Unsetenv("SHIPYARD_IN_CONTAINER")
+	os.Unsetenv("SHIPYARD_CONTAINER_ID")
+	containerEnvFiles = []string{filepath.Join(dir, ".dockerenv")}
+	mountInfoPath = filepath.Join(dir, "mountinfo")
+
+	return dir
+}
+
+func TestInContainerReturnsTrueWhenDockerEnvExists(t *testing.T) {
+	dir := setupContainerEnv(t)
+	assert.False(t, InContainer())
+
+	ioutil.WriteFile(filepath.Join(dir, ".dockerenv"), []byte(""), os.ModePerm)
+	assert.True(t, InContainer())
+}
+
+func TestInContainerIsOverriddenByEnv(t *testing.T) {
+	dir := setupContainerEnv(t)
+	ioutil.WriteFile(filepath.Join(dir, ".dockerenv"), []byte(""), os.ModePerm)
+
+	os.Setenv("SHIPYARD_IN_CONTAINER", "false")
+	assert.False(t, InContainer())
+
+	os.Setenv("SHIPYARD_IN_CONTAINER", "true")
+	assert.True(t, InContainer())
+}
+
+func TestContainerIDReadsIDFromMountInfo(t *testing.T) {
+	dir := setupContainerEnv(t)
+
+	id := "8f3c2a1b4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8"
+	mi := fmt.Sprintf("1140 1120 254:1 /docker/containers/%s/hostname /etc/hostname rw,relatime - ext4 /dev/vda1 rw\n", id)
+	ioutil.WriteFile(filepath.Join(dir, "mountinfo"), []byte(mi), os.ModePerm)
+
+	assert.Equal(t, id, ContainerID())
+
+	os.Setenv("SHIPYARD_CONTAINER_ID", "abc")
+	assert.Equal(t, "abc", ContainerID())
+}
+
+func TestContainerIDDefaultsToHostname(t *testing.T) {
+	setupContainerEnv(t)
+
+	assert.Equal(t, GetHostname(), ContainerID())
+}
+
+func TestSetDockerIPOverridesDockerIP(t *testing.T) {
+	SetDockerIP("172.17.0.1")
+	t.Cleanup(func() { SetDockerIP("") })
+
+	assert.Equal(t, "172.17.0.1", GetDockerIP())
+}
+
+func TestSetShipyardHomeOverridesPaths(t *testing.T) {
+	SetShipyardHome("/tmp/embedded")
+	t.Cleanup(func() { SetShipyardHome("") })
+
+	assert.Equal(t, "/tmp/embedded", ShipyardHome())
+	assert.Equal(t, filepath.Join("/tmp/embedded", "state", "state.json"), StatePath())
+}
+
+func setupGlobTests(t *testing.T) string {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "manifests", "db", "config"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "manifests", "web.yaml"), []byte(""), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "manifests", "db", "db.yaml"), []byte(""), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "manifests", "db", "config", "config.yaml"), []byte(""), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "manifests", "db", "README.md"), []byte(""), 0644)
+
+	return dir
+}
+
+func TestIsGlobDetectsPatterns(t *testing.T) {
+	assert.True(t, IsGlob("./manifests/*.yaml"))
+	assert.True(t, IsGlob("./manifests/[ab].yaml"))
+	assert.False(t, IsGlob("./manifests/app.yaml"))
+}
+
+func TestGlobMatchesFilesInDirectory(t *testing.T) {
+	dir := setupGlobTests(t)
+
+	files, err := Glob(filepath.Join(dir, "manifests", "*.yaml"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{filepath.Join(dir, "manifests", "web.yaml")}, files)
+}
+
+func TestGlobMatchesFilesInSubDirectoriesSorted(t *testing.T) {
+	dir := setupGlobTests(t)
+
+	files, err := Glob(filepath.Join(dir, "manifests", "**", "*.yaml"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "manifests", "db", "config", "config.yaml"),
+		filepath.Join(dir, "manifests", "db", "db.yaml"),
+		filepath.Join(dir, "manifests", "web.yaml"),
+	}, files)
+}
+
+func TestGlobReturnsEmptyWhenDirectoryDoesNotExist(t *testing.T) {
+	files, err := Glob(filepath.Join(t.TempDir(), "missing", "*.yaml"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestGlobReturnsErrorWithInvalidPattern(t *testing.T) {
+	_, err := Glob("/manifests/[a.yaml")
+	assert.Error(t, err)
+}
+
+func TestDockerHostReturnsNamedPipeOnWindows(t *testing.T) {
+	setupPodmanSocket(t, false)
+
+	old := goos
+	goos = "windows"
+	t.Cleanup(func() {
+		goos = old
+	})
+
+	assert.Equal(t, "npipe:////./pipe/docker_engine", GetDockerHost())
+}
+
+func TestDockerHostReturnsDockerHostEnvOnWindows(t *testing.T) {
+	setupPodmanSocket(t, false)
+	os.Setenv("DOCKER_HOST", "tcp://localhost:2375")
+
+	old := goos
+	goos = "windows"
+	t.Cleanup(func() {
+		goos = old
+	})
+
+	assert.Equal(t, "tcp://localhost:2375", GetDockerHost())
+}
+
+func TestClusterConfigExistsReturnsFalseWithoutCreatingConfig(t *testing.T) {
+	setupClusterConfigTest(t)
+
+	assert.False(t, ClusterConfigExists("k8s_cluster.testing"))
+	assert.NoDirExists(t, filepath.Join(ShipyardHome(), "config", "testing"))
+
+	_, _, err := GetClusterConfig("k8s_cluster.testing")
+	assert.NoError(t, err)
+
+	assert.True(t, ClusterConfigExists("k8s_cluster.testing"))
+}
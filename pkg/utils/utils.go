@@ -2,6 +2,8 @@ package utils
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -12,6 +14,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -39,15 +42,13 @@ func ValidateName(name string) (bool, error) {
 	return true, nil
 }
 
+// nonURIChars matches the characters which can not be used in a URI
+var nonURIChars = regexp.MustCompile(`[^a-zA-Z0-9\-\.]+`)
+
 // ReplaceNonURIChars replaces any characters in the resrouce name which
 // can not be used in a URI
 func ReplaceNonURIChars(s string) (string, error) {
-	reg, err := regexp.Compile(`[^a-zA-Z0-9\-\.]+`)
-	if err != nil {
-		return "", err
-	}
-
-	return reg.ReplaceAllString(s, "-"), nil
+	return nonURIChars.ReplaceAllString(s, "-"), nil
 }
 
 // FQDN generates the full qualified name for a container
@@ -55,70 +56,63 @@ func FQDN(name, typeName string) string {
 	fqdn := fmt.Sprintf("%s.%s.shipyard.run", name, typeName)
 
 	// ensure that the name is valid for URI schema
-	cleanName, err := ReplaceNonURIChars(fqdn)
-	if err != nil {
-		panic(err)
-	}
-
-	return cleanName
+	return nonURIChars.ReplaceAllString(fqdn, "-")
 }
 
 // FQDNVolumeName creates a full qualified volume name
 func FQDNVolumeName(name string) string {
 	// ensure that the name is valid for URI schema
-	cleanName, err := ReplaceNonURIChars(name)
-	if err != nil {
-		panic(err)
-	}
-
-	return fmt.Sprintf("%s.volume.shipyard.run", cleanName)
+	return fmt.Sprintf("%s.volume.shipyard.run", nonURIChars.ReplaceAllString(name, "-"))
 }
 
 // CreateKubeConfigPath creates the file path for the KubeConfig file when
-// using Kubernetes cluster
-func CreateKubeConfigPath(name string) (dir, filePath string, dockerPath string) {
-	dir = filepath.Join(ShipyardHome(), "/config/", name)
-	filePath = filepath.Join(dir, "/kubeconfig.yaml")
-	dockerPath = filepath.Join(dir, "/kubeconfig-docker.yaml")
+// using Kubernetes cluster, an error is returned when the folder can not be created
+func CreateKubeConfigPath(name string) (dir, filePath string, dockerPath string, err error) {
+	dir = filepath.Join(ShipyardHome(), "config", name)
+	filePath = filepath.Join(dir, "kubeconfig.yaml")
+	dockerPath = filepath.Join(dir, "kubeconfig-docker.yaml")
 
 	// create the folders
-	err := os.MkdirAll(dir, 0755)
+	err = os.MkdirAll(dir, 0755)
 	if err != nil {
-		panic(err)
+		return "", "", "", fmt.Errorf("unable to create the config folder for cluster %s: %w", name, err)
 	}
 
-	return
+	return dir, filePath, dockerPath, nil
 }
 
 // GetClusterConfig creates the file path for the Cluster config
-// which stores details such as the API server location
-func GetClusterConfig(name string) (ClusterConfig, string) {
-	// check if the file exists return if so
-
+// which stores details such as the API server location, an empty config
+// is returned when the name is not a cluster
+func GetClusterConfig(name string) (ClusterConfig, string, error) {
 	// split the name
 	parts := strings.Split(name, ".")
 	if len(parts) < 2 {
-		return ClusterConfig{}, ""
+		return ClusterConfig{}, "", nil
 	}
 
 	if parts[0] != "nomad_cluster" && parts[0] != "k8s_cluster" {
-		return ClusterConfig{}, ""
+		return ClusterConfig{}, "", nil
 	}
 
-	dir := filepath.Join(ShipyardHome(), "/config/", parts[1])
-	filePath := filepath.Join(dir, "/config.json")
+	dir := filepath.Join(ShipyardHome(), "config", parts[1])
+	filePath := filepath.Join(dir, "config.json")
 
-	if _, err := os.Stat(filePath); err == nil {
+	// check if the file exists return if so
+	if ClusterConfigExists(name) {
 		cc := ClusterConfig{}
-		cc.Load(filePath)
+		err := cc.Load(filePath)
+		if err != nil {
+			return ClusterConfig{}, "", fmt.Errorf("unable to read the config for %s from %s: %w", name, filePath, err)
+		}
 
-		return cc, dir
+		return cc, dir, nil
 	}
 
 	// create the folders
 	err := os.MkdirAll(dir, 0755)
 	if err != nil {
-		panic(err)
+		return ClusterConfig{}, "", fmt.Errorf("unable to create the config folder for %s: %w", name, err)
 	}
 
 	//// create the config file
@@ -140,9 +134,24 @@ func GetClusterConfig(name string) (ClusterConfig, string) {
 		RemoteAPIPort: remoteAPIPort,
 	}
 
-	config.Save(filePath)
+	err = config.Save(filePath)
+	if err != nil {
+		return ClusterConfig{}, "", fmt.Errorf("unable to write the config for %s to %s: %w", name, filePath, err)
+	}
 
-	return config, dir
+	return config, dir, nil
+}
+
+// ClusterConfigExists returns true when the config for the cluster with the given
+// name has been created, unlike GetClusterConfig it does not create the config
+func ClusterConfigExists(name string) bool {
+	parts := strings.Split(name, ".")
+	if len(parts) < 2 {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join(ShipyardHome(), "config", parts[1], "config.json"))
+	return err == nil
 }
 
 // HomeFolder returns the users homefolder this will be $HOME on windows and mac and
@@ -153,7 +162,7 @@ func HomeFolder() string {
 
 // HomeEnvName returns the environment variable used to store the home path
 func HomeEnvName() string {
-	if runtime.GOOS == "windows" {
+	if goos == "windows" {
 		return "USERPROFILE"
 	}
 
@@ -163,18 +172,33 @@ func HomeEnvName() string {
 // ShipyardHome returns the location of the shipyard
 // folder, usually $HOME/.shipyard
 func ShipyardHome() string {
+	if shipyardHome != "" {
+		return shipyardHome
+	}
+
 	return filepath.Join(HomeFolder(), "/.shipyard")
 }
 
-// ShipyardTemp returns a temporary folder
-func ShipyardTemp() string {
-	dir := filepath.Join(ShipyardHome(), "/tmp")
+// shipyardHome overrides the folder returned by ShipyardHome, it is set by
+// programs which embed the engine and do not use $HOME/.shipyard
+var shipyardHome string
+
+// SetShipyardHome sets the folder returned by ShipyardHome, setting a blank
+// folder removes the override
+func SetShipyardHome(dir string) {
+	shipyardHome = dir
+}
+
+// ShipyardTemp returns a temporary folder, the folder is created
+// when it does not exist
+func ShipyardTemp() (string, error) {
+	dir := filepath.Join(ShipyardHome(), "tmp")
 	err := os.MkdirAll(dir, 0755)
 	if err != nil {
-		panic(err)
+		return "", fmt.Errorf("unable to create the temporary folder %s: %w", dir, err)
 	}
 
-	return dir
+	return dir, nil
 }
 
 // StateDir returns the location of the shipyard
@@ -203,14 +227,62 @@ func LogsDir() string {
 	return logs
 }
 
+// EngineLogsDir returns the location of the log files written by
+// each run of the shipyard CLI, usually $HOME/.shipyard/logs/engine
+func EngineLogsDir() string {
+	return filepath.Join(ShipyardHome(), "logs", "engine")
+}
+
 // StatePath returns the full path for the state file
 func StatePath() string {
 	return filepath.Join(StateDir(), "/state.json")
 }
 
+// HistoryPath returns the location of the file which records
+// the history of apply and destroy commands
+func HistoryPath() string {
+	return filepath.Join(ShipyardHome(), "/history.log")
+}
+
+// ProgressSocketPath returns the location of the unix socket which
+// exposes the progress of a running apply
+func ProgressSocketPath() string {
+	return filepath.Join(ShipyardHome(), "/progress.sock")
+}
+
+// APITokenPath returns the location of the token used to authenticate
+// with the API started by shipyard serve
+func APITokenPath() string {
+	return filepath.Join(ShipyardHome(), "/api_token")
+}
+
+// PluginsDir returns the location of the plugin executables which
+// provide additional resource types, usually $HOME/.shipyard/plugins
+func PluginsDir() string {
+	return filepath.Join(ShipyardHome(), "/plugins")
+}
+
+// SnapshotsDir returns the location of the environment snapshots
+// created with shipyard snapshot, usually $HOME/.shipyard/snapshots
+func SnapshotsDir() string {
+	return filepath.Join(ShipyardHome(), "/snapshots")
+}
+
+// UserConfigPath returns the location of the global user config
+// which defines settings such as hooks for all blueprints
+func UserConfigPath() string {
+	return filepath.Join(ShipyardHome(), "/config.hcl")
+}
+
+// SourceLockPath returns the location of the lock file which records the
+// version of the blueprints and Helm charts in the cache
+func SourceLockPath() string {
+	return filepath.Join(ShipyardHome(), "sources.lock.json")
+}
+
 // ImageCacheLog returns the location of the image cache log
 func ImageCacheLog() string {
-	return fmt.Sprintf("%s/images.log", ShipyardHome())
+	return filepath.Join(ShipyardHome(), "images.log")
 }
 
 // IsLocalFolder tests if the given path is a localfolder and can
@@ -231,8 +303,12 @@ func IsLocalFolder(path string) bool {
 	return true
 }
 
-// IsHCLFile tests if the given path resolves to a HCL config file
-func IsHCLFile(path string) bool {
+// ConfigExtensions are the extensions of the files which can contain resources,
+// resources can be defined in HCL, JSON, or YAML
+var ConfigExtensions = []string{".hcl", ".json", ".yaml", ".yml"}
+
+// IsConfigFile tests if the given path resolves to a HCL, JSON, or YAML config file
+func IsConfigFile(path string) bool {
 	s, err := os.Stat(path)
 	if err != nil {
 		return false
@@ -242,11 +318,13 @@ func IsHCLFile(path string) bool {
 		return false
 	}
 
-	if filepath.Ext(s.Name()) != ".hcl" {
-		return false
+	for _, ext := range ConfigExtensions {
+		if filepath.Ext(s.Name()) == ext {
+			return true
+		}
 	}
 
-	return true
+	return false
 }
 
 func sanitizeBlueprintFolder(blueprint string) string {
@@ -255,7 +333,8 @@ func sanitizeBlueprintFolder(blueprint string) string {
 	blueprint = strings.ReplaceAll(blueprint, "&", "/")
 	blueprint = strings.ReplaceAll(blueprint, "=", "/")
 
-	return blueprint
+	// the folder is used as a path on the host
+	return filepath.FromSlash(blueprint)
 }
 
 // GetBlueprintFolder parses a blueprint uri and returns the top level
@@ -269,7 +348,7 @@ func GetBlueprintFolder(blueprint string) (string, error) {
 		return "", InvalidBlueprintURIError
 	}
 
-	return sanitizeBlueprintFolder(parts[1]), nil
+	return filepath.ToSlash(sanitizeBlueprintFolder(parts[1])), nil
 }
 
 // GetBlueprintLocalFolder returns the full storage path
@@ -290,6 +369,103 @@ func GetHelmLocalFolder(chart string) string {
 	return filepath.Join(ShipyardHome(), "helm_charts", chart)
 }
 
+// GetManifestLocalFolder returns the full storage path
+// for the given Kubernetes manifest URI
+func GetManifestLocalFolder(uri string) string {
+	uri = sanitizeBlueprintFolder(uri)
+
+	return filepath.Join(ShipyardHome(), "manifests", uri)
+}
+
+// IsGlob returns true when the path contains glob pattern characters
+func IsGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// Glob returns the files which match the pattern sorted by name, in addition to the
+// patterns supported by filepath.Match, ** matches any number of directories
+func Glob(pattern string) ([]string, error) {
+	pattern = filepath.ToSlash(pattern)
+
+	re, err := globRegexp(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %s: %s", pattern, err)
+	}
+
+	// walk from the deepest directory which does not contain a pattern
+	root := pattern[:strings.IndexAny(pattern, "*?[")]
+	root = root[:strings.LastIndex(root, "/")+1]
+	if root == "" {
+		root = "."
+	}
+
+	files := []string{}
+	err = filepath.Walk(filepath.FromSlash(root), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && re.MatchString(filepath.ToSlash(path)) {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// globRegexp converts a glob pattern into a regular expression
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	re := strings.Builder{}
+	re.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+
+		switch c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				// matches zero or more directories
+				re.WriteString("(.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				re.WriteString(".*")
+				i++
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			re.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("missing closing ]")
+			}
+
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			re.WriteString("[" + class + "]")
+			i += end
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	re.WriteString("$")
+
+	return regexp.Compile(re.String())
+}
+
 // GetReleasesFolder return the path of the Shipyard releases
 func GetReleasesFolder() string {
 	return filepath.Join(ShipyardHome(), "releases")
@@ -303,26 +479,159 @@ func GetDataFolder(p string) string {
 	return data
 }
 
+// default locations for the Docker and Podman API sockets, variables
+// allow the locations to be replaced in tests
+var dockerSocket = "/var/run/docker.sock"
+var dockerPipe = "npipe:////./pipe/docker_engine"
+var podmanRootSocket = "/run/podman/podman.sock"
+
+// goos is the operating system Shipyard is running on, it is replaced in tests
+var goos = runtime.GOOS
+
 // GetDockerHost returns the location of the Docker API depending on the platform
+// when Docker is not installed in the default location the location of the
+// rootless Docker socket or the Podman socket is returned
 func GetDockerHost() string {
 	if dh := os.Getenv("DOCKER_HOST"); dh != "" {
 		return dh
 	}
 
-	return "/var/run/docker.sock"
+	// use the endpoint from the active docker context, this can be
+	// a unix socket, tcp, ssh, or npipe endpoint
+	if ch := dockerContextHost(); ch != "" {
+		return ch
+	}
+
+	// Docker Desktop on Windows listens on a named pipe
+	if goos == "windows" {
+		return dockerPipe
+	}
+
+	if _, err := os.Stat(dockerSocket); err != nil {
+		// rootless Docker creates the socket in the users runtime directory
+		if xdg := os.Getenv("XDG_RUNTIME_DIR"); xdg != "" {
+			rs := filepath.Join(xdg, "docker.sock")
+			if _, err := os.Stat(rs); err == nil {
+				return rs
+			}
+		}
+
+		if ps := GetPodmanSocket(); ps != "" {
+			return ps
+		}
+	}
+
+	return dockerSocket
+}
+
+// dockerContextHost returns the Docker endpoint for the active docker context,
+// the context is read from the DOCKER_CONTEXT environment variable or the
+// docker config file. Returns an empty string when the default context is used
+func dockerContextHost() string {
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		configDir = filepath.Join(HomeFolder(), ".docker")
+	}
+
+	name := os.Getenv("DOCKER_CONTEXT")
+	if name == "" {
+		d, err := ioutil.ReadFile(filepath.Join(configDir, "config.json"))
+		if err != nil {
+			return ""
+		}
+
+		dc := struct {
+			CurrentContext string `json:"currentContext"`
+		}{}
+
+		if json.Unmarshal(d, &dc) != nil {
+			return ""
+		}
+
+		name = dc.CurrentContext
+	}
+
+	if name == "" || name == "default" {
+		return ""
+	}
+
+	// the context store uses the sha256 of the context name as the folder name
+	meta := filepath.Join(configDir, "contexts", "meta", fmt.Sprintf("%x", sha256.Sum256([]byte(name))), "meta.json")
+
+	d, err := ioutil.ReadFile(meta)
+	if err != nil {
+		return ""
+	}
+
+	cm := struct {
+		Endpoints map[string]struct {
+			Host string `json:"Host"`
+		} `json:"Endpoints"`
+	}{}
+
+	if json.Unmarshal(d, &cm) != nil {
+		return ""
+	}
+
+	return cm.Endpoints["docker"].Host
+}
+
+// GetPodmanSocket returns the location of the Podman API socket, the rootless
+// socket in $XDG_RUNTIME_DIR is preferred over the system socket.
+// Returns an empty string when Podman is not running
+func GetPodmanSocket() string {
+	if xdg := os.Getenv("XDG_RUNTIME_DIR"); xdg != "" {
+		s := filepath.Join(xdg, "podman", "podman.sock")
+		if _, err := os.Stat(s); err == nil {
+			return s
+		}
+	}
+
+	if _, err := os.Stat(podmanRootSocket); err == nil {
+		return podmanRootSocket
+	}
+
+	return ""
+}
+
+// IsRootlessPodmanSocket returns true when the given socket is a rootless
+// Podman socket which is owned by the current user
+func IsRootlessPodmanSocket(socket string) bool {
+	xdg := os.Getenv("XDG_RUNTIME_DIR")
+	if xdg == "" {
+		return false
+	}
+
+	socket = strings.TrimPrefix(socket, "unix://")
+
+	return strings.HasPrefix(socket, filepath.Join(xdg, "podman"))
+}
+
+// dockerIP overrides the address of the Docker server, it is set when Shipyard
+// is running in a container and the engine is not reachable on localhost
+var dockerIP string
+
+// SetDockerIP sets the address returned by GetDockerIP, setting a blank
+// address removes the override
+func SetDockerIP(ip string) {
+	dockerIP = ip
 }
 
 // GetDockerIP returns the location of the Docker Server IP address
 func GetDockerIP() string {
-	if dh := os.Getenv("DOCKER_HOST"); dh != "" {
-		if strings.HasPrefix(dh, "tcp://") {
-			u, err := url.Parse(dh)
-			if err == nil {
-				host := strings.Split(u.Host, ":")[0]
-				ip, err := net.LookupHost(host)
-				if err == nil && len(ip) > 0 {
-					return ip[0]
-				}
+	if dockerIP != "" {
+		return dockerIP
+	}
+
+	dh := GetDockerHost()
+
+	// remote engines are accessed using the ip of the remote host
+	if strings.HasPrefix(dh, "tcp://") || strings.HasPrefix(dh, "ssh://") {
+		u, err := url.Parse(dh)
+		if err == nil {
+			ip, err := net.LookupHost(u.Hostname())
+			if err == nil && len(ip) > 0 {
+				return ip[0]
 			}
 		}
 	}
@@ -330,11 +639,60 @@ func GetDockerIP() string {
 	return "127.0.0.1"
 }
 
+// files created by Docker and Podman in the root of a container, and the mount table
+// of the current process, variables allow the locations to be replaced in tests
+var containerEnvFiles = []string{"/.dockerenv", "/run/.containerenv"}
+var mountInfoPath = "/proc/self/mountinfo"
+
+// containerIDRegex matches the id of a container in the path of the files Docker
+// mounts into the container e.g. /var/lib/docker/containers/[id]/hostname
+var containerIDRegex = regexp.MustCompile(`/containers/([0-9a-f]{64})/`)
+
+// InContainer returns true when Shipyard is running inside a container, e.g. a CI job
+// using a Docker executor. The detection can be overridden by setting the environment
+// variable SHIPYARD_IN_CONTAINER to true or false
+func InContainer() bool {
+	if ic := os.Getenv("SHIPYARD_IN_CONTAINER"); ic != "" {
+		return strings.ToLower(ic) == "true" || ic == "1"
+	}
+
+	for _, f := range containerEnvFiles {
+		if _, err := os.Stat(f); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainerID returns the id of the container Shipyard is running in, the id is read
+// from the environment variable SHIPYARD_CONTAINER_ID, the files Docker mounts into the
+// container, or the hostname which Docker sets to the short id of the container
+func ContainerID() string {
+	if id := os.Getenv("SHIPYARD_CONTAINER_ID"); id != "" {
+		return id
+	}
+
+	if d, err := ioutil.ReadFile(mountInfoPath); err == nil {
+		if m := containerIDRegex.FindSubmatch(d); len(m) == 2 {
+			return string(m[1])
+		}
+	}
+
+	return GetHostname()
+}
+
 // GetConnectorPIDFile returns the connector PID file used by the connector
 func GetConnectorPIDFile() string {
 	return filepath.Join(ShipyardHome(), "connector.pid")
 }
 
+// RemoteConnectorsDir returns the folder containing the connectors on other
+// machines which have been joined with shipyard connector join
+func RemoteConnectorsDir() string {
+	return filepath.Join(ShipyardHome(), "remotes")
+}
+
 // GetConnectorLogFile returns the log file used by the connector
 func GetConnectorLogFile() string {
 	return filepath.Join(LogsDir(), "connector.log")
@@ -377,9 +735,7 @@ func compileShipyardBinary(path string) error {
 
 				err := cmd.Run()
 				if err != nil {
-					fmt.Println("Error building temporary binary:", cmd.Args)
-					fmt.Println(outwriter.String())
-					panic(fmt.Errorf("unable to build connector binary: %s", err))
+					return fmt.Errorf("unable to build connector binary with %s: %s\n%s", strings.Join(cmd.Args, " "), err, outwriter.String())
 				}
 
 				return nil
@@ -388,33 +744,38 @@ func compileShipyardBinary(path string) error {
 
 		// check the parent
 		dir = filepath.Join(dir, "../")
-		fmt.Println(dir)
 		currentLevel++
 		if currentLevel > maxLevels {
-			panic("unable to find go.mod")
+			return fmt.Errorf("unable to build connector binary, unable to find go.mod")
 		}
 	}
 }
 
 var buildSync = sync.Once{}
+var buildErr error
 
-// GetShipyardBinaryPath returns the path to the running Shipyard binary
-func GetShipyardBinaryPath() string {
+// GetShipyardBinaryPath returns the path to the running Shipyard binary, when
+// running from tests the binary is compiled from the source
+func GetShipyardBinaryPath() (string, error) {
 	if strings.HasSuffix(os.Args[0], "shipyard") || strings.HasSuffix(os.Args[0], "yard-dev") || strings.HasSuffix(os.Args[0], "shipyard.exe") {
 		ex, err := os.Executable()
 		if err != nil {
-			panic(err)
+			return "", fmt.Errorf("unable to determine the path of the Shipyard binary: %w", err)
 		}
 
-		return ex
+		return ex, nil
 	}
 
 	tmpBinary := filepath.Join(os.TempDir(), "shipyard-dev")
 	buildSync.Do(func() {
-		compileShipyardBinary(tmpBinary)
+		buildErr = compileShipyardBinary(tmpBinary)
 	})
 
-	return tmpBinary
+	if buildErr != nil {
+		return "", buildErr
+	}
+
+	return tmpBinary, nil
 }
 
 // GetHostname returns the hostname for the current machine
@@ -445,6 +806,17 @@ func GetLocalIPAddresses() []string {
 	return addresses
 }
 
+// GetFreePort returns a port which is not in use on the local machine
+func GetFreePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
 // GetLocalIPAndHostname returns the IP Address of the machine
 // running shipyard and the hostname for that machine
 func GetLocalIPAndHostname() (string, string) {
@@ -471,6 +843,33 @@ func HTTPProxyAddress() string {
 	return shipyardProxyAddress
 }
 
+// NoProxy returns the hosts which are not proxied, the Shipyard defaults are
+// merged with the hosts in the environment variable NO_PROXY
+func NoProxy() string {
+	return MergeNoProxy(ProxyBypass, os.Getenv("NO_PROXY"))
+}
+
+// MergeNoProxy combines comma separated lists of hosts which are not proxied,
+// duplicate and empty entries are removed
+func MergeNoProxy(lists ...string) string {
+	hosts := []string{}
+	seen := map[string]bool{}
+
+	for _, l := range lists {
+		for _, h := range strings.Split(l, ",") {
+			h = strings.TrimSpace(h)
+			if h == "" || seen[h] {
+				continue
+			}
+
+			seen[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+
+	return strings.Join(hosts, ",")
+}
+
 // HTTPSProxyAddress returns the default HTTPProxy used by
 // Nomad and Kubernetes clusters unless the environment variable
 // HTTPS_PROXY is set when it returns this value